@@ -0,0 +1,289 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/emvi/logbuch"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+const adminUsage = `usage: wakapi admin <command> [<args>]
+
+commands:
+  user create <user> [-email <email>] [-password <password>] [-admin]
+  user list
+  user reset-password <user> [-password <password>]
+  user reset-api-key <user>
+  import <user> [-legacy]                  # import heartbeats from wakatime, using the user's wakatime api key
+  export <user> <file>                     # write the user's data export archive to a file
+  aggregate [-from <date>] [-to <date>] [<user>...]   # dates as yyyy-mm-dd, inclusive, default to yesterday
+  config                                   # print the effective config, with sensitive values masked`
+
+// adminConfig prints the effective config, it is run before connecting to the database, so it also helps to debug connection issues
+func adminConfig() int {
+	data, err := config.DumpRedacted()
+	if err != nil {
+		logbuch.Error("failed to dump config, %v", err)
+		return 1
+	}
+	os.Stdout.Write(data)
+	return 0
+}
+
+// admin runs administrative tasks from the command line, e.g. on headless deployments, where the web ui is not conveniently at hand
+func admin(args []string) int {
+	if len(args) == 0 {
+		logbuch.Error(adminUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "user":
+		if len(args) < 2 {
+			logbuch.Error(adminUsage)
+			return 2
+		}
+		switch args[1] {
+		case "create":
+			return adminCreateUser(args[2:])
+		case "list":
+			return adminListUsers()
+		case "reset-password":
+			return adminResetPassword(args[2:])
+		case "reset-api-key":
+			return adminResetApiKey(args[2:])
+		}
+	case "import":
+		return adminImport(args[1:])
+	case "export":
+		return adminExport(args[1:])
+	case "aggregate":
+		return adminAggregate(args[1:])
+	}
+
+	logbuch.Error(adminUsage)
+	return 2
+}
+
+func adminCreateUser(args []string) int {
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	email := fs.String("email", "", "e-mail address")
+	password := fs.String("password", "", "password, a random one is generated if not given")
+	isAdmin := fs.Bool("admin", false, "whether to grant admin privileges")
+	username, err := parseAdminArgs(fs, args)
+	if err != nil || len(username) != 1 {
+		logbuch.Error("usage: wakapi admin user create <user> [-email <email>] [-password <password>] [-admin]")
+		return 2
+	}
+
+	generated := *password == ""
+	if generated {
+		*password = randomPassword()
+	}
+
+	signup := &models.Signup{Username: username[0], Email: *email, Password: *password, Location: time.Local.String()}
+	if !models.ValidateUsername(signup.Username) || !models.ValidateEmail(signup.Email) || !models.ValidatePassword(signup.Password) {
+		logbuch.Error("invalid username, e-mail address or password (must be at least 6 characters)")
+		return 2
+	}
+
+	user, created, err := userService.CreateOrGet(signup, *isAdmin)
+	if err != nil {
+		logbuch.Error("failed to create user '%s', %v", signup.Username, err)
+		return 1
+	}
+	if !created {
+		logbuch.Error("user '%s' already exists", user.ID)
+		return 1
+	}
+
+	fmt.Printf("created user '%s'\napi key: %s\n", user.ID, user.ApiKey)
+	if generated {
+		fmt.Printf("password: %s\n", *password)
+	}
+	return 0
+}
+
+func adminListUsers() int {
+	users, err := userService.GetAll()
+	if err != nil {
+		logbuch.Error("failed to fetch users, %v", err)
+		return 1
+	}
+
+	fmt.Printf("%-32s %-40s %-6s %s\n", "USER", "E-MAIL", "ADMIN", "LAST LOGGED IN")
+	for _, u := range users {
+		fmt.Printf("%-32s %-40s %-6t %s\n", u.ID, u.Email, u.IsAdmin, u.LastLoggedInAt.T().Format(conf.SimpleDateTimeFormat))
+	}
+	return 0
+}
+
+func adminResetPassword(args []string) int {
+	fs := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	password := fs.String("password", "", "new password, a random one is generated if not given")
+	userIds, err := parseAdminArgs(fs, args)
+	if err != nil || len(userIds) != 1 {
+		logbuch.Error("usage: wakapi admin user reset-password <user> [-password <password>]")
+		return 2
+	}
+
+	user, err := userService.GetUserById(userIds[0])
+	if err != nil {
+		logbuch.Error("user '%s' not found", userIds[0])
+		return 1
+	}
+
+	generated := *password == ""
+	if generated {
+		*password = randomPassword()
+	}
+	if !models.ValidatePassword(*password) {
+		logbuch.Error("invalid password, must be at least 6 characters")
+		return 2
+	}
+
+	hash, err := utils.HashPassword(*password, config.Security.PasswordSalt)
+	if err != nil {
+		logbuch.Error("failed to hash password, %v", err)
+		return 1
+	}
+	user.Password = hash
+	if _, err := userService.Update(user); err != nil {
+		logbuch.Error("failed to update password of user '%s', %v", user.ID, err)
+		return 1
+	}
+
+	fmt.Printf("reset password of user '%s'\n", user.ID)
+	if generated {
+		fmt.Printf("password: %s\n", *password)
+	}
+	return 0
+}
+
+func adminResetApiKey(args []string) int {
+	if len(args) != 1 {
+		logbuch.Error("usage: wakapi admin user reset-api-key <user>")
+		return 2
+	}
+
+	user, err := userService.GetUserById(args[0])
+	if err != nil {
+		logbuch.Error("user '%s' not found", args[0])
+		return 1
+	}
+	if user, err = userService.ResetApiKey(user); err != nil {
+		logbuch.Error("failed to reset api key of user '%s', %v", args[0], err)
+		return 1
+	}
+
+	fmt.Printf("api key: %s\n", user.ApiKey)
+	return 0
+}
+
+func adminImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	legacy := fs.Bool("legacy", false, "use the legacy importer, which fetches heartbeats day by day instead of requesting a data dump")
+	userIds, err := parseAdminArgs(fs, args)
+	if err != nil || len(userIds) != 1 {
+		logbuch.Error("usage: wakapi admin import <user> [-legacy]")
+		return 2
+	}
+
+	user, err := userService.GetUserById(userIds[0])
+	if err != nil {
+		logbuch.Error("user '%s' not found", userIds[0])
+		return 1
+	}
+	if user.WakatimeApiKey == "" {
+		logbuch.Error("user '%s' has no wakatime api key configured", user.ID)
+		return 1
+	}
+
+	count, err := importService.RunWakatimeImport(user, *legacy)
+	if err != nil {
+		logbuch.Error("failed to import heartbeats of user '%s' after %d heartbeats, %v", user.ID, count, err)
+		return 1
+	}
+	logbuch.Info("imported %d heartbeats of user '%s'", count, user.ID)
+	return 0
+}
+
+func adminExport(args []string) int {
+	if len(args) != 2 {
+		logbuch.Error("usage: wakapi admin export <user> <file>")
+		return 2
+	}
+
+	user, err := userService.GetUserById(args[0])
+	if err != nil {
+		logbuch.Error("user '%s' not found", args[0])
+		return 1
+	}
+
+	file, err := os.OpenFile(args[1], os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		logbuch.Error("failed to create export file, %v", err)
+		return 1
+	}
+	if err := exportService.Export(user, file); err != nil {
+		file.Close()
+		os.Remove(args[1])
+		logbuch.Error("failed to export data of user '%s', %v", user.ID, err)
+		return 1
+	}
+	if err := file.Close(); err != nil {
+		logbuch.Error("failed to write export file, %v", err)
+		return 1
+	}
+
+	logbuch.Info("exported data of user '%s' to %s", user.ID, args[1])
+	return 0
+}
+
+func adminAggregate(args []string) int {
+	yesterday := time.Now().AddDate(0, 0, -1).Format(conf.SimpleDateFormat)
+
+	fs := flag.NewFlagSet("aggregate", flag.ContinueOnError)
+	from := fs.String("from", yesterday, "first day to aggregate (yyyy-mm-dd)")
+	to := fs.String("to", yesterday, "last day to aggregate (yyyy-mm-dd)")
+	userIds, err := parseAdminArgs(fs, args)
+	if err != nil {
+		logbuch.Error("usage: wakapi admin aggregate [-from <date>] [-to <date>] [<user>...]")
+		return 2
+	}
+
+	return backfill(*from, *to, userIds)
+}
+
+// parseAdminArgs parses flags given either before or after positional arguments and returns the latter
+func parseAdminArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(os.Stderr)
+
+	positional := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func randomPassword() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		logbuch.Fatal("failed to generate password, %v", err)
+	}
+	return strings.TrimRight(base64.URLEncoding.EncodeToString(b), "=")
+}
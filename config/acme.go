@@ -0,0 +1,78 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const (
+	AcmeChallengeTlsAlpn = "tls-alpn-01"
+	AcmeChallengeHttp    = "http-01"
+)
+
+func (c *acmeConfig) GetDomains() []string {
+	domains := make([]string, 0)
+	for _, d := range strings.Split(c.Domains, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
+func (c *acmeConfig) UseHttpChallenge() bool {
+	return c.Challenge == AcmeChallengeHttp
+}
+
+// NewManager creates an autocert manager, which obtains certificates for the configured domains on their first request and renews them before they expire
+// certificates and the account key are kept in the cache dir, so they survive restarts without hitting the ca's rate limits
+func (c *acmeConfig) NewManager() *autocert.Manager {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(c.CacheDir),
+		HostPolicy: autocert.HostWhitelist(c.GetDomains()...),
+		Email:      c.Email,
+	}
+	if c.DirectoryUrl != "" {
+		m.Client = &acme.Client{DirectoryURL: c.DirectoryUrl}
+	}
+	return m
+}
+
+// TLSConfig returns the server tls config for the given manager, which only answers tls-alpn-01 challenges if configured to use these
+func (c *acmeConfig) TLSConfig(m *autocert.Manager) *tls.Config {
+	if c.UseHttpChallenge() {
+		return &tls.Config{GetCertificate: m.GetCertificate, NextProtos: []string{"h2", "http/1.1"}}
+	}
+	return m.TLSConfig()
+}
+
+func (c *acmeConfig) validate(useTls bool) error {
+	if !c.Enabled {
+		return nil
+	}
+	if useTls {
+		return errors.New("acme can not be used together with tls_cert_path and tls_key_path")
+	}
+	if len(c.GetDomains()) == 0 {
+		return errors.New("acme requires at least one domain")
+	}
+	if c.Challenge != AcmeChallengeTlsAlpn && c.Challenge != AcmeChallengeHttp {
+		return fmt.Errorf("invalid acme challenge '%s', must be one of %s, %s", c.Challenge, AcmeChallengeTlsAlpn, AcmeChallengeHttp)
+	}
+	if c.UseHttpChallenge() && (c.HttpPort <= 0 || c.HttpPort > 65535) {
+		return errors.New("invalid acme http_port")
+	}
+	if c.DirectoryUrl != "" {
+		if u, err := url.Parse(c.DirectoryUrl); err != nil || u.Scheme != "https" {
+			return errors.New("invalid acme directory_url, must be an https url")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+type cacheConfig struct {
+	RedisUrl    string `yaml:"redis_url" env:"WAKAPI_CACHE_REDIS_URL"`                        // e.g. redis://:password@localhost:6379/0, leave blank to keep caches in memory
+	RedisPrefix string `yaml:"redis_prefix" default:"wakapi" env:"WAKAPI_CACHE_REDIS_PREFIX"` // prefix of all keys, e.g. to share a redis database among multiple instances
+}
+
+var (
+	redisClient     *redis.Client
+	redisClientOnce sync.Once
+)
+
+// NewCache returns a cache of the given name, which is kept in redis to be shared among replicas if configured, or in memory otherwise
+func NewCache(name string, defaultTtl, cleanupInterval time.Duration) utils.Cache {
+	config := Get()
+	if config == nil || config.Cache.RedisUrl == "" {
+		return utils.NewMemoryCache(defaultTtl, cleanupInterval)
+	}
+
+	redisClientOnce.Do(func() {
+		client, err := utils.NewRedisClient(config.Cache.RedisUrl)
+		if err != nil {
+			logbuch.Fatal("failed to set up redis cache - %v", err)
+		}
+		if err := client.Ping(context.Background()).Err(); err != nil {
+			logbuch.Warn("redis is not reachable, caching will be ineffective until it is - %v", err)
+		}
+		redisClient = client
+	})
+
+	return utils.NewRedisCache(redisClient, config.Cache.RedisPrefix+":"+name, defaultTtl)
+}
@@ -2,13 +2,16 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/emvi/logbuch"
@@ -16,6 +19,7 @@ import (
 	"github.com/jinzhu/configor"
 	"github.com/muety/wakapi/data"
 	"github.com/muety/wakapi/utils"
+	"github.com/redis/go-redis/v9"
 	"github.com/robfig/cron/v3"
 	uuid "github.com/satori/go.uuid"
 )
@@ -29,11 +33,21 @@ const (
 
 	KeyLatestTotalTime              = "latest_total_time"
 	KeyLatestTotalUsers             = "latest_total_users"
+	KeyLatestTotalTimePublic        = "latest_total_time_public"
+	KeyLatestTotalUsersPublic       = "latest_total_users_public"
 	KeyLastImport                   = "last_import"            // import attempt
 	KeyLastImportSuccess            = "last_successful_import" // last actual successful import
 	KeyFirstHeartbeat               = "first_heartbeat"
 	KeySubscriptionNotificationSent = "sub_reminder"
 	KeyNewsbox                      = "newsbox"
+	KeyActiveUsers                  = "active_users" // suffixed by activity class, e.g. active_users_weekly
+	KeyLatestCliVersion             = "latest_cli_version"
+	KeyOutdatedClientNotification   = "outdated_client_notification"
+	KeyInactivityAlert              = "inactivity_alert"
+	KeyDataRetentionConfirmed       = "data_retention_confirmed_months" // retention period last confirmed by an admin
+	KeyReportFailure                = "report_failure"                  // suffixed by user id, why the user's latest report could not be sent
+	KeyLoginLockout                 = "login_lockout"                   // suffixed by user id, until when logging in to the account is blocked after too many failed attempts
+	KeyPrivacyNoise                 = "privacy_noise"                   // suffixed by scope, laplace noise drawn for publicly shown aggregates
 
 	SessionKeyDefault = "default"
 
@@ -41,7 +55,9 @@ const (
 	SimpleDateTimeFormat = "2006-01-02 15:04:05"
 
 	ErrUnauthorized        = "401 unauthorized"
+	ErrForbidden           = "403 forbidden"
 	ErrBadRequest          = "400 bad request"
+	ErrNotFound            = "404 not found"
 	ErrInternalServerError = "500 internal server error"
 )
 
@@ -59,18 +75,59 @@ const (
 const (
 	MailProviderSmtp      = "smtp"
 	MailProviderMailWhale = "mailwhale"
+	MailProviderSes       = "ses"
+	MailProviderSendgrid  = "sendgrid"
+	MailProviderMailgun   = "mailgun"
 )
 
+const (
+	SmtpEncryptionNone     = "none"
+	SmtpEncryptionStartTls = "starttls"
+	SmtpEncryptionTls      = "tls"
+	SmtpAuthPlain          = "PLAIN"
+	SmtpAuthLogin          = "LOGIN"
+	SmtpAuthCramMd5        = "CRAM-MD5"
+)
+
+const (
+	MetricsPushModePushgateway = "pushgateway"
+	MetricsPushModeRemoteWrite = "remote_write"
+)
+
+const (
+	CaptchaProviderHCaptcha  = "hcaptcha"
+	CaptchaProviderTurnstile = "turnstile"
+	CaptchaProviderPow       = "pow" // built-in proof-of-work challenge, solved by the browser without any third party involved
+)
+
+const (
+	RetentionModeDelete    = "delete"
+	RetentionModeAnonymize = "anonymize" // strip identifying fields from expired data instead of deleting it
+)
+
+const (
+	PaymentProviderStripe = "stripe"
+	PaymentProviderPayPal = "paypal"
+)
+
+const DefaultSubscriptionPlan = "standard" // id of the plan implied by the legacy standard_price_id option
+
 var emailProviders = []string{
 	MailProviderSmtp,
 	MailProviderMailWhale,
+	MailProviderSes,
+	MailProviderSendgrid,
+	MailProviderMailgun,
 }
 
-var cfg *Config
+// cfg is swapped as a whole on reloads instead of being modified in place, so that concurrent readers never see a partially updated config
+var cfg atomic.Pointer[Config]
 var env string
+var configPath string
 
 type appConfig struct {
 	AggregationTime           string                       `yaml:"aggregation_time" default:"0 15 2 * * *" env:"WAKAPI_AGGREGATION_TIME"`
+	IncrementalAggregationMin int                          `yaml:"incremental_aggregation_min" default:"5" env:"WAKAPI_INCREMENTAL_AGGREGATION_MIN"` // 0 to disable
 	LeaderboardGenerationTime string                       `yaml:"leaderboard_generation_time" default:"0 0 6 * * *,0 0 18 * * *" env:"WAKAPI_LEADERBOARD_GENERATION_TIME"`
 	ReportTimeWeekly          string                       `yaml:"report_time_weekly" default:"0 0 18 * * 5" env:"WAKAPI_REPORT_TIME_WEEKLY"`
 	DataCleanupTime           string                       `yaml:"data_cleanup_time" default:"0 0 6 * * 0" env:"WAKAPI_DATA_CLEANUP_TIME"`
@@ -79,31 +136,169 @@ type appConfig struct {
 	ImportMaxRate             int                          `yaml:"import_max_rate" default:"24" env:"WAKAPI_IMPORT_MAX_RATE"` // at max one successful import every x hours
 	ImportBatchSize           int                          `yaml:"import_batch_size" default:"50" env:"WAKAPI_IMPORT_BATCH_SIZE"`
 	InactiveDays              int                          `yaml:"inactive_days" default:"7" env:"WAKAPI_INACTIVE_DAYS"`
+	ActivityDaysDaily         int                          `yaml:"activity_days_daily" default:"1" env:"WAKAPI_ACTIVITY_DAYS_DAILY"`
+	ActivityDaysWeekly        int                          `yaml:"activity_days_weekly" default:"7" env:"WAKAPI_ACTIVITY_DAYS_WEEKLY"`
+	ActivityDaysMonthly       int                          `yaml:"activity_days_monthly" default:"30" env:"WAKAPI_ACTIVITY_DAYS_MONTHLY"`
+	InactiveCleanupDays       int                          `yaml:"inactive_cleanup_days" default:"0" env:"WAKAPI_INACTIVE_CLEANUP_DAYS"`
 	HeartbeatMaxAge           string                       `yaml:"heartbeat_max_age" default:"4320h" env:"WAKAPI_HEARTBEAT_MAX_AGE"`
 	CountCacheTTLMin          int                          `yaml:"count_cache_ttl_min" default:"30" env:"WAKAPI_COUNT_CACHE_TTL_MIN"`
 	DataRetentionMonths       int                          `yaml:"data_retention_months" default:"-1" env:"WAKAPI_DATA_RETENTION_MONTHS"`
+	DataRetentionMode         string                       `yaml:"data_retention_mode" default:"delete" env:"WAKAPI_DATA_RETENTION_MODE"`  // one of ['delete', 'anonymize']
 	DataCleanupDryRun         bool                         `yaml:"data_cleanup_dry_run" default:"false" env:"WAKAPI_DATA_CLEANUP_DRY_RUN"` // for debugging only
+	ExportDir                 string                       `yaml:"export_dir" default:"exports" env:"WAKAPI_EXPORT_DIR"`
+	ExportMaxAge              string                       `yaml:"export_max_age" default:"72h" env:"WAKAPI_EXPORT_MAX_AGE"`
+	ExportTargetsEnabled      bool                         `yaml:"export_targets_enabled" default:"false" env:"WAKAPI_EXPORT_TARGETS_ENABLED"` // whether users may have their data dumped to their own s3 bucket regularly
+	ExportTargetsTime         string                       `yaml:"export_targets_time" default:"0 0 3 * * *" env:"WAKAPI_EXPORT_TARGETS_TIME"`
+	AccountDeletionGraceDays  int                          `yaml:"account_deletion_grace_days" default:"7" env:"WAKAPI_ACCOUNT_DELETION_GRACE_DAYS"`
 	AvatarURLTemplate         string                       `yaml:"avatar_url_template" default:"api/avatar/{username_hash}.svg" env:"WAKAPI_AVATAR_URL_TEMPLATE"`
 	SupportContact            string                       `yaml:"support_contact" default:"hostmaster@wakapi.dev" env:"WAKAPI_SUPPORT_CONTACT"`
 	CustomLanguages           map[string]string            `yaml:"custom_languages"`
+	DetectLanguages           bool                         `yaml:"detect_languages" default:"true" env:"WAKAPI_DETECT_LANGUAGES"` // whether to detect languages from file names and extensions if not reported by the client
+	ClientVersions            map[string]string            `yaml:"client_versions"`
+	Newsbox                   newsboxConfig                `yaml:"newsbox"`
+	PublicStatsPrivacy        privacyConfig                `yaml:"public_stats_privacy"`
+	PublicTrends              bool                         `yaml:"public_trends" default:"false" env:"WAKAPI_PUBLIC_TRENDS"` // whether to publish anonymized, instance-wide language trends via the api
+	LeaderboardEligibility    leaderboardEligibilityConfig `yaml:"leaderboard_eligibility"`
+	Archive                   archiveConfig                `yaml:"archive"`
+	Quotas                    quotaConfig                  `yaml:"quotas"`
+	CliReleaseUrl             string                       `yaml:"cli_release_url" default:"https://api.github.com/repos/wakatime/wakatime-cli/releases/latest" env:"WAKAPI_CLI_RELEASE_URL"`
+	OutdatedClientMinorLag    int                          `yaml:"outdated_client_minor_lag" default:"10" env:"WAKAPI_OUTDATED_CLIENT_MINOR_LAG"`
+	OutdatedClientMails       bool                         `yaml:"outdated_client_mails" default:"false" env:"WAKAPI_OUTDATED_CLIENT_MAILS"`
+	WatchConfig               bool                         `yaml:"watch_config" default:"false" env:"WAKAPI_WATCH_CONFIG"`
+	WatchConfigIntervalSec    int                          `yaml:"watch_config_interval_sec" default:"10" env:"WAKAPI_WATCH_CONFIG_INTERVAL_SEC"`
+	OrgMode                   bool                         `yaml:"org_mode" default:"false" env:"WAKAPI_ORG_MODE"`              // instance is run by a single organization, whose admins review changes to members' data
+	SandboxEnabled            bool                         `yaml:"sandbox_enabled" default:"true" env:"WAKAPI_SANDBOX_ENABLED"` // whether plugin developers may send test heartbeats to /api/sandbox
+	SandboxPurgeTime          string                       `yaml:"sandbox_purge_time" default:"0 30 3 * * *" env:"WAKAPI_SANDBOX_PURGE_TIME"`
+	DistributedLocking        bool                         `yaml:"distributed_locking" default:"false" env:"WAKAPI_DISTRIBUTED_LOCKING"` // required when running multiple instances against the same database
+	PersistentJobs            bool                         `yaml:"persistent_jobs" default:"false" env:"WAKAPI_PERSISTENT_JOBS"`         // keep pending reports, imports and cleanups in the database to resume them after a restart
+	GeoIpUrl                  string                       `yaml:"geoip_url" default:"" env:"WAKAPI_GEOIP_URL"`                          // json endpoint to look up machines' locations by their ip, which is substituted for {ip}, e.g. http://ip-api.com/json/{ip}
 	Colors                    map[string]map[string]string `yaml:"-"`
 }
 
+// newsboxConfig is a message to show on the front page, which takes precedence over one set in the database
+type newsboxConfig struct {
+	Type string `yaml:"type" default:"info" env:"WAKAPI_NEWSBOX_TYPE"`
+	Text string `yaml:"text" env:"WAKAPI_NEWSBOX_TEXT"`
+}
+
+// privacyConfig controls the differential privacy applied to instance-wide aggregate statistics shown to the public, e.g. total coding time on the front page
+type privacyConfig struct {
+	Enabled         bool    `yaml:"enabled" default:"false" env:"WAKAPI_PUBLIC_STATS_PRIVACY_ENABLED"`
+	Epsilon         float64 `yaml:"epsilon" default:"1.0" env:"WAKAPI_PUBLIC_STATS_PRIVACY_EPSILON"`
+	MinUsers        int     `yaml:"min_users" default:"10" env:"WAKAPI_PUBLIC_STATS_PRIVACY_MIN_USERS"`
+	MaxHoursPerUser int     `yaml:"max_hours_per_user" default:"5000" env:"WAKAPI_PUBLIC_STATS_PRIVACY_MAX_HOURS_PER_USER"`
+}
+
+// leaderboardEligibilityConfig holds additional criteria users must meet to be listed in the public leaderboard, e.g. to keep throwaway accounts off of it
+type leaderboardEligibilityConfig struct {
+	MinAccountAgeDays    int  `yaml:"min_account_age_days" default:"0" env:"WAKAPI_LEADERBOARD_MIN_ACCOUNT_AGE_DAYS"`
+	MinActiveDays        int  `yaml:"min_active_days" default:"0" env:"WAKAPI_LEADERBOARD_MIN_ACTIVE_DAYS"` // number of days with any coding activity within the leaderboard interval
+	RequireVerifiedEmail bool `yaml:"require_verified_email" default:"false" env:"WAKAPI_LEADERBOARD_REQUIRE_VERIFIED_EMAIL"`
+}
+
+// quotaConfig limits what a single (non-admin) user may send, e.g. to protect public instances from runaway or malicious clients, 0 means unlimited
+// heartbeats beyond a limit are rejected, while getting close to one (warn_ratio) is only logged and reported to the client
+type quotaConfig struct {
+	HeartbeatsPerDay int     `yaml:"heartbeats_per_day" default:"0" env:"WAKAPI_QUOTA_HEARTBEATS_PER_DAY"`
+	Machines         int     `yaml:"machines" default:"0" env:"WAKAPI_QUOTA_MACHINES"`
+	Projects         int     `yaml:"projects" default:"0" env:"WAKAPI_QUOTA_PROJECTS"`
+	WarnRatio        float64 `yaml:"warn_ratio" default:"0.8" env:"WAKAPI_QUOTA_WARN_RATIO"`
+}
+
+type integrationsConfig struct {
+	Influx influxConfig `yaml:"influx"`
+}
+
+// influxConfig makes the instance periodically write users' daily summaries to influxdb (or any other database speaking its line protocol, like victoriametrics), e.g. for grafana dashboards
+type influxConfig struct {
+	Url         string `yaml:"url" default:"" env:"WAKAPI_INFLUX_URL"` // e.g. http://localhost:8086, leave blank to disable
+	Org         string `yaml:"org" default:"" env:"WAKAPI_INFLUX_ORG"`
+	Bucket      string `yaml:"bucket" default:"wakapi" env:"WAKAPI_INFLUX_BUCKET"`
+	Token       string `yaml:"token" default:"" env:"WAKAPI_INFLUX_TOKEN"`
+	Measurement string `yaml:"measurement" default:"wakapi" env:"WAKAPI_INFLUX_MEASUREMENT"` // prefix of the measurements' names
+	Time        string `yaml:"time" default:"0 45 2 * * *" env:"WAKAPI_INFLUX_TIME"`         // after daily aggregation
+	Days        int    `yaml:"days" default:"1" env:"WAKAPI_INFLUX_DAYS"`                    // number of past days to (re-)write on every run
+	Users       string `yaml:"users" default:"" env:"WAKAPI_INFLUX_USERS"`                   // comma-separated list of users to export, leave blank for all active users
+}
+
+// archiveConfig controls moving old heartbeats out of the database into compressed files, while their aggregated summaries are kept
+type archiveConfig struct {
+	AfterMonths int      `yaml:"after_months" default:"0" env:"WAKAPI_ARCHIVE_AFTER_MONTHS"` // 0 to disable
+	Time        string   `yaml:"time" default:"0 0 4 * * 0" env:"WAKAPI_ARCHIVE_TIME"`
+	Dir         string   `yaml:"dir" default:"archive" env:"WAKAPI_ARCHIVE_DIR"` // only used if no s3 bucket is configured
+	S3          s3Config `yaml:"s3"`
+}
+
+type s3Config struct {
+	Endpoint  string `yaml:"endpoint" env:"WAKAPI_ARCHIVE_S3_ENDPOINT"` // leave blank for aws
+	Region    string `yaml:"region" env:"WAKAPI_ARCHIVE_S3_REGION"`
+	Bucket    string `yaml:"bucket" env:"WAKAPI_ARCHIVE_S3_BUCKET"`
+	AccessKey string `yaml:"access_key" env:"WAKAPI_ARCHIVE_S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secret_key" env:"WAKAPI_ARCHIVE_S3_SECRET_KEY"`
+	PathStyle bool   `yaml:"path_style" default:"false" env:"WAKAPI_ARCHIVE_S3_PATH_STYLE"`
+}
+
 type securityConfig struct {
-	AllowSignup      bool `yaml:"allow_signup" default:"true" env:"WAKAPI_ALLOW_SIGNUP"`
-	ExposeMetrics    bool `yaml:"expose_metrics" default:"false" env:"WAKAPI_EXPOSE_METRICS"`
-	EnableProxy      bool `yaml:"enable_proxy" default:"false" env:"WAKAPI_ENABLE_PROXY"` // only intended for production instance at wakapi.dev
-	DisableFrontpage bool `yaml:"disable_frontpage" default:"false" env:"WAKAPI_DISABLE_FRONTPAGE"`
+	AllowSignup       bool `yaml:"allow_signup" default:"true" env:"WAKAPI_ALLOW_SIGNUP"`
+	ExposeMetrics     bool `yaml:"expose_metrics" default:"false" env:"WAKAPI_EXPOSE_METRICS"`
+	ExposePublicStats bool `yaml:"expose_public_stats" default:"false" env:"WAKAPI_EXPOSE_PUBLIC_STATS"` // anonymous, instance-wide stats at /api/stats/public
+	EnableProxy       bool `yaml:"enable_proxy" default:"false" env:"WAKAPI_ENABLE_PROXY"`               // only intended for production instance at wakapi.dev
+	DisableFrontpage  bool `yaml:"disable_frontpage" default:"false" env:"WAKAPI_DISABLE_FRONTPAGE"`
 	// this is actually a pepper (https://en.wikipedia.org/wiki/Pepper_(cryptography))
-	PasswordSalt              string                     `yaml:"password_salt" default:"" env:"WAKAPI_PASSWORD_SALT"`
-	InsecureCookies           bool                       `yaml:"insecure_cookies" default:"false" env:"WAKAPI_INSECURE_COOKIES"`
-	CookieMaxAgeSec           int                        `yaml:"cookie_max_age" default:"172800" env:"WAKAPI_COOKIE_MAX_AGE"`
-	TrustedHeaderAuth         bool                       `yaml:"trusted_header_auth" default:"false" env:"WAKAPI_TRUSTED_HEADER_AUTH"`
-	TrustedHeaderAuthKey      string                     `yaml:"trusted_header_auth_key" default:"Remote-User" env:"WAKAPI_TRUSTED_HEADER_AUTH_KEY"`
-	TrustReverseProxyIps      string                     `yaml:"trust_reverse_proxy_ips" default:"" env:"WAKAPI_TRUST_REVERSE_PROXY_IPS"` // comma-separated list of trusted reverse proxy ips
-	SecureCookie              *securecookie.SecureCookie `yaml:"-"`
-	SessionKey                []byte                     `yaml:"-"`
-	trustReverseProxyIpParsed []net.IP
+	PasswordSalt                string                     `yaml:"password_salt" default:"" env:"WAKAPI_PASSWORD_SALT"`
+	EncryptionKey               string                     `yaml:"encryption_key" default:"" env:"WAKAPI_ENCRYPTION_KEY"` // used to encrypt credentials stored in the database, must not be changed afterwards
+	InsecureCookies             bool                       `yaml:"insecure_cookies" default:"false" env:"WAKAPI_INSECURE_COOKIES"`
+	CookieMaxAgeSec             int                        `yaml:"cookie_max_age" default:"172800" env:"WAKAPI_COOKIE_MAX_AGE"`
+	TrustedHeaderAuth           bool                       `yaml:"trusted_header_auth" default:"false" env:"WAKAPI_TRUSTED_HEADER_AUTH"`
+	TrustedHeaderAuthKey        string                     `yaml:"trusted_header_auth_key" default:"Remote-User" env:"WAKAPI_TRUSTED_HEADER_AUTH_KEY"`
+	TrustedHeaderAuthEmailKey   string                     `yaml:"trusted_header_auth_email_key" default:"" env:"WAKAPI_TRUSTED_HEADER_AUTH_EMAIL_KEY"`          // e.g. Remote-Email, leave blank to not sync e-mail addresses
+	TrustedHeaderAuthGroupsKey  string                     `yaml:"trusted_header_auth_groups_key" default:"" env:"WAKAPI_TRUSTED_HEADER_AUTH_GROUPS_KEY"`        // e.g. Remote-Groups, comma- or pipe-separated
+	TrustedHeaderAuthAdminGroup string                     `yaml:"trusted_header_auth_admin_group" default:"" env:"WAKAPI_TRUSTED_HEADER_AUTH_ADMIN_GROUP"`      // members of this group are made admins, all others are not
+	TrustedHeaderAuthAutoSignup bool                       `yaml:"trusted_header_auth_auto_signup" default:"false" env:"WAKAPI_TRUSTED_HEADER_AUTH_AUTO_SIGNUP"` // create accounts for unknown users, regardless of allow_signup
+	TrustReverseProxyIps        string                     `yaml:"trust_reverse_proxy_ips" default:"" env:"WAKAPI_TRUST_REVERSE_PROXY_IPS"`                      // comma-separated list of trusted reverse proxy ips
+	AdminIpAllowlist            string                     `yaml:"admin_ip_allowlist" default:"" env:"WAKAPI_ADMIN_IP_ALLOWLIST"`                                // comma-separated list of ips or cidr ranges allowed to access admin endpoints, pprof and admin metrics, leave blank to not restrict
+	Captcha                     CaptchaConfig              `yaml:"captcha"`
+	LoginThrottling             loginThrottlingConfig      `yaml:"login_throttling"`
+	Headers                     securityHeadersConfig      `yaml:"headers"`
+	MetricsPush                 metricsPushConfig          `yaml:"metrics_push"`
+	SecureCookie                *securecookie.SecureCookie `yaml:"-"`
+	SessionKey                  []byte                     `yaml:"-"`
+	trustReverseProxyIpParsed   []net.IP
+	adminIpAllowlistParsed      []*net.IPNet
+}
+
+// metricsPushConfig makes the instance periodically push its operator metrics, e.g. when running behind nat, where prometheus can't scrape it
+type metricsPushConfig struct {
+	Mode        string `yaml:"mode" default:"" env:"WAKAPI_METRICS_PUSH_MODE"` // pushgateway or remote_write, leave blank to disable
+	Url         string `yaml:"url" default:"" env:"WAKAPI_METRICS_PUSH_URL"`   // pushgateway base url or remote write endpoint
+	IntervalSec int    `yaml:"interval_sec" default:"60" env:"WAKAPI_METRICS_PUSH_INTERVAL_SEC"`
+	Job         string `yaml:"job" default:"wakapi" env:"WAKAPI_METRICS_PUSH_JOB"`
+	Username    string `yaml:"username" default:"" env:"WAKAPI_METRICS_PUSH_USERNAME"`
+	Password    string `yaml:"password" default:"" env:"WAKAPI_METRICS_PUSH_PASSWORD"`
+}
+
+// securityHeadersConfig controls the security headers sent along with web pages and embeddable resources (badges, activity charts)
+type securityHeadersConfig struct {
+	Enabled               bool              `yaml:"enabled" default:"true" env:"WAKAPI_SECURITY_HEADERS_ENABLED"`
+	HstsMaxAgeSec         int               `yaml:"hsts_max_age_sec" default:"0" env:"WAKAPI_SECURITY_HEADERS_HSTS_MAX_AGE_SEC"` // 0 to not send strict-transport-security
+	HstsIncludeSubdomains bool              `yaml:"hsts_include_subdomains" default:"false" env:"WAKAPI_SECURITY_HEADERS_HSTS_INCLUDE_SUBDOMAINS"`
+	EmbedFrameAncestors   string            `yaml:"embed_frame_ancestors" default:"*" env:"WAKAPI_SECURITY_HEADERS_EMBED_FRAME_ANCESTORS"` // space-separated csp sources allowed to frame embeddable resources
+	Overrides             map[string]string `yaml:"overrides"`                                                                             // header name to value, an empty value removes the header
+}
+
+// CaptchaConfig configures the challenge to be solved for signing up and requesting password resets
+type CaptchaConfig struct {
+	Provider      string `yaml:"provider" default:"" env:"WAKAPI_CAPTCHA_PROVIDER"` // hcaptcha, turnstile or pow, leave blank to disable
+	SiteKey       string `yaml:"site_key" env:"WAKAPI_CAPTCHA_SITE_KEY"`
+	SecretKey     string `yaml:"secret_key" env:"WAKAPI_CAPTCHA_SECRET_KEY"`
+	PowDifficulty int    `yaml:"pow_difficulty" default:"18" env:"WAKAPI_CAPTCHA_POW_DIFFICULTY"` // number of leading zero bits required for a proof-of-work solution
+}
+
+type loginThrottlingConfig struct {
+	Enabled        bool `yaml:"enabled" default:"true" env:"WAKAPI_LOGIN_THROTTLING_ENABLED"`
+	MaxAttempts    int  `yaml:"max_attempts" default:"5" env:"WAKAPI_LOGIN_THROTTLING_MAX_ATTEMPTS"`        // failed logins per account, before it is locked temporarily
+	MaxAttemptsIp  int  `yaml:"max_attempts_ip" default:"20" env:"WAKAPI_LOGIN_THROTTLING_MAX_ATTEMPTS_IP"` // failed logins per ip address, before it is locked temporarily
+	LockoutMinutes int  `yaml:"lockout_minutes" default:"15" env:"WAKAPI_LOGIN_THROTTLING_LOCKOUT_MINUTES"`
 }
 
 type dbConfig struct {
@@ -117,32 +312,96 @@ type dbConfig struct {
 	Charset                 string `default:"utf8mb4" env:"WAKAPI_DB_CHARSET"`
 	Type                    string `yaml:"dialect" default:"sqlite3" env:"WAKAPI_DB_TYPE"`
 	DSN                     string `yaml:"DSN" default:"" env:"WAKAPI_DB_DSN"`
+	Replicas                string `yaml:"replicas" default:"" env:"WAKAPI_DB_REPLICAS"` // comma-separated list of read replica dsns
 	MaxConn                 uint   `yaml:"max_conn" default:"2" env:"WAKAPI_DB_MAX_CONNECTIONS"`
+	ConnectRetries          int    `yaml:"connect_retries" default:"5" env:"WAKAPI_DB_CONNECT_RETRIES"`
+	ConnectBackoff          string `yaml:"connect_backoff" default:"2s" env:"WAKAPI_DB_CONNECT_BACKOFF"` // initial delay between connection attempts at startup, doubled after every attempt
 	Ssl                     bool   `default:"false" env:"WAKAPI_DB_SSL"`
+	Timescale               bool   `yaml:"timescale" default:"false" env:"WAKAPI_DB_TIMESCALE"`       // whether to manage heartbeats as timescale hypertable (postgres only), enabled automatically if the extension is found
+	Partitioning            bool   `yaml:"partitioning" default:"false" env:"WAKAPI_DB_PARTITIONING"` // whether to partition heartbeats by month (postgres only, ignored with timescale)
 	AutoMigrateFailSilently bool   `yaml:"automigrate_fail_silently" default:"false" env:"WAKAPI_DB_AUTOMIGRATE_FAIL_SILENTLY"`
 }
 
 type serverConfig struct {
-	Port             int    `default:"3000" env:"WAKAPI_PORT"`
-	ListenIpV4       string `yaml:"listen_ipv4" default:"127.0.0.1" env:"WAKAPI_LISTEN_IPV4"`
-	ListenIpV6       string `yaml:"listen_ipv6" default:"::1" env:"WAKAPI_LISTEN_IPV6"`
-	ListenSocket     string `yaml:"listen_socket" default:"" env:"WAKAPI_LISTEN_SOCKET"`
-	ListenSocketMode uint32 `yaml:"listen_socket_mode" default:"0666" env:"WAKAPI_LISTEN_SOCKET_MODE"`
-	TimeoutSec       int    `yaml:"timeout_sec" default:"30" env:"WAKAPI_TIMEOUT_SEC"`
-	BasePath         string `yaml:"base_path" default:"/" env:"WAKAPI_BASE_PATH"`
-	PublicUrl        string `yaml:"public_url" default:"http://localhost:3000" env:"WAKAPI_PUBLIC_URL"`
-	TlsCertPath      string `yaml:"tls_cert_path" default:"" env:"WAKAPI_TLS_CERT_PATH"`
-	TlsKeyPath       string `yaml:"tls_key_path" default:"" env:"WAKAPI_TLS_KEY_PATH"`
+	Port               int               `default:"3000" env:"WAKAPI_PORT"`
+	ListenIpV4         string            `yaml:"listen_ipv4" default:"127.0.0.1" env:"WAKAPI_LISTEN_IPV4"`
+	ListenIpV6         string            `yaml:"listen_ipv6" default:"::1" env:"WAKAPI_LISTEN_IPV6"`
+	ListenSocket       string            `yaml:"listen_socket" default:"" env:"WAKAPI_LISTEN_SOCKET"`
+	ListenSocketMode   uint32            `yaml:"listen_socket_mode" default:"0666" env:"WAKAPI_LISTEN_SOCKET_MODE"`
+	TimeoutSec         int               `yaml:"timeout_sec" default:"30" env:"WAKAPI_TIMEOUT_SEC"`
+	ShutdownTimeoutSec int               `yaml:"shutdown_timeout_sec" default:"25" env:"WAKAPI_SHUTDOWN_TIMEOUT_SEC"`
+	BasePath           string            `yaml:"base_path" default:"/" env:"WAKAPI_BASE_PATH"`
+	PublicUrl          string            `yaml:"public_url" default:"http://localhost:3000" env:"WAKAPI_PUBLIC_URL"`
+	TlsCertPath        string            `yaml:"tls_cert_path" default:"" env:"WAKAPI_TLS_CERT_PATH"`
+	TlsKeyPath         string            `yaml:"tls_key_path" default:"" env:"WAKAPI_TLS_KEY_PATH"`
+	Acme               acmeConfig        `yaml:"acme"`
+	Compression        compressionConfig `yaml:"compression"`
+	Cors               CorsConfig        `yaml:"cors"`
+	AccessLog          accessLogConfig   `yaml:"access_log"`
+	SwaggerUi          bool              `yaml:"swagger_ui" default:"true" env:"WAKAPI_SWAGGER_UI"`
+}
+
+// CorsConfig configures cross-origin access to the api, e.g. for browser-based dashboards hosted elsewhere
+type CorsConfig struct {
+	AllowedOrigins   string `yaml:"allowed_origins" default:"" env:"WAKAPI_CORS_ALLOWED_ORIGINS"` // comma-separated list of origins or *, leave blank to disable cors
+	AllowedMethods   string `yaml:"allowed_methods" default:"GET,POST,PUT,PATCH,DELETE" env:"WAKAPI_CORS_ALLOWED_METHODS"`
+	AllowedHeaders   string `yaml:"allowed_headers" default:"Authorization,Content-Type,Api-Version" env:"WAKAPI_CORS_ALLOWED_HEADERS"`
+	AllowCredentials bool   `yaml:"allow_credentials" default:"false" env:"WAKAPI_CORS_ALLOW_CREDENTIALS"` // whether to let browsers send cookies along, not compatible with origin *
+	MaxAgeSec        int    `yaml:"max_age_sec" default:"600" env:"WAKAPI_CORS_MAX_AGE_SEC"`               // how long browsers may cache preflight responses
+}
+
+// accessLogConfig enables a structured (json lines) access log, in addition to the regular request logging
+type accessLogConfig struct {
+	Enabled             bool    `yaml:"enabled" default:"false" env:"WAKAPI_ACCESS_LOG_ENABLED"`
+	Path                string  `yaml:"path" default:"" env:"WAKAPI_ACCESS_LOG_PATH"`                                                                // file to append to, leave blank for stdout
+	ExcludePaths        string  `yaml:"exclude_paths" default:"/assets,/favicon,/api/health,/healthz,/readyz" env:"WAKAPI_ACCESS_LOG_EXCLUDE_PATHS"` // comma-separated list of path prefixes
+	HeartbeatSampleRate float64 `yaml:"heartbeat_sample_rate" default:"0.01" env:"WAKAPI_ACCESS_LOG_HEARTBEAT_SAMPLE_RATE"`                          // fraction of heartbeat requests to log, from 0 to 1
+}
+
+type compressionConfig struct {
+	Enabled bool `yaml:"enabled" default:"true" env:"WAKAPI_COMPRESSION_ENABLED"`
+	Level   int  `yaml:"level" default:"5" env:"WAKAPI_COMPRESSION_LEVEL"` // 1 (fastest) to 9 (smallest)
+}
+
+// acmeConfig enables obtaining and renewing tls certificates from let's encrypt (or another acme ca) automatically, when wakapi is exposed to the internet without a reverse proxy
+type acmeConfig struct {
+	Enabled      bool   `yaml:"enabled" default:"false" env:"WAKAPI_ACME_ENABLED"`
+	Domains      string `yaml:"domains" env:"WAKAPI_ACME_DOMAINS"` // comma-separated list of domains to obtain certificates for
+	Email        string `yaml:"email" env:"WAKAPI_ACME_EMAIL"`
+	Challenge    string `yaml:"challenge" default:"tls-alpn-01" env:"WAKAPI_ACME_CHALLENGE"` // tls-alpn-01 (requires port 443) or http-01 (requires port 80)
+	HttpPort     int    `yaml:"http_port" default:"80" env:"WAKAPI_ACME_HTTP_PORT"`          // only used for http-01 challenges
+	CacheDir     string `yaml:"cache_dir" default:"data/certs" env:"WAKAPI_ACME_CACHE_DIR"`
+	DirectoryUrl string `yaml:"directory_url" env:"WAKAPI_ACME_DIRECTORY_URL"` // leave blank for let's encrypt production
 }
 
 type subscriptionsConfig struct {
-	Enabled              bool   `yaml:"enabled" default:"false" env:"WAKAPI_SUBSCRIPTIONS_ENABLED"`
-	ExpiryNotifications  bool   `yaml:"expiry_notifications" default:"true" env:"WAKAPI_SUBSCRIPTIONS_EXPIRY_NOTIFICATIONS"`
-	StripeApiKey         string `yaml:"stripe_api_key" env:"WAKAPI_SUBSCRIPTIONS_STRIPE_API_KEY"`
-	StripeSecretKey      string `yaml:"stripe_secret_key" env:"WAKAPI_SUBSCRIPTIONS_STRIPE_SECRET_KEY"`
-	StripeEndpointSecret string `yaml:"stripe_endpoint_secret" env:"WAKAPI_SUBSCRIPTIONS_STRIPE_ENDPOINT_SECRET"`
-	StandardPriceId      string `yaml:"standard_price_id" env:"WAKAPI_SUBSCRIPTIONS_STANDARD_PRICE_ID"`
-	StandardPrice        string `yaml:"-"`
+	Enabled              bool                `yaml:"enabled" default:"false" env:"WAKAPI_SUBSCRIPTIONS_ENABLED"`
+	Provider             string              `yaml:"provider" default:"stripe" env:"WAKAPI_SUBSCRIPTIONS_PROVIDER"` // one of ['stripe', 'paypal']
+	ExpiryNotifications  bool                `yaml:"expiry_notifications" default:"true" env:"WAKAPI_SUBSCRIPTIONS_EXPIRY_NOTIFICATIONS"`
+	StripeApiKey         string              `yaml:"stripe_api_key" env:"WAKAPI_SUBSCRIPTIONS_STRIPE_API_KEY"`
+	StripeSecretKey      string              `yaml:"stripe_secret_key" env:"WAKAPI_SUBSCRIPTIONS_STRIPE_SECRET_KEY"`
+	StripeEndpointSecret string              `yaml:"stripe_endpoint_secret" env:"WAKAPI_SUBSCRIPTIONS_STRIPE_ENDPOINT_SECRET"`
+	StandardPriceId      string              `yaml:"standard_price_id" env:"WAKAPI_SUBSCRIPTIONS_STANDARD_PRICE_ID"` // price of the single standard plan, only used if no plans are configured
+	TrialDays            int                 `yaml:"trial_days" default:"0" env:"WAKAPI_SUBSCRIPTIONS_TRIAL_DAYS"`   // free trial period for users who never had a subscription before, 0 to disable
+	Plans                []*SubscriptionPlan `yaml:"plans"`
+	PayPal               PayPalConfig        `yaml:"paypal"`
+}
+
+type PayPalConfig struct {
+	ClientId     string `yaml:"client_id" env:"WAKAPI_SUBSCRIPTIONS_PAYPAL_CLIENT_ID"`
+	ClientSecret string `yaml:"client_secret" env:"WAKAPI_SUBSCRIPTIONS_PAYPAL_CLIENT_SECRET"`
+	WebhookId    string `yaml:"webhook_id" env:"WAKAPI_SUBSCRIPTIONS_PAYPAL_WEBHOOK_ID"`
+	Sandbox      bool   `yaml:"sandbox" default:"false" env:"WAKAPI_SUBSCRIPTIONS_PAYPAL_SANDBOX"`
+}
+
+// SubscriptionPlan is a tier of paid subscriptions, which corresponds to a recurring stripe price or paypal billing plan and comes with its own feature limits
+type SubscriptionPlan struct {
+	Id              string `yaml:"id"`
+	Name            string `yaml:"name"`
+	PriceId         string `yaml:"price_id"`         // stripe price id or paypal billing plan id, depending on the provider
+	RetentionMonths int    `yaml:"retention_months"` // history kept for subscribers (never less than the free one), 0 for unlimited
+	TeamSize        int    `yaml:"team_size"`        // max. number of members in teams owned by a subscriber, 0 for unlimited
+	Price           string `yaml:"-"`                // formatted monthly price, as fetched from the payment provider on startup
 }
 
 type sentryConfig struct {
@@ -157,6 +416,9 @@ type mailConfig struct {
 	Provider  string              `env:"WAKAPI_MAIL_PROVIDER" default:"smtp"`
 	MailWhale MailwhaleMailConfig `yaml:"mailwhale"`
 	Smtp      SMTPMailConfig      `yaml:"smtp"`
+	Ses       SESMailConfig       `yaml:"ses"`
+	Sendgrid  SendgridMailConfig  `yaml:"sendgrid"`
+	Mailgun   MailgunMailConfig   `yaml:"mailgun"`
 	Sender    string              `env:"WAKAPI_MAIL_SENDER" yaml:"sender"`
 }
 
@@ -167,17 +429,60 @@ type MailwhaleMailConfig struct {
 }
 
 type SMTPMailConfig struct {
-	Host     string `env:"WAKAPI_MAIL_SMTP_HOST"`
-	Port     uint   `env:"WAKAPI_MAIL_SMTP_PORT"`
-	Username string `env:"WAKAPI_MAIL_SMTP_USER"`
-	Password string `env:"WAKAPI_MAIL_SMTP_PASS"`
-	TLS      bool   `env:"WAKAPI_MAIL_SMTP_TLS"`
+	Host          string         `env:"WAKAPI_MAIL_SMTP_HOST"`
+	Port          uint           `env:"WAKAPI_MAIL_SMTP_PORT"`
+	Username      string         `env:"WAKAPI_MAIL_SMTP_USER"`
+	Password      string         `env:"WAKAPI_MAIL_SMTP_PASS"`
+	TLS           bool           `env:"WAKAPI_MAIL_SMTP_TLS"`                                                  // deprecated, use encryption instead
+	Encryption    string         `yaml:"encryption" env:"WAKAPI_MAIL_SMTP_ENCRYPTION"`                         // none, starttls or tls, if blank: tls if TLS is set, otherwise starttls if offered by the server
+	AuthMechanism string         `yaml:"auth_mechanism" default:"PLAIN" env:"WAKAPI_MAIL_SMTP_AUTH_MECHANISM"` // PLAIN, LOGIN or CRAM-MD5
+	Dkim          SMTPDkimConfig `yaml:"dkim"`
+}
+
+// SMTPDkimConfig enables signing mails sent via smtp with dkim, for servers that don't sign outgoing mails themselves
+type SMTPDkimConfig struct {
+	Domain         string `yaml:"domain" env:"WAKAPI_MAIL_SMTP_DKIM_DOMAIN"`
+	Selector       string `yaml:"selector" env:"WAKAPI_MAIL_SMTP_DKIM_SELECTOR"`
+	PrivateKeyPath string `yaml:"private_key_path" env:"WAKAPI_MAIL_SMTP_DKIM_PRIVATE_KEY_PATH"` // pem-encoded rsa or ed25519 key, leave blank to disable signing
+}
+
+// SESMailConfig configures sending mails through the amazon ses http api, for instances that can't connect to smtp servers
+type SESMailConfig struct {
+	Region               string `env:"WAKAPI_MAIL_SES_REGION"`
+	AccessKey            string `yaml:"access_key" env:"WAKAPI_MAIL_SES_ACCESS_KEY"`
+	SecretKey            string `yaml:"secret_key" env:"WAKAPI_MAIL_SES_SECRET_KEY"`
+	Endpoint             string `env:"WAKAPI_MAIL_SES_ENDPOINT"`                                             // optional, defaults to https://email.<region>.amazonaws.com
+	ConfigurationSet     string `yaml:"configuration_set" env:"WAKAPI_MAIL_SES_CONFIGURATION_SET"`           // optional, e.g. to publish bounce events
+	NotificationTopicArn string `yaml:"notification_topic_arn" env:"WAKAPI_MAIL_SES_NOTIFICATION_TOPIC_ARN"` // sns topic to accept bounce and complaint notifications from, leave blank to disable
+}
+
+type SendgridMailConfig struct {
+	ApiKey  string `yaml:"api_key" env:"WAKAPI_MAIL_SENDGRID_API_KEY"`
+	Sandbox bool   `yaml:"sandbox" default:"false" env:"WAKAPI_MAIL_SENDGRID_SANDBOX"` // validate mails without actually delivering them
+}
+
+type MailgunMailConfig struct {
+	ApiKey   string `yaml:"api_key" env:"WAKAPI_MAIL_MAILGUN_API_KEY"`
+	Domain   string `yaml:"domain" env:"WAKAPI_MAIL_MAILGUN_DOMAIN"`
+	Region   string `yaml:"region" default:"us" env:"WAKAPI_MAIL_MAILGUN_REGION"`          // us or eu
+	TestMode bool   `yaml:"test_mode" default:"false" env:"WAKAPI_MAIL_MAILGUN_TEST_MODE"` // accept mails without actually delivering them
+}
+
+type outboundConfig struct {
+	MaxConcurrent        int                `yaml:"max_concurrent" default:"64" env:"WAKAPI_OUTBOUND_MAX_CONCURRENT"`
+	MaxConcurrentPerHost int                `yaml:"max_concurrent_per_host" default:"16" env:"WAKAPI_OUTBOUND_MAX_CONCURRENT_PER_HOST"`
+	RateLimit            float64            `yaml:"rate_limit" default:"0" env:"WAKAPI_OUTBOUND_RATE_LIMIT"`
+	RateBurst            int                `yaml:"rate_burst" default:"10" env:"WAKAPI_OUTBOUND_RATE_BURST"`
+	HostRateLimits       map[string]float64 `yaml:"host_rate_limits"`
+	BreakerThreshold     int                `yaml:"breaker_threshold" default:"10" env:"WAKAPI_OUTBOUND_BREAKER_THRESHOLD"`
+	BreakerCooldownSec   int                `yaml:"breaker_cooldown_sec" default:"60" env:"WAKAPI_OUTBOUND_BREAKER_COOLDOWN_SEC"`
 }
 
 type Config struct {
 	Env            string `default:"dev" env:"ENVIRONMENT"`
 	Version        string `yaml:"-"`
 	QuickStart     bool   `yaml:"quick_start" env:"WAKAPI_QUICK_START"`
+	DemoMode       bool   `yaml:"demo_mode" env:"WAKAPI_DEMO_MODE"` // seed a demo user with generated data, e.g. for evaluation or a public demo instance
 	SkipMigrations bool   `yaml:"skip_migrations" env:"WAKAPI_SKIP_MIGRATIONS"`
 	InstanceId     string `yaml:"-"` // only temporary, changes between runs
 	EnablePprof    bool   `yaml:"enable_pprof" env:"WAKAPI_ENABLE_PPROF"`
@@ -188,6 +493,10 @@ type Config struct {
 	Subscriptions  subscriptionsConfig
 	Sentry         sentryConfig
 	Mail           mailConfig
+	Outbound       outboundConfig
+	Vault          vaultConfig
+	Cache          cacheConfig
+	Integrations   integrationsConfig
 }
 
 func (c *Config) CreateCookie(name, value string) *http.Cookie {
@@ -218,6 +527,81 @@ func (c *Config) UseTLS() bool {
 	return c.Server.TlsCertPath != "" && c.Server.TlsKeyPath != ""
 }
 
+func (c *Config) UseAcme() bool {
+	return c.Server.Acme.Enabled
+}
+
+func (c *quotaConfig) Enabled() bool {
+	return c.HeartbeatsPerDay > 0 || c.Machines > 0 || c.Projects > 0
+}
+
+// GetPlan returns the subscription plan with the given id or nil
+func (c *subscriptionsConfig) GetPlan(id string) *SubscriptionPlan {
+	for _, p := range c.Plans {
+		if p.Id == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// GetPlanByPriceId returns the subscription plan, which is billed with the given stripe price or paypal plan, or nil
+func (c *subscriptionsConfig) GetPlanByPriceId(priceId string) *SubscriptionPlan {
+	for _, p := range c.Plans {
+		if p.PriceId == priceId {
+			return p
+		}
+	}
+	return nil
+}
+
+// DefaultPlan returns the first configured plan, which is assumed for subscriptions not (yet) associated with any plan
+func (c *subscriptionsConfig) DefaultPlan() *SubscriptionPlan {
+	if len(c.Plans) == 0 {
+		return nil
+	}
+	return c.Plans[0]
+}
+
+// normalizePlans falls back to a single standard plan with unlimited history, if only the legacy standard_price_id is configured
+func (c *subscriptionsConfig) normalizePlans() {
+	if len(c.Plans) == 0 && c.StandardPriceId != "" {
+		c.Plans = []*SubscriptionPlan{{Id: DefaultSubscriptionPlan, Name: "Standard", PriceId: c.StandardPriceId}}
+	}
+}
+
+func (c *subscriptionsConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Provider != PaymentProviderStripe && c.Provider != PaymentProviderPayPal {
+		return fmt.Errorf("unsupported payment provider '%s'", c.Provider)
+	}
+	if c.Provider == PaymentProviderPayPal && (c.PayPal.ClientId == "" || c.PayPal.ClientSecret == "" || c.PayPal.WebhookId == "") {
+		return errors.New("paypal requires client_id, client_secret and webhook_id")
+	}
+	if len(c.Plans) == 0 {
+		return errors.New("at least one subscription plan (or standard_price_id) must be configured")
+	}
+	if c.TrialDays < 0 {
+		return errors.New("subscriptions.trial_days must not be negative")
+	}
+	ids, priceIds := make(map[string]bool), make(map[string]bool)
+	for _, p := range c.Plans {
+		if p.Id == "" || p.PriceId == "" {
+			return errors.New("subscription plans require an id and a price_id")
+		}
+		if ids[p.Id] || priceIds[p.PriceId] {
+			return fmt.Errorf("duplicate subscription plan '%s'", p.Id)
+		}
+		if p.RetentionMonths < 0 || p.TeamSize < 0 {
+			return fmt.Errorf("limits of subscription plan '%s' must not be negative", p.Id)
+		}
+		ids[p.Id], priceIds[p.PriceId] = true, true
+	}
+	return nil
+}
+
 func (c *appConfig) GetCustomLanguages() map[string]string {
 	return utils.CloneStringMap(c.CustomLanguages, false)
 }
@@ -234,6 +618,10 @@ func (c *appConfig) GetOSColors() map[string]string {
 	return utils.CloneStringMap(c.Colors["operating_systems"], true)
 }
 
+func (c *appConfig) IncrementalAggregationInterval() time.Duration {
+	return time.Duration(c.IncrementalAggregationMin) * time.Minute
+}
+
 func (c *appConfig) GetAggregationTimeCron() string {
 	if strings.Contains(c.AggregationTime, ":") {
 		// old gocron format, e.g. "15:04"
@@ -311,11 +699,130 @@ func (c *appConfig) GetLeaderboardGenerationTimeCron() []string {
 	return crons
 }
 
+func (c *appConfig) normalizeCustomLanguages() {
+	for k, v := range c.CustomLanguages {
+		if v == "" {
+			c.CustomLanguages[k] = "unknown"
+		}
+	}
+}
+
+func (c *appConfig) validateSchedules() error {
+	cronParser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+	if _, err := cronParser.Parse(c.GetWeeklyReportCron()); err != nil {
+		return errors.New("invalid cron expression for report_time_weekly")
+	}
+	if _, err := cronParser.Parse(c.GetAggregationTimeCron()); err != nil {
+		return errors.New("invalid cron expression for aggregation_time")
+	}
+	for _, exp := range c.GetLeaderboardGenerationTimeCron() {
+		if _, err := cronParser.Parse(exp); err != nil {
+			return errors.New("invalid cron expression for leaderboard_generation_time")
+		}
+	}
+	if _, err := cronParser.Parse(c.DataCleanupTime); err != nil {
+		return errors.New("invalid cron expression for data_cleanup_time")
+	}
+	if _, err := cronParser.Parse(c.ExportTargetsTime); c.ExportTargetsEnabled && err != nil {
+		return errors.New("invalid cron expression for export_targets_time")
+	}
+	if _, err := cronParser.Parse(c.SandboxPurgeTime); c.SandboxEnabled && err != nil {
+		return errors.New("invalid cron expression for sandbox_purge_time")
+	}
+	if _, err := cronParser.Parse(c.Archive.Time); c.Archive.IsEnabled() && err != nil {
+		return errors.New("invalid cron expression for archive.time")
+	}
+	return nil
+}
+
+// AnonymizeExpiredData tells whether data older than the retention period is to be anonymized instead of deleted
+func (c *appConfig) AnonymizeExpiredData() bool {
+	return c.DataRetentionMode == RetentionModeAnonymize
+}
+
+// NextDataCleanup returns the time of the next scheduled data cleanup run after the given time
+func (c *appConfig) NextDataCleanup(after time.Time) (time.Time, error) {
+	schedule, err := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor).Parse(c.DataCleanupTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return schedule.Next(after), nil
+}
+
 func (c *appConfig) HeartbeatsMaxAge() time.Duration {
 	d, _ := time.ParseDuration(c.HeartbeatMaxAge)
 	return d
 }
 
+func (c *influxConfig) IsEnabled() bool {
+	return c.Url != ""
+}
+
+// WriteUrl returns the endpoint of influxdb's v2 write api, which victoriametrics supports as well
+func (c *influxConfig) WriteUrl() string {
+	query := url.Values{}
+	query.Set("bucket", c.Bucket)
+	query.Set("precision", "s")
+	if c.Org != "" {
+		query.Set("org", c.Org)
+	}
+	return strings.TrimSuffix(c.Url, "/") + "/api/v2/write?" + query.Encode()
+}
+
+func (c *influxConfig) GetUsers() []string {
+	users := make([]string, 0)
+	for _, u := range strings.Split(c.Users, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			users = append(users, u)
+		}
+	}
+	return users
+}
+
+func (c *influxConfig) validate() error {
+	if !c.IsEnabled() {
+		return nil
+	}
+	if u, err := url.Parse(c.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("invalid influx url")
+	}
+	if c.Bucket == "" || c.Measurement == "" {
+		return errors.New("influx requires bucket and measurement")
+	}
+	if c.Days < 1 {
+		return errors.New("influx days must be positive")
+	}
+	if _, err := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor).Parse(c.Time); err != nil {
+		return errors.New("invalid cron expression for influx.time")
+	}
+	return nil
+}
+
+func (c *archiveConfig) IsEnabled() bool {
+	return c.AfterMonths > 0
+}
+
+func (c *archiveConfig) UseS3() bool {
+	return c.S3.Bucket != ""
+}
+
+func (c *s3Config) Options() utils.S3Options {
+	return utils.S3Options{
+		Endpoint:  c.Endpoint,
+		Region:    c.Region,
+		Bucket:    c.Bucket,
+		AccessKey: c.AccessKey,
+		SecretKey: c.SecretKey,
+		PathStyle: c.PathStyle,
+	}
+}
+
+func (c *appConfig) ExportsMaxAge() time.Duration {
+	d, _ := time.ParseDuration(c.ExportMaxAge)
+	return d
+}
+
 func (c *securityConfig) ParseTrustReverseProxyIPs() {
 	c.trustReverseProxyIpParsed = make([]net.IP, 0)
 	for _, ip := range strings.Split(c.TrustReverseProxyIps, ",") {
@@ -331,6 +838,112 @@ func (c *securityConfig) TrustReverseProxyIPs() []net.IP {
 	return c.trustReverseProxyIpParsed
 }
 
+func (c *metricsPushConfig) Enabled() bool {
+	return c.Mode != ""
+}
+
+func (c *metricsPushConfig) Interval() time.Duration {
+	return time.Duration(c.IntervalSec) * time.Second
+}
+
+func (c *metricsPushConfig) validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if c.Mode != MetricsPushModePushgateway && c.Mode != MetricsPushModeRemoteWrite {
+		return fmt.Errorf("invalid metrics_push mode '%s', must be one of %s, %s", c.Mode, MetricsPushModePushgateway, MetricsPushModeRemoteWrite)
+	}
+	if u, err := url.Parse(c.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("metrics_push requires a valid http(s) url")
+	}
+	if c.IntervalSec < 10 {
+		return errors.New("metrics_push interval_sec must be at least 10")
+	}
+	if c.Job == "" {
+		return errors.New("metrics_push requires a job name")
+	}
+	return nil
+}
+
+// HstsHeader returns the value for the strict-transport-security header or an empty string, if disabled
+func (c *securityHeadersConfig) HstsHeader() string {
+	if c.HstsMaxAgeSec <= 0 {
+		return ""
+	}
+	if c.HstsIncludeSubdomains {
+		return fmt.Sprintf("max-age=%d; includeSubDomains", c.HstsMaxAgeSec)
+	}
+	return fmt.Sprintf("max-age=%d", c.HstsMaxAgeSec)
+}
+
+// ParseAdminIpAllowlist parses the comma-separated list of single ips (v4 or v6) and cidr ranges
+func (c *securityConfig) ParseAdminIpAllowlist() error {
+	c.adminIpAllowlistParsed = make([]*net.IPNet, 0)
+	for _, entry := range strings.Split(c.AdminIpAllowlist, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return fmt.Errorf("invalid admin_ip_allowlist entry '%s'", entry)
+			}
+			if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return fmt.Errorf("invalid admin_ip_allowlist entry '%s'", entry)
+		}
+		c.adminIpAllowlistParsed = append(c.adminIpAllowlistParsed, ipNet)
+	}
+	return nil
+}
+
+// IsAdminIpAllowed tells whether the given ip may access the management surface, which is always the case if no allowlist is configured
+func (c *securityConfig) IsAdminIpAllowed(ip string) bool {
+	if len(c.adminIpAllowlistParsed) == 0 {
+		return true
+	}
+	parsedIp := net.ParseIP(ip)
+	if parsedIp == nil {
+		return false
+	}
+	for _, ipNet := range c.adminIpAllowlistParsed {
+		if ipNet.Contains(parsedIp) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *accessLogConfig) GetExcludePaths() []string {
+	paths := make([]string, 0)
+	for _, p := range strings.Split(c.ExcludePaths, ",") {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+func (c *serverConfig) ShutdownTimeout() time.Duration {
+	return time.Duration(c.ShutdownTimeoutSec) * time.Second
+}
+
+func (c *dbConfig) ConnectBackoffDuration() time.Duration {
+	d, _ := time.ParseDuration(c.ConnectBackoff)
+	return d
+}
+
+// IsPartitioned returns whether heartbeats are stored in natively partitioned table, which is not the case when timescale manages them instead
+func (c *dbConfig) IsPartitioned() bool {
+	return c.Partitioning && c.IsPostgres() && !c.Timescale
+}
+
 func (c *dbConfig) IsSQLite() bool {
 	return c.Dialect == "sqlite3"
 }
@@ -347,10 +960,238 @@ func (c *serverConfig) GetPublicUrl() string {
 	return strings.TrimSuffix(c.PublicUrl, "/")
 }
 
+// validate performs some sanity checks on a freshly loaded config
+func (c *Config) validate() error {
+	if c.Server.ListenIpV4 == "-" && c.Server.ListenIpV6 == "-" && c.Server.ListenSocket == "" {
+		return errors.New("either of listen_ipv4 or listen_ipv6 or listen_socket must be set")
+	}
+	if c.Db.MaxConn <= 0 {
+		return errors.New("you must allow at least one database connection")
+	}
+	if err := c.Server.Acme.validate(c.UseTLS()); err != nil {
+		return err
+	}
+	if c.Server.AccessLog.HeartbeatSampleRate < 0 || c.Server.AccessLog.HeartbeatSampleRate > 1 {
+		return errors.New("access_log heartbeat_sample_rate must be between 0 and 1")
+	}
+	if err := c.Server.Cors.validate(); err != nil {
+		return err
+	}
+	if c.Server.Compression.Enabled && (c.Server.Compression.Level < 1 || c.Server.Compression.Level > 9) {
+		return errors.New("compression level must be between 1 and 9")
+	}
+	if c.Server.ShutdownTimeoutSec < 0 {
+		return errors.New("shutdown_timeout_sec must not be negative")
+	}
+	if c.Db.ConnectRetries < 0 {
+		return errors.New("connect_retries must not be negative")
+	}
+	if _, err := time.ParseDuration(c.Db.ConnectBackoff); err != nil {
+		return errors.New("invalid duration set for connect_backoff")
+	}
+	if err := c.Mail.validate(); err != nil {
+		return err
+	}
+	if err := c.Subscriptions.validate(); err != nil {
+		return err
+	}
+	if err := c.Integrations.Influx.validate(); err != nil {
+		return err
+	}
+	if err := c.Security.MetricsPush.validate(); err != nil {
+		return err
+	}
+	if c.Security.Headers.HstsMaxAgeSec < 0 {
+		return errors.New("hsts_max_age_sec must not be negative")
+	}
+	if err := c.Security.Captcha.validate(); err != nil {
+		return err
+	}
+	if c.Security.LoginThrottling.Enabled && (c.Security.LoginThrottling.MaxAttempts < 1 || c.Security.LoginThrottling.MaxAttemptsIp < 1 || c.Security.LoginThrottling.LockoutMinutes < 1) {
+		return errors.New("login_throttling max_attempts, max_attempts_ip and lockout_minutes must be positive")
+	}
+	if _, err := time.ParseDuration(c.App.HeartbeatMaxAge); err != nil {
+		return errors.New("invalid duration set for heartbeat_max_age")
+	}
+	if _, err := time.ParseDuration(c.App.ExportMaxAge); err != nil {
+		return errors.New("invalid duration set for export_max_age")
+	}
+	if c.App.DataRetentionMode != RetentionModeDelete && c.App.DataRetentionMode != RetentionModeAnonymize {
+		return fmt.Errorf("invalid data_retention_mode '%s'", c.App.DataRetentionMode)
+	}
+	if c.App.IncrementalAggregationMin < 0 {
+		return errors.New("incremental_aggregation_min must not be negative")
+	}
+	if c.App.LeaderboardEligibility.MinAccountAgeDays < 0 || c.App.LeaderboardEligibility.MinActiveDays < 0 {
+		return errors.New("leaderboard eligibility criteria must not be negative")
+	}
+	if c.Cache.RedisUrl != "" {
+		if _, err := redis.ParseURL(c.Cache.RedisUrl); err != nil {
+			return fmt.Errorf("invalid cache.redis_url - %v", err)
+		}
+	}
+	if c.App.ExportTargetsEnabled && c.Security.EncryptionKey == "" {
+		return errors.New("export targets require security.encryption_key to be set")
+	}
+	if c.App.Archive.AfterMonths < 0 {
+		return errors.New("archive.after_months must not be negative")
+	}
+	if c.App.Archive.S3.Bucket != "" {
+		if _, err := utils.NewS3Client(c.App.Archive.S3.Options(), nil); err != nil {
+			return fmt.Errorf("invalid archive.s3 config - %v", err)
+		}
+	}
+	return c.App.validateSchedules()
+}
+
+func (c *mailConfig) validate() error {
+	if c.Provider != "" && utils.FindString(c.Provider, emailProviders, "") == "" {
+		return fmt.Errorf("unknown mail provider '%s'", c.Provider)
+	}
+	if c.Enabled && c.Provider == MailProviderSes && (c.Ses.Region == "" || c.Ses.AccessKey == "" || c.Ses.SecretKey == "") {
+		return errors.New("ses region, access key and secret key must be set")
+	}
+	if c.Enabled && c.Provider == MailProviderSmtp {
+		if err := c.Smtp.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Enabled && c.Provider == MailProviderSendgrid && c.Sendgrid.ApiKey == "" {
+		return errors.New("sendgrid api key must be set")
+	}
+	if c.Enabled && c.Provider == MailProviderMailgun {
+		if c.Mailgun.ApiKey == "" || c.Mailgun.Domain == "" {
+			return errors.New("mailgun api key and domain must be set")
+		}
+		if c.Mailgun.Region != "us" && c.Mailgun.Region != "eu" {
+			return fmt.Errorf("invalid mailgun region '%s'", c.Mailgun.Region)
+		}
+	}
+	return nil
+}
+
+// NewPrivatizer returns a privatizer for public aggregate statistics or nil if differential privacy is disabled
+func (c *privacyConfig) NewPrivatizer() *utils.Privatizer {
+	if !c.Enabled {
+		return nil
+	}
+	return utils.NewPrivatizer(c.Epsilon, c.MinUsers)
+}
+
 func (c *SMTPMailConfig) ConnStr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
 }
 
+func (c *SMTPMailConfig) validate() error {
+	if c.Encryption != "" && c.Encryption != SmtpEncryptionNone && c.Encryption != SmtpEncryptionStartTls && c.Encryption != SmtpEncryptionTls {
+		return fmt.Errorf("invalid smtp encryption '%s'", c.Encryption)
+	}
+	if mechanism := strings.ToUpper(c.AuthMechanism); mechanism != "" && mechanism != SmtpAuthPlain && mechanism != SmtpAuthLogin && mechanism != SmtpAuthCramMd5 {
+		return fmt.Errorf("unsupported smtp auth mechanism '%s'", c.AuthMechanism)
+	}
+	if c.Dkim.Enabled() {
+		if c.Dkim.Domain == "" || c.Dkim.Selector == "" {
+			return errors.New("dkim domain and selector must be set")
+		}
+		if _, err := c.Dkim.Signer(); err != nil {
+			return fmt.Errorf("invalid dkim private key - %v", err)
+		}
+	}
+	return nil
+}
+
+// EffectiveEncryption resolves the configured encryption, falling back to the legacy tls flag, in which case an empty string means to use starttls opportunistically
+func (c *SMTPMailConfig) EffectiveEncryption() string {
+	if c.Encryption != "" {
+		return c.Encryption
+	}
+	if c.TLS {
+		return SmtpEncryptionTls
+	}
+	return ""
+}
+
+func (c *SMTPDkimConfig) Enabled() bool {
+	return c.PrivateKeyPath != ""
+}
+
+func (c *SMTPDkimConfig) Signer() (*utils.DkimSigner, error) {
+	key, err := os.ReadFile(c.PrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	return utils.NewDkimSigner(c.Domain, c.Selector, key)
+}
+
+func (c *CaptchaConfig) Enabled() bool {
+	return c.Provider != ""
+}
+
+func (c *CaptchaConfig) validate() error {
+	switch c.Provider {
+	case "":
+		return nil
+	case CaptchaProviderHCaptcha, CaptchaProviderTurnstile:
+		if c.SiteKey == "" || c.SecretKey == "" {
+			return errors.New("captcha site key and secret key must be set")
+		}
+	case CaptchaProviderPow:
+		if c.PowDifficulty < 1 || c.PowDifficulty > 32 {
+			return errors.New("captcha pow_difficulty must be between 1 and 32")
+		}
+	default:
+		return fmt.Errorf("unknown captcha provider '%s'", c.Provider)
+	}
+	return nil
+}
+
+// VerifyUrl returns the third-party provider's endpoint to verify a solved captcha's response token with
+func (c *CaptchaConfig) VerifyUrl() string {
+	switch c.Provider {
+	case CaptchaProviderHCaptcha:
+		return "https://api.hcaptcha.com/siteverify"
+	case CaptchaProviderTurnstile:
+		return "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+	}
+	return ""
+}
+
+// ScriptUrl returns the third-party provider's widget script to be included in pages showing a captcha
+func (c *CaptchaConfig) ScriptUrl() string {
+	switch c.Provider {
+	case CaptchaProviderHCaptcha:
+		return "https://js.hcaptcha.com/1/api.js"
+	case CaptchaProviderTurnstile:
+		return "https://challenges.cloudflare.com/turnstile/v0/api.js"
+	}
+	return ""
+}
+
+// Origins returns the third-party provider's origins, which the widget needs to load scripts, frames and styles from
+func (c *CaptchaConfig) Origins() []string {
+	switch c.Provider {
+	case CaptchaProviderHCaptcha:
+		return []string{"https://hcaptcha.com", "https://*.hcaptcha.com"}
+	case CaptchaProviderTurnstile:
+		return []string{"https://challenges.cloudflare.com"}
+	}
+	return []string{}
+}
+
+func (c *MailgunMailConfig) ApiUrl() string {
+	if c.Region == "eu" {
+		return "https://api.eu.mailgun.net"
+	}
+	return "https://api.mailgun.net"
+}
+
+func (c *SESMailConfig) ApiUrl() string {
+	if c.Endpoint != "" {
+		return strings.TrimSuffix(c.Endpoint, "/")
+	}
+	return fmt.Sprintf("https://email.%s.amazonaws.com", c.Region)
+}
+
 func IsDev(env string) bool {
 	return env == "dev" || env == "development"
 }
@@ -392,19 +1233,25 @@ func resolveDbDialect(dbType string) string {
 }
 
 func Set(config *Config) {
-	cfg = config
+	cfg.Store(config)
 }
 
 func Get() *Config {
-	return cfg
+	return cfg.Load()
 }
 
 func Load(configFlag string, version string) *Config {
 	config := &Config{}
 
+	if err := resolveSecretFiles(); err != nil {
+		logbuch.Fatal(err.Error())
+	}
 	if err := configor.New(&configor.Config{}).Load(config, configFlag); err != nil {
 		logbuch.Fatal("failed to read config: %v", err)
 	}
+	if err := loadVaultSecrets(config, true); err != nil {
+		logbuch.Fatal(err.Error())
+	}
 
 	env = config.Env
 
@@ -431,14 +1278,14 @@ func Load(configFlag string, version string) *Config {
 	config.Security.SecureCookie = securecookie.New(hashKey, blockKey)
 	config.Security.SessionKey = sessionKey
 	config.Security.ParseTrustReverseProxyIPs()
+	if err := config.Security.ParseAdminIpAllowlist(); err != nil {
+		logbuch.Fatal(err.Error())
+	}
 
 	config.Server.BasePath = strings.TrimSuffix(config.Server.BasePath, "/")
 
-	for k, v := range config.App.CustomLanguages {
-		if v == "" {
-			config.App.CustomLanguages[k] = "unknown"
-		}
-	}
+	config.App.normalizeCustomLanguages()
+	config.Subscriptions.normalizePlans()
 
 	if config.Sentry.Dsn != "" {
 		logbuch.Info("enabling sentry integration")
@@ -449,45 +1296,29 @@ func Load(configFlag string, version string) *Config {
 		logbuch.Info("disabling data retention policy, keeping data forever")
 	} else {
 		dataRetentionWarning := fmt.Sprintf("⚠️ data retention policy will cause user data older than %d months to be deleted", config.App.DataRetentionMonths)
+		if config.App.AnonymizeExpiredData() {
+			dataRetentionWarning = fmt.Sprintf("⚠️ data retention policy will cause user data older than %d months to be anonymized", config.App.DataRetentionMonths)
+		}
 		if config.Subscriptions.Enabled {
-			dataRetentionWarning += " (except for users with active subscriptions)"
+			dataRetentionWarning += " (except for users with active subscriptions, according to their plan)"
 		}
 		logbuch.Warn(dataRetentionWarning)
 	}
 
-	// some validation checks
-	if config.Server.ListenIpV4 == "-" && config.Server.ListenIpV6 == "-" && config.Server.ListenSocket == "" {
-		logbuch.Fatal("either of listen_ipv4 or listen_ipv6 or listen_socket must be set")
-	}
-	if config.Db.MaxConn <= 0 {
-		logbuch.Fatal("you must allow at least one database connection")
-	}
 	if config.Db.MaxConn > 1 && config.Db.IsSQLite() {
 		logbuch.Warn("with sqlite, only a single connection is supported") // otherwise 'PRAGMA foreign_keys=ON' would somehow have to be set for every connection in the pool
 		config.Db.MaxConn = 1
 	}
-	if config.Mail.Provider != "" && utils.FindString(config.Mail.Provider, emailProviders, "") == "" {
-		logbuch.Fatal("unknown mail provider '%s'", config.Mail.Provider)
-	}
-	if _, err := time.ParseDuration(config.App.HeartbeatMaxAge); err != nil {
-		logbuch.Fatal("invalid duration set for heartbeat_max_age")
+	if config.Db.Partitioning && !config.Db.IsPostgres() {
+		logbuch.Warn("partitioning of heartbeats is only supported with postgres") // mysql doesn't support foreign keys on partitioned tables
+		config.Db.Partitioning = false
 	}
 	if config.Security.TrustedHeaderAuth && len(config.Security.trustReverseProxyIpParsed) == 0 {
 		config.Security.TrustedHeaderAuth = false
 	}
 
-	cronParser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
-
-	if _, err := cronParser.Parse(config.App.GetWeeklyReportCron()); err != nil {
-		logbuch.Fatal("invalid cron expression for report_time_weekly")
-	}
-	if _, err := cronParser.Parse(config.App.GetAggregationTimeCron()); err != nil {
-		logbuch.Fatal("invalid cron expression for aggregation_time")
-	}
-	for _, c := range config.App.GetLeaderboardGenerationTimeCron() {
-		if _, err := cronParser.Parse(c); err != nil {
-			logbuch.Fatal("invalid cron expression for leaderboard_generation_time")
-		}
+	if err := config.validate(); err != nil {
+		logbuch.Fatal(err.Error())
 	}
 
 	// deprecation notices
@@ -501,6 +1332,8 @@ func Load(configFlag string, version string) *Config {
 		logbuch.Warn("you're using deprecated syntax for 'leaderboard_generation_time', please change it to a semicolon-separated list if valid cron expressions")
 	}
 
+	configPath = configFlag
+
 	Set(config)
 	return Get()
 }
@@ -514,5 +1347,7 @@ func Empty() *Config {
 		Subscriptions: subscriptionsConfig{},
 		Sentry:        sentryConfig{},
 		Mail:          mailConfig{},
+		Outbound:      outboundConfig{},
+		Vault:         vaultConfig{},
 	}
 }
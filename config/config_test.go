@@ -88,3 +88,173 @@ func Test_sqliteConnectionString(t *testing.T) {
 	}
 	assert.Equal(t, c.Name, sqliteConnectionString(c))
 }
+
+func Test_acmeConfig_validate(t *testing.T) {
+	c := &acmeConfig{Enabled: true, Domains: " wakapi.example.org, ,stats.example.org", Challenge: AcmeChallengeTlsAlpn}
+	assert.Nil(t, c.validate(false))
+	assert.Equal(t, []string{"wakapi.example.org", "stats.example.org"}, c.GetDomains())
+	assert.NotNil(t, c.validate(true))
+
+	c.Challenge = AcmeChallengeHttp
+	assert.NotNil(t, c.validate(false))
+	c.HttpPort = 80
+	assert.Nil(t, c.validate(false))
+
+	c.Challenge = "dns-01"
+	assert.NotNil(t, c.validate(false))
+
+	c.Challenge, c.DirectoryUrl = AcmeChallengeTlsAlpn, "http://acme.example.org/directory"
+	assert.NotNil(t, c.validate(false))
+
+	c.Domains, c.DirectoryUrl = "", ""
+	assert.NotNil(t, c.validate(false))
+	assert.Nil(t, (&acmeConfig{}).validate(true))
+}
+
+func Test_CorsConfig_validate(t *testing.T) {
+	c := &CorsConfig{}
+	assert.Nil(t, c.validate())
+	assert.False(t, c.Enabled())
+
+	c.AllowedOrigins = "https://dashboard.example.org/, http://localhost:8080"
+	assert.Nil(t, c.validate())
+	assert.Equal(t, []string{"https://dashboard.example.org", "http://localhost:8080"}, c.Origins())
+	assert.True(t, c.AllowsOrigin("https://Dashboard.example.org"))
+	assert.False(t, c.AllowsOrigin("https://evil.example.org"))
+
+	c.AllowCredentials = true
+	assert.Nil(t, c.validate())
+	c.AllowedOrigins = "*"
+	assert.NotNil(t, c.validate())
+	assert.True(t, c.AllowsOrigin("https://evil.example.org"))
+
+	c.AllowCredentials, c.AllowedOrigins = false, "dashboard.example.org"
+	assert.NotNil(t, c.validate())
+}
+
+func Test_SMTPMailConfig_validate(t *testing.T) {
+	c := &SMTPMailConfig{AuthMechanism: "login"}
+	assert.Nil(t, c.validate())
+	assert.Equal(t, "", c.EffectiveEncryption())
+
+	c.TLS = true
+	assert.Equal(t, SmtpEncryptionTls, c.EffectiveEncryption())
+	c.Encryption = SmtpEncryptionStartTls
+	assert.Equal(t, SmtpEncryptionStartTls, c.EffectiveEncryption())
+
+	c.Encryption = "ssl"
+	assert.NotNil(t, c.validate())
+	c.Encryption, c.AuthMechanism = SmtpEncryptionNone, "XOAUTH2"
+	assert.NotNil(t, c.validate())
+
+	c.AuthMechanism, c.Dkim = SmtpAuthCramMd5, SMTPDkimConfig{Domain: "wakapi.dev", Selector: "mail", PrivateKeyPath: "does/not/exist.pem"}
+	assert.NotNil(t, c.validate())
+}
+
+func Test_CaptchaConfig_validate(t *testing.T) {
+	c := &CaptchaConfig{}
+	assert.Nil(t, c.validate())
+	assert.False(t, c.Enabled())
+
+	c.Provider = CaptchaProviderTurnstile
+	assert.NotNil(t, c.validate())
+	c.SiteKey, c.SecretKey = "site", "secret"
+	assert.Nil(t, c.validate())
+
+	c.Provider, c.PowDifficulty = CaptchaProviderPow, 0
+	assert.NotNil(t, c.validate())
+	c.PowDifficulty = 18
+	assert.Nil(t, c.validate())
+
+	c.Provider = "recaptcha"
+	assert.NotNil(t, c.validate())
+}
+
+func Test_securityConfig_IsAdminIpAllowed(t *testing.T) {
+	c := &securityConfig{}
+	assert.Nil(t, c.ParseAdminIpAllowlist())
+	assert.True(t, c.IsAdminIpAllowed("203.0.113.7"))
+
+	c.AdminIpAllowlist = "127.0.0.1, ::1,10.0.0.0/8"
+	assert.Nil(t, c.ParseAdminIpAllowlist())
+	assert.True(t, c.IsAdminIpAllowed("127.0.0.1"))
+	assert.True(t, c.IsAdminIpAllowed("::1"))
+	assert.True(t, c.IsAdminIpAllowed("10.13.37.1"))
+	assert.False(t, c.IsAdminIpAllowed("203.0.113.7"))
+	assert.False(t, c.IsAdminIpAllowed("not an ip"))
+
+	c.AdminIpAllowlist = "10.0.0.0/33"
+	assert.NotNil(t, c.ParseAdminIpAllowlist())
+	c.AdminIpAllowlist = "localhost"
+	assert.NotNil(t, c.ParseAdminIpAllowlist())
+}
+
+func Test_subscriptionsConfig_Plans(t *testing.T) {
+	c := &subscriptionsConfig{Enabled: true, Provider: PaymentProviderStripe}
+	assert.NotNil(t, c.validate())
+
+	c.StandardPriceId = "price_standard"
+	c.normalizePlans()
+	assert.Nil(t, c.validate())
+	assert.Len(t, c.Plans, 1)
+	assert.Equal(t, DefaultSubscriptionPlan, c.DefaultPlan().Id)
+	assert.Equal(t, c.Plans[0], c.GetPlanByPriceId("price_standard"))
+
+	c.Plans = []*SubscriptionPlan{
+		{Id: "basic", PriceId: "price_basic", RetentionMonths: 12},
+		{Id: "team", PriceId: "price_team", TeamSize: 10},
+	}
+	c.normalizePlans()
+	assert.Nil(t, c.validate())
+	assert.Len(t, c.Plans, 2)
+	assert.Equal(t, "basic", c.DefaultPlan().Id)
+	assert.Equal(t, 10, c.GetPlan("team").TeamSize)
+	assert.Equal(t, "team", c.GetPlanByPriceId("price_team").Id)
+	assert.Nil(t, c.GetPlan("standard"))
+	assert.Nil(t, c.GetPlanByPriceId("price_standard"))
+
+	c.Plans = append(c.Plans, &SubscriptionPlan{Id: "basic", PriceId: "price_basic_yearly"})
+	assert.NotNil(t, c.validate())
+
+	c.Plans = []*SubscriptionPlan{{Id: "basic"}}
+	assert.NotNil(t, c.validate())
+
+	c.Plans = []*SubscriptionPlan{{Id: "basic", PriceId: "price_basic", RetentionMonths: -1}}
+	assert.NotNil(t, c.validate())
+
+	c.Plans, c.TrialDays = []*SubscriptionPlan{{Id: "basic", PriceId: "price_basic"}}, -1
+	assert.NotNil(t, c.validate())
+
+	c.TrialDays, c.Provider = 0, PaymentProviderPayPal
+	assert.NotNil(t, c.validate())
+	c.PayPal = PayPalConfig{ClientId: "client", ClientSecret: "secret", WebhookId: "webhook"}
+	assert.Nil(t, c.validate())
+
+	c.Provider = "bitcoin"
+	assert.NotNil(t, c.validate())
+
+	assert.Nil(t, (&subscriptionsConfig{}).validate())
+}
+
+func Test_influxConfig(t *testing.T) {
+	c := &influxConfig{Bucket: "wakapi", Measurement: "wakapi", Time: "0 45 2 * * *", Days: 1}
+	assert.False(t, c.IsEnabled())
+	assert.Nil(t, c.validate())
+
+	c.Url = "http://localhost:8086/"
+	assert.True(t, c.IsEnabled())
+	assert.Nil(t, c.validate())
+	assert.Equal(t, "http://localhost:8086/api/v2/write?bucket=wakapi&precision=s", c.WriteUrl())
+
+	c.Org = "my org"
+	assert.Equal(t, "http://localhost:8086/api/v2/write?bucket=wakapi&org=my+org&precision=s", c.WriteUrl())
+
+	c.Users = " alice, ,bob"
+	assert.Equal(t, []string{"alice", "bob"}, c.GetUsers())
+
+	c.Days = 0
+	assert.NotNil(t, c.validate())
+
+	c.Days, c.Url = 1, "localhost:8086"
+	assert.NotNil(t, c.validate())
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const CorsAnyOrigin = "*"
+
+func (c *CorsConfig) Enabled() bool {
+	return len(c.Origins()) > 0
+}
+
+func (c *CorsConfig) Origins() []string {
+	return splitCorsList(c.AllowedOrigins, func(s string) string {
+		return strings.TrimSuffix(s, "/")
+	})
+}
+
+func (c *CorsConfig) Methods() []string {
+	return splitCorsList(c.AllowedMethods, strings.ToUpper)
+}
+
+func (c *CorsConfig) Headers() []string {
+	return splitCorsList(c.AllowedHeaders, func(s string) string { return s })
+}
+
+// AllowsOrigin tells whether cross-origin requests from the given origin (scheme, host and port, as sent by the browser) are permitted
+func (c *CorsConfig) AllowsOrigin(origin string) bool {
+	for _, o := range c.Origins() {
+		if o == CorsAnyOrigin || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CorsConfig) AllowsAnyOrigin() bool {
+	for _, o := range c.Origins() {
+		if o == CorsAnyOrigin {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CorsConfig) validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	for _, o := range c.Origins() {
+		if o == CorsAnyOrigin {
+			continue
+		}
+		if u, err := url.Parse(o); err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			return fmt.Errorf("invalid cors origin '%s', must be of the form scheme://host[:port]", o)
+		}
+	}
+	if c.AllowCredentials && c.AllowsAnyOrigin() {
+		return errors.New("cors allow_credentials can not be used together with origin *")
+	}
+	if c.MaxAgeSec < 0 {
+		return errors.New("cors max_age_sec must not be negative")
+	}
+	return nil
+}
+
+func splitCorsList(list string, normalize func(string) string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, normalize(item))
+		}
+	}
+	return items
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
@@ -54,6 +55,25 @@ func (c *dbConfig) GetDialector() gorm.Dialector {
 	return nil
 }
 
+func (c *dbConfig) GetReplicaDialectors() []gorm.Dialector {
+	dialectors := make([]gorm.Dialector, 0)
+	for _, dsn := range strings.Split(c.Replicas, ",") {
+		dsn = strings.TrimSpace(dsn)
+		if dsn == "" {
+			continue
+		}
+		switch c.Dialect {
+		case SQLDialectMysql:
+			dialectors = append(dialectors, mysql.New(mysql.Config{DriverName: c.Dialect, DSN: dsn}))
+		case SQLDialectPostgres:
+			dialectors = append(dialectors, postgres.New(postgres.Config{DSN: dsn}))
+		case SQLDialectSqlite:
+			dialectors = append(dialectors, sqlite.Open(dsn))
+		}
+	}
+	return dialectors
+}
+
 func mysqlConnectionString(config *dbConfig) string {
 	if len(config.DSN) > 0 {
 		return config.DSN
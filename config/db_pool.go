@@ -0,0 +1,67 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// RotatingConnPool is a gorm connection pool, whose underlying database handle can be replaced at runtime, e.g. to reconnect with rotated credentials
+// queries (and transactions) already started on the previous handle keep running on it
+type RotatingConnPool struct {
+	db   *sql.DB
+	lock sync.RWMutex
+}
+
+// UseRotatingConnPool makes the given gorm db send all queries through a RotatingConnPool
+// must be called before the db is used concurrently
+func UseRotatingConnPool(db *gorm.DB) (*RotatingConnPool, error) {
+	sqlDb, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	pool := &RotatingConnPool{db: sqlDb}
+	db.ConnPool, db.Statement.ConnPool = pool, pool
+	return pool, nil
+}
+
+// Swap replaces the underlying database handle and returns the previous one, which the caller is responsible for closing
+func (p *RotatingConnPool) Swap(db *sql.DB) *sql.DB {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	previous := p.db
+	p.db = db
+	return previous
+}
+
+func (p *RotatingConnPool) GetDBConn() (*sql.DB, error) {
+	return p.get(), nil
+}
+
+func (p *RotatingConnPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.get().PrepareContext(ctx, query)
+}
+
+func (p *RotatingConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.get().ExecContext(ctx, query, args...)
+}
+
+func (p *RotatingConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.get().QueryContext(ctx, query, args...)
+}
+
+func (p *RotatingConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.get().QueryRowContext(ctx, query, args...)
+}
+
+func (p *RotatingConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return p.get().BeginTx(ctx, opts)
+}
+
+func (p *RotatingConnPool) get() *sql.DB {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.db
+}
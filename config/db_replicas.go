@@ -0,0 +1,27 @@
+package config
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// replicas are registered as a named resolver instead of a global one, so that only queries explicitly marked via ReadReplica() are routed to them
+// all other queries, as well as any query within a transaction, keep going to the primary
+const replicaResolverName = "wakapi:read_replica"
+
+// NewReplicaResolver returns a gorm plugin, that routes queries marked via ReadReplica() to one of the configured read replicas (round-robin)
+func NewReplicaResolver(dbConfig *dbConfig) *dbresolver.DBResolver {
+	return dbresolver.
+		Register(dbresolver.Config{
+			Replicas: dbConfig.GetReplicaDialectors(),
+			Policy:   dbresolver.StrictRoundRobinPolicy(),
+		}, replicaResolverName).
+		SetMaxIdleConns(int(dbConfig.MaxConn)).
+		SetMaxOpenConns(int(dbConfig.MaxConn))
+}
+
+// ReadReplica marks the given query to be (preferably) served by a read replica
+// only use for reads that are fine with slightly outdated data, as replicas might lag behind the primary
+func ReadReplica(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Use(replicaResolverName)).Session(&gorm.Session{})
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestReplicaResolver(t *testing.T) {
+	primaryPath, replicaPath := filepath.Join(t.TempDir(), "primary.db"), filepath.Join(t.TempDir(), "replica.db")
+
+	open := func(path string, value string) *gorm.DB {
+		db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		assert.Nil(t, err)
+		assert.Nil(t, db.Exec("CREATE TABLE items (name TEXT)").Error)
+		assert.Nil(t, db.Exec("INSERT INTO items (name) VALUES (?)", value).Error)
+		return db
+	}
+
+	replicaDb := open(replicaPath, "replica")
+	db := open(primaryPath, "primary")
+
+	assert.Nil(t, db.Use(NewReplicaResolver(&dbConfig{Dialect: SQLDialectSqlite, Replicas: " " + replicaPath + ", ", MaxConn: 1})))
+
+	var name string
+	assert.Nil(t, db.Table("items").Select("name").Scan(&name).Error)
+	assert.Equal(t, "primary", name)
+
+	replicated := ReadReplica(db)
+	assert.Nil(t, replicated.Table("items").Select("name").Scan(&name).Error)
+	assert.Equal(t, "replica", name)
+	assert.Nil(t, replicated.Raw("SELECT name FROM items").Scan(&name).Error)
+	assert.Equal(t, "replica", name)
+
+	var names []string
+	assert.Nil(t, replicated.Table("items").Pluck("name", &names).Error)
+	assert.Equal(t, []string{"replica"}, names)
+
+	// transactions always stay on primary
+	assert.Nil(t, db.Transaction(func(tx *gorm.DB) error {
+		return ReadReplica(tx).Table("items").Select("name").Scan(&name).Error
+	}))
+	assert.Equal(t, "primary", name)
+
+	// writes always go to primary
+	assert.Nil(t, replicated.Exec("INSERT INTO items (name) VALUES (?)", "new").Error)
+	var count int64
+	assert.Nil(t, replicaDb.Table("items").Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/configor"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	DoctorStatusOk      = "ok"
+	DoctorStatusWarning = "warning"
+	DoctorStatusError   = "error"
+	DoctorStatusSkipped = "skipped"
+)
+
+const (
+	doctorTimeout         = 5 * time.Second
+	doctorCertExpiryAlert = 14 * 24 * time.Hour
+)
+
+type DoctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// DoctorReport is the outcome of checking a config file and the environment it refers to prior to actually starting the server
+type DoctorReport struct {
+	ConfigFile string         `json:"config_file"`
+	Ok         bool           `json:"ok"`
+	Checks     []*DoctorCheck `json:"checks"`
+}
+
+func (r *DoctorReport) add(name, status, message string, args ...interface{}) {
+	r.Checks = append(r.Checks, &DoctorCheck{Name: name, Status: status, Message: fmt.Sprintf(message, args...)})
+	if status == DoctorStatusError {
+		r.Ok = false
+	}
+}
+
+// Doctor loads the given config file without applying it and checks whether the server could be started with it, i.e. whether all settings are valid, the database and mail server are reachable, tls certificates can be loaded and all directories are writable
+func Doctor(configFlag string) *DoctorReport {
+	return runDoctor(configFlag, false)
+}
+
+func runDoctor(configFlag string, selfTest bool) *DoctorReport {
+	report := &DoctorReport{ConfigFile: configFlag, Ok: true, Checks: make([]*DoctorCheck, 0)}
+
+	if err := resolveSecretFiles(); err != nil {
+		report.add("config", DoctorStatusError, "%v", err)
+		return report
+	}
+	config := &Config{}
+	if err := configor.New(&configor.Config{}).Load(config, configFlag); err != nil {
+		report.add("config", DoctorStatusError, "failed to read config: %v", err)
+		return report
+	}
+	if config.Vault.Enabled {
+		if err := loadVaultSecrets(config, true); err != nil {
+			report.add("vault", DoctorStatusError, "%v", err)
+		} else {
+			report.add("vault", DoctorStatusOk, "loaded secrets from vault")
+		}
+	}
+	config.Db.Dialect = resolveDbDialect(config.Db.Type)
+	config.Security.ParseTrustReverseProxyIPs()
+	if err := config.Security.ParseAdminIpAllowlist(); err != nil {
+		report.add("admin_ip_allowlist", DoctorStatusError, "%v", err)
+	}
+	config.Subscriptions.normalizePlans()
+	if _, err := os.Stat(configFlag); err != nil {
+		report.add("config", DoctorStatusWarning, "config file not found, using defaults and environment variables only")
+	} else {
+		report.add("config", DoctorStatusOk, "")
+	}
+
+	if err := config.validate(); err != nil {
+		report.add("validation", DoctorStatusError, "%v", err)
+	} else {
+		report.add("validation", DoctorStatusOk, "")
+	}
+
+	doctorCheckDatabase(config, report, selfTest)
+	doctorCheckMail(config, report, selfTest)
+	doctorCheckTLS(config, report)
+	doctorCheckDirectories(config, report)
+	if selfTest {
+		selfTestStripe(config, report)
+		selfTestOutbound(config, report)
+	}
+
+	return report
+}
+
+func doctorCheckDatabase(config *Config, report *DoctorReport, selfTest bool) {
+	dialector := config.Db.GetDialector()
+	if dialector == nil {
+		report.add("database", DoctorStatusError, "unsupported database type '%s'", config.Db.Type)
+		return
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}, GetWakapiDBOpts(&config.Db))
+	if err != nil {
+		report.add("database", DoctorStatusError, "failed to open %s database: %v", config.Db.Dialect, err)
+		return
+	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		report.add("database", DoctorStatusError, "failed to connect to %s database: %v", config.Db.Dialect, err)
+		return
+	}
+	defer sqlDb.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	if err := sqlDb.PingContext(ctx); err != nil {
+		report.add("database", DoctorStatusError, "failed to ping %s database: %v", config.Db.Dialect, err)
+		return
+	}
+	report.add("database", DoctorStatusOk, "connected to %s database", config.Db.Dialect)
+
+	if selfTest {
+		selfTestDatabasePermissions(db.WithContext(ctx), report)
+	}
+}
+
+func doctorCheckMail(config *Config, report *DoctorReport, selfTest bool) {
+	if !config.Mail.Enabled {
+		report.add("mail", DoctorStatusSkipped, "mail is disabled")
+		return
+	}
+
+	switch config.Mail.Provider {
+	case MailProviderSmtp:
+		conn, err := net.DialTimeout("tcp", config.Mail.Smtp.ConnStr(), doctorTimeout)
+		if err != nil {
+			report.add("mail", DoctorStatusError, "smtp server at %s is not reachable: %v", config.Mail.Smtp.ConnStr(), err)
+			return
+		}
+		conn.Close()
+		if selfTest {
+			selfTestSmtpLogin(&config.Mail.Smtp, report)
+		}
+	case MailProviderMailWhale:
+		res, err := (&http.Client{Timeout: doctorTimeout}).Get(config.Mail.MailWhale.Url)
+		if err != nil {
+			report.add("mail", DoctorStatusError, "mailwhale at %s is not reachable: %v", config.Mail.MailWhale.Url, err)
+			return
+		}
+		res.Body.Close()
+	default:
+		report.add("mail", DoctorStatusSkipped, "unknown mail provider '%s'", config.Mail.Provider)
+		return
+	}
+
+	if config.Mail.Sender == "" {
+		report.add("mail", DoctorStatusWarning, "%s server is reachable, but no sender address is configured", config.Mail.Provider)
+		return
+	}
+	report.add("mail", DoctorStatusOk, "%s server is reachable", config.Mail.Provider)
+}
+
+func doctorCheckTLS(config *Config, report *DoctorReport) {
+	if config.UseAcme() {
+		report.add("tls", DoctorStatusOk, "certificates for %s are obtained via acme (%s)", strings.Join(config.Server.Acme.GetDomains(), ", "), config.Server.Acme.Challenge)
+		return
+	}
+	if !config.UseTLS() {
+		if config.Server.TlsCertPath != "" || config.Server.TlsKeyPath != "" {
+			report.add("tls", DoctorStatusWarning, "only one of tls_cert_path and tls_key_path is set, tls will be disabled")
+			return
+		}
+		report.add("tls", DoctorStatusSkipped, "tls is disabled")
+		return
+	}
+
+	cert, err := tls.LoadX509KeyPair(config.Server.TlsCertPath, config.Server.TlsKeyPath)
+	if err != nil {
+		report.add("tls", DoctorStatusError, "failed to load certificate: %v", err)
+		return
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		report.add("tls", DoctorStatusError, "failed to parse certificate: %v", err)
+		return
+	}
+	if time.Now().After(leaf.NotAfter) {
+		report.add("tls", DoctorStatusError, "certificate expired at %s", leaf.NotAfter.Format(time.RFC3339))
+		return
+	}
+	if time.Until(leaf.NotAfter) < doctorCertExpiryAlert {
+		report.add("tls", DoctorStatusWarning, "certificate expires soon, at %s", leaf.NotAfter.Format(time.RFC3339))
+		return
+	}
+	report.add("tls", DoctorStatusOk, "certificate valid until %s", leaf.NotAfter.Format(time.RFC3339))
+}
+
+func doctorCheckDirectories(config *Config, report *DoctorReport) {
+	dirs := map[string]string{"export_dir": config.App.ExportDir}
+	if config.App.Archive.IsEnabled() && !config.App.Archive.UseS3() {
+		dirs["archive_dir"] = config.App.Archive.Dir
+	}
+	if config.Db.IsSQLite() {
+		dirs["db"] = filepath.Dir(config.Db.Name)
+	}
+	if config.Server.ListenSocket != "" && config.Server.ListenSocket != "-" {
+		dirs["listen_socket"] = filepath.Dir(config.Server.ListenSocket)
+	}
+	if config.UseAcme() {
+		dirs["acme_cache_dir"] = config.Server.Acme.CacheDir
+	}
+
+	for _, key := range []string{"db", "export_dir", "archive_dir", "listen_socket", "acme_cache_dir"} {
+		dir, ok := dirs[key]
+		if !ok {
+			continue
+		}
+		name := "directory." + key
+		if err := checkWritable(dir); err != nil {
+			report.add(name, DoctorStatusError, "%s is not writable: %v", dir, err)
+			continue
+		}
+		report.add(name, DoctorStatusOk, "%s is writable", dir)
+	}
+}
+
+// checkWritable checks whether files can be created inside the given directory, or inside its closest existing parent directory if it doesn't exist yet
+func checkWritable(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !os.IsNotExist(err) || filepath.Dir(dir) == dir {
+			return err
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	f, err := os.CreateTemp(dir, ".wakapi-doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoctor(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yml")
+	content := "app:\n  export_dir: " + filepath.Join(dir, "exports") + "\ndb:\n  name: " + filepath.Join(dir, "wakapi.db") + "\nmail:\n  enabled: false\n"
+	assert.Nil(t, os.WriteFile(configFile, []byte(content), 0600))
+
+	report := Doctor(configFile)
+	assert.True(t, report.Ok)
+	assert.Equal(t, DoctorStatusOk, findCheck(report, "database").Status)
+	assert.Equal(t, DoctorStatusSkipped, findCheck(report, "mail").Status)
+	assert.Equal(t, DoctorStatusSkipped, findCheck(report, "tls").Status)
+	assert.Equal(t, DoctorStatusOk, findCheck(report, "directory.export_dir").Status)
+
+	assert.Nil(t, os.WriteFile(configFile, []byte("app:\n  aggregation_time: 'not a cron expression'\n"+content[len("app:\n"):]), 0600))
+
+	report = Doctor(configFile)
+	assert.False(t, report.Ok)
+	assert.Equal(t, DoctorStatusError, findCheck(report, "validation").Status)
+
+	assert.Nil(t, os.WriteFile(configFile, []byte("app: ["), 0600))
+
+	report = Doctor(configFile)
+	assert.False(t, report.Ok)
+	assert.Equal(t, DoctorStatusError, findCheck(report, "config").Status)
+}
+
+func findCheck(report *DoctorReport, name string) *DoctorCheck {
+	for _, c := range report.Checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
@@ -8,18 +8,33 @@ type ApplicationEvent struct {
 }
 
 const (
-	TopicUser               = "user.*"
-	TopicHeartbeat          = "heartbeat.*"
-	TopicProjectLabel       = "project_label.*"
-	EventUserUpdate         = "user.update"
-	EventUserDelete         = "user.delete"
-	EventHeartbeatCreate    = "heartbeat.create"
-	EventProjectLabelCreate = "project_label.create"
-	EventProjectLabelDelete = "project_label.delete"
-	EventWakatimeFailure    = "wakatime.failure"
-	FieldPayload            = "payload"
-	FieldUser               = "user"
-	FieldUserId             = "user.id"
+	TopicUser                  = "user.*"
+	TopicHeartbeat             = "heartbeat.*"
+	TopicProjectLabel          = "project_label.*"
+	TopicProjectRemote         = "project_remote.*"
+	TopicSummary               = "summary.*"
+	TopicClient                = "client.*"
+	TopicProjectGroup          = "project_group.*"
+	TopicAlias                 = "alias.*"
+	EventUserUpdate            = "user.update"
+	EventUserDelete            = "user.delete"
+	EventHeartbeatCreate       = "heartbeat.create"
+	EventProjectLabelCreate    = "project_label.create"
+	EventProjectLabelDelete    = "project_label.delete"
+	EventProjectRemoteUpdate   = "project_remote.update"
+	EventSummaryCreate         = "summary.create"
+	EventClientUpdate          = "client.update"
+	EventProjectGroupUpdate    = "project_group.update"
+	EventAliasUpdate           = "alias.update"
+	EventWakatimeFailure       = "wakatime.failure"
+	EventMailBounce            = "mail.bounce"
+	EventConfigReload          = "config.reload"
+	EventLanguageMappingUpdate = "language_mapping.update"
+	EventLabelRuleUpdate       = "label_rule.update"
+	EventDbCredentialsUpdate   = "db_credentials.update"
+	FieldPayload               = "payload"
+	FieldUser                  = "user"
+	FieldUserId                = "user.id"
 )
 
 var eventHub *hub.Hub
@@ -10,13 +10,22 @@ import (
 var jobQueues map[string]*artifex.Dispatcher
 var jobCounts map[string]int
 
+const (
+	queueCapacity = 4096
+	// share of a queue's capacity, beyond which it is considered saturated
+	queueSaturationThreshold = 0.9
+)
+
 const (
 	QueueDefault      = "wakapi.default"
 	QueueProcessing   = "wakapi.processing"
 	QueueReports      = "wakapi.reports"
 	QueueMails        = "wakapi.mail"
 	QueueImports      = "wakapi.imports"
+	QueueImportJobs   = "wakapi.imports.jobs"
 	QueueHousekeeping = "wakapi.housekeeping"
+	QueueExports      = "wakapi.exports"
+	QueueProjects     = "wakapi.projects"
 )
 
 type JobQueueMetrics struct {
@@ -35,7 +44,10 @@ func StartJobs() {
 	InitQueue(QueueReports, 1)
 	InitQueue(QueueMails, 1)
 	InitQueue(QueueImports, 1)
+	InitQueue(QueueImportJobs, 1)
 	InitQueue(QueueHousekeeping, utils.HalfCPUs())
+	InitQueue(QueueExports, 1)
+	InitQueue(QueueProjects, 1)
 }
 
 func InitQueue(name string, workers int) error {
@@ -43,7 +55,7 @@ func InitQueue(name string, workers int) error {
 		return fmt.Errorf("queue '%s' already existing", name)
 	}
 	logbuch.Info("creating job queue '%s' (%d workers)", name, workers)
-	jobQueues[name] = artifex.NewDispatcher(workers, 4096)
+	jobQueues[name] = artifex.NewDispatcher(workers, queueCapacity)
 	jobQueues[name].Start()
 	return nil
 }
@@ -59,6 +71,11 @@ func GetQueue(name string) *artifex.Dispatcher {
 	return jobQueues[name]
 }
 
+// Saturated returns whether the queue is (almost) full, so that further dispatches would be blocking
+func (m *JobQueueMetrics) Saturated() bool {
+	return float64(m.EnqueuedJobs-m.FinishedJobs) >= queueSaturationThreshold*queueCapacity
+}
+
 func GetQueueMetrics() []*JobQueueMetrics {
 	metrics := make([]*JobQueueMetrics, 0, len(jobQueues))
 	for name, queue := range jobQueues {
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/muety/wakapi/utils"
+)
+
+var outboundDispatcher *utils.OutboundDispatcher
+var outboundOnce sync.Once
+
+// GetOutboundDispatcher returns the dispatcher all outbound http requests are supposed to be passed through
+func GetOutboundDispatcher() *utils.OutboundDispatcher {
+	outboundOnce.Do(func() {
+		outboundDispatcher = utils.NewOutboundDispatcher(outboundDispatcherOptions(Get().Outbound))
+	})
+	return outboundDispatcher
+}
+
+func NewOutboundClient(priority utils.OutboundPriority, timeout time.Duration) *http.Client {
+	return GetOutboundDispatcher().Client(priority, timeout)
+}
+
+func outboundDispatcherOptions(c outboundConfig) utils.OutboundDispatcherOptions {
+	return utils.OutboundDispatcherOptions{
+		MaxConcurrent:        c.MaxConcurrent,
+		MaxConcurrentPerHost: c.MaxConcurrentPerHost,
+		RateLimit:            c.RateLimit,
+		RateBurst:            c.RateBurst,
+		HostRateLimits:       c.HostRateLimits,
+		BreakerThreshold:     c.BreakerThreshold,
+		BreakerCooldown:      time.Duration(c.BreakerCooldownSec) * time.Second,
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/emvi/logbuch"
+	"github.com/jinzhu/configor"
+	"github.com/leandro-lugaresi/hub"
+)
+
+// config keys which can be changed at runtime, all other changes require a restart
+const (
+	ReloadKeyCustomLanguages    = "app.custom_languages"
+	ReloadKeyNewsbox            = "app.newsbox"
+	ReloadKeyAggregationTime    = "app.aggregation_time"
+	ReloadKeyLeaderboardTime    = "app.leaderboard_generation_time"
+	ReloadKeyReportTimeWeekly   = "app.report_time_weekly"
+	ReloadKeyDataCleanupTime    = "app.data_cleanup_time"
+	ReloadKeyImportRateLimits   = "app.import_rate_limits"
+	ReloadKeyMail               = "mail"
+	ReloadKeyOutboundRateLimits = "outbound"
+)
+
+var reloadLock sync.Mutex
+
+// Reload re-reads the config file (and environment) and applies all changes to sections which support being reloaded at runtime
+// returns the keys of all changed sections, which are also published as part of an EventConfigReload event
+func Reload() (changed []string, err error) {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+	defer func() { setReloadStatus(err) }()
+
+	if err := resolveSecretFiles(); err != nil {
+		return nil, err
+	}
+	fresh := &Config{}
+	if err := configor.New(&configor.Config{}).Load(fresh, configPath); err != nil {
+		return nil, err
+	}
+	if err := loadVaultSecrets(fresh, false); err != nil {
+		return nil, err
+	}
+	fresh.App.normalizeCustomLanguages()
+
+	if err := fresh.validate(); err != nil {
+		return nil, err
+	}
+
+	// changes are applied to a copy, which then replaces the current config as a whole, as the latter is read concurrently
+	// services must therefore read reloadable sections via Get() at the time of use, instead of keeping a reference to the config
+	current := Get()
+	next := *current
+	changed = make([]string, 0)
+
+	apply := func(key string, dst, src interface{}) {
+		dstVal, srcVal := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+		if reflect.DeepEqual(dstVal.Interface(), srcVal.Interface()) {
+			return
+		}
+		dstVal.Set(srcVal)
+		if !slice.Contain(changed, key) {
+			changed = append(changed, key)
+		}
+	}
+
+	apply(ReloadKeyCustomLanguages, &next.App.CustomLanguages, &fresh.App.CustomLanguages)
+	apply(ReloadKeyNewsbox, &next.App.Newsbox, &fresh.App.Newsbox)
+	apply(ReloadKeyAggregationTime, &next.App.AggregationTime, &fresh.App.AggregationTime)
+	apply(ReloadKeyLeaderboardTime, &next.App.LeaderboardGenerationTime, &fresh.App.LeaderboardGenerationTime)
+	apply(ReloadKeyReportTimeWeekly, &next.App.ReportTimeWeekly, &fresh.App.ReportTimeWeekly)
+	apply(ReloadKeyDataCleanupTime, &next.App.DataCleanupTime, &fresh.App.DataCleanupTime)
+	apply(ReloadKeyImportRateLimits, &next.App.ImportBackoffMin, &fresh.App.ImportBackoffMin)
+	apply(ReloadKeyImportRateLimits, &next.App.ImportMaxRate, &fresh.App.ImportMaxRate)
+	apply(ReloadKeyMail, &next.Mail, &fresh.Mail)
+	apply(ReloadKeyOutboundRateLimits, &next.Outbound, &fresh.Outbound)
+
+	if len(changed) > 0 {
+		Set(&next)
+	}
+
+	if slice.Contain(changed, ReloadKeyOutboundRateLimits) {
+		GetOutboundDispatcher().SetOptions(outboundDispatcherOptions(next.Outbound))
+	}
+
+	logbuch.Info("reloaded config, changed sections: %v", changed)
+
+	if len(changed) > 0 {
+		EventBus().Publish(hub.Message{
+			Name:   EventConfigReload,
+			Fields: map[string]interface{}{FieldPayload: changed},
+		})
+	}
+
+	return changed, nil
+}
+
+// OnReload calls the given function whenever any of the given config sections was changed by a reload
+func OnReload(f func(), keys ...string) {
+	sub := EventBus().Subscribe(0, EventConfigReload)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			changed := m.Fields[FieldPayload].([]string)
+			for _, k := range keys {
+				if slice.Contain(changed, k) {
+					f()
+					break
+				}
+			}
+		}
+	}(&sub)
+}
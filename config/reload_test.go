@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	defer func(path string, c *Config) { configPath = path; Set(c) }(configPath, Get())
+
+	configPath = filepath.Join(t.TempDir(), "config.yml")
+	write := func(content string) {
+		assert.Nil(t, os.WriteFile(configPath, []byte(content), 0600))
+	}
+
+	write("app:\n  aggregation_time: '0 15 2 * * *'\n  custom_languages:\n    vue: Vue\n    foo: ''\n")
+	Set(Empty())
+	initial := Get()
+
+	changed, err := Reload()
+	assert.Nil(t, err)
+	assert.Contains(t, changed, ReloadKeyAggregationTime)
+	assert.Contains(t, changed, ReloadKeyCustomLanguages)
+	assert.Equal(t, map[string]string{"vue": "Vue", "foo": "unknown"}, Get().App.CustomLanguages)
+	assert.NotSame(t, initial, Get()) // config is swapped instead of modified in place
+	assert.Empty(t, initial.App.CustomLanguages)
+	assert.Equal(t, initial.Security, Get().Security)
+
+	write("app:\n  aggregation_time: '0 0 3 * * *'\n  custom_languages:\n    vue: Vue\n    foo: ''\n")
+	changed, err = Reload()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{ReloadKeyAggregationTime}, changed)
+	assert.Equal(t, "0 0 3 * * *", Get().App.AggregationTime)
+
+	current := Get()
+	write("app:\n  aggregation_time: 'not a cron expression'\n")
+	_, err = Reload()
+	assert.NotNil(t, err)
+	assert.Same(t, current, Get())
+	assert.Equal(t, "0 0 3 * * *", Get().App.AggregationTime)
+}
+
+func TestWatchConfig(t *testing.T) {
+	defer func(path string, c *Config) { configPath = path; Set(c) }(configPath, Get())
+
+	configPath = filepath.Join(t.TempDir(), "config.yml")
+	write := func(content string) {
+		assert.Nil(t, os.WriteFile(configPath, []byte(content), 0600))
+	}
+
+	write("app:\n  aggregation_time: '0 15 2 * * *'\n")
+	Set(Empty())
+
+	stop := WatchConfig(10 * time.Millisecond)
+	defer stop()
+
+	write("app:\n  aggregation_time: '0 0 3 * * *'\n")
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "0 0 3 * * *", Get().App.AggregationTime)
+	assert.True(t, GetReloadStatus().InSync)
+
+	write("app:\n  aggregation_time: 'not a cron expression'\n")
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "0 0 3 * * *", Get().App.AggregationTime) // previous config is kept
+	assert.False(t, GetReloadStatus().InSync)
+	assert.NotEmpty(t, GetReloadStatus().Error)
+}
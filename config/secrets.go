@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/emvi/logbuch"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	secretFileEnvSuffix = "_FILE"
+	redactedValue       = "********"
+)
+
+// environment variables holding sensitive values, each of which can alternatively be read from a file, whose path is given as <name>_FILE (e.g. docker or kubernetes secrets)
+var secretEnvVars = []string{
+	"WAKAPI_PASSWORD_SALT",
+	"WAKAPI_ENCRYPTION_KEY",
+	"WAKAPI_CAPTCHA_SECRET_KEY",
+	"WAKAPI_METRICS_PUSH_PASSWORD",
+	"WAKAPI_INFLUX_TOKEN",
+	"WAKAPI_DB_PASSWORD",
+	"WAKAPI_DB_DSN",
+	"WAKAPI_DB_REPLICAS",
+	"WAKAPI_CACHE_REDIS_URL",
+	"WAKAPI_ARCHIVE_S3_SECRET_KEY",
+	"WAKAPI_SUBSCRIPTIONS_STRIPE_API_KEY",
+	"WAKAPI_SUBSCRIPTIONS_STRIPE_SECRET_KEY",
+	"WAKAPI_SUBSCRIPTIONS_STRIPE_ENDPOINT_SECRET",
+	"WAKAPI_SUBSCRIPTIONS_PAYPAL_CLIENT_SECRET",
+	"WAKAPI_SENTRY_DSN",
+	"WAKAPI_MAIL_MAILWHALE_CLIENT_SECRET",
+	"WAKAPI_MAIL_SES_SECRET_KEY",
+	"WAKAPI_MAIL_SENDGRID_API_KEY",
+	"WAKAPI_MAIL_MAILGUN_API_KEY",
+	"WAKAPI_MAIL_SMTP_PASS",
+	"WAKAPI_VAULT_TOKEN",
+	"WAKAPI_VAULT_SECRET_ID",
+}
+
+// environment variables, whose values were read from a secret file, so they can be updated when the file changes
+var secretEnvVarsFromFile = map[string]bool{}
+
+// resolveSecretFiles reads the values of all sensitive environment variables given as files and sets them as regular environment variables for the config to be loaded from
+// explicitly set variables take precedence over files
+func resolveSecretFiles() error {
+	for _, name := range secretEnvVars {
+		path := os.Getenv(name + secretFileEnvSuffix)
+		if path == "" {
+			continue
+		}
+		if _, ok := os.LookupEnv(name); ok && !secretEnvVarsFromFile[name] {
+			logbuch.Warn("both %s and %s are set, ignoring the latter", name, name+secretFileEnvSuffix)
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s from file: %v", name, err)
+		}
+		if err := os.Setenv(name, strings.TrimRight(string(data), "\r\n")); err != nil {
+			return err
+		}
+		secretEnvVarsFromFile[name] = true
+	}
+	return nil
+}
+
+// DumpRedacted serializes the effective config as yaml, with all sensitive values masked, e.g. for inspecting it via the admin cli without leaking credentials into logs or support requests
+func (c *Config) DumpRedacted() ([]byte, error) {
+	redacted := *c // nested sections are values, so masking fields of the copy leaves the actual config untouched
+	redactSecrets(reflect.ValueOf(&redacted).Elem())
+	return yaml.Marshal(&redacted)
+}
+
+func redactSecrets(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field, fieldType := v.Field(i), v.Type().Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.Struct:
+			redactSecrets(field)
+		case reflect.String:
+			if field.String() != "" && isSecretEnvVar(fieldType.Tag.Get("env")) {
+				field.SetString(redactedValue)
+			}
+		}
+	}
+}
+
+func isSecretEnvVar(name string) bool {
+	for _, secret := range secretEnvVars {
+		if name == secret {
+			return true
+		}
+	}
+	return false
+}
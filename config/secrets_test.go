@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSecretFiles(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret")
+	t.Cleanup(func() {
+		os.Unsetenv("WAKAPI_DB_PASSWORD")
+		delete(secretEnvVarsFromFile, "WAKAPI_DB_PASSWORD")
+	})
+
+	assert.Nil(t, os.WriteFile(file, []byte("s3cr3t\n"), 0600))
+	t.Setenv("WAKAPI_DB_PASSWORD_FILE", file)
+	assert.Nil(t, resolveSecretFiles())
+	assert.Equal(t, "s3cr3t", os.Getenv("WAKAPI_DB_PASSWORD"))
+
+	// file was updated, e.g. secret rotated
+	assert.Nil(t, os.WriteFile(file, []byte("n3w"), 0600))
+	assert.Nil(t, resolveSecretFiles())
+	assert.Equal(t, "n3w", os.Getenv("WAKAPI_DB_PASSWORD"))
+
+	// explicitly set variable takes precedence
+	t.Setenv("WAKAPI_MAIL_SMTP_PASS", "explicit")
+	t.Setenv("WAKAPI_MAIL_SMTP_PASS_FILE", file)
+	assert.Nil(t, resolveSecretFiles())
+	assert.Equal(t, "explicit", os.Getenv("WAKAPI_MAIL_SMTP_PASS"))
+
+	t.Setenv("WAKAPI_PASSWORD_SALT_FILE", filepath.Join(t.TempDir(), "missing"))
+	assert.NotNil(t, resolveSecretFiles())
+}
+
+func TestConfig_DumpRedacted(t *testing.T) {
+	cfg := &Config{}
+	cfg.Security.PasswordSalt = "s4lt"
+	cfg.Db.Host = "db.example.org"
+	cfg.Db.Password = "s3cr3t"
+	cfg.App.Archive.S3.SecretKey = "k3y"
+	cfg.Cache.RedisUrl = "redis://:r3d1s@localhost:6379/0"
+
+	data, err := cfg.DumpRedacted()
+	assert.Nil(t, err)
+
+	dump := string(data)
+	assert.Contains(t, dump, "db.example.org")
+	assert.Contains(t, dump, redactedValue)
+	assert.NotContains(t, dump, "s4lt")
+	assert.NotContains(t, dump, "s3cr3t")
+	assert.NotContains(t, dump, "k3y")
+	assert.NotContains(t, dump, "r3d1s")
+
+	// actual config is left untouched
+	assert.Equal(t, "s3cr3t", cfg.Db.Password)
+	assert.Equal(t, "k3y", cfg.App.Archive.S3.SecretKey)
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/emersion/go-sasl"
+	"github.com/emersion/go-smtp"
+	"gorm.io/gorm"
+)
+
+const selfTestTable = "wakapi_self_test"
+
+var stripeApiUrl = "https://api.stripe.com/v1"
+
+// SelfTest runs all checks of Doctor and additionally verifies that the configured credentials actually work, i.e. that wakapi may create and modify tables in its database, log in to the smtp server and use the stripe api, and that outbound connections to the internet (e.g. for data imports) are possible
+func SelfTest(configFlag string) *DoctorReport {
+	return runDoctor(configFlag, true)
+}
+
+// selfTestDatabasePermissions checks whether the database user is permitted to run schema migrations and write data
+func selfTestDatabasePermissions(db *gorm.DB, report *DoctorReport) {
+	statements := []string{
+		fmt.Sprintf("CREATE TABLE %s (id INTEGER)", selfTestTable),
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN value INTEGER", selfTestTable),
+		fmt.Sprintf("INSERT INTO %s (id, value) VALUES (1, 1)", selfTestTable),
+		fmt.Sprintf("DELETE FROM %s", selfTestTable),
+	}
+
+	db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", selfTestTable)) // leftover from a previous, interrupted run
+	defer db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", selfTestTable))
+
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			report.add("database.permissions", DoctorStatusError, "failed to execute '%s': %v", stmt, err)
+			return
+		}
+	}
+	report.add("database.permissions", DoctorStatusOk, "database user may create, alter and write tables")
+}
+
+func selfTestSmtpLogin(config *SMTPMailConfig, report *DoctorReport) {
+	if config.Username == "" {
+		report.add("mail.login", DoctorStatusSkipped, "no smtp credentials configured")
+		return
+	}
+
+	conn, err := net.DialTimeout("tcp", config.ConnStr(), doctorTimeout)
+	if err != nil {
+		report.add("mail.login", DoctorStatusError, "failed to connect to smtp server: %v", err)
+		return
+	}
+	if config.TLS {
+		conn = tls.Client(conn, &tls.Config{ServerName: config.Host})
+	}
+
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		conn.Close()
+		report.add("mail.login", DoctorStatusError, "failed to initiate smtp session: %v", err)
+		return
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && !config.TLS {
+		if err := client.StartTLS(nil); err != nil {
+			report.add("mail.login", DoctorStatusError, "failed to start tls: %v", err)
+			return
+		}
+	}
+	if ok, _ := client.Extension("AUTH"); !ok {
+		report.add("mail.login", DoctorStatusError, "smtp server doesn't support authentication")
+		return
+	}
+	if err := client.Auth(sasl.NewPlainClient("", config.Username, config.Password)); err != nil {
+		report.add("mail.login", DoctorStatusError, "failed to log in as '%s': %v", config.Username, err)
+		return
+	}
+	report.add("mail.login", DoctorStatusOk, "logged in as '%s'", config.Username)
+}
+
+func selfTestStripe(config *Config, report *DoctorReport) {
+	if !config.Subscriptions.Enabled {
+		report.add("stripe", DoctorStatusSkipped, "subscriptions are disabled")
+		return
+	}
+	if config.Subscriptions.Provider != PaymentProviderStripe {
+		report.add("stripe", DoctorStatusSkipped, "subscriptions are paid via %s", config.Subscriptions.Provider)
+		return
+	}
+	if !strings.HasPrefix(config.Subscriptions.StripeApiKey, "pk_") {
+		report.add("stripe", DoctorStatusError, "stripe_api_key is not a publishable key")
+		return
+	}
+	if !strings.HasPrefix(config.Subscriptions.StripeEndpointSecret, "whsec_") {
+		report.add("stripe", DoctorStatusError, "stripe_endpoint_secret is not a webhook signing secret")
+		return
+	}
+
+	// the balance is readable by every secret or restricted key, so it serves well for checking the key itself
+	if status, err := stripeGet(config, "/balance"); err != nil {
+		report.add("stripe", DoctorStatusError, "stripe api is not reachable: %v", err)
+		return
+	} else if status != http.StatusOK {
+		report.add("stripe", DoctorStatusError, "stripe_secret_key was rejected (status %d)", status)
+		return
+	}
+
+	for _, plan := range config.Subscriptions.Plans {
+		if status, err := stripeGet(config, "/prices/"+plan.PriceId); err != nil || status != http.StatusOK {
+			report.add("stripe", DoctorStatusError, "price '%s' of plan '%s' not found", plan.PriceId, plan.Id)
+			return
+		}
+	}
+	report.add("stripe", DoctorStatusOk, "stripe keys and prices are valid")
+}
+
+func stripeGet(config *Config, path string) (int, error) {
+	req, err := http.NewRequest(http.MethodGet, stripeApiUrl+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.SetBasicAuth(config.Subscriptions.StripeSecretKey, "")
+
+	res, err := (&http.Client{Timeout: doctorTimeout}).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+	return res.StatusCode, nil
+}
+
+// selfTestOutbound checks whether external apis, which are required for data imports, can be reached
+func selfTestOutbound(config *Config, report *DoctorReport) {
+	if !config.App.ImportEnabled {
+		report.add("outbound", DoctorStatusSkipped, "imports are disabled")
+		return
+	}
+
+	res, err := (&http.Client{Timeout: doctorTimeout}).Get(WakatimeApiUrl)
+	if err != nil {
+		report.add("outbound", DoctorStatusError, "failed to reach %s: %v", WakatimeApiUrl, err)
+		return
+	}
+	res.Body.Close()
+	report.add("outbound", DoctorStatusOk, "%s is reachable", WakatimeApiUrl)
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelfTest(t *testing.T) {
+	stripe := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, _, _ := r.BasicAuth(); key != "sk_test_valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/balance" || r.URL.Path == "/prices/price_standard" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer stripe.Close()
+	defer func(url string) { stripeApiUrl = url }(stripeApiUrl)
+	stripeApiUrl = stripe.URL
+
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yml")
+	write := func(secretKey string) {
+		content := "app:\n  import_enabled: false\n  export_dir: " + filepath.Join(dir, "exports") +
+			"\ndb:\n  name: " + filepath.Join(dir, "wakapi.db") +
+			"\nmail:\n  enabled: false\nsubscriptions:\n  enabled: true\n  stripe_api_key: pk_test_key\n  stripe_endpoint_secret: whsec_secret\n  standard_price_id: price_standard\n  stripe_secret_key: " + secretKey + "\n"
+		assert.Nil(t, os.WriteFile(configFile, []byte(content), 0600))
+	}
+
+	write("sk_test_valid")
+	report := SelfTest(configFile)
+	assert.True(t, report.Ok)
+	assert.Equal(t, DoctorStatusOk, findCheck(report, "database.permissions").Status)
+	assert.Equal(t, DoctorStatusOk, findCheck(report, "stripe").Status)
+	assert.Equal(t, DoctorStatusSkipped, findCheck(report, "outbound").Status)
+	assert.Nil(t, findCheck(Doctor(configFile), "stripe")) // only part of the self-test
+
+	write("sk_test_invalid")
+	report = SelfTest(configFile)
+	assert.False(t, report.Ok)
+	assert.Equal(t, DoctorStatusError, findCheck(report, "stripe").Status)
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	shuttingDown atomic.Bool
+	shutdownCh   = make(chan struct{})
+	shutdownOnce sync.Once
+)
+
+// BeginShutdown marks the instance as shutting down, e.g. after receiving SIGTERM, so that long-running jobs can stop early and leave themselves to be resumed after the restart
+func BeginShutdown() {
+	shutdownOnce.Do(func() {
+		shuttingDown.Store(true)
+		close(shutdownCh)
+	})
+}
+
+func IsShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+// ShutdownSignal returns a channel, which is closed once the instance starts shutting down
+func ShutdownSignal() <-chan struct{} {
+	return shutdownCh
+}
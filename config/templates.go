@@ -7,8 +7,10 @@ const (
 	SignupTemplate        = "signup.tpl.html"
 	SetPasswordTemplate   = "set-password.tpl.html"
 	ResetPasswordTemplate = "reset-password.tpl.html"
+	DeleteAccountTemplate = "delete-account.tpl.html"
 	SettingsTemplate      = "settings.tpl.html"
 	SummaryTemplate       = "summary.tpl.html"
 	LeaderboardTemplate   = "leaderboard.tpl.html"
 	ProjectsTemplate      = "projects.tpl.html"
+	PairTemplate          = "pair.tpl.html"
 )
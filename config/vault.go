@@ -0,0 +1,282 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/emvi/logbuch"
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/leandro-lugaresi/hub"
+)
+
+const (
+	vaultTimeout         = 10 * time.Second
+	vaultRetryBackoff    = 500 * time.Millisecond
+	vaultMaxRetryBackoff = 1 * time.Minute
+)
+
+// keys of the kv secret read from vault and the config fields they're applied to
+const (
+	VaultKeyDbUser       = "db_user"
+	VaultKeyDbPassword   = "db_password"
+	VaultKeySmtpUser     = "smtp_user"
+	VaultKeySmtpPassword = "smtp_password"
+	VaultKeyPasswordSalt = "password_salt"
+)
+
+type vaultConfig struct {
+	Enabled     bool   `yaml:"enabled" default:"false" env:"WAKAPI_VAULT_ENABLED"`
+	Address     string `yaml:"address" env:"WAKAPI_VAULT_ADDR"`
+	Token       string `yaml:"token" env:"WAKAPI_VAULT_TOKEN"`                        // either a token or approle credentials must be set
+	RoleId      string `yaml:"role_id" env:"WAKAPI_VAULT_ROLE_ID"`                    // approle auth
+	SecretId    string `yaml:"secret_id" env:"WAKAPI_VAULT_SECRET_ID"`                // approle auth
+	KvMount     string `yaml:"kv_mount" default:"secret" env:"WAKAPI_VAULT_KV_MOUNT"` // mount path of a kv (version 2) secrets engine
+	SecretPath  string `yaml:"secret_path" env:"WAKAPI_VAULT_SECRET_PATH"`            // path of a secret holding any of db_user, db_password, smtp_user, smtp_password and password_salt
+	DbCredsPath string `yaml:"db_creds_path" env:"WAKAPI_VAULT_DB_CREDS_PATH"`        // path to generate dynamic database credentials from, e.g. database/creds/wakapi
+}
+
+// vaultClient wraps hashicorp vault's api client, see https://developer.hashicorp.com/vault/api-docs
+type vaultClient struct {
+	config vaultConfig
+	client *vaultapi.Client
+}
+
+var vault *vaultClient
+
+func newVaultClient(config vaultConfig) (*vaultClient, error) {
+	if config.Address == "" {
+		return nil, errors.New("vault address must be set")
+	}
+
+	apiConfig := vaultapi.DefaultConfig() // also picks up VAULT_NAMESPACE, VAULT_CACERT, etc. from the environment
+	if apiConfig.Error != nil {
+		return nil, apiConfig.Error
+	}
+	apiConfig.Address = config.Address
+	apiConfig.Timeout = vaultTimeout
+
+	client, err := vaultapi.NewClient(apiConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &vaultClient{config: config, client: client}
+	if config.Token != "" {
+		client.SetToken(config.Token)
+	} else if err := c.login(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// loadVaultSecrets applies secrets from vault to the given config
+// dynamic database credentials are only generated (and their lease renewed in the background) on initial load, but never on a reload
+func loadVaultSecrets(config *Config, initial bool) error {
+	if !config.Vault.Enabled {
+		return nil
+	}
+
+	if vault == nil {
+		client, err := newVaultClient(config.Vault)
+		if err != nil {
+			return err
+		}
+		vault = client
+	}
+
+	if config.Vault.SecretPath != "" {
+		secret, err := vault.readKv(config.Vault.SecretPath)
+		if err != nil {
+			return err
+		}
+		applyVaultSecret(config, secret)
+	}
+
+	if initial && config.Vault.DbCredsPath != "" {
+		lease, creds, err := vault.readDbCreds(config.Vault.DbCredsPath)
+		if err != nil {
+			return err
+		}
+		config.Db.User, config.Db.Password = creds["username"], creds["password"]
+		logbuch.Info("using dynamic database credentials from vault (lease duration %ds)", lease.LeaseDuration)
+		go vault.renewPeriodically(config.Vault.DbCredsPath, lease)
+	}
+
+	return nil
+}
+
+func applyVaultSecret(config *Config, secret map[string]string) {
+	set := func(key string, target *string) {
+		if v, ok := secret[key]; ok && v != "" {
+			*target = v
+		}
+	}
+	set(VaultKeyDbUser, &config.Db.User)
+	set(VaultKeyDbPassword, &config.Db.Password)
+	set(VaultKeySmtpUser, &config.Mail.Smtp.Username)
+	set(VaultKeySmtpPassword, &config.Mail.Smtp.Password)
+	set(VaultKeyPasswordSalt, &config.Security.PasswordSalt)
+}
+
+func (c *vaultClient) login() error {
+	if c.config.RoleId == "" || c.config.SecretId == "" {
+		return errors.New("either a vault token or approle credentials must be set")
+	}
+
+	payload := map[string]interface{}{"role_id": c.config.RoleId, "secret_id": c.config.SecretId}
+	secret, err := c.client.Logical().Write("auth/approle/login", payload)
+	if err != nil {
+		return fmt.Errorf("failed to log in to vault: %v", err)
+	}
+	if secret == nil || secret.Auth == nil {
+		return errors.New("failed to log in to vault: no token returned")
+	}
+	c.client.SetToken(secret.Auth.ClientToken)
+	return nil
+}
+
+// relogin obtains a fresh token via approle auth, e.g. once the previous one expired, if configured to log in at all
+func (c *vaultClient) relogin() {
+	if c.config.Token != "" {
+		return
+	}
+	if err := c.login(); err != nil {
+		Log().Error("failed to log in to vault again, %v", err)
+	}
+}
+
+func (c *vaultClient) readKv(path string) (map[string]string, error) {
+	result, err := c.client.KVv2(c.config.KvMount).Get(context.Background(), path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret '%s' from vault: %v", path, err)
+	}
+
+	secret := make(map[string]string, len(result.Data))
+	for k, v := range result.Data {
+		secret[k] = fmt.Sprintf("%v", v)
+	}
+	return secret, nil
+}
+
+func (c *vaultClient) readDbCreds(path string) (*vaultapi.Secret, map[string]string, error) {
+	secret, err := c.client.Logical().Read(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate database credentials from vault: %v", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil, fmt.Errorf("failed to generate database credentials from vault: no data at '%s'", path)
+	}
+
+	creds := make(map[string]string, len(secret.Data))
+	for k, v := range secret.Data {
+		creds[k] = fmt.Sprintf("%v", v)
+	}
+	return secret, creds, nil
+}
+
+// renewPeriodically keeps the database credentials' lease alive for as long as the app is running
+// once vault refuses to renew the lease any further (i.e. its max. ttl is reached), new credentials are generated and published as EventDbCredentialsUpdate for the connection pool to be rotated
+func (c *vaultClient) renewPeriodically(path string, lease *vaultapi.Secret) {
+	for lease.LeaseDuration > 0 { // credentials without a lease duration never expire
+		c.renewUntilExpiry(lease)
+		lease = c.rotateDbCreds(path)
+	}
+}
+
+// renewUntilExpiry renews the lease once two thirds of its duration have passed and returns shortly before it expires for good
+// failed renewals are retried with a short backoff for as long as the lease is still valid
+func (c *vaultClient) renewUntilExpiry(lease *vaultapi.Secret) {
+	ttl, final := time.Duration(lease.LeaseDuration)*time.Second, !lease.Renewable
+	for {
+		expiry := time.Now().Add(ttl)
+		time.Sleep(ttl * 2 / 3)
+		if final {
+			return
+		}
+
+		renewed, err := c.renewLease(lease, expiry)
+		if err != nil {
+			Log().Error("giving up renewing vault lease '%s', %v", lease.LeaseID, err)
+			return
+		}
+
+		// vault caps the lease duration at the remaining max. ttl
+		ttl = time.Duration(renewed.LeaseDuration) * time.Second
+		final = !renewed.Renewable || renewed.LeaseDuration < lease.LeaseDuration
+		if final {
+			Log().Warn("vault lease '%s' can not be renewed any further, generating new database credentials in %v", lease.LeaseID, ttl*2/3)
+		}
+	}
+}
+
+func (c *vaultClient) renewLease(lease *vaultapi.Secret, expiry time.Time) (*vaultapi.Secret, error) {
+	backoff := vaultRetryBackoff
+	for attempt := 1; ; attempt++ {
+		renewed, err := c.client.Sys().Renew(lease.LeaseID, lease.LeaseDuration)
+		if err == nil && renewed != nil {
+			return renewed, nil
+		}
+		if err == nil {
+			err = errors.New("no lease returned")
+		}
+		if time.Now().Add(backoff).After(expiry) {
+			return nil, err
+		}
+
+		Log().Warn("failed to renew vault lease '%s' (attempt %d), retrying in %v, %v", lease.LeaseID, attempt, backoff, err)
+		if isVaultPermissionDenied(err) {
+			c.relogin()
+		}
+		time.Sleep(backoff)
+		backoff = nextVaultBackoff(backoff)
+	}
+}
+
+// rotateDbCreds generates new database credentials, retrying (and logging in again) until succeeding, as there's no way to keep the app running without
+func (c *vaultClient) rotateDbCreds(path string) *vaultapi.Secret {
+	backoff := vaultRetryBackoff
+	for attempt := 1; ; attempt++ {
+		lease, creds, err := c.readDbCreds(path)
+		if err == nil {
+			applyDbCreds(creds["username"], creds["password"])
+			logbuch.Info("rotated dynamic database credentials from vault (lease duration %ds)", lease.LeaseDuration)
+			return lease
+		}
+
+		Log().Error("failed to rotate database credentials (attempt %d), retrying in %v, %v", attempt, backoff, err)
+		c.relogin()
+		time.Sleep(backoff)
+		backoff = nextVaultBackoff(backoff)
+	}
+}
+
+// applyDbCreds replaces the database credentials of the current config and notifies subscribers to reconnect with them (see Get())
+func applyDbCreds(user, password string) {
+	reloadLock.Lock()
+	current := Get()
+	if current == nil {
+		reloadLock.Unlock()
+		return
+	}
+	next := *current
+	next.Db.User, next.Db.Password = user, password
+	Set(&next)
+	reloadLock.Unlock()
+
+	EventBus().Publish(hub.Message{Name: EventDbCredentialsUpdate})
+}
+
+func isVaultPermissionDenied(err error) bool {
+	var responseErr *vaultapi.ResponseError
+	return errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusForbidden
+}
+
+func nextVaultBackoff(backoff time.Duration) time.Duration {
+	if backoff *= 2; backoff > vaultMaxRetryBackoff {
+		return vaultMaxRetryBackoff
+	}
+	return backoff
+}
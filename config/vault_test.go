@@ -0,0 +1,92 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadVaultSecrets(t *testing.T) {
+	var logins, renewals, leases int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/approle/login" && r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			atomic.AddInt32(&logins, 1)
+			var payload map[string]string
+			json.NewDecoder(r.Body).Decode(&payload)
+			assert.Equal(t, "role", payload["role_id"])
+			w.Write([]byte(`{"auth": {"client_token": "s.token"}}`))
+		case "/v1/secret/data/wakapi":
+			w.Write([]byte(`{"data": {"data": {"smtp_password": "smtp-secret", "password_salt": "pepper"}, "metadata": {"version": 1}}}`))
+		case "/v1/database/creds/wakapi":
+			// first credentials are renewable up to a max. ttl, the rotated ones never expire
+			if n := atomic.AddInt32(&leases, 1); n == 1 {
+				w.Write([]byte(`{"lease_id": "database/creds/wakapi/1", "lease_duration": 2, "renewable": true, "data": {"username": "v-wakapi-1", "password": "db-secret"}}`))
+			} else {
+				w.Write([]byte(fmt.Sprintf(`{"lease_id": "database/creds/wakapi/%d", "lease_duration": 0, "renewable": false, "data": {"username": "v-wakapi-%d", "password": "db-secret"}}`, n, n)))
+			}
+		case "/v1/sys/leases/renew":
+			// first renewal fails as if the token had expired, the second one hits the lease's max. ttl
+			if atomic.AddInt32(&renewals, 1) == 1 {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"errors": ["permission denied"]}`))
+				return
+			}
+			w.Write([]byte(`{"lease_id": "database/creds/wakapi/1", "lease_duration": 1, "renewable": true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	defer func() { vault = nil }()
+
+	config := Empty()
+	config.Db.Password = "from-config"
+	config.Vault = vaultConfig{
+		Enabled:     true,
+		Address:     server.URL,
+		RoleId:      "role",
+		SecretId:    "secret",
+		KvMount:     "secret",
+		SecretPath:  "wakapi",
+		DbCredsPath: "database/creds/wakapi",
+	}
+
+	previous := Get()
+	Set(config)
+	defer Set(previous)
+
+	sub := EventBus().Subscribe(1, EventDbCredentialsUpdate)
+	defer EventBus().Unsubscribe(sub)
+
+	assert.Nil(t, loadVaultSecrets(config, true))
+	assert.Equal(t, "smtp-secret", config.Mail.Smtp.Password)
+	assert.Equal(t, "pepper", config.Security.PasswordSalt)
+	assert.Equal(t, "v-wakapi-1", config.Db.User)
+	assert.Equal(t, "db-secret", config.Db.Password)
+
+	select {
+	case <-sub.Receiver:
+	case <-time.After(5 * time.Second):
+		t.Fatal("database credentials were not rotated")
+	}
+	assert.Equal(t, "v-wakapi-2", Get().Db.User)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&renewals))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&logins))
+
+	vault.client.SetToken("s.invalid")
+	assert.NotNil(t, loadVaultSecrets(config, false))
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+)
+
+// ReloadStatus describes the outcome of the most recent attempt to reload the config
+// a failed reload leaves the previously loaded config in place, which is reported as not in sync with the config file
+type ReloadStatus struct {
+	LastAttempt time.Time `json:"last_attempt"`
+	LastSuccess time.Time `json:"last_success"`
+	Error       string    `json:"error,omitempty"`
+	InSync      bool      `json:"in_sync"`
+}
+
+var (
+	reloadStatus     = ReloadStatus{InSync: true}
+	reloadStatusLock sync.RWMutex
+)
+
+func GetReloadStatus() ReloadStatus {
+	reloadStatusLock.RLock()
+	defer reloadStatusLock.RUnlock()
+	return reloadStatus
+}
+
+func setReloadStatus(err error) {
+	reloadStatusLock.Lock()
+	defer reloadStatusLock.Unlock()
+	reloadStatus.LastAttempt = time.Now()
+	reloadStatus.InSync = err == nil
+	reloadStatus.Error = ""
+	if err != nil {
+		reloadStatus.Error = err.Error()
+	} else {
+		reloadStatus.LastSuccess = reloadStatus.LastAttempt
+	}
+}
+
+// WatchConfig periodically checks the config file for changes and reloads it, until the returned stop function is called
+// the file's content is compared instead of relying on file system notifications, because mounted kubernetes config maps are updated by atomically swapping symlinks, which is not reliably reported as a change to the file itself
+func WatchConfig(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	checksum, _ := configChecksum()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			newChecksum, err := configChecksum()
+			if err != nil {
+				// file might be missing temporarily while being replaced
+				Log().Warn("failed to read config file for change detection, %v", err)
+				continue
+			}
+			if newChecksum == checksum {
+				continue
+			}
+			checksum = newChecksum
+
+			logbuch.Info("config file changed, reloading")
+			if _, err := Reload(); err != nil {
+				Log().Error("failed to reload changed config file, keeping previous config, %v", err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+func configChecksum() (string, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -4,3 +4,6 @@ import _ "embed"
 
 //go:embed colors.json
 var ColorsFile []byte
+
+//go:embed languages.json
+var LanguagesFile []byte
@@ -80,6 +80,43 @@ func ResolveIntervalTZ(interval *models.IntervalKey, tz *time.Location) (err err
 	return err, from, to
 }
 
+// ShiftIntervalTZ moves the given range, as resolved from interval, n periods of that interval back in time, e.g. "this week so far" to the same days of last week
+// calendar periods are shifted by their unit (and clamped to the end of shorter months), rolling ones by their length, custom ranges (interval == nil) by their duration
+func ShiftIntervalTZ(interval *models.IntervalKey, from, to time.Time, n int) (err error, shiftedFrom, shiftedTo time.Time) {
+	switch interval {
+	case models.IntervalToday, models.IntervalYesterday, models.IntervalPastDay:
+		return nil, from.AddDate(0, 0, -n), to.AddDate(0, 0, -n)
+	case models.IntervalThisWeek, models.IntervalLastWeek, models.IntervalPast7Days, models.IntervalPast7DaysYesterday:
+		return nil, from.AddDate(0, 0, -7*n), to.AddDate(0, 0, -7*n)
+	case models.IntervalPast14Days:
+		return nil, from.AddDate(0, 0, -14*n), to.AddDate(0, 0, -14*n)
+	case models.IntervalPast30Days:
+		return nil, from.AddDate(0, 0, -30*n), to.AddDate(0, 0, -30*n)
+	case models.IntervalThisMonth, models.IntervalLastMonth:
+		return nil, addMonthsClamped(from, -n), addMonthsClamped(to, -n)
+	case models.IntervalPast6Months:
+		return nil, addMonthsClamped(from, -6*n), addMonthsClamped(to, -6*n)
+	case models.IntervalThisYear, models.IntervalPast12Months:
+		return nil, addMonthsClamped(from, -12*n), addMonthsClamped(to, -12*n)
+	case models.IntervalAny:
+		return errors.New("cannot shift unbounded interval"), time.Time{}, time.Time{}
+	case nil:
+		length := to.Sub(from)
+		return nil, from.Add(-length * time.Duration(n)), to.Add(-length * time.Duration(n))
+	}
+	return errors.New("invalid interval"), time.Time{}, time.Time{}
+}
+
+// addMonthsClamped adds months to t like time.AddDate, except for not overflowing into the next month, e.g. mar 31st minus one month is feb 28th (not mar 3rd)
+func addMonthsClamped(t time.Time, months int) time.Time {
+	firstOfMonth := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location()).AddDate(0, months, 0)
+	day := t.Day()
+	if daysInMonth := firstOfMonth.AddDate(0, 1, -1).Day(); day > daysInMonth {
+		day = daysInMonth
+	}
+	return firstOfMonth.AddDate(0, 0, day-1)
+}
+
 // ResolveMaximumRange returns the interval label (e.g. "last_7_days") of the maximum allowed range when having opted to share this many days or an error for days == 0.
 func ResolveMaximumRange(days int) (error, *models.IntervalKey) {
 	if days == 0 {
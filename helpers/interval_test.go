@@ -25,3 +25,32 @@ func TestResolveMaximumRange_EdgeCases(t *testing.T) {
 	_, maximumInterval := ResolveMaximumRange(-1)
 	assert.Equal(t, models.IntervalAny, maximumInterval)
 }
+
+func TestShiftIntervalTZ(t *testing.T) {
+	tz := time.UTC
+	from, to := time.Date(2024, 3, 1, 0, 0, 0, 0, tz), time.Date(2024, 3, 31, 15, 0, 0, 0, tz)
+
+	err, shiftedFrom, shiftedTo := ShiftIntervalTZ(models.IntervalThisMonth, from, to, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, tz), shiftedFrom)
+	assert.Equal(t, time.Date(2024, 2, 29, 15, 0, 0, 0, tz), shiftedTo)
+
+	err, shiftedFrom, shiftedTo = ShiftIntervalTZ(models.IntervalThisYear, time.Date(2024, 1, 1, 0, 0, 0, 0, tz), time.Date(2024, 2, 29, 12, 0, 0, 0, tz), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, tz), shiftedFrom)
+	assert.Equal(t, time.Date(2023, 2, 28, 12, 0, 0, 0, tz), shiftedTo)
+
+	from, to = time.Date(2024, 3, 4, 0, 0, 0, 0, tz), time.Date(2024, 3, 6, 14, 0, 0, 0, tz)
+	err, shiftedFrom, shiftedTo = ShiftIntervalTZ(models.IntervalThisWeek, from, to, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2024, 2, 19, 0, 0, 0, 0, tz), shiftedFrom)
+	assert.Equal(t, time.Date(2024, 2, 21, 14, 0, 0, 0, tz), shiftedTo)
+
+	err, shiftedFrom, shiftedTo = ShiftIntervalTZ(nil, from, to, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, to.Sub(from), shiftedTo.Sub(shiftedFrom))
+	assert.Equal(t, from, shiftedTo)
+
+	err, _, _ = ShiftIntervalTZ(models.IntervalAny, time.Time{}, to, 1)
+	assert.NotNil(t, err)
+}
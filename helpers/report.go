@@ -0,0 +1,56 @@
+package helpers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/models"
+)
+
+// ParseReportDefinition resolves a user-supplied report definition to actual report parameters in the given time zone
+func ParseReportDefinition(def *models.ReportDefinition, tz *time.Location) (*models.ReportParams, error) {
+	var err error
+	var from, to time.Time
+
+	if def.Interval != "" {
+		if err, from, to = ResolveIntervalRawTZ(def.Interval, tz); err != nil {
+			return nil, errors.New("invalid 'interval'")
+		}
+	} else {
+		if from, err = ParseDateTimeTZ(def.From, tz); err != nil {
+			return nil, errors.New("missing or invalid 'from'")
+		}
+		if to, err = ParseDateTimeTZ(def.To, tz); err != nil {
+			return nil, errors.New("missing or invalid 'to'")
+		}
+	}
+	if !to.After(from) {
+		return nil, errors.New("'to' must be after 'from'")
+	}
+
+	filters := &models.Filters{}
+	for name, key := range def.Filters {
+		t, err := models.ParseAliasType(name)
+		if err != nil {
+			return nil, err
+		}
+		filters.With(t, key)
+	}
+
+	groupings := make([]uint8, 0, len(def.Groupings))
+	for _, name := range def.Groupings {
+		t, err := models.ParseAliasType(name)
+		if err != nil {
+			return nil, err
+		}
+		groupings = append(groupings, t)
+	}
+
+	return &models.ReportParams{
+		From:      from,
+		To:        to,
+		Filters:   filters,
+		Groupings: groupings,
+		Daily:     def.Daily,
+	}, nil
+}
@@ -0,0 +1,37 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReportDefinition(t *testing.T) {
+	params, err := ParseReportDefinition(&models.ReportDefinition{
+		From:      "2023-05-01",
+		To:        "2023-05-08",
+		Filters:   map[string]string{"project": "wakapi"},
+		Groupings: []string{"language", "editor"},
+		Daily:     true,
+	}, time.UTC)
+
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), params.From)
+	assert.Equal(t, time.Date(2023, 5, 8, 0, 0, 0, 0, time.UTC), params.To)
+	assert.Equal(t, models.OrFilter{"wakapi"}, params.Filters.Project)
+	assert.Equal(t, []uint8{models.SummaryLanguage, models.SummaryEditor}, params.Groupings)
+	assert.True(t, params.Daily)
+}
+
+func TestParseReportDefinition_Invalid(t *testing.T) {
+	_, err := ParseReportDefinition(&models.ReportDefinition{From: "2023-05-08", To: "2023-05-01"}, time.UTC)
+	assert.Error(t, err)
+
+	_, err = ParseReportDefinition(&models.ReportDefinition{Interval: "last_fortnight"}, time.UTC)
+	assert.Error(t, err)
+
+	_, err = ParseReportDefinition(&models.ReportDefinition{Interval: "week", Groupings: []string{"weather"}}, time.UTC)
+	assert.Error(t, err)
+}
@@ -2,8 +2,12 @@ package helpers
 
 import (
 	"errors"
+	"fmt"
+	"github.com/duke-git/lancet/v2/slice"
 	"github.com/muety/wakapi/models"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -32,7 +36,10 @@ func ParseSummaryParams(r *http.Request) (*models.SummaryParams, error) {
 
 	recompute := params.Get("recompute") != "" && params.Get("recompute") != "false"
 
-	filters := ParseSummaryFilters(r)
+	filters, err := ParseSummaryFilters(r)
+	if err != nil {
+		return nil, err
+	}
 
 	return &models.SummaryParams{
 		From:      from,
@@ -43,33 +50,106 @@ func ParseSummaryParams(r *http.Request) (*models.SummaryParams, error) {
 	}, nil
 }
 
-func ParseSummaryFilters(r *http.Request) *models.Filters {
-	filters := &models.Filters{}
-	if q := r.URL.Query().Get("project"); q != "" {
-		filters.With(models.SummaryProject, q)
-	}
-	if q := r.URL.Query().Get("language"); q != "" {
-		filters.With(models.SummaryLanguage, q)
+// summaryFilterParams are the query parameters to filter by, in the order of the entity types they refer to
+// the order is fixed to yield deterministic filter hashes
+var summaryFilterParams = []struct {
+	name   string
+	entity uint8
+}{
+	{"project", models.SummaryProject},
+	{"language", models.SummaryLanguage},
+	{"editor", models.SummaryEditor},
+	{"operating_system", models.SummaryOS},
+	{"machine", models.SummaryMachine},
+	{"label", models.SummaryLabel},
+	{"branch", models.SummaryBranch},
+	{"entity", models.SummaryEntity},
+	{"namespace", models.SummaryNamespace},
+	{"client", models.SummaryClient},
+}
+
+// ParseSummaryFilters reads filters from the request's query parameters, where, for instance
+// project=foo,bar matches either of both projects, project!=foo excludes a project,
+// project~=^foo matches projects by a regular expression and project!~=^foo excludes them
+func ParseSummaryFilters(r *http.Request) (*models.Filters, error) {
+	return parseSummaryFiltersQuery(r.URL.Query())
+}
+
+// ParseSavedFilterQuery validates the query of a saved filter, which must consist of (at least one) filter parameters only
+func ParseSavedFilterQuery(query string) (*models.Filters, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, errors.New("malformed filter query")
 	}
-	if q := r.URL.Query().Get("editor"); q != "" {
-		filters.With(models.SummaryEditor, q)
+	for key := range values {
+		if !isSummaryFilterParam(key) {
+			return nil, fmt.Errorf("'%s' is not a filter parameter", key)
+		}
 	}
-	if q := r.URL.Query().Get("machine"); q != "" {
-		filters.With(models.SummaryMachine, q)
+
+	filters, err := parseSummaryFiltersQuery(values)
+	if err != nil {
+		return nil, err
 	}
-	if q := r.URL.Query().Get("operating_system"); q != "" {
-		filters.With(models.SummaryOS, q)
+	if filters.IsEmpty() {
+		return nil, errors.New("empty filter query")
 	}
-	if q := r.URL.Query().Get("label"); q != "" {
-		filters.With(models.SummaryLabel, q)
+	return filters, nil
+}
+
+func parseSummaryFiltersQuery(query url.Values) (*models.Filters, error) {
+	filters := &models.Filters{}
+
+	for _, p := range summaryFilterParams {
+		param, entity := p.name, p.entity
+		if keys := splitFilterKeys(query[param]); len(keys) > 0 {
+			filters.WithMultiple(entity, keys)
+		}
+		if keys := splitFilterKeys(query[param+"!"]); len(keys) > 0 {
+			filters.WithCondition(models.NewExclusionCondition(entity, keys))
+		}
+		for _, suffix := range []string{"~", "!~"} {
+			for _, pattern := range query[param+suffix] {
+				if pattern == "" {
+					continue
+				}
+				if !slice.Contain(models.NativeSummaryTypes(), entity) {
+					return nil, fmt.Errorf("regular expressions are not supported for '%s' filters", param)
+				}
+				condition, err := models.NewRegexCondition(entity, pattern, suffix == "!~")
+				if err != nil {
+					return nil, fmt.Errorf("invalid regular expression for '%s' filter", param)
+				}
+				filters.WithCondition(condition)
+			}
+		}
 	}
-	if q := r.URL.Query().Get("branch"); q != "" {
-		filters.With(models.SummaryBranch, q)
+
+	return filters, nil
+}
+
+func isSummaryFilterParam(key string) bool {
+	for _, p := range summaryFilterParams {
+		for _, suffix := range []string{"", "!", "~", "!~"} {
+			if key == p.name+suffix {
+				return true
+			}
+		}
 	}
-	if q := r.URL.Query().Get("entity"); q != "" {
-		filters.With(models.SummaryBranch, q)
+	return false
+}
+
+// splitFilterKeys splits comma-separated filter values, possibly given as multiple parameters
+func splitFilterKeys(values []string) []string {
+	keys := make([]string, 0, len(values))
+	for _, v := range values {
+		for _, k := range strings.Split(v, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				keys = append(keys, k)
+			}
+		}
 	}
-	return filters
+	return keys
 }
 
 func extractUser(r *http.Request) *models.User {
@@ -0,0 +1,55 @@
+package helpers
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSummaryFilters(t *testing.T) {
+	query := url.Values{}
+	query.Set("project", "wakapi,anchr")
+	query.Set("language!", "Go")
+	query.Set("branch~", "^feature/")
+	query.Set("entity", "main.go")
+
+	filters, err := ParseSummaryFilters(httptest.NewRequest("GET", "/api/summary?"+query.Encode(), nil))
+	assert.Nil(t, err)
+	assert.Equal(t, models.OrFilter{"wakapi", "anchr"}, filters.Project)
+	assert.Equal(t, models.OrFilter{"main.go"}, filters.Entity)
+	assert.Empty(t, filters.Branch)
+	assert.Len(t, filters.Conditions, 2)
+	assert.Equal(t, models.SummaryLanguage, filters.Conditions[0].Entity)
+	assert.Equal(t, []string{"Go"}, filters.Conditions[0].Keys)
+	assert.True(t, filters.Conditions[0].Negated)
+	assert.Equal(t, models.SummaryBranch, filters.Conditions[1].Entity)
+	assert.True(t, filters.Conditions[1].Regex)
+	assert.False(t, filters.Conditions[1].Negated)
+}
+
+func TestParseSummaryFilters_Invalid(t *testing.T) {
+	_, err := ParseSummaryFilters(httptest.NewRequest("GET", "/api/summary?"+url.Values{"project~": {"(wakapi"}}.Encode(), nil))
+	assert.Error(t, err)
+
+	_, err = ParseSummaryFilters(httptest.NewRequest("GET", "/api/summary?"+url.Values{"label~": {"^oss"}}.Encode(), nil))
+	assert.Error(t, err)
+}
+
+func TestParseSavedFilterQuery(t *testing.T) {
+	filters, err := ParseSavedFilterQuery("project=wakapi,anchr&branch!=main")
+	assert.Nil(t, err)
+	assert.Equal(t, models.OrFilter{"wakapi", "anchr"}, filters.Project)
+	assert.Len(t, filters.Conditions, 1)
+
+	_, err = ParseSavedFilterQuery("interval=week&project=wakapi")
+	assert.Error(t, err)
+
+	_, err = ParseSavedFilterQuery("project~=(wakapi")
+	assert.Error(t, err)
+
+	_, err = ParseSavedFilterQuery("project=")
+	assert.Error(t, err)
+}
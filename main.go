@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"embed"
+	"encoding/json"
+	"errors"
 	"flag"
+	"io"
 	"io/fs"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/emvi/logbuch"
@@ -25,6 +34,7 @@ import (
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/migrations"
+	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/repositories"
 	"github.com/muety/wakapi/routes"
 	"github.com/muety/wakapi/routes/api"
@@ -49,6 +59,11 @@ var version string
 //go:embed static
 var staticFiles embed.FS
 
+const (
+	maxDbConnectBackoff   = 1 * time.Minute
+	dbRotationGracePeriod = 1 * time.Minute // time for queries on previous connections to finish, before these are closed
+)
+
 var (
 	db     *gorm.DB
 	config *conf.Config
@@ -60,11 +75,32 @@ var (
 	userRepository            repositories.IUserRepository
 	languageMappingRepository repositories.ILanguageMappingRepository
 	projectLabelRepository    repositories.IProjectLabelRepository
+	labelRuleRepository       repositories.ILabelRuleRepository
+	blockRuleRepository       repositories.IBlockRuleRepository
+	invitationRepository      repositories.IInvitationRepository
+	mappingConfigRepository   repositories.IMappingConfigRepository
+	annotationRepository      repositories.IAnnotationRepository
+	milestoneRepository       repositories.IMilestoneRepository
+	savedFilterRepository     repositories.ISavedFilterRepository
+	achievementRepository     repositories.IAchievementRepository
+	projectRemoteRepository   repositories.IProjectRemoteRepository
+	machineRepository         repositories.IMachineRepository
+	clientRepository          repositories.IClientRepository
+	projectGroupRepository    repositories.IProjectGroupRepository
+	billableProjectRepository repositories.IBillableProjectRepository
+	notificationRepository    repositories.INotificationRepository
 	summaryRepository         repositories.ISummaryRepository
 	leaderboardRepository     *repositories.LeaderboardRepository
 	keyValueRepository        repositories.IKeyValueRepository
 	diagnosticsRepository     repositories.IDiagnosticsRepository
 	metricsRepository         *repositories.MetricsRepository
+	abuseReportRepository     repositories.IAbuseReportRepository
+	dataCorrectionRepository  repositories.IDataCorrectionRepository
+	accessTokenRepository     repositories.IAccessTokenRepository
+	sandboxRepository         repositories.ISandboxRepository
+	jobLockRepository         repositories.IJobLockRepository
+	queuedJobRepository       repositories.IQueuedJobRepository
+	exportTargetRepository    repositories.IExportTargetRepository
 )
 
 var (
@@ -73,6 +109,28 @@ var (
 	userService            services.IUserService
 	languageMappingService services.ILanguageMappingService
 	projectLabelService    services.IProjectLabelService
+	labelRuleService       services.ILabelRuleService
+	blockRuleService       services.IBlockRuleService
+	invitationService      services.IInvitationService
+	captchaService         services.ICaptchaService
+	loginThrottleService   services.ILoginThrottleService
+	mappingConfigService   services.IMappingConfigService
+	annotationService      services.IAnnotationService
+	milestoneService       services.IMilestoneService
+	savedFilterService     services.ISavedFilterService
+	achievementService     services.IAchievementService
+	projectRemoteService   services.IProjectRemoteService
+	machineService         services.IMachineService
+	clientService          services.IClientService
+	projectGroupService    services.IProjectGroupService
+	notificationService    services.INotificationService
+	clientVersionService   services.IClientVersionService
+	inactivityAlertService services.IInactivityAlertService
+	trendsService          services.ITrendsService
+	userTrendsService      services.IUserTrendsService
+	workingHoursService    services.IWorkingHoursService
+	billingService         services.IBillingService
+	publicStatsService     services.IPublicStatsService
 	durationService        services.IDurationService
 	summaryService         services.ISummaryService
 	leaderboardService     services.ILeaderboardService
@@ -83,7 +141,24 @@ var (
 	activityService        services.IActivityService
 	diagnosticsService     services.IDiagnosticsService
 	housekeepingService    services.IHousekeepingService
+	archiveService         services.IArchiveService
+	sandboxService         services.ISandboxService
+	demoService            services.IDemoService
+	influxExportService    services.IInfluxExportService
+	quotaService           services.IQuotaService
+	jobLockService         services.IJobLockService
+	persistentQueueService services.IPersistentQueueService
+	importService          services.IImportService
 	miscService            services.IMiscService
+	exportService          services.IExportService
+	abuseReportService     services.IAbuseReportService
+	dataCorrectionService  services.IDataCorrectionService
+	projectService         services.IProjectService
+	reprocessingService    services.IReprocessingService
+	mirrorService          services.IMirrorService
+	pairingService         services.IPairingService
+	accessTokenService     services.IAccessTokenService
+	presenceService        services.IPresenceService
 )
 
 // TODO: Refactor entire project to be structured after business domains
@@ -110,14 +185,28 @@ var (
 func main() {
 	var versionFlag = flag.Bool("version", false, "print version")
 	var configFlag = flag.String("config", conf.DefaultConfigPath, "config file location")
+	var validateConfigFlag = flag.Bool("validate-config", false, "check config, database, mail server, tls certificates and directories, print a report and exit (same as 'doctor' command)")
+	var selfTestFlag = flag.Bool("self-test", false, "like -validate-config, but additionally verify database permissions, smtp login, stripe keys and outbound connectivity")
+	var reportFlag = flag.String("report", "", "file to write the json report of -validate-config or -self-test to, in addition to stdout")
 	flag.Parse()
 
 	if *versionFlag {
 		print(version)
 		os.Exit(0)
 	}
+
+	if *selfTestFlag || flag.Arg(0) == "self-test" {
+		os.Exit(doctor(conf.SelfTest(*configFlag), *reportFlag))
+	}
+	if *validateConfigFlag || flag.Arg(0) == "doctor" {
+		os.Exit(doctor(conf.Doctor(*configFlag), *reportFlag))
+	}
 	config = conf.Load(*configFlag, version)
 
+	if flag.Arg(0) == "admin" && flag.Arg(1) == "config" {
+		os.Exit(adminConfig())
+	}
+
 	// Configure Swagger docs
 	docs.SwaggerInfo.BasePath = config.Server.BasePath + "/api"
 
@@ -142,7 +231,7 @@ func main() {
 	// Connect to database
 	var err error
 	logbuch.Info("starting with %s database", config.Db.Dialect)
-	db, err = gorm.Open(config.Db.GetDialector(), &gorm.Config{Logger: gormLogger}, conf.GetWakapiDBOpts(&config.Db))
+	db, err = connectDatabase(gormLogger)
 	if err != nil {
 		logbuch.Error(err.Error())
 		logbuch.Fatal("could not open database")
@@ -160,6 +249,24 @@ func main() {
 	sqlDb.SetMaxOpenConns(int(config.Db.MaxConn))
 	defer sqlDb.Close()
 
+	// dynamic credentials from vault expire at some point, after which all connections are re-established with new ones
+	// must happen before registering any plugins, as these might hold on to the connection pool
+	if config.Vault.Enabled && config.Vault.DbCredsPath != "" {
+		pool, err := conf.UseRotatingConnPool(db)
+		if err != nil {
+			logbuch.Error(err.Error())
+			logbuch.Fatal("could not set up database connection rotation")
+		}
+		go rotateDatabaseConnections(pool, gormLogger)
+	}
+
+	if config.Db.Replicas != "" {
+		if err := db.Use(conf.NewReplicaResolver(&config.Db)); err != nil {
+			logbuch.Error(err.Error())
+			logbuch.Fatal("could not set up read replicas")
+		}
+	}
+
 	// Migrate database schema
 	if !config.SkipMigrations {
 		migrations.Run(db, config)
@@ -171,29 +278,103 @@ func main() {
 	userRepository = repositories.NewUserRepository(db)
 	languageMappingRepository = repositories.NewLanguageMappingRepository(db)
 	projectLabelRepository = repositories.NewProjectLabelRepository(db)
+	labelRuleRepository = repositories.NewLabelRuleRepository(db)
+	blockRuleRepository = repositories.NewBlockRuleRepository(db)
+	invitationRepository = repositories.NewInvitationRepository(db)
+	mappingConfigRepository = repositories.NewMappingConfigRepository(db)
+	annotationRepository = repositories.NewAnnotationRepository(db)
+	milestoneRepository = repositories.NewMilestoneRepository(db)
+	savedFilterRepository = repositories.NewSavedFilterRepository(db)
+	achievementRepository = repositories.NewAchievementRepository(db)
+	projectRemoteRepository = repositories.NewProjectRemoteRepository(db)
+	machineRepository = repositories.NewMachineRepository(db)
+	clientRepository = repositories.NewClientRepository(db)
+	projectGroupRepository = repositories.NewProjectGroupRepository(db)
+	billableProjectRepository = repositories.NewBillableProjectRepository(db)
+	notificationRepository = repositories.NewNotificationRepository(db)
 	summaryRepository = repositories.NewSummaryRepository(db)
 	leaderboardRepository = repositories.NewLeaderboardRepository(db)
 	keyValueRepository = repositories.NewKeyValueRepository(db)
 	diagnosticsRepository = repositories.NewDiagnosticsRepository(db)
 	metricsRepository = repositories.NewMetricsRepository(db)
+	abuseReportRepository = repositories.NewAbuseReportRepository(db)
+	dataCorrectionRepository = repositories.NewDataCorrectionRepository(db)
+	accessTokenRepository = repositories.NewAccessTokenRepository(db)
+	sandboxRepository = repositories.NewSandboxRepository(db)
+	jobLockRepository = repositories.NewJobLockRepository(db)
+	queuedJobRepository = repositories.NewQueuedJobRepository(db)
+	exportTargetRepository = repositories.NewExportTargetRepository(db)
 
 	// Services
 	mailService = mail.NewMailService()
+	jobLockService = services.NewJobLockService(jobLockRepository)
+	persistentQueueService = services.NewPersistentQueueService(queuedJobRepository)
 	aliasService = services.NewAliasService(aliasRepository)
-	userService = services.NewUserService(mailService, userRepository)
+	notificationService = services.NewNotificationService(notificationRepository, mailService)
+	userService = services.NewUserService(mailService, notificationService, userRepository)
 	languageMappingService = services.NewLanguageMappingService(languageMappingRepository)
 	projectLabelService = services.NewProjectLabelService(projectLabelRepository)
-	heartbeatService = services.NewHeartbeatService(heartbeatRepository, languageMappingService)
+	blockRuleService = services.NewBlockRuleService(blockRuleRepository)
+	invitationService = services.NewInvitationService(invitationRepository)
+	captchaService = services.NewCaptchaService()
+	heartbeatService = services.NewHeartbeatService(heartbeatRepository, languageMappingService, blockRuleService)
 	durationService = services.NewDurationService(heartbeatService)
-	summaryService = services.NewSummaryService(summaryRepository, durationService, aliasService, projectLabelService)
-	leaderboardService = services.NewLeaderboardService(leaderboardRepository, summaryService, userService)
-	aggregationService = services.NewAggregationService(userService, summaryService, heartbeatService)
+	projectRemoteService = services.NewProjectRemoteService(projectRemoteRepository)
+	machineService = services.NewMachineService(machineRepository)
+	clientService = services.NewClientService(clientRepository)
+	projectGroupService = services.NewProjectGroupService(projectGroupRepository)
+	summaryService = services.NewSummaryService(summaryRepository, durationService, aliasService, projectLabelService, projectRemoteService, clientService, projectGroupService)
+	leaderboardService = services.NewLeaderboardService(leaderboardRepository, summaryService, userService, jobLockService)
+	aggregationService = services.NewAggregationService(userService, summaryService, heartbeatService, jobLockService)
 	keyValueService = services.NewKeyValueService(keyValueRepository)
-	reportService = services.NewReportService(summaryService, userService, mailService)
+	loginThrottleService = services.NewLoginThrottleService(keyValueService, mailService)
+	annotationService = services.NewAnnotationService(annotationRepository)
+	milestoneService = services.NewMilestoneService(milestoneRepository)
+	savedFilterService = services.NewSavedFilterService(savedFilterRepository)
+	userTrendsService = services.NewUserTrendsService(summaryService)
+	workingHoursService = services.NewWorkingHoursService(durationService)
+	billingService = services.NewBillingService(billableProjectRepository, summaryService)
+	reportService = services.NewReportService(summaryService, userService, mailService, keyValueService, annotationService, userTrendsService, workingHoursService, persistentQueueService, jobLockService)
 	activityService = services.NewActivityService(summaryService)
 	diagnosticsService = services.NewDiagnosticsService(diagnosticsRepository)
-	housekeepingService = services.NewHousekeepingService(userService, heartbeatService, summaryService)
-	miscService = services.NewMiscService(userService, heartbeatService, summaryService, keyValueService, mailService)
+	housekeepingService = services.NewHousekeepingService(userService, heartbeatService, summaryService, machineService, keyValueService, persistentQueueService, jobLockService)
+	miscService = services.NewMiscService(userService, heartbeatService, summaryService, keyValueService, mailService, notificationService)
+	exportService = services.NewExportService(heartbeatService, summaryService, aliasService, projectLabelService, languageMappingService, machineService, exportTargetRepository, jobLockService)
+	abuseReportService = services.NewAbuseReportService(abuseReportRepository, userService, aliasService, heartbeatService)
+	dataCorrectionService = services.NewDataCorrectionService(dataCorrectionRepository, userService, heartbeatService, aggregationService)
+	projectService = services.NewProjectService(heartbeatService, aliasService, projectLabelService, aggregationService)
+	mirrorService = services.NewMirrorService(userService)
+	labelRuleService = services.NewLabelRuleService(labelRuleRepository, heartbeatService, projectLabelService)
+	reprocessingService = services.NewReprocessingService(userService, heartbeatService, labelRuleService, aggregationService)
+	mappingConfigService = services.NewMappingConfigService(mappingConfigRepository, aliasService, projectLabelService, labelRuleService)
+	pairingService = services.NewPairingService()
+	accessTokenService = services.NewAccessTokenService(accessTokenRepository)
+	clientVersionService = services.NewClientVersionService(userService, heartbeatService, keyValueService, mailService, notificationService)
+	inactivityAlertService = services.NewInactivityAlertService(userService, heartbeatService, keyValueService, mailService, notificationService)
+	achievementService = services.NewAchievementService(achievementRepository, userService, summaryService, mailService, notificationService)
+	trendsService = services.NewTrendsService(summaryRepository, keyValueService)
+	publicStatsService = services.NewPublicStatsService(keyValueService, trendsService)
+	presenceService = services.NewPresenceService(heartbeatService)
+	archiveService = services.NewArchiveService(userService, heartbeatService, summaryService, jobLockService)
+	sandboxService = services.NewSandboxService(sandboxRepository, languageMappingService, blockRuleService)
+	demoService = services.NewDemoService(userService, heartbeatService, aggregationService)
+	influxExportService = services.NewInfluxExportService(userService, summaryService, jobLockService)
+	quotaService = services.NewQuotaService(heartbeatService)
+	importService = services.NewImportService(userService, heartbeatService, summaryService, aggregationService, keyValueService, mailService, notificationService, persistentQueueService)
+
+	if flag.Arg(0) == "restore-archive" {
+		os.Exit(restoreArchive(flag.Arg(1), flag.Arg(2), flag.Arg(3)))
+	}
+	if flag.Arg(0) == "backfill" {
+		var userIds []string
+		if flag.NArg() > 3 {
+			userIds = flag.Args()[3:]
+		}
+		os.Exit(backfill(flag.Arg(1), flag.Arg(2), userIds))
+	}
+	if flag.Arg(0) == "admin" {
+		os.Exit(admin(flag.Args()[1:]))
+	}
 
 	// Schedule background tasks
 	go conf.StartJobs()
@@ -201,39 +382,88 @@ func main() {
 	go leaderboardService.Schedule()
 	go reportService.Schedule()
 	go housekeepingService.Schedule()
+	go archiveService.Schedule()
 	go miscService.Schedule()
+	go exportService.Schedule()
+	go notificationService.Schedule()
+	go clientVersionService.Schedule()
+	go inactivityAlertService.Schedule()
+	go achievementService.Schedule()
+	go blockRuleService.Schedule()
+	go sandboxService.Schedule()
+	go demoService.Schedule()
+	go influxExportService.Schedule()
+	go persistentQueueService.Resume()
+
+	// Reload selected config sections on SIGHUP
+	go reloadOnSignal()
+	if config.App.WatchConfig {
+		conf.WatchConfig(time.Duration(config.App.WatchConfigIntervalSec) * time.Second)
+	}
 
 	routes.Init()
 
 	// API Handlers
 	healthApiHandler := api.NewHealthApiHandler(db)
-	heartbeatApiHandler := api.NewHeartbeatApiHandler(userService, heartbeatService, languageMappingService)
-	summaryApiHandler := api.NewSummaryApiHandler(userService, summaryService)
+	heartbeatApiHandler := api.NewHeartbeatApiHandler(userService, heartbeatService, languageMappingService, aggregationService, accessTokenService, quotaService)
+	summaryApiHandler := api.NewSummaryApiHandler(userService, summaryService, accessTokenService, annotationService, milestoneService, heartbeatService, userTrendsService, workingHoursService, savedFilterService)
 	metricsHandler := api.NewMetricsHandler(userService, summaryService, heartbeatService, keyValueService, metricsRepository)
+	go metricsHandler.SchedulePush()
 	diagnosticsHandler := api.NewDiagnosticsApiHandler(userService, diagnosticsService)
+	sandboxHandler := api.NewSandboxApiHandler(userService, heartbeatService, sandboxService, accessTokenService)
 	avatarHandler := api.NewAvatarHandler()
 	activityHandler := api.NewActivityApiHandler(userService, activityService)
-	badgeHandler := api.NewBadgeHandler(userService, summaryService)
+	badgeHandler := api.NewBadgeHandler(userService, summaryService, heartbeatService, savedFilterService)
+	adminHandler := api.NewAdminApiHandler(userService, abuseReportService, keyValueService, heartbeatService, blockRuleService, dataCorrectionService, housekeepingService, aggregationService, mailService, invitationService, loginThrottleService)
+	abuseReportHandler := api.NewAbuseReportApiHandler(userService, abuseReportService)
+	dataCorrectionHandler := api.NewDataCorrectionApiHandler(userService, dataCorrectionService)
+	projectHandler := api.NewProjectApiHandler(userService, projectService)
+	aggregationHandler := api.NewAggregationApiHandler(userService, aggregationService)
+	languageMappingHandler := api.NewLanguageMappingApiHandler(userService, languageMappingService, reprocessingService)
+	labelRuleHandler := api.NewLabelRuleApiHandler(userService, labelRuleService)
+	mappingConfigHandler := api.NewMappingConfigApiHandler(userService, mappingConfigService)
+	annotationHandler := api.NewAnnotationApiHandler(userService, annotationService)
+	milestoneHandler := api.NewMilestoneApiHandler(userService, milestoneService)
+	savedFilterHandler := api.NewSavedFilterApiHandler(userService, savedFilterService)
+	billingHandler := api.NewBillingApiHandler(userService, billingService)
+	projectClientHandler := api.NewProjectClientApiHandler(userService, clientService)
+	projectGroupHandler := api.NewProjectGroupApiHandler(userService, projectGroupService)
+	machineHandler := api.NewMachineApiHandler(userService, machineService)
+	achievementHandler := api.NewAchievementApiHandler(userService, achievementService)
+	reportHandler := api.NewReportApiHandler(userService, reportService, mailService)
+	clientHandler := api.NewClientApiHandler(userService, clientVersionService)
+	oauthHandler := api.NewOAuthApiHandler(userService, pairingService, accessTokenService)
+	trayHandler := api.NewTrayApiHandler(userService, summaryService, heartbeatService, pairingService, accessTokenService)
+	exportHandler := api.NewExportApiHandler(userService, exportService)
+	trendsHandler := api.NewTrendsApiHandler(trendsService)
+	publicStatsHandler := api.NewPublicStatsApiHandler(publicStatsService)
+	presenceHandler := api.NewPresenceApiHandler(userService, presenceService, accessTokenService)
+	homeAssistantHandler := api.NewHomeAssistantApiHandler(userService, summaryService, presenceService, accessTokenService)
+	openApiHandler := api.NewOpenApiHandler()
+	mailNotificationHandler := api.NewMailNotificationApiHandler()
 
 	// Compat Handlers
-	wakatimeV1StatusBarHandler := wtV1Routes.NewStatusBarHandler(userService, summaryService)
-	wakatimeV1AllHandler := wtV1Routes.NewAllTimeHandler(userService, summaryService)
-	wakatimeV1SummariesHandler := wtV1Routes.NewSummariesHandler(userService, summaryService)
-	wakatimeV1StatsHandler := wtV1Routes.NewStatsHandler(userService, summaryService)
+	wakatimeV1StatusBarHandler := wtV1Routes.NewStatusBarHandler(userService, summaryService, accessTokenService)
+	wakatimeV1AllHandler := wtV1Routes.NewAllTimeHandler(userService, summaryService, accessTokenService)
+	wakatimeV1SummariesHandler := wtV1Routes.NewSummariesHandler(userService, summaryService, accessTokenService)
+	wakatimeV1StatsHandler := wtV1Routes.NewStatsHandler(userService, summaryService, accessTokenService)
 	wakatimeV1UsersHandler := wtV1Routes.NewUsersHandler(userService, heartbeatService)
-	wakatimeV1ProjectsHandler := wtV1Routes.NewProjectsHandler(userService, heartbeatService)
+	wakatimeV1OrgsHandler := wtV1Routes.NewOrgsHandler(userService, durationService)
+	wakatimeV1ProjectsHandler := wtV1Routes.NewProjectsHandler(userService, heartbeatService, projectRemoteService)
+	wakatimeV1UserAgentsHandler := wtV1Routes.NewUserAgentsHandler(userService, heartbeatService)
 	wakatimeV1HeartbeatsHandler := wtV1Routes.NewHeartbeatHandler(userService, heartbeatService)
-	shieldV1BadgeHandler := shieldsV1Routes.NewBadgeHandler(summaryService, userService)
+	shieldV1BadgeHandler := shieldsV1Routes.NewBadgeHandler(summaryService, userService, heartbeatService, savedFilterService)
 
 	// MVC Handlers
-	summaryHandler := routes.NewSummaryHandler(summaryService, userService, keyValueService)
-	settingsHandler := routes.NewSettingsHandler(userService, heartbeatService, summaryService, aliasService, aggregationService, languageMappingService, projectLabelService, keyValueService, mailService)
+	summaryHandler := routes.NewSummaryHandler(summaryService, userService, keyValueService, annotationService, workingHoursService, savedFilterService)
+	settingsHandler := routes.NewSettingsHandler(userService, heartbeatService, summaryService, aliasService, aggregationService, languageMappingService, projectLabelService, keyValueService, mailService, accessTokenService, reprocessingService, mirrorService, notificationService, importService)
 	subscriptionHandler := routes.NewSubscriptionHandler(userService, mailService, keyValueService)
 	leaderboardHandler := routes.NewLeaderboardHandler(userService, leaderboardService)
 	projectsHandler := routes.NewProjectsHandler(userService, heartbeatService)
 	homeHandler := routes.NewHomeHandler(keyValueService)
-	loginHandler := routes.NewLoginHandler(userService, mailService)
+	loginHandler := routes.NewLoginHandler(userService, mailService, invitationService, captchaService, loginThrottleService)
 	imprintHandler := routes.NewImprintHandler(keyValueService)
+	pairHandler := routes.NewPairHandler(userService, pairingService)
 
 	// Other Handlers
 	relayHandler := relay.NewRelayHandler()
@@ -251,26 +481,41 @@ func main() {
 			"/service-worker.js",
 			"/api/health",
 			"/api/avatar",
+			"/healthz",
+			"/readyz",
 		}),
 	)
+	if config.Server.AccessLog.Enabled {
+		router.Use(middlewares.NewAccessLogMiddleware(openAccessLog(config)))
+	}
 	if config.Sentry.Dsn != "" {
 		router.Use(middlewares.NewSentryMiddleware())
 	}
+	if config.Server.Compression.Enabled {
+		router.Use(middlewares.NewCompressionMiddleware(config.Server.Compression.Level))
+	}
 
 	// Setup Sub Routers
 	rootRouter := chi.NewRouter()
 	rootRouter.Use(middlewares.NewSecurityMiddleware())
 
 	apiRouter := chi.NewRouter()
+	apiRouter.Use(middlewares.NewCorsMiddleware(), middlewares.NewApiVersionMiddleware(0))
+
+	apiV2Router := chi.NewRouter()
+	apiV2Router.Use(middlewares.NewCorsMiddleware(), middlewares.NewApiVersionMiddleware(middlewares.ApiVersion2))
 
 	// Hook sub routers
 	router.Mount("/", rootRouter)
 	router.Mount("/api", apiRouter)
+	router.Mount("/api/v2", apiV2Router)
 
 	// Route registrations
+	healthApiHandler.RegisterProbeRoutes(router)
 	homeHandler.RegisterRoutes(rootRouter)
 	loginHandler.RegisterRoutes(rootRouter)
 	imprintHandler.RegisterRoutes(rootRouter)
+	pairHandler.RegisterRoutes(rootRouter)
 	summaryHandler.RegisterRoutes(rootRouter)
 	leaderboardHandler.RegisterRoutes(rootRouter)
 	projectsHandler.RegisterRoutes(rootRouter)
@@ -279,20 +524,58 @@ func main() {
 	relayHandler.RegisterRoutes(rootRouter)
 
 	// API route registrations
-	summaryApiHandler.RegisterRoutes(apiRouter)
-	healthApiHandler.RegisterRoutes(apiRouter)
-	heartbeatApiHandler.RegisterRoutes(apiRouter)
-	metricsHandler.RegisterRoutes(apiRouter)
-	diagnosticsHandler.RegisterRoutes(apiRouter)
-	avatarHandler.RegisterRoutes(apiRouter)
-	activityHandler.RegisterRoutes(apiRouter)
-	badgeHandler.RegisterRoutes(apiRouter)
+	// native api handlers are served both unversioned (version 1 by default, see ApiVersionMiddleware) and under /api/v2, so handlers can introduce breaking changes for later versions only
+	nativeApiHandlers := []routes.Handler{
+		summaryApiHandler,
+		healthApiHandler,
+		heartbeatApiHandler,
+		metricsHandler,
+		diagnosticsHandler,
+		sandboxHandler,
+		avatarHandler,
+		activityHandler,
+		badgeHandler,
+		adminHandler,
+		exportHandler,
+		trendsHandler,
+		publicStatsHandler,
+		presenceHandler,
+		homeAssistantHandler,
+		abuseReportHandler,
+		dataCorrectionHandler,
+		projectHandler,
+		aggregationHandler,
+		languageMappingHandler,
+		labelRuleHandler,
+		mappingConfigHandler,
+		annotationHandler,
+		milestoneHandler,
+		savedFilterHandler,
+		billingHandler,
+		projectClientHandler,
+		projectGroupHandler,
+		machineHandler,
+		achievementHandler,
+		reportHandler,
+		clientHandler,
+		trayHandler,
+		oauthHandler,
+	}
+	for _, r := range []chi.Router{apiRouter, apiV2Router} {
+		for _, h := range nativeApiHandlers {
+			h.RegisterRoutes(r)
+		}
+	}
+	openApiHandler.RegisterRoutes(apiRouter)
+	mailNotificationHandler.RegisterRoutes(apiRouter)
 	wakatimeV1StatusBarHandler.RegisterRoutes(apiRouter)
 	wakatimeV1AllHandler.RegisterRoutes(apiRouter)
 	wakatimeV1SummariesHandler.RegisterRoutes(apiRouter)
 	wakatimeV1StatsHandler.RegisterRoutes(apiRouter)
 	wakatimeV1UsersHandler.RegisterRoutes(apiRouter)
+	wakatimeV1OrgsHandler.RegisterRoutes(apiRouter)
 	wakatimeV1ProjectsHandler.RegisterRoutes(apiRouter)
+	wakatimeV1UserAgentsHandler.RegisterRoutes(apiRouter)
 	wakatimeV1HeartbeatsHandler.RegisterRoutes(apiRouter)
 	shieldV1BadgeHandler.RegisterRoutes(apiRouter)
 
@@ -310,13 +593,15 @@ func main() {
 
 	router.Get("/contribute.json", staticFileServer.ServeHTTP)
 	router.Get("/assets/*", assetsFileServer.ServeHTTP)
-	router.Get("/swagger-ui", http.RedirectHandler("swagger-ui/", http.StatusMovedPermanently).ServeHTTP) // https://github.com/swaggo/http-swagger/issues/44
-	router.Get("/swagger-ui/*", httpSwagger.WrapHandler)
+	if config.Server.SwaggerUi {
+		router.Get("/swagger-ui", http.RedirectHandler("swagger-ui/", http.StatusMovedPermanently).ServeHTTP) // https://github.com/swaggo/http-swagger/issues/44
+		router.Get("/swagger-ui/*", httpSwagger.Handler(httpSwagger.URL(config.Server.BasePath+"/api/openapi.json")))
+	}
 
 	if config.EnablePprof {
 		logbuch.Info("profiling enabled, exposing pprof data at http://127.0.0.1:6060/debug/pprof")
 		go func() {
-			_ = http.ListenAndServe("127.0.0.1:6060", nil)
+			_ = http.ListenAndServe("127.0.0.1:6060", middlewares.NewAdminIpAllowlistMiddleware()(http.DefaultServeMux))
 		}()
 	}
 
@@ -324,8 +609,229 @@ func main() {
 	listen(router)
 }
 
+// openAccessLog returns the file to append access log entries to, or stdout if none is configured
+func openAccessLog(config *conf.Config) io.Writer {
+	if config.Server.AccessLog.Path == "" {
+		return os.Stdout
+	}
+	file, err := os.OpenFile(config.Server.AccessLog.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		logbuch.Fatal("could not open access log file '%s' - %v", config.Server.AccessLog.Path, err)
+	}
+	return file
+}
+
+// doctor prints a json report of whether the server could be started with the given config and returns a non-zero exit code otherwise, e.g. for use before container rollouts
+func doctor(report *conf.DoctorReport, reportPath string) int {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		logbuch.Error("failed to encode doctor report, %v", err)
+		return 2
+	}
+	os.Stdout.Write(append(data, '\n'))
+
+	if reportPath != "" {
+		if err := os.WriteFile(reportPath, data, 0644); err != nil {
+			logbuch.Error("failed to write doctor report to %s, %v", reportPath, err)
+			return 2
+		}
+	}
+
+	if !report.Ok {
+		return 1
+	}
+	return 0
+}
+
+// restoreArchive moves a user's archived heartbeats back into the database, optionally only those between from and to (inclusive, yyyy-mm-dd)
+func restoreArchive(userId, fromParam, toParam string) int {
+	if userId == "" {
+		logbuch.Error("usage: wakapi restore-archive <user> [<from> [<to>]]")
+		return 2
+	}
+
+	user, err := userService.GetUserById(userId)
+	if err != nil {
+		logbuch.Error("user '%s' not found", userId)
+		return 1
+	}
+
+	from, to := time.Time{}, time.Now()
+	if fromParam != "" {
+		if from, err = time.ParseInLocation(conf.SimpleDateFormat, fromParam, user.TZ()); err != nil {
+			logbuch.Error("invalid from date '%s'", fromParam)
+			return 2
+		}
+	}
+	if toParam != "" {
+		if to, err = time.ParseInLocation(conf.SimpleDateFormat, toParam, user.TZ()); err != nil {
+			logbuch.Error("invalid to date '%s'", toParam)
+			return 2
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+
+	count, err := archiveService.Restore(user, from, to)
+	if err != nil {
+		logbuch.Error("failed to restore archived heartbeats of user '%s' after %d heartbeats, %v", user.ID, count, err)
+		return 1
+	}
+	logbuch.Info("restored %d archived heartbeats of user '%s'", count, user.ID)
+	return 0
+}
+
+func backfill(fromParam, toParam string, userIds []string) int {
+	req := &models.AggregationBackfillRequest{From: fromParam, To: toParam, Users: userIds}
+	if !req.IsValid() {
+		logbuch.Error("usage: wakapi backfill <from> <to> [<user>...]   # dates as yyyy-mm-dd, inclusive")
+		return 2
+	}
+
+	job, err := aggregationService.Backfill(req)
+	if err != nil {
+		logbuch.Error("failed to backfill summaries, %v", err)
+		return 1
+	}
+	if len(job.Failed) > 0 {
+		logbuch.Error("failed to backfill summaries of users %s", strings.Join(job.Failed, ", "))
+		return 1
+	}
+	return 0
+}
+
+func reloadOnSignal() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	for range sigs {
+		logbuch.Info("received SIGHUP, reloading config")
+		if _, err := conf.Reload(); err != nil {
+			conf.Log().Error("failed to reload config, keeping previous one, %v", err)
+		}
+	}
+}
+
+// connectDatabase opens the database connection and retries with exponential backoff, while the database isn't reachable yet (e.g. because it's still starting up in docker-compose or kubernetes)
+func connectDatabase(gormLogger logger.Interface) (*gorm.DB, error) {
+	backoff := config.Db.ConnectBackoffDuration()
+	var stopWaiting func()
+
+	for attempt := 0; ; attempt++ {
+		db, err := gorm.Open(config.Db.GetDialector(), &gorm.Config{Logger: gormLogger}, conf.GetWakapiDBOpts(&config.Db))
+		if err == nil || attempt >= config.Db.ConnectRetries {
+			if stopWaiting != nil {
+				stopWaiting()
+			}
+			return db, err
+		}
+
+		if stopWaiting == nil {
+			stopWaiting = serveWaiting()
+		}
+		logbuch.Warn("database not available yet (attempt %d of %d), retrying in %v - %v", attempt+1, config.Db.ConnectRetries+1, backoff, err)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxDbConnectBackoff {
+			backoff = maxDbConnectBackoff
+		}
+	}
+}
+
+// rotateDatabaseConnections reconnects to the database whenever vault issued new credentials and closes the previous connections once in-flight queries had time to finish
+func rotateDatabaseConnections(pool *conf.RotatingConnPool, gormLogger logger.Interface) {
+	sub := conf.EventBus().Subscribe(0, conf.EventDbCredentialsUpdate)
+	for range sub.Receiver {
+		dbConfig := conf.Get().Db
+		fresh, err := gorm.Open(dbConfig.GetDialector(), &gorm.Config{Logger: gormLogger}, conf.GetWakapiDBOpts(&dbConfig))
+		if err != nil {
+			conf.Log().Error("failed to reconnect to database with rotated credentials, keeping previous connections, %v", err)
+			continue
+		}
+		sqlDb, err := fresh.DB()
+		if err != nil {
+			conf.Log().Error("failed to reconnect to database with rotated credentials, keeping previous connections, %v", err)
+			continue
+		}
+		sqlDb.SetMaxIdleConns(int(dbConfig.MaxConn))
+		sqlDb.SetMaxOpenConns(int(dbConfig.MaxConn))
+
+		previous := pool.Swap(sqlDb)
+		time.AfterFunc(dbRotationGracePeriod, func() { previous.Close() })
+		logbuch.Info("reconnected to database with rotated credentials")
+	}
+}
+
+// serveWaiting temporarily answers all requests with 503, while waiting for the database, so health checks can tell a starting instance from a dead one
+// unix sockets are only bound once the app is ready
+func serveWaiting() (stop func()) {
+	healthPath := strings.TrimSuffix(config.Server.BasePath, "/") + "/api/health"
+	livenessPath := strings.TrimSuffix(config.Server.BasePath, "/") + "/healthz"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == livenessPath {
+			// the process itself is alive, orchestrators should only hold back traffic (see /readyz), not restart it
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"status":"ok"}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if r.URL.Path == healthPath {
+			w.Write([]byte("app=0\ndb=0\nwaiting=1"))
+			return
+		}
+		w.Write([]byte("waiting for database"))
+	})
+
+	servers := make([]*http.Server, 0, 2)
+	if config.Server.ListenIpV4 != "-" && config.Server.ListenIpV4 != "" {
+		servers = append(servers, &http.Server{Handler: handler, Addr: config.Server.ListenIpV4 + ":" + strconv.Itoa(config.Server.Port)})
+	}
+	if config.Server.ListenIpV6 != "-" && config.Server.ListenIpV6 != "" {
+		servers = append(servers, &http.Server{Handler: handler, Addr: "[" + config.Server.ListenIpV6 + "]:" + strconv.Itoa(config.Server.Port)})
+	}
+
+	for _, s := range servers {
+		go func(s *http.Server) {
+			var err error
+			if config.UseTLS() {
+				err = s.ListenAndServeTLS(config.Server.TlsCertPath, config.Server.TlsKeyPath)
+			} else if config.UseAcme() {
+				s.TLSConfig = config.Server.Acme.TLSConfig(config.Server.Acme.NewManager())
+				err = s.ListenAndServeTLS("", "")
+			} else {
+				err = s.ListenAndServe()
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logbuch.Warn("failed to serve waiting state on %s - %v", s.Addr, err)
+			}
+		}(s)
+	}
+
+	return func() {
+		for _, s := range servers {
+			s.Close()
+		}
+	}
+}
+
 func listen(handler http.Handler) {
-	var s4, s6, sSocket *http.Server
+	var s4, s6, sSocket, sAcme *http.Server
+
+	// certificates are either loaded from files or obtained via acme, in which case they're provided by the servers' tls config
+	useTls, certPath, keyPath := config.UseTLS(), config.Server.TlsCertPath, config.Server.TlsKeyPath
+	var tlsConfig *tls.Config
+	if config.UseAcme() {
+		manager := config.Server.Acme.NewManager()
+		useTls, certPath, keyPath = true, "", ""
+		tlsConfig = config.Server.Acme.TLSConfig(manager)
+		if config.Server.Acme.UseHttpChallenge() {
+			// challenges have to be answered on port 80 of all interfaces, other requests are redirected to https
+			sAcme = &http.Server{
+				Handler:     manager.HTTPHandler(nil),
+				Addr:        ":" + strconv.Itoa(config.Server.Acme.HttpPort),
+				ReadTimeout: time.Duration(config.Server.TimeoutSec) * time.Second,
+			}
+		}
+	}
 
 	// IPv4
 	if config.Server.ListenIpV4 != "-" && config.Server.ListenIpV4 != "" {
@@ -335,6 +841,7 @@ func listen(handler http.Handler) {
 			Addr:         bindString4,
 			ReadTimeout:  time.Duration(config.Server.TimeoutSec) * time.Second,
 			WriteTimeout: time.Duration(config.Server.TimeoutSec) * time.Second,
+			TLSConfig:    tlsConfig,
 		}
 	}
 
@@ -346,6 +853,7 @@ func listen(handler http.Handler) {
 			Addr:         bindString6,
 			ReadTimeout:  time.Duration(config.Server.TimeoutSec) * time.Second,
 			WriteTimeout: time.Duration(config.Server.TimeoutSec) * time.Second,
+			TLSConfig:    tlsConfig,
 		}
 	}
 
@@ -362,14 +870,24 @@ func listen(handler http.Handler) {
 			Handler:      handler,
 			ReadTimeout:  time.Duration(config.Server.TimeoutSec) * time.Second,
 			WriteTimeout: time.Duration(config.Server.TimeoutSec) * time.Second,
+			TLSConfig:    tlsConfig,
 		}
 	}
 
-	if config.UseTLS() {
+	if sAcme != nil {
+		logbuch.Info("👉 Listening for ACME challenges on %s... ✅", sAcme.Addr)
+		go func() {
+			if err := sAcme.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logbuch.Fatal(err.Error())
+			}
+		}()
+	}
+
+	if useTls {
 		if s4 != nil {
 			logbuch.Info("👉 Listening for HTTPS on %s... ✅", s4.Addr)
 			go func() {
-				if err := s4.ListenAndServeTLS(config.Server.TlsCertPath, config.Server.TlsKeyPath); err != nil {
+				if err := s4.ListenAndServeTLS(certPath, keyPath); err != nil && !errors.Is(err, http.ErrServerClosed) {
 					logbuch.Fatal(err.Error())
 				}
 			}()
@@ -377,7 +895,7 @@ func listen(handler http.Handler) {
 		if s6 != nil {
 			logbuch.Info("👉 Listening for HTTPS on %s... ✅", s6.Addr)
 			go func() {
-				if err := s6.ListenAndServeTLS(config.Server.TlsCertPath, config.Server.TlsKeyPath); err != nil {
+				if err := s6.ListenAndServeTLS(certPath, keyPath); err != nil && !errors.Is(err, http.ErrServerClosed) {
 					logbuch.Fatal(err.Error())
 				}
 			}()
@@ -392,7 +910,7 @@ func listen(handler http.Handler) {
 				if err := os.Chmod(config.Server.ListenSocket, os.FileMode(config.Server.ListenSocketMode)); err != nil {
 					logbuch.Warn("failed to set user permissions for unix socket, %v", err)
 				}
-				if err := sSocket.ServeTLS(unixListener, config.Server.TlsCertPath, config.Server.TlsKeyPath); err != nil {
+				if err := sSocket.ServeTLS(unixListener, certPath, keyPath); err != nil && !errors.Is(err, http.ErrServerClosed) {
 					logbuch.Fatal(err.Error())
 				}
 			}()
@@ -401,7 +919,7 @@ func listen(handler http.Handler) {
 		if s4 != nil {
 			logbuch.Info("👉 Listening for HTTP on %s... ✅", s4.Addr)
 			go func() {
-				if err := s4.ListenAndServe(); err != nil {
+				if err := s4.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 					logbuch.Fatal(err.Error())
 				}
 			}()
@@ -409,7 +927,7 @@ func listen(handler http.Handler) {
 		if s6 != nil {
 			logbuch.Info("👉 Listening for HTTP on %s... ✅", s6.Addr)
 			go func() {
-				if err := s6.ListenAndServe(); err != nil {
+				if err := s6.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 					logbuch.Fatal(err.Error())
 				}
 			}()
@@ -424,12 +942,53 @@ func listen(handler http.Handler) {
 				if err := os.Chmod(config.Server.ListenSocket, os.FileMode(config.Server.ListenSocketMode)); err != nil {
 					logbuch.Warn("failed to set user permissions for unix socket, %v", err)
 				}
-				if err := sSocket.Serve(unixListener); err != nil {
+				if err := sSocket.Serve(unixListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 					logbuch.Fatal(err.Error())
 				}
 			}()
 		}
 	}
 
-	<-make(chan interface{}, 1)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	sig := <-sigs
+	logbuch.Info("received %v, shutting down", sig)
+
+	servers := make([]*http.Server, 0, 4)
+	for _, s := range []*http.Server{s4, s6, sSocket, sAcme} {
+		if s != nil {
+			servers = append(servers, s)
+		}
+	}
+	shutdown(servers)
+}
+
+// shutdown stops accepting new requests and waits for in-flight requests and running jobs to finish, before the database connection gets closed
+// jobs not finished in time (or not even started yet) remain in the database and are resumed after the restart
+func shutdown(servers []*http.Server) {
+	deadline := time.Now().Add(config.Server.ShutdownTimeout())
+	conf.BeginShutdown()
+
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, s := range servers {
+		wg.Add(1)
+		go func(s *http.Server) {
+			defer wg.Done()
+			if err := s.Shutdown(ctx); err != nil {
+				logbuch.Warn("failed to drain requests on %s in time - %v", s.Addr, err)
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	if !persistentQueueService.Drain(time.Until(deadline)) {
+		logbuch.Warn("some jobs did not finish in time, they will be resumed after restart")
+	}
+	mirrorService.Flush()
+	conf.CloseQueues()
+
+	logbuch.Info("shutdown complete")
 }
@@ -0,0 +1,103 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+)
+
+type accessLogEntry struct {
+	Time       string  `json:"time"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"` // without query, as it might contain an api key
+	Status     int     `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+	Bytes      int     `json:"bytes"`
+	User       string  `json:"user"`
+	Ip         string  `json:"ip"`
+	Sampled    bool    `json:"sampled,omitempty"` // whether the entry is only one out of many similar requests
+}
+
+// AccessLogMiddleware writes one json object per request to the given writer, skipping excluded paths and all but a sample of heartbeat requests
+// Must be placed after PrincipalMiddleware in the chain to be able to tell the requesting user
+type AccessLogMiddleware struct {
+	handler             http.Handler
+	out                 io.Writer
+	mutex               *sync.Mutex
+	excludePrefixes     []string
+	heartbeatSampleRate float64
+	random              func() float64
+}
+
+func NewAccessLogMiddleware(out io.Writer) func(http.Handler) http.Handler {
+	config := conf.Get().Server.AccessLog
+	mutex := &sync.Mutex{}
+	return func(h http.Handler) http.Handler {
+		return &AccessLogMiddleware{
+			handler:             h,
+			out:                 out,
+			mutex:               mutex,
+			excludePrefixes:     config.GetExcludePaths(),
+			heartbeatSampleRate: config.HeartbeatSampleRate,
+			random:              rand.Float64,
+		}
+	}
+}
+
+func (m *AccessLogMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.ToLower(r.URL.Path)
+	for _, prefix := range m.excludePrefixes {
+		if strings.HasPrefix(path, prefix) {
+			m.handler.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	sampled := isHeartbeatRequest(r)
+	if sampled && m.random() >= m.heartbeatSampleRate {
+		m.handler.ServeHTTP(w, r)
+		return
+	}
+
+	ww := wrapWriter(w)
+	start := time.Now()
+	m.handler.ServeHTTP(ww, r)
+	duration := time.Since(start)
+
+	status := ww.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	data, err := json.Marshal(&accessLogEntry{
+		Time:       start.Format(time.RFC3339Nano),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Status:     status,
+		DurationMs: float64(duration.Microseconds()) / 1000,
+		Bytes:      ww.BytesWritten(),
+		User:       readUserID(r),
+		Ip:         ClientIp(r),
+		Sampled:    sampled,
+	})
+	if err != nil {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, err := m.out.Write(append(data, '\n')); err != nil {
+		conf.Log().Error("failed to write access log - %v", err)
+	}
+}
+
+// isHeartbeatRequest tells whether the request sends heartbeats, which make up the vast majority of all requests
+func isHeartbeatRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.Contains(strings.ToLower(r.URL.Path), "/heartbeat")
+}
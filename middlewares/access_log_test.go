@@ -0,0 +1,61 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLogMiddleware_ServeHTTP(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Server.AccessLog.ExcludePaths = "/assets, /api/health"
+	cfg.Server.AccessLog.HeartbeatSampleRate = 0.1
+	config.Set(cfg)
+
+	var out bytes.Buffer
+	var random float64
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetPrincipal(r, &models.User{ID: "user01"})
+		w.WriteHeader(http.StatusAccepted)
+	})
+	sut := NewAccessLogMiddleware(&out)(handler).(*AccessLogMiddleware)
+	sut.random = func() float64 { return random }
+
+	serve := func(method, target string) {
+		req := httptest.NewRequest(method, target, nil)
+		req.RemoteAddr = "203.0.113.7:54654"
+		NewPrincipalMiddleware()(sut).ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve(http.MethodGet, "/api/summary?api_key=secret")
+	var entry accessLogEntry
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, http.MethodGet, entry.Method)
+	assert.Equal(t, "/api/summary", entry.Path)
+	assert.Equal(t, http.StatusAccepted, entry.Status)
+	assert.Equal(t, "user01", entry.User)
+	assert.Equal(t, "203.0.113.7", entry.Ip)
+	assert.False(t, entry.Sampled)
+	assert.NotContains(t, out.String(), "secret")
+
+	out.Reset()
+	serve(http.MethodGet, "/assets/app.js")
+	serve(http.MethodGet, "/api/health")
+	assert.Empty(t, out.String())
+
+	random = 0.5
+	serve(http.MethodPost, "/api/heartbeat")
+	assert.Empty(t, out.String())
+
+	random = 0.05
+	serve(http.MethodPost, "/api/compat/wakatime/v1/users/current/heartbeats.bulk")
+	assert.Equal(t, 1, strings.Count(out.String(), "\n"))
+	assert.Contains(t, out.String(), `"sampled":true`)
+}
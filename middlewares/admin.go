@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"net/http"
+
+	conf "github.com/muety/wakapi/config"
+)
+
+// AdminMiddleware rejects all requests not issued by an administrator
+// Must be placed after AuthenticateMiddleware in the chain, as it relies on the principal to be set
+type AdminMiddleware struct {
+	handler http.Handler
+}
+
+func NewAdminMiddleware() func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return &AdminMiddleware{h}
+	}
+}
+
+func (m *AdminMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user := GetPrincipal(r); user == nil || !user.IsAdmin {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(conf.ErrForbidden))
+		return
+	}
+	m.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminMiddleware_ServeHTTP(t *testing.T) {
+	config.Set(config.Empty())
+
+	var called bool
+	sut := NewAdminMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	serve := func(principal *models.User) int {
+		called = false
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/reports", nil)
+		NewPrincipalMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if principal != nil {
+				SetPrincipal(r, principal)
+			}
+			sut.ServeHTTP(w, r)
+		})).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(&models.User{ID: "admin", IsAdmin: true}))
+	assert.True(t, called)
+
+	assert.Equal(t, http.StatusForbidden, serve(&models.User{ID: "user"}))
+	assert.False(t, called)
+
+	assert.Equal(t, http.StatusForbidden, serve(nil))
+	assert.False(t, called)
+}
@@ -0,0 +1,115 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+)
+
+const (
+	ApiVersion1      = 1 // unversioned /api routes
+	ApiVersion2      = 2 // /api/v2 routes
+	ApiVersionLatest = ApiVersion2
+
+	HeaderApiVersion = "Api-Version"
+)
+
+const keyApiVersion = "api_version"
+
+type apiDeprecation struct {
+	since  time.Time
+	sunset time.Time
+}
+
+// deprecatedApiVersions lists api versions, which are about to be removed, along with when they were deprecated and when they will stop working
+// e.g. ApiVersion1: {since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), sunset: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)}
+var deprecatedApiVersions = map[int]apiDeprecation{}
+
+// ApiVersionMiddleware determines the version of the native api a request is targeted at and makes it available to handlers via GetApiVersion
+// Requests to versioned routes (e.g. /api/v2/...) are bound to that version. For unversioned routes (/api/...) the version is negotiated through the Api-Version request header,
+// defaulting to 1 for backwards compatibility. The version used is reported back in the Api-Version response header, along with deprecation headers for deprecated versions.
+type ApiVersionMiddleware struct {
+	handler http.Handler
+	version int // 0 for unversioned routes
+}
+
+func NewApiVersionMiddleware(version int) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return &ApiVersionMiddleware{handler: h, version: version}
+	}
+}
+
+func (m *ApiVersionMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	version := m.version
+	if version == 0 {
+		requested, err := parseApiVersion(r.Header.Get(HeaderApiVersion))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		version = requested
+	}
+
+	w.Header().Set(HeaderApiVersion, strconv.Itoa(version))
+	if deprecation, ok := deprecatedApiVersions[version]; ok {
+		setDeprecationHeaders(w, deprecation.since, deprecation.sunset, fmt.Sprintf("%s/api/v%d", conf.Get().Server.BasePath, ApiVersionLatest))
+	}
+
+	ctx := context.WithValue(r.Context(), keyApiVersion, version)
+	m.handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// GetApiVersion returns the api version negotiated for the request, i.e. 1 unless the client asked for a later one
+func GetApiVersion(r *http.Request) int {
+	if v, ok := r.Context().Value(keyApiVersion).(int); ok {
+		return v
+	}
+	return ApiVersion1
+}
+
+// DeprecationMiddleware marks responses of a single deprecated route as such, so clients can migrate to its successor before the route is removed at the sunset date
+// see https://datatracker.ietf.org/doc/html/draft-ietf-httpapi-deprecation-header and https://www.rfc-editor.org/rfc/rfc8594
+type DeprecationMiddleware struct {
+	handler   http.Handler
+	since     time.Time
+	sunset    time.Time // zero if not yet decided
+	successor string    // url of the route replacing the deprecated one, if any
+}
+
+func NewDeprecationMiddleware(since, sunset time.Time, successor string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return &DeprecationMiddleware{handler: h, since: since, sunset: sunset, successor: successor}
+	}
+}
+
+func (m *DeprecationMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	setDeprecationHeaders(w, m.since, m.sunset, m.successor)
+	m.handler.ServeHTTP(w, r)
+}
+
+func parseApiVersion(header string) (int, error) {
+	header = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(header)), "v")
+	if header == "" {
+		return ApiVersion1, nil
+	}
+	if version, err := strconv.Atoi(header); err == nil && version >= ApiVersion1 && version <= ApiVersionLatest {
+		return version, nil
+	}
+	return 0, fmt.Errorf("unsupported api version, must be between %d and %d", ApiVersion1, ApiVersionLatest)
+}
+
+func setDeprecationHeaders(w http.ResponseWriter, since, sunset time.Time, successor string) {
+	w.Header().Set("Deprecation", fmt.Sprintf("@%d", since.Unix()))
+	if !sunset.IsZero() {
+		w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+	}
+	if successor != "" {
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+	}
+}
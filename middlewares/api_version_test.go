@@ -0,0 +1,75 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApiVersionMiddleware_ServeHTTP(t *testing.T) {
+	config.Set(config.Empty())
+
+	var version int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version = GetApiVersion(r)
+	})
+
+	serve := func(routeVersion int, header string) *httptest.ResponseRecorder {
+		version = 0
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/summary", nil)
+		if header != "" {
+			req.Header.Set(HeaderApiVersion, header)
+		}
+		NewApiVersionMiddleware(routeVersion)(handler).ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(0, "")
+	assert.Equal(t, ApiVersion1, version)
+	assert.Equal(t, "1", rec.Header().Get(HeaderApiVersion))
+
+	rec = serve(0, "v2")
+	assert.Equal(t, ApiVersion2, version)
+	assert.Equal(t, "2", rec.Header().Get(HeaderApiVersion))
+
+	serve(ApiVersion2, "1")
+	assert.Equal(t, ApiVersion2, version) // path takes precedence over header
+
+	rec = serve(0, "99")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, 0, version)
+
+	t.Run("when version is deprecated", func(t *testing.T) {
+		since, sunset := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+		deprecatedApiVersions[ApiVersion1] = apiDeprecation{since: since, sunset: sunset}
+		defer delete(deprecatedApiVersions, ApiVersion1)
+
+		rec := serve(0, "")
+		assert.Equal(t, "@1704067200", rec.Header().Get("Deprecation"))
+		assert.Equal(t, "Mon, 01 Jul 2024 00:00:00 GMT", rec.Header().Get("Sunset"))
+		assert.Equal(t, `</api/v2>; rel="successor-version"`, rec.Header().Get("Link"))
+
+		rec = serve(ApiVersion2, "")
+		assert.Empty(t, rec.Header().Get("Deprecation"))
+	})
+}
+
+func TestDeprecationMiddleware_ServeHTTP(t *testing.T) {
+	var called bool
+	sut := NewDeprecationMiddleware(time.Unix(1704067200, 0), time.Time{}, "/api/v2/summary")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	rec := httptest.NewRecorder()
+	sut.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/summary", nil))
+
+	assert.True(t, called)
+	assert.Equal(t, "@1704067200", rec.Header().Get("Deprecation"))
+	assert.Empty(t, rec.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v2/summary>; rel="successor-version"`, rec.Header().Get("Link"))
+}
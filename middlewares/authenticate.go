@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"github.com/duke-git/lancet/v2/slice"
+	"github.com/emvi/logbuch"
 	"github.com/muety/wakapi/helpers"
+	uuid "github.com/satori/go.uuid"
 	"net"
 	"net/http"
 	"strings"
@@ -21,13 +23,19 @@ const (
 )
 
 var (
-	errEmptyKey = fmt.Errorf("the api_key is empty")
+	errEmptyKey          = fmt.Errorf("the api_key is empty")
+	errAccountSuspended  = fmt.Errorf("account suspended")
+	errAccountInactive   = fmt.Errorf("account deactivated")
+	errInsufficientScope = fmt.Errorf("insufficient_scope")
 )
 
 type AuthenticateMiddleware struct {
 	config               *conf.Config
 	userSrvc             services.IUserService
+	accessTokenSrvc      services.IAccessTokenService // optional
+	requiredScope        string                       // optional
 	optionalForPaths     []string
+	rejectDeactivated    bool
 	redirectTarget       string // optional
 	redirectErrorMessage string // optional
 }
@@ -45,6 +53,21 @@ func (m *AuthenticateMiddleware) WithOptionalFor(paths []string) *AuthenticateMi
 	return m
 }
 
+// WithAccessTokens makes the middleware additionally accept scoped access tokens, as long as they were granted the given scope
+// Access tokens are rejected by all routes not explicitly opting in
+func (m *AuthenticateMiddleware) WithAccessTokens(accessTokenService services.IAccessTokenService, requiredScope string) *AuthenticateMiddleware {
+	m.accessTokenSrvc = accessTokenService
+	m.requiredScope = requiredScope
+	return m
+}
+
+// WithRejectDeactivated makes the middleware reject users who deactivated their account, e.g. for routes that ingest data
+// must be placed before any middleware acting on the request on behalf of the user, like the wakatime relay
+func (m *AuthenticateMiddleware) WithRejectDeactivated() *AuthenticateMiddleware {
+	m.rejectDeactivated = true
+	return m
+}
+
 func (m *AuthenticateMiddleware) WithRedirectTarget(path string) *AuthenticateMiddleware {
 	m.redirectTarget = path
 	return m
@@ -65,6 +88,13 @@ func (m *AuthenticateMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	var user *models.User
 
 	user, err := m.tryGetUserByCookie(r)
+	if err != nil && m.accessTokenSrvc != nil {
+		user, err = m.tryGetUserByAccessToken(r)
+		if err == errInsufficientScope {
+			m.reject(w, r, http.StatusForbidden, err.Error(), m.redirectErrorMessage)
+			return
+		}
+	}
 	if err != nil {
 		user, err = m.tryGetUserByApiKeyHeader(r)
 	}
@@ -81,18 +111,17 @@ func (m *AuthenticateMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Reques
 			return
 		}
 
-		if m.redirectTarget == "" {
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte(conf.ErrUnauthorized))
-		} else {
-			if m.redirectErrorMessage != "" {
-				session, _ := conf.GetSessionStore().Get(r, conf.SessionKeyDefault)
-				session.AddFlash(m.redirectErrorMessage, "error")
-				session.Save(r, w)
-			}
-			http.SetCookie(w, m.config.GetClearCookie(models.AuthCookieKey))
-			http.Redirect(w, r, m.redirectTarget, http.StatusFound)
-		}
+		m.reject(w, r, http.StatusUnauthorized, conf.ErrUnauthorized, m.redirectErrorMessage)
+		return
+	}
+
+	if user.IsSuspended() {
+		m.reject(w, r, http.StatusForbidden, errAccountSuspended.Error(), "your account has been suspended")
+		return
+	}
+
+	if m.rejectDeactivated && user.IsDeactivated() {
+		m.reject(w, r, http.StatusForbidden, errAccountInactive.Error(), "your account is deactivated")
 		return
 	}
 
@@ -100,6 +129,22 @@ func (m *AuthenticateMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	next(w, r)
 }
 
+func (m *AuthenticateMiddleware) reject(w http.ResponseWriter, r *http.Request, status int, text string, redirectMessage string) {
+	if m.redirectTarget == "" {
+		w.WriteHeader(status)
+		w.Write([]byte(text))
+		return
+	}
+
+	if redirectMessage != "" {
+		session, _ := conf.GetSessionStore().Get(r, conf.SessionKeyDefault)
+		session.AddFlash(redirectMessage, "error")
+		session.Save(r, w)
+	}
+	http.SetCookie(w, m.config.GetClearCookie(models.AuthCookieKey))
+	http.Redirect(w, r, m.redirectTarget, http.StatusFound)
+}
+
 func (m *AuthenticateMiddleware) isOptional(requestPath string) bool {
 	for _, p := range m.optionalForPaths {
 		if strings.HasPrefix(requestPath, p) || requestPath == p {
@@ -124,6 +169,50 @@ func (m *AuthenticateMiddleware) tryGetUserByApiKeyHeader(r *http.Request) (*mod
 	return user, nil
 }
 
+func (m *AuthenticateMiddleware) tryGetUserByAccessToken(r *http.Request) (*models.User, error) {
+	plainToken := extractAccessToken(r)
+	if plainToken == "" {
+		return nil, errors.New("no access token given")
+	}
+
+	token, err := m.accessTokenSrvc.GetByToken(plainToken)
+	if err != nil {
+		return nil, err
+	}
+	if !token.HasScope(m.requiredScope) {
+		return nil, errInsufficientScope
+	}
+
+	user, err := m.userSrvc.GetUserById(token.UserID)
+	if err != nil {
+		return nil, err
+	}
+	SetAccessToken(r, token) // for handlers to check further scopes, see HasScope()
+	return user, nil
+}
+
+// extractAccessToken looks for an access token in the same places as for api keys, that is, as plain bearer token, base64-encoded in the authorization header or as query parameter
+// this way, read-only tokens can be used as drop-in replacement for the api key, e.g. in widgets or dashboards
+func extractAccessToken(r *http.Request) string {
+	candidates := make([]string, 0, 3)
+	if authHeader := strings.Fields(r.Header.Get("Authorization")); len(authHeader) == 2 && authHeader[0] == "Bearer" {
+		candidates = append(candidates, authHeader[1])
+	}
+	if key, err := utils.ExtractBearerAuth(r); err == nil {
+		candidates = append(candidates, key)
+	}
+	if r.URL != nil {
+		candidates = append(candidates, r.URL.Query().Get(queryApiKey))
+	}
+
+	for _, c := range candidates {
+		if c = strings.TrimSpace(c); strings.HasPrefix(c, models.AccessTokenPrefix) {
+			return c
+		}
+	}
+	return ""
+}
+
 func (m *AuthenticateMiddleware) tryGetUserByApiKeyQuery(r *http.Request) (*models.User, error) {
 	key := r.URL.Query().Get(queryApiKey)
 	var user *models.User
@@ -148,7 +237,89 @@ func (m *AuthenticateMiddleware) tryGetUserByTrustedHeader(r *http.Request) (*mo
 	}) {
 		return nil, errors.New("reverse proxy not trusted")
 	}
-	return m.userSrvc.GetUserById(remoteUser)
+
+	user, err := m.userSrvc.GetUserById(remoteUser)
+	if err != nil {
+		if !m.config.Security.TrustedHeaderAuthAutoSignup {
+			return nil, err
+		}
+		if user, err = m.provisionTrustedHeaderUser(r, remoteUser); err != nil {
+			return nil, err
+		}
+	}
+	return m.syncTrustedHeaderUser(r, user), nil
+}
+
+// provisionTrustedHeaderUser creates an account for a user authenticated by the reverse proxy, but not known yet
+func (m *AuthenticateMiddleware) provisionTrustedHeaderUser(r *http.Request, username string) (*models.User, error) {
+	if !models.ValidateUsername(username) {
+		return nil, errors.New("invalid username in trusted header")
+	}
+
+	signup := &models.Signup{
+		Username: username,
+		Password: uuid.NewV4().String(), // not used for logging in, since authentication is up to the proxy
+	}
+	if email := m.trustedHeaderEmail(r); models.ValidateEmail(email) {
+		signup.Email = email
+	}
+
+	isAdmin, _ := m.trustedHeaderIsAdmin(r)
+	user, created, err := m.userSrvc.CreateOrGet(signup, isAdmin)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to provision user '%s' from trusted header - %v", username, err)
+		return nil, err
+	}
+	if created {
+		logbuch.Info("provisioned user '%s' from trusted header", user.ID)
+	}
+	return user, nil
+}
+
+// syncTrustedHeaderUser updates the user's e-mail address and admin flag, if the proxy passes different ones
+func (m *AuthenticateMiddleware) syncTrustedHeaderUser(r *http.Request, user *models.User) *models.User {
+	var changed bool
+	if email := m.trustedHeaderEmail(r); email != "" && email != user.Email && models.ValidateEmail(email) {
+		user.Email = email
+		changed = true
+	}
+	if isAdmin, ok := m.trustedHeaderIsAdmin(r); ok && isAdmin != user.IsAdmin {
+		user.IsAdmin = isAdmin
+		changed = true
+	}
+	if !changed {
+		return user
+	}
+
+	if _, err := m.userSrvc.Update(user); err != nil {
+		conf.Log().Request(r).Error("failed to update user '%s' from trusted header - %v", user.ID, err)
+	}
+	return user
+}
+
+func (m *AuthenticateMiddleware) trustedHeaderEmail(r *http.Request) string {
+	if m.config.Security.TrustedHeaderAuthEmailKey == "" {
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get(m.config.Security.TrustedHeaderAuthEmailKey))
+}
+
+// trustedHeaderIsAdmin tells whether the groups passed by the proxy include the admin group, ok is false if no groups were passed or no admin group is configured
+func (m *AuthenticateMiddleware) trustedHeaderIsAdmin(r *http.Request) (isAdmin bool, ok bool) {
+	groupsKey, adminGroup := m.config.Security.TrustedHeaderAuthGroupsKey, m.config.Security.TrustedHeaderAuthAdminGroup
+	if groupsKey == "" || adminGroup == "" || len(r.Header.Values(groupsKey)) == 0 {
+		return false, false
+	}
+
+	groups := strings.FieldsFunc(strings.Join(r.Header.Values(groupsKey), ","), func(c rune) bool {
+		return c == ',' || c == '|'
+	})
+	for _, g := range groups {
+		if strings.TrimSpace(g) == adminGroup {
+			return true, true
+		}
+	}
+	return false, true
 }
 
 func (m *AuthenticateMiddleware) tryGetUserByCookie(r *http.Request) (*models.User, error) {
@@ -1,16 +1,20 @@
 package middlewares
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/muety/wakapi/config"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
 	"github.com/muety/wakapi/mocks"
 	"github.com/muety/wakapi/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestAuthenticateMiddleware_tryGetUserByApiKeyHeader_Success(t *testing.T) {
@@ -178,4 +182,291 @@ func TestAuthenticateMiddleware_tryGetUserByTrustedHeader_Success(t *testing.T)
 	assert.Nil(t, actualErr)
 }
 
+func TestAuthenticateMiddleware_tryGetUserByTrustedHeader_AutoSignup(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Env = "dev"
+	cfg.Security.TrustedHeaderAuth = true
+	cfg.Security.TrustedHeaderAuthKey = "Remote-User"
+	cfg.Security.TrustedHeaderAuthEmailKey = "Remote-Email"
+	cfg.Security.TrustedHeaderAuthGroupsKey = "Remote-Groups"
+	cfg.Security.TrustedHeaderAuthAdminGroup = "wakapi-admins"
+	cfg.Security.TrustedHeaderAuthAutoSignup = true
+	cfg.Security.TrustReverseProxyIps = "127.0.0.1,::1"
+	cfg.Security.ParseTrustReverseProxyIPs()
+	config.Set(cfg)
+
+	testUser := &models.User{ID: "user01", Email: "user01@example.org", IsAdmin: true}
+
+	mockRequest := &http.Request{
+		Header: http.Header{
+			"Remote-User":   []string{testUser.ID},
+			"Remote-Email":  []string{testUser.Email},
+			"Remote-Groups": []string{"users|wakapi-admins"},
+		},
+		RemoteAddr: "[::1]:54654",
+	}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserById", testUser.ID).Return((*models.User)(nil), errors.New(""))
+	userServiceMock.On("CreateOrGet", mock.MatchedBy(func(signup *models.Signup) bool {
+		return signup.Username == testUser.ID && signup.Email == testUser.Email && signup.Password != ""
+	}), true).Return(testUser, true, nil)
+
+	sut := NewAuthenticateMiddleware(userServiceMock)
+
+	result, actualErr := sut.tryGetUserByTrustedHeader(mockRequest)
+	assert.Equal(t, testUser, result)
+	assert.Nil(t, actualErr)
+	userServiceMock.AssertNotCalled(t, "Update", mock.Anything)
+
+	cfg.Security.TrustedHeaderAuthAutoSignup = false
+
+	result, actualErr = sut.tryGetUserByTrustedHeader(mockRequest)
+	assert.Nil(t, result)
+	assert.Error(t, actualErr)
+}
+
+func TestAuthenticateMiddleware_tryGetUserByTrustedHeader_SyncUser(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Env = "dev"
+	cfg.Security.TrustedHeaderAuth = true
+	cfg.Security.TrustedHeaderAuthKey = "Remote-User"
+	cfg.Security.TrustedHeaderAuthEmailKey = "Remote-Email"
+	cfg.Security.TrustedHeaderAuthGroupsKey = "Remote-Groups"
+	cfg.Security.TrustedHeaderAuthAdminGroup = "wakapi-admins"
+	cfg.Security.TrustReverseProxyIps = "127.0.0.1,::1"
+	cfg.Security.ParseTrustReverseProxyIPs()
+	config.Set(cfg)
+
+	testUser := &models.User{ID: "user01", Email: "old@example.org", IsAdmin: true}
+
+	mockRequest := &http.Request{
+		Header: http.Header{
+			"Remote-User":   []string{testUser.ID},
+			"Remote-Email":  []string{"new@example.org"},
+			"Remote-Groups": []string{"users, developers"},
+		},
+		RemoteAddr: "[::1]:54654",
+	}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserById", testUser.ID).Return(testUser, nil)
+	userServiceMock.On("Update", testUser).Return(testUser, nil)
+
+	sut := NewAuthenticateMiddleware(userServiceMock)
+
+	result, actualErr := sut.tryGetUserByTrustedHeader(mockRequest)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, "new@example.org", result.Email)
+	assert.False(t, result.IsAdmin)
+	userServiceMock.AssertNumberOfCalls(t, "Update", 1)
+
+	// admin flag is left untouched if proxy doesn't pass any groups
+	testUser.IsAdmin = true
+	mockRequest.Header.Del("Remote-Groups")
+
+	result, actualErr = sut.tryGetUserByTrustedHeader(mockRequest)
+	assert.Nil(t, actualErr)
+	assert.True(t, result.IsAdmin)
+	userServiceMock.AssertNumberOfCalls(t, "Update", 1)
+}
+
+func TestAuthenticateMiddleware_ServeHTTP_Suspended(t *testing.T) {
+	config.Set(config.Empty())
+
+	testApiKey := "z5uig69cn9ut93n"
+	testUser := &models.User{ID: "user01", ApiKey: testApiKey, Status: models.UserStatusSuspended}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByKey", testApiKey).Return(testUser, nil)
+
+	var called bool
+	next := func(w http.ResponseWriter, r *http.Request) { called = true }
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/heartbeat?api_key="+testApiKey, nil)
+
+	NewAuthenticateMiddleware(userServiceMock).ServeHTTP(rec, req, next)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.False(t, called)
+
+	testUser.Status = models.UserStatusActive
+	rec = httptest.NewRecorder()
+
+	NewAuthenticateMiddleware(userServiceMock).ServeHTTP(rec, req, next)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, called)
+}
+
+func TestAuthenticateMiddleware_ServeHTTP_Deactivated(t *testing.T) {
+	config.Set(config.Empty())
+
+	testApiKey := "z5uig69cn9ut93n"
+	testUser := &models.User{ID: "user01", ApiKey: testApiKey, Status: models.UserStatusDeactivated}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByKey", testApiKey).Return(testUser, nil)
+
+	var called bool
+	next := func(w http.ResponseWriter, r *http.Request) { called = true }
+
+	req := httptest.NewRequest(http.MethodPost, "/api/heartbeat?api_key="+testApiKey, nil)
+
+	// deactivated users may still log in, e.g. to reactivate their account
+	rec := httptest.NewRecorder()
+	NewAuthenticateMiddleware(userServiceMock).ServeHTTP(rec, req, next)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, called)
+
+	// ... but must not push any data
+	called = false
+	rec = httptest.NewRecorder()
+	NewAuthenticateMiddleware(userServiceMock).WithRejectDeactivated().ServeHTTP(rec, req, next)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.False(t, called)
+}
+
+func TestAuthenticateMiddleware_tryGetUserByAccessToken_Success(t *testing.T) {
+	testToken := models.AccessTokenPrefix + "abc123"
+	testUser := &models.User{ID: "user01"}
+
+	mockRequest := &http.Request{
+		Header: http.Header{
+			"Authorization": []string{fmt.Sprintf("Bearer %s", testToken)},
+		},
+	}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserById", testUser.ID).Return(testUser, nil)
+
+	accessTokenServiceMock := new(mocks.AccessTokenServiceMock)
+	accessTokenServiceMock.On("GetByToken", testToken).Return(&models.AccessToken{UserID: testUser.ID, Scope: "read_stats write_heartbeats"}, nil)
+
+	sut := NewAuthenticateMiddleware(userServiceMock).WithAccessTokens(accessTokenServiceMock, models.ScopeWriteHeartbeats)
+
+	result, err := sut.tryGetUserByAccessToken(mockRequest)
+
+	assert.Nil(t, err)
+	assert.Equal(t, testUser, result)
+}
+
+func TestAuthenticateMiddleware_tryGetUserByAccessToken_Invalid(t *testing.T) {
+	mockRequest := &http.Request{
+		Header: http.Header{
+			// not an access token, but an api key
+			"Authorization": []string{"Bearer z5uig69cn9ut93n"},
+		},
+	}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	accessTokenServiceMock := new(mocks.AccessTokenServiceMock)
+
+	sut := NewAuthenticateMiddleware(userServiceMock).WithAccessTokens(accessTokenServiceMock, models.ScopeReadStats)
+
+	result, err := sut.tryGetUserByAccessToken(mockRequest)
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	accessTokenServiceMock.AssertNotCalled(t, "GetByToken", mock.Anything)
+}
+
+func TestAuthenticateMiddleware_tryGetUserByAccessToken_ApiKeyFormats(t *testing.T) {
+	testToken := models.AccessTokenPrefix + "abc123"
+	testUser := &models.User{ID: "user01"}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserById", testUser.ID).Return(testUser, nil)
+
+	accessTokenServiceMock := new(mocks.AccessTokenServiceMock)
+	accessTokenServiceMock.On("GetByToken", testToken).Return(&models.AccessToken{UserID: testUser.ID, Scope: "read_stats"}, nil)
+
+	sut := NewAuthenticateMiddleware(userServiceMock).WithAccessTokens(accessTokenServiceMock, models.ScopeReadStats)
+
+	basicRequest := &http.Request{
+		Header: http.Header{
+			"Authorization": []string{fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(testToken)))},
+		},
+	}
+	result, err := sut.tryGetUserByAccessToken(basicRequest)
+	assert.Nil(t, err)
+	assert.Equal(t, testUser, result)
+
+	queryRequest := &http.Request{
+		URL: &url.URL{
+			RawQuery: fmt.Sprintf("%s=%s", queryApiKey, testToken),
+		},
+	}
+	result, err = sut.tryGetUserByAccessToken(queryRequest)
+	assert.Nil(t, err)
+	assert.Equal(t, testUser, result)
+	accessTokenServiceMock.AssertNumberOfCalls(t, "GetByToken", 2)
+}
+
+func TestAuthenticateMiddleware_ServeHTTP_InsufficientScope(t *testing.T) {
+	config.Set(config.Empty())
+
+	testToken := models.AccessTokenPrefix + "abc123"
+	testUser := &models.User{ID: "user01"}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserById", testUser.ID).Return(testUser, nil)
+
+	accessTokenServiceMock := new(mocks.AccessTokenServiceMock)
+	accessTokenServiceMock.On("GetByToken", testToken).Return(&models.AccessToken{UserID: testUser.ID, Scope: models.ScopeReadStats}, nil)
+
+	var called bool
+	next := func(w http.ResponseWriter, r *http.Request) { called = true }
+
+	req := httptest.NewRequest(http.MethodPost, "/api/heartbeat", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+
+	rec := httptest.NewRecorder()
+	NewAuthenticateMiddleware(userServiceMock).WithAccessTokens(accessTokenServiceMock, models.ScopeWriteHeartbeats).ServeHTTP(rec, req, next)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.False(t, called)
+
+	rec = httptest.NewRecorder()
+	NewAuthenticateMiddleware(userServiceMock).WithAccessTokens(accessTokenServiceMock, models.ScopeReadStats).ServeHTTP(rec, req, next)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, called)
+}
+
+func TestAuthenticateMiddleware_ServeHTTP_AccessTokenScopes(t *testing.T) {
+	config.Set(config.Empty())
+
+	testToken := models.AccessTokenPrefix + "abc123"
+	testUser := &models.User{ID: "user01", ApiKey: "z5uig69cn9ut93n"}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserById", testUser.ID).Return(testUser, nil)
+	userServiceMock.On("GetUserByKey", testUser.ApiKey).Return(testUser, nil)
+
+	accessTokenServiceMock := new(mocks.AccessTokenServiceMock)
+	accessTokenServiceMock.On("GetByToken", testToken).Return(&models.AccessToken{UserID: testUser.ID, Scope: models.ScopeReadStats}, nil)
+
+	sut := NewAuthenticateMiddleware(userServiceMock).WithAccessTokens(accessTokenServiceMock, models.ScopeReadStats)
+
+	serve := func(key string) (canRead, canWrite bool) {
+		req := httptest.NewRequest(http.MethodGet, "/api/summary", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		req = req.WithContext(context.WithValue(req.Context(), keyPrincipal, &PrincipalContainer{}))
+		sut.ServeHTTP(httptest.NewRecorder(), req, func(w http.ResponseWriter, r *http.Request) {
+			canRead, canWrite = HasScope(r, models.ScopeReadStats), HasScope(r, models.ScopeWriteHeartbeats)
+		})
+		return
+	}
+
+	// read-only access token -> restricted to its scope
+	canRead, canWrite := serve(testToken)
+	assert.True(t, canRead)
+	assert.False(t, canWrite)
+
+	// api key -> full access
+	canRead, canWrite = serve(base64.StdEncoding.EncodeToString([]byte(testUser.ApiKey)))
+	assert.True(t, canRead)
+	assert.True(t, canWrite)
+}
+
 // TODO: somehow test cookie auth function
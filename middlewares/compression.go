@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// content types worth compressing, most notably summaries (json) and badges (svg), while images and archives already are compressed
+var compressibleTypes = []string{
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+	"text/html",
+	"text/css",
+	"text/csv",
+	"text/plain",
+}
+
+// CompressionMiddleware brotli-, gzip- or deflate-encodes responses of compressible content types, if accepted by the client
+// streaming responses are compressed chunk-wise, i.e. flushes are passed through the encoder, except for server-sent events, which are never compressed
+type CompressionMiddleware struct {
+	handler    http.Handler
+	compressed http.Handler
+}
+
+func NewCompressionMiddleware(level int) func(http.Handler) http.Handler {
+	compressor := middleware.NewCompressor(level, compressibleTypes...)
+	// brotli yields considerably smaller responses than gzip at the same speed, so it's preferred by clients accepting both
+	compressor.SetEncoder("br", func(w io.Writer, level int) io.Writer {
+		return brotli.NewWriterLevel(w, level)
+	})
+	return func(h http.Handler) http.Handler {
+		return &CompressionMiddleware{
+			handler:    h,
+			compressed: compressor.Handler(h),
+		}
+	}
+}
+
+func (m *CompressionMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") || r.Header.Get("Upgrade") != "" {
+		m.handler.ServeHTTP(w, r)
+		return
+	}
+	m.compressed.ServeHTTP(w, r)
+}
@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressionMiddleware_ServeHTTP(t *testing.T) {
+	payload := strings.Repeat(`{"project":"wakapi"}`, 100)
+
+	sut := NewCompressionMiddleware(5)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(payload))
+	}))
+
+	serve := func(headers map[string]string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/summary", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		sut.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// brotli preferred over gzip
+	rec := serve(map[string]string{"Accept-Encoding": "gzip, deflate, br"})
+	assert.Equal(t, "br", rec.Header().Get("Content-Encoding"))
+	decoded, err := io.ReadAll(brotli.NewReader(rec.Body))
+	assert.Nil(t, err)
+	assert.Equal(t, payload, string(decoded))
+
+	rec = serve(map[string]string{"Accept-Encoding": "gzip"})
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+
+	rec = serve(map[string]string{"Accept-Encoding": "br", "Accept": "text/event-stream"})
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, payload, rec.Body.String())
+}
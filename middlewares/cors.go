@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	conf "github.com/muety/wakapi/config"
+)
+
+// response headers that cross-origin scripts may read, in addition to the cors-safelisted ones
+var corsExposedHeaders = []string{HeaderApiVersion, "Deprecation", "Sunset", "Retry-After"}
+
+// CorsMiddleware answers preflight requests and adds cors headers to responses for requests from allowed origins, as configured in server.cors
+// Requests from other origins are passed on unchanged, so browsers will block their responses
+type CorsMiddleware struct {
+	handler http.Handler
+	config  *conf.CorsConfig
+}
+
+func NewCorsMiddleware() func(http.Handler) http.Handler {
+	config := conf.Get().Server.Cors
+	return func(h http.Handler) http.Handler {
+		return &CorsMiddleware{handler: h, config: &config}
+	}
+}
+
+func (m *CorsMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !m.config.Enabled() {
+		m.handler.ServeHTTP(w, r)
+		return
+	}
+
+	origin := r.Header.Get("Origin")
+	isPreflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+	w.Header().Add("Vary", "Origin")
+	if origin == "" || !m.config.AllowsOrigin(origin) {
+		if isPreflight {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		m.handler.ServeHTTP(w, r)
+		return
+	}
+
+	if m.config.AllowsAnyOrigin() {
+		w.Header().Set("Access-Control-Allow-Origin", conf.CorsAnyOrigin)
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if m.config.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if isPreflight {
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(m.config.Methods(), ", "))
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(m.config.Headers(), ", "))
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(m.config.MaxAgeSec))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+	m.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorsMiddleware_ServeHTTP(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Server.Cors = config.CorsConfig{
+		AllowedOrigins:   "https://dashboard.example.org",
+		AllowedMethods:   "GET,POST",
+		AllowedHeaders:   "Authorization",
+		AllowCredentials: true,
+		MaxAgeSec:        600,
+	}
+	config.Set(cfg)
+
+	var called bool
+	sut := NewCorsMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	serve := func(method, origin string, preflight bool) *httptest.ResponseRecorder {
+		called = false
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/summary", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if preflight {
+			req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		}
+		sut.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodOptions, "https://dashboard.example.org", true)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.False(t, called)
+	assert.Equal(t, "https://dashboard.example.org", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", rec.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization", rec.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+
+	rec = serve(http.MethodGet, "https://dashboard.example.org", false)
+	assert.True(t, called)
+	assert.Equal(t, "https://dashboard.example.org", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, rec.Header().Get("Access-Control-Expose-Headers"), HeaderApiVersion)
+
+	rec = serve(http.MethodOptions, "https://evil.example.org", true)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.False(t, called)
+
+	rec = serve(http.MethodGet, "https://evil.example.org", false)
+	assert.True(t, called)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+	rec = serve(http.MethodGet, "", false)
+	assert.True(t, called)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCorsMiddleware_ServeHTTP_Disabled(t *testing.T) {
+	config.Set(config.Empty())
+
+	sut := NewCorsMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/summary", nil)
+	req.Header.Set("Origin", "https://dashboard.example.org")
+	sut.ServeHTTP(rec, req)
+
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, rec.Header().Get("Vary"))
+}
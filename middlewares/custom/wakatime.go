@@ -12,6 +12,7 @@ import (
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/models"
 	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/utils"
 	"github.com/patrickmn/go-cache"
 	"io"
 	"io/ioutil"
@@ -31,9 +32,7 @@ type WakatimeRelayMiddleware struct {
 
 func NewWakatimeRelayMiddleware() *WakatimeRelayMiddleware {
 	return &WakatimeRelayMiddleware{
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		httpClient:   config.NewOutboundClient(utils.OutboundPriorityHigh, 10*time.Second),
 		hashCache:    cache.New(10*time.Minute, 10*time.Minute),
 		failureCache: cache.New(24*time.Hour, 1*time.Hour),
 		eventBus:     config.EventBus(),
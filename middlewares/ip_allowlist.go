@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/utils"
+)
+
+// AdminIpAllowlistMiddleware rejects all requests not originating from one of the addresses configured as admin_ip_allowlist
+// Intended to guard the instance's management surface (admin endpoints, pprof, etc.), thus should be placed first in the chain
+type AdminIpAllowlistMiddleware struct {
+	handler http.Handler
+}
+
+func NewAdminIpAllowlistMiddleware() func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return &AdminIpAllowlistMiddleware{h}
+	}
+}
+
+func (m *AdminIpAllowlistMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !IsAdminIpAllowed(r) {
+		conf.Log().Request(r).Warn("rejecting management request from non-allowlisted ip %s", ClientIp(r))
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(conf.ErrForbidden))
+		return
+	}
+	m.handler.ServeHTTP(w, r)
+}
+
+// IsAdminIpAllowed tells whether the request's client may access management features
+func IsAdminIpAllowed(r *http.Request) bool {
+	return conf.Get().Security.IsAdminIpAllowed(ClientIp(r))
+}
+
+// ClientIp returns the request's originating ip address
+// other than ReadUserIP, forwarding headers are only respected if set by a trusted reverse proxy, as they could be spoofed otherwise
+func ClientIp(r *http.Request) string {
+	remoteIp := utils.ParseRemoteIp(r.RemoteAddr)
+	if !isTrustedProxyIp(remoteIp) {
+		return remoteIp
+	}
+
+	if realIp := strings.TrimSpace(r.Header.Get("X-Real-Ip")); realIp != "" {
+		return realIp
+	}
+
+	// each proxy appends the address it received the request from, so walk backwards until reaching the first non-trusted hop
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		if hop := strings.TrimSpace(forwarded[i]); hop != "" && !isTrustedProxyIp(hop) {
+			return hop
+		}
+	}
+	return remoteIp
+}
+
+func isTrustedProxyIp(ip string) bool {
+	parsedIp := net.ParseIP(ip)
+	if parsedIp == nil {
+		return false
+	}
+	for _, trustedIp := range conf.Get().Security.TrustReverseProxyIPs() {
+		if trustedIp.Equal(parsedIp) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminIpAllowlistMiddleware_ServeHTTP(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Security.AdminIpAllowlist = "10.0.0.0/8,::1"
+	cfg.Security.TrustReverseProxyIps = "127.0.0.1"
+	cfg.Security.ParseTrustReverseProxyIPs()
+	assert.Nil(t, cfg.Security.ParseAdminIpAllowlist())
+	config.Set(cfg)
+
+	var called bool
+	sut := NewAdminIpAllowlistMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	serve := func(remoteAddr string, headers map[string]string) int {
+		called = false
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/stats", nil)
+		req.RemoteAddr = remoteAddr
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		sut.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("10.1.2.3:54654", nil))
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, serve("[::1]:54654", nil))
+	assert.True(t, called)
+
+	assert.Equal(t, http.StatusForbidden, serve("203.0.113.7:54654", nil))
+	assert.False(t, called)
+
+	// forwarding headers are only respected from trusted proxies
+	assert.Equal(t, http.StatusForbidden, serve("203.0.113.7:54654", map[string]string{"X-Forwarded-For": "10.1.2.3"}))
+	assert.Equal(t, http.StatusOK, serve("127.0.0.1:54654", map[string]string{"X-Forwarded-For": "10.1.2.3"}))
+	assert.Equal(t, http.StatusForbidden, serve("127.0.0.1:54654", map[string]string{"X-Forwarded-For": "10.1.2.3, 203.0.113.7"}))
+	assert.Equal(t, http.StatusForbidden, serve("127.0.0.1:54654", nil))
+}
@@ -9,7 +9,8 @@ import (
 const keyPrincipal = "principal"
 
 type PrincipalContainer struct {
-	principal *models.User
+	principal   *models.User
+	accessToken *models.AccessToken // only set if authenticated by a scoped access token
 }
 
 func (c *PrincipalContainer) SetPrincipal(user *models.User) {
@@ -20,6 +21,14 @@ func (c *PrincipalContainer) GetPrincipal() *models.User {
 	return c.principal
 }
 
+func (c *PrincipalContainer) SetAccessToken(token *models.AccessToken) {
+	c.accessToken = token
+}
+
+func (c *PrincipalContainer) GetAccessToken() *models.AccessToken {
+	return c.accessToken
+}
+
 func (c *PrincipalContainer) GetPrincipalIdentity() string {
 	if c.principal == nil {
 		return ""
@@ -69,3 +78,19 @@ func GetPrincipal(r *http.Request) *models.User {
 	}
 	return nil
 }
+
+func SetAccessToken(r *http.Request, token *models.AccessToken) {
+	if p := r.Context().Value(keyPrincipal); p != nil {
+		p.(*PrincipalContainer).SetAccessToken(token)
+	}
+}
+
+// HasScope tells whether the request may act within the given scope, which is always the case, unless it was authenticated by a scoped access token
+func HasScope(r *http.Request, scope string) bool {
+	if p := r.Context().Value(keyPrincipal); p != nil {
+		if token := p.(*PrincipalContainer).GetAccessToken(); token != nil {
+			return token.HasScope(scope)
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/muety/wakapi/services"
+)
+
+const savedFilterParam = "filter"
+
+// SavedFilterMiddleware expands the "filter" query parameter, which refers to one of the authenticated user's saved filters by name, into the filter parameters stored with it
+// further filter parameters of the request are kept, i.e. all of them have to match. Requires the principal to be set, i.e. has to run after authentication.
+type SavedFilterMiddleware struct {
+	handler         http.Handler
+	savedFilterSrvc services.ISavedFilterService
+}
+
+func NewSavedFilterMiddleware(savedFilterService services.ISavedFilterService) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return &SavedFilterMiddleware{handler: h, savedFilterSrvc: savedFilterService}
+	}
+}
+
+func (m *SavedFilterMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	name := query.Get(savedFilterParam)
+	user := GetPrincipal(r)
+	if name == "" || user == nil {
+		m.handler.ServeHTTP(w, r)
+		return
+	}
+
+	savedFilter, err := m.savedFilterSrvc.GetByUserAndName(user, name)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("saved filter not found"))
+		return
+	}
+
+	savedQuery, err := url.ParseQuery(savedFilter.Query)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("malformed saved filter"))
+		return
+	}
+
+	query.Del(savedFilterParam)
+	for key, values := range savedQuery {
+		for _, v := range values {
+			query.Add(key, v)
+		}
+	}
+
+	r = r.Clone(r.Context())
+	r.URL.RawQuery = query.Encode()
+	m.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSavedFilterMiddleware_ServeHTTP(t *testing.T) {
+	user := &models.User{ID: "user1"}
+
+	savedFilterServiceMock := new(mocks.SavedFilterServiceMock)
+	savedFilterServiceMock.On("GetByUserAndName", user, "work").Return(&models.SavedFilter{UserID: user.ID, Name: "work", Query: "project=wakapi,anchr&branch!=main"}, nil)
+	savedFilterServiceMock.On("GetByUserAndName", user, "unknown").Return((*models.SavedFilter)(nil), errors.New("saved filter not found"))
+
+	var query map[string][]string
+	sut := NewSavedFilterMiddleware(savedFilterServiceMock)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+	}))
+
+	serve := func(url string) int {
+		query = nil
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		NewPrincipalMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			SetPrincipal(r, user)
+			sut.ServeHTTP(w, r)
+		})).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/api/summary?interval=week&filter=work&language=Go"))
+	assert.Equal(t, []string{"week"}, query["interval"])
+	assert.Equal(t, []string{"Go"}, query["language"])
+	assert.Equal(t, []string{"wakapi,anchr"}, query["project"])
+	assert.Equal(t, []string{"main"}, query["branch!"])
+	assert.NotContains(t, query, "filter")
+
+	assert.Equal(t, http.StatusOK, serve("/api/summary?interval=week"))
+	assert.Equal(t, []string{"week"}, query["interval"])
+
+	assert.Equal(t, http.StatusNotFound, serve("/api/summary?interval=week&filter=unknown"))
+	assert.Nil(t, query)
+}
@@ -2,31 +2,92 @@ package middlewares
 
 import (
 	"net/http"
+	"strings"
+
+	conf "github.com/muety/wakapi/config"
 )
 
 var securityHeaders = map[string]string{
 	"Cross-Origin-Opener-Policy": "same-origin",
-	"Content-Security-Policy":    "default-src 'self' 'unsafe-inline' 'unsafe-eval'; img-src 'self' https: data:; form-action 'self' *.stripe.com; block-all-mixed-content;",
+	"Content-Security-Policy":    "default-src 'self' 'unsafe-inline' 'unsafe-eval'; img-src 'self' https: data:; form-action 'self' *.stripe.com *.paypal.com; block-all-mixed-content;",
 	"X-Frame-Options":            "DENY",
 	"X-Content-Type-Options":     "nosniff",
+	"Referrer-Policy":            "strict-origin-when-cross-origin",
+}
+
+// embeddableSecurityHeaders are sent along with resources intended to be included in third-party sites (badges, charts), thus must neither deny framing nor cross-origin loading
+var embeddableSecurityHeaders = map[string]string{
+	"Content-Security-Policy":      "default-src 'none'; style-src 'unsafe-inline'; img-src data:;",
+	"Cross-Origin-Resource-Policy": "cross-origin",
+	"X-Content-Type-Options":       "nosniff",
+	"Referrer-Policy":              "strict-origin-when-cross-origin",
 }
 
 // SecurityMiddleware is a handler to add some basic security headers to responses
 type SecurityMiddleware struct {
 	handler http.Handler
+	headers map[string]string
 }
 
 func NewSecurityMiddleware() func(http.Handler) http.Handler {
+	config := conf.Get().Security
+	headers := copyHeaders(securityHeaders)
+
+	// captcha widgets load their scripts, frames and styles from the provider
+	if origins := config.Captcha.Origins(); len(origins) > 0 {
+		headers["Content-Security-Policy"] = strings.Replace(headers["Content-Security-Policy"], "default-src 'self'", "default-src 'self' "+strings.Join(origins, " "), 1)
+	}
+
+	return newSecurityMiddleware(headers)
+}
+
+// NewEmbeddableSecurityMiddleware adds relaxed security headers for resources that may be embedded elsewhere, where framing is restricted to embed_frame_ancestors
+func NewEmbeddableSecurityMiddleware() func(http.Handler) http.Handler {
+	config := conf.Get().Security
+	headers := copyHeaders(embeddableSecurityHeaders)
+	if ancestors := strings.TrimSpace(config.Headers.EmbedFrameAncestors); ancestors != "" {
+		headers["Content-Security-Policy"] += " frame-ancestors " + ancestors + ";"
+	}
+	return newSecurityMiddleware(headers)
+}
+
+// newSecurityMiddleware applies the hsts and override settings on top of the given headers, which are dropped altogether if disabled
+func newSecurityMiddleware(headers map[string]string) func(http.Handler) http.Handler {
+	config := conf.Get().Security.Headers
+	if !config.Enabled {
+		headers = map[string]string{}
+	}
+
+	if hsts := config.HstsHeader(); hsts != "" {
+		headers["Strict-Transport-Security"] = hsts
+	}
+	for k, v := range config.Overrides {
+		k = http.CanonicalHeaderKey(k)
+		if v == "" {
+			delete(headers, k)
+		} else {
+			headers[k] = v
+		}
+	}
+
 	return func(h http.Handler) http.Handler {
-		return &SecurityMiddleware{h}
+		return &SecurityMiddleware{handler: h, headers: headers}
 	}
 }
 
 func (f *SecurityMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	for k, v := range securityHeaders {
+	for k, v := range f.headers {
 		if w.Header().Get(k) == "" {
 			w.Header().Set(k, v)
 		}
 	}
 	f.handler.ServeHTTP(w, r)
 }
+
+func copyHeaders(headers map[string]string) map[string]string {
+	copied := make(map[string]string, len(headers))
+	for k, v := range headers {
+		copied[k] = v
+	}
+	return copied
+}
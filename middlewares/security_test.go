@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityMiddleware_ServeHTTP(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Security.Headers.Enabled = true
+	cfg.Security.Headers.HstsMaxAgeSec = 31536000
+	cfg.Security.Headers.Overrides = map[string]string{
+		"x-frame-options":    "SAMEORIGIN",
+		"Referrer-Policy":    "",
+		"Permissions-Policy": "geolocation=()",
+	}
+	config.Set(cfg)
+
+	rec := serveSecurityMiddleware(NewSecurityMiddleware())
+	assert.Equal(t, "SAMEORIGIN", rec.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "max-age=31536000", rec.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "geolocation=()", rec.Header().Get("Permissions-Policy"))
+	assert.Empty(t, rec.Header().Get("Referrer-Policy"))
+
+	cfg.Security.Headers = config.Empty().Security.Headers
+	rec = serveSecurityMiddleware(NewSecurityMiddleware())
+	assert.Empty(t, rec.Header().Get("X-Frame-Options"))
+	assert.Empty(t, rec.Header().Get("Content-Security-Policy"))
+}
+
+func TestEmbeddableSecurityMiddleware_ServeHTTP(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Security.Headers.Enabled = true
+	cfg.Security.Headers.EmbedFrameAncestors = "https://blog.example.org"
+	config.Set(cfg)
+
+	rec := serveSecurityMiddleware(NewEmbeddableSecurityMiddleware())
+	assert.Empty(t, rec.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "cross-origin", rec.Header().Get("Cross-Origin-Resource-Policy"))
+	assert.Contains(t, rec.Header().Get("Content-Security-Policy"), "frame-ancestors https://blog.example.org;")
+}
+
+func serveSecurityMiddleware(middleware func(http.Handler) http.Handler) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+	return rec
+}
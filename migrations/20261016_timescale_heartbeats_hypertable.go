@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"gorm.io/gorm"
+)
+
+const timescaleChunkInterval = "7 days"
+
+// converts the heartbeats table into a timescale hypertable, partitioned by time, if the timescaledb extension is available (or explicitly enabled) on postgres
+// timescale requires all unique constraints to include the partitioning column, so primary key and hash index are extended by the time column beforehand
+func init() {
+	const name = "20261016-timescale_heartbeats_hypertable"
+	f := migrationFunc{
+		name: name,
+		f: func(db *gorm.DB, cfg *config.Config) error {
+			if !cfg.Db.IsPostgres() {
+				return nil
+			}
+
+			var installed int64
+			if err := db.Raw("SELECT count(*) FROM pg_extension WHERE extname = 'timescaledb'").Scan(&installed).Error; err != nil {
+				return err
+			}
+
+			if installed == 0 {
+				if !cfg.Db.Timescale {
+					return nil
+				}
+				if err := db.Exec("CREATE EXTENSION IF NOT EXISTS timescaledb").Error; err != nil {
+					return fmt.Errorf("failed to create timescaledb extension - %v", err)
+				}
+			}
+
+			// remember for data cleanup to drop chunks instead of deleting single rows
+			cfg.Db.Timescale = true
+
+			var isHypertable int64
+			if err := db.Raw("SELECT count(*) FROM timescaledb_information.hypertables WHERE hypertable_name = 'heartbeats'").Scan(&isHypertable).Error; err != nil {
+				return err
+			}
+			if isHypertable > 0 {
+				logbuch.Info("no need to migrate '%s'", name)
+				return nil
+			}
+
+			logbuch.Info("converting heartbeats table to timescale hypertable, this may take a while (up to hours)")
+
+			return db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec("ALTER TABLE heartbeats DROP CONSTRAINT IF EXISTS heartbeats_pkey").Error; err != nil {
+					return err
+				}
+				if err := tx.Exec("ALTER TABLE heartbeats ADD PRIMARY KEY (id, time)").Error; err != nil {
+					return err
+				}
+				// keeping the index name, so auto migration won't attempt to recreate the original one
+				if err := tx.Exec("DROP INDEX IF EXISTS idx_heartbeats_hash").Error; err != nil {
+					return err
+				}
+				if err := tx.Exec("CREATE UNIQUE INDEX idx_heartbeats_hash ON heartbeats (hash, time)").Error; err != nil {
+					return err
+				}
+				if err := tx.Exec(fmt.Sprintf("SELECT create_hypertable('heartbeats', 'time', chunk_time_interval => INTERVAL '%s', migrate_data => true)", timescaleChunkInterval)).Error; err != nil {
+					return err
+				}
+				logbuch.Info("migrated heartbeats table to timescale hypertable")
+				return nil
+			})
+		},
+	}
+
+	registerPostMigration(f)
+}
@@ -0,0 +1,112 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"gorm.io/gorm"
+)
+
+const heartbeatPartitionsAhead = 3 // number of months to create partitions for in advance
+
+// re-creates the heartbeats table as a table partitioned by month (postgres only), if enabled
+// postgres can't convert an existing table, so data is copied over to a new one, after which indexes are recreated
+// all unique constraints of a partitioned table must include the partitioning column, so primary key and hash index are extended by the time column
+func init() {
+	const name = "20261017-heartbeats_monthly_partitions"
+	f := migrationFunc{
+		name: name,
+		f: func(db *gorm.DB, cfg *config.Config) error {
+			if !cfg.Db.IsPartitioned() {
+				if cfg.Db.Partitioning && cfg.Db.Timescale {
+					logbuch.Warn("not partitioning heartbeats, because they're managed by timescale already")
+				}
+				return nil
+			}
+
+			var isPartitioned int64
+			if err := db.Raw("SELECT count(*) FROM pg_partitioned_table pt JOIN pg_class c ON c.oid = pt.partrelid WHERE c.relname = 'heartbeats'").Scan(&isPartitioned).Error; err != nil {
+				return err
+			}
+			if isPartitioned > 0 {
+				logbuch.Info("no need to migrate '%s'", name)
+				return nil
+			}
+
+			logbuch.Info("converting heartbeats table to partitioned table, this may take a while (up to hours)")
+
+			return db.Transaction(func(tx *gorm.DB) error {
+				var oldest *time.Time
+				if err := tx.Raw("SELECT min(time) FROM heartbeats").Scan(&oldest).Error; err != nil {
+					return err
+				}
+				var sequence *string
+				if err := tx.Raw("SELECT pg_get_serial_sequence('heartbeats', 'id')").Scan(&sequence).Error; err != nil {
+					return err
+				}
+
+				if err := tx.Exec("ALTER TABLE heartbeats RENAME TO heartbeats_unpartitioned").Error; err != nil {
+					return err
+				}
+				if err := tx.Exec("CREATE TABLE heartbeats (LIKE heartbeats_unpartitioned INCLUDING DEFAULTS INCLUDING STORAGE) PARTITION BY RANGE (time)").Error; err != nil {
+					return err
+				}
+				if sequence != nil {
+					if err := tx.Exec(fmt.Sprintf("ALTER SEQUENCE %s OWNED BY heartbeats.id", *sequence)).Error; err != nil {
+						return err
+					}
+				}
+
+				from := time.Now()
+				if oldest != nil {
+					from = *oldest
+				}
+				if err := tx.Exec(fmt.Sprintf("CREATE TABLE %s PARTITION OF heartbeats DEFAULT", repositories.HeartbeatDefaultPartition)).Error; err != nil {
+					return err
+				}
+				if err := repositories.NewHeartbeatRepository(tx).CreatePartitions(from, time.Now().AddDate(0, heartbeatPartitionsAhead, 0)); err != nil {
+					return err
+				}
+
+				if err := tx.Exec("INSERT INTO heartbeats SELECT * FROM heartbeats_unpartitioned").Error; err != nil {
+					return err
+				}
+				if err := tx.Exec("DROP TABLE heartbeats_unpartitioned").Error; err != nil {
+					return err
+				}
+
+				// recreate keys, indexes and constraints, using the same names as auto migration, so it won't attempt to create them again
+				if err := tx.Exec("ALTER TABLE heartbeats ADD PRIMARY KEY (id, time)").Error; err != nil {
+					return err
+				}
+				stmt := &gorm.Statement{DB: tx}
+				if err := stmt.Parse(&models.Heartbeat{}); err != nil {
+					return err
+				}
+				for _, idx := range stmt.Schema.ParseIndexes() {
+					if idx.Class == "UNIQUE" {
+						if err := tx.Exec(fmt.Sprintf("CREATE UNIQUE INDEX %s ON heartbeats (%s, time)", idx.Name, idx.Fields[0].DBName)).Error; err != nil {
+							return err
+						}
+						continue
+					}
+					if err := tx.Migrator().CreateIndex(&models.Heartbeat{}, idx.Name); err != nil {
+						return err
+					}
+				}
+				if err := tx.Migrator().CreateConstraint(&models.Heartbeat{}, "User"); err != nil {
+					return err
+				}
+
+				logbuch.Info("migrated heartbeats table to monthly partitions")
+				return nil
+			})
+		},
+	}
+
+	registerPostMigration(f)
+}
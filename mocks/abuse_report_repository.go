@@ -0,0 +1,41 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type AbuseReportRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *AbuseReportRepositoryMock) GetById(id uint) (*models.AbuseReport, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.AbuseReport), args.Error(1)
+}
+
+func (m *AbuseReportRepositoryMock) GetByStatus(s string) ([]*models.AbuseReport, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.AbuseReport), args.Error(1)
+}
+
+func (m *AbuseReportRepositoryMock) CountByReporterAfter(s string, t time.Time) (int64, error) {
+	args := m.Called(s, t)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *AbuseReportRepositoryMock) CountOpenByReporterAndTarget(report *models.AbuseReport) (int64, error) {
+	args := m.Called(report)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *AbuseReportRepositoryMock) Insert(report *models.AbuseReport) (*models.AbuseReport, error) {
+	args := m.Called(report)
+	return args.Get(0).(*models.AbuseReport), args.Error(1)
+}
+
+func (m *AbuseReportRepositoryMock) UpdateStatus(report *models.AbuseReport, s string) (*models.AbuseReport, error) {
+	args := m.Called(report, s)
+	return args.Get(0).(*models.AbuseReport), args.Error(1)
+}
@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type AbuseReportServiceMock struct {
+	mock.Mock
+}
+
+func (m *AbuseReportServiceMock) GetById(id uint) (*models.AbuseReport, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.AbuseReport), args.Error(1)
+}
+
+func (m *AbuseReportServiceMock) GetOpen() ([]*models.AbuseReport, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.AbuseReport), args.Error(1)
+}
+
+func (m *AbuseReportServiceMock) Create(report *models.AbuseReport) (*models.AbuseReport, error) {
+	args := m.Called(report)
+	return args.Get(0).(*models.AbuseReport), args.Error(1)
+}
+
+func (m *AbuseReportServiceMock) Resolve(report *models.AbuseReport, resolution *models.AbuseReportResolution) (*models.AbuseReport, error) {
+	args := m.Called(report, resolution)
+	return args.Get(0).(*models.AbuseReport), args.Error(1)
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type AccessTokenRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *AccessTokenRepositoryMock) GetByHash(s string) (*models.AccessToken, error) {
+	args := m.Called(s)
+	return args.Get(0).(*models.AccessToken), args.Error(1)
+}
+
+func (m *AccessTokenRepositoryMock) GetByUser(s string) ([]*models.AccessToken, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.AccessToken), args.Error(1)
+}
+
+func (m *AccessTokenRepositoryMock) Insert(t *models.AccessToken) (*models.AccessToken, error) {
+	args := m.Called(t)
+	return args.Get(0).(*models.AccessToken), args.Error(1)
+}
+
+func (m *AccessTokenRepositoryMock) UpdateLastUsed(t *models.AccessToken) error {
+	args := m.Called(t)
+	return args.Error(0)
+}
+
+func (m *AccessTokenRepositoryMock) DeleteByUserAndId(s string, id uint) error {
+	args := m.Called(s, id)
+	return args.Error(0)
+}
@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type AccessTokenServiceMock struct {
+	mock.Mock
+}
+
+func (m *AccessTokenServiceMock) Create(user *models.User, name, scope string) (string, *models.AccessToken, error) {
+	args := m.Called(user, name, scope)
+	return args.String(0), args.Get(1).(*models.AccessToken), args.Error(2)
+}
+
+func (m *AccessTokenServiceMock) GetByToken(s string) (*models.AccessToken, error) {
+	args := m.Called(s)
+	return args.Get(0).(*models.AccessToken), args.Error(1)
+}
+
+func (m *AccessTokenServiceMock) GetByUser(user *models.User) ([]*models.AccessToken, error) {
+	args := m.Called(user)
+	return args.Get(0).([]*models.AccessToken), args.Error(1)
+}
+
+func (m *AccessTokenServiceMock) Delete(user *models.User, id uint) error {
+	args := m.Called(user, id)
+	return args.Error(0)
+}
@@ -0,0 +1,46 @@
+package mocks
+
+import (
+	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type AggregationServiceMock struct {
+	mock.Mock
+}
+
+func (m *AggregationServiceMock) Schedule() {
+	m.Called()
+}
+
+func (m *AggregationServiceMock) AggregateSummaries(set datastructure.Set[string]) error {
+	args := m.Called(set)
+	return args.Error(0)
+}
+
+func (m *AggregationServiceMock) RegenerateSummaries(user *models.User, from, to time.Time) error {
+	args := m.Called(user, from, to)
+	return args.Error(0)
+}
+
+func (m *AggregationServiceMock) ScheduleRegeneration(user *models.User, from, to time.Time) error {
+	args := m.Called(user, from, to)
+	return args.Error(0)
+}
+
+func (m *AggregationServiceMock) ScheduleBackfill(req *models.AggregationBackfillRequest) (*models.AggregationBackfillJob, error) {
+	args := m.Called(req)
+	return args.Get(0).(*models.AggregationBackfillJob), args.Error(1)
+}
+
+func (m *AggregationServiceMock) Backfill(req *models.AggregationBackfillRequest) (*models.AggregationBackfillJob, error) {
+	args := m.Called(req)
+	return args.Get(0).(*models.AggregationBackfillJob), args.Error(1)
+}
+
+func (m *AggregationServiceMock) GetBackfillJob() *models.AggregationBackfillJob {
+	args := m.Called()
+	return args.Get(0).(*models.AggregationBackfillJob)
+}
@@ -0,0 +1,32 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type AnnotationServiceMock struct {
+	mock.Mock
+}
+
+func (m *AnnotationServiceMock) GetById(id uint) (*models.Annotation, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.Annotation), args.Error(1)
+}
+
+func (m *AnnotationServiceMock) GetByUserWithin(user *models.User, from, to time.Time) ([]*models.Annotation, error) {
+	args := m.Called(user, from, to)
+	return args.Get(0).([]*models.Annotation), args.Error(1)
+}
+
+func (m *AnnotationServiceMock) Create(a *models.Annotation) (*models.Annotation, error) {
+	args := m.Called(a)
+	return args.Get(0).(*models.Annotation), args.Error(1)
+}
+
+func (m *AnnotationServiceMock) Delete(a *models.Annotation) error {
+	args := m.Called(a)
+	return args.Error(0)
+}
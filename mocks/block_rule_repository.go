@@ -0,0 +1,37 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type BlockRuleRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *BlockRuleRepositoryMock) GetAll() ([]*models.BlockRule, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.BlockRule), args.Error(1)
+}
+
+func (m *BlockRuleRepositoryMock) GetById(id uint) (*models.BlockRule, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.BlockRule), args.Error(1)
+}
+
+func (m *BlockRuleRepositoryMock) Insert(r *models.BlockRule) (*models.BlockRule, error) {
+	args := m.Called(r)
+	return args.Get(0).(*models.BlockRule), args.Error(1)
+}
+
+func (m *BlockRuleRepositoryMock) IncrementDropped(id uint, n int64, at time.Time) error {
+	args := m.Called(id, n, at)
+	return args.Error(0)
+}
+
+func (m *BlockRuleRepositoryMock) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
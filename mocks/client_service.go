@@ -0,0 +1,40 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type ClientServiceMock struct {
+	mock.Mock
+}
+
+func (m *ClientServiceMock) GetByUser(userId string) ([]*models.Client, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]*models.Client), args.Error(1)
+}
+
+func (m *ClientServiceMock) GetByUserAndId(userId string, id uint) (*models.Client, error) {
+	args := m.Called(userId, id)
+	return args.Get(0).(*models.Client), args.Error(1)
+}
+
+func (m *ClientServiceMock) GetClientsByProject(userId string) (map[string]string, error) {
+	args := m.Called(userId)
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+func (m *ClientServiceMock) Create(client *models.Client) (*models.Client, error) {
+	args := m.Called(client)
+	return args.Get(0).(*models.Client), args.Error(1)
+}
+
+func (m *ClientServiceMock) Update(client *models.Client) (*models.Client, error) {
+	args := m.Called(client)
+	return args.Get(0).(*models.Client), args.Error(1)
+}
+
+func (m *ClientServiceMock) Delete(client *models.Client) error {
+	args := m.Called(client)
+	return args.Error(0)
+}
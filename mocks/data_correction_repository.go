@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type DataCorrectionRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *DataCorrectionRepositoryMock) GetById(id uint) (*models.DataCorrection, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.DataCorrection), args.Error(1)
+}
+
+func (m *DataCorrectionRepositoryMock) GetByUser(s string) ([]*models.DataCorrection, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.DataCorrection), args.Error(1)
+}
+
+func (m *DataCorrectionRepositoryMock) GetByStatus(s string) ([]*models.DataCorrection, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.DataCorrection), args.Error(1)
+}
+
+func (m *DataCorrectionRepositoryMock) Insert(correction *models.DataCorrection) (*models.DataCorrection, error) {
+	args := m.Called(correction)
+	return args.Get(0).(*models.DataCorrection), args.Error(1)
+}
+
+func (m *DataCorrectionRepositoryMock) Update(correction *models.DataCorrection) (*models.DataCorrection, error) {
+	args := m.Called(correction)
+	return args.Get(0).(*models.DataCorrection), args.Error(1)
+}
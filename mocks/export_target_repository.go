@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type ExportTargetRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *ExportTargetRepositoryMock) GetAll() ([]*models.ExportTarget, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.ExportTarget), args.Error(1)
+}
+
+func (m *ExportTargetRepositoryMock) GetByUser(userId string) (*models.ExportTarget, error) {
+	args := m.Called(userId)
+	return args.Get(0).(*models.ExportTarget), args.Error(1)
+}
+
+func (m *ExportTargetRepositoryMock) Upsert(target *models.ExportTarget) (*models.ExportTarget, error) {
+	args := m.Called(target)
+	return args.Get(0).(*models.ExportTarget), args.Error(1)
+}
+
+func (m *ExportTargetRepositoryMock) UpdateStatus(target *models.ExportTarget) error {
+	args := m.Called(target)
+	return args.Error(0)
+}
+
+func (m *ExportTargetRepositoryMock) DeleteByUser(userId string) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
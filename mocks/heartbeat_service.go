@@ -21,6 +21,20 @@ func (m *HeartbeatServiceMock) InsertBatch(h []*models.Heartbeat) error {
 	return args.Error(0)
 }
 
+// FilterBlocked returns either the configured heartbeats or the result of the configured filter function
+func (m *HeartbeatServiceMock) FilterBlocked(h []*models.Heartbeat) []*models.Heartbeat {
+	args := m.Called(h)
+	if filter, ok := args.Get(0).(func([]*models.Heartbeat) []*models.Heartbeat); ok {
+		return filter(h)
+	}
+	return args.Get(0).([]*models.Heartbeat)
+}
+
+func (m *HeartbeatServiceMock) GetExistingHashes(hashes []string) ([]string, error) {
+	args := m.Called(hashes)
+	return args.Get(0).([]string), args.Error(1)
+}
+
 func (m *HeartbeatServiceMock) Count(a bool) (int64, error) {
 	args := m.Called(a)
 	return int64(args.Int(0)), args.Error(1)
@@ -28,7 +42,7 @@ func (m *HeartbeatServiceMock) Count(a bool) (int64, error) {
 
 func (m *HeartbeatServiceMock) CountByUser(user *models.User) (int64, error) {
 	args := m.Called(user)
-	return args.Get(0).(int64), args.Error(0)
+	return args.Get(0).(int64), args.Error(1)
 }
 
 func (m *HeartbeatServiceMock) CountByUsers(users []*models.User) ([]*models.CountByUser, error) {
@@ -46,6 +60,11 @@ func (m *HeartbeatServiceMock) GetAllWithinByFilters(time time.Time, time2 time.
 	return args.Get(0).([]*models.Heartbeat), args.Error(1)
 }
 
+func (m *HeartbeatServiceMock) GetPageByUser(user *models.User, afterId uint64, limit int) ([]*models.Heartbeat, error) {
+	args := m.Called(user, afterId, limit)
+	return args.Get(0).([]*models.Heartbeat), args.Error(1)
+}
+
 func (m *HeartbeatServiceMock) GetFirstByUsers() ([]*models.TimeByUser, error) {
 	args := m.Called()
 	return args.Get(0).([]*models.TimeByUser), args.Error(1)
@@ -66,6 +85,16 @@ func (m *HeartbeatServiceMock) GetLatestByFilters(u *models.User, f *models.Filt
 	return args.Get(0).(*models.Heartbeat), args.Error(1)
 }
 
+func (m *HeartbeatServiceMock) GetByUserAndId(u *models.User, id uint64) (*models.Heartbeat, error) {
+	args := m.Called(u, id)
+	return args.Get(0).(*models.Heartbeat), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) GetTimeRangeByUserAndProjects(u *models.User, projects []string) (*models.HeartbeatTimeRange, error) {
+	args := m.Called(u, projects)
+	return args.Get(0).(*models.HeartbeatTimeRange), args.Error(1)
+}
+
 func (m *HeartbeatServiceMock) GetEntitySetByUser(u uint8, user string) ([]string, error) {
 	args := m.Called(u, user)
 	return args.Get(0).([]string), args.Error(1)
@@ -81,12 +110,92 @@ func (m *HeartbeatServiceMock) DeleteByUser(u *models.User) error {
 	return args.Error(0)
 }
 
+func (m *HeartbeatServiceMock) CountByUsersBefore(t time.Time) ([]*models.CountByUser, error) {
+	args := m.Called(t)
+	return args.Get(0).([]*models.CountByUser), args.Error(1)
+}
+
 func (m *HeartbeatServiceMock) DeleteByUserBefore(u *models.User, t time.Time) error {
 	args := m.Called(u, t)
 	return args.Error(0)
 }
 
+func (m *HeartbeatServiceMock) AnonymizeByUserBefore(u *models.User, t time.Time) (int64, error) {
+	args := m.Called(u, t)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) DropChunksBefore(t time.Time) error {
+	args := m.Called(t)
+	return args.Error(0)
+}
+
+func (m *HeartbeatServiceMock) CreatePartitions(t1, t2 time.Time) error {
+	args := m.Called(t1, t2)
+	return args.Error(0)
+}
+
+func (m *HeartbeatServiceMock) DropPartitionsBefore(t time.Time) error {
+	args := m.Called(t)
+	return args.Error(0)
+}
+
+func (m *HeartbeatServiceMock) RenameProjectByUser(u *models.User, p1 string, p2 string) (int64, error) {
+	args := m.Called(u, p1, p2)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) RenameProjectByUserWithin(u *models.User, from, to time.Time, p1 string, p2 string) (int64, error) {
+	args := m.Called(u, from, to, p1, p2)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) GetDistinctByUser(u *models.User, columns []string, offset, limit int) ([]*models.Heartbeat, error) {
+	args := m.Called(u, columns, offset, limit)
+	return args.Get(0).([]*models.Heartbeat), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) ReprocessLanguagesByUser(u *models.User) (int64, error) {
+	args := m.Called(u)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) DeleteByUserAndId(u *models.User, id uint64) error {
+	args := m.Called(u, id)
+	return args.Error(0)
+}
+
+func (m *HeartbeatServiceMock) Update(h *models.Heartbeat) error {
+	args := m.Called(h)
+	return args.Error(0)
+}
+
+func (m *HeartbeatServiceMock) UpdateBatch(h []*models.Heartbeat) error {
+	args := m.Called(h)
+	return args.Error(0)
+}
+
+func (m *HeartbeatServiceMock) DeleteByUserWithinByFilters(u *models.User, t1 time.Time, t2 time.Time, f *models.Filters, p string) (int64, error) {
+	args := m.Called(u, t1, t2, f, p)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) CountByUserWithinByFilters(u *models.User, t1 time.Time, t2 time.Time, f *models.Filters, p string) (int64, error) {
+	args := m.Called(u, t1, t2, f, p)
+	return int64(args.Int(0)), args.Error(1)
+}
+
 func (m *HeartbeatServiceMock) GetUserProjectStats(u *models.User, t, t2 time.Time, p *utils.PageParams, b bool) ([]*models.ProjectStats, error) {
 	args := m.Called(u, t, t2, p, b)
 	return args.Get(0).([]*models.ProjectStats), args.Error(1)
 }
+
+func (m *HeartbeatServiceMock) GetUserAgentsByUser(u *models.User) ([]*models.UserAgentStats, error) {
+	args := m.Called(u)
+	return args.Get(0).([]*models.UserAgentStats), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) GetPluginVersionStats(t time.Time) ([]*models.PluginVersionStats, error) {
+	args := m.Called(t)
+	return args.Get(0).([]*models.PluginVersionStats), args.Error(1)
+}
@@ -0,0 +1,16 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type JobLockRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *JobLockRepositoryMock) TryAcquire(name, holder string, until time.Time) (bool, error) {
+	args := m.Called(name, holder, until)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type KeyValueServiceMock struct {
+	mock.Mock
+}
+
+func (m *KeyValueServiceMock) GetString(key string) (*models.KeyStringValue, error) {
+	args := m.Called(key)
+	return args.Get(0).(*models.KeyStringValue), args.Error(1)
+}
+
+func (m *KeyValueServiceMock) MustGetString(key string) *models.KeyStringValue {
+	args := m.Called(key)
+	return args.Get(0).(*models.KeyStringValue)
+}
+
+func (m *KeyValueServiceMock) GetByPrefix(prefix string) ([]*models.KeyStringValue, error) {
+	args := m.Called(prefix)
+	return args.Get(0).([]*models.KeyStringValue), args.Error(1)
+}
+
+func (m *KeyValueServiceMock) PutString(kv *models.KeyStringValue) error {
+	args := m.Called(kv)
+	return args.Error(0)
+}
+
+func (m *KeyValueServiceMock) DeleteString(key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type LabelRuleRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *LabelRuleRepositoryMock) GetById(id uint) (*models.LabelRule, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.LabelRule), args.Error(1)
+}
+
+func (m *LabelRuleRepositoryMock) GetByUser(s string) ([]*models.LabelRule, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.LabelRule), args.Error(1)
+}
+
+func (m *LabelRuleRepositoryMock) Insert(r *models.LabelRule) (*models.LabelRule, error) {
+	args := m.Called(r)
+	return args.Get(0).(*models.LabelRule), args.Error(1)
+}
+
+func (m *LabelRuleRepositoryMock) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
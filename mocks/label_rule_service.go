@@ -0,0 +1,44 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type LabelRuleServiceMock struct {
+	mock.Mock
+}
+
+func (m *LabelRuleServiceMock) GetById(id uint) (*models.LabelRule, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.LabelRule), args.Error(1)
+}
+
+func (m *LabelRuleServiceMock) GetByUser(s string) ([]*models.LabelRule, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.LabelRule), args.Error(1)
+}
+
+func (m *LabelRuleServiceMock) Create(r *models.LabelRule) (*models.LabelRule, error) {
+	args := m.Called(r)
+	return args.Get(0).(*models.LabelRule), args.Error(1)
+}
+
+func (m *LabelRuleServiceMock) Delete(r *models.LabelRule) error {
+	args := m.Called(r)
+	return args.Error(0)
+}
+
+func (m *LabelRuleServiceMock) Backfill(user *models.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *LabelRuleServiceMock) ApplyToExisting(user *models.User) (int, error) {
+	args := m.Called(user)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *LabelRuleServiceMock) FlushUserCache(s string) {
+	m.Called(s)
+}
@@ -0,0 +1,40 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type LanguageMappingServiceMock struct {
+	mock.Mock
+}
+
+func (m *LanguageMappingServiceMock) GetById(id uint) (*models.LanguageMapping, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.LanguageMapping), args.Error(1)
+}
+
+func (m *LanguageMappingServiceMock) GetByUser(userId string) ([]*models.LanguageMapping, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]*models.LanguageMapping), args.Error(1)
+}
+
+func (m *LanguageMappingServiceMock) ResolveByUser(userId string) (map[string]string, error) {
+	args := m.Called(userId)
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+func (m *LanguageMappingServiceMock) GetDefaults() map[string]string {
+	args := m.Called()
+	return args.Get(0).(map[string]string)
+}
+
+func (m *LanguageMappingServiceMock) Create(mapping *models.LanguageMapping) (*models.LanguageMapping, error) {
+	args := m.Called(mapping)
+	return args.Get(0).(*models.LanguageMapping), args.Error(1)
+}
+
+func (m *LanguageMappingServiceMock) Delete(mapping *models.LanguageMapping) error {
+	args := m.Called(mapping)
+	return args.Error(0)
+}
@@ -0,0 +1,37 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MachineRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *MachineRepositoryMock) GetByUser(userId string) ([]*models.Machine, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]*models.Machine), args.Error(1)
+}
+
+func (m *MachineRepositoryMock) Upsert(machine *models.Machine) (*models.Machine, error) {
+	args := m.Called(machine)
+	return args.Get(0).(*models.Machine), args.Error(1)
+}
+
+func (m *MachineRepositoryMock) UpdateLocation(machine *models.Machine) error {
+	args := m.Called(machine)
+	return args.Error(0)
+}
+
+func (m *MachineRepositoryMock) DeleteByUserBefore(userId string, t time.Time) error {
+	args := m.Called(userId, t)
+	return args.Error(0)
+}
+
+func (m *MachineRepositoryMock) AnonymizeByUserBefore(userId string, t time.Time) (int64, error) {
+	args := m.Called(userId, t)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -0,0 +1,27 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MachineServiceMock struct {
+	mock.Mock
+}
+
+func (m *MachineServiceMock) GetByUser(userId string) ([]*models.Machine, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]*models.Machine), args.Error(1)
+}
+
+func (m *MachineServiceMock) DeleteByUserBefore(userId string, t time.Time) error {
+	args := m.Called(userId, t)
+	return args.Error(0)
+}
+
+func (m *MachineServiceMock) AnonymizeByUserBefore(userId string, t time.Time) (int64, error) {
+	args := m.Called(userId, t)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -0,0 +1,15 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MappingConfigRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *MappingConfigRepositoryMock) ImportByUser(userId string, aliases []*models.Alias, labels []*models.ProjectLabel, rules []*models.LabelRule, replace bool) error {
+	args := m.Called(userId, aliases, labels, rules, replace)
+	return args.Error(0)
+}
@@ -0,0 +1,32 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MilestoneServiceMock struct {
+	mock.Mock
+}
+
+func (m *MilestoneServiceMock) GetById(id uint) (*models.Milestone, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.Milestone), args.Error(1)
+}
+
+func (m *MilestoneServiceMock) GetByUserWithin(user *models.User, from, to time.Time) ([]*models.Milestone, error) {
+	args := m.Called(user, from, to)
+	return args.Get(0).([]*models.Milestone), args.Error(1)
+}
+
+func (m *MilestoneServiceMock) Create(milestone *models.Milestone) (*models.Milestone, error) {
+	args := m.Called(milestone)
+	return args.Get(0).(*models.Milestone), args.Error(1)
+}
+
+func (m *MilestoneServiceMock) Delete(milestone *models.Milestone) error {
+	args := m.Called(milestone)
+	return args.Error(0)
+}
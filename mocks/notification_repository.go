@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type NotificationRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *NotificationRepositoryMock) GetAllPending() ([]*models.Notification, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.Notification), args.Error(1)
+}
+
+func (m *NotificationRepositoryMock) Insert(n *models.Notification) (*models.Notification, error) {
+	args := m.Called(n)
+	return args.Get(0).(*models.Notification), args.Error(1)
+}
+
+func (m *NotificationRepositoryMock) DeleteByIds(ids []uint) error {
+	args := m.Called(ids)
+	return args.Error(0)
+}
@@ -0,0 +1,15 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type PresenceServiceMock struct {
+	mock.Mock
+}
+
+func (m *PresenceServiceMock) GetByUser(user *models.User) (*models.Presence, error) {
+	args := m.Called(user)
+	return args.Get(0).(*models.Presence), args.Error(1)
+}
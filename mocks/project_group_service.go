@@ -0,0 +1,40 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type ProjectGroupServiceMock struct {
+	mock.Mock
+}
+
+func (m *ProjectGroupServiceMock) GetByUser(userId string) ([]*models.ProjectGroup, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]*models.ProjectGroup), args.Error(1)
+}
+
+func (m *ProjectGroupServiceMock) GetByUserAndId(userId string, id uint) (*models.ProjectGroup, error) {
+	args := m.Called(userId, id)
+	return args.Get(0).(*models.ProjectGroup), args.Error(1)
+}
+
+func (m *ProjectGroupServiceMock) GetGroupsByProject(userId string) (map[string]string, error) {
+	args := m.Called(userId)
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+func (m *ProjectGroupServiceMock) Create(group *models.ProjectGroup) (*models.ProjectGroup, error) {
+	args := m.Called(group)
+	return args.Get(0).(*models.ProjectGroup), args.Error(1)
+}
+
+func (m *ProjectGroupServiceMock) Update(group *models.ProjectGroup) (*models.ProjectGroup, error) {
+	args := m.Called(group)
+	return args.Get(0).(*models.ProjectGroup), args.Error(1)
+}
+
+func (m *ProjectGroupServiceMock) Delete(group *models.ProjectGroup) error {
+	args := m.Called(group)
+	return args.Error(0)
+}
@@ -38,3 +38,7 @@ func (p *ProjectLabelServiceMock) Delete(l *models.ProjectLabel) error {
 	args := p.Called(l)
 	return args.Error(0)
 }
+
+func (p *ProjectLabelServiceMock) FlushUserCache(s string) {
+	p.Called(s)
+}
@@ -0,0 +1,20 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type ProjectRemoteServiceMock struct {
+	mock.Mock
+}
+
+func (m *ProjectRemoteServiceMock) GetByUser(userId string) ([]*models.ProjectRemote, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]*models.ProjectRemote), args.Error(1)
+}
+
+func (m *ProjectRemoteServiceMock) GetNamespacesByUser(userId string) (map[string]string, error) {
+	args := m.Called(userId)
+	return args.Get(0).(map[string]string), args.Error(1)
+}
@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type QueuedJobRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *QueuedJobRepositoryMock) GetAll() ([]*models.QueuedJob, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.QueuedJob), args.Error(1)
+}
+
+func (m *QueuedJobRepositoryMock) Insert(job *models.QueuedJob) (*models.QueuedJob, error) {
+	args := m.Called(job)
+	return args.Get(0).(*models.QueuedJob), args.Error(1)
+}
+
+func (m *QueuedJobRepositoryMock) Delete(id uint64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
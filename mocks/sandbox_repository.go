@@ -0,0 +1,37 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type SandboxRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *SandboxRepositoryMock) GetByUser(userId string, limit int) ([]*models.SandboxHeartbeat, error) {
+	args := m.Called(userId, limit)
+	return args.Get(0).([]*models.SandboxHeartbeat), args.Error(1)
+}
+
+func (m *SandboxRepositoryMock) CountByUser(userId string) (int64, error) {
+	args := m.Called(userId)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *SandboxRepositoryMock) InsertBatch(heartbeats []*models.SandboxHeartbeat) error {
+	args := m.Called(heartbeats)
+	return args.Error(0)
+}
+
+func (m *SandboxRepositoryMock) DeleteByUser(userId string) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+func (m *SandboxRepositoryMock) DeleteBefore(t time.Time) error {
+	args := m.Called(t)
+	return args.Error(0)
+}
@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type SavedFilterServiceMock struct {
+	mock.Mock
+}
+
+func (m *SavedFilterServiceMock) GetByUser(user *models.User) ([]*models.SavedFilter, error) {
+	args := m.Called(user)
+	return args.Get(0).([]*models.SavedFilter), args.Error(1)
+}
+
+func (m *SavedFilterServiceMock) GetByUserAndName(user *models.User, name string) (*models.SavedFilter, error) {
+	args := m.Called(user, name)
+	return args.Get(0).(*models.SavedFilter), args.Error(1)
+}
+
+func (m *SavedFilterServiceMock) Create(filter *models.SavedFilter) (*models.SavedFilter, error) {
+	args := m.Called(filter)
+	return args.Get(0).(*models.SavedFilter), args.Error(1)
+}
+
+func (m *SavedFilterServiceMock) Delete(filter *models.SavedFilter) error {
+	args := m.Called(filter)
+	return args.Error(0)
+}
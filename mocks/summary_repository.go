@@ -30,6 +30,16 @@ func (m *SummaryRepositoryMock) GetLastByUser() ([]*models.TimeByUser, error) {
 	return args.Get(0).([]*models.TimeByUser), args.Error(1)
 }
 
+func (m *SummaryRepositoryMock) GetTotalsByTypeWithin(entityType uint8, t1 time.Time, t2 time.Time, maxPerUser time.Duration) ([]*models.TotalByKey, error) {
+	args := m.Called(entityType, t1, t2, maxPerUser)
+	return args.Get(0).([]*models.TotalByKey), args.Error(1)
+}
+
+func (m *SummaryRepositoryMock) CountByUsersBefore(t time.Time) ([]*models.CountByUser, error) {
+	args := m.Called(t)
+	return args.Get(0).([]*models.CountByUser), args.Error(1)
+}
+
 func (m *SummaryRepositoryMock) DeleteByUser(s string) error {
 	args := m.Called(s)
 	return args.Error(0)
@@ -39,3 +49,28 @@ func (m *SummaryRepositoryMock) DeleteByUserBefore(s string, t time.Time) error
 	args := m.Called(s, t)
 	return args.Error(0)
 }
+
+func (m *SummaryRepositoryMock) DeleteByUserWithin(s string, t1 time.Time, t2 time.Time) error {
+	args := m.Called(s, t1, t2)
+	return args.Error(0)
+}
+
+func (m *SummaryRepositoryMock) DeleteItemsByUserBefore(s string, t time.Time, types []uint8) error {
+	args := m.Called(s, t, types)
+	return args.Error(0)
+}
+
+func (m *SummaryRepositoryMock) DeletePartialByUser(s string) error {
+	args := m.Called(s)
+	return args.Error(0)
+}
+
+func (m *SummaryRepositoryMock) DeletePartialBefore(t time.Time) error {
+	args := m.Called(t)
+	return args.Error(0)
+}
+
+func (m *SummaryRepositoryMock) ReplacePartial(s *models.Summary) error {
+	args := m.Called(s)
+	return args.Error(0)
+}
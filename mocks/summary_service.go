@@ -26,6 +26,11 @@ func (m *SummaryServiceMock) Summarize(t time.Time, t2 time.Time, u *models.User
 	return args.Get(0).(*models.Summary), args.Error(1)
 }
 
+func (m *SummaryServiceMock) GetByUserWithin(u *models.User, t1 time.Time, t2 time.Time) ([]*models.Summary, error) {
+	args := m.Called(u, t1, t2)
+	return args.Get(0).([]*models.Summary), args.Error(1)
+}
+
 func (m *SummaryServiceMock) GetLatestByUser() ([]*models.TimeByUser, error) {
 	args := m.Called()
 	return args.Get(0).([]*models.TimeByUser), args.Error(1)
@@ -36,12 +41,47 @@ func (m *SummaryServiceMock) DeleteByUser(s string) error {
 	return args.Error(0)
 }
 
+func (m *SummaryServiceMock) CountByUsersBefore(t time.Time) ([]*models.CountByUser, error) {
+	args := m.Called(t)
+	return args.Get(0).([]*models.CountByUser), args.Error(1)
+}
+
 func (m *SummaryServiceMock) DeleteByUserBefore(s string, t time.Time) error {
 	args := m.Called(s, t)
 	return args.Error(0)
 }
 
+func (m *SummaryServiceMock) DeleteByUserWithin(s string, t1 time.Time, t2 time.Time) error {
+	args := m.Called(s, t1, t2)
+	return args.Error(0)
+}
+
+func (m *SummaryServiceMock) AnonymizeByUserBefore(s string, t time.Time) error {
+	args := m.Called(s, t)
+	return args.Error(0)
+}
+
 func (m *SummaryServiceMock) Insert(s *models.Summary) error {
 	args := m.Called(s)
 	return args.Error(0)
 }
+
+func (m *SummaryServiceMock) DeletePartialByUser(s string) error {
+	args := m.Called(s)
+	return args.Error(0)
+}
+
+func (m *SummaryServiceMock) DeletePartialBefore(t time.Time) error {
+	args := m.Called(t)
+	return args.Error(0)
+}
+
+func (m *SummaryServiceMock) ReplacePartial(s *models.Summary) error {
+	args := m.Called(s)
+	return args.Error(0)
+}
+
+func (m *SummaryServiceMock) DataVersion(s string) time.Time {
+	args := m.Called(s)
+	return args.Get(0).(time.Time)
+}
@@ -0,0 +1,96 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type UserRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *UserRepositoryMock) FindOne(user models.User) (*models.User, error) {
+	args := m.Called(user)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserRepositoryMock) GetByIds(ids []string) ([]*models.User, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *UserRepositoryMock) GetAll() ([]*models.User, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *UserRepositoryMock) GetMany(ids []string) ([]*models.User, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *UserRepositoryMock) GetAllByReports(b bool) ([]*models.User, error) {
+	args := m.Called(b)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *UserRepositoryMock) GetAllByLeaderboard(b bool) ([]*models.User, error) {
+	args := m.Called(b)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *UserRepositoryMock) GetByLoggedInAfter(t time.Time) ([]*models.User, error) {
+	args := m.Called(t)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *UserRepositoryMock) GetByLastActiveAfter(t time.Time) ([]*models.User, error) {
+	args := m.Called(t)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *UserRepositoryMock) GetByDeleteAtBefore(t time.Time) ([]*models.User, error) {
+	args := m.Called(t)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *UserRepositoryMock) Count() (int64, error) {
+	args := m.Called()
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *UserRepositoryMock) CountByLastActiveAfter(t time.Time) (int64, error) {
+	args := m.Called(t)
+	return int64(args.Int(0)), args.Error(1)
+}
+
+func (m *UserRepositoryMock) GetInactiveSince(t time.Time) ([]*models.User, error) {
+	args := m.Called(t)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *UserRepositoryMock) InsertOrGet(user *models.User) (*models.User, bool, error) {
+	args := m.Called(user)
+	return args.Get(0).(*models.User), args.Bool(1), args.Error(2)
+}
+
+func (m *UserRepositoryMock) Update(user *models.User) (*models.User, error) {
+	args := m.Called(user)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserRepositoryMock) UpdateField(user *models.User, key string, value interface{}) (*models.User, error) {
+	args := m.Called(user, key, value)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserRepositoryMock) ChangeId(user *models.User, newId string) (*models.User, error) {
+	args := m.Called(user, newId)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserRepositoryMock) Delete(user *models.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
@@ -59,6 +59,11 @@ func (m *UserServiceMock) GetUserByStripeCustomerId(s string) (*models.User, err
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *UserServiceMock) GetInactive(days int) ([]*models.User, error) {
+	args := m.Called(days)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
 func (m *UserServiceMock) GetActive(b bool) ([]*models.User, error) {
 	args := m.Called(b)
 	return args.Get(0).([]*models.User), args.Error(1)
@@ -99,11 +104,61 @@ func (m *UserServiceMock) SetWakatimeApiCredentials(user *models.User, s1, s2 st
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *UserServiceMock) SetMirrorUrl(user *models.User, s1, s2 string) (*models.User, error) {
+	args := m.Called(user, s1, s2)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 func (m *UserServiceMock) GenerateResetToken(user *models.User) (*models.User, error) {
 	args := m.Called(user)
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *UserServiceMock) CountActiveByClass(class models.ActivityClass) (int64, error) {
+	args := m.Called(class)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *UserServiceMock) GetUserByDeletionToken(s string) (*models.User, error) {
+	args := m.Called(s)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserServiceMock) GetScheduledForDeletion() ([]*models.User, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *UserServiceMock) GenerateDeletionToken(user *models.User) (*models.User, error) {
+	args := m.Called(user)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserServiceMock) GenerateVerificationToken(user *models.User) (*models.User, error) {
+	args := m.Called(user)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserServiceMock) VerifyEmail(s string) (*models.User, error) {
+	args := m.Called(s)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserServiceMock) ScheduleDeletion(user *models.User) (*models.User, error) {
+	args := m.Called(user)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserServiceMock) CancelDeletion(user *models.User) (*models.User, error) {
+	args := m.Called(user)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserServiceMock) ChangeUserId(user *models.User, newUserId string) (*models.User, error) {
+	args := m.Called(user, newUserId)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 func (m *UserServiceMock) FlushCache() {
 	m.Called()
 }
@@ -0,0 +1,69 @@
+package models
+
+const (
+	AbuseReportTargetUser    = "user"
+	AbuseReportTargetProject = "project"
+)
+
+const (
+	AbuseReportStatusOpen      = "open"
+	AbuseReportStatusResolved  = "resolved"
+	AbuseReportStatusDismissed = "dismissed"
+)
+
+const (
+	AbuseReportActionHide    = "hide"
+	AbuseReportActionRename  = "rename"
+	AbuseReportActionDismiss = "dismiss"
+)
+
+type AbuseReport struct {
+	ID            uint       `json:"id" gorm:"primary_key"`
+	Reporter      *User      `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	ReporterID    *string    `json:"reporter_id"` // pointer because nullable
+	Target        *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	TargetID      string     `json:"target_id" gorm:"not null; index:idx_abuse_report_target"`
+	TargetType    string     `json:"target_type" gorm:"not null; size:32"`
+	TargetProject string     `json:"target_project" gorm:"size:255"`
+	Reason        string     `json:"reason" gorm:"type:text"`
+	Status        string     `json:"status" gorm:"not null; default:open; size:32; index:idx_abuse_report_status"`
+	CreatedAt     CustomTime `json:"created_at" gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type AbuseReportRequest struct {
+	TargetType    string `json:"target_type"`
+	TargetID      string `json:"target_id"`
+	TargetProject string `json:"target_project"`
+	Reason        string `json:"reason"`
+}
+
+type AbuseReportResolution struct {
+	Action  string `json:"action"`
+	NewName string `json:"new_name"` // only for action "rename"
+}
+
+func (r *AbuseReport) IsValid() bool {
+	switch r.TargetType {
+	case AbuseReportTargetUser:
+		return r.TargetID != "" && r.TargetProject == ""
+	case AbuseReportTargetProject:
+		return r.TargetID != "" && r.TargetProject != ""
+	default:
+		return false
+	}
+}
+
+func (r *AbuseReport) IsOpen() bool {
+	return r.Status == AbuseReportStatusOpen
+}
+
+func (r *AbuseReportResolution) IsValid() bool {
+	switch r.Action {
+	case AbuseReportActionHide, AbuseReportActionDismiss:
+		return true
+	case AbuseReportActionRename:
+		return r.NewName != ""
+	default:
+		return false
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/duke-git/lancet/v2/slice"
+)
+
+const (
+	AccessTokenPrefix = "wakapi_"
+
+	ScopeReadStats       = "read_stats"
+	ScopeWriteHeartbeats = "write_heartbeats"
+)
+
+// AccessToken is a revocable, scoped alternative to the global api key, issued e.g. to devices through the device authorization flow
+// Only a hash of the actual token is persisted
+type AccessToken struct {
+	ID         uint        `json:"id" gorm:"primary_key"`
+	User       *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID     string      `json:"-" gorm:"not null; index:idx_access_token_user"`
+	TokenHash  string      `json:"-" gorm:"not null; uniqueIndex; size:64"`
+	Name       string      `json:"name" gorm:"size:255"`
+	Scope      string      `json:"scope" gorm:"size:255"` // space-separated list of scopes
+	CreatedAt  CustomTime  `json:"created_at" gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	LastUsedAt *CustomTime `json:"last_used_at" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// AccessTokenResponse is a successful token response as of https://datatracker.ietf.org/doc/html/rfc6749#section-5.1
+type AccessTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	Scope       string `json:"scope"`
+}
+
+// AccessTokenErrorResponse is an error response as of https://datatracker.ietf.org/doc/html/rfc8628#section-3.5
+type AccessTokenErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func AllScopes() []string {
+	return []string{ScopeReadStats, ScopeWriteHeartbeats}
+}
+
+// ParseScope normalizes the given space-separated scope string and returns false if it contains any unknown scope
+// an empty scope is interpreted as all scopes
+func ParseScope(scope string) (string, bool) {
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		return strings.Join(AllScopes(), " "), true
+	}
+	for _, s := range scopes {
+		if !slice.Contain(AllScopes(), s) {
+			return "", false
+		}
+	}
+	return strings.Join(slice.Unique(scopes), " "), true
+}
+
+func (t *AccessToken) HasScope(scope string) bool {
+	return slice.Contain(strings.Fields(t.Scope), scope)
+}
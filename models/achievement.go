@@ -0,0 +1,189 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+)
+
+// Achievement is a milestone in a user's coding history (e.g. "100 hours of coding"), which is unlocked once and kept forever
+type Achievement struct {
+	ID          uint       `json:"-" gorm:"primary_key"`
+	User        *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID      string     `json:"-" gorm:"not null; uniqueIndex:idx_achievement_user_key"`
+	Key         string     `json:"key" gorm:"not null; type:varchar(64); uniqueIndex:idx_achievement_user_key"`
+	Name        string     `json:"name" gorm:"-"`
+	Description string     `json:"description" gorm:"-"`
+	UnlockedAt  CustomTime `json:"unlocked_at" gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// AchievementDefinition describes an achievement and the condition for it to be unlocked
+type AchievementDefinition struct {
+	Key         string
+	Name        string
+	Description string
+	IsReached   func(stats *AchievementStats) bool
+}
+
+// AchievementStats are a user's all-time key figures, which achievements are evaluated against
+type AchievementStats struct {
+	Total         time.Duration
+	ByLanguage    map[string]time.Duration
+	Languages     int           // number of languages coded in for at least an hour
+	Projects      int           // number of distinct projects
+	LongestStreak int           // max. number of consecutive days with any coding activity
+	MaxDaily      time.Duration // most time coded on a single day
+}
+
+// Achievements is the registry of all achievements that can be unlocked, new ones will be granted retroactively with the next evaluation
+var Achievements = []*AchievementDefinition{
+	newTotalTimeAchievement("first_hour", "Hello World", "Code for your first hour", 1),
+	newTotalTimeAchievement("hours_100", "Centurion", "Code for 100 hours in total", 100),
+	newTotalTimeAchievement("hours_1000", "Seasoned", "Code for 1,000 hours in total", 1000),
+	newTotalTimeAchievement("hours_10000", "Mastery", "Code for 10,000 hours in total", 10000),
+	newLanguageTimeAchievement("language_hours_100", "Fluent", "Code for 100 hours in a single language", 100),
+	newLanguageTimeAchievement("language_hours_1000", "Native Speaker", "Code for 1,000 hours in a single language", 1000),
+	newStreakAchievement("streak_7", "On a Roll", "Code on 7 consecutive days", 7),
+	newStreakAchievement("streak_30", "Habit", "Code on 30 consecutive days", 30),
+	newStreakAchievement("streak_100", "Unstoppable", "Code on 100 consecutive days", 100),
+	{
+		Key:         "marathon",
+		Name:        "Marathon",
+		Description: "Code for 10 hours on a single day",
+		IsReached: func(stats *AchievementStats) bool {
+			return stats.MaxDaily >= 10*time.Hour
+		},
+	},
+	{
+		Key:         "polyglot",
+		Name:        "Polyglot",
+		Description: "Code for at least an hour in each of 10 different languages",
+		IsReached: func(stats *AchievementStats) bool {
+			return stats.Languages >= 10
+		},
+	},
+	{
+		Key:         "projects_25",
+		Name:        "Serial Starter",
+		Description: "Work on 25 different projects",
+		IsReached: func(stats *AchievementStats) bool {
+			return stats.Projects >= 25
+		},
+	},
+}
+
+func GetAchievementDefinition(key string) *AchievementDefinition {
+	for _, d := range Achievements {
+		if d.Key == key {
+			return d
+		}
+	}
+	return nil
+}
+
+// WithDefinition populates the achievement's name and description from its definition, if still existing
+func (a *Achievement) WithDefinition() *Achievement {
+	if d := GetAchievementDefinition(a.Key); d != nil {
+		a.Name = d.Name
+		a.Description = d.Description
+	}
+	return a
+}
+
+// NewAchievementStats computes a user's key figures from their (daily) summaries, whose days are determined in the given time zone
+func NewAchievementStats(summaries []*Summary, tz *time.Location) *AchievementStats {
+	stats := &AchievementStats{ByLanguage: map[string]time.Duration{}}
+	projects := map[string]bool{}
+	daily := map[string]time.Duration{}
+
+	for _, s := range summaries {
+		total := s.TotalTime()
+		stats.Total += total
+		daily[s.FromTime.T().In(tz).Format(conf.SimpleDateFormat)] += total
+
+		for _, item := range s.Languages {
+			if item.Key != UnknownSummaryKey {
+				stats.ByLanguage[item.Key] += item.Total * time.Second
+			}
+		}
+		for _, item := range s.Projects {
+			if item.Total > 0 && item.Key != UnknownSummaryKey {
+				projects[item.Key] = true
+			}
+		}
+	}
+
+	for _, d := range stats.ByLanguage {
+		if d >= time.Hour {
+			stats.Languages++
+		}
+	}
+	stats.Projects = len(projects)
+
+	days := make([]string, 0, len(daily))
+	for day, total := range daily {
+		if total > 0 {
+			days = append(days, day)
+		}
+		if total > stats.MaxDaily {
+			stats.MaxDaily = total
+		}
+	}
+	sort.Strings(days)
+
+	var streak int
+	var prev time.Time
+	for _, day := range days {
+		t, _ := time.Parse(conf.SimpleDateFormat, day)
+		if !prev.IsZero() && prev.AddDate(0, 0, 1).Equal(t) {
+			streak++
+		} else {
+			streak = 1
+		}
+		if streak > stats.LongestStreak {
+			stats.LongestStreak = streak
+		}
+		prev = t
+	}
+
+	return stats
+}
+
+func newTotalTimeAchievement(key, name, description string, hours int) *AchievementDefinition {
+	return &AchievementDefinition{
+		Key:         key,
+		Name:        name,
+		Description: description,
+		IsReached: func(stats *AchievementStats) bool {
+			return stats.Total >= time.Duration(hours)*time.Hour
+		},
+	}
+}
+
+func newLanguageTimeAchievement(key, name, description string, hours int) *AchievementDefinition {
+	return &AchievementDefinition{
+		Key:         key,
+		Name:        name,
+		Description: description,
+		IsReached: func(stats *AchievementStats) bool {
+			for _, d := range stats.ByLanguage {
+				if d >= time.Duration(hours)*time.Hour {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+func newStreakAchievement(key, name, description string, days int) *AchievementDefinition {
+	return &AchievementDefinition{
+		Key:         key,
+		Name:        name,
+		Description: description,
+		IsReached: func(stats *AchievementStats) bool {
+			return stats.LongestStreak >= days
+		},
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewAchievementStats(t *testing.T) {
+	tz := time.UTC
+	day := func(d int, total time.Duration, language, project string) *Summary {
+		from := time.Date(2023, 5, d, 0, 0, 0, 0, tz)
+		return &Summary{
+			FromTime:  CustomTime(from),
+			ToTime:    CustomTime(from.AddDate(0, 0, 1)),
+			Projects:  SummaryItems{{Type: SummaryProject, Key: project, Total: total / time.Second}},
+			Languages: SummaryItems{{Type: SummaryLanguage, Key: language, Total: total / time.Second}},
+		}
+	}
+
+	stats := NewAchievementStats([]*Summary{
+		day(1, 2*time.Hour, "Go", "wakapi"),
+		day(2, 11*time.Hour, "Go", "wakapi"),
+		day(3, 30*time.Minute, "Java", "other"),
+		day(5, 1*time.Hour, UnknownSummaryKey, UnknownSummaryKey),
+		day(6, 1*time.Hour, "Go", "wakapi"),
+	}, tz)
+
+	assert.Equal(t, 15*time.Hour+30*time.Minute, stats.Total)
+	assert.Equal(t, 14*time.Hour, stats.ByLanguage["Go"])
+	assert.Equal(t, 1, stats.Languages)
+	assert.Equal(t, 2, stats.Projects)
+	assert.Equal(t, 3, stats.LongestStreak)
+	assert.Equal(t, 11*time.Hour, stats.MaxDaily)
+
+	assert.True(t, GetAchievementDefinition("first_hour").IsReached(stats))
+	assert.True(t, GetAchievementDefinition("marathon").IsReached(stats))
+	assert.False(t, GetAchievementDefinition("hours_100").IsReached(stats))
+	assert.False(t, GetAchievementDefinition("streak_7").IsReached(stats))
+	assert.Nil(t, GetAchievementDefinition("unknown"))
+}
+
+func TestAchievements_UniqueKeys(t *testing.T) {
+	keys := map[string]bool{}
+	for _, d := range Achievements {
+		assert.False(t, keys[d.Key], d.Key)
+		assert.LessOrEqual(t, len(d.Key), 64)
+		keys[d.Key] = true
+	}
+}
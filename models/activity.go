@@ -0,0 +1,40 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+)
+
+// ActivityClass buckets users by how recently they sent their last heartbeat (like DAU / WAU / MAU)
+type ActivityClass string
+
+const (
+	ActivityClassDaily   ActivityClass = "daily"
+	ActivityClassWeekly  ActivityClass = "weekly"
+	ActivityClassMonthly ActivityClass = "monthly"
+)
+
+func AllActivityClasses() []ActivityClass {
+	return []ActivityClass{ActivityClassDaily, ActivityClassWeekly, ActivityClassMonthly}
+}
+
+// Window returns the period of time within which a user must have sent at least one heartbeat to be considered part of the class
+func (c ActivityClass) Window() time.Duration {
+	var days int
+	switch c {
+	case ActivityClassDaily:
+		days = conf.Get().App.ActivityDaysDaily
+	case ActivityClassWeekly:
+		days = conf.Get().App.ActivityDaysWeekly
+	case ActivityClassMonthly:
+		days = conf.Get().App.ActivityDaysMonthly
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Key returns the key under which the class' latest user count is persisted as a key-value
+func (c ActivityClass) Key() string {
+	return fmt.Sprintf("%s_%s", conf.KeyActiveUsers, c)
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActivityClass_Window(t *testing.T) {
+	cfg := config.Empty()
+	cfg.App.ActivityDaysDaily = 1
+	cfg.App.ActivityDaysWeekly = 7
+	cfg.App.ActivityDaysMonthly = 28
+	config.Set(cfg)
+
+	assert.Equal(t, 24*time.Hour, ActivityClassDaily.Window())
+	assert.Equal(t, 7*24*time.Hour, ActivityClassWeekly.Window())
+	assert.Equal(t, 28*24*time.Hour, ActivityClassMonthly.Window())
+	assert.Zero(t, ActivityClass("yearly").Window())
+}
+
+func TestActivityClass_Key(t *testing.T) {
+	assert.Equal(t, "active_users_daily", ActivityClassDaily.Key())
+	assert.Equal(t, "active_users_monthly", ActivityClassMonthly.Key())
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+)
+
+const (
+	AggregationBackfillStatusNone    = "none"
+	AggregationBackfillStatusPending = "pending"
+	AggregationBackfillStatusDone    = "done"
+	AggregationBackfillStatusFailed  = "failed"
+)
+
+// AggregationBackfillRequest asks to re-run summary aggregation for the given users (or all, if empty) between two dates, both inclusive
+type AggregationBackfillRequest struct {
+	From  string   `json:"from"` // e.g. 2021-02-07
+	To    string   `json:"to"`   // e.g. 2021-02-08
+	Users []string `json:"users"`
+}
+
+// AggregationBackfillJob describes the state of the running or most recent backfill
+type AggregationBackfillJob struct {
+	Status    string    `json:"status"`
+	Progress  float64   `json:"progress"` // between 0 and 1
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Users     int       `json:"users"`
+	Processed int       `json:"processed"`
+	Failed    []string  `json:"failed"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Range parses the request's dates as midnight in the given time zone
+func (r *AggregationBackfillRequest) Range(tz *time.Location) (time.Time, time.Time, error) {
+	from, err := time.ParseInLocation(conf.SimpleDateFormat, r.From, tz)
+	if err != nil {
+		return from, time.Time{}, err
+	}
+	to, err := time.ParseInLocation(conf.SimpleDateFormat, r.To, tz)
+	return from, to, err
+}
+
+func (r *AggregationBackfillRequest) IsValid() bool {
+	from, to, err := r.Range(time.UTC)
+	return err == nil && !to.Before(from)
+}
+
+func (j *AggregationBackfillJob) IsPending() bool {
+	return j.Status == AggregationBackfillStatusPending
+}
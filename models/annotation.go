@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+)
+
+// Annotation is a user's note on a specific day, optionally referring to a single project (e.g. "conference day" or "release crunch")
+type Annotation struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"-" gorm:"not null; index:idx_annotation_user_day"`
+	Day       string     `json:"day" gorm:"not null; type:varchar(10); index:idx_annotation_user_day" example:"2006-01-02"` // in the user's time zone
+	Project   string     `json:"project,omitempty" gorm:"type:varchar(255)"`
+	Text      string     `json:"text" gorm:"type:varchar(255)"`
+	CreatedAt CustomTime `json:"created_at" gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+func (a *Annotation) IsValid() bool {
+	if _, err := time.Parse(conf.SimpleDateFormat, a.Day); err != nil {
+		return false
+	}
+	return a.Text != "" && len(a.Text) <= 255 && len(a.Project) <= 255
+}
+
+// AnnotationDayRange returns the first and last day, formatted like Annotation.Day, covered by the given time interval in the given time zone
+func AnnotationDayRange(from, to time.Time, tz *time.Location) (string, string) {
+	if to.After(from) {
+		to = to.Add(-1 * time.Nanosecond) // intervals are exclusive at their end
+	}
+	return from.In(tz).Format(conf.SimpleDateFormat), to.In(tz).Format(conf.SimpleDateFormat)
+}
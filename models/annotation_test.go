@@ -0,0 +1,38 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAnnotationDayRange(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	newYork, _ := time.LoadLocation("America/New_York")
+
+	// a full day in the user's time zone, exclusive at its end
+	from, to := AnnotationDayRange(time.Date(2023, 5, 1, 0, 0, 0, 0, berlin), time.Date(2023, 5, 2, 0, 0, 0, 0, berlin), berlin)
+	assert.Equal(t, "2023-05-01", from)
+	assert.Equal(t, "2023-05-01", to)
+
+	// a week
+	from, to = AnnotationDayRange(time.Date(2023, 5, 1, 0, 0, 0, 0, berlin), time.Date(2023, 5, 8, 0, 0, 0, 0, berlin), berlin)
+	assert.Equal(t, "2023-05-01", from)
+	assert.Equal(t, "2023-05-07", to)
+
+	// interval given in utc, but days are determined in the user's time zone
+	from, to = AnnotationDayRange(time.Date(2023, 5, 1, 2, 0, 0, 0, time.UTC), time.Date(2023, 5, 1, 23, 30, 0, 0, time.UTC), newYork)
+	assert.Equal(t, "2023-04-30", from)
+	assert.Equal(t, "2023-05-01", to)
+
+	// empty interval
+	from, to = AnnotationDayRange(time.Date(2023, 5, 1, 12, 0, 0, 0, berlin), time.Date(2023, 5, 1, 12, 0, 0, 0, berlin), berlin)
+	assert.Equal(t, "2023-05-01", from)
+	assert.Equal(t, "2023-05-01", to)
+}
+
+func TestAnnotation_IsValid(t *testing.T) {
+	assert.True(t, (&Annotation{Day: "2023-05-01", Text: "conference"}).IsValid())
+	assert.False(t, (&Annotation{Day: "01.05.2023", Text: "conference"}).IsValid())
+	assert.False(t, (&Annotation{Day: "2023-05-01", Text: ""}).IsValid())
+}
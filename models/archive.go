@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	ArchiveFileExtension = ".jsonl.gz"
+	archiveMonthFormat   = "2006-01"
+)
+
+// ArchivedHeartbeat is a heartbeat as stored in cold-storage archive files (gzipped json lines)
+// in addition to the heartbeat's regular json representation, it holds those fields required to restore it as it was
+type ArchivedHeartbeat struct {
+	Heartbeat        *Heartbeat `json:"heartbeat"`
+	Hash             string     `json:"hash"`
+	LanguageOriginal string     `json:"language_original,omitempty"`
+	Origin           string     `json:"origin,omitempty"`
+	OriginId         string     `json:"origin_id,omitempty"`
+}
+
+func NewArchivedHeartbeat(h *Heartbeat) *ArchivedHeartbeat {
+	return &ArchivedHeartbeat{
+		Heartbeat:        h,
+		Hash:             h.Hash,
+		LanguageOriginal: h.LanguageOriginal,
+		Origin:           h.Origin,
+		OriginId:         h.OriginId,
+	}
+}
+
+// Restore returns the archived heartbeat, ready to be inserted for the given user again
+func (a *ArchivedHeartbeat) Restore(user *User) *Heartbeat {
+	h := *a.Heartbeat
+	h.ID = 0
+	h.User = user
+	h.UserID = user.ID
+	h.Hash = a.Hash
+	h.LanguageOriginal = a.LanguageOriginal
+	h.Origin = a.Origin
+	h.OriginId = a.OriginId
+	if h.Hash == "" {
+		h.Hashed()
+	}
+	return &h
+}
+
+// ArchiveFileName returns the name of the archive file holding the given user's heartbeats of the month starting at the given time, e.g. "user1/2024-01.jsonl.gz"
+func ArchiveFileName(userId string, month time.Time) string {
+	return fmt.Sprintf("%s/%s%s", userId, month.Format(archiveMonthFormat), ArchiveFileExtension)
+}
+
+// ParseArchiveFileName returns the month (in the given location) an archive file holds heartbeats of
+func ParseArchiveFileName(name string, loc *time.Location) (time.Time, error) {
+	base := path.Base(name)
+	if !strings.HasSuffix(base, ArchiveFileExtension) {
+		return time.Time{}, fmt.Errorf("not an archive file: %s", name)
+	}
+	return time.ParseInLocation(archiveMonthFormat, strings.TrimSuffix(base, ArchiveFileExtension), loc)
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	BillingFormatJson  = "json"
+	BillingFormatCsv   = "csv"
+	BillingFormatPdf   = "pdf"
+	BillingMonthFormat = "2006-01"
+)
+
+// BillableProject marks one of a user's projects as billable to a client at a given hourly rate
+type BillableProject struct {
+	ID         uint    `json:"-" gorm:"primary_key"`
+	User       *User   `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID     string  `json:"-" gorm:"not null; uniqueIndex:idx_billable_project_user_project"`
+	Project    string  `json:"project" gorm:"not null; type:varchar(255); uniqueIndex:idx_billable_project_user_project"`
+	Client     string  `json:"client" gorm:"not null; type:varchar(255)"`
+	HourlyRate float64 `json:"hourly_rate"`
+	Currency   string  `json:"currency" gorm:"type:varchar(3)"` // iso 4217 code, all projects of the same client share a currency
+}
+
+// BillingReport breaks down a user's billable hours within some interval by client and month
+type BillingReport struct {
+	From    time.Time        `json:"from"`
+	To      time.Time        `json:"to"`
+	Clients []*BillingClient `json:"clients"`
+}
+
+type BillingClient struct {
+	Client   string          `json:"client"`
+	Currency string          `json:"currency"`
+	Months   []*BillingMonth `json:"months"`
+	Hours    float64         `json:"hours"`
+	Amount   float64         `json:"amount"`
+}
+
+type BillingMonth struct {
+	Month    string                `json:"month"` // formatted as yyyy-mm
+	Projects []*BillingProjectItem `json:"projects"`
+	Hours    float64               `json:"hours"`
+	Amount   float64               `json:"amount"`
+}
+
+type BillingProjectItem struct {
+	Project    string  `json:"project"`
+	Hours      float64 `json:"hours"`
+	HourlyRate float64 `json:"hourly_rate"`
+	Amount     float64 `json:"amount"`
+}
+
+func (p *BillableProject) IsValid() bool {
+	return p.Project != "" && strings.TrimSpace(p.Client) != "" && len(p.Client) <= 255 &&
+		p.HourlyRate >= 0 && (p.Currency == "" || len(p.Currency) == 3)
+}
+
+// NewBillingReport creates an empty report, to which monthly summaries are added one by one
+func NewBillingReport(from, to time.Time) *BillingReport {
+	return &BillingReport{From: from, To: to, Clients: []*BillingClient{}}
+}
+
+// AddMonth adds the time coded in billable projects according to the given (aliased) summary to the month starting at the given date
+// projects without any time coded in that month are omitted
+func (r *BillingReport) AddMonth(month time.Time, summary *Summary, projects []*BillableProject) {
+	for _, p := range projects {
+		total := summary.TotalTimeByKey(SummaryProject, p.Project)
+		if total <= 0 {
+			continue
+		}
+
+		hours := total.Hours()
+		item := &BillingProjectItem{Project: p.Project, Hours: hours, HourlyRate: p.HourlyRate, Amount: hours * p.HourlyRate}
+
+		c := r.client(p)
+		m := c.month(month.Format(BillingMonthFormat))
+		m.Projects = append(m.Projects, item)
+		m.Hours += item.Hours
+		m.Amount += item.Amount
+		c.Hours += item.Hours
+		c.Amount += item.Amount
+	}
+}
+
+// Rows flattens the report to one row per client, month and project, e.g. for csv exports, the first row holds column titles
+func (r *BillingReport) Rows() [][]string {
+	rows := [][]string{{"Client", "Month", "Project", "Hours", "Hourly Rate", "Amount", "Currency"}}
+	for _, c := range r.Clients {
+		for _, m := range c.Months {
+			for _, p := range m.Projects {
+				rows = append(rows, []string{c.Client, m.Month, p.Project, fmt.Sprintf("%.2f", p.Hours), fmt.Sprintf("%.2f", p.HourlyRate), fmt.Sprintf("%.2f", p.Amount), c.Currency})
+			}
+		}
+	}
+	return rows
+}
+
+func (r *BillingReport) Sorted() *BillingReport {
+	sort.Slice(r.Clients, func(i, j int) bool {
+		return strings.ToLower(r.Clients[i].Client) < strings.ToLower(r.Clients[j].Client)
+	})
+	for _, c := range r.Clients {
+		sort.Slice(c.Months, func(i, j int) bool {
+			return c.Months[i].Month < c.Months[j].Month
+		})
+		for _, m := range c.Months {
+			sort.Slice(m.Projects, func(i, j int) bool {
+				return m.Projects[i].Hours > m.Projects[j].Hours
+			})
+		}
+	}
+	return r
+}
+
+func (r *BillingReport) client(p *BillableProject) *BillingClient {
+	for _, c := range r.Clients {
+		if c.Client == p.Client {
+			return c
+		}
+	}
+	c := &BillingClient{Client: p.Client, Currency: p.Currency, Months: []*BillingMonth{}}
+	r.Clients = append(r.Clients, c)
+	return c
+}
+
+func (c *BillingClient) month(month string) *BillingMonth {
+	for _, m := range c.Months {
+		if m.Month == month {
+			return m
+		}
+	}
+	m := &BillingMonth{Month: month, Projects: []*BillingProjectItem{}}
+	c.Months = append(c.Months, m)
+	return m
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBillableProject_IsValid(t *testing.T) {
+	assert.True(t, (&BillableProject{Project: "wakapi", Client: "Acme", HourlyRate: 80, Currency: "EUR"}).IsValid())
+	assert.True(t, (&BillableProject{Project: "wakapi", Client: "Acme"}).IsValid())
+	assert.False(t, (&BillableProject{Project: "", Client: "Acme"}).IsValid())
+	assert.False(t, (&BillableProject{Project: "wakapi", Client: " "}).IsValid())
+	assert.False(t, (&BillableProject{Project: "wakapi", Client: "Acme", HourlyRate: -1}).IsValid())
+	assert.False(t, (&BillableProject{Project: "wakapi", Client: "Acme", Currency: "Euro"}).IsValid())
+}
+
+func TestBillingReport_AddMonth(t *testing.T) {
+	projects := []*BillableProject{
+		{Project: "wakapi", Client: "Acme", HourlyRate: 80, Currency: "EUR"},
+		{Project: "anchr", Client: "Acme", HourlyRate: 60, Currency: "EUR"},
+		{Project: "website", Client: "Beta Corp", HourlyRate: 100, Currency: "USD"},
+	}
+	newSummary := func(totals map[string]time.Duration) *Summary {
+		s := &Summary{Projects: []*SummaryItem{}}
+		for k, d := range totals {
+			// total time of summary items is represented in seconds
+			s.Projects = append(s.Projects, &SummaryItem{Type: SummaryProject, Key: k, Total: d / time.Second})
+		}
+		return s
+	}
+
+	from, to := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	sut := NewBillingReport(from, to)
+	sut.AddMonth(time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), newSummary(map[string]time.Duration{"wakapi": 2 * time.Hour, "private": 5 * time.Hour}), projects)
+	sut.AddMonth(time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), newSummary(map[string]time.Duration{"wakapi": 1 * time.Hour, "anchr": 3 * time.Hour, "website": 30 * time.Minute}), projects)
+	sut.Sorted()
+
+	assert.Len(t, sut.Clients, 2)
+
+	acme := sut.Clients[0]
+	assert.Equal(t, "Acme", acme.Client)
+	assert.Equal(t, "EUR", acme.Currency)
+	assert.Equal(t, 6.0, acme.Hours)
+	assert.Equal(t, 420.0, acme.Amount)
+	assert.Len(t, acme.Months, 2)
+	assert.Equal(t, "2023-05", acme.Months[0].Month)
+	assert.Equal(t, 4.0, acme.Months[0].Hours)
+	assert.Equal(t, 260.0, acme.Months[0].Amount)
+	assert.Equal(t, "anchr", acme.Months[0].Projects[0].Project) // most hours first
+	assert.Equal(t, "2023-06", acme.Months[1].Month)
+	assert.Len(t, acme.Months[1].Projects, 1)
+
+	beta := sut.Clients[1]
+	assert.Equal(t, "Beta Corp", beta.Client)
+	assert.Equal(t, 0.5, beta.Hours)
+	assert.Equal(t, 50.0, beta.Amount)
+
+	rows := sut.Rows()
+	assert.Len(t, rows, 5)
+	assert.Equal(t, []string{"Client", "Month", "Project", "Hours", "Hourly Rate", "Amount", "Currency"}, rows[0])
+	assert.Equal(t, []string{"Acme", "2023-05", "anchr", "3.00", "60.00", "180.00", "EUR"}, rows[1])
+	assert.Equal(t, []string{"Beta Corp", "2023-05", "website", "0.50", "100.00", "50.00", "USD"}, rows[4])
+}
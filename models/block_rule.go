@@ -0,0 +1,61 @@
+package models
+
+import "regexp"
+
+const (
+	BlockRuleFieldProject  = "project"
+	BlockRuleFieldLanguage = "language"
+	BlockRuleFieldEntity   = "entity"
+	BlockRuleFieldBranch   = "branch"
+)
+
+// BlockRule is an instance-wide rule set up by an admin to drop any incoming heartbeat, whose field matches the rule's regular expression, before it is persisted
+type BlockRule struct {
+	ID            uint           `json:"id" gorm:"primary_key"`
+	Field         string         `json:"field" gorm:"type:varchar(16)"`
+	Pattern       string         `json:"pattern" gorm:"type:varchar(255)"`
+	Comment       string         `json:"comment" gorm:"type:varchar(255)"`
+	Dropped       int64          `json:"dropped"` // number of heartbeats dropped by this rule so far
+	LastDroppedAt *CustomTime    `json:"last_dropped_at" gorm:"type:timestamp(3)" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt     CustomTime     `json:"created_at" gorm:"type:timestamp(3)" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	regex         *regexp.Regexp `gorm:"-"`
+}
+
+func (r *BlockRule) IsValid() bool {
+	if r.Field != BlockRuleFieldProject && r.Field != BlockRuleFieldLanguage && r.Field != BlockRuleFieldEntity && r.Field != BlockRuleFieldBranch {
+		return false
+	}
+	if r.Pattern == "" || len(r.Pattern) > 255 || len(r.Comment) > 255 {
+		return false
+	}
+	_, err := regexp.Compile(r.Pattern)
+	return err == nil
+}
+
+// Compile prepares the rule's pattern for matching, must be called before Matches()
+func (r *BlockRule) Compile() error {
+	regex, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return err
+	}
+	r.regex = regex
+	return nil
+}
+
+func (r *BlockRule) Matches(heartbeat *Heartbeat) bool {
+	if r.regex == nil {
+		return false
+	}
+
+	switch r.Field {
+	case BlockRuleFieldProject:
+		return heartbeat.Project != "" && r.regex.MatchString(heartbeat.Project)
+	case BlockRuleFieldLanguage:
+		return heartbeat.Language != "" && r.regex.MatchString(heartbeat.Language)
+	case BlockRuleFieldEntity:
+		return r.regex.MatchString(heartbeat.Entity)
+	case BlockRuleFieldBranch:
+		return heartbeat.Branch != "" && r.regex.MatchString(heartbeat.Branch)
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBlockRule_IsValid(t *testing.T) {
+	assert.True(t, (&BlockRule{Field: BlockRuleFieldProject, Pattern: "^confidential-.*$"}).IsValid())
+	assert.True(t, (&BlockRule{Field: BlockRuleFieldLanguage, Pattern: "^Go$", Comment: "no go"}).IsValid())
+	assert.False(t, (&BlockRule{Field: "editor", Pattern: "^vim$"}).IsValid())
+	assert.False(t, (&BlockRule{Field: BlockRuleFieldEntity, Pattern: "(unclosed"}).IsValid())
+	assert.False(t, (&BlockRule{Field: BlockRuleFieldBranch, Pattern: ""}).IsValid())
+}
+
+func TestBlockRule_Matches(t *testing.T) {
+	sut1 := &BlockRule{Field: BlockRuleFieldProject, Pattern: "^confidential-"}
+	sut2 := &BlockRule{Field: BlockRuleFieldLanguage, Pattern: "^COBOL$"}
+	sut3 := &BlockRule{Field: BlockRuleFieldEntity, Pattern: `/secret/`}
+	for _, r := range []*BlockRule{sut1, sut2, sut3} {
+		assert.Nil(t, r.Compile())
+	}
+
+	hb1 := &Heartbeat{Project: "confidential-merger", Language: "COBOL", Entity: "/home/me/secret/main.cbl"}
+	hb2 := &Heartbeat{Project: "wakapi", Language: "Go", Entity: "/home/me/dev/wakapi/main.go"}
+
+	assert.True(t, sut1.Matches(hb1))
+	assert.True(t, sut2.Matches(hb1))
+	assert.True(t, sut3.Matches(hb1))
+	assert.False(t, sut1.Matches(hb2))
+	assert.False(t, sut2.Matches(hb2))
+	assert.False(t, sut3.Matches(hb2))
+	assert.False(t, (&BlockRule{Field: BlockRuleFieldProject, Pattern: ".*"}).Matches(hb2)) // not compiled
+}
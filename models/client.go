@@ -0,0 +1,52 @@
+package models
+
+import "strings"
+
+// ClientReverseResolver returns all projects for a given client
+type ClientReverseResolver func(client string) []string
+
+// Client is a customer, under which a user groups the projects they work on for them, e.g. to report time per client across many repositories
+type Client struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"-" gorm:"not null; uniqueIndex:idx_client_user_name"`
+	Name      string     `json:"name" gorm:"not null; type:varchar(255); uniqueIndex:idx_client_user_name"`
+	Projects  []string   `json:"projects" gorm:"-"` // persisted as client projects
+	CreatedAt CustomTime `json:"created_at" gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// ClientProject assigns a project to a client, every project belongs to at most one client
+type ClientProject struct {
+	ID         uint    `gorm:"primary_key"`
+	User       *User   `gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID     string  `gorm:"not null; uniqueIndex:idx_client_project_user_project"`
+	Client     *Client `gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	ClientID   uint    `gorm:"not null; index:idx_client_project_client"`
+	ProjectKey string  `gorm:"not null; type:varchar(255); uniqueIndex:idx_client_project_user_project"`
+}
+
+func (c *Client) IsValid() bool {
+	if name := strings.TrimSpace(c.Name); name == "" || len(name) > 255 || name == UnknownSummaryKey {
+		return false
+	}
+	for _, p := range c.Projects {
+		if p == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ClientProjects returns the client's projects as assignments to be persisted
+func (c *Client) ClientProjects() []*ClientProject {
+	seen := make(map[string]bool, len(c.Projects))
+	assignments := make([]*ClientProject, 0, len(c.Projects))
+	for _, p := range c.Projects {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		assignments = append(assignments, &ClientProject{UserID: c.UserID, ClientID: c.ID, ProjectKey: p})
+	}
+	return assignments
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClient_IsValid(t *testing.T) {
+	assert.True(t, (&Client{Name: "Acme", Projects: []string{"wakapi", "anchr"}}).IsValid())
+	assert.True(t, (&Client{Name: "Acme"}).IsValid())
+	assert.False(t, (&Client{Name: " "}).IsValid())
+	assert.False(t, (&Client{Name: UnknownSummaryKey}).IsValid())
+	assert.False(t, (&Client{Name: "Acme", Projects: []string{"wakapi", ""}}).IsValid())
+}
+
+func TestClient_ClientProjects(t *testing.T) {
+	sut := &Client{ID: 1, UserID: "user1", Name: "Acme", Projects: []string{"wakapi", "anchr", "wakapi"}}
+
+	result := sut.ClientProjects()
+	assert.Len(t, result, 2)
+	assert.Equal(t, &ClientProject{UserID: "user1", ClientID: 1, ProjectKey: "wakapi"}, result[0])
+	assert.Equal(t, "anchr", result[1].ProjectKey)
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// OutdatedClient is a plugin or wakatime-cli version recently used by a user, which lags severely behind the latest known release
+type OutdatedClient struct {
+	Plugin        string     `json:"plugin"`
+	Version       string     `json:"version"`
+	LatestVersion string     `json:"latest_version"`
+	UserAgent     string     `json:"user_agent"`
+	LastSeenAt    CustomTime `json:"last_seen_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+func (c *OutdatedClient) Key() string {
+	return c.Plugin + "/" + c.Version
+}
+
+// IsVersionOutdated checks whether a version is a major version behind the latest one or lags behind by at least the given number of minor versions
+func IsVersionOutdated(version, latest string, minorLag int) bool {
+	v, ok1 := parseVersion(version)
+	l, ok2 := parseVersion(latest)
+	if !ok1 || !ok2 {
+		return false
+	}
+	if v[0] != l[0] {
+		return v[0] < l[0]
+	}
+	return l[1]-v[1] >= minorLag
+}
+
+// parseVersion parses major and minor from versions like "v1.73.1", "24.0.10" or "2.1.0-beta.1"
+func parseVersion(version string) ([2]int, bool) {
+	var result [2]int
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return result, false
+	}
+	for i := range result {
+		n, err := strconv.Atoi(strings.SplitN(parts[i], "-", 2)[0])
+		if err != nil {
+			return result, false
+		}
+		result[i] = n
+	}
+	return result, true
+}
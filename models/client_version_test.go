@@ -0,0 +1,18 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsVersionOutdated(t *testing.T) {
+	assert.False(t, IsVersionOutdated("1.90.0", "1.90.0", 10))
+	assert.False(t, IsVersionOutdated("v1.85.2", "v1.90.0", 10))
+	assert.True(t, IsVersionOutdated("v1.73.1", "1.90.0", 10))
+	assert.True(t, IsVersionOutdated("23.5.0", "24.0.10", 10))
+	assert.False(t, IsVersionOutdated("25.0.0", "24.0.10", 10))
+	assert.True(t, IsVersionOutdated("2.1.0-beta.1", "2.11.0", 10))
+	assert.False(t, IsVersionOutdated("unset", "1.90.0", 10))
+	assert.False(t, IsVersionOutdated("1.73.1", "", 10))
+}
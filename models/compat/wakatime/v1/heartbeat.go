@@ -28,6 +28,25 @@ type HeartbeatEntry struct {
 	MachineNameId string    `json:"machine_name_id"`
 	UserAgentId   string    `json:"user_agent_id"`
 	CreatedAt     time.Time `json:"created_at"`
+
+	Extras models.HeartbeatExtras `json:"-"` // fields unknown to wakapi, passed through as top-level fields
+}
+
+func (e *HeartbeatEntry) MarshalJSON() ([]byte, error) {
+	type entry HeartbeatEntry // prevent recursion
+	return models.MarshalWithExtras((*entry)(e), e.Extras)
+}
+
+func (e *HeartbeatEntry) UnmarshalJSON(data []byte) error {
+	type entry HeartbeatEntry // prevent recursion
+	var en entry
+	extras, err := models.UnmarshalWithExtras(data, &en)
+	if err != nil {
+		return err
+	}
+	*e = HeartbeatEntry(en)
+	e.Extras = extras
+	return nil
 }
 
 func HeartbeatsToCompat(entries []*models.Heartbeat) []*HeartbeatEntry {
@@ -48,6 +67,7 @@ func HeartbeatsToCompat(entries []*models.Heartbeat) []*HeartbeatEntry {
 			MachineNameId: entry.Machine,
 			UserAgentId:   entry.UserAgent,
 			CreatedAt:     entry.CreatedAt.T(),
+			Extras:        entry.Extras,
 		}
 	}
 	return out
@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"math"
+	"time"
+
+	"github.com/muety/wakapi/models"
+)
+
+// partially compatible with https://wakatime.com/developers#orgs, https://wakatime.com/developers#org_dashboards,
+// https://wakatime.com/developers#org_dashboard_members and https://wakatime.com/developers#org_dashboard_member_durations
+
+type Pagination struct {
+	Total      int  `json:"total"`
+	TotalPages int  `json:"total_pages"`
+	Page       int  `json:"page"`
+	PrevPage   *int `json:"prev_page"`
+	NextPage   *int `json:"next_page"`
+}
+
+type OrgsViewModel struct {
+	Data []*Org `json:"data"`
+	Pagination
+}
+
+type Org struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Timeout    int    `json:"timeout"` // minutes
+	WritesOnly bool   `json:"writes_only"`
+	Timezone   string `json:"timezone"`
+}
+
+type DashboardsViewModel struct {
+	Data []*Dashboard `json:"data"`
+	Pagination
+}
+
+type Dashboard struct {
+	ID                  string `json:"id"`
+	Name                string `json:"name"`
+	MembersCount        int    `json:"members_count"`
+	IsCurrentUserMember bool   `json:"is_current_user_member"`
+}
+
+type DashboardMembersViewModel struct {
+	Data []*DashboardMember `json:"data"`
+	Pagination
+}
+
+type DashboardMember struct {
+	ID         string `json:"id"`
+	Email      string `json:"email"`
+	FullName   string `json:"full_name"`
+	IsViewOnly bool   `json:"is_view_only"`
+	Photo      string `json:"photo"`
+	Username   string `json:"username"`
+}
+
+type DurationsViewModel struct {
+	Data     []*Duration `json:"data"`
+	Start    time.Time   `json:"start"`
+	End      time.Time   `json:"end"`
+	Timezone string      `json:"timezone"`
+}
+
+type Duration struct {
+	Project         string  `json:"project"`
+	Time            float64 `json:"time"`     // unix timestamp
+	Duration        float64 `json:"duration"` // seconds
+	Language        string  `json:"language"`
+	Editor          string  `json:"editor"`
+	OperatingSystem string  `json:"operating_system"`
+	Machine         string  `json:"machine"`
+	Branch          string  `json:"branch"`
+	Entity          string  `json:"entity,omitempty"`
+}
+
+// NewPagination describes the given page (starting at 1) of items, split into pages of the given size
+func NewPagination(total, page, pageSize int) Pagination {
+	p := Pagination{
+		Total:      total,
+		TotalPages: int(math.Max(1, math.Ceil(float64(total)/float64(pageSize)))),
+		Page:       page,
+	}
+	if page > 1 {
+		prev := page - 1
+		p.PrevPage = &prev
+	}
+	if page < p.TotalPages {
+		next := page + 1
+		p.NextPage = &next
+	}
+	return p
+}
+
+func NewDashboardMemberFrom(user *models.User) *DashboardMember {
+	u := NewFromUser(user)
+	return &DashboardMember{
+		ID:       u.ID,
+		Email:    u.Email,
+		FullName: u.FullName,
+		Photo:    u.Photo,
+		Username: u.Username,
+	}
+}
+
+func NewDurationsFrom(durations models.Durations, from, to time.Time) *DurationsViewModel {
+	data := make([]*Duration, len(durations))
+	for i, d := range durations {
+		data[i] = &Duration{
+			Project:         d.Project,
+			Time:            float64(d.Time.T().UnixNano()) / 1e9,
+			Duration:        d.Duration.Seconds(),
+			Language:        d.Language,
+			Editor:          d.Editor,
+			OperatingSystem: d.OperatingSystem,
+			Machine:         d.Machine,
+			Branch:          d.Branch,
+			Entity:          d.Entity,
+		}
+	}
+
+	return &DurationsViewModel{
+		Data:     data,
+		Start:    from,
+		End:      to,
+		Timezone: from.Location().String(),
+	}
+}
@@ -1,16 +1,75 @@
 package v1
 
-import "time"
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+)
+
+// partially compatible with https://wakatime.com/developers#projects
 
 type ProjectsViewModel struct {
 	Data []*Project `json:"data"`
+	Pagination
 }
 
 type Project struct {
-	ID                           string    `json:"id"`
-	Name                         string    `json:"name"`
-	LastHeartbeatAt              time.Time `json:"last_heartbeat_at"`
-	HumanReadableLastHeartbeatAt string    `json:"human_readable_last_heartbeat_at"`
-	UrlencodedName               string    `json:"urlencoded_name"`
-	CreatedAt                    time.Time `json:"created_at"`
+	ID                            string             `json:"id"`
+	Name                          string             `json:"name"`
+	Repository                    *ProjectRepository `json:"repository"`
+	Badge                         interface{}        `json:"badge"`
+	Color                         *string            `json:"color"`
+	HasPublicUrl                  bool               `json:"has_public_url"`
+	FirstHeartbeatAt              time.Time          `json:"first_heartbeat_at"`
+	HumanReadableFirstHeartbeatAt string             `json:"human_readable_first_heartbeat_at"`
+	LastHeartbeatAt               time.Time          `json:"last_heartbeat_at"`
+	HumanReadableLastHeartbeatAt  string             `json:"human_readable_last_heartbeat_at"`
+	UrlencodedName                string             `json:"urlencoded_name"`
+	CreatedAt                     time.Time          `json:"created_at"`
+}
+
+type ProjectRepository struct {
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	Url      string `json:"url"`
+	HtmlUrl  string `json:"html_url"`
+	Provider string `json:"provider"`
+}
+
+// NewProjectFrom converts a project's stats to its wakatime representation, including the repository, if a remote is known for the project
+func NewProjectFrom(stats *models.ProjectStats, remote *models.ProjectRemote) *Project {
+	return &Project{
+		ID:                            stats.Project,
+		Name:                          stats.Project,
+		Repository:                    NewProjectRepositoryFrom(remote),
+		FirstHeartbeatAt:              stats.First.T(),
+		HumanReadableFirstHeartbeatAt: helpers.FormatDateTimeHuman(stats.First.T()),
+		LastHeartbeatAt:               stats.Last.T(),
+		HumanReadableLastHeartbeatAt:  helpers.FormatDateTimeHuman(stats.Last.T()),
+		UrlencodedName:                url.QueryEscape(stats.Project),
+		CreatedAt:                     stats.First.T(),
+	}
+}
+
+func NewProjectRepositoryFrom(remote *models.ProjectRemote) *ProjectRepository {
+	if remote == nil {
+		return nil
+	}
+	webUrl := models.ParseRemoteWebUrl(remote.Remote)
+	if webUrl == "" {
+		return nil
+	}
+
+	u, _ := url.Parse(webUrl)
+	fullName := strings.TrimPrefix(u.Path, "/")
+	return &ProjectRepository{
+		Name:     fullName[strings.LastIndex(fullName, "/")+1:],
+		FullName: fullName,
+		Url:      webUrl,
+		HtmlUrl:  webUrl,
+		Provider: strings.TrimPrefix(u.Hostname(), "www."),
+	}
 }
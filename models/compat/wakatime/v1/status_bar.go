@@ -0,0 +1,144 @@
+package v1
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+)
+
+// https://wakatime.com/api/v1/users/current/status_bar/today
+// no official documentation, schema as queried by wakatime-cli's --today flag and the editor plugins' status bars
+
+// StatusBarCategoryCoding is the only category reported, as summaries are not aggregated by heartbeat category
+const StatusBarCategoryCoding = "Coding"
+
+type StatusBarViewModel struct {
+	CachedAt        time.Time      `json:"cached_at"`
+	Data            *StatusBarData `json:"data"`
+	HasTeamFeatures bool           `json:"has_team_features"`
+}
+
+type StatusBarData struct {
+	Categories       []*StatusBarEntry   `json:"categories"`
+	Dependencies     []*StatusBarEntry   `json:"dependencies"`
+	Editors          []*StatusBarEntry   `json:"editors"`
+	Languages        []*StatusBarEntry   `json:"languages"`
+	Machines         []*StatusBarEntry   `json:"machines"`
+	OperatingSystems []*StatusBarEntry   `json:"operating_systems"`
+	Projects         []*StatusBarEntry   `json:"projects"`
+	GrandTotal       *StatusBarTotal     `json:"grand_total"`
+	Range            *StatusBarDataRange `json:"range"`
+}
+
+type StatusBarEntry struct {
+	Decimal      string  `json:"decimal"`
+	Digital      string  `json:"digital"`
+	Hours        int     `json:"hours"`
+	Minutes      int     `json:"minutes"`
+	Name         string  `json:"name"`
+	Percent      float64 `json:"percent"`
+	Seconds      int     `json:"seconds"`
+	Text         string  `json:"text"`
+	TotalSeconds float64 `json:"total_seconds"`
+}
+
+type StatusBarTotal struct {
+	Decimal      string  `json:"decimal"`
+	Digital      string  `json:"digital"`
+	Hours        int     `json:"hours"`
+	Minutes      int     `json:"minutes"`
+	Text         string  `json:"text"`
+	TotalSeconds float64 `json:"total_seconds"`
+}
+
+type StatusBarDataRange struct {
+	Date     string    `json:"date"`
+	End      time.Time `json:"end"`
+	Start    time.Time `json:"start"`
+	Text     string    `json:"text"`
+	Timezone string    `json:"timezone"`
+}
+
+// NewStatusBarFrom converts a summary of the given range into the status bar schema, with range start and end as requested rather than those of the summary's first and last activity
+func NewStatusBarFrom(summary *models.Summary, interval *models.IntervalKey, from, to time.Time) *StatusBarViewModel {
+	total := summary.TotalTime()
+
+	categories := make([]*StatusBarEntry, 0, 1)
+	if total > 0 {
+		categories = append(categories, newStatusBarEntry(StatusBarCategoryCoding, total, total))
+	}
+
+	var rangeText string
+	if interval != nil {
+		rangeText = interval.GetHumanReadable()
+	}
+
+	return &StatusBarViewModel{
+		CachedAt: time.Now(),
+		Data: &StatusBarData{
+			Categories:       categories,
+			Dependencies:     make([]*StatusBarEntry, 0),
+			Editors:          newStatusBarEntries(summary, models.SummaryEditor),
+			Languages:        newStatusBarEntries(summary, models.SummaryLanguage),
+			Machines:         newStatusBarEntries(summary, models.SummaryMachine),
+			OperatingSystems: newStatusBarEntries(summary, models.SummaryOS),
+			Projects:         newStatusBarEntries(summary, models.SummaryProject),
+			GrandTotal:       newStatusBarTotal(total),
+			Range: &StatusBarDataRange{
+				Date:     from.Format(time.DateOnly),
+				End:      to,
+				Start:    from,
+				Text:     rangeText,
+				Timezone: from.Location().String(),
+			},
+		},
+	}
+}
+
+func newStatusBarEntries(summary *models.Summary, entityType uint8) []*StatusBarEntry {
+	items := *summary.ItemsByType(entityType)
+	entityTotal := summary.TotalTimeBy(entityType)
+
+	entries := make([]*StatusBarEntry, len(items))
+	for i, item := range items {
+		entries[i] = newStatusBarEntry(item.Key, item.TotalFixed(), entityTotal)
+	}
+	return entries
+}
+
+func newStatusBarEntry(name string, total, entityTotal time.Duration) *StatusBarEntry {
+	t := newStatusBarTotal(total)
+	percentage := math.Round((total.Seconds()/entityTotal.Seconds())*1e4) / 100
+	if math.IsNaN(percentage) || math.IsInf(percentage, 0) {
+		percentage = 0
+	}
+
+	return &StatusBarEntry{
+		Decimal:      t.Decimal,
+		Digital:      t.Digital,
+		Hours:        t.Hours,
+		Minutes:      t.Minutes,
+		Name:         name,
+		Percent:      percentage,
+		Seconds:      int((total - time.Duration(t.Hours)*time.Hour - time.Duration(t.Minutes)*time.Minute).Seconds()),
+		Text:         t.Text,
+		TotalSeconds: total.Seconds(),
+	}
+}
+
+func newStatusBarTotal(total time.Duration) *StatusBarTotal {
+	hrs := int(total.Hours())
+	mins := int((total - time.Duration(hrs)*time.Hour).Minutes())
+
+	return &StatusBarTotal{
+		Decimal:      fmt.Sprintf("%.2f", total.Hours()),
+		Digital:      fmt.Sprintf("%d:%02d", hrs, mins),
+		Hours:        hrs,
+		Minutes:      mins,
+		Text:         helpers.FmtWakatimeDuration(total),
+		TotalSeconds: total.Seconds(),
+	}
+}
@@ -33,6 +33,7 @@ type User struct {
 	CreatedAt        models.CustomTime `json:"created_at"`
 	ModifiedAt       models.CustomTime `json:"modified_at"`
 	Photo            string            `json:"photo"`
+	Timeout          int               `json:"timeout"` // minutes
 }
 
 func NewFromUser(user *models.User) *User {
@@ -57,6 +58,7 @@ func NewFromUser(user *models.User) *User {
 		CreatedAt:   user.CreatedAt,
 		ModifiedAt:  user.CreatedAt,
 		Photo:       avatarURL,
+		Timeout:     int(user.HeartbeatsTimeout().Minutes()),
 	}
 }
 
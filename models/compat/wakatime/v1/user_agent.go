@@ -1,13 +1,51 @@
 package v1
 
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+)
+
 type UserAgentsViewModel struct {
 	Data       []*UserAgentEntry `json:"data"`
 	TotalPages int               `json:"total_pages"`
 }
 
 type UserAgentEntry struct {
-	Id     string `json:"id"`
-	Editor string `json:"editor"`
-	Os     string `json:"os"`
-	Value  string `json:"value"`
+	Id                 string    `json:"id"`
+	Editor             string    `json:"editor"`
+	Os                 string    `json:"os"`
+	Value              string    `json:"value"`
+	Version            string    `json:"version,omitempty"`
+	EditorVersion      string    `json:"editor_version,omitempty"`
+	CliVersion         string    `json:"cli_version,omitempty"`
+	GoVersion          string    `json:"go_version,omitempty"`
+	IsBrowserExtension bool      `json:"is_browser_extension"`
+	IsDesktopApp       bool      `json:"is_desktop_app"`
+	CreatedAt          time.Time `json:"created_at"`
+	FirstSeenAt        time.Time `json:"first_seen_at"`
+	LastSeenAt         time.Time `json:"last_seen_at"`
+}
+
+func NewUserAgentsFrom(stats []*models.UserAgentStats) *UserAgentsViewModel {
+	data := make([]*UserAgentEntry, len(stats))
+	for i, s := range stats {
+		info := models.ParseUserAgentInfo(s.UserAgent)
+		data[i] = &UserAgentEntry{
+			Id:                 s.UserAgent, // no dedicated ids for user agents, heartbeats reference them by value
+			Editor:             info.Editor,
+			Os:                 info.Os,
+			Value:              s.UserAgent,
+			Version:            info.PluginVersion,
+			EditorVersion:      info.EditorVersion,
+			CliVersion:         info.CliVersion,
+			GoVersion:          info.GoVersion,
+			IsBrowserExtension: info.IsBrowserExtension(),
+			IsDesktopApp:       info.IsDesktopApp(),
+			CreatedAt:          s.First.T(),
+			FirstSeenAt:        s.First.T(),
+			LastSeenAt:         s.Last.T(),
+		}
+	}
+	return &UserAgentsViewModel{Data: data, TotalPages: 1}
 }
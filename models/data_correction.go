@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+const (
+	DataCorrectionStatusPending  = "pending"
+	DataCorrectionStatusApproved = "approved" // approved, but not applied yet
+	DataCorrectionStatusApplied  = "applied"
+	DataCorrectionStatusRejected = "rejected"
+	DataCorrectionStatusFailed   = "failed"
+)
+
+const (
+	DataCorrectionDecisionApprove = "approve"
+	DataCorrectionDecisionReject  = "reject"
+)
+
+// DataCorrection is a member's request to reassign their coding time within a time range from one project to another (org mode only)
+// it only takes effect once approved by an admin and is kept afterwards as an audit trail of who requested and reviewed which change when
+type DataCorrection struct {
+	ID            uint        `json:"id" gorm:"primary_key"`
+	User          *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID        string      `json:"user_id" gorm:"not null; index:idx_data_correction_user"`
+	FromProject   string      `json:"from_project" gorm:"not null; size:255"`
+	ToProject     string      `json:"to_project" gorm:"not null; size:255"`
+	FromTime      CustomTime  `json:"from" gorm:"type:timestamp(3)" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	ToTime        CustomTime  `json:"to" gorm:"type:timestamp(3)" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	Reason        string      `json:"reason" gorm:"type:text"`
+	Status        string      `json:"status" gorm:"not null; default:pending; size:32; index:idx_data_correction_status"`
+	Reviewer      *User       `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	ReviewerID    *string     `json:"reviewer_id"` // pointer because nullable
+	ReviewComment string      `json:"review_comment" gorm:"type:text"`
+	ReviewedAt    *CustomTime `json:"reviewed_at" gorm:"type:timestamp(3)" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	AppliedAt     *CustomTime `json:"applied_at" gorm:"type:timestamp(3)" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	Rewritten     int64       `json:"rewritten"` // number of heartbeats moved to the target project
+	CreatedAt     CustomTime  `json:"created_at" gorm:"type:timestamp(3); default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type DataCorrectionRequest struct {
+	FromProject string    `json:"from_project"`
+	ToProject   string    `json:"to_project"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Reason      string    `json:"reason"`
+}
+
+type DataCorrectionReview struct {
+	Decision string `json:"decision"`
+	Comment  string `json:"comment"`
+}
+
+func (c *DataCorrection) IsValid() bool {
+	return c.UserID != "" &&
+		c.FromProject != "" &&
+		c.ToProject != "" &&
+		c.FromProject != c.ToProject &&
+		c.FromTime.T().Before(c.ToTime.T())
+}
+
+func (c *DataCorrection) IsPending() bool {
+	return c.Status == DataCorrectionStatusPending
+}
+
+func (r *DataCorrectionReview) IsValid() bool {
+	return r.Decision == DataCorrectionDecisionApprove || r.Decision == DataCorrectionDecisionReject
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// DevicePairing represents a pending device authorization request as of https://datatracker.ietf.org/doc/html/rfc8628, e.g. issued by a companion app or a headless agent
+// The device polls using the secret DeviceCode, while the user confirms the request by entering the short UserCode in the web interface
+type DevicePairing struct {
+	DeviceCode              string    `json:"device_code"`
+	UserCode                string    `json:"user_code"`
+	VerificationUri         string    `json:"verification_uri"`
+	VerificationUriComplete string    `json:"verification_uri_complete"`
+	ExpiresIn               int       `json:"expires_in"` // seconds
+	Interval                int       `json:"interval"`   // seconds
+	ClientID                string    `json:"-"`
+	Scope                   string    `json:"-"`
+	UserID                  string    `json:"-"`
+	ExpiresAt               time.Time `json:"-"`
+	LastPolledAt            time.Time `json:"-"`
+}
+
+func (p *DevicePairing) IsConfirmed() bool {
+	return p.UserID != ""
+}
+
+func (p *DevicePairing) IsExpired() bool {
+	return time.Now().After(p.ExpiresAt)
+}
+
+func (p *DevicePairing) Scopes() []string {
+	return strings.Fields(p.Scope)
+}
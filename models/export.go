@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+const (
+	ExportStatusNone       = "none"
+	ExportStatusPending    = "pending"
+	ExportStatusReady      = "ready"
+	ExportStatusFailed     = "failed"
+	ExportArchiveExtension = ".zip"
+)
+
+// UserExportProfile is the machine-readable representation of a user's account and settings as part of a data export
+// Unlike User, it intentionally includes settings fields, but excludes any secrets (password hash, api keys, tokens)
+type UserExportProfile struct {
+	ID                 string      `json:"id"`
+	Email              string      `json:"email"`
+	Location           string      `json:"location"`
+	CreatedAt          CustomTime  `json:"created_at"`
+	LastLoggedInAt     CustomTime  `json:"last_logged_in_at"`
+	ShareDataMaxDays   int         `json:"share_data_max_days"`
+	ShareEditors       bool        `json:"share_editors"`
+	ShareLanguages     bool        `json:"share_languages"`
+	ShareProjects      bool        `json:"share_projects"`
+	ShareOSs           bool        `json:"share_oss"`
+	ShareMachines      bool        `json:"share_machines"`
+	ShareLabels        bool        `json:"share_labels"`
+	SharePresence      bool        `json:"share_presence"`
+	ReportsWeekly      bool        `json:"reports_weekly"`
+	PublicLeaderboard  bool        `json:"public_leaderboard"`
+	NotificationDigest bool        `json:"notification_digest"`
+	QuietHoursStart    int         `json:"quiet_hours_start"`
+	QuietHoursEnd      int         `json:"quiet_hours_end"`
+	SubscribedUntil    *CustomTime `json:"subscribed_until"`
+}
+
+type UserExport struct {
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // only set if ready
+	Path      string    `json:"-"`
+}
+
+func (e *UserExport) IsPending() bool {
+	return e.Status == ExportStatusPending
+}
+
+func NewUserExportProfile(u *User) *UserExportProfile {
+	return &UserExportProfile{
+		ID:                 u.ID,
+		Email:              u.Email,
+		Location:           u.Location,
+		CreatedAt:          u.CreatedAt,
+		LastLoggedInAt:     u.LastLoggedInAt,
+		ShareDataMaxDays:   u.ShareDataMaxDays,
+		ShareEditors:       u.ShareEditors,
+		ShareLanguages:     u.ShareLanguages,
+		ShareProjects:      u.ShareProjects,
+		ShareOSs:           u.ShareOSs,
+		ShareMachines:      u.ShareMachines,
+		ShareLabels:        u.ShareLabels,
+		SharePresence:      u.SharePresence,
+		ReportsWeekly:      u.ReportsWeekly,
+		PublicLeaderboard:  u.PublicLeaderboard,
+		NotificationDigest: u.NotificationDigest,
+		QuietHoursStart:    u.QuietHoursStart,
+		QuietHoursEnd:      u.QuietHoursEnd,
+		SubscribedUntil:    u.SubscribedUntil,
+	}
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/muety/wakapi/utils"
+)
+
+const ExportTargetEncryption = "AES256"
+
+// ExportTarget is an s3 bucket owned by a user, to which their data export archive is uploaded on a regular basis, as a personal backup independent of the instance operator
+// dumps are encrypted at rest by the store and tagged with the desired retention period, so that the bucket's lifecycle rules can expire them
+type ExportTarget struct {
+	UserID        string          `json:"-" gorm:"primary_key"`
+	User          *User           `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Endpoint      string          `json:"endpoint" gorm:"type:varchar(255)"` // blank for aws
+	Region        string          `json:"region" gorm:"not null; type:varchar(64)"`
+	Bucket        string          `json:"bucket" gorm:"not null; type:varchar(255)"`
+	Prefix        string          `json:"prefix" gorm:"type:varchar(255)"`
+	PathStyle     bool            `json:"path_style"`
+	AccessKey     string          `json:"access_key" gorm:"not null; type:varchar(255)"`
+	SecretKey     EncryptedString `json:"-" gorm:"not null; type:varchar(255)"` // encrypted at rest, never returned
+	RetentionDays int             `json:"retention_days"`                       // lifecycle hint only, 0 to keep dumps forever
+	LastDumpKey   string          `json:"last_dump_key" gorm:"type:varchar(1024)"`
+	LastDumpAt    *CustomTime     `json:"last_dump_at" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	LastError     string          `json:"last_error" gorm:"type:varchar(1024)"`
+}
+
+func (t *ExportTarget) S3Options() utils.S3Options {
+	return utils.S3Options{
+		Endpoint:  t.Endpoint,
+		Region:    t.Region,
+		Bucket:    t.Bucket,
+		AccessKey: t.AccessKey,
+		SecretKey: string(t.SecretKey),
+		PathStyle: t.PathStyle,
+	}
+}
+
+// DumpKey returns the object key of a dump created at the given time, e.g. "backups/wakapi_export_john_20240101T030000Z.zip"
+func (t *ExportTarget) DumpKey(at time.Time) string {
+	prefix := strings.TrimPrefix(t.Prefix, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return fmt.Sprintf("%swakapi_export_%s_%s%s", prefix, t.UserID, at.UTC().Format("20060102T150405Z"), ExportArchiveExtension)
+}
+
+// DumpTags returns the tags to attach to every dump, which lifecycle rules can filter on
+func (t *ExportTarget) DumpTags() map[string]string {
+	tags := map[string]string{"source": "wakapi"}
+	if t.RetentionDays > 0 {
+		tags["retention-days"] = fmt.Sprintf("%d", t.RetentionDays)
+	}
+	return tags
+}
+
+type ExportTargetRequest struct {
+	Endpoint      string `json:"endpoint"`
+	Region        string `json:"region"`
+	Bucket        string `json:"bucket"`
+	Prefix        string `json:"prefix"`
+	PathStyle     bool   `json:"path_style"`
+	AccessKey     string `json:"access_key"`
+	SecretKey     string `json:"secret_key"`
+	RetentionDays int    `json:"retention_days"`
+}
+
+func (r *ExportTargetRequest) IsValid() bool {
+	return r.Region != "" && r.Bucket != "" && r.AccessKey != "" && r.SecretKey != "" && r.RetentionDays >= 0
+}
+
+func (r *ExportTargetRequest) Target() *ExportTarget {
+	return &ExportTarget{
+		Endpoint:      strings.TrimSpace(r.Endpoint),
+		Region:        strings.TrimSpace(r.Region),
+		Bucket:        strings.TrimSpace(r.Bucket),
+		Prefix:        strings.TrimSpace(r.Prefix),
+		PathStyle:     r.PathStyle,
+		AccessKey:     strings.TrimSpace(r.AccessKey),
+		SecretKey:     EncryptedString(strings.TrimSpace(r.SecretKey)),
+		RetentionDays: r.RetentionDays,
+	}
+}
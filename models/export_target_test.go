@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportTarget_SecretKey(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Security.EncryptionKey = "encryption key"
+	config.Set(cfg)
+
+	target := (&ExportTargetRequest{Region: "local", Bucket: "backups", AccessKey: "key", SecretKey: " wJalrXUtnFEMI/K7MDENG "}).Target()
+	assert.Equal(t, "wJalrXUtnFEMI/K7MDENG", target.S3Options().SecretKey)
+
+	stored, err := target.SecretKey.Value()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(stored.(string), "enc:"))
+	assert.NotContains(t, stored, "wJalrXUtnFEMI")
+
+	var secretKey EncryptedString
+	assert.Nil(t, secretKey.Scan([]byte(stored.(string))))
+	assert.Equal(t, target.SecretKey, secretKey)
+
+	assert.Nil(t, secretKey.Scan("legacy plaintext"))
+	assert.Equal(t, EncryptedString("legacy plaintext"), secretKey)
+
+	serialized, _ := json.Marshal(target)
+	assert.NotContains(t, string(serialized), "wJalrXUtnFEMI")
+
+	cfg.Security.EncryptionKey = ""
+	_, err = target.SecretKey.Value()
+	assert.Error(t, err)
+}
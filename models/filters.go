@@ -2,23 +2,50 @@ package models
 
 import (
 	"fmt"
+	"github.com/duke-git/lancet/v2/slice"
 	"github.com/emvi/logbuch"
 	"github.com/mitchellh/hashstructure/v2"
+	"regexp"
 )
 
 type Filters struct {
-	Project  OrFilter
-	OS       OrFilter
-	Language OrFilter
-	Editor   OrFilter
-	Machine  OrFilter
-	Label    OrFilter
-	Branch   OrFilter
-	Entity   OrFilter
+	Project   OrFilter
+	OS        OrFilter
+	Language  OrFilter
+	Editor    OrFilter
+	Machine   OrFilter
+	Label     OrFilter
+	Branch    OrFilter
+	Entity    OrFilter
+	Namespace OrFilter
+	Client    OrFilter
+	// additional conditions beyond plain equality, all of which (and the above or-filters) must hold
+	Conditions []*FilterCondition
 }
 
 type OrFilter []string
 
+// FilterCondition either excludes a set of keys or matches (or excludes) keys by a regular expression
+type FilterCondition struct {
+	Entity  uint8
+	Keys    []string // exactly one pattern in case of regex conditions
+	Negated bool
+	Regex   bool
+	pattern *regexp.Regexp
+}
+
+// ColumnCondition is a filter condition resolved to a heartbeat column, to be evaluated as part of a database query
+// regex conditions are the exception, they are matched against fetched heartbeats instead, because sqlite has no regexp function
+// and postgres' and mysql's regex flavors differ from the re2 syntax patterns are validated against
+type ColumnCondition struct {
+	Entity  uint8
+	Column  string
+	Keys    []string
+	Negated bool
+	Regex   bool
+	pattern *regexp.Regexp
+}
+
 func (f OrFilter) Exists() bool {
 	return len(f) > 0 && f[0] != ""
 }
@@ -32,6 +59,40 @@ func (f OrFilter) MatchAny(search string) bool {
 	return false
 }
 
+func NewExclusionCondition(entity uint8, keys []string) *FilterCondition {
+	return &FilterCondition{Entity: entity, Keys: keys, Negated: true}
+}
+
+func NewRegexCondition(entity uint8, pattern string, negated bool) (*FilterCondition, error) {
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &FilterCondition{Entity: entity, Keys: []string{pattern}, Negated: negated, Regex: true, pattern: compiled}, nil
+}
+
+// Match evaluates the condition against a raw (i.e. possibly empty) key
+func (c *FilterCondition) Match(key string) bool {
+	var matches bool
+	if c.Regex {
+		if c.pattern == nil {
+			c.pattern = regexp.MustCompile(c.Keys[0]) // only ever constructed from validated patterns
+		}
+		matches = c.pattern.MatchString(key)
+	} else {
+		matches = OrFilter(c.Keys).MatchAny(key)
+	}
+	return matches != c.Negated
+}
+
+// Match evaluates a regex condition against the respective field of a heartbeat
+func (c *ColumnCondition) Match(h *Heartbeat) bool {
+	if c.pattern == nil {
+		c.pattern = regexp.MustCompile(c.Keys[0]) // only ever constructed from validated patterns
+	}
+	return c.pattern.MatchString(h.getRawKey(c.Entity)) != c.Negated
+}
+
 type FilterElement struct {
 	entity uint8
 	filter OrFilter
@@ -68,10 +129,19 @@ func (f *Filters) WithMultiple(entity uint8, keys []string) *Filters {
 		f.Branch = append(f.Branch, keys...)
 	case SummaryEntity:
 		f.Entity = append(f.Entity, keys...)
+	case SummaryNamespace:
+		f.Namespace = append(f.Namespace, keys...)
+	case SummaryClient:
+		f.Client = append(f.Client, keys...)
 	}
 	return f
 }
 
+func (f *Filters) WithCondition(condition *FilterCondition) *Filters {
+	f.Conditions = append(f.Conditions, condition)
+	return f
+}
+
 func (f *Filters) One() (bool, uint8, OrFilter) {
 	if f.Project != nil && f.Project.Exists() {
 		return true, SummaryProject, f.Project
@@ -89,6 +159,10 @@ func (f *Filters) One() (bool, uint8, OrFilter) {
 		return true, SummaryBranch, f.Branch
 	} else if f.Entity != nil && f.Entity.Exists() {
 		return true, SummaryEntity, f.Entity
+	} else if f.Namespace != nil && f.Namespace.Exists() {
+		return true, SummaryNamespace, f.Namespace
+	} else if f.Client != nil && f.Client.Exists() {
+		return true, SummaryClient, f.Client
 	}
 	return false, 0, OrFilter{}
 }
@@ -102,12 +176,12 @@ func (f *Filters) OneOrEmpty() FilterElement {
 
 func (f *Filters) IsEmpty() bool {
 	nonEmpty, _, _ := f.One()
-	return !nonEmpty
+	return !nonEmpty && len(f.Conditions) == 0
 }
 
 func (f *Filters) Count() int {
 	var count int
-	for i := SummaryProject; i <= SummaryEntity; i++ {
+	for i := SummaryProject; i <= SummaryClient; i++ {
 		count += f.CountByEntity(i)
 	}
 	return count
@@ -119,7 +193,7 @@ func (f *Filters) CountByEntity(entity uint8) int {
 
 func (f *Filters) EntityCount() int {
 	var count int
-	for i := SummaryProject; i <= SummaryEntity; i++ {
+	for i := SummaryProject; i <= SummaryClient; i++ {
 		if c := f.CountByEntity(i); c > 0 {
 			count++
 		}
@@ -127,6 +201,48 @@ func (f *Filters) EntityCount() int {
 	return count
 }
 
+// Entities returns the types of all entities filtered by, either directly or through a condition
+func (f *Filters) Entities() []uint8 {
+	entities := make([]uint8, 0)
+	for _, t := range SummaryTypes() {
+		if f.CountByEntity(t) > 0 || slice.ContainBy[*FilterCondition](f.Conditions, func(c *FilterCondition) bool { return c.Entity == t }) {
+			entities = append(entities, t)
+		}
+	}
+	return entities
+}
+
+// IsNative returns true if all filters apply to fields stored as part of a heartbeat itself, i.e. there is no filter by label
+func (f *Filters) IsNative() bool {
+	for _, t := range SummaryTypes() {
+		if !slice.Contain(NativeSummaryTypes(), t) && f.CountByEntity(t) > 0 {
+			return false
+		}
+	}
+	for _, c := range f.Conditions {
+		if !slice.Contain(NativeSummaryTypes(), c.Entity) {
+			return false
+		}
+	}
+	return true
+}
+
+// ColumnConditions translates all filters on fields stored as part of a heartbeat into conditions on the respective database columns
+func (f *Filters) ColumnConditions() []*ColumnCondition {
+	conditions := make([]*ColumnCondition, 0)
+	for _, t := range NativeSummaryTypes() {
+		if keys := *f.ResolveEntity(t); len(keys) > 0 {
+			conditions = append(conditions, &ColumnCondition{Entity: t, Column: GetEntityColumn(t), Keys: keys})
+		}
+	}
+	for _, c := range f.Conditions {
+		if slice.Contain(NativeSummaryTypes(), c.Entity) && len(c.Keys) > 0 {
+			conditions = append(conditions, &ColumnCondition{Entity: c.Entity, Column: GetEntityColumn(c.Entity), Keys: c.Keys, Negated: c.Negated, Regex: c.Regex})
+		}
+	}
+	return conditions
+}
+
 func (f *Filters) ResolveEntity(entityId uint8) *OrFilter {
 	switch entityId {
 	case SummaryProject:
@@ -145,6 +261,10 @@ func (f *Filters) ResolveEntity(entityId uint8) *OrFilter {
 		return &f.Branch
 	case SummaryEntity:
 		return &f.Entity
+	case SummaryNamespace:
+		return &f.Namespace
+	case SummaryClient:
+		return &f.Client
 	default:
 		return &OrFilter{}
 	}
@@ -163,7 +283,45 @@ func (f *Filters) Match(h *Heartbeat) bool {
 		(f.OS == nil || f.OS.MatchAny(h.OperatingSystem)) &&
 		(f.Language == nil || f.Language.MatchAny(h.Language)) &&
 		(f.Editor == nil || f.Editor.MatchAny(h.Editor)) &&
-		(f.Machine == nil || f.Machine.MatchAny(h.Machine))
+		(f.Machine == nil || f.Machine.MatchAny(h.Machine)) &&
+		f.matchConditions(h)
+}
+
+func (f *Filters) matchConditions(h *Heartbeat) bool {
+	for _, c := range f.Conditions {
+		if !slice.Contain(NativeSummaryTypes(), c.Entity) {
+			continue
+		}
+		if !c.Match(h.getRawKey(c.Entity)) {
+			return false
+		}
+	}
+	return true
+}
+
+// WithProjectGroups adds the member projects of every project group filtered by (or excluded) as additional filter keys
+// has to be applied before WithAliases, such that aliases of the members are considered as well
+func (f *Filters) WithProjectGroups(resolve ProjectGroupReverseResolver) *Filters {
+	if f.Project != nil {
+		updated := OrFilter(make([]string, 0, len(f.Project)))
+		for _, e := range f.Project {
+			updated = append(updated, e)
+			updated = append(updated, resolve(e)...)
+		}
+		f.Project = updated
+	}
+	for _, c := range f.Conditions {
+		if c.Entity != SummaryProject || c.Regex {
+			continue
+		}
+		updated := make([]string, 0, len(c.Keys))
+		for _, e := range c.Keys {
+			updated = append(updated, e)
+			updated = append(updated, resolve(e)...)
+		}
+		c.Keys = updated
+	}
+	return f
 }
 
 // WithAliases adds OR-conditions for every alias of a filter key as additional filter keys
@@ -216,11 +374,23 @@ func (f *Filters) WithAliases(resolve AliasReverseResolver) *Filters {
 		}
 		f.Branch = updated
 	}
+	for _, c := range f.Conditions {
+		if c.Regex || !slice.Contain(NativeSummaryTypes(), c.Entity) {
+			continue
+		}
+		updated := make([]string, 0, len(c.Keys))
+		for _, e := range c.Keys {
+			updated = append(updated, e)
+			updated = append(updated, resolve(c.Entity, e)...)
+		}
+		c.Keys = updated
+	}
 	// no aliases for entites / files
 	return f
 }
 
 func (f *Filters) WithProjectLabels(resolve ProjectLabelReverseResolver) *Filters {
+	f.withProjectExclusions(SummaryLabel, resolve)
 	if f.Label == nil || !f.Label.Exists() {
 		return f
 	}
@@ -230,6 +400,44 @@ func (f *Filters) WithProjectLabels(resolve ProjectLabelReverseResolver) *Filter
 	return f
 }
 
+func (f *Filters) WithProjectNamespaces(resolve ProjectNamespaceReverseResolver) *Filters {
+	f.withProjectExclusions(SummaryNamespace, resolve)
+	if f.Namespace == nil || !f.Namespace.Exists() {
+		return f
+	}
+	for _, ns := range f.Namespace {
+		f.WithMultiple(SummaryProject, resolve(ns))
+	}
+	return f
+}
+
+func (f *Filters) WithClients(resolve ClientReverseResolver) *Filters {
+	f.withProjectExclusions(SummaryClient, resolve)
+	if f.Client == nil || !f.Client.Exists() {
+		return f
+	}
+	for _, c := range f.Client {
+		f.WithMultiple(SummaryProject, resolve(c))
+	}
+	return f
+}
+
+// withProjectExclusions translates exclusions of labels, namespaces or clients into exclusions of the respective projects
+func (f *Filters) withProjectExclusions(entity uint8, resolve func(string) []string) {
+	for _, c := range f.Conditions {
+		if c.Entity != entity || c.Regex || !c.Negated {
+			continue
+		}
+		projects := make([]string, 0)
+		for _, k := range c.Keys {
+			projects = append(projects, resolve(k)...)
+		}
+		if len(projects) > 0 {
+			f.WithCondition(NewExclusionCondition(SummaryProject, projects))
+		}
+	}
+}
+
 func (f *Filters) IsProjectDetails() bool {
 	return f != nil && f.Project != nil && f.Project.Exists()
 }
@@ -80,6 +80,7 @@ func TestFiltersTestSuite(t *testing.T) {
 func (suite *FiltersTestSuite) TestFilters_IsEmpty() {
 	assert.False(suite.T(), NewFiltersWith(SummaryProject, "wakapi").IsEmpty())
 	assert.True(suite.T(), (&Filters{}).IsEmpty())
+	assert.False(suite.T(), (&Filters{}).WithCondition(NewExclusionCondition(SummaryProject, []string{"wakapi"})).IsEmpty())
 }
 
 func (suite *FiltersTestSuite) TestFilters_Match() {
@@ -105,6 +106,45 @@ func (suite *FiltersTestSuite) TestFilters_Match() {
 	assert.True(suite.T(), sut4.Match(heartbeats[1]))
 }
 
+func (suite *FiltersTestSuite) TestFilters_Match_Conditions() {
+	heartbeats := []*Heartbeat{
+		{Project: "wakapi", Language: "Go"},
+		{Project: "anchr", Language: "Javascript"},
+		{Project: "", Language: "Go"},
+	}
+
+	sut1 := (&Filters{}).WithCondition(NewExclusionCondition(SummaryProject, []string{"wakapi", "-"}))
+	assert.False(suite.T(), sut1.Match(heartbeats[0]))
+	assert.True(suite.T(), sut1.Match(heartbeats[1]))
+	assert.False(suite.T(), sut1.Match(heartbeats[2]))
+
+	regex, _ := NewRegexCondition(SummaryProject, "^wak", false)
+	sut2 := NewFiltersWith(SummaryLanguage, "Go").WithCondition(regex)
+	assert.True(suite.T(), sut2.Match(heartbeats[0]))
+	assert.False(suite.T(), sut2.Match(heartbeats[1]))
+	assert.False(suite.T(), sut2.Match(heartbeats[2]))
+
+	negatedRegex, _ := NewRegexCondition(SummaryLanguage, "script$", true)
+	sut3 := (&Filters{}).WithCondition(negatedRegex)
+	assert.True(suite.T(), sut3.Match(heartbeats[0]))
+	assert.False(suite.T(), sut3.Match(heartbeats[1]))
+}
+
+func (suite *FiltersTestSuite) TestFilters_ColumnConditions() {
+	regex, _ := NewRegexCondition(SummaryBranch, "^feature/", true)
+	sut := NewFilterWithMultiple(SummaryProject, []string{"wakapi", "anchr"}).
+		With(SummaryLabel, "oss").
+		WithCondition(NewExclusionCondition(SummaryLanguage, []string{"Go"})).
+		WithCondition(NewExclusionCondition(SummaryClient, []string{"acme"})).
+		WithCondition(regex)
+
+	conditions := sut.ColumnConditions()
+	assert.Len(suite.T(), conditions, 3)
+	assert.Equal(suite.T(), &ColumnCondition{Entity: SummaryProject, Column: "project", Keys: []string{"wakapi", "anchr"}}, conditions[0])
+	assert.Equal(suite.T(), &ColumnCondition{Entity: SummaryLanguage, Column: "language", Keys: []string{"Go"}, Negated: true}, conditions[1])
+	assert.Equal(suite.T(), &ColumnCondition{Entity: SummaryBranch, Column: "branch", Keys: []string{"^feature/"}, Negated: true, Regex: true}, conditions[2])
+}
+
 func (suite *FiltersTestSuite) TestFilters_One() {
 	sut1 := NewFiltersWith(SummaryLanguage, "Java")
 	ok1, type1, filters1 := sut1.One()
@@ -140,6 +180,11 @@ func (suite *FiltersTestSuite) TestFilters_WithAliases() {
 	assert.Len(suite.T(), sut3.Project, 1)
 	assert.Len(suite.T(), sut3.Language, 0)
 	assert.Contains(suite.T(), sut3.Project, "foo")
+
+	sut4 := (&Filters{}).WithCondition(NewExclusionCondition(SummaryProject, []string{"wakapi"}))
+	sut4 = sut4.WithAliases(suite.GetAliasReverseResolver([]int{0, 1, 2}))
+	assert.Empty(suite.T(), sut4.Project)
+	assert.ElementsMatch(suite.T(), []string{"wakapi", "wakapi-mobile", "wakapi-desktop"}, sut4.Conditions[0].Keys)
 }
 
 func (suite *FiltersTestSuite) TestFilters_WithProjectLabels() {
@@ -157,4 +202,52 @@ func (suite *FiltersTestSuite) TestFilters_WithProjectLabels() {
 	assert.Contains(suite.T(), sut2.Project, "wakapi")
 	assert.Contains(suite.T(), sut2.Project, "anchr")
 	assert.Contains(suite.T(), sut2.Label, "oss")
+
+	sut3 := (&Filters{}).WithCondition(NewExclusionCondition(SummaryLabel, []string{"oss"}))
+	sut3 = sut3.WithProjectLabels(suite.GetProjectLabelReverseResolver([]int{0, 1, 2}))
+	assert.Empty(suite.T(), sut3.Project)
+	assert.Len(suite.T(), sut3.Conditions, 2)
+	assert.Equal(suite.T(), SummaryProject, sut3.Conditions[1].Entity)
+	assert.ElementsMatch(suite.T(), []string{"wakapi", "anchr"}, sut3.Conditions[1].Keys)
+	assert.True(suite.T(), sut3.Conditions[1].Negated)
+}
+
+func (suite *FiltersTestSuite) TestFilters_WithProjectGroups() {
+	resolve := func(group string) []string {
+		if group == "wakapi-suite" {
+			return []string{"wakapi", "wakapi-mobile"}
+		}
+		return []string{}
+	}
+
+	sut1 := NewFiltersWith(SummaryProject, "wakapi-suite").With(SummaryLanguage, "Go")
+	sut1 = sut1.WithProjectGroups(resolve)
+	assert.Len(suite.T(), sut1.Project, 3)
+	assert.Contains(suite.T(), sut1.Project, "wakapi-suite")
+	assert.Contains(suite.T(), sut1.Project, "wakapi")
+	assert.Contains(suite.T(), sut1.Project, "wakapi-mobile")
+	assert.Len(suite.T(), sut1.Language, 1)
+
+	sut2 := NewFiltersWith(SummaryProject, "anchr")
+	sut2 = sut2.WithProjectGroups(resolve)
+	assert.Equal(suite.T(), OrFilter{"anchr"}, sut2.Project)
+
+	sut3 := (&Filters{}).WithCondition(NewExclusionCondition(SummaryProject, []string{"wakapi-suite"}))
+	sut3 = sut3.WithProjectGroups(resolve)
+	assert.ElementsMatch(suite.T(), []string{"wakapi-suite", "wakapi", "wakapi-mobile"}, sut3.Conditions[0].Keys)
+}
+
+func (suite *FiltersTestSuite) TestFilters_IsNative() {
+	assert.True(suite.T(), (&Filters{}).IsNative())
+	assert.True(suite.T(), NewFiltersWith(SummaryProject, "wakapi").With(SummaryBranch, "master").IsNative())
+	assert.False(suite.T(), NewFiltersWith(SummaryLabel, "oss").IsNative())
+	assert.False(suite.T(), NewFiltersWith(SummaryProject, "wakapi").With(SummaryLabel, "oss").IsNative())
+	assert.True(suite.T(), (&Filters{}).WithCondition(NewExclusionCondition(SummaryProject, []string{"wakapi"})).IsNative())
+	assert.False(suite.T(), (&Filters{}).WithCondition(NewExclusionCondition(SummaryLabel, []string{"oss"})).IsNative())
+}
+
+func (suite *FiltersTestSuite) TestFilters_Entities() {
+	assert.Empty(suite.T(), (&Filters{}).Entities())
+	sut := NewFiltersWith(SummaryProject, "wakapi").With(SummaryLanguage, "Go").WithCondition(NewExclusionCondition(SummaryBranch, []string{"main"}))
+	assert.Equal(suite.T(), []uint8{SummaryProject, SummaryLanguage, SummaryBranch}, sut.Entities())
 }
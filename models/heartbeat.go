@@ -1,34 +1,84 @@
 package models
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/duke-git/lancet/v2/strutil"
 	"github.com/emvi/logbuch"
 	"github.com/mitchellh/hashstructure/v2"
+	"github.com/muety/wakapi/utils"
 	"strings"
 	"time"
 )
 
+// HashedEntityPrefix marks entities, which were replaced by a salted hash of the original file path
+const HashedEntityPrefix = "hashed:"
+
+const maxHashedEntityExtLength = 16
+
+// LastBranchPlaceholder is sent by wakatime clients to indicate to use the most recent branch of the heartbeat's project
+const LastBranchPlaceholder = "<<LAST_BRANCH>>"
+
+const defaultHeartbeatCategory = "coding"
+
 type Heartbeat struct {
-	ID              uint64     `gorm:"primary_key" hash:"ignore"`
-	User            *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" hash:"ignore"`
-	UserID          string     `json:"-" gorm:"not null; index:idx_time_user; index:idx_user_project"` // idx_user_project is for quickly fetching a user's project list (settings page)
-	Entity          string     `json:"entity" gorm:"not null"`
-	Type            string     `json:"type" gorm:"size:255"`
-	Category        string     `json:"category" gorm:"size:255"`
-	Project         string     `json:"project" gorm:"index:idx_project; index:idx_user_project"`
-	Branch          string     `json:"branch" gorm:"index:idx_branch"`
-	Language        string     `json:"language" gorm:"index:idx_language"`
-	IsWrite         bool       `json:"is_write"`
-	Editor          string     `json:"editor" gorm:"index:idx_editor" hash:"ignore"`                     // ignored because editor might be parsed differently by wakatime
-	OperatingSystem string     `json:"operating_system" gorm:"index:idx_operating_system" hash:"ignore"` // ignored because os might be parsed differently by wakatime
-	Machine         string     `json:"machine" gorm:"index:idx_machine" hash:"ignore"`                   // ignored because wakatime api doesn't return machines currently
-	UserAgent       string     `json:"user_agent" hash:"ignore" gorm:"type:varchar(255)"`
-	Time            CustomTime `json:"time" gorm:"type:timestamp(3); index:idx_time; index:idx_time_user" swaggertype:"primitive,number"`
-	Hash            string     `json:"-" gorm:"type:varchar(17); uniqueIndex"`
-	Origin          string     `json:"-" hash:"ignore" gorm:"type:varchar(255)"`
-	OriginId        string     `json:"-" hash:"ignore" gorm:"type:varchar(255)"`
-	CreatedAt       CustomTime `json:"created_at" gorm:"type:timestamp(3)" swaggertype:"primitive,number" hash:"ignore"` // https://gorm.io/docs/conventions.html#CreatedAt
+	ID               uint64     `gorm:"primary_key" hash:"ignore"`
+	User             *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" hash:"ignore"`
+	UserID           string     `json:"-" gorm:"not null; index:idx_time_user; index:idx_user_project"` // idx_user_project is for quickly fetching a user's project list (settings page)
+	Entity           string     `json:"entity" gorm:"not null"`
+	Type             string     `json:"type" gorm:"size:255"`
+	Category         string     `json:"category" gorm:"size:255"`
+	Project          string     `json:"project" gorm:"index:idx_project; index:idx_user_project"`
+	Branch           string     `json:"branch" gorm:"index:idx_branch"`
+	ProjectRemote    string     `json:"project_remote,omitempty" gorm:"-" hash:"ignore"` // repository remote url as sent by newer clients, not persisted with the heartbeat, but per project (see ProjectRemote)
+	Language         string     `json:"language" gorm:"index:idx_language"`
+	LanguageOriginal string     `json:"-" hash:"ignore" gorm:"type:varchar(255)"` // language as reported by the client, only set if overridden by one of the user's language mappings
+	IsWrite          bool       `json:"is_write"`
+	Editor           string     `json:"editor" gorm:"index:idx_editor" hash:"ignore"`                     // ignored because editor might be parsed differently by wakatime
+	OperatingSystem  string     `json:"operating_system" gorm:"index:idx_operating_system" hash:"ignore"` // ignored because os might be parsed differently by wakatime
+	Machine          string     `json:"machine" gorm:"index:idx_machine" hash:"ignore"`                   // ignored because wakatime api doesn't return machines currently
+	UserAgent        string     `json:"user_agent" hash:"ignore" gorm:"type:varchar(255)"`
+	RemoteIp         string     `json:"-" gorm:"-" hash:"ignore"` // address the heartbeat was sent from, not persisted with the heartbeat, but per machine (see Machine)
+	Time             CustomTime `json:"time" gorm:"type:timestamp(3); index:idx_time; index:idx_time_user" swaggertype:"primitive,number"`
+	Hash             string     `json:"-" gorm:"type:varchar(17); uniqueIndex"`
+	Origin           string     `json:"-" hash:"ignore" gorm:"type:varchar(255)"`
+	OriginId         string     `json:"-" hash:"ignore" gorm:"type:varchar(255)"`
+	CreatedAt        CustomTime `json:"created_at" gorm:"type:timestamp(3)" swaggertype:"primitive,number" hash:"ignore"` // https://gorm.io/docs/conventions.html#CreatedAt
+
+	SchemaVersion int             `json:"-" hash:"ignore" gorm:"default:1"`
+	Extras        HeartbeatExtras `json:"-" hash:"ignore" gorm:"type:text"` // fields sent by the client, which are unknown to wakapi
+}
+
+// MarshalJSON encodes a heartbeat including its extras, so they're passed on when mirroring or exporting heartbeats
+func (h *Heartbeat) MarshalJSON() ([]byte, error) {
+	type heartbeat Heartbeat // prevent recursion
+	return MarshalWithExtras((*heartbeat)(h), h.Extras)
+}
+
+// UnmarshalJSON decodes a heartbeat as sent by a client, keeping all unknown fields as extras instead of dropping them
+func (h *Heartbeat) UnmarshalJSON(data []byte) error {
+	type heartbeat Heartbeat // prevent recursion
+	var hb heartbeat
+	extras, err := UnmarshalWithExtras(data, &hb)
+	if err != nil {
+		return err
+	}
+	*h = Heartbeat(hb)
+	h.Extras = extras
+	h.SchemaVersion = HeartbeatSchemaVersion
+	return nil
+}
+
+// HeartbeatTimeRange holds the times of the first and the last of a set of heartbeats, both of which are nil if the set is empty
+type HeartbeatTimeRange struct {
+	First *CustomTime
+	Last  *CustomTime
+}
+
+func (r *HeartbeatTimeRange) IsEmpty() bool {
+	return r.First == nil || r.Last == nil
 }
 
 func (h *Heartbeat) Valid() bool {
@@ -50,6 +100,11 @@ func (h *Heartbeat) Sanitize() *Heartbeat {
 		h.Project = ""
 	}
 
+	// category is optional in wakatime's api and defaults to coding, but e.g. some importers don't set it at all
+	if h.Category == "" && (h.Type == "" || h.Type == "file") {
+		h.Category = defaultHeartbeatCategory
+	}
+
 	h.OperatingSystem = strutil.Capitalize(h.OperatingSystem)
 	h.Editor = strutil.Capitalize(h.Editor)
 
@@ -66,7 +121,56 @@ func (h *Heartbeat) Augment(languageMappings map[string]string) {
 	}
 }
 
+// MapLanguage applies the given language mappings at ingestion time, while keeping track of the originally reported language
+func (h *Heartbeat) MapLanguage(languageMappings map[string]string) {
+	original := h.Language
+	if h.LanguageOriginal != "" {
+		original = h.LanguageOriginal
+	}
+	h.Language, h.LanguageOriginal = original, ""
+	h.Augment(languageMappings)
+	if h.Language != original {
+		h.LanguageOriginal = original
+	}
+}
+
+// DetectLanguage fills in the language from the file's name or extension, if the client did not report any
+func (h *Heartbeat) DetectLanguage() {
+	if (h.Language != "" && !strings.EqualFold(h.Language, UnknownSummaryKey)) || (h.Type != "" && h.Type != "file") {
+		return
+	}
+	if language := utils.DetectLanguage(h.Entity); language != "" {
+		h.Language = language
+	}
+}
+
+// HashEntity replaces the heartbeat's entity by a salted hash, only keeping the file extension, so that languages can still be detected from it
+func (h *Heartbeat) HashEntity(salt string) {
+	if h.Entity == "" || strings.HasPrefix(h.Entity, HashedEntityPrefix) {
+		return
+	}
+
+	var ext string
+	if h.Type == "" || h.Type == "file" {
+		filename := h.Entity[strings.LastIndexAny(h.Entity, "/\\")+1:]
+		if i := strings.Index(filename, "."); i > 0 && len(filename)-i <= maxHashedEntityExtLength {
+			ext = filename[i:] // keep compound extensions like .blade.php, which language mappings might refer to
+		}
+	}
+
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(h.Entity))
+	h.Entity = HashedEntityPrefix + hex.EncodeToString(mac.Sum(nil))[:32] + ext
+}
+
 func (h *Heartbeat) GetKey(t uint8) (key string) {
+	if key = h.getRawKey(t); key == "" {
+		key = UnknownSummaryKey
+	}
+	return key
+}
+
+func (h *Heartbeat) getRawKey(t uint8) (key string) {
 	switch t {
 	case SummaryProject:
 		key = h.Project
@@ -83,11 +187,6 @@ func (h *Heartbeat) GetKey(t uint8) (key string) {
 	case SummaryEntity:
 		key = h.Entity
 	}
-
-	if key == "" {
-		key = UnknownSummaryKey
-	}
-
 	return key
 }
 
@@ -133,5 +232,6 @@ func GetEntityColumn(t uint8) string {
 		"machine",
 		"label",
 		"branch",
+		"entity",
 	}[t]
 }
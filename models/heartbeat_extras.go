@@ -0,0 +1,135 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	HeartbeatSchemaVersionLegacy = 1 // fields unknown to wakapi were dropped
+	HeartbeatSchemaVersion       = 2 // fields unknown to wakapi are kept as extras
+)
+
+const heartbeatExtrasMaxSize = 4096 // max. total size of json-encoded extras per heartbeat in bytes
+
+var knownJsonKeysCache sync.Map
+
+// HeartbeatExtras holds fields sent by a client, which wakapi doesn't know (yet), e.g. ones introduced by newer wakatime-cli releases
+// they're persisted as a json column, so they aren't lost and can be passed on to other instances when exporting or relaying heartbeats
+type HeartbeatExtras map[string]interface{}
+
+func (e HeartbeatExtras) Value() (driver.Value, error) {
+	if len(e) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (e *HeartbeatExtras) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("unsupported type: %T", value)
+	}
+	if len(data) == 0 {
+		*e = nil
+		return nil
+	}
+	return json.Unmarshal(data, e)
+}
+
+// UnmarshalWithExtras decodes a json object into v and returns all of its top-level fields that don't correspond to any of v's json fields
+// extras are capped to a total size of heartbeatExtrasMaxSize, exceeding fields are dropped in alphabetical order
+func UnmarshalWithExtras(data []byte, v interface{}) (HeartbeatExtras, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	known := knownJsonKeys(reflect.TypeOf(v))
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if !known[strings.ToLower(k)] {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	sort.Strings(keys)
+
+	extras, size := make(HeartbeatExtras, len(keys)), 0
+	for _, k := range keys {
+		if size += len(k) + len(fields[k]); size > heartbeatExtrasMaxSize {
+			break
+		}
+		var value interface{}
+		if err := json.Unmarshal(fields[k], &value); err != nil {
+			return nil, err
+		}
+		extras[k] = value
+	}
+	return extras, nil
+}
+
+// MarshalWithExtras encodes v as a json object, including all extras that don't collide with any of v's own fields
+func MarshalWithExtras(v interface{}, extras HeartbeatExtras) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extras) == 0 {
+		return data, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for k, value := range extras {
+		if _, ok := fields[k]; !ok {
+			fields[k] = value
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// knownJsonKeys returns the (lower-cased) json field names of a struct type, including the ones explicitly ignored, as these are known, but intentionally not decoded
+func knownJsonKeys(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if cached, ok := knownJsonKeysCache.Load(t); ok {
+		return cached.(map[string]bool)
+	}
+
+	keys := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+		keys[strings.ToLower(name)] = true
+	}
+
+	knownJsonKeysCache.Store(t, keys)
+	return keys
+}
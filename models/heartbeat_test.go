@@ -1,7 +1,9 @@
 package models
 
 import (
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 	"time"
 )
@@ -52,6 +54,58 @@ func TestHeartbeat_Augment(t *testing.T) {
 	assert.Equal(t, "PHP 8", sut3.Language)
 }
 
+func TestHeartbeat_MapLanguage(t *testing.T) {
+	sut := &Heartbeat{
+		Entity:   "~/dev/file.py",
+		Language: "Python",
+	}
+
+	sut.MapLanguage(map[string]string{"py": "Python3"})
+	assert.Equal(t, "Python3", sut.Language)
+	assert.Equal(t, "Python", sut.LanguageOriginal)
+
+	// re-mapping starts from the originally reported language
+	sut.MapLanguage(map[string]string{"py": "Snake"})
+	assert.Equal(t, "Snake", sut.Language)
+	assert.Equal(t, "Python", sut.LanguageOriginal)
+
+	// removing the mapping restores the original
+	sut.MapLanguage(map[string]string{})
+	assert.Equal(t, "Python", sut.Language)
+	assert.Empty(t, sut.LanguageOriginal)
+
+	// mapping to the reported language itself does not count as an override
+	sut.MapLanguage(map[string]string{"py": "Python"})
+	assert.Equal(t, "Python", sut.Language)
+	assert.Empty(t, sut.LanguageOriginal)
+}
+
+func TestHeartbeat_Sanitize_Category(t *testing.T) {
+	assert.Equal(t, "coding", (&Heartbeat{Type: "file"}).Sanitize().Category)
+	assert.Equal(t, "debugging", (&Heartbeat{Type: "file", Category: "debugging"}).Sanitize().Category)
+	assert.Empty(t, (&Heartbeat{Type: "url"}).Sanitize().Category)
+}
+
+func TestHeartbeat_DetectLanguage(t *testing.T) {
+	sut := &Heartbeat{Entity: "~/dev/Dockerfile", Type: "file"}
+	sut.DetectLanguage()
+	assert.Equal(t, "Dockerfile", sut.Language)
+
+	// reported languages are kept
+	sut = &Heartbeat{Entity: "~/dev/file.py", Language: "Snake"}
+	sut.DetectLanguage()
+	assert.Equal(t, "Snake", sut.Language)
+
+	sut = &Heartbeat{Entity: "~/dev/file.py", Language: "Unknown"}
+	sut.DetectLanguage()
+	assert.Equal(t, "Python", sut.Language)
+
+	// urls and domains are no files
+	sut = &Heartbeat{Entity: "https://example.org/index.php", Type: "url"}
+	sut.DetectLanguage()
+	assert.Empty(t, sut.Language)
+}
+
 func TestHeartbeat_GetKey(t *testing.T) {
 	sut := &Heartbeat{
 		Project: "wakapi",
@@ -103,3 +157,83 @@ func TestHeartbeat_Hashed_NoCollision(t *testing.T) {
 		hashes[sut.Hash] = true
 	}
 }
+
+func TestHeartbeat_UnmarshalJSON_Extras(t *testing.T) {
+	var sut Heartbeat
+	data := `{"entity": "main.go", "project": "wakapi", "time": 1673810732, "is_write": true, "lineno": 42, "ai_line_changes": {"added": 3}}`
+
+	assert.Nil(t, json.Unmarshal([]byte(data), &sut))
+	assert.Equal(t, "main.go", sut.Entity)
+	assert.True(t, sut.IsWrite)
+	assert.Equal(t, HeartbeatSchemaVersion, sut.SchemaVersion)
+	assert.Equal(t, HeartbeatExtras{"lineno": float64(42), "ai_line_changes": map[string]interface{}{"added": float64(3)}}, sut.Extras)
+
+	encoded, err := json.Marshal(&sut)
+	assert.Nil(t, err)
+	assert.Contains(t, string(encoded), `"lineno":42`)
+	assert.Contains(t, string(encoded), `"entity":"main.go"`)
+}
+
+func TestHeartbeat_UnmarshalJSON_ExtrasMaxSize(t *testing.T) {
+	var sut Heartbeat
+	data := `{"entity": "main.go", "a": 1, "b": "` + strings.Repeat("x", heartbeatExtrasMaxSize) + `"}`
+
+	assert.Nil(t, json.Unmarshal([]byte(data), &sut))
+	assert.Equal(t, HeartbeatExtras{"a": float64(1)}, sut.Extras)
+}
+
+func TestHeartbeatExtras_Scan(t *testing.T) {
+	var sut HeartbeatExtras
+
+	assert.Nil(t, sut.Scan(`{"lineno": 42}`))
+	assert.Equal(t, HeartbeatExtras{"lineno": float64(42)}, sut)
+
+	assert.Nil(t, sut.Scan(nil))
+	assert.Nil(t, sut)
+
+	value, err := HeartbeatExtras{}.Value()
+	assert.Nil(t, err)
+	assert.Nil(t, value)
+}
+
+func TestHeartbeat_MarshalJSON_RoundTrip(t *testing.T) {
+	sut := &Heartbeat{Entity: "main.go", Time: CustomTime(time.Unix(1673810732, 0)), Extras: HeartbeatExtras{"lineno": float64(42)}}
+
+	encoded, err := json.Marshal(sut)
+	assert.Nil(t, err)
+
+	var decoded Heartbeat
+	assert.Nil(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, sut.Entity, decoded.Entity)
+	assert.True(t, sut.Time.T().Equal(decoded.Time.T()))
+	assert.Equal(t, sut.Extras, decoded.Extras)
+}
+
+func TestHeartbeat_HashEntity(t *testing.T) {
+	sut1 := &Heartbeat{Entity: "/home/john/dev/secret-project/payroll.go", Type: "file"}
+	sut2 := &Heartbeat{Entity: "C:\\dev\\secret.project\\views\\index.blade.php", Type: "file"}
+	sut3 := &Heartbeat{Entity: "https://example.org/admin", Type: "url"}
+	sut4 := &Heartbeat{Entity: "/home/john/dev/secret-project/payroll.go", Type: "file"}
+
+	sut1.HashEntity("salt1")
+	sut2.HashEntity("salt1")
+	sut3.HashEntity("salt1")
+	sut4.HashEntity("salt2")
+
+	assert.True(t, strings.HasPrefix(sut1.Entity, HashedEntityPrefix))
+	assert.True(t, strings.HasSuffix(sut1.Entity, ".go"))
+	assert.NotContains(t, sut1.Entity, "payroll")
+	assert.True(t, strings.HasSuffix(sut2.Entity, ".blade.php"))
+	assert.NotContains(t, sut2.Entity, "secret")
+	assert.Len(t, strings.TrimPrefix(sut3.Entity, HashedEntityPrefix), 32)
+	assert.NotEqual(t, sut1.Entity, sut4.Entity)
+
+	// hashing is stable and not applied twice
+	hashed := sut1.Entity
+	sut1.HashEntity("salt1")
+	assert.Equal(t, hashed, sut1.Entity)
+
+	sut5 := &Heartbeat{Entity: "/home/john/dev/secret-project/payroll.go", Type: "file"}
+	sut5.HashEntity("salt1")
+	assert.Equal(t, hashed, sut5.Entity)
+}
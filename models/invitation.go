@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Invitation is an admin-issued code, that allows to sign up even though public registration is disabled
+type Invitation struct {
+	ID        uint        `json:"id" gorm:"primary_key"`
+	Code      string      `json:"code" gorm:"type:varchar(64); uniqueIndex:idx_invitation_code"`
+	Comment   string      `json:"comment" gorm:"type:varchar(255)"`
+	MaxUses   int         `json:"max_uses"` // 0 for unlimited
+	Uses      int         `json:"uses"`
+	ExpiresAt *CustomTime `json:"expires_at" gorm:"type:timestamp(3)" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt CustomTime  `json:"created_at" gorm:"type:timestamp(3)" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	SignupUrl string      `json:"signup_url" gorm:"-"`
+}
+
+func (i *Invitation) IsValid() bool {
+	return i.MaxUses >= 0 && len(i.Comment) <= 255
+}
+
+// IsUsable tells whether the invitation is neither expired nor used up at the given time
+func (i *Invitation) IsUsable(now time.Time) bool {
+	if i.ExpiresAt != nil && !i.ExpiresAt.T().IsZero() && !now.Before(i.ExpiresAt.T()) {
+		return false
+	}
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestInvitation_IsValid(t *testing.T) {
+	assert.True(t, (&Invitation{}).IsValid())
+	assert.True(t, (&Invitation{MaxUses: 5, Comment: "for the team"}).IsValid())
+	assert.False(t, (&Invitation{MaxUses: -1}).IsValid())
+}
+
+func TestInvitation_IsUsable(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	past, future := CustomTime(now.Add(-time.Hour)), CustomTime(now.Add(time.Hour))
+
+	assert.True(t, (&Invitation{}).IsUsable(now))
+	assert.True(t, (&Invitation{Uses: 100}).IsUsable(now))
+	assert.True(t, (&Invitation{MaxUses: 2, Uses: 1, ExpiresAt: &future}).IsUsable(now))
+	assert.False(t, (&Invitation{MaxUses: 1, Uses: 1}).IsUsable(now))
+	assert.False(t, (&Invitation{ExpiresAt: &past}).IsUsable(now))
+}
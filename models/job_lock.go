@@ -0,0 +1,9 @@
+package models
+
+// JobLock is a lease on a scheduled job, held by one out of multiple wakapi instances sharing the same database
+// leases aren't released after a job has finished, but expire some time before the job's next run, so that other instances won't run it again in the meantime
+type JobLock struct {
+	Name      string     `gorm:"primary_key"`
+	Holder    string     `gorm:"not null"`
+	ExpiresAt CustomTime `gorm:"type:timestamp; not null"`
+}
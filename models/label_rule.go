@@ -0,0 +1,58 @@
+package models
+
+import "regexp"
+
+const (
+	LabelRuleFieldProject = "project"
+	LabelRuleFieldEntity  = "entity"
+	LabelRuleFieldBranch  = "branch"
+)
+
+// LabelRule automatically assigns a label to every project, for which any heartbeat's field matches the rule's regular expression
+type LabelRule struct {
+	ID      uint           `json:"id" gorm:"primary_key"`
+	User    *User          `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID  string         `json:"-" gorm:"not null; index:idx_label_rule_user"`
+	Field   string         `json:"field" gorm:"type:varchar(16)"`
+	Pattern string         `json:"pattern" gorm:"type:varchar(255)"`
+	Label   string         `json:"label" gorm:"type:varchar(64)"`
+	regex   *regexp.Regexp `gorm:"-"`
+}
+
+func (r *LabelRule) IsValid() bool {
+	if r.Field != LabelRuleFieldProject && r.Field != LabelRuleFieldEntity && r.Field != LabelRuleFieldBranch {
+		return false
+	}
+	if r.Label == "" || len(r.Label) > 64 || r.Pattern == "" || len(r.Pattern) > 255 {
+		return false
+	}
+	_, err := regexp.Compile(r.Pattern)
+	return err == nil
+}
+
+// Compile prepares the rule's pattern for matching, must be called before Matches()
+func (r *LabelRule) Compile() error {
+	regex, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return err
+	}
+	r.regex = regex
+	return nil
+}
+
+// Matches checks whether the rule applies to the given heartbeat, which must at least have a project
+func (r *LabelRule) Matches(heartbeat *Heartbeat) bool {
+	if r.regex == nil || heartbeat.Project == "" {
+		return false
+	}
+
+	switch r.Field {
+	case LabelRuleFieldProject:
+		return r.regex.MatchString(heartbeat.Project)
+	case LabelRuleFieldEntity:
+		return r.regex.MatchString(heartbeat.Entity)
+	case LabelRuleFieldBranch:
+		return heartbeat.Branch != "" && r.regex.MatchString(heartbeat.Branch)
+	}
+	return false
+}
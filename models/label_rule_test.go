@@ -0,0 +1,41 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLabelRule_IsValid(t *testing.T) {
+	assert.True(t, (&LabelRule{Field: LabelRuleFieldProject, Pattern: "^wakapi-.*$", Label: "oss"}).IsValid())
+	assert.False(t, (&LabelRule{Field: "language", Pattern: "^Go$", Label: "oss"}).IsValid())
+	assert.False(t, (&LabelRule{Field: LabelRuleFieldEntity, Pattern: "(unclosed", Label: "oss"}).IsValid())
+	assert.False(t, (&LabelRule{Field: LabelRuleFieldBranch, Pattern: "^main$", Label: ""}).IsValid())
+}
+
+func TestLabelRule_Matches(t *testing.T) {
+	sut1 := &LabelRule{Field: LabelRuleFieldProject, Pattern: "^wakapi"}
+	sut2 := &LabelRule{Field: LabelRuleFieldEntity, Pattern: `/work/`}
+	sut3 := &LabelRule{Field: LabelRuleFieldBranch, Pattern: `^release/`}
+	for _, r := range []*LabelRule{sut1, sut2, sut3} {
+		assert.Nil(t, r.Compile())
+	}
+
+	hb1 := &Heartbeat{Project: "wakapi", Entity: "/home/me/work/wakapi/main.go", Branch: "release/2.10"}
+	hb2 := &Heartbeat{Project: "anchr", Entity: "/home/me/private/anchr/index.js", Branch: "master"}
+	hb3 := &Heartbeat{Project: "", Entity: "/home/me/work/scratch.txt"}
+
+	assert.True(t, sut1.Matches(hb1))
+	assert.True(t, sut2.Matches(hb1))
+	assert.True(t, sut3.Matches(hb1))
+	assert.False(t, sut1.Matches(hb2))
+	assert.False(t, sut2.Matches(hb2))
+	assert.False(t, sut3.Matches(hb2))
+	assert.False(t, sut2.Matches(hb3)) // projects without name can't be labeled
+	assert.False(t, (&LabelRule{Field: LabelRuleFieldProject, Pattern: ".*"}).Matches(hb1))
+}
+
+func TestLabelRule_Matches_Uncompiled(t *testing.T) {
+	sut := &LabelRule{Field: LabelRuleFieldProject, Pattern: "^wakapi"}
+	assert.False(t, sut.Matches(&Heartbeat{Project: "wakapi"}))
+	assert.NotNil(t, (&LabelRule{Field: LabelRuleFieldProject, Pattern: "(unclosed"}).Compile())
+}
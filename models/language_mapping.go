@@ -1,5 +1,9 @@
 package models
 
+import (
+	"strings"
+)
+
 type LanguageMapping struct {
 	ID        uint   `json:"id" gorm:"primary_key"`
 	User      *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
@@ -8,6 +12,13 @@ type LanguageMapping struct {
 	Language  string `json:"language" gorm:"type:varchar(64)"`
 }
 
+// Normalize strips any leading dot from the extension, e.g. ".vue" -> "vue"
+func (m *LanguageMapping) Normalize() *LanguageMapping {
+	m.Extension = strings.TrimPrefix(strings.TrimSpace(m.Extension), ".")
+	m.Language = strings.TrimSpace(m.Language)
+	return m
+}
+
 func (m *LanguageMapping) IsValid() bool {
 	return m.validateLanguage() && m.validateExtension()
 }
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// LoginAttempts keeps track of recently failed logins for an account or ip address
+type LoginAttempts struct {
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// LoginLockout is an account temporarily blocked from logging in after too many failed attempts
+type LoginLockout struct {
+	UserID      string    `json:"user_id"`
+	Failures    int       `json:"failures"`
+	LastIp      string    `json:"last_ip"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+func (a *LoginAttempts) IsLocked(now time.Time) bool {
+	return now.Before(a.LockedUntil)
+}
+
+// RetryAfter returns how long to wait until the next attempt, which doubles with every failure, starting at baseDelay and capped at maxDelay
+func (a *LoginAttempts) RetryAfter(now time.Time, baseDelay, maxDelay time.Duration) time.Duration {
+	if a.IsLocked(now) {
+		return a.LockedUntil.Sub(now)
+	}
+	if a.Failures == 0 {
+		return 0
+	}
+	delay := time.Duration(math.Min(float64(baseDelay)*math.Pow(2, float64(a.Failures-1)), float64(maxDelay)))
+	if wait := a.LastFailure.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+func (l *LoginLockout) IsActive(now time.Time) bool {
+	return now.Before(l.LockedUntil)
+}
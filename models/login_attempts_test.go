@@ -0,0 +1,19 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLoginAttempts_RetryAfter(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Zero(t, (&LoginAttempts{}).RetryAfter(now, time.Second, time.Minute))
+	assert.Equal(t, 1*time.Second, (&LoginAttempts{Failures: 1, LastFailure: now}).RetryAfter(now, time.Second, time.Minute))
+	assert.Equal(t, 8*time.Second, (&LoginAttempts{Failures: 4, LastFailure: now}).RetryAfter(now, time.Second, time.Minute))
+	assert.Equal(t, 6*time.Second, (&LoginAttempts{Failures: 4, LastFailure: now.Add(-2 * time.Second)}).RetryAfter(now, time.Second, time.Minute))
+	assert.Equal(t, time.Minute, (&LoginAttempts{Failures: 20, LastFailure: now}).RetryAfter(now, time.Second, time.Minute))
+	assert.Zero(t, (&LoginAttempts{Failures: 3, LastFailure: now.Add(-time.Hour)}).RetryAfter(now, time.Second, time.Minute))
+	assert.Equal(t, 10*time.Minute, (&LoginAttempts{Failures: 5, LastFailure: now, LockedUntil: now.Add(10 * time.Minute)}).RetryAfter(now, time.Second, time.Minute))
+}
@@ -0,0 +1,25 @@
+package models
+
+// Machine is one of the devices a user sends heartbeats from, along with when, with which operating system and from where it was last seen
+type Machine struct {
+	ID              uint       `json:"-" gorm:"primary_key"`
+	User            *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID          string     `json:"-" gorm:"not null; uniqueIndex:idx_machine_user_name"`
+	Name            string     `json:"name" gorm:"not null; type:varchar(255); uniqueIndex:idx_machine_user_name"`
+	OperatingSystem string     `json:"operating_system" gorm:"type:varchar(255)"`
+	LastIp          string     `json:"last_ip" gorm:"type:varchar(64)"`
+	Country         string     `json:"country" gorm:"type:varchar(255)"` // empty unless geoip lookups are enabled
+	City            string     `json:"city" gorm:"type:varchar(255)"`
+	LastSeenAt      CustomTime `json:"last_seen_at" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt       CustomTime `json:"first_seen_at" gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// GeoLocation is the response of a geoip lookup, fields follow ip-api.com and ipinfo.io, which both respond with (at least) these
+type GeoLocation struct {
+	Country string `json:"country"`
+	City    string `json:"city"`
+}
+
+func (m *Machine) IsValid() bool {
+	return m.UserID != "" && m.Name != "" && m.LastIp != ""
+}
@@ -39,6 +39,14 @@ func (m MailAddress) Raw() string {
 	return ""
 }
 
+// Name returns the display name part of an address like "John Doe <john.doe@example.org>", if any
+func (m MailAddress) Name() string {
+	if i := strings.LastIndex(string(m), " <"); i > 0 && m.Raw() != "" {
+		return strings.Trim(strings.TrimSpace(string(m)[:i]), `"`)
+	}
+	return ""
+}
+
 func (m MailAddress) Domain() string {
 	split := strings.Split(m.Raw(), "@")
 	if len(split) != 2 {
@@ -30,6 +30,13 @@ func TestMailAddress_SingleRaw(t *testing.T) {
 	}
 }
 
+func TestMailAddress_Name(t *testing.T) {
+	assert.Equal(t, "John Doe", MailAddress("John Doe <john.doe@example.org>").Name())
+	assert.Equal(t, "Wakapi", MailAddress(`"Wakapi" <noreply@wakapi.dev>`).Name())
+	assert.Equal(t, "", MailAddress("john.doe@example.org").Name())
+	assert.Equal(t, "", MailAddress("invalid").Name())
+}
+
 func TestMailAddress_AllRaw(t *testing.T) {
 	tests := []struct {
 		in  []string
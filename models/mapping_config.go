@@ -0,0 +1,83 @@
+package models
+
+import "fmt"
+
+var aliasTypeNames = map[uint8]string{
+	SummaryProject:   "project",
+	SummaryLanguage:  "language",
+	SummaryEditor:    "editor",
+	SummaryOS:        "operating_system",
+	SummaryMachine:   "machine",
+	SummaryLabel:     "label",
+	SummaryBranch:    "branch",
+	SummaryEntity:    "entity",
+	SummaryNamespace: "namespace",
+	SummaryClient:    "client",
+}
+
+// MappingConfig is a portable representation of a user's aliases, project labels and labeling rules, meant to be version-controlled and replicated across instances
+type MappingConfig struct {
+	Aliases    []*MappingConfigAlias     `json:"aliases" yaml:"aliases"`
+	Labels     []*MappingConfigLabel     `json:"labels" yaml:"labels"`
+	LabelRules []*MappingConfigLabelRule `json:"label_rules" yaml:"label_rules"`
+}
+
+type MappingConfigAlias struct {
+	Type   string   `json:"type" yaml:"type"` // e.g. "project" or "language"
+	Key    string   `json:"key" yaml:"key"`
+	Values []string `json:"values" yaml:"values"`
+}
+
+type MappingConfigLabel struct {
+	Label    string   `json:"label" yaml:"label"`
+	Projects []string `json:"projects" yaml:"projects"`
+}
+
+type MappingConfigLabelRule struct {
+	Field   string `json:"field" yaml:"field"`
+	Pattern string `json:"pattern" yaml:"pattern"`
+	Label   string `json:"label" yaml:"label"`
+}
+
+// MappingConfigImportResult counts the entries actually created by an import, i.e. excluding those already present
+type MappingConfigImportResult struct {
+	Aliases    int `json:"aliases"`
+	Labels     int `json:"labels"`
+	LabelRules int `json:"label_rules"`
+}
+
+func AliasTypeName(t uint8) string {
+	return aliasTypeNames[t]
+}
+
+func ParseAliasType(name string) (uint8, error) {
+	for t, n := range aliasTypeNames {
+		if n == name {
+			return t, nil
+		}
+	}
+	return SummaryUnknown, fmt.Errorf("unknown alias type '%s'", name)
+}
+
+// Validate checks all entries and reports the first invalid one
+func (c *MappingConfig) Validate() error {
+	for _, a := range c.Aliases {
+		if _, err := ParseAliasType(a.Type); err != nil {
+			return err
+		}
+		if a.Key == "" || len(a.Values) == 0 {
+			return fmt.Errorf("alias '%s' must have a key and at least one value", a.Key)
+		}
+	}
+	for _, l := range c.Labels {
+		if l.Label == "" || len(l.Label) > 64 {
+			return fmt.Errorf("invalid label '%s'", l.Label)
+		}
+	}
+	for _, r := range c.LabelRules {
+		if !(&LabelRule{Field: r.Field, Pattern: r.Pattern, Label: r.Label}).IsValid() {
+			return fmt.Errorf("invalid label rule '%s' on %s", r.Pattern, r.Field)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMappingConfig_Validate(t *testing.T) {
+	sut := &MappingConfig{
+		Aliases:    []*MappingConfigAlias{{Type: "project", Key: "wakapi", Values: []string{"wakapi-v2"}}},
+		Labels:     []*MappingConfigLabel{{Label: "oss", Projects: []string{"wakapi"}}},
+		LabelRules: []*MappingConfigLabelRule{{Field: LabelRuleFieldProject, Pattern: "^wakapi", Label: "oss"}},
+	}
+	assert.Nil(t, sut.Validate())
+
+	sut.Aliases[0].Type = "planet"
+	assert.NotNil(t, sut.Validate())
+
+	sut.Aliases[0].Type = "operating_system"
+	sut.LabelRules[0].Pattern = "["
+	assert.NotNil(t, sut.Validate())
+}
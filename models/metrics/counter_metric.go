@@ -20,3 +20,7 @@ func (c CounterMetric) Print() string {
 func (c CounterMetric) Header() string {
 	return fmt.Sprintf("# HELP %s %s\n# TYPE %s counter", c.Name, c.Desc, c.Name)
 }
+
+func (c CounterMetric) Sample() Sample {
+	return Sample{Name: c.Name, Labels: c.Labels, Value: float64(c.Value)}
+}
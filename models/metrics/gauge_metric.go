@@ -20,3 +20,7 @@ func (c GaugeMetric) Print() string {
 func (c GaugeMetric) Header() string {
 	return fmt.Sprintf("# HELP %s %s\n# TYPE %s gauge", c.Name, c.Desc, c.Name)
 }
+
+func (c GaugeMetric) Sample() Sample {
+	return Sample{Name: c.Name, Labels: c.Labels, Value: float64(c.Value)}
+}
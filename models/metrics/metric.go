@@ -40,4 +40,12 @@ type Metric interface {
 	Key() string
 	Header() string
 	Print() string
+	Sample() Sample
+}
+
+// Sample is a metric's plain name, labels and value, e.g. for pushing it to a remote endpoint
+type Sample struct {
+	Name   string
+	Labels Labels
+	Value  float64
 }
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+)
+
+// Milestone marks a notable event in a project's history (e.g. "v1.0 shipped") on a specific day, so that activity can be correlated with it
+type Milestone struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"-" gorm:"not null; index:idx_milestone_user_day"`
+	Day       string     `json:"day" gorm:"not null; type:varchar(10); index:idx_milestone_user_day" example:"2006-01-02"` // in the user's time zone
+	Project   string     `json:"project" gorm:"not null; type:varchar(255)"`
+	Name      string     `json:"name" gorm:"not null; type:varchar(255)"`
+	CreatedAt CustomTime `json:"created_at" gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+func (m *Milestone) IsValid() bool {
+	if _, err := time.Parse(conf.SimpleDateFormat, m.Day); err != nil {
+		return false
+	}
+	return m.Project != "" && len(m.Project) <= 255 && m.Name != "" && len(m.Name) <= 255
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMilestone_IsValid(t *testing.T) {
+	assert.True(t, (&Milestone{Day: "2023-05-01", Project: "wakapi", Name: "v1.0"}).IsValid())
+	assert.False(t, (&Milestone{Day: "01.05.2023", Project: "wakapi", Name: "v1.0"}).IsValid())
+	assert.False(t, (&Milestone{Day: "2023-05-01", Project: "", Name: "v1.0"}).IsValid())
+	assert.False(t, (&Milestone{Day: "2023-05-01", Project: "wakapi", Name: ""}).IsValid())
+}
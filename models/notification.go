@@ -0,0 +1,11 @@
+package models
+
+// Notification is an e-mail notification held back because of the recipient's quiet hours or digest preference, to be sent as part of a digest later on
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"-" gorm:"not null; index:idx_notification_user"`
+	Subject   string     `json:"subject" gorm:"type:varchar(255)"`
+	Text      string     `json:"text"`
+	CreatedAt CustomTime `json:"created_at" gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
@@ -0,0 +1,27 @@
+package models
+
+// Presence tells whether a user is currently coding and on what, derived from their most recent heartbeats
+type Presence struct {
+	Active          bool        `json:"active"`
+	Project         string      `json:"project,omitempty"`
+	Language        string      `json:"language,omitempty"`
+	Editor          string      `json:"editor,omitempty"`
+	SessionStart    *CustomTime `json:"session_start,omitempty" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	SessionSeconds  int64       `json:"session_seconds"` // time since the start of the current session, i.e. since the first heartbeat after a break
+	LastHeartbeatAt *CustomTime `json:"last_heartbeat_at,omitempty" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// Public strips all details the user did not choose to share publicly
+func (p *Presence) Public(user *User) *Presence {
+	public := *p
+	if !user.ShareProjects {
+		public.Project = ""
+	}
+	if !user.ShareLanguages {
+		public.Language = ""
+	}
+	if !user.ShareEditors {
+		public.Editor = ""
+	}
+	return &public
+}
@@ -0,0 +1,53 @@
+package models
+
+import "strings"
+
+// ProjectGroupReverseResolver returns all member projects of a given project group
+type ProjectGroupReverseResolver func(group string) []string
+
+// ProjectGroup is an umbrella project, which rolls up multiple projects (e.g. a backend, a frontend and a mobile app repository) into a single logical project in summaries
+type ProjectGroup struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"-" gorm:"not null; uniqueIndex:idx_project_group_user_name"`
+	Name      string     `json:"name" gorm:"not null; type:varchar(255); uniqueIndex:idx_project_group_user_name"`
+	Projects  []string   `json:"projects" gorm:"-"` // persisted as project group members
+	CreatedAt CustomTime `json:"created_at" gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// ProjectGroupMember assigns a project to a group, every project belongs to at most one group
+type ProjectGroupMember struct {
+	ID             uint          `gorm:"primary_key"`
+	User           *User         `gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID         string        `gorm:"not null; uniqueIndex:idx_project_group_member_user_project"`
+	ProjectGroup   *ProjectGroup `gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	ProjectGroupID uint          `gorm:"not null; index:idx_project_group_member_group"`
+	ProjectKey     string        `gorm:"not null; type:varchar(255); uniqueIndex:idx_project_group_member_user_project"`
+}
+
+func (g *ProjectGroup) IsValid() bool {
+	if name := strings.TrimSpace(g.Name); name == "" || len(name) > 255 || name == UnknownSummaryKey {
+		return false
+	}
+	for _, p := range g.Projects {
+		// a group containing itself would be resolved to itself anyway, but is most likely a mistake
+		if p == "" || p == g.Name {
+			return false
+		}
+	}
+	return true
+}
+
+// Members returns the group's projects as assignments to be persisted
+func (g *ProjectGroup) Members() []*ProjectGroupMember {
+	seen := make(map[string]bool, len(g.Projects))
+	members := make([]*ProjectGroupMember, 0, len(g.Projects))
+	for _, p := range g.Projects {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		members = append(members, &ProjectGroupMember{UserID: g.UserID, ProjectGroupID: g.ID, ProjectKey: p})
+	}
+	return members
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestProjectGroup_IsValid(t *testing.T) {
+	assert.True(t, (&ProjectGroup{Name: "wakapi-all", Projects: []string{"wakapi", "wakapi-mobile"}}).IsValid())
+	assert.True(t, (&ProjectGroup{Name: "wakapi-all"}).IsValid())
+	assert.False(t, (&ProjectGroup{Name: " "}).IsValid())
+	assert.False(t, (&ProjectGroup{Name: UnknownSummaryKey}).IsValid())
+	assert.False(t, (&ProjectGroup{Name: "wakapi-all", Projects: []string{"wakapi", ""}}).IsValid())
+	assert.False(t, (&ProjectGroup{Name: "wakapi", Projects: []string{"wakapi", "wakapi-mobile"}}).IsValid())
+}
+
+func TestProjectGroup_Members(t *testing.T) {
+	sut := &ProjectGroup{ID: 1, UserID: "user1", Name: "wakapi-all", Projects: []string{"wakapi", "wakapi-mobile", "wakapi"}}
+
+	result := sut.Members()
+	assert.Len(t, result, 2)
+	assert.Equal(t, &ProjectGroupMember{UserID: "user1", ProjectGroupID: 1, ProjectKey: "wakapi"}, result[0])
+	assert.Equal(t, "wakapi-mobile", result[1].ProjectKey)
+}
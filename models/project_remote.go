@@ -0,0 +1,66 @@
+package models
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// matches scp-like git remotes, e.g. "git@github.com:muety/wakapi.git"
+var scpRemoteRegex = regexp.MustCompile(`^(?:[\w.\-]+@)?([\w.\-]+):(?:/)?(.+)$`)
+
+// ProjectNamespaceReverseResolver returns all projects for a given namespace
+type ProjectNamespaceReverseResolver func(ns string) []string
+
+// ProjectRemote is the repository remote last reported for one of a user's projects, along with the namespace (e.g. "github.com/muety") derived from it
+type ProjectRemote struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"-" gorm:"not null; uniqueIndex:idx_project_remote_user_project"`
+	Project   string     `json:"project" gorm:"not null; type:varchar(255); uniqueIndex:idx_project_remote_user_project"`
+	Remote    string     `json:"remote" gorm:"type:varchar(1024)"`
+	Namespace string     `json:"namespace" gorm:"type:varchar(255)"`
+	UpdatedAt CustomTime `json:"updated_at" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+func (r *ProjectRemote) IsValid() bool {
+	return r.Project != "" && r.Remote != "" && r.Namespace != ""
+}
+
+// ParseRemoteNamespace derives a namespace from a repository remote by stripping its scheme, credentials and the repository name itself,
+// e.g. "git@github.com:muety/wakapi.git" and "https://github.com/muety/wakapi" both yield "github.com/muety", while nested groups like "gitlab.com/group/sub/repo" yield "gitlab.com/group/sub"
+// returns an empty string if no namespace can be derived
+func ParseRemoteNamespace(remote string) string {
+	host, path := parseRemote(remote)
+	idx := strings.LastIndex(path, "/")
+	if host == "" || idx <= 0 {
+		return ""
+	}
+	return strings.ToLower(host + "/" + path[:idx])
+}
+
+// ParseRemoteWebUrl derives the repository's web url from a remote, assuming the hoster serves it under the same path as the remote, which holds for github, gitlab, bitbucket, gitea and the like,
+// e.g. "git@github.com:muety/wakapi.git" yields "https://github.com/muety/wakapi"
+// returns an empty string if no url can be derived
+func ParseRemoteWebUrl(remote string) string {
+	host, path := parseRemote(remote)
+	if host == "" || !strings.Contains(path, "/") {
+		return ""
+	}
+	return "https://" + strings.ToLower(host) + "/" + path
+}
+
+// parseRemote splits a remote into host and repository path, stripped of credentials, port and .git suffix
+func parseRemote(remote string) (host, path string) {
+	remote = strings.TrimSpace(remote)
+
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" && u.Host != "" {
+		host, path = u.Hostname(), u.Path
+	} else if m := scpRemoteRegex.FindStringSubmatch(remote); m != nil {
+		host, path = m[1], m[2]
+	} else {
+		return "", ""
+	}
+
+	return host, strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+}
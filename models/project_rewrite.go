@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+const (
+	ProjectRewriteStatusNone    = "none"
+	ProjectRewriteStatusPending = "pending"
+	ProjectRewriteStatusDone    = "done"
+	ProjectRewriteStatusFailed  = "failed"
+)
+
+type ProjectRenameRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type ProjectMergeRequest struct {
+	Sources []string `json:"sources"`
+	Target  string   `json:"target"`
+}
+
+// ProjectRewriteJob describes the state of a user's running or most recent project rename or merge
+type ProjectRewriteJob struct {
+	Status    string    `json:"status"`
+	Sources   []string  `json:"sources"`
+	Target    string    `json:"target"`
+	Progress  float64   `json:"progress"` // between 0 and 1
+	Rewritten int64     `json:"rewritten"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (r *ProjectRenameRequest) IsValid() bool {
+	return r.From != "" && r.To != "" && r.From != r.To
+}
+
+func (r *ProjectMergeRequest) IsValid() bool {
+	if r.Target == "" || len(r.Sources) == 0 {
+		return false
+	}
+	for _, s := range r.Sources {
+		if s == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (j *ProjectRewriteJob) IsPending() bool {
+	return j.Status == ProjectRewriteStatusPending
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// PublicStats are anonymous, instance-wide statistics, e.g. to be shown on status pages
+type PublicStats struct {
+	TotalUsers   int                  `json:"total_users"`
+	TotalHours   int                  `json:"total_hours"`
+	TopLanguages *LanguageTrendPeriod `json:"top_languages"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+}
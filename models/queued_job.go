@@ -0,0 +1,9 @@
+package models
+
+// QueuedJob is a background job, which is kept in the database for as long as it is pending, so that it can be resumed if the instance is restarted in the meantime
+type QueuedJob struct {
+	ID        uint64     `gorm:"primary_key"`
+	Type      string     `gorm:"not null; index:idx_queued_job_type"`
+	Payload   string     `gorm:"type:text"`         // json-encoded job parameters, specific to the type
+	CreatedAt CustomTime `gorm:"type:timestamp(3)"` // https://gorm.io/docs/conventions.html#CreatedAt
+}
@@ -3,9 +3,44 @@ package models
 import "time"
 
 type Report struct {
-	From           time.Time
-	To             time.Time
-	User           *User
-	Summary        *Summary
-	DailySummaries []*Summary
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"`
+	User           *User         `json:"-"`
+	Summary        *Summary      `json:"summary"`
+	DailySummaries []*Summary    `json:"daily_summaries"`
+	Annotations    []*Annotation `json:"annotations"`
+	Trends         *UserTrends   `json:"trends,omitempty"`        // only included in the weekly e-mail reports
+	WorkingHours   *WorkingHours `json:"working_hours,omitempty"` // only included if the user has set up a working schedule
 }
+
+// ReportFailure records why sending a user's latest report failed, to let them know their reports aren't arriving
+type ReportFailure struct {
+	Time   time.Time `json:"time"`
+	Reason string    `json:"reason"`
+}
+
+// ReportParams describe an ad-hoc report, as opposed to the weekly one sent out regularly
+type ReportParams struct {
+	From      time.Time
+	To        time.Time
+	Filters   *Filters
+	Groupings []uint8 // summary types to include, all if empty
+	Daily     bool    // whether to include per-day summaries
+}
+
+// ReportDefinition is the user-supplied description of an ad-hoc report
+type ReportDefinition struct {
+	Interval  string            `json:"interval" example:"last_month"` // alternatively, from and to can be specified
+	From      string            `json:"from" example:"2021-02-07"`
+	To        string            `json:"to" example:"2021-02-08"`
+	Filters   map[string]string `json:"filters"`                                 // summary type name to key, e.g. "project": "wakapi"
+	Groupings []string          `json:"groupings" example:"project,language"`    // summary type names to include, all if empty
+	Daily     bool              `json:"daily"`                                   // whether to include a per-day breakdown
+	Format    string            `json:"format" enums:"json,html" example:"json"` // output format, json by default
+	Async     bool              `json:"async"`                                   // whether to generate the report in the background and send it by e-mail instead of returning it
+}
+
+const (
+	ReportFormatJson = "json"
+	ReportFormatHtml = "html"
+)
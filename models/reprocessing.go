@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	ReprocessingStatusNone    = "none"
+	ReprocessingStatusPending = "pending"
+	ReprocessingStatusDone    = "done"
+	ReprocessingStatusFailed  = "failed"
+)
+
+const (
+	ReprocessingStepLanguageMappings = "language_mappings"
+	ReprocessingStepHeartbeats       = "heartbeats" // language detection, category and branch extraction
+	ReprocessingStepLabelRules       = "label_rules"
+	ReprocessingStepSummaries        = "summaries"
+)
+
+// ReprocessingJob describes the state of a user's running or most recent job to apply their current rules to existing heartbeats
+type ReprocessingJob struct {
+	Status    string    `json:"status"`
+	Step      string    `json:"step,omitempty"`
+	Progress  float64   `json:"progress"`  // between 0 and 1
+	Rewritten int64     `json:"rewritten"` // heartbeats with a mapped language
+	Updated   int64     `json:"updated"`   // heartbeats with a newly detected language, category or branch
+	Labeled   int       `json:"labeled"`   // newly assigned project labels
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (j *ReprocessingJob) IsPending() bool {
+	return j.Status == ReprocessingStatusPending
+}
@@ -0,0 +1,27 @@
+package models
+
+// RetentionImpact describes which data the next cleanup run would delete under the configured data retention period, compared to the one last confirmed by an admin
+type RetentionImpact struct {
+	RetentionMonths          int                    `json:"retention_months"`           // as currently configured, 0 or less if disabled
+	ConfirmedRetentionMonths int                    `json:"confirmed_retention_months"` // as last confirmed, 0 or less if disabled
+	ConfirmationRequired     bool                   `json:"confirmation_required"`      // whether cleanups are paused, because the configured period would delete more data than the confirmed one
+	Mode                     string                 `json:"mode"`                       // whether expired data is deleted or anonymized
+	NextRun                  *CustomTime            `json:"next_run" swaggertype:"string" format:"date"`
+	Cutoff                   *CustomTime            `json:"cutoff" swaggertype:"string" format:"date"` // data older than this is deleted at the next run, nil if retention is disabled
+	Heartbeats               int64                  `json:"heartbeats"`
+	Summaries                int64                  `json:"summaries"`
+	Users                    []*UserRetentionImpact `json:"users"` // affected users only, users exempt from data retention (e.g. subscribers) are omitted
+}
+
+type UserRetentionImpact struct {
+	User                 string `json:"user"`
+	Heartbeats           int64  `json:"heartbeats"`
+	Summaries            int64  `json:"summaries"`
+	AdditionalHeartbeats int64  `json:"additional_heartbeats"` // number of heartbeats, which would not have been deleted under the confirmed retention period
+	AdditionalSummaries  int64  `json:"additional_summaries"`
+}
+
+// RetentionRequiresConfirmation returns whether changing the data retention period from confirmed to configured months deletes data, which would have been kept before
+func RetentionRequiresConfirmation(configured, confirmed int) bool {
+	return configured > 0 && (confirmed <= 0 || configured < confirmed)
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+)
+
+// SandboxHeartbeat is the result of a heartbeat sent to the sandbox endpoint, where plugin developers can test their clients without affecting any statistics
+// sandbox heartbeats are kept apart from regular ones and purged nightly
+type SandboxHeartbeat struct {
+	ID        uint64     `json:"-" gorm:"primary_key"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"-" gorm:"not null; index:idx_sandbox_heartbeat_user"`
+	Result    string     `json:"-" gorm:"type:text"`         // json-encoded SandboxResult
+	CreatedAt CustomTime `json:"-" gorm:"type:timestamp(3)"` // https://gorm.io/docs/conventions.html#CreatedAt
+}
+
+// SandboxResult describes how a heartbeat sent to the sandbox would have been processed by the regular heartbeats endpoint
+type SandboxResult struct {
+	Index            int        `json:"index"`             // position within the request
+	Status           int        `json:"status"`            // http status the heartbeat would have been answered with
+	Accepted         bool       `json:"accepted"`          // whether the heartbeat would have been stored
+	Errors           []string   `json:"errors"`            // reasons for the heartbeat to be rejected
+	Warnings         []string   `json:"warnings"`          // issues which don't prevent the heartbeat from being stored, but might be unintended
+	Blocked          bool       `json:"blocked"`           // dropped by an instance-wide block rule
+	Duplicate        bool       `json:"duplicate"`         // identical to a previous heartbeat of the same request and thus ignored
+	Hash             string     `json:"hash"`              // used for deduplication
+	LanguageOriginal string     `json:"language_original"` // language as reported by the client, if overridden by one of the user's language mappings
+	UnknownFields    []string   `json:"unknown_fields"`    // fields unknown to wakapi, which are kept as extras
+	Heartbeat        *Heartbeat `json:"heartbeat"`         // the heartbeat as it would have been stored
+	ReceivedAt       CustomTime `json:"received_at" swaggertype:"primitive,number"`
+}
+
+func NewSandboxHeartbeat(userId string, result *SandboxResult) (*SandboxHeartbeat, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return &SandboxHeartbeat{UserID: userId, Result: string(data)}, nil
+}
+
+func (h *SandboxHeartbeat) Unmarshal() (*SandboxResult, error) {
+	var result SandboxResult
+	if err := json.Unmarshal([]byte(h.Result), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"regexp"
+)
+
+var savedFilterNameReg = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// SavedFilter is a named set of summary filters (e.g. "work" for "project=a,b&branch!=main"), which can be referred to by its name instead of repeating all filter parameters
+type SavedFilter struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"-" gorm:"not null; uniqueIndex:idx_saved_filter_user_name"`
+	Name      string     `json:"name" gorm:"not null; type:varchar(64); uniqueIndex:idx_saved_filter_user_name" example:"work"`
+	Query     string     `json:"query" gorm:"not null; type:text" example:"project=wakapi,anchr&branch!=main"` // filter query parameters, as accepted by the summary endpoints
+	CreatedAt CustomTime `json:"created_at" gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// IsValid only checks the name, which must be usable as part of urls, the query itself is validated when parsing it
+func (f *SavedFilter) IsValid() bool {
+	return savedFilterNameReg.MatchString(f.Name) && f.Query != ""
+}
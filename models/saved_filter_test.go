@@ -0,0 +1,15 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSavedFilter_IsValid(t *testing.T) {
+	assert.True(t, (&SavedFilter{Name: "work", Query: "project=wakapi"}).IsValid())
+	assert.True(t, (&SavedFilter{Name: "side_projects-2", Query: "label=oss"}).IsValid())
+	assert.False(t, (&SavedFilter{Name: "", Query: "project=wakapi"}).IsValid())
+	assert.False(t, (&SavedFilter{Name: "my work", Query: "project=wakapi"}).IsValid())
+	assert.False(t, (&SavedFilter{Name: "work:wakapi", Query: "project=wakapi"}).IsValid())
+	assert.False(t, (&SavedFilter{Name: "work", Query: ""}).IsValid())
+}
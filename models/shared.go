@@ -8,6 +8,9 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/utils"
 )
 
 const (
@@ -43,13 +46,33 @@ func (j *CustomTime) UnmarshalJSON(b []byte) error {
 	s := strings.Trim(string(b), "\"")
 	ts, err := strconv.ParseFloat(s, 64)
 	if err != nil {
-		return err
+		// also accept the format produced by MarshalJSON(), e.g. when reading back heartbeats relayed by another instance
+		t, err2 := time.Parse(time.RFC3339Nano, s)
+		if err2 != nil {
+			return err
+		}
+		*j = CustomTime(t)
+		return nil
 	}
 	t := time.Unix(0, int64(ts*1e9)) // ms to ns
 	*j = CustomTime(t)
 	return nil
 }
 
+// MarshalBinary makes custom times gob-encodable (e.g. for caching in redis), which they otherwise aren't for lack of exported fields
+func (j CustomTime) MarshalBinary() ([]byte, error) {
+	return j.T().MarshalBinary()
+}
+
+func (j *CustomTime) UnmarshalBinary(data []byte) error {
+	var t time.Time
+	if err := t.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	*j = CustomTime(t)
+	return nil
+}
+
 func (j *CustomTime) Scan(value interface{}) error {
 	var (
 		t   time.Time
@@ -97,3 +120,41 @@ func (j CustomTime) T() time.Time {
 func (j CustomTime) Valid() bool {
 	return j.T().Unix() >= 0
 }
+
+// EncryptedString is a string, that is transparently encrypted when written to and decrypted when read from the database, using the configured encryption key
+type EncryptedString string
+
+const encryptedStringPrefix = "enc:"
+
+func (s *EncryptedString) Scan(value interface{}) error {
+	var raw string
+	switch v := value.(type) {
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return errors.New(fmt.Sprintf("unsupported type: %T", value))
+	}
+
+	// values stored before encryption was introduced are read as they are and encrypted upon their next update
+	if !strings.HasPrefix(raw, encryptedStringPrefix) {
+		*s = EncryptedString(raw)
+		return nil
+	}
+
+	plaintext, err := utils.DecryptString(strings.TrimPrefix(raw, encryptedStringPrefix), config.Get().Security.EncryptionKey)
+	if err != nil {
+		return err
+	}
+	*s = EncryptedString(plaintext)
+	return nil
+}
+
+func (s EncryptedString) Value() (driver.Value, error) {
+	ciphertext, err := utils.EncryptString(string(s), config.Get().Security.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return encryptedStringPrefix + ciphertext, nil
+}
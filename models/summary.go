@@ -9,16 +9,18 @@ import (
 )
 
 const (
-	NSummaryTypes   uint8 = 99
-	SummaryUnknown  uint8 = 98
-	SummaryProject  uint8 = 0
-	SummaryLanguage uint8 = 1
-	SummaryEditor   uint8 = 2
-	SummaryOS       uint8 = 3
-	SummaryMachine  uint8 = 4
-	SummaryLabel    uint8 = 5
-	SummaryBranch   uint8 = 6
-	SummaryEntity   uint8 = 7
+	NSummaryTypes    uint8 = 99
+	SummaryUnknown   uint8 = 98
+	SummaryProject   uint8 = 0
+	SummaryLanguage  uint8 = 1
+	SummaryEditor    uint8 = 2
+	SummaryOS        uint8 = 3
+	SummaryMachine   uint8 = 4
+	SummaryLabel     uint8 = 5
+	SummaryBranch    uint8 = 6
+	SummaryEntity    uint8 = 7
+	SummaryNamespace uint8 = 8
+	SummaryClient    uint8 = 9
 )
 
 const UnknownSummaryKey = "unknown"
@@ -37,10 +39,13 @@ type Summary struct {
 	Editors          SummaryItems `json:"editors" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	OperatingSystems SummaryItems `json:"operating_systems" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	Machines         SummaryItems `json:"machines" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	Labels           SummaryItems `json:"labels" gorm:"-"`   // labels are not persisted, but calculated at runtime, i.e. when summary is retrieved
-	Branches         SummaryItems `json:"branches" gorm:"-"` // branches are not persisted, but calculated at runtime in case a project filter is applied
-	Entities         SummaryItems `json:"entities" gorm:"-"` // entities are not persisted, but calculated at runtime in case a project filter is applied
+	Labels           SummaryItems `json:"labels" gorm:"-"`     // labels are not persisted, but calculated at runtime, i.e. when summary is retrieved
+	Branches         SummaryItems `json:"branches" gorm:"-"`   // branches are not persisted, but calculated at runtime in case a project filter is applied
+	Entities         SummaryItems `json:"entities" gorm:"-"`   // entities are not persisted, but calculated at runtime in case a project filter is applied
+	Namespaces       SummaryItems `json:"namespaces" gorm:"-"` // namespaces are not persisted, but calculated at runtime from projects' repository remotes
+	Clients          SummaryItems `json:"clients" gorm:"-"`    // clients are not persisted, but calculated at runtime from the user's client projects
 	NumHeartbeats    int          `json:"-"`
+	Partial          bool         `json:"-" gorm:"default:false"` // today's summary, which is updated incrementally throughout the day and superseded by the final one from the nightly aggregation
 }
 
 type SummaryItems []*SummaryItem
@@ -68,7 +73,7 @@ type SummaryParams struct {
 }
 
 func SummaryTypes() []uint8 {
-	return []uint8{SummaryProject, SummaryLanguage, SummaryEditor, SummaryOS, SummaryMachine, SummaryLabel, SummaryBranch, SummaryEntity}
+	return []uint8{SummaryProject, SummaryLanguage, SummaryEditor, SummaryOS, SummaryMachine, SummaryLabel, SummaryBranch, SummaryEntity, SummaryNamespace, SummaryClient}
 }
 
 func NativeSummaryTypes() []uint8 {
@@ -89,6 +94,8 @@ func NewEmptySummary() *Summary {
 		Labels:           SummaryItems{},
 		Branches:         SummaryItems{},
 		Entities:         SummaryItems{},
+		Namespaces:       SummaryItems{},
+		Clients:          SummaryItems{},
 	}
 }
 
@@ -101,6 +108,8 @@ func (s *Summary) Sorted() *Summary {
 	sort.Sort(sort.Reverse(s.Labels))
 	sort.Sort(sort.Reverse(s.Branches))
 	sort.Sort(sort.Reverse(s.Entities))
+	sort.Sort(sort.Reverse(s.Namespaces))
+	sort.Sort(sort.Reverse(s.Clients))
 	return s
 }
 
@@ -110,14 +119,16 @@ func (s *Summary) Types() []uint8 {
 
 func (s *Summary) MappedItems() map[uint8]*SummaryItems {
 	return map[uint8]*SummaryItems{
-		SummaryProject:  &s.Projects,
-		SummaryLanguage: &s.Languages,
-		SummaryEditor:   &s.Editors,
-		SummaryOS:       &s.OperatingSystems,
-		SummaryMachine:  &s.Machines,
-		SummaryLabel:    &s.Labels,
-		SummaryBranch:   &s.Branches,
-		SummaryEntity:   &s.Entities,
+		SummaryProject:   &s.Projects,
+		SummaryLanguage:  &s.Languages,
+		SummaryEditor:    &s.Editors,
+		SummaryOS:        &s.OperatingSystems,
+		SummaryMachine:   &s.Machines,
+		SummaryLabel:     &s.Labels,
+		SummaryBranch:    &s.Branches,
+		SummaryEntity:    &s.Entities,
+		SummaryNamespace: &s.Namespaces,
+		SummaryClient:    &s.Clients,
 	}
 }
 
@@ -139,6 +150,10 @@ func (s *Summary) ItemsByType(summaryType uint8) *SummaryItems {
 		return &s.Branches
 	case SummaryEntity:
 		return &s.Entities
+	case SummaryNamespace:
+		return &s.Namespaces
+	case SummaryClient:
+		return &s.Clients
 	}
 	return nil
 }
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"math"
+	"sort"
+)
+
+// SummaryComparison holds the summaries of two periods of the same kind (e.g. this week and last week) along with their differences
+type SummaryComparison struct {
+	Current  *Summary      `json:"current"`
+	Previous *Summary      `json:"previous"`
+	Deltas   *SummaryDelta `json:"deltas"`
+}
+
+// SummaryDelta lists the changes from the previous to the current period, all durations are in seconds, just like summary items' totals
+type SummaryDelta struct {
+	Current          int64               `json:"current"`
+	Previous         int64               `json:"previous"`
+	Total            int64               `json:"total"`
+	Percent          *float64            `json:"percent"` // relative change, nil if nothing was tracked in the previous period
+	Projects         []*SummaryItemDelta `json:"projects"`
+	Languages        []*SummaryItemDelta `json:"languages"`
+	Editors          []*SummaryItemDelta `json:"editors"`
+	OperatingSystems []*SummaryItemDelta `json:"operating_systems"`
+	Machines         []*SummaryItemDelta `json:"machines"`
+	Labels           []*SummaryItemDelta `json:"labels"`
+	Branches         []*SummaryItemDelta `json:"branches"`
+	Entities         []*SummaryItemDelta `json:"entities"`
+}
+
+type SummaryItemDelta struct {
+	Key      string   `json:"key"`
+	Current  int64    `json:"current"`
+	Previous int64    `json:"previous"`
+	Total    int64    `json:"total"`
+	Percent  *float64 `json:"percent"`
+}
+
+func NewSummaryComparison(current, previous *Summary) *SummaryComparison {
+	currentTotal, previousTotal := int64(current.TotalTime().Seconds()), int64(previous.TotalTime().Seconds())
+	return &SummaryComparison{
+		Current:  current,
+		Previous: previous,
+		Deltas: &SummaryDelta{
+			Current:          currentTotal,
+			Previous:         previousTotal,
+			Total:            currentTotal - previousTotal,
+			Percent:          relativeChange(currentTotal, previousTotal),
+			Projects:         itemDeltas(current.Projects, previous.Projects),
+			Languages:        itemDeltas(current.Languages, previous.Languages),
+			Editors:          itemDeltas(current.Editors, previous.Editors),
+			OperatingSystems: itemDeltas(current.OperatingSystems, previous.OperatingSystems),
+			Machines:         itemDeltas(current.Machines, previous.Machines),
+			Labels:           itemDeltas(current.Labels, previous.Labels),
+			Branches:         itemDeltas(current.Branches, previous.Branches),
+			Entities:         itemDeltas(current.Entities, previous.Entities),
+		},
+	}
+}
+
+// itemDeltas matches items of both periods by key, items present in only one of them count as zero in the other
+// results are sorted by absolute change, biggest first
+func itemDeltas(current, previous SummaryItems) []*SummaryItemDelta {
+	deltasByKey := make(map[string]*SummaryItemDelta)
+	for _, item := range current {
+		if _, ok := deltasByKey[item.Key]; !ok {
+			deltasByKey[item.Key] = &SummaryItemDelta{Key: item.Key}
+		}
+		deltasByKey[item.Key].Current += int64(item.Total)
+	}
+	for _, item := range previous {
+		if _, ok := deltasByKey[item.Key]; !ok {
+			deltasByKey[item.Key] = &SummaryItemDelta{Key: item.Key}
+		}
+		deltasByKey[item.Key].Previous += int64(item.Total)
+	}
+
+	deltas := make([]*SummaryItemDelta, 0, len(deltasByKey))
+	for _, d := range deltasByKey {
+		d.Total = d.Current - d.Previous
+		d.Percent = relativeChange(d.Current, d.Previous)
+		deltas = append(deltas, d)
+	}
+
+	sort.Slice(deltas, func(i, j int) bool {
+		if a, b := abs(deltas[i].Total), abs(deltas[j].Total); a != b {
+			return a > b
+		}
+		return deltas[i].Key < deltas[j].Key
+	})
+	return deltas
+}
+
+func relativeChange(current, previous int64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	percent := math.Round(float64(current-previous)/float64(previous)*10000) / 100
+	return &percent
+}
+
+func abs(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
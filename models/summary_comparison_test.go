@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewSummaryComparison(t *testing.T) {
+	current := NewEmptySummary()
+	current.Projects = SummaryItems{{Key: "wakapi", Total: 7200}, {Key: "anchr", Total: 1800}}
+	current.Languages = SummaryItems{{Key: "Go", Total: 9000}}
+
+	previous := NewEmptySummary()
+	previous.Projects = SummaryItems{{Key: "wakapi", Total: 3600}, {Key: "website", Total: 900}}
+	previous.Languages = SummaryItems{{Key: "Go", Total: 4500}}
+
+	sut := NewSummaryComparison(current, previous)
+
+	assert.Same(t, current, sut.Current)
+	assert.Same(t, previous, sut.Previous)
+	assert.Equal(t, int64(9000), sut.Deltas.Current)
+	assert.Equal(t, int64(4500), sut.Deltas.Previous)
+	assert.Equal(t, int64(4500), sut.Deltas.Total)
+	assert.Equal(t, 100.0, *sut.Deltas.Percent)
+
+	assert.Len(t, sut.Deltas.Projects, 3)
+	assert.Equal(t, &SummaryItemDelta{Key: "wakapi", Current: 7200, Previous: 3600, Total: 3600, Percent: sut.Deltas.Projects[0].Percent}, sut.Deltas.Projects[0])
+	assert.Equal(t, 100.0, *sut.Deltas.Projects[0].Percent)
+	assert.Equal(t, "anchr", sut.Deltas.Projects[1].Key)
+	assert.Nil(t, sut.Deltas.Projects[1].Percent) // new project
+	assert.Equal(t, "website", sut.Deltas.Projects[2].Key)
+	assert.Equal(t, int64(-900), sut.Deltas.Projects[2].Total)
+	assert.Equal(t, -100.0, *sut.Deltas.Projects[2].Percent)
+	assert.Empty(t, sut.Deltas.Machines)
+}
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// TotalByKey is the time spent on a certain entity (e.g. a language) by all users within some period
+type TotalByKey struct {
+	Key   string
+	Total time.Duration // in seconds, as stored in summary items
+	Users int           // number of distinct users who contributed to the total
+}
+
+type LanguageTrends struct {
+	Interval string                 `json:"interval"`
+	From     time.Time              `json:"from"`
+	To       time.Time              `json:"to"`
+	Periods  []*LanguageTrendPeriod `json:"periods"`
+}
+
+type LanguageTrendPeriod struct {
+	From      time.Time            `json:"from"`
+	To        time.Time            `json:"to"`
+	Languages []*LanguageTrendItem `json:"languages"`
+}
+
+type LanguageTrendItem struct {
+	Language string  `json:"language"`
+	Hours    float64 `json:"hours"`
+	Percent  float64 `json:"percent"`
+}
+
+// UserTrends describes a user's coding habits over the past weeks, along with projections for the current week and month, all times are in hours
+type UserTrends struct {
+	From         time.Time           `json:"from"`
+	To           time.Time           `json:"to"`
+	DailyAverage float64             `json:"daily_average"` // excluding today
+	Days         []*UserTrendDay     `json:"days"`
+	Weekdays     []*UserTrendWeekday `json:"weekdays"` // starting on monday
+	Week         *UserProjection     `json:"week"`
+	Month        *UserProjection     `json:"month"`
+}
+
+type UserTrendDay struct {
+	Date           string  `json:"date"`
+	Hours          float64 `json:"hours"`
+	RollingAverage float64 `json:"rolling_average"` // average hours per day of the past seven days, up to and including this one
+}
+
+type UserTrendWeekday struct {
+	Weekday      string  `json:"weekday"`
+	AverageHours float64 `json:"average_hours"`
+	Percent      float64 `json:"percent"` // share of the total time
+}
+
+type UserProjection struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Hours    float64   `json:"hours"`    // tracked so far
+	Expected float64   `json:"expected"` // projected total by the end of the period
+}
+
+// BusiestWeekday returns the weekday with the highest average, nil if there's no data at all
+func (t *UserTrends) BusiestWeekday() *UserTrendWeekday {
+	var busiest *UserTrendWeekday
+	for _, w := range t.Weekdays {
+		if w.AverageHours > 0 && (busiest == nil || w.AverageHours > busiest.AverageHours) {
+			busiest = w
+		}
+	}
+	return busiest
+}
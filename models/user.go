@@ -6,39 +6,80 @@ import (
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/utils"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
+const MaxInactivityAlertDays = 30
+
+const (
+	DefaultHeartbeatsTimeoutMinutes = 2
+	MinHeartbeatsTimeoutMinutes     = 2
+	MaxHeartbeatsTimeoutMinutes     = 30
+)
+
+const (
+	UserStatusActive      = "active"
+	UserStatusSuspended   = "suspended"
+	UserStatusDeactivated = "deactivated"
+)
+
 func init() {
 	mailRegex = regexp.MustCompile(MailPattern)
 }
 
 type User struct {
-	ID                  string      `json:"id" gorm:"primary_key"`
-	ApiKey              string      `json:"api_key" gorm:"unique; default:NULL"`
-	Email               string      `json:"email" gorm:"index:idx_user_email; size:255"`
-	Location            string      `json:"location"`
-	Password            string      `json:"-"`
-	CreatedAt           CustomTime  `gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
-	LastLoggedInAt      CustomTime  `gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
-	ShareDataMaxDays    int         `json:"-"`
-	ShareEditors        bool        `json:"-" gorm:"default:false; type:bool"`
-	ShareLanguages      bool        `json:"-" gorm:"default:false; type:bool"`
-	ShareProjects       bool        `json:"-" gorm:"default:false; type:bool"`
-	ShareOSs            bool        `json:"-" gorm:"default:false; type:bool; column:share_oss"`
-	ShareMachines       bool        `json:"-" gorm:"default:false; type:bool"`
-	ShareLabels         bool        `json:"-" gorm:"default:false; type:bool"`
-	IsAdmin             bool        `json:"-" gorm:"default:false; type:bool"`
-	HasData             bool        `json:"-" gorm:"default:false; type:bool"`
-	WakatimeApiKey      string      `json:"-"` // for relay middleware and imports
-	WakatimeApiUrl      string      `json:"-"` // for relay middleware and imports
-	ResetToken          string      `json:"-"`
-	ReportsWeekly       bool        `json:"-" gorm:"default:false; type:bool"`
-	PublicLeaderboard   bool        `json:"-" gorm:"default:false; type:bool"`
-	SubscribedUntil     *CustomTime `json:"-" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
-	SubscriptionRenewal *CustomTime `json:"-" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
-	StripeCustomerId    string      `json:"-"`
+	ID                       string      `json:"id" gorm:"primary_key"`
+	ApiKey                   string      `json:"api_key" gorm:"unique; default:NULL"`
+	Email                    string      `json:"email" gorm:"index:idx_user_email; size:255"`
+	Location                 string      `json:"location"`
+	Password                 string      `json:"-"`
+	CreatedAt                CustomTime  `gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	LastLoggedInAt           CustomTime  `gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	ShareDataMaxDays         int         `json:"-"`
+	ShareEditors             bool        `json:"-" gorm:"default:false; type:bool"`
+	ShareLanguages           bool        `json:"-" gorm:"default:false; type:bool"`
+	ShareProjects            bool        `json:"-" gorm:"default:false; type:bool"`
+	ShareOSs                 bool        `json:"-" gorm:"default:false; type:bool; column:share_oss"`
+	ShareMachines            bool        `json:"-" gorm:"default:false; type:bool"`
+	ShareLabels              bool        `json:"-" gorm:"default:false; type:bool"`
+	SharePresence            bool        `json:"-" gorm:"default:false; type:bool"` // whether to publicly tell what the user is currently working on
+	IsAdmin                  bool        `json:"-" gorm:"default:false; type:bool"`
+	HasData                  bool        `json:"-" gorm:"default:false; type:bool"`
+	WakatimeApiKey           string      `json:"-"` // for relay middleware and imports
+	WakatimeApiUrl           string      `json:"-"` // for relay middleware and imports
+	MirrorUrl                string      `json:"-"` // endpoint to mirror all accepted heartbeats to
+	MirrorSecret             string      `json:"-"` // key to sign mirrored heartbeat batches with
+	ResetToken               string      `json:"-"`
+	ReportsWeekly            bool        `json:"-" gorm:"default:false; type:bool"`
+	PublicLeaderboard        bool        `json:"-" gorm:"default:false; type:bool"`
+	SubscribedUntil          *CustomTime `json:"-" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	SubscriptionRenewal      *CustomTime `json:"-" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	StripeCustomerId         string      `json:"-"`
+	SubscriptionPlan         string      `json:"-" gorm:"size:64"` // id of the subscribed plan, see config.SubscriptionPlan
+	SubscriptionId           string      `json:"-"`                // the payment provider's id of the user's current subscription
+	Status                   string      `json:"-" gorm:"default:active; size:32"`
+	DeletionToken            string      `json:"-"`
+	DeletionTokenExpiry      *CustomTime `json:"-" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	DeleteAt                 *CustomTime `json:"-" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	IsHidden                 bool        `json:"-" gorm:"default:false; type:bool"` // hidden from all public views by moderation
+	DailyGoalMinutes         int         `json:"-" gorm:"default:0"`
+	NotificationDigest       bool        `json:"-" gorm:"default:false; type:bool"` // whether to batch notifications into one mail per day
+	QuietHoursStart          int         `json:"-" gorm:"default:0"`                // hour of day (in the user's time zone) from which on to hold back notifications
+	QuietHoursEnd            int         `json:"-" gorm:"default:0"`                // hour of day until which to hold back notifications, quiet hours are disabled if equal to start
+	EmailVerified            bool        `json:"-" gorm:"default:false; type:bool"` // whether the user confirmed to own their current e-mail address
+	VerificationToken        string      `json:"-"`                                 // to confirm the e-mail address with
+	HashEntities             bool        `json:"-" gorm:"default:false; type:bool"` // whether to store file paths of all projects as salted hashes only
+	HashEntityProjects       string      `json:"-"`                                 // comma-separated list of projects to store file paths as salted hashes for, if not enabled for all projects
+	EntityHashSalt           string      `json:"-"`                                 // random per-user salt for file path hashes
+	InactivityAlertDays      int         `json:"-" gorm:"default:0"`                // number of workdays without any heartbeats after which to notify the user, e.g. about a broken plugin setup, 0 to disable
+	HeartbeatsTimeoutMinutes int         `json:"-" gorm:"default:2"`                // max. gap between two heartbeats to still be glued together into one duration
+	NotifyAchievements       bool        `json:"-" gorm:"default:false; type:bool"` // whether to get notified about newly unlocked achievements
+	WorkingDays              string      `json:"-"`                                 // comma-separated weekdays (0 = sunday) the user usually works on, no working schedule if empty
+	WorkingHoursStart        int         `json:"-" gorm:"default:9"`                // hour of day (in the user's time zone) at which work starts
+	WorkingHoursEnd          int         `json:"-" gorm:"default:17"`               // hour of day at which work ends (exclusive)
+	WeeklyTargetHours        int         `json:"-" gorm:"default:0"`                // number of hours the user aims to work per week, 0 for no target
 }
 
 type Login struct {
@@ -52,6 +93,7 @@ type Signup struct {
 	Password       string `schema:"password"`
 	PasswordRepeat string `schema:"password_repeat"`
 	Location       string `schema:"location"`
+	InviteCode     string `schema:"invite_code"`
 }
 
 type SetPasswordRequest struct {
@@ -60,6 +102,10 @@ type SetPasswordRequest struct {
 	Token          string `schema:"token"`
 }
 
+type DeleteAccountRequest struct {
+	Token string `schema:"token"`
+}
+
 type ResetPasswordRequest struct {
 	Email string `schema:"email"`
 }
@@ -71,10 +117,17 @@ type CredentialsReset struct {
 }
 
 type UserDataUpdate struct {
-	Email             string `schema:"email"`
-	Location          string `schema:"location"`
-	ReportsWeekly     bool   `schema:"reports_weekly"`
-	PublicLeaderboard bool   `schema:"public_leaderboard"`
+	Email                    string `schema:"email"`
+	Location                 string `schema:"location"`
+	ReportsWeekly            bool   `schema:"reports_weekly"`
+	PublicLeaderboard        bool   `schema:"public_leaderboard"`
+	DailyGoalMinutes         int    `schema:"daily_goal_minutes"`
+	NotificationDigest       bool   `schema:"notification_digest"`
+	QuietHoursStart          int    `schema:"quiet_hours_start"`
+	QuietHoursEnd            int    `schema:"quiet_hours_end"`
+	InactivityAlertDays      int    `schema:"inactivity_alert_days"`
+	HeartbeatsTimeoutMinutes int    `schema:"heartbeats_timeout_minutes"`
+	NotifyAchievements       bool   `schema:"notify_achievements"`
 }
 
 type TimeByUser struct {
@@ -87,6 +140,112 @@ type CountByUser struct {
 	Count int64
 }
 
+// HashesEntitiesOf tells whether file paths of the given project are to be stored as salted hashes only
+func (u *User) HashesEntitiesOf(project string) bool {
+	if u.HashEntities {
+		return true
+	}
+	for _, p := range strings.Split(u.HashEntityProjects, ",") {
+		if p = strings.TrimSpace(p); p != "" && p == project {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkingWeekdays returns the days of the user's working schedule, ordered as in a week starting on sunday
+func (u *User) WorkingWeekdays() []time.Weekday {
+	days := make([]time.Weekday, 0, 7)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		for _, s := range strings.Split(u.WorkingDays, ",") {
+			if strings.TrimSpace(s) == strconv.Itoa(int(d)) {
+				days = append(days, d)
+				break
+			}
+		}
+	}
+	return days
+}
+
+func (u *User) WorksOn(day time.Weekday) bool {
+	for _, d := range u.WorkingWeekdays() {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *User) HasWorkingSchedule() bool {
+	return len(u.WorkingWeekdays()) > 0
+}
+
+// IsWorkingTime tells whether the given time is within the user's working hours on one of their working days, as seen from their time zone
+func (u *User) IsWorkingTime(t time.Time) bool {
+	t = t.In(u.TZ())
+	return u.WorksOn(t.Weekday()) && t.Hour() >= u.WorkingHoursStart && t.Hour() < u.WorkingHoursEnd
+}
+
+// WorkingTargetWithin is the share of the user's weekly target for all working days touched by the given interval, split evenly across working days
+func (u *User) WorkingTargetWithin(from, to time.Time) time.Duration {
+	days := u.WorkingWeekdays()
+	if u.WeeklyTargetHours <= 0 || len(days) == 0 || !to.After(from) {
+		return 0
+	}
+
+	var numDays int
+	tz := u.TZ()
+	from, to = from.In(tz), to.In(tz)
+	for day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, tz); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if u.WorksOn(day.Weekday()) {
+			numDays++
+		}
+	}
+	return time.Duration(u.WeeklyTargetHours) * time.Hour * time.Duration(numDays) / time.Duration(len(days))
+}
+
+// MissedWorkdays counts the full workdays (monday to friday, in the user's time zone) between the day of the last heartbeat and the given time's day, both exclusive
+func (u *User) MissedWorkdays(lastHeartbeat, t time.Time) int {
+	var missed int
+	tz := u.TZ()
+	last, now := lastHeartbeat.In(tz), t.In(tz)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, tz)
+	for day := time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, tz).AddDate(0, 0, 1); day.Before(today); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			missed++
+		}
+	}
+	return missed
+}
+
+// HeartbeatsTimeout returns the user's keystroke timeout, falling back to the default for unset or out-of-range values
+func (u *User) HeartbeatsTimeout() time.Duration {
+	if u.HeartbeatsTimeoutMinutes < MinHeartbeatsTimeoutMinutes || u.HeartbeatsTimeoutMinutes > MaxHeartbeatsTimeoutMinutes {
+		return DefaultHeartbeatsTimeoutMinutes * time.Minute
+	}
+	return time.Duration(u.HeartbeatsTimeoutMinutes) * time.Minute
+}
+
+func (u *User) DailyGoal() time.Duration {
+	return time.Duration(u.DailyGoalMinutes) * time.Minute
+}
+
+func (u *User) HasQuietHours() bool {
+	return u.QuietHoursStart != u.QuietHoursEnd
+}
+
+// IsQuietTime checks whether the given point in time falls into the user's quiet hours, which may span midnight (e.g. 22 to 7)
+func (u *User) IsQuietTime(t time.Time) bool {
+	if !u.HasQuietHours() {
+		return false
+	}
+	h := t.In(u.TZ()).Hour()
+	if u.QuietHoursStart < u.QuietHoursEnd {
+		return h >= u.QuietHoursStart && h < u.QuietHoursEnd
+	}
+	return h >= u.QuietHoursStart || h < u.QuietHoursEnd
+}
+
 func (u *User) Identity() string {
 	return u.ID
 }
@@ -154,15 +313,66 @@ func (u *User) SubscriptionExpiredSince() (bool, time.Duration) {
 	return diff >= 0, diff
 }
 
+// Plan returns the plan of the user's active subscription or nil, if they don't have one
+// subscriptions without a known plan (e.g. ones created before plans were introduced) are considered to be of the default plan
+func (u *User) Plan() *conf.SubscriptionPlan {
+	if !u.HasActiveSubscription() {
+		return nil
+	}
+	cfg := conf.Get()
+	if plan := cfg.Subscriptions.GetPlan(u.SubscriptionPlan); plan != nil {
+		return plan
+	}
+	return cfg.Subscriptions.DefaultPlan()
+}
+
 func (u *User) MinDataAge() time.Time {
 	retentionMonths := conf.Get().App.DataRetentionMonths
-	if retentionMonths <= 0 || u.HasActiveSubscription() {
+	if retentionMonths > 0 && u.HasActiveSubscription() {
+		// subscribers never get less history than free users
+		if plan := u.Plan(); plan == nil || plan.RetentionMonths <= 0 {
+			retentionMonths = 0
+		} else if plan.RetentionMonths > retentionMonths {
+			retentionMonths = plan.RetentionMonths
+		}
+	}
+	if retentionMonths <= 0 {
 		return time.Time{}
 	}
 	// this is not exactly precise, because of summer / winter time, etc.
 	return time.Now().AddDate(0, -retentionMonths, 0)
 }
 
+// IsSuspended returns true if the account was frozen by an administrator, in which case the user can neither log in nor send data
+func (u *User) IsSuspended() bool {
+	return u.Status == UserStatusSuspended
+}
+
+// IsDeactivated returns true if the user paused their account, in which case they can still log in (e.g. to reactivate), but no data is accepted
+func (u *User) IsDeactivated() bool {
+	return u.Status == UserStatusDeactivated
+}
+
+// IsActive returns true if the account is neither suspended nor deactivated, i.e. the user participates in leaderboards, receives mails, etc.
+// HasVerifiedEmail returns true if the user has an e-mail address set and confirmed to own it
+func (u *User) HasVerifiedEmail() bool {
+	return u.Email != "" && u.EmailVerified
+}
+
+func (u *User) IsActive() bool {
+	return u.Status == "" || u.Status == UserStatusActive
+}
+
+// IsScheduledForDeletion returns true if the user confirmed to delete their account, which will happen after the grace period has passed
+func (u *User) IsScheduledForDeletion() bool {
+	return u.DeleteAt != nil
+}
+
+// SharesPresence returns true if the user's presence may be publicly retrieved, which requires an explicit opt-in and an account that is neither hidden nor frozen
+func (u *User) SharesPresence() bool {
+	return u.SharePresence && !u.IsHidden && u.IsActive()
+}
+
 func (u *User) AnyDataShared() bool {
 	return u.ShareDataMaxDays != 0 && (u.ShareEditors || u.ShareLanguages || u.ShareProjects || u.ShareOSs || u.ShareMachines || u.ShareLabels)
 }
@@ -185,7 +395,10 @@ func (s *Signup) IsValid() bool {
 }
 
 func (r *UserDataUpdate) IsValid() bool {
-	return ValidateEmail(r.Email) && ValidateTimezone(r.Location)
+	return ValidateEmail(r.Email) && ValidateTimezone(r.Location) && r.DailyGoalMinutes >= 0 && r.DailyGoalMinutes <= 24*60 &&
+		r.QuietHoursStart >= 0 && r.QuietHoursStart < 24 && r.QuietHoursEnd >= 0 && r.QuietHoursEnd < 24 &&
+		r.InactivityAlertDays >= 0 && r.InactivityAlertDays <= MaxInactivityAlertDays &&
+		r.HeartbeatsTimeoutMinutes >= MinHeartbeatsTimeoutMinutes && r.HeartbeatsTimeoutMinutes <= MaxHeartbeatsTimeoutMinutes
 }
 
 func ValidateUsername(username string) bool {
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/muety/wakapi/utils"
+)
+
+const (
+	UserAgentPluginSuffix = "-wakatime"
+	UserAgentCliPlugin    = "wakatime-cli"
+)
+
+var (
+	userAgentPlatformPattern = regexp.MustCompile(`\([^)]*\)`)
+	userAgentGoPattern       = regexp.MustCompile(`^go(\d[\w.\-]*)$`)
+	browserPlugins           = []string{"chrome-wakatime", "firefox-wakatime", "edge-wakatime", "browser-wakatime"}
+	desktopPlugins           = []string{"desktop-wakatime", "macos-wakatime", "windows-wakatime"}
+)
+
+// UserAgentInfo holds the details extracted from a wakatime client's user agent string, e.g.
+// wakatime/v1.73.1 (linux-5.15.0-1019-aws-x86_64) go1.20.3 vscode/1.77.3 vscode-wakatime/24.0.10
+type UserAgentInfo struct {
+	Value         string
+	Os            string
+	Editor        string
+	EditorVersion string
+	Plugin        string
+	PluginVersion string
+	CliVersion    string
+	GoVersion     string
+}
+
+// UserAgentStats holds first and last occurrence of a user agent among a user's heartbeats
+type UserAgentStats struct {
+	UserId    string
+	UserAgent string
+	Count     int64
+	First     CustomTime
+	Last      CustomTime
+}
+
+// PluginVersionStats holds the number of distinct users sending heartbeats with a certain plugin version
+type PluginVersionStats struct {
+	Editor     string     `json:"editor"`
+	Plugin     string     `json:"plugin"`
+	Version    string     `json:"version"`
+	Users      int        `json:"users"`
+	LastSeenAt CustomTime `json:"last_seen_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+func ParseUserAgentInfo(ua string) *UserAgentInfo {
+	info := &UserAgentInfo{Value: ua}
+	info.Os, info.Editor, _ = utils.ParseUserAgent(ua)
+
+	tokens := strings.Fields(userAgentPlatformPattern.ReplaceAllString(ua, " "))
+	for i, token := range tokens {
+		if m := userAgentGoPattern.FindStringSubmatch(token); m != nil {
+			info.GoVersion = m[1]
+			continue
+		}
+
+		name, version, ok := strings.Cut(token, "/")
+		if !ok {
+			continue
+		}
+		version = strings.TrimPrefix(version, "v")
+
+		switch {
+		case i == 0 && strings.EqualFold(name, "wakatime"):
+			if version != "unset" {
+				info.CliVersion = version
+			}
+		case strings.HasSuffix(strings.ToLower(name), UserAgentPluginSuffix):
+			info.Plugin = strings.ToLower(name)
+			info.PluginVersion = version
+		case info.Plugin == "":
+			// editor token directly precedes the plugin token
+			info.EditorVersion = version
+		}
+	}
+
+	return info
+}
+
+func (i *UserAgentInfo) IsBrowserExtension() bool {
+	return slice.Contain(browserPlugins, i.Plugin)
+}
+
+func (i *UserAgentInfo) IsDesktopApp() bool {
+	return slice.Contain(desktopPlugins, i.Plugin)
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUserAgentInfo(t *testing.T) {
+	tests := []struct {
+		in  string
+		out UserAgentInfo
+	}{
+		{
+			"wakatime/v1.73.1 (linux-5.15.0-1019-aws-x86_64) go1.20.3 vscode/1.77.3 vscode-wakatime/24.0.10",
+			UserAgentInfo{Os: "Linux", Editor: "vscode", EditorVersion: "1.77.3", Plugin: "vscode-wakatime", PluginVersion: "24.0.10", CliVersion: "1.73.1", GoVersion: "1.20.3"},
+		},
+		{
+			"wakatime/13.0.7 (Linux-4.15.0-96-generic-x86_64-with-glibc2.4) Python3.8.0.final.0 GoLand/2019.3.4 GoLand-wakatime/11.0.1",
+			UserAgentInfo{Os: "Linux", Editor: "GoLand", EditorVersion: "2019.3.4", Plugin: "goland-wakatime", PluginVersion: "11.0.1", CliVersion: "13.0.7"},
+		},
+		{
+			"wakatime/unset (windows-10.0.22621-x86_64) go1.21.1 emacs-wakatime/1.0.2",
+			UserAgentInfo{Os: "Windows", Editor: "emacs", Plugin: "emacs-wakatime", PluginVersion: "1.0.2", GoVersion: "1.21.1"},
+		},
+		{
+			"",
+			UserAgentInfo{},
+		},
+	}
+
+	for _, test := range tests {
+		test.out.Value = test.in
+		assert.Equal(t, &test.out, ParseUserAgentInfo(test.in), test.in)
+	}
+}
+
+func TestUserAgentInfo_IsBrowserExtension(t *testing.T) {
+	assert.True(t, ParseUserAgentInfo("Chrome/120.0.0.0 chrome-wakatime/3.0.17").IsBrowserExtension())
+	assert.False(t, ParseUserAgentInfo("wakatime/v1.73.1 (linux-5.15.0-x86_64) go1.20.3 vscode/1.77.3 vscode-wakatime/24.0.10").IsBrowserExtension())
+}
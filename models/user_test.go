@@ -57,4 +57,119 @@ func TestUser_MinDataAge(t *testing.T) {
 	until1 := CustomTime(time.Now().AddDate(0, 1, 0))
 	sut = &User{SubscribedUntil: &until1}
 	assert.Zero(t, sut.MinDataAge())
+
+	// test with limited retention time, and user has got a plan with limited history
+	c.App.DataRetentionMonths = 1
+	c.Subscriptions.Enabled = true
+	c.Subscriptions.Plans = []*conf.SubscriptionPlan{
+		{Id: "basic", PriceId: "price_basic", RetentionMonths: 12},
+		{Id: "pro", PriceId: "price_pro"},
+	}
+	sut = &User{SubscribedUntil: &until1, SubscriptionPlan: "basic"}
+	assert.WithinRange(t, sut.MinDataAge(), time.Now().AddDate(0, -12, -1), time.Now().AddDate(0, -12, 1))
+
+	// test with limited retention time, and user has got a plan with unlimited history
+	sut = &User{SubscribedUntil: &until1, SubscriptionPlan: "pro"}
+	assert.Zero(t, sut.MinDataAge())
+
+	// test with subscription of unknown plan, which falls back to the default (first) one
+	sut = &User{SubscribedUntil: &until1, SubscriptionPlan: "legacy"}
+	assert.WithinRange(t, sut.MinDataAge(), time.Now().AddDate(0, -12, -1), time.Now().AddDate(0, -12, 1))
+
+	// test with plan granting less history than free users get
+	c.App.DataRetentionMonths = 24
+	sut = &User{SubscribedUntil: &until1, SubscriptionPlan: "basic"}
+	assert.WithinRange(t, sut.MinDataAge(), time.Now().AddDate(0, -24, -1), time.Now().AddDate(0, -24, 1))
+
+	c.Subscriptions.Plans = nil
+}
+
+func TestUser_Status(t *testing.T) {
+	sut := &User{}
+	assert.True(t, sut.IsActive())
+	assert.False(t, sut.IsSuspended())
+	assert.False(t, sut.IsDeactivated())
+
+	sut.Status = UserStatusDeactivated
+	assert.False(t, sut.IsActive())
+	assert.True(t, sut.IsDeactivated())
+
+	sut.Status = UserStatusSuspended
+	assert.False(t, sut.IsActive())
+	assert.True(t, sut.IsSuspended())
+}
+
+func TestUser_SharesPresence(t *testing.T) {
+	sut := &User{ShareDataMaxDays: -1, ShareProjects: true}
+	assert.False(t, sut.SharesPresence())
+
+	sut.SharePresence = true
+	assert.True(t, sut.SharesPresence())
+
+	sut.IsHidden = true
+	assert.False(t, sut.SharesPresence())
+
+	sut.IsHidden, sut.Status = false, UserStatusSuspended
+	assert.False(t, sut.SharesPresence())
+
+	sut.Status = UserStatusDeactivated
+	assert.False(t, sut.SharesPresence())
+}
+
+func TestUser_IsQuietTime(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	at := func(hour int) time.Time {
+		return time.Date(2023, 5, 1, hour, 30, 0, 0, berlin)
+	}
+
+	disabled := &User{Location: "Europe/Berlin", QuietHoursStart: 8, QuietHoursEnd: 8}
+	assert.False(t, disabled.IsQuietTime(at(8)))
+
+	daytime := &User{Location: "Europe/Berlin", QuietHoursStart: 12, QuietHoursEnd: 14}
+	assert.False(t, daytime.IsQuietTime(at(11)))
+	assert.True(t, daytime.IsQuietTime(at(12)))
+	assert.True(t, daytime.IsQuietTime(at(13)))
+	assert.False(t, daytime.IsQuietTime(at(14)))
+
+	overnight := &User{Location: "Europe/Berlin", QuietHoursStart: 22, QuietHoursEnd: 7}
+	assert.True(t, overnight.IsQuietTime(at(23)))
+	assert.True(t, overnight.IsQuietTime(at(0)))
+	assert.True(t, overnight.IsQuietTime(at(6)))
+	assert.False(t, overnight.IsQuietTime(at(7)))
+	assert.False(t, overnight.IsQuietTime(at(21)))
+
+	// hours are interpreted in the user's time zone
+	assert.True(t, overnight.IsQuietTime(time.Date(2023, 5, 1, 21, 30, 0, 0, time.UTC))) // 23:30 in berlin
+}
+
+func TestUser_MissedWorkdays(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	sut := &User{Location: "Europe/Berlin"}
+
+	friday := time.Date(2023, 5, 5, 18, 0, 0, 0, berlin)
+	assert.Equal(t, 0, sut.MissedWorkdays(friday, time.Date(2023, 5, 6, 10, 0, 0, 0, berlin)))  // saturday
+	assert.Equal(t, 0, sut.MissedWorkdays(friday, time.Date(2023, 5, 8, 10, 0, 0, 0, berlin)))  // monday, still in progress
+	assert.Equal(t, 1, sut.MissedWorkdays(friday, time.Date(2023, 5, 9, 10, 0, 0, 0, berlin)))  // tuesday
+	assert.Equal(t, 5, sut.MissedWorkdays(friday, time.Date(2023, 5, 15, 10, 0, 0, 0, berlin))) // monday a week later
+	assert.Equal(t, 0, sut.MissedWorkdays(friday, friday.Add(time.Hour)))
+
+	// days are determined in the user's time zone
+	assert.Equal(t, 1, sut.MissedWorkdays(friday, time.Date(2023, 5, 8, 22, 30, 0, 0, time.UTC))) // 00:30 on tuesday in berlin
+}
+
+func TestUser_HeartbeatsTimeout(t *testing.T) {
+	assert.Equal(t, 2*time.Minute, (&User{}).HeartbeatsTimeout())
+	assert.Equal(t, 2*time.Minute, (&User{HeartbeatsTimeoutMinutes: 60}).HeartbeatsTimeout())
+	assert.Equal(t, 15*time.Minute, (&User{HeartbeatsTimeoutMinutes: 15}).HeartbeatsTimeout())
+}
+
+func TestUser_HashesEntitiesOf(t *testing.T) {
+	sut := &User{HashEntityProjects: "secret-project, client-work"}
+	assert.True(t, sut.HashesEntitiesOf("secret-project"))
+	assert.True(t, sut.HashesEntitiesOf("client-work"))
+	assert.False(t, sut.HashesEntitiesOf("wakapi"))
+	assert.False(t, sut.HashesEntitiesOf(""))
+
+	sut.HashEntities = true
+	assert.True(t, sut.HashesEntitiesOf("wakapi"))
 }
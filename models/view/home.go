@@ -9,6 +9,7 @@ type HomeViewModel struct {
 	Messages
 	TotalHours int
 	TotalUsers int
+	HideTotals bool
 	Newsbox    *Newsbox
 }
 
@@ -4,6 +4,16 @@ type LoginViewModel struct {
 	Messages
 	TotalUsers  int
 	AllowSignup bool
+	InviteCode  string
+	Captcha     *CaptchaViewModel
+}
+
+type CaptchaViewModel struct {
+	Provider   string
+	SiteKey    string
+	ScriptUrl  string
+	Challenge  string // proof-of-work only
+	Difficulty int    // proof-of-work only
 }
 
 type SetPasswordViewModel struct {
@@ -11,6 +21,12 @@ type SetPasswordViewModel struct {
 	Token string
 }
 
+type DeleteAccountViewModel struct {
+	LoginViewModel
+	Token     string
+	GraceDays int
+}
+
 func (s *LoginViewModel) WithSuccess(m string) *LoginViewModel {
 	s.SetSuccess(m)
 	return s
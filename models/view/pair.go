@@ -0,0 +1,21 @@
+package view
+
+import "github.com/muety/wakapi/models"
+
+type PairViewModel struct {
+	Messages
+	User     *models.User
+	ApiKey   string
+	UserCode string
+	Pairing  *models.DevicePairing // the request to be confirmed, if any
+}
+
+func (s *PairViewModel) WithSuccess(m string) *PairViewModel {
+	s.SetSuccess(m)
+	return s
+}
+
+func (s *PairViewModel) WithError(m string) *PairViewModel {
+	s.SetError(m)
+	return s
+}
@@ -1,22 +1,36 @@
 package view
 
 import (
+	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
 	"time"
 )
 
 type SettingsViewModel struct {
 	Messages
-	User                *models.User
-	LanguageMappings    []*models.LanguageMapping
-	Aliases             []*SettingsVMCombinedAlias
-	Labels              []*SettingsVMCombinedLabel
-	Projects            []string
-	SubscriptionPrice   string
-	DataRetentionMonths int
-	UserFirstData       time.Time
-	SupportContact      string
-	ApiKey              string
+	User                     *models.User
+	LanguageMappings         []*models.LanguageMapping
+	DefaultLanguageMappings  map[string]string
+	Aliases                  []*SettingsVMCombinedAlias
+	Labels                   []*SettingsVMCombinedLabel
+	Projects                 []string
+	SubscriptionPlans        []*conf.SubscriptionPlan
+	SubscriptionTrialDays    int
+	PaymentProvider          string
+	DataRetentionMonths      int
+	AccountDeletionGraceDays int
+	UserFirstData            time.Time
+	SupportContact           string
+	ApiKey                   string
+	AccessTokens             []*models.AccessToken
+	LeaderboardEligibility   *SettingsVMLeaderboardEligibility
+	ReportFailure            *models.ReportFailure // set if the user's latest report could not be sent
+}
+
+type SettingsVMLeaderboardEligibility struct {
+	MinAccountAgeDays    int
+	MinActiveDays        int
+	RequireVerifiedEmail bool
 }
 
 type SettingsVMCombinedAlias struct {
@@ -31,7 +45,12 @@ type SettingsVMCombinedLabel struct {
 }
 
 func (s *SettingsViewModel) SubscriptionsEnabled() bool {
-	return s.SubscriptionPrice != ""
+	return len(s.SubscriptionPlans) > 0
+}
+
+// SubscriptionTrialAvailable returns true if the user is eligible for a free trial, which is only granted once
+func (s *SettingsViewModel) SubscriptionTrialAvailable() bool {
+	return s.SubscriptionTrialDays > 0 && s.User.SubscribedUntil == nil
 }
 
 func (s *SettingsViewModel) WithSuccess(m string) *SettingsViewModel {
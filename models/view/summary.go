@@ -19,6 +19,8 @@ type SummaryViewModel struct {
 	RawQuery            string
 	UserFirstData       time.Time
 	DataRetentionMonths int
+	Annotations         []*models.Annotation
+	WorkingHours        *models.WorkingHours // nil unless the user has a working schedule
 }
 
 func (s SummaryViewModel) UserDataExpiring() bool {
@@ -30,6 +32,22 @@ func (s SummaryViewModel) UserDataExpiring() bool {
 		time.Now().AddDate(0, -cfg.App.DataRetentionMonths, 0).After(s.UserFirstData)
 }
 
+// HasClients tells whether the user grouped any of the projects within the summary under a client or is filtering by one
+func (s SummaryViewModel) HasClients() bool {
+	if s.SummaryParams != nil && s.Filters != nil && s.Filters.CountByEntity(models.SummaryClient) > 0 {
+		return true
+	}
+	if s.Summary == nil {
+		return false
+	}
+	for _, c := range s.Clients {
+		if c.Key != models.UnknownSummaryKey {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *SummaryViewModel) WithSuccess(m string) *SummaryViewModel {
 	s.SetSuccess(m)
 	return s
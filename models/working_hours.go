@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+)
+
+// WorkingHours relates a user's coding time within some interval to their working schedule, all times are in hours
+type WorkingHours struct {
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	Total         float64   `json:"total"`
+	InHours       float64   `json:"in_hours"`       // within working hours on working days
+	OutOfHours    float64   `json:"out_of_hours"`   // outside working hours or on days off
+	Target        float64   `json:"target"`         // share of the weekly target for the working days within the interval, 0 if no target is set
+	Overtime      float64   `json:"overtime"`       // time coded beyond the target
+	TargetPercent float64   `json:"target_percent"` // share of the target reached so far, 0 if no target is set
+}
+
+type WorkingHoursUpdate struct {
+	WorkingDays       []int `schema:"working_days"`
+	WorkingHoursStart int   `schema:"working_hours_start"`
+	WorkingHoursEnd   int   `schema:"working_hours_end"`
+	WeeklyTargetHours int   `schema:"weekly_target_hours"`
+}
+
+// NewWorkingHours splits the given durations into time coded within and outside the user's working hours and compares the total to their target
+// durations spanning the start or end of working hours are split at full hours accordingly
+func NewWorkingHours(user *User, durations Durations, from, to time.Time) *WorkingHours {
+	var inHours, outOfHours time.Duration
+
+	tz := user.TZ()
+	for _, d := range durations {
+		start, end := d.Time.T().In(tz), d.Time.T().Add(d.Duration).In(tz)
+		for cur := start; cur.Before(end); {
+			// full hours in the user's time zone, which might be offset by less than an hour from utc
+			next := time.Date(cur.Year(), cur.Month(), cur.Day(), cur.Hour()+1, 0, 0, 0, tz)
+			if !next.After(cur) {
+				next = cur.Add(time.Hour) // ambiguous wall clock time when daylight saving time ends
+			}
+			if next.After(end) {
+				next = end
+			}
+			if user.IsWorkingTime(cur) {
+				inHours += next.Sub(cur)
+			} else {
+				outOfHours += next.Sub(cur)
+			}
+			cur = next
+		}
+	}
+
+	total := inHours + outOfHours
+	target := user.WorkingTargetWithin(from, to)
+
+	result := &WorkingHours{
+		From:       from,
+		To:         to,
+		Total:      total.Hours(),
+		InHours:    inHours.Hours(),
+		OutOfHours: outOfHours.Hours(),
+		Target:     target.Hours(),
+	}
+	if target > 0 {
+		result.TargetPercent = float64(total) / float64(target) * 100
+		if total > target {
+			result.Overtime = (total - target).Hours()
+		}
+	}
+	return result
+}
+
+func (r *WorkingHoursUpdate) IsValid() bool {
+	for _, d := range r.WorkingDays {
+		if d < int(time.Sunday) || d > int(time.Saturday) {
+			return false
+		}
+	}
+	return r.WorkingHoursStart >= 0 && r.WorkingHoursStart < r.WorkingHoursEnd && r.WorkingHoursEnd <= 24 &&
+		r.WeeklyTargetHours >= 0 && r.WeeklyTargetHours <= 7*24
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewWorkingHours(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	sut := &User{Location: "Europe/Berlin", WorkingDays: "1,2,3,4,5", WorkingHoursStart: 9, WorkingHoursEnd: 17, WeeklyTargetHours: 10}
+
+	monday := time.Date(2023, 5, 8, 0, 0, 0, 0, berlin)
+	durations := Durations{
+		{Time: CustomTime(monday.Add(8*time.Hour + 30*time.Minute)), Duration: 1 * time.Hour}, // half before, half within working hours
+		{Time: CustomTime(monday.Add(12 * time.Hour)), Duration: 2 * time.Hour},
+		{Time: CustomTime(monday.Add(22 * time.Hour)), Duration: 30 * time.Minute},
+		{Time: CustomTime(monday.AddDate(0, 0, 5).Add(10 * time.Hour)), Duration: 1 * time.Hour}, // saturday
+	}
+
+	result := NewWorkingHours(sut, durations, monday, monday.AddDate(0, 0, 7))
+	assert.Equal(t, 4.5, result.Total)
+	assert.Equal(t, 2.5, result.InHours)
+	assert.Equal(t, 2.0, result.OutOfHours)
+	assert.Equal(t, 10.0, result.Target)
+	assert.InDelta(t, 45.0, result.TargetPercent, 0.01)
+	assert.Zero(t, result.Overtime)
+
+	// only monday's share of the weekly target
+	result = NewWorkingHours(sut, durations[:3], monday, monday.Add(23*time.Hour))
+	assert.Equal(t, 2.0, result.Target)
+	assert.Equal(t, 1.5, result.Overtime)
+}
+
+func TestUser_WorkingTargetWithin(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	monday := time.Date(2023, 5, 8, 0, 0, 0, 0, berlin)
+
+	sut := &User{Location: "Europe/Berlin", WorkingDays: "1,3,5", WeeklyTargetHours: 30}
+	assert.Equal(t, 30*time.Hour, sut.WorkingTargetWithin(monday, monday.AddDate(0, 0, 7)))
+	assert.Equal(t, 10*time.Hour, sut.WorkingTargetWithin(monday, monday.AddDate(0, 0, 2)))
+	assert.Zero(t, sut.WorkingTargetWithin(monday.AddDate(0, 0, 1), monday.AddDate(0, 0, 2))) // tuesday
+
+	sut.WorkingDays = ""
+	assert.Zero(t, sut.WorkingTargetWithin(monday, monday.AddDate(0, 0, 7)))
+	assert.False(t, sut.HasWorkingSchedule())
+}
+
+func TestWorkingHoursUpdate_IsValid(t *testing.T) {
+	assert.True(t, (&WorkingHoursUpdate{WorkingDays: []int{1, 2, 3}, WorkingHoursStart: 9, WorkingHoursEnd: 17}).IsValid())
+	assert.True(t, (&WorkingHoursUpdate{WorkingHoursStart: 0, WorkingHoursEnd: 24, WeeklyTargetHours: 40}).IsValid())
+	assert.False(t, (&WorkingHoursUpdate{WorkingDays: []int{7}, WorkingHoursStart: 9, WorkingHoursEnd: 17}).IsValid())
+	assert.False(t, (&WorkingHoursUpdate{WorkingHoursStart: 17, WorkingHoursEnd: 9}).IsValid())
+	assert.False(t, (&WorkingHoursUpdate{WorkingHoursStart: 9, WorkingHoursEnd: 17, WeeklyTargetHours: -1}).IsValid())
+}
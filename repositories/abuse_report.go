@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+type AbuseReportRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewAbuseReportRepository(db *gorm.DB) *AbuseReportRepository {
+	return &AbuseReportRepository{config: config.Get(), db: db}
+}
+
+func (r *AbuseReportRepository) GetById(id uint) (*models.AbuseReport, error) {
+	report := &models.AbuseReport{}
+	if err := r.db.Where(&models.AbuseReport{ID: id}).First(report).Error; err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+func (r *AbuseReportRepository) GetByStatus(status string) ([]*models.AbuseReport, error) {
+	var reports []*models.AbuseReport
+	if err := r.db.
+		Where(&models.AbuseReport{Status: status}).
+		Order("created_at asc").
+		Find(&reports).Error; err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (r *AbuseReportRepository) CountByReporterAfter(reporterId string, t time.Time) (int64, error) {
+	var count int64
+	if err := r.db.
+		Model(&models.AbuseReport{}).
+		Where("reporter_id = ?", reporterId).
+		Where("created_at > ?", t.Local()).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *AbuseReportRepository) CountOpenByReporterAndTarget(report *models.AbuseReport) (int64, error) {
+	var count int64
+	if err := r.db.
+		Model(&models.AbuseReport{}).
+		Where("reporter_id = ?", report.ReporterID).
+		Where("target_id = ?", report.TargetID).
+		Where("target_type = ?", report.TargetType).
+		Where("target_project = ?", report.TargetProject).
+		Where("status = ?", models.AbuseReportStatusOpen).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *AbuseReportRepository) Insert(report *models.AbuseReport) (*models.AbuseReport, error) {
+	if !report.IsValid() {
+		return nil, errors.New("invalid report")
+	}
+	if err := r.db.Create(report).Error; err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (r *AbuseReportRepository) UpdateStatus(report *models.AbuseReport, status string) (*models.AbuseReport, error) {
+	if err := r.db.Model(report).Update("status", status).Error; err != nil {
+		return nil, err
+	}
+	report.Status = status
+	return report, nil
+}
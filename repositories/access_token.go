@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type AccessTokenRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewAccessTokenRepository(db *gorm.DB) *AccessTokenRepository {
+	return &AccessTokenRepository{config: config.Get(), db: db}
+}
+
+func (r *AccessTokenRepository) GetByHash(hash string) (*models.AccessToken, error) {
+	token := &models.AccessToken{}
+	if err := r.db.Where(&models.AccessToken{TokenHash: hash}).First(token).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *AccessTokenRepository) GetByUser(userId string) ([]*models.AccessToken, error) {
+	var tokens []*models.AccessToken
+	if err := r.db.
+		Where(&models.AccessToken{UserID: userId}).
+		Order("created_at desc").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *AccessTokenRepository) Insert(token *models.AccessToken) (*models.AccessToken, error) {
+	if err := r.db.Create(token).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *AccessTokenRepository) UpdateLastUsed(token *models.AccessToken) error {
+	return r.db.Model(token).Update("last_used_at", token.LastUsedAt).Error
+}
+
+func (r *AccessTokenRepository) DeleteByUserAndId(userId string, id uint) error {
+	return r.db.
+		Where("user_id = ?", userId).
+		Where("id = ?", id).
+		Delete(models.AccessToken{}).Error
+}
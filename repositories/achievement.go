@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AchievementRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewAchievementRepository(db *gorm.DB) *AchievementRepository {
+	return &AchievementRepository{config: config.Get(), db: db}
+}
+
+func (r *AchievementRepository) GetByUser(userId string) ([]*models.Achievement, error) {
+	var achievements []*models.Achievement
+	if err := r.db.
+		Where(&models.Achievement{UserID: userId}).
+		Order("unlocked_at asc, id asc").
+		Find(&achievements).Error; err != nil {
+		return nil, err
+	}
+	return achievements, nil
+}
+
+// InsertBatch stores newly unlocked achievements, ones already unlocked before are silently skipped
+func (r *AchievementRepository) InsertBatch(achievements []*models.Achievement) error {
+	if len(achievements) == 0 {
+		return nil
+	}
+	return r.db.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&achievements).Error
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type AnnotationRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewAnnotationRepository(db *gorm.DB) *AnnotationRepository {
+	return &AnnotationRepository{config: config.Get(), db: db}
+}
+
+func (r *AnnotationRepository) GetById(id uint) (*models.Annotation, error) {
+	annotation := &models.Annotation{}
+	if err := r.db.Where(&models.Annotation{ID: id}).First(annotation).Error; err != nil {
+		return annotation, err
+	}
+	return annotation, nil
+}
+
+// GetByUserWithin returns all of a user's annotations between the given days (inclusive, formatted as yyyy-mm-dd)
+func (r *AnnotationRepository) GetByUserWithin(userId string, fromDay, toDay string) ([]*models.Annotation, error) {
+	var annotations []*models.Annotation
+	if err := r.db.
+		Where(&models.Annotation{UserID: userId}).
+		Where("day >= ?", fromDay).
+		Where("day <= ?", toDay).
+		Order("day asc, id asc").
+		Find(&annotations).Error; err != nil {
+		return nil, err
+	}
+	return annotations, nil
+}
+
+func (r *AnnotationRepository) Insert(annotation *models.Annotation) (*models.Annotation, error) {
+	if !annotation.IsValid() {
+		return nil, errors.New("invalid annotation")
+	}
+	result := r.db.Create(annotation)
+	if err := result.Error; err != nil {
+		return nil, err
+	}
+	return annotation, nil
+}
+
+func (r *AnnotationRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.Annotation{}).Error
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BillableProjectRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewBillableProjectRepository(db *gorm.DB) *BillableProjectRepository {
+	return &BillableProjectRepository{config: config.Get(), db: db}
+}
+
+func (r *BillableProjectRepository) GetByUser(userId string) ([]*models.BillableProject, error) {
+	if userId == "" {
+		return []*models.BillableProject{}, nil
+	}
+	var projects []*models.BillableProject
+	if err := r.db.
+		Where(&models.BillableProject{UserID: userId}).
+		Order("client asc, project asc").
+		Find(&projects).Error; err != nil {
+		return projects, err
+	}
+	return projects, nil
+}
+
+// Upsert stores the given billable project, replacing client and rate previously set for the same project, if any
+func (r *BillableProjectRepository) Upsert(project *models.BillableProject) (*models.BillableProject, error) {
+	if !project.IsValid() {
+		return nil, errors.New("invalid billable project")
+	}
+	if err := r.db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "project"}},
+			DoUpdates: clause.AssignmentColumns([]string{"client", "hourly_rate", "currency"}),
+		}).
+		Create(project).Error; err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func (r *BillableProjectRepository) DeleteByUserAndProject(userId, project string) error {
+	return r.db.
+		Where("user_id = ?", userId).
+		Where("project = ?", project).
+		Delete(models.BillableProject{}).Error
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type BlockRuleRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewBlockRuleRepository(db *gorm.DB) *BlockRuleRepository {
+	return &BlockRuleRepository{config: config.Get(), db: db}
+}
+
+func (r *BlockRuleRepository) GetAll() ([]*models.BlockRule, error) {
+	var rules []*models.BlockRule
+	if err := r.db.Order("id asc").Find(&rules).Error; err != nil {
+		return rules, err
+	}
+	return rules, nil
+}
+
+func (r *BlockRuleRepository) GetById(id uint) (*models.BlockRule, error) {
+	rule := &models.BlockRule{}
+	if err := r.db.Where(&models.BlockRule{ID: id}).First(rule).Error; err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+func (r *BlockRuleRepository) Insert(rule *models.BlockRule) (*models.BlockRule, error) {
+	if !rule.IsValid() {
+		return nil, errors.New("invalid block rule")
+	}
+	result := r.db.Create(rule)
+	if err := result.Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *BlockRuleRepository) IncrementDropped(id uint, n int64, at time.Time) error {
+	return r.db.
+		Model(&models.BlockRule{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"dropped":         gorm.Expr("dropped + ?", n),
+			"last_dropped_at": at,
+		}).Error
+}
+
+func (r *BlockRuleRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.BlockRule{}).Error
+}
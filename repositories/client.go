@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type ClientRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewClientRepository(db *gorm.DB) *ClientRepository {
+	return &ClientRepository{config: config.Get(), db: db}
+}
+
+// GetByUser returns all of a user's clients along with their projects
+func (r *ClientRepository) GetByUser(userId string) ([]*models.Client, error) {
+	if userId == "" {
+		return []*models.Client{}, nil
+	}
+
+	var clients []*models.Client
+	if err := r.db.
+		Where(&models.Client{UserID: userId}).
+		Order("name asc").
+		Find(&clients).Error; err != nil {
+		return nil, err
+	}
+
+	var assignments []*models.ClientProject
+	if err := r.db.
+		Where(&models.ClientProject{UserID: userId}).
+		Order("project_key asc").
+		Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+
+	clientsById := make(map[uint]*models.Client, len(clients))
+	for _, c := range clients {
+		c.Projects = []string{}
+		clientsById[c.ID] = c
+	}
+	for _, a := range assignments {
+		if c, ok := clientsById[a.ClientID]; ok {
+			c.Projects = append(c.Projects, a.ProjectKey)
+		}
+	}
+	return clients, nil
+}
+
+func (r *ClientRepository) Insert(client *models.Client) (*models.Client, error) {
+	if !client.IsValid() {
+		return nil, errors.New("invalid client")
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Projects").Create(client).Error; err != nil {
+			return err
+		}
+		return r.assignProjects(tx, client)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Update renames the client and replaces its projects, projects previously assigned to another client are moved to this one
+func (r *ClientRepository) Update(client *models.Client) (*models.Client, error) {
+	if !client.IsValid() {
+		return nil, errors.New("invalid client")
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(client).Update("name", client.Name).Error; err != nil {
+			return err
+		}
+		return r.assignProjects(tx, client)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (r *ClientRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("client_id = ?", id).Delete(models.ClientProject{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(models.Client{}).Error
+	})
+}
+
+func (r *ClientRepository) assignProjects(tx *gorm.DB, client *models.Client) error {
+	if err := tx.Where("client_id = ?", client.ID).Delete(models.ClientProject{}).Error; err != nil {
+		return err
+	}
+
+	assignments := client.ClientProjects()
+	if len(assignments) == 0 {
+		return nil
+	}
+	if err := tx.
+		Where("user_id = ?", client.UserID).
+		Where("project_key in ?", client.Projects).
+		Delete(models.ClientProject{}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&assignments).Error
+}
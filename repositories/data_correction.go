@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type DataCorrectionRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewDataCorrectionRepository(db *gorm.DB) *DataCorrectionRepository {
+	return &DataCorrectionRepository{config: config.Get(), db: db}
+}
+
+func (r *DataCorrectionRepository) GetById(id uint) (*models.DataCorrection, error) {
+	correction := &models.DataCorrection{}
+	if err := r.db.Where(&models.DataCorrection{ID: id}).First(correction).Error; err != nil {
+		return correction, err
+	}
+	return correction, nil
+}
+
+func (r *DataCorrectionRepository) GetByUser(userId string) ([]*models.DataCorrection, error) {
+	var corrections []*models.DataCorrection
+	if err := r.db.
+		Where(&models.DataCorrection{UserID: userId}).
+		Order("created_at desc").
+		Find(&corrections).Error; err != nil {
+		return nil, err
+	}
+	return corrections, nil
+}
+
+func (r *DataCorrectionRepository) GetByStatus(status string) ([]*models.DataCorrection, error) {
+	var corrections []*models.DataCorrection
+	if err := r.db.
+		Where(&models.DataCorrection{Status: status}).
+		Order("created_at asc").
+		Find(&corrections).Error; err != nil {
+		return nil, err
+	}
+	return corrections, nil
+}
+
+func (r *DataCorrectionRepository) Insert(correction *models.DataCorrection) (*models.DataCorrection, error) {
+	if !correction.IsValid() {
+		return nil, errors.New("invalid data correction")
+	}
+	if err := r.db.Create(correction).Error; err != nil {
+		return nil, err
+	}
+	return correction, nil
+}
+
+// Update persists a correction's review and application state, while the requested change itself is immutable
+func (r *DataCorrectionRepository) Update(correction *models.DataCorrection) (*models.DataCorrection, error) {
+	if err := r.db.
+		Model(correction).
+		Select("status", "reviewer_id", "review_comment", "reviewed_at", "applied_at", "rewritten").
+		Updates(correction).Error; err != nil {
+		return nil, err
+	}
+	return correction, nil
+}
@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExportTargetRepository struct {
+	db *gorm.DB
+}
+
+func NewExportTargetRepository(db *gorm.DB) *ExportTargetRepository {
+	return &ExportTargetRepository{db: db}
+}
+
+// GetAll returns all targets along with their users
+func (r *ExportTargetRepository) GetAll() ([]*models.ExportTarget, error) {
+	var targets []*models.ExportTarget
+	if err := r.db.Preload("User").Find(&targets).Error; err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+func (r *ExportTargetRepository) GetByUser(userId string) (*models.ExportTarget, error) {
+	target := &models.ExportTarget{}
+	if err := r.db.Where(&models.ExportTarget{UserID: userId}).First(target).Error; err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// Upsert stores the given target, replacing the user's previous one, if any
+func (r *ExportTargetRepository) Upsert(target *models.ExportTarget) (*models.ExportTarget, error) {
+	if err := r.db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			UpdateAll: true,
+		}).
+		Omit("User").
+		Create(target).Error; err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// UpdateStatus records the outcome of the latest dump, without touching the target's settings, which the user might have changed in the meantime
+func (r *ExportTargetRepository) UpdateStatus(target *models.ExportTarget) error {
+	return r.db.Model(&models.ExportTarget{}).
+		Where("user_id = ?", target.UserID).
+		Updates(map[string]interface{}{
+			"last_dump_key": target.LastDumpKey,
+			"last_dump_at":  target.LastDumpAt,
+			"last_error":    target.LastError,
+		}).Error
+}
+
+func (r *ExportTargetRepository) DeleteByUser(userId string) error {
+	return r.db.Where("user_id = ?", userId).Delete(&models.ExportTarget{}).Error
+}
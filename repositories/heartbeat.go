@@ -4,8 +4,10 @@ import (
 	"github.com/duke-git/lancet/v2/slice"
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"strings"
 	"time"
 )
 
@@ -38,6 +40,21 @@ func (r *HeartbeatRepository) InsertBatch(heartbeats []*models.Heartbeat) error
 	return nil
 }
 
+// GetExistingHashes returns those of the given hashes, for which a heartbeat is stored already
+func (r *HeartbeatRepository) GetExistingHashes(hashes []string) ([]string, error) {
+	existing := make([]string, 0)
+	if len(hashes) == 0 {
+		return existing, nil
+	}
+	if err := r.db.
+		Model(&models.Heartbeat{}).
+		Where("hash in ?", hashes).
+		Pluck("hash", &existing).Error; err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
 func (r *HeartbeatRepository) GetLatestByUser(user *models.User) (*models.Heartbeat, error) {
 	var heartbeat models.Heartbeat
 	if err := r.db.
@@ -79,7 +96,21 @@ func (r *HeartbeatRepository) GetAllWithin(from, to time.Time, user *models.User
 	return heartbeats, nil
 }
 
-func (r *HeartbeatRepository) GetAllWithinByFilters(from, to time.Time, user *models.User, filterMap map[string][]string) ([]*models.Heartbeat, error) {
+// GetPageByUser returns up to limit of the user's heartbeats with an id greater than afterId, ordered by id, to iterate over all of them page by page
+func (r *HeartbeatRepository) GetPageByUser(user *models.User, afterId uint64, limit int) ([]*models.Heartbeat, error) {
+	var heartbeats []*models.Heartbeat
+	if err := r.db.
+		Where(&models.Heartbeat{UserID: user.ID}).
+		Where("id > ?", afterId).
+		Order("id asc").
+		Limit(limit).
+		Find(&heartbeats).Error; err != nil {
+		return nil, err
+	}
+	return heartbeats, nil
+}
+
+func (r *HeartbeatRepository) GetAllWithinByFilters(from, to time.Time, user *models.User, conditions []*models.ColumnCondition) ([]*models.Heartbeat, error) {
 	// https://stackoverflow.com/a/20765152/3112139
 	var heartbeats []*models.Heartbeat
 
@@ -88,25 +119,45 @@ func (r *HeartbeatRepository) GetAllWithinByFilters(from, to time.Time, user *mo
 		Where("time >= ?", from.Local()).
 		Where("time < ?", to.Local()).
 		Order("time asc")
-	q = r.filteredQuery(q, filterMap)
+	q = r.filteredQuery(q, conditions)
 
 	if err := q.Find(&heartbeats).Error; err != nil {
 		return nil, err
 	}
+	if regexConditions := r.regexConditions(conditions); len(regexConditions) > 0 {
+		heartbeats = slice.Filter[*models.Heartbeat](heartbeats, func(i int, h *models.Heartbeat) bool {
+			return r.matchRegexConditions(h, regexConditions)
+		})
+	}
 	return heartbeats, nil
 }
 
-func (r *HeartbeatRepository) GetLatestByFilters(user *models.User, filterMap map[string][]string) (*models.Heartbeat, error) {
+func (r *HeartbeatRepository) GetLatestByFilters(user *models.User, conditions []*models.ColumnCondition) (*models.Heartbeat, error) {
 	var heartbeat *models.Heartbeat
 
 	q := r.db.
 		Where(&models.Heartbeat{UserID: user.ID}).
 		Order("time desc")
-	q = r.filteredQuery(q, filterMap)
+	q = r.filteredQuery(q, conditions)
+
+	regexConditions := r.regexConditions(conditions)
+	if len(regexConditions) == 0 {
+		if err := q.First(&heartbeat).Error; err != nil {
+			return nil, err
+		}
+		return heartbeat, nil
+	}
 
-	if err := q.First(&heartbeat).Error; err != nil {
+	err := r.scanMatching(q, regexConditions, func(h *models.Heartbeat) bool {
+		heartbeat = h
+		return false
+	})
+	if err != nil {
 		return nil, err
 	}
+	if heartbeat == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
 	return heartbeat, nil
 }
 
@@ -130,6 +181,22 @@ func (r *HeartbeatRepository) GetLastByUsers() ([]*models.TimeByUser, error) {
 	return result, nil
 }
 
+// GetTimeRangeByUserAndProjects returns the times of a user's first and last heartbeat within any of the given projects, or among all of their heartbeats if projects is nil
+func (r *HeartbeatRepository) GetTimeRangeByUserAndProjects(user *models.User, projects []string) (*models.HeartbeatTimeRange, error) {
+	var result models.HeartbeatTimeRange
+	q := r.db.
+		Model(&models.Heartbeat{}).
+		Select("min(time) as first, max(time) as last").
+		Where("user_id = ?", user.ID)
+	if projects != nil {
+		q = q.Where("project in ?", projects)
+	}
+	if err := q.Scan(&result).Error; err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 func (r *HeartbeatRepository) Count(approximate bool) (count int64, err error) {
 	if r.config.Db.IsMySQL() && approximate {
 		err = r.db.Table("information_schema.tables").
@@ -194,6 +261,32 @@ func (r *HeartbeatRepository) GetEntitySetByUser(entityType uint8, userId string
 	return results, nil
 }
 
+// GetDistinctByUser returns a page of the distinct combinations of the given columns among the user's heartbeats, with all other fields left empty
+func (r *HeartbeatRepository) GetDistinctByUser(user *models.User, columns []string, offset, limit int) ([]*models.Heartbeat, error) {
+	var heartbeats []*models.Heartbeat
+	if err := r.db.
+		Model(&models.Heartbeat{}).
+		Distinct(columns).
+		Where(&models.Heartbeat{UserID: user.ID}).
+		Order(strings.Join(columns, ", ")).
+		Offset(offset).
+		Limit(limit).
+		Find(&heartbeats).Error; err != nil {
+		return nil, err
+	}
+	return heartbeats, nil
+}
+
+func (r *HeartbeatRepository) GetByUserAndId(user *models.User, id uint64) (*models.Heartbeat, error) {
+	var heartbeat models.Heartbeat
+	if err := r.db.
+		Where(&models.Heartbeat{UserID: user.ID, ID: id}).
+		First(&heartbeat).Error; err != nil {
+		return nil, err
+	}
+	return &heartbeat, nil
+}
+
 func (r *HeartbeatRepository) DeleteBefore(t time.Time) error {
 	if err := r.db.
 		Where("time <= ?", t.Local()).
@@ -212,6 +305,20 @@ func (r *HeartbeatRepository) DeleteByUser(user *models.User) error {
 	return nil
 }
 
+// CountByUsersBefore returns the number of heartbeats older than the given time per user, omitting users without any
+func (r *HeartbeatRepository) CountByUsersBefore(t time.Time) ([]*models.CountByUser, error) {
+	var counts []*models.CountByUser
+	if err := r.db.
+		Model(&models.Heartbeat{}).
+		Select("user_id as user, count(id) as count").
+		Where("time <= ?", t.Local()).
+		Group("user").
+		Find(&counts).Error; err != nil {
+		return counts, err
+	}
+	return counts, nil
+}
+
 func (r *HeartbeatRepository) DeleteByUserBefore(user *models.User, t time.Time) error {
 	if err := r.db.
 		Where("user_id = ?", user.ID).
@@ -222,6 +329,210 @@ func (r *HeartbeatRepository) DeleteByUserBefore(user *models.User, t time.Time)
 	return nil
 }
 
+// AnonymizeByUserBefore strips identifying fields (file paths, branches, machine names, user agents and extras sent by the client) from all of the user's heartbeats older than t, while keeping the ones relevant for statistics
+// returns the number of heartbeats, which were not anonymized before
+func (r *HeartbeatRepository) AnonymizeByUserBefore(user *models.User, t time.Time) (int64, error) {
+	result := r.db.
+		Model(&models.Heartbeat{}).
+		Where("user_id = ?", user.ID).
+		Where("time <= ?", t.Local()).
+		Where("(entity != '' OR branch != '' OR machine != '' OR user_agent != '' OR language_original != '' OR extras IS NOT NULL)").
+		// extras may contain arbitrary fields sent by the client, e.g. dependencies or paths, user agents and original languages may tell about the machine
+		Updates(map[string]interface{}{"entity": "", "branch": "", "machine": "", "user_agent": "", "language_original": "", "extras": nil})
+	return result.RowsAffected, result.Error
+}
+
+// DropChunksBefore drops all of the heartbeats hypertable's chunks, which only contain heartbeats older than t, without having to delete rows one by one (timescale only)
+func (r *HeartbeatRepository) DropChunksBefore(t time.Time) error {
+	return r.db.Exec("SELECT drop_chunks('heartbeats', older_than => ?::timestamp)", t.Local()).Error
+}
+
+func (r *HeartbeatRepository) DeleteByUserAndId(user *models.User, id uint64) error {
+	if err := r.db.
+		Where("user_id = ?", user.ID).
+		Where("id = ?", id).
+		Delete(models.Heartbeat{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteByUserWithinByFilters deletes all of a user's heartbeats within the given time range that match the given filters and returns their number
+// entityPattern is optional and may contain "*" as a wildcard
+func (r *HeartbeatRepository) DeleteByUserWithinByFilters(user *models.User, from, to time.Time, conditions []*models.ColumnCondition, entityPattern string) (int64, error) {
+	regexConditions := r.regexConditions(conditions)
+	if len(regexConditions) == 0 {
+		result := r.withinByFiltersQuery(r.db, user, from, to, conditions, entityPattern).Delete(models.Heartbeat{})
+		if err := result.Error; err != nil {
+			return 0, err
+		}
+		return result.RowsAffected, nil
+	}
+
+	ids := make([]uint64, 0)
+	if err := r.scanMatching(r.withinByFiltersQuery(r.db.Model(&models.Heartbeat{}), user, from, to, conditions, entityPattern), regexConditions, func(h *models.Heartbeat) bool {
+		ids = append(ids, h.ID)
+		return true
+	}); err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, chunk := range slice.Chunk[uint64](ids, 1000) {
+		result := r.db.Where("user_id = ?", user.ID).Where("id in ?", chunk).Delete(models.Heartbeat{})
+		if err := result.Error; err != nil {
+			return deleted, err
+		}
+		deleted += result.RowsAffected
+	}
+	return deleted, nil
+}
+
+// CountByUserWithinByFilters counts the heartbeats DeleteByUserWithinByFilters would delete
+func (r *HeartbeatRepository) CountByUserWithinByFilters(user *models.User, from, to time.Time, conditions []*models.ColumnCondition, entityPattern string) (int64, error) {
+	var count int64
+	q := r.withinByFiltersQuery(r.db.Model(&models.Heartbeat{}), user, from, to, conditions, entityPattern)
+
+	regexConditions := r.regexConditions(conditions)
+	if len(regexConditions) == 0 {
+		if err := q.Count(&count).Error; err != nil {
+			return 0, err
+		}
+		return count, nil
+	}
+
+	if err := r.scanMatching(q, regexConditions, func(h *models.Heartbeat) bool {
+		count++
+		return true
+	}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *HeartbeatRepository) withinByFiltersQuery(q *gorm.DB, user *models.User, from, to time.Time, conditions []*models.ColumnCondition, entityPattern string) *gorm.DB {
+	q = q.
+		Where("user_id = ?", user.ID).
+		Where("time >= ?", from.Local()).
+		Where("time < ?", to.Local())
+	q = r.filteredQuery(q, conditions)
+	if entityPattern != "" {
+		q = q.Where("entity like ? escape '!'", utils.WildcardToLike(entityPattern))
+	}
+	return q
+}
+
+// Update saves a heartbeat's project, language and branch, while all other fields remain unchanged
+// the hash is left untouched on purpose, see UpdateProjectByUser
+func (r *HeartbeatRepository) Update(heartbeat *models.Heartbeat) error {
+	return r.update(r.db, heartbeat)
+}
+
+func (r *HeartbeatRepository) UpdateBatch(heartbeats []*models.Heartbeat) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, hb := range heartbeats {
+			if err := r.update(tx, hb); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *HeartbeatRepository) update(tx *gorm.DB, heartbeat *models.Heartbeat) error {
+	return tx.
+		Model(heartbeat).
+		Where("user_id = ?", heartbeat.UserID).
+		Select("project", "language", "language_original", "branch", "category").
+		Updates(heartbeat).Error
+}
+
+// UpdateProjectByUser moves all of a user's heartbeats from one project to another and returns their number
+// hashes are left untouched on purpose, so that re-sent or re-imported copies of the original heartbeats are still recognized as duplicates
+func (r *HeartbeatRepository) UpdateProjectByUser(user *models.User, oldProject, newProject string) (int64, error) {
+	result := r.db.
+		Model(&models.Heartbeat{}).
+		Where("user_id = ?", user.ID).
+		Where("project = ?", oldProject).
+		Update("project", newProject)
+	if err := result.Error; err != nil {
+		return 0, err
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *HeartbeatRepository) UpdateProjectByUserWithin(user *models.User, from, to time.Time, oldProject, newProject string) (int64, error) {
+	result := r.db.
+		Model(&models.Heartbeat{}).
+		Where("user_id = ?", user.ID).
+		Where("project = ?", oldProject).
+		Where("time >= ?", from.Local()).
+		Where("time < ?", to.Local()).
+		Update("project", newProject)
+	if err := result.Error; err != nil {
+		return 0, err
+	}
+	return result.RowsAffected, nil
+}
+
+// ApplyLanguageMappingsByUser re-computes the languages of all of a user's heartbeats from the originally reported ones and the given mappings, which are applied in the given order of extensions
+// returns the number of heartbeats whose language differs from the originally reported one afterwards
+func (r *HeartbeatRepository) ApplyLanguageMappingsByUser(user *models.User, extensions []string, mappings map[string]string) (int64, error) {
+	var updated int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// restore originally reported languages
+		if err := tx.
+			Model(&models.Heartbeat{}).
+			Where("user_id = ?", user.ID).
+			Where("language_original is not null and language_original != ''").
+			Updates(map[string]interface{}{"language": gorm.Expr("language_original")}).Error; err != nil {
+			return err
+		}
+		if err := tx.
+			Model(&models.Heartbeat{}).
+			Where("user_id = ?", user.ID).
+			Where("language_original is not null and language_original != ''").
+			Update("language_original", "").Error; err != nil {
+			return err
+		}
+
+		for _, ext := range extensions {
+			matches := func() *gorm.DB {
+				return tx.
+					Model(&models.Heartbeat{}).
+					Where("user_id = ?", user.ID).
+					Where("entity like ? escape '!'", utils.WildcardToLike("*."+ext)).
+					Where("language != ?", mappings[ext])
+			}
+			// keep the original language, unless already overwritten by a less concrete mapping
+			if err := matches().
+				Where("language_original is null or language_original = ''").
+				Update("language_original", gorm.Expr("language")).Error; err != nil {
+				return err
+			}
+			if err := matches().Update("language", mappings[ext]).Error; err != nil {
+				return err
+			}
+		}
+
+		// a more concrete mapping might have mapped back to the original language
+		if err := tx.
+			Model(&models.Heartbeat{}).
+			Where("user_id = ?", user.ID).
+			Where("language_original = language").
+			Update("language_original", "").Error; err != nil {
+			return err
+		}
+
+		return tx.
+			Model(&models.Heartbeat{}).
+			Where("user_id = ?", user.ID).
+			Where("language_original is not null and language_original != ''").
+			Count(&updated).Error
+	})
+	return updated, err
+}
+
 func (r *HeartbeatRepository) GetUserProjectStats(user *models.User, from, to time.Time, limit, offset int) ([]*models.ProjectStats, error) {
 	var projectStats []*models.ProjectStats
 
@@ -254,15 +565,89 @@ func (r *HeartbeatRepository) GetUserProjectStats(user *models.User, from, to ti
 	return projectStats, nil
 }
 
-func (r *HeartbeatRepository) filteredQuery(q *gorm.DB, filterMap map[string][]string) *gorm.DB {
-	for col, vals := range filterMap {
-		q = q.Where(col+" in ?", slice.Map[string, string](vals, func(i int, val string) string {
+func (r *HeartbeatRepository) GetUserAgentStatsByUser(user *models.User) ([]*models.UserAgentStats, error) {
+	var stats []*models.UserAgentStats
+	if err := r.db.
+		Model(&models.Heartbeat{}).
+		Select("user_id, user_agent, count(*) as count, min(time) as first, max(time) as last").
+		Where(&models.Heartbeat{UserID: user.ID}).
+		Where("user_agent != ''").
+		Group("user_id, user_agent").
+		Order("last desc").
+		Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (r *HeartbeatRepository) GetUserAgentStatsSince(since time.Time) ([]*models.UserAgentStats, error) {
+	var stats []*models.UserAgentStats
+	if err := r.db.
+		Model(&models.Heartbeat{}).
+		Select("user_id, user_agent, count(*) as count, min(time) as first, max(time) as last").
+		Where("time >= ?", since).
+		Where("user_agent != ''").
+		Group("user_id, user_agent").
+		Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// filteredQuery adds all but regex conditions to the query, the latter have to be applied to the results using matchRegexConditions
+func (r *HeartbeatRepository) filteredQuery(q *gorm.DB, conditions []*models.ColumnCondition) *gorm.DB {
+	for _, c := range conditions {
+		if c.Regex {
+			continue
+		}
+
+		keys := slice.Map[string, string](c.Keys, func(i int, val string) string {
 			// query for "unknown" projects, languages, etc.
 			if val == "-" {
 				return ""
 			}
 			return val
-		}))
+		})
+		if c.Negated {
+			q = q.Where(c.Column+" not in ?", keys)
+		} else {
+			q = q.Where(c.Column+" in ?", keys)
+		}
 	}
 	return q
 }
+
+func (r *HeartbeatRepository) regexConditions(conditions []*models.ColumnCondition) []*models.ColumnCondition {
+	return slice.Filter[*models.ColumnCondition](conditions, func(i int, c *models.ColumnCondition) bool {
+		return c.Regex
+	})
+}
+
+func (r *HeartbeatRepository) matchRegexConditions(h *models.Heartbeat, conditions []*models.ColumnCondition) bool {
+	for _, c := range conditions {
+		if !c.Match(h) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanMatching streams the query's results and calls fn for every heartbeat matching all regex conditions, until fn returns false
+func (r *HeartbeatRepository) scanMatching(q *gorm.DB, regexConditions []*models.ColumnCondition, fn func(*models.Heartbeat) bool) error {
+	rows, err := q.Model(&models.Heartbeat{}).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var heartbeat models.Heartbeat
+		if err := r.db.ScanRows(rows, &heartbeat); err != nil {
+			return err
+		}
+		if r.matchRegexConditions(&heartbeat, regexConditions) && !fn(&heartbeat) {
+			break
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+	conf "github.com/muety/wakapi/config"
+	"gorm.io/gorm"
+)
+
+// monthly partitions of the heartbeats table (postgres only), named after the month they cover, e.g. heartbeats_p202401
+// heartbeats outside any monthly partition's range (e.g. imported ones from long ago) end up in a default partition
+
+const (
+	heartbeatPartitionPrefix  = "heartbeats_p"
+	heartbeatPartitionFormat  = "200601"
+	HeartbeatDefaultPartition = "heartbeats_default"
+)
+
+// GetPartitions returns the start of month of every monthly partition of the heartbeats table
+func (r *HeartbeatRepository) GetPartitions() ([]time.Time, error) {
+	var names []string
+	if err := r.db.Raw(`
+		SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		WHERE p.relname = 'heartbeats'`).
+		Scan(&names).Error; err != nil {
+		return nil, err
+	}
+
+	months := make([]time.Time, 0, len(names))
+	for _, name := range names {
+		if month, ok := parseHeartbeatPartitionName(name); ok {
+			months = append(months, month)
+		}
+	}
+	return months, nil
+}
+
+// CreatePartitions creates monthly partitions of the heartbeats table for every month between from and to (inclusive), unless existing already
+// heartbeats that went to the default partition before (e.g. ones with a timestamp in the future) are moved to the new partition, because postgres refuses to create a partition, whose range overlaps with rows in the default partition
+func (r *HeartbeatRepository) CreatePartitions(from, to time.Time) error {
+	for month := datetime.BeginOfMonth(from.Local()); !month.After(to); month = month.AddDate(0, 1, 0) {
+		if err := r.createPartition(month); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *HeartbeatRepository) createPartition(month time.Time) error {
+	name := heartbeatPartitionName(month)
+	lower, upper := month.Format(conf.SimpleDateTimeFormat), month.AddDate(0, 1, 0).Format(conf.SimpleDateTimeFormat)
+
+	var exists bool
+	if err := r.db.Raw("SELECT to_regclass(?) IS NOT NULL", name).Scan(&exists).Error; err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// attaching requires an exclusive lock on the default partition anyway, taking it up front keeps new heartbeats from slipping in meanwhile
+		if err := tx.Exec(fmt.Sprintf("LOCK TABLE %s IN ACCESS EXCLUSIVE MODE", HeartbeatDefaultPartition)).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(fmt.Sprintf("CREATE TABLE %s (LIKE heartbeats INCLUDING DEFAULTS INCLUDING STORAGE)", name)).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(fmt.Sprintf(
+			"WITH moved AS (DELETE FROM %s WHERE time >= '%s' AND time < '%s' RETURNING *) INSERT INTO %s SELECT * FROM moved",
+			HeartbeatDefaultPartition, lower, upper, name,
+		)).Error; err != nil {
+			return err
+		}
+		// indexes and constraints of the parent table are created on the partition when attaching it
+		return tx.Exec(fmt.Sprintf("ALTER TABLE heartbeats ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')", name, lower, upper)).Error
+	})
+}
+
+// DropPartitionsBefore drops all monthly partitions of the heartbeats table, which only contain heartbeats older than t, which is way cheaper than deleting rows one by one
+func (r *HeartbeatRepository) DropPartitionsBefore(t time.Time) error {
+	months, err := r.GetPartitions()
+	if err != nil {
+		return err
+	}
+	for _, month := range months {
+		if month.AddDate(0, 1, 0).After(t) {
+			continue
+		}
+		if err := r.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", heartbeatPartitionName(month))).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func heartbeatPartitionName(month time.Time) string {
+	return heartbeatPartitionPrefix + month.Format(heartbeatPartitionFormat)
+}
+
+func parseHeartbeatPartitionName(name string) (time.Time, bool) {
+	if len(name) != len(heartbeatPartitionPrefix)+len(heartbeatPartitionFormat) || name[:len(heartbeatPartitionPrefix)] != heartbeatPartitionPrefix {
+		return time.Time{}, false
+	}
+	month, err := time.ParseInLocation(heartbeatPartitionFormat, name[len(heartbeatPartitionPrefix):], time.Local)
+	return month, err == nil
+}
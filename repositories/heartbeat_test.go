@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestHeartbeatRepository_RegexFilters(t *testing.T) {
+	config.Set(config.Empty())
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	assert.Nil(t, err)
+	assert.Nil(t, db.AutoMigrate(&models.Heartbeat{}))
+
+	user := &models.User{ID: "user1"}
+	now := time.Now()
+	sut := NewHeartbeatRepository(db)
+
+	assert.Nil(t, sut.InsertBatch([]*models.Heartbeat{
+		{UserID: user.ID, Project: "wakapi", Language: "Go", Branch: "feature/regex", Time: models.CustomTime(now.Add(-3 * time.Minute)), Hash: "1"},
+		{UserID: user.ID, Project: "wakapi", Language: "Go", Branch: "master", Time: models.CustomTime(now.Add(-2 * time.Minute)), Hash: "2"},
+		{UserID: user.ID, Project: "anchr", Language: "TypeScript", Branch: "feature/ui", Time: models.CustomTime(now.Add(-1 * time.Minute)), Hash: "3"},
+	}))
+
+	branchRegex, _ := models.NewRegexCondition(models.SummaryBranch, `^feature/\w+$`, false)
+	projectRegex, _ := models.NewRegexCondition(models.SummaryProject, `^an`, true)
+	conditions := (&models.Filters{}).WithCondition(branchRegex).WithCondition(projectRegex).ColumnConditions()
+	from, to := now.Add(-1*time.Hour), now
+
+	heartbeats, err := sut.GetAllWithinByFilters(from, to, user, conditions)
+	assert.Nil(t, err)
+	assert.Len(t, heartbeats, 1)
+	assert.Equal(t, "feature/regex", heartbeats[0].Branch)
+
+	latest, err := sut.GetLatestByFilters(user, (&models.Filters{}).WithCondition(branchRegex).ColumnConditions())
+	assert.Nil(t, err)
+	assert.Equal(t, "anchr", latest.Project)
+
+	_, err = sut.GetLatestByFilters(user, models.NewFiltersWith(models.SummaryLanguage, "Python").WithCondition(branchRegex).ColumnConditions())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	count, err := sut.CountByUserWithinByFilters(user, from, to, conditions, "")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+
+	deleted, err := sut.DeleteByUserWithinByFilters(user, from, to, conditions, "")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	remaining, err := sut.GetAllWithinByFilters(from, to, user, []*models.ColumnCondition{})
+	assert.Nil(t, err)
+	assert.Len(t, remaining, 2)
+}
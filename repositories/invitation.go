@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type InvitationRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) *InvitationRepository {
+	return &InvitationRepository{config: config.Get(), db: db}
+}
+
+func (r *InvitationRepository) GetAll() ([]*models.Invitation, error) {
+	var invitations []*models.Invitation
+	if err := r.db.Order("id asc").Find(&invitations).Error; err != nil {
+		return invitations, err
+	}
+	return invitations, nil
+}
+
+func (r *InvitationRepository) GetById(id uint) (*models.Invitation, error) {
+	invitation := &models.Invitation{}
+	if err := r.db.Where(&models.Invitation{ID: id}).First(invitation).Error; err != nil {
+		return invitation, err
+	}
+	return invitation, nil
+}
+
+func (r *InvitationRepository) GetByCode(code string) (*models.Invitation, error) {
+	invitation := &models.Invitation{}
+	if err := r.db.Where(&models.Invitation{Code: code}).First(invitation).Error; err != nil {
+		return invitation, err
+	}
+	return invitation, nil
+}
+
+func (r *InvitationRepository) Insert(invitation *models.Invitation) (*models.Invitation, error) {
+	if !invitation.IsValid() || invitation.Code == "" {
+		return nil, errors.New("invalid invitation")
+	}
+	result := r.db.Create(invitation)
+	if err := result.Error; err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// IncrementUses atomically consumes one use of the invitation, returns false if it was used up already
+func (r *InvitationRepository) IncrementUses(id uint) (bool, error) {
+	result := r.db.
+		Model(&models.Invitation{}).
+		Where("id = ?", id).
+		Where("max_uses = 0 OR uses < max_uses").
+		Update("uses", gorm.Expr("uses + 1"))
+	if err := result.Error; err != nil {
+		return false, err
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *InvitationRepository) DecrementUses(id uint) error {
+	return r.db.
+		Model(&models.Invitation{}).
+		Where("id = ? AND uses > 0", id).
+		Update("uses", gorm.Expr("uses - 1")).Error
+}
+
+func (r *InvitationRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.Invitation{}).Error
+}
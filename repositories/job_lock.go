@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobLockRepository struct {
+	db *gorm.DB
+}
+
+func NewJobLockRepository(db *gorm.DB) *JobLockRepository {
+	return &JobLockRepository{db: db}
+}
+
+// TryAcquire takes over the named lock if it is expired or already held by the given holder, or creates it if it doesn't exist yet
+// both steps are single, conditional statements, so that out of multiple instances trying concurrently, only one will succeed
+func (r *JobLockRepository) TryAcquire(name, holder string, until time.Time) (bool, error) {
+	now := time.Now()
+
+	result := r.db.
+		Model(&models.JobLock{}).
+		Where("name = ? AND (expires_at <= ? OR holder = ?)", name, now.Local(), holder).
+		Updates(map[string]interface{}{"holder": holder, "expires_at": until.Local()})
+	if err := result.Error; err != nil {
+		return false, err
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	result = r.db.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.JobLock{Name: name, Holder: holder, ExpiresAt: models.CustomTime(until)})
+	if err := result.Error; err != nil {
+		return false, err
+	}
+	return result.RowsAffected > 0, nil
+}
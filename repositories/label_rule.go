@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type LabelRuleRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewLabelRuleRepository(db *gorm.DB) *LabelRuleRepository {
+	return &LabelRuleRepository{config: config.Get(), db: db}
+}
+
+func (r *LabelRuleRepository) GetById(id uint) (*models.LabelRule, error) {
+	rule := &models.LabelRule{}
+	if err := r.db.Where(&models.LabelRule{ID: id}).First(rule).Error; err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+func (r *LabelRuleRepository) GetByUser(userId string) ([]*models.LabelRule, error) {
+	var rules []*models.LabelRule
+	if userId == "" {
+		return rules, nil
+	}
+	if err := r.db.
+		Where(&models.LabelRule{UserID: userId}).
+		Find(&rules).Error; err != nil {
+		return rules, err
+	}
+	return rules, nil
+}
+
+func (r *LabelRuleRepository) Insert(rule *models.LabelRule) (*models.LabelRule, error) {
+	if !rule.IsValid() {
+		return nil, errors.New("invalid label rule")
+	}
+	result := r.db.Create(rule)
+	if err := result.Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *LabelRuleRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.LabelRule{}).Error
+}
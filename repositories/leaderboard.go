@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/utils"
 	"gorm.io/gorm"
@@ -26,7 +27,7 @@ func (r *LeaderboardRepository) InsertBatch(items []*models.LeaderboardItem) err
 
 func (r *LeaderboardRepository) CountAllByUser(userId string) (int64, error) {
 	var count int64
-	err := r.db.
+	err := config.ReadReplica(r.db).
 		Table("leaderboard_items").
 		Where("user_id = ?", userId).
 		Count(&count).Error
@@ -35,7 +36,7 @@ func (r *LeaderboardRepository) CountAllByUser(userId string) (int64, error) {
 
 func (r *LeaderboardRepository) CountUsers() (int64, error) {
 	var count int64
-	err := r.db.
+	err := config.ReadReplica(r.db).
 		Table("leaderboard_items").
 		Distinct("user_id").
 		Count(&count).Error
@@ -52,7 +53,7 @@ func (r *LeaderboardRepository) GetAllAggregatedByInterval(key *models.IntervalK
 		Where("\"interval\" in ?", *key)
 	subq = utils.WhereNullable(subq, "\"by\"", by)
 
-	q := r.db.Table("(?) as ranked", subq)
+	q := config.ReadReplica(r.db).Table("(?) as ranked", subq)
 	q = r.withPaging(q, limit, skip)
 
 	if err := q.Find(&items).Error; err != nil {
@@ -69,7 +70,7 @@ func (r *LeaderboardRepository) GetAggregatedByUserAndInterval(userId string, ke
 		Where("\"interval\" in ?", *key)
 	subq = utils.WhereNullable(subq, "\"by\"", by)
 
-	q := r.db.Table("(?) as ranked", subq).Where("user_id = ?", userId)
+	q := config.ReadReplica(r.db).Table("(?) as ranked", subq).Where("user_id = ?", userId)
 	q = r.withPaging(q, limit, skip)
 
 	if err := q.Find(&items).Error; err != nil {
@@ -97,6 +98,19 @@ func (r *LeaderboardRepository) DeleteByUserAndInterval(userId string, key *mode
 	return nil
 }
 
+func (r *LeaderboardRepository) DeleteByUsersAndInterval(userIds []string, key *models.IntervalKey) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+	if err := r.db.
+		Where("user_id in ?", userIds).
+		Where("\"interval\" in ?", *key).
+		Delete(models.LeaderboardItem{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
 func (r *LeaderboardRepository) withPaging(q *gorm.DB, limit, skip int) *gorm.DB {
 	if limit > 0 {
 		q = q.Where("\"rank\" <= ?", skip+limit)
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MachineRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewMachineRepository(db *gorm.DB) *MachineRepository {
+	return &MachineRepository{config: config.Get(), db: db}
+}
+
+func (r *MachineRepository) GetByUser(userId string) ([]*models.Machine, error) {
+	if userId == "" {
+		return []*models.Machine{}, nil
+	}
+	var machines []*models.Machine
+	if err := r.db.
+		Where(&models.Machine{UserID: userId}).
+		Order("last_seen_at desc").
+		Find(&machines).Error; err != nil {
+		return machines, err
+	}
+	return machines, nil
+}
+
+// Upsert stores the given machine, replacing what was previously seen of the machine with the same name, except for when it was first seen
+func (r *MachineRepository) Upsert(machine *models.Machine) (*models.Machine, error) {
+	if !machine.IsValid() {
+		return nil, errors.New("invalid machine")
+	}
+	if err := r.db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"operating_system", "last_ip", "country", "city", "last_seen_at"}),
+		}).
+		Create(machine).Error; err != nil {
+		return nil, err
+	}
+	return machine, nil
+}
+
+// UpdateLocation sets a machine's location, unless its ip has changed in the meantime
+func (r *MachineRepository) UpdateLocation(machine *models.Machine) error {
+	return r.db.
+		Model(&models.Machine{}).
+		Where(&models.Machine{UserID: machine.UserID, Name: machine.Name, LastIp: machine.LastIp}).
+		Updates(map[string]interface{}{"country": machine.Country, "city": machine.City}).Error
+}
+
+// DeleteByUserBefore removes all of the user's machines, which were last seen before t
+func (r *MachineRepository) DeleteByUserBefore(userId string, t time.Time) error {
+	return r.db.
+		Where("user_id = ?", userId).
+		Where("last_seen_at < ?", t.Local()).
+		Delete(models.Machine{}).Error
+}
+
+// AnonymizeByUserBefore clears the last ip and location of all of the user's machines, which were last seen before t, while keeping their names and operating systems
+// returns the number of machines, which were not anonymized before
+func (r *MachineRepository) AnonymizeByUserBefore(userId string, t time.Time) (int64, error) {
+	result := r.db.
+		Model(&models.Machine{}).
+		Where("user_id = ?", userId).
+		Where("last_seen_at < ?", t.Local()).
+		Where("last_ip != '' OR country != '' OR city != ''").
+		Updates(map[string]interface{}{"last_ip": "", "country": "", "city": ""})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type MappingConfigRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewMappingConfigRepository(db *gorm.DB) *MappingConfigRepository {
+	return &MappingConfigRepository{config: config.Get(), db: db}
+}
+
+// ImportByUser inserts the given aliases, project labels and label rules for a user all at once or not at all
+// if replace is set, all of the user's existing aliases, labels and rules are deleted beforehand, as part of the same transaction
+func (r *MappingConfigRepository) ImportByUser(userId string, aliases []*models.Alias, labels []*models.ProjectLabel, rules []*models.LabelRule, replace bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if replace {
+			for _, m := range []interface{}{&models.Alias{}, &models.ProjectLabel{}, &models.LabelRule{}} {
+				if err := tx.Where("user_id = ?", userId).Delete(m).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(aliases) > 0 {
+			if err := tx.Create(&aliases).Error; err != nil {
+				return err
+			}
+		}
+		if len(labels) > 0 {
+			if err := tx.Create(&labels).Error; err != nil {
+				return err
+			}
+		}
+		if len(rules) > 0 {
+			if err := tx.Create(&rules).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
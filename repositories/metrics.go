@@ -28,14 +28,15 @@ func NewMetricsRepository(db *gorm.DB) *MetricsRepository {
 
 func (srv *MetricsRepository) GetDatabaseSize() (size int64, err error) {
 	cfg := srv.config.Db
+	db := config.ReadReplica(srv.db)
 
-	query := srv.db.Raw("SELECT 0")
+	query := db.Raw("SELECT 0")
 	if cfg.IsMySQL() {
-		query = srv.db.Raw(sizeTplMysql, cfg.Name)
+		query = db.Raw(sizeTplMysql, cfg.Name)
 	} else if cfg.IsPostgres() {
-		query = srv.db.Raw(sizeTplPostgres, cfg.Name)
+		query = db.Raw(sizeTplPostgres, cfg.Name)
 	} else if cfg.IsSQLite() {
-		query = srv.db.Raw(sizeTplSqlite)
+		query = db.Raw(sizeTplSqlite)
 	}
 
 	err = query.Scan(&size).Error
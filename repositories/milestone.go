@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type MilestoneRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewMilestoneRepository(db *gorm.DB) *MilestoneRepository {
+	return &MilestoneRepository{config: config.Get(), db: db}
+}
+
+func (r *MilestoneRepository) GetById(id uint) (*models.Milestone, error) {
+	milestone := &models.Milestone{}
+	if err := r.db.Where(&models.Milestone{ID: id}).First(milestone).Error; err != nil {
+		return milestone, err
+	}
+	return milestone, nil
+}
+
+// GetByUserWithin returns all of a user's milestones between the given days (inclusive, formatted as yyyy-mm-dd)
+func (r *MilestoneRepository) GetByUserWithin(userId string, fromDay, toDay string) ([]*models.Milestone, error) {
+	var milestones []*models.Milestone
+	if err := r.db.
+		Where(&models.Milestone{UserID: userId}).
+		Where("day >= ?", fromDay).
+		Where("day <= ?", toDay).
+		Order("day asc, id asc").
+		Find(&milestones).Error; err != nil {
+		return nil, err
+	}
+	return milestones, nil
+}
+
+func (r *MilestoneRepository) Insert(milestone *models.Milestone) (*models.Milestone, error) {
+	if !milestone.IsValid() {
+		return nil, errors.New("invalid milestone")
+	}
+	result := r.db.Create(milestone)
+	if err := result.Error; err != nil {
+		return nil, err
+	}
+	return milestone, nil
+}
+
+func (r *MilestoneRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.Milestone{}).Error
+}
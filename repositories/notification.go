@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type NotificationRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{config: config.Get(), db: db}
+}
+
+// GetAllPending returns all held back notifications, including their recipients, oldest first
+func (r *NotificationRepository) GetAllPending() ([]*models.Notification, error) {
+	var notifications []*models.Notification
+	if err := r.db.
+		Preload("User").
+		Order("created_at asc, id asc").
+		Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (r *NotificationRepository) Insert(notification *models.Notification) (*models.Notification, error) {
+	if err := r.db.Create(notification).Error; err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+func (r *NotificationRepository) DeleteByIds(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.
+		Where("id in ?", ids).
+		Delete(models.Notification{}).Error
+}
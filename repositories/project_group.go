@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type ProjectGroupRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewProjectGroupRepository(db *gorm.DB) *ProjectGroupRepository {
+	return &ProjectGroupRepository{config: config.Get(), db: db}
+}
+
+// GetByUser returns all of a user's project groups along with their member projects
+func (r *ProjectGroupRepository) GetByUser(userId string) ([]*models.ProjectGroup, error) {
+	if userId == "" {
+		return []*models.ProjectGroup{}, nil
+	}
+
+	var groups []*models.ProjectGroup
+	if err := r.db.
+		Where(&models.ProjectGroup{UserID: userId}).
+		Order("name asc").
+		Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	var members []*models.ProjectGroupMember
+	if err := r.db.
+		Where(&models.ProjectGroupMember{UserID: userId}).
+		Order("project_key asc").
+		Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	groupsById := make(map[uint]*models.ProjectGroup, len(groups))
+	for _, g := range groups {
+		g.Projects = []string{}
+		groupsById[g.ID] = g
+	}
+	for _, m := range members {
+		if g, ok := groupsById[m.ProjectGroupID]; ok {
+			g.Projects = append(g.Projects, m.ProjectKey)
+		}
+	}
+	return groups, nil
+}
+
+func (r *ProjectGroupRepository) Insert(group *models.ProjectGroup) (*models.ProjectGroup, error) {
+	if !group.IsValid() {
+		return nil, errors.New("invalid project group")
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Projects").Create(group).Error; err != nil {
+			return err
+		}
+		return r.assignProjects(tx, group)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// Update renames the group and replaces its projects, projects previously assigned to another group are moved to this one
+func (r *ProjectGroupRepository) Update(group *models.ProjectGroup) (*models.ProjectGroup, error) {
+	if !group.IsValid() {
+		return nil, errors.New("invalid project group")
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(group).Update("name", group.Name).Error; err != nil {
+			return err
+		}
+		return r.assignProjects(tx, group)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (r *ProjectGroupRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("project_group_id = ?", id).Delete(models.ProjectGroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(models.ProjectGroup{}).Error
+	})
+}
+
+func (r *ProjectGroupRepository) assignProjects(tx *gorm.DB, group *models.ProjectGroup) error {
+	if err := tx.Where("project_group_id = ?", group.ID).Delete(models.ProjectGroupMember{}).Error; err != nil {
+		return err
+	}
+
+	members := group.Members()
+	if len(members) == 0 {
+		return nil
+	}
+	if err := tx.
+		Where("user_id = ?", group.UserID).
+		Where("project_key in ?", group.Projects).
+		Delete(models.ProjectGroupMember{}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&members).Error
+}
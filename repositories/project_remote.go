@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProjectRemoteRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewProjectRemoteRepository(db *gorm.DB) *ProjectRemoteRepository {
+	return &ProjectRemoteRepository{config: config.Get(), db: db}
+}
+
+func (r *ProjectRemoteRepository) GetByUser(userId string) ([]*models.ProjectRemote, error) {
+	if userId == "" {
+		return []*models.ProjectRemote{}, nil
+	}
+	var remotes []*models.ProjectRemote
+	if err := r.db.
+		Where(&models.ProjectRemote{UserID: userId}).
+		Find(&remotes).Error; err != nil {
+		return remotes, err
+	}
+	return remotes, nil
+}
+
+// Upsert stores the given remote, replacing the one previously reported for the same project, if any
+func (r *ProjectRemoteRepository) Upsert(remote *models.ProjectRemote) (*models.ProjectRemote, error) {
+	if !remote.IsValid() {
+		return nil, errors.New("invalid project remote")
+	}
+	if err := r.db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "project"}},
+			DoUpdates: clause.AssignmentColumns([]string{"remote", "namespace", "updated_at"}),
+		}).
+		Create(remote).Error; err != nil {
+		return nil, err
+	}
+	return remote, nil
+}
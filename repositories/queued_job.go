@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type QueuedJobRepository struct {
+	db *gorm.DB
+}
+
+func NewQueuedJobRepository(db *gorm.DB) *QueuedJobRepository {
+	return &QueuedJobRepository{db: db}
+}
+
+// GetAll returns all pending jobs, oldest first
+func (r *QueuedJobRepository) GetAll() ([]*models.QueuedJob, error) {
+	var jobs []*models.QueuedJob
+	if err := r.db.Order("id asc").Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *QueuedJobRepository) Insert(job *models.QueuedJob) (*models.QueuedJob, error) {
+	if err := r.db.Create(job).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (r *QueuedJobRepository) Delete(id uint64) error {
+	return r.db.Delete(&models.QueuedJob{}, id).Error
+}
@@ -5,6 +5,31 @@ import (
 	"time"
 )
 
+type IAbuseReportRepository interface {
+	GetById(uint) (*models.AbuseReport, error)
+	GetByStatus(string) ([]*models.AbuseReport, error)
+	CountByReporterAfter(string, time.Time) (int64, error)
+	CountOpenByReporterAndTarget(*models.AbuseReport) (int64, error)
+	Insert(*models.AbuseReport) (*models.AbuseReport, error)
+	UpdateStatus(*models.AbuseReport, string) (*models.AbuseReport, error)
+}
+
+type IDataCorrectionRepository interface {
+	GetById(uint) (*models.DataCorrection, error)
+	GetByUser(string) ([]*models.DataCorrection, error)
+	GetByStatus(string) ([]*models.DataCorrection, error)
+	Insert(*models.DataCorrection) (*models.DataCorrection, error)
+	Update(*models.DataCorrection) (*models.DataCorrection, error)
+}
+
+type IAccessTokenRepository interface {
+	GetByHash(string) (*models.AccessToken, error)
+	GetByUser(string) ([]*models.AccessToken, error)
+	Insert(*models.AccessToken) (*models.AccessToken, error)
+	UpdateLastUsed(*models.AccessToken) error
+	DeleteByUserAndId(string, uint) error
+}
+
 type IAliasRepository interface {
 	Insert(*models.Alias) (*models.Alias, error)
 	Delete(uint) error
@@ -18,22 +43,43 @@ type IAliasRepository interface {
 
 type IHeartbeatRepository interface {
 	InsertBatch([]*models.Heartbeat) error
+	GetExistingHashes([]string) ([]string, error)
 	GetAll() ([]*models.Heartbeat, error)
 	GetAllWithin(time.Time, time.Time, *models.User) ([]*models.Heartbeat, error)
-	GetAllWithinByFilters(time.Time, time.Time, *models.User, map[string][]string) ([]*models.Heartbeat, error)
-	GetLatestByFilters(*models.User, map[string][]string) (*models.Heartbeat, error)
+	GetPageByUser(*models.User, uint64, int) ([]*models.Heartbeat, error)
+	GetAllWithinByFilters(time.Time, time.Time, *models.User, []*models.ColumnCondition) ([]*models.Heartbeat, error)
+	GetLatestByFilters(*models.User, []*models.ColumnCondition) (*models.Heartbeat, error)
 	GetFirstByUsers() ([]*models.TimeByUser, error)
 	GetLastByUsers() ([]*models.TimeByUser, error)
 	GetLatestByUser(*models.User) (*models.Heartbeat, error)
 	GetLatestByOriginAndUser(string, *models.User) (*models.Heartbeat, error)
+	GetByUserAndId(*models.User, uint64) (*models.Heartbeat, error)
+	GetTimeRangeByUserAndProjects(*models.User, []string) (*models.HeartbeatTimeRange, error)
 	Count(bool) (int64, error)
 	CountByUser(*models.User) (int64, error)
 	CountByUsers([]*models.User) ([]*models.CountByUser, error)
+	CountByUsersBefore(time.Time) ([]*models.CountByUser, error)
 	GetEntitySetByUser(uint8, string) ([]string, error)
 	DeleteBefore(time.Time) error
 	DeleteByUser(*models.User) error
 	DeleteByUserBefore(*models.User, time.Time) error
+	AnonymizeByUserBefore(*models.User, time.Time) (int64, error)
+	DropChunksBefore(time.Time) error
+	GetPartitions() ([]time.Time, error)
+	CreatePartitions(time.Time, time.Time) error
+	DropPartitionsBefore(time.Time) error
+	DeleteByUserAndId(*models.User, uint64) error
+	DeleteByUserWithinByFilters(*models.User, time.Time, time.Time, []*models.ColumnCondition, string) (int64, error)
+	CountByUserWithinByFilters(*models.User, time.Time, time.Time, []*models.ColumnCondition, string) (int64, error)
+	Update(*models.Heartbeat) error
+	UpdateBatch([]*models.Heartbeat) error
+	UpdateProjectByUser(*models.User, string, string) (int64, error)
+	UpdateProjectByUserWithin(*models.User, time.Time, time.Time, string, string) (int64, error)
+	ApplyLanguageMappingsByUser(*models.User, []string, map[string]string) (int64, error)
+	GetDistinctByUser(*models.User, []string, int, int) ([]*models.Heartbeat, error)
 	GetUserProjectStats(*models.User, time.Time, time.Time, int, int) ([]*models.ProjectStats, error)
+	GetUserAgentStatsByUser(*models.User) ([]*models.UserAgentStats, error)
+	GetUserAgentStatsSince(time.Time) ([]*models.UserAgentStats, error)
 }
 
 type IDiagnosticsRepository interface {
@@ -56,6 +102,126 @@ type ILanguageMappingRepository interface {
 	Delete(uint) error
 }
 
+type IAnnotationRepository interface {
+	GetById(uint) (*models.Annotation, error)
+	GetByUserWithin(string, string, string) ([]*models.Annotation, error)
+	Insert(*models.Annotation) (*models.Annotation, error)
+	Delete(uint) error
+}
+
+type IMilestoneRepository interface {
+	GetById(uint) (*models.Milestone, error)
+	GetByUserWithin(string, string, string) ([]*models.Milestone, error)
+	Insert(*models.Milestone) (*models.Milestone, error)
+	Delete(uint) error
+}
+
+type ISavedFilterRepository interface {
+	GetByUser(string) ([]*models.SavedFilter, error)
+	GetByUserAndName(string, string) (*models.SavedFilter, error)
+	Insert(*models.SavedFilter) (*models.SavedFilter, error)
+	Delete(uint) error
+}
+
+type IAchievementRepository interface {
+	GetByUser(string) ([]*models.Achievement, error)
+	InsertBatch([]*models.Achievement) error
+}
+
+type IProjectRemoteRepository interface {
+	GetByUser(string) ([]*models.ProjectRemote, error)
+	Upsert(*models.ProjectRemote) (*models.ProjectRemote, error)
+}
+
+type IMachineRepository interface {
+	GetByUser(string) ([]*models.Machine, error)
+	Upsert(*models.Machine) (*models.Machine, error)
+	UpdateLocation(*models.Machine) error
+	DeleteByUserBefore(string, time.Time) error
+	AnonymizeByUserBefore(string, time.Time) (int64, error)
+}
+
+type IClientRepository interface {
+	GetByUser(string) ([]*models.Client, error)
+	Insert(*models.Client) (*models.Client, error)
+	Update(*models.Client) (*models.Client, error)
+	Delete(uint) error
+}
+
+type IProjectGroupRepository interface {
+	GetByUser(string) ([]*models.ProjectGroup, error)
+	Insert(*models.ProjectGroup) (*models.ProjectGroup, error)
+	Update(*models.ProjectGroup) (*models.ProjectGroup, error)
+	Delete(uint) error
+}
+
+type IBillableProjectRepository interface {
+	GetByUser(string) ([]*models.BillableProject, error)
+	Upsert(*models.BillableProject) (*models.BillableProject, error)
+	DeleteByUserAndProject(string, string) error
+}
+
+type INotificationRepository interface {
+	GetAllPending() ([]*models.Notification, error)
+	Insert(*models.Notification) (*models.Notification, error)
+	DeleteByIds([]uint) error
+}
+
+type ISandboxRepository interface {
+	GetByUser(string, int) ([]*models.SandboxHeartbeat, error)
+	CountByUser(string) (int64, error)
+	InsertBatch([]*models.SandboxHeartbeat) error
+	DeleteByUser(string) error
+	DeleteBefore(time.Time) error
+}
+
+type IJobLockRepository interface {
+	TryAcquire(string, string, time.Time) (bool, error)
+}
+
+type IQueuedJobRepository interface {
+	GetAll() ([]*models.QueuedJob, error)
+	Insert(*models.QueuedJob) (*models.QueuedJob, error)
+	Delete(uint64) error
+}
+
+type IExportTargetRepository interface {
+	GetAll() ([]*models.ExportTarget, error)
+	GetByUser(string) (*models.ExportTarget, error)
+	Upsert(*models.ExportTarget) (*models.ExportTarget, error)
+	UpdateStatus(*models.ExportTarget) error
+	DeleteByUser(string) error
+}
+
+type ILabelRuleRepository interface {
+	GetById(uint) (*models.LabelRule, error)
+	GetByUser(string) ([]*models.LabelRule, error)
+	Insert(*models.LabelRule) (*models.LabelRule, error)
+	Delete(uint) error
+}
+
+type IBlockRuleRepository interface {
+	GetAll() ([]*models.BlockRule, error)
+	GetById(uint) (*models.BlockRule, error)
+	Insert(*models.BlockRule) (*models.BlockRule, error)
+	IncrementDropped(uint, int64, time.Time) error
+	Delete(uint) error
+}
+
+type IInvitationRepository interface {
+	GetAll() ([]*models.Invitation, error)
+	GetById(uint) (*models.Invitation, error)
+	GetByCode(string) (*models.Invitation, error)
+	Insert(*models.Invitation) (*models.Invitation, error)
+	IncrementUses(uint) (bool, error)
+	DecrementUses(uint) error
+	Delete(uint) error
+}
+
+type IMappingConfigRepository interface {
+	ImportByUser(string, []*models.Alias, []*models.ProjectLabel, []*models.LabelRule, bool) error
+}
+
 type IProjectLabelRepository interface {
 	GetAll() ([]*models.ProjectLabel, error)
 	GetById(uint) (*models.ProjectLabel, error)
@@ -69,8 +235,15 @@ type ISummaryRepository interface {
 	GetAll() ([]*models.Summary, error)
 	GetByUserWithin(*models.User, time.Time, time.Time) ([]*models.Summary, error)
 	GetLastByUser() ([]*models.TimeByUser, error)
+	GetTotalsByTypeWithin(uint8, time.Time, time.Time, time.Duration) ([]*models.TotalByKey, error)
+	CountByUsersBefore(time.Time) ([]*models.CountByUser, error)
 	DeleteByUser(string) error
 	DeleteByUserBefore(string, time.Time) error
+	DeleteByUserWithin(string, time.Time, time.Time) error
+	DeleteItemsByUserBefore(string, time.Time, []uint8) error
+	DeletePartialByUser(string) error
+	DeletePartialBefore(time.Time) error
+	ReplacePartial(*models.Summary) error
 }
 
 type IUserRepository interface {
@@ -82,10 +255,14 @@ type IUserRepository interface {
 	GetAllByLeaderboard(bool) ([]*models.User, error)
 	GetByLoggedInAfter(time.Time) ([]*models.User, error)
 	GetByLastActiveAfter(time.Time) ([]*models.User, error)
+	GetByDeleteAtBefore(time.Time) ([]*models.User, error)
 	Count() (int64, error)
+	CountByLastActiveAfter(time.Time) (int64, error)
+	GetInactiveSince(time.Time) ([]*models.User, error)
 	InsertOrGet(*models.User) (*models.User, bool, error)
 	Update(*models.User) (*models.User, error)
 	UpdateField(*models.User, string, interface{}) (*models.User, error)
+	ChangeId(*models.User, string) (*models.User, error)
 	Delete(*models.User) error
 }
 
@@ -95,6 +272,7 @@ type ILeaderboardRepository interface {
 	CountUsers() (int64, error)
 	DeleteByUser(string) error
 	DeleteByUserAndInterval(string, *models.IntervalKey) error
+	DeleteByUsersAndInterval([]string, *models.IntervalKey) error
 	GetAllAggregatedByInterval(*models.IntervalKey, *uint8, int, int) ([]*models.LeaderboardItemRanked, error)
 	GetAggregatedByUserAndInterval(string, *models.IntervalKey, *uint8, int, int) ([]*models.LeaderboardItemRanked, error)
 }
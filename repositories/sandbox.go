@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+type SandboxRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewSandboxRepository(db *gorm.DB) *SandboxRepository {
+	return &SandboxRepository{config: config.Get(), db: db}
+}
+
+// GetByUser returns the user's most recent sandbox heartbeats, newest first
+func (r *SandboxRepository) GetByUser(userId string, limit int) ([]*models.SandboxHeartbeat, error) {
+	var heartbeats []*models.SandboxHeartbeat
+	if err := r.db.
+		Where(&models.SandboxHeartbeat{UserID: userId}).
+		Order("id desc").
+		Limit(limit).
+		Find(&heartbeats).Error; err != nil {
+		return nil, err
+	}
+	return heartbeats, nil
+}
+
+func (r *SandboxRepository) CountByUser(userId string) (int64, error) {
+	var count int64
+	if err := r.db.
+		Model(&models.SandboxHeartbeat{}).
+		Where(&models.SandboxHeartbeat{UserID: userId}).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *SandboxRepository) InsertBatch(heartbeats []*models.SandboxHeartbeat) error {
+	if len(heartbeats) == 0 {
+		return nil
+	}
+	return r.db.Create(&heartbeats).Error
+}
+
+func (r *SandboxRepository) DeleteByUser(userId string) error {
+	return r.db.
+		Where("user_id = ?", userId).
+		Delete(models.SandboxHeartbeat{}).Error
+}
+
+func (r *SandboxRepository) DeleteBefore(t time.Time) error {
+	return r.db.
+		Where("created_at <= ?", t.Local()).
+		Delete(models.SandboxHeartbeat{}).Error
+}
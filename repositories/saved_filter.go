@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type SavedFilterRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewSavedFilterRepository(db *gorm.DB) *SavedFilterRepository {
+	return &SavedFilterRepository{config: config.Get(), db: db}
+}
+
+func (r *SavedFilterRepository) GetByUser(userId string) ([]*models.SavedFilter, error) {
+	var filters []*models.SavedFilter
+	if err := r.db.
+		Where(&models.SavedFilter{UserID: userId}).
+		Order("name asc").
+		Find(&filters).Error; err != nil {
+		return nil, err
+	}
+	return filters, nil
+}
+
+func (r *SavedFilterRepository) GetByUserAndName(userId, name string) (*models.SavedFilter, error) {
+	filter := &models.SavedFilter{}
+	if err := r.db.
+		Where(&models.SavedFilter{UserID: userId, Name: name}).
+		First(filter).Error; err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func (r *SavedFilterRepository) Insert(filter *models.SavedFilter) (*models.SavedFilter, error) {
+	if !filter.IsValid() {
+		return nil, errors.New("invalid saved filter")
+	}
+	result := r.db.Create(filter)
+	if err := result.Error; err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func (r *SavedFilterRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.SavedFilter{}).Error
+}
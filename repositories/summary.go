@@ -1,7 +1,9 @@
 package repositories
 
 import (
+	"fmt"
 	"github.com/duke-git/lancet/v2/slice"
+	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -25,7 +27,7 @@ func (r *SummaryRepository) GetAll() ([]*models.Summary, error) {
 		return nil, err
 	}
 
-	if err := r.populateItems(summaries, []clause.Interface{}); err != nil {
+	if err := r.populateItems(r.db, summaries, []clause.Interface{}); err != nil {
 		return nil, err
 	}
 
@@ -48,7 +50,8 @@ func (r *SummaryRepository) GetByUserWithin(user *models.User, from, to time.Tim
 		clause.Where{Exprs: r.db.Statement.BuildCondition("to_time <= ?", to.Local())},
 	}
 
-	q := r.db.Model(&models.Summary{}).
+	db := config.ReadReplica(r.db)
+	q := db.Model(&models.Summary{}).
 		Order("from_time asc")
 
 	for _, c := range queryConditions {
@@ -60,23 +63,66 @@ func (r *SummaryRepository) GetByUserWithin(user *models.User, from, to time.Tim
 		return nil, err
 	}
 
-	if err := r.populateItems(summaries, queryConditions); err != nil {
+	if err := r.populateItems(db, summaries, queryConditions); err != nil {
 		return nil, err
 	}
 
 	return summaries, nil
 }
 
+// GetLastByUser returns the end of every user's latest final summary, partial ones don't count, as they'll have to be re-generated nevertheless
 func (r *SummaryRepository) GetLastByUser() ([]*models.TimeByUser, error) {
 	var result []*models.TimeByUser
 	r.db.Model(&models.User{}).
 		Select("users.id as user, max(to_time) as time").
-		Joins("left join summaries on users.id = summaries.user_id").
+		Joins("left join summaries on users.id = summaries.user_id and summaries.partial = ?", false).
 		Group("user").
 		Scan(&result)
 	return result, nil
 }
 
+// GetTotalsByTypeWithin sums up the time of all users' summaries within the given range per key of the given entity type
+// if maxPerUser is positive, each user's contribution to a key's total is capped at it
+func (r *SummaryRepository) GetTotalsByTypeWithin(entityType uint8, from, to time.Time, maxPerUser time.Duration) ([]*models.TotalByKey, error) {
+	perUser := r.db.
+		Table("summary_items").
+		Select("summary_items.\"key\" as \"key\", summaries.user_id as user_id, sum(summary_items.total) as total").
+		Joins("inner join summaries on summaries.id = summary_items.summary_id").
+		Where("summary_items.type = ?", entityType).
+		Where("summaries.from_time >= ?", from.Local()).
+		Where("summaries.to_time <= ?", to.Local()).
+		Group("summary_items.\"key\", summaries.user_id")
+
+	total := "sum(total)"
+	if maxPerUser > 0 {
+		total = fmt.Sprintf("sum(case when total > %[1]d then %[1]d else total end)", int64(maxPerUser.Seconds()))
+	}
+
+	var result []*models.TotalByKey
+	if err := config.ReadReplica(r.db).
+		Table("(?) as totals", perUser).
+		Select("\"key\", " + total + " as total, count(*) as users").
+		Group("\"key\"").
+		Scan(&result).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CountByUsersBefore returns the number of summaries ending before the given time per user, omitting users without any
+func (r *SummaryRepository) CountByUsersBefore(t time.Time) ([]*models.CountByUser, error) {
+	var counts []*models.CountByUser
+	if err := r.db.
+		Model(&models.Summary{}).
+		Select("user_id as user, count(id) as count").
+		Where("to_time <= ?", t.Local()).
+		Group("user").
+		Find(&counts).Error; err != nil {
+		return counts, err
+	}
+	return counts, nil
+}
+
 func (r *SummaryRepository) DeleteByUser(userId string) error {
 	if err := r.db.
 		Where("user_id = ?", userId).
@@ -96,15 +142,70 @@ func (r *SummaryRepository) DeleteByUserBefore(userId string, t time.Time) error
 	return nil
 }
 
+// DeleteItemsByUserBefore deletes the items of the given types from all of the user's summaries older than t, but keeps the summaries themselves
+func (r *SummaryRepository) DeleteItemsByUserBefore(userId string, t time.Time, types []uint8) error {
+	if err := r.db.
+		Where("type in ?", types).
+		Where("summary_id in (?)", r.db.Model(&models.Summary{}).Select("id").Where("user_id = ?", userId).Where("to_time <= ?", t.Local())).
+		Delete(models.SummaryItem{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *SummaryRepository) DeleteByUserWithin(userId string, from, to time.Time) error {
+	if err := r.db.
+		Where("user_id = ?", userId).
+		Where("from_time >= ?", from.Local()).
+		Where("to_time <= ?", to.Local()).
+		Delete(models.Summary{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReplacePartial deletes the user's previous partial summaries and inserts the given one instead
+func (r *SummaryRepository) ReplacePartial(summary *models.Summary) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("user_id = ?", summary.UserID).
+			Where("partial = ?", true).
+			Delete(models.Summary{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(summary).Error
+	})
+}
+
+func (r *SummaryRepository) DeletePartialByUser(userId string) error {
+	if err := r.db.
+		Where("user_id = ?", userId).
+		Where("partial = ?", true).
+		Delete(models.Summary{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *SummaryRepository) DeletePartialBefore(t time.Time) error {
+	if err := r.db.
+		Where("partial = ?", true).
+		Where("from_time < ?", t.Local()).
+		Delete(models.Summary{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
 // inplace
-func (r *SummaryRepository) populateItems(summaries []*models.Summary, conditions []clause.Interface) error {
+func (r *SummaryRepository) populateItems(db *gorm.DB, summaries []*models.Summary, conditions []clause.Interface) error {
 	var items []*models.SummaryItem
 
 	summaryMap := slice.GroupWith[*models.Summary, uint](summaries, func(s *models.Summary) uint {
 		return s.ID
 	})
 
-	q := r.db.Model(&models.SummaryItem{}).
+	q := db.Model(&models.SummaryItem{}).
 		Select("summary_items.*").
 		Joins("cross join summaries").
 		Where("summary_items.summary_id = summaries.id").
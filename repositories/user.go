@@ -2,11 +2,36 @@ package repositories
 
 import (
 	"errors"
+	"fmt"
+	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
 	"gorm.io/gorm"
 	"time"
 )
 
+// models referencing a user via a user_id column
+var userOwnedModels = []interface{}{
+	&models.Alias{},
+	&models.Heartbeat{},
+	&models.Summary{},
+	&models.LanguageMapping{},
+	&models.ProjectLabel{},
+	&models.LabelRule{},
+	&models.Annotation{},
+	&models.LeaderboardItem{},
+	&models.AccessToken{},
+}
+
+// key-value entries whose key is suffixed by a user id
+var userKeyValuePrefixes = []string{
+	config.KeyLastImport,
+	config.KeyLastImportSuccess,
+	config.KeyFirstHeartbeat,
+	config.KeySubscriptionNotificationSent,
+	config.KeyReportFailure,
+	config.KeyLoginLockout,
+}
+
 type UserRepository struct {
 	db *gorm.DB
 }
@@ -100,6 +125,16 @@ func (r *UserRepository) GetByLastActiveAfter(t time.Time) ([]*models.User, erro
 	return r.GetByIds(userIds)
 }
 
+func (r *UserRepository) GetByDeleteAtBefore(t time.Time) ([]*models.User, error) {
+	var users []*models.User
+	if err := r.db.
+		Where("delete_at is not null and delete_at <= ?", t.Local()).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (r *UserRepository) Count() (int64, error) {
 	var count int64
 	if err := r.db.
@@ -110,6 +145,32 @@ func (r *UserRepository) Count() (int64, error) {
 	return count, nil
 }
 
+// Returns the number of users, whose last heartbeat is not older than t
+func (r *UserRepository) CountByLastActiveAfter(t time.Time) (int64, error) {
+	var count int64
+	if err := r.activeSince(t).
+		Distinct("user_id").
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Returns all non-admin users in active state, who already existed at t, but haven't sent any heartbeat since, i.e. those not counted by CountByLastActiveAfter
+// deactivated and suspended accounts are frozen on purpose and therefore never considered inactive
+func (r *UserRepository) GetInactiveSince(t time.Time) ([]*models.User, error) {
+	var users []*models.User
+	if err := r.db.
+		Where("is_admin = ?", false).
+		Where("status = ? or status = '' or status is null", models.UserStatusActive).
+		Where("created_at < ?", t.Local()).
+		Where("id not in (?)", r.activeSince(t).Select("user_id")).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (r *UserRepository) InsertOrGet(user *models.User) (*models.User, bool, error) {
 	if u, err := r.FindOne(models.User{ID: user.ID}); err == nil && u != nil && u.ID != "" {
 		return u, false, nil
@@ -125,27 +186,53 @@ func (r *UserRepository) InsertOrGet(user *models.User) (*models.User, bool, err
 
 func (r *UserRepository) Update(user *models.User) (*models.User, error) {
 	updateMap := map[string]interface{}{
-		"api_key":              user.ApiKey,
-		"password":             user.Password,
-		"email":                user.Email,
-		"last_logged_in_at":    user.LastLoggedInAt,
-		"share_data_max_days":  user.ShareDataMaxDays,
-		"share_editors":        user.ShareEditors,
-		"share_languages":      user.ShareLanguages,
-		"share_oss":            user.ShareOSs,
-		"share_projects":       user.ShareProjects,
-		"share_machines":       user.ShareMachines,
-		"share_labels":         user.ShareLabels,
-		"wakatime_api_key":     user.WakatimeApiKey,
-		"wakatime_api_url":     user.WakatimeApiUrl,
-		"has_data":             user.HasData,
-		"reset_token":          user.ResetToken,
-		"location":             user.Location,
-		"reports_weekly":       user.ReportsWeekly,
-		"public_leaderboard":   user.PublicLeaderboard,
-		"subscribed_until":     user.SubscribedUntil,
-		"subscription_renewal": user.SubscriptionRenewal,
-		"stripe_customer_id":   user.StripeCustomerId,
+		"api_key":                    user.ApiKey,
+		"password":                   user.Password,
+		"email":                      user.Email,
+		"last_logged_in_at":          user.LastLoggedInAt,
+		"share_data_max_days":        user.ShareDataMaxDays,
+		"share_editors":              user.ShareEditors,
+		"share_languages":            user.ShareLanguages,
+		"share_oss":                  user.ShareOSs,
+		"share_projects":             user.ShareProjects,
+		"share_machines":             user.ShareMachines,
+		"share_labels":               user.ShareLabels,
+		"share_presence":             user.SharePresence,
+		"wakatime_api_key":           user.WakatimeApiKey,
+		"wakatime_api_url":           user.WakatimeApiUrl,
+		"mirror_url":                 user.MirrorUrl,
+		"mirror_secret":              user.MirrorSecret,
+		"has_data":                   user.HasData,
+		"reset_token":                user.ResetToken,
+		"location":                   user.Location,
+		"reports_weekly":             user.ReportsWeekly,
+		"public_leaderboard":         user.PublicLeaderboard,
+		"subscribed_until":           user.SubscribedUntil,
+		"subscription_renewal":       user.SubscriptionRenewal,
+		"stripe_customer_id":         user.StripeCustomerId,
+		"subscription_plan":          user.SubscriptionPlan,
+		"subscription_id":            user.SubscriptionId,
+		"status":                     user.Status,
+		"deletion_token":             user.DeletionToken,
+		"deletion_token_expiry":      user.DeletionTokenExpiry,
+		"delete_at":                  user.DeleteAt,
+		"is_hidden":                  user.IsHidden,
+		"daily_goal_minutes":         user.DailyGoalMinutes,
+		"notification_digest":        user.NotificationDigest,
+		"quiet_hours_start":          user.QuietHoursStart,
+		"quiet_hours_end":            user.QuietHoursEnd,
+		"email_verified":             user.EmailVerified,
+		"verification_token":         user.VerificationToken,
+		"hash_entities":              user.HashEntities,
+		"hash_entity_projects":       user.HashEntityProjects,
+		"entity_hash_salt":           user.EntityHashSalt,
+		"inactivity_alert_days":      user.InactivityAlertDays,
+		"heartbeats_timeout_minutes": user.HeartbeatsTimeoutMinutes,
+		"notify_achievements":        user.NotifyAchievements,
+		"working_days":               user.WorkingDays,
+		"working_hours_start":        user.WorkingHoursStart,
+		"working_hours_end":          user.WorkingHoursEnd,
+		"weekly_target_hours":        user.WeeklyTargetHours,
 	}
 
 	result := r.db.Model(user).Updates(updateMap)
@@ -169,6 +256,59 @@ func (r *UserRepository) UpdateField(user *models.User, key string, value interf
 	return user, nil
 }
 
+// ChangeId renames a user by re-inserting them under the new id, moving over all references and deleting the old row, all within one transaction.
+// References are updated explicitly instead of relying on ON UPDATE CASCADE, which is not enforced everywhere (e.g. sqlite without foreign keys).
+func (r *UserRepository) ChangeId(user *models.User, newId string) (*models.User, error) {
+	oldId := user.ID
+	renamed := *user
+	renamed.ID = newId
+	renamed.ApiKey = "" // unique, thus only set after the old row is gone
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&renamed).Error; err != nil {
+			return err
+		}
+
+		for _, m := range userOwnedModels {
+			if err := tx.Model(m).Where("user_id = ?", oldId).Update("user_id", newId).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.AbuseReport{}).Where("reporter_id = ?", oldId).Update("reporter_id", newId).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.AbuseReport{}).Where("target_id = ?", oldId).Update("target_id", newId).Error; err != nil {
+			return err
+		}
+		for _, prefix := range userKeyValuePrefixes {
+			if err := tx.Model(&models.KeyStringValue{}).
+				Where(&models.KeyStringValue{Key: fmt.Sprintf("%s_%s", prefix, oldId)}).
+				Update("key", fmt.Sprintf("%s_%s", prefix, newId)).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Delete(&models.User{ID: oldId}).Error; err != nil {
+			return err
+		}
+		if user.ApiKey != "" {
+			return tx.Model(&renamed).Update("api_key", user.ApiKey).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	renamed.ApiKey = user.ApiKey
+	return &renamed, nil
+}
+
 func (r *UserRepository) Delete(user *models.User) error {
 	return r.db.Delete(user).Error
 }
+
+// heartbeats since t, whose users count as active
+func (r *UserRepository) activeSince(t time.Time) *gorm.DB {
+	return r.db.Model(&models.Heartbeat{}).Where("time >= ?", t.Local())
+}
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestUserRepository_GetInactiveSince(t *testing.T) {
+	config.Set(config.Empty())
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	assert.Nil(t, err)
+	assert.Nil(t, db.AutoMigrate(&models.User{}, &models.Heartbeat{}))
+
+	now := time.Now()
+	longAgo := models.CustomTime(now.AddDate(0, 0, -100))
+	since := now.AddDate(0, 0, -30)
+
+	users := []*models.User{
+		{ID: "active", CreatedAt: longAgo, Status: models.UserStatusActive},
+		{ID: "inactive", CreatedAt: longAgo, Status: models.UserStatusActive},
+		{ID: "never-active", CreatedAt: longAgo, Status: models.UserStatusActive},
+		{ID: "new", CreatedAt: models.CustomTime(now), Status: models.UserStatusActive},
+		{ID: "admin", CreatedAt: longAgo, Status: models.UserStatusActive, IsAdmin: true},
+		{ID: "deactivated", CreatedAt: longAgo, Status: models.UserStatusDeactivated},
+		{ID: "suspended", CreatedAt: longAgo, Status: models.UserStatusSuspended},
+	}
+	for i, u := range users {
+		u.ApiKey = string(rune('a' + i))
+		assert.Nil(t, db.Create(u).Error)
+	}
+
+	assert.Nil(t, NewHeartbeatRepository(db).InsertBatch([]*models.Heartbeat{
+		{UserID: "active", Time: models.CustomTime(now.Add(-1 * time.Hour)), Hash: "1"},
+		{UserID: "active", Time: longAgo, Hash: "2"},
+		{UserID: "inactive", Time: longAgo, Hash: "3"},
+		{UserID: "deactivated", Time: longAgo, Hash: "4"},
+	}))
+
+	sut := NewUserRepository(db)
+
+	inactive, err := sut.GetInactiveSince(since)
+	assert.Nil(t, err)
+	ids := make([]string, 0, len(inactive))
+	for _, u := range inactive {
+		ids = append(ids, u.ID)
+	}
+	assert.ElementsMatch(t, []string{"inactive", "never-active"}, ids)
+
+	count, err := sut.CountByLastActiveAfter(since)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+}
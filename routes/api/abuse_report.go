@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+type AbuseReportApiHandler struct {
+	config          *conf.Config
+	userSrvc        services.IUserService
+	abuseReportSrvc services.IAbuseReportService
+}
+
+func NewAbuseReportApiHandler(userService services.IUserService, abuseReportService services.IAbuseReportService) *AbuseReportApiHandler {
+	return &AbuseReportApiHandler{
+		config:          conf.Get(),
+		userSrvc:        userService,
+		abuseReportSrvc: abuseReportService,
+	}
+}
+
+func (h *AbuseReportApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Post("/reports", h.Post)
+	})
+}
+
+// @Summary Report an offensive username or project name
+// @ID post-abuse-report
+// @Tags moderation
+// @Accept json
+// @Param report body models.AbuseReportRequest true "Report"
+// @Security ApiKeyAuth
+// @Success 202
+// @Failure 429
+// @Router /reports [post]
+func (h *AbuseReportApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	reporter := middlewares.GetPrincipal(r)
+
+	var payload models.AbuseReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	if payload.TargetID == reporter.ID {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("you cannot report yourself"))
+		return
+	}
+
+	report := &models.AbuseReport{
+		ReporterID:    &reporter.ID,
+		TargetID:      payload.TargetID,
+		TargetType:    payload.TargetType,
+		TargetProject: payload.TargetProject,
+		Reason:        payload.Reason,
+	}
+	if !report.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid report"))
+		return
+	}
+
+	// unknown targets and duplicates are accepted silently, so as to not reveal which users or projects exist
+	if _, err := h.abuseReportSrvc.Create(report); err != nil {
+		switch {
+		case errors.Is(err, services.ErrAbuseReportTargetNotFound), errors.Is(err, services.ErrAbuseReportDuplicate):
+		case errors.Is(err, services.ErrAbuseReportLimitExceeded):
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(err.Error()))
+			return
+		default:
+			conf.Log().Request(r).Error("failed to create abuse report - %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(conf.ErrInternalServerError))
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAbuseReportApiHandler_Post(t *testing.T) {
+	config.Set(config.Empty())
+
+	reporter := &models.User{ID: "reporter", ApiKey: "reporter-key"}
+
+	setup := func(createErr error) *chi.Mux {
+		router := chi.NewRouter()
+		apiRouter := chi.NewRouter()
+		apiRouter.Use(middlewares.NewPrincipalMiddleware())
+		router.Mount("/api", apiRouter)
+
+		userServiceMock := new(mocks.UserServiceMock)
+		userServiceMock.On("GetUserByKey", reporter.ApiKey).Return(reporter, nil)
+
+		abuseReportServiceMock := new(mocks.AbuseReportServiceMock)
+		abuseReportServiceMock.On("Create", mock.Anything).Return(&models.AbuseReport{}, createErr)
+
+		NewAbuseReportApiHandler(userServiceMock, abuseReportServiceMock).RegisterRoutes(apiRouter)
+		return router
+	}
+
+	serve := func(router *chi.Mux, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/reports?api_key="+reporter.ApiKey, strings.NewReader(body))
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("when reporting existing and unknown users alike", func(t *testing.T) {
+		body := `{"target_type": "user", "target_id": "someone"}`
+
+		recExisting := serve(setup(nil), body)
+		recUnknown := serve(setup(services.ErrAbuseReportTargetNotFound), body)
+		recDuplicate := serve(setup(services.ErrAbuseReportDuplicate), body)
+
+		assert.Equal(t, http.StatusAccepted, recExisting.Code)
+		assert.Equal(t, recExisting.Code, recUnknown.Code)
+		assert.Equal(t, recExisting.Code, recDuplicate.Code)
+		assert.Equal(t, recExisting.Body.String(), recUnknown.Body.String())
+	})
+
+	t.Run("when exceeding the daily limit", func(t *testing.T) {
+		rec := serve(setup(services.ErrAbuseReportLimitExceeded), `{"target_type": "user", "target_id": "someone"}`)
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	})
+
+	t.Run("when reporting oneself", func(t *testing.T) {
+		rec := serve(setup(nil), `{"target_type": "user", "target_id": "reporter"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("when report is invalid", func(t *testing.T) {
+		rec := serve(setup(nil), `{"target_type": "project", "target_id": "someone"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
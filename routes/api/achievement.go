@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type AchievementApiHandler struct {
+	config          *conf.Config
+	userSrvc        services.IUserService
+	achievementSrvc services.IAchievementService
+}
+
+func NewAchievementApiHandler(userService services.IUserService, achievementService services.IAchievementService) *AchievementApiHandler {
+	return &AchievementApiHandler{
+		config:          conf.Get(),
+		userSrvc:        userService,
+		achievementSrvc: achievementService,
+	}
+}
+
+func (h *AchievementApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/users/{user}/achievements", h.GetAll)
+	})
+}
+
+// @Summary Retrieve a user's unlocked achievements
+// @Description Achievements (e.g. 100 hours of coding in total or a 30-day streak) are evaluated after summaries were aggregated, so newly reached ones might show up with a delay.
+// @ID get-achievements
+// @Tags achievements
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Achievement
+// @Router /users/{user}/achievements [get]
+func (h *AchievementApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	achievements, err := h.achievementSrvc.GetByUser(user)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to fetch achievements for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, achievements)
+}
@@ -30,6 +30,7 @@ func NewActivityApiHandler(userService services.IUserService, activityService se
 func (h *ActivityApiHandler) RegisterRoutes(router chi.Router) {
 	r := chi.NewRouter()
 	r.Use(
+		middlewares.NewEmbeddableSecurityMiddleware(),
 		middlewares.NewAuthenticateMiddleware(h.userService).WithOptionalFor([]string{"/api/activity/chart/"}).Handler,
 		middleware.Compress(9, "image/svg+xml"),
 	)
@@ -0,0 +1,826 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+type AdminApiHandler struct {
+	config           *conf.Config
+	userSrvc         services.IUserService
+	abuseReportSrvc  services.IAbuseReportService
+	keyValueSrvc     services.IKeyValueService
+	heartbeatSrvc    services.IHeartbeatService
+	blockRuleSrvc    services.IBlockRuleService
+	correctionSrvc   services.IDataCorrectionService
+	housekeepingSrvc services.IHousekeepingService
+	aggregationSrvc  services.IAggregationService
+	mailSrvc         services.IMailService
+	invitationSrvc   services.IInvitationService
+	throttleSrvc     services.ILoginThrottleService
+}
+
+type adminRetentionConfirmRequestVm struct {
+	RetentionMonths int `json:"retention_months"` // must match the configured retention period
+}
+
+type adminRegenerateResponseVm struct {
+	Scheduled int      `json:"scheduled"`
+	Skipped   []string `json:"skipped"` // users with an aggregation already in progress
+}
+
+type adminTestMailRequestVm struct {
+	Recipient string `json:"recipient"` // defaults to the admin's own e-mail address
+}
+
+type adminTestMailResponseVm struct {
+	Recipient string `json:"recipient"`
+	Provider  string `json:"provider"`
+}
+
+type adminInvitationRequestVm struct {
+	Comment   string `json:"comment"`
+	MaxUses   int    `json:"max_uses"`   // 0 for unlimited
+	ValidDays int    `json:"valid_days"` // 0 for no expiry
+}
+
+type adminStatsResponseVm struct {
+	UsersTotal           int64                          `json:"users_total"`
+	UsersActive          int                            `json:"users_active"` // within the last inactive_days
+	UsersByActivityClass map[models.ActivityClass]int64 `json:"users_by_activity_class"`
+}
+
+func NewAdminApiHandler(userService services.IUserService, abuseReportService services.IAbuseReportService, keyValueService services.IKeyValueService, heartbeatService services.IHeartbeatService, blockRuleService services.IBlockRuleService, dataCorrectionService services.IDataCorrectionService, housekeepingService services.IHousekeepingService, aggregationService services.IAggregationService, mailService services.IMailService, invitationService services.IInvitationService, loginThrottleService services.ILoginThrottleService) *AdminApiHandler {
+	return &AdminApiHandler{
+		config:           conf.Get(),
+		userSrvc:         userService,
+		abuseReportSrvc:  abuseReportService,
+		keyValueSrvc:     keyValueService,
+		heartbeatSrvc:    heartbeatService,
+		blockRuleSrvc:    blockRuleService,
+		correctionSrvc:   dataCorrectionService,
+		housekeepingSrvc: housekeepingService,
+		aggregationSrvc:  aggregationService,
+		mailSrvc:         mailService,
+		invitationSrvc:   invitationService,
+		throttleSrvc:     loginThrottleService,
+	}
+}
+
+func (h *AdminApiHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(
+		middlewares.NewAdminIpAllowlistMiddleware(),
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+		middlewares.NewAdminMiddleware(),
+	)
+	r.Get("/stats", h.GetStats)
+	r.Post("/users/{user}/suspend", h.PostSuspend)
+	r.Post("/users/{user}/unsuspend", h.PostUnsuspend)
+	r.Delete("/users/{user}/lockout", h.DeleteLockout)
+	r.Get("/lockouts", h.GetLockouts)
+	r.Get("/plugins", h.GetPlugins)
+	r.Get("/config/reload", h.GetReloadStatus)
+	r.Post("/config/reload", h.PostReloadConfig)
+	r.Post("/mail/test", h.PostTestMail)
+	r.Get("/retention/impact", h.GetRetentionImpact)
+	r.Post("/retention/confirm", h.PostConfirmRetention)
+	r.Post("/summaries/regenerate", h.PostRegenerateSummaries)
+	r.Get("/aggregation/backfill", h.GetBackfill)
+	r.Post("/aggregation/backfill", h.PostBackfill)
+	r.Get("/block_rules", h.GetBlockRules)
+	r.Post("/block_rules", h.PostBlockRule)
+	r.Delete("/block_rules/{id}", h.DeleteBlockRule)
+	r.Get("/invitations", h.GetInvitations)
+	r.Post("/invitations", h.PostInvitation)
+	r.Delete("/invitations/{id}", h.DeleteInvitation)
+	r.Get("/reports", h.GetReports)
+	r.Post("/reports/{id}/resolve", h.PostResolveReport)
+	if h.config.App.OrgMode {
+		r.Get("/corrections", h.GetCorrections)
+		r.Post("/corrections/{id}/review", h.PostReviewCorrection)
+	}
+
+	router.Mount("/admin", r)
+}
+
+// @Summary Retrieve user statistics
+// @Description Returns the total number of users, the number of active users and the number of users per activity class (daily, weekly and monthly active), as last computed by the hourly rollup job
+// @ID get-admin-stats
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} adminStatsResponseVm
+// @Router /admin/stats [get]
+func (h *AdminApiHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	totalUsers, err := h.userSrvc.Count()
+	if err != nil {
+		conf.Log().Request(r).Error("failed to count users - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	activeUsers, err := h.userSrvc.GetActive(false)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch active users - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, &adminStatsResponseVm{
+		UsersTotal:           totalUsers,
+		UsersActive:          len(activeUsers),
+		UsersByActivityClass: loadActiveUserCounts(h.keyValueSrvc),
+	})
+}
+
+// @Summary Suspend a user account
+// @Description Freezes the account without deleting any data. Suspended users can neither log in nor push heartbeats.
+// @ID post-admin-suspend
+// @Tags admin
+// @Param user path string true "Username"
+// @Security ApiKeyAuth
+// @Success 200
+// @Router /admin/users/{user}/suspend [post]
+func (h *AdminApiHandler) PostSuspend(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, models.UserStatusSuspended)
+}
+
+// @Summary Lift the suspension of a user account
+// @ID post-admin-unsuspend
+// @Tags admin
+// @Param user path string true "Username"
+// @Security ApiKeyAuth
+// @Success 200
+// @Router /admin/users/{user}/unsuspend [post]
+func (h *AdminApiHandler) PostUnsuspend(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, models.UserStatusActive)
+}
+
+// @Summary Retrieve all accounts currently locked after too many failed login attempts
+// @ID get-admin-lockouts
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.LoginLockout
+// @Router /admin/lockouts [get]
+func (h *AdminApiHandler) GetLockouts(w http.ResponseWriter, r *http.Request) {
+	lockouts, err := h.throttleSrvc.GetLockouts()
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch login lockouts - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, lockouts)
+}
+
+// @Summary Lift the login lockout of a user account
+// @Description Also resets the account's failed login attempts
+// @ID delete-admin-lockout
+// @Tags admin
+// @Param user path string true "Username"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /admin/users/{user}/lockout [delete]
+func (h *AdminApiHandler) DeleteLockout(w http.ResponseWriter, r *http.Request) {
+	user, err := h.userSrvc.GetUserById(chi.URLParam(r, "user"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	if err := h.throttleSrvc.Unlock(user.ID); err != nil {
+		conf.Log().Request(r).Error("failed to lift login lockout of user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Retrieve the distribution of plugin versions
+// @Description Returns the number of distinct users per plugin- and wakatime-cli version among all heartbeats of the last days, e.g. to spot users stuck on broken client versions
+// @ID get-admin-plugins
+// @Tags admin
+// @Produce json
+// @Param days query int false "Number of days to look back (defaults to inactive_days)"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.PluginVersionStats
+// @Router /admin/plugins [get]
+func (h *AdminApiHandler) GetPlugins(w http.ResponseWriter, r *http.Request) {
+	days := h.config.App.InactiveDays
+	if daysParam := r.URL.Query().Get("days"); daysParam != "" {
+		var err error
+		if days, err = strconv.Atoi(daysParam); err != nil || days <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(conf.ErrBadRequest))
+			return
+		}
+	}
+
+	stats, err := h.heartbeatSrvc.GetPluginVersionStats(time.Now().AddDate(0, 0, -days).Truncate(time.Hour))
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch plugin version stats - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, stats)
+}
+
+// @Summary Retrieve the outcome of the most recent config reload
+// @Description Reports whether the running config is in sync with the config file or whether the last reload failed and the previous config is still in use.
+// @ID get-admin-reload-config
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} config.ReloadStatus
+// @Router /admin/config/reload [get]
+func (h *AdminApiHandler) GetReloadStatus(w http.ResponseWriter, r *http.Request) {
+	helpers.RespondJSON(w, r, http.StatusOK, conf.GetReloadStatus())
+}
+
+// @Summary Reload the server config
+// @Description Re-reads the config file and applies changes to custom languages, mail settings, rate limits, newsbox and cron schedules without a restart. Returns the changed config sections.
+// @ID post-admin-reload-config
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} string
+// @Router /admin/config/reload [post]
+func (h *AdminApiHandler) PostReloadConfig(w http.ResponseWriter, r *http.Request) {
+	changed, err := conf.Reload()
+	if err != nil {
+		conf.Log().Request(r).Warn("failed to reload config - %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, changed)
+}
+
+// @Summary Send a test mail
+// @Description Sends a mail through the configured mail provider to check the mail setup. Errors reported by the provider are returned as-is.
+// @ID post-admin-test-mail
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body adminTestMailRequestVm false "Recipient, defaults to the admin's own e-mail address"
+// @Security ApiKeyAuth
+// @Success 200 {object} adminTestMailResponseVm
+// @Router /admin/mail/test [post]
+func (h *AdminApiHandler) PostTestMail(w http.ResponseWriter, r *http.Request) {
+	if !conf.Get().Mail.Enabled {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("mail is disabled"))
+		return
+	}
+
+	var payload adminTestMailRequestVm
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(conf.ErrBadRequest))
+			return
+		}
+	}
+	if payload.Recipient == "" {
+		payload.Recipient = middlewares.GetPrincipal(r).Email
+	}
+	if !models.MailAddress(payload.Recipient).Valid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("missing or invalid recipient"))
+		return
+	}
+
+	if err := h.mailSrvc.SendTestMail(payload.Recipient); err != nil {
+		conf.Log().Request(r).Warn("failed to send test mail to '%s' - %v", payload.Recipient, err)
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, &adminTestMailResponseVm{Recipient: payload.Recipient, Provider: conf.Get().Mail.Provider})
+}
+
+// @Summary Preview the impact of the data retention period
+// @Description Lists the number of heartbeats and summaries per user, which the next data cleanup run will delete under the configured retention period, including those which would have been kept under the previously confirmed one. If the configured period was shortened, cleanups are paused until it is confirmed.
+// @ID get-admin-retention-impact
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.RetentionImpact
+// @Router /admin/retention/impact [get]
+func (h *AdminApiHandler) GetRetentionImpact(w http.ResponseWriter, r *http.Request) {
+	impact, err := h.housekeepingSrvc.GetRetentionImpact()
+	if err != nil {
+		conf.Log().Request(r).Error("failed to compute retention impact - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, impact)
+}
+
+// @Summary Confirm the data retention period
+// @Description Resumes data cleanups after the retention period was shortened. The confirmed period must match the configured one, so that a config change in between is not confirmed inadvertently.
+// @ID post-admin-retention-confirm
+// @Tags admin
+// @Accept json
+// @Param confirmation body adminRetentionConfirmRequestVm true "Retention period to confirm"
+// @Security ApiKeyAuth
+// @Success 204
+// @Failure 409
+// @Router /admin/retention/confirm [post]
+func (h *AdminApiHandler) PostConfirmRetention(w http.ResponseWriter, r *http.Request) {
+	var payload adminRetentionConfirmRequestVm
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	if err := h.housekeepingSrvc.ConfirmRetention(payload.RetentionMonths); err != nil {
+		if errors.Is(err, services.ErrRetentionMismatch) {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		conf.Log().Request(r).Error("failed to confirm retention period - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Re-generate all users' summaries
+// @Description Drops all users' stored summaries within the given date range, invalidates cached ones and re-computes them from heartbeats in the background, e.g. after changing server-wide language mappings. Without from or to, the range spans from each user's first or until their last heartbeat respectively. Users with an aggregation already in progress are skipped.
+// @ID post-admin-summaries-regenerate
+// @Tags admin
+// @Produce json
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date, inclusive (e.g. '2021-02-08')"
+// @Security ApiKeyAuth
+// @Success 202 {object} adminRegenerateResponseVm
+// @Router /admin/summaries/regenerate [post]
+func (h *AdminApiHandler) PostRegenerateSummaries(w http.ResponseWriter, r *http.Request) {
+	// dates are interpreted in each user's own time zone, so they're only validated upfront and parsed again per user
+	if _, _, err := parseRegenerationRange(r, time.UTC); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	users, err := h.userSrvc.GetAll()
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch users - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	result := &adminRegenerateResponseVm{Skipped: []string{}}
+	for _, u := range users {
+		from, to, _ := parseRegenerationRange(r, u.TZ())
+		if err := h.aggregationSrvc.ScheduleRegeneration(u, from, to); err != nil {
+			if errors.Is(err, services.ErrAggregationInProgress) {
+				result.Skipped = append(result.Skipped, u.ID)
+				continue
+			}
+			conf.Log().Request(r).Error("failed to schedule summary regeneration for user '%s' - %v", u.ID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(conf.ErrInternalServerError))
+			return
+		}
+		result.Scheduled++
+	}
+
+	helpers.RespondJSON(w, r, http.StatusAccepted, result)
+}
+
+// @Summary Retrieve the progress of the current or latest aggregation backfill
+// @ID get-admin-aggregation-backfill
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.AggregationBackfillJob
+// @Router /admin/aggregation/backfill [get]
+func (h *AdminApiHandler) GetBackfill(w http.ResponseWriter, r *http.Request) {
+	helpers.RespondJSON(w, r, http.StatusOK, h.aggregationSrvc.GetBackfillJob())
+}
+
+// @Summary Backfill summaries for a date range
+// @Description Re-runs summary aggregation for the given users (or all users, if none are given) between two dates, both inclusive and interpreted in each user's time zone. Other than the nightly aggregation, existing summaries within the range are re-generated as well. Users are processed one after another in the background, progress can be tracked via /admin/aggregation/backfill.
+// @ID post-admin-aggregation-backfill
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param backfill body models.AggregationBackfillRequest true "Date range and users to backfill"
+// @Security ApiKeyAuth
+// @Success 202 {object} models.AggregationBackfillJob
+// @Failure 409
+// @Router /admin/aggregation/backfill [post]
+func (h *AdminApiHandler) PostBackfill(w http.ResponseWriter, r *http.Request) {
+	var payload models.AggregationBackfillRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || !payload.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	job, err := h.aggregationSrvc.ScheduleBackfill(&payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrBackfillUnknownUsers):
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+		case errors.Is(err, services.ErrBackfillPending):
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(err.Error()))
+		default:
+			conf.Log().Request(r).Error("failed to schedule aggregation backfill - %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(conf.ErrInternalServerError))
+		}
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusAccepted, job)
+}
+
+// @Summary Retrieve all instance-wide heartbeat block rules
+// @Description Includes the number of heartbeats dropped by each rule so far
+// @ID get-admin-block-rules
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.BlockRule
+// @Router /admin/block_rules [get]
+func (h *AdminApiHandler) GetBlockRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.blockRuleSrvc.GetAll()
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch block rules - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, rules)
+}
+
+// @Summary Create an instance-wide heartbeat block rule
+// @Description Drops all incoming heartbeats of any user, whose project, language, entity or branch matches the given regular expression, before they are persisted
+// @ID post-admin-block-rule
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param rule body models.BlockRule true "Rule to create"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.BlockRule
+// @Router /admin/block_rules [post]
+func (h *AdminApiHandler) PostBlockRule(w http.ResponseWriter, r *http.Request) {
+	var payload models.BlockRule
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	rule := &models.BlockRule{Field: payload.Field, Pattern: payload.Pattern, Comment: payload.Comment}
+	if !rule.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid block rule"))
+		return
+	}
+
+	result, err := h.blockRuleSrvc.Create(rule)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to create block rule - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Delete an instance-wide heartbeat block rule
+// @ID delete-admin-block-rule
+// @Tags admin
+// @Param id path int true "Rule ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /admin/block_rules/{id} [delete]
+func (h *AdminApiHandler) DeleteBlockRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	rule, err := h.blockRuleSrvc.GetById(uint(id))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	if err := h.blockRuleSrvc.Delete(rule); err != nil {
+		conf.Log().Request(r).Error("failed to delete block rule %d - %v", rule.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Retrieve all invitations
+// @Description Includes the number of times each invitation was used to sign up so far
+// @ID get-admin-invitations
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Invitation
+// @Router /admin/invitations [get]
+func (h *AdminApiHandler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	invitations, err := h.invitationSrvc.GetAll()
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch invitations - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, invitations)
+}
+
+// @Summary Create an invitation
+// @Description Generates a new invitation code, that allows to sign up even if registration is disabled. Can be limited to a number of uses and expire after a number of days.
+// @ID post-admin-invitation
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param invitation body adminInvitationRequestVm true "Invitation to create"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.Invitation
+// @Router /admin/invitations [post]
+func (h *AdminApiHandler) PostInvitation(w http.ResponseWriter, r *http.Request) {
+	var payload adminInvitationRequestVm
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	invitation := &models.Invitation{Comment: payload.Comment, MaxUses: payload.MaxUses}
+	if payload.ValidDays > 0 {
+		expiresAt := models.CustomTime(time.Now().AddDate(0, 0, payload.ValidDays))
+		invitation.ExpiresAt = &expiresAt
+	}
+	if !invitation.IsValid() || payload.ValidDays < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid invitation"))
+		return
+	}
+
+	result, err := h.invitationSrvc.Create(invitation)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to create invitation - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Delete an invitation
+// @Description Revokes the invitation, accounts created with it are not affected
+// @ID delete-admin-invitation
+// @Tags admin
+// @Param id path int true "Invitation ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /admin/invitations/{id} [delete]
+func (h *AdminApiHandler) DeleteInvitation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	invitation, err := h.invitationSrvc.GetById(uint(id))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	if err := h.invitationSrvc.Delete(invitation); err != nil {
+		conf.Log().Request(r).Error("failed to delete invitation %d - %v", invitation.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Retrieve the moderation queue of open abuse reports
+// @ID get-admin-reports
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.AbuseReport
+// @Router /admin/reports [get]
+func (h *AdminApiHandler) GetReports(w http.ResponseWriter, r *http.Request) {
+	reports, err := h.abuseReportSrvc.GetOpen()
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch abuse reports - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, reports)
+}
+
+// @Summary Resolve an abuse report
+// @Description Either hides the reported user (or their projects) from all public views, force-renames the reported user or project or dismisses the report
+// @ID post-admin-resolve-report
+// @Tags admin
+// @Accept json
+// @Param id path int true "Report ID"
+// @Param resolution body models.AbuseReportResolution true "Moderation action"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.AbuseReport
+// @Router /admin/reports/{id}/resolve [post]
+func (h *AdminApiHandler) PostResolveReport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	report, err := h.abuseReportSrvc.GetById(uint(id))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	var resolution models.AbuseReportResolution
+	if err := json.NewDecoder(r.Body).Decode(&resolution); err != nil || !resolution.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	if !report.IsOpen() {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("report already closed"))
+		return
+	}
+
+	result, err := h.abuseReportSrvc.Resolve(report, &resolution)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidUsername) || errors.Is(err, services.ErrUsernameTaken) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		conf.Log().Request(r).Error("failed to resolve abuse report %d - %v", report.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, result)
+}
+
+// @Summary Retrieve the review queue of pending data corrections
+// @Description Only available in org mode
+// @ID get-admin-corrections
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.DataCorrection
+// @Router /admin/corrections [get]
+func (h *AdminApiHandler) GetCorrections(w http.ResponseWriter, r *http.Request) {
+	corrections, err := h.correctionSrvc.GetPending()
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch data corrections - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, corrections)
+}
+
+// @Summary Approve or reject a data correction
+// @Description Approved corrections are applied in the background, their status changes to "applied" once done. Admins can't review their own corrections. Only available in org mode.
+// @ID post-admin-review-correction
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Correction ID"
+// @Param review body models.DataCorrectionReview true "Decision"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.DataCorrection
+// @Router /admin/corrections/{id}/review [post]
+func (h *AdminApiHandler) PostReviewCorrection(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	correction, err := h.correctionSrvc.GetById(uint(id))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	var review models.DataCorrectionReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil || !review.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	result, err := h.correctionSrvc.Review(correction, middlewares.GetPrincipal(r), &review)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrDataCorrectionNotPending):
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(err.Error()))
+		case errors.Is(err, services.ErrDataCorrectionSelfReview):
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(err.Error()))
+		default:
+			conf.Log().Request(r).Error("failed to review data correction %d - %v", correction.ID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(conf.ErrInternalServerError))
+		}
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, result)
+}
+
+func (h *AdminApiHandler) setStatus(w http.ResponseWriter, r *http.Request, status string) {
+	principal := middlewares.GetPrincipal(r)
+
+	user, err := h.userSrvc.GetUserById(chi.URLParam(r, "user"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	if user.ID == principal.ID {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("you cannot change the status of your own account"))
+		return
+	}
+
+	user.Status = status
+	if _, err := h.userSrvc.Update(user); err != nil {
+		conf.Log().Request(r).Error("failed to set status of user '%s' to '%s' - %v", user.ID, status, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// loadActiveUserCounts returns the latest user counts per activity class, as persisted by the rollup job, omitting classes not computed yet
+func loadActiveUserCounts(keyValueService services.IKeyValueService) map[models.ActivityClass]int64 {
+	counts := make(map[models.ActivityClass]int64)
+	for _, class := range models.AllActivityClasses() {
+		kv, err := keyValueService.GetString(class.Key())
+		if err != nil || kv == nil || kv.Value == "" {
+			continue
+		}
+		if count, err := strconv.ParseInt(kv.Value, 10, 64); err == nil {
+			counts[class] = count
+		}
+	}
+	return counts
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAdminApiHandler_PostSuspend(t *testing.T) {
+	config.Set(config.Empty())
+
+	admin := &models.User{ID: "admin", ApiKey: "admin-key", IsAdmin: true}
+	regular := &models.User{ID: "regular", ApiKey: "regular-key"}
+
+	setup := func(target *models.User) (*chi.Mux, *mocks.UserServiceMock) {
+		router := chi.NewRouter()
+		apiRouter := chi.NewRouter()
+		apiRouter.Use(middlewares.NewPrincipalMiddleware())
+		router.Mount("/api", apiRouter)
+
+		userServiceMock := new(mocks.UserServiceMock)
+		userServiceMock.On("GetUserByKey", admin.ApiKey).Return(admin, nil)
+		userServiceMock.On("GetUserByKey", regular.ApiKey).Return(regular, nil)
+		userServiceMock.On("GetUserById", admin.ID).Return(admin, nil)
+		if target != nil {
+			userServiceMock.On("GetUserById", target.ID).Return(target, nil)
+		}
+		userServiceMock.On("GetUserById", mock.Anything).Return((*models.User)(nil), errors.New("not found"))
+		userServiceMock.On("Update", mock.Anything).Return(target, nil)
+
+		NewAdminApiHandler(userServiceMock, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil).RegisterRoutes(apiRouter)
+		return router, userServiceMock
+	}
+
+	serve := func(router *chi.Mux, path, apiKey string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path+"?api_key="+apiKey, nil)
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("when suspending another user as admin", func(t *testing.T) {
+		target := &models.User{ID: "target", Status: models.UserStatusActive}
+		router, userServiceMock := setup(target)
+
+		assert.Equal(t, http.StatusOK, serve(router, "/api/admin/users/target/suspend", admin.ApiKey))
+		assert.True(t, target.IsSuspended())
+		userServiceMock.AssertCalled(t, "Update", target)
+
+		assert.Equal(t, http.StatusOK, serve(router, "/api/admin/users/target/unsuspend", admin.ApiKey))
+		assert.True(t, target.IsActive())
+	})
+
+	t.Run("when suspending own account", func(t *testing.T) {
+		router, userServiceMock := setup(nil)
+
+		assert.Equal(t, http.StatusBadRequest, serve(router, "/api/admin/users/admin/suspend", admin.ApiKey))
+		assert.False(t, admin.IsSuspended())
+		userServiceMock.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("when suspending unknown user", func(t *testing.T) {
+		router, _ := setup(nil)
+
+		assert.Equal(t, http.StatusNotFound, serve(router, "/api/admin/users/unknown/suspend", admin.ApiKey))
+	})
+
+	t.Run("when suspending as non-admin", func(t *testing.T) {
+		target := &models.User{ID: "target", Status: models.UserStatusActive}
+		router, userServiceMock := setup(target)
+
+		assert.Equal(t, http.StatusForbidden, serve(router, "/api/admin/users/target/suspend", regular.ApiKey))
+		assert.False(t, target.IsSuspended())
+		userServiceMock.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("when suspended admin calls admin endpoint", func(t *testing.T) {
+		suspendedAdmin := &models.User{ID: "admin2", ApiKey: "admin2-key", IsAdmin: true, Status: models.UserStatusSuspended}
+		target := &models.User{ID: "target", Status: models.UserStatusActive}
+		router, userServiceMock := setup(target)
+		userServiceMock.On("GetUserByKey", suspendedAdmin.ApiKey).Return(suspendedAdmin, nil)
+
+		assert.Equal(t, http.StatusForbidden, serve(router, "/api/admin/users/target/suspend", suspendedAdmin.ApiKey))
+		assert.False(t, target.IsSuspended())
+	})
+}
+
+func TestAdminApiHandler_GetStats(t *testing.T) {
+	config.Set(config.Empty())
+
+	admin := &models.User{ID: "admin", ApiKey: "admin-key", IsAdmin: true}
+
+	router := chi.NewRouter()
+	apiRouter := chi.NewRouter()
+	apiRouter.Use(middlewares.NewPrincipalMiddleware())
+	router.Mount("/api", apiRouter)
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByKey", admin.ApiKey).Return(admin, nil)
+	userServiceMock.On("Count").Return(10, nil)
+	userServiceMock.On("GetActive", false).Return([]*models.User{admin, {ID: "user1"}}, nil)
+
+	keyValueServiceMock := new(mocks.KeyValueServiceMock)
+	keyValueServiceMock.On("GetString", models.ActivityClassDaily.Key()).Return(&models.KeyStringValue{Key: models.ActivityClassDaily.Key(), Value: "1"}, nil)
+	keyValueServiceMock.On("GetString", models.ActivityClassWeekly.Key()).Return(&models.KeyStringValue{Key: models.ActivityClassWeekly.Key(), Value: "3"}, nil)
+	keyValueServiceMock.On("GetString", models.ActivityClassMonthly.Key()).Return((*models.KeyStringValue)(nil), errors.New("not found"))
+
+	NewAdminApiHandler(userServiceMock, nil, keyValueServiceMock, nil, nil, nil, nil, nil, nil, nil, nil).RegisterRoutes(apiRouter)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/stats?api_key="+admin.ApiKey, nil)
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var stats adminStatsResponseVm
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&stats))
+	assert.Equal(t, int64(10), stats.UsersTotal)
+	assert.Equal(t, 2, stats.UsersActive)
+	assert.Equal(t, map[models.ActivityClass]int64{models.ActivityClassDaily: 1, models.ActivityClassWeekly: 3}, stats.UsersByActivityClass)
+}
+
+func TestAdminApiHandler_PostResolveReport(t *testing.T) {
+	config.Set(config.Empty())
+
+	admin := &models.User{ID: "admin", ApiKey: "admin-key", IsAdmin: true}
+
+	setup := func(resolveErr error) *chi.Mux {
+		router := chi.NewRouter()
+		apiRouter := chi.NewRouter()
+		apiRouter.Use(middlewares.NewPrincipalMiddleware())
+		router.Mount("/api", apiRouter)
+
+		userServiceMock := new(mocks.UserServiceMock)
+		userServiceMock.On("GetUserByKey", admin.ApiKey).Return(admin, nil)
+
+		report := &models.AbuseReport{ID: 1, TargetID: "target", TargetType: models.AbuseReportTargetUser, Status: models.AbuseReportStatusOpen}
+		abuseReportServiceMock := new(mocks.AbuseReportServiceMock)
+		abuseReportServiceMock.On("GetById", uint(1)).Return(report, nil)
+		abuseReportServiceMock.On("Resolve", report, mock.Anything).Return(report, resolveErr)
+
+		NewAdminApiHandler(userServiceMock, abuseReportServiceMock, nil, nil, nil, nil, nil, nil, nil, nil, nil).RegisterRoutes(apiRouter)
+		return router
+	}
+
+	serve := func(router *chi.Mux) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/reports/1/resolve?api_key="+admin.ApiKey, strings.NewReader(`{"action": "rename", "new_name": "renamed"}`))
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("when resolving successfully", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(setup(nil)).Code)
+	})
+
+	t.Run("when new username is taken", func(t *testing.T) {
+		rec := serve(setup(services.ErrUsernameTaken))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, services.ErrUsernameTaken.Error(), rec.Body.String())
+	})
+
+	t.Run("when resolving fails unexpectedly", func(t *testing.T) {
+		rec := serve(setup(assert.AnError))
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NotContains(t, rec.Body.String(), assert.AnError.Error())
+	})
+}
+
+func TestAdminApiHandler_GetPlugins(t *testing.T) {
+	config.Set(config.Empty())
+
+	admin := &models.User{ID: "admin", ApiKey: "admin-key", IsAdmin: true}
+
+	router := chi.NewRouter()
+	apiRouter := chi.NewRouter()
+	apiRouter.Use(middlewares.NewPrincipalMiddleware())
+	router.Mount("/api", apiRouter)
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByKey", admin.ApiKey).Return(admin, nil)
+
+	heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
+	heartbeatServiceMock.On("GetPluginVersionStats", mock.Anything).Return([]*models.PluginVersionStats{
+		{Editor: "vscode", Plugin: "vscode-wakatime", Version: "24.0.10", Users: 3},
+	}, nil)
+
+	NewAdminApiHandler(userServiceMock, nil, nil, heartbeatServiceMock, nil, nil, nil, nil, nil, nil, nil).RegisterRoutes(apiRouter)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/plugins?days=abc&api_key="+admin.ApiKey, nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/plugins?days=30&api_key="+admin.ApiKey, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var stats []*models.PluginVersionStats
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&stats))
+	assert.Len(t, stats, 1)
+	assert.Equal(t, 3, stats[0].Users)
+	heartbeatServiceMock.AssertNumberOfCalls(t, "GetPluginVersionStats", 1)
+}
+
+func TestAdminApiHandler_PostRegenerateSummaries(t *testing.T) {
+	config.Set(config.Empty())
+
+	admin := &models.User{ID: "admin", ApiKey: "admin-key", IsAdmin: true}
+	busy := &models.User{ID: "busy"}
+
+	setup := func() (*chi.Mux, *mocks.AggregationServiceMock) {
+		router := chi.NewRouter()
+		apiRouter := chi.NewRouter()
+		apiRouter.Use(middlewares.NewPrincipalMiddleware())
+		router.Mount("/api", apiRouter)
+
+		userServiceMock := new(mocks.UserServiceMock)
+		userServiceMock.On("GetUserByKey", admin.ApiKey).Return(admin, nil)
+		userServiceMock.On("GetAll").Return([]*models.User{admin, busy}, nil)
+
+		aggregationServiceMock := new(mocks.AggregationServiceMock)
+		aggregationServiceMock.On("ScheduleRegeneration", admin, mock.Anything, mock.Anything).Return(nil)
+		aggregationServiceMock.On("ScheduleRegeneration", busy, mock.Anything, mock.Anything).Return(services.ErrAggregationInProgress)
+
+		NewAdminApiHandler(userServiceMock, nil, nil, nil, nil, nil, nil, aggregationServiceMock, nil, nil, nil).RegisterRoutes(apiRouter)
+		return router, aggregationServiceMock
+	}
+
+	t.Run("when regenerating within a date range", func(t *testing.T) {
+		router, aggregationServiceMock := setup()
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/summaries/regenerate?from=2024-01-01&to=2024-01-31&api_key="+admin.ApiKey, nil)
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+
+		var result adminRegenerateResponseVm
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(&result))
+		assert.Equal(t, 1, result.Scheduled)
+		assert.Equal(t, []string{busy.ID}, result.Skipped)
+
+		call := aggregationServiceMock.Calls[0]
+		assert.Equal(t, "2024-01-01", call.Arguments.Get(1).(time.Time).Format(config.SimpleDateFormat))
+		assert.Equal(t, "2024-01-31", call.Arguments.Get(2).(time.Time).Format(config.SimpleDateFormat))
+	})
+
+	t.Run("when regenerating with invalid range", func(t *testing.T) {
+		router, aggregationServiceMock := setup()
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/summaries/regenerate?from=2024-02-01&to=2024-01-01&api_key="+admin.ApiKey, nil)
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		aggregationServiceMock.AssertNotCalled(t, "ScheduleRegeneration", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type AggregationApiHandler struct {
+	config          *conf.Config
+	userSrvc        services.IUserService
+	aggregationSrvc services.IAggregationService
+}
+
+func NewAggregationApiHandler(userService services.IUserService, aggregationService services.IAggregationService) *AggregationApiHandler {
+	return &AggregationApiHandler{
+		config:          conf.Get(),
+		userSrvc:        userService,
+		aggregationSrvc: aggregationService,
+	}
+}
+
+func (h *AggregationApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Post("/users/{user}/summaries/regenerate", h.PostRegenerate)
+	})
+}
+
+// @Summary Re-generate a user's summaries
+// @Description Drops the user's stored summaries within the given date range, invalidates cached ones and re-computes them from heartbeats in the background. Useful after changing aliases, project labels or language mappings, which otherwise only apply to summaries generated afterwards. Without from or to, the range spans from the user's first or until their last heartbeat respectively.
+// @ID post-summaries-regenerate
+// @Tags summary
+// @Param user path string true "Username (or current)"
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date, inclusive (e.g. '2021-02-08')"
+// @Security ApiKeyAuth
+// @Success 202
+// @Failure 409
+// @Router /users/{user}/summaries/regenerate [post]
+func (h *AggregationApiHandler) PostRegenerate(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	from, to, err := parseRegenerationRange(r, user.TZ())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if err := h.aggregationSrvc.ScheduleRegeneration(user, from, to); err != nil {
+		if errors.Is(err, services.ErrAggregationInProgress) {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		conf.Log().Request(r).Error("failed to schedule summary regeneration for user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// parseRegenerationRange parses the optional from and to dates of a regeneration request, either of which is zero if not given
+func parseRegenerationRange(r *http.Request, tz *time.Location) (from, to time.Time, err error) {
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.ParseInLocation(conf.SimpleDateFormat, v, tz); err != nil {
+			return from, to, errors.New("invalid from date")
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.ParseInLocation(conf.SimpleDateFormat, v, tz); err != nil {
+			return from, to, errors.New("invalid to date")
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return from, to, errors.New("to date must not be before from date")
+	}
+	return from, to, nil
+}
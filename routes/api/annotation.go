@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type AnnotationApiHandler struct {
+	config         *conf.Config
+	userSrvc       services.IUserService
+	annotationSrvc services.IAnnotationService
+}
+
+func NewAnnotationApiHandler(userService services.IUserService, annotationService services.IAnnotationService) *AnnotationApiHandler {
+	return &AnnotationApiHandler{
+		config:         conf.Get(),
+		userSrvc:       userService,
+		annotationSrvc: annotationService,
+	}
+}
+
+func (h *AnnotationApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/users/{user}/annotations", h.GetAll)
+		r.Post("/users/{user}/annotations", h.Post)
+		r.Delete("/users/{user}/annotations/{id}", h.Delete)
+	})
+}
+
+// @Summary Retrieve a user's annotations
+// @ID get-annotations
+// @Tags annotations
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Annotation
+// @Router /users/{user}/annotations [get]
+func (h *AnnotationApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	params, err := helpers.ParseSummaryParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	annotations, err := h.annotationSrvc.GetByUserWithin(user, params.From, params.To)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to fetch annotations for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, annotations)
+}
+
+// @Summary Add an annotation
+// @Description Attaches a note to a day (in the user's time zone) and, optionally, to a single project. Annotations are returned alongside summaries and shown in reports.
+// @ID post-annotation
+// @Tags annotations
+// @Accept json
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param annotation body models.Annotation true "Day (yyyy-mm-dd), text and optional project"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.Annotation
+// @Router /users/{user}/annotations [post]
+func (h *AnnotationApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var payload models.Annotation
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	annotation := &models.Annotation{
+		UserID:  user.ID,
+		Day:     strings.TrimSpace(payload.Day),
+		Project: strings.TrimSpace(payload.Project),
+		Text:    strings.TrimSpace(payload.Text),
+	}
+	if !annotation.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid annotation - missing text or malformed day?"))
+		return
+	}
+
+	result, err := h.annotationSrvc.Create(annotation)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to create annotation for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Delete an annotation
+// @ID delete-annotation
+// @Tags annotations
+// @Param user path string true "Username (or current)"
+// @Param id path int true "Annotation ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /users/{user}/annotations/{id} [delete]
+func (h *AnnotationApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	annotation, err := h.annotationSrvc.GetById(uint(id))
+	if err != nil || annotation == nil || annotation.UserID != user.ID {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	if err := h.annotationSrvc.Delete(annotation); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to delete annotation %d for user '%s' - %v", annotation.ID, user.ID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -19,24 +19,31 @@ import (
 )
 
 type BadgeHandler struct {
-	config      *conf.Config
-	cache       *cache.Cache
-	userSrvc    services.IUserService
-	summarySrvc services.ISummaryService
+	config          *conf.Config
+	cache           *cache.Cache
+	userSrvc        services.IUserService
+	summarySrvc     services.ISummaryService
+	heartbeatSrvc   services.IHeartbeatService
+	savedFilterSrvc services.ISavedFilterService
 }
 
-func NewBadgeHandler(userService services.IUserService, summaryService services.ISummaryService) *BadgeHandler {
+func NewBadgeHandler(userService services.IUserService, summaryService services.ISummaryService, heartbeatService services.IHeartbeatService, savedFilterService services.ISavedFilterService) *BadgeHandler {
 	return &BadgeHandler{
-		config:      conf.Get(),
-		cache:       cache.New(time.Hour, time.Hour),
-		userSrvc:    userService,
-		summarySrvc: summaryService,
+		config:          conf.Get(),
+		cache:           cache.New(time.Hour, time.Hour),
+		userSrvc:        userService,
+		summarySrvc:     summaryService,
+		heartbeatSrvc:   heartbeatService,
+		savedFilterSrvc: savedFilterService,
 	}
 }
 
 func (h *BadgeHandler) RegisterRoutes(router chi.Router) {
 	r := chi.NewRouter()
-	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithOptionalFor([]string{"/api/badge/"}).Handler)
+	r.Use(
+		middlewares.NewEmbeddableSecurityMiddleware(),
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).WithOptionalFor([]string{"/api/badge/"}).Handler,
+	)
 	r.Get("/{user}/*", h.Get)
 	router.Mount("/badge", r)
 }
@@ -49,15 +56,28 @@ func (h *BadgeHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	interval, filters, err := routeutils.GetBadgeParams(r.URL.Path, authorizedUser, user)
+	var savedFilter *models.SavedFilter
+	if name := routeutils.GetBadgeSavedFilterName(r.URL.Path); name != "" {
+		if savedFilter, err = h.savedFilterSrvc.GetByUserAndName(user, name); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("saved filter not found"))
+			return
+		}
+	}
+
+	interval, filters, err := routeutils.GetBadgeParams(r.URL.Path, authorizedUser, user, savedFilter)
 	if err != nil {
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte(err.Error()))
 		return
 	}
 
-	cacheKey := fmt.Sprintf("%s_%v_%s_%s", user.ID, *interval.Key, filters.Hash(), r.URL.RawQuery)
+	// badges are cached for a while despite new heartbeats, but not beyond their summaries being invalidated (e.g. by deleting heartbeats)
+	cacheKey := routeutils.SummaryVariant(user, fmt.Sprintf("%v_%s_%s_%d", *interval.Key, filters.Hash(), r.URL.RawQuery, h.summarySrvc.DataVersion(user.ID).UnixNano()))
 	noCache := utils.IsNoCache(r, 1*time.Hour)
+	if routeutils.CheckNotModified(w, r, routeutils.SummaryVersion(h.heartbeatSrvc, h.summarySrvc, user), interval.Start, interval.End, cacheKey) {
+		return
+	}
 	if cacheResult, ok := h.cache.Get(cacheKey); ok && !noCache {
 		respondSvg(w, cacheResult.([]byte))
 		return
@@ -78,6 +98,9 @@ func (h *BadgeHandler) Get(w http.ResponseWriter, r *http.Request) {
 	}
 
 	badgeData := v1.NewBadgeDataFrom(summary)
+	if color, ok := routeutils.GetBadgeColor(h.config.App.GetLanguageColors(), filters, summary); ok {
+		badgeData.Color = color
+	}
 	if customLabel := r.URL.Query().Get("label"); customLabel != "" {
 		badgeData.Label = customLabel
 	}
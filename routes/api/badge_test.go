@@ -52,8 +52,16 @@ func TestBadgeHandler_Get(t *testing.T) {
 
 	summaryServiceMock := new(mocks.SummaryServiceMock)
 	summaryServiceMock.On("Aliased", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), &user1, mock.Anything, mock.Anything).Return(&summary1, nil)
+	summaryServiceMock.On("DataVersion", "user1").Return(time.Date(2023, 3, 1, 0, 0, 0, 0, time.Local))
 
-	badgeHandler := NewBadgeHandler(userServiceMock, summaryServiceMock)
+	heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
+	heartbeatServiceMock.On("GetLatestByUser", &user1).Return(&models.Heartbeat{Time: models.CustomTime(time.Date(2023, 3, 14, 12, 0, 0, 0, time.Local))}, nil)
+
+	savedFilterServiceMock := new(mocks.SavedFilterServiceMock)
+	savedFilterServiceMock.On("GetByUserAndName", &user1, "languages").Return(&models.SavedFilter{UserID: "user1", Name: "languages", Query: "language=go,rust"}, nil)
+	savedFilterServiceMock.On("GetByUserAndName", &user1, "work").Return(&models.SavedFilter{UserID: "user1", Name: "work", Query: "project=foo&language=go"}, nil)
+
+	badgeHandler := NewBadgeHandler(userServiceMock, summaryServiceMock, heartbeatServiceMock, savedFilterServiceMock)
 	badgeHandler.RegisterRoutes(apiRouter)
 
 	t.Run("when requesting badge", func(t *testing.T) {
@@ -78,6 +86,34 @@ func TestBadgeHandler_Get(t *testing.T) {
 			assert.Contains(t, string(data), "0 hrs 12 mins")
 		})
 
+		t.Run("should return not modified for matching etag", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/badge/{user}/interval:week/language:go", nil)
+			req = withUrlParam(req, "user", "user1")
+			router.ServeHTTP(rec, req)
+
+			etag := rec.Result().Header.Get("ETag")
+			assert.True(t, strings.HasPrefix(etag, `W/"`))
+			assert.NotEmpty(t, rec.Result().Header.Get("Last-Modified"))
+
+			rec = httptest.NewRecorder()
+			req = httptest.NewRequest(http.MethodGet, "/api/badge/{user}/interval:week/language:go", nil)
+			req = withUrlParam(req, "user", "user1")
+			req.Header.Set("If-None-Match", etag)
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusNotModified, rec.Result().StatusCode)
+			assert.Empty(t, rec.Body.Bytes())
+
+			rec = httptest.NewRecorder()
+			req = httptest.NewRequest(http.MethodGet, "/api/badge/{user}/interval:today/language:go", nil)
+			req = withUrlParam(req, "user", "user1")
+			req.Header.Set("If-None-Match", etag)
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Result().StatusCode)
+		})
+
 		t.Run("should not return badge if shared interval exceeded", func(t *testing.T) {
 			rec := httptest.NewRecorder()
 
@@ -98,6 +134,32 @@ func TestBadgeHandler_Get(t *testing.T) {
 			assert.False(t, strings.HasPrefix(string(data), "<svg"))
 		})
 
+		t.Run("should return badge for saved filter", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/badge/{user}/interval:week/filter:languages", nil)
+			req = withUrlParam(req, "user", "user1")
+
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+		})
+
+		t.Run("should not return badge for saved filter if any of its entity types is not shared", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/badge/{user}/interval:week/filter:work", nil)
+			req = withUrlParam(req, "user", "user1")
+
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		})
+
 		t.Run("should not return badge if entity type not shared", func(t *testing.T) {
 			rec := httptest.NewRecorder()
 
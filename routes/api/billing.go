@@ -0,0 +1,287 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type BillingApiHandler struct {
+	config      *conf.Config
+	userSrvc    services.IUserService
+	billingSrvc services.IBillingService
+}
+
+func NewBillingApiHandler(userService services.IUserService, billingService services.IBillingService) *BillingApiHandler {
+	return &BillingApiHandler{
+		config:      conf.Get(),
+		userSrvc:    userService,
+		billingSrvc: billingService,
+	}
+}
+
+func (h *BillingApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/users/{user}/billing/projects", h.GetProjects)
+		r.Put("/users/{user}/billing/projects", h.PutProject)
+		r.Delete("/users/{user}/billing/projects", h.DeleteProject)
+		r.Get("/users/{user}/billing/report", h.GetReport)
+	})
+}
+
+// @Summary Retrieve a user's billable projects
+// @ID get-billable-projects
+// @Tags billing
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.BillableProject
+// @Router /users/{user}/billing/projects [get]
+func (h *BillingApiHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	projects, err := h.billingSrvc.GetProjectsByUser(user.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to fetch billable projects for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, projects)
+}
+
+// @Summary Mark a project as billable
+// @Description Assigns a project to a client and sets its hourly rate, replacing any previous settings for the same project. All projects of a client must share the same currency.
+// @ID put-billable-project
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param project body models.BillableProject true "Project, client, hourly rate and currency (e.g. EUR)"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.BillableProject
+// @Router /users/{user}/billing/projects [put]
+func (h *BillingApiHandler) PutProject(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var payload models.BillableProject
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || !payload.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+	payload.ID, payload.UserID = 0, user.ID
+
+	project, err := h.billingSrvc.SetProject(&payload)
+	if err != nil {
+		if errors.Is(err, services.ErrBillingCurrencyMismatch) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to save billable project for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, project)
+}
+
+// @Summary Unmark a project as billable
+// @ID delete-billable-project
+// @Tags billing
+// @Param user path string true "Username (or current)"
+// @Param project query string true "Project name"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /users/{user}/billing/projects [delete]
+func (h *BillingApiHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	project := r.URL.Query().Get("project")
+	if project == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	if err := h.billingSrvc.DeleteProject(user.ID, project); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to delete billable project for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Retrieve a user's billable hours
+// @Description Breaks down the time coded in billable projects by client and calendar month, along with the resulting amounts, e.g. to write invoices from. Available as json, csv (one row per client, month and project) or pdf.
+// @ID get-billing-report
+// @Tags billing
+// @Produce json,text/csv,application/pdf
+// @Param user path string true "Username (or current)"
+// @Param interval query string false "Interval identifier" default(last_month) Enums(today, yesterday, week, last_week, month, last_month, year, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
+// @Param from query string false "Start date (e.g. '2021-02-07'), takes precedence over interval"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Param format query string false "Output format" default(json) Enums(json, csv, pdf)
+// @Security ApiKeyAuth
+// @Success 200 {object} models.BillingReport
+// @Router /users/{user}/billing/report [get]
+func (h *BillingApiHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	from, to, err := parseBillingInterval(r, user.TZ())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = models.BillingFormatJson
+	}
+	if format != models.BillingFormatJson && format != models.BillingFormatCsv && format != models.BillingFormatPdf {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid format"))
+		return
+	}
+
+	report, err := h.billingSrvc.GetReport(user, from, to)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to generate billing report for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	if format == models.BillingFormatJson {
+		helpers.RespondJSON(w, r, http.StatusOK, report)
+		return
+	}
+
+	// write to buffer first to still be able to respond with an error status
+	var buf bytes.Buffer
+	contentType := "text/csv; charset=utf-8"
+	if format == models.BillingFormatCsv {
+		cw := csv.NewWriter(&buf)
+		err = cw.WriteAll(report.Rows())
+	} else {
+		contentType = utils.PdfMimeType
+		err = utils.WritePdf(&buf, buildBillingPdf(user, report))
+	}
+	if err != nil {
+		conf.Log().Request(r).Error("failed to write billing report for user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	fromDate, toDate := from.In(user.TZ()).Format(conf.SimpleDateFormat), to.In(user.TZ()).Format(conf.SimpleDateFormat)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"wakapi_billing_%s_%s_%s.%s\"", user.ID, fromDate, toDate, format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func parseBillingInterval(r *http.Request, tz *time.Location) (from, to time.Time, err error) {
+	params := r.URL.Query()
+
+	if params.Get("from") != "" {
+		if from, err = helpers.ParseDateTimeTZ(params.Get("from"), tz); err != nil {
+			return from, to, errors.New("invalid 'from' parameter")
+		}
+		if to, err = helpers.ParseDateTimeTZ(params.Get("to"), tz); err != nil {
+			return from, to, errors.New("missing or invalid 'to' parameter")
+		}
+		return from, to, nil
+	}
+
+	interval := models.IntervalLastMonth
+	if intervalParam := params.Get("interval"); intervalParam != "" {
+		if interval, err = helpers.ParseInterval(intervalParam); err != nil {
+			return from, to, errors.New("invalid interval")
+		}
+	}
+	if err, from, to = helpers.ResolveIntervalTZ(interval, tz); err != nil {
+		return from, to, errors.New("invalid interval")
+	}
+	return from, to, nil
+}
+
+// buildBillingPdf lays out the report as one table per client, listing hours and amounts per month and project
+func buildBillingPdf(user *models.User, report *models.BillingReport) *utils.PdfDocument {
+	const rowFormat = "%-8s  %-36s  %8s  %10s  %12s"
+
+	doc := &utils.PdfDocument{
+		Title: fmt.Sprintf("Billable Hours of %s", user.ID),
+		Lines: []string{
+			fmt.Sprintf("%s - %s", report.From.In(user.TZ()).Format(conf.SimpleDateFormat), report.To.In(user.TZ()).Format(conf.SimpleDateFormat)),
+			"",
+		},
+	}
+
+	if len(report.Clients) == 0 {
+		doc.Lines = append(doc.Lines, "No billable hours within this period.")
+	}
+
+	for _, c := range report.Clients {
+		doc.Lines = append(doc.Lines,
+			fmt.Sprintf("Client: %s", c.Client),
+			"",
+			fmt.Sprintf(rowFormat, "Month", "Project", "Hours", "Rate", "Amount"),
+			strings.Repeat("-", 82),
+		)
+		for _, m := range c.Months {
+			for _, p := range m.Projects {
+				doc.Lines = append(doc.Lines, fmt.Sprintf(rowFormat, m.Month, truncateRunes(p.Project, 36), fmt.Sprintf("%.2f", p.Hours), fmt.Sprintf("%.2f", p.HourlyRate), fmt.Sprintf("%.2f", p.Amount)))
+			}
+			doc.Lines = append(doc.Lines, fmt.Sprintf(rowFormat, "", "Subtotal "+m.Month, fmt.Sprintf("%.2f", m.Hours), "", fmt.Sprintf("%.2f", m.Amount)))
+		}
+		doc.Lines = append(doc.Lines,
+			strings.Repeat("-", 82),
+			fmt.Sprintf(rowFormat, "", "Total", fmt.Sprintf("%.2f", c.Hours), "", strings.TrimSpace(fmt.Sprintf("%.2f %s", c.Amount, c.Currency))),
+			"",
+			"",
+		)
+	}
+
+	return doc
+}
+
+func truncateRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n-1]) + "~"
+	}
+	return s
+}
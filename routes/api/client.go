@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type ClientApiHandler struct {
+	config            *conf.Config
+	userSrvc          services.IUserService
+	clientVersionSrvc services.IClientVersionService
+}
+
+type outdatedClientsResponseVm struct {
+	Outdated       bool                     `json:"outdated"`
+	Clients        []*models.OutdatedClient `json:"clients"`
+	LatestVersions map[string]string        `json:"latest_versions"`
+}
+
+func NewClientApiHandler(userService services.IUserService, clientVersionService services.IClientVersionService) *ClientApiHandler {
+	return &ClientApiHandler{
+		config:            conf.Get(),
+		userSrvc:          userService,
+		clientVersionSrvc: clientVersionService,
+	}
+}
+
+func (h *ClientApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/users/{user}/clients/outdated", h.GetOutdated)
+	})
+}
+
+// @Summary Check a user's recently used clients for being outdated
+// @Description Compares the wakatime-cli and plugin versions of a user's heartbeats of the last days against the latest known releases. Severely outdated clients are known to cause data quality issues.
+// @ID get-outdated-clients
+// @Tags clients
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 200 {object} outdatedClientsResponseVm
+// @Router /users/{user}/clients/outdated [get]
+func (h *ClientApiHandler) GetOutdated(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	clients, err := h.clientVersionSrvc.GetOutdatedByUser(user)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to check clients of user '%s' for being outdated - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, &outdatedClientsResponseVm{
+		Outdated:       len(clients) > 0,
+		Clients:        clients,
+		LatestVersions: h.clientVersionSrvc.GetLatestVersions(),
+	})
+}
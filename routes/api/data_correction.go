@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+type DataCorrectionApiHandler struct {
+	config             *conf.Config
+	userSrvc           services.IUserService
+	dataCorrectionSrvc services.IDataCorrectionService
+}
+
+func NewDataCorrectionApiHandler(userService services.IUserService, dataCorrectionService services.IDataCorrectionService) *DataCorrectionApiHandler {
+	return &DataCorrectionApiHandler{
+		config:             conf.Get(),
+		userSrvc:           userService,
+		dataCorrectionSrvc: dataCorrectionService,
+	}
+}
+
+func (h *DataCorrectionApiHandler) RegisterRoutes(router chi.Router) {
+	if !h.config.App.OrgMode {
+		return
+	}
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/corrections", h.Get)
+		r.Post("/corrections", h.Post)
+	})
+}
+
+// @Summary Retrieve the current user's data corrections
+// @Description Only available in org mode
+// @ID get-corrections
+// @Tags correction
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.DataCorrection
+// @Router /corrections [get]
+func (h *DataCorrectionApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+
+	corrections, err := h.dataCorrectionSrvc.GetByUser(user)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch data corrections - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, corrections)
+}
+
+// @Summary Submit a data correction for review
+// @Description Requests to move the current user's coding time within the given time range from one project to another. Only takes effect once approved by an admin. Only available in org mode.
+// @ID post-correction
+// @Tags correction
+// @Accept json
+// @Produce json
+// @Param correction body models.DataCorrectionRequest true "Correction"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.DataCorrection
+// @Failure 429
+// @Router /corrections [post]
+func (h *DataCorrectionApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+
+	var payload models.DataCorrectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	correction := &models.DataCorrection{
+		UserID:      user.ID,
+		FromProject: payload.FromProject,
+		ToProject:   payload.ToProject,
+		FromTime:    models.CustomTime(payload.From),
+		ToTime:      models.CustomTime(payload.To),
+		Reason:      payload.Reason,
+	}
+	if !correction.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid correction"))
+		return
+	}
+
+	result, err := h.dataCorrectionSrvc.Create(correction)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrDataCorrectionProjectNotFound):
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+		case errors.Is(err, services.ErrDataCorrectionLimitExceeded):
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(err.Error()))
+		default:
+			conf.Log().Request(r).Error("failed to create data correction - %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(conf.ErrInternalServerError))
+		}
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusCreated, result)
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type exportTargetViewModel struct {
+	*models.ExportTarget
+	DumpUrl string `json:"dump_url,omitempty"` // presigned, expires after an hour
+}
+
+type ExportApiHandler struct {
+	config     *conf.Config
+	userSrvc   services.IUserService
+	exportSrvc services.IExportService
+}
+
+func NewExportApiHandler(userService services.IUserService, exportService services.IExportService) *ExportApiHandler {
+	return &ExportApiHandler{
+		config:     conf.Get(),
+		userSrvc:   userService,
+		exportSrvc: exportService,
+	}
+}
+
+func (h *ExportApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/users/{user}/export", h.Get)
+		r.Post("/users/{user}/export", h.Post)
+		if h.config.App.ExportTargetsEnabled {
+			r.Get("/users/{user}/export/target", h.GetTarget)
+			r.Put("/users/{user}/export/target", h.PutTarget)
+			r.Delete("/users/{user}/export/target", h.DeleteTarget)
+		}
+	})
+}
+
+// @Summary Retrieve the user's data export
+// @Description Returns the export archive (zip), if ready, or the current export status otherwise
+// @ID get-export
+// @Tags user
+// @Produce json,application/zip
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.UserExport
+// @Router /users/{user}/export [get]
+func (h *ExportApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	export := h.exportSrvc.Get(user)
+	if export.Status != models.ExportStatusReady {
+		helpers.RespondJSON(w, r, http.StatusOK, export)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"wakapi_export_%s%s\"", user.ID, models.ExportArchiveExtension))
+	http.ServeFile(w, r, export.Path)
+}
+
+// @Summary Request a new export of all the user's data
+// @Description Generates a machine-readable archive containing the user's profile, settings, heartbeats, summaries, aliases and labels asynchronously. The archive can be downloaded until it expires (72 hours by default).
+// @ID post-export
+// @Tags user
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /users/{user}/export [post]
+func (h *ExportApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	if err := h.exportSrvc.Request(user); err == services.ErrExportPending {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+		return
+	} else if err != nil {
+		conf.Log().Request(r).Error("failed to schedule data export for user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// @Summary Retrieve the user's export target
+// @Description Returns the user's own s3 bucket, to which their data is dumped every day, along with the status of the latest dump and a link to download it
+// @ID get-export-target
+// @Tags user
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 200 {object} exportTargetViewModel
+// @Failure 404
+// @Router /users/{user}/export/target [get]
+func (h *ExportApiHandler) GetTarget(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	target, err := h.exportSrvc.GetTarget(user)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	vm := &exportTargetViewModel{ExportTarget: target}
+	if url, err := h.exportSrvc.GetDumpUrl(target); err == nil {
+		vm.DumpUrl = url
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, vm)
+}
+
+// @Summary Set the user's export target
+// @Description Configures an s3 bucket owned by the user, to which an archive of all their data is uploaded every day, starting right away. Dumps are encrypted at rest (SSE-S3) and tagged with the given retention period, for the bucket's lifecycle rules to match on.
+// @ID put-export-target
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param target body models.ExportTargetRequest true "Bucket and credentials"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ExportTarget
+// @Router /users/{user}/export/target [put]
+func (h *ExportApiHandler) PutTarget(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var payload models.ExportTargetRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || !payload.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	target, err := h.exportSrvc.SetTarget(user, payload.Target())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, target)
+}
+
+// @Summary Remove the user's export target
+// @Description Stops dumping data to the user's bucket, previous dumps are left untouched
+// @ID delete-export-target
+// @Tags user
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /users/{user}/export/target [delete]
+func (h *ExportApiHandler) DeleteTarget(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	if err := h.exportSrvc.DeleteTarget(user); err != nil {
+		conf.Log().Request(r).Error("failed to delete export target for user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -5,6 +5,9 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/migrations"
 	"gorm.io/gorm"
 )
 
@@ -12,6 +15,16 @@ type HealthApiHandler struct {
 	db *gorm.DB
 }
 
+type probeCheck struct {
+	Ok      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+type probeResult struct {
+	Status string                 `json:"status"`
+	Checks map[string]*probeCheck `json:"checks,omitempty"`
+}
+
 func NewHealthApiHandler(db *gorm.DB) *HealthApiHandler {
 	return &HealthApiHandler{db: db}
 }
@@ -20,6 +33,12 @@ func (h *HealthApiHandler) RegisterRoutes(router chi.Router) {
 	router.Get("/health", h.Get)
 }
 
+// RegisterProbeRoutes registers liveness and readiness probes for container orchestrators, to be mounted at top level
+func (h *HealthApiHandler) RegisterProbeRoutes(router chi.Router) {
+	router.Get("/healthz", h.GetLiveness)
+	router.Get("/readyz", h.GetReadiness)
+}
+
 // @Summary Check the application's health status
 // @ID get-health
 // @Tags misc
@@ -34,6 +53,66 @@ func (h *HealthApiHandler) Get(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	var configStatus int
+	if conf.GetReloadStatus().InSync {
+		configStatus = 1
+	}
+
 	w.Header().Set("Content-Type", "text/plain")
-	w.Write([]byte(fmt.Sprintf("app=1\ndb=%d", dbStatus)))
+	w.Write([]byte(fmt.Sprintf("app=1\ndb=%d\nconfig=%d", dbStatus, configStatus)))
+}
+
+// GetLiveness only tells whether the process is up and serving requests, it deliberately does not check any dependencies, so a database outage won't get the container restarted
+func (h *HealthApiHandler) GetLiveness(w http.ResponseWriter, r *http.Request) {
+	helpers.RespondJSON(w, r, http.StatusOK, &probeResult{Status: "ok"})
+}
+
+// GetReadiness tells whether the instance is ready to accept traffic, i.e. the database is reachable, migrations are applied, job queues can take more work and no shutdown is in progress
+func (h *HealthApiHandler) GetReadiness(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]*probeCheck{
+		"database":   h.checkDatabase(),
+		"migrations": h.checkMigrations(),
+		"queues":     h.checkQueues(),
+		"shutdown":   {Ok: !conf.IsShuttingDown()},
+	}
+
+	result, status := &probeResult{Status: "ok", Checks: checks}, http.StatusOK
+	for _, c := range checks {
+		if !c.Ok {
+			result.Status, status = "unavailable", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	helpers.RespondJSON(w, r, status, result)
+}
+
+func (h *HealthApiHandler) checkDatabase() *probeCheck {
+	sqlDb, err := h.db.DB()
+	if err == nil {
+		err = sqlDb.Ping()
+	}
+	if err != nil {
+		return &probeCheck{Ok: false, Message: err.Error()}
+	}
+	return &probeCheck{Ok: true}
+}
+
+func (h *HealthApiHandler) checkMigrations() *probeCheck {
+	if conf.Get().SkipMigrations {
+		return &probeCheck{Ok: true, Message: "skipped"}
+	}
+	if !migrations.Applied() {
+		return &probeCheck{Ok: false, Message: "pending"}
+	}
+	return &probeCheck{Ok: true}
+}
+
+func (h *HealthApiHandler) checkQueues() *probeCheck {
+	for _, qm := range conf.GetQueueMetrics() {
+		if qm.Saturated() {
+			return &probeCheck{Ok: false, Message: fmt.Sprintf("queue '%s' is saturated (%d pending jobs)", qm.Queue, qm.EnqueuedJobs-qm.FinishedJobs)}
+		}
+	}
+	return &probeCheck{Ok: true}
 }
@@ -1,10 +1,15 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"github.com/duke-git/lancet/v2/condition"
+	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
 	"github.com/go-chi/chi/v5"
 	"github.com/muety/wakapi/helpers"
 	"net/http"
+	"strconv"
+	"time"
 
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
@@ -21,25 +26,49 @@ type HeartbeatApiHandler struct {
 	userSrvc            services.IUserService
 	heartbeatSrvc       services.IHeartbeatService
 	languageMappingSrvc services.ILanguageMappingService
+	aggregationSrvc     services.IAggregationService
+	accessTokenSrvc     services.IAccessTokenService
+	quotaSrvc           services.IQuotaService
 }
 
-func NewHeartbeatApiHandler(userService services.IUserService, heartbeatService services.IHeartbeatService, languageMappingService services.ILanguageMappingService) *HeartbeatApiHandler {
+func NewHeartbeatApiHandler(userService services.IUserService, heartbeatService services.IHeartbeatService, languageMappingService services.ILanguageMappingService, aggregationService services.IAggregationService, accessTokenService services.IAccessTokenService, quotaService services.IQuotaService) *HeartbeatApiHandler {
 	return &HeartbeatApiHandler{
 		config:              conf.Get(),
 		userSrvc:            userService,
 		heartbeatSrvc:       heartbeatService,
 		languageMappingSrvc: languageMappingService,
+		aggregationSrvc:     aggregationService,
+		accessTokenSrvc:     accessTokenService,
+		quotaSrvc:           quotaService,
 	}
 }
 
+const headerQuotaWarning = "X-Wakapi-Quota-Warning"
+
 type heartbeatResponseVm struct {
 	Responses [][]interface{} `json:"responses"`
 }
 
+type heartbeatErrorVm struct {
+	Error string `json:"error"`
+}
+
+type heartbeatDeleteResponseVm struct {
+	Deleted int64 `json:"deleted"`
+	Matched int64 `json:"matched"`
+	DryRun  bool  `json:"dry_run"`
+}
+
+type heartbeatUpdateRequestVm struct {
+	Project  *string `json:"project"`
+	Language *string `json:"language"`
+	Branch   *string `json:"branch"`
+}
+
 func (h *HeartbeatApiHandler) RegisterRoutes(router chi.Router) {
 	router.Group(func(r chi.Router) {
 		r.Use(
-			middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+			middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAccessTokens(h.accessTokenSrvc, models.ScopeWriteHeartbeats).WithRejectDeactivated().Handler,
 			customMiddleware.NewWakatimeRelayMiddleware().Handler,
 		)
 		// see https://github.com/muety/wakapi/issues/203
@@ -52,15 +81,26 @@ func (h *HeartbeatApiHandler) RegisterRoutes(router chi.Router) {
 		r.Post("/compat/wakatime/v1/users/{user}/heartbeats", h.Post)
 		r.Post("/compat/wakatime/v1/users/{user}/heartbeats.bulk", h.Post)
 	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Delete("/heartbeats", h.DeleteMany)
+		r.Delete("/users/{user}/heartbeats", h.DeleteMany)
+		r.Patch("/users/{user}/heartbeats/{id}", h.Patch)
+		r.Delete("/users/{user}/heartbeats/{id}", h.Delete)
+	})
 }
 
 // @Summary Push a new heartbeat
+// @Description Responds with a status per heartbeat, i.e. 201 if it was stored, 400 if it was invalid or 409 if it was a duplicate, so clients can retry failed ones only. The request as a whole only fails if none of the heartbeats was valid.
 // @ID post-heartbeat
 // @Tags heartbeat
 // @Accept json
+// @Produce json
 // @Param heartbeat body models.Heartbeat true "A single heartbeat"
 // @Security ApiKeyAuth
-// @Success 201
+// @Success 201 {object} heartbeatResponseVm
+// @Failure 400 {object} heartbeatResponseVm
 // @Router /heartbeat [post]
 func (h *HeartbeatApiHandler) Post(w http.ResponseWriter, r *http.Request) {
 	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
@@ -77,58 +117,99 @@ func (h *HeartbeatApiHandler) Post(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userAgent := r.Header.Get("User-Agent")
-	opSys, editor, _ := utils.ParseUserAgent(userAgent)
-	machineName := r.Header.Get("X-Machine-Name")
+	if err := populateHeartbeats(r, user, heartbeats, h.heartbeatSrvc); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
 
-	for _, hb := range heartbeats {
-		if hb == nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("invalid heartbeat object"))
-			return
+	responses := make([][]interface{}, len(heartbeats))
+	hashes := make([]string, 0, len(heartbeats))
+
+	for i, hb := range heartbeats {
+		if !hb.Valid() || !hb.Timely(h.config.App.HeartbeatsMaxAge()) {
+			responses[i] = []interface{}{&heartbeatErrorVm{Error: "invalid heartbeat object"}, http.StatusBadRequest}
+			continue
 		}
+		hashes = append(hashes, hb.Hashed().Hash)
+	}
 
-		// TODO: unit test this
-		if hb.UserAgent != "" {
-			userAgent = hb.UserAgent
-			localOpSys, localEditor, _ := utils.ParseUserAgent(userAgent)
-			opSys = condition.TernaryOperator[bool, string](localOpSys != "", localOpSys, opSys)
-			editor = condition.TernaryOperator[bool, string](localEditor != "", localEditor, editor)
+	if len(hashes) == 0 {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, &heartbeatResponseVm{Responses: responses})
+		return
+	}
+
+	existingHashes, err := h.heartbeatSrvc.GetExistingHashes(hashes)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to look up existing heartbeats - %v", err)
+		return
+	}
+	seenHashes := datastructure.NewSet[string](existingHashes...)
+
+	candidates, candidateIndices := make([]*models.Heartbeat, 0, len(hashes)), make([]int, 0, len(hashes))
+	for i, hb := range heartbeats {
+		if responses[i] != nil {
+			continue
 		}
-		if hb.Machine != "" {
-			machineName = hb.Machine
+		if seenHashes.Contain(hb.Hash) {
+			responses[i] = []interface{}{&heartbeatErrorVm{Error: "duplicate heartbeat"}, http.StatusConflict}
+			continue
 		}
+		seenHashes.Add(hb.Hash)
+		candidates, candidateIndices = append(candidates, hb), append(candidateIndices, i)
+	}
 
-		if hb.Branch == "<<LAST_BRANCH>>" {
-			if latest, err := h.heartbeatSrvc.GetLatestByFilters(user, models.NewFiltersWith(models.SummaryProject, hb.Project)); latest != nil && err == nil {
-				hb.Branch = latest.Branch
-			}
+	// heartbeats dropped by block rules are acknowledged like accepted ones, but must not count towards the user's quotas
+	unblocked := datastructure.NewSet(h.heartbeatSrvc.FilterBlocked(candidates)...)
+	allowed, allowedIndices := make([]*models.Heartbeat, 0, len(candidates)), make([]int, 0, len(candidates))
+	for j, hb := range candidates {
+		if !unblocked.Contain(hb) {
+			responses[candidateIndices[j]] = []interface{}{nil, http.StatusCreated}
+			continue
 		}
+		allowed, allowedIndices = append(allowed, hb), append(allowedIndices, candidateIndices[j])
+	}
+	candidates, candidateIndices = allowed, allowedIndices
 
-		hb.User = user
-		hb.UserID = user.ID
-		hb.Machine = machineName
-		hb.OperatingSystem = opSys
-		hb.Editor = editor
-		hb.UserAgent = userAgent
+	quotaErrors, quotaWarnings, err := h.quotaSrvc.Check(user, candidates)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to check quotas - %v", err)
+		return
+	}
+	for _, warning := range quotaWarnings {
+		w.Header().Add(headerQuotaWarning, warning)
+	}
 
-		if !hb.Valid() || !hb.Timely(h.config.App.HeartbeatsMaxAge()) {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("invalid heartbeat object"))
-			return
+	accepted := make([]*models.Heartbeat, 0, len(candidates))
+	for j, hb := range candidates {
+		i := candidateIndices[j]
+		if quotaErrors[j] != nil {
+			responses[i] = []interface{}{&heartbeatErrorVm{Error: quotaErrors[j].Error()}, http.StatusTooManyRequests}
+			continue
 		}
-
-		hb.Hashed()
+		// data is left empty, wakatime-cli only relies on the status (see https://github.com/wakatime/wakatime-cli/blob/c2076c0e1abc1449baf5b7ac7db391b06041c719/pkg/api/heartbeat.go#L127)
+		responses[i] = []interface{}{nil, http.StatusCreated}
+		accepted = append(accepted, hb)
+	}
+	if len(accepted) == 0 && len(candidates) > 0 {
+		// all new heartbeats were rejected because of quotas, tell the client to back off
+		helpers.RespondJSON(w, r, http.StatusTooManyRequests, &heartbeatResponseVm{Responses: responses})
+		return
 	}
 
-	if err := h.heartbeatSrvc.InsertBatch(heartbeats); err != nil {
+	if err := h.heartbeatSrvc.InsertBatch(accepted); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(conf.ErrInternalServerError))
 		conf.Log().Request(r).Error("failed to batch-insert heartbeats - %v", err)
 		return
 	}
+	h.quotaSrvc.Record(user, len(accepted))
 
-	if !user.HasData {
+	if !user.HasData && len(accepted) > 0 {
 		user.HasData = true
 		if _, err := h.userSrvc.Update(user); err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -138,30 +219,9 @@ func (h *HeartbeatApiHandler) Post(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	defer func() {}()
-
-	helpers.RespondJSON(w, r, http.StatusCreated, constructSuccessResponse(len(heartbeats)))
-}
-
-// construct weird response format (see https://github.com/wakatime/wakatime/blob/2e636d389bf5da4e998e05d5285a96ce2c181e3d/wakatime/api.py#L288)
-// to make the cli consider all heartbeats to having been successfully saved
-// response looks like: { "responses": [ [ null, 201 ], ... ] }
-// this was probably a temporary bug at wakatime, responses actually looks like so: https://pastr.de/p/nyf6kj2e6843fbw4xkj4h4pj
-// TODO: adapt response format some time
-// however, wakatime-cli is still able to parse the response (see https://github.com/wakatime/wakatime-cli/blob/c2076c0e1abc1449baf5b7ac7db391b06041c719/pkg/api/heartbeat.go#L127), so no urgent need for action
-func constructSuccessResponse(n int) *heartbeatResponseVm {
-	responses := make([][]interface{}, n)
-
-	for i := 0; i < n; i++ {
-		r := make([]interface{}, 2)
-		r[0] = nil
-		r[1] = http.StatusCreated
-		responses[i] = r
-	}
-
-	return &heartbeatResponseVm{
-		Responses: responses,
-	}
+	// response format follows wakatime's (see https://github.com/wakatime/wakatime/blob/2e636d389bf5da4e998e05d5285a96ce2c181e3d/wakatime/api.py#L288),
+	// i.e. a [result, status] pair per heartbeat, in the order they were sent: { "responses": [ [ null, 201 ], [ { "error": "duplicate heartbeat" }, 409 ], ... ] }
+	helpers.RespondJSON(w, r, http.StatusCreated, &heartbeatResponseVm{Responses: responses})
 }
 
 // Only for Swagger
@@ -241,3 +301,227 @@ func (h *HeartbeatApiHandler) postAlias6() {}
 // @Success 201
 // @Router /users/{user}/heartbeats.bulk [post]
 func (h *HeartbeatApiHandler) postAlias7() {}
+
+// @Summary Edit a single heartbeat
+// @Description Changes the project, language and / or branch of the heartbeat and re-generates the summary of the day it belongs to
+// @ID patch-heartbeat
+// @Tags heartbeat
+// @Accept json
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param id path int true "Heartbeat ID"
+// @Param heartbeat body heartbeatUpdateRequestVm true "Fields to change"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Heartbeat
+// @Router /users/{user}/heartbeats/{id} [patch]
+func (h *HeartbeatApiHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid heartbeat id"))
+		return
+	}
+
+	var payload heartbeatUpdateRequestVm
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || (payload.Project == nil && payload.Language == nil && payload.Branch == nil) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	heartbeat, err := h.heartbeatSrvc.GetByUserAndId(user, id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	if payload.Project != nil {
+		heartbeat.Project = *payload.Project
+	}
+	if payload.Language != nil {
+		heartbeat.Language = *payload.Language
+		heartbeat.LanguageOriginal = "" // manual edits take precedence over language mappings
+	}
+	if payload.Branch != nil {
+		heartbeat.Branch = *payload.Branch
+	}
+
+	if err := h.heartbeatSrvc.Update(heartbeat); err != nil {
+		conf.Log().Request(r).Error("failed to update heartbeat %d of user '%s' - %v", heartbeat.ID, user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	h.regenerateSummaries(r, user, heartbeat.Time.T(), heartbeat.Time.T())
+
+	helpers.RespondJSON(w, r, http.StatusOK, heartbeat)
+}
+
+// @Summary Delete a single heartbeat
+// @Description Deletes the heartbeat and re-generates the summary of the day it belongs to
+// @ID delete-heartbeat
+// @Tags heartbeat
+// @Param user path string true "Username (or current)"
+// @Param id path int true "Heartbeat ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /users/{user}/heartbeats/{id} [delete]
+func (h *HeartbeatApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid heartbeat id"))
+		return
+	}
+
+	heartbeat, err := h.heartbeatSrvc.GetByUserAndId(user, id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	if err := h.heartbeatSrvc.DeleteByUserAndId(user, heartbeat.ID); err != nil {
+		conf.Log().Request(r).Error("failed to delete heartbeat %d of user '%s' - %v", heartbeat.ID, user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	h.regenerateSummaries(r, user, heartbeat.Time.T(), heartbeat.Time.T())
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Delete all heartbeats matching the given filters
+// @Description Deletes all heartbeats within the given time range, optionally filtered by project, language, etc. and an entity pattern ("*" as wildcard), and re-generates all affected summaries
+// @Description With dry_run set, only the number of heartbeats that would be deleted is returned, e.g. to have the user confirm it first
+// @ID delete-heartbeats
+// @Tags heartbeat
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param from query string true "Start date (e.g. '2021-02-07')"
+// @Param to query string true "End date (e.g. '2021-02-08')"
+// @Param project query string false "Project to filter by"
+// @Param language query string false "Language to filter by"
+// @Param editor query string false "Editor to filter by"
+// @Param operating_system query string false "OS to filter by"
+// @Param machine query string false "Machine to filter by"
+// @Param branch query string false "Branch to filter by"
+// @Param entity_pattern query string false "Entity (e.g. file path) pattern to filter by, use '*' as wildcard"
+// @Param dry_run query bool false "Whether to only count the matching heartbeats instead of deleting them"
+// @Security ApiKeyAuth
+// @Success 200 {object} heartbeatDeleteResponseVm
+// @Router /users/{user}/heartbeats [delete]
+func (h *HeartbeatApiHandler) DeleteMany(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	params, err := helpers.ParseSummaryParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if !params.From.Before(params.To) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid time range"))
+		return
+	}
+
+	if !params.Filters.IsNative() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(services.ErrNonNativeFilters.Error()))
+		return
+	}
+
+	entityPattern := r.URL.Query().Get("entity_pattern")
+
+	if r.URL.Query().Get("dry_run") == "true" {
+		matched, err := h.heartbeatSrvc.CountByUserWithinByFilters(user, params.From, params.To, params.Filters, entityPattern)
+		if err != nil {
+			conf.Log().Request(r).Error("failed to count heartbeats of user '%s' - %v", user.ID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(conf.ErrInternalServerError))
+			return
+		}
+		helpers.RespondJSON(w, r, http.StatusOK, &heartbeatDeleteResponseVm{Matched: matched, DryRun: true})
+		return
+	}
+
+	deleted, err := h.heartbeatSrvc.DeleteByUserWithinByFilters(user, params.From, params.To, params.Filters, entityPattern)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to delete heartbeats of user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	if deleted > 0 {
+		h.regenerateSummaries(r, user, params.From, params.To)
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, &heartbeatDeleteResponseVm{Deleted: deleted, Matched: deleted})
+}
+
+// populateHeartbeats assigns the heartbeats to the user and fills in client information from request headers, unless sent as part of the heartbeats themselves
+func populateHeartbeats(r *http.Request, user *models.User, heartbeats []*models.Heartbeat, heartbeatService services.IHeartbeatService) error {
+	userAgent := r.Header.Get("User-Agent")
+	opSys, editor, _ := utils.ParseUserAgent(userAgent)
+	machineName := r.Header.Get("X-Machine-Name")
+	remoteIp := middlewares.ClientIp(r)
+
+	for _, hb := range heartbeats {
+		if hb == nil {
+			return errors.New("invalid heartbeat object")
+		}
+
+		// TODO: unit test this
+		if hb.UserAgent != "" {
+			userAgent = hb.UserAgent
+			localOpSys, localEditor, _ := utils.ParseUserAgent(userAgent)
+			opSys = condition.TernaryOperator[bool, string](localOpSys != "", localOpSys, opSys)
+			editor = condition.TernaryOperator[bool, string](localEditor != "", localEditor, editor)
+		}
+		if hb.Machine != "" {
+			machineName = hb.Machine
+		}
+
+		if hb.Branch == models.LastBranchPlaceholder {
+			if latest, err := heartbeatService.GetLatestByFilters(user, models.NewFiltersWith(models.SummaryProject, hb.Project)); latest != nil && err == nil {
+				hb.Branch = latest.Branch
+			}
+		}
+
+		hb.User = user
+		hb.UserID = user.ID
+		hb.Machine = machineName
+		hb.OperatingSystem = opSys
+		hb.Editor = editor
+		hb.UserAgent = userAgent
+		hb.RemoteIp = remoteIp
+	}
+
+	return nil
+}
+
+func (h *HeartbeatApiHandler) regenerateSummaries(r *http.Request, user *models.User, from, to time.Time) {
+	if err := h.aggregationSrvc.RegenerateSummaries(user, from.Local(), to.Local()); err != nil {
+		conf.Log().Request(r).Error("failed to regenerate summaries for user '%s' - %v", user.ID, err)
+	}
+}
@@ -0,0 +1,299 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func filterBlockedProjects(projects ...string) func([]*models.Heartbeat) []*models.Heartbeat {
+	return func(heartbeats []*models.Heartbeat) []*models.Heartbeat {
+		return slice.Filter(heartbeats, func(_ int, hb *models.Heartbeat) bool {
+			return !slice.Contain(projects, hb.Project)
+		})
+	}
+}
+
+func setupHeartbeatApiHandler(user *models.User) (*chi.Mux, *mocks.HeartbeatServiceMock, *mocks.AggregationServiceMock) {
+	router := chi.NewRouter()
+	apiRouter := chi.NewRouter()
+	apiRouter.Use(middlewares.NewPrincipalMiddleware())
+	router.Mount("/api", apiRouter)
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByKey", user.ApiKey).Return(user, nil)
+
+	heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
+	aggregationServiceMock := new(mocks.AggregationServiceMock)
+	aggregationServiceMock.On("RegenerateSummaries", user, mock.Anything, mock.Anything).Return(nil)
+
+	NewHeartbeatApiHandler(userServiceMock, heartbeatServiceMock, nil, aggregationServiceMock, nil, services.NewQuotaService(heartbeatServiceMock)).RegisterRoutes(apiRouter)
+	return router, heartbeatServiceMock, aggregationServiceMock
+}
+
+func TestHeartbeatApiHandler_Post(t *testing.T) {
+	cfg := config.Empty()
+	cfg.App.HeartbeatMaxAge = "4320h"
+	config.Set(cfg)
+
+	user := &models.User{ID: "testuser", ApiKey: "testuser-key", HasData: true}
+	ts := time.Now().Add(-1 * time.Hour).Truncate(time.Second)
+
+	newHeartbeat := func(entity string) *models.Heartbeat {
+		return &models.Heartbeat{UserID: user.ID, Entity: entity, Type: "file", Project: "wakapi", Language: "Go", Time: models.CustomTime(ts)}
+	}
+	toJson := func(hb *models.Heartbeat, t time.Time) string {
+		return fmt.Sprintf(`{"entity": "%s", "type": "file", "project": "wakapi", "language": "Go", "time": %d}`, hb.Entity, t.Unix())
+	}
+
+	t.Run("when pushing partially invalid and duplicate heartbeats", func(t *testing.T) {
+		router, heartbeatServiceMock, _ := setupHeartbeatApiHandler(user)
+
+		newHb, existingHb := newHeartbeat("main.go"), newHeartbeat("existing.go")
+		heartbeatServiceMock.On("GetExistingHashes", mock.Anything).Return([]string{existingHb.Hashed().Hash}, nil)
+		heartbeatServiceMock.On("FilterBlocked", mock.Anything).Return(filterBlockedProjects())
+		heartbeatServiceMock.On("InsertBatch", mock.Anything).Return(nil)
+
+		body := fmt.Sprintf("[%s, %s, %s, %s]",
+			toJson(newHb, ts),
+			toJson(newHb, time.Time{}),
+			toJson(existingHb, ts),
+			toJson(newHb, ts),
+		)
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/users/current/heartbeats.bulk?api_key="+user.ApiKey, strings.NewReader(body))
+		router.ServeHTTP(rec, req)
+
+		var result heartbeatResponseVm
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(&result))
+		assert.Len(t, result.Responses, 4)
+		assert.Equal(t, float64(http.StatusCreated), result.Responses[0][1])
+		assert.Equal(t, float64(http.StatusBadRequest), result.Responses[1][1])
+		assert.Equal(t, float64(http.StatusConflict), result.Responses[2][1])
+		assert.Equal(t, float64(http.StatusConflict), result.Responses[3][1]) // duplicate within the same batch
+		assert.NotNil(t, result.Responses[1][0])
+
+		heartbeatServiceMock.AssertCalled(t, "InsertBatch", mock.MatchedBy(func(heartbeats []*models.Heartbeat) bool {
+			return len(heartbeats) == 1 && heartbeats[0].Entity == "main.go"
+		}))
+	})
+
+	t.Run("when pushing invalid heartbeats only", func(t *testing.T) {
+		router, heartbeatServiceMock, _ := setupHeartbeatApiHandler(user)
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/heartbeat?api_key="+user.ApiKey, strings.NewReader(toJson(newHeartbeat("main.go"), time.Time{})))
+		router.ServeHTTP(rec, req)
+
+		var result heartbeatResponseVm
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(&result))
+		assert.Len(t, result.Responses, 1)
+		heartbeatServiceMock.AssertNotCalled(t, "InsertBatch", mock.Anything)
+	})
+}
+
+func TestHeartbeatApiHandler_Post_Quotas(t *testing.T) {
+	cfg := config.Empty()
+	cfg.App.HeartbeatMaxAge = "4320h"
+	cfg.App.Quotas.Projects = 2
+	cfg.App.Quotas.WarnRatio = 0.8
+	config.Set(cfg)
+
+	user := &models.User{ID: "testuser", ApiKey: "testuser-key", HasData: true}
+	ts := time.Now().Add(-1 * time.Hour).Truncate(time.Second)
+
+	toJson := func(project string) string {
+		return fmt.Sprintf(`{"entity": "main.go", "type": "file", "project": "%s", "language": "Go", "time": %d}`, project, ts.Unix())
+	}
+	post := func(router *chi.Mux, body string) (*httptest.ResponseRecorder, heartbeatResponseVm) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/users/current/heartbeats.bulk?api_key="+user.ApiKey, strings.NewReader(body))
+		router.ServeHTTP(rec, req)
+
+		var result heartbeatResponseVm
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(&result))
+		return rec, result
+	}
+
+	t.Run("when exceeding the project quota", func(t *testing.T) {
+		router, heartbeatServiceMock, _ := setupHeartbeatApiHandler(user)
+		heartbeatServiceMock.On("GetExistingHashes", mock.Anything).Return([]string{}, nil)
+		heartbeatServiceMock.On("FilterBlocked", mock.Anything).Return(filterBlockedProjects())
+		heartbeatServiceMock.On("GetEntitySetByUser", models.SummaryProject, user.ID).Return([]string{"wakapi"}, nil)
+		heartbeatServiceMock.On("InsertBatch", mock.Anything).Return(nil)
+
+		rec, result := post(router, fmt.Sprintf("[%s, %s, %s]", toJson("wakapi"), toJson("anchr"), toJson("mailwhale")))
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, float64(http.StatusCreated), result.Responses[0][1])
+		assert.Equal(t, float64(http.StatusCreated), result.Responses[1][1])
+		assert.Equal(t, float64(http.StatusTooManyRequests), result.Responses[2][1])
+		assert.Equal(t, "2 of 2 projects", rec.Header().Get(headerQuotaWarning))
+
+		heartbeatServiceMock.AssertCalled(t, "InsertBatch", mock.MatchedBy(func(heartbeats []*models.Heartbeat) bool {
+			return len(heartbeats) == 2
+		}))
+	})
+
+	t.Run("when all heartbeats exceed the project quota", func(t *testing.T) {
+		router, heartbeatServiceMock, _ := setupHeartbeatApiHandler(user)
+		heartbeatServiceMock.On("GetExistingHashes", mock.Anything).Return([]string{}, nil)
+		heartbeatServiceMock.On("FilterBlocked", mock.Anything).Return(filterBlockedProjects())
+		heartbeatServiceMock.On("GetEntitySetByUser", models.SummaryProject, user.ID).Return([]string{"wakapi", "anchr"}, nil)
+
+		rec, result := post(router, fmt.Sprintf("[%s]", toJson("mailwhale")))
+
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, float64(http.StatusTooManyRequests), result.Responses[0][1])
+		heartbeatServiceMock.AssertNotCalled(t, "InsertBatch", mock.Anything)
+	})
+
+	t.Run("when blocked heartbeats would exceed the project quota", func(t *testing.T) {
+		router, heartbeatServiceMock, _ := setupHeartbeatApiHandler(user)
+		heartbeatServiceMock.On("GetExistingHashes", mock.Anything).Return([]string{}, nil)
+		heartbeatServiceMock.On("FilterBlocked", mock.Anything).Return(filterBlockedProjects("anchr"))
+		heartbeatServiceMock.On("GetEntitySetByUser", models.SummaryProject, user.ID).Return([]string{"wakapi"}, nil)
+		heartbeatServiceMock.On("InsertBatch", mock.Anything).Return(nil)
+
+		rec, result := post(router, fmt.Sprintf("[%s, %s]", toJson("anchr"), toJson("mailwhale")))
+
+		// blocked heartbeats are acknowledged, but don't take up any quota
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, float64(http.StatusCreated), result.Responses[0][1])
+		assert.Equal(t, float64(http.StatusCreated), result.Responses[1][1])
+
+		heartbeatServiceMock.AssertCalled(t, "InsertBatch", mock.MatchedBy(func(heartbeats []*models.Heartbeat) bool {
+			return len(heartbeats) == 1 && heartbeats[0].Project == "mailwhale"
+		}))
+	})
+}
+
+func TestHeartbeatApiHandler_DeleteMany(t *testing.T) {
+	config.Set(config.Empty())
+
+	user := &models.User{ID: "testuser", ApiKey: "testuser-key"}
+
+	serve := func(router *chi.Mux, query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/api/users/current/heartbeats?api_key="+user.ApiKey+"&from=2023-01-01&to=2023-01-08"+query, nil)
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("when deleting by project and entity pattern", func(t *testing.T) {
+		router, heartbeatServiceMock, aggregationServiceMock := setupHeartbeatApiHandler(user)
+		heartbeatServiceMock.On("DeleteByUserWithinByFilters", user, mock.Anything, mock.Anything, mock.Anything, "*/private/*").Return(3, nil)
+
+		rec := serve(router, "&project=wakapi&entity_pattern=*/private/*")
+
+		var result heartbeatDeleteResponseVm
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(&result))
+		assert.Equal(t, int64(3), result.Deleted)
+		heartbeatServiceMock.AssertCalled(t, "DeleteByUserWithinByFilters", user, mock.Anything, mock.Anything, mock.MatchedBy(func(f *models.Filters) bool {
+			return len(f.Project) == 1 && f.Project[0] == "wakapi"
+		}), "*/private/*")
+		aggregationServiceMock.AssertCalled(t, "RegenerateSummaries", user, mock.Anything, mock.Anything)
+	})
+
+	t.Run("when filtering by label", func(t *testing.T) {
+		router, heartbeatServiceMock, _ := setupHeartbeatApiHandler(user)
+
+		rec := serve(router, "&label=oss")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		heartbeatServiceMock.AssertNotCalled(t, "DeleteByUserWithinByFilters", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("when doing a dry run", func(t *testing.T) {
+		router, heartbeatServiceMock, aggregationServiceMock := setupHeartbeatApiHandler(user)
+		heartbeatServiceMock.On("CountByUserWithinByFilters", user, mock.Anything, mock.Anything, mock.Anything, "").Return(5, nil)
+
+		rec := serve(router, "&project=wakapi&dry_run=true")
+
+		var result heartbeatDeleteResponseVm
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(&result))
+		assert.Equal(t, int64(5), result.Matched)
+		assert.Equal(t, int64(0), result.Deleted)
+		assert.True(t, result.DryRun)
+		heartbeatServiceMock.AssertNotCalled(t, "DeleteByUserWithinByFilters", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		aggregationServiceMock.AssertNotCalled(t, "RegenerateSummaries", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("when nothing was deleted", func(t *testing.T) {
+		router, heartbeatServiceMock, aggregationServiceMock := setupHeartbeatApiHandler(user)
+		heartbeatServiceMock.On("DeleteByUserWithinByFilters", user, mock.Anything, mock.Anything, mock.Anything, "").Return(0, nil)
+
+		rec := serve(router, "")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		aggregationServiceMock.AssertNotCalled(t, "RegenerateSummaries", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestHeartbeatApiHandler_Patch(t *testing.T) {
+	config.Set(config.Empty())
+
+	user := &models.User{ID: "testuser", ApiKey: "testuser-key"}
+
+	serve := func(router *chi.Mux, id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/api/users/current/heartbeats/"+id+"?api_key="+user.ApiKey, strings.NewReader(body))
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("when changing the project", func(t *testing.T) {
+		heartbeat := &models.Heartbeat{ID: 1, UserID: user.ID, Project: "private", Language: "Go", Time: models.CustomTime(time.Now())}
+
+		router, heartbeatServiceMock, aggregationServiceMock := setupHeartbeatApiHandler(user)
+		heartbeatServiceMock.On("GetByUserAndId", user, uint64(1)).Return(heartbeat, nil)
+		heartbeatServiceMock.On("Update", heartbeat).Return(nil)
+
+		rec := serve(router, "1", `{"project": "public"}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "public", heartbeat.Project)
+		assert.Equal(t, "Go", heartbeat.Language)
+		heartbeatServiceMock.AssertCalled(t, "Update", heartbeat)
+		aggregationServiceMock.AssertCalled(t, "RegenerateSummaries", user, mock.Anything, mock.Anything)
+	})
+
+	t.Run("when heartbeat does not exist", func(t *testing.T) {
+		router, heartbeatServiceMock, _ := setupHeartbeatApiHandler(user)
+		heartbeatServiceMock.On("GetByUserAndId", user, uint64(2)).Return((*models.Heartbeat)(nil), errors.New("not found"))
+
+		rec := serve(router, "2", `{"project": "public"}`)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		heartbeatServiceMock.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("when nothing is to be changed", func(t *testing.T) {
+		router, heartbeatServiceMock, _ := setupHeartbeatApiHandler(user)
+
+		rec := serve(router, "1", `{}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		heartbeatServiceMock.AssertNotCalled(t, "GetByUserAndId", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+// HomeAssistantViewModel is a flat object, such that every field can directly be mapped to a sensor state or attribute in home assistant's rest integration
+// all fields are always present (i.e. no omitempty) to not break templates referring to them while not coding
+type HomeAssistantViewModel struct {
+	Today           int64      `json:"today"`       // seconds
+	TodayHours      float64    `json:"today_hours"` // rounded to two decimals
+	TodayText       string     `json:"today_text"`  // e.g. "2 hrs 15 mins"
+	Active          bool       `json:"active"`
+	Project         string     `json:"project"`
+	Language        string     `json:"language"`
+	Editor          string     `json:"editor"`
+	SessionSeconds  int64      `json:"session_seconds"`
+	LastHeartbeatAt *time.Time `json:"last_heartbeat_at"` // rfc 3339, as expected by home assistant's timestamp device class
+}
+
+type HomeAssistantApiHandler struct {
+	config          *conf.Config
+	userSrvc        services.IUserService
+	summarySrvc     services.ISummaryService
+	presenceSrvc    services.IPresenceService
+	accessTokenSrvc services.IAccessTokenService
+}
+
+func NewHomeAssistantApiHandler(userService services.IUserService, summaryService services.ISummaryService, presenceService services.IPresenceService, accessTokenService services.IAccessTokenService) *HomeAssistantApiHandler {
+	return &HomeAssistantApiHandler{
+		config:          conf.Get(),
+		userSrvc:        userService,
+		summarySrvc:     summaryService,
+		presenceSrvc:    presenceService,
+		accessTokenSrvc: accessTokenService,
+	}
+}
+
+func (h *HomeAssistantApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAccessTokens(h.accessTokenSrvc, models.ScopeReadStats).Handler)
+		r.Get("/homeassistant", h.Get)
+	})
+}
+
+// @Summary Retrieve today's coding time and what the user is currently working on for home assistant rest sensors
+// @Description Flat response, whose fields can be mapped to sensor states and attributes one by one. Best used with a read-only access token.
+// @ID get-homeassistant
+// @Tags homeassistant
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} HomeAssistantViewModel
+// @Router /homeassistant [get]
+func (h *HomeAssistantApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+
+	vm, err := h.buildViewModel(user)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to build home assistant view model for user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache") // meant to be polled frequently
+	helpers.RespondJSON(w, r, http.StatusOK, vm)
+}
+
+func (h *HomeAssistantApiHandler) buildViewModel(user *models.User) (*HomeAssistantViewModel, error) {
+	err, from, to := helpers.ResolveIntervalTZ(models.IntervalToday, user.TZ())
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := h.summarySrvc.Aliased(from, to, user, h.summarySrvc.Retrieve, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	presence, err := h.presenceSrvc.GetByUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	today := summary.TotalTime()
+	vm := &HomeAssistantViewModel{
+		Today:          int64(today.Seconds()),
+		TodayHours:     math.Round(today.Hours()*100) / 100,
+		TodayText:      helpers.FmtWakatimeDuration(today),
+		Active:         presence.Active,
+		Project:        presence.Project,
+		Language:       presence.Language,
+		Editor:         presence.Editor,
+		SessionSeconds: presence.SessionSeconds,
+	}
+
+	if presence.LastHeartbeatAt != nil {
+		lastHeartbeatAt := presence.LastHeartbeatAt.T().In(user.TZ())
+		vm.LastHeartbeatAt = &lastHeartbeatAt
+	}
+
+	return vm, nil
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHomeAssistantApiHandler_buildViewModel(t *testing.T) {
+	config.Set(config.Empty())
+
+	summaryServiceMock := new(mocks.SummaryServiceMock)
+	summaryServiceMock.On("Aliased", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), &user1, mock.Anything, mock.Anything).Return(&summary1, nil)
+
+	presenceServiceMock := new(mocks.PresenceServiceMock)
+	sut := NewHomeAssistantApiHandler(new(mocks.UserServiceMock), summaryServiceMock, presenceServiceMock, nil)
+
+	t.Run("when not coding", func(t *testing.T) {
+		presenceServiceMock.On("GetByUser", &user1).Return(&models.Presence{}, nil).Once()
+
+		vm, err := sut.buildViewModel(&user1)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(12*60), vm.Today)
+		assert.Equal(t, 0.2, vm.TodayHours)
+		assert.Equal(t, "0 hrs 12 mins", vm.TodayText)
+		assert.False(t, vm.Active)
+		assert.Empty(t, vm.Project)
+		assert.Nil(t, vm.LastHeartbeatAt)
+	})
+
+	t.Run("when coding", func(t *testing.T) {
+		lastHeartbeatAt := models.CustomTime(time.Date(2023, 3, 14, 12, 0, 0, 0, time.UTC))
+		presenceServiceMock.On("GetByUser", &user1).Return(&models.Presence{
+			Active:          true,
+			Project:         "wakapi",
+			Language:        "Go",
+			Editor:          "vscode",
+			SessionSeconds:  600,
+			LastHeartbeatAt: &lastHeartbeatAt,
+		}, nil).Once()
+
+		vm, err := sut.buildViewModel(&user1)
+		assert.Nil(t, err)
+		assert.True(t, vm.Active)
+		assert.Equal(t, "wakapi", vm.Project)
+		assert.Equal(t, "Go", vm.Language)
+		assert.Equal(t, "vscode", vm.Editor)
+		assert.Equal(t, int64(600), vm.SessionSeconds)
+		assert.True(t, vm.LastHeartbeatAt.Equal(lastHeartbeatAt.T()))
+	})
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type LabelRuleApiHandler struct {
+	config        *conf.Config
+	userSrvc      services.IUserService
+	labelRuleSrvc services.ILabelRuleService
+}
+
+func NewLabelRuleApiHandler(userService services.IUserService, labelRuleService services.ILabelRuleService) *LabelRuleApiHandler {
+	return &LabelRuleApiHandler{
+		config:        conf.Get(),
+		userSrvc:      userService,
+		labelRuleSrvc: labelRuleService,
+	}
+}
+
+func (h *LabelRuleApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/users/{user}/settings/label_rules", h.GetAll)
+		r.Post("/users/{user}/settings/label_rules", h.Post)
+		r.Delete("/users/{user}/settings/label_rules/{id}", h.Delete)
+		r.Post("/users/{user}/settings/label_rules/backfill", h.PostBackfill)
+	})
+}
+
+// @Summary Retrieve a user's automatic labeling rules
+// @ID get-label-rules
+// @Tags settings
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.LabelRule
+// @Router /users/{user}/settings/label_rules [get]
+func (h *LabelRuleApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	rules, err := h.labelRuleSrvc.GetByUser(user.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to fetch label rules for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, rules)
+}
+
+// @Summary Add an automatic labeling rule
+// @Description Assigns the given label to every project, for which a newly received heartbeat's project name, entity (file path) or branch matches the given regular expression. Use /users/{user}/settings/label_rules/backfill to apply rules to existing data as well.
+// @ID post-label-rule
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param rule body models.LabelRule true "Field (one of project, entity, branch), pattern and label"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.LabelRule
+// @Router /users/{user}/settings/label_rules [post]
+func (h *LabelRuleApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var rule models.LabelRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+	rule.ID = 0
+	rule.UserID = user.ID
+	rule.Label = strings.TrimSpace(rule.Label)
+
+	if !rule.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid rule - unknown field or invalid regular expression?"))
+		return
+	}
+
+	result, err := h.labelRuleSrvc.Create(&rule)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to create label rule for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Delete an automatic labeling rule
+// @Description Labels previously assigned by the rule are kept.
+// @ID delete-label-rule
+// @Tags settings
+// @Param user path string true "Username (or current)"
+// @Param id path int true "Rule ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /users/{user}/settings/label_rules/{id} [delete]
+func (h *LabelRuleApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	rule, err := h.labelRuleSrvc.GetById(uint(id))
+	if err != nil || rule == nil || rule.UserID != user.ID {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	if err := h.labelRuleSrvc.Delete(rule); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to delete label rule %d for user '%s' - %v", rule.ID, user.ID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Apply automatic labeling rules to existing data
+// @Description Assigns labels according to all of the user's rules to projects of their existing heartbeats in the background.
+// @ID post-label-rules-backfill
+// @Tags settings
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /users/{user}/settings/label_rules/backfill [post]
+func (h *LabelRuleApiHandler) PostBackfill(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	if err := h.labelRuleSrvc.Backfill(user); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to schedule label rule backfill for user '%s' - %v", user.ID, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
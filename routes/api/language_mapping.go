@@ -0,0 +1,217 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type LanguageMappingApiHandler struct {
+	config              *conf.Config
+	userSrvc            services.IUserService
+	languageMappingSrvc services.ILanguageMappingService
+	reprocessingSrvc    services.IReprocessingService
+}
+
+type languageMappingsResponseVm struct {
+	Defaults map[string]string         `json:"defaults"`
+	Mappings []*models.LanguageMapping `json:"mappings"`
+}
+
+func NewLanguageMappingApiHandler(userService services.IUserService, languageMappingService services.ILanguageMappingService, reprocessingService services.IReprocessingService) *LanguageMappingApiHandler {
+	return &LanguageMappingApiHandler{
+		config:              conf.Get(),
+		userSrvc:            userService,
+		languageMappingSrvc: languageMappingService,
+		reprocessingSrvc:    reprocessingService,
+	}
+}
+
+func (h *LanguageMappingApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/users/{user}/settings/language_mappings", h.GetAll)
+		r.Post("/users/{user}/settings/language_mappings", h.Post)
+		r.Delete("/users/{user}/settings/language_mappings/{id}", h.Delete)
+		r.Post("/users/{user}/settings/reprocess", h.PostReprocess)
+		r.Get("/users/{user}/settings/reprocess", h.GetReprocess)
+		r.Post("/users/{user}/settings/language_mappings/reprocess", h.PostReprocess) // legacy
+		r.Get("/users/{user}/settings/language_mappings/reprocess", h.GetReprocess)   // legacy
+	})
+}
+
+// @Summary Retrieve a user's language mappings
+// @Description Returns the user's own extension to language mappings as well as the server-wide defaults, which the user's mappings take precedence over
+// @ID get-language-mappings
+// @Tags settings
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 200 {object} languageMappingsResponseVm
+// @Router /users/{user}/settings/language_mappings [get]
+func (h *LanguageMappingApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	mappings, err := h.languageMappingSrvc.GetByUser(user.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to fetch language mappings for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, &languageMappingsResponseVm{
+		Defaults: h.languageMappingSrvc.GetDefaults(),
+		Mappings: mappings,
+	})
+}
+
+// @Summary Add a language mapping
+// @Description Maps a file extension to a language for all heartbeats received from now on. It is applied to existing data a few minutes later, or immediately via /users/{user}/settings/reprocess.
+// @ID post-language-mapping
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param mapping body models.LanguageMapping true "Extension and language"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.LanguageMapping
+// @Router /users/{user}/settings/language_mappings [post]
+func (h *LanguageMappingApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var mapping models.LanguageMapping
+	if err := json.NewDecoder(r.Body).Decode(&mapping); err != nil || !mapping.Normalize().IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+	mapping.UserID = user.ID
+
+	result, err := h.languageMappingSrvc.Create(&mapping)
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("mapping already exists"))
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Delete a language mapping
+// @ID delete-language-mapping
+// @Tags settings
+// @Param user path string true "Username (or current)"
+// @Param id path int true "Mapping ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /users/{user}/settings/language_mappings/{id} [delete]
+func (h *LanguageMappingApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	mapping, err := h.languageMappingSrvc.GetById(uint(id))
+	if err != nil || mapping == nil || mapping.UserID != user.ID {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	if err := h.languageMappingSrvc.Delete(mapping); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to delete language mapping %d for user '%s' - %v", mapping.ID, user.ID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Apply current rules to existing data
+// @Description Re-applies language mappings, language detection, category and branch extraction and label rules to all of the user's existing heartbeats and re-generates summaries in the background. Also happens automatically a few minutes after language mappings or label rules were changed. Progress can be tracked via GET on the same endpoint.
+// @ID post-reprocess
+// @Tags settings
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 202
+// @Failure 409
+// @Router /users/{user}/settings/reprocess [post]
+func (h *LanguageMappingApiHandler) PostReprocess(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	if err := h.reprocessingSrvc.Reprocess(user); err != nil {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// @Summary Retrieve the progress of the latest re-processing
+// @ID get-reprocess
+// @Tags settings
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ReprocessingJob
+// @Router /users/{user}/settings/reprocess [get]
+func (h *LanguageMappingApiHandler) GetReprocess(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, h.reprocessingSrvc.GetJob(user))
+}
+
+// Only for Swagger
+
+// @Summary Apply current rules to existing data
+// @Description Same as POST /users/{user}/settings/reprocess, kept for backwards compatibility
+// @ID post-reprocess-2
+// @Tags settings
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 202
+// @Failure 409
+// @Deprecated
+// @Router /users/{user}/settings/language_mappings/reprocess [post]
+func (h *LanguageMappingApiHandler) postReprocessAlias() {}
+
+// @Summary Retrieve the progress of the latest re-processing
+// @Description Same as GET /users/{user}/settings/reprocess, kept for backwards compatibility
+// @ID get-reprocess-2
+// @Tags settings
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ReprocessingJob
+// @Deprecated
+// @Router /users/{user}/settings/language_mappings/reprocess [get]
+func (h *LanguageMappingApiHandler) getReprocessAlias() {}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type MachineApiHandler struct {
+	config      *conf.Config
+	userSrvc    services.IUserService
+	machineSrvc services.IMachineService
+}
+
+func NewMachineApiHandler(userService services.IUserService, machineService services.IMachineService) *MachineApiHandler {
+	return &MachineApiHandler{
+		config:      conf.Get(),
+		userSrvc:    userService,
+		machineSrvc: machineService,
+	}
+}
+
+func (h *MachineApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/users/{user}/machines", h.GetAll)
+	})
+}
+
+// @Summary Retrieve the machines a user sent heartbeats from
+// @Description Lists every machine along with when, with which operating system and from which ip address (and location, if enabled) it was last seen, most recently seen first. Helps to notice an api key being used from unexpected places.
+// @ID get-machines
+// @Tags user
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Machine
+// @Router /users/{user}/machines [get]
+func (h *MachineApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	machines, err := h.machineSrvc.GetByUser(user.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to fetch machines for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, machines)
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/go-chi/chi/v5"
+	"github.com/leandro-lugaresi/hub"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/utils"
+)
+
+const maxMailNotificationSize = 256 * 1024
+
+// MailNotificationApiHandler receives bounce and complaint notifications about sent mails, published by amazon ses through sns
+type MailNotificationApiHandler struct {
+	config     *conf.Config
+	eventBus   *hub.Hub
+	httpClient *http.Client
+	verifier   *utils.SnsVerifier
+}
+
+// sesNotification is an ses bounce or complaint notification (or event, if published through a configuration set)
+// see https://docs.aws.amazon.com/ses/latest/dg/notification-contents.html
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	EventType        string `json:"eventType"`
+	Bounce           struct {
+		BounceType        string `json:"bounceType"`
+		BouncedRecipients []struct {
+			EmailAddress string `json:"emailAddress"`
+		} `json:"bouncedRecipients"`
+	} `json:"bounce"`
+	Complaint struct {
+		ComplainedRecipients []struct {
+			EmailAddress string `json:"emailAddress"`
+		} `json:"complainedRecipients"`
+	} `json:"complaint"`
+}
+
+func NewMailNotificationApiHandler() *MailNotificationApiHandler {
+	httpClient := conf.NewOutboundClient(utils.OutboundPriorityNormal, 10*time.Second)
+	return &MailNotificationApiHandler{
+		config:     conf.Get(),
+		eventBus:   conf.EventBus(),
+		httpClient: httpClient,
+		verifier:   utils.NewSnsVerifier(httpClient),
+	}
+}
+
+func (h *MailNotificationApiHandler) RegisterRoutes(router chi.Router) {
+	router.Post("/mail/ses/notifications", h.PostSes)
+}
+
+// @Summary Receive amazon ses bounce and complaint notifications
+// @Description Endpoint for an sns subscription to the topic configured as mail.ses.notification_topic_arn. Recipients of permanent bounces and complaints have their e-mail address marked as unverified and their reports disabled.
+// @ID post-mail-ses-notification
+// @Tags misc
+// @Accept json
+// @Success 200
+// @Router /mail/ses/notifications [post]
+func (h *MailNotificationApiHandler) PostSes(w http.ResponseWriter, r *http.Request) {
+	topicArn := conf.Get().Mail.Ses.NotificationTopicArn
+	if topicArn == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxMailNotificationSize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	message, err := utils.ParseSnsMessage(payload)
+	if err != nil || message.TopicArn != topicArn {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := h.verifier.Verify(message); err != nil {
+		conf.Log().Request(r).Warn("got sns message '%s' with invalid signature - %v", message.MessageId, err)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	switch message.Type {
+	case utils.SnsTypeSubscriptionConfirmation:
+		if !utils.IsSnsUrl(message.SubscribeURL) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		res, err := utils.RaiseForStatus(h.httpClient.Get(message.SubscribeURL))
+		if err != nil {
+			conf.Log().Request(r).Error("failed to confirm subscription to sns topic '%s' - %v", message.TopicArn, err)
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		res.Body.Close()
+		logbuch.Info("confirmed subscription to sns topic '%s'", message.TopicArn)
+	case utils.SnsTypeNotification:
+		var notification sesNotification
+		if err := json.Unmarshal([]byte(message.Message), &notification); err != nil {
+			conf.Log().Request(r).Warn("failed to parse ses notification '%s' - %v", message.MessageId, err)
+			break // don't make sns retry the message
+		}
+		for _, address := range notification.suppressedAddresses() {
+			logbuch.Info("received ses %s notification for '%s'", strings.ToLower(notification.kind()), address)
+			h.eventBus.Publish(hub.Message{
+				Name:   conf.EventMailBounce,
+				Fields: map[string]interface{}{conf.FieldPayload: address},
+			})
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (n *sesNotification) kind() string {
+	if n.NotificationType != "" {
+		return n.NotificationType
+	}
+	return n.EventType
+}
+
+// suppressedAddresses returns the recipients, which no more mail should be sent to, i.e. ones that permanently bounced or complained
+// transient bounces (e.g. full mailboxes) are ignored
+func (n *sesNotification) suppressedAddresses() []string {
+	addresses := make([]string, 0)
+	switch n.kind() {
+	case "Bounce":
+		if n.Bounce.BounceType != "Permanent" {
+			break
+		}
+		for _, r := range n.Bounce.BouncedRecipients {
+			addresses = append(addresses, r.EmailAddress)
+		}
+	case "Complaint":
+		for _, r := range n.Complaint.ComplainedRecipients {
+			addresses = append(addresses, r.EmailAddress)
+		}
+	}
+	return addresses
+}
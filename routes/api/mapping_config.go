@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	mappingConfigMaxBytes  = 1 << 20
+	mappingConfigYamlType  = "application/yaml"
+	mappingConfigFormatArg = "format"
+)
+
+type MappingConfigApiHandler struct {
+	config            *conf.Config
+	userSrvc          services.IUserService
+	mappingConfigSrvc services.IMappingConfigService
+}
+
+func NewMappingConfigApiHandler(userService services.IUserService, mappingConfigService services.IMappingConfigService) *MappingConfigApiHandler {
+	return &MappingConfigApiHandler{
+		config:            conf.Get(),
+		userSrvc:          userService,
+		mappingConfigSrvc: mappingConfigService,
+	}
+}
+
+func (h *MappingConfigApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/users/{user}/settings/mappings", h.Get)
+		r.Post("/users/{user}/settings/mappings", h.Post)
+	})
+}
+
+// @Summary Export a user's aliases, project labels and labeling rules
+// @ID get-mapping-config
+// @Tags settings
+// @Produce json
+// @Produce application/yaml
+// @Param user path string true "Username (or current)"
+// @Param format query string false "Output format, either json (default) or yaml"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.MappingConfig
+// @Router /users/{user}/settings/mappings [get]
+func (h *MappingConfigApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	mappingConfig, err := h.mappingConfigSrvc.Export(user)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to export mapping config for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	if r.URL.Query().Get(mappingConfigFormatArg) != "yaml" {
+		helpers.RespondJSON(w, r, http.StatusOK, mappingConfig)
+		return
+	}
+
+	data, err := yaml.Marshal(mappingConfig)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to encode mapping config for user '%s' - %v", user.ID, err)
+		return
+	}
+	w.Header().Set("Content-Type", mappingConfigYamlType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// @Summary Import aliases, project labels and labeling rules
+// @Description Adds all aliases, labels and rules from the given configuration, which do not exist yet. With mode "replace", all existing ones are deleted first. Accepts JSON or, if sent with a YAML content type, YAML in the format produced by the export.
+// @ID post-mapping-config
+// @Tags settings
+// @Accept json
+// @Accept application/yaml
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param mode query string false "Either merge (default) or replace"
+// @Param config body models.MappingConfig true "Mapping configuration"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.MappingConfigImportResult
+// @Router /users/{user}/settings/mappings [post]
+func (h *MappingConfigApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var mappingConfig models.MappingConfig
+	body := http.MaxBytesReader(w, r.Body, mappingConfigMaxBytes)
+	if strings.Contains(r.Header.Get("Content-Type"), "yaml") {
+		err = yaml.NewDecoder(body).Decode(&mappingConfig)
+	} else {
+		err = json.NewDecoder(body).Decode(&mappingConfig)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	if err := mappingConfig.Validate(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	result, err := h.mappingConfigSrvc.Import(user, &mappingConfig, r.URL.Query().Get("mode") == "replace")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to import mapping config for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, result)
+}
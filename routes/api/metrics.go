@@ -1,7 +1,6 @@
 package api
 
 import (
-	"errors"
 	"github.com/alitto/pond"
 	"github.com/emvi/logbuch"
 	"github.com/go-chi/chi/v5"
@@ -40,10 +39,18 @@ const (
 	DescAdminUserTime        = "Total tracked activity in seconds (all time) (active users only)."
 	DescAdminTotalUsers      = "Total number of registered users."
 	DescAdminActiveUsers     = "Number of active users."
+	DescAdminActivityClass   = "Number of users active within the last day, week or month."
 
 	DescJobQueueEnqueued      = "Number of jobs currently enqueued"
 	DescJobQueueTotalFinished = "Total number of processed jobs"
 
+	DescOutboundActive    = "Number of currently running outbound requests by destination host."
+	DescOutboundRequests  = "Total number of outbound requests by destination host."
+	DescOutboundFailures  = "Total number of failed outbound requests by destination host."
+	DescOutboundThrottled = "Total number of rate-limited outbound requests by destination host."
+	DescOutboundRejected  = "Total number of outbound requests refused due to an open circuit by destination host."
+	DescOutboundOpen      = "Whether the circuit for a destination host is open."
+
 	DescMemAllocTotal = "Total number of bytes allocated for heap"
 	DescMemSysTotal   = "Total number of bytes obtained from the OS"
 	DescPausedTotal   = "Total nanoseconds stop-the-world pause time due to GC"
@@ -107,8 +114,9 @@ func (h *MetricsHandler) Get(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if reqUser.IsAdmin {
-		if adminMetrics, err := h.getAdminMetrics(reqUser); err != nil {
+	// admin metrics reveal instance-wide information, thus are only included for requests from allowlisted ips
+	if reqUser.IsAdmin && middlewares.IsAdminIpAllowed(r) {
+		if adminMetrics, err := h.getAdminMetrics(); err != nil {
 			conf.Log().Request(r).Error("%v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte(conf.ErrInternalServerError))
@@ -226,6 +234,15 @@ func (h *MetricsHandler) getUserMetrics(user *models.User) (*mm.Metrics, error)
 		})
 	}
 
+	metrics = append(metrics, *h.getSystemMetrics()...)
+
+	return &metrics, nil
+}
+
+// getSystemMetrics returns metrics about the process, database and job queues
+func (h *MetricsHandler) getSystemMetrics() *mm.Metrics {
+	var metrics mm.Metrics
+
 	// Runtime metrics
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
@@ -295,19 +312,16 @@ func (h *MetricsHandler) getUserMetrics(user *models.User) (*mm.Metrics, error)
 		})
 	}
 
-	return &metrics, nil
+	return &metrics
 }
 
-func (h *MetricsHandler) getAdminMetrics(user *models.User) (*mm.Metrics, error) {
+// getAdminMetrics returns instance-wide metrics, only to be exposed to admins
+func (h *MetricsHandler) getAdminMetrics() (*mm.Metrics, error) {
 	var metrics mm.Metrics
 
 	t0 := time.Now()
 	logbuch.Debug("[metrics] start admin metrics calculation")
 
-	if !user.IsAdmin {
-		return nil, errors.New("unauthorized")
-	}
-
 	var totalSeconds int
 	if t, err := h.keyValueSrvc.GetString(conf.KeyLatestTotalTime); err == nil && t != nil && t.Value != "" {
 		if d, err := time.ParseDuration(t.Value); err == nil {
@@ -354,6 +368,20 @@ func (h *MetricsHandler) getAdminMetrics(user *models.User) (*mm.Metrics, error)
 		Labels: []mm.Label{},
 	})
 
+	activeUserCounts := loadActiveUserCounts(h.keyValueSrvc)
+	for _, class := range models.AllActivityClasses() {
+		count, ok := activeUserCounts[class]
+		if !ok {
+			continue
+		}
+		metrics = append(metrics, &mm.GaugeMetric{
+			Name:   MetricsPrefix + "_admin_users_active_by_class",
+			Desc:   DescAdminActivityClass,
+			Value:  count,
+			Labels: []mm.Label{{Key: "class", Value: string(class)}},
+		})
+	}
+
 	// Count per-user heartbeats
 
 	userCounts, err := h.heartbeatSrvc.CountByUsers(activeUsers)
@@ -401,5 +429,20 @@ func (h *MetricsHandler) getAdminMetrics(user *models.User) (*mm.Metrics, error)
 	wp.StopAndWait()
 	logbuch.Debug("[metrics] finished retrieving total activity time by user after %v", time.Now().Sub(t0))
 
+	for _, om := range conf.GetOutboundDispatcher().Metrics() {
+		labels := []mm.Label{{Key: "host", Value: om.Host}}
+		var open int64
+		if om.Open {
+			open = 1
+		}
+
+		metrics = append(metrics, &mm.GaugeMetric{Name: MetricsPrefix + "_admin_outbound_requests_active", Desc: DescOutboundActive, Value: int64(om.Active), Labels: labels})
+		metrics = append(metrics, &mm.CounterMetric{Name: MetricsPrefix + "_admin_outbound_requests_total", Desc: DescOutboundRequests, Value: om.Requests, Labels: labels})
+		metrics = append(metrics, &mm.CounterMetric{Name: MetricsPrefix + "_admin_outbound_requests_failed_total", Desc: DescOutboundFailures, Value: om.Failures, Labels: labels})
+		metrics = append(metrics, &mm.CounterMetric{Name: MetricsPrefix + "_admin_outbound_requests_throttled_total", Desc: DescOutboundThrottled, Value: om.Throttled, Labels: labels})
+		metrics = append(metrics, &mm.CounterMetric{Name: MetricsPrefix + "_admin_outbound_requests_rejected_total", Desc: DescOutboundRejected, Value: om.Rejected, Labels: labels})
+		metrics = append(metrics, &mm.GaugeMetric{Name: MetricsPrefix + "_admin_outbound_circuit_open", Desc: DescOutboundOpen, Value: open, Labels: labels})
+	}
+
 	return &metrics, nil
 }
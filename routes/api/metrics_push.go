@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emvi/logbuch"
+	conf "github.com/muety/wakapi/config"
+	mm "github.com/muety/wakapi/models/metrics"
+	"github.com/muety/wakapi/utils"
+)
+
+// SchedulePush periodically pushes the operator metrics (system and admin metrics, no per-user ones) to a pushgateway or remote write endpoint, if configured
+// This is independent of whether metrics are exposed for scraping
+func (h *MetricsHandler) SchedulePush() {
+	pushConfig := h.config.Security.MetricsPush
+	if !pushConfig.Enabled() {
+		return
+	}
+
+	logbuch.Info("scheduling metrics push to %s via %s", pushConfig.Url, pushConfig.Mode)
+	if _, err := conf.GetDefaultQueue().DispatchEvery(func() {
+		if err := h.PushMetrics(); err != nil {
+			conf.Log().Error("failed to push metrics - %v", err)
+		}
+	}, pushConfig.Interval()); err != nil {
+		conf.Log().Error("failed to schedule metrics push, %v", err)
+	}
+}
+
+func (h *MetricsHandler) PushMetrics() error {
+	metrics := *h.getSystemMetrics()
+	adminMetrics, err := h.getAdminMetrics()
+	if err != nil {
+		return err
+	}
+	metrics = append(metrics, *adminMetrics...)
+	sort.Sort(metrics)
+
+	var req *http.Request
+	if h.config.Security.MetricsPush.Mode == conf.MetricsPushModeRemoteWrite {
+		req, err = h.newRemoteWriteRequest(metrics)
+	} else {
+		req, err = h.newPushgatewayRequest(metrics)
+	}
+	if err != nil {
+		return err
+	}
+
+	if username := h.config.Security.MetricsPush.Username; username != "" {
+		req.SetBasicAuth(username, h.config.Security.MetricsPush.Password)
+	}
+
+	res, err := utils.RaiseForStatus(conf.NewOutboundClient(utils.OutboundPriorityNormal, 30*time.Second).Do(req))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+// newPushgatewayRequest replaces all metrics of the job's group with the current ones, see https://github.com/prometheus/pushgateway#api
+func (h *MetricsHandler) newPushgatewayRequest(metrics mm.Metrics) (*http.Request, error) {
+	pushUrl := fmt.Sprintf(
+		"%s/metrics/job/%s/instance/%s",
+		strings.TrimSuffix(h.config.Security.MetricsPush.Url, "/"),
+		url.PathEscape(h.config.Security.MetricsPush.Job),
+		url.PathEscape(metricsInstanceName()),
+	)
+
+	req, err := http.NewRequest(http.MethodPut, pushUrl, bytes.NewBufferString(metrics.Print()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	return req, nil
+}
+
+// newRemoteWriteRequest sends the current values as samples, labeled with job and instance just like scraped ones, see https://prometheus.io/docs/concepts/remote_write_spec/
+func (h *MetricsHandler) newRemoteWriteRequest(metrics mm.Metrics) (*http.Request, error) {
+	now := time.Now().UnixMilli()
+	series := make([]*utils.RemoteWriteSeries, len(metrics))
+	for i, m := range metrics {
+		sample := m.Sample()
+		labels := []utils.RemoteWriteLabel{
+			{Name: "__name__", Value: sample.Name},
+			{Name: "job", Value: h.config.Security.MetricsPush.Job},
+			{Name: "instance", Value: metricsInstanceName()},
+		}
+		for _, l := range sample.Labels {
+			labels = append(labels, utils.RemoteWriteLabel{Name: l.Key, Value: l.Value})
+		}
+		series[i] = &utils.RemoteWriteSeries{Labels: labels, Value: sample.Value, Timestamp: now}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.config.Security.MetricsPush.Url, bytes.NewBuffer(utils.EncodeRemoteWriteRequest(series)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	return req, nil
+}
+
+func metricsInstanceName() string {
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
+	}
+	return "wakapi"
+}
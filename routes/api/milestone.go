@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type MilestoneApiHandler struct {
+	config        *conf.Config
+	userSrvc      services.IUserService
+	milestoneSrvc services.IMilestoneService
+}
+
+func NewMilestoneApiHandler(userService services.IUserService, milestoneService services.IMilestoneService) *MilestoneApiHandler {
+	return &MilestoneApiHandler{
+		config:        conf.Get(),
+		userSrvc:      userService,
+		milestoneSrvc: milestoneService,
+	}
+}
+
+func (h *MilestoneApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/users/{user}/milestones", h.GetAll)
+		r.Post("/users/{user}/milestones", h.Post)
+		r.Delete("/users/{user}/milestones/{id}", h.Delete)
+	})
+}
+
+// @Summary Retrieve a user's milestones
+// @ID get-milestones
+// @Tags milestones
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Param project query string false "Project to filter by"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Milestone
+// @Router /users/{user}/milestones [get]
+func (h *MilestoneApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	params, err := helpers.ParseSummaryParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	milestones, err := routeutils.LoadUserMilestonesByParams(h.milestoneSrvc, params)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to fetch milestones for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, milestones)
+}
+
+// @Summary Add a milestone
+// @Description Marks a notable event of a project (e.g. a release) on a day (in the user's time zone). Milestones can be overlaid on summaries to correlate them with activity.
+// @ID post-milestone
+// @Tags milestones
+// @Accept json
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param milestone body models.Milestone true "Day (yyyy-mm-dd), project and name"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.Milestone
+// @Router /users/{user}/milestones [post]
+func (h *MilestoneApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var payload models.Milestone
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	milestone := &models.Milestone{
+		UserID:  user.ID,
+		Day:     strings.TrimSpace(payload.Day),
+		Project: strings.TrimSpace(payload.Project),
+		Name:    strings.TrimSpace(payload.Name),
+	}
+	if !milestone.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid milestone - missing project or name or malformed day?"))
+		return
+	}
+
+	result, err := h.milestoneSrvc.Create(milestone)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to create milestone for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Delete a milestone
+// @ID delete-milestone
+// @Tags milestones
+// @Param user path string true "Username (or current)"
+// @Param id path int true "Milestone ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /users/{user}/milestones/{id} [delete]
+func (h *MilestoneApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	milestone, err := h.milestoneSrvc.GetById(uint(id))
+	if err != nil || milestone == nil || milestone.UserID != user.ID {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	if err := h.milestoneSrvc.Delete(milestone); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to delete milestone %d for user '%s' - %v", milestone.ID, user.ID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
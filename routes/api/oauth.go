@@ -0,0 +1,128 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+const grantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+// OAuthApiHandler implements the device authorization grant (https://datatracker.ietf.org/doc/html/rfc8628)
+// to let headless clients (cli agents, kiosks, ...) obtain scoped access tokens, without the user having to copy-paste their api key
+type OAuthApiHandler struct {
+	config          *conf.Config
+	userSrvc        services.IUserService
+	pairingSrvc     services.IPairingService
+	accessTokenSrvc services.IAccessTokenService
+}
+
+func NewOAuthApiHandler(userService services.IUserService, pairingService services.IPairingService, accessTokenService services.IAccessTokenService) *OAuthApiHandler {
+	return &OAuthApiHandler{
+		config:          conf.Get(),
+		userSrvc:        userService,
+		pairingSrvc:     pairingService,
+		accessTokenSrvc: accessTokenService,
+	}
+}
+
+func (h *OAuthApiHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Post("/device/code", h.PostDeviceCode)
+	r.Post("/token", h.PostToken)
+
+	router.Mount("/oauth", r)
+}
+
+// @Summary Initiate a device authorization request
+// @Description See https://datatracker.ietf.org/doc/html/rfc8628#section-3.1. Available scopes are "read_stats" and "write_heartbeats", defaults to all.
+// @ID post-oauth-device-code
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param client_id formData string true "Name of the client application"
+// @Param scope formData string false "Space-separated list of requested scopes"
+// @Success 200 {object} models.DevicePairing
+// @Router /oauth/device/code [post]
+func (h *OAuthApiHandler) PostDeviceCode(w http.ResponseWriter, r *http.Request) {
+	clientId := r.PostFormValue("client_id")
+	if clientId == "" {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, &models.AccessTokenErrorResponse{Error: "invalid_client"})
+		return
+	}
+
+	scope, ok := models.ParseScope(r.PostFormValue("scope"))
+	if !ok {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, &models.AccessTokenErrorResponse{Error: "invalid_scope"})
+		return
+	}
+
+	pairing, err := h.pairingSrvc.Create(clientId, scope)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to create device authorization request - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, pairing)
+}
+
+// @Summary Poll for an access token
+// @Description See https://datatracker.ietf.org/doc/html/rfc8628#section-3.4. Responds with 400 and error "authorization_pending" until the user has approved the request, "expired_token" once the device code has expired and "invalid_grant" for unknown device codes.
+// @ID post-oauth-token
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "Must be urn:ietf:params:oauth:grant-type:device_code"
+// @Param device_code formData string true "Device code"
+// @Param client_id formData string true "Name of the client application"
+// @Success 200 {object} models.AccessTokenResponse
+// @Failure 400 {object} models.AccessTokenErrorResponse
+// @Router /oauth/token [post]
+func (h *OAuthApiHandler) PostToken(w http.ResponseWriter, r *http.Request) {
+	if r.PostFormValue("grant_type") != grantTypeDeviceCode {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, &models.AccessTokenErrorResponse{Error: "unsupported_grant_type"})
+		return
+	}
+	respondDeviceToken(w, r, h.userSrvc, h.pairingSrvc, h.accessTokenSrvc, r.PostFormValue("device_code"), r.PostFormValue("client_id"))
+}
+
+// respondDeviceToken exchanges an approved device code for a new access token
+func respondDeviceToken(w http.ResponseWriter, r *http.Request, userSrvc services.IUserService, pairingSrvc services.IPairingService, accessTokenSrvc services.IAccessTokenService, deviceCode, clientId string) {
+	pairing, err := pairingSrvc.Poll(deviceCode)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, &models.AccessTokenErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if pairing.ClientID != clientId {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, &models.AccessTokenErrorResponse{Error: "invalid_client"})
+		return
+	}
+
+	user, err := userSrvc.GetUserById(pairing.UserID)
+	if err != nil || !user.IsActive() {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, &models.AccessTokenErrorResponse{Error: "access_denied"})
+		return
+	}
+
+	plainToken, token, err := accessTokenSrvc.Create(user, fmt.Sprintf("%s (device authorization)", pairing.ClientID), pairing.Scope)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to issue access token for user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	helpers.RespondJSON(w, r, http.StatusOK, &models.AccessTokenResponse{
+		AccessToken: plainToken,
+		TokenType:   "Bearer",
+		Scope:       token.Scope,
+	})
+}
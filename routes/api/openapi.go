@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/utils"
+	"github.com/swaggo/swag"
+)
+
+// OpenApiHandler serves the api's openapi 3 spec, which is derived from the swagger 2.0 spec generated by swag (see README)
+type OpenApiHandler struct {
+	config  *conf.Config
+	once    sync.Once
+	spec    []byte
+	specErr error
+}
+
+func NewOpenApiHandler() *OpenApiHandler {
+	return &OpenApiHandler{config: conf.Get()}
+}
+
+func (h *OpenApiHandler) RegisterRoutes(router chi.Router) {
+	router.Get("/openapi.json", h.Get)
+}
+
+// @Summary Retrieve the api's openapi 3 specification
+// @Description Covers both the native and the compat (wakatime, shields) apis, e.g. for generating clients
+// @ID get-openapi
+// @Tags misc
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /openapi.json [get]
+func (h *OpenApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	h.once.Do(func() {
+		doc, err := swag.ReadDoc()
+		if err != nil {
+			h.specErr = err
+			return
+		}
+		h.spec, h.specErr = utils.ConvertSwaggerToOpenApi3([]byte(doc), h.config.Server.BasePath+"/api")
+	})
+
+	if h.specErr != nil {
+		conf.Log().Request(r).Error("failed to generate openapi spec - %v", h.specErr)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(h.spec)
+}
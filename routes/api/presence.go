@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type PresenceApiHandler struct {
+	config          *conf.Config
+	userSrvc        services.IUserService
+	presenceSrvc    services.IPresenceService
+	accessTokenSrvc services.IAccessTokenService
+}
+
+func NewPresenceApiHandler(userService services.IUserService, presenceService services.IPresenceService, accessTokenService services.IAccessTokenService) *PresenceApiHandler {
+	return &PresenceApiHandler{
+		config:          conf.Get(),
+		userSrvc:        userService,
+		presenceSrvc:    presenceService,
+		accessTokenSrvc: accessTokenService,
+	}
+}
+
+func (h *PresenceApiHandler) RegisterRoutes(router chi.Router) {
+	router.Get("/presence/{user}", h.GetPublic)
+
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAccessTokens(h.accessTokenSrvc, models.ScopeReadStats).Handler)
+		r.Get("/users/{user}/presence", h.Get)
+	})
+}
+
+// @Summary Retrieve what a user is currently working on
+// @Description Tells whether the user is currently active, i.e. sent a heartbeat within the last 15 minutes, and if so, on which project, language and editor and since when without a break of more than 15 minutes
+// @ID get-presence
+// @Tags presence
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Presence
+// @Router /users/{user}/presence [get]
+func (h *PresenceApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	h.respond(w, r, user, false)
+}
+
+// @Summary Retrieve what a user is currently working on, publicly
+// @Description Same as /users/{user}/presence, e.g. for streaming overlays or team pages, but only available if the user opted in to share their presence and only including the project, language and editor if they share these respectively
+// @ID get-presence-public
+// @Tags presence
+// @Produce json
+// @Param user path string true "Username"
+// @Success 200 {object} models.Presence
+// @Failure 404
+// @Router /presence/{user} [get]
+func (h *PresenceApiHandler) GetPublic(w http.ResponseWriter, r *http.Request) {
+	// not telling apart unknown users from ones who don't share their presence, so that the endpoint can't be used to probe for accounts
+	user, err := h.userSrvc.GetUserById(chi.URLParam(r, "user"))
+	if err != nil || !user.SharesPresence() {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	h.respond(w, r, user, true)
+}
+
+func (h *PresenceApiHandler) respond(w http.ResponseWriter, r *http.Request, user *models.User, public bool) {
+	presence, err := h.presenceSrvc.GetByUser(user)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to get presence of user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	if public {
+		presence = presence.Public(user)
+	}
+
+	w.Header().Set("Cache-Control", "no-cache") // meant to be polled frequently
+	helpers.RespondJSON(w, r, http.StatusOK, presence)
+}
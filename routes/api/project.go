@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type ProjectApiHandler struct {
+	config      *conf.Config
+	userSrvc    services.IUserService
+	projectSrvc services.IProjectService
+}
+
+func NewProjectApiHandler(userService services.IUserService, projectService services.IProjectService) *ProjectApiHandler {
+	return &ProjectApiHandler{
+		config:      conf.Get(),
+		userSrvc:    userService,
+		projectSrvc: projectService,
+	}
+}
+
+func (h *ProjectApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Post("/users/{user}/projects/rename", h.PostRename)
+		r.Post("/users/{user}/projects/merge", h.PostMerge)
+		r.Get("/users/{user}/projects/rewrite", h.GetRewrite)
+	})
+}
+
+// @Summary Rename a project
+// @Description Moves all heartbeats of a project to a new project name and re-generates summaries in the background. Progress can be tracked via /users/{user}/projects/rewrite.
+// @ID post-project-rename
+// @Tags project
+// @Accept json
+// @Param user path string true "Username (or current)"
+// @Param rename body models.ProjectRenameRequest true "Old and new project name"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /users/{user}/projects/rename [post]
+func (h *ProjectApiHandler) PostRename(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var payload models.ProjectRenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || !payload.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	h.respondScheduled(w, h.projectSrvc.Rename(user, payload.From, payload.To))
+}
+
+// @Summary Merge several projects into one
+// @Description Moves all heartbeats of the source projects to the target project and re-generates summaries in the background. Progress can be tracked via /users/{user}/projects/rewrite.
+// @ID post-project-merge
+// @Tags project
+// @Accept json
+// @Param user path string true "Username (or current)"
+// @Param merge body models.ProjectMergeRequest true "Source projects and target project"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /users/{user}/projects/merge [post]
+func (h *ProjectApiHandler) PostMerge(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var payload models.ProjectMergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || !payload.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	h.respondScheduled(w, h.projectSrvc.Merge(user, payload.Sources, payload.Target))
+}
+
+// @Summary Retrieve the progress of the latest project rename or merge
+// @ID get-project-rewrite
+// @Tags project
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ProjectRewriteJob
+// @Router /users/{user}/projects/rewrite [get]
+func (h *ProjectApiHandler) GetRewrite(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, h.projectSrvc.GetJob(user))
+}
+
+func (h *ProjectApiHandler) respondScheduled(w http.ResponseWriter, err error) {
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+// ProjectClientApiHandler manages the customers a user groups their projects under, not to be confused with ClientApiHandler, which is about editor plugins
+type ProjectClientApiHandler struct {
+	config     *conf.Config
+	userSrvc   services.IUserService
+	clientSrvc services.IClientService
+}
+
+func NewProjectClientApiHandler(userService services.IUserService, clientService services.IClientService) *ProjectClientApiHandler {
+	return &ProjectClientApiHandler{
+		config:     conf.Get(),
+		userSrvc:   userService,
+		clientSrvc: clientService,
+	}
+}
+
+func (h *ProjectClientApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/users/{user}/settings/clients", h.GetAll)
+		r.Post("/users/{user}/settings/clients", h.Post)
+		r.Put("/users/{user}/settings/clients/{id}", h.Put)
+		r.Delete("/users/{user}/settings/clients/{id}", h.Delete)
+	})
+}
+
+// @Summary Retrieve a user's clients
+// @ID get-clients
+// @Tags settings
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Client
+// @Router /users/{user}/settings/clients [get]
+func (h *ProjectClientApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	clients, err := h.clientSrvc.GetByUser(user.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to fetch clients for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, clients)
+}
+
+// @Summary Add a client
+// @Description Groups the given projects under a new client (customer). Time per client is included in summaries and can be filtered by using the client query parameter. Projects previously assigned to another client are moved to the new one.
+// @ID post-client
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param client body models.Client true "Name and projects"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.Client
+// @Router /users/{user}/settings/clients [post]
+func (h *ProjectClientApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	client, ok := h.parseClient(w, r)
+	if !ok {
+		return
+	}
+	client.UserID = user.ID
+
+	result, err := h.clientSrvc.Create(client)
+	if err != nil {
+		h.respondError(w, r, user, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Update a client
+// @Description Renames a client and replaces its projects
+// @ID put-client
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param id path int true "Client ID"
+// @Param client body models.Client true "Name and projects"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Client
+// @Router /users/{user}/settings/clients/{id} [put]
+func (h *ProjectClientApiHandler) Put(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	existing, ok := h.loadClient(w, r, user)
+	if !ok {
+		return
+	}
+
+	client, ok := h.parseClient(w, r)
+	if !ok {
+		return
+	}
+	client.ID, client.UserID, client.CreatedAt = existing.ID, user.ID, existing.CreatedAt
+
+	result, err := h.clientSrvc.Update(client)
+	if err != nil {
+		h.respondError(w, r, user, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, result)
+}
+
+// @Summary Delete a client
+// @Description Deletes a client, its projects remain untouched
+// @ID delete-client
+// @Tags settings
+// @Param user path string true "Username (or current)"
+// @Param id path int true "Client ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /users/{user}/settings/clients/{id} [delete]
+func (h *ProjectClientApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	client, ok := h.loadClient(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := h.clientSrvc.Delete(client); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to delete client %d for user '%s' - %v", client.ID, user.ID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ProjectClientApiHandler) parseClient(w http.ResponseWriter, r *http.Request) (*models.Client, bool) {
+	var payload models.Client
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return nil, false
+	}
+
+	client := &models.Client{Name: strings.TrimSpace(payload.Name), Projects: make([]string, 0, len(payload.Projects))}
+	for _, p := range payload.Projects {
+		client.Projects = append(client.Projects, strings.TrimSpace(p))
+	}
+	if !client.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid client - missing name or empty project?"))
+		return nil, false
+	}
+	return client, true
+}
+
+func (h *ProjectClientApiHandler) loadClient(w http.ResponseWriter, r *http.Request, user *models.User) (*models.Client, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return nil, false
+	}
+
+	client, err := h.clientSrvc.GetByUserAndId(user.ID, uint(id))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return nil, false
+	}
+	return client, true
+}
+
+func (h *ProjectClientApiHandler) respondError(w http.ResponseWriter, r *http.Request, user *models.User, err error) {
+	if errors.Is(err, services.ErrClientExists) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write([]byte(conf.ErrInternalServerError))
+	conf.Log().Request(r).Error("failed to save client for user '%s' - %v", user.ID, err)
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+// ProjectGroupApiHandler manages a user's project groups, i.e. umbrella projects, which multiple projects are rolled up into
+type ProjectGroupApiHandler struct {
+	config           *conf.Config
+	userSrvc         services.IUserService
+	projectGroupSrvc services.IProjectGroupService
+}
+
+func NewProjectGroupApiHandler(userService services.IUserService, projectGroupService services.IProjectGroupService) *ProjectGroupApiHandler {
+	return &ProjectGroupApiHandler{
+		config:           conf.Get(),
+		userSrvc:         userService,
+		projectGroupSrvc: projectGroupService,
+	}
+}
+
+func (h *ProjectGroupApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/users/{user}/settings/project_groups", h.GetAll)
+		r.Post("/users/{user}/settings/project_groups", h.Post)
+		r.Put("/users/{user}/settings/project_groups/{id}", h.Put)
+		r.Delete("/users/{user}/settings/project_groups/{id}", h.Delete)
+	})
+}
+
+// @Summary Retrieve a user's project groups
+// @ID get-project-groups
+// @Tags settings
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.ProjectGroup
+// @Router /users/{user}/settings/project_groups [get]
+func (h *ProjectGroupApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	groups, err := h.projectGroupSrvc.GetByUser(user.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to fetch project groups for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, groups)
+}
+
+// @Summary Add a project group
+// @Description Rolls up the given projects into a single logical project named after the group. Applies to summaries, leaderboards and badges and the group's name can be used as a regular project filter. Projects previously assigned to another group are moved to the new one.
+// @ID post-project-group
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param group body models.ProjectGroup true "Name and projects"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.ProjectGroup
+// @Router /users/{user}/settings/project_groups [post]
+func (h *ProjectGroupApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	group, ok := h.parseProjectGroup(w, r)
+	if !ok {
+		return
+	}
+	group.UserID = user.ID
+
+	result, err := h.projectGroupSrvc.Create(group)
+	if err != nil {
+		h.respondError(w, r, user, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Update a project group
+// @Description Renames a project group and replaces its projects
+// @ID put-project-group
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param id path int true "Project group ID"
+// @Param group body models.ProjectGroup true "Name and projects"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ProjectGroup
+// @Router /users/{user}/settings/project_groups/{id} [put]
+func (h *ProjectGroupApiHandler) Put(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	existing, ok := h.loadProjectGroup(w, r, user)
+	if !ok {
+		return
+	}
+
+	group, ok := h.parseProjectGroup(w, r)
+	if !ok {
+		return
+	}
+	group.ID, group.UserID, group.CreatedAt = existing.ID, user.ID, existing.CreatedAt
+
+	result, err := h.projectGroupSrvc.Update(group)
+	if err != nil {
+		h.respondError(w, r, user, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, result)
+}
+
+// @Summary Delete a project group
+// @Description Deletes a project group, its projects are listed individually again
+// @ID delete-project-group
+// @Tags settings
+// @Param user path string true "Username (or current)"
+// @Param id path int true "Project group ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /users/{user}/settings/project_groups/{id} [delete]
+func (h *ProjectGroupApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	group, ok := h.loadProjectGroup(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := h.projectGroupSrvc.Delete(group); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to delete project group %d for user '%s' - %v", group.ID, user.ID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ProjectGroupApiHandler) parseProjectGroup(w http.ResponseWriter, r *http.Request) (*models.ProjectGroup, bool) {
+	var payload models.ProjectGroup
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return nil, false
+	}
+
+	group := &models.ProjectGroup{Name: strings.TrimSpace(payload.Name), Projects: make([]string, 0, len(payload.Projects))}
+	for _, p := range payload.Projects {
+		group.Projects = append(group.Projects, strings.TrimSpace(p))
+	}
+	if !group.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid project group - missing name, empty project or project named like the group?"))
+		return nil, false
+	}
+	return group, true
+}
+
+func (h *ProjectGroupApiHandler) loadProjectGroup(w http.ResponseWriter, r *http.Request, user *models.User) (*models.ProjectGroup, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return nil, false
+	}
+
+	group, err := h.projectGroupSrvc.GetByUserAndId(user.ID, uint(id))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return nil, false
+	}
+	return group, true
+}
+
+func (h *ProjectGroupApiHandler) respondError(w http.ResponseWriter, r *http.Request, user *models.User, err error) {
+	if errors.Is(err, services.ErrProjectGroupExists) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write([]byte(conf.ErrInternalServerError))
+	conf.Log().Request(r).Error("failed to save project group for user '%s' - %v", user.ID, err)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/services"
+)
+
+type PublicStatsApiHandler struct {
+	config          *conf.Config
+	publicStatsSrvc services.IPublicStatsService
+}
+
+func NewPublicStatsApiHandler(publicStatsService services.IPublicStatsService) *PublicStatsApiHandler {
+	return &PublicStatsApiHandler{
+		config:          conf.Get(),
+		publicStatsSrvc: publicStatsService,
+	}
+}
+
+func (h *PublicStatsApiHandler) RegisterRoutes(router chi.Router) {
+	if !h.config.Security.ExposePublicStats {
+		return
+	}
+	router.Get("/stats/public", h.Get)
+}
+
+// @Summary Retrieve anonymous, instance-wide statistics
+// @Description Total number of users, total coding hours and the most used languages of the past 30 days across all users of this instance, e.g. for status pages. Only available if enabled by the instance's operator.
+// @ID get-public-stats
+// @Tags misc
+// @Produce json
+// @Success 200 {object} models.PublicStats
+// @Router /stats/public [get]
+func (h *PublicStatsApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.publicStatsSrvc.Get()
+	if err != nil {
+		conf.Log().Request(r).Error("failed to compute public stats - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	helpers.RespondJSON(w, r, http.StatusOK, stats)
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type ReportApiHandler struct {
+	config     *conf.Config
+	userSrvc   services.IUserService
+	reportSrvc services.IReportService
+	mailSrvc   services.IMailService
+}
+
+func NewReportApiHandler(userService services.IUserService, reportService services.IReportService, mailService services.IMailService) *ReportApiHandler {
+	return &ReportApiHandler{
+		config:     conf.Get(),
+		userSrvc:   userService,
+		reportSrvc: reportService,
+		mailSrvc:   mailService,
+	}
+}
+
+func (h *ReportApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Post("/users/{user}/reports", h.Post)
+	})
+}
+
+// @Summary Generate a custom report
+// @Description Generates a one-off report for the given interval, filters and groupings. Unless requested to be generated asynchronously, the report is returned either as json or rendered as html, using the same template as the weekly e-mail reports. Asynchronously generated reports are sent to the user's e-mail address instead.
+// @ID post-report
+// @Tags reports
+// @Accept json
+// @Produce json,html
+// @Param user path string true "Username (or current)"
+// @Param report body models.ReportDefinition true "Report definition"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Report
+// @Success 202
+// @Router /users/{user}/reports [post]
+func (h *ReportApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var def models.ReportDefinition
+	if err := json.NewDecoder(r.Body).Decode(&def); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	if def.Format != "" && def.Format != models.ReportFormatJson && def.Format != models.ReportFormatHtml {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid format"))
+		return
+	}
+
+	params, err := helpers.ParseReportDefinition(&def, user.TZ())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if def.Async {
+		if user.Email == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("no e-mail address set"))
+			return
+		}
+		if err := h.reportSrvc.SendCustomReport(user, params); err == services.ErrReportRangeTooLarge {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		} else if err != nil {
+			conf.Log().Request(r).Error("failed to schedule custom report for user '%s' - %v", user.ID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(conf.ErrInternalServerError))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	report, err := h.reportSrvc.Generate(user, params)
+	if err == services.ErrReportRangeTooLarge {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	} else if err != nil {
+		conf.Log().Request(r).Error("failed to generate custom report for user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	if def.Format == models.ReportFormatHtml {
+		rendered, err := h.mailSrvc.RenderReport(report)
+		if err != nil {
+			conf.Log().Request(r).Error("failed to render custom report for user '%s' - %v", user.ID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(conf.ErrInternalServerError))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(rendered))
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, report)
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+// SandboxApiHandler accepts heartbeats for testing purposes, e.g. during plugin development, without them ever affecting any statistics
+// plugins can be pointed to the sandbox by setting their api url to <base url>/api/sandbox
+type SandboxApiHandler struct {
+	config          *conf.Config
+	userSrvc        services.IUserService
+	heartbeatSrvc   services.IHeartbeatService
+	sandboxSrvc     services.ISandboxService
+	accessTokenSrvc services.IAccessTokenService
+}
+
+type sandboxResultsViewModel struct {
+	Data []*models.SandboxResult `json:"data"`
+}
+
+func NewSandboxApiHandler(userService services.IUserService, heartbeatService services.IHeartbeatService, sandboxService services.ISandboxService, accessTokenService services.IAccessTokenService) *SandboxApiHandler {
+	return &SandboxApiHandler{
+		config:          conf.Get(),
+		userSrvc:        userService,
+		heartbeatSrvc:   heartbeatService,
+		sandboxSrvc:     sandboxService,
+		accessTokenSrvc: accessTokenService,
+	}
+}
+
+func (h *SandboxApiHandler) RegisterRoutes(router chi.Router) {
+	if !h.config.App.SandboxEnabled {
+		return
+	}
+
+	router.Route("/sandbox", func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAccessTokens(h.accessTokenSrvc, models.ScopeWriteHeartbeats).WithRejectDeactivated().Handler)
+		r.Post("/heartbeat", h.Post)
+		r.Post("/heartbeats", h.Post)
+		r.Post("/users/{user}/heartbeats", h.Post)
+		r.Post("/users/{user}/heartbeats.bulk", h.Post)
+		r.Get("/heartbeats", h.Get)
+		r.Delete("/heartbeats", h.Delete)
+	})
+}
+
+// @Summary Push heartbeats to the sandbox
+// @Description Validates and processes heartbeats just like the regular endpoint, but keeps them apart from real ones, so they never affect any statistics. Responds with details on how each heartbeat was processed, in the same format as the regular endpoint (list of [result, status] pairs). Sandbox heartbeats are purged nightly.
+// @ID post-sandbox-heartbeats
+// @Tags sandbox
+// @Accept json
+// @Produce json
+// @Param heartbeat body []models.Heartbeat true "One or multiple heartbeats"
+// @Security ApiKeyAuth
+// @Success 201 {object} heartbeatResponseVm
+// @Failure 429
+// @Router /sandbox/heartbeats [post]
+func (h *SandboxApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	heartbeats, err := routeutils.ParseHeartbeats(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if err := populateHeartbeats(r, user, heartbeats, h.heartbeatSrvc); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	results, err := h.sandboxSrvc.Process(user, heartbeats)
+	if err != nil {
+		if errors.Is(err, services.ErrSandboxLimitExceeded) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		conf.Log().Request(r).Error("failed to process sandbox heartbeats of user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	responses := make([][]interface{}, len(results))
+	for i, result := range results {
+		responses[i] = []interface{}{result, result.Status}
+	}
+
+	helpers.RespondJSON(w, r, http.StatusCreated, &heartbeatResponseVm{Responses: responses})
+}
+
+// @Summary Retrieve the results of recent sandbox heartbeats
+// @ID get-sandbox-heartbeats
+// @Tags sandbox
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} sandboxResultsViewModel
+// @Router /sandbox/heartbeats [get]
+func (h *SandboxApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+
+	results, err := h.sandboxSrvc.GetByUser(user, services.SandboxResultsLimit)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to get sandbox heartbeats of user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, &sandboxResultsViewModel{Data: results})
+}
+
+// @Summary Delete all of the user's sandbox heartbeats
+// @ID delete-sandbox-heartbeats
+// @Tags sandbox
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /sandbox/heartbeats [delete]
+func (h *SandboxApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+
+	if err := h.sandboxSrvc.DeleteByUser(user); err != nil {
+		conf.Log().Request(r).Error("failed to delete sandbox heartbeats of user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type SavedFilterApiHandler struct {
+	config          *conf.Config
+	userSrvc        services.IUserService
+	savedFilterSrvc services.ISavedFilterService
+}
+
+func NewSavedFilterApiHandler(userService services.IUserService, savedFilterService services.ISavedFilterService) *SavedFilterApiHandler {
+	return &SavedFilterApiHandler{
+		config:          conf.Get(),
+		userSrvc:        userService,
+		savedFilterSrvc: savedFilterService,
+	}
+}
+
+func (h *SavedFilterApiHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/users/{user}/filters", h.GetAll)
+		r.Get("/users/{user}/filters/{name}", h.Get)
+		r.Post("/users/{user}/filters", h.Post)
+		r.Delete("/users/{user}/filters/{name}", h.Delete)
+	})
+}
+
+// @Summary Retrieve a user's saved filters
+// @ID get-saved-filters
+// @Tags filters
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.SavedFilter
+// @Router /users/{user}/filters [get]
+func (h *SavedFilterApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	filters, err := h.savedFilterSrvc.GetByUser(user)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to fetch saved filters for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, filters)
+}
+
+// @Summary Retrieve a saved filter
+// @ID get-saved-filter
+// @Tags filters
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param name path string true "Filter name"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.SavedFilter
+// @Router /users/{user}/filters/{name} [get]
+func (h *SavedFilterApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	filter, err := h.savedFilterSrvc.GetByUserAndName(user, chi.URLParam(r, "name"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, filter)
+}
+
+// @Summary Save a filter
+// @Description Persists a named set of filter parameters (e.g. 'project=wakapi,anchr&branch!=main'), which can then be applied to summaries using the 'filter' query parameter (e.g. '?interval=week&filter=work') and to badges using a 'filter:<name>' path segment
+// @ID post-saved-filter
+// @Tags filters
+// @Accept json
+// @Produce json
+// @Param user path string true "Username (or current)"
+// @Param filter body models.SavedFilter true "Name (letters, digits, dashes and underscores) and query"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.SavedFilter
+// @Router /users/{user}/filters [post]
+func (h *SavedFilterApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var payload models.SavedFilter
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(conf.ErrBadRequest))
+		return
+	}
+
+	filter := &models.SavedFilter{
+		UserID: user.ID,
+		Name:   strings.TrimSpace(payload.Name),
+		Query:  strings.TrimPrefix(strings.TrimSpace(payload.Query), "?"),
+	}
+	if !filter.IsValid() {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid saved filter - missing query or name not consisting of letters, digits, dashes and underscores only?"))
+		return
+	}
+	if _, err := helpers.ParseSavedFilterQuery(filter.Query); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if existing, _ := h.savedFilterSrvc.GetByUserAndName(user, filter.Name); existing != nil {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("filter with the same name already exists"))
+		return
+	}
+
+	result, err := h.savedFilterSrvc.Create(filter)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to create saved filter for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Delete a saved filter
+// @ID delete-saved-filter
+// @Tags filters
+// @Param user path string true "Username (or current)"
+// @Param name path string true "Filter name"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /users/{user}/filters/{name} [delete]
+func (h *SavedFilterApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	filter, err := h.savedFilterSrvc.GetByUserAndName(user, chi.URLParam(r, "name"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	if err := h.savedFilterSrvc.Delete(filter); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to delete saved filter '%s' for user '%s' - %v", filter.Name, user.ID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,34 +1,73 @@
 package api
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/duke-git/lancet/v2/datetime"
 	"github.com/go-chi/chi/v5"
 	"github.com/muety/wakapi/helpers"
 	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/utils"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/services"
 )
 
 type SummaryApiHandler struct {
-	config      *conf.Config
-	userSrvc    services.IUserService
-	summarySrvc services.ISummaryService
+	config           *conf.Config
+	userSrvc         services.IUserService
+	summarySrvc      services.ISummaryService
+	accessTokenSrvc  services.IAccessTokenService
+	annotationSrvc   services.IAnnotationService
+	milestoneSrvc    services.IMilestoneService
+	heartbeatSrvc    services.IHeartbeatService
+	trendsSrvc       services.IUserTrendsService
+	workingHoursSrvc services.IWorkingHoursService
+	savedFilterSrvc  services.ISavedFilterService
 }
 
-func NewSummaryApiHandler(userService services.IUserService, summaryService services.ISummaryService) *SummaryApiHandler {
+// summaryResponseVm is only returned if annotations, milestones, working hours or colors were requested explicitly, otherwise the plain summary is
+// overlays are pointers to tell apart lists that weren't requested (omitted) from empty ones
+type summaryResponseVm struct {
+	*models.Summary
+	Annotations    *[]*models.Annotation `json:"annotations,omitempty"`
+	Milestones     *[]*models.Milestone  `json:"milestones,omitempty"`
+	WorkingHours   *models.WorkingHours  `json:"working_hours,omitempty"`
+	LanguageColors map[string]string     `json:"language_colors,omitempty"`
+}
+
+func NewSummaryApiHandler(userService services.IUserService, summaryService services.ISummaryService, accessTokenService services.IAccessTokenService, annotationService services.IAnnotationService, milestoneService services.IMilestoneService, heartbeatService services.IHeartbeatService, userTrendsService services.IUserTrendsService, workingHoursService services.IWorkingHoursService, savedFilterService services.ISavedFilterService) *SummaryApiHandler {
 	return &SummaryApiHandler{
-		summarySrvc: summaryService,
-		userSrvc:    userService,
-		config:      conf.Get(),
+		summarySrvc:      summaryService,
+		userSrvc:         userService,
+		accessTokenSrvc:  accessTokenService,
+		annotationSrvc:   annotationService,
+		milestoneSrvc:    milestoneService,
+		heartbeatSrvc:    heartbeatService,
+		trendsSrvc:       userTrendsService,
+		workingHoursSrvc: workingHoursService,
+		savedFilterSrvc:  savedFilterService,
+		config:           conf.Get(),
 	}
 }
 
 func (h *SummaryApiHandler) RegisterRoutes(router chi.Router) {
 	r := chi.NewRouter()
-	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAccessTokens(h.accessTokenSrvc, models.ScopeReadStats).Handler,
+		middlewares.NewSavedFilterMiddleware(h.savedFilterSrvc),
+	)
 	r.Get("/", h.Get)
+	r.Get("/xlsx", h.GetXlsx)
+	r.Get("/compare", h.GetComparison)
+	r.Get("/trends", h.GetTrends)
 
 	router.Mount("/summary", r)
 }
@@ -40,23 +79,310 @@ func (h *SummaryApiHandler) RegisterRoutes(router chi.Router) {
 // @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
 // @Param from query string false "Start date (e.g. '2021-02-07')"
 // @Param to query string false "End date (e.g. '2021-02-08')"
-// @Param recompute query bool false "Whether to recompute the summary from raw heartbeat or use cache"
+// @Param recompute query bool false "Whether to recompute the summary from raw heartbeat or use cache, requires the 'write_heartbeats' scope when authenticating by access token"
 // @Param project query string false "Project to filter by"
 // @Param language query string false "Language to filter by"
 // @Param editor query string false "Editor to filter by"
 // @Param operating_system query string false "OS to filter by"
 // @Param machine query string false "Machine to filter by"
 // @Param label query string false "Project label to filter by"
+// @Param client query string false "Client to filter by"
+// @Param filter query string false "Name of a saved filter to apply"
+// @Param annotations query bool false "Whether to include the user's annotations within the interval"
+// @Param milestones query bool false "Whether to include the user's project milestones within the interval"
+// @Param working_hours query bool false "Whether to include time coded within and outside the user's working hours, only for users with a working schedule and intervals of up to 31 days"
+// @Param colors query bool false "Whether to include the colors of the summary's languages, as shown on the dashboard (derived ones for languages without a predefined color)"
 // @Security ApiKeyAuth
 // @Success 200 {object} models.Summary
 // @Router /summary [get]
 func (h *SummaryApiHandler) Get(w http.ResponseWriter, r *http.Request) {
-	summary, err, status := routeutils.LoadUserSummary(h.summarySrvc, r)
+	summaryParams, err := helpers.ParseSummaryParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if !checkRecomputeAllowed(w, r, summaryParams) {
+		return
+	}
+
+	// annotations, milestones and working schedules can change independently of heartbeats, so responses including them are not validated
+	withAnnotations, withMilestones := r.URL.Query().Get("annotations") == "true", r.URL.Query().Get("milestones") == "true"
+	withWorkingHours, withColors := r.URL.Query().Get("working_hours") == "true", r.URL.Query().Get("colors") == "true"
+	if !summaryParams.Recompute && !withAnnotations && !withMilestones && !withWorkingHours {
+		variant := routeutils.SummaryVariant(summaryParams.User, r.URL.RawQuery)
+		if routeutils.CheckNotModified(w, r, routeutils.SummaryVersion(h.heartbeatSrvc, h.summarySrvc, summaryParams.User), summaryParams.From, summaryParams.To, variant) {
+			return
+		}
+	}
+
+	summary, err, status := routeutils.LoadUserSummaryByParams(h.summarySrvc, summaryParams)
+	if err != nil {
+		w.WriteHeader(status)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if !withAnnotations && !withMilestones && !withWorkingHours && !withColors {
+		helpers.RespondJSON(w, r, http.StatusOK, summary)
+		return
+	}
+
+	// overlays are supplementary, so failing to load them doesn't fail the whole request, just like on the summary page and in reports
+	vm := &summaryResponseVm{Summary: summary}
+	if withAnnotations {
+		annotations, err := routeutils.LoadUserAnnotationsByParams(h.annotationSrvc, summaryParams)
+		if err != nil {
+			conf.Log().Request(r).Error("failed to load annotations for user '%s' - %v", summaryParams.User.ID, err)
+			annotations = []*models.Annotation{}
+		}
+		vm.Annotations = &annotations
+	}
+	if withMilestones {
+		milestones, err := routeutils.LoadUserMilestonesByParams(h.milestoneSrvc, summaryParams)
+		if err != nil {
+			conf.Log().Request(r).Error("failed to load milestones for user '%s' - %v", summaryParams.User.ID, err)
+			milestones = []*models.Milestone{}
+		}
+		vm.Milestones = &milestones
+	}
+	if withWorkingHours {
+		workingHours, err := h.workingHoursSrvc.GetByUser(summaryParams.User, summaryParams.From, summaryParams.To, summaryParams.Filters)
+		if err != nil && !errors.Is(err, services.ErrWorkingHoursRangeTooLarge) {
+			conf.Log().Request(r).Error("failed to compute working hours for user '%s' - %v", summaryParams.User.ID, err)
+		}
+		vm.WorkingHours = workingHours
+	}
+	if withColors {
+		vm.LanguageColors = routeutils.FilterColorsOrDerive(h.config.App.GetLanguageColors(), summary.Languages)
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, vm)
+}
+
+// @Summary Compare a summary to the one of a previous period
+// @Description Retrieves the summary of the given interval along with the one of the same interval, shifted back in time by offset periods (e.g. this week so far vs. the same days of last week), plus per-dimension deltas (in seconds)
+// @ID get-summary-compare
+// @Tags summary
+// @Produce json
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year)
+// @Param offset query int false "Number of periods to go back for the comparison (e.g. 52 to compare a week to the same one last year)" default(1)
+// @Param from query string false "Start date (e.g. '2021-02-07'), if no interval is given, the previous period is the one of equal length right before"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Param project query string false "Project to filter by"
+// @Param language query string false "Language to filter by"
+// @Param editor query string false "Editor to filter by"
+// @Param operating_system query string false "OS to filter by"
+// @Param machine query string false "Machine to filter by"
+// @Param label query string false "Project label to filter by"
+// @Param client query string false "Client to filter by"
+// @Param filter query string false "Name of a saved filter to apply"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.SummaryComparison
+// @Failure 400
+// @Router /summary/compare [get]
+func (h *SummaryApiHandler) GetComparison(w http.ResponseWriter, r *http.Request) {
+	summaryParams, err := helpers.ParseSummaryParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if !checkRecomputeAllowed(w, r, summaryParams) {
+		return
+	}
+
+	offset := 1
+	if offsetParam := r.URL.Query().Get("offset"); offsetParam != "" {
+		if offset, err = strconv.Atoi(offsetParam); err != nil || offset < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid offset"))
+			return
+		}
+	}
+
+	var interval *models.IntervalKey
+	intervalParam := r.URL.Query().Get("interval")
+	if intervalParam == "" {
+		intervalParam = r.URL.Query().Get("start")
+	}
+	if intervalParam != "" {
+		interval = helpers.MustParseInterval(intervalParam) // already validated when parsing summary params
+	}
+
+	err, previousFrom, previousTo := helpers.ShiftIntervalTZ(interval, summaryParams.From, summaryParams.To, offset)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	current, err, status := routeutils.LoadUserSummaryByParams(h.summarySrvc, summaryParams)
+	if err != nil {
+		w.WriteHeader(status)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	previous, err, status := routeutils.LoadUserSummaryByParams(h.summarySrvc, &models.SummaryParams{
+		From:      previousFrom,
+		To:        previousTo,
+		User:      summaryParams.User,
+		Filters:   summaryParams.Filters,
+		Recompute: summaryParams.Recompute,
+	})
 	if err != nil {
 		w.WriteHeader(status)
 		w.Write([]byte(err.Error()))
 		return
 	}
 
-	helpers.RespondJSON(w, r, http.StatusOK, summary)
+	helpers.RespondJSON(w, r, http.StatusOK, models.NewSummaryComparison(current, previous))
+}
+
+// @Summary Retrieve trends and projections
+// @Description Rolling 7-day averages and the distribution across weekdays of the past weeks' coding time, plus the hours to expect by the end of the current week and month, projected from the average of each remaining weekday. All times are in hours.
+// @ID get-summary-trends
+// @Tags summary
+// @Produce json
+// @Param weeks query int false "Number of past weeks to consider (5 to 26)" default(8)
+// @Security ApiKeyAuth
+// @Success 200 {object} models.UserTrends
+// @Failure 400
+// @Router /summary/trends [get]
+func (h *SummaryApiHandler) GetTrends(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+
+	weeks := services.UserTrendsDefaultWeeks
+	if weeksParam := r.URL.Query().Get("weeks"); weeksParam != "" {
+		var err error
+		if weeks, err = strconv.Atoi(weeksParam); err != nil || weeks < services.UserTrendsMinWeeks || weeks > services.UserTrendsMaxWeeks {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf("weeks must be between %d and %d", services.UserTrendsMinWeeks, services.UserTrendsMaxWeeks)))
+			return
+		}
+	}
+
+	trends, err := h.trendsSrvc.GetByUser(user, weeks)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to compute trends for user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, trends)
+}
+
+// @Summary Export a summary as excel workbook
+// @Description Exports the summary as an xlsx workbook, containing one sheet each for projects, languages and days
+// @ID get-summary-xlsx
+// @Tags summary
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Param project query string false "Project to filter by"
+// @Param language query string false "Language to filter by"
+// @Param editor query string false "Editor to filter by"
+// @Param operating_system query string false "OS to filter by"
+// @Param machine query string false "Machine to filter by"
+// @Param label query string false "Project label to filter by"
+// @Param client query string false "Client to filter by"
+// @Param filter query string false "Name of a saved filter to apply"
+// @Security ApiKeyAuth
+// @Success 200 {file} file
+// @Router /summary/xlsx [get]
+func (h *SummaryApiHandler) GetXlsx(w http.ResponseWriter, r *http.Request) {
+	summaryParams, err := helpers.ParseSummaryParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if !checkRecomputeAllowed(w, r, summaryParams) {
+		return
+	}
+
+	summary, err, status := routeutils.LoadUserSummaryByParams(h.summarySrvc, summaryParams)
+	if err != nil {
+		w.WriteHeader(status)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	daysSheet, err := h.buildDaysSheet(summaryParams)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to generate daily summaries for xlsx export for user '%s' - %v", summaryParams.User.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	sheets := []*utils.XlsxSheet{
+		buildItemsSheet("Projects", "Project", summary.Projects, summary.TotalTimeBy(models.SummaryProject)),
+		buildItemsSheet("Languages", "Language", summary.Languages, summary.TotalTimeBy(models.SummaryLanguage)),
+		daysSheet,
+	}
+
+	// write to buffer first to still be able to respond with an error status
+	var buf bytes.Buffer
+	if err := utils.WriteXlsx(&buf, sheets); err != nil {
+		conf.Log().Request(r).Error("failed to write xlsx export for user '%s' - %v", summaryParams.User.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	from, to := summaryParams.From.In(summaryParams.User.TZ()), summaryParams.To.In(summaryParams.User.TZ())
+	w.Header().Set("Content-Type", utils.XlsxMimeType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"wakapi_summary_%s_%s_%s.xlsx\"", summaryParams.User.ID, from.Format(conf.SimpleDateFormat), to.Format(conf.SimpleDateFormat)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func (h *SummaryApiHandler) buildDaysSheet(params *models.SummaryParams) (*utils.XlsxSheet, error) {
+	sheet := &utils.XlsxSheet{Name: "Days", Rows: [][]interface{}{{"Date", "Hours"}}}
+
+	for _, interval := range utils.SplitRangeByDays(params.From, params.To) {
+		from, to := datetime.BeginOfDay(interval[0]), interval[1]
+		summary, err, _ := routeutils.LoadUserSummaryByParams(h.summarySrvc, &models.SummaryParams{
+			From:    from,
+			To:      to,
+			User:    params.User,
+			Filters: params.Filters,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sheet.Rows = append(sheet.Rows, []interface{}{from.Format(conf.SimpleDateFormat), durationToHours(summary.TotalTime())})
+	}
+
+	return sheet, nil
+}
+
+func buildItemsSheet(name, keyTitle string, items models.SummaryItems, total time.Duration) *utils.XlsxSheet {
+	sheet := &utils.XlsxSheet{Name: name, Rows: [][]interface{}{{keyTitle, "Hours", "Percent"}}}
+	for _, item := range items {
+		percent := 0.0
+		if total > 0 {
+			percent = math.Round(float64(item.TotalFixed())/float64(total)*10000) / 100
+		}
+		sheet.Rows = append(sheet.Rows, []interface{}{item.Key, durationToHours(item.TotalFixed()), percent})
+	}
+	return sheet
+}
+
+func durationToHours(d time.Duration) float64 {
+	return math.Round(d.Hours()*100) / 100
+}
+
+// recomputing summaries replaces the cached ones, so read-only access tokens (e.g. embedded in widgets) must not trigger it, as opposed to the api key or a session
+func checkRecomputeAllowed(w http.ResponseWriter, r *http.Request, params *models.SummaryParams) bool {
+	if params.Recompute && !middlewares.HasScope(r, models.ScopeWriteHeartbeats) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(fmt.Sprintf("recomputing summaries requires the '%s' scope", models.ScopeWriteHeartbeats)))
+		return false
+	}
+	return true
 }
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+const (
+	// a project is considered "current" if there was a heartbeat for it within this period
+	trayCurrentProjectThreshold = 15 * time.Minute
+	trayClientId                = "wakapi-tray"
+)
+
+type TrayViewModel struct {
+	Today        int64   `json:"today"` // seconds
+	Project      string  `json:"project,omitempty"`
+	Goal         int64   `json:"goal,omitempty"` // seconds
+	GoalProgress float64 `json:"goal_progress"`  // between 0 and 1
+}
+
+type TrayApiHandler struct {
+	config          *conf.Config
+	userSrvc        services.IUserService
+	summarySrvc     services.ISummaryService
+	heartbeatSrvc   services.IHeartbeatService
+	pairingSrvc     services.IPairingService
+	accessTokenSrvc services.IAccessTokenService
+}
+
+func NewTrayApiHandler(userService services.IUserService, summaryService services.ISummaryService, heartbeatService services.IHeartbeatService, pairingService services.IPairingService, accessTokenService services.IAccessTokenService) *TrayApiHandler {
+	return &TrayApiHandler{
+		config:          conf.Get(),
+		userSrvc:        userService,
+		summarySrvc:     summaryService,
+		heartbeatSrvc:   heartbeatService,
+		pairingSrvc:     pairingService,
+		accessTokenSrvc: accessTokenService,
+	}
+}
+
+func (h *TrayApiHandler) RegisterRoutes(router chi.Router) {
+	router.Post("/tray/pair", h.PostPair)
+	router.Post("/tray/pair/token", h.PostPairToken)
+
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAccessTokens(h.accessTokenSrvc, models.ScopeReadStats).Handler)
+		r.Get("/tray", h.Get)
+	})
+}
+
+// @Summary Retrieve today's coding time, current project and goal progress for companion apps
+// @Description Optimized for frequent polling. Supports long-polling by passing the previous response's ETag as If-None-Match header along with a wait parameter, in which case the request blocks until new data arrives or the timeout is reached (304).
+// @ID get-tray
+// @Tags tray
+// @Produce json
+// @Param wait query int false "Maximum number of seconds to wait for changes"
+// @Security ApiKeyAuth
+// @Success 200 {object} TrayViewModel
+// @Success 304
+// @Router /tray [get]
+func (h *TrayApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+
+	vm, err := h.buildViewModel(user)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to build tray view model for user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	etag := vm.etag()
+	if r.Header.Get("If-None-Match") == etag {
+		if h.waitForHeartbeat(r, user) {
+			if vm, err = h.buildViewModel(user); err == nil {
+				etag = vm.etag()
+			}
+		}
+		if err != nil || r.Header.Get("If-None-Match") == etag {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.Header().Set("ETag", etag)
+	helpers.RespondJSON(w, r, http.StatusOK, vm)
+}
+
+// @Summary Initiate the pairing of a companion app
+// @Description Shorthand for a device authorization request (see /oauth/device/code) with read-only scope. Returns a device code for polling and a short user code, which the user has to enter at the verification uri
+// @ID post-tray-pair
+// @Tags tray
+// @Produce json
+// @Success 201 {object} models.DevicePairing
+// @Router /tray/pair [post]
+func (h *TrayApiHandler) PostPair(w http.ResponseWriter, r *http.Request) {
+	pairing, err := h.pairingSrvc.Create(trayClientId, models.ScopeReadStats)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to create device pairing - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, pairing)
+}
+
+// @Summary Poll for the result of a pairing request
+// @Description Responds with 400 and error "authorization_pending" until the user has confirmed the pairing
+// @ID post-tray-pair-token
+// @Tags tray
+// @Produce json
+// @Param device_code formData string true "Device code"
+// @Success 200 {object} models.AccessTokenResponse
+// @Failure 400 {object} models.AccessTokenErrorResponse
+// @Router /tray/pair/token [post]
+func (h *TrayApiHandler) PostPairToken(w http.ResponseWriter, r *http.Request) {
+	respondDeviceToken(w, r, h.userSrvc, h.pairingSrvc, h.accessTokenSrvc, r.PostFormValue("device_code"), trayClientId)
+}
+
+func (h *TrayApiHandler) buildViewModel(user *models.User) (*TrayViewModel, error) {
+	err, from, to := helpers.ResolveIntervalTZ(models.IntervalToday, user.TZ())
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := h.summarySrvc.Aliased(from, to, user, h.summarySrvc.Retrieve, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	vm := &TrayViewModel{
+		Today: int64(summary.TotalTime().Seconds()),
+		Goal:  int64(user.DailyGoal().Seconds()),
+	}
+
+	if vm.Goal > 0 {
+		vm.GoalProgress = math.Min(float64(vm.Today)/float64(vm.Goal), 1)
+	}
+
+	if latest, err := h.heartbeatSrvc.GetLatestByUser(user); err == nil && latest != nil && time.Since(latest.Time.T()) <= trayCurrentProjectThreshold {
+		vm.Project = latest.Project
+	}
+
+	return vm, nil
+}
+
+// waitForHeartbeat blocks until a new heartbeat arrives for the given user, the requested wait time elapses or the client disconnects
+// returns whether a new heartbeat has arrived
+func (h *TrayApiHandler) waitForHeartbeat(r *http.Request, user *models.User) bool {
+	wait, _ := strconv.Atoi(r.URL.Query().Get("wait"))
+	if maxWait := h.config.Server.TimeoutSec - 5; wait > maxWait {
+		wait = maxWait // stay clear of the server's write timeout
+	}
+	if wait <= 0 {
+		return false
+	}
+
+	eventBus := conf.EventBus()
+	sub := eventBus.NonBlockingSubscribe(16, conf.EventHeartbeatCreate) // non-blocking to never stall heartbeat ingestion
+	defer eventBus.Unsubscribe(sub)
+
+	timeout := time.After(time.Duration(wait) * time.Second)
+	for {
+		select {
+		case m := <-sub.Receiver:
+			if hb, ok := m.Fields[conf.FieldPayload].(*models.Heartbeat); ok && hb.UserID == user.ID {
+				return true
+			}
+		case <-timeout:
+			return false
+		case <-r.Context().Done():
+			return false
+		}
+	}
+}
+
+func (vm *TrayViewModel) etag() string {
+	h := fnv.New64a()
+	h.Write([]byte(fmt.Sprintf("%d;%s;%d", vm.Today, vm.Project, vm.Goal)))
+	return fmt.Sprintf("\"%x\"", h.Sum64())
+}
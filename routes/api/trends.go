@@ -0,0 +1,68 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+type TrendsApiHandler struct {
+	config     *conf.Config
+	trendsSrvc services.ITrendsService
+}
+
+func NewTrendsApiHandler(trendsService services.ITrendsService) *TrendsApiHandler {
+	return &TrendsApiHandler{
+		config:     conf.Get(),
+		trendsSrvc: trendsService,
+	}
+}
+
+func (h *TrendsApiHandler) RegisterRoutes(router chi.Router) {
+	if !h.config.App.PublicTrends {
+		return
+	}
+	router.Get("/trends/languages", h.GetLanguages)
+}
+
+// @Summary Retrieve instance-wide language trends
+// @Description Time spent in each language across all users of this instance, per day or month (for intervals longer than two months). Languages used by only few users are omitted. Only available if enabled by the instance's operator.
+// @ID get-trends-languages
+// @Tags trends
+// @Produce json
+// @Param interval query string false "Interval to get trends for" default(12_months)
+// @Success 200 {object} models.LanguageTrends
+// @Failure 400
+// @Router /trends/languages [get]
+func (h *TrendsApiHandler) GetLanguages(w http.ResponseWriter, r *http.Request) {
+	interval := models.IntervalPast12Months
+	if intervalParam := r.URL.Query().Get("interval"); intervalParam != "" {
+		var err error
+		if interval, err = helpers.ParseInterval(intervalParam); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid interval"))
+			return
+		}
+	}
+
+	trends, err := h.trendsSrvc.GetLanguageTrends(interval)
+	if err != nil {
+		if errors.Is(err, services.ErrTrendsIntervalUnsupported) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		conf.Log().Request(r).Error("failed to compute language trends - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	helpers.RespondJSON(w, r, http.StatusOK, trends)
+}
@@ -17,18 +17,22 @@ import (
 )
 
 type BadgeHandler struct {
-	config      *conf.Config
-	userSrvc    services.IUserService
-	summarySrvc services.ISummaryService
-	cache       *cache.Cache
+	config          *conf.Config
+	userSrvc        services.IUserService
+	summarySrvc     services.ISummaryService
+	heartbeatSrvc   services.IHeartbeatService
+	savedFilterSrvc services.ISavedFilterService
+	cache           *cache.Cache
 }
 
-func NewBadgeHandler(summaryService services.ISummaryService, userService services.IUserService) *BadgeHandler {
+func NewBadgeHandler(summaryService services.ISummaryService, userService services.IUserService, heartbeatService services.IHeartbeatService, savedFilterService services.ISavedFilterService) *BadgeHandler {
 	return &BadgeHandler{
-		summarySrvc: summaryService,
-		userSrvc:    userService,
-		cache:       cache.New(time.Hour, time.Hour),
-		config:      conf.Get(),
+		summarySrvc:     summaryService,
+		userSrvc:        userService,
+		heartbeatSrvc:   heartbeatService,
+		savedFilterSrvc: savedFilterService,
+		cache:           cache.New(time.Hour, time.Hour),
+		config:          conf.Get(),
 	}
 }
 
@@ -44,7 +48,7 @@ func (h *BadgeHandler) RegisterRoutes(router chi.Router) {
 // @Produce json
 // @Param user path string true "User ID to fetch data for"
 // @Param interval path string true "Interval to aggregate data for" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
-// @Param filter path string true "Filter to apply (e.g. 'project:wakapi' or 'language:Go')"
+// @Param filter path string true "Filter to apply (e.g. 'project:wakapi', 'language:Go' or 'filter:<saved filter name>')"
 // @Success 200 {object} v1.BadgeData
 // @Router /compat/shields/v1/{user}/{interval}/{filter} [get]
 func (h *BadgeHandler) Get(w http.ResponseWriter, r *http.Request) {
@@ -54,14 +58,27 @@ func (h *BadgeHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	interval, filters, err := routeutils.GetBadgeParams(r.URL.Path, nil, user)
+	var savedFilter *models.SavedFilter
+	if name := routeutils.GetBadgeSavedFilterName(r.URL.Path); name != "" {
+		if savedFilter, err = h.savedFilterSrvc.GetByUserAndName(user, name); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("saved filter not found"))
+			return
+		}
+	}
+
+	interval, filters, err := routeutils.GetBadgeParams(r.URL.Path, nil, user, savedFilter)
 	if err != nil {
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte(err.Error()))
 		return
 	}
 
-	cacheKey := fmt.Sprintf("%s_%v_%s", user.ID, *interval.Key, filters.Hash())
+	// badges are cached for a while despite new heartbeats, but not beyond their summaries being invalidated (e.g. by deleting heartbeats)
+	cacheKey := routeutils.SummaryVariant(user, fmt.Sprintf("%v_%s_%d", *interval.Key, filters.Hash(), h.summarySrvc.DataVersion(user.ID).UnixNano()))
+	if routeutils.CheckNotModified(w, r, routeutils.SummaryVersion(h.heartbeatSrvc, h.summarySrvc, user), interval.Start, interval.End, cacheKey) {
+		return
+	}
 	if cacheResult, ok := h.cache.Get(cacheKey); ok {
 		helpers.RespondJSON(w, r, http.StatusOK, cacheResult.(*v1.BadgeData))
 		return
@@ -82,6 +99,9 @@ func (h *BadgeHandler) Get(w http.ResponseWriter, r *http.Request) {
 	}
 
 	vm := v1.NewBadgeDataFrom(summary)
+	if color, ok := routeutils.GetBadgeColor(h.config.App.GetLanguageColors(), filters, summary); ok {
+		vm.Color = color
+	}
 	h.cache.SetDefault(cacheKey, vm)
 	helpers.RespondJSON(w, r, http.StatusOK, vm)
 }
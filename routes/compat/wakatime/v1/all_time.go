@@ -15,22 +15,24 @@ import (
 )
 
 type AllTimeHandler struct {
-	config      *conf.Config
-	userSrvc    services.IUserService
-	summarySrvc services.ISummaryService
+	config          *conf.Config
+	userSrvc        services.IUserService
+	summarySrvc     services.ISummaryService
+	accessTokenSrvc services.IAccessTokenService
 }
 
-func NewAllTimeHandler(userService services.IUserService, summaryService services.ISummaryService) *AllTimeHandler {
+func NewAllTimeHandler(userService services.IUserService, summaryService services.ISummaryService, accessTokenService services.IAccessTokenService) *AllTimeHandler {
 	return &AllTimeHandler{
-		userSrvc:    userService,
-		summarySrvc: summaryService,
-		config:      conf.Get(),
+		userSrvc:        userService,
+		summarySrvc:     summaryService,
+		accessTokenSrvc: accessTokenService,
+		config:          conf.Get(),
 	}
 }
 
 func (h *AllTimeHandler) RegisterRoutes(router chi.Router) {
 	router.Group(func(r chi.Router) {
-		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAccessTokens(h.accessTokenSrvc, models.ScopeReadStats).Handler)
 		r.Get("/compat/wakatime/v1/users/{user}/all_time_since_today", h.Get)
 	})
 }
@@ -50,7 +52,14 @@ func (h *AllTimeHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return // response was already sent by util function
 	}
 
-	summary, err, status := h.loadUserSummary(user, helpers.ParseSummaryFilters(r))
+	filters, err := helpers.ParseSummaryFilters(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	summary, err, status := h.loadUserSummary(user, filters)
 	if err != nil {
 		w.WriteHeader(status)
 		w.Write([]byte(err.Error()))
@@ -0,0 +1,257 @@
+package v1
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/helpers"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+// in org mode, the whole instance is represented as a single organization with a single dashboard, which all (non-deactivated) users are members of
+// only admins may view the dashboard and its members' data, just like they are the ones to review members' data corrections
+const (
+	orgId                 = "default"
+	orgDashboardId        = "all-members"
+	orgDashboardName      = "All Members"
+	orgMembersPageSize    = 100
+	orgDurationDateFormat = "2006-01-02"
+)
+
+type OrgsHandler struct {
+	config       *conf.Config
+	userSrvc     services.IUserService
+	durationSrvc services.IDurationService
+}
+
+func NewOrgsHandler(userService services.IUserService, durationService services.IDurationService) *OrgsHandler {
+	return &OrgsHandler{
+		userSrvc:     userService,
+		durationSrvc: durationService,
+		config:       conf.Get(),
+	}
+}
+
+func (h *OrgsHandler) RegisterRoutes(router chi.Router) {
+	if !h.config.App.OrgMode {
+		return
+	}
+
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/compat/wakatime/v1/users/{user}/orgs", h.GetOrgs)
+		r.Get("/compat/wakatime/v1/users/{user}/orgs/{org}/dashboards", h.GetDashboards)
+		r.Get("/compat/wakatime/v1/users/{user}/orgs/{org}/dashboards/{dashboard}/members", h.GetMembers)
+		r.Get("/compat/wakatime/v1/users/{user}/orgs/{org}/dashboards/{dashboard}/members/{member}/durations", h.GetMemberDurations)
+	})
+}
+
+// @Summary List the user's organizations
+// @Description Mimics https://wakatime.com/developers#orgs. Only available in org mode, where the instance itself is the only organization.
+// @ID get-wakatime-orgs
+// @Tags wakatime
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {object} v1.OrgsViewModel
+// @Router /compat/wakatime/v1/users/{user}/orgs [get]
+func (h *OrgsHandler) GetOrgs(w http.ResponseWriter, r *http.Request) {
+	if _, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current"); err != nil {
+		return // response was already sent by util function
+	}
+
+	name := h.config.Server.GetPublicUrl()
+	if u, err := url.Parse(name); err == nil && u.Host != "" {
+		name = u.Host
+	}
+	tz, _ := time.Now().Zone()
+
+	helpers.RespondJSON(w, r, http.StatusOK, &v1.OrgsViewModel{
+		Data: []*v1.Org{{
+			ID:       orgId,
+			Name:     name,
+			Timeout:  int(services.HeartbeatDiffThreshold.Minutes()),
+			Timezone: tz,
+		}},
+		Pagination: v1.NewPagination(1, 1, 1),
+	})
+}
+
+// @Summary List the organization's dashboards
+// @Description Mimics https://wakatime.com/developers#org_dashboards. There is a single dashboard, which is only visible to admins.
+// @ID get-wakatime-org-dashboards
+// @Tags wakatime
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Param org path string true "Organization ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} v1.DashboardsViewModel
+// @Router /compat/wakatime/v1/users/{user}/orgs/{org}/dashboards [get]
+func (h *OrgsHandler) GetDashboards(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+	if chi.URLParam(r, "org") != orgId {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	dashboards := []*v1.Dashboard{}
+	if user.IsAdmin && middlewares.IsAdminIpAllowed(r) {
+		members, err := h.getMembers()
+		if err != nil {
+			conf.Log().Request(r).Error("failed to get org members - %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(conf.ErrInternalServerError))
+			return
+		}
+		dashboards = append(dashboards, &v1.Dashboard{
+			ID:                  orgDashboardId,
+			Name:                orgDashboardName,
+			MembersCount:        len(members),
+			IsCurrentUserMember: !user.IsDeactivated(),
+		})
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, &v1.DashboardsViewModel{
+		Data:       dashboards,
+		Pagination: v1.NewPagination(len(dashboards), 1, 1),
+	})
+}
+
+// @Summary List the dashboard's members
+// @Description Mimics https://wakatime.com/developers#org_dashboard_members. Requires admin permissions.
+// @ID get-wakatime-org-dashboard-members
+// @Tags wakatime
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Param org path string true "Organization ID"
+// @Param dashboard path string true "Dashboard ID"
+// @Param page query int false "Page number (starting at 1)"
+// @Security ApiKeyAuth
+// @Success 200 {object} v1.DashboardMembersViewModel
+// @Router /compat/wakatime/v1/users/{user}/orgs/{org}/dashboards/{dashboard}/members [get]
+func (h *OrgsHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.checkDashboardAccess(w, r); !ok {
+		return // response was already sent
+	}
+
+	members, err := h.getMembers()
+	if err != nil {
+		conf.Log().Request(r).Error("failed to get org members - %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	data := make([]*v1.DashboardMember, 0, orgMembersPageSize)
+	for i := (page - 1) * orgMembersPageSize; i < len(members) && i < page*orgMembersPageSize; i++ {
+		data = append(data, v1.NewDashboardMemberFrom(members[i]))
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, &v1.DashboardMembersViewModel{
+		Data:       data,
+		Pagination: v1.NewPagination(len(members), page, orgMembersPageSize),
+	})
+}
+
+// @Summary Retrieve a dashboard member's coding activity on the given day
+// @Description Mimics https://wakatime.com/developers#org_dashboard_member_durations. Requires admin permissions.
+// @ID get-wakatime-org-dashboard-member-durations
+// @Tags wakatime
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Param org path string true "Organization ID"
+// @Param dashboard path string true "Dashboard ID"
+// @Param member path string true "Member's user ID"
+// @Param date query string true "Day to retrieve durations for (e.g. '2021-02-07'), in the member's time zone"
+// @Param project query string false "Project to filter by"
+// @Security ApiKeyAuth
+// @Success 200 {object} v1.DurationsViewModel
+// @Router /compat/wakatime/v1/users/{user}/orgs/{org}/dashboards/{dashboard}/members/{member}/durations [get]
+func (h *OrgsHandler) GetMemberDurations(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.checkDashboardAccess(w, r); !ok {
+		return // response was already sent
+	}
+
+	member, err := h.userSrvc.GetUserById(chi.URLParam(r, "member"))
+	if err != nil || member.IsDeactivated() {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	from, err := time.ParseInLocation(orgDurationDateFormat, r.URL.Query().Get("date"), member.TZ())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("missing or invalid date"))
+		return
+	}
+	to := from.AddDate(0, 0, 1)
+
+	filters, err := helpers.ParseSummaryFilters(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	durations, err := h.durationSrvc.Get(from, to, member, filters)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to get durations for user '%s' - %v", member.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, v1.NewDurationsFrom(durations, from, to))
+}
+
+func (h *OrgsHandler) checkDashboardAccess(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return nil, false // response was already sent by util function
+	}
+	if chi.URLParam(r, "org") != orgId || chi.URLParam(r, "dashboard") != orgDashboardId {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return nil, false
+	}
+	if !user.IsAdmin || !middlewares.IsAdminIpAllowed(r) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(conf.ErrForbidden))
+		return nil, false
+	}
+	return user, true
+}
+
+func (h *OrgsHandler) getMembers() ([]*models.User, error) {
+	users, err := h.userSrvc.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]*models.User, 0, len(users))
+	for _, u := range users {
+		if !u.IsDeactivated() {
+			members = append(members, u)
+		}
+	}
+	return members, nil
+}
@@ -0,0 +1,117 @@
+package v1
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOrgsHandler(t *testing.T) {
+	config.Set(config.Empty())
+	config.Get().App.OrgMode = true
+
+	router := chi.NewRouter()
+	apiRouter := chi.NewRouter()
+	apiRouter.Use(middlewares.NewPrincipalMiddleware())
+	router.Mount("/api", apiRouter)
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByKey", "admin-user-api-key").Return(adminUser, nil)
+	userServiceMock.On("GetUserByKey", "basic-user-api-key").Return(basicUser, nil)
+	userServiceMock.On("GetUserById", "BasicUser").Return(basicUser, nil)
+	userServiceMock.On("GetAll").Return([]*models.User{adminUser, basicUser}, nil)
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	durationServiceMock := new(mocks.DurationServiceMock)
+	durationServiceMock.On("Get", day, day.AddDate(0, 0, 1), basicUser, mock.Anything).Return(models.Durations{
+		{Project: "wakapi", Language: "Go", Time: models.CustomTime(day.Add(time.Hour)), Duration: 90 * time.Second},
+	}, nil)
+
+	NewOrgsHandler(userServiceMock, durationServiceMock).RegisterRoutes(apiRouter)
+
+	request := func(path string, user *models.User) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/compat/wakatime/v1/users/current"+path, nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", base64.StdEncoding.EncodeToString([]byte(user.ApiKey))))
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("should list the instance as the only org", func(t *testing.T) {
+		rec := request("/orgs", basicUser)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var vm v1.OrgsViewModel
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(&vm))
+		assert.Len(t, vm.Data, 1)
+		assert.Equal(t, "default", vm.Data[0].ID)
+		assert.Equal(t, 1, vm.TotalPages)
+	})
+
+	t.Run("should only list dashboards to admins", func(t *testing.T) {
+		var vm v1.DashboardsViewModel
+		assert.Nil(t, json.NewDecoder(request("/orgs/default/dashboards", basicUser).Body).Decode(&vm))
+		assert.Empty(t, vm.Data)
+
+		assert.Nil(t, json.NewDecoder(request("/orgs/default/dashboards", adminUser).Body).Decode(&vm))
+		assert.Len(t, vm.Data, 1)
+		assert.Equal(t, 2, vm.Data[0].MembersCount)
+
+		assert.Equal(t, http.StatusNotFound, request("/orgs/other/dashboards", adminUser).Code)
+	})
+
+	t.Run("should list dashboard members to admins", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request("/orgs/default/dashboards/all-members/members", basicUser).Code)
+
+		var vm v1.DashboardMembersViewModel
+		rec := request("/orgs/default/dashboards/all-members/members", adminUser)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(&vm))
+		assert.Len(t, vm.Data, 2)
+		assert.Equal(t, "BasicUser", vm.Data[1].Username)
+		assert.Nil(t, vm.NextPage)
+
+		assert.Nil(t, json.NewDecoder(request("/orgs/default/dashboards/all-members/members?page=2", adminUser).Body).Decode(&vm))
+		assert.Empty(t, vm.Data)
+		assert.Equal(t, 1, *vm.PrevPage)
+	})
+
+	t.Run("should return member durations to admins", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request("/orgs/default/dashboards/all-members/members/BasicUser/durations?date=2024-03-01", basicUser).Code)
+		assert.Equal(t, http.StatusBadRequest, request("/orgs/default/dashboards/all-members/members/BasicUser/durations", adminUser).Code)
+
+		var vm v1.DurationsViewModel
+		rec := request("/orgs/default/dashboards/all-members/members/BasicUser/durations?date=2024-03-01", adminUser)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(&vm))
+		assert.Len(t, vm.Data, 1)
+		assert.Equal(t, "wakapi", vm.Data[0].Project)
+		assert.Equal(t, 90.0, vm.Data[0].Duration)
+		assert.Equal(t, float64(day.Add(time.Hour).Unix()), vm.Data[0].Time)
+	})
+}
+
+func TestOrgsHandler_NoOrgMode(t *testing.T) {
+	config.Set(config.Empty())
+
+	router := chi.NewRouter()
+	userServiceMock := new(mocks.UserServiceMock)
+	NewOrgsHandler(userServiceMock, new(mocks.DurationServiceMock)).RegisterRoutes(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/compat/wakatime/v1/users/current/orgs", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
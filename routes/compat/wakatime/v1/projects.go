@@ -2,7 +2,8 @@ package v1
 
 import (
 	"net/http"
-	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,40 +12,48 @@ import (
 
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
 	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
 	routeutils "github.com/muety/wakapi/routes/utils"
 	"github.com/muety/wakapi/services"
 	"github.com/muety/wakapi/utils"
 )
 
+const projectsPageSize = 100
+
 type ProjectsHandler struct {
-	config        *conf.Config
-	userSrvc      services.IUserService
-	heartbeatSrvc services.IHeartbeatService
+	config            *conf.Config
+	userSrvc          services.IUserService
+	heartbeatSrvc     services.IHeartbeatService
+	projectRemoteSrvc services.IProjectRemoteService
 }
 
-func NewProjectsHandler(userService services.IUserService, heartbeatsService services.IHeartbeatService) *ProjectsHandler {
+func NewProjectsHandler(userService services.IUserService, heartbeatsService services.IHeartbeatService, projectRemoteService services.IProjectRemoteService) *ProjectsHandler {
 	return &ProjectsHandler{
-		userSrvc:      userService,
-		heartbeatSrvc: heartbeatsService,
-		config:        conf.Get(),
+		userSrvc:          userService,
+		heartbeatSrvc:     heartbeatsService,
+		projectRemoteSrvc: projectRemoteService,
+		config:            conf.Get(),
 	}
 }
 
 func (h *ProjectsHandler) RegisterRoutes(router chi.Router) {
 	router.Group(func(r chi.Router) {
 		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/users/{user}/projects", h.Get)
+		r.Get("/v1/users/{user}/projects", h.Get)
 		r.Get("/compat/wakatime/v1/users/{user}/projects", h.Get)
 	})
 }
 
-// @Summary Retrieve and fitler the user's projects
-// @Description Mimics https://wakatime.com/developers#projects
+// @Summary Retrieve and filter the user's projects
+// @Description Mimics https://wakatime.com/developers#projects. Projects are sorted by their latest heartbeat, most recent first.
 // @ID get-wakatime-projects
 // @Tags wakatime
 // @Produce json
 // @Param user path string true "User ID to fetch data for (or 'current')"
-// @Param q query string true "Query to filter projects by"
+// @Param q query string false "Query to filter projects by (case-insensitive substring of the project name)"
+// @Param page query int false "Page number (starting at 1)"
 // @Security ApiKeyAuth
 // @Success 200 {object} v1.ProjectsViewModel
 // @Router /compat/wakatime/v1/users/{user}/projects [get]
@@ -54,30 +63,82 @@ func (h *ProjectsHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return // response was already sent by util function
 	}
 
-	results, err := h.heartbeatSrvc.GetUserProjectStats(user, time.Time{}, utils.BeginOfToday(time.Local), nil, false)
+	results, err := h.getProjectStats(user)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("something went wrong"))
+		w.Write([]byte(conf.ErrInternalServerError))
 		conf.Log().Request(r).Error(err.Error())
 		return
 	}
 
-	q := r.URL.Query().Get("q")
+	remotes, err := h.projectRemoteSrvc.GetByUser(user.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error(err.Error())
+		return
+	}
+	remotesByProject := make(map[string]*models.ProjectRemote, len(remotes))
+	for _, remote := range remotes {
+		remotesByProject[remote.Project] = remote
+	}
 
-	projects := make([]*v1.Project, 0, len(results))
+	q := strings.ToLower(r.URL.Query().Get("q"))
+
+	filtered := make([]*models.ProjectStats, 0, len(results))
 	for _, p := range results {
-		if strings.HasPrefix(p.Project, q) {
-			projects = append(projects, &v1.Project{
-				ID:                           p.Project,
-				Name:                         p.Project,
-				LastHeartbeatAt:              p.Last.T(),
-				HumanReadableLastHeartbeatAt: helpers.FormatDateTimeHuman(p.Last.T()),
-				UrlencodedName:               url.QueryEscape(p.Project),
-				CreatedAt:                    p.First.T(),
-			})
+		if strings.Contains(strings.ToLower(p.Project), q) {
+			filtered = append(filtered, p)
 		}
 	}
 
-	vm := &v1.ProjectsViewModel{Data: projects}
-	helpers.RespondJSON(w, r, http.StatusOK, vm)
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	projects := make([]*v1.Project, 0, projectsPageSize)
+	for i := (page - 1) * projectsPageSize; i < len(filtered) && i < page*projectsPageSize; i++ {
+		projects = append(projects, v1.NewProjectFrom(filtered[i], remotesByProject[filtered[i].Project]))
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, &v1.ProjectsViewModel{
+		Data:       projects,
+		Pagination: v1.NewPagination(len(filtered), page, projectsPageSize),
+	})
+}
+
+// getProjectStats combines the (cached) stats up to the beginning of today with those of today, so that latest heartbeats are up-to-date and projects first seen today are included
+func (h *ProjectsHandler) getProjectStats(user *models.User) ([]*models.ProjectStats, error) {
+	past, err := h.heartbeatSrvc.GetUserProjectStats(user, time.Time{}, utils.BeginOfToday(time.Local), nil, false)
+	if err != nil {
+		return nil, err
+	}
+	today, err := h.heartbeatSrvc.GetUserProjectStats(user, utils.BeginOfToday(time.Local), time.Time{}, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	statsByProject := make(map[string]*models.ProjectStats, len(past)+len(today))
+	results := make([]*models.ProjectStats, 0, len(past)+len(today))
+	for _, p := range past {
+		merged := *p // don't modify cached stats
+		statsByProject[p.Project] = &merged
+		results = append(results, &merged)
+	}
+	for _, p := range today {
+		if existing, ok := statsByProject[p.Project]; ok {
+			existing.Count += p.Count
+			existing.Last = p.Last
+			continue
+		}
+		merged := *p
+		statsByProject[p.Project] = &merged
+		results = append(results, &merged)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Last.T().After(results[j].Last.T())
+	})
+	return results, nil
 }
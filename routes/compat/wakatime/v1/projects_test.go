@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestProjectsHandler_Get(t *testing.T) {
+	config.Set(config.Empty())
+
+	router := chi.NewRouter()
+	apiRouter := chi.NewRouter()
+	apiRouter.Use(middlewares.NewPrincipalMiddleware())
+	router.Mount("/api", apiRouter)
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByKey", basicUser.ApiKey).Return(basicUser, nil)
+
+	t0 := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
+	heartbeatServiceMock.On("GetUserProjectStats", basicUser, time.Time{}, mock.Anything, mock.Anything, false).Return([]*models.ProjectStats{
+		{Project: "wakapi", Count: 10, First: models.CustomTime(t0), Last: models.CustomTime(t0.Add(2 * time.Hour))},
+		{Project: "anchr", Count: 5, First: models.CustomTime(t0), Last: models.CustomTime(t0.Add(1 * time.Hour))},
+		{Project: "Wakapi-Plugins", Count: 1, First: models.CustomTime(t0), Last: models.CustomTime(t0)},
+	}, nil)
+	heartbeatServiceMock.On("GetUserProjectStats", basicUser, utils.BeginOfToday(time.Local), time.Time{}, mock.Anything, true).Return([]*models.ProjectStats{
+		{Project: "anchr", Count: 2, First: models.CustomTime(t0.Add(24 * time.Hour)), Last: models.CustomTime(t0.Add(25 * time.Hour))},
+		{Project: "new-project", Count: 1, First: models.CustomTime(t0.Add(24 * time.Hour)), Last: models.CustomTime(t0.Add(24 * time.Hour))},
+	}, nil)
+
+	projectRemoteServiceMock := new(mocks.ProjectRemoteServiceMock)
+	projectRemoteServiceMock.On("GetByUser", basicUser.ID).Return([]*models.ProjectRemote{
+		{Project: "wakapi", Remote: "git@github.com:muety/wakapi.git"},
+	}, nil)
+
+	NewProjectsHandler(userServiceMock, heartbeatServiceMock, projectRemoteServiceMock).RegisterRoutes(apiRouter)
+
+	get := func(path string) *v1.ProjectsViewModel {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", base64.StdEncoding.EncodeToString([]byte(basicUser.ApiKey))))
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var result v1.ProjectsViewModel
+		assert.Nil(t, json.NewDecoder(rec.Body).Decode(&result))
+		return &result
+	}
+
+	t.Run("when listing all projects", func(t *testing.T) {
+		result := get("/api/users/current/projects")
+		assert.Len(t, result.Data, 4)
+		assert.Equal(t, 4, result.Total)
+		assert.Equal(t, 1, result.TotalPages)
+		assert.Nil(t, result.NextPage)
+
+		// sorted by latest heartbeat, including today's
+		assert.Equal(t, "anchr", result.Data[0].Name)
+		assert.Equal(t, t0.Add(25*time.Hour), result.Data[0].LastHeartbeatAt.UTC())
+		assert.Equal(t, t0, result.Data[0].FirstHeartbeatAt.UTC())
+		assert.Equal(t, "new-project", result.Data[1].Name)
+		assert.Equal(t, "wakapi", result.Data[2].Name)
+
+		assert.Nil(t, result.Data[0].Repository)
+		assert.NotNil(t, result.Data[2].Repository)
+		assert.Equal(t, "https://github.com/muety/wakapi", result.Data[2].Repository.Url)
+		assert.Equal(t, "muety/wakapi", result.Data[2].Repository.FullName)
+		assert.Equal(t, "wakapi", result.Data[2].Repository.Name)
+	})
+
+	t.Run("when searching projects", func(t *testing.T) {
+		result := get("/api/compat/wakatime/v1/users/current/projects?q=WAKA")
+		assert.Len(t, result.Data, 2)
+		assert.Equal(t, "wakapi", result.Data[0].Name)
+		assert.Equal(t, "Wakapi-Plugins", result.Data[1].Name)
+	})
+
+	t.Run("when requesting a page beyond the last", func(t *testing.T) {
+		result := get("/api/v1/users/current/projects?page=2")
+		assert.Empty(t, result.Data)
+		assert.Equal(t, 2, result.Page)
+		assert.NotNil(t, result.PrevPage)
+	})
+}
@@ -15,23 +15,25 @@ import (
 )
 
 type StatsHandler struct {
-	config      *conf.Config
-	userSrvc    services.IUserService
-	summarySrvc services.ISummaryService
+	config          *conf.Config
+	userSrvc        services.IUserService
+	summarySrvc     services.ISummaryService
+	accessTokenSrvc services.IAccessTokenService
 }
 
-func NewStatsHandler(userService services.IUserService, summaryService services.ISummaryService) *StatsHandler {
+func NewStatsHandler(userService services.IUserService, summaryService services.ISummaryService, accessTokenService services.IAccessTokenService) *StatsHandler {
 	return &StatsHandler{
-		userSrvc:    userService,
-		summarySrvc: summaryService,
-		config:      conf.Get(),
+		userSrvc:        userService,
+		summarySrvc:     summaryService,
+		accessTokenSrvc: accessTokenService,
+		config:          conf.Get(),
 	}
 }
 
 func (h *StatsHandler) RegisterRoutes(router chi.Router) {
 	router.Group(func(r chi.Router) {
 		r.Use(
-			middlewares.NewAuthenticateMiddleware(h.userSrvc).WithOptionalFor([]string{"/"}).Handler,
+			middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAccessTokens(h.accessTokenSrvc, models.ScopeReadStats).WithOptionalFor([]string{"/"}).Handler,
 		)
 		r.Get("/v1/users/{user}/stats/{range}", h.Get)
 		r.Get("/compat/wakatime/v1/users/{user}/stats/{range}", h.Get)
@@ -102,7 +104,14 @@ func (h *StatsHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	summary, err, status := h.loadUserSummary(requestedUser, rangeFrom, rangeTo, helpers.ParseSummaryFilters(r))
+	filters, err := helpers.ParseSummaryFilters(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	summary, err, status := h.loadUserSummary(requestedUser, rangeFrom, rangeTo, filters)
 	if err != nil {
 		w.WriteHeader(status)
 		w.Write([]byte(err.Error()))
@@ -15,43 +15,43 @@ import (
 	"github.com/muety/wakapi/services"
 )
 
-type StatusBarViewModel struct {
-	CachedAt time.Time        `json:"cached_at"`
-	Data     v1.SummariesData `json:"data"`
-}
-
 type StatusBarHandler struct {
-	config      *conf.Config
-	userSrvc    services.IUserService
-	summarySrvc services.ISummaryService
+	config          *conf.Config
+	userSrvc        services.IUserService
+	summarySrvc     services.ISummaryService
+	accessTokenSrvc services.IAccessTokenService
 }
 
-func NewStatusBarHandler(userService services.IUserService, summaryService services.ISummaryService) *StatusBarHandler {
+func NewStatusBarHandler(userService services.IUserService, summaryService services.ISummaryService, accessTokenService services.IAccessTokenService) *StatusBarHandler {
 	return &StatusBarHandler{
-		userSrvc:    userService,
-		summarySrvc: summaryService,
-		config:      conf.Get(),
+		userSrvc:        userService,
+		summarySrvc:     summaryService,
+		accessTokenSrvc: accessTokenService,
+		config:          conf.Get(),
 	}
 }
 
 func (h *StatusBarHandler) RegisterRoutes(router chi.Router) {
 	router.Group(func(r chi.Router) {
-		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAccessTokens(h.accessTokenSrvc, models.ScopeReadStats).Handler)
 		r.Get("/users/{user}/statusbar/{range}", h.Get)
 		r.Get("/v1/users/{user}/statusbar/{range}", h.Get)
 		r.Get("/compat/wakatime/v1/users/{user}/statusbar/{range}", h.Get)
+		r.Get("/users/{user}/status_bar/{range}", h.Get)
+		r.Get("/v1/users/{user}/status_bar/{range}", h.Get)
+		r.Get("/compat/wakatime/v1/users/{user}/status_bar/{range}", h.Get)
 	})
 }
 
 // @Summary Retrieve summary for statusbar
-// @Description Mimics https://wakatime.com/api/v1/users/current/statusbar/today. Have no official documentation
+// @Description Mimics https://wakatime.com/api/v1/users/current/status_bar/today, as queried by the editor plugins for their status bar text. Have no official documentation. Heartbeat categories are not tracked, so all time is reported as "Coding".
 // @ID statusbar
 // @Tags wakatime
 // @Produce json
 // @Param user path string true "User ID to fetch data for (or 'current')"
 // @Security ApiKeyAuth
-// @Success 200 {object} StatusBarViewModel
-// @Router /users/{user}/statusbar/today [get]
+// @Success 200 {object} v1.StatusBarViewModel
+// @Router /users/{user}/status_bar/today [get]
 func (h *StatusBarHandler) Get(w http.ResponseWriter, r *http.Request) {
 	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
 	if err != nil {
@@ -63,7 +63,14 @@ func (h *StatusBarHandler) Get(w http.ResponseWriter, r *http.Request) {
 		rangeParam = (*models.IntervalToday)[0]
 	}
 
-	err, rangeFrom, rangeTo := helpers.ResolveIntervalRawTZ(rangeParam, user.TZ())
+	interval, err := helpers.ParseInterval(rangeParam)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid range"))
+		return
+	}
+
+	err, rangeFrom, rangeTo := helpers.ResolveIntervalTZ(interval, user.TZ())
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte("invalid range"))
@@ -76,11 +83,7 @@ func (h *StatusBarHandler) Get(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(err.Error()))
 		return
 	}
-	summariesView := v1.NewSummariesFrom([]*models.Summary{summary})
-	helpers.RespondJSON(w, r, http.StatusOK, StatusBarViewModel{
-		CachedAt: time.Now(),
-		Data:     *summariesView.Data[0],
-	})
+	helpers.RespondJSON(w, r, http.StatusOK, v1.NewStatusBarFrom(summary, interval, rangeFrom, rangeTo))
 }
 
 func (h *StatusBarHandler) loadUserSummary(user *models.User, start, end time.Time) (*models.Summary, int, error) {
@@ -0,0 +1,69 @@
+package v1
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStatusBarHandler_Get(t *testing.T) {
+	config.Set(config.Empty())
+
+	router := chi.NewRouter()
+	apiRouter := chi.NewRouter()
+	apiRouter.Use(middlewares.NewPrincipalMiddleware())
+	router.Mount("/api", apiRouter)
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByKey", basicUser.ApiKey).Return(basicUser, nil)
+
+	summaryServiceMock := new(mocks.SummaryServiceMock)
+	summaryServiceMock.On("Aliased", mock.Anything, mock.Anything, basicUser, mock.Anything, mock.Anything, false).Return(&models.Summary{
+		UserID: basicUser.ID,
+		Projects: []*models.SummaryItem{
+			{Type: models.SummaryProject, Key: "wakapi", Total: 3600 + 15*60},
+			{Type: models.SummaryProject, Key: "anchr", Total: 45 * 60},
+		},
+		Languages: []*models.SummaryItem{
+			{Type: models.SummaryLanguage, Key: "Go", Total: 2 * 3600},
+		},
+	}, nil)
+
+	NewStatusBarHandler(userServiceMock, summaryServiceMock, nil).RegisterRoutes(apiRouter)
+
+	for _, path := range []string{"/api/compat/wakatime/v1/users/current/status_bar/today", "/api/users/current/statusbar/today"} {
+		t.Run(path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", base64.StdEncoding.EncodeToString([]byte(basicUser.ApiKey))))
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var result v1.StatusBarViewModel
+			assert.Nil(t, json.NewDecoder(rec.Body).Decode(&result))
+			assert.Equal(t, "2:00", result.Data.GrandTotal.Digital)
+			assert.Equal(t, "2 hrs 0 mins", result.Data.GrandTotal.Text)
+			assert.Equal(t, 7200.0, result.Data.GrandTotal.TotalSeconds)
+			assert.Len(t, result.Data.Categories, 1)
+			assert.Equal(t, v1.StatusBarCategoryCoding, result.Data.Categories[0].Name)
+			assert.Equal(t, 100.0, result.Data.Categories[0].Percent)
+			assert.Len(t, result.Data.Projects, 2)
+			assert.Equal(t, "1:15", result.Data.Projects[0].Digital)
+			assert.Equal(t, 62.5, result.Data.Projects[0].Percent)
+			assert.Equal(t, "Today", result.Data.Range.Text)
+		})
+	}
+}
@@ -19,22 +19,24 @@ import (
 )
 
 type SummariesHandler struct {
-	config      *conf.Config
-	userSrvc    services.IUserService
-	summarySrvc services.ISummaryService
+	config          *conf.Config
+	userSrvc        services.IUserService
+	summarySrvc     services.ISummaryService
+	accessTokenSrvc services.IAccessTokenService
 }
 
-func NewSummariesHandler(userService services.IUserService, summaryService services.ISummaryService) *SummariesHandler {
+func NewSummariesHandler(userService services.IUserService, summaryService services.ISummaryService, accessTokenService services.IAccessTokenService) *SummariesHandler {
 	return &SummariesHandler{
-		userSrvc:    userService,
-		summarySrvc: summaryService,
-		config:      conf.Get(),
+		userSrvc:        userService,
+		summarySrvc:     summaryService,
+		accessTokenSrvc: accessTokenService,
+		config:          conf.Get(),
 	}
 }
 
 func (h *SummariesHandler) RegisterRoutes(router chi.Router) {
 	router.Group(func(r chi.Router) {
-		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAccessTokens(h.accessTokenSrvc, models.ScopeReadStats).Handler)
 		r.Get("/compat/wakatime/v1/users/{user}/summaries", h.Get)
 	})
 }
@@ -136,7 +138,10 @@ func (h *SummariesHandler) loadUserSummaries(r *http.Request, user *models.User)
 	summaries := make([]*models.Summary, len(intervals))
 
 	// filtering
-	filters := helpers.ParseSummaryFilters(r)
+	filters, err := helpers.ParseSummaryFilters(r)
+	if err != nil {
+		return nil, err, http.StatusBadRequest
+	}
 
 	for i, interval := range intervals {
 		summary, err := h.summarySrvc.Aliased(interval[0], interval[1], user, h.summarySrvc.Retrieve, filters, end.After(time.Now()))
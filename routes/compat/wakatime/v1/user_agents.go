@@ -0,0 +1,60 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type UserAgentsHandler struct {
+	config        *conf.Config
+	userSrvc      services.IUserService
+	heartbeatSrvc services.IHeartbeatService
+}
+
+func NewUserAgentsHandler(userService services.IUserService, heartbeatsService services.IHeartbeatService) *UserAgentsHandler {
+	return &UserAgentsHandler{
+		userSrvc:      userService,
+		heartbeatSrvc: heartbeatsService,
+		config:        conf.Get(),
+	}
+}
+
+func (h *UserAgentsHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Get("/compat/wakatime/v1/users/{user}/user_agents", h.Get)
+	})
+}
+
+// @Summary Retrieve the user's user agents
+// @Description Mimics https://wakatime.com/developers#user_agents
+// @ID get-wakatime-user-agents
+// @Tags wakatime
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {object} v1.UserAgentsViewModel
+// @Router /compat/wakatime/v1/users/{user}/user_agents [get]
+func (h *UserAgentsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	results, err := h.heartbeatSrvc.GetUserAgentsByUser(user)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to fetch user agents - %v", err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, v1.NewUserAgentsFrom(results))
+}
@@ -24,6 +24,7 @@ type HomeHandler struct {
 var loginDecoder = schema.NewDecoder()
 var signupDecoder = schema.NewDecoder()
 var resetPasswordDecoder = schema.NewDecoder()
+var deleteAccountDecoder = schema.NewDecoder()
 
 func NewHomeHandler(keyValueService services.IKeyValueService) *HomeHandler {
 	return &HomeHandler{
@@ -58,19 +59,26 @@ func (h *HomeHandler) buildViewModel(r *http.Request, w http.ResponseWriter) *vi
 	var totalUsers int
 	var newsbox view.Newsbox
 
-	if kv, err := h.keyValueSrvc.GetString(conf.KeyLatestTotalTime); err == nil && kv != nil && kv.Value != "" {
+	keyTotalTime, keyTotalUsers := conf.KeyLatestTotalTime, conf.KeyLatestTotalUsers
+	if h.config.App.PublicStatsPrivacy.Enabled {
+		keyTotalTime, keyTotalUsers = conf.KeyLatestTotalTimePublic, conf.KeyLatestTotalUsersPublic
+	}
+
+	if kv, err := h.keyValueSrvc.GetString(keyTotalTime); err == nil && kv != nil && kv.Value != "" {
 		if d, err := time.ParseDuration(kv.Value); err == nil {
 			totalHours = int(d.Hours())
 		}
 	}
 
-	if kv, err := h.keyValueSrvc.GetString(conf.KeyLatestTotalUsers); err == nil && kv != nil && kv.Value != "" {
+	if kv, err := h.keyValueSrvc.GetString(keyTotalUsers); err == nil && kv != nil && kv.Value != "" {
 		if d, err := strconv.Atoi(kv.Value); err == nil {
 			totalUsers = d
 		}
 	}
 
-	if kv, err := h.keyValueSrvc.GetString(conf.KeyNewsbox); err == nil && kv != nil && kv.Value != "" {
+	if configured := conf.Get().App.Newsbox; configured.Text != "" {
+		newsbox = view.Newsbox{Type: configured.Type, Text: configured.Text}
+	} else if kv, err := h.keyValueSrvc.GetString(conf.KeyNewsbox); err == nil && kv != nil && kv.Value != "" {
 		if err := json.NewDecoder(strings.NewReader(kv.Value)).Decode(&newsbox); err != nil {
 			conf.Log().Request(r).Error("failed to decode newsbox message - %v", err)
 		}
@@ -79,6 +87,7 @@ func (h *HomeHandler) buildViewModel(r *http.Request, w http.ResponseWriter) *vi
 	vm := &view.HomeViewModel{
 		TotalHours: totalHours,
 		TotalUsers: totalUsers,
+		HideTotals: totalUsers == 0, // not computed yet or suppressed for privacy reasons
 		Newsbox:    &newsbox,
 	}
 	return routeutils.WithSessionMessages(vm, r, w)
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
 	"github.com/emvi/logbuch"
 	"github.com/go-chi/chi/v5"
@@ -11,22 +12,30 @@ import (
 	routeutils "github.com/muety/wakapi/routes/utils"
 	"github.com/muety/wakapi/services"
 	"github.com/muety/wakapi/utils"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 )
 
 type LoginHandler struct {
-	config   *conf.Config
-	userSrvc services.IUserService
-	mailSrvc services.IMailService
+	config         *conf.Config
+	userSrvc       services.IUserService
+	mailSrvc       services.IMailService
+	invitationSrvc services.IInvitationService
+	captchaSrvc    services.ICaptchaService
+	throttleSrvc   services.ILoginThrottleService
 }
 
-func NewLoginHandler(userService services.IUserService, mailService services.IMailService) *LoginHandler {
+func NewLoginHandler(userService services.IUserService, mailService services.IMailService, invitationService services.IInvitationService, captchaService services.ICaptchaService, loginThrottleService services.ILoginThrottleService) *LoginHandler {
 	return &LoginHandler{
-		config:   conf.Get(),
-		userSrvc: userService,
-		mailSrvc: mailService,
+		config:         conf.Get(),
+		userSrvc:       userService,
+		mailSrvc:       mailService,
+		invitationSrvc: invitationService,
+		captchaSrvc:    captchaService,
+		throttleSrvc:   loginThrottleService,
 	}
 }
 
@@ -39,6 +48,9 @@ func (h *LoginHandler) RegisterRoutes(router chi.Router) {
 	router.Post("/set-password", h.PostSetPassword)
 	router.Get("/reset-password", h.GetResetPassword)
 	router.Post("/reset-password", h.PostResetPassword)
+	router.Get("/delete-account", h.GetDeleteAccount)
+	router.Post("/delete-account", h.PostDeleteAccount)
+	router.Get("/verify-email", h.GetVerifyEmail)
 
 	authMiddleware := middlewares.NewAuthenticateMiddleware(h.userSrvc).
 		WithRedirectTarget(defaultErrorRedirectTarget()).
@@ -86,18 +98,35 @@ func (h *LoginHandler) PostLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	remoteIp := middlewares.ClientIp(r)
+	if wait := h.throttleSrvc.Check(login.Username, remoteIp); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		w.WriteHeader(http.StatusTooManyRequests)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w).WithError(fmt.Sprintf("too many failed login attempts, please try again in %s", wait.Round(time.Second))))
+		return
+	}
+
 	user, err := h.userSrvc.GetUserById(login.Username)
 	if err != nil {
+		h.throttleSrvc.RecordFailure(nil, login.Username, remoteIp)
 		w.WriteHeader(http.StatusNotFound)
 		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w).WithError("resource not found"))
 		return
 	}
 
 	if !utils.ComparePassword(user.Password, login.Password, h.config.Security.PasswordSalt) {
+		h.throttleSrvc.RecordFailure(user, login.Username, remoteIp)
 		w.WriteHeader(http.StatusUnauthorized)
 		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w).WithError("invalid credentials"))
 		return
 	}
+	h.throttleSrvc.RecordSuccess(login.Username)
+
+	if user.IsSuspended() {
+		w.WriteHeader(http.StatusForbidden)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w).WithError("your account has been suspended"))
+		return
+	}
 
 	encoded, err := h.config.Security.SecureCookie.Encode(models.AuthCookieKey, login.Username)
 	if err != nil {
@@ -136,7 +165,17 @@ func (h *LoginHandler) GetSignup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	templates[conf.SignupTemplate].Execute(w, h.buildViewModel(r, w))
+	vm := h.buildViewModel(r, w)
+	if code := r.URL.Query().Get("invite"); code != "" && !vm.AllowSignup {
+		if invitation, err := h.invitationSrvc.GetByCode(code); err == nil && invitation.IsUsable(time.Now()) {
+			vm.AllowSignup = true
+			vm.InviteCode = code
+		} else {
+			vm.SetError("invitation is invalid or expired")
+		}
+	}
+
+	templates[conf.SignupTemplate].Execute(w, vm)
 }
 
 func (h *LoginHandler) PostSignup(w http.ResponseWriter, r *http.Request) {
@@ -144,12 +183,6 @@ func (h *LoginHandler) PostSignup(w http.ResponseWriter, r *http.Request) {
 		loadTemplates()
 	}
 
-	if !h.config.IsDev() && !h.config.Security.AllowSignup {
-		w.WriteHeader(http.StatusForbidden)
-		templates[conf.SignupTemplate].Execute(w, h.buildViewModel(r, w).WithError("registration is disabled on this server"))
-		return
-	}
-
 	if cookie, err := r.Cookie(models.AuthCookieKey); err == nil && cookie.Value != "" {
 		http.Redirect(w, r, fmt.Sprintf("%s/summary", h.config.Server.BasePath), http.StatusFound)
 		return
@@ -161,32 +194,73 @@ func (h *LoginHandler) PostSignup(w http.ResponseWriter, r *http.Request) {
 		templates[conf.SignupTemplate].Execute(w, h.buildViewModel(r, w).WithError("missing parameters"))
 		return
 	}
+	if err := h.captchaSrvc.Verify(r.PostForm, middlewares.ClientIp(r)); err != nil {
+		h.logCaptchaError(r, err)
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.SignupTemplate].Execute(w, h.buildSignupViewModel(r, w, r.PostForm.Get("invite_code")).WithError("captcha verification failed, please try again"))
+		return
+	}
 	if err := signupDecoder.Decode(&signup, r.PostForm); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		templates[conf.SignupTemplate].Execute(w, h.buildViewModel(r, w).WithError("missing parameters"))
 		return
 	}
 
+	signupEnabled := h.config.IsDev() || h.config.Security.AllowSignup
+	if !signupEnabled && signup.InviteCode == "" {
+		w.WriteHeader(http.StatusForbidden)
+		templates[conf.SignupTemplate].Execute(w, h.buildViewModel(r, w).WithError("registration is disabled on this server"))
+		return
+	}
+
 	if !signup.IsValid() {
 		w.WriteHeader(http.StatusBadRequest)
-		templates[conf.SignupTemplate].Execute(w, h.buildViewModel(r, w).WithError("invalid parameters"))
+		templates[conf.SignupTemplate].Execute(w, h.buildSignupViewModel(r, w, signup.InviteCode).WithError("invalid parameters"))
 		return
 	}
 
+	// invitations are only needed (and consumed) while public registration is disabled
+	var invitation *models.Invitation
+	if !signupEnabled {
+		var err error
+		if invitation, err = h.invitationSrvc.Redeem(signup.InviteCode); err != nil {
+			if !errors.Is(err, services.ErrInvitationInvalid) {
+				conf.Log().Request(r).Error("failed to redeem invitation - %v", err)
+			}
+			w.WriteHeader(http.StatusForbidden)
+			templates[conf.SignupTemplate].Execute(w, h.buildViewModel(r, w).WithError("invitation is invalid or expired"))
+			return
+		}
+	}
+
 	numUsers, _ := h.userSrvc.Count()
 
-	_, created, err := h.userSrvc.CreateOrGet(&signup, numUsers == 0)
+	user, created, err := h.userSrvc.CreateOrGet(&signup, numUsers == 0)
+	if err != nil || !created {
+		if invitation != nil {
+			if err := h.invitationSrvc.Release(invitation); err != nil {
+				conf.Log().Request(r).Error("failed to release invitation %d - %v", invitation.ID, err)
+			}
+		}
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		conf.Log().Request(r).Error("failed to create new user - %v", err)
-		templates[conf.SignupTemplate].Execute(w, h.buildViewModel(r, w).WithError("failed to create new user"))
+		templates[conf.SignupTemplate].Execute(w, h.buildSignupViewModel(r, w, signup.InviteCode).WithError("failed to create new user"))
 		return
 	}
 	if !created {
 		w.WriteHeader(http.StatusConflict)
-		templates[conf.SignupTemplate].Execute(w, h.buildViewModel(r, w).WithError("user already existing"))
+		templates[conf.SignupTemplate].Execute(w, h.buildSignupViewModel(r, w, signup.InviteCode).WithError("user already existing"))
 		return
 	}
+	if invitation != nil {
+		logbuch.Info("user '%s' signed up using invitation %d", user.ID, invitation.ID)
+	}
+
+	if conf.Get().Mail.Enabled && user.Email != "" {
+		requestEmailVerification(r, user, h.userSrvc, h.mailSrvc)
+	}
 
 	routeutils.SetSuccess(r, w, "account created successfully")
 	http.Redirect(w, r, h.config.Server.BasePath, http.StatusFound)
@@ -277,7 +351,7 @@ func (h *LoginHandler) PostResetPassword(w http.ResponseWriter, r *http.Request)
 		loadTemplates()
 	}
 
-	if !h.config.Mail.Enabled {
+	if !conf.Get().Mail.Enabled {
 		w.WriteHeader(http.StatusNotImplemented)
 		templates[conf.ResetPasswordTemplate].Execute(w, h.buildViewModel(r, w).WithError("mailing is disabled on this server"))
 		return
@@ -289,6 +363,12 @@ func (h *LoginHandler) PostResetPassword(w http.ResponseWriter, r *http.Request)
 		templates[conf.ResetPasswordTemplate].Execute(w, h.buildViewModel(r, w).WithError("missing parameters"))
 		return
 	}
+	if err := h.captchaSrvc.Verify(r.PostForm, middlewares.ClientIp(r)); err != nil {
+		h.logCaptchaError(r, err)
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.ResetPasswordTemplate].Execute(w, h.buildViewModel(r, w).WithError("captcha verification failed, please try again"))
+		return
+	}
 	if err := resetPasswordDecoder.Decode(&resetRequest, r.PostForm); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		templates[conf.ResetPasswordTemplate].Execute(w, h.buildViewModel(r, w).WithError("missing parameters"))
@@ -319,6 +399,88 @@ func (h *LoginHandler) PostResetPassword(w http.ResponseWriter, r *http.Request)
 	http.Redirect(w, r, h.config.Server.BasePath, http.StatusFound)
 }
 
+func (h *LoginHandler) GetVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user, err := h.userSrvc.VerifyEmail(r.URL.Query().Get("token"))
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w).WithError("invalid or expired token"))
+		return
+	}
+
+	logbuch.Info("user '%s' verified their e-mail address", user.ID)
+
+	routeutils.SetSuccess(r, w, "your e-mail address was verified successfully")
+	http.Redirect(w, r, h.config.Server.BasePath, http.StatusFound)
+}
+
+func (h *LoginHandler) GetDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	token := r.URL.Query().Get("token")
+	if _, err := h.userSrvc.GetUserByDeletionToken(token); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w).WithError("invalid or expired token"))
+		return
+	}
+
+	vm := &view.DeleteAccountViewModel{
+		LoginViewModel: *h.buildViewModel(r, w),
+		Token:          token,
+		GraceDays:      h.config.App.AccountDeletionGraceDays,
+	}
+
+	templates[conf.DeleteAccountTemplate].Execute(w, vm)
+}
+
+func (h *LoginHandler) PostDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	var deleteRequest models.DeleteAccountRequest
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w).WithError("missing parameters"))
+		return
+	}
+	if err := deleteAccountDecoder.Decode(&deleteRequest, r.PostForm); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w).WithError("missing parameters"))
+		return
+	}
+
+	user, err := h.userSrvc.GetUserByDeletionToken(deleteRequest.Token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w).WithError("invalid or expired token"))
+		return
+	}
+
+	if _, err := h.userSrvc.ScheduleDeletion(user); err != nil {
+		if errors.Is(err, services.ErrUserSuspended) {
+			w.WriteHeader(http.StatusForbidden)
+			templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w).WithError("your account has been suspended"))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		conf.Log().Request(r).Error("failed to schedule deletion of user '%s' - %v", user.ID, err)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w).WithError("failed to delete account"))
+		return
+	}
+
+	logbuch.Info("scheduled deletion of user '%s'", user.ID)
+
+	routeutils.SetSuccess(r, w, fmt.Sprintf("Your account was deactivated and will be deleted in %d days. Log in and reactivate it to cancel. Sorry to see you go.", h.config.App.AccountDeletionGraceDays))
+	http.SetCookie(w, h.config.GetClearCookie(models.AuthCookieKey))
+	http.Redirect(w, r, h.config.Server.BasePath, http.StatusFound)
+}
+
 func (h *LoginHandler) buildViewModel(r *http.Request, w http.ResponseWriter) *view.LoginViewModel {
 	numUsers, _ := h.userSrvc.Count()
 
@@ -326,5 +488,58 @@ func (h *LoginHandler) buildViewModel(r *http.Request, w http.ResponseWriter) *v
 		TotalUsers:  int(numUsers),
 		AllowSignup: h.config.IsDev() || h.config.Security.AllowSignup,
 	}
+
+	if h.captchaSrvc.Enabled() {
+		captchaConfig := h.config.Security.Captcha
+		vm.Captcha = &view.CaptchaViewModel{
+			Provider:   captchaConfig.Provider,
+			SiteKey:    captchaConfig.SiteKey,
+			ScriptUrl:  captchaConfig.ScriptUrl(),
+			Difficulty: captchaConfig.PowDifficulty,
+		}
+		if challenge, err := h.captchaSrvc.IssueChallenge(); err == nil {
+			vm.Captcha.Challenge = challenge
+		} else {
+			conf.Log().Request(r).Error("failed to issue captcha challenge - %v", err)
+		}
+	}
+
 	return routeutils.WithSessionMessages(vm, r, w)
 }
+
+// logCaptchaError logs failures to reach the captcha provider, while mere wrong solutions are to be expected
+func (h *LoginHandler) logCaptchaError(r *http.Request, err error) {
+	if !errors.Is(err, services.ErrCaptchaFailed) {
+		conf.Log().Request(r).Error("failed to verify captcha - %v", err)
+	}
+}
+
+// buildSignupViewModel keeps the signup form usable after an error, if the user came with an invitation
+func (h *LoginHandler) buildSignupViewModel(r *http.Request, w http.ResponseWriter, inviteCode string) *view.LoginViewModel {
+	vm := h.buildViewModel(r, w)
+	if !vm.AllowSignup && inviteCode != "" {
+		vm.AllowSignup = true
+		vm.InviteCode = inviteCode
+	}
+	return vm
+}
+
+// requestEmailVerification issues a new verification token for the user and mails them a link to confirm their e-mail address with
+func requestEmailVerification(r *http.Request, user *models.User, userService services.IUserService, mailService services.IMailService) error {
+	u, err := userService.GenerateVerificationToken(user)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to generate verification token for user '%s' - %v", user.ID, err)
+		return err
+	}
+
+	go func(user *models.User) {
+		link := fmt.Sprintf("%s/verify-email?token=%s", conf.Get().Server.GetPublicUrl(), user.VerificationToken)
+		if err := mailService.SendEmailVerification(user, link); err != nil {
+			conf.Log().Request(r).Error("failed to send e-mail verification mail to %s - %v", user.ID, err)
+		} else {
+			logbuch.Info("sent e-mail verification mail to %s", user.ID)
+		}
+	}(u)
+
+	return nil
+}
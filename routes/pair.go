@@ -0,0 +1,96 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/emvi/logbuch"
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models/view"
+	"github.com/muety/wakapi/services"
+)
+
+type PairHandler struct {
+	config      *conf.Config
+	userSrvc    services.IUserService
+	pairingSrvc services.IPairingService
+}
+
+func NewPairHandler(userService services.IUserService, pairingService services.IPairingService) *PairHandler {
+	return &PairHandler{
+		config:      conf.Get(),
+		userSrvc:    userService,
+		pairingSrvc: pairingService,
+	}
+}
+
+func (h *PairHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).
+			WithRedirectTarget(defaultErrorRedirectTarget()).
+			WithRedirectErrorMessage("unauthorized").Handler,
+	)
+	r.Get("/", h.GetIndex)
+	r.Post("/", h.PostIndex)
+
+	router.Mount("/pair", r)
+}
+
+func (h *PairHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	userCode := r.URL.Query().Get("code")
+	vm := h.buildViewModel(r, userCode)
+	if userCode != "" {
+		// let the user review the request right away, if the code was passed via the verification link
+		if pairing, err := h.pairingSrvc.GetByUserCode(userCode); err == nil {
+			vm.Pairing = pairing
+		}
+	}
+	templates[conf.PairTemplate].Execute(w, vm)
+}
+
+// PostIndex first shows the details of the pairing request to let the user review it and only confirms it once the user agreed to
+func (h *PairHandler) PostIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	userCode := r.PostFormValue("user_code")
+
+	if r.PostFormValue("confirm") != "true" {
+		pairing, err := h.pairingSrvc.GetByUserCode(userCode)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			templates[conf.PairTemplate].Execute(w, h.buildViewModel(r, userCode).WithError("invalid or expired code"))
+			return
+		}
+		vm := h.buildViewModel(r, userCode)
+		vm.Pairing = pairing
+		templates[conf.PairTemplate].Execute(w, vm)
+		return
+	}
+
+	if err := h.pairingSrvc.Confirm(userCode, user); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.PairTemplate].Execute(w, h.buildViewModel(r, userCode).WithError("invalid or expired code"))
+		return
+	}
+
+	logbuch.Info("user '%s' paired a new device", user.ID)
+	templates[conf.PairTemplate].Execute(w, h.buildViewModel(r, "").WithSuccess("device connected successfully, you may now close this page"))
+}
+
+func (h *PairHandler) buildViewModel(r *http.Request, userCode string) *view.PairViewModel {
+	user := middlewares.GetPrincipal(r)
+	return &view.PairViewModel{
+		User:     user,
+		ApiKey:   user.ApiKey,
+		UserCode: userCode,
+	}
+}
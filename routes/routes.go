@@ -97,6 +97,12 @@ func typeName(t uint8) string {
 	if t == models.SummaryEntity {
 		return "entity"
 	}
+	if t == models.SummaryNamespace {
+		return "namespace"
+	}
+	if t == models.SummaryClient {
+		return "client"
+	}
 	return "unknown"
 }
 
@@ -2,6 +2,8 @@ package routes
 
 import (
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	"net/http"
@@ -19,8 +21,8 @@ import (
 	"github.com/muety/wakapi/models/view"
 	routeutils "github.com/muety/wakapi/routes/utils"
 	"github.com/muety/wakapi/services"
-	"github.com/muety/wakapi/services/imports"
 	"github.com/muety/wakapi/utils"
+	uuid "github.com/satori/go.uuid"
 )
 
 const criticalError = "a critical error has occurred, sorry"
@@ -36,6 +38,11 @@ type SettingsHandler struct {
 	projectLabelSrvc    services.IProjectLabelService
 	keyValueSrvc        services.IKeyValueService
 	mailSrvc            services.IMailService
+	accessTokenSrvc     services.IAccessTokenService
+	reprocessingSrvc    services.IReprocessingService
+	mirrorSrvc          services.IMirrorService
+	notificationSrvc    services.INotificationService
+	importSrvc          services.IImportService
 	httpClient          *http.Client
 }
 
@@ -51,6 +58,11 @@ func NewSettingsHandler(
 	projectLabelService services.IProjectLabelService,
 	keyValueService services.IKeyValueService,
 	mailService services.IMailService,
+	accessTokenService services.IAccessTokenService,
+	reprocessingService services.IReprocessingService,
+	mirrorService services.IMirrorService,
+	notificationService services.INotificationService,
+	importService services.IImportService,
 ) *SettingsHandler {
 	return &SettingsHandler{
 		config:              conf.Get(),
@@ -63,7 +75,12 @@ func NewSettingsHandler(
 		heartbeatSrvc:       heartbeatService,
 		keyValueSrvc:        keyValueService,
 		mailSrvc:            mailService,
-		httpClient:          &http.Client{Timeout: 10 * time.Second},
+		accessTokenSrvc:     accessTokenService,
+		reprocessingSrvc:    reprocessingService,
+		mirrorSrvc:          mirrorService,
+		notificationSrvc:    notificationService,
+		importSrvc:          importService,
+		httpClient:          conf.NewOutboundClient(utils.OutboundPriorityHigh, 10*time.Second),
 	}
 }
 
@@ -98,6 +115,13 @@ func (h *SettingsHandler) PostIndex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the demo account is shared by everyone, so nobody must be able to lock others out or wipe the data
+	if h.config.DemoMode && middlewares.GetPrincipal(r).ID == services.DemoUsername {
+		w.WriteHeader(http.StatusForbidden)
+		templates[conf.SettingsTemplate].Execute(w, h.buildViewModel(r, w).WithError("settings of the demo account can not be changed"))
+		return
+	}
+
 	action := r.PostForm.Get("action")
 	r.PostForm.Del("action")
 
@@ -137,6 +161,10 @@ func (h *SettingsHandler) dispatchAction(action string) action {
 		return h.actionUpdateUser
 	case "reset_apikey":
 		return h.actionResetApiKey
+	case "create_access_token":
+		return h.actionCreateAccessToken
+	case "revoke_access_token":
+		return h.actionRevokeAccessToken
 	case "delete_alias":
 		return h.actionDeleteAlias
 	case "add_alias":
@@ -149,12 +177,22 @@ func (h *SettingsHandler) dispatchAction(action string) action {
 		return h.actionDeleteLanguageMapping
 	case "add_mapping":
 		return h.actionAddLanguageMapping
+	case "reprocess_mappings":
+		return h.actionReprocessLanguageMappings
 	case "update_sharing":
 		return h.actionUpdateSharing
+	case "update_privacy":
+		return h.actionUpdatePrivacy
+	case "update_working_hours":
+		return h.actionUpdateWorkingHours
 	case "update_leaderboard":
 		return h.actionUpdateLeaderboard
+	case "verify_email":
+		return h.actionVerifyEmail
 	case "toggle_wakatime":
 		return h.actionSetWakatimeApiKey
+	case "toggle_mirror":
+		return h.actionSetMirrorUrl
 	case "import_wakatime":
 		return h.actionImportWakatime
 	case "regenerate_summaries":
@@ -163,6 +201,10 @@ func (h *SettingsHandler) dispatchAction(action string) action {
 		return h.actionClearData
 	case "delete_account":
 		return h.actionDeleteUser
+	case "deactivate_account":
+		return h.actionDeactivateUser
+	case "reactivate_account":
+		return h.actionReactivateUser
 	}
 	return nil
 }
@@ -190,15 +232,48 @@ func (h *SettingsHandler) actionUpdateUser(w http.ResponseWriter, r *http.Reques
 		return http.StatusBadRequest, "", "cannot unset email while subscription is active"
 	}
 
+	emailChanged := payload.Email != user.Email
+	timeoutChanged := payload.HeartbeatsTimeoutMinutes != user.HeartbeatsTimeoutMinutes
+	if emailChanged {
+		user.EmailVerified = false
+		user.VerificationToken = ""
+	}
+
 	user.Email = payload.Email
 	user.Location = payload.Location
 	user.ReportsWeekly = payload.ReportsWeekly
-	user.PublicLeaderboard = payload.PublicLeaderboard
+	user.PublicLeaderboard = payload.PublicLeaderboard && !user.IsHidden
+	user.DailyGoalMinutes = payload.DailyGoalMinutes
+	user.NotificationDigest = payload.NotificationDigest
+	user.QuietHoursStart = payload.QuietHoursStart
+	user.QuietHoursEnd = payload.QuietHoursEnd
+	user.InactivityAlertDays = payload.InactivityAlertDays
+	user.HeartbeatsTimeoutMinutes = payload.HeartbeatsTimeoutMinutes
+	user.NotifyAchievements = payload.NotifyAchievements
 
 	if _, err := h.userSrvc.Update(user); err != nil {
 		return http.StatusInternalServerError, "", conf.ErrInternalServerError
 	}
 
+	// durations are computed at aggregation time, so existing summaries still reflect the previous timeout
+	var regenerationPending bool
+	if timeoutChanged {
+		if err := h.aggregationSrvc.ScheduleRegeneration(user, time.Time{}, time.Time{}); errors.Is(err, services.ErrAggregationInProgress) {
+			regenerationPending = true
+		} else if err != nil {
+			conf.Log().Request(r).Error("failed to schedule summary regeneration for user '%s' - %v", user.ID, err)
+		}
+	}
+
+	if emailChanged && user.Email != "" && conf.Get().Mail.Enabled {
+		if err := requestEmailVerification(r, user, h.userSrvc, h.mailSrvc); err == nil {
+			return http.StatusOK, "user updated successfully, please check your inbox to verify your new e-mail address", ""
+		}
+	}
+
+	if regenerationPending {
+		return http.StatusOK, "user updated successfully, please regenerate your summaries once the current aggregation has finished for the new timeout to take effect", ""
+	}
 	return http.StatusOK, "user updated successfully", ""
 }
 
@@ -272,6 +347,10 @@ func (h *SettingsHandler) actionUpdateLeaderboard(w http.ResponseWriter, r *http
 	user := middlewares.GetPrincipal(r)
 	defer h.userSrvc.FlushCache()
 
+	if user.IsHidden {
+		return http.StatusForbidden, "", "your account has been hidden from public views by a moderator"
+	}
+
 	user.PublicLeaderboard, err = strconv.ParseBool(r.PostFormValue("enable_leaderboard"))
 
 	if err != nil {
@@ -283,6 +362,30 @@ func (h *SettingsHandler) actionUpdateLeaderboard(w http.ResponseWriter, r *http
 	return http.StatusOK, "settings updated", ""
 }
 
+func (h *SettingsHandler) actionVerifyEmail(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	if !conf.Get().Mail.Enabled {
+		return http.StatusNotImplemented, "", "mailing is disabled on this server"
+	}
+	if user.Email == "" {
+		return http.StatusBadRequest, "", "you need to set an e-mail address first"
+	}
+	if user.EmailVerified {
+		return http.StatusBadRequest, "", "your e-mail address is already verified"
+	}
+
+	if err := requestEmailVerification(r, user, h.userSrvc, h.mailSrvc); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusAccepted, "we sent you an e-mail, please click the link in there to verify your e-mail address", ""
+}
+
 func (h *SettingsHandler) actionUpdateSharing(w http.ResponseWriter, r *http.Request) (int, string, string) {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -293,12 +396,17 @@ func (h *SettingsHandler) actionUpdateSharing(w http.ResponseWriter, r *http.Req
 
 	defer h.userSrvc.FlushUserCache(user.ID)
 
+	if user.IsHidden {
+		return http.StatusForbidden, "", "your account has been hidden from public views by a moderator"
+	}
+
 	user.ShareProjects, err = strconv.ParseBool(r.PostFormValue("share_projects"))
 	user.ShareLanguages, err = strconv.ParseBool(r.PostFormValue("share_languages"))
 	user.ShareEditors, err = strconv.ParseBool(r.PostFormValue("share_editors"))
 	user.ShareOSs, err = strconv.ParseBool(r.PostFormValue("share_oss"))
 	user.ShareMachines, err = strconv.ParseBool(r.PostFormValue("share_machines"))
 	user.ShareLabels, err = strconv.ParseBool(r.PostFormValue("share_labels"))
+	user.SharePresence, err = strconv.ParseBool(r.PostFormValue("share_presence"))
 	user.ShareDataMaxDays, err = strconv.Atoi(r.PostFormValue("max_days"))
 
 	if err != nil {
@@ -312,6 +420,114 @@ func (h *SettingsHandler) actionUpdateSharing(w http.ResponseWriter, r *http.Req
 	return http.StatusOK, "settings updated", ""
 }
 
+func (h *SettingsHandler) actionUpdatePrivacy(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	var err error
+	user := middlewares.GetPrincipal(r)
+	defer h.userSrvc.FlushUserCache(user.ID)
+
+	user.HashEntities, err = strconv.ParseBool(r.PostFormValue("hash_entities"))
+	if err != nil {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+
+	projects := make([]string, 0)
+	for _, p := range strings.Split(r.PostFormValue("hash_entity_projects"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			projects = append(projects, p)
+		}
+	}
+	user.HashEntityProjects = strings.Join(projects, ",")
+
+	if user.EntityHashSalt == "" && (user.HashEntities || len(projects) > 0) {
+		user.EntityHashSalt = uuid.NewV4().String()
+	}
+
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+	return http.StatusOK, "settings updated, file paths of new heartbeats will be hashed accordingly", ""
+}
+
+func (h *SettingsHandler) actionUpdateWorkingHours(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	defer h.userSrvc.FlushUserCache(user.ID)
+
+	var payload models.WorkingHoursUpdate
+	if err := r.ParseForm(); err != nil {
+		return http.StatusBadRequest, "", "missing parameters"
+	}
+	if err := credentialsDecoder.Decode(&payload, r.PostForm); err != nil {
+		return http.StatusBadRequest, "", "missing parameters"
+	}
+	if !payload.IsValid() {
+		return http.StatusBadRequest, "", "invalid parameters - working hours must end after they start"
+	}
+
+	days := make([]string, len(payload.WorkingDays))
+	for i, d := range payload.WorkingDays {
+		days[i] = strconv.Itoa(d)
+	}
+
+	user.WorkingDays = strings.Join(days, ",")
+	user.WorkingHoursStart = payload.WorkingHoursStart
+	user.WorkingHoursEnd = payload.WorkingHoursEnd
+	user.WeeklyTargetHours = payload.WeeklyTargetHours
+
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+	return http.StatusOK, "working hours updated successfully", ""
+}
+
+// actionCreateAccessToken issues a token restricted to reading stats and summaries, e.g. to be embedded in widgets or dashboards instead of the api key
+func (h *SettingsHandler) actionCreateAccessToken(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	name := strings.TrimSpace(r.PostFormValue("token_name"))
+	if name == "" {
+		name = "Read-only token"
+	}
+	if len(name) > 255 {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+
+	plainToken, _, err := h.accessTokenSrvc.Create(user, name, models.ScopeReadStats)
+	if err != nil {
+		return http.StatusInternalServerError, "", "could not create access token"
+	}
+
+	return http.StatusOK, fmt.Sprintf("read-only token created, make sure to copy it now, as it won't be shown again: %s", plainToken), ""
+}
+
+func (h *SettingsHandler) actionRevokeAccessToken(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	id, err := strconv.ParseUint(r.PostFormValue("token_id"), 10, 32)
+	if err != nil {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+
+	if err := h.accessTokenSrvc.Delete(user, uint(id)); err != nil {
+		return http.StatusInternalServerError, "", "could not revoke access token"
+	}
+
+	return http.StatusOK, "access token revoked successfully", ""
+}
+
 func (h *SettingsHandler) actionDeleteAlias(w http.ResponseWriter, r *http.Request) (int, string, string) {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -445,17 +661,15 @@ func (h *SettingsHandler) actionAddLanguageMapping(w http.ResponseWriter, r *htt
 		loadTemplates()
 	}
 	user := middlewares.GetPrincipal(r)
-	extension := r.PostFormValue("extension")
-	language := r.PostFormValue("language")
 
-	if extension[0] == '.' {
-		extension = extension[1:]
-	}
-
-	mapping := &models.LanguageMapping{
+	mapping := (&models.LanguageMapping{
 		UserID:    user.ID,
-		Extension: extension,
-		Language:  language,
+		Extension: r.PostFormValue("extension"),
+		Language:  r.PostFormValue("language"),
+	}).Normalize()
+
+	if !mapping.IsValid() {
+		return http.StatusBadRequest, "", "invalid mapping"
 	}
 
 	if _, err := h.languageMappingSrvc.Create(mapping); err != nil {
@@ -465,6 +679,18 @@ func (h *SettingsHandler) actionAddLanguageMapping(w http.ResponseWriter, r *htt
 	return http.StatusOK, "mapping added successfully", ""
 }
 
+func (h *SettingsHandler) actionReprocessLanguageMappings(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	if err := h.reprocessingSrvc.Reprocess(middlewares.GetPrincipal(r)); err != nil {
+		return http.StatusConflict, "", err.Error()
+	}
+
+	return http.StatusAccepted, "your language mappings and label rules are being applied to your existing data - this may take up to a couple of minutes, please come back later", ""
+}
+
 func (h *SettingsHandler) actionSetWakatimeApiKey(w http.ResponseWriter, r *http.Request) (int, string, string) {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -489,6 +715,28 @@ func (h *SettingsHandler) actionSetWakatimeApiKey(w http.ResponseWriter, r *http
 	return http.StatusOK, "Wakatime API Key updated successfully", ""
 }
 
+func (h *SettingsHandler) actionSetMirrorUrl(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	mirrorUrl := strings.TrimSpace(r.PostFormValue("mirror_url"))
+
+	if _, err := h.mirrorSrvc.Configure(user, mirrorUrl); err != nil {
+		if err == services.ErrMirrorInvalidUrl || err == services.ErrMirrorUnreachable {
+			return http.StatusBadRequest, "", err.Error()
+		}
+		conf.Log().Request(r).Error("failed to configure heartbeat mirroring for user '%s' - %v", user.ID, err)
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	if mirrorUrl == "" {
+		return http.StatusOK, "heartbeat mirroring disabled", ""
+	}
+	return http.StatusOK, "heartbeat mirroring enabled successfully", ""
+}
+
 func (h *SettingsHandler) actionImportWakatime(w http.ResponseWriter, r *http.Request) (int, string, string) {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -502,6 +750,9 @@ func (h *SettingsHandler) actionImportWakatime(w http.ResponseWriter, r *http.Re
 	if user.WakatimeApiKey == "" {
 		return http.StatusForbidden, "", "not connected to wakatime"
 	}
+	if user.IsDeactivated() {
+		return http.StatusForbidden, "", "imports are not possible while your account is deactivated"
+	}
 
 	useLegacyImporter, _ := strconv.ParseBool(r.PostFormValue("use_legacy_importer"))
 	kvKeyLastImport := fmt.Sprintf("%s_%s", conf.KeyLastImport, user.ID)
@@ -509,89 +760,24 @@ func (h *SettingsHandler) actionImportWakatime(w http.ResponseWriter, r *http.Re
 
 	if !h.config.IsDev() {
 		lastImport, _ := time.Parse(time.RFC822, h.keyValueSrvc.MustGetString(kvKeyLastImport).Value)
-		if time.Now().Sub(lastImport) < time.Duration(h.config.App.ImportBackoffMin)*time.Minute {
+		if time.Now().Sub(lastImport) < time.Duration(conf.Get().App.ImportBackoffMin)*time.Minute {
 			return http.StatusTooManyRequests,
 				"",
-				fmt.Sprintf("Too many data imports - you are only allowed to request an import every %d minutes.", h.config.App.ImportBackoffMin)
+				fmt.Sprintf("Too many data imports - you are only allowed to request an import every %d minutes.", conf.Get().App.ImportBackoffMin)
 		}
 
 		lastImportSuccess, _ := time.Parse(time.RFC822, h.keyValueSrvc.MustGetString(kvKeyLastImportSuccess).Value)
-		if time.Now().Sub(lastImportSuccess) < time.Duration(h.config.App.ImportMaxRate)*time.Hour {
+		if time.Now().Sub(lastImportSuccess) < time.Duration(conf.Get().App.ImportMaxRate)*time.Hour {
 			return http.StatusTooManyRequests,
 				"",
-				fmt.Sprintf("Too many data imports - last import ran less than %d hours ago, please wait.", h.config.App.ImportMaxRate)
+				fmt.Sprintf("Too many data imports - last import ran less than %d hours ago, please wait.", conf.Get().App.ImportMaxRate)
 		}
 	}
 
-	go func(user *models.User) {
-		start := time.Now()
-		importer := imports.NewWakatimeImporter(user.WakatimeApiKey, useLegacyImporter)
-
-		countBefore, _ := h.heartbeatSrvc.CountByUser(user)
-
-		var (
-			stream      <-chan *models.Heartbeat
-			importError error
-		)
-		if latest, err := h.heartbeatSrvc.GetLatestByOriginAndUser(imports.OriginWakatime, user); latest == nil || err != nil {
-			stream, importError = importer.ImportAll(user)
-		} else {
-			// if an import has happened before, only import heartbeats newer than the latest of the last import
-			stream, importError = importer.Import(user, latest.Time.T(), time.Now())
-		}
-		if importError != nil {
-			conf.Log().Error("wakatime import for user '%s' failed - %v", user.ID, importError)
-			return
-		}
-
-		// import successful
-		h.keyValueSrvc.PutString(&models.KeyStringValue{
-			Key:   kvKeyLastImportSuccess,
-			Value: time.Now().Format(time.RFC822),
-		})
-
-		count := 0
-		batch := make([]*models.Heartbeat, 0, h.config.App.ImportBatchSize)
-
-		insert := func(batch []*models.Heartbeat) {
-			if err := h.heartbeatSrvc.InsertBatch(batch); err != nil {
-				logbuch.Warn("failed to insert imported heartbeat, already existing? - %v", err)
-			}
-		}
-
-		for hb := range stream {
-			count++
-			batch = append(batch, hb)
-
-			if len(batch) == h.config.App.ImportBatchSize {
-				insert(batch)
-				batch = make([]*models.Heartbeat, 0, h.config.App.ImportBatchSize)
-			}
-		}
-		if len(batch) > 0 {
-			insert(batch)
-		}
-
-		countAfter, _ := h.heartbeatSrvc.CountByUser(user)
-		logbuch.Info("downloaded %d heartbeats for user '%s' (%d actually imported)", count, user.ID, countAfter-countBefore)
-
-		h.regenerateSummaries(user)
-
-		if !user.HasData {
-			user.HasData = true
-			if _, err := h.userSrvc.Update(user); err != nil {
-				conf.Log().Request(r).Error("failed to set 'has_data' flag for user %s - %v", user.ID, err)
-			}
-		}
-
-		if user.Email != "" {
-			if err := h.mailSrvc.SendImportNotification(user, time.Now().Sub(start), int(countAfter-countBefore)); err != nil {
-				conf.Log().Request(r).Error("failed to send import notification mail to %s - %v", user.ID, err)
-			} else {
-				logbuch.Info("sent import notification mail to %s", user.ID)
-			}
-		}
-	}(user)
+	if err := h.importSrvc.ImportWakatime(user, useLegacyImporter); err != nil {
+		conf.Log().Request(r).Error("failed to dispatch wakatime import for user '%s' - %v", user.ID, err)
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
 
 	h.keyValueSrvc.PutString(&models.KeyStringValue{
 		Key:   kvKeyLastImport,
@@ -638,26 +824,84 @@ func (h *SettingsHandler) actionClearData(w http.ResponseWriter, r *http.Request
 	return http.StatusAccepted, "deletion in progress, this may take a couple of seconds", ""
 }
 
+func (h *SettingsHandler) actionDeactivateUser(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if !user.IsActive() {
+		return http.StatusBadRequest, "", "account is not active"
+	}
+
+	user.Status = models.UserStatusDeactivated
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	logbuch.Info("user '%s' deactivated their account", user.ID)
+	return http.StatusOK, "your account was deactivated, all data is kept and you can reactivate it at any time", ""
+}
+
+func (h *SettingsHandler) actionReactivateUser(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if !user.IsDeactivated() {
+		return http.StatusBadRequest, "", "account is not deactivated"
+	}
+
+	// reactivating also recovers an account during its deletion grace period
+	if _, err := h.userSrvc.CancelDeletion(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	logbuch.Info("user '%s' reactivated their account", user.ID)
+	return http.StatusOK, "your account was reactivated", ""
+}
+
 func (h *SettingsHandler) actionDeleteUser(w http.ResponseWriter, r *http.Request) (int, string, string) {
 	if h.config.IsDev() {
 		loadTemplates()
 	}
 
 	user := middlewares.GetPrincipal(r)
+	if user.IsScheduledForDeletion() {
+		return http.StatusBadRequest, "", "account is already scheduled for deletion"
+	}
+
+	// without being able to confirm via e-mail, deletion is scheduled right away
+	if !conf.Get().Mail.Enabled || user.Email == "" {
+		if _, err := h.userSrvc.ScheduleDeletion(user); err != nil {
+			conf.Log().Request(r).Error("failed to schedule deletion of user '%s' - %v", user.ID, err)
+			return http.StatusInternalServerError, "", conf.ErrInternalServerError
+		}
+		logbuch.Info("scheduled deletion of user '%s'", user.ID)
+
+		routeutils.SetSuccess(r, w, fmt.Sprintf("Your account was deactivated and will be deleted in %d days. Log in and reactivate it to cancel. Sorry to see you go.", h.config.App.AccountDeletionGraceDays))
+		http.SetCookie(w, h.config.GetClearCookie(models.AuthCookieKey))
+		http.Redirect(w, r, h.config.Server.BasePath, http.StatusFound)
+		return -1, "", ""
+	}
+
+	u, err := h.userSrvc.GenerateDeletionToken(user)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to generate deletion token for user '%s' - %v", user.ID, err)
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
 	go func(user *models.User) {
-		logbuch.Info("deleting user '%s' shortly", user.ID)
-		time.Sleep(5 * time.Minute)
-		if err := h.userSrvc.Delete(user); err != nil {
-			conf.Log().Request(r).Error("failed to delete user '%s' - %v", user.ID, err)
+		link := fmt.Sprintf("%s/delete-account?token=%s", h.config.Server.GetPublicUrl(), user.DeletionToken)
+		if err := h.mailSrvc.SendAccountDeletionConfirmation(user, link); err != nil {
+			conf.Log().Request(r).Error("failed to send account deletion confirmation mail to %s - %v", user.ID, err)
 		} else {
-			logbuch.Info("successfully deleted user '%s'", user.ID)
+			logbuch.Info("sent account deletion confirmation mail to %s", user.ID)
 		}
-	}(user)
+	}(u)
 
-	routeutils.SetSuccess(r, w, "Your account will be deleted in a few minutes. Sorry to you go.")
-	http.SetCookie(w, h.config.GetClearCookie(models.AuthCookieKey))
-	http.Redirect(w, r, h.config.Server.BasePath, http.StatusFound)
-	return -1, "", ""
+	return http.StatusAccepted, "we sent you an e-mail, please click the link in there to confirm the deletion of your account", ""
 }
 
 func (h *SettingsHandler) validateWakatimeKey(apiKey string, baseUrl string) bool {
@@ -769,10 +1013,17 @@ func (h *SettingsHandler) buildViewModel(r *http.Request, w http.ResponseWriter)
 		return &view.SettingsViewModel{Messages: view.Messages{Error: criticalError}}
 	}
 
+	// access tokens
+	accessTokens, err := h.accessTokenSrvc.GetByUser(user)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching access tokens - %v", err)
+		return &view.SettingsViewModel{Messages: view.Messages{Error: criticalError}}
+	}
+
 	// subscriptions
-	var subscriptionPrice string
+	var subscriptionPlans []*conf.SubscriptionPlan
 	if h.config.Subscriptions.Enabled {
-		subscriptionPrice = h.config.Subscriptions.StandardPrice
+		subscriptionPlans = h.config.Subscriptions.Plans
 	}
 
 	// user first data
@@ -782,17 +1033,36 @@ func (h *SettingsHandler) buildViewModel(r *http.Request, w http.ResponseWriter)
 		firstData, _ = time.Parse(time.RFC822Z, firstDataKv.Value)
 	}
 
+	// report delivery
+	var reportFailure *models.ReportFailure
+	if reportFailureKv := h.keyValueSrvc.MustGetString(fmt.Sprintf("%s_%s", conf.KeyReportFailure, user.ID)); reportFailureKv.Value != "" {
+		if err := json.Unmarshal([]byte(reportFailureKv.Value), &reportFailure); err != nil {
+			reportFailure = nil
+		}
+	}
+
 	vm := &view.SettingsViewModel{
-		User:                user,
-		LanguageMappings:    mappings,
-		Aliases:             combinedAliases,
-		Labels:              combinedLabels,
-		Projects:            projects,
-		ApiKey:              user.ApiKey,
-		UserFirstData:       firstData,
-		SubscriptionPrice:   subscriptionPrice,
-		SupportContact:      h.config.App.SupportContact,
-		DataRetentionMonths: h.config.App.DataRetentionMonths,
+		User:                     user,
+		LanguageMappings:         mappings,
+		DefaultLanguageMappings:  h.languageMappingSrvc.GetDefaults(),
+		Aliases:                  combinedAliases,
+		Labels:                   combinedLabels,
+		Projects:                 projects,
+		ApiKey:                   user.ApiKey,
+		AccessTokens:             accessTokens,
+		UserFirstData:            firstData,
+		SubscriptionPlans:        subscriptionPlans,
+		SubscriptionTrialDays:    h.config.Subscriptions.TrialDays,
+		PaymentProvider:          h.config.Subscriptions.Provider,
+		SupportContact:           h.config.App.SupportContact,
+		DataRetentionMonths:      h.config.App.DataRetentionMonths,
+		AccountDeletionGraceDays: h.config.App.AccountDeletionGraceDays,
+		LeaderboardEligibility: &view.SettingsVMLeaderboardEligibility{
+			MinAccountAgeDays:    h.config.App.LeaderboardEligibility.MinAccountAgeDays,
+			MinActiveDays:        h.config.App.LeaderboardEligibility.MinActiveDays,
+			RequireVerifiedEmail: h.config.App.LeaderboardEligibility.RequireVerifiedEmail,
+		},
+		ReportFailure: reportFailure,
 	}
 	return routeutils.WithSessionMessages(vm, r, w)
 }
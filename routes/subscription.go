@@ -1,7 +1,6 @@
 package routes
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/emvi/logbuch"
@@ -12,27 +11,12 @@ import (
 	"github.com/muety/wakapi/models"
 	routeutils "github.com/muety/wakapi/routes/utils"
 	"github.com/muety/wakapi/services"
-	"github.com/stripe/stripe-go/v74"
-	stripePortalSession "github.com/stripe/stripe-go/v74/billingportal/session"
-	stripeCheckoutSession "github.com/stripe/stripe-go/v74/checkout/session"
-	stripeCustomer "github.com/stripe/stripe-go/v74/customer"
-	stripePrice "github.com/stripe/stripe-go/v74/price"
-	stripeSubscription "github.com/stripe/stripe-go/v74/subscription"
-	"github.com/stripe/stripe-go/v74/webhook"
-	"io/ioutil"
+	"github.com/muety/wakapi/services/payment"
 	"net/http"
-	"strings"
 	"time"
 )
 
-/*
-  How to integrate with Stripe?
-  ---
-  1. Create a plan with recurring payment (https://dashboard.stripe.com/test/products?active=true), copy its ID and save it as 'standard_price_id'
-  2. Create a webhook (https://dashboard.stripe.com/test/webhooks), with target URL '/subscription/webhook' and events ['customer.subscription.created', 'customer.subscription.updated', 'customer.subscription.deleted', 'checkout.session.completed'], copy the endpoint secret and save it to 'stripe_endpoint_secret'
-  3. Create a secret API key (https://dashboard.stripe.com/test/apikeys), copy it and save it to 'stripe_secret_key'
-  4. Copy the publishable API key (https://dashboard.stripe.com/test/apikeys) and save it to 'stripe_api_key'
-*/
+// see services/payment for how to integrate with the individual payment providers
 
 // TODO: move all logic inside this controller into a separate service
 
@@ -42,7 +26,7 @@ type SubscriptionHandler struct {
 	userSrvc     services.IUserService
 	mailSrvc     services.IMailService
 	keyValueSrvc services.IKeyValueService
-	httpClient   *http.Client
+	provider     payment.Provider
 }
 
 func NewSubscriptionHandler(
@@ -53,24 +37,28 @@ func NewSubscriptionHandler(
 	config := conf.Get()
 	eventBus := conf.EventBus()
 
-	if config.Subscriptions.Enabled {
-		stripe.Key = config.Subscriptions.StripeSecretKey
-
-		price, err := stripePrice.Get(config.Subscriptions.StandardPriceId, nil)
-		if err != nil {
-			logbuch.Fatal("failed to fetch stripe plan details: %v", err)
-		}
-		config.Subscriptions.StandardPrice = strings.TrimSpace(fmt.Sprintf("%2.f €", price.UnitAmountDecimal/100.0)) // TODO: respect actual currency
-
-		logbuch.Info("enabling subscriptions with stripe payment for %s / month", config.Subscriptions.StandardPrice)
-	}
-
 	handler := &SubscriptionHandler{
 		config:       config,
 		userSrvc:     userService,
 		mailSrvc:     mailService,
 		keyValueSrvc: keyValueService,
-		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+
+	if config.Subscriptions.Enabled {
+		provider, err := payment.NewProvider(config)
+		if err != nil {
+			logbuch.Fatal(err.Error())
+		}
+		if err := provider.FetchPrices(config.Subscriptions.Plans); err != nil {
+			logbuch.Fatal(err.Error())
+		}
+		for _, plan := range config.Subscriptions.Plans {
+			if plan.Name == "" {
+				plan.Name = plan.Id
+			}
+			logbuch.Info("enabling subscription plan '%s' with %s payment for %s / month", plan.Id, provider.Name(), plan.Price)
+		}
+		handler.provider = provider
 	}
 
 	onUserDelete := eventBus.Subscribe(0, conf.EventUserDelete)
@@ -81,11 +69,11 @@ func NewSubscriptionHandler(
 				continue
 			}
 
-			logbuch.Info("cancelling subscription for user '%s' (email '%s', stripe customer '%s') upon account deletion", user.ID, user.Email, user.StripeCustomerId)
-			if err := handler.cancelUserSubscription(user); err == nil {
-				logbuch.Info("successfully cancelled subscription for user '%s' (email '%s', stripe customer '%s')", user.ID, user.Email, user.StripeCustomerId)
+			logbuch.Info("cancelling subscription for user '%s' (email '%s', subscription '%s') upon account deletion", user.ID, user.Email, user.SubscriptionId)
+			if err := handler.provider.Cancel(user); err == nil {
+				logbuch.Info("successfully cancelled subscription for user '%s' (email '%s', subscription '%s')", user.ID, user.Email, user.SubscriptionId)
 			} else {
-				conf.Log().Error("failed to cancel subscription for user '%s' (email '%s', stripe customer '%s') - %v", user.ID, user.Email, user.StripeCustomerId, err)
+				conf.Log().Error("failed to cancel subscription for user '%s' (email '%s', subscription '%s') - %v", user.ID, user.Email, user.SubscriptionId, err)
 			}
 		}
 	}(&onUserDelete)
@@ -93,8 +81,6 @@ func NewSubscriptionHandler(
 	return handler
 }
 
-// https://stripe.com/docs/billing/quickstart?lang=go
-
 func (h *SubscriptionHandler) RegisterRoutes(router chi.Router) {
 	if !h.config.Subscriptions.Enabled {
 		return
@@ -113,6 +99,7 @@ func (h *SubscriptionHandler) RegisterRoutes(router chi.Router) {
 	)
 	subRouterPrivate.Post("/checkout", h.PostCheckout)
 	subRouterPrivate.Post("/portal", h.PostPortal)
+	subRouterPrivate.Post("/change", h.PostChangePlan)
 
 	subRouterPublic.Mount("/", subRouterPrivate)
 	router.Mount("/subscription", subRouterPublic)
@@ -136,35 +123,37 @@ func (h *SubscriptionHandler) PostCheckout(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	checkoutParams := &stripe.CheckoutSessionParams{
-		Mode: stripe.String(string(stripe.CheckoutSessionModeSubscription)),
-		LineItems: []*stripe.CheckoutSessionLineItemParams{
-			{
-				Price:    &h.config.Subscriptions.StandardPriceId,
-				Quantity: stripe.Int64(1),
-			},
-		},
-		ClientReferenceID:   &user.ID,
-		AllowPromotionCodes: stripe.Bool(true),
-		SuccessURL:          stripe.String(fmt.Sprintf("%s%s/subscription/success", h.config.Server.PublicUrl, h.config.Server.BasePath)),
-		CancelURL:           stripe.String(fmt.Sprintf("%s%s/subscription/cancel", h.config.Server.PublicUrl, h.config.Server.BasePath)),
+	plan := h.config.Subscriptions.DefaultPlan()
+	if planId := r.PostFormValue("plan"); planId != "" {
+		plan = h.config.Subscriptions.GetPlan(planId)
+	}
+	if plan == nil {
+		routeutils.SetError(r, w, "invalid subscription plan")
+		http.Redirect(w, r, fmt.Sprintf("%s/settings#subscription", h.config.Server.BasePath), http.StatusFound)
+		return
 	}
 
-	if user.StripeCustomerId != "" {
-		checkoutParams.Customer = &user.StripeCustomerId
-	} else {
-		checkoutParams.CustomerEmail = &user.Email
+	// trials are only granted to users who never had a subscription before
+	var trialDays int
+	if user.SubscribedUntil == nil {
+		trialDays = h.config.Subscriptions.TrialDays
 	}
 
-	session, err := stripeCheckoutSession.New(checkoutParams)
+	checkoutUrl, err := h.provider.Checkout(
+		user,
+		plan,
+		trialDays,
+		fmt.Sprintf("%s%s/subscription/success", h.config.Server.PublicUrl, h.config.Server.BasePath),
+		fmt.Sprintf("%s%s/subscription/cancel", h.config.Server.PublicUrl, h.config.Server.BasePath),
+	)
 	if err != nil {
-		conf.Log().Request(r).Error("failed to create stripe checkout session: %v", err)
+		conf.Log().Request(r).Error("failed to create %s checkout session: %v", h.provider.Name(), err)
 		routeutils.SetError(r, w, "something went wrong")
 		http.Redirect(w, r, fmt.Sprintf("%s/settings#subscription", h.config.Server.BasePath), http.StatusFound)
 		return
 	}
 
-	http.Redirect(w, r, session.URL, http.StatusSeeOther)
+	http.Redirect(w, r, checkoutUrl, http.StatusSeeOther)
 }
 
 func (h *SubscriptionHandler) PostPortal(w http.ResponseWriter, r *http.Request) {
@@ -173,112 +162,116 @@ func (h *SubscriptionHandler) PostPortal(w http.ResponseWriter, r *http.Request)
 	}
 
 	user := middlewares.GetPrincipal(r)
-	if user.StripeCustomerId == "" {
+	portalUrl, err := h.provider.Portal(user, h.config.Server.PublicUrl)
+	if errors.Is(err, payment.ErrNoSubscription) {
 		routeutils.SetError(r, w, "no subscription found with your e-mail address, please contact us!")
 		http.Redirect(w, r, fmt.Sprintf("%s/settings#subscription", h.config.Server.BasePath), http.StatusFound)
 		return
 	}
+	if err != nil {
+		conf.Log().Request(r).Error("failed to create %s portal session: %v", h.provider.Name(), err)
+		routeutils.SetError(r, w, "something went wrong")
+		http.Redirect(w, r, fmt.Sprintf("%s/settings#subscription", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
 
-	portalParams := &stripe.BillingPortalSessionParams{
-		Customer:  &user.StripeCustomerId,
-		ReturnURL: &h.config.Server.PublicUrl,
+	http.Redirect(w, r, portalUrl, http.StatusSeeOther)
+}
+
+// PostChangePlan switches the user's current subscription to a different plan
+// depending on the provider, the change is either applied right away (with the price difference prorated) or has to be approved by the user first
+// the user's plan itself is only updated once the provider reports the change via webhook
+func (h *SubscriptionHandler) PostChangePlan(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	plan := h.config.Subscriptions.GetPlan(r.PostFormValue("plan"))
+	if plan == nil {
+		routeutils.SetError(r, w, "invalid subscription plan")
+		http.Redirect(w, r, fmt.Sprintf("%s/settings#subscription", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
+	if !user.HasActiveSubscription() {
+		routeutils.SetError(r, w, "no active subscription found, please subscribe first")
+		http.Redirect(w, r, fmt.Sprintf("%s/settings#subscription", h.config.Server.BasePath), http.StatusFound)
+		return
 	}
 
-	session, err := stripePortalSession.New(portalParams)
+	approvalUrl, err := h.provider.ChangePlan(user, plan, fmt.Sprintf("%s%s/settings#subscription", h.config.Server.PublicUrl, h.config.Server.BasePath))
+	if errors.Is(err, payment.ErrNoSubscription) {
+		routeutils.SetError(r, w, "no subscription found with your e-mail address, please contact us!")
+		http.Redirect(w, r, fmt.Sprintf("%s/settings#subscription", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
 	if err != nil {
-		conf.Log().Request(r).Error("failed to create stripe portal session: %v", err)
+		conf.Log().Request(r).Error("failed to change subscription of user '%s' to plan '%s', %v", user.ID, plan.Id, err)
 		routeutils.SetError(r, w, "something went wrong")
 		http.Redirect(w, r, fmt.Sprintf("%s/settings#subscription", h.config.Server.BasePath), http.StatusFound)
 		return
 	}
 
-	http.Redirect(w, r, session.URL, http.StatusSeeOther)
+	logbuch.Info("user '%s' requested to change their subscription to plan '%s'", user.ID, plan.Id)
+	if approvalUrl != "" {
+		http.Redirect(w, r, approvalUrl, http.StatusSeeOther)
+		return
+	}
+
+	routeutils.SetSuccess(r, w, fmt.Sprintf("you have switched to the %s plan, it might take a moment until the change is reflected here", plan.Name))
+	http.Redirect(w, r, fmt.Sprintf("%s/settings#subscription", h.config.Server.BasePath), http.StatusFound)
 }
 
 func (h *SubscriptionHandler) PostWebhook(w http.ResponseWriter, r *http.Request) {
-	bodyReader := http.MaxBytesReader(w, r.Body, int64(65536))
-	payload, err := ioutil.ReadAll(bodyReader)
+	event, err := h.provider.ParseWebhook(r)
 	if err != nil {
-		conf.Log().Request(r).Error("error in stripe webhook request: %v", err)
-		w.WriteHeader(http.StatusServiceUnavailable)
+		conf.Log().Request(r).Error("error in %s webhook request: %v", h.provider.Name(), err)
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-
-	event, err := webhook.ConstructEventWithOptions(payload, r.Header.Get("Stripe-Signature"), h.config.Subscriptions.StripeEndpointSecret, webhook.ConstructEventOptions{
-		IgnoreAPIVersionMismatch: true,
-	})
-	if err != nil {
-		conf.Log().Request(r).Error("stripe webhook signature verification failed: %v", err)
-		w.WriteHeader(http.StatusBadRequest)
+	if event == nil {
+		w.WriteHeader(http.StatusOK) // not relevant to us
 		return
 	}
 
 	switch event.Type {
-	case "customer.subscription.deleted",
-		"customer.subscription.updated",
-		"customer.subscription.created":
-		// example payload: https://pastr.de/p/k7bx3alx38b1iawo6amtx09k
-		subscription, err := h.parseSubscriptionEvent(w, r, event)
-		if err != nil {
-			return // status code already written
-		}
-		logbuch.Info("received stripe subscription event of type '%s' for subscription '%s' (customer '%s').", event.Type, subscription.ID, subscription.Customer.ID)
+	case payment.EventSubscription:
+		subscription := event.Subscription
+		logbuch.Info("received %s subscription event '%s' for subscription '%s' (customer '%s', user '%s').", h.provider.Name(), event.Id, subscription.Id, subscription.CustomerId, subscription.UserId)
 
-		// first, try to get user by associated customer id (requires checkout.session.completed event to have been processed before)
-		user, err := h.userSrvc.GetUserByStripeCustomerId(subscription.Customer.ID)
+		user, err := h.findSubscriptionUser(subscription)
 		if err != nil {
-			conf.Log().Request(r).Warn("failed to find user with stripe customer id '%s' to update their subscription (status '%s')", subscription.Customer.ID, subscription.Status)
-
-			// second, resolve customer and try to get user by email
-			customer, err := stripeCustomer.Get(subscription.Customer.ID, nil)
-			if err != nil {
-				conf.Log().Request(r).Error("failed to fetch stripe customer with id '%s', %v", subscription.Customer.ID, err)
-				w.WriteHeader(http.StatusOK) // don't make stripe retry the event
-				return
-			}
-
-			u, err := h.userSrvc.GetUserByEmail(customer.Email)
-			if err != nil {
-				conf.Log().Request(r).Error("failed to get user with email '%s' as stripe customer '%s' for processing event for subscription %s, %v", customer.Email, subscription.Customer.ID, subscription.ID, err)
-				w.WriteHeader(http.StatusOK) // don't make stripe retry the event
-				return
-			}
-			user = u
+			conf.Log().Request(r).Error("failed to find user for processing event for subscription %s (customer '%s'), %v", subscription.Id, subscription.CustomerId, err)
+			w.WriteHeader(http.StatusOK) // don't make the provider retry the event
+			return
 		}
 
 		if err := h.handleSubscriptionEvent(subscription, user); err != nil {
-			conf.Log().Request(r).Error("failed to handle subscription event %s (%s) for user %s, %v", event.ID, event.Type, user.ID, err)
-			w.WriteHeader(http.StatusOK) // don't make stripe retry the event
+			conf.Log().Request(r).Error("failed to handle subscription event %s for user %s, %v", event.Id, user.ID, err)
+			w.WriteHeader(http.StatusOK) // don't make the provider retry the event
 			return
 		}
 
-	case "checkout.session.completed":
-		// example payload: https://pastr.de/p/d01iniw9naq9hkmvyqtxin2w
-		checkoutSession, err := h.parseCheckoutSessionEvent(w, r, event)
-		if err != nil {
-			return // status code already written
-		}
-		logbuch.Info("received stripe checkout session event of type '%s' for session '%s' (customer '%s' with email '%s').", event.Type, checkoutSession.ID, checkoutSession.Customer.ID, checkoutSession.CustomerEmail)
+	case payment.EventCheckoutComplete:
+		logbuch.Info("received %s checkout event '%s' (customer '%s').", h.provider.Name(), event.Id, event.CustomerId)
 
-		user, err := h.userSrvc.GetUserById(checkoutSession.ClientReferenceID)
+		user, err := h.userSrvc.GetUserById(event.UserId)
 		if err != nil {
-			conf.Log().Request(r).Error("failed to find user with id '%s' to update associated stripe customer (%s)", user.ID, checkoutSession.Customer.ID)
-			return // status code already written
+			conf.Log().Request(r).Error("failed to find user with id '%s' to update associated customer (%s)", event.UserId, event.CustomerId)
+			w.WriteHeader(http.StatusOK)
+			return
 		}
 
 		if user.StripeCustomerId == "" {
-			user.StripeCustomerId = checkoutSession.Customer.ID
+			user.StripeCustomerId = event.CustomerId
 			if _, err := h.userSrvc.Update(user); err != nil {
-				conf.Log().Request(r).Error("failed to update stripe customer id (%s) for user '%s', %v", checkoutSession.Customer.ID, user.ID, err)
+				conf.Log().Request(r).Error("failed to update customer id (%s) for user '%s', %v", event.CustomerId, user.ID, err)
 			} else {
-				logbuch.Info("associated user '%s' with stripe customer '%s'", user.ID, checkoutSession.Customer.ID)
+				logbuch.Info("associated user '%s' with customer '%s'", user.ID, event.CustomerId)
 			}
-		} else if user.StripeCustomerId != checkoutSession.Customer.ID {
-			conf.Log().Request(r).Error("invalid state: tried to associate user '%s' with stripe customer '%s', but '%s' already assigned", user.ID, checkoutSession.Customer.ID, user.StripeCustomerId)
+		} else if user.StripeCustomerId != event.CustomerId {
+			conf.Log().Request(r).Error("invalid state: tried to associate user '%s' with customer '%s', but '%s' already assigned", user.ID, event.CustomerId, user.StripeCustomerId)
 		}
-
-	default:
-		logbuch.Warn("got stripe event '%s' with no handler defined", event.Type)
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -293,30 +286,66 @@ func (h *SubscriptionHandler) GetCheckoutCancel(w http.ResponseWriter, r *http.R
 	http.Redirect(w, r, fmt.Sprintf("%s/settings#subscription", h.config.Server.BasePath), http.StatusFound)
 }
 
-func (h *SubscriptionHandler) handleSubscriptionEvent(subscription *stripe.Subscription, user *models.User) error {
+// findSubscriptionUser resolves the user a subscription belongs to, either by a user reference passed back by the provider, by the associated customer (requires the checkout event to have been processed before) or, as a last resort, by the customer's e-mail address
+func (h *SubscriptionHandler) findSubscriptionUser(subscription *payment.Subscription) (*models.User, error) {
+	if subscription.UserId != "" {
+		return h.userSrvc.GetUserById(subscription.UserId)
+	}
+
+	if subscription.CustomerId != "" {
+		if user, err := h.userSrvc.GetUserByStripeCustomerId(subscription.CustomerId); err == nil {
+			return user, nil
+		}
+		logbuch.Warn("failed to find user with customer id '%s' to update their subscription (status '%s')", subscription.CustomerId, subscription.Status)
+	}
+
+	email, err := h.provider.CustomerEmail(subscription)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch customer, %v", err)
+	}
+	return h.userSrvc.GetUserByEmail(email)
+}
+
+func (h *SubscriptionHandler) handleSubscriptionEvent(subscription *payment.Subscription, user *models.User) error {
 	var hasSubscribed bool
 
 	switch subscription.Status {
-	case "active":
-		until := models.CustomTime(time.Unix(subscription.CurrentPeriodEnd, 0))
+	case payment.StatusActive, payment.StatusTrialing:
+		// during trials, the current period ends with the trial
+		until := models.CustomTime(subscription.PeriodEnd)
 
 		if user.SubscribedUntil == nil || !user.SubscribedUntil.T().Equal(until.T()) {
 			hasSubscribed = true
 			user.SubscribedUntil = &until
 			user.SubscriptionRenewal = &until
-			logbuch.Info("user %s got active subscription %s until %v", user.ID, subscription.ID, user.SubscribedUntil)
+			logbuch.Info("user %s got %s subscription %s until %v", user.ID, subscription.Status, subscription.Id, user.SubscribedUntil)
 		}
+		user.SubscriptionId = subscription.Id
 
-		if cancelAt := time.Unix(subscription.CancelAt, 0); !cancelAt.IsZero() && cancelAt.After(time.Now()) {
+		if cancelAt := subscription.CancelAt; !cancelAt.IsZero() && cancelAt.After(time.Now()) {
 			user.SubscriptionRenewal = nil
-			logbuch.Info("user %s chose to cancel subscription %s by %v", user.ID, subscription.ID, cancelAt)
+			logbuch.Info("user %s chose to cancel subscription %s by %v", user.ID, subscription.Id, cancelAt)
+		}
+
+		// plan changes (incl. up- and downgrades) take effect immediately, the provider itself takes care of billing the price difference
+		if plan := h.config.Subscriptions.GetPlanByPriceId(subscription.PriceId); plan == nil {
+			conf.Log().Warn("subscription %s of user %s is not billed with the price of any configured plan", subscription.Id, user.ID)
+		} else if plan.Id != user.SubscriptionPlan {
+			logbuch.Info("user %s's subscription %s changed from plan '%s' to '%s'", user.ID, subscription.Id, user.SubscriptionPlan, plan.Id)
+			user.SubscriptionPlan = plan.Id
+		}
+	case payment.StatusCanceled:
+		if user.SubscriptionId != "" && user.SubscriptionId != subscription.Id {
+			logbuch.Info("ignoring cancellation of subscription %s, as user %s has since got subscription %s", subscription.Id, user.ID, user.SubscriptionId)
+			return nil
 		}
-	case "canceled", "unpaid", "incomplete_expired":
 		user.SubscribedUntil = nil
 		user.SubscriptionRenewal = nil
-		logbuch.Info("user %s's subscription %s got canceled, because of status update to '%s'", user.ID, subscription.ID, subscription.Status)
+		user.SubscriptionPlan = ""
+		user.SubscriptionId = ""
+		logbuch.Info("user %s's subscription %s got canceled", user.ID, subscription.Id)
 	default:
-		logbuch.Info("got subscription (%s) status update to '%s' for user '%s'", subscription.ID, subscription.Status, user.ID)
+		logbuch.Info("got subscription (%s) status update to '%s' for user '%s'", subscription.Id, subscription.Status, user.ID)
 		return nil
 	}
 
@@ -327,74 +356,6 @@ func (h *SubscriptionHandler) handleSubscriptionEvent(subscription *stripe.Subsc
 	return err
 }
 
-func (h *SubscriptionHandler) parseSubscriptionEvent(w http.ResponseWriter, r *http.Request, event stripe.Event) (*stripe.Subscription, error) {
-	var subscription stripe.Subscription
-	if err := json.Unmarshal(event.Data.Raw, &subscription); err != nil {
-		conf.Log().Request(r).Error("failed to parse stripe webhook payload: %v", err)
-		w.WriteHeader(http.StatusBadRequest)
-		return nil, err
-	}
-	return &subscription, nil
-}
-
-func (h *SubscriptionHandler) parseCheckoutSessionEvent(w http.ResponseWriter, r *http.Request, event stripe.Event) (*stripe.CheckoutSession, error) {
-	var checkoutSession stripe.CheckoutSession
-	if err := json.Unmarshal(event.Data.Raw, &checkoutSession); err != nil {
-		conf.Log().Request(r).Error("failed to parse stripe webhook payload: %v", err)
-		w.WriteHeader(http.StatusBadRequest)
-		return nil, err
-	}
-
-	return &checkoutSession, nil
-}
-
-func (h *SubscriptionHandler) cancelUserSubscription(user *models.User) error {
-	// TODO: directly store subscription id with user object
-	subscription, err := h.findCurrentStripeSubscription(user.StripeCustomerId)
-	if err != nil {
-		return err
-	}
-	_, err = stripeSubscription.Cancel(subscription.ID, nil)
-	return err
-}
-
-func (h *SubscriptionHandler) findStripeCustomerByEmail(email string) (*stripe.Customer, error) {
-	params := &stripe.CustomerSearchParams{
-		SearchParams: stripe.SearchParams{
-			Query: fmt.Sprintf(`email:"%s"`, email),
-		},
-	}
-
-	results := stripeCustomer.Search(params)
-	if err := results.Err(); err != nil {
-		return nil, err
-	}
-
-	if results.Next() {
-		return results.Customer(), nil
-	} else {
-		return nil, errors.New("no customer found with given criteria")
-	}
-}
-
-func (h *SubscriptionHandler) findCurrentStripeSubscription(customerId string) (*stripe.Subscription, error) {
-	paramStatus := "active"
-	params := &stripe.SubscriptionListParams{
-		Customer: &customerId,
-		Price:    &h.config.Subscriptions.StandardPriceId,
-		Status:   &paramStatus,
-		CurrentPeriodEndRange: &stripe.RangeQueryParams{
-			GreaterThan: time.Now().Unix(),
-		},
-	}
-	params.Filters.AddFilter("limit", "", "1")
-
-	if result := stripeSubscription.List(params); result.Next() {
-		return result.Subscription(), nil
-	}
-	return nil, fmt.Errorf("no active subscription found for customer '%s'", customerId)
-}
-
 func (h *SubscriptionHandler) clearSubscriptionNotificationStatus(userId string) {
 	key := fmt.Sprintf("%s_%s", conf.KeySubscriptionNotificationSent, userId)
 	if err := h.keyValueSrvc.DeleteString(key); err != nil {
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	conf "github.com/muety/wakapi/config"
@@ -15,18 +16,24 @@ import (
 )
 
 type SummaryHandler struct {
-	config       *conf.Config
-	userSrvc     services.IUserService
-	summarySrvc  services.ISummaryService
-	keyValueSrvc services.IKeyValueService
+	config           *conf.Config
+	userSrvc         services.IUserService
+	summarySrvc      services.ISummaryService
+	keyValueSrvc     services.IKeyValueService
+	annotationSrvc   services.IAnnotationService
+	workingHoursSrvc services.IWorkingHoursService
+	savedFilterSrvc  services.ISavedFilterService
 }
 
-func NewSummaryHandler(summaryService services.ISummaryService, userService services.IUserService, keyValueService services.IKeyValueService) *SummaryHandler {
+func NewSummaryHandler(summaryService services.ISummaryService, userService services.IUserService, keyValueService services.IKeyValueService, annotationService services.IAnnotationService, workingHoursService services.IWorkingHoursService, savedFilterService services.ISavedFilterService) *SummaryHandler {
 	return &SummaryHandler{
-		summarySrvc:  summaryService,
-		userSrvc:     userService,
-		keyValueSrvc: keyValueService,
-		config:       conf.Get(),
+		summarySrvc:      summaryService,
+		userSrvc:         userService,
+		keyValueSrvc:     keyValueService,
+		annotationSrvc:   annotationService,
+		workingHoursSrvc: workingHoursService,
+		savedFilterSrvc:  savedFilterService,
+		config:           conf.Get(),
 	}
 }
 
@@ -35,6 +42,7 @@ func (h *SummaryHandler) RegisterRoutes(router chi.Router) {
 	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).
 		WithRedirectTarget(defaultErrorRedirectTarget()).
 		WithRedirectErrorMessage("unauthorized").Handler,
+		middlewares.NewSavedFilterMiddleware(h.savedFilterSrvc),
 	)
 	r.Get("/", h.GetIndex)
 
@@ -86,17 +94,29 @@ func (h *SummaryHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
 		firstData, _ = time.Parse(time.RFC822Z, firstDataKv.Value)
 	}
 
+	annotations, err := su.LoadUserAnnotationsByParams(h.annotationSrvc, summaryParams)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to load annotations - %v", err)
+	}
+
+	workingHours, err := h.workingHoursSrvc.GetByUser(user, summaryParams.From, summaryParams.To, summaryParams.Filters)
+	if err != nil && !errors.Is(err, services.ErrWorkingHoursRangeTooLarge) {
+		conf.Log().Request(r).Error("failed to compute working hours - %v", err)
+	}
+
 	vm := view.SummaryViewModel{
 		Summary:             summary,
 		SummaryParams:       summaryParams,
 		User:                user,
 		EditorColors:        su.FilterColors(h.config.App.GetEditorColors(), summary.Editors),
-		LanguageColors:      su.FilterColors(h.config.App.GetLanguageColors(), summary.Languages),
+		LanguageColors:      su.FilterColorsOrDerive(h.config.App.GetLanguageColors(), summary.Languages),
 		OSColors:            su.FilterColors(h.config.App.GetOSColors(), summary.OperatingSystems),
 		ApiKey:              user.ApiKey,
 		RawQuery:            rawQuery,
 		UserFirstData:       firstData,
 		DataRetentionMonths: h.config.App.DataRetentionMonths,
+		Annotations:         annotations,
+		WorkingHours:        workingHours,
 	}
 
 	templates[conf.SummaryTemplate].Execute(w, vm)
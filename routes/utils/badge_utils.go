@@ -10,19 +10,32 @@ import (
 const (
 	intervalPattern     = `interval:([a-z0-9_]+)`
 	entityFilterPattern = `(project|os|editor|language|machine|label):([^:?&/]+)`
+	savedFilterPattern  = `filter:([a-zA-Z0-9_-]+)`
 )
 
 var (
 	intervalReg     *regexp.Regexp
 	entityFilterReg *regexp.Regexp
+	savedFilterReg  *regexp.Regexp
 )
 
 func init() {
 	intervalReg = regexp.MustCompile(intervalPattern)
 	entityFilterReg = regexp.MustCompile(entityFilterPattern)
+	savedFilterReg = regexp.MustCompile(savedFilterPattern)
 }
 
-func GetBadgeParams(reqPath string, authorizedUser, requestedUser *models.User) (*models.KeyedInterval, *models.Filters, error) {
+// GetBadgeSavedFilterName returns the name of the saved filter referred to by a "filter:<name>" path segment, if any
+func GetBadgeSavedFilterName(reqPath string) string {
+	if groups := savedFilterReg.FindStringSubmatch(reqPath); len(groups) > 1 {
+		return groups[1]
+	}
+	return ""
+}
+
+// GetBadgeParams resolves the badge's interval and filters from the request path
+// savedFilter is optional and, if given, takes precedence over an entity filter in the path
+func GetBadgeParams(reqPath string, authorizedUser, requestedUser *models.User, savedFilter *models.SavedFilter) (*models.KeyedInterval, *models.Filters, error) {
 	isSameUser := authorizedUser != nil && authorizedUser.ID == requestedUser.ID
 
 	var filterEntity, filterKey string
@@ -49,6 +62,19 @@ func GetBadgeParams(reqPath string, authorizedUser, requestedUser *models.User)
 		return nil, nil, errors.New("requested time range too broad")
 	}
 
+	if savedFilter != nil {
+		filters, err := helpers.ParseSavedFilterQuery(savedFilter.Query)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, t := range filters.Entities() {
+			if !sharesEntity(requestedUser, t) && !isSameUser {
+				return nil, nil, errors.New("user did not opt in to share entity-specific data")
+			}
+		}
+		return interval, filters, nil
+	}
+
 	var permitEntity bool
 	var filters *models.Filters
 	switch filterEntity {
@@ -83,3 +109,24 @@ func GetBadgeParams(reqPath string, authorizedUser, requestedUser *models.User)
 
 	return interval, filters, nil
 }
+
+// sharesEntity tells whether the user opted in to share data of the given type publicly
+// branches, files, namespaces and clients cannot be shared on their own
+func sharesEntity(user *models.User, entity uint8) bool {
+	switch entity {
+	case models.SummaryProject:
+		return user.ShareProjects
+	case models.SummaryOS:
+		return user.ShareOSs
+	case models.SummaryEditor:
+		return user.ShareEditors
+	case models.SummaryLanguage:
+		return user.ShareLanguages
+	case models.SummaryMachine:
+		return user.ShareMachines
+	case models.SummaryLabel:
+		return user.ShareLabels
+	default:
+		return false
+	}
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+// SummaryVersion returns when the user's summaries last changed, i.e. the time of their latest heartbeat or the time their summaries were last invalidated
+// (e.g. because heartbeats were edited or deleted, projects renamed or aliases changed), whichever is later
+func SummaryVersion(hs services.IHeartbeatService, ss services.ISummaryService, user *models.User) time.Time {
+	version := ss.DataVersion(user.ID)
+	if latest, err := hs.GetLatestByUser(user); err == nil && latest != nil && latest.Time.T().After(version) {
+		version = latest.Time.T()
+	}
+	return version
+}
+
+// SummaryVariant tells apart representations of the same summary range, e.g. by request and the user's settings affecting how summaries are computed
+func SummaryVariant(user *models.User, discriminator string) string {
+	return fmt.Sprintf("%s_%s_%s", user.ID, user.HeartbeatsTimeout(), discriminator)
+}
+
+// CheckNotModified sets a weak etag and a last-modified header for a summary of the given range, derived from the given version (see SummaryVersion)
+// the variant is to tell apart different representations of the same range, e.g. by filters or query params
+// range bounds are considered with hourly precision only, so that responses for rolling intervals (e.g. last 7 days) are fresh for up to an hour, like the badge cache
+// returns true, if the client's cached copy is still valid, in which case 304 has already been written and nothing else must be
+func CheckNotModified(w http.ResponseWriter, r *http.Request, version, from, to time.Time, variant string) bool {
+	from, to = from.Truncate(time.Hour), to.Truncate(time.Hour)
+
+	lastModified := version
+	for _, t := range []time.Time{from, to} {
+		if t.After(lastModified) && !t.After(time.Now()) {
+			lastModified = t
+		}
+	}
+	lastModified = lastModified.UTC().Truncate(time.Second)
+
+	h := fnv.New64a()
+	h.Write([]byte(fmt.Sprintf("%s|%d|%d|%d", variant, from.Unix(), to.Unix(), version.UnixNano())))
+	etag := fmt.Sprintf(`W/"%x"`, h.Sum64())
+
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+
+	// if-none-match takes precedence, see https://www.rfc-editor.org/rfc/rfc9110#section-13.1.3
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if !matchETag(inm, etag) {
+			return false
+		}
+	} else if ims, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err != nil || lastModified.IsZero() || lastModified.After(ims) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// matchETag performs a weak comparison of the given etag against all listed in an if-none-match header
+func matchETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
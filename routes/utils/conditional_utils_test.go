@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummaryVersion(t *testing.T) {
+	user := &models.User{ID: "user1"}
+	latestHeartbeat := time.Date(2023, 3, 14, 12, 0, 0, 0, time.UTC)
+
+	heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
+	heartbeatServiceMock.On("GetLatestByUser", user).Return(&models.Heartbeat{Time: models.CustomTime(latestHeartbeat)}, nil)
+
+	summaryServiceMock := new(mocks.SummaryServiceMock)
+	summaryServiceMock.On("DataVersion", "user1").Return(latestHeartbeat.Add(-1 * time.Hour)).Once()
+	summaryServiceMock.On("DataVersion", "user1").Return(latestHeartbeat.Add(1 * time.Hour)).Once()
+
+	assert.Equal(t, latestHeartbeat, SummaryVersion(heartbeatServiceMock, summaryServiceMock, user))
+	assert.Equal(t, latestHeartbeat.Add(1*time.Hour), SummaryVersion(heartbeatServiceMock, summaryServiceMock, user)) // e.g. heartbeats deleted
+}
+
+func TestCheckNotModified(t *testing.T) {
+	version := time.Date(2023, 3, 14, 12, 0, 0, 0, time.UTC)
+	from, to := version.AddDate(0, 0, -7), version
+
+	rec := httptest.NewRecorder()
+	assert.False(t, CheckNotModified(rec, httptest.NewRequest(http.MethodGet, "/", nil), version, from, to, "variant"))
+	etag := rec.Header().Get("ETag")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	assert.True(t, CheckNotModified(rec, req, version, from, to, "variant"))
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	// summaries invalidated since
+	rec = httptest.NewRecorder()
+	assert.False(t, CheckNotModified(rec, req, version.Add(1*time.Minute), from, to, "variant"))
+
+	// keystroke timeout changed since
+	user := &models.User{ID: "user1", HeartbeatsTimeoutMinutes: 2}
+	variant := SummaryVariant(user, "variant")
+	user.HeartbeatsTimeoutMinutes = 5
+	assert.NotEqual(t, variant, SummaryVariant(user, "variant"))
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/models/types"
 	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
 	"net/http"
 	"strings"
 )
@@ -41,6 +42,48 @@ func LoadUserSummaryByParams(ss services.ISummaryService, params *models.Summary
 	return summary, nil, http.StatusOK
 }
 
+// LoadUserAnnotationsByParams returns the user's annotations within the summary's interval, restricted to day-wide ones and those of the filtered project when looking at a single project
+func LoadUserAnnotationsByParams(as services.IAnnotationService, params *models.SummaryParams) ([]*models.Annotation, error) {
+	annotations, err := as.GetByUserWithin(params.User, params.From, params.To)
+	if err != nil {
+		return nil, err
+	}
+
+	project := params.GetProjectFilter()
+	if project == "" {
+		return annotations, nil
+	}
+
+	filtered := make([]*models.Annotation, 0, len(annotations))
+	for _, a := range annotations {
+		if a.Project == "" || a.Project == project {
+			filtered = append(filtered, a)
+		}
+	}
+	return filtered, nil
+}
+
+// LoadUserMilestonesByParams returns the user's milestones within the summary's interval, restricted to those of the filtered project when looking at a single project
+func LoadUserMilestonesByParams(ms services.IMilestoneService, params *models.SummaryParams) ([]*models.Milestone, error) {
+	milestones, err := ms.GetByUserWithin(params.User, params.From, params.To)
+	if err != nil {
+		return nil, err
+	}
+
+	project := params.GetProjectFilter()
+	if project == "" {
+		return milestones, nil
+	}
+
+	filtered := make([]*models.Milestone, 0, len(milestones))
+	for _, m := range milestones {
+		if m.Project == project {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered, nil
+}
+
 func FilterColors(all map[string]string, haystack models.SummaryItems) map[string]string {
 	subset := make(map[string]string)
 	for _, item := range haystack {
@@ -50,3 +93,25 @@ func FilterColors(all map[string]string, haystack models.SummaryItems) map[strin
 	}
 	return subset
 }
+
+// FilterColorsOrDerive is like FilterColors, but assigns a stable, hash-based color to items without a predefined one
+func FilterColorsOrDerive(all map[string]string, haystack models.SummaryItems) map[string]string {
+	subset := FilterColors(all, haystack)
+	for _, item := range haystack {
+		key := strings.ToLower(item.Key)
+		if _, ok := subset[key]; !ok && key != strings.ToLower(models.UnknownSummaryKey) {
+			subset[key] = utils.DeriveColor(key)
+		}
+	}
+	return subset
+}
+
+// GetBadgeColor returns the color (without leading hash) of the language a badge is filtered by, if it is filtered by exactly one, so that it matches the dashboard's charts
+func GetBadgeColor(languageColors map[string]string, filters *models.Filters, summary *models.Summary) (string, bool) {
+	if filters == nil || filters.CountByEntity(models.SummaryLanguage) != 1 {
+		return "", false
+	}
+	language := strings.ToLower((*filters.ResolveEntity(models.SummaryLanguage))[0])
+	color, ok := FilterColorsOrDerive(languageColors, summary.Languages)[language]
+	return strings.TrimPrefix(color, "#"), ok
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadUserAnnotationsByParams(t *testing.T) {
+	user := &models.User{ID: "user1"}
+	from, to := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 5, 8, 0, 0, 0, 0, time.UTC)
+	annotations := []*models.Annotation{
+		{ID: 1, Day: "2023-05-01", Text: "conference"},
+		{ID: 2, Day: "2023-05-02", Project: "wakapi", Text: "release"},
+		{ID: 3, Day: "2023-05-03", Project: "anchr", Text: "refactoring"},
+	}
+
+	annotationServiceMock := new(mocks.AnnotationServiceMock)
+	annotationServiceMock.On("GetByUserWithin", user, from, to).Return(annotations, nil)
+
+	// no filter -> all annotations
+	result, err := LoadUserAnnotationsByParams(annotationServiceMock, &models.SummaryParams{User: user, From: from, To: to})
+	assert.Nil(t, err)
+	assert.Len(t, result, 3)
+
+	// single project -> day-wide ones and those of the project
+	result, err = LoadUserAnnotationsByParams(annotationServiceMock, &models.SummaryParams{User: user, From: from, To: to, Filters: models.NewFiltersWith(models.SummaryProject, "wakapi")})
+	assert.Nil(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, uint(1), result[0].ID)
+	assert.Equal(t, uint(2), result[1].ID)
+
+	// other filters -> no restriction
+	result, err = LoadUserAnnotationsByParams(annotationServiceMock, &models.SummaryParams{User: user, From: from, To: to, Filters: models.NewFiltersWith(models.SummaryLanguage, "Go")})
+	assert.Nil(t, err)
+	assert.Len(t, result, 3)
+}
+
+func TestLoadUserAnnotationsByParams_Error(t *testing.T) {
+	user := &models.User{ID: "user1"}
+	from, to := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 5, 8, 0, 0, 0, 0, time.UTC)
+
+	annotationServiceMock := new(mocks.AnnotationServiceMock)
+	annotationServiceMock.On("GetByUserWithin", user, from, to).Return([]*models.Annotation{}, errors.New("failed"))
+
+	result, err := LoadUserAnnotationsByParams(annotationServiceMock, &models.SummaryParams{User: user, From: from, To: to})
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestLoadUserMilestonesByParams(t *testing.T) {
+	user := &models.User{ID: "user1"}
+	from, to := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 5, 8, 0, 0, 0, 0, time.UTC)
+	milestones := []*models.Milestone{
+		{ID: 1, Day: "2023-05-02", Project: "wakapi", Name: "v1.0"},
+		{ID: 2, Day: "2023-05-03", Project: "anchr", Name: "v2.0"},
+	}
+
+	milestoneServiceMock := new(mocks.MilestoneServiceMock)
+	milestoneServiceMock.On("GetByUserWithin", user, from, to).Return(milestones, nil)
+
+	// no filter -> all milestones
+	result, err := LoadUserMilestonesByParams(milestoneServiceMock, &models.SummaryParams{User: user, From: from, To: to})
+	assert.Nil(t, err)
+	assert.Len(t, result, 2)
+
+	// single project -> only those of the project
+	result, err = LoadUserMilestonesByParams(milestoneServiceMock, &models.SummaryParams{User: user, From: from, To: to, Filters: models.NewFiltersWith(models.SummaryProject, "wakapi")})
+	assert.Nil(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, uint(1), result[0].ID)
+}
+
+func TestFilterColorsOrDerive(t *testing.T) {
+	colors := map[string]string{"go": "#00ADD8", "java": "#B07219"}
+	items := models.SummaryItems{
+		{Key: "Go"},
+		{Key: "Zig-Nightly"},
+		{Key: models.UnknownSummaryKey},
+	}
+
+	result := FilterColorsOrDerive(colors, items)
+	assert.Len(t, result, 2)
+	assert.Equal(t, "#00ADD8", result["go"])
+	assert.NotEmpty(t, result["zig-nightly"])
+	assert.Equal(t, result["zig-nightly"], FilterColorsOrDerive(colors, items)["zig-nightly"])
+	assert.NotContains(t, result, "java")
+}
+
+func TestGetBadgeColor(t *testing.T) {
+	colors := map[string]string{"go": "#00ADD8"}
+	summary := &models.Summary{Languages: models.SummaryItems{{Key: "Go"}, {Key: "Zig"}}}
+
+	color, ok := GetBadgeColor(colors, models.NewFiltersWith(models.SummaryLanguage, "Go"), summary)
+	assert.True(t, ok)
+	assert.Equal(t, "00ADD8", color)
+
+	color, ok = GetBadgeColor(colors, models.NewFiltersWith(models.SummaryLanguage, "zig"), summary)
+	assert.True(t, ok)
+	assert.NotEmpty(t, color)
+
+	_, ok = GetBadgeColor(colors, models.NewFiltersWith(models.SummaryProject, "wakapi"), summary)
+	assert.False(t, ok)
+}
@@ -32,7 +32,8 @@ func CheckEffectiveUser(w http.ResponseWriter, r *http.Request, userService serv
 		return authorizedUser, nil
 	}
 
-	if authorizedUser.ID != userParam && !authorizedUser.IsAdmin {
+	// admins may act on behalf of others, but only from within the management network, if restricted
+	if authorizedUser.ID != userParam && (!authorizedUser.IsAdmin || !middlewares.IsAdminIpAllowed(r)) {
 		return respondError(http.StatusUnauthorized, conf.ErrUnauthorized)
 	}
 
@@ -3,6 +3,7 @@ package utils
 import (
 	"context"
 	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/mocks"
 	"github.com/muety/wakapi/models"
@@ -23,6 +24,8 @@ func TestCheckEffectiveUser_Current(t *testing.T) {
 }
 
 func TestCheckEffectiveUser_Other(t *testing.T) {
+	config.Set(config.Empty())
+
 	// request someone else as admin -> return someone else
 	r, w, userServiceMock := mockUserAwareRequest("user2", "admin")
 	user, err := CheckEffectiveUser(w, r, userServiceMock, "current")
@@ -32,6 +35,34 @@ func TestCheckEffectiveUser_Other(t *testing.T) {
 	userServiceMock.AssertNumberOfCalls(t, "GetUserById", 1)
 }
 
+func TestCheckEffectiveUser_OtherOutsideAdminIpAllowlist(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Security.AdminIpAllowlist = "10.0.0.0/8"
+	assert.Nil(t, cfg.Security.ParseAdminIpAllowlist())
+	config.Set(cfg)
+	defer config.Set(config.Empty())
+
+	// request someone else as admin from outside the allowlist -> error
+	r, w, userServiceMock := mockUserAwareRequest("user2", "admin")
+	user, err := CheckEffectiveUser(w, r, userServiceMock, "current")
+	assert.NotNil(t, err)
+	assert.Nil(t, user)
+	userServiceMock.AssertNumberOfCalls(t, "GetUserById", 0)
+
+	// request myself as admin from outside the allowlist -> return myself
+	r, w, userServiceMock = mockUserAwareRequest("admin", "admin")
+	user, err = CheckEffectiveUser(w, r, userServiceMock, "current")
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", user.ID)
+
+	// request someone else as admin from within the allowlist -> return someone else
+	r, w, userServiceMock = mockUserAwareRequest("user2", "admin")
+	r.RemoteAddr = "10.1.2.3:54654"
+	user, err = CheckEffectiveUser(w, r, userServiceMock, "current")
+	assert.Nil(t, err)
+	assert.Equal(t, "user2", user.ID)
+}
+
 func TestCheckEffectiveUser_FallbackUnauthorized(t *testing.T) {
 	// request someone else as non-admin -> error
 	r, w, userServiceMock := mockUserAwareRequest("user2", "user1")
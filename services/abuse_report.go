@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"time"
+)
+
+const maxAbuseReportsPerDay = 10
+
+var (
+	ErrAbuseReportTargetNotFound = errors.New("reported user or project not found")
+	ErrAbuseReportDuplicate      = errors.New("an open report for this target already exists")
+	ErrAbuseReportLimitExceeded  = errors.New("too many reports")
+)
+
+type AbuseReportService struct {
+	config           *config.Config
+	repository       repositories.IAbuseReportRepository
+	userService      IUserService
+	aliasService     IAliasService
+	heartbeatService IHeartbeatService
+}
+
+func NewAbuseReportService(abuseReportRepo repositories.IAbuseReportRepository, userService IUserService, aliasService IAliasService, heartbeatService IHeartbeatService) *AbuseReportService {
+	return &AbuseReportService{
+		config:           config.Get(),
+		repository:       abuseReportRepo,
+		userService:      userService,
+		aliasService:     aliasService,
+		heartbeatService: heartbeatService,
+	}
+}
+
+func (srv *AbuseReportService) GetById(id uint) (*models.AbuseReport, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *AbuseReportService) GetOpen() ([]*models.AbuseReport, error) {
+	return srv.repository.GetByStatus(models.AbuseReportStatusOpen)
+}
+
+// Create files a new report after checking that its target exists, that the reporter has no open report for the same target yet and that they did not exceed their daily limit
+func (srv *AbuseReportService) Create(report *models.AbuseReport) (*models.AbuseReport, error) {
+	if report.ReporterID != nil {
+		count, err := srv.repository.CountByReporterAfter(*report.ReporterID, time.Now().Add(-24*time.Hour))
+		if err != nil {
+			return nil, err
+		}
+		if count >= maxAbuseReportsPerDay {
+			return nil, ErrAbuseReportLimitExceeded
+		}
+	}
+
+	if _, err := srv.userService.GetUserById(report.TargetID); err != nil {
+		return nil, ErrAbuseReportTargetNotFound
+	}
+
+	if report.TargetType == models.AbuseReportTargetProject {
+		projects, err := srv.heartbeatService.GetEntitySetByUser(models.SummaryProject, report.TargetID)
+		if err != nil {
+			return nil, err
+		}
+		if !slice.Contain(projects, report.TargetProject) {
+			return nil, ErrAbuseReportTargetNotFound
+		}
+	}
+
+	if report.ReporterID != nil {
+		count, err := srv.repository.CountOpenByReporterAndTarget(report)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, ErrAbuseReportDuplicate
+		}
+	}
+
+	report.Status = models.AbuseReportStatusOpen
+	return srv.repository.Insert(report)
+}
+
+// Resolve applies the moderation action to the reported user or project and closes the report
+func (srv *AbuseReportService) Resolve(report *models.AbuseReport, resolution *models.AbuseReportResolution) (*models.AbuseReport, error) {
+	if !report.IsOpen() {
+		return nil, errors.New("report already closed")
+	}
+
+	if resolution.Action == models.AbuseReportActionDismiss {
+		return srv.repository.UpdateStatus(report, models.AbuseReportStatusDismissed)
+	}
+
+	user, err := srv.userService.GetUserById(report.TargetID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resolution.Action {
+	case models.AbuseReportActionHide:
+		err = srv.hide(user, report)
+	case models.AbuseReportActionRename:
+		err = srv.rename(user, report, resolution.NewName)
+	default:
+		err = errors.New("unsupported action")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return srv.repository.UpdateStatus(report, models.AbuseReportStatusResolved)
+}
+
+func (srv *AbuseReportService) hide(user *models.User, report *models.AbuseReport) error {
+	if report.TargetType == models.AbuseReportTargetProject {
+		// projects are only ever exposed publicly through shared stats
+		user.ShareProjects = false
+	} else {
+		user.IsHidden = true
+		user.PublicLeaderboard = false
+		user.ShareDataMaxDays = 0
+	}
+	_, err := srv.userService.Update(user)
+	return err
+}
+
+func (srv *AbuseReportService) rename(user *models.User, report *models.AbuseReport, newName string) error {
+	if report.TargetType == models.AbuseReportTargetUser {
+		_, err := srv.userService.ChangeUserId(user, newName)
+		return err
+	}
+
+	// force-renaming a project is done by aliasing it, which leaves the raw heartbeats untouched
+	_, err := srv.aliasService.Create(&models.Alias{
+		Type:   models.SummaryProject,
+		UserID: user.ID,
+		Key:    newName,
+		Value:  report.TargetProject,
+	})
+	return err
+}
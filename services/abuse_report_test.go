@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type AbuseReportServiceTestSuite struct {
+	suite.Suite
+	Reporter              *models.User
+	Target                *models.User
+	AbuseReportRepository *mocks.AbuseReportRepositoryMock
+	UserService           *mocks.UserServiceMock
+	AliasService          *mocks.AliasServiceMock
+	HeartbeatService      *mocks.HeartbeatServiceMock
+}
+
+func (suite *AbuseReportServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+	suite.Reporter = &models.User{ID: "reporter"}
+	suite.Target = &models.User{ID: "target"}
+}
+
+func (suite *AbuseReportServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.AbuseReportRepository = new(mocks.AbuseReportRepositoryMock)
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.AliasService = new(mocks.AliasServiceMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+
+	suite.UserService.On("GetUserById", suite.Target.ID).Return(suite.Target, nil)
+	suite.UserService.On("GetUserById", mock.Anything).Return((*models.User)(nil), errors.New("not found"))
+	suite.HeartbeatService.On("GetEntitySetByUser", models.SummaryProject, suite.Target.ID).Return([]string{"wakapi"}, nil)
+	suite.AbuseReportRepository.On("Insert", mock.Anything).Return(&models.AbuseReport{}, nil)
+}
+
+func TestAbuseReportServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(AbuseReportServiceTestSuite))
+}
+
+func (suite *AbuseReportServiceTestSuite) TestAbuseReportService_Create() {
+	suite.AbuseReportRepository.On("CountByReporterAfter", suite.Reporter.ID, mock.Anything).Return(0, nil)
+	suite.AbuseReportRepository.On("CountOpenByReporterAndTarget", mock.Anything).Return(0, nil)
+
+	sut := NewAbuseReportService(suite.AbuseReportRepository, suite.UserService, suite.AliasService, suite.HeartbeatService)
+
+	report := &models.AbuseReport{ReporterID: &suite.Reporter.ID, TargetID: suite.Target.ID, TargetType: models.AbuseReportTargetProject, TargetProject: "wakapi"}
+	_, err := sut.Create(report)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), models.AbuseReportStatusOpen, report.Status)
+	suite.AbuseReportRepository.AssertCalled(suite.T(), "Insert", report)
+}
+
+func (suite *AbuseReportServiceTestSuite) TestAbuseReportService_Create_UnknownTarget() {
+	suite.AbuseReportRepository.On("CountByReporterAfter", suite.Reporter.ID, mock.Anything).Return(0, nil)
+	suite.AbuseReportRepository.On("CountOpenByReporterAndTarget", mock.Anything).Return(0, nil)
+
+	sut := NewAbuseReportService(suite.AbuseReportRepository, suite.UserService, suite.AliasService, suite.HeartbeatService)
+
+	_, err1 := sut.Create(&models.AbuseReport{ReporterID: &suite.Reporter.ID, TargetID: "unknown", TargetType: models.AbuseReportTargetUser})
+	_, err2 := sut.Create(&models.AbuseReport{ReporterID: &suite.Reporter.ID, TargetID: suite.Target.ID, TargetType: models.AbuseReportTargetProject, TargetProject: "anchr"})
+
+	assert.ErrorIs(suite.T(), err1, ErrAbuseReportTargetNotFound)
+	assert.ErrorIs(suite.T(), err2, ErrAbuseReportTargetNotFound)
+	suite.AbuseReportRepository.AssertNotCalled(suite.T(), "Insert", mock.Anything)
+}
+
+func (suite *AbuseReportServiceTestSuite) TestAbuseReportService_Create_Duplicate() {
+	suite.AbuseReportRepository.On("CountByReporterAfter", suite.Reporter.ID, mock.Anything).Return(1, nil)
+	suite.AbuseReportRepository.On("CountOpenByReporterAndTarget", mock.Anything).Return(1, nil)
+
+	sut := NewAbuseReportService(suite.AbuseReportRepository, suite.UserService, suite.AliasService, suite.HeartbeatService)
+
+	_, err := sut.Create(&models.AbuseReport{ReporterID: &suite.Reporter.ID, TargetID: suite.Target.ID, TargetType: models.AbuseReportTargetUser})
+
+	assert.ErrorIs(suite.T(), err, ErrAbuseReportDuplicate)
+	suite.AbuseReportRepository.AssertNotCalled(suite.T(), "Insert", mock.Anything)
+}
+
+func (suite *AbuseReportServiceTestSuite) TestAbuseReportService_Create_LimitExceeded() {
+	suite.AbuseReportRepository.On("CountByReporterAfter", suite.Reporter.ID, mock.Anything).Return(maxAbuseReportsPerDay, nil)
+
+	sut := NewAbuseReportService(suite.AbuseReportRepository, suite.UserService, suite.AliasService, suite.HeartbeatService)
+
+	_, err := sut.Create(&models.AbuseReport{ReporterID: &suite.Reporter.ID, TargetID: suite.Target.ID, TargetType: models.AbuseReportTargetUser})
+
+	assert.ErrorIs(suite.T(), err, ErrAbuseReportLimitExceeded)
+	suite.AbuseReportRepository.AssertNotCalled(suite.T(), "Insert", mock.Anything)
+}
+
+func (suite *AbuseReportServiceTestSuite) TestAbuseReportService_Resolve_Rename() {
+	report := &models.AbuseReport{ID: 1, TargetID: suite.Target.ID, TargetType: models.AbuseReportTargetUser, Status: models.AbuseReportStatusOpen}
+
+	suite.UserService.On("ChangeUserId", suite.Target, "renamed").Return(&models.User{ID: "renamed"}, nil)
+	suite.AbuseReportRepository.On("UpdateStatus", report, models.AbuseReportStatusResolved).Return(report, nil)
+
+	sut := NewAbuseReportService(suite.AbuseReportRepository, suite.UserService, suite.AliasService, suite.HeartbeatService)
+
+	_, err := sut.Resolve(report, &models.AbuseReportResolution{Action: models.AbuseReportActionRename, NewName: "renamed"})
+
+	assert.Nil(suite.T(), err)
+	suite.UserService.AssertCalled(suite.T(), "ChangeUserId", suite.Target, "renamed")
+	suite.AbuseReportRepository.AssertCalled(suite.T(), "UpdateStatus", report, models.AbuseReportStatusResolved)
+}
+
+func (suite *AbuseReportServiceTestSuite) TestAbuseReportService_Resolve_RenameTaken() {
+	report := &models.AbuseReport{ID: 1, TargetID: suite.Target.ID, TargetType: models.AbuseReportTargetUser, Status: models.AbuseReportStatusOpen}
+
+	suite.UserService.On("ChangeUserId", suite.Target, "taken").Return((*models.User)(nil), ErrUsernameTaken)
+
+	sut := NewAbuseReportService(suite.AbuseReportRepository, suite.UserService, suite.AliasService, suite.HeartbeatService)
+
+	_, err := sut.Resolve(report, &models.AbuseReportResolution{Action: models.AbuseReportActionRename, NewName: "taken"})
+
+	assert.ErrorIs(suite.T(), err, ErrUsernameTaken)
+	suite.AbuseReportRepository.AssertNotCalled(suite.T(), "UpdateStatus", mock.Anything, mock.Anything)
+}
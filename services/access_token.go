@@ -0,0 +1,105 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+)
+
+const (
+	accessTokenBytes = 24
+	// interval at which a token's last usage is persisted at most, to avoid a database write on every request
+	accessTokenLastUsedPrecision = 1 * time.Hour
+)
+
+type AccessTokenService struct {
+	config     *config.Config
+	cache      utils.Cache // shared among replicas, if configured, so that deleted tokens are revoked everywhere at once
+	repository repositories.IAccessTokenRepository
+}
+
+func NewAccessTokenService(accessTokenRepo repositories.IAccessTokenRepository) *AccessTokenService {
+	return &AccessTokenService{
+		config:     config.Get(),
+		cache:      config.NewCache("access_tokens", 1*time.Hour, 1*time.Hour),
+		repository: accessTokenRepo,
+	}
+}
+
+// Create issues a new token for the given user and returns the plain token, which is not retrievable afterwards
+func (srv *AccessTokenService) Create(user *models.User, name, scope string) (string, *models.AccessToken, error) {
+	randomBytes := make([]byte, accessTokenBytes)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", nil, err
+	}
+	plainToken := models.AccessTokenPrefix + hex.EncodeToString(randomBytes)
+
+	token, err := srv.repository.Insert(&models.AccessToken{
+		UserID:    user.ID,
+		TokenHash: srv.hash(plainToken),
+		Name:      name,
+		Scope:     scope,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return plainToken, token, nil
+}
+
+// GetByToken resolves a plain token and keeps track of its usage
+// returns a copy, since cached tokens are shared among concurrent requests and must not be modified
+func (srv *AccessTokenService) GetByToken(plainToken string) (*models.AccessToken, error) {
+	hash := srv.hash(plainToken)
+
+	var token models.AccessToken
+	if !srv.cache.Get(hash, &token) {
+		t, err := srv.repository.GetByHash(hash)
+		if err != nil {
+			return nil, err
+		}
+		token = *t
+		srv.cache.SetDefault(hash, token)
+	}
+
+	if token.LastUsedAt == nil || time.Since(token.LastUsedAt.T()) > accessTokenLastUsedPrecision {
+		now := models.CustomTime(time.Now())
+		token.LastUsedAt = &now
+		if err := srv.repository.UpdateLastUsed(&token); err != nil {
+			config.Log().Error("failed to update last usage of access token %d - %v", token.ID, err)
+		}
+		srv.cache.SetDefault(hash, token)
+	}
+
+	return &token, nil
+}
+
+func (srv *AccessTokenService) GetByUser(user *models.User) ([]*models.AccessToken, error) {
+	return srv.repository.GetByUser(user.ID)
+}
+
+func (srv *AccessTokenService) Delete(user *models.User, id uint) error {
+	tokens, err := srv.repository.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := srv.repository.DeleteByUserAndId(user.ID, id); err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		if t.ID == id {
+			srv.cache.Delete(t.TokenHash)
+		}
+	}
+	return nil
+}
+
+func (srv *AccessTokenService) hash(plainToken string) string {
+	h := sha256.Sum256([]byte(plainToken))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAccessTokenService_GetByToken_DoesNotModifyCachedToken(t *testing.T) {
+	config.Set(config.Empty())
+
+	plainToken := models.AccessTokenPrefix + "abc123"
+	storedToken := &models.AccessToken{ID: 1, UserID: "user01", Scope: models.ScopeReadStats}
+
+	repositoryMock := new(mocks.AccessTokenRepositoryMock)
+	repositoryMock.On("GetByHash", mock.Anything).Return(storedToken, nil)
+	repositoryMock.On("UpdateLastUsed", mock.Anything).Return(nil)
+
+	sut := NewAccessTokenService(repositoryMock)
+
+	token1, err := sut.GetByToken(plainToken)
+	assert.Nil(t, err)
+	assert.NotNil(t, token1.LastUsedAt)
+	assert.Nil(t, storedToken.LastUsedAt)
+
+	token2, err := sut.GetByToken(plainToken)
+	assert.Nil(t, err)
+	assert.NotSame(t, token1, token2)
+	assert.Equal(t, token1.LastUsedAt.T(), token2.LastUsedAt.T())
+
+	// second lookup is served from cache and last usage is persisted at most once per hour
+	repositoryMock.AssertNumberOfCalls(t, "GetByHash", 1)
+	repositoryMock.AssertNumberOfCalls(t, "UpdateLastUsed", 1)
+}
+
+func TestAccessTokenService_Delete_RevokesCachedToken(t *testing.T) {
+	config.Set(config.Empty())
+
+	plainToken := models.AccessTokenPrefix + "abc123"
+	sut := NewAccessTokenService(nil)
+	storedToken := &models.AccessToken{ID: 1, UserID: "user01", TokenHash: sut.hash(plainToken), Scope: models.ScopeReadStats}
+
+	repositoryMock := new(mocks.AccessTokenRepositoryMock)
+	repositoryMock.On("GetByHash", storedToken.TokenHash).Return(storedToken, nil).Once()
+	repositoryMock.On("GetByHash", storedToken.TokenHash).Return((*models.AccessToken)(nil), errors.New("record not found"))
+	repositoryMock.On("UpdateLastUsed", mock.Anything).Return(nil)
+	repositoryMock.On("GetByUser", "user01").Return([]*models.AccessToken{storedToken}, nil)
+	repositoryMock.On("DeleteByUserAndId", "user01", uint(1)).Return(nil)
+	sut.repository = repositoryMock
+
+	_, err := sut.GetByToken(plainToken)
+	assert.Nil(t, err)
+
+	assert.Nil(t, sut.Delete(&models.User{ID: "user01"}, 1))
+
+	_, err = sut.GetByToken(plainToken)
+	assert.NotNil(t, err)
+	repositoryMock.AssertNumberOfCalls(t, "GetByHash", 2)
+}
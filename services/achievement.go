@@ -0,0 +1,176 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+// users whose summaries were updated are evaluated in batches, so that an aggregation run spanning many days triggers only a single evaluation per user
+const evaluateAchievementsEvery = 10 * time.Minute
+
+type AchievementService struct {
+	config         *config.Config
+	repository     repositories.IAchievementRepository
+	userService    IUserService
+	summaryService ISummaryService
+	mailService    IMailService
+	notifySrvc     INotificationService
+	eventBus       *hub.Hub
+	queueDefault   *artifex.Dispatcher
+	queueWorkers   *artifex.Dispatcher
+	queueMails     *artifex.Dispatcher
+	pendingUsers   datastructure.Set[string] // users who got new summaries since the last evaluation
+	pendingLock    sync.Mutex
+}
+
+func NewAchievementService(achievementRepository repositories.IAchievementRepository, userService IUserService, summaryService ISummaryService, mailService IMailService, notificationService INotificationService) *AchievementService {
+	srv := &AchievementService{
+		config:         config.Get(),
+		repository:     achievementRepository,
+		userService:    userService,
+		summaryService: summaryService,
+		mailService:    mailService,
+		notifySrvc:     notificationService,
+		eventBus:       config.EventBus(),
+		queueDefault:   config.GetDefaultQueue(),
+		queueWorkers:   config.GetQueue(config.QueueProcessing),
+		queueMails:     config.GetQueue(config.QueueMails),
+		pendingUsers:   datastructure.NewSet[string](),
+	}
+
+	onSummaryCreate := srv.eventBus.Subscribe(0, config.EventSummaryCreate)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.pendingLock.Lock()
+			srv.pendingUsers.Add(m.Fields[config.FieldUserId].(string))
+			srv.pendingLock.Unlock()
+		}
+	}(&onSummaryCreate)
+
+	return srv
+}
+
+func (srv *AchievementService) Schedule() {
+	logbuch.Info("scheduling achievements evaluation")
+	if _, err := srv.queueDefault.DispatchEvery(srv.evaluatePending, evaluateAchievementsEvery); err != nil {
+		config.Log().Error("failed to schedule achievements evaluation, %v", err)
+	}
+}
+
+// GetByUser returns all achievements unlocked by the user, ordered by the time they were unlocked at
+func (srv *AchievementService) GetByUser(user *models.User) ([]*models.Achievement, error) {
+	achievements, err := srv.repository.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.Achievement, 0, len(achievements))
+	for _, a := range achievements {
+		// achievements whose definition was removed in the meantime are kept in the database, but not shown anymore
+		if models.GetAchievementDefinition(a.Key) != nil {
+			result = append(result, a.WithDefinition())
+		}
+	}
+	return result, nil
+}
+
+// Evaluate checks the user's all-time statistics against every achievement definition and unlocks the ones newly reached, which are returned
+func (srv *AchievementService) Evaluate(user *models.User) ([]*models.Achievement, error) {
+	existing, err := srv.repository.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	unlocked := datastructure.NewSet[string]()
+	for _, a := range existing {
+		unlocked.Add(a.Key)
+	}
+
+	summaries, err := srv.summaryService.GetByUserWithin(user, time.Time{}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	stats := models.NewAchievementStats(summaries, user.TZ())
+
+	now := models.CustomTime(time.Now())
+	reached := make([]*models.Achievement, 0)
+	for _, d := range models.Achievements {
+		if unlocked.Contain(d.Key) || !d.IsReached(stats) {
+			continue
+		}
+		reached = append(reached, &models.Achievement{UserID: user.ID, Key: d.Key, UnlockedAt: now})
+	}
+
+	if err := srv.repository.InsertBatch(reached); err != nil {
+		return nil, err
+	}
+	for _, a := range reached {
+		a.WithDefinition()
+	}
+	return reached, nil
+}
+
+func (srv *AchievementService) evaluatePending() {
+	srv.pendingLock.Lock()
+	userIds := srv.pendingUsers
+	srv.pendingUsers = datastructure.NewSet[string]()
+	srv.pendingLock.Unlock()
+
+	if userIds.IsEmpty() {
+		return
+	}
+
+	users, err := srv.userService.GetMany(userIds.Values())
+	if err != nil {
+		config.Log().Error("failed to fetch users for achievements evaluation, %v", err)
+		return
+	}
+
+	for _, user := range users {
+		u := user
+		if err := srv.queueWorkers.Dispatch(func() {
+			reached, err := srv.Evaluate(u)
+			if err != nil {
+				config.Log().Error("failed to evaluate achievements of user '%s', %v", u.ID, err)
+				return
+			}
+			if len(reached) > 0 {
+				logbuch.Info("user '%s' unlocked %d new achievements", u.ID, len(reached))
+				srv.notifyUnlocked(u, reached)
+			}
+		}); err != nil {
+			config.Log().Error("failed to dispatch achievements evaluation for user '%s', %v", u.ID, err)
+		}
+	}
+}
+
+func (srv *AchievementService) notifyUnlocked(user *models.User, achievements []*models.Achievement) {
+	if !user.NotifyAchievements || user.Email == "" || !config.Get().Mail.Enabled {
+		return
+	}
+
+	names := make([]string, len(achievements))
+	for i, a := range achievements {
+		names[i] = a.Name
+	}
+
+	srv.queueMails.Dispatch(func() {
+		logbuch.Info("sending achievements mail to %s", user.ID)
+
+		text := fmt.Sprintf("You unlocked %d new achievements: %s.", len(achievements), strings.Join(names, ", "))
+		if err := srv.notifySrvc.Notify(user, "New achievements unlocked", text, func() error {
+			return srv.mailService.SendAchievementsUnlocked(user, achievements)
+		}); err != nil {
+			config.Log().Error("failed to send achievements mail to user '%s', %v", user.ID, err)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
 	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
 	"github.com/muety/artifex/v2"
 	"github.com/muety/wakapi/config"
 	"sync"
@@ -18,6 +19,15 @@ const (
 
 var aggregationLock = sync.Mutex{}
 
+// there is at most one backfill across all users at a time, which is tracked under this key
+const backfillJobKey = "*"
+
+var (
+	ErrAggregationInProgress = errors.New("aggregation already in progress for at least of the request users")
+	ErrBackfillPending       = errors.New("another backfill is still in progress")
+	ErrBackfillUnknownUsers  = errors.New("backfill requested for unknown users")
+)
+
 type AggregationService struct {
 	config           *config.Config
 	userService      IUserService
@@ -26,9 +36,17 @@ type AggregationService struct {
 	inProgress       datastructure.Set[string]
 	queueDefault     *artifex.Dispatcher
 	queueWorkers     *artifex.Dispatcher
+	crons            *cronJobs
+	backfills        *userJobs[models.AggregationBackfillJob]
+	eventBus         *hub.Hub
+	activeUsers      datastructure.Set[string] // users who sent heartbeats since the last incremental aggregation
+	activeUsersLock  sync.Mutex
+	partialsCleared  time.Time            // start of the day as of which outdated partial summaries were last deleted
+	invalidated      map[string]time.Time // per user, when their heartbeats of the current day were last modified
+	invalidatedLock  sync.Mutex
 }
 
-func NewAggregationService(userService IUserService, summaryService ISummaryService, heartbeatService IHeartbeatService) *AggregationService {
+func NewAggregationService(userService IUserService, summaryService ISummaryService, heartbeatService IHeartbeatService, jobLockService IJobLockService) *AggregationService {
 	return &AggregationService{
 		config:           config.Get(),
 		userService:      userService,
@@ -37,6 +55,11 @@ func NewAggregationService(userService IUserService, summaryService ISummaryServ
 		inProgress:       datastructure.NewSet[string](),
 		queueDefault:     config.GetDefaultQueue(),
 		queueWorkers:     config.GetQueue(config.QueueProcessing),
+		crons:            newCronJobs("aggregation", config.GetDefaultQueue(), jobLockService),
+		backfills:        newUserJobs((*models.AggregationBackfillJob).IsPending),
+		eventBus:         config.EventBus(),
+		activeUsers:      datastructure.NewSet[string](),
+		invalidated:      map[string]time.Time{},
 	}
 }
 
@@ -48,13 +71,39 @@ type AggregationJob struct {
 
 // Schedule a job to (re-)generate summaries every day shortly after midnight
 func (srv *AggregationService) Schedule() {
+	srv.scheduleAggregation()
+	config.OnReload(srv.scheduleAggregation, config.ReloadKeyAggregationTime)
+
+	if interval := srv.config.App.IncrementalAggregationInterval(); interval > 0 {
+		srv.scheduleIncrementalAggregation(interval)
+	}
+}
+
+// scheduleIncrementalAggregation keeps partial summaries of the current day up to date for users who are currently active, so that retrieving today's stats only requires to summarize the last few minutes' heartbeats
+func (srv *AggregationService) scheduleIncrementalAggregation(interval time.Duration) {
+	logbuch.Info("scheduling incremental summary aggregation every %v", interval)
+
+	onHeartbeat := srv.eventBus.Subscribe(0, config.EventHeartbeatCreate)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			heartbeat := m.Fields[config.FieldPayload].(*models.Heartbeat)
+			srv.markActive(heartbeat.UserID)
+		}
+	}(&onHeartbeat)
+
+	if _, err := srv.queueDefault.DispatchEvery(srv.AggregateIncrementally, interval); err != nil {
+		config.Log().Error("failed to schedule incremental summary aggregation, %v", err)
+	}
+}
+
+func (srv *AggregationService) scheduleAggregation() {
 	logbuch.Info("scheduling summary aggregation")
 
-	if _, err := srv.queueDefault.DispatchCron(func() {
+	if err := srv.crons.Schedule(func() {
 		if err := srv.AggregateSummaries(datastructure.NewSet[string]()); err != nil {
 			config.Log().Error("failed to generate summaries, %v", err)
 		}
-	}, srv.config.App.GetAggregationTimeCron()); err != nil {
+	}, config.Get().App.GetAggregationTimeCron()); err != nil {
 		config.Log().Error("failed to schedule summary generation, %v", err)
 	}
 }
@@ -67,6 +116,13 @@ func (srv *AggregationService) AggregateSummaries(userIds datastructure.Set[stri
 
 	logbuch.Info("generating summaries")
 
+	// partial summaries of past days would otherwise overlap with the final ones generated below
+	now := time.Now()
+	if err := srv.summaryService.DeletePartialBefore(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())); err != nil {
+		config.Log().Error(err.Error())
+		return err
+	}
+
 	// Get a map from user ids to the time of their latest summary or nil if none exists yet
 	lastUserSummaryTimes, err := srv.summaryService.GetLatestByUser()
 	if err != nil {
@@ -94,6 +150,10 @@ func (srv *AggregationService) AggregateSummaries(userIds datastructure.Set[stri
 		for jobRef := range jobs {
 			job := *jobRef
 			if err := srv.queueWorkers.Dispatch(func() {
+				// jobs are queued in chronological order per user, so skipping all remaining ones leaves no gaps and the next run picks up from the latest summary
+				if config.IsShuttingDown() {
+					return
+				}
 				srv.process(job)
 			}); err != nil {
 				config.Log().Error("failed to dispatch summary generation job for user '%s'", job.UserID)
@@ -123,8 +183,249 @@ func (srv *AggregationService) AggregateSummaries(userIds datastructure.Set[stri
 	return nil
 }
 
+// AggregateIncrementally (re-)generates today's partial summaries of all users who sent heartbeats since its last run
+// partial summaries of past days are dropped, the nightly aggregation replaces them with final ones
+func (srv *AggregationService) AggregateIncrementally() {
+	if config.IsShuttingDown() {
+		return
+	}
+
+	now := time.Now()
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	// only once a day, as partial summaries aren't indexed
+	if startOfToday.After(srv.partialsCleared) {
+		if err := srv.summaryService.DeletePartialBefore(startOfToday); err != nil {
+			config.Log().Error("failed to delete outdated partial summaries, %v", err)
+		} else {
+			srv.partialsCleared = startOfToday
+		}
+
+		srv.invalidatedLock.Lock()
+		srv.invalidated = map[string]time.Time{}
+		srv.invalidatedLock.Unlock()
+	}
+
+	srv.activeUsersLock.Lock()
+	userIds := srv.activeUsers
+	srv.activeUsers = datastructure.NewSet[string]()
+	srv.activeUsersLock.Unlock()
+
+	for userId := range userIds {
+		uid := userId
+		if err := srv.queueWorkers.Dispatch(func() {
+			srv.processPartial(uid, startOfToday, now)
+		}); err != nil {
+			config.Log().Error("failed to dispatch partial summary generation job for user '%s'", uid)
+		}
+	}
+}
+
+// RegenerateSummaries drops all of the user's daily summaries touching the given time range and re-computes them from heartbeats
+// to be called whenever heartbeats in the past were modified, returns only after all summaries were re-computed
+func (srv *AggregationService) RegenerateSummaries(user *models.User, from, to time.Time) error {
+	userIds := datastructure.NewSet(user.ID)
+	if err := srv.lockUsers(userIds); err != nil {
+		return err
+	}
+	defer srv.unlockUsers(userIds)
+
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	to = time.Date(to.Year(), to.Month(), to.Day()+aggregateIntervalDays, 0, 0, 0, 0, to.Location())
+
+	logbuch.Info("regenerating summaries for user '%s' between %v and %v", user.ID, from, to)
+
+	if err := srv.summaryService.DeleteByUserWithin(user.ID, from, to); err != nil {
+		config.Log().Error("failed to clear summaries for user '%s' - %v", user.ID, err)
+		return err
+	}
+
+	// today's summary does not exist yet and will be generated by the regular aggregation run, but a partial one might
+	if to.After(getStartOfToday()) {
+		if err := srv.invalidatePartial(user.ID); err != nil {
+			config.Log().Error("failed to clear partial summary for user '%s' - %v", user.ID, err)
+			return err
+		}
+	}
+
+	end := getStartOfToday().Add(-1 * time.Second)
+	var wg sync.WaitGroup
+	for cur := from; cur.Before(to) && cur.Before(end); cur = cur.AddDate(0, 0, aggregateIntervalDays) {
+		job := AggregationJob{user.ID, cur, cur.AddDate(0, 0, aggregateIntervalDays)}
+		wg.Add(1)
+		if err := srv.queueWorkers.Dispatch(func() {
+			defer wg.Done()
+			srv.process(job)
+		}); err != nil {
+			wg.Done()
+			config.Log().Error("failed to dispatch summary generation job for user '%s'", job.UserID)
+		}
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// ScheduleRegeneration invalidates the user's summaries within the given time range and re-computes them from heartbeats in the background
+// to be called after changes that affect how heartbeats are summarized, e.g. aliases, project labels or language mappings
+// a zero from or to defaults to the user's first or last heartbeat respectively
+func (srv *AggregationService) ScheduleRegeneration(user *models.User, from, to time.Time) error {
+	aggregationLock.Lock()
+	inProgress := srv.inProgress.Contain(user.ID)
+	aggregationLock.Unlock()
+	if inProgress {
+		return ErrAggregationInProgress
+	}
+
+	if from.IsZero() || to.IsZero() {
+		timeRange, err := srv.heartbeatService.GetTimeRangeByUserAndProjects(user, nil)
+		if err != nil {
+			return err
+		}
+		if timeRange.IsEmpty() {
+			return nil
+		}
+		if from.IsZero() {
+			from = timeRange.First.T().In(user.TZ())
+		}
+		if to.IsZero() {
+			to = timeRange.Last.T().In(user.TZ())
+		}
+	}
+
+	u := *user
+	return srv.queueDefault.Dispatch(func() {
+		if err := srv.RegenerateSummaries(&u, from, to); err != nil {
+			config.Log().Error("failed to regenerate summaries for user '%s' - %v", u.ID, err)
+		}
+	})
+}
+
+// ScheduleBackfill re-runs summary aggregation for the requested users and date range in the background, other than the nightly run also re-generating existing summaries
+// users are resolved upfront, so that unknown ones are rejected before anything is started
+func (srv *AggregationService) ScheduleBackfill(req *models.AggregationBackfillRequest) (*models.AggregationBackfillJob, error) {
+	users, err := srv.resolveBackfillUsers(req.Users)
+	if err != nil {
+		return nil, err
+	}
+
+	job := newBackfillJob(req, len(users))
+	if !srv.backfills.Start(backfillJobKey, job) {
+		return nil, ErrBackfillPending
+	}
+
+	if err := srv.queueDefault.Dispatch(func() {
+		srv.backfill(users, req, job)
+	}); err != nil {
+		srv.backfills.Update(job, func(j *models.AggregationBackfillJob) {
+			j.Status = models.AggregationBackfillStatusFailed
+		})
+		return nil, err
+	}
+
+	snapshot, _ := srv.backfills.Get(backfillJobKey)
+	return snapshot, nil
+}
+
+// Backfill is like ScheduleBackfill, but returns only after all requested summaries were re-generated, e.g. for use from the command line
+func (srv *AggregationService) Backfill(req *models.AggregationBackfillRequest) (*models.AggregationBackfillJob, error) {
+	users, err := srv.resolveBackfillUsers(req.Users)
+	if err != nil {
+		return nil, err
+	}
+
+	job := newBackfillJob(req, len(users))
+	if !srv.backfills.Start(backfillJobKey, job) {
+		return nil, ErrBackfillPending
+	}
+	srv.backfill(users, req, job)
+
+	snapshot, _ := srv.backfills.Get(backfillJobKey)
+	return snapshot, nil
+}
+
+// GetBackfillJob returns a snapshot of the current or latest backfill
+func (srv *AggregationService) GetBackfillJob() *models.AggregationBackfillJob {
+	if job, ok := srv.backfills.Get(backfillJobKey); ok {
+		return job
+	}
+	return &models.AggregationBackfillJob{Status: models.AggregationBackfillStatusNone, Failed: []string{}}
+}
+
+// backfill re-generates the users' summaries one user after another, each with the request's dates interpreted in the user's own time zone
+// users whose summaries are being aggregated concurrently are skipped and reported as failed
+func (srv *AggregationService) backfill(users []*models.User, req *models.AggregationBackfillRequest, job *models.AggregationBackfillJob) {
+	logbuch.Info("backfilling summaries of %d users between %s and %s", len(users), req.From, req.To)
+
+	var failed int
+	for i, u := range users {
+		if config.IsShuttingDown() {
+			config.Log().Warn("backfill interrupted by shutdown after %d of %d users", i, len(users))
+			srv.backfills.Update(job, func(j *models.AggregationBackfillJob) {
+				j.Status = models.AggregationBackfillStatusFailed
+			})
+			return
+		}
+
+		from, to, _ := req.Range(u.TZ())
+		err := srv.RegenerateSummaries(u, from, to)
+		if err != nil {
+			config.Log().Error("failed to backfill summaries of user '%s' - %v", u.ID, err)
+			failed++
+		}
+
+		srv.backfills.Update(job, func(j *models.AggregationBackfillJob) {
+			j.Processed = i + 1
+			j.Progress = float64(i+1) / float64(len(users))
+			if err != nil {
+				j.Failed = append(j.Failed, u.ID)
+			}
+		})
+		logbuch.Info("backfilled summaries of user '%s' (%d / %d)", u.ID, i+1, len(users))
+	}
+
+	srv.backfills.Update(job, func(j *models.AggregationBackfillJob) {
+		j.Status = models.AggregationBackfillStatusDone
+		j.Progress = 1
+	})
+	logbuch.Info("finished backfilling summaries of %d users (%d failed)", len(users), failed)
+}
+
+func (srv *AggregationService) resolveBackfillUsers(userIds []string) ([]*models.User, error) {
+	if len(userIds) == 0 {
+		return srv.userService.GetAll()
+	}
+
+	users, err := srv.userService.GetMany(userIds)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) != len(datastructure.NewSet(userIds...)) {
+		return nil, ErrBackfillUnknownUsers
+	}
+	return users, nil
+}
+
+func newBackfillJob(req *models.AggregationBackfillRequest, numUsers int) *models.AggregationBackfillJob {
+	return &models.AggregationBackfillJob{
+		Status:    models.AggregationBackfillStatusPending,
+		From:      req.From,
+		To:        req.To,
+		Users:     numUsers,
+		Failed:    []string{},
+		CreatedAt: time.Now(),
+	}
+}
+
 func (srv *AggregationService) process(job AggregationJob) {
-	if summary, err := srv.summaryService.Summarize(job.From, job.To, &models.User{ID: job.UserID}, nil); err != nil {
+	// summarizing depends on the user's settings, e.g. their heartbeats timeout
+	user, err := srv.userService.GetUserById(job.UserID)
+	if err != nil {
+		config.Log().Error("failed to fetch user '%s' for summary generation - %v", job.UserID, err)
+		return
+	}
+
+	if summary, err := srv.summaryService.Summarize(job.From, job.To, user, nil); err != nil {
 		config.Log().Error("failed to generate summary (%v, %v, %s) - %v", job.From, job.To, job.UserID, err)
 	} else {
 		logbuch.Info("successfully generated summary (%v, %v, %s)", job.From, job.To, job.UserID)
@@ -134,6 +435,67 @@ func (srv *AggregationService) process(job AggregationJob) {
 	}
 }
 
+func (srv *AggregationService) processPartial(userId string, from, to time.Time) {
+	// users with a full aggregation in progress are picked up again by the next run
+	aggregationLock.Lock()
+	inProgress := srv.inProgress.Contain(userId)
+	aggregationLock.Unlock()
+	if inProgress {
+		srv.markActive(userId)
+		return
+	}
+
+	user, err := srv.userService.GetUserById(userId)
+	if err != nil {
+		config.Log().Error("failed to fetch user '%s' for partial summary generation - %v", userId, err)
+		return
+	}
+
+	started := time.Now()
+	summary, err := srv.summaryService.Summarize(from, to, user, nil)
+	if err != nil {
+		config.Log().Error("failed to generate partial summary (%v, %v, %s) - %v", from, to, userId, err)
+		return
+	}
+
+	// the summary must cover exactly up to the time it was generated at, so that heartbeats after that aren't missed or counted twice when retrieving it
+	summary.FromTime = models.CustomTime(from)
+	summary.ToTime = models.CustomTime(to)
+	summary.Partial = true
+
+	srv.invalidatedLock.Lock()
+	defer srv.invalidatedLock.Unlock()
+
+	// heartbeats were edited or deleted while summarizing, so the summary might still include them, it is re-generated by the next run instead
+	if invalidated, ok := srv.invalidated[userId]; ok && !invalidated.Before(started) {
+		srv.markActive(userId)
+		return
+	}
+
+	if err := srv.summaryService.ReplacePartial(summary); err != nil {
+		config.Log().Error("failed to save partial summary (%v, %v, %s) - %v", from, to, userId, err)
+	}
+}
+
+// invalidatePartial drops the user's partial summary after their heartbeats of the current day were edited or deleted and has it re-generated by the next incremental aggregation run
+func (srv *AggregationService) invalidatePartial(userId string) error {
+	srv.invalidatedLock.Lock()
+	defer srv.invalidatedLock.Unlock()
+
+	srv.invalidated[userId] = time.Now()
+	if err := srv.summaryService.DeletePartialByUser(userId); err != nil {
+		return err
+	}
+	srv.markActive(userId)
+	return nil
+}
+
+func (srv *AggregationService) markActive(userId string) {
+	srv.activeUsersLock.Lock()
+	srv.activeUsers.Add(userId)
+	srv.activeUsersLock.Unlock()
+}
+
 func generateUserJobs(userId string, from time.Time, jobs chan<- *AggregationJob) {
 	var to time.Time
 
@@ -167,7 +529,7 @@ func (srv *AggregationService) lockUsers(userIds datastructure.Set[string]) erro
 	defer aggregationLock.Unlock()
 	for uid := range userIds {
 		if srv.inProgress.Contain(uid) {
-			return errors.New("aggregation already in progress for at least of the request users")
+			return ErrAggregationInProgress
 		}
 	}
 	srv.inProgress = srv.inProgress.Union(userIds)
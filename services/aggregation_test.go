@@ -0,0 +1,135 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type AggregationServiceTestSuite struct {
+	suite.Suite
+	TestUsers        []*models.User
+	UserService      *mocks.UserServiceMock
+	SummaryService   *mocks.SummaryServiceMock
+	HeartbeatService *mocks.HeartbeatServiceMock
+}
+
+func (suite *AggregationServiceTestSuite) SetupSuite() {
+	suite.TestUsers = []*models.User{{ID: "user1"}, {ID: "user2"}}
+	config.Set(config.Empty())
+}
+
+func (suite *AggregationServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+}
+
+func TestAggregationServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(AggregationServiceTestSuite))
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_Backfill_AllUsers() {
+	sut := NewAggregationService(suite.UserService, suite.SummaryService, suite.HeartbeatService, nil)
+
+	suite.UserService.On("GetAll").Return(suite.TestUsers, nil)
+	suite.UserService.On("GetUserById", "user1").Return(suite.TestUsers[0], nil)
+	suite.UserService.On("GetUserById", "user2").Return(suite.TestUsers[1], nil)
+	suite.SummaryService.On("DeleteByUserWithin", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.SummaryService.On("Summarize", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&models.Summary{}, nil)
+	suite.SummaryService.On("Insert", mock.Anything).Return(nil)
+
+	job, err := sut.Backfill(&models.AggregationBackfillRequest{From: "2023-01-01", To: "2023-01-02"})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), models.AggregationBackfillStatusDone, job.Status)
+	assert.Equal(suite.T(), 2, job.Processed)
+	assert.Empty(suite.T(), job.Failed)
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "DeleteByUserWithin", 2)
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Insert", 4) // two days for each of both users
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_Backfill_UnknownUsers() {
+	sut := NewAggregationService(suite.UserService, suite.SummaryService, suite.HeartbeatService, nil)
+
+	suite.UserService.On("GetMany", []string{"user1", "unknown"}).Return(suite.TestUsers[:1], nil)
+
+	_, err := sut.Backfill(&models.AggregationBackfillRequest{From: "2023-01-01", To: "2023-01-02", Users: []string{"user1", "unknown"}})
+
+	assert.ErrorIs(suite.T(), err, ErrBackfillUnknownUsers)
+	suite.SummaryService.AssertNotCalled(suite.T(), "DeleteByUserWithin", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_GetBackfillJob_None() {
+	sut := NewAggregationService(suite.UserService, suite.SummaryService, suite.HeartbeatService, nil)
+
+	assert.Equal(suite.T(), models.AggregationBackfillStatusNone, sut.GetBackfillJob().Status)
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_ProcessPartial() {
+	sut := NewAggregationService(suite.UserService, suite.SummaryService, suite.HeartbeatService, nil)
+
+	from, to := time.Date(2023, 1, 1, 0, 0, 0, 0, time.Local), time.Date(2023, 1, 1, 13, 37, 0, 0, time.Local)
+
+	suite.UserService.On("GetUserById", "user1").Return(suite.TestUsers[0], nil)
+	suite.SummaryService.On("Summarize", from, to, suite.TestUsers[0], mock.Anything).Return(&models.Summary{
+		UserID:   "user1",
+		FromTime: models.CustomTime(from.Add(8 * time.Hour)),
+		ToTime:   models.CustomTime(to.Add(-2 * time.Minute)),
+	}, nil)
+	suite.SummaryService.On("ReplacePartial", mock.Anything).Return(nil)
+
+	sut.processPartial("user1", from, to)
+
+	summary := suite.SummaryService.Calls[1].Arguments.Get(0).(*models.Summary)
+	assert.True(suite.T(), summary.Partial)
+	assert.Equal(suite.T(), from, summary.FromTime.T())
+	assert.Equal(suite.T(), to, summary.ToTime.T())
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_ProcessPartial_DiscardsInvalidated() {
+	sut := NewAggregationService(suite.UserService, suite.SummaryService, suite.HeartbeatService, nil)
+
+	from, to := time.Date(2023, 1, 1, 0, 0, 0, 0, time.Local), time.Date(2023, 1, 1, 13, 37, 0, 0, time.Local)
+
+	// heartbeats are deleted while the partial summary is being computed
+	suite.UserService.On("GetUserById", "user1").Return(suite.TestUsers[0], nil)
+	suite.SummaryService.On("DeletePartialByUser", "user1").Return(nil)
+	suite.SummaryService.On("Summarize", from, to, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		assert.Nil(suite.T(), sut.invalidatePartial("user1"))
+	}).Return(&models.Summary{UserID: "user1"}, nil)
+
+	sut.processPartial("user1", from, to)
+
+	suite.SummaryService.AssertNotCalled(suite.T(), "ReplacePartial", mock.Anything)
+	assert.True(suite.T(), sut.activeUsers.Contain("user1"))
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_RegenerateSummaries_InvalidatesPartial() {
+	sut := NewAggregationService(suite.UserService, suite.SummaryService, suite.HeartbeatService, nil)
+
+	suite.SummaryService.On("DeleteByUserWithin", "user1", mock.Anything, mock.Anything).Return(nil)
+	suite.SummaryService.On("DeletePartialByUser", "user1").Return(nil)
+
+	assert.Nil(suite.T(), sut.RegenerateSummaries(suite.TestUsers[0], time.Now(), time.Now()))
+
+	suite.SummaryService.AssertCalled(suite.T(), "DeletePartialByUser", "user1")
+	assert.True(suite.T(), sut.activeUsers.Contain("user1"))
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_AggregateIncrementally_DeletesOutdatedPartialsOnce() {
+	sut := NewAggregationService(suite.UserService, suite.SummaryService, suite.HeartbeatService, nil)
+
+	suite.SummaryService.On("DeletePartialBefore", mock.Anything).Return(nil)
+
+	sut.AggregateIncrementally()
+	sut.AggregateIncrementally()
+
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "DeletePartialBefore", 1)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
+	"github.com/leandro-lugaresi/hub"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/repositories"
@@ -12,12 +13,14 @@ import (
 
 type AliasService struct {
 	config     *config.Config
+	eventBus   *hub.Hub
 	repository repositories.IAliasRepository
 }
 
 func NewAliasService(aliasRepo repositories.IAliasRepository) *AliasService {
 	return &AliasService{
 		config:     config.Get(),
+		eventBus:   config.EventBus(),
 		repository: aliasRepo,
 	}
 }
@@ -95,6 +98,7 @@ func (srv *AliasService) Create(alias *models.Alias) (*models.Alias, error) {
 	srv.updateCache(alias, false)
 	// reload entire cache (async, though)
 	go srv.MayInitializeUser(alias.UserID)
+	srv.notifyUpdate(alias.UserID)
 
 	return result, nil
 }
@@ -108,6 +112,7 @@ func (srv *AliasService) Delete(alias *models.Alias) error {
 	// manually update cache
 	if err == nil {
 		srv.updateCache(alias, false)
+		srv.notifyUpdate(alias.UserID)
 	}
 	// reload entire cache (async, though)
 	go srv.MayInitializeUser(alias.UserID)
@@ -138,6 +143,9 @@ func (srv *AliasService) DeleteMulti(aliases []*models.Alias) error {
 	// reload entire cache (async, though)
 	for k := range affectedUsers {
 		go srv.MayInitializeUser(k)
+		if err == nil {
+			srv.notifyUpdate(k)
+		}
 	}
 
 	return err
@@ -179,3 +187,11 @@ func (srv *AliasService) getFiltered(userId string, check func(alias *models.Ali
 		return nil, errors.New(fmt.Sprintf("no user aliases loaded for user %s", userId))
 	}
 }
+
+// notifyUpdate lets other services, e.g. summaries, know the user's aliases changed
+func (srv *AliasService) notifyUpdate(userId string) {
+	srv.eventBus.Publish(hub.Message{
+		Name:   config.EventAliasUpdate,
+		Fields: map[string]interface{}{config.FieldUserId: userId},
+	})
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+type AnnotationService struct {
+	config     *config.Config
+	repository repositories.IAnnotationRepository
+}
+
+func NewAnnotationService(annotationRepository repositories.IAnnotationRepository) *AnnotationService {
+	return &AnnotationService{
+		config:     config.Get(),
+		repository: annotationRepository,
+	}
+}
+
+func (srv *AnnotationService) GetById(id uint) (*models.Annotation, error) {
+	return srv.repository.GetById(id)
+}
+
+// GetByUserWithin returns all of the user's annotations on days touched by the given interval, as seen from the user's time zone
+func (srv *AnnotationService) GetByUserWithin(user *models.User, from, to time.Time) ([]*models.Annotation, error) {
+	fromDay, toDay := models.AnnotationDayRange(from, to, user.TZ())
+	return srv.repository.GetByUserWithin(user.ID, fromDay, toDay)
+}
+
+func (srv *AnnotationService) Create(annotation *models.Annotation) (*models.Annotation, error) {
+	return srv.repository.Insert(annotation)
+}
+
+func (srv *AnnotationService) Delete(annotation *models.Annotation) error {
+	if annotation.UserID == "" {
+		return errors.New("no user id specified")
+	}
+	return srv.repository.Delete(annotation.ID)
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+const archiveTempSuffix = ".tmp"
+
+// archiveStorage is where archive files are put, either a local directory or an s3 bucket
+type archiveStorage interface {
+	Put(name string, data []byte) error
+	Get(name string) ([]byte, error) // returns os.ErrNotExist if there is no such file
+	List(prefix string) ([]string, error)
+	Delete(name string) error
+}
+
+// ArchiveService moves heartbeats older than a configurable number of months out of the database into gzipped json lines files, one per user and month
+// only months fully covered by the user's summaries are archived, so statistics remain unchanged, while raw heartbeats can be restored on demand
+type ArchiveService struct {
+	config        *config.Config
+	eventBus      *hub.Hub
+	userSrvc      IUserService
+	heartbeatSrvc IHeartbeatService
+	summarySrvc   ISummaryService
+	storage       archiveStorage
+	queueWorkers  *artifex.Dispatcher
+	crons         *cronJobs
+}
+
+func NewArchiveService(userService IUserService, heartbeatService IHeartbeatService, summaryService ISummaryService, jobLockService IJobLockService) *ArchiveService {
+	cfg := config.Get()
+
+	srv := &ArchiveService{
+		config:        cfg,
+		eventBus:      config.EventBus(),
+		userSrvc:      userService,
+		heartbeatSrvc: heartbeatService,
+		summarySrvc:   summaryService,
+		queueWorkers:  config.GetQueue(config.QueueHousekeeping),
+		crons:         newCronJobs("archive", config.GetDefaultQueue(), jobLockService),
+	}
+
+	if cfg.App.Archive.UseS3() {
+		client, err := utils.NewS3Client(cfg.App.Archive.S3.Options(), nil)
+		if err != nil {
+			logbuch.Fatal("failed to set up s3 archive storage - %v", err)
+		}
+		srv.storage = &s3ArchiveStorage{client: client}
+	} else {
+		srv.storage = &localArchiveStorage{dir: cfg.App.Archive.Dir}
+	}
+
+	onUserDelete := srv.eventBus.Subscribe(0, config.EventUserDelete)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			user := m.Fields[config.FieldPayload].(*models.User)
+			if err := srv.DeleteByUser(user); err != nil {
+				config.Log().Error("failed to delete archived heartbeats of user '%s' - %v", user.ID, err)
+			}
+		}
+	}(&onUserDelete)
+
+	return srv
+}
+
+func (srv *ArchiveService) Schedule() {
+	if !srv.config.App.Archive.IsEnabled() {
+		return
+	}
+
+	logbuch.Info("scheduling heartbeat archival")
+
+	if err := srv.crons.Schedule(srv.runArchive, srv.config.App.Archive.Time); err != nil {
+		config.Log().Error("failed to schedule heartbeat archival, %v", err)
+	}
+}
+
+// ArchiveUserBefore archives all of the user's heartbeats between the beginning of the month of from and before (expected to be the beginning of a month as well) and returns their number
+func (srv *ArchiveService) ArchiveUserBefore(user *models.User, from, before time.Time) (int, error) {
+	var count int
+
+	for _, month := range utils.SplitRangeByMonths(datetime.BeginOfMonth(from), before) {
+		heartbeats, err := srv.heartbeatSrvc.GetAllWithin(month[0], month[1], user)
+		if err != nil {
+			return count, err
+		}
+		if len(heartbeats) == 0 {
+			continue
+		}
+
+		// heartbeats might have been added to an already archived month later on, e.g. by an import
+		name := models.ArchiveFileName(user.ID, month[0])
+		archived, err := srv.read(name)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return count, err
+		}
+
+		data, err := encodeArchive(archived, heartbeats)
+		if err != nil {
+			return count, err
+		}
+		if err := srv.storage.Put(name, data); err != nil {
+			return count, err
+		}
+
+		if _, err := srv.heartbeatSrvc.DeleteByUserWithinByFilters(user, month[0], month[1], &models.Filters{}, ""); err != nil {
+			return count, err
+		}
+
+		logbuch.Info("archived %d heartbeats of user '%s' to %s", len(heartbeats), user.ID, name)
+		count += len(heartbeats)
+	}
+
+	return count, nil
+}
+
+// Restore inserts the user's archived heartbeats from all months overlapping with the given interval back into the database and returns their number
+// archive files are kept, so restored heartbeats will be archived again by the next run, unless after_months was increased in the meantime
+func (srv *ArchiveService) Restore(user *models.User, from, to time.Time) (int, error) {
+	names, err := srv.storage.List(user.ID + "/")
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, name := range names {
+		month, err := models.ParseArchiveFileName(name, time.Local)
+		if err != nil {
+			continue
+		}
+		if !month.Before(to) || !month.AddDate(0, 1, 0).After(from) {
+			continue
+		}
+
+		archived, err := srv.read(name)
+		if err != nil {
+			return count, err
+		}
+
+		heartbeats := make([]*models.Heartbeat, 0, len(archived))
+		for _, a := range archived {
+			heartbeats = append(heartbeats, a.Restore(user))
+		}
+
+		batchSize := srv.config.App.ImportBatchSize
+		for i := 0; i < len(heartbeats); i += batchSize {
+			end := i + batchSize
+			if end > len(heartbeats) {
+				end = len(heartbeats)
+			}
+			if err := srv.heartbeatSrvc.InsertBatch(heartbeats[i:end]); err != nil {
+				return count, err
+			}
+		}
+
+		logbuch.Info("restored %d heartbeats of user '%s' from %s", len(heartbeats), user.ID, name)
+		count += len(heartbeats)
+	}
+
+	return count, nil
+}
+
+func (srv *ArchiveService) DeleteByUser(user *models.User) error {
+	names, err := srv.storage.List(user.ID + "/")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := srv.storage.Delete(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (srv *ArchiveService) runArchive() {
+	before := datetime.BeginOfMonth(time.Now()).AddDate(0, -srv.config.App.Archive.AfterMonths, 0)
+
+	firstHeartbeats, err := srv.heartbeatSrvc.GetFirstByUsers()
+	if err != nil {
+		config.Log().Error("failed to get first heartbeats for archival, %v", err)
+		return
+	}
+
+	latestSummaries, err := srv.summarySrvc.GetLatestByUser()
+	if err != nil {
+		config.Log().Error("failed to get latest summaries for archival, %v", err)
+		return
+	}
+	summarizedUntil := make(map[string]time.Time, len(latestSummaries))
+	for _, s := range latestSummaries {
+		summarizedUntil[s.User] = s.Time.T()
+	}
+
+	for _, first := range firstHeartbeats {
+		// never archive heartbeats not yet covered by summaries, as they'd be missing from statistics otherwise
+		until := before
+		if latest, ok := summarizedUntil[first.User]; !ok {
+			continue
+		} else if latest.Before(until) {
+			until = datetime.BeginOfMonth(latest)
+		}
+		if !first.Time.T().Before(until) {
+			continue
+		}
+
+		user, err := srv.userSrvc.GetUserById(first.User)
+		if err != nil {
+			config.Log().Error("failed to get user '%s' for archival, %v", first.User, err)
+			continue
+		}
+
+		from := first.Time.T()
+		if err := srv.queueWorkers.Dispatch(func() {
+			if _, err := srv.ArchiveUserBefore(user, from, until); err != nil {
+				config.Log().Error("failed to archive heartbeats of user '%s', %v", user.ID, err)
+			}
+		}); err != nil {
+			config.Log().Error("failed to dispatch heartbeat archival for user '%s', %v", user.ID, err)
+		}
+	}
+}
+
+func (srv *ArchiveService) read(name string) ([]*models.ArchivedHeartbeat, error) {
+	data, err := srv.storage.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return decodeArchive(data)
+}
+
+// encodeArchive merges the given heartbeats into the already archived ones (if any) and writes them as gzipped json lines
+func encodeArchive(archived []*models.ArchivedHeartbeat, heartbeats []*models.Heartbeat) ([]byte, error) {
+	hashes := make(map[string]bool, len(archived)+len(heartbeats))
+	for _, a := range archived {
+		hashes[a.Hash] = true
+	}
+	for _, h := range heartbeats {
+		if !hashes[h.Hash] {
+			archived = append(archived, models.NewArchivedHeartbeat(h))
+			hashes[h.Hash] = true
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(zw)
+	for _, a := range archived {
+		if err := encoder.Encode(a); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeArchive(data []byte) ([]*models.ArchivedHeartbeat, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	archived := make([]*models.ArchivedHeartbeat, 0)
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var a models.ArchivedHeartbeat
+		if err := json.Unmarshal(scanner.Bytes(), &a); err != nil {
+			return nil, err
+		}
+		if a.Heartbeat != nil {
+			archived = append(archived, &a)
+		}
+	}
+	return archived, scanner.Err()
+}
+
+type localArchiveStorage struct {
+	dir string
+}
+
+func (s *localArchiveStorage) Put(name string, data []byte) error {
+	file := filepath.Join(s.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+
+	// write to temp file first to never leave a partially written archive behind
+	if err := os.WriteFile(file+archiveTempSuffix, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(file+archiveTempSuffix, file)
+}
+
+func (s *localArchiveStorage) Get(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(name)))
+}
+
+func (s *localArchiveStorage) List(prefix string) ([]string, error) {
+	names := make([]string, 0)
+	dir := path.Dir(prefix)
+	entries, err := os.ReadDir(filepath.Join(s.dir, filepath.FromSlash(dir)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return names, nil
+		}
+		return nil, err
+	}
+	for _, e := range entries {
+		name := path.Join(dir, e.Name())
+		if !e.IsDir() && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, models.ArchiveFileExtension) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (s *localArchiveStorage) Delete(name string) error {
+	if err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(name))); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+type s3ArchiveStorage struct {
+	client *utils.S3Client
+}
+
+func (s *s3ArchiveStorage) Put(name string, data []byte) error {
+	return s.client.PutObject(name, data, "application/gzip")
+}
+
+func (s *s3ArchiveStorage) Get(name string) ([]byte, error) {
+	data, err := s.client.GetObject(name)
+	if errors.Is(err, utils.ErrS3NotFound) {
+		return nil, os.ErrNotExist
+	}
+	return data, err
+}
+
+func (s *s3ArchiveStorage) List(prefix string) ([]string, error) {
+	return s.client.ListObjects(prefix)
+}
+
+func (s *s3ArchiveStorage) Delete(name string) error {
+	return s.client.DeleteObject(name)
+}
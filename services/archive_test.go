@@ -0,0 +1,120 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type ArchiveServiceTestSuite struct {
+	suite.Suite
+	TestUser         *models.User
+	TestHeartbeats   []*models.Heartbeat
+	HeartbeatService *mocks.HeartbeatServiceMock
+	Dir              string
+}
+
+func (suite *ArchiveServiceTestSuite) SetupSuite() {
+	suite.TestUser = &models.User{ID: "user1"}
+	suite.TestHeartbeats = []*models.Heartbeat{
+		{UserID: "user1", Entity: "main.go", Language: "Go", Project: "wakapi", Time: models.CustomTime(time.Date(2024, 1, 3, 10, 0, 0, 0, time.Local))},
+		{UserID: "user1", Entity: "main.go", Language: "Go", Project: "wakapi", Time: models.CustomTime(time.Date(2024, 1, 3, 10, 1, 0, 0, time.Local)), Origin: "wakatime", OriginId: "abc"},
+	}
+	for _, h := range suite.TestHeartbeats {
+		h.Hashed()
+	}
+}
+
+func (suite *ArchiveServiceTestSuite) BeforeTest(suiteName, testName string) {
+	config.Set(config.Empty())
+	config.Get().App.ImportBatchSize = 50
+	suite.Dir = suite.T().TempDir()
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+}
+
+func TestArchiveServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ArchiveServiceTestSuite))
+}
+
+func (suite *ArchiveServiceTestSuite) TestArchiveService_ArchiveAndRestore() {
+	jan, feb, mar := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local), time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+
+	suite.HeartbeatService.On("GetAllWithin", jan, feb, suite.TestUser).Return(suite.TestHeartbeats, nil)
+	suite.HeartbeatService.On("GetAllWithin", feb, mar, suite.TestUser).Return([]*models.Heartbeat{}, nil)
+	suite.HeartbeatService.On("DeleteByUserWithinByFilters", suite.TestUser, jan, feb, mock.Anything, "").Return(2, nil)
+	suite.HeartbeatService.On("InsertBatch", mock.Anything).Return(nil)
+
+	sut := suite.newService()
+
+	count, err := sut.ArchiveUserBefore(suite.TestUser, jan.Add(48*time.Hour), mar)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, count)
+	suite.HeartbeatService.AssertNumberOfCalls(suite.T(), "DeleteByUserWithinByFilters", 1)
+
+	names, _ := sut.storage.List("user1/")
+	assert.Equal(suite.T(), []string{"user1/2024-01.jsonl.gz"}, names)
+
+	// archiving the same heartbeats again must not duplicate them
+	_, err = sut.ArchiveUserBefore(suite.TestUser, jan, feb)
+	assert.Nil(suite.T(), err)
+
+	// restoring months outside the interval is a no-op
+	count, err = sut.Restore(suite.TestUser, feb, mar)
+	assert.Nil(suite.T(), err)
+	assert.Zero(suite.T(), count)
+
+	count, err = sut.Restore(suite.TestUser, time.Time{}, time.Now())
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, count)
+	suite.HeartbeatService.AssertCalled(suite.T(), "InsertBatch", mock.MatchedBy(func(heartbeats []*models.Heartbeat) bool {
+		return len(heartbeats) == 2 &&
+			heartbeats[0].UserID == "user1" &&
+			heartbeats[0].Hash == suite.TestHeartbeats[0].Hash &&
+			heartbeats[1].Origin == "wakatime" &&
+			heartbeats[1].OriginId == "abc" &&
+			heartbeats[1].Time.T().Equal(suite.TestHeartbeats[1].Time.T())
+	}))
+
+	assert.Nil(suite.T(), sut.DeleteByUser(suite.TestUser))
+	names, _ = sut.storage.List("user1/")
+	assert.Empty(suite.T(), names)
+}
+
+func (suite *ArchiveServiceTestSuite) TestArchiveService_RunArchive_OnlySummarized() {
+	config.Get().App.Archive.AfterMonths = 3
+
+	userService := new(mocks.UserServiceMock)
+	summaryService := new(mocks.SummaryServiceMock)
+
+	recent := time.Now().AddDate(0, -1, 0)
+	suite.HeartbeatService.On("GetFirstByUsers").Return([]*models.TimeByUser{
+		{User: "user1", Time: models.CustomTime(time.Now().AddDate(-1, 0, 0))}, // old heartbeats, but not yet summarized
+		{User: "user2", Time: models.CustomTime(recent)},                       // no heartbeats old enough
+	}, nil)
+	summaryService.On("GetLatestByUser").Return([]*models.TimeByUser{
+		{User: "user1", Time: models.CustomTime(time.Now().AddDate(-2, 0, 0))},
+		{User: "user2", Time: models.CustomTime(recent)},
+	}, nil)
+
+	sut := suite.newService()
+	sut.userSrvc = userService
+	sut.summarySrvc = summaryService
+	sut.runArchive()
+
+	userService.AssertNotCalled(suite.T(), "GetUserById", mock.Anything)
+}
+
+func (suite *ArchiveServiceTestSuite) newService() *ArchiveService {
+	// constructed directly to not subscribe to the event bus
+	return &ArchiveService{
+		config:        config.Get(),
+		heartbeatSrvc: suite.HeartbeatService,
+		storage:       &localArchiveStorage{dir: suite.Dir},
+		queueWorkers:  config.GetQueue(config.QueueHousekeeping),
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+)
+
+var ErrBillingCurrencyMismatch = errors.New("all projects of a client must be billed in the same currency")
+
+type BillingService struct {
+	config         *config.Config
+	cache          *cache.Cache
+	repository     repositories.IBillableProjectRepository
+	summaryService ISummaryService
+}
+
+func NewBillingService(billableProjectRepository repositories.IBillableProjectRepository, summaryService ISummaryService) *BillingService {
+	return &BillingService{
+		config:         config.Get(),
+		cache:          cache.New(1*time.Hour, 1*time.Hour),
+		repository:     billableProjectRepository,
+		summaryService: summaryService,
+	}
+}
+
+func (srv *BillingService) GetProjectsByUser(userId string) ([]*models.BillableProject, error) {
+	if projects, found := srv.cache.Get(userId); found {
+		return projects.([]*models.BillableProject), nil
+	}
+
+	projects, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Set(userId, projects, cache.DefaultExpiration)
+	return projects, nil
+}
+
+// SetProject marks a project as billable or updates its client and rate, if it already is
+func (srv *BillingService) SetProject(project *models.BillableProject) (*models.BillableProject, error) {
+	project.Client = strings.TrimSpace(project.Client)
+	project.Currency = strings.ToUpper(project.Currency)
+
+	existing, err := srv.GetProjectsByUser(project.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range existing {
+		if p.Client == project.Client && p.Project != project.Project && p.Currency != project.Currency {
+			return nil, ErrBillingCurrencyMismatch
+		}
+	}
+
+	result, err := srv.repository.Upsert(project)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Delete(project.UserID)
+	return result, nil
+}
+
+func (srv *BillingService) DeleteProject(userId, project string) error {
+	srv.cache.Delete(userId)
+	return srv.repository.DeleteByUserAndProject(userId, project)
+}
+
+// GetReport breaks down the time coded in the user's billable projects within the given interval by client and calendar month (in the user's time zone)
+func (srv *BillingService) GetReport(user *models.User, from, to time.Time) (*models.BillingReport, error) {
+	projects, err := srv.GetProjectsByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := models.NewBillingReport(from, to)
+	if len(projects) == 0 {
+		return report, nil
+	}
+
+	for _, interval := range utils.SplitRangeByMonths(from.In(user.TZ()), to.In(user.TZ())) {
+		summary, err := srv.summaryService.Aliased(interval[0], interval[1], user, srv.summaryService.Retrieve, nil, false)
+		if err != nil {
+			return nil, err
+		}
+		report.AddMonth(interval[0], summary, projects)
+	}
+
+	return report.Sorted(), nil
+}
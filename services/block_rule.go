@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	blockRulesCacheKey          = "block_rules"
+	flushDroppedHeartbeatsEvery = 1 * time.Minute
+)
+
+// BlockRuleService drops incoming heartbeats matching any of the instance-wide block rules and keeps count of them per rule
+type BlockRuleService struct {
+	config     *config.Config
+	cache      *cache.Cache
+	repository repositories.IBlockRuleRepository
+	queue      *artifex.Dispatcher
+	dropped    map[uint]int64 // number of dropped heartbeats per rule, not yet persisted
+	droppedAt  time.Time
+	lock       sync.Mutex
+}
+
+func NewBlockRuleService(blockRuleRepository repositories.IBlockRuleRepository) *BlockRuleService {
+	return &BlockRuleService{
+		config:     config.Get(),
+		cache:      cache.New(1*time.Hour, 1*time.Hour),
+		repository: blockRuleRepository,
+		queue:      config.GetDefaultQueue(),
+		dropped:    map[uint]int64{},
+	}
+}
+
+func (srv *BlockRuleService) Schedule() {
+	logbuch.Info("scheduling dropped heartbeats counting")
+	if _, err := srv.queue.DispatchEvery(srv.flushDropped, flushDroppedHeartbeatsEvery); err != nil {
+		config.Log().Error("failed to schedule dropped heartbeats counting jobs, %v", err)
+	}
+}
+
+func (srv *BlockRuleService) GetAll() ([]*models.BlockRule, error) {
+	if rules, found := srv.cache.Get(blockRulesCacheKey); found {
+		return rules.([]*models.BlockRule), nil
+	}
+
+	rules, err := srv.repository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		if err := r.Compile(); err != nil {
+			logbuch.Warn("failed to compile block rule %d - %v", r.ID, err)
+		}
+	}
+	srv.cache.SetDefault(blockRulesCacheKey, rules)
+	return rules, nil
+}
+
+func (srv *BlockRuleService) GetById(id uint) (*models.BlockRule, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *BlockRuleService) Create(rule *models.BlockRule) (*models.BlockRule, error) {
+	result, err := srv.repository.Insert(rule)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Delete(blockRulesCacheKey)
+	return result, nil
+}
+
+func (srv *BlockRuleService) Delete(rule *models.BlockRule) error {
+	if rule.ID == 0 {
+		return errors.New("no rule id specified")
+	}
+	err := srv.repository.Delete(rule.ID)
+	srv.cache.Delete(blockRulesCacheKey)
+	return err
+}
+
+// Filter returns all heartbeats not matching any block rule
+func (srv *BlockRuleService) Filter(heartbeats []*models.Heartbeat) []*models.Heartbeat {
+	rules, err := srv.GetAll()
+	if err != nil {
+		config.Log().Error("failed to fetch block rules, not filtering heartbeats - %v", err)
+		return heartbeats
+	}
+	if len(rules) == 0 {
+		return heartbeats
+	}
+
+	filtered := make([]*models.Heartbeat, 0, len(heartbeats))
+	dropped := map[uint]int64{}
+
+outer:
+	for _, hb := range heartbeats {
+		for _, rule := range rules {
+			if rule.Matches(hb) {
+				dropped[rule.ID]++
+				continue outer
+			}
+		}
+		filtered = append(filtered, hb)
+	}
+
+	if len(dropped) > 0 {
+		srv.lock.Lock()
+		for id, n := range dropped {
+			srv.dropped[id] += n
+		}
+		srv.droppedAt = time.Now()
+		srv.lock.Unlock()
+	}
+
+	return filtered
+}
+
+// flushDropped persists the counts of recently dropped heartbeats with their respective rules
+func (srv *BlockRuleService) flushDropped() {
+	srv.lock.Lock()
+	dropped, droppedAt := srv.dropped, srv.droppedAt
+	srv.dropped = map[uint]int64{}
+	srv.lock.Unlock()
+
+	for id, n := range dropped {
+		if err := srv.repository.IncrementDropped(id, n, droppedAt); err != nil {
+			config.Log().Error("failed to update dropped heartbeats count of block rule %d - %v", id, err)
+			continue
+		}
+		logbuch.Info("dropped %d heartbeats due to block rule %d", n, id)
+	}
+	if len(dropped) > 0 {
+		srv.cache.Delete(blockRulesCacheKey)
+	}
+}
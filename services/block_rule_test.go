@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type BlockRuleServiceTestSuite struct {
+	suite.Suite
+	BlockRuleRepository *mocks.BlockRuleRepositoryMock
+}
+
+func (suite *BlockRuleServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+}
+
+func (suite *BlockRuleServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.BlockRuleRepository = new(mocks.BlockRuleRepositoryMock)
+}
+
+func TestBlockRuleServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(BlockRuleServiceTestSuite))
+}
+
+func (suite *BlockRuleServiceTestSuite) TestBlockRuleService_Filter() {
+	sut := NewBlockRuleService(suite.BlockRuleRepository)
+
+	rules := []*models.BlockRule{
+		{ID: 1, Field: models.BlockRuleFieldProject, Pattern: "^confidential-"},
+		{ID: 2, Field: models.BlockRuleFieldLanguage, Pattern: "^Secret$"},
+	}
+	suite.BlockRuleRepository.On("GetAll").Return(rules, nil)
+	suite.BlockRuleRepository.On("IncrementDropped", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	result := sut.Filter([]*models.Heartbeat{
+		{Project: "wakapi", Language: "Go"},
+		{Project: "confidential-merger", Language: "Go"},
+		{Project: "confidential-merger", Language: "Secret"},
+		{Project: "wakapi", Language: "Secret"},
+		{Project: "", Language: "Go"},
+	})
+	assert.Len(suite.T(), result, 2)
+	assert.Equal(suite.T(), "wakapi", result[0].Project)
+	assert.Equal(suite.T(), "", result[1].Project)
+
+	sut.Filter([]*models.Heartbeat{{Project: "confidential-hr"}})
+	sut.flushDropped()
+
+	suite.BlockRuleRepository.AssertNumberOfCalls(suite.T(), "GetAll", 1)
+	suite.BlockRuleRepository.AssertCalled(suite.T(), "IncrementDropped", uint(1), int64(3), mock.Anything)
+	suite.BlockRuleRepository.AssertCalled(suite.T(), "IncrementDropped", uint(2), int64(1), mock.Anything)
+
+	sut.flushDropped()
+	suite.BlockRuleRepository.AssertNumberOfCalls(suite.T(), "IncrementDropped", 2)
+
+	sut.GetAll() // cache invalidated after flushing counts
+	suite.BlockRuleRepository.AssertNumberOfCalls(suite.T(), "GetAll", 2)
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/gorilla/securecookie"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	CaptchaFieldPowChallenge = "captcha_challenge"
+	CaptchaFieldPowSolution  = "captcha_solution"
+
+	captchaPowMaxAge = 30 * time.Minute
+)
+
+// form fields populated by the providers' widgets
+var captchaResponseFields = map[string][]string{
+	config.CaptchaProviderHCaptcha:  {"h-captcha-response", "g-recaptcha-response"},
+	config.CaptchaProviderTurnstile: {"cf-turnstile-response"},
+	config.CaptchaProviderPow:       {CaptchaFieldPowChallenge, CaptchaFieldPowSolution},
+}
+
+var ErrCaptchaFailed = errors.New("captcha verification failed")
+
+type captchaVerifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// CaptchaService verifies captchas solved on signup and password reset, either by asking a third-party provider (hcaptcha, turnstile) or by checking a built-in proof-of-work challenge
+type CaptchaService struct {
+	config     config.CaptchaConfig
+	httpClient *http.Client
+	verifyUrl  string
+	pow        *utils.PowChallenger
+	powSolved  *cache.Cache // challenges already used, to prevent solutions from being replayed
+}
+
+func NewCaptchaService() *CaptchaService {
+	captchaConfig := config.Get().Security.Captcha
+	return &CaptchaService{
+		config:     captchaConfig,
+		httpClient: config.NewOutboundClient(utils.OutboundPriorityHigh, 10*time.Second),
+		verifyUrl:  captchaConfig.VerifyUrl(),
+		pow:        utils.NewPowChallenger(securecookie.GenerateRandomKey(32), captchaConfig.PowDifficulty, captchaPowMaxAge),
+		powSolved:  cache.New(captchaPowMaxAge, captchaPowMaxAge),
+	}
+}
+
+func (srv *CaptchaService) Enabled() bool {
+	return srv.config.Enabled()
+}
+
+// IssueChallenge returns a new proof-of-work challenge or an empty string, if a third-party provider is used
+func (srv *CaptchaService) IssueChallenge() (string, error) {
+	if srv.config.Provider != config.CaptchaProviderPow {
+		return "", nil
+	}
+	return srv.pow.Issue()
+}
+
+// Verify checks the captcha solution included in the given (form) values and removes all captcha-related fields from them afterwards
+func (srv *CaptchaService) Verify(form url.Values, remoteIp string) error {
+	if !srv.Enabled() {
+		return nil
+	}
+
+	defer func() {
+		for _, field := range captchaResponseFields[srv.config.Provider] {
+			form.Del(field)
+		}
+	}()
+
+	if srv.config.Provider == config.CaptchaProviderPow {
+		return srv.verifyPow(form.Get(CaptchaFieldPowChallenge), form.Get(CaptchaFieldPowSolution))
+	}
+	return srv.verifyRemote(form.Get(captchaResponseFields[srv.config.Provider][0]), remoteIp)
+}
+
+func (srv *CaptchaService) verifyPow(challenge, solution string) error {
+	if challenge == "" || solution == "" {
+		return ErrCaptchaFailed
+	}
+	if err := srv.pow.Verify(challenge, solution); err != nil {
+		return ErrCaptchaFailed
+	}
+	if err := srv.powSolved.Add(challenge, true, cache.DefaultExpiration); err != nil {
+		return ErrCaptchaFailed // already used before
+	}
+	return nil
+}
+
+// verifyRemote validates a response token with the provider, both hcaptcha and turnstile implement the same siteverify protocol
+func (srv *CaptchaService) verifyRemote(response, remoteIp string) error {
+	if response == "" {
+		return ErrCaptchaFailed
+	}
+
+	form := url.Values{
+		"secret":   []string{srv.config.SecretKey},
+		"response": []string{response},
+		"sitekey":  []string{srv.config.SiteKey},
+	}
+	if remoteIp = utils.ParseRemoteIp(remoteIp); remoteIp != "" {
+		form.Set("remoteip", remoteIp)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, srv.verifyUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := utils.RaiseForStatus(srv.httpClient.Do(req))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var verifyResponse captchaVerifyResponse
+	if err := json.NewDecoder(res.Body).Decode(&verifyResponse); err != nil {
+		return err
+	}
+	if !verifyResponse.Success {
+		logbuch.Debug("captcha verification failed - %v", verifyResponse.ErrorCodes)
+		return ErrCaptchaFailed
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaptchaService_Verify_Pow(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Security.Captcha = config.CaptchaConfig{Provider: config.CaptchaProviderPow, PowDifficulty: 8}
+	config.Set(cfg)
+
+	sut := NewCaptchaService()
+	challenge, err := sut.IssueChallenge()
+	assert.Nil(t, err)
+
+	var solution string
+	for i := 0; ; i++ {
+		digest := sha256.Sum256([]byte(challenge + strconv.Itoa(i)))
+		if utils.PowLeadingZeroBits(digest[:]) >= 8 {
+			solution = strconv.Itoa(i)
+			break
+		}
+	}
+
+	form := url.Values{CaptchaFieldPowChallenge: {challenge}, CaptchaFieldPowSolution: {solution}, "email": {"john.doe@example.org"}}
+	assert.Nil(t, sut.Verify(form, ""))
+	assert.Equal(t, url.Values{"email": {"john.doe@example.org"}}, form)
+
+	// solutions must not be replayed
+	assert.ErrorIs(t, sut.Verify(url.Values{CaptchaFieldPowChallenge: {challenge}, CaptchaFieldPowSolution: {solution}}, ""), ErrCaptchaFailed)
+	assert.ErrorIs(t, sut.Verify(url.Values{}, ""), ErrCaptchaFailed)
+}
+
+func TestCaptchaService_Verify_Remote(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Security.Captcha = config.CaptchaConfig{Provider: config.CaptchaProviderTurnstile, SiteKey: "site", SecretKey: "secret"}
+	config.Set(cfg)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		assert.Equal(t, "secret", r.PostForm.Get("secret"))
+		assert.Equal(t, "127.0.0.1", r.PostForm.Get("remoteip"))
+		if r.PostForm.Get("response") == "valid-token" {
+			w.Write([]byte(`{"success": true}`))
+		} else {
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+		}
+	}))
+	defer server.Close()
+
+	sut := NewCaptchaService()
+	sut.verifyUrl = server.URL
+
+	challenge, err := sut.IssueChallenge()
+	assert.Nil(t, err)
+	assert.Empty(t, challenge)
+
+	form := url.Values{"cf-turnstile-response": {"valid-token"}}
+	assert.Nil(t, sut.Verify(form, "127.0.0.1:54321"))
+	assert.Empty(t, form)
+	assert.ErrorIs(t, sut.Verify(url.Values{"cf-turnstile-response": {"forged-token"}}, "127.0.0.1"), ErrCaptchaFailed)
+}
+
+func TestCaptchaService_Verify_Disabled(t *testing.T) {
+	config.Set(config.Empty())
+	assert.Nil(t, NewCaptchaService().Verify(url.Values{}, ""))
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+var (
+	ErrClientNotFound = errors.New("client not found")
+	ErrClientExists   = errors.New("a client with this name already exists")
+)
+
+// ClientService manages the customers, under which a user groups their projects
+type ClientService struct {
+	config     *config.Config
+	cache      *cache.Cache
+	eventBus   *hub.Hub
+	repository repositories.IClientRepository
+}
+
+func NewClientService(clientRepository repositories.IClientRepository) *ClientService {
+	return &ClientService{
+		config:     config.Get(),
+		cache:      cache.New(1*time.Hour, 1*time.Hour),
+		eventBus:   config.EventBus(),
+		repository: clientRepository,
+	}
+}
+
+func (srv *ClientService) GetByUser(userId string) ([]*models.Client, error) {
+	if clients, found := srv.cache.Get(userId); found {
+		return clients.([]*models.Client), nil
+	}
+
+	clients, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Set(userId, clients, cache.DefaultExpiration)
+	return clients, nil
+}
+
+func (srv *ClientService) GetByUserAndId(userId string, id uint) (*models.Client, error) {
+	clients, err := srv.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range clients {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, ErrClientNotFound
+}
+
+// GetClientsByProject maps each of the user's projects, which is assigned to a client, to the client's name
+func (srv *ClientService) GetClientsByProject(userId string) (map[string]string, error) {
+	clients, err := srv.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	mapping := make(map[string]string)
+	for _, c := range clients {
+		for _, p := range c.Projects {
+			mapping[p] = c.Name
+		}
+	}
+	return mapping, nil
+}
+
+func (srv *ClientService) Create(client *models.Client) (*models.Client, error) {
+	client.Name = strings.TrimSpace(client.Name)
+	if err := srv.checkNameAvailable(client); err != nil {
+		return nil, err
+	}
+	result, err := srv.repository.Insert(client)
+	if err != nil {
+		return nil, err
+	}
+	srv.notifyUpdate(client.UserID)
+	return result, nil
+}
+
+func (srv *ClientService) Update(client *models.Client) (*models.Client, error) {
+	client.Name = strings.TrimSpace(client.Name)
+	if err := srv.checkNameAvailable(client); err != nil {
+		return nil, err
+	}
+	result, err := srv.repository.Update(client)
+	if err != nil {
+		return nil, err
+	}
+	srv.notifyUpdate(client.UserID)
+	return result, nil
+}
+
+func (srv *ClientService) Delete(client *models.Client) error {
+	if client.UserID == "" {
+		return errors.New("no user id assigned")
+	}
+	if err := srv.repository.Delete(client.ID); err != nil {
+		return err
+	}
+	srv.notifyUpdate(client.UserID)
+	return nil
+}
+
+func (srv *ClientService) checkNameAvailable(client *models.Client) error {
+	clients, err := srv.GetByUser(client.UserID)
+	if err != nil {
+		return err
+	}
+	for _, c := range clients {
+		if c.ID != client.ID && c.Name == client.Name {
+			return ErrClientExists
+		}
+	}
+	return nil
+}
+
+// notifyUpdate invalidates the user's cached clients and lets other services, e.g. summaries, know their projects were regrouped
+func (srv *ClientService) notifyUpdate(userId string) {
+	srv.cache.Delete(userId)
+	srv.eventBus.Publish(hub.Message{
+		Name:   config.EventClientUpdate,
+		Fields: map[string]interface{}{config.FieldPayload: userId, config.FieldUserId: userId},
+	})
+}
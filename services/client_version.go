@@ -0,0 +1,215 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/emvi/logbuch"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+const (
+	fetchLatestCliVersionEvery  = 24 * time.Hour
+	notifyOutdatedClientsEvery  = 24 * time.Hour
+	notifyOutdatedClientsMaxAge = 7 * 24 * time.Hour
+)
+
+// ClientVersionService compares the plugin- and wakatime-cli versions reported by users' clients against the latest known releases
+type ClientVersionService struct {
+	config           *config.Config
+	httpClient       *http.Client
+	userService      IUserService
+	heartbeatService IHeartbeatService
+	keyValueService  IKeyValueService
+	mailService      IMailService
+	notifySrvc       INotificationService
+	queueDefault     *artifex.Dispatcher
+	queueMails       *artifex.Dispatcher
+}
+
+func NewClientVersionService(userService IUserService, heartbeatService IHeartbeatService, keyValueService IKeyValueService, mailService IMailService, notificationService INotificationService) *ClientVersionService {
+	return &ClientVersionService{
+		config:           config.Get(),
+		httpClient:       config.NewOutboundClient(utils.OutboundPriorityNormal, 10*time.Second),
+		userService:      userService,
+		heartbeatService: heartbeatService,
+		keyValueService:  keyValueService,
+		mailService:      mailService,
+		notifySrvc:       notificationService,
+		queueDefault:     config.GetDefaultQueue(),
+		queueMails:       config.GetQueue(config.QueueMails),
+	}
+}
+
+func (srv *ClientVersionService) Schedule() {
+	if srv.config.App.CliReleaseUrl != "" {
+		logbuch.Info("scheduling latest wakatime-cli version fetching")
+		if err := srv.queueDefault.Dispatch(srv.FetchLatestCliVersion); err != nil {
+			config.Log().Error("failed to dispatch wakatime-cli version fetching job, %v", err)
+		}
+		if _, err := srv.queueDefault.DispatchEvery(srv.FetchLatestCliVersion, fetchLatestCliVersionEvery); err != nil {
+			config.Log().Error("failed to schedule wakatime-cli version fetching jobs, %v", err)
+		}
+	}
+
+	if srv.config.App.OutdatedClientMails && config.Get().Mail.Enabled {
+		logbuch.Info("scheduling outdated client notifications")
+		if _, err := srv.queueDefault.DispatchEvery(srv.NotifyOutdatedClients, notifyOutdatedClientsEvery); err != nil {
+			config.Log().Error("failed to schedule outdated client notification jobs, %v", err)
+		}
+	}
+}
+
+// GetLatestVersions returns the latest known version per plugin, including the wakatime-cli
+func (srv *ClientVersionService) GetLatestVersions() map[string]string {
+	versions := make(map[string]string, len(srv.config.App.ClientVersions)+1)
+	for plugin, version := range srv.config.App.ClientVersions {
+		versions[strings.ToLower(plugin)] = version
+	}
+	if kv, err := srv.keyValueService.GetString(config.KeyLatestCliVersion); err == nil && kv.Value != "" {
+		if _, ok := versions[models.UserAgentCliPlugin]; !ok {
+			versions[models.UserAgentCliPlugin] = kv.Value
+		}
+	}
+	return versions
+}
+
+// GetOutdatedByUser returns the severely outdated clients a user sent heartbeats with recently
+func (srv *ClientVersionService) GetOutdatedByUser(user *models.User) ([]*models.OutdatedClient, error) {
+	userAgents, err := srv.heartbeatService.GetUserAgentsByUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	latestVersions := srv.GetLatestVersions()
+	minLastSeen := time.Now().AddDate(0, 0, -srv.config.App.InactiveDays)
+
+	outdated := make(map[string]*models.OutdatedClient)
+	check := func(plugin, version string, ua *models.UserAgentStats) {
+		latest, ok := latestVersions[plugin]
+		if !ok || version == "" || !models.IsVersionOutdated(version, latest, srv.config.App.OutdatedClientMinorLag) {
+			return
+		}
+		client := &models.OutdatedClient{Plugin: plugin, Version: version, LatestVersion: latest, UserAgent: ua.UserAgent, LastSeenAt: ua.Last}
+		if existing, ok := outdated[client.Key()]; !ok || existing.LastSeenAt.T().Before(client.LastSeenAt.T()) {
+			outdated[client.Key()] = client
+		}
+	}
+
+	for _, ua := range userAgents {
+		if ua.Last.T().Before(minLastSeen) {
+			continue
+		}
+		info := models.ParseUserAgentInfo(ua.UserAgent)
+		check(info.Plugin, info.PluginVersion, ua)
+		check(models.UserAgentCliPlugin, info.CliVersion, ua)
+	}
+
+	results := make([]*models.OutdatedClient, 0, len(outdated))
+	for _, client := range outdated {
+		results = append(results, client)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Key() < results[j].Key()
+	})
+	return results, nil
+}
+
+func (srv *ClientVersionService) FetchLatestCliVersion() {
+	req, err := http.NewRequest(http.MethodGet, srv.config.App.CliReleaseUrl, nil)
+	if err != nil {
+		config.Log().Error("failed to create request for latest wakatime-cli release, %v", err)
+		return
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := utils.RaiseForStatus(srv.httpClient.Do(req))
+	if err != nil {
+		config.Log().Warn("failed to fetch latest wakatime-cli release, %v", err)
+		return
+	}
+	defer res.Body.Close()
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&release); err != nil || release.TagName == "" {
+		config.Log().Warn("failed to decode latest wakatime-cli release, %v", err)
+		return
+	}
+
+	if err := srv.keyValueService.PutString(&models.KeyStringValue{
+		Key:   config.KeyLatestCliVersion,
+		Value: strings.TrimPrefix(release.TagName, "v"),
+	}); err != nil {
+		config.Log().Error("failed to persist latest wakatime-cli version, %v", err)
+	}
+}
+
+func (srv *ClientVersionService) NotifyOutdatedClients() {
+	users, err := srv.userService.GetActive(false)
+	if err != nil {
+		config.Log().Error("failed to fetch active users for outdated client notifications, %v", err)
+		return
+	}
+
+	for _, u := range users {
+		if u.Email == "" || !u.IsActive() {
+			continue
+		}
+
+		clients, err := srv.GetOutdatedByUser(u)
+		if err != nil {
+			config.Log().Error("failed to check clients of user '%s' for being outdated, %v", u.ID, err)
+			continue
+		}
+		if len(clients) == 0 {
+			continue
+		}
+
+		// only notify once per set of outdated clients, unless the last notification is older than a week
+		key := fmt.Sprintf("%s_%s", config.KeyOutdatedClientNotification, u.ID)
+		fingerprint := strings.Join(slice.Map[*models.OutdatedClient, string](clients, func(i int, c *models.OutdatedClient) string {
+			return c.Key()
+		}), ",")
+		if kv, err := srv.keyValueService.GetString(key); err == nil {
+			if last, value, ok := strings.Cut(kv.Value, "|"); ok && value == fingerprint {
+				if sendDate, err := time.Parse(time.RFC822Z, last); err == nil && time.Since(sendDate) < notifyOutdatedClientsMaxAge {
+					continue
+				}
+			}
+		}
+
+		srv.sendOutdatedClientsNotificationScheduled(u, clients, key, fingerprint)
+	}
+}
+
+func (srv *ClientVersionService) sendOutdatedClientsNotificationScheduled(user *models.User, clients []*models.OutdatedClient, key, fingerprint string) {
+	u := *user
+	srv.queueMails.Dispatch(func() {
+		logbuch.Info("sending outdated client notification mail to %s (%s)", u.ID, fingerprint)
+
+		text := fmt.Sprintf("Some of your clients are severely outdated (%s), which can cause inaccurate statistics. Please update them to their latest version.", fingerprint)
+		if err := srv.notifySrvc.Notify(&u, "Outdated clients", text, func() error {
+			return srv.mailService.SendOutdatedClientsNotification(&u, clients)
+		}); err != nil {
+			config.Log().Error("failed to send outdated client notification mail to user '%s', %v", u.ID, err)
+			return
+		}
+
+		if err := srv.keyValueService.PutString(&models.KeyStringValue{
+			Key:   key,
+			Value: time.Now().Format(time.RFC822Z) + "|" + fingerprint,
+		}); err != nil {
+			config.Log().Error("failed to update outdated client notification status for user '%s', %v", u.ID, err)
+		}
+	})
+}
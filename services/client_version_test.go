@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClientVersionServiceTestSuite struct {
+	suite.Suite
+	HeartbeatService *mocks.HeartbeatServiceMock
+	KeyValueService  *mocks.KeyValueServiceMock
+}
+
+func (suite *ClientVersionServiceTestSuite) SetupSuite() {
+	cfg := config.Empty()
+	cfg.App.InactiveDays = 7
+	cfg.App.OutdatedClientMinorLag = 10
+	cfg.App.ClientVersions = map[string]string{"VSCode-Wakatime": "24.0.10"}
+	config.Set(cfg)
+}
+
+func (suite *ClientVersionServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.KeyValueService = new(mocks.KeyValueServiceMock)
+}
+
+func TestClientVersionServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ClientVersionServiceTestSuite))
+}
+
+func (suite *ClientVersionServiceTestSuite) TestClientVersionService_GetLatestVersions() {
+	sut := NewClientVersionService(nil, suite.HeartbeatService, suite.KeyValueService, nil, nil)
+
+	suite.KeyValueService.On("GetString", config.KeyLatestCliVersion).Return(&models.KeyStringValue{Key: config.KeyLatestCliVersion, Value: "1.90.0"}, nil)
+
+	assert.Equal(suite.T(), map[string]string{"vscode-wakatime": "24.0.10", "wakatime-cli": "1.90.0"}, sut.GetLatestVersions())
+}
+
+func (suite *ClientVersionServiceTestSuite) TestClientVersionService_GetOutdatedByUser() {
+	sut := NewClientVersionService(nil, suite.HeartbeatService, suite.KeyValueService, nil, nil)
+	user := &models.User{ID: "testuser"}
+	now := time.Now()
+
+	suite.KeyValueService.On("GetString", config.KeyLatestCliVersion).Return(&models.KeyStringValue{Key: config.KeyLatestCliVersion, Value: "1.90.0"}, nil)
+	suite.HeartbeatService.On("GetUserAgentsByUser", user).Return([]*models.UserAgentStats{
+		{UserId: user.ID, UserAgent: "wakatime/v1.73.1 (linux-5.15.0-x86_64) go1.20.3 vscode/1.77.3 vscode-wakatime/24.0.10", Last: models.CustomTime(now.Add(-1 * time.Hour))},
+		{UserId: user.ID, UserAgent: "wakatime/v1.89.0 (linux-5.15.0-x86_64) go1.21.3 vscode/1.80.0 vscode-wakatime/22.1.0", Last: models.CustomTime(now.Add(-2 * time.Hour))},
+		{UserId: user.ID, UserAgent: "wakatime/v1.50.0 (linux-5.15.0-x86_64) go1.19.1 vim-wakatime/9.0.0", Last: models.CustomTime(now.AddDate(0, 0, -30))}, // not used recently
+	}, nil)
+
+	result, err := sut.GetOutdatedByUser(user)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+	assert.Equal(suite.T(), "vscode-wakatime/22.1.0", result[0].Key())
+	assert.Equal(suite.T(), "24.0.10", result[0].LatestVersion)
+	assert.Equal(suite.T(), "wakatime-cli/1.73.1", result[1].Key())
+	assert.Equal(suite.T(), "1.90.0", result[1].LatestVersion)
+}
+
+func (suite *ClientVersionServiceTestSuite) TestClientVersionService_GetOutdatedByUser_NoLatestVersions() {
+	cfg := config.Get()
+	defer func(versions map[string]string) { cfg.App.ClientVersions = versions }(cfg.App.ClientVersions)
+	cfg.App.ClientVersions = nil
+
+	sut := NewClientVersionService(nil, suite.HeartbeatService, suite.KeyValueService, nil, nil)
+	user := &models.User{ID: "testuser"}
+
+	suite.KeyValueService.On("GetString", config.KeyLatestCliVersion).Return((*models.KeyStringValue)(nil), errors.New("not found"))
+	suite.HeartbeatService.On("GetUserAgentsByUser", user).Return([]*models.UserAgentStats{
+		{UserId: user.ID, UserAgent: "wakatime/v1.73.1 (linux-5.15.0-x86_64) go1.20.3 vscode/1.77.3 vscode-wakatime/1.0.0", Last: models.CustomTime(time.Now())},
+	}, nil)
+
+	result, err := sut.GetOutdatedByUser(user)
+
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), result)
+}
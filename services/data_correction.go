@@ -0,0 +1,154 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/emvi/logbuch"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+const maxPendingDataCorrectionsPerUser = 20
+
+var (
+	ErrDataCorrectionProjectNotFound = errors.New("project not found")
+	ErrDataCorrectionLimitExceeded   = errors.New("too many pending corrections")
+	ErrDataCorrectionNotPending      = errors.New("correction was already reviewed")
+	ErrDataCorrectionSelfReview      = errors.New("cannot review own correction")
+)
+
+// DataCorrectionService manages the review queue of data corrections submitted by members of an organization (see app.org_mode)
+// an approved correction moves the member's heartbeats within the given time range from one project to another and re-generates the affected summaries in the background
+type DataCorrectionService struct {
+	config          *config.Config
+	repository      repositories.IDataCorrectionRepository
+	userSrvc        IUserService
+	heartbeatSrvc   IHeartbeatService
+	aggregationSrvc IAggregationService
+	queue           *artifex.Dispatcher
+}
+
+func NewDataCorrectionService(dataCorrectionRepo repositories.IDataCorrectionRepository, userService IUserService, heartbeatService IHeartbeatService, aggregationService IAggregationService) *DataCorrectionService {
+	return &DataCorrectionService{
+		config:          config.Get(),
+		repository:      dataCorrectionRepo,
+		userSrvc:        userService,
+		heartbeatSrvc:   heartbeatService,
+		aggregationSrvc: aggregationService,
+		queue:           config.GetQueue(config.QueueProjects),
+	}
+}
+
+func (srv *DataCorrectionService) GetById(id uint) (*models.DataCorrection, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *DataCorrectionService) GetByUser(user *models.User) ([]*models.DataCorrection, error) {
+	return srv.repository.GetByUser(user.ID)
+}
+
+func (srv *DataCorrectionService) GetPending() ([]*models.DataCorrection, error) {
+	return srv.repository.GetByStatus(models.DataCorrectionStatusPending)
+}
+
+// Create submits a new correction for review after checking that the source project exists and the user didn't exceed their number of pending corrections
+func (srv *DataCorrectionService) Create(correction *models.DataCorrection) (*models.DataCorrection, error) {
+	existing, err := srv.repository.GetByUser(correction.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(slice.Filter(existing, func(i int, c *models.DataCorrection) bool { return c.IsPending() })) >= maxPendingDataCorrectionsPerUser {
+		return nil, ErrDataCorrectionLimitExceeded
+	}
+
+	projects, err := srv.heartbeatSrvc.GetEntitySetByUser(models.SummaryProject, correction.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !slice.Contain(projects, correction.FromProject) {
+		return nil, ErrDataCorrectionProjectNotFound
+	}
+
+	correction.Status = models.DataCorrectionStatusPending
+	return srv.repository.Insert(correction)
+}
+
+// Review records an admin's decision on a pending correction and, if approved, schedules it to be applied
+func (srv *DataCorrectionService) Review(correction *models.DataCorrection, reviewer *models.User, review *models.DataCorrectionReview) (*models.DataCorrection, error) {
+	if !correction.IsPending() {
+		return nil, ErrDataCorrectionNotPending
+	}
+	if correction.UserID == reviewer.ID {
+		return nil, ErrDataCorrectionSelfReview
+	}
+
+	now := models.CustomTime(time.Now())
+	correction.ReviewerID = &reviewer.ID
+	correction.ReviewComment = review.Comment
+	correction.ReviewedAt = &now
+	correction.Status = models.DataCorrectionStatusRejected
+	if review.Decision == models.DataCorrectionDecisionApprove {
+		correction.Status = models.DataCorrectionStatusApproved
+	}
+
+	result, err := srv.repository.Update(correction)
+	if err != nil {
+		return nil, err
+	}
+	logbuch.Info("data correction %d of user '%s' was %s by '%s'", correction.ID, correction.UserID, correction.Status, reviewer.ID)
+
+	if correction.Status == models.DataCorrectionStatusApproved {
+		c := *correction
+		if err := srv.queue.Dispatch(func() {
+			srv.apply(&c)
+		}); err != nil {
+			config.Log().Error("failed to dispatch data correction %d - %v", correction.ID, err)
+			srv.fail(correction)
+		}
+	}
+
+	return result, nil
+}
+
+func (srv *DataCorrectionService) apply(correction *models.DataCorrection) {
+	user, err := srv.userSrvc.GetUserById(correction.UserID)
+	if err != nil {
+		config.Log().Error("failed to get user '%s' for data correction %d - %v", correction.UserID, correction.ID, err)
+		srv.fail(correction)
+		return
+	}
+
+	from, to := correction.FromTime.T(), correction.ToTime.T()
+	n, err := srv.heartbeatSrvc.RenameProjectByUserWithin(user, from, to, correction.FromProject, correction.ToProject)
+	if err != nil {
+		config.Log().Error("failed to apply data correction %d - %v", correction.ID, err)
+		srv.fail(correction)
+		return
+	}
+
+	if err := srv.aggregationSrvc.RegenerateSummaries(user, from, to); err != nil {
+		// heartbeats were rewritten already, so the correction itself is applied, only summaries might be outdated until re-generated otherwise
+		config.Log().Error("failed to re-generate summaries after data correction %d - %v", correction.ID, err)
+	}
+
+	now := models.CustomTime(time.Now())
+	correction.Status = models.DataCorrectionStatusApplied
+	correction.AppliedAt = &now
+	correction.Rewritten = n
+	if _, err := srv.repository.Update(correction); err != nil {
+		config.Log().Error("failed to update data correction %d - %v", correction.ID, err)
+		return
+	}
+	logbuch.Info("applied data correction %d, moved %d heartbeats of user '%s' from '%s' to '%s'", correction.ID, n, user.ID, correction.FromProject, correction.ToProject)
+}
+
+func (srv *DataCorrectionService) fail(correction *models.DataCorrection) {
+	correction.Status = models.DataCorrectionStatusFailed
+	if _, err := srv.repository.Update(correction); err != nil {
+		config.Log().Error("failed to update data correction %d - %v", correction.ID, err)
+	}
+}
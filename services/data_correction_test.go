@@ -0,0 +1,144 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type DataCorrectionServiceTestSuite struct {
+	suite.Suite
+	Member                   *models.User
+	Admin                    *models.User
+	From                     time.Time
+	To                       time.Time
+	DataCorrectionRepository *mocks.DataCorrectionRepositoryMock
+	UserService              *mocks.UserServiceMock
+	HeartbeatService         *mocks.HeartbeatServiceMock
+	AggregationService       *mocks.AggregationServiceMock
+}
+
+func (suite *DataCorrectionServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+	suite.Member = &models.User{ID: "member"}
+	suite.Admin = &models.User{ID: "admin", IsAdmin: true}
+	suite.From = time.Date(2023, 1, 2, 9, 0, 0, 0, time.Local)
+	suite.To = time.Date(2023, 1, 2, 17, 0, 0, 0, time.Local)
+}
+
+func (suite *DataCorrectionServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.DataCorrectionRepository = new(mocks.DataCorrectionRepositoryMock)
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.AggregationService = new(mocks.AggregationServiceMock)
+
+	suite.UserService.On("GetUserById", suite.Member.ID).Return(suite.Member, nil)
+	suite.HeartbeatService.On("GetEntitySetByUser", models.SummaryProject, suite.Member.ID).Return([]string{"wakapi", "anchr"}, nil)
+	suite.DataCorrectionRepository.On("Insert", mock.Anything).Return(&models.DataCorrection{}, nil)
+}
+
+func TestDataCorrectionServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(DataCorrectionServiceTestSuite))
+}
+
+func (suite *DataCorrectionServiceTestSuite) TestDataCorrectionService_Create() {
+	suite.DataCorrectionRepository.On("GetByUser", suite.Member.ID).Return([]*models.DataCorrection{}, nil)
+
+	sut := NewDataCorrectionService(suite.DataCorrectionRepository, suite.UserService, suite.HeartbeatService, suite.AggregationService)
+
+	correction := suite.newCorrection("wakapi")
+	_, err := sut.Create(correction)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), models.DataCorrectionStatusPending, correction.Status)
+	suite.DataCorrectionRepository.AssertCalled(suite.T(), "Insert", correction)
+
+	_, err = sut.Create(suite.newCorrection("unknown"))
+	assert.ErrorIs(suite.T(), err, ErrDataCorrectionProjectNotFound)
+	suite.DataCorrectionRepository.AssertNumberOfCalls(suite.T(), "Insert", 1)
+}
+
+func (suite *DataCorrectionServiceTestSuite) TestDataCorrectionService_Create_LimitExceeded() {
+	pending := make([]*models.DataCorrection, maxPendingDataCorrectionsPerUser)
+	for i := range pending {
+		pending[i] = &models.DataCorrection{Status: models.DataCorrectionStatusPending}
+	}
+	suite.DataCorrectionRepository.On("GetByUser", suite.Member.ID).Return(pending, nil)
+
+	sut := NewDataCorrectionService(suite.DataCorrectionRepository, suite.UserService, suite.HeartbeatService, suite.AggregationService)
+
+	_, err := sut.Create(suite.newCorrection("wakapi"))
+	assert.ErrorIs(suite.T(), err, ErrDataCorrectionLimitExceeded)
+	suite.DataCorrectionRepository.AssertNotCalled(suite.T(), "Insert", mock.Anything)
+}
+
+func (suite *DataCorrectionServiceTestSuite) TestDataCorrectionService_Review_Reject() {
+	suite.DataCorrectionRepository.On("Update", mock.Anything).Return(&models.DataCorrection{}, nil)
+
+	sut := NewDataCorrectionService(suite.DataCorrectionRepository, suite.UserService, suite.HeartbeatService, suite.AggregationService)
+
+	correction := suite.newCorrection("wakapi")
+	correction.Status = models.DataCorrectionStatusPending
+
+	_, err := sut.Review(correction, suite.Member, &models.DataCorrectionReview{Decision: models.DataCorrectionDecisionApprove})
+	assert.ErrorIs(suite.T(), err, ErrDataCorrectionSelfReview)
+
+	_, err = sut.Review(correction, suite.Admin, &models.DataCorrectionReview{Decision: models.DataCorrectionDecisionReject, Comment: "time was spent on wakapi"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), models.DataCorrectionStatusRejected, correction.Status)
+	assert.Equal(suite.T(), suite.Admin.ID, *correction.ReviewerID)
+	assert.Equal(suite.T(), "time was spent on wakapi", correction.ReviewComment)
+	assert.NotNil(suite.T(), correction.ReviewedAt)
+
+	_, err = sut.Review(correction, suite.Admin, &models.DataCorrectionReview{Decision: models.DataCorrectionDecisionApprove})
+	assert.ErrorIs(suite.T(), err, ErrDataCorrectionNotPending)
+	suite.HeartbeatService.AssertNotCalled(suite.T(), "RenameProjectByUserWithin", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *DataCorrectionServiceTestSuite) TestDataCorrectionService_Review_Approve() {
+	suite.HeartbeatService.On("RenameProjectByUserWithin", suite.Member, suite.From, suite.To, "wakapi", "anchr").Return(42, nil)
+	suite.AggregationService.On("RegenerateSummaries", suite.Member, suite.From, suite.To).Return(nil)
+
+	applied := make(chan *models.DataCorrection, 1)
+	suite.DataCorrectionRepository.On("Update", mock.MatchedBy(func(c *models.DataCorrection) bool {
+		return c.Status == models.DataCorrectionStatusApproved
+	})).Return(&models.DataCorrection{}, nil).Once()
+	suite.DataCorrectionRepository.On("Update", mock.Anything).Return(&models.DataCorrection{}, nil).Run(func(args mock.Arguments) {
+		applied <- args.Get(0).(*models.DataCorrection)
+	}).Once()
+
+	sut := NewDataCorrectionService(suite.DataCorrectionRepository, suite.UserService, suite.HeartbeatService, suite.AggregationService)
+
+	correction := suite.newCorrection("wakapi")
+	correction.Status = models.DataCorrectionStatusPending
+
+	_, err := sut.Review(correction, suite.Admin, &models.DataCorrectionReview{Decision: models.DataCorrectionDecisionApprove})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), models.DataCorrectionStatusApproved, correction.Status)
+
+	select {
+	case c := <-applied:
+		assert.Equal(suite.T(), models.DataCorrectionStatusApplied, c.Status)
+		assert.Equal(suite.T(), int64(42), c.Rewritten)
+		assert.NotNil(suite.T(), c.AppliedAt)
+		assert.Equal(suite.T(), suite.Admin.ID, *c.ReviewerID)
+	case <-time.After(1 * time.Second):
+		suite.T().Fatal("correction was not applied")
+	}
+	suite.AggregationService.AssertCalled(suite.T(), "RegenerateSummaries", suite.Member, suite.From, suite.To)
+}
+
+func (suite *DataCorrectionServiceTestSuite) newCorrection(fromProject string) *models.DataCorrection {
+	return &models.DataCorrection{
+		UserID:      suite.Member.ID,
+		FromProject: fromProject,
+		ToProject:   "anchr",
+		FromTime:    models.CustomTime(suite.From),
+		ToTime:      models.CustomTime(suite.To),
+	}
+}
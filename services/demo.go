@@ -0,0 +1,280 @@
+package services
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
+	"github.com/emvi/logbuch"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+const (
+	DemoUsername = "demo"
+	DemoPassword = "demo1234"
+
+	demoOrigin          = "demo"
+	demoHistory         = 365 * 24 * time.Hour
+	demoTopUpEvery      = 1 * time.Hour
+	demoInsertBatchSize = 1000
+)
+
+type demoProject struct {
+	name      string
+	weight    int                 // relative likelihood of a session being spent on this project
+	languages map[string][]string // language -> files
+	branches  []string
+}
+
+var demoProjects = []demoProject{
+	{
+		name:   "wakapi",
+		weight: 6,
+		languages: map[string][]string{
+			"Go":         {"main.go", "services/summary.go", "services/heartbeat.go", "routes/summary.go", "models/summary.go", "config/config.go"},
+			"HTML":       {"views/summary.tpl.html", "views/settings.tpl.html"},
+			"JavaScript": {"static/assets/js/summary.js"},
+			"YAML":       {"config.default.yml"},
+			"Markdown":   {"README.md"},
+		},
+		branches: []string{"master", "feature/demo-mode", "fix/timezones"},
+	},
+	{
+		name:   "portfolio",
+		weight: 3,
+		languages: map[string][]string{
+			"TypeScript": {"src/App.tsx", "src/components/Header.tsx", "src/components/Projects.tsx"},
+			"CSS":        {"src/index.css"},
+			"JSON":       {"package.json"},
+		},
+		branches: []string{"main", "redesign"},
+	},
+	{
+		name:   "ml-experiments",
+		weight: 2,
+		languages: map[string][]string{
+			"Python":  {"train.py", "data/loader.py", "models/transformer.py"},
+			"Jupyter": {"notebooks/exploration.ipynb"},
+		},
+		branches: []string{"main"},
+	},
+	{
+		name:   "dotfiles",
+		weight: 1,
+		languages: map[string][]string{
+			"Bash": {".bashrc", "install.sh"},
+			"Lua":  {".config/nvim/init.lua"},
+		},
+		branches: []string{"main"},
+	},
+}
+
+var demoClients = []struct {
+	editor, os, machine, userAgent string
+}{
+	{"vscode", "Linux", "workstation", "wakatime/v1.73.0 (linux-6.1.0-amd64) go1.21.0 vscode/1.85.1 vscode-wakatime/24.4.0"},
+	{"goland", "Linux", "workstation", "wakatime/v1.73.0 (linux-6.1.0-amd64) go1.21.0 GoLand/2023.3 GoLand-wakatime/14.3.1"},
+	{"neovim", "Mac", "macbook", "wakatime/v1.73.0 (darwin-23.1.0-arm64) go1.21.0 neovim/0.9.4 vim-wakatime/11.1.1"},
+}
+
+// DemoService seeds a demo user with a year of realistic, generated heartbeats, so that a demo instance can showcase all charts without importing real data
+// heartbeats are generated deterministically per day and topped up regularly, so the demo data always reaches up to the present
+type DemoService struct {
+	config          *config.Config
+	userSrvc        IUserService
+	heartbeatSrvc   IHeartbeatService
+	aggregationSrvc IAggregationService
+	queueDefault    *artifex.Dispatcher
+}
+
+func NewDemoService(userService IUserService, heartbeatService IHeartbeatService, aggregationService IAggregationService) *DemoService {
+	return &DemoService{
+		config:          config.Get(),
+		userSrvc:        userService,
+		heartbeatSrvc:   heartbeatService,
+		aggregationSrvc: aggregationService,
+		queueDefault:    config.GetDefaultQueue(),
+	}
+}
+
+func (srv *DemoService) Schedule() {
+	if !srv.config.DemoMode {
+		return
+	}
+
+	logbuch.Info("scheduling demo data generation")
+	if _, err := srv.queueDefault.DispatchEvery(func() {
+		if err := srv.Seed(); err != nil {
+			config.Log().Error("failed to generate demo data, %v", err)
+		}
+	}, demoTopUpEvery); err != nil {
+		config.Log().Error("failed to schedule demo data generation, %v", err)
+	}
+	if err := srv.queueDefault.Dispatch(func() {
+		if err := srv.Seed(); err != nil {
+			config.Log().Error("failed to generate demo data, %v", err)
+		}
+	}); err != nil {
+		config.Log().Error("failed to dispatch demo data generation, %v", err)
+	}
+}
+
+// Seed creates the demo user, unless existing, and generates heartbeats from where the latest ones left off (at most a year ago) up to now
+// summaries are generated right away for the initial year, afterwards by the regular aggregation
+func (srv *DemoService) Seed() error {
+	user, created, err := srv.userSrvc.CreateOrGet(&models.Signup{
+		Username: DemoUsername,
+		Password: DemoPassword,
+		Location: time.Local.String(),
+	}, false)
+	if err != nil {
+		return err
+	}
+	if created {
+		logbuch.Info("created demo user '%s'", user.ID)
+	}
+
+	now := time.Now()
+	from, initial := now.Add(-demoHistory), true
+	if latest, err := srv.heartbeatSrvc.GetLatestByUser(user); err == nil && latest != nil && latest.Time.T().After(from) {
+		from, initial = latest.Time.T(), false
+	}
+
+	count := 0
+	batch := make([]*models.Heartbeat, 0, demoInsertBatchSize)
+	for day := beginOfDay(from, user.TZ()); day.Before(now); day = day.AddDate(0, 0, 1) {
+		for _, hb := range GenerateDemoHeartbeats(user, day) {
+			if t := hb.Time.T(); !t.After(from) || t.After(now) {
+				continue
+			}
+			batch = append(batch, hb)
+			if len(batch) == demoInsertBatchSize {
+				if err := srv.heartbeatSrvc.InsertBatch(batch); err != nil {
+					return err
+				}
+				count, batch = count+len(batch), make([]*models.Heartbeat, 0, demoInsertBatchSize)
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := srv.heartbeatSrvc.InsertBatch(batch); err != nil {
+			return err
+		}
+		count += len(batch)
+	}
+
+	if count == 0 {
+		return nil
+	}
+	logbuch.Info("generated %d demo heartbeats for user '%s'", count, user.ID)
+
+	if !user.HasData {
+		user.HasData = true
+		if _, err := srv.userSrvc.Update(user); err != nil {
+			return err
+		}
+	}
+	if initial {
+		return srv.aggregationSrvc.AggregateSummaries(datastructure.NewSet(user.ID))
+	}
+	return nil
+}
+
+// GenerateDemoHeartbeats generates a plausible day of coding for the given user, i.e. one to three sessions, mostly on weekdays, each spent on a single project
+// the result only depends on the day, so repeated calls yield the same heartbeats, which are then deduplicated by their hashes
+func GenerateDemoHeartbeats(user *models.User, day time.Time) []*models.Heartbeat {
+	day = beginOfDay(day, user.TZ())
+	rnd := rand.New(rand.NewSource(day.Unix()))
+	heartbeats := make([]*models.Heartbeat, 0)
+
+	activeChance := 0.9
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		activeChance = 0.3
+	}
+	if rnd.Float64() > activeChance {
+		return heartbeats
+	}
+
+	numSessions := 1 + rnd.Intn(3)
+	sessionStart := day.Add(time.Duration(8+rnd.Intn(3))*time.Hour + time.Duration(rnd.Intn(60))*time.Minute)
+
+	for i := 0; i < numSessions; i++ {
+		project := pickDemoProject(rnd)
+		client := demoClients[rnd.Intn(len(demoClients))]
+		branch := project.branches[rnd.Intn(len(project.branches))]
+		category := "coding"
+		if rnd.Float64() < 0.15 {
+			category = "debugging"
+		}
+
+		sessionEnd := sessionStart.Add(time.Duration(30+rnd.Intn(150)) * time.Minute)
+		language, file := pickDemoFile(rnd, project)
+
+		for t := sessionStart; t.Before(sessionEnd); t = t.Add(time.Duration(30+rnd.Intn(90)) * time.Second) {
+			// switch files every now and then
+			if rnd.Float64() < 0.1 {
+				language, file = pickDemoFile(rnd, project)
+			}
+
+			heartbeats = append(heartbeats, (&models.Heartbeat{
+				User:            user,
+				UserID:          user.ID,
+				Entity:          fmt.Sprintf("/home/demo/dev/%s/%s", project.name, file),
+				Type:            "file",
+				Category:        category,
+				Project:         project.name,
+				Branch:          branch,
+				Language:        language,
+				IsWrite:         rnd.Float64() < 0.6,
+				Editor:          client.editor,
+				OperatingSystem: client.os,
+				Machine:         client.machine,
+				UserAgent:       client.userAgent,
+				Time:            models.CustomTime(t),
+				Origin:          demoOrigin,
+				SchemaVersion:   models.HeartbeatSchemaVersion,
+			}).Hashed())
+		}
+
+		// take a break before the next session
+		sessionStart = sessionEnd.Add(time.Duration(30+rnd.Intn(120)) * time.Minute)
+	}
+
+	return heartbeats
+}
+
+func pickDemoProject(rnd *rand.Rand) *demoProject {
+	total := 0
+	for _, p := range demoProjects {
+		total += p.weight
+	}
+	n := rnd.Intn(total)
+	for i := range demoProjects {
+		if n -= demoProjects[i].weight; n < 0 {
+			return &demoProjects[i]
+		}
+	}
+	return &demoProjects[0]
+}
+
+func pickDemoFile(rnd *rand.Rand, project *demoProject) (string, string) {
+	// pick from languages in a stable order, as map iteration order is random
+	languages := make([]string, 0, len(project.languages))
+	for language := range project.languages {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	language := languages[rnd.Intn(len(languages))]
+	files := project.languages[language]
+	return language, files[rnd.Intn(len(files))]
+}
+
+func beginOfDay(t time.Time, tz *time.Location) time.Time {
+	t = t.In(tz)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, tz)
+}
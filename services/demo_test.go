@@ -0,0 +1,85 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type DemoServiceTestSuite struct {
+	suite.Suite
+	TestUser           *models.User
+	UserService        *mocks.UserServiceMock
+	HeartbeatService   *mocks.HeartbeatServiceMock
+	AggregationService *mocks.AggregationServiceMock
+}
+
+func (suite *DemoServiceTestSuite) SetupSuite() {
+	suite.TestUser = &models.User{ID: DemoUsername, Location: "UTC", HasData: true}
+	config.Set(config.Empty())
+}
+
+func (suite *DemoServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.AggregationService = new(mocks.AggregationServiceMock)
+}
+
+func TestDemoServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(DemoServiceTestSuite))
+}
+
+func (suite *DemoServiceTestSuite) TestDemoService_GenerateDemoHeartbeats() {
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	var total int
+	for i := 0; i < 7; i++ {
+		current := day.AddDate(0, 0, i)
+		heartbeats := GenerateDemoHeartbeats(suite.TestUser, current)
+		total += len(heartbeats)
+
+		// deterministic per day
+		assert.Equal(suite.T(), heartbeats, GenerateDemoHeartbeats(suite.TestUser, current.Add(13*time.Hour)))
+
+		hashes := make(map[string]bool)
+		for _, hb := range heartbeats {
+			assert.False(suite.T(), hb.Time.T().Before(current))
+			assert.True(suite.T(), hb.Time.T().Before(current.AddDate(0, 0, 1)))
+			assert.NotEmpty(suite.T(), hb.Project)
+			assert.NotEmpty(suite.T(), hb.Language)
+			assert.NotEmpty(suite.T(), hb.Hash)
+			assert.False(suite.T(), hashes[hb.Hash])
+			hashes[hb.Hash] = true
+		}
+	}
+	assert.Greater(suite.T(), total, 0)
+}
+
+func (suite *DemoServiceTestSuite) TestDemoService_Seed_TopUp() {
+	sut := NewDemoService(suite.UserService, suite.HeartbeatService, suite.AggregationService)
+
+	latest := time.Now().AddDate(0, 0, -14)
+	var inserted []*models.Heartbeat
+
+	suite.UserService.On("CreateOrGet", mock.Anything, false).Return(suite.TestUser, false, nil)
+	suite.HeartbeatService.On("GetLatestByUser", suite.TestUser).Return(&models.Heartbeat{Time: models.CustomTime(latest)}, nil)
+	suite.HeartbeatService.On("InsertBatch", mock.Anything).Run(func(args mock.Arguments) {
+		inserted = append(inserted, args.Get(0).([]*models.Heartbeat)...)
+	}).Return(nil)
+
+	assert.Nil(suite.T(), sut.Seed())
+
+	assert.NotEmpty(suite.T(), inserted)
+	for _, hb := range inserted {
+		assert.True(suite.T(), hb.Time.T().After(latest))
+		assert.False(suite.T(), hb.Time.T().After(time.Now()))
+	}
+	suite.AggregationService.AssertNotCalled(suite.T(), "AggregateSummaries", mock.Anything)
+	suite.UserService.AssertNotCalled(suite.T(), "Update", mock.Anything)
+}
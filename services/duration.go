@@ -8,7 +8,8 @@ import (
 	"time"
 )
 
-const HeartbeatDiffThreshold = 2 * time.Minute
+// HeartbeatDiffThreshold is the default keystroke timeout, users may configure their own (see models.User.HeartbeatsTimeout)
+const HeartbeatDiffThreshold = models.DefaultHeartbeatsTimeoutMinutes * time.Minute
 
 type DurationService struct {
 	config           *config.Config
@@ -37,6 +38,8 @@ func (srv *DurationService) Get(from, to time.Time, user *models.User, filters *
 		return nil, err
 	}
 
+	timeout := user.HeartbeatsTimeout()
+
 	// Aggregation
 	// the below logic is approximately equivalent to the SQL query at scripts/aggregate_durations.sql,
 	// but unfortunately we cannot use it, as it features mysql-specific functions (lag(), timediff(), ...)
@@ -67,13 +70,13 @@ func (srv *DurationService) Get(from, to time.Time, user *models.User, filters *
 		sameDay := datetime.BeginOfDay(d1.Time.T()) == datetime.BeginOfDay(latest.Time.T())
 		dur := time.Duration(mathutil.Min(
 			int64(d1.Time.T().Sub(latest.Time.T().Add(latest.Duration))),
-			int64(HeartbeatDiffThreshold),
+			int64(timeout),
 		))
 
 		// skip heartbeats that span across two adjacent summaries (assuming there are no more than 1 summary per day)
 		// this is relevant to prevent the time difference between generating summaries from raw heartbeats and aggregating pre-generated summaries
 		// for the latter case, the very last heartbeat of a day won't be counted, so we don't want to count it here either
-		// another option would be to adapt the Summarize() method to always append up to HeartbeatDiffThreshold seconds to a day's very last duration
+		// another option would be to adapt the Summarize() method to always append up to timeout seconds to a day's very last duration
 		if !sameDay {
 			dur = 0
 		}
@@ -83,7 +86,7 @@ func (srv *DurationService) Get(from, to time.Time, user *models.User, filters *
 		// (a) heartbeats were too far apart each other,
 		// (b) if they are of a different entity or,
 		// (c) if they span across two days
-		if dur >= HeartbeatDiffThreshold || latest.GroupHash != d1.GroupHash || !sameDay {
+		if dur >= timeout || latest.GroupHash != d1.GroupHash || !sameDay {
 			list := mapping[d1.GroupHash]
 			if d0 := list[len(list)-1]; d0 != d1 {
 				mapping[d1.GroupHash] = append(mapping[d1.GroupHash], d1)
@@ -112,7 +115,7 @@ func (srv *DurationService) Get(from, to time.Time, user *models.User, filters *
 	}
 
 	if len(heartbeats) == 1 && len(durations) == 1 {
-		durations[0].Duration = HeartbeatDiffThreshold
+		durations[0].Duration = timeout
 	}
 
 	return durations.Sorted(), nil
@@ -178,6 +178,23 @@ func (suite *DurationServiceTestSuite) TestDurationService_Get() {
 	assert.Equal(suite.T(), 3, durations[2].NumHeartbeats)
 }
 
+func (suite *DurationServiceTestSuite) TestDurationService_Get_CustomTimeout() {
+	sut := NewDurationService(suite.HeartbeatService)
+	user := &models.User{ID: TestUserId, HeartbeatsTimeoutMinutes: 5}
+
+	from, to := suite.TestStartTime, suite.TestStartTime.Add(1*time.Hour)
+	suite.HeartbeatService.On("GetAllWithin", from, to, user).Return(filterHeartbeats(from, to, suite.TestHeartbeats), nil)
+
+	durations, err := sut.Get(from, to, user, nil)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), durations, 2)
+	assert.Equal(suite.T(), 180*time.Second, durations[0].Duration)
+	assert.Equal(suite.T(), 15*time.Second, durations[1].Duration)
+	assert.Equal(suite.T(), 4, durations[0].NumHeartbeats)
+	assert.Equal(suite.T(), 3, durations[1].NumHeartbeats)
+}
+
 func (suite *DurationServiceTestSuite) TestDurationService_Get_Filtered() {
 	sut := NewDurationService(suite.HeartbeatService)
 
@@ -0,0 +1,458 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+)
+
+const (
+	exportHeartbeatsPageSize = 10_000
+	exportCleanupEvery       = 1 * time.Hour
+	exportTempSuffix         = ".tmp"
+	exportDumpTimeout        = 5 * time.Minute
+	exportDumpUrlTtl         = 1 * time.Hour
+	exportMaxErrorLength     = 1024
+)
+
+var (
+	ErrExportPending   = errors.New("an export is already in progress")
+	ErrExportNoDump    = errors.New("no dump available yet")
+	ErrExportNoTargets = errors.New("export targets are disabled")
+)
+
+// ExportService generates data export archives in the background
+// archives are written to the configured export directory, from where they are served until they expire, also across restarts
+// additionally, users may configure an s3 bucket of their own (export target), to which an archive is uploaded every day
+type ExportService struct {
+	config              *config.Config
+	eventBus            *hub.Hub
+	heartbeatSrvc       IHeartbeatService
+	summarySrvc         ISummaryService
+	aliasSrvc           IAliasService
+	projectLabelSrvc    IProjectLabelService
+	languageMappingSrvc ILanguageMappingService
+	machineSrvc         IMachineService
+	queue               *artifex.Dispatcher
+	queueDefault        *artifex.Dispatcher
+	jobs                *userJobs[models.UserExport]
+	targetRepository    repositories.IExportTargetRepository
+	httpClient          *http.Client
+	crons               *cronJobs
+}
+
+func NewExportService(heartbeatService IHeartbeatService, summaryService ISummaryService, aliasService IAliasService, projectLabelService IProjectLabelService, languageMappingService ILanguageMappingService, machineService IMachineService, exportTargetRepo repositories.IExportTargetRepository, jobLockService IJobLockService) *ExportService {
+	// buckets are user-supplied, so they must not be used to reach internal services
+	httpClient := utils.NewPublicOnlyHttpClient(exportDumpTimeout)
+	httpClient.Transport = config.GetOutboundDispatcher().Transport(httpClient.Transport, utils.OutboundPriorityLow)
+
+	srv := &ExportService{
+		config:              config.Get(),
+		eventBus:            config.EventBus(),
+		heartbeatSrvc:       heartbeatService,
+		summarySrvc:         summaryService,
+		aliasSrvc:           aliasService,
+		projectLabelSrvc:    projectLabelService,
+		languageMappingSrvc: languageMappingService,
+		machineSrvc:         machineService,
+		queue:               config.GetQueue(config.QueueExports),
+		queueDefault:        config.GetDefaultQueue(),
+		jobs:                newUserJobs((*models.UserExport).IsPending),
+		targetRepository:    exportTargetRepo,
+		httpClient:          httpClient,
+		crons:               newCronJobs("export_targets", config.GetDefaultQueue(), jobLockService),
+	}
+
+	onUserDelete := srv.eventBus.Subscribe(0, config.EventUserDelete)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.Delete(m.Fields[config.FieldPayload].(*models.User))
+		}
+	}(&onUserDelete)
+
+	return srv
+}
+
+func (srv *ExportService) Schedule() {
+	logbuch.Info("scheduling export archive cleanup")
+
+	if _, err := srv.queueDefault.DispatchEvery(srv.deleteExpired, exportCleanupEvery); err != nil {
+		config.Log().Error("failed to schedule export archive cleanup, %v", err)
+	}
+
+	if srv.config.App.ExportTargetsEnabled {
+		logbuch.Info("scheduling data dumps to export targets")
+		if err := srv.crons.Schedule(srv.dumpAll, srv.config.App.ExportTargetsTime); err != nil {
+			config.Log().Error("failed to schedule data dumps to export targets, %v", err)
+		}
+	}
+}
+
+// Request schedules the generation of a new data export archive for the given user
+func (srv *ExportService) Request(user *models.User) error {
+	job := &models.UserExport{Status: models.ExportStatusPending, CreatedAt: time.Now()}
+	if !srv.jobs.Start(user.ID, job) {
+		return ErrExportPending
+	}
+
+	u := *user
+	if err := srv.queue.Dispatch(func() {
+		path, err := srv.generate(&u)
+		if err != nil {
+			config.Log().Error("failed to generate data export for user '%s' - %v", u.ID, err)
+			srv.jobs.Update(job, func(j *models.UserExport) {
+				j.Status = models.ExportStatusFailed
+			})
+			return
+		}
+		logbuch.Info("generated data export for user '%s'", u.ID)
+		srv.jobs.Update(job, func(j *models.UserExport) {
+			j.Status = models.ExportStatusReady
+			j.Path = path
+		})
+	}); err != nil {
+		srv.jobs.Update(job, func(j *models.UserExport) {
+			j.Status = models.ExportStatusFailed
+		})
+		return err
+	}
+	return nil
+}
+
+// Get returns the state of the user's latest export, including the archive's file path, if ready and not yet expired
+func (srv *ExportService) Get(user *models.User) *models.UserExport {
+	if job, ok := srv.jobs.Get(user.ID); ok && job.Status != models.ExportStatusReady {
+		return job
+	}
+
+	// ready archives are looked up on disk, because job state does not survive restarts
+	path := srv.archivePath(user.ID)
+	info, err := os.Stat(path)
+	if err != nil {
+		return &models.UserExport{Status: models.ExportStatusNone}
+	}
+
+	expiresAt := info.ModTime().Add(srv.config.App.ExportsMaxAge())
+	if time.Now().After(expiresAt) {
+		return &models.UserExport{Status: models.ExportStatusNone}
+	}
+
+	return &models.UserExport{
+		Status:    models.ExportStatusReady,
+		CreatedAt: info.ModTime(),
+		ExpiresAt: expiresAt,
+		Path:      path,
+	}
+}
+
+func (srv *ExportService) Delete(user *models.User) {
+	path := srv.archivePath(user.ID)
+	for _, p := range []string{path, path + exportTempSuffix} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			config.Log().Error("failed to delete data export archive for user '%s' - %v", user.ID, err)
+		}
+	}
+	srv.jobs.Delete(user.ID)
+
+	if srv.targetRepository != nil {
+		if err := srv.targetRepository.DeleteByUser(user.ID); err != nil {
+			config.Log().Error("failed to delete export target for user '%s' - %v", user.ID, err)
+		}
+	}
+}
+
+func (srv *ExportService) GetTarget(user *models.User) (*models.ExportTarget, error) {
+	return srv.targetRepository.GetByUser(user.ID)
+}
+
+// SetTarget validates and stores the user's export target and dumps their data to it right away, so that misconfigurations show up immediately
+func (srv *ExportService) SetTarget(user *models.User, target *models.ExportTarget) (*models.ExportTarget, error) {
+	if !srv.config.App.ExportTargetsEnabled {
+		return nil, ErrExportNoTargets
+	}
+	target.UserID, target.User = user.ID, user
+	if target.RetentionDays < 0 {
+		return nil, errors.New("retention days must not be negative")
+	}
+	if _, err := utils.NewS3Client(target.S3Options(), srv.httpClient); err != nil {
+		return nil, err
+	}
+
+	target.LastDumpKey, target.LastDumpAt, target.LastError = "", nil, ""
+	result, err := srv.targetRepository.Upsert(target)
+	if err != nil {
+		return nil, err
+	}
+
+	t := *result
+	if err := srv.queue.Dispatch(func() {
+		srv.dump(&t)
+	}); err != nil {
+		config.Log().Error("failed to dispatch data dump for user '%s' - %v", t.UserID, err)
+	}
+	return result, nil
+}
+
+func (srv *ExportService) DeleteTarget(user *models.User) error {
+	return srv.targetRepository.DeleteByUser(user.ID)
+}
+
+// GetDumpUrl returns a presigned url to download the latest dump from the user's own bucket, valid for an hour
+func (srv *ExportService) GetDumpUrl(target *models.ExportTarget) (string, error) {
+	if target.LastDumpKey == "" {
+		return "", ErrExportNoDump
+	}
+	client, err := utils.NewS3Client(target.S3Options(), srv.httpClient)
+	if err != nil {
+		return "", err
+	}
+	return client.PresignGetObject(target.LastDumpKey, exportDumpUrlTtl)
+}
+
+func (srv *ExportService) archivePath(userId string) string {
+	return filepath.Join(srv.config.App.ExportDir, fmt.Sprintf("%s%s", userId, models.ExportArchiveExtension))
+}
+
+// generate writes the user's export archive to a temporary file first and only moves it to its final location once complete, so that a partial archive is never served
+func (srv *ExportService) generate(user *models.User) (string, error) {
+	if err := os.MkdirAll(srv.config.App.ExportDir, 0700); err != nil {
+		return "", err
+	}
+
+	path := srv.archivePath(user.ID)
+	tmpPath := path + exportTempSuffix
+
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return "", err
+	}
+
+	if err := srv.writeArchive(file, user); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+
+	return path, nil
+}
+
+// Export synchronously writes the user's export archive to the given writer, e.g. for the admin cli
+func (srv *ExportService) Export(user *models.User, w io.Writer) error {
+	return srv.writeArchive(w, user)
+}
+
+func (srv *ExportService) writeArchive(w io.Writer, user *models.User) error {
+	archive := zip.NewWriter(w)
+
+	if err := srv.writeHeartbeats(archive, user); err != nil {
+		return err
+	}
+
+	summaries, err := srv.summarySrvc.GetByUserWithin(user, time.Time{}, time.Now())
+	if err != nil {
+		return err
+	}
+	aliases, err := srv.aliasSrvc.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	labels, err := srv.projectLabelSrvc.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	mappings, err := srv.languageMappingSrvc.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	machines, err := srv.machineSrvc.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+
+	entries := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", models.NewUserExportProfile(user)},
+		{"summaries.json", summaries},
+		{"aliases.json", aliases},
+		{"project_labels.json", labels},
+		{"language_mappings.json", mappings},
+		{"machines.json", machines},
+	}
+
+	for _, e := range entries {
+		entryWriter, err := archive.Create(e.name)
+		if err != nil {
+			return err
+		}
+		if err := json.NewEncoder(entryWriter).Encode(e.data); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// writeHeartbeats streams all of the user's heartbeats into the archive as a json array, fetching them page by page to not hold the user's entire history in memory
+func (srv *ExportService) writeHeartbeats(archive *zip.Writer, user *models.User) error {
+	w, err := archive.Create("heartbeats.json")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	var afterId uint64
+	var count int
+	for {
+		page, err := srv.heartbeatSrvc.GetPageByUser(user, afterId, exportHeartbeatsPageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, hb := range page {
+			data, err := json.Marshal(hb)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+			count++
+		}
+
+		if len(page) < exportHeartbeatsPageSize {
+			break
+		}
+		afterId = page[len(page)-1].ID
+	}
+
+	_, err = io.WriteString(w, "]\n")
+	return err
+}
+
+// deleteExpired removes all export archives older than the configured maximum age, as well as leftovers of interrupted exports
+func (srv *ExportService) deleteExpired() {
+	entries, err := os.ReadDir(srv.config.App.ExportDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			config.Log().Error("failed to list export archives - %v", err)
+		}
+		return
+	}
+
+	maxAge := srv.config.App.ExportsMaxAge()
+	for _, e := range entries {
+		if e.IsDir() || !(strings.HasSuffix(e.Name(), models.ExportArchiveExtension) || strings.HasSuffix(e.Name(), exportTempSuffix)) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < maxAge {
+			continue
+		}
+		if err := os.Remove(filepath.Join(srv.config.App.ExportDir, e.Name())); err != nil && !os.IsNotExist(err) {
+			config.Log().Error("failed to delete expired export archive '%s' - %v", e.Name(), err)
+			continue
+		}
+		logbuch.Info("deleted expired export archive '%s'", e.Name())
+	}
+}
+
+func (srv *ExportService) dumpAll() {
+	targets, err := srv.targetRepository.GetAll()
+	if err != nil {
+		config.Log().Error("failed to fetch export targets - %v", err)
+		return
+	}
+
+	logbuch.Info("dumping data of %d users to their export targets", len(targets))
+
+	for _, t := range targets {
+		target := t
+		if err := srv.queue.Dispatch(func() {
+			srv.dump(target)
+		}); err != nil {
+			config.Log().Error("failed to dispatch data dump for user '%s' - %v", target.UserID, err)
+		}
+	}
+}
+
+// dump uploads a fresh export archive to the user's export target and records the outcome
+// the archive is streamed to the bucket while being written, so that it never has to be held in memory as a whole
+func (srv *ExportService) dump(target *models.ExportTarget) {
+	err := func() error {
+		client, err := utils.NewS3Client(target.S3Options(), srv.httpClient)
+		if err != nil {
+			return err
+		}
+		if target.User == nil {
+			return errors.New("user not loaded")
+		}
+
+		now := time.Now()
+		key := target.DumpKey(now)
+
+		reader, writer := io.Pipe()
+		go func() {
+			writer.CloseWithError(srv.writeArchive(writer, target.User))
+		}()
+
+		err = client.Upload(key, reader, "application/zip", utils.S3PutOptions{
+			ServerSideEncryption: models.ExportTargetEncryption,
+			Tags:                 target.DumpTags(),
+		})
+		reader.CloseWithError(err) // unblocks the archive writer in case the upload was aborted
+		if err != nil {
+			return err
+		}
+
+		dumpedAt := models.CustomTime(now)
+		target.LastDumpKey, target.LastDumpAt = key, &dumpedAt
+		return nil
+	}()
+
+	target.LastError = ""
+	if err != nil {
+		config.Log().Warn("failed to dump data of user '%s' to export target - %v", target.UserID, err)
+		target.LastError = err.Error()
+		if len(target.LastError) > exportMaxErrorLength {
+			target.LastError = target.LastError[:exportMaxErrorLength]
+		}
+	} else {
+		logbuch.Info("dumped data of user '%s' to export target", target.UserID)
+	}
+
+	if err := srv.targetRepository.UpdateStatus(target); err != nil {
+		config.Log().Error("failed to update export target status for user '%s' - %v", target.UserID, err)
+	}
+}
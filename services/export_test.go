@@ -0,0 +1,252 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExportServiceTestSuite struct {
+	suite.Suite
+	TestUser               *models.User
+	HeartbeatService       *mocks.HeartbeatServiceMock
+	SummaryService         *mocks.SummaryServiceMock
+	AliasService           *mocks.AliasServiceMock
+	ProjectLabelService    *mocks.ProjectLabelServiceMock
+	LanguageMappingService *mocks.LanguageMappingServiceMock
+	ExportTargetRepository *mocks.ExportTargetRepositoryMock
+}
+
+func (suite *ExportServiceTestSuite) SetupSuite() {
+	suite.TestUser = &models.User{ID: "testuser"}
+}
+
+func (suite *ExportServiceTestSuite) BeforeTest(suiteName, testName string) {
+	cfg := config.Empty()
+	cfg.App.ExportDir = suite.T().TempDir()
+	cfg.App.ExportMaxAge = "72h"
+	config.Set(cfg)
+
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.AliasService = new(mocks.AliasServiceMock)
+	suite.ProjectLabelService = new(mocks.ProjectLabelServiceMock)
+	suite.LanguageMappingService = new(mocks.LanguageMappingServiceMock)
+	suite.ExportTargetRepository = new(mocks.ExportTargetRepositoryMock)
+
+	suite.SummaryService.On("GetByUserWithin", suite.TestUser, mock.Anything, mock.Anything).Return([]*models.Summary{}, nil)
+	suite.AliasService.On("GetByUser", suite.TestUser.ID).Return([]*models.Alias{}, nil)
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
+	suite.LanguageMappingService.On("GetByUser", suite.TestUser.ID).Return([]*models.LanguageMapping{}, nil)
+}
+
+func TestExportServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ExportServiceTestSuite))
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_WriteArchive_PagesHeartbeats() {
+	page1 := make([]*models.Heartbeat, exportHeartbeatsPageSize)
+	for i := range page1 {
+		page1[i] = &models.Heartbeat{ID: uint64(i + 1), Project: "wakapi"}
+	}
+	page2 := []*models.Heartbeat{{ID: uint64(exportHeartbeatsPageSize + 1), Project: "anchr"}}
+
+	suite.HeartbeatService.On("GetPageByUser", suite.TestUser, uint64(0), exportHeartbeatsPageSize).Return(page1, nil)
+	suite.HeartbeatService.On("GetPageByUser", suite.TestUser, uint64(exportHeartbeatsPageSize), exportHeartbeatsPageSize).Return(page2, nil)
+
+	sut := suite.newService()
+
+	var buf bytes.Buffer
+	err := sut.writeArchive(&buf, suite.TestUser)
+	assert.Nil(suite.T(), err)
+
+	// times are exported as rfc 3339 strings, which models.CustomTime can't unmarshal, so only decode the fields of interest
+	heartbeats := make([]struct {
+		Project string `json:"project"`
+	}, 0)
+	suite.readEntry(buf.Bytes(), "heartbeats.json", &heartbeats)
+
+	assert.Len(suite.T(), heartbeats, exportHeartbeatsPageSize+1)
+	assert.Equal(suite.T(), "wakapi", heartbeats[0].Project)
+	assert.Equal(suite.T(), "anchr", heartbeats[exportHeartbeatsPageSize].Project)
+	suite.HeartbeatService.AssertNumberOfCalls(suite.T(), "GetPageByUser", 2)
+
+	var profile struct {
+		ID string `json:"id"`
+	}
+	suite.readEntry(buf.Bytes(), "profile.json", &profile)
+	assert.Equal(suite.T(), suite.TestUser.ID, profile.ID)
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_Generate_RemovesPartialArchiveOnError() {
+	suite.HeartbeatService.On("GetPageByUser", suite.TestUser, uint64(0), exportHeartbeatsPageSize).Return([]*models.Heartbeat{}, assert.AnError)
+
+	sut := suite.newService()
+
+	path, err := sut.generate(suite.TestUser)
+	assert.Error(suite.T(), err)
+	assert.Empty(suite.T(), path)
+
+	entries, _ := os.ReadDir(config.Get().App.ExportDir)
+	assert.Empty(suite.T(), entries)
+	assert.Equal(suite.T(), models.ExportStatusNone, sut.Get(suite.TestUser).Status)
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_Get_Expiry() {
+	suite.HeartbeatService.On("GetPageByUser", suite.TestUser, uint64(0), exportHeartbeatsPageSize).Return([]*models.Heartbeat{}, nil)
+
+	sut := suite.newService()
+
+	path, err := sut.generate(suite.TestUser)
+	assert.Nil(suite.T(), err)
+
+	result := sut.Get(suite.TestUser)
+	assert.Equal(suite.T(), models.ExportStatusReady, result.Status)
+	assert.Equal(suite.T(), path, result.Path)
+
+	expired := time.Now().Add(-73 * time.Hour)
+	assert.Nil(suite.T(), os.Chtimes(path, expired, expired))
+	assert.Equal(suite.T(), models.ExportStatusNone, sut.Get(suite.TestUser).Status)
+
+	sut.deleteExpired()
+	_, err = os.Stat(path)
+	assert.True(suite.T(), os.IsNotExist(err))
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_Request_Pending() {
+	sut := suite.newService()
+
+	assert.True(suite.T(), sut.jobs.Start(suite.TestUser.ID, &models.UserExport{Status: models.ExportStatusPending}))
+	assert.Equal(suite.T(), ErrExportPending, sut.Request(suite.TestUser))
+	assert.Equal(suite.T(), models.ExportStatusPending, sut.Get(suite.TestUser).Status)
+
+	suite.ExportTargetRepository.On("DeleteByUser", suite.TestUser.ID).Return(nil)
+	sut.Delete(suite.TestUser)
+	suite.ExportTargetRepository.AssertCalled(suite.T(), "DeleteByUser", suite.TestUser.ID)
+	assert.Equal(suite.T(), models.ExportStatusNone, sut.Get(suite.TestUser).Status)
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_Dump() {
+	suite.HeartbeatService.On("GetPageByUser", suite.TestUser, uint64(0), exportHeartbeatsPageSize).Return([]*models.Heartbeat{{ID: 1, Project: "wakapi"}}, nil)
+	suite.ExportTargetRepository.On("UpdateStatus", mock.Anything).Return(nil)
+
+	var uploaded []byte
+	var uploadedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(suite.T(), http.MethodPut, r.Method)
+		assert.Equal(suite.T(), "AES256", r.Header.Get("X-Amz-Server-Side-Encryption"))
+		assert.Equal(suite.T(), "retention-days=30&source=wakapi", r.Header.Get("X-Amz-Tagging"))
+		uploadedPath = r.URL.Path
+		uploaded, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	target := &models.ExportTarget{
+		UserID:        suite.TestUser.ID,
+		User:          suite.TestUser,
+		Endpoint:      server.URL,
+		Region:        "local",
+		Bucket:        "backups",
+		Prefix:        "wakapi",
+		PathStyle:     true,
+		AccessKey:     "key",
+		SecretKey:     "secret",
+		RetentionDays: 30,
+	}
+
+	sut := suite.newService()
+	sut.httpClient = server.Client()
+	sut.dump(target)
+
+	assert.True(suite.T(), strings.HasPrefix(uploadedPath, "/backups/wakapi/wakapi_export_testuser_"))
+	assert.True(suite.T(), strings.HasSuffix(target.LastDumpKey, models.ExportArchiveExtension))
+	assert.Equal(suite.T(), "/backups/"+target.LastDumpKey, uploadedPath)
+	assert.NotNil(suite.T(), target.LastDumpAt)
+	assert.Empty(suite.T(), target.LastError)
+	suite.ExportTargetRepository.AssertCalled(suite.T(), "UpdateStatus", target)
+
+	var profile struct {
+		ID string `json:"id"`
+	}
+	suite.readEntry(uploaded, "profile.json", &profile)
+	assert.Equal(suite.T(), suite.TestUser.ID, profile.ID)
+
+	signedUrl, err := sut.GetDumpUrl(target)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), strings.HasPrefix(signedUrl, server.URL+"/backups/wakapi/wakapi_export_testuser_"))
+	assert.Contains(suite.T(), signedUrl, "X-Amz-Expires=3600")
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_Dump_RecordsError() {
+	suite.ExportTargetRepository.On("UpdateStatus", mock.Anything).Return(nil)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	suite.HeartbeatService.On("GetPageByUser", suite.TestUser, uint64(0), exportHeartbeatsPageSize).Return([]*models.Heartbeat{}, nil)
+
+	target := &models.ExportTarget{UserID: suite.TestUser.ID, User: suite.TestUser, Endpoint: server.URL, Region: "local", Bucket: "backups", PathStyle: true, AccessKey: "key", SecretKey: "wrong"}
+
+	sut := suite.newService()
+	sut.httpClient = server.Client()
+	sut.dump(target)
+
+	assert.NotEmpty(suite.T(), target.LastError)
+	assert.Empty(suite.T(), target.LastDumpKey)
+	suite.ExportTargetRepository.AssertCalled(suite.T(), "UpdateStatus", target)
+
+	_, err := sut.GetDumpUrl(target)
+	assert.ErrorIs(suite.T(), err, ErrExportNoDump)
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_SetTarget_Disabled() {
+	sut := suite.newService()
+
+	_, err := sut.SetTarget(suite.TestUser, &models.ExportTarget{Region: "local", Bucket: "backups", AccessKey: "key", SecretKey: "secret"})
+	assert.ErrorIs(suite.T(), err, ErrExportNoTargets)
+	suite.ExportTargetRepository.AssertNotCalled(suite.T(), "Upsert", mock.Anything)
+}
+
+func (suite *ExportServiceTestSuite) newService() *ExportService {
+	return &ExportService{
+		config:              config.Get(),
+		heartbeatSrvc:       suite.HeartbeatService,
+		summarySrvc:         suite.SummaryService,
+		aliasSrvc:           suite.AliasService,
+		projectLabelSrvc:    suite.ProjectLabelService,
+		languageMappingSrvc: suite.LanguageMappingService,
+		jobs:                newUserJobs((*models.UserExport).IsPending),
+		targetRepository:    suite.ExportTargetRepository,
+	}
+}
+
+func (suite *ExportServiceTestSuite) readEntry(archive []byte, name string, target interface{}) {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if !assert.Nil(suite.T(), err) {
+		return
+	}
+	f, err := reader.Open(filepath.ToSlash(name))
+	if !assert.Nil(suite.T(), err) {
+		return
+	}
+	defer f.Close()
+	data, _ := io.ReadAll(f)
+	assert.Nil(suite.T(), json.Unmarshal(data, target))
+}
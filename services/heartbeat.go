@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
 	"github.com/duke-git/lancet/v2/maputil"
@@ -10,6 +11,7 @@ import (
 	"github.com/muety/wakapi/utils"
 	"github.com/patrickmn/go-cache"
 	"math"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -17,22 +19,28 @@ import (
 	"github.com/muety/wakapi/models"
 )
 
+var ErrNonNativeFilters = errors.New("filtering by labels is not supported here")
+
 type HeartbeatService struct {
 	config              *config.Config
 	cache               *cache.Cache
+	countCache          utils.Cache // shared among replicas, if configured
 	eventBus            *hub.Hub
 	repository          repositories.IHeartbeatRepository
 	languageMappingSrvc ILanguageMappingService
+	blockRuleSrvc       IBlockRuleService
 	entityCacheLock     *sync.RWMutex
 }
 
-func NewHeartbeatService(heartbeatRepo repositories.IHeartbeatRepository, languageMappingService ILanguageMappingService) *HeartbeatService {
+func NewHeartbeatService(heartbeatRepo repositories.IHeartbeatRepository, languageMappingService ILanguageMappingService, blockRuleService IBlockRuleService) *HeartbeatService {
 	srv := &HeartbeatService{
 		config:              config.Get(),
 		cache:               cache.New(24*time.Hour, 24*time.Hour),
+		countCache:          config.NewCache("heartbeat_counts", 24*time.Hour, 24*time.Hour),
 		eventBus:            config.EventBus(),
 		repository:          heartbeatRepo,
 		languageMappingSrvc: languageMappingService,
+		blockRuleSrvc:       blockRuleService,
 		entityCacheLock:     &sync.RWMutex{},
 	}
 
@@ -43,8 +51,8 @@ func NewHeartbeatService(heartbeatRepo repositories.IHeartbeatRepository, langua
 	go func(sub *hub.Subscription) {
 		for m := range sub.Receiver {
 			heartbeat := m.Fields[config.FieldPayload].(*models.Heartbeat)
-			srv.cache.IncrementInt64(srv.countByUserCacheKey(heartbeat.UserID), 1) // increment doesn't update expiration time
-			srv.cache.IncrementInt64(srv.countTotalCacheKey(), 1)
+			srv.countCache.IncrementInt64(srv.countByUserCacheKey(heartbeat.UserID), 1) // increment doesn't update expiration time
+			srv.countCache.IncrementInt64(srv.countTotalCacheKey(), 1)
 			srv.checkInvalidateProjectStatsCache(heartbeat)
 		}
 	}(&sub1)
@@ -53,8 +61,7 @@ func NewHeartbeatService(heartbeatRepo repositories.IHeartbeatRepository, langua
 }
 
 func (srv *HeartbeatService) Insert(heartbeat *models.Heartbeat) error {
-	go srv.updateEntityUserCacheByHeartbeat(heartbeat)
-	return srv.repository.InsertBatch([]*models.Heartbeat{heartbeat})
+	return srv.InsertBatch([]*models.Heartbeat{heartbeat})
 }
 
 func (srv *HeartbeatService) InsertBatch(heartbeats []*models.Heartbeat) error {
@@ -62,16 +69,29 @@ func (srv *HeartbeatService) InsertBatch(heartbeats []*models.Heartbeat) error {
 		return nil
 	}
 
+	// drop heartbeats blocked instance-wide before they make it into any cache
+	if heartbeats = srv.blockRuleSrvc.Filter(heartbeats); len(heartbeats) == 0 {
+		return nil
+	}
+
 	hashes := datastructure.NewSet[string]()
 
 	// https://github.com/muety/wakapi/issues/139
 	filteredHeartbeats := make([]*models.Heartbeat, 0, len(heartbeats))
 	for _, hb := range heartbeats {
 		if !hashes.Contain(hb.Hash) {
-			hb = hb.Sanitize()
-			filteredHeartbeats = append(filteredHeartbeats, hb)
+			filteredHeartbeats = append(filteredHeartbeats, hb.Sanitize())
 			hashes.Add(hb.Hash)
 		}
+	}
+
+	// languages are detected from the plain file names, which hashing would otherwise discard (e.g. for a Makefile)
+	srv.applyLanguageMappings(filteredHeartbeats)
+
+	// file paths must never hit the database (nor any cache) in plain text for users who opted for hashing them
+	srv.applyEntityHashing(filteredHeartbeats)
+
+	for _, hb := range filteredHeartbeats {
 		go srv.updateEntityUserCacheByHeartbeat(hb)
 	}
 
@@ -82,27 +102,37 @@ func (srv *HeartbeatService) InsertBatch(heartbeats []*models.Heartbeat) error {
 	return err
 }
 
+// FilterBlocked returns those of the given heartbeats, which don't match any instance-wide block rule
+func (srv *HeartbeatService) FilterBlocked(heartbeats []*models.Heartbeat) []*models.Heartbeat {
+	return srv.blockRuleSrvc.Filter(heartbeats)
+}
+
+// GetExistingHashes returns those of the given heartbeat hashes, which are already known, i.e. belong to duplicate heartbeats
+func (srv *HeartbeatService) GetExistingHashes(hashes []string) ([]string, error) {
+	return srv.repository.GetExistingHashes(hashes)
+}
+
 func (srv *HeartbeatService) Count(approximate bool) (int64, error) {
-	result, ok := srv.cache.Get(srv.countTotalCacheKey())
-	if ok {
-		return result.(int64), nil
+	var result int64
+	if srv.countCache.Get(srv.countTotalCacheKey(), &result) {
+		return result, nil
 	}
 	count, err := srv.repository.Count(approximate)
 	if err == nil {
-		srv.cache.Set(srv.countTotalCacheKey(), count, srv.countCacheTtl())
+		srv.countCache.Set(srv.countTotalCacheKey(), count, srv.countCacheTtl())
 	}
 	return count, err
 }
 
 func (srv *HeartbeatService) CountByUser(user *models.User) (int64, error) {
 	key := srv.countByUserCacheKey(user.ID)
-	result, ok := srv.cache.Get(key)
-	if ok {
-		return result.(int64), nil
+	var result int64
+	if srv.countCache.Get(key, &result) {
+		return result, nil
 	}
 	count, err := srv.repository.CountByUser(user)
 	if err == nil {
-		srv.cache.Set(key, count, srv.countCacheTtl())
+		srv.countCache.Set(key, count, srv.countCacheTtl())
 	}
 	return count, err
 }
@@ -113,9 +143,9 @@ func (srv *HeartbeatService) CountByUsers(users []*models.User) ([]*models.Count
 
 	for _, u := range users {
 		key := srv.countByUserCacheKey(u.ID)
-		result, ok := srv.cache.Get(key)
-		if ok {
-			userCounts = append(userCounts, &models.CountByUser{User: u.ID, Count: result.(int64)})
+		var result int64
+		if srv.countCache.Get(key, &result) {
+			userCounts = append(userCounts, &models.CountByUser{User: u.ID, Count: result})
 		} else {
 			missingUsers = append(missingUsers, u)
 		}
@@ -128,7 +158,7 @@ func (srv *HeartbeatService) CountByUsers(users []*models.User) ([]*models.Count
 
 	for _, uc := range counts {
 		key := srv.countByUserCacheKey(uc.User)
-		srv.cache.Set(key, uc.Count, srv.countCacheTtl())
+		srv.countCache.Set(key, uc.Count, srv.countCacheTtl())
 		userCounts = append(userCounts, uc)
 	}
 
@@ -143,8 +173,16 @@ func (srv *HeartbeatService) GetAllWithin(from, to time.Time, user *models.User)
 	return srv.augmented(heartbeats, user.ID)
 }
 
+func (srv *HeartbeatService) GetPageByUser(user *models.User, afterId uint64, limit int) ([]*models.Heartbeat, error) {
+	heartbeats, err := srv.repository.GetPageByUser(user, afterId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return srv.augmented(heartbeats, user.ID)
+}
+
 func (srv *HeartbeatService) GetAllWithinByFilters(from, to time.Time, user *models.User, filters *models.Filters) ([]*models.Heartbeat, error) {
-	heartbeats, err := srv.repository.GetAllWithinByFilters(from, to, user, srv.filtersToColumnMap(filters))
+	heartbeats, err := srv.repository.GetAllWithinByFilters(from, to, user, filters.ColumnConditions())
 	if err != nil {
 		return nil, err
 	}
@@ -160,7 +198,23 @@ func (srv *HeartbeatService) GetLatestByOriginAndUser(origin string, user *model
 }
 
 func (srv *HeartbeatService) GetLatestByFilters(user *models.User, filters *models.Filters) (*models.Heartbeat, error) {
-	return srv.repository.GetLatestByFilters(user, srv.filtersToColumnMap(filters))
+	return srv.repository.GetLatestByFilters(user, filters.ColumnConditions())
+}
+
+func (srv *HeartbeatService) GetByUserAndId(user *models.User, id uint64) (*models.Heartbeat, error) {
+	return srv.repository.GetByUserAndId(user, id)
+}
+
+func (srv *HeartbeatService) GetTimeRangeByUserAndProjects(user *models.User, projects []string) (*models.HeartbeatTimeRange, error) {
+	return srv.repository.GetTimeRangeByUserAndProjects(user, projects)
+}
+
+func (srv *HeartbeatService) GetDistinctByUser(user *models.User, columns []string, offset, limit int) ([]*models.Heartbeat, error) {
+	return srv.repository.GetDistinctByUser(user, columns, offset, limit)
+}
+
+func (srv *HeartbeatService) CountByUsersBefore(t time.Time) ([]*models.CountByUser, error) {
+	return srv.repository.CountByUsersBefore(t)
 }
 
 func (srv *HeartbeatService) GetFirstByUsers() ([]*models.TimeByUser, error) {
@@ -192,20 +246,96 @@ func (srv *HeartbeatService) GetEntitySetByUser(entityType uint8, userId string)
 }
 
 func (srv *HeartbeatService) DeleteBefore(t time.Time) error {
-	go srv.cache.Flush()
+	go srv.flushCaches()
 	return srv.repository.DeleteBefore(t)
 }
 
 func (srv *HeartbeatService) DeleteByUser(user *models.User) error {
-	go srv.cache.Flush()
+	go srv.flushCaches()
 	return srv.repository.DeleteByUser(user)
 }
 
 func (srv *HeartbeatService) DeleteByUserBefore(user *models.User, t time.Time) error {
-	go srv.cache.Flush()
+	go srv.flushCaches()
 	return srv.repository.DeleteByUserBefore(user, t)
 }
 
+func (srv *HeartbeatService) AnonymizeByUserBefore(user *models.User, t time.Time) (int64, error) {
+	go srv.flushCaches()
+	return srv.repository.AnonymizeByUserBefore(user, t)
+}
+
+func (srv *HeartbeatService) DropChunksBefore(t time.Time) error {
+	go srv.flushCaches()
+	return srv.repository.DropChunksBefore(t)
+}
+
+func (srv *HeartbeatService) CreatePartitions(from, to time.Time) error {
+	return srv.repository.CreatePartitions(from, to)
+}
+
+func (srv *HeartbeatService) DropPartitionsBefore(t time.Time) error {
+	go srv.flushCaches()
+	return srv.repository.DropPartitionsBefore(t)
+}
+
+func (srv *HeartbeatService) DeleteByUserAndId(user *models.User, id uint64) error {
+	go srv.flushCaches()
+	return srv.repository.DeleteByUserAndId(user, id)
+}
+
+func (srv *HeartbeatService) DeleteByUserWithinByFilters(user *models.User, from, to time.Time, filters *models.Filters, entityPattern string) (int64, error) {
+	if !filters.IsNative() {
+		return 0, ErrNonNativeFilters
+	}
+	go srv.flushCaches()
+	return srv.repository.DeleteByUserWithinByFilters(user, from, to, filters.ColumnConditions(), entityPattern)
+}
+
+func (srv *HeartbeatService) CountByUserWithinByFilters(user *models.User, from, to time.Time, filters *models.Filters, entityPattern string) (int64, error) {
+	if !filters.IsNative() {
+		return 0, ErrNonNativeFilters
+	}
+	return srv.repository.CountByUserWithinByFilters(user, from, to, filters.ColumnConditions(), entityPattern)
+}
+
+func (srv *HeartbeatService) Update(heartbeat *models.Heartbeat) error {
+	go srv.flushCaches()
+	return srv.repository.Update(heartbeat)
+}
+
+func (srv *HeartbeatService) UpdateBatch(heartbeats []*models.Heartbeat) error {
+	go srv.flushCaches()
+	return srv.repository.UpdateBatch(heartbeats)
+}
+
+func (srv *HeartbeatService) RenameProjectByUser(user *models.User, oldProject, newProject string) (int64, error) {
+	go srv.flushCaches()
+	return srv.repository.UpdateProjectByUser(user, oldProject, newProject)
+}
+
+func (srv *HeartbeatService) RenameProjectByUserWithin(user *models.User, from, to time.Time, oldProject, newProject string) (int64, error) {
+	go srv.flushCaches()
+	return srv.repository.UpdateProjectByUserWithin(user, from, to, oldProject, newProject)
+}
+
+// ReprocessLanguagesByUser applies the user's current language mappings to all of their existing heartbeats, including reverting those of deleted mappings, and returns the number of heartbeats with a mapped language
+func (srv *HeartbeatService) ReprocessLanguagesByUser(user *models.User) (int64, error) {
+	mappings, err := srv.languageMappingSrvc.ResolveByUser(user.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	// apply less concrete mappings first, so that more concrete ones (e.g. "blade.php" vs. "php") take precedence, just like in Augment()
+	extensions := maputil.Keys(mappings)
+	sort.Slice(extensions, func(i, j int) bool {
+		return strings.Count(extensions[i], ".") < strings.Count(extensions[j], ".")
+	})
+
+	go srv.flushCaches()
+	return srv.repository.ApplyLanguageMappingsByUser(user, extensions, mappings)
+}
+
 func (srv *HeartbeatService) GetUserProjectStats(user *models.User, from, to time.Time, pageParams *utils.PageParams, skipCache bool) ([]*models.ProjectStats, error) {
 	// for projects page, call this like: GetUserProjectStats(&models.User{ID: "n1try"}, time.Time{}, utils.BeginOfToday(time.Local), false)
 
@@ -240,6 +370,75 @@ func (srv *HeartbeatService) GetUserProjectStats(user *models.User, from, to tim
 	return results, err
 }
 
+func (srv *HeartbeatService) GetUserAgentsByUser(user *models.User) ([]*models.UserAgentStats, error) {
+	cacheKey := fmt.Sprintf("user_agents_%s", user.ID)
+	if results, found := srv.cache.Get(cacheKey); found {
+		return results.([]*models.UserAgentStats), nil
+	}
+
+	results, err := srv.repository.GetUserAgentStatsByUser(user)
+	if err == nil {
+		srv.cache.Set(cacheKey, results, 1*time.Hour)
+	}
+	return results, err
+}
+
+// GetPluginVersionStats returns the number of distinct users per plugin- and cli version among all heartbeats since the given time
+func (srv *HeartbeatService) GetPluginVersionStats(since time.Time) ([]*models.PluginVersionStats, error) {
+	cacheKey := fmt.Sprintf("plugin_version_stats_%d", since.Truncate(time.Hour).Unix())
+	if results, found := srv.cache.Get(cacheKey); found {
+		return results.([]*models.PluginVersionStats), nil
+	}
+
+	userAgents, err := srv.repository.GetUserAgentStatsSince(since)
+	if err != nil {
+		return nil, err
+	}
+
+	statsByKey := map[string]*models.PluginVersionStats{}
+	usersByKey := map[string]map[string]bool{}
+
+	count := func(editor, plugin, version string, ua *models.UserAgentStats) {
+		if plugin == "" || version == "" {
+			return
+		}
+		key := plugin + "/" + version
+		if _, ok := statsByKey[key]; !ok {
+			statsByKey[key] = &models.PluginVersionStats{Editor: editor, Plugin: plugin, Version: version}
+			usersByKey[key] = map[string]bool{}
+		}
+		if ua.Last.T().After(statsByKey[key].LastSeenAt.T()) {
+			statsByKey[key].LastSeenAt = ua.Last
+		}
+		usersByKey[key][ua.UserId] = true
+	}
+
+	for _, ua := range userAgents {
+		info := models.ParseUserAgentInfo(ua.UserAgent)
+		count(info.Editor, info.Plugin, info.PluginVersion, ua)
+		count("", models.UserAgentCliPlugin, info.CliVersion, ua)
+	}
+
+	results := make([]*models.PluginVersionStats, 0, len(statsByKey))
+	for key, stats := range statsByKey {
+		stats.Users = len(usersByKey[key])
+		results = append(results, stats)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Plugin != results[j].Plugin {
+			return results[i].Plugin < results[j].Plugin
+		}
+		if results[i].Users != results[j].Users {
+			return results[i].Users > results[j].Users
+		}
+		return results[i].Version > results[j].Version
+	})
+
+	srv.cache.Set(cacheKey, results, 1*time.Hour)
+	return results, nil
+}
+
 func (srv *HeartbeatService) augmented(heartbeats []*models.Heartbeat, userId string) ([]*models.Heartbeat, error) {
 	languageMapping, err := srv.languageMappingSrvc.ResolveByUser(userId)
 	if err != nil {
@@ -253,6 +452,36 @@ func (srv *HeartbeatService) augmented(heartbeats []*models.Heartbeat, userId st
 	return heartbeats, nil
 }
 
+// applyLanguageMappings persists the language resolved from the respective user's mappings, while the one reported by the client is kept to be able to revert it later on
+// heartbeats without any language after that are assigned the one detected from their file's name or extension
+func (srv *HeartbeatService) applyLanguageMappings(heartbeats []*models.Heartbeat) {
+	mappingsByUser := map[string]map[string]string{}
+	for _, hb := range heartbeats {
+		mappings, ok := mappingsByUser[hb.UserID]
+		if !ok {
+			var err error
+			if mappings, err = srv.languageMappingSrvc.ResolveByUser(hb.UserID); err != nil {
+				config.Log().Error("failed to resolve language mappings for user '%s' - %v", hb.UserID, err)
+			}
+			mappingsByUser[hb.UserID] = mappings
+		}
+		hb.MapLanguage(mappings)
+		if srv.config.App.DetectLanguages {
+			hb.DetectLanguage()
+		}
+	}
+}
+
+// applyEntityHashing replaces file paths by salted hashes for those users or projects, which have it enabled
+// heartbeats' dedup hashes are computed from the original file paths before, so clients' retries are still detected as duplicates
+func (srv *HeartbeatService) applyEntityHashing(heartbeats []*models.Heartbeat) {
+	for _, hb := range heartbeats {
+		if hb.User != nil && hb.User.HashesEntitiesOf(hb.Project) {
+			hb.HashEntity(srv.config.Security.PasswordSalt + hb.User.EntityHashSalt)
+		}
+	}
+}
+
 func (srv *HeartbeatService) getEntityUserCacheKey(entityType uint8, userId string) string {
 	return fmt.Sprintf("entity_set_%d_%s", entityType, userId)
 }
@@ -305,19 +534,14 @@ func (srv *HeartbeatService) countTotalCacheKey() string {
 	return "heartbeat-count"
 }
 
-func (srv *HeartbeatService) countCacheTtl() time.Duration {
-	return time.Duration(srv.config.App.CountCacheTTLMin) * time.Minute
+// flushCaches drops both process-local entity caches and heartbeat counts
+func (srv *HeartbeatService) flushCaches() {
+	srv.cache.Flush()
+	srv.countCache.Flush()
 }
 
-func (srv *HeartbeatService) filtersToColumnMap(filters *models.Filters) map[string][]string {
-	columnMap := map[string][]string{}
-	for _, t := range models.NativeSummaryTypes() {
-		f := filters.ResolveEntity(t)
-		if len(*f) > 0 {
-			columnMap[models.GetEntityColumn(t)] = *f
-		}
-	}
-	return columnMap
+func (srv *HeartbeatService) countCacheTtl() time.Duration {
+	return time.Duration(srv.config.App.CountCacheTTLMin) * time.Minute
 }
 
 func (srv *HeartbeatService) populateUniqueUserProjects(userId string) {
@@ -1,38 +1,86 @@
 package services
 
 import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+
 	"github.com/duke-git/lancet/v2/slice"
 	"github.com/emvi/logbuch"
 	"github.com/muety/artifex/v2"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/utils"
+	"sync"
 	"time"
 )
 
+const heartbeatPartitionsAhead = 3 // number of months to create heartbeat partitions for in advance
+
+const (
+	jobTypeUserDataCleanup     = "user_data_cleanup"
+	jobTypeInactiveUserCleanup = "inactive_user_cleanup"
+)
+
+var ErrRetentionMismatch = errors.New("retention period to confirm does not match the configured one")
+
+type userCleanupJob struct {
+	UserID string `json:"user_id"`
+}
+
 type HousekeepingService struct {
 	config        *config.Config
 	userSrvc      IUserService
 	heartbeatSrvc IHeartbeatService
 	summarySrvc   ISummaryService
+	machineSrvc   IMachineService
+	keyValueSrvc  IKeyValueService
+	jobLockSrvc   IJobLockService
+	queueSrvc     IPersistentQueueService
 	queueDefault  *artifex.Dispatcher
 	queueWorkers  *artifex.Dispatcher
+	cleanupCrons  *cronJobs
+	inactiveCrons *cronJobs
+	deletions     sync.Map // ids of users whose deletion is currently dispatched
 }
 
-func NewHousekeepingService(userService IUserService, heartbeatService IHeartbeatService, summaryService ISummaryService) *HousekeepingService {
-	return &HousekeepingService{
+func NewHousekeepingService(userService IUserService, heartbeatService IHeartbeatService, summaryService ISummaryService, machineService IMachineService, keyValueService IKeyValueService, persistentQueueService IPersistentQueueService, jobLockService IJobLockService) *HousekeepingService {
+	srv := &HousekeepingService{
 		config:        config.Get(),
 		userSrvc:      userService,
 		heartbeatSrvc: heartbeatService,
 		summarySrvc:   summaryService,
+		machineSrvc:   machineService,
+		keyValueSrvc:  keyValueService,
+		jobLockSrvc:   jobLockService,
+		queueSrvc:     persistentQueueService,
 		queueDefault:  config.GetDefaultQueue(),
 		queueWorkers:  config.GetQueue(config.QueueHousekeeping),
+		cleanupCrons:  newCronJobs("data_cleanup", config.GetDefaultQueue(), jobLockService),
+		inactiveCrons: newCronJobs("inactive_user_cleanup", config.GetDefaultQueue(), jobLockService),
+	}
+
+	if persistentQueueService != nil {
+		persistentQueueService.Register(jobTypeUserDataCleanup, srv.queueWorkers, srv.runUserDataCleanupJob)
+		persistentQueueService.Register(jobTypeInactiveUserCleanup, srv.queueWorkers, srv.runInactiveUserCleanupJob)
 	}
+
+	return srv
 }
 
 func (s *HousekeepingService) Schedule() {
+	s.initRetentionConfirmation()
 	s.scheduleDataCleanups()
+	s.scheduleInactiveUserCleanups()
+	s.scheduleAccountDeletions()
 	s.scheduleProjectStatsCacheWarming()
+	s.schedulePartitionMaintenance()
+
+	config.OnReload(func() {
+		s.scheduleDataCleanups()
+		s.scheduleInactiveUserCleanups()
+	}, config.ReloadKeyDataCleanupTime)
 }
 
 func (s *HousekeepingService) CleanUserDataBefore(user *models.User, before time.Time) error {
@@ -42,6 +90,10 @@ func (s *HousekeepingService) CleanUserDataBefore(user *models.User, before time
 		return nil
 	}
 
+	if s.config.App.AnonymizeExpiredData() {
+		return s.anonymizeUserDataBefore(user, before)
+	}
+
 	// clear old heartbeats
 	if err := s.heartbeatSrvc.DeleteByUserBefore(user, before); err != nil {
 		return err
@@ -53,9 +105,141 @@ func (s *HousekeepingService) CleanUserDataBefore(user *models.User, before time
 		return err
 	}
 
+	// clear machines not seen anymore, along with their last ip and location
+	logbuch.Info("clearing machines for user '%s' not seen since %v", user.ID, before)
+	if err := s.machineSrvc.DeleteByUserBefore(user.ID, before); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// anonymizeUserDataBefore keeps old heartbeats, summaries and machines, so that long-term statistics (e.g. time per project or language) survive, but strips them of anything identifying
+func (s *HousekeepingService) anonymizeUserDataBefore(user *models.User, before time.Time) error {
+	count, err := s.heartbeatSrvc.AnonymizeByUserBefore(user, before)
+	if err != nil {
+		return err
+	}
+	logbuch.Info("anonymized %d heartbeats of user '%s' older than %v", count, user.ID, before)
+
+	logbuch.Info("anonymizing summaries for user '%s' older than %v", user.ID, before)
+	if err := s.summarySrvc.AnonymizeByUserBefore(user.ID, before); err != nil {
+		return err
+	}
+
+	count, err = s.machineSrvc.AnonymizeByUserBefore(user.ID, before)
+	if err != nil {
+		return err
+	}
+	logbuch.Info("anonymized %d machines of user '%s' not seen since %v", count, user.ID, before)
+	return nil
+}
+
+// GetRetentionImpact computes which data the next cleanup run will delete per user, given the currently configured retention period
+func (s *HousekeepingService) GetRetentionImpact() (*models.RetentionImpact, error) {
+	configured, confirmed := s.config.App.DataRetentionMonths, s.confirmedRetentionMonths()
+	impact := &models.RetentionImpact{
+		RetentionMonths:          configured,
+		ConfirmedRetentionMonths: confirmed,
+		ConfirmationRequired:     models.RetentionRequiresConfirmation(configured, confirmed),
+		Mode:                     s.config.App.DataRetentionMode,
+		Users:                    []*models.UserRetentionImpact{},
+	}
+	if configured <= 0 {
+		return impact, nil
+	}
+
+	nextRun, err := s.config.App.NextDataCleanup(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	cutoff := nextRun.AddDate(0, -configured, 0)
+	nextRunTime, cutoffTime := models.CustomTime(nextRun), models.CustomTime(cutoff)
+	impact.NextRun, impact.Cutoff = &nextRunTime, &cutoffTime
+
+	users, err := s.userSrvc.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	exempt := make(map[string]bool)
+	for _, u := range users {
+		if u.HasActiveSubscription() {
+			exempt[u.ID] = true
+		}
+	}
+
+	heartbeats, summaries, err := s.countDataBefore(cutoff)
+	if err != nil {
+		return nil, err
+	}
+	confirmedHeartbeats, confirmedSummaries := map[string]int64{}, map[string]int64{}
+	if confirmed > configured {
+		// the shorter the period, the more data is deleted, so data deleted under a longer confirmed period is a subset of what's deleted now
+		if confirmedHeartbeats, confirmedSummaries, err = s.countDataBefore(nextRun.AddDate(0, -confirmed, 0)); err != nil {
+			return nil, err
+		}
+	} else if confirmed > 0 {
+		// confirmed period is at most as long as the configured one, so nothing is deleted additionally
+		confirmedHeartbeats, confirmedSummaries = heartbeats, summaries
+	}
+
+	for userId := range mergeKeys(heartbeats, summaries) {
+		if exempt[userId] {
+			continue
+		}
+		impact.Heartbeats += heartbeats[userId]
+		impact.Summaries += summaries[userId]
+		impact.Users = append(impact.Users, &models.UserRetentionImpact{
+			User:                 userId,
+			Heartbeats:           heartbeats[userId],
+			Summaries:            summaries[userId],
+			AdditionalHeartbeats: heartbeats[userId] - confirmedHeartbeats[userId],
+			AdditionalSummaries:  summaries[userId] - confirmedSummaries[userId],
+		})
+	}
+	sort.Slice(impact.Users, func(i, j int) bool {
+		return impact.Users[i].Heartbeats > impact.Users[j].Heartbeats || (impact.Users[i].Heartbeats == impact.Users[j].Heartbeats && impact.Users[i].User < impact.Users[j].User)
+	})
+
+	return impact, nil
+}
+
+// ConfirmRetention accepts the configured retention period, which is required before cleanups delete more data than under the previously confirmed one
+// the period to confirm is passed explicitly to make sure the admin confirms what they have previously reviewed
+func (s *HousekeepingService) ConfirmRetention(months int) error {
+	if months != s.config.App.DataRetentionMonths {
+		return ErrRetentionMismatch
+	}
+	logbuch.Warn("data retention period of %d months was confirmed", months)
+	return s.storeConfirmedRetention(months)
+}
+
+// DropExpiredHeartbeats drops all timescale chunks or monthly partitions of heartbeats older than the data retention period
+func (s *HousekeepingService) DropExpiredHeartbeats() error {
+	if s.config.App.DataRetentionMonths <= 0 || s.config.App.AnonymizeExpiredData() {
+		return nil
+	}
+
+	before := time.Now().AddDate(0, -s.config.App.DataRetentionMonths, 0)
+	logbuch.Warn("dropping heartbeat chunks or partitions older than %v", before)
+	if s.config.App.DataCleanupDryRun {
+		logbuch.Info("skipping actual chunk or partition deletion, because this is just a dry run")
+		return nil
+	}
+
+	if s.config.Db.Timescale {
+		return s.heartbeatSrvc.DropChunksBefore(before)
+	}
+	return s.heartbeatSrvc.DropPartitionsBefore(before)
+}
+
+// CreateHeartbeatPartitions creates the heartbeats table's monthly partitions for the upcoming months in advance
+func (s *HousekeepingService) CreateHeartbeatPartitions() error {
+	now := time.Now()
+	logbuch.Info("creating heartbeat partitions until %v", now.AddDate(0, heartbeatPartitionsAhead, 0))
+	return s.heartbeatSrvc.CreatePartitions(now, now.AddDate(0, heartbeatPartitionsAhead, 0))
+}
+
 func (s *HousekeepingService) WarmUserProjectStatsCache(user *models.User) error {
 	logbuch.Info("pre-warming project stats cache for '%s'", user.ID)
 	if _, err := s.heartbeatSrvc.GetUserProjectStats(user, time.Time{}, utils.BeginOfToday(time.Local), nil, true); err != nil {
@@ -98,6 +282,16 @@ func (s *HousekeepingService) runWarmProjectStatsCache() {
 }
 
 func (s *HousekeepingService) runCleanData() {
+	if configured, confirmed := s.config.App.DataRetentionMonths, s.confirmedRetentionMonths(); models.RetentionRequiresConfirmation(configured, confirmed) {
+		logbuch.Warn("skipping data cleanup, because data retention period was shortened from %d to %d months without being confirmed by an admin yet (see /api/admin/retention/impact)", confirmed, configured)
+		return
+	} else if configured != confirmed {
+		// less data is deleted than before, so no need for confirmation
+		if err := s.storeConfirmedRetention(configured); err != nil {
+			config.Log().Error("failed to store confirmed data retention period, %v", err)
+		}
+	}
+
 	// fetch all users
 	users, err := s.userSrvc.GetAll()
 	if err != nil {
@@ -105,6 +299,14 @@ func (s *HousekeepingService) runCleanData() {
 		return
 	}
 
+	// with timescale or partitioning, heartbeats can be dropped chunk- or partition-wise for all users at once, which is way cheaper than deleting single rows
+	// only possible as long as no user is exempt from data retention, remaining heartbeats from partially covered chunks are still deleted per user below
+	if (s.config.Db.Timescale || s.config.Db.IsPartitioned()) && !s.config.Subscriptions.Enabled {
+		if err := s.DropExpiredHeartbeats(); err != nil {
+			config.Log().Error("failed to drop expired heartbeats, %v", err)
+		}
+	}
+
 	// schedule jobs
 	for _, u := range users {
 		// don't clean data for subscribed users or when they otherwise have unlimited data access
@@ -112,15 +314,161 @@ func (s *HousekeepingService) runCleanData() {
 			continue
 		}
 
+		if err := s.queueSrvc.Dispatch(jobTypeUserDataCleanup, &userCleanupJob{UserID: u.ID}); err != nil {
+			config.Log().Error("failed to dispatch data cleanup for user '%s', %v", u.ID, err)
+		}
+	}
+}
+
+func (s *HousekeepingService) runCleanInactiveUsers() {
+	users, err := s.userSrvc.GetInactive(s.config.App.InactiveCleanupDays)
+	if err != nil {
+		config.Log().Error("failed to get inactive users for cleanup, %v", err)
+		return
+	}
+
+	for _, u := range users {
+		// never delete accounts of paying users
+		if u.HasActiveSubscription() {
+			continue
+		}
+
+		if err := s.queueSrvc.Dispatch(jobTypeInactiveUserCleanup, &userCleanupJob{UserID: u.ID}); err != nil {
+			config.Log().Error("failed to dispatch deletion of inactive user '%s', %v", u.ID, err)
+		}
+	}
+}
+
+func (s *HousekeepingService) runUserDataCleanupJob(payload []byte) error {
+	user, err := s.getJobUser(payload)
+	if err != nil {
+		return err
+	}
+
+	if err := s.CleanUserDataBefore(user, user.MinDataAge()); err != nil {
+		config.Log().Error("failed to clear old user data for '%s'", user.ID)
+	}
+	return nil
+}
+
+func (s *HousekeepingService) runInactiveUserCleanupJob(payload []byte) error {
+	user, err := s.getJobUser(payload)
+	if err != nil {
+		return err
+	}
+
+	// the account might have been deactivated or suspended since the job was dispatched
+	if !user.IsActive() {
+		return nil
+	}
+
+	logbuch.Warn("deleting user '%s' after more than %d days of inactivity", user.ID, s.config.App.InactiveCleanupDays)
+	if s.config.App.DataCleanupDryRun {
+		logbuch.Info("skipping actual deletion of '%v', because this is just a dry run", user.ID)
+		return nil
+	}
+	if err := s.userSrvc.Delete(user); err != nil {
+		config.Log().Error("failed to delete inactive user '%s', %v", user.ID, err)
+	}
+	return nil
+}
+
+func (s *HousekeepingService) getJobUser(payload []byte) (*models.User, error) {
+	var job userCleanupJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, err
+	}
+	return s.userSrvc.GetUserById(job.UserID)
+}
+
+func (s *HousekeepingService) runDeleteScheduledUsers() {
+	users, err := s.userSrvc.GetScheduledForDeletion()
+	if err != nil {
+		config.Log().Error("failed to get users scheduled for deletion, %v", err)
+		return
+	}
+
+	for _, u := range users {
 		user := *u
-		s.queueWorkers.Dispatch(func() {
-			if err := s.CleanUserDataBefore(&user, user.MinDataAge()); err != nil {
-				config.Log().Error("failed to clear old user data for '%s'", user.ID)
+
+		// skip users whose deletion is still queued or running from a previous run
+		if _, pending := s.deletions.LoadOrStore(user.ID, true); pending {
+			continue
+		}
+
+		if err := s.queueWorkers.Dispatch(func() {
+			defer s.deletions.Delete(user.ID)
+			logbuch.Info("deleting user '%s' after grace period has passed", user.ID)
+			if err := s.userSrvc.Delete(&user); err != nil {
+				config.Log().Error("failed to delete user '%s', %v", user.ID, err)
 			}
-		})
+		}); err != nil {
+			s.deletions.Delete(user.ID)
+			config.Log().Error("failed to dispatch deletion of user '%s', %v", user.ID, err)
+		}
 	}
 }
 
+// initRetentionConfirmation takes the retention period configured when first started as confirmed, so that existing setups keep being cleaned up without any intervention
+func (s *HousekeepingService) initRetentionConfirmation() {
+	if _, err := s.keyValueSrvc.GetString(config.KeyDataRetentionConfirmed); err != nil {
+		if err := s.storeConfirmedRetention(s.config.App.DataRetentionMonths); err != nil {
+			config.Log().Error("failed to store confirmed data retention period, %v", err)
+		}
+		return
+	}
+
+	if configured, confirmed := s.config.App.DataRetentionMonths, s.confirmedRetentionMonths(); models.RetentionRequiresConfirmation(configured, confirmed) {
+		logbuch.Warn("data retention period was shortened from %d to %d months, data cleanups are paused until an admin reviews and confirms the impact (see /api/admin/retention/impact)", confirmed, configured)
+	}
+}
+
+func (s *HousekeepingService) confirmedRetentionMonths() int {
+	months, err := strconv.Atoi(s.keyValueSrvc.MustGetString(config.KeyDataRetentionConfirmed).Value)
+	if err != nil {
+		return 0
+	}
+	return months
+}
+
+func (s *HousekeepingService) storeConfirmedRetention(months int) error {
+	return s.keyValueSrvc.PutString(&models.KeyStringValue{
+		Key:   config.KeyDataRetentionConfirmed,
+		Value: strconv.Itoa(months),
+	})
+}
+
+// countDataBefore returns the number of heartbeats and summaries older than the given time per user
+func (s *HousekeepingService) countDataBefore(t time.Time) (map[string]int64, map[string]int64, error) {
+	heartbeatCounts, err := s.heartbeatSrvc.CountByUsersBefore(t)
+	if err != nil {
+		return nil, nil, err
+	}
+	summaryCounts, err := s.summarySrvc.CountByUsersBefore(t)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	heartbeats, summaries := make(map[string]int64, len(heartbeatCounts)), make(map[string]int64, len(summaryCounts))
+	for _, c := range heartbeatCounts {
+		heartbeats[c.User] = c.Count
+	}
+	for _, c := range summaryCounts {
+		summaries[c.User] = c.Count
+	}
+	return heartbeats, summaries, nil
+}
+
+func mergeKeys(maps ...map[string]int64) map[string]bool {
+	keys := make(map[string]bool)
+	for _, m := range maps {
+		for k := range m {
+			keys[k] = true
+		}
+	}
+	return keys
+}
+
 // individual scheduling functions
 
 func (s *HousekeepingService) scheduleDataCleanups() {
@@ -130,12 +478,32 @@ func (s *HousekeepingService) scheduleDataCleanups() {
 
 	logbuch.Info("scheduling data cleanup")
 
-	_, err := s.queueDefault.DispatchCron(s.runCleanData, s.config.App.DataCleanupTime)
-	if err != nil {
+	if err := s.cleanupCrons.Schedule(s.runCleanData, config.Get().App.DataCleanupTime); err != nil {
 		config.Log().Error("failed to dispatch data cleanup jobs, %v", err)
 	}
 }
 
+func (s *HousekeepingService) scheduleInactiveUserCleanups() {
+	if s.config.App.InactiveCleanupDays <= 0 {
+		return
+	}
+
+	logbuch.Info("scheduling inactive user cleanup")
+
+	if err := s.inactiveCrons.Schedule(s.runCleanInactiveUsers, config.Get().App.DataCleanupTime); err != nil {
+		config.Log().Error("failed to dispatch inactive user cleanup jobs, %v", err)
+	}
+}
+
+func (s *HousekeepingService) scheduleAccountDeletions() {
+	logbuch.Info("scheduling account deletions")
+
+	_, err := s.queueDefault.DispatchEvery(s.jobLockSrvc.Exclusive("account_deletions", 50*time.Minute, s.runDeleteScheduledUsers), 1*time.Hour)
+	if err != nil {
+		config.Log().Error("failed to dispatch account deletion jobs, %v", err)
+	}
+}
+
 func (s *HousekeepingService) scheduleProjectStatsCacheWarming() {
 	logbuch.Info("scheduling project stats cache pre-warming")
 
@@ -149,3 +517,24 @@ func (s *HousekeepingService) scheduleProjectStatsCacheWarming() {
 		config.Log().Error("failed to dispatch pre-warming project stats cache, %v", err)
 	}
 }
+
+func (s *HousekeepingService) schedulePartitionMaintenance() {
+	if !s.config.Db.IsPartitioned() {
+		return
+	}
+
+	logbuch.Info("scheduling heartbeat partition maintenance")
+
+	createPartitions := func() {
+		if err := s.CreateHeartbeatPartitions(); err != nil {
+			config.Log().Error("failed to create heartbeat partitions, %v", err)
+		}
+	}
+
+	if _, err := s.queueDefault.DispatchEvery(createPartitions, 24*time.Hour); err != nil {
+		config.Log().Error("failed to dispatch heartbeat partition maintenance jobs, %v", err)
+	}
+	if err := s.queueDefault.Dispatch(createPartitions); err != nil {
+		config.Log().Error("failed to dispatch heartbeat partition maintenance jobs, %v", err)
+	}
+}
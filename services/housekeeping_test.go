@@ -0,0 +1,181 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type HousekeepingServiceTestSuite struct {
+	suite.Suite
+	HeartbeatService *mocks.HeartbeatServiceMock
+}
+
+func (suite *HousekeepingServiceTestSuite) BeforeTest(suiteName, testName string) {
+	config.Set(config.Empty())
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.HeartbeatService.On("DropChunksBefore", mock.Anything).Return(nil)
+	suite.HeartbeatService.On("DropPartitionsBefore", mock.Anything).Return(nil)
+	suite.HeartbeatService.On("CreatePartitions", mock.Anything, mock.Anything).Return(nil)
+}
+
+func TestHousekeepingServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(HousekeepingServiceTestSuite))
+}
+
+func (suite *HousekeepingServiceTestSuite) TestHousekeepingService_DropExpiredHeartbeats() {
+	config.Get().App.DataRetentionMonths = 3
+	config.Get().Db.Timescale = true
+
+	sut := NewHousekeepingService(nil, suite.HeartbeatService, nil, nil, nil, nil, nil)
+
+	assert.Nil(suite.T(), sut.DropExpiredHeartbeats())
+	suite.HeartbeatService.AssertCalled(suite.T(), "DropChunksBefore", mock.MatchedBy(func(t time.Time) bool {
+		expected := time.Now().AddDate(0, -3, 0)
+		return t.Before(expected.Add(time.Minute)) && t.After(expected.Add(-time.Minute))
+	}))
+}
+
+func (suite *HousekeepingServiceTestSuite) TestHousekeepingService_DropExpiredHeartbeats_NoRetention() {
+	config.Get().App.DataRetentionMonths = -1
+
+	sut := NewHousekeepingService(nil, suite.HeartbeatService, nil, nil, nil, nil, nil)
+
+	assert.Nil(suite.T(), sut.DropExpiredHeartbeats())
+	suite.HeartbeatService.AssertNotCalled(suite.T(), "DropChunksBefore", mock.Anything)
+}
+
+func (suite *HousekeepingServiceTestSuite) TestHousekeepingService_DropExpiredHeartbeats_DryRun() {
+	config.Get().App.DataRetentionMonths = 3
+	config.Get().App.DataCleanupDryRun = true
+
+	sut := NewHousekeepingService(nil, suite.HeartbeatService, nil, nil, nil, nil, nil)
+
+	assert.Nil(suite.T(), sut.DropExpiredHeartbeats())
+	suite.HeartbeatService.AssertNotCalled(suite.T(), "DropChunksBefore", mock.Anything)
+}
+
+func (suite *HousekeepingServiceTestSuite) TestHousekeepingService_DropExpiredHeartbeats_Partitioned() {
+	config.Get().App.DataRetentionMonths = 3
+	config.Get().Db.Partitioning = true
+
+	sut := NewHousekeepingService(nil, suite.HeartbeatService, nil, nil, nil, nil, nil)
+
+	assert.Nil(suite.T(), sut.DropExpiredHeartbeats())
+	suite.HeartbeatService.AssertNotCalled(suite.T(), "DropChunksBefore", mock.Anything)
+	suite.HeartbeatService.AssertCalled(suite.T(), "DropPartitionsBefore", mock.Anything)
+}
+
+func (suite *HousekeepingServiceTestSuite) TestHousekeepingService_CreateHeartbeatPartitions() {
+	sut := NewHousekeepingService(nil, suite.HeartbeatService, nil, nil, nil, nil, nil)
+
+	assert.Nil(suite.T(), sut.CreateHeartbeatPartitions())
+	suite.HeartbeatService.AssertCalled(suite.T(), "CreatePartitions", mock.Anything, mock.MatchedBy(func(t time.Time) bool {
+		return t.After(time.Now().AddDate(0, heartbeatPartitionsAhead, -1))
+	}))
+}
+
+func (suite *HousekeepingServiceTestSuite) TestHousekeepingService_GetRetentionImpact() {
+	config.Get().App.DataRetentionMonths = 3
+	config.Get().App.DataCleanupTime = "0 0 6 * * 0"
+	config.Get().Subscriptions.Enabled = true
+
+	userService := new(mocks.UserServiceMock)
+	summaryService := new(mocks.SummaryServiceMock)
+	keyValueService := new(mocks.KeyValueServiceMock)
+
+	subscribedUntil := models.CustomTime(time.Now().Add(24 * time.Hour))
+	userService.On("GetAll").Return([]*models.User{{ID: "user1"}, {ID: "user2"}, {ID: "user3", SubscribedUntil: &subscribedUntil}}, nil)
+	keyValueService.On("MustGetString", config.KeyDataRetentionConfirmed).Return(&models.KeyStringValue{Key: config.KeyDataRetentionConfirmed, Value: "6"})
+
+	nextRun, _ := config.Get().App.NextDataCleanup(time.Now())
+	cutoff, confirmedCutoff := nextRun.AddDate(0, -3, 0), nextRun.AddDate(0, -6, 0)
+	suite.HeartbeatService.On("CountByUsersBefore", cutoff).Return([]*models.CountByUser{{User: "user1", Count: 100}, {User: "user2", Count: 10}, {User: "user3", Count: 50}}, nil)
+	suite.HeartbeatService.On("CountByUsersBefore", confirmedCutoff).Return([]*models.CountByUser{{User: "user1", Count: 40}, {User: "user3", Count: 20}}, nil)
+	summaryService.On("CountByUsersBefore", cutoff).Return([]*models.CountByUser{{User: "user1", Count: 3}, {User: "user3", Count: 2}}, nil)
+	summaryService.On("CountByUsersBefore", confirmedCutoff).Return([]*models.CountByUser{{User: "user1", Count: 1}}, nil)
+
+	sut := NewHousekeepingService(userService, suite.HeartbeatService, summaryService, nil, keyValueService, nil, nil)
+
+	impact, err := sut.GetRetentionImpact()
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), impact.ConfirmationRequired)
+	assert.Equal(suite.T(), 6, impact.ConfirmedRetentionMonths)
+	assert.True(suite.T(), impact.Cutoff.T().Equal(cutoff))
+	assert.Equal(suite.T(), int64(110), impact.Heartbeats) // subscribed users are exempt
+	assert.Equal(suite.T(), int64(3), impact.Summaries)
+	assert.Len(suite.T(), impact.Users, 2)
+	assert.Equal(suite.T(), &models.UserRetentionImpact{User: "user1", Heartbeats: 100, Summaries: 3, AdditionalHeartbeats: 60, AdditionalSummaries: 2}, impact.Users[0])
+	assert.Equal(suite.T(), &models.UserRetentionImpact{User: "user2", Heartbeats: 10, AdditionalHeartbeats: 10}, impact.Users[1])
+}
+
+func (suite *HousekeepingServiceTestSuite) TestHousekeepingService_RunCleanData_Unconfirmed() {
+	config.Get().App.DataRetentionMonths = 3
+
+	userService := new(mocks.UserServiceMock)
+	keyValueService := new(mocks.KeyValueServiceMock)
+	keyValueService.On("MustGetString", config.KeyDataRetentionConfirmed).Return(&models.KeyStringValue{Key: config.KeyDataRetentionConfirmed, Value: "-1"})
+
+	sut := NewHousekeepingService(userService, suite.HeartbeatService, nil, nil, keyValueService, nil, nil)
+	sut.runCleanData()
+
+	userService.AssertNotCalled(suite.T(), "GetAll")
+	keyValueService.AssertNotCalled(suite.T(), "PutString", mock.Anything)
+
+	assert.ErrorIs(suite.T(), sut.ConfirmRetention(6), ErrRetentionMismatch)
+
+	keyValueService.On("PutString", mock.Anything).Return(nil)
+	assert.Nil(suite.T(), sut.ConfirmRetention(3))
+	keyValueService.AssertCalled(suite.T(), "PutString", &models.KeyStringValue{Key: config.KeyDataRetentionConfirmed, Value: "3"})
+}
+
+func (suite *HousekeepingServiceTestSuite) TestHousekeepingService_RunCleanData_Extended() {
+	config.Get().App.DataRetentionMonths = 12
+
+	userService := new(mocks.UserServiceMock)
+	keyValueService := new(mocks.KeyValueServiceMock)
+	keyValueService.On("MustGetString", config.KeyDataRetentionConfirmed).Return(&models.KeyStringValue{Key: config.KeyDataRetentionConfirmed, Value: "6"})
+	keyValueService.On("PutString", mock.Anything).Return(nil)
+	userService.On("GetAll").Return([]*models.User{}, nil)
+
+	sut := NewHousekeepingService(userService, suite.HeartbeatService, nil, nil, keyValueService, nil, nil)
+	sut.runCleanData()
+
+	// extending the retention period deletes less data, so it doesn't need to be confirmed
+	keyValueService.AssertCalled(suite.T(), "PutString", &models.KeyStringValue{Key: config.KeyDataRetentionConfirmed, Value: "12"})
+	userService.AssertCalled(suite.T(), "GetAll")
+}
+
+func (suite *HousekeepingServiceTestSuite) TestHousekeepingService_CleanUserDataBefore_Anonymize() {
+	config.Get().App.DataRetentionMonths = 3
+	config.Get().App.DataRetentionMode = config.RetentionModeAnonymize
+	config.Get().Db.Timescale = true
+
+	user := &models.User{ID: "user1"}
+	before := time.Now().AddDate(0, -3, 0)
+
+	summaryService := new(mocks.SummaryServiceMock)
+	summaryService.On("AnonymizeByUserBefore", user.ID, before).Return(nil)
+	suite.HeartbeatService.On("AnonymizeByUserBefore", user, before).Return(int64(42), nil)
+	machineService := new(mocks.MachineServiceMock)
+	machineService.On("AnonymizeByUserBefore", user.ID, before).Return(int64(2), nil)
+
+	sut := NewHousekeepingService(nil, suite.HeartbeatService, summaryService, machineService, nil, nil, nil)
+
+	assert.Nil(suite.T(), sut.CleanUserDataBefore(user, before))
+	suite.HeartbeatService.AssertCalled(suite.T(), "AnonymizeByUserBefore", user, before)
+	suite.HeartbeatService.AssertNotCalled(suite.T(), "DeleteByUserBefore", mock.Anything, mock.Anything)
+	summaryService.AssertCalled(suite.T(), "AnonymizeByUserBefore", user.ID, before)
+	summaryService.AssertNotCalled(suite.T(), "DeleteByUserBefore", mock.Anything, mock.Anything)
+	machineService.AssertCalled(suite.T(), "AnonymizeByUserBefore", user.ID, before)
+	machineService.AssertNotCalled(suite.T(), "DeleteByUserBefore", mock.Anything, mock.Anything)
+
+	// heartbeats must not be dropped in bulk, as they're kept around in anonymized form
+	assert.Nil(suite.T(), sut.DropExpiredHeartbeats())
+	suite.HeartbeatService.AssertNotCalled(suite.T(), "DropChunksBefore", mock.Anything)
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services/imports"
+)
+
+const jobTypeWakatimeImport = "wakatime_import"
+
+type wakatimeImportJob struct {
+	UserID            string    `json:"user_id"`
+	From              time.Time `json:"from"`
+	To                time.Time `json:"to"`
+	UseLegacyImporter bool      `json:"use_legacy_importer"`
+}
+
+// ImportService runs imports of users' heartbeats from wakatime (or a compatible service) in the background
+type ImportService struct {
+	config           *config.Config
+	userSrvc         IUserService
+	heartbeatSrvc    IHeartbeatService
+	summarySrvc      ISummaryService
+	aggregationSrvc  IAggregationService
+	keyValueSrvc     IKeyValueService
+	mailSrvc         IMailService
+	notificationSrvc INotificationService
+	queueSrvc        IPersistentQueueService
+}
+
+func NewImportService(userService IUserService, heartbeatService IHeartbeatService, summaryService ISummaryService, aggregationService IAggregationService, keyValueService IKeyValueService, mailService IMailService, notificationService INotificationService, persistentQueueService IPersistentQueueService) *ImportService {
+	srv := &ImportService{
+		config:           config.Get(),
+		userSrvc:         userService,
+		heartbeatSrvc:    heartbeatService,
+		summarySrvc:      summaryService,
+		aggregationSrvc:  aggregationService,
+		keyValueSrvc:     keyValueService,
+		mailSrvc:         mailService,
+		notificationSrvc: notificationService,
+		queueSrvc:        persistentQueueService,
+	}
+
+	// the importers themselves dispatch their fetching to the imports queue, so the waiting import job must not block it
+	persistentQueueService.Register(jobTypeWakatimeImport, config.GetQueue(config.QueueImportJobs), srv.runWakatimeImportJob)
+
+	return srv
+}
+
+// ImportWakatime dispatches an import of the user's wakatime heartbeats, which only covers heartbeats newer than the latest one of any previous import
+func (srv *ImportService) ImportWakatime(user *models.User, useLegacyImporter bool) error {
+	job := &wakatimeImportJob{UserID: user.ID, To: time.Now(), UseLegacyImporter: useLegacyImporter}
+	if latest, err := srv.heartbeatSrvc.GetLatestByOriginAndUser(imports.OriginWakatime, user); latest != nil && err == nil {
+		job.From = latest.Time.T()
+	}
+	return srv.queueSrvc.Dispatch(jobTypeWakatimeImport, job)
+}
+
+// RunWakatimeImport imports the user's wakatime heartbeats right away, other than ImportWakatime, and only returns once summaries have been regenerated, e.g. for the admin cli
+// returns the number of heartbeats downloaded
+func (srv *ImportService) RunWakatimeImport(user *models.User, useLegacyImporter bool) (int, error) {
+	from, to := time.Time{}, time.Now()
+	if latest, err := srv.heartbeatSrvc.GetLatestByOriginAndUser(imports.OriginWakatime, user); latest != nil && err == nil {
+		from = latest.Time.T()
+	}
+
+	count, err := srv.importWakatime(user, from, to, useLegacyImporter)
+	if err != nil {
+		return count, err
+	}
+
+	if from.IsZero() {
+		if first, err := srv.heartbeatSrvc.GetFirstByUsers(); err == nil {
+			for _, f := range first {
+				if f.User == user.ID {
+					from = f.Time.T()
+				}
+			}
+		}
+	}
+	if from.IsZero() {
+		return count, nil // nothing imported
+	}
+	return count, srv.aggregationSrvc.RegenerateSummaries(user, from.In(user.TZ()), to.In(user.TZ()))
+}
+
+func (srv *ImportService) runWakatimeImportJob(payload []byte) error {
+	var job wakatimeImportJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+
+	user, err := srv.userSrvc.GetUserById(job.UserID)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	countBefore, _ := srv.heartbeatSrvc.CountByUser(user)
+
+	if _, err := srv.importWakatime(user, job.From, job.To, job.UseLegacyImporter); err != nil {
+		return err
+	}
+
+	countAfter, _ := srv.heartbeatSrvc.CountByUser(user)
+
+	logbuch.Info("clearing summaries for user '%s'", user.ID)
+	if err := srv.summarySrvc.DeleteByUser(user.ID); err != nil {
+		config.Log().Error("failed to clear summaries: %v", err)
+	} else if err := srv.aggregationSrvc.AggregateSummaries(datastructure.NewSet(user.ID)); err != nil {
+		config.Log().Error("failed to regenerate summaries: %v", err)
+	}
+
+	if user.Email != "" {
+		duration, numImported := time.Now().Sub(start), int(countAfter-countBefore)
+		text := fmt.Sprintf("Your import of WakaTime data has finished after %.0f seconds (%d new heartbeats imported).", duration.Seconds(), numImported)
+		if err := srv.notificationSrvc.Notify(user, "Data Import Finished", text, func() error {
+			return srv.mailSrvc.SendImportNotification(user, duration, numImported)
+		}); err != nil {
+			config.Log().Error("failed to send import notification mail to %s - %v", user.ID, err)
+		} else {
+			logbuch.Info("sent import notification mail to %s", user.ID)
+		}
+	}
+
+	return nil
+}
+
+// importWakatime downloads the user's wakatime heartbeats between from and to and inserts them in batches, leaving summaries as they are
+func (srv *ImportService) importWakatime(user *models.User, from, to time.Time, useLegacyImporter bool) (int, error) {
+	importer := imports.NewWakatimeImporter(user.WakatimeApiKey, useLegacyImporter)
+
+	countBefore, _ := srv.heartbeatSrvc.CountByUser(user)
+
+	stream, err := importer.Import(user, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("wakatime import for user '%s' failed - %v", user.ID, err)
+	}
+
+	// import successful
+	srv.keyValueSrvc.PutString(&models.KeyStringValue{
+		Key:   fmt.Sprintf("%s_%s", config.KeyLastImportSuccess, user.ID),
+		Value: time.Now().Format(time.RFC822),
+	})
+
+	count := 0
+	batch := make([]*models.Heartbeat, 0, srv.config.App.ImportBatchSize)
+
+	insert := func(batch []*models.Heartbeat) {
+		if err := srv.heartbeatSrvc.InsertBatch(batch); err != nil {
+			logbuch.Warn("failed to insert imported heartbeat, already existing? - %v", err)
+		}
+	}
+
+consume:
+	for {
+		select {
+		case hb, ok := <-stream:
+			if !ok {
+				break consume
+			}
+			count++
+			batch = append(batch, hb)
+
+			if len(batch) == srv.config.App.ImportBatchSize {
+				insert(batch)
+				batch = make([]*models.Heartbeat, 0, srv.config.App.ImportBatchSize)
+			}
+		case <-config.ShutdownSignal():
+			// keep what was downloaded so far, the resumed import will skip these heartbeats as duplicates
+			if len(batch) > 0 {
+				insert(batch)
+			}
+			logbuch.Info("interrupted wakatime import for user '%s' after %d heartbeats", user.ID, count)
+			return count, ErrJobInterrupted
+		}
+	}
+	if len(batch) > 0 {
+		insert(batch)
+	}
+
+	countAfter, _ := srv.heartbeatSrvc.CountByUser(user)
+	logbuch.Info("downloaded %d heartbeats for user '%s' (%d actually imported)", count, user.ID, countAfter-countBefore)
+
+	if !user.HasData {
+		user.HasData = true
+		if _, err := srv.userSrvc.Update(user); err != nil {
+			config.Log().Error("failed to set 'has_data' flag for user %s - %v", user.ID, err)
+		}
+	}
+
+	return count, nil
+}
@@ -28,7 +28,7 @@ type WakatimeDumpImporter struct {
 func NewWakatimeDumpImporter(apiKey string) *WakatimeDumpImporter {
 	return &WakatimeDumpImporter{
 		apiKey:     apiKey,
-		httpClient: &http.Client{Timeout: 10 * time.Second},
+		httpClient: config.NewOutboundClient(utils.OutboundPriorityLow, 10*time.Second),
 		queue:      config.GetQueue(config.QueueImports),
 	}
 }
@@ -93,7 +93,7 @@ func (w *WakatimeDumpImporter) Import(user *models.User, minFrom time.Time, maxT
 
 		// download
 		req, _ := http.NewRequest(http.MethodGet, dump.DownloadUrl, nil)
-		res, err := utils.RaiseForStatus((&http.Client{Timeout: 5 * time.Minute}).Do(req))
+		res, err := utils.RaiseForStatus(config.NewOutboundClient(utils.OutboundPriorityLow, 5*time.Minute).Do(req))
 		if err != nil {
 			config.Log().Error("failed to download %s - %v", dump.DownloadUrl, err)
 			return
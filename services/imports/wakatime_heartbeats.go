@@ -38,7 +38,7 @@ type WakatimeHeartbeatsImporter struct {
 func NewWakatimeHeartbeatImporter(apiKey string) *WakatimeHeartbeatsImporter {
 	return &WakatimeHeartbeatsImporter{
 		apiKey:     apiKey,
-		httpClient: &http.Client{Timeout: 10 * time.Second},
+		httpClient: config.NewOutboundClient(utils.OutboundPriorityLow, 10*time.Second),
 		queue:      config.GetQueue(config.QueueImports),
 	}
 }
@@ -54,6 +54,7 @@ func (w *WakatimeHeartbeatsImporter) Import(user *models.User, minFrom time.Time
 		startDate, endDate, err := w.fetchRange(baseUrl)
 		if err != nil {
 			config.Log().Error("failed to fetch date range while importing wakatime heartbeats for user '%s' - %v", user.ID, err)
+			close(out)
 			return
 		}
 
@@ -70,6 +71,7 @@ func (w *WakatimeHeartbeatsImporter) Import(user *models.User, minFrom time.Time
 		} else if strings.Contains(baseUrl, "wakatime.com") {
 			// when importing from wakatime, resolving user agents is mandatorily required
 			config.Log().Error("failed to fetch user agents while importing wakatime heartbeats for user '%s' - %v", user.ID, err)
+			close(out)
 			return
 		}
 
@@ -79,10 +81,15 @@ func (w *WakatimeHeartbeatsImporter) Import(user *models.User, minFrom time.Time
 		} else if strings.Contains(baseUrl, "wakatime.com") {
 			// when importing from wakatime, resolving machine names is mandatorily required
 			config.Log().Error("failed to fetch machine names while importing wakatime heartbeats for user '%s' - %v", user.ID, err)
+			close(out)
 			return
 		}
 
 		days := generateDays(startDate, endDate)
+		if len(days) == 0 {
+			close(out)
+			return
+		}
 
 		c := atomic.NewUint32(uint32(len(days)))
 		wp := pond.New(maxWorkers, 0)
@@ -15,7 +15,7 @@ import (
 // https://wakatime.com/api/v1/users/current/machine_names
 // https://pastr.de/p/v58cv0xrupp3zvyyv8o6973j
 func fetchMachineNames(baseUrl, apiKey string) (map[string]*wakatime.MachineEntry, error) {
-	httpClient := &http.Client{Timeout: 10 * time.Second}
+	httpClient := config.NewOutboundClient(utils.OutboundPriorityLow, 10*time.Second)
 
 	machines := make(map[string]*wakatime.MachineEntry)
 
@@ -53,7 +53,7 @@ func fetchMachineNames(baseUrl, apiKey string) (map[string]*wakatime.MachineEntr
 // https://wakatime.com/api/v1/users/current/user_agents
 // https://pastr.de/p/05k5do8q108k94lic4lfl3pc
 func fetchUserAgents(baseUrl, apiKey string) (map[string]*wakatime.UserAgentEntry, error) {
-	httpClient := &http.Client{Timeout: 10 * time.Second}
+	httpClient := config.NewOutboundClient(utils.OutboundPriorityLow, 10*time.Second)
 
 	userAgents := make(map[string]*wakatime.UserAgentEntry)
 
@@ -136,5 +136,7 @@ func mapHeartbeat(
 		Origin:          OriginWakatime,
 		OriginId:        entry.Id,
 		CreatedAt:       models.CustomTime(entry.CreatedAt),
+		SchemaVersion:   models.HeartbeatSchemaVersion,
+		Extras:          entry.Extras,
 	}).Hashed()
 }
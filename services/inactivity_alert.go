@@ -0,0 +1,107 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+// checked hourly, so that alerts go out early in the morning for users in any time zone
+const checkInactivityAlertsEvery = 1 * time.Hour
+
+// InactivityAlertService notifies users who opted for it when no heartbeats arrived for a number of workdays, which usually indicates a silently broken plugin setup
+type InactivityAlertService struct {
+	config           *config.Config
+	userService      IUserService
+	heartbeatService IHeartbeatService
+	keyValueService  IKeyValueService
+	mailService      IMailService
+	notifySrvc       INotificationService
+	queueDefault     *artifex.Dispatcher
+	queueMails       *artifex.Dispatcher
+}
+
+func NewInactivityAlertService(userService IUserService, heartbeatService IHeartbeatService, keyValueService IKeyValueService, mailService IMailService, notificationService INotificationService) *InactivityAlertService {
+	return &InactivityAlertService{
+		config:           config.Get(),
+		userService:      userService,
+		heartbeatService: heartbeatService,
+		keyValueService:  keyValueService,
+		mailService:      mailService,
+		notifySrvc:       notificationService,
+		queueDefault:     config.GetDefaultQueue(),
+		queueMails:       config.GetQueue(config.QueueMails),
+	}
+}
+
+func (srv *InactivityAlertService) Schedule() {
+	if !config.Get().Mail.Enabled {
+		return
+	}
+
+	logbuch.Info("scheduling inactivity alerts")
+	if _, err := srv.queueDefault.DispatchEvery(srv.CheckAll, checkInactivityAlertsEvery); err != nil {
+		config.Log().Error("failed to schedule inactivity alert jobs, %v", err)
+	}
+}
+
+// CheckAll alerts every user, who has an alert rule configured and hasn't sent any heartbeats for at least that many workdays
+// users are alerted only once per gap in activity, i.e. not again until a new heartbeat has arrived
+func (srv *InactivityAlertService) CheckAll() {
+	users, err := srv.userService.GetAll()
+	if err != nil {
+		config.Log().Error("failed to fetch users for inactivity alerts, %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, u := range users {
+		if u.InactivityAlertDays <= 0 || u.Email == "" || !u.IsActive() {
+			continue
+		}
+
+		latest, err := srv.heartbeatService.GetLatestByUser(u)
+		if err != nil || latest == nil {
+			continue // never got any heartbeats at all, so there's nothing to have broken
+		}
+
+		missed := u.MissedWorkdays(latest.Time.T(), now)
+		if missed < u.InactivityAlertDays {
+			continue
+		}
+
+		key := fmt.Sprintf("%s_%s", config.KeyInactivityAlert, u.ID)
+		fingerprint := latest.Time.T().UTC().Format(time.RFC3339)
+		if kv, err := srv.keyValueService.GetString(key); err == nil && kv.Value == fingerprint {
+			continue
+		}
+
+		srv.sendInactivityAlertScheduled(u, latest.Time.T(), missed, key, fingerprint)
+	}
+}
+
+func (srv *InactivityAlertService) sendInactivityAlertScheduled(user *models.User, lastHeartbeat time.Time, missed int, key, fingerprint string) {
+	u := *user
+	srv.queueMails.Dispatch(func() {
+		logbuch.Info("sending inactivity alert mail to %s (%d workdays without activity)", u.ID, missed)
+
+		text := fmt.Sprintf("We haven't received any coding activity from you for %d workdays (last heartbeat at %s). If you've been coding in the meantime, please check your plugin setup.", missed, lastHeartbeat.In(u.TZ()).Format(config.SimpleDateTimeFormat))
+		if err := srv.notifySrvc.Notify(&u, "No coding activity received", text, func() error {
+			return srv.mailService.SendInactivityAlert(&u, lastHeartbeat, missed)
+		}); err != nil {
+			config.Log().Error("failed to send inactivity alert mail to user '%s', %v", u.ID, err)
+			return
+		}
+
+		if err := srv.keyValueService.PutString(&models.KeyStringValue{
+			Key:   key,
+			Value: fingerprint,
+		}); err != nil {
+			config.Log().Error("failed to update inactivity alert status for user '%s', %v", u.ID, err)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+// InfluxExportService periodically writes users' daily coding time, in total and per language and project, to influxdb via line protocol
+// points of past days are simply re-written, which is idempotent, as points are identified by measurement, tags and time
+type InfluxExportService struct {
+	config      *config.Config
+	userSrvc    IUserService
+	summarySrvc ISummaryService
+	httpClient  *http.Client
+	crons       *cronJobs
+}
+
+func NewInfluxExportService(userService IUserService, summaryService ISummaryService, jobLockService IJobLockService) *InfluxExportService {
+	return &InfluxExportService{
+		config:      config.Get(),
+		userSrvc:    userService,
+		summarySrvc: summaryService,
+		httpClient:  config.NewOutboundClient(utils.OutboundPriorityLow, 30*time.Second),
+		crons:       newCronJobs("influx_export", config.GetDefaultQueue(), jobLockService),
+	}
+}
+
+func (srv *InfluxExportService) Schedule() {
+	if !srv.config.Integrations.Influx.IsEnabled() {
+		return
+	}
+
+	logbuch.Info("scheduling influxdb export")
+
+	if err := srv.crons.Schedule(srv.runExport, srv.config.Integrations.Influx.Time); err != nil {
+		config.Log().Error("failed to schedule influxdb export, %v", err)
+	}
+}
+
+// Export writes the points of the configured number of past days (in the respective user's time zone) for all configured users, one request per user
+func (srv *InfluxExportService) Export() error {
+	users, err := srv.getUsers()
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, user := range users {
+		points, err := srv.UserPoints(user)
+		if err != nil {
+			config.Log().Error("failed to get influxdb points for user '%s' - %v", user.ID, err)
+			failed++
+			continue
+		}
+		if err := srv.write(points); err != nil {
+			config.Log().Error("failed to write influxdb points for user '%s' - %v", user.ID, err)
+			failed++
+		}
+	}
+
+	logbuch.Info("exported summaries of %d users to influxdb (%d failed)", len(users)-failed, failed)
+	return nil
+}
+
+// UserPoints returns the user's total, per-language and per-project seconds for each of the past days
+func (srv *InfluxExportService) UserPoints(user *models.User) ([]*utils.InfluxPoint, error) {
+	measurement := srv.config.Integrations.Influx.Measurement
+	today := utils.BeginOfToday(user.TZ())
+
+	points := make([]*utils.InfluxPoint, 0)
+	for i := srv.config.Integrations.Influx.Days; i > 0; i-- {
+		from := today.AddDate(0, 0, -i)
+		to := from.AddDate(0, 0, 1)
+
+		summary, err := srv.summarySrvc.Aliased(from, to, user, srv.summarySrvc.Retrieve, nil, false)
+		if err != nil {
+			return nil, err
+		}
+
+		points = append(points, &utils.InfluxPoint{
+			Measurement: measurement + "_total",
+			Tags:        map[string]string{"user": user.ID},
+			Fields:      map[string]int64{"seconds": int64(summary.TotalTime().Seconds())},
+			Time:        from,
+		})
+		for _, item := range summary.Languages {
+			points = append(points, &utils.InfluxPoint{
+				Measurement: measurement + "_language",
+				Tags:        map[string]string{"user": user.ID, "language": item.Key},
+				Fields:      map[string]int64{"seconds": int64(item.TotalFixed().Seconds())},
+				Time:        from,
+			})
+		}
+		for _, item := range summary.Projects {
+			points = append(points, &utils.InfluxPoint{
+				Measurement: measurement + "_project",
+				Tags:        map[string]string{"user": user.ID, "project": item.Key},
+				Fields:      map[string]int64{"seconds": int64(item.TotalFixed().Seconds())},
+				Time:        from,
+			})
+		}
+	}
+
+	return points, nil
+}
+
+func (srv *InfluxExportService) runExport() {
+	if err := srv.Export(); err != nil {
+		config.Log().Error("failed to export summaries to influxdb - %v", err)
+	}
+}
+
+func (srv *InfluxExportService) getUsers() ([]*models.User, error) {
+	userIds := srv.config.Integrations.Influx.GetUsers()
+	if len(userIds) == 0 {
+		return srv.userSrvc.GetActive(false)
+	}
+
+	users := make([]*models.User, 0, len(userIds))
+	for _, id := range userIds {
+		user, err := srv.userSrvc.GetUserById(id)
+		if err != nil {
+			config.Log().Warn("user '%s' configured for influxdb export not found", id)
+			continue
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (srv *InfluxExportService) write(points []*utils.InfluxPoint) error {
+	req, err := http.NewRequest(http.MethodPost, srv.config.Integrations.Influx.WriteUrl(), bytes.NewBuffer(utils.EncodeInfluxPoints(points)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if token := srv.config.Integrations.Influx.Token; token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+
+	res, err := utils.RaiseForStatus(srv.httpClient.Do(req))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
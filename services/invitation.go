@@ -0,0 +1,102 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+const invitationCodeBytes = 12
+
+var ErrInvitationInvalid = errors.New("invitation code is invalid, expired or used up")
+
+// InvitationService manages invitation codes, which allow people to sign up on instances with public registration disabled
+type InvitationService struct {
+	config     *config.Config
+	repository repositories.IInvitationRepository
+}
+
+func NewInvitationService(invitationRepository repositories.IInvitationRepository) *InvitationService {
+	return &InvitationService{
+		config:     config.Get(),
+		repository: invitationRepository,
+	}
+}
+
+func (srv *InvitationService) GetAll() ([]*models.Invitation, error) {
+	invitations, err := srv.repository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range invitations {
+		srv.populateSignupUrl(i)
+	}
+	return invitations, nil
+}
+
+func (srv *InvitationService) GetById(id uint) (*models.Invitation, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *InvitationService) GetByCode(code string) (*models.Invitation, error) {
+	if code == "" {
+		return nil, ErrInvitationInvalid
+	}
+	return srv.repository.GetByCode(code)
+}
+
+// Create persists a new invitation with a randomly generated code
+func (srv *InvitationService) Create(invitation *models.Invitation) (*models.Invitation, error) {
+	randomBytes := make([]byte, invitationCodeBytes)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, err
+	}
+	invitation.Code = hex.EncodeToString(randomBytes)
+	invitation.Uses = 0
+
+	result, err := srv.repository.Insert(invitation)
+	if err != nil {
+		return nil, err
+	}
+	srv.populateSignupUrl(result)
+	return result, nil
+}
+
+func (srv *InvitationService) Delete(invitation *models.Invitation) error {
+	if invitation.ID == 0 {
+		return errors.New("no invitation id specified")
+	}
+	return srv.repository.Delete(invitation.ID)
+}
+
+// Redeem consumes one use of the invitation with the given code, fails with ErrInvitationInvalid if it's not (or no longer) usable
+func (srv *InvitationService) Redeem(code string) (*models.Invitation, error) {
+	invitation, err := srv.GetByCode(code)
+	if err != nil || !invitation.IsUsable(time.Now()) {
+		return nil, ErrInvitationInvalid
+	}
+	ok, err := srv.repository.IncrementUses(invitation.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvitationInvalid
+	}
+	invitation.Uses++
+	return invitation, nil
+}
+
+// Release gives back a previously redeemed use, e.g. if the signup failed after all
+func (srv *InvitationService) Release(invitation *models.Invitation) error {
+	return srv.repository.DecrementUses(invitation.ID)
+}
+
+func (srv *InvitationService) populateSignupUrl(invitation *models.Invitation) {
+	invitation.SignupUrl = fmt.Sprintf("%s/signup?invite=%s", srv.config.Server.GetPublicUrl(), invitation.Code)
+}
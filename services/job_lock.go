@@ -0,0 +1,56 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/repositories"
+	uuid "github.com/satori/go.uuid"
+)
+
+// JobLockService makes sure that scheduled jobs are run by only one out of multiple instances sharing the same database
+// every instance keeps scheduling jobs as usual, but only the one to first acquire the job's lease for the current run actually executes it
+type JobLockService struct {
+	config     *config.Config
+	repository repositories.IJobLockRepository
+	instanceId string
+}
+
+func NewJobLockService(jobLockRepo repositories.IJobLockRepository) *JobLockService {
+	hostname, _ := os.Hostname()
+	return &JobLockService{
+		config:     config.Get(),
+		repository: jobLockRepo,
+		instanceId: fmt.Sprintf("%s-%s", hostname, uuid.NewV4().String()[:8]),
+	}
+}
+
+// TryLock attempts to acquire the named job's lease for the given duration and reports whether the job may be run
+// always succeeds if distributed locking is disabled
+func (srv *JobLockService) TryLock(name string, ttl time.Duration) bool {
+	if !srv.config.App.DistributedLocking {
+		return true
+	}
+
+	ok, err := srv.repository.TryAcquire(name, srv.instanceId, time.Now().Add(ttl))
+	if err != nil {
+		config.Log().Error("failed to acquire lock for job '%s' - %v", name, err)
+		return false
+	}
+	if !ok {
+		logbuch.Info("skipping job '%s', because it is run by another instance", name)
+	}
+	return ok
+}
+
+// Exclusive wraps the given job, so that it is only run if its lease could be acquired
+func (srv *JobLockService) Exclusive(name string, ttl time.Duration, run func()) func() {
+	return func() {
+		if srv.TryLock(name, ttl) {
+			run()
+		}
+	}
+}
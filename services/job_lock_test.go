@@ -0,0 +1,79 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/robfig/cron/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type JobLockServiceTestSuite struct {
+	suite.Suite
+	JobLockRepository *mocks.JobLockRepositoryMock
+}
+
+func (suite *JobLockServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+}
+
+func (suite *JobLockServiceTestSuite) BeforeTest(suiteName, testName string) {
+	config.Get().App.DistributedLocking = true
+	suite.JobLockRepository = new(mocks.JobLockRepositoryMock)
+}
+
+func TestJobLockServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(JobLockServiceTestSuite))
+}
+
+func (suite *JobLockServiceTestSuite) TestJobLockService_TryLock() {
+	suite.JobLockRepository.On("TryAcquire", "foo", mock.Anything, mock.Anything).Return(true, nil)
+	suite.JobLockRepository.On("TryAcquire", "bar", mock.Anything, mock.Anything).Return(false, nil)
+	suite.JobLockRepository.On("TryAcquire", "baz", mock.Anything, mock.Anything).Return(false, errors.New(""))
+
+	sut := NewJobLockService(suite.JobLockRepository)
+
+	assert.True(suite.T(), sut.TryLock("foo", time.Minute))
+	assert.False(suite.T(), sut.TryLock("bar", time.Minute))
+	assert.False(suite.T(), sut.TryLock("baz", time.Minute))
+
+	suite.JobLockRepository.AssertCalled(suite.T(), "TryAcquire", "foo", sut.instanceId, mock.MatchedBy(func(until time.Time) bool {
+		return until.After(time.Now().Add(59*time.Second)) && until.Before(time.Now().Add(61*time.Second))
+	}))
+}
+
+func (suite *JobLockServiceTestSuite) TestJobLockService_TryLock_Disabled() {
+	config.Get().App.DistributedLocking = false
+
+	sut := NewJobLockService(suite.JobLockRepository)
+
+	assert.True(suite.T(), sut.TryLock("foo", time.Minute))
+	suite.JobLockRepository.AssertNotCalled(suite.T(), "TryAcquire", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *JobLockServiceTestSuite) TestCronJobs_Guard() {
+	suite.JobLockRepository.On("TryAcquire", "job1", mock.Anything, mock.Anything).Return(true, nil)
+	suite.JobLockRepository.On("TryAcquire", "job2", mock.Anything, mock.Anything).Return(false, nil)
+
+	sut := NewJobLockService(suite.JobLockRepository)
+
+	hourly, _ := cronParser.Parse("0 0 * * * *")
+	daily, _ := cronParser.Parse("0 0 6 * * *")
+
+	var runs int
+	newCronJobs("job1", artifex.NewDispatcher(1, 1), sut).guard(func() { runs++ }, []cron.Schedule{daily, hourly})()
+	newCronJobs("job2", artifex.NewDispatcher(1, 1), sut).guard(func() { runs++ }, []cron.Schedule{daily, hourly})()
+	newCronJobs("job3", artifex.NewDispatcher(1, 1), nil).guard(func() { runs++ }, []cron.Schedule{daily, hourly})()
+	assert.Equal(suite.T(), 2, runs)
+
+	// lease is held until shortly before the earliest next run
+	suite.JobLockRepository.AssertCalled(suite.T(), "TryAcquire", "job1", mock.Anything, mock.MatchedBy(func(until time.Time) bool {
+		return until.Before(time.Now().Add(time.Hour))
+	}))
+}
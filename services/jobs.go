@@ -0,0 +1,127 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/muety/artifex/v2"
+	"github.com/robfig/cron/v3"
+)
+
+// same format as used by artifex
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// userJobs keeps track of the current or most recent background job per user, of which at most one may be pending at a time
+// job state is held in memory only and therefore lost on restart, along with the queued jobs themselves
+type userJobs[J any] struct {
+	jobs      map[string]*J
+	isPending func(*J) bool
+	lock      sync.RWMutex
+}
+
+// newUserJobs creates a new job registry, e.g. newUserJobs((*models.UserExport).IsPending)
+func newUserJobs[J any](isPending func(*J) bool) *userJobs[J] {
+	return &userJobs[J]{jobs: map[string]*J{}, isPending: isPending}
+}
+
+// Start registers the given job as the user's current one, unless another one is still pending
+func (r *userJobs[J]) Start(userId string, job *J) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if j, ok := r.jobs[userId]; ok && r.isPending(j) {
+		return false
+	}
+	r.jobs[userId] = job
+	return true
+}
+
+// Update modifies the given job while holding the lock, so that concurrent reads always see a consistent state
+func (r *userJobs[J]) Update(job *J, update func(*J)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	update(job)
+}
+
+// Get returns a snapshot of the user's current or most recent job
+func (r *userJobs[J]) Get(userId string) (*J, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if j, ok := r.jobs[userId]; ok {
+		jobCopy := *j
+		return &jobCopy, true
+	}
+	return nil, false
+}
+
+func (r *userJobs[J]) Delete(userId string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.jobs, userId)
+}
+
+// cronJobs keeps track of a service's cron jobs, so that they can be re-registered once their schedule got changed by a config reload
+// if given a lock service, every run is guarded by a lease on the job's name, so that only one out of multiple instances executes it
+type cronJobs struct {
+	name  string
+	queue *artifex.Dispatcher
+	locks IJobLockService
+	jobs  []*artifex.DispatchCron
+	lock  sync.Mutex
+}
+
+func newCronJobs(name string, queue *artifex.Dispatcher, locks IJobLockService) *cronJobs {
+	return &cronJobs{name: name, queue: queue, locks: locks}
+}
+
+// Schedule stops all previously scheduled jobs and schedules the given function for every one of the given cron expressions instead
+func (c *cronJobs) Schedule(run func(), cronExps ...string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, job := range c.jobs {
+		job.Stop()
+	}
+	c.jobs = make([]*artifex.DispatchCron, 0, len(cronExps))
+
+	schedules := make([]cron.Schedule, 0, len(cronExps))
+	for _, exp := range cronExps {
+		schedule, err := cronParser.Parse(exp)
+		if err != nil {
+			return err
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	for _, exp := range cronExps {
+		job, err := c.queue.DispatchCron(c.guard(run, schedules), exp)
+		if err != nil {
+			return err
+		}
+		c.jobs = append(c.jobs, job)
+	}
+	return nil
+}
+
+// guard wraps the given function to only run if the job's lease could be acquired
+// the lease is held until shortly before the job's next scheduled run, which tolerates for the instances' clocks to deviate by a bit
+func (c *cronJobs) guard(run func(), schedules []cron.Schedule) func() {
+	if c.locks == nil {
+		return run
+	}
+
+	return func() {
+		now := time.Now()
+		var next time.Time
+		for _, schedule := range schedules {
+			if t := schedule.Next(now); next.IsZero() || t.Before(next) {
+				next = t
+			}
+		}
+
+		if c.locks.TryLock(c.name, next.Sub(now)*9/10) {
+			run()
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+const labelRuleBackfillPageSize = 1000
+
+// labelRuleState holds a user's compiled rules along with the project labels assigned so far, so that incoming heartbeats can be checked against them without any database lookups
+type labelRuleState struct {
+	rules    []*models.LabelRule
+	assigned datastructure.Set[string] // project + label
+	lock     sync.Mutex
+}
+
+// LabelRuleService automatically labels projects according to users' rules, both for newly received heartbeats and, on request, for existing ones
+type LabelRuleService struct {
+	config           *config.Config
+	cache            *cache.Cache
+	eventBus         *hub.Hub
+	repository       repositories.ILabelRuleRepository
+	heartbeatSrvc    IHeartbeatService
+	projectLabelSrvc IProjectLabelService
+	queue            *artifex.Dispatcher
+}
+
+func NewLabelRuleService(labelRuleRepository repositories.ILabelRuleRepository, heartbeatService IHeartbeatService, projectLabelService IProjectLabelService) *LabelRuleService {
+	srv := &LabelRuleService{
+		config:           config.Get(),
+		cache:            cache.New(24*time.Hour, 24*time.Hour),
+		eventBus:         config.EventBus(),
+		repository:       labelRuleRepository,
+		heartbeatSrvc:    heartbeatService,
+		projectLabelSrvc: projectLabelService,
+		queue:            config.GetQueue(config.QueueProjects),
+	}
+
+	onHeartbeat := srv.eventBus.Subscribe(0, config.EventHeartbeatCreate)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			heartbeat := m.Fields[config.FieldPayload].(*models.Heartbeat)
+			if _, err := srv.apply(heartbeat.UserID, []*models.Heartbeat{heartbeat}); err != nil {
+				config.Log().Error("failed to apply label rules for user '%s' - %v", heartbeat.UserID, err)
+			}
+		}
+	}(&onHeartbeat)
+
+	// labels might also be deleted or assigned manually
+	onLabelChange := srv.eventBus.Subscribe(0, config.EventProjectLabelCreate, config.EventProjectLabelDelete)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.cache.Delete(srv.stateCacheKey(m.Fields[config.FieldUserId].(string)))
+		}
+	}(&onLabelChange)
+
+	return srv
+}
+
+func (srv *LabelRuleService) GetById(id uint) (*models.LabelRule, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *LabelRuleService) GetByUser(userId string) ([]*models.LabelRule, error) {
+	if rules, found := srv.cache.Get(userId); found {
+		return rules.([]*models.LabelRule), nil
+	}
+
+	rules, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		if err := r.Compile(); err != nil {
+			logbuch.Warn("failed to compile label rule %d of user '%s' - %v", r.ID, userId, err)
+		}
+	}
+	srv.cache.Set(userId, rules, cache.DefaultExpiration)
+	return rules, nil
+}
+
+func (srv *LabelRuleService) Create(rule *models.LabelRule) (*models.LabelRule, error) {
+	result, err := srv.repository.Insert(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	srv.invalidate(result.UserID)
+	srv.notifyUpdate(result.UserID)
+	return result, nil
+}
+
+func (srv *LabelRuleService) Delete(rule *models.LabelRule) error {
+	if rule.UserID == "" {
+		return errors.New("no user id specified")
+	}
+	err := srv.repository.Delete(rule.ID)
+	srv.invalidate(rule.UserID)
+	srv.notifyUpdate(rule.UserID)
+	return err
+}
+
+// Backfill schedules a background job to apply the user's rules to all of their existing heartbeats
+// labels assigned earlier are never removed, neither by this nor by deleting a rule
+func (srv *LabelRuleService) Backfill(user *models.User) error {
+	u := *user
+	return srv.queue.Dispatch(func() {
+		n, err := srv.ApplyToExisting(&u)
+		if err != nil {
+			config.Log().Error("failed to backfill label rules for user '%s' - %v", u.ID, err)
+			return
+		}
+		logbuch.Info("assigned %d labels from rules for user '%s'", n, u.ID)
+	})
+}
+
+// ApplyToExisting is like Backfill, but returns the number of newly assigned labels only after all of the user's heartbeats were checked
+func (srv *LabelRuleService) ApplyToExisting(user *models.User) (int, error) {
+	var created int
+	for offset := 0; ; offset += labelRuleBackfillPageSize {
+		candidates, err := srv.heartbeatSrvc.GetDistinctByUser(user, []string{"project", "entity", "branch"}, offset, labelRuleBackfillPageSize)
+		if err != nil {
+			return created, err
+		}
+
+		n, err := srv.apply(user.ID, candidates)
+		created += n
+		if err != nil || len(candidates) < labelRuleBackfillPageSize {
+			return created, err
+		}
+	}
+}
+
+// apply assigns labels of all matching rules to the heartbeats' projects, unless already present, and returns the number of newly created labels
+func (srv *LabelRuleService) apply(userId string, heartbeats []*models.Heartbeat) (int, error) {
+	state, err := srv.getState(userId)
+	if err != nil || len(state.rules) == 0 {
+		return 0, err
+	}
+
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	var created int
+	for _, hb := range heartbeats {
+		for _, rule := range state.rules {
+			key := hb.Project + "\x00" + rule.Label
+			if state.assigned.Contain(key) || !rule.Matches(hb) {
+				continue
+			}
+			if _, err := srv.projectLabelSrvc.Create(&models.ProjectLabel{
+				UserID:     userId,
+				ProjectKey: hb.Project,
+				Label:      rule.Label,
+			}); err != nil {
+				return created, err
+			}
+			state.assigned.Add(key)
+			created++
+		}
+	}
+	return created, nil
+}
+
+func (srv *LabelRuleService) getState(userId string) (*labelRuleState, error) {
+	if state, found := srv.cache.Get(srv.stateCacheKey(userId)); found {
+		return state.(*labelRuleState), nil
+	}
+
+	rules, err := srv.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &labelRuleState{rules: rules, assigned: datastructure.NewSet[string]()}
+	if len(rules) > 0 {
+		existing, err := srv.projectLabelSrvc.GetByUser(userId)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range existing {
+			state.assigned.Add(l.ProjectKey + "\x00" + l.Label)
+		}
+	}
+
+	srv.cache.Set(srv.stateCacheKey(userId), state, cache.DefaultExpiration)
+	return state, nil
+}
+
+// FlushUserCache drops the user's cached rules, e.g. after they were modified in the database directly
+func (srv *LabelRuleService) FlushUserCache(userId string) {
+	srv.invalidate(userId)
+	srv.notifyUpdate(userId)
+}
+
+func (srv *LabelRuleService) invalidate(userId string) {
+	srv.cache.Delete(userId)
+	srv.cache.Delete(srv.stateCacheKey(userId))
+}
+
+func (srv *LabelRuleService) notifyUpdate(userId string) {
+	srv.eventBus.Publish(hub.Message{
+		Name:   config.EventLabelRuleUpdate,
+		Fields: map[string]interface{}{config.FieldUserId: userId},
+	})
+}
+
+func (srv *LabelRuleService) stateCacheKey(userId string) string {
+	return fmt.Sprintf("state_%s", userId)
+}
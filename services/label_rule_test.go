@@ -0,0 +1,119 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type LabelRuleServiceTestSuite struct {
+	suite.Suite
+	TestUser            *models.User
+	LabelRuleRepository *mocks.LabelRuleRepositoryMock
+	HeartbeatService    *mocks.HeartbeatServiceMock
+	ProjectLabelService *mocks.ProjectLabelServiceMock
+}
+
+func (suite *LabelRuleServiceTestSuite) SetupSuite() {
+	suite.TestUser = &models.User{ID: "testuser"}
+	config.Set(config.Empty())
+}
+
+func (suite *LabelRuleServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.LabelRuleRepository = new(mocks.LabelRuleRepositoryMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.ProjectLabelService = new(mocks.ProjectLabelServiceMock)
+}
+
+func TestLabelRuleServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(LabelRuleServiceTestSuite))
+}
+
+func (suite *LabelRuleServiceTestSuite) TestLabelRuleService_Apply_CachesState() {
+	sut := NewLabelRuleService(suite.LabelRuleRepository, suite.HeartbeatService, suite.ProjectLabelService)
+
+	rules := []*models.LabelRule{
+		{ID: 1, UserID: suite.TestUser.ID, Field: models.LabelRuleFieldProject, Pattern: "^wakapi", Label: "oss"},
+		{ID: 2, UserID: suite.TestUser.ID, Field: models.LabelRuleFieldEntity, Pattern: "/work/", Label: "work"},
+	}
+	existing := []*models.ProjectLabel{
+		{UserID: suite.TestUser.ID, ProjectKey: "wakapi", Label: "oss"},
+	}
+
+	suite.LabelRuleRepository.On("GetByUser", suite.TestUser.ID).Return(rules, nil)
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return(existing, nil)
+	suite.ProjectLabelService.On("Create", mock.Anything).Return(&models.ProjectLabel{}, nil)
+
+	n, err := sut.apply(suite.TestUser.ID, []*models.Heartbeat{
+		{UserID: suite.TestUser.ID, Project: "wakapi", Entity: "/home/me/work/wakapi/main.go"},
+		{UserID: suite.TestUser.ID, Project: "wakapi-ui", Entity: "/home/me/private/wakapi-ui/index.js"},
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, n)
+	suite.ProjectLabelService.AssertCalled(suite.T(), "Create", &models.ProjectLabel{UserID: suite.TestUser.ID, ProjectKey: "wakapi", Label: "work"})
+	suite.ProjectLabelService.AssertCalled(suite.T(), "Create", &models.ProjectLabel{UserID: suite.TestUser.ID, ProjectKey: "wakapi-ui", Label: "oss"})
+
+	// labels assigned just now are remembered and rules and labels are not fetched again
+	n, err = sut.apply(suite.TestUser.ID, []*models.Heartbeat{
+		{UserID: suite.TestUser.ID, Project: "wakapi", Entity: "/home/me/work/wakapi/go.mod"},
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 0, n)
+	suite.LabelRuleRepository.AssertNumberOfCalls(suite.T(), "GetByUser", 1)
+	suite.ProjectLabelService.AssertNumberOfCalls(suite.T(), "GetByUser", 1)
+	suite.ProjectLabelService.AssertNumberOfCalls(suite.T(), "Create", 2)
+}
+
+func (suite *LabelRuleServiceTestSuite) TestLabelRuleService_Apply_InvalidatesOnRuleChange() {
+	sut := NewLabelRuleService(suite.LabelRuleRepository, suite.HeartbeatService, suite.ProjectLabelService)
+
+	rule := &models.LabelRule{ID: 1, UserID: suite.TestUser.ID, Field: models.LabelRuleFieldProject, Pattern: "^wakapi", Label: "oss"}
+
+	suite.LabelRuleRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.LabelRule{}, nil).Once()
+	suite.LabelRuleRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.LabelRule{rule}, nil)
+	suite.LabelRuleRepository.On("Insert", rule).Return(rule, nil)
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
+	suite.ProjectLabelService.On("Create", mock.Anything).Return(&models.ProjectLabel{}, nil)
+
+	heartbeats := []*models.Heartbeat{{UserID: suite.TestUser.ID, Project: "wakapi"}}
+
+	n, _ := sut.apply(suite.TestUser.ID, heartbeats)
+	assert.Equal(suite.T(), 0, n)
+
+	_, err := sut.Create(rule)
+	assert.Nil(suite.T(), err)
+
+	n, _ = sut.apply(suite.TestUser.ID, heartbeats)
+	assert.Equal(suite.T(), 1, n)
+}
+
+func (suite *LabelRuleServiceTestSuite) TestLabelRuleService_Backfill_Paginates() {
+	sut := NewLabelRuleService(suite.LabelRuleRepository, suite.HeartbeatService, suite.ProjectLabelService)
+
+	rules := []*models.LabelRule{{ID: 1, UserID: suite.TestUser.ID, Field: models.LabelRuleFieldBranch, Pattern: "^release/", Label: "shipped"}}
+
+	page1 := make([]*models.Heartbeat, labelRuleBackfillPageSize)
+	for i := range page1 {
+		page1[i] = &models.Heartbeat{Project: "wakapi", Branch: "master"}
+	}
+	page2 := []*models.Heartbeat{{Project: "anchr", Branch: "release/1.0"}}
+
+	columns := []string{"project", "entity", "branch"}
+	suite.LabelRuleRepository.On("GetByUser", suite.TestUser.ID).Return(rules, nil)
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
+	suite.ProjectLabelService.On("Create", mock.Anything).Return(&models.ProjectLabel{}, nil)
+	suite.HeartbeatService.On("GetDistinctByUser", suite.TestUser, columns, 0, labelRuleBackfillPageSize).Return(page1, nil)
+	suite.HeartbeatService.On("GetDistinctByUser", suite.TestUser, columns, labelRuleBackfillPageSize, labelRuleBackfillPageSize).Return(page2, nil)
+
+	n, err := sut.ApplyToExisting(suite.TestUser)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, n)
+	suite.HeartbeatService.AssertNumberOfCalls(suite.T(), "GetDistinctByUser", 2)
+	suite.ProjectLabelService.AssertCalled(suite.T(), "Create", &models.ProjectLabel{UserID: suite.TestUser.ID, ProjectKey: "anchr", Label: "shipped"})
+}
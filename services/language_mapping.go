@@ -2,6 +2,7 @@ package services
 
 import (
 	"errors"
+	"github.com/leandro-lugaresi/hub"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/repositories"
@@ -12,6 +13,7 @@ import (
 type LanguageMappingService struct {
 	config     *config.Config
 	cache      *cache.Cache
+	eventBus   *hub.Hub
 	repository repositories.ILanguageMappingRepository
 }
 
@@ -20,6 +22,7 @@ func NewLanguageMappingService(languageMappingsRepo repositories.ILanguageMappin
 		config:     config.Get(),
 		repository: languageMappingsRepo,
 		cache:      cache.New(24*time.Hour, 24*time.Hour),
+		eventBus:   config.EventBus(),
 	}
 }
 
@@ -41,7 +44,7 @@ func (srv *LanguageMappingService) GetByUser(userId string) ([]*models.LanguageM
 }
 
 func (srv *LanguageMappingService) ResolveByUser(userId string) (map[string]string, error) {
-	mappings := srv.getServerMappings()
+	mappings := srv.GetDefaults()
 	userMappings, err := srv.GetByUser(userId)
 	if err != nil {
 		return nil, err
@@ -60,6 +63,7 @@ func (srv *LanguageMappingService) Create(mapping *models.LanguageMapping) (*mod
 	}
 
 	srv.cache.Delete(result.UserID)
+	srv.notifyUpdate(result.UserID)
 	return result, nil
 }
 
@@ -69,10 +73,19 @@ func (srv *LanguageMappingService) Delete(mapping *models.LanguageMapping) error
 	}
 	err := srv.repository.Delete(mapping.ID)
 	srv.cache.Delete(mapping.UserID)
+	srv.notifyUpdate(mapping.UserID)
 	return err
 }
 
-func (srv *LanguageMappingService) getServerMappings() map[string]string {
+// GetDefaults returns the server-wide mappings as configured by custom_languages, which users' own mappings take precedence over
+func (srv *LanguageMappingService) GetDefaults() map[string]string {
 	// https://dave.cheney.net/2017/04/30/if-a-map-isnt-a-reference-variable-what-is-it
-	return srv.config.App.GetCustomLanguages()
+	return config.Get().App.GetCustomLanguages()
+}
+
+func (srv *LanguageMappingService) notifyUpdate(userId string) {
+	srv.eventBus.Publish(hub.Message{
+		Name:   config.EventLanguageMappingUpdate,
+		Fields: map[string]interface{}{config.FieldUserId: userId},
+	})
 }
@@ -1,6 +1,7 @@
 package services
 
 import (
+	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
 	"github.com/emvi/logbuch"
 	"github.com/leandro-lugaresi/hub"
 	"github.com/muety/artifex/v2"
@@ -9,7 +10,6 @@ import (
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/repositories"
 	"github.com/muety/wakapi/utils"
-	"github.com/patrickmn/go-cache"
 	"reflect"
 	"strconv"
 	"strings"
@@ -18,25 +18,27 @@ import (
 
 type LeaderboardService struct {
 	config         *config.Config
-	cache          *cache.Cache
+	cache          utils.Cache
 	eventBus       *hub.Hub
 	repository     repositories.ILeaderboardRepository
 	summaryService ISummaryService
 	userService    IUserService
 	queueDefault   *artifex.Dispatcher
 	queueWorkers   *artifex.Dispatcher
+	crons          *cronJobs
 }
 
-func NewLeaderboardService(leaderboardRepo repositories.ILeaderboardRepository, summaryService ISummaryService, userService IUserService) *LeaderboardService {
+func NewLeaderboardService(leaderboardRepo repositories.ILeaderboardRepository, summaryService ISummaryService, userService IUserService, jobLockService IJobLockService) *LeaderboardService {
 	srv := &LeaderboardService{
 		config:         config.Get(),
-		cache:          cache.New(6*time.Hour, 6*time.Hour),
+		cache:          config.NewCache("leaderboard", 6*time.Hour, 6*time.Hour),
 		eventBus:       config.EventBus(),
 		repository:     leaderboardRepo,
 		summaryService: summaryService,
 		userService:    userService,
 		queueDefault:   config.GetDefaultQueue(),
 		queueWorkers:   config.GetQueue(config.QueueProcessing),
+		crons:          newCronJobs("leaderboard", config.GetDefaultQueue(), jobLockService),
 	}
 
 	onUserUpdate := srv.eventBus.Subscribe(0, config.EventUserUpdate)
@@ -51,10 +53,18 @@ func NewLeaderboardService(leaderboardRepo repositories.ILeaderboardRepository,
 				config.Log().Error("failed to check existing leaderboards upon user update - %v", err)
 			}
 
-			if user.PublicLeaderboard && !exists {
+			eligible := user.PublicLeaderboard && user.IsActive()
+			if eligible {
+				if eligible, err = srv.MeetsEligibilityCriteria(user, models.IntervalPast7Days); err != nil {
+					config.Log().Error("failed to check leaderboard eligibility of user '%s' upon user update - %v", user.ID, err)
+					continue
+				}
+			}
+
+			if eligible && !exists {
 				logbuch.Info("generating leaderboard for '%s' after settings update", user.ID)
 				srv.ComputeLeaderboard([]*models.User{user}, models.IntervalPast7Days, []uint8{models.SummaryLanguage})
-			} else if !user.PublicLeaderboard && exists {
+			} else if !eligible && exists {
 				logbuch.Info("clearing leaderboard for '%s' after settings update", user.ID)
 				if err := srv.repository.DeleteByUser(user.ID); err != nil {
 					config.Log().Error("failed to clear leaderboard for user '%s' - %v", user.ID, err)
@@ -76,14 +86,54 @@ func (srv *LeaderboardService) Schedule() {
 			config.Log().Error("failed to get users for leaderboard generation - %v", err)
 			return
 		}
-		srv.ComputeLeaderboard(users, models.IntervalPast7Days, []uint8{models.SummaryLanguage})
+
+		// only users considered active (and not having deactivated their account) are eligible for the leaderboard, stale entries of inactive ones are removed
+		activeUsers, err := srv.userService.GetActive(false)
+		if err != nil {
+			config.Log().Error("failed to get active users for leaderboard generation - %v", err)
+			return
+		}
+		activeIds := datastructure.NewSet[string]()
+		for _, u := range activeUsers {
+			activeIds.Add(u.ID)
+		}
+
+		eligibleUsers := make([]*models.User, 0, len(users))
+		ineligibleIds := make([]string, 0)
+		for _, u := range users {
+			if !activeIds.Contain(u.ID) || !u.IsActive() {
+				ineligibleIds = append(ineligibleIds, u.ID)
+				continue
+			}
+
+			// additionally configured criteria, e.g. to keep throwaway accounts off the public leaderboard
+			eligible, err := srv.MeetsEligibilityCriteria(u, models.IntervalPast7Days)
+			if err != nil {
+				config.Log().Error("failed to check leaderboard eligibility of user '%s' - %v", u.ID, err)
+				continue
+			}
+			if eligible {
+				eligibleUsers = append(eligibleUsers, u)
+			} else {
+				ineligibleIds = append(ineligibleIds, u.ID)
+			}
+		}
+		if err := srv.repository.DeleteByUsersAndInterval(ineligibleIds, models.IntervalPast7Days); err != nil {
+			config.Log().Error("failed to delete leaderboard items for %d ineligible users - %v", len(ineligibleIds), err)
+		}
+		srv.cache.Flush()
+
+		srv.ComputeLeaderboard(eligibleUsers, models.IntervalPast7Days, []uint8{models.SummaryLanguage})
 	}
 
-	for _, cronExp := range srv.config.App.GetLeaderboardGenerationTimeCron() {
-		if _, err := srv.queueDefault.DispatchCron(generate, cronExp); err != nil {
-			config.Log().Error("failed to schedule leaderboard generation (%s), %v", cronExp, err)
+	schedule := func() {
+		if err := srv.crons.Schedule(generate, config.Get().App.GetLeaderboardGenerationTimeCron()...); err != nil {
+			config.Log().Error("failed to schedule leaderboard generation (%v), %v", config.Get().App.GetLeaderboardGenerationTimeCron(), err)
 		}
 	}
+
+	schedule()
+	config.OnReload(schedule, config.ReloadKeyLeaderboardTime)
 }
 
 func (srv *LeaderboardService) ComputeLeaderboard(users []*models.User, interval *models.IntervalKey, by []uint8) error {
@@ -129,6 +179,43 @@ func (srv *LeaderboardService) ComputeLeaderboard(users []*models.User, interval
 	return nil
 }
 
+// MeetsEligibilityCriteria checks whether the user fulfills the configured minimum account age, number of active days within the given interval and e-mail verification requirements to be listed in the leaderboard
+func (srv *LeaderboardService) MeetsEligibilityCriteria(user *models.User, interval *models.IntervalKey) (bool, error) {
+	criteria := srv.config.App.LeaderboardEligibility
+
+	if criteria.RequireVerifiedEmail && !user.HasVerifiedEmail() {
+		return false, nil
+	}
+
+	if criteria.MinAccountAgeDays > 0 && time.Since(user.CreatedAt.T()) < time.Duration(criteria.MinAccountAgeDays)*24*time.Hour {
+		return false, nil
+	}
+
+	if criteria.MinActiveDays > 0 {
+		err, from, to := helpers.ResolveIntervalTZ(interval, user.TZ())
+		if err != nil {
+			return false, err
+		}
+
+		summaries, err := srv.summaryService.GetByUserWithin(user, from, to)
+		if err != nil {
+			return false, err
+		}
+
+		activeDays := datastructure.NewSet[string]()
+		for _, s := range summaries {
+			if s.TotalTime() > 0 {
+				activeDays.Add(s.FromTime.T().In(user.TZ()).Format(config.SimpleDateFormat))
+			}
+		}
+		if activeDays.Size() < criteria.MinActiveDays {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
 func (srv *LeaderboardService) ExistsAnyByUser(userId string) (bool, error) {
 	count, err := srv.repository.CountAllByUser(userId)
 	return count > 0, err
@@ -137,8 +224,9 @@ func (srv *LeaderboardService) ExistsAnyByUser(userId string) (bool, error) {
 func (srv *LeaderboardService) CountUsers() (int64, error) {
 	// check cache
 	cacheKey := "count_total"
-	if cacheResult, ok := srv.cache.Get(cacheKey); ok {
-		return cacheResult.(int64), nil
+	var cacheResult int64
+	if srv.cache.Get(cacheKey, &cacheResult) {
+		return cacheResult, nil
 	}
 
 	count, err := srv.repository.CountUsers()
@@ -159,8 +247,9 @@ func (srv *LeaderboardService) GetByIntervalAndUser(interval *models.IntervalKey
 func (srv *LeaderboardService) GetAggregatedByInterval(interval *models.IntervalKey, by *uint8, pageParams *utils.PageParams, resolveUsers bool) (models.Leaderboard, error) {
 	// check cache
 	cacheKey := srv.getHash(interval, by, "", pageParams)
-	if cacheResult, ok := srv.cache.Get(cacheKey); ok {
-		return cacheResult.([]*models.LeaderboardItemRanked), nil
+	var cacheResult []*models.LeaderboardItemRanked
+	if srv.cache.Get(cacheKey, &cacheResult) {
+		return cacheResult, nil
 	}
 
 	items, err := srv.repository.GetAllAggregatedByInterval(interval, by, pageParams.Limit(), pageParams.Offset())
@@ -188,8 +277,9 @@ func (srv *LeaderboardService) GetAggregatedByInterval(interval *models.Interval
 func (srv *LeaderboardService) GetAggregatedByIntervalAndUser(interval *models.IntervalKey, userId string, by *uint8, resolveUser bool) (models.Leaderboard, error) {
 	// check cache
 	cacheKey := srv.getHash(interval, by, userId, nil)
-	if cacheResult, ok := srv.cache.Get(cacheKey); ok {
-		return cacheResult.([]*models.LeaderboardItemRanked), nil
+	var cacheResult []*models.LeaderboardItemRanked
+	if srv.cache.Get(cacheKey, &cacheResult) {
+		return cacheResult, nil
 	}
 
 	items, err := srv.repository.GetAggregatedByUserAndInterval(userId, interval, by, 0, 0)
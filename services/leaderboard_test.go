@@ -0,0 +1,93 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type LeaderboardServiceTestSuite struct {
+	suite.Suite
+	SummaryService *mocks.SummaryServiceMock
+}
+
+func (suite *LeaderboardServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+}
+
+func (suite *LeaderboardServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+}
+
+func (suite *LeaderboardServiceTestSuite) TearDownTest() {
+	config.Get().App.LeaderboardEligibility = config.Empty().App.LeaderboardEligibility
+}
+
+func TestLeaderboardServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(LeaderboardServiceTestSuite))
+}
+
+func (suite *LeaderboardServiceTestSuite) TestLeaderboardService_MeetsEligibilityCriteria_Default() {
+	sut := &LeaderboardService{config: config.Get(), summaryService: suite.SummaryService}
+
+	eligible, err := sut.MeetsEligibilityCriteria(&models.User{ID: "user1", CreatedAt: models.CustomTime(time.Now())}, models.IntervalPast7Days)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), eligible)
+	suite.SummaryService.AssertNotCalled(suite.T(), "GetByUserWithin", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *LeaderboardServiceTestSuite) TestLeaderboardService_MeetsEligibilityCriteria_AccountAgeAndEmail() {
+	config.Get().App.LeaderboardEligibility.MinAccountAgeDays = 3
+	config.Get().App.LeaderboardEligibility.RequireVerifiedEmail = true
+
+	sut := &LeaderboardService{config: config.Get(), summaryService: suite.SummaryService}
+
+	old := models.CustomTime(time.Now().AddDate(0, 0, -4))
+	fresh := models.CustomTime(time.Now().AddDate(0, 0, -1))
+
+	eligible, _ := sut.MeetsEligibilityCriteria(&models.User{ID: "user1", CreatedAt: old, Email: "user1@wakapi.dev", EmailVerified: true}, models.IntervalPast7Days)
+	assert.True(suite.T(), eligible)
+
+	eligible, _ = sut.MeetsEligibilityCriteria(&models.User{ID: "user2", CreatedAt: fresh, Email: "user2@wakapi.dev", EmailVerified: true}, models.IntervalPast7Days)
+	assert.False(suite.T(), eligible)
+
+	eligible, _ = sut.MeetsEligibilityCriteria(&models.User{ID: "user3", CreatedAt: old, Email: "user3@wakapi.dev"}, models.IntervalPast7Days)
+	assert.False(suite.T(), eligible)
+
+	eligible, _ = sut.MeetsEligibilityCriteria(&models.User{ID: "user4", CreatedAt: old, EmailVerified: true}, models.IntervalPast7Days)
+	assert.False(suite.T(), eligible)
+}
+
+func (suite *LeaderboardServiceTestSuite) TestLeaderboardService_MeetsEligibilityCriteria_ActiveDays() {
+	config.Get().App.LeaderboardEligibility.MinActiveDays = 2
+
+	user1 := &models.User{ID: "user1"}
+	user2 := &models.User{ID: "user2"}
+
+	day := func(daysAgo int, duration time.Duration) *models.Summary {
+		from := time.Now().AddDate(0, 0, -daysAgo).Truncate(24 * time.Hour)
+		return &models.Summary{
+			FromTime: models.CustomTime(from),
+			ToTime:   models.CustomTime(from.Add(24 * time.Hour)),
+			Projects: []*models.SummaryItem{{Type: models.SummaryProject, Key: "wakapi", Total: duration / time.Second}},
+		}
+	}
+
+	suite.SummaryService.On("GetByUserWithin", user1, mock.Anything, mock.Anything).Return([]*models.Summary{day(1, time.Hour), day(2, 0), day(3, time.Minute)}, nil)
+	suite.SummaryService.On("GetByUserWithin", user2, mock.Anything, mock.Anything).Return([]*models.Summary{day(1, time.Hour), day(2, 0)}, nil)
+
+	sut := &LeaderboardService{config: config.Get(), summaryService: suite.SummaryService}
+
+	eligible, err := sut.MeetsEligibilityCriteria(user1, models.IntervalPast7Days)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), eligible)
+
+	eligible, err = sut.MeetsEligibilityCriteria(user2, models.IntervalPast7Days)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), eligible)
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+const loginThrottleBaseDelay = 1 * time.Second
+
+// LoginThrottleService counts failed logins per account and per ip address, delays further attempts exponentially and locks them temporarily after too many failures
+// counters are kept in the (possibly shared) cache, while account lockouts are persisted, so admins can inspect and lift them
+type LoginThrottleService struct {
+	config       *config.Config
+	cache        utils.Cache
+	keyValueSrvc IKeyValueService
+	mailSrvc     IMailService
+	lock         sync.Mutex
+	now          func() time.Time
+}
+
+func NewLoginThrottleService(keyValueService IKeyValueService, mailService IMailService) *LoginThrottleService {
+	cfg := config.Get()
+	lockout := time.Duration(cfg.Security.LoginThrottling.LockoutMinutes) * time.Minute
+	return &LoginThrottleService{
+		config:       cfg,
+		cache:        config.NewCache("login_attempts", lockout, lockout),
+		keyValueSrvc: keyValueService,
+		mailSrvc:     mailService,
+		now:          time.Now,
+	}
+}
+
+// Check returns how long to wait before the next login attempt for the given account from the given ip address is allowed, zero if it's allowed right away
+func (srv *LoginThrottleService) Check(username, remoteIp string) time.Duration {
+	if !srv.config.Security.LoginThrottling.Enabled {
+		return 0
+	}
+
+	now := srv.now()
+	wait := srv.getAttempts(srv.ipKey(remoteIp)).RetryAfter(now, loginThrottleBaseDelay, srv.lockoutDuration())
+	if accountWait := srv.getAttempts(srv.accountKey(username)).RetryAfter(now, loginThrottleBaseDelay, srv.lockoutDuration()); accountWait > wait {
+		wait = accountWait
+	}
+	if lockout := srv.getLockout(username); lockout != nil && lockout.LockedUntil.Sub(now) > wait {
+		wait = lockout.LockedUntil.Sub(now)
+	}
+	return wait
+}
+
+// RecordFailure counts a failed login attempt, user is nil if no account with the given name exists
+func (srv *LoginThrottleService) RecordFailure(user *models.User, username, remoteIp string) {
+	if !srv.config.Security.LoginThrottling.Enabled {
+		return
+	}
+
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	if ipAttempts := srv.increment(srv.ipKey(remoteIp), srv.config.Security.LoginThrottling.MaxAttemptsIp); ipAttempts.IsLocked(srv.now()) && ipAttempts.Failures == srv.config.Security.LoginThrottling.MaxAttemptsIp {
+		logbuch.Warn("locked logins from ip '%s' after %d failed attempts", remoteIp, ipAttempts.Failures)
+	}
+
+	accountAttempts := srv.increment(srv.accountKey(username), srv.config.Security.LoginThrottling.MaxAttempts)
+	if user == nil || !accountAttempts.IsLocked(srv.now()) || accountAttempts.Failures != srv.config.Security.LoginThrottling.MaxAttempts {
+		return
+	}
+
+	lockout := &models.LoginLockout{
+		UserID:      user.ID,
+		Failures:    accountAttempts.Failures,
+		LastIp:      utils.ParseRemoteIp(remoteIp),
+		LockedUntil: accountAttempts.LockedUntil,
+	}
+	value, _ := json.Marshal(lockout)
+	if err := srv.keyValueSrvc.PutString(&models.KeyStringValue{Key: srv.lockoutKey(user.ID), Value: string(value)}); err != nil {
+		config.Log().Error("failed to persist login lockout of user '%s' - %v", user.ID, err)
+	}
+	logbuch.Warn("locked account '%s' after %d failed login attempts", user.ID, accountAttempts.Failures)
+
+	if config.Get().Mail.Enabled && user.Email != "" {
+		go func(user *models.User, lockout *models.LoginLockout) {
+			if err := srv.mailSrvc.SendLoginLockout(user, lockout); err != nil {
+				config.Log().Error("failed to send login lockout notification to '%s' - %v", user.ID, err)
+			}
+		}(user, lockout)
+	}
+}
+
+// RecordSuccess resets the account's failed attempts, while those of the ip address are kept to not let an attacker reset them using their own account
+func (srv *LoginThrottleService) RecordSuccess(username string) {
+	if !srv.config.Security.LoginThrottling.Enabled {
+		return
+	}
+	srv.cache.Delete(srv.accountKey(username))
+}
+
+// GetLockouts returns all accounts currently locked
+func (srv *LoginThrottleService) GetLockouts() ([]*models.LoginLockout, error) {
+	values, err := srv.keyValueSrvc.GetByPrefix(config.KeyLoginLockout + "_")
+	if err != nil {
+		return nil, err
+	}
+
+	lockouts := make([]*models.LoginLockout, 0, len(values))
+	for _, kv := range values {
+		var lockout models.LoginLockout
+		if err := json.Unmarshal([]byte(kv.Value), &lockout); err != nil || !lockout.IsActive(srv.now()) {
+			continue
+		}
+		lockouts = append(lockouts, &lockout)
+	}
+	return lockouts, nil
+}
+
+// Unlock lifts the user's lockout and resets their failed attempts
+func (srv *LoginThrottleService) Unlock(userId string) error {
+	srv.cache.Delete(srv.accountKey(userId))
+	return srv.keyValueSrvc.DeleteString(srv.lockoutKey(userId))
+}
+
+func (srv *LoginThrottleService) getAttempts(key string) *models.LoginAttempts {
+	var attempts models.LoginAttempts
+	srv.cache.Get(key, &attempts)
+	return &attempts
+}
+
+func (srv *LoginThrottleService) getLockout(userId string) *models.LoginLockout {
+	kv := srv.keyValueSrvc.MustGetString(srv.lockoutKey(userId))
+	if kv.Value == "" {
+		return nil
+	}
+	var lockout models.LoginLockout
+	if err := json.Unmarshal([]byte(kv.Value), &lockout); err != nil || !lockout.IsActive(srv.now()) {
+		return nil
+	}
+	return &lockout
+}
+
+// increment counts another failure and locks once the maximum number of attempts is reached
+func (srv *LoginThrottleService) increment(key string, maxAttempts int) *models.LoginAttempts {
+	now := srv.now()
+	attempts := srv.getAttempts(key)
+	if !attempts.LockedUntil.IsZero() && !attempts.IsLocked(now) {
+		attempts = &models.LoginAttempts{} // previous lockout has expired, start over
+	}
+
+	attempts.Failures++
+	attempts.LastFailure = now
+	if attempts.Failures == maxAttempts {
+		attempts.LockedUntil = now.Add(srv.lockoutDuration())
+	}
+	srv.cache.Set(key, attempts, srv.lockoutDuration())
+	return attempts
+}
+
+func (srv *LoginThrottleService) lockoutDuration() time.Duration {
+	return time.Duration(srv.config.Security.LoginThrottling.LockoutMinutes) * time.Minute
+}
+
+func (srv *LoginThrottleService) accountKey(username string) string {
+	return "account:" + strings.ToLower(username)
+}
+
+func (srv *LoginThrottleService) ipKey(remoteIp string) string {
+	return "ip:" + utils.ParseRemoteIp(remoteIp)
+}
+
+func (srv *LoginThrottleService) lockoutKey(userId string) string {
+	return fmt.Sprintf("%s_%s", config.KeyLoginLockout, userId)
+}
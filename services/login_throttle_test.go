@@ -0,0 +1,115 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type LoginThrottleServiceTestSuite struct {
+	suite.Suite
+	KeyValueService *mocks.KeyValueServiceMock
+	TestUser        *models.User
+}
+
+func (suite *LoginThrottleServiceTestSuite) SetupSuite() {
+	cfg := config.Empty()
+	cfg.Security.LoginThrottling.Enabled = true
+	cfg.Security.LoginThrottling.MaxAttempts = 3
+	cfg.Security.LoginThrottling.MaxAttemptsIp = 5
+	cfg.Security.LoginThrottling.LockoutMinutes = 15
+	config.Set(cfg)
+
+	suite.TestUser = &models.User{ID: "testuser"}
+}
+
+func (suite *LoginThrottleServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.KeyValueService = new(mocks.KeyValueServiceMock)
+}
+
+func TestLoginThrottleServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(LoginThrottleServiceTestSuite))
+}
+
+func (suite *LoginThrottleServiceTestSuite) TestLoginThrottleService_Account() {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	sut := NewLoginThrottleService(suite.KeyValueService, nil)
+	sut.now = func() time.Time { return now }
+
+	var persisted *models.KeyStringValue
+	suite.KeyValueService.On("MustGetString", "login_lockout_testuser").Return(&models.KeyStringValue{})
+	suite.KeyValueService.On("PutString", mock.Anything).Run(func(args mock.Arguments) {
+		persisted = args.Get(0).(*models.KeyStringValue)
+	}).Return(nil)
+
+	assert.Zero(suite.T(), sut.Check("testuser", "127.0.0.1:1234"))
+
+	sut.RecordFailure(suite.TestUser, "testuser", "127.0.0.1:1234")
+	assert.Equal(suite.T(), 1*time.Second, sut.Check("testuser", "127.0.0.2"))
+
+	now = now.Add(time.Minute)
+	sut.RecordFailure(suite.TestUser, "testuser", "127.0.0.2:1234")
+	assert.Equal(suite.T(), 2*time.Second, sut.Check("testuser", "127.0.0.3"))
+	assert.Nil(suite.T(), persisted)
+
+	now = now.Add(time.Minute)
+	sut.RecordFailure(suite.TestUser, "testuser", "127.0.0.3:1234")
+	assert.Equal(suite.T(), 15*time.Minute, sut.Check("testuser", "127.0.0.4"))
+	suite.KeyValueService.AssertNumberOfCalls(suite.T(), "PutString", 1)
+
+	var lockout models.LoginLockout
+	assert.Nil(suite.T(), json.Unmarshal([]byte(persisted.Value), &lockout))
+	assert.Equal(suite.T(), "testuser", lockout.UserID)
+	assert.Equal(suite.T(), "127.0.0.3", lockout.LastIp)
+	assert.Equal(suite.T(), now.Add(15*time.Minute), lockout.LockedUntil)
+
+	// lockout has expired
+	now = now.Add(16 * time.Minute)
+	assert.Zero(suite.T(), sut.Check("testuser", "127.0.0.4"))
+	sut.RecordFailure(suite.TestUser, "testuser", "127.0.0.4")
+	assert.Equal(suite.T(), 1*time.Second, sut.Check("testuser", "127.0.0.4"))
+
+	sut.RecordSuccess("testuser")
+	assert.Zero(suite.T(), sut.Check("testuser", "127.0.0.5"))
+}
+
+func (suite *LoginThrottleServiceTestSuite) TestLoginThrottleService_Ip() {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	sut := NewLoginThrottleService(suite.KeyValueService, nil)
+	sut.now = func() time.Time { return now }
+
+	suite.KeyValueService.On("MustGetString", mock.Anything).Return(&models.KeyStringValue{})
+
+	// different, non-existing accounts from the same ip
+	for i, username := range []string{"a", "b", "c", "d", "e"} {
+		assert.Zero(suite.T(), sut.Check(username, "10.0.0.1"), i)
+		sut.RecordFailure(nil, username, "10.0.0.1")
+		now = now.Add(time.Minute)
+	}
+	assert.Equal(suite.T(), 14*time.Minute, sut.Check("f", "10.0.0.1"))
+	assert.Zero(suite.T(), sut.Check("f", "10.0.0.2"))
+	suite.KeyValueService.AssertNotCalled(suite.T(), "PutString", mock.Anything)
+}
+
+func (suite *LoginThrottleServiceTestSuite) TestLoginThrottleService_GetLockouts() {
+	sut := NewLoginThrottleService(suite.KeyValueService, nil)
+
+	active, _ := json.Marshal(&models.LoginLockout{UserID: "active", LockedUntil: time.Now().Add(time.Minute)})
+	expired, _ := json.Marshal(&models.LoginLockout{UserID: "expired", LockedUntil: time.Now().Add(-time.Minute)})
+	suite.KeyValueService.On("GetByPrefix", "login_lockout_").Return([]*models.KeyStringValue{
+		{Key: "login_lockout_active", Value: string(active)},
+		{Key: "login_lockout_expired", Value: string(expired)},
+	}, nil)
+
+	lockouts, err := sut.GetLockouts()
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), lockouts, 1)
+	assert.Equal(suite.T(), "active", lockouts[0].UserID)
+}
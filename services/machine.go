@@ -0,0 +1,168 @@
+package services
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+)
+
+// a machine's last-seen timestamp is only persisted every so often, as long as neither its ip nor its operating system changes
+const machineTouchInterval = 5 * time.Minute
+
+// MachineService keeps track of when, with which operating system and from where a user's machines last sent heartbeats,
+// which helps to notice an api key being used from unexpected places
+type MachineService struct {
+	config       *config.Config
+	cache        *cache.Cache
+	eventBus     *hub.Hub
+	httpClient   *http.Client
+	queueDefault *artifex.Dispatcher
+	repository   repositories.IMachineRepository
+}
+
+func NewMachineService(machineRepository repositories.IMachineRepository) *MachineService {
+	srv := &MachineService{
+		config:       config.Get(),
+		cache:        cache.New(24*time.Hour, 24*time.Hour),
+		eventBus:     config.EventBus(),
+		httpClient:   config.NewOutboundClient(utils.OutboundPriorityLow, 10*time.Second),
+		queueDefault: config.GetDefaultQueue(),
+		repository:   machineRepository,
+	}
+
+	onHeartbeat := srv.eventBus.Subscribe(0, config.EventHeartbeatCreate)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			heartbeat := m.Fields[config.FieldPayload].(*models.Heartbeat)
+			if err := srv.track(heartbeat); err != nil {
+				config.Log().Error("failed to track machine for user '%s' - %v", heartbeat.UserID, err)
+			}
+		}
+	}(&onHeartbeat)
+
+	return srv
+}
+
+// GetByUser returns the user's machines, most recently seen ones first
+func (srv *MachineService) GetByUser(userId string) ([]*models.Machine, error) {
+	if machines, found := srv.cache.Get(userId); found {
+		return machines.([]*models.Machine), nil
+	}
+
+	machines, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Set(userId, machines, cache.DefaultExpiration)
+	return machines, nil
+}
+
+// DeleteByUserBefore removes the user's machines, which were not seen anymore since t, as part of the data retention cleanup
+func (srv *MachineService) DeleteByUserBefore(userId string, t time.Time) error {
+	defer srv.cache.Delete(userId)
+	return srv.repository.DeleteByUserBefore(userId, t)
+}
+
+// AnonymizeByUserBefore strips the last ip and location from the user's machines, which were not seen anymore since t, as part of the data retention cleanup
+func (srv *MachineService) AnonymizeByUserBefore(userId string, t time.Time) (int64, error) {
+	defer srv.cache.Delete(userId)
+	return srv.repository.AnonymizeByUserBefore(userId, t)
+}
+
+// track persists the machine a heartbeat was sent from, heartbeats not received via the api (e.g. imported ones) don't have a remote ip and are skipped
+func (srv *MachineService) track(heartbeat *models.Heartbeat) error {
+	if heartbeat.Machine == "" || heartbeat.RemoteIp == "" {
+		return nil
+	}
+
+	machines, err := srv.GetByUser(heartbeat.UserID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	machine := &models.Machine{
+		UserID:          heartbeat.UserID,
+		Name:            heartbeat.Machine,
+		OperatingSystem: heartbeat.OperatingSystem,
+		LastIp:          heartbeat.RemoteIp,
+		LastSeenAt:      models.CustomTime(now),
+	}
+
+	var known *models.Machine
+	for _, m := range machines {
+		if m.Name == machine.Name {
+			known = m
+			break
+		}
+	}
+
+	ipChanged := known == nil || known.LastIp != machine.LastIp
+	if !ipChanged {
+		if known.OperatingSystem == machine.OperatingSystem && now.Sub(known.LastSeenAt.T()) < machineTouchInterval {
+			return nil
+		}
+		machine.Country, machine.City = known.Country, known.City
+	}
+
+	if _, err := srv.repository.Upsert(machine); err != nil {
+		return err
+	}
+	srv.cache.Delete(heartbeat.UserID)
+
+	if ipChanged && srv.config.App.GeoIpUrl != "" {
+		if err := srv.queueDefault.Dispatch(func() {
+			if err := srv.locate(machine); err != nil {
+				config.Log().Warn("failed to look up location of machine '%s' of user '%s' - %v", machine.Name, machine.UserID, err)
+			}
+		}); err != nil {
+			config.Log().Error("failed to dispatch machine location lookup, %v", err)
+		}
+	}
+
+	return nil
+}
+
+// locate looks up the location of the machine's last ip, addresses from private networks are left without a location
+func (srv *MachineService) locate(machine *models.Machine) error {
+	if ip := net.ParseIP(machine.LastIp); ip == nil || ip.IsPrivate() || ip.IsLoopback() || ip.IsUnspecified() {
+		machine.Country, machine.City = "", ""
+	} else {
+		location, err := srv.lookupLocation(machine.LastIp)
+		if err != nil {
+			return err
+		}
+		machine.Country, machine.City = location.Country, location.City
+	}
+
+	if err := srv.repository.UpdateLocation(machine); err != nil {
+		return err
+	}
+	srv.cache.Delete(machine.UserID)
+	return nil
+}
+
+func (srv *MachineService) lookupLocation(ip string) (*models.GeoLocation, error) {
+	res, err := utils.RaiseForStatus(srv.httpClient.Get(strings.ReplaceAll(srv.config.App.GeoIpUrl, "{ip}", url.PathEscape(ip))))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var location models.GeoLocation
+	if err := json.NewDecoder(res.Body).Decode(&location); err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMachineService_Track(t *testing.T) {
+	config.Set(config.Empty())
+
+	known := &models.Machine{UserID: TestUserId, Name: TestMachine1, OperatingSystem: TestOsLinux, LastIp: "203.0.113.7", Country: "Germany", City: "Karlsruhe", LastSeenAt: models.CustomTime(time.Now().Add(-1 * time.Minute))}
+
+	repositoryMock := new(mocks.MachineRepositoryMock)
+	repositoryMock.On("GetByUser", TestUserId).Return([]*models.Machine{known}, nil)
+	repositoryMock.On("Upsert", mock.Anything).Return(&models.Machine{}, nil)
+
+	sut := NewMachineService(repositoryMock)
+
+	// heartbeats without remote ip (e.g. imported ones) are not tracked
+	assert.Nil(t, sut.track(&models.Heartbeat{UserID: TestUserId, Machine: TestMachine2}))
+	// seen recently from the same ip
+	assert.Nil(t, sut.track(&models.Heartbeat{UserID: TestUserId, Machine: TestMachine1, OperatingSystem: TestOsLinux, RemoteIp: "203.0.113.7"}))
+	repositoryMock.AssertNotCalled(t, "Upsert", mock.Anything)
+
+	// same ip, but different operating system, location is kept
+	assert.Nil(t, sut.track(&models.Heartbeat{UserID: TestUserId, Machine: TestMachine1, OperatingSystem: TestOsWin, RemoteIp: "203.0.113.7"}))
+	// new machine
+	assert.Nil(t, sut.track(&models.Heartbeat{UserID: TestUserId, Machine: TestMachine2, OperatingSystem: TestOsLinux, RemoteIp: "198.51.100.1"}))
+
+	repositoryMock.AssertNumberOfCalls(t, "Upsert", 2)
+	upserted1 := repositoryMock.Calls[1].Arguments[0].(*models.Machine)
+	assert.Equal(t, TestOsWin, upserted1.OperatingSystem)
+	assert.Equal(t, "Germany", upserted1.Country)
+	upserted2 := repositoryMock.Calls[3].Arguments[0].(*models.Machine)
+	assert.Equal(t, TestMachine2, upserted2.Name)
+	assert.Equal(t, "198.51.100.1", upserted2.LastIp)
+	assert.Empty(t, upserted2.Country)
+}
+
+func TestMachineService_Locate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/json/198.51.100.1", r.URL.Path)
+		w.Write([]byte(`{"status": "success", "country": "Germany", "countryCode": "DE", "city": "Karlsruhe"}`))
+	}))
+	defer server.Close()
+
+	cfg := config.Empty()
+	cfg.App.GeoIpUrl = server.URL + "/json/{ip}"
+	config.Set(cfg)
+
+	repositoryMock := new(mocks.MachineRepositoryMock)
+	repositoryMock.On("UpdateLocation", mock.Anything).Return(nil)
+
+	sut := NewMachineService(repositoryMock)
+
+	machine := &models.Machine{UserID: TestUserId, Name: TestMachine1, LastIp: "198.51.100.1"}
+	assert.Nil(t, sut.locate(machine))
+	assert.Equal(t, "Germany", machine.Country)
+	assert.Equal(t, "Karlsruhe", machine.City)
+
+	// no lookup for private addresses
+	machine = &models.Machine{UserID: TestUserId, Name: TestMachine1, LastIp: "192.168.0.10"}
+	assert.Nil(t, sut.locate(machine))
+	assert.Empty(t, machine.Country)
+
+	repositoryMock.AssertNumberOfCalls(t, "UpdateLocation", 2)
+}
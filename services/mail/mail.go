@@ -9,6 +9,7 @@ import (
 	"github.com/muety/wakapi/services"
 	"github.com/muety/wakapi/utils"
 	"github.com/muety/wakapi/views/mail"
+	"sync"
 	"time"
 
 	conf "github.com/muety/wakapi/config"
@@ -20,11 +21,28 @@ const (
 	tplNameWakatimeFailureNotification = "wakatime_connection_failure"
 	tplNameReport                      = "report"
 	tplNameSubscriptionNotification    = "subscription_expiring"
+	tplNameAccountDeletion             = "account_deletion"
+	tplNameUsernameChange              = "username_changed"
+	tplNameNotificationDigest          = "notification_digest"
+	tplNameOutdatedClients             = "outdated_clients"
+	tplNameEmailVerification           = "verify_email"
+	tplNameInactivityAlert             = "inactivity_alert"
+	tplNameAchievementsUnlocked        = "achievements_unlocked"
+	tplNameLoginLockout                = "login_lockout"
 	subjectPasswordReset               = "Wakapi - Password Reset"
 	subjectImportNotification          = "Wakapi - Data Import Finished"
 	subjectWakatimeFailureNotification = "Wakapi - WakaTime Connection Failure"
 	subjectReport                      = "Wakapi - Report from %s"
 	subjectSubscriptionNotification    = "Wakapi - Subscription expiring / expired"
+	subjectAccountDeletion             = "Wakapi - Confirm Account Deletion"
+	subjectUsernameChange              = "Wakapi - Username Changed"
+	subjectNotificationDigest          = "Wakapi - Notification Digest (%d)"
+	subjectOutdatedClients             = "Wakapi - Outdated Clients"
+	subjectEmailVerification           = "Wakapi - Verify E-Mail Address"
+	subjectInactivityAlert             = "Wakapi - No Coding Activity Received"
+	subjectAchievementsUnlocked        = "Wakapi - New Achievements Unlocked"
+	subjectLoginLockout                = "Wakapi - Account Temporarily Locked"
+	subjectTestMail                    = "Wakapi - Test Mail"
 )
 
 type SendingService interface {
@@ -35,22 +53,12 @@ type MailService struct {
 	config         *conf.Config
 	sendingService SendingService
 	templates      utils.TemplateMap
+	lock           sync.RWMutex
 }
 
 func NewMailService() services.IMailService {
 	config := conf.Get()
 
-	var sendingService SendingService
-	sendingService = &NoopSendingService{}
-
-	if config.Mail.Enabled {
-		if config.Mail.Provider == conf.MailProviderMailWhale {
-			sendingService = NewMailWhaleSendingService(config.Mail.MailWhale)
-		} else if config.Mail.Provider == conf.MailProviderSmtp {
-			sendingService = NewSMTPSendingService(config.Mail.Smtp)
-		}
-	}
-
 	// Use local file system when in 'dev' environment, go embed file system otherwise
 	templateFs := conf.ChooseFS("views/mail", mail.TemplateFiles)
 	templates, err := utils.LoadTemplates(templateFs, routes.DefaultTemplateFuncs())
@@ -58,7 +66,38 @@ func NewMailService() services.IMailService {
 		panic(err)
 	}
 
-	return &MailService{sendingService: sendingService, config: config, templates: templates}
+	srv := &MailService{sendingService: newSendingService(config), config: config, templates: templates}
+
+	conf.OnReload(func() {
+		srv.lock.Lock()
+		defer srv.lock.Unlock()
+		srv.sendingService = newSendingService(conf.Get())
+	}, conf.ReloadKeyMail)
+
+	return srv
+}
+
+func newSendingService(config *conf.Config) SendingService {
+	if config.Mail.Enabled {
+		if config.Mail.Provider == conf.MailProviderMailWhale {
+			return NewMailWhaleSendingService(config.Mail.MailWhale)
+		} else if config.Mail.Provider == conf.MailProviderSmtp {
+			return NewSMTPSendingService(config.Mail.Smtp)
+		} else if config.Mail.Provider == conf.MailProviderSes {
+			return NewSESSendingService(config.Mail.Ses)
+		} else if config.Mail.Provider == conf.MailProviderSendgrid {
+			return NewSendgridSendingService(config.Mail.Sendgrid)
+		} else if config.Mail.Provider == conf.MailProviderMailgun {
+			return NewMailgunSendingService(config.Mail.Mailgun)
+		}
+	}
+	return &NoopSendingService{}
+}
+
+func (m *MailService) sender() SendingService {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.sendingService
 }
 
 func (m *MailService) SendPasswordReset(recipient *models.User, resetLink string) error {
@@ -67,12 +106,12 @@ func (m *MailService) SendPasswordReset(recipient *models.User, resetLink string
 		return err
 	}
 	mail := &models.Mail{
-		From:    models.MailAddress(m.config.Mail.Sender),
+		From:    models.MailAddress(conf.Get().Mail.Sender),
 		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
 		Subject: subjectPasswordReset,
 	}
 	mail.WithHTML(tpl.String())
-	return m.sendingService.Send(mail)
+	return m.sender().Send(mail)
 }
 
 func (m *MailService) SendWakatimeFailureNotification(recipient *models.User, numFailures int) error {
@@ -84,12 +123,12 @@ func (m *MailService) SendWakatimeFailureNotification(recipient *models.User, nu
 		return err
 	}
 	mail := &models.Mail{
-		From:    models.MailAddress(m.config.Mail.Sender),
+		From:    models.MailAddress(conf.Get().Mail.Sender),
 		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
 		Subject: subjectWakatimeFailureNotification,
 	}
 	mail.WithHTML(tpl.String())
-	return m.sendingService.Send(mail)
+	return m.sender().Send(mail)
 }
 
 func (m *MailService) SendImportNotification(recipient *models.User, duration time.Duration, numHeartbeats int) error {
@@ -102,12 +141,12 @@ func (m *MailService) SendImportNotification(recipient *models.User, duration ti
 		return err
 	}
 	mail := &models.Mail{
-		From:    models.MailAddress(m.config.Mail.Sender),
+		From:    models.MailAddress(conf.Get().Mail.Sender),
 		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
 		Subject: subjectImportNotification,
 	}
 	mail.WithHTML(tpl.String())
-	return m.sendingService.Send(mail)
+	return m.sender().Send(mail)
 }
 
 func (m *MailService) SendReport(recipient *models.User, report *models.Report) error {
@@ -116,12 +155,21 @@ func (m *MailService) SendReport(recipient *models.User, report *models.Report)
 		return err
 	}
 	mail := &models.Mail{
-		From:    models.MailAddress(m.config.Mail.Sender),
+		From:    models.MailAddress(conf.Get().Mail.Sender),
 		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
 		Subject: fmt.Sprintf(subjectReport, helpers.FormatDateHuman(time.Now().In(recipient.TZ()))),
 	}
 	mail.WithHTML(tpl.String())
-	return m.sendingService.Send(mail)
+	return m.sender().Send(mail)
+}
+
+// RenderReport renders a report using the same template as for e-mails, e.g. for viewing it in the browser
+func (m *MailService) RenderReport(report *models.Report) (string, error) {
+	tpl, err := m.getReportTemplate(ReportTplData{report})
+	if err != nil {
+		return "", err
+	}
+	return tpl.String(), nil
 }
 
 func (m *MailService) SendSubscriptionNotification(recipient *models.User, hasExpired bool) error {
@@ -134,12 +182,160 @@ func (m *MailService) SendSubscriptionNotification(recipient *models.User, hasEx
 		return err
 	}
 	mail := &models.Mail{
-		From:    models.MailAddress(m.config.Mail.Sender),
+		From:    models.MailAddress(conf.Get().Mail.Sender),
 		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
 		Subject: subjectSubscriptionNotification,
 	}
 	mail.WithHTML(tpl.String())
-	return m.sendingService.Send(mail)
+	return m.sender().Send(mail)
+}
+
+func (m *MailService) SendAccountDeletionConfirmation(recipient *models.User, confirmLink string) error {
+	tpl, err := m.getAccountDeletionTemplate(AccountDeletionTplData{
+		ConfirmLink: confirmLink,
+		GraceDays:   m.config.App.AccountDeletionGraceDays,
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(conf.Get().Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: subjectAccountDeletion,
+	}
+	mail.WithHTML(tpl.String())
+	return m.sender().Send(mail)
+}
+
+func (m *MailService) SendEmailVerification(recipient *models.User, verifyLink string) error {
+	tpl, err := m.getEmailVerificationTemplate(EmailVerificationTplData{VerifyLink: verifyLink})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(conf.Get().Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: subjectEmailVerification,
+	}
+	mail.WithHTML(tpl.String())
+	return m.sender().Send(mail)
+}
+
+func (m *MailService) SendUsernameChange(recipient *models.User, oldUsername string) error {
+	tpl, err := m.getUsernameChangeTemplate(UsernameChangeTplData{
+		PublicUrl:   m.config.Server.PublicUrl,
+		OldUsername: oldUsername,
+		NewUsername: recipient.ID,
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(conf.Get().Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: subjectUsernameChange,
+	}
+	mail.WithHTML(tpl.String())
+	return m.sender().Send(mail)
+}
+
+func (m *MailService) SendNotificationDigest(recipient *models.User, notifications []*models.Notification) error {
+	tpl, err := m.getNotificationDigestTemplate(NotificationDigestTplData{
+		PublicUrl:     m.config.Server.PublicUrl,
+		Notifications: notifications,
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(conf.Get().Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: fmt.Sprintf(subjectNotificationDigest, len(notifications)),
+	}
+	mail.WithHTML(tpl.String())
+	return m.sender().Send(mail)
+}
+
+func (m *MailService) SendOutdatedClientsNotification(recipient *models.User, clients []*models.OutdatedClient) error {
+	tpl, err := m.getOutdatedClientsTemplate(OutdatedClientsTplData{
+		PublicUrl: m.config.Server.PublicUrl,
+		Clients:   clients,
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(conf.Get().Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: subjectOutdatedClients,
+	}
+	mail.WithHTML(tpl.String())
+	return m.sender().Send(mail)
+}
+
+func (m *MailService) SendInactivityAlert(recipient *models.User, lastHeartbeat time.Time, days int) error {
+	tpl, err := m.getInactivityAlertTemplate(InactivityAlertTplData{
+		PublicUrl:     m.config.Server.PublicUrl,
+		LastHeartbeat: lastHeartbeat.In(recipient.TZ()),
+		Days:          days,
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(conf.Get().Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: subjectInactivityAlert,
+	}
+	mail.WithHTML(tpl.String())
+	return m.sender().Send(mail)
+}
+
+func (m *MailService) SendAchievementsUnlocked(recipient *models.User, achievements []*models.Achievement) error {
+	tpl, err := m.getAchievementsUnlockedTemplate(AchievementsUnlockedTplData{
+		PublicUrl:    m.config.Server.PublicUrl,
+		Achievements: achievements,
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(conf.Get().Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: subjectAchievementsUnlocked,
+	}
+	mail.WithHTML(tpl.String())
+	return m.sender().Send(mail)
+}
+
+func (m *MailService) SendLoginLockout(recipient *models.User, lockout *models.LoginLockout) error {
+	tpl, err := m.getLoginLockoutTemplate(LoginLockoutTplData{
+		PublicUrl:   m.config.Server.PublicUrl,
+		Failures:    lockout.Failures,
+		LastIp:      lockout.LastIp,
+		LockedUntil: lockout.LockedUntil.In(recipient.TZ()),
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(conf.Get().Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: subjectLoginLockout,
+	}
+	mail.WithHTML(tpl.String())
+	return m.sender().Send(mail)
+}
+
+// SendTestMail sends a plain mail to check the mail setup, errors are passed on as-is to let admins see what went wrong
+func (m *MailService) SendTestMail(recipient string) error {
+	mail := &models.Mail{
+		From:    models.MailAddress(conf.Get().Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient)}),
+		Subject: subjectTestMail,
+	}
+	mail.WithText(fmt.Sprintf("This is a test mail from your Wakapi instance at %s, sent via %s. If you are reading this, your mail setup works.", m.config.Server.PublicUrl, conf.Get().Mail.Provider))
+	return m.sender().Send(mail)
 }
 
 func (m *MailService) getPasswordResetTemplate(data PasswordResetTplData) (*bytes.Buffer, error) {
@@ -182,6 +378,70 @@ func (m *MailService) getSubscriptionNotificationTemplate(data SubscriptionNotif
 	return &rendered, nil
 }
 
+func (m *MailService) getAccountDeletionTemplate(data AccountDeletionTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameAccountDeletion)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+func (m *MailService) getEmailVerificationTemplate(data EmailVerificationTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameEmailVerification)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+func (m *MailService) getUsernameChangeTemplate(data UsernameChangeTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameUsernameChange)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+func (m *MailService) getNotificationDigestTemplate(data NotificationDigestTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameNotificationDigest)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+func (m *MailService) getOutdatedClientsTemplate(data OutdatedClientsTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameOutdatedClients)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+func (m *MailService) getInactivityAlertTemplate(data InactivityAlertTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameInactivityAlert)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+func (m *MailService) getAchievementsUnlockedTemplate(data AchievementsUnlockedTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameAchievementsUnlocked)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+func (m *MailService) getLoginLockoutTemplate(data LoginLockoutTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameLoginLockout)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
 func (m *MailService) fmtName(name string) string {
 	return fmt.Sprintf("%s.tpl.html", name)
 }
@@ -0,0 +1,75 @@
+package mail
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+// MailgunSendingService sends mails through the mailgun v3 api, see https://documentation.mailgun.com/en/latest/api-sending.html
+type MailgunSendingService struct {
+	config     conf.MailgunMailConfig
+	apiUrl     string
+	httpClient *http.Client
+}
+
+type mailgunErrorResponse struct {
+	Message string `json:"message"`
+}
+
+func NewMailgunSendingService(config conf.MailgunMailConfig) *MailgunSendingService {
+	return &MailgunSendingService{
+		config:     config,
+		apiUrl:     config.ApiUrl(),
+		httpClient: conf.NewOutboundClient(utils.OutboundPriorityNormal, 10*time.Second),
+	}
+}
+
+func (s *MailgunSendingService) Send(mail *models.Mail) error {
+	if len(mail.To) == 0 {
+		return errors.New("not sending mail as recipient mail address seems to be invalid")
+	}
+
+	form := url.Values{
+		"from":    []string{mail.From.String()},
+		"to":      mail.To.Strings(),
+		"subject": []string{mail.Subject},
+	}
+	if mail.Type == models.HtmlType {
+		form.Set("html", mail.Body)
+	} else {
+		form.Set("text", mail.Body)
+	}
+	if s.config.TestMode {
+		form.Set("o:testmode", "yes")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/v3/%s/messages", s.apiUrl, url.PathEscape(s.config.Domain)), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("api", s.config.ApiKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return checkProviderResponse(conf.MailProviderMailgun, res, func(body []byte) string {
+		var errorResponse mailgunErrorResponse
+		if err := json.Unmarshal(body, &errorResponse); err != nil {
+			return ""
+		}
+		return errorResponse.Message
+	})
+}
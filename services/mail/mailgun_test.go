@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMailgunSendingService_Send(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/mg.wakapi.dev/messages", r.URL.Path)
+		if user, key, _ := r.BasicAuth(); user != "api" || key != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("Forbidden"))
+			return
+		}
+
+		r.ParseForm()
+		if r.Form.Get("to") == "bounce@example.org" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message": "to parameter is not a valid address. please check documentation"}`))
+			return
+		}
+		assert.Equal(t, "Wakapi <noreply@wakapi.dev>", r.Form.Get("from"))
+		assert.Equal(t, []string{"john.doe@example.org", "Jane Doe <jane.doe@example.org>"}, r.Form["to"])
+		assert.Equal(t, "Hello", r.Form.Get("text"))
+		assert.Equal(t, "yes", r.Form.Get("o:testmode"))
+		w.Write([]byte(`{"id": "<20230601@mg.wakapi.dev>", "message": "Queued. Thank you."}`))
+	}))
+	defer server.Close()
+
+	sut := NewMailgunSendingService(conf.MailgunMailConfig{ApiKey: "key", Domain: "mg.wakapi.dev", Region: "eu", TestMode: true})
+	assert.Equal(t, "https://api.eu.mailgun.net", sut.apiUrl)
+	sut.apiUrl = server.URL
+
+	mail := &models.Mail{From: "Wakapi <noreply@wakapi.dev>", To: models.MailAddresses{"john.doe@example.org", "Jane Doe <jane.doe@example.org>"}, Subject: "Test", Body: "Hello"}
+	assert.Nil(t, sut.Send(mail))
+
+	mail.To = models.MailAddresses{"bounce@example.org"}
+	err := sut.Send(mail)
+	var providerErr *ProviderError
+	assert.ErrorAs(t, err, &providerErr)
+	assert.Equal(t, "to parameter is not a valid address. please check documentation", providerErr.Message)
+
+	sut.config.ApiKey = "invalid"
+	assert.EqualError(t, sut.Send(mail), "mailgun refused to send mail (status 401) - Forbidden")
+}
@@ -28,10 +28,8 @@ type MailWhaleSendRequest struct {
 
 func NewMailWhaleSendingService(config conf.MailwhaleMailConfig) *MailWhaleSendingService {
 	return &MailWhaleSendingService{
-		config: config,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		config:     config,
+		httpClient: conf.NewOutboundClient(utils.OutboundPriorityNormal, 10*time.Second),
 	}
 }
 
@@ -0,0 +1,117 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+const sendgridApiUrl = "https://api.sendgrid.com"
+
+// SendgridSendingService sends mails through the sendgrid v3 api, see https://docs.sendgrid.com/api-reference/mail-send/mail-send
+type SendgridSendingService struct {
+	config     conf.SendgridMailConfig
+	apiUrl     string
+	httpClient *http.Client
+}
+
+type sendgridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendgridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendgridPersonalization struct {
+	To []sendgridAddress `json:"to"`
+}
+
+type sendgridSendRequest struct {
+	Personalizations []sendgridPersonalization `json:"personalizations"`
+	From             sendgridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendgridContent         `json:"content"`
+	MailSettings     struct {
+		SandboxMode struct {
+			Enable bool `json:"enable"`
+		} `json:"sandbox_mode"`
+	} `json:"mail_settings"`
+}
+
+type sendgridErrorResponse struct {
+	Errors []struct {
+		Message string `json:"message"`
+		Field   string `json:"field"`
+	} `json:"errors"`
+}
+
+func NewSendgridSendingService(config conf.SendgridMailConfig) *SendgridSendingService {
+	return &SendgridSendingService{
+		config:     config,
+		apiUrl:     sendgridApiUrl,
+		httpClient: conf.NewOutboundClient(utils.OutboundPriorityNormal, 10*time.Second),
+	}
+}
+
+func (s *SendgridSendingService) Send(mail *models.Mail) error {
+	if len(mail.To) == 0 {
+		return errors.New("not sending mail as recipient mail address seems to be invalid")
+	}
+
+	recipients := make([]sendgridAddress, 0, len(mail.To))
+	for _, to := range mail.To {
+		recipients = append(recipients, sendgridAddress{Email: to.Raw(), Name: to.Name()})
+	}
+
+	sendRequest := &sendgridSendRequest{
+		Personalizations: []sendgridPersonalization{{To: recipients}},
+		From:             sendgridAddress{Email: mail.From.Raw(), Name: mail.From.Name()},
+		Subject:          mail.Subject,
+	}
+	if mail.Type == models.HtmlType {
+		sendRequest.Content = []sendgridContent{{Type: "text/html", Value: mail.Body}}
+	} else {
+		sendRequest.Content = []sendgridContent{{Type: "text/plain", Value: mail.Body}}
+	}
+	sendRequest.MailSettings.SandboxMode.Enable = s.config.Sandbox
+	payload, _ := json.Marshal(sendRequest)
+
+	req, err := http.NewRequest(http.MethodPost, s.apiUrl+"/v3/mail/send", bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.config.ApiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return checkProviderResponse(conf.MailProviderSendgrid, res, func(body []byte) string {
+		var errorResponse sendgridErrorResponse
+		if err := json.Unmarshal(body, &errorResponse); err != nil {
+			return ""
+		}
+		messages := make([]string, 0, len(errorResponse.Errors))
+		for _, e := range errorResponse.Errors {
+			if e.Field != "" {
+				messages = append(messages, e.Field+": "+e.Message)
+			} else {
+				messages = append(messages, e.Message)
+			}
+		}
+		return strings.Join(messages, "; ")
+	})
+}
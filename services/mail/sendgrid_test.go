@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendgridSendingService_Send(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/mail/send", r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errors": [{"message": "The provided authorization grant is invalid, expired, or revoked", "field": null}]}`))
+			return
+		}
+
+		var payload sendgridSendRequest
+		json.NewDecoder(r.Body).Decode(&payload)
+		assert.Equal(t, sendgridAddress{Email: "noreply@wakapi.dev", Name: "Wakapi"}, payload.From)
+		assert.Equal(t, []sendgridAddress{{Email: "john.doe@example.org"}}, payload.Personalizations[0].To)
+		assert.Equal(t, "text/html", payload.Content[0].Type)
+		assert.True(t, payload.MailSettings.SandboxMode.Enable)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	mail := &models.Mail{From: "Wakapi <noreply@wakapi.dev>", To: models.MailAddresses{"john.doe@example.org"}, Subject: "Test"}
+	mail.WithHTML("<p>Hello</p>")
+
+	sut := NewSendgridSendingService(conf.SendgridMailConfig{ApiKey: "key", Sandbox: true})
+	sut.apiUrl = server.URL
+	assert.Nil(t, sut.Send(mail))
+
+	sut.config.ApiKey = "invalid"
+	err := sut.Send(mail)
+	var providerErr *ProviderError
+	assert.ErrorAs(t, err, &providerErr)
+	assert.Equal(t, http.StatusUnauthorized, providerErr.Status)
+	assert.Equal(t, "The provided authorization grant is invalid, expired, or revoked", providerErr.Message)
+}
@@ -0,0 +1,88 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ses"
+	"github.com/aws/aws-sdk-go-v2/service/ses/types"
+	"github.com/aws/smithy-go"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+const sesCharset = "UTF-8"
+
+// SESSendingService sends mails through amazon ses, see https://docs.aws.amazon.com/ses/latest/APIReference/API_SendEmail.html
+type SESSendingService struct {
+	config conf.SESMailConfig
+	client *ses.Client
+}
+
+func NewSESSendingService(config conf.SESMailConfig) *SESSendingService {
+	options := ses.Options{
+		Region:      config.Region,
+		Credentials: credentials.NewStaticCredentialsProvider(config.AccessKey, config.SecretKey, ""),
+		HTTPClient:  conf.NewOutboundClient(utils.OutboundPriorityNormal, 10*time.Second),
+	}
+	if config.Endpoint != "" {
+		options.BaseEndpoint = aws.String(config.ApiUrl())
+	}
+
+	return &SESSendingService{
+		config: config,
+		client: ses.New(options),
+	}
+}
+
+func (s *SESSendingService) Send(mail *models.Mail) error {
+	if len(mail.To) == 0 {
+		return errors.New("not sending mail as recipient mail address seems to be invalid")
+	}
+
+	content := &types.Content{Data: aws.String(mail.Body), Charset: aws.String(sesCharset)}
+	input := &ses.SendEmailInput{
+		Source:      aws.String(mail.From.String()),
+		Destination: &types.Destination{ToAddresses: mail.To.Strings()},
+		Message: &types.Message{
+			Subject: &types.Content{Data: aws.String(mail.Subject), Charset: aws.String(sesCharset)},
+			Body:    &types.Body{},
+		},
+	}
+	if mail.Type == models.HtmlType {
+		input.Message.Body.Html = content
+	} else {
+		input.Message.Body.Text = content
+	}
+	if s.config.ConfigurationSet != "" {
+		input.ConfigurationSetName = aws.String(s.config.ConfigurationSet)
+	}
+
+	if _, err := s.client.SendEmail(context.Background(), input); err != nil {
+		return sesProviderError(err)
+	}
+	return nil
+}
+
+// sesProviderError turns errors returned by the api into a ProviderError, like for every other provider
+func sesProviderError(err error) error {
+	var responseErr *awshttp.ResponseError
+	if !errors.As(err, &responseErr) {
+		return err
+	}
+
+	message := responseErr.Err.Error()
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorMessage() != "" {
+		message = apiErr.ErrorMessage()
+	}
+	if len(message) > maxProviderErrorLength {
+		message = message[:maxProviderErrorLength] + "..."
+	}
+	return &ProviderError{Provider: conf.MailProviderSes, Status: responseErr.HTTPStatusCode(), Message: message}
+}
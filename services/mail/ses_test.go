@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSESSendingService_Send(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-central-1/ses/aws4_request")
+
+		r.ParseForm()
+		assert.Equal(t, "SendEmail", r.Form.Get("Action"))
+		if r.Form.Get("Destination.ToAddresses.member.1") == "bounce@example.org" {
+			w.Header().Set("Content-Type", "text/xml")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>MessageRejected</Code><Message>Email address is not verified.</Message></Error><RequestId>1</RequestId></ErrorResponse>`))
+			return
+		}
+		assert.Equal(t, "Wakapi <noreply@wakapi.dev>", r.Form.Get("Source"))
+		assert.Equal(t, "john.doe@example.org", r.Form.Get("Destination.ToAddresses.member.1"))
+		assert.Equal(t, "Test", r.Form.Get("Message.Subject.Data"))
+		assert.Equal(t, "Hello", r.Form.Get("Message.Body.Text.Data"))
+		assert.Equal(t, "bounces", r.Form.Get("ConfigurationSetName"))
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<SendEmailResponse><SendEmailResult><MessageId>1</MessageId></SendEmailResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></SendEmailResponse>`))
+	}))
+	defer server.Close()
+
+	sut := NewSESSendingService(conf.SESMailConfig{Region: "eu-central-1", AccessKey: "key", SecretKey: "secret", Endpoint: server.URL, ConfigurationSet: "bounces"})
+
+	mail := &models.Mail{From: "Wakapi <noreply@wakapi.dev>", To: models.MailAddresses{"john.doe@example.org"}, Subject: "Test", Body: "Hello"}
+	assert.Nil(t, sut.Send(mail))
+
+	mail.To = models.MailAddresses{"bounce@example.org"}
+	err := sut.Send(mail)
+	var providerErr *ProviderError
+	assert.ErrorAs(t, err, &providerErr)
+	assert.Equal(t, 400, providerErr.Status)
+	assert.Equal(t, "Email address is not verified.", providerErr.Message)
+}
@@ -1,35 +1,57 @@
 package mail
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/hex"
 	"errors"
+	"io"
+	"strings"
+
 	"github.com/emersion/go-sasl"
 	"github.com/emersion/go-smtp"
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
-	"io"
+	"github.com/muety/wakapi/utils"
 )
 
 type SMTPSendingService struct {
 	config conf.SMTPMailConfig
 	auth   sasl.Client
+	dkim   *utils.DkimSigner
+}
+
+// cramMd5Client implements the CRAM-MD5 sasl mechanism, see https://www.rfc-editor.org/rfc/rfc2195
+type cramMd5Client struct {
+	username string
+	password string
 }
 
 func NewSMTPSendingService(config conf.SMTPMailConfig) *SMTPSendingService {
-	return &SMTPSendingService{
+	srv := &SMTPSendingService{
 		config: config,
-		auth: sasl.NewPlainClient(
-			"",
-			config.Username,
-			config.Password,
-		),
+		auth:   newSMTPAuthClient(config),
 	}
+
+	if config.Dkim.Enabled() {
+		signer, err := config.Dkim.Signer()
+		if err != nil {
+			conf.Log().Error("failed to load dkim key, sending mails unsigned - %v", err)
+		}
+		srv.dkim = signer
+	}
+
+	return srv
 }
 
 func (s *SMTPSendingService) Send(mail *models.Mail) error {
 	mail = mail.Sanitized()
 
+	encryption := s.config.EffectiveEncryption()
+
 	dial := smtp.Dial
-	if s.config.TLS {
+	if encryption == conf.SmtpEncryptionTls {
 		dial = func(addr string) (*smtp.Client, error) {
 			return smtp.DialTLS(addr, nil)
 		}
@@ -42,13 +64,24 @@ func (s *SMTPSendingService) Send(mail *models.Mail) error {
 
 	defer c.Close()
 
-	if ok, _ := c.Extension("STARTTLS"); ok {
+	switch encryption {
+	case conf.SmtpEncryptionStartTls:
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("smtp: server doesn't support STARTTLS")
+		}
 		if err = c.StartTLS(nil); err != nil {
-			errCode := err.(*smtp.SMTPError).Code
-			if errCode == 503 {
-				// TLS already active
-			} else {
-				return err
+			return err
+		}
+	case "":
+		// legacy behavior, use starttls if offered
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err = c.StartTLS(nil); err != nil {
+				errCode := err.(*smtp.SMTPError).Code
+				if errCode == 503 {
+					// TLS already active
+				} else {
+					return err
+				}
 			}
 		}
 	}
@@ -65,6 +98,14 @@ func (s *SMTPSendingService) Send(mail *models.Mail) error {
 			return err
 		}
 	}
+
+	message := []byte(mail.String())
+	if s.dkim != nil {
+		if message, err = s.dkim.Sign(message); err != nil {
+			return err
+		}
+	}
+
 	if err = c.Mail(mail.From.Raw(), nil); err != nil {
 		return err
 	}
@@ -77,7 +118,7 @@ func (s *SMTPSendingService) Send(mail *models.Mail) error {
 	if err != nil {
 		return err
 	}
-	_, err = io.Copy(w, mail.Reader())
+	_, err = io.Copy(w, bytes.NewReader(message))
 	if err != nil {
 		return err
 	}
@@ -87,3 +128,24 @@ func (s *SMTPSendingService) Send(mail *models.Mail) error {
 	}
 	return c.Quit()
 }
+
+func newSMTPAuthClient(config conf.SMTPMailConfig) sasl.Client {
+	switch strings.ToUpper(config.AuthMechanism) {
+	case conf.SmtpAuthLogin:
+		return sasl.NewLoginClient(config.Username, config.Password)
+	case conf.SmtpAuthCramMd5:
+		return &cramMd5Client{username: config.Username, password: config.Password}
+	default:
+		return sasl.NewPlainClient("", config.Username, config.Password)
+	}
+}
+
+func (c *cramMd5Client) Start() (string, []byte, error) {
+	return conf.SmtpAuthCramMd5, nil, nil
+}
+
+func (c *cramMd5Client) Next(challenge []byte) ([]byte, error) {
+	h := hmac.New(md5.New, []byte(c.password))
+	h.Write(challenge)
+	return []byte(c.username + " " + hex.EncodeToString(h.Sum(nil))), nil
+}
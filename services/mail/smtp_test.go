@@ -0,0 +1,22 @@
+package mail
+
+import (
+	"testing"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCramMd5Client(t *testing.T) {
+	// example taken from https://www.rfc-editor.org/rfc/rfc2195#section-2
+	sut := newSMTPAuthClient(conf.SMTPMailConfig{Username: "tim", Password: "tanstaaftanstaaf", AuthMechanism: "cram-md5"})
+
+	mechanism, ir, err := sut.Start()
+	assert.Nil(t, err)
+	assert.Equal(t, "CRAM-MD5", mechanism)
+	assert.Nil(t, ir)
+
+	response, err := sut.Next([]byte("<1896.697170952@postoffice.reston.mci.net>"))
+	assert.Nil(t, err)
+	assert.Equal(t, "tim b913a602c7eda7a495b4e6e7334d3890", string(response))
+}
@@ -1,6 +1,9 @@
 package mail
 
-import "github.com/muety/wakapi/models"
+import (
+	"github.com/muety/wakapi/models"
+	"time"
+)
 
 type PasswordResetTplData struct {
 	ResetLink string
@@ -26,3 +29,46 @@ type SubscriptionNotificationTplData struct {
 	HasExpired          bool
 	DataRetentionMonths int
 }
+
+type AccountDeletionTplData struct {
+	ConfirmLink string
+	GraceDays   int
+}
+
+type EmailVerificationTplData struct {
+	VerifyLink string
+}
+
+type UsernameChangeTplData struct {
+	PublicUrl   string
+	OldUsername string
+	NewUsername string
+}
+
+type NotificationDigestTplData struct {
+	PublicUrl     string
+	Notifications []*models.Notification
+}
+
+type InactivityAlertTplData struct {
+	PublicUrl     string
+	LastHeartbeat time.Time
+	Days          int
+}
+
+type AchievementsUnlockedTplData struct {
+	PublicUrl    string
+	Achievements []*models.Achievement
+}
+
+type LoginLockoutTplData struct {
+	PublicUrl   string
+	Failures    int
+	LastIp      string
+	LockedUntil time.Time
+}
+
+type OutdatedClientsTplData struct {
+	PublicUrl string
+	Clients   []*models.OutdatedClient
+}
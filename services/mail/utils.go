@@ -1 +1,41 @@
 package mail
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const maxProviderErrorLength = 512
+
+// ProviderError is returned when a mail provider's api refuses to send a mail, carrying the reason the provider gave
+type ProviderError struct {
+	Provider string
+	Status   int
+	Message  string
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s refused to send mail (status %d) - %s", e.Provider, e.Status, e.Message)
+}
+
+// checkProviderResponse returns a ProviderError for unsuccessful responses, using the given function to extract a message from the provider's error response, if possible
+func checkProviderResponse(provider string, res *http.Response, parseMessage func([]byte) string) error {
+	if res.StatusCode < 400 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 16*1024))
+	message := parseMessage(body)
+	if message == "" {
+		message = strings.TrimSpace(string(body))
+	}
+	if message == "" {
+		message = http.StatusText(res.StatusCode)
+	}
+	if len(message) > maxProviderErrorLength {
+		message = message[:maxProviderErrorLength] + "..."
+	}
+	return &ProviderError{Provider: provider, Status: res.StatusCode, Message: message}
+}
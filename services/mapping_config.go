@@ -0,0 +1,182 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+// MappingConfigService exports and imports a user's aliases, project labels and labeling rules as a whole
+type MappingConfigService struct {
+	config           *config.Config
+	repository       repositories.IMappingConfigRepository
+	aliasSrvc        IAliasService
+	projectLabelSrvc IProjectLabelService
+	labelRuleSrvc    ILabelRuleService
+}
+
+func NewMappingConfigService(mappingConfigRepository repositories.IMappingConfigRepository, aliasService IAliasService, projectLabelService IProjectLabelService, labelRuleService ILabelRuleService) *MappingConfigService {
+	return &MappingConfigService{
+		config:           config.Get(),
+		repository:       mappingConfigRepository,
+		aliasSrvc:        aliasService,
+		projectLabelSrvc: projectLabelService,
+		labelRuleSrvc:    labelRuleService,
+	}
+}
+
+// Export returns the user's full mapping configuration, sorted deterministically to produce minimal diffs when version-controlled
+func (srv *MappingConfigService) Export(user *models.User) (*models.MappingConfig, error) {
+	aliases, err := srv.aliasSrvc.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := srv.projectLabelSrvc.GetByUserGroupedInverted(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := srv.labelRuleSrvc.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.MappingConfig{
+		Aliases:    []*models.MappingConfigAlias{},
+		Labels:     []*models.MappingConfigLabel{},
+		LabelRules: []*models.MappingConfigLabelRule{},
+	}
+
+	aliasesByKey := map[uint8]map[string]*models.MappingConfigAlias{}
+	for _, a := range aliases {
+		if _, ok := aliasesByKey[a.Type]; !ok {
+			aliasesByKey[a.Type] = map[string]*models.MappingConfigAlias{}
+		}
+		if _, ok := aliasesByKey[a.Type][a.Key]; !ok {
+			entry := &models.MappingConfigAlias{Type: models.AliasTypeName(a.Type), Key: a.Key, Values: []string{}}
+			aliasesByKey[a.Type][a.Key] = entry
+			result.Aliases = append(result.Aliases, entry)
+		}
+		aliasesByKey[a.Type][a.Key].Values = append(aliasesByKey[a.Type][a.Key].Values, a.Value)
+	}
+	for _, a := range result.Aliases {
+		sort.Strings(a.Values)
+	}
+	sort.Slice(result.Aliases, func(i, j int) bool {
+		if result.Aliases[i].Type != result.Aliases[j].Type {
+			return result.Aliases[i].Type < result.Aliases[j].Type
+		}
+		return result.Aliases[i].Key < result.Aliases[j].Key
+	})
+
+	for label, projectLabels := range labels {
+		entry := &models.MappingConfigLabel{Label: label, Projects: make([]string, len(projectLabels))}
+		for i, l := range projectLabels {
+			entry.Projects[i] = l.ProjectKey
+		}
+		sort.Strings(entry.Projects)
+		result.Labels = append(result.Labels, entry)
+	}
+	sort.Slice(result.Labels, func(i, j int) bool {
+		return result.Labels[i].Label < result.Labels[j].Label
+	})
+
+	for _, r := range rules {
+		result.LabelRules = append(result.LabelRules, &models.MappingConfigLabelRule{Field: r.Field, Pattern: r.Pattern, Label: r.Label})
+	}
+
+	return result, nil
+}
+
+// Import adds all entries of the given configuration, which do not exist yet, to the user's mapping configuration
+// if replace is set, all of the user's existing aliases, labels and rules are deleted beforehand
+// either the whole configuration is imported or, in case of an error, nothing is changed at all
+func (srv *MappingConfigService) Import(user *models.User, mappingConfig *models.MappingConfig, replace bool) (*models.MappingConfigImportResult, error) {
+	if err := mappingConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	existingAliases := map[models.Alias]bool{}
+	existingLabels := map[models.ProjectLabel]bool{}
+	existingRules := map[models.MappingConfigLabelRule]bool{}
+
+	if !replace {
+		aliases, err := srv.aliasSrvc.GetByUser(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range aliases {
+			existingAliases[models.Alias{Type: a.Type, Key: a.Key, Value: a.Value}] = true
+		}
+
+		labels, err := srv.projectLabelSrvc.GetByUser(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range labels {
+			existingLabels[models.ProjectLabel{ProjectKey: l.ProjectKey, Label: l.Label}] = true
+		}
+
+		rules, err := srv.labelRuleSrvc.GetByUser(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rules {
+			existingRules[models.MappingConfigLabelRule{Field: r.Field, Pattern: r.Pattern, Label: r.Label}] = true
+		}
+	}
+
+	newAliases := make([]*models.Alias, 0)
+	for _, a := range mappingConfig.Aliases {
+		aliasType, _ := models.ParseAliasType(a.Type)
+		for _, v := range a.Values {
+			alias := models.Alias{Type: aliasType, Key: a.Key, Value: v}
+			if existingAliases[alias] {
+				continue
+			}
+			existingAliases[alias] = true
+			alias.UserID = user.ID
+			newAliases = append(newAliases, &alias)
+		}
+	}
+
+	newLabels := make([]*models.ProjectLabel, 0)
+	for _, l := range mappingConfig.Labels {
+		for _, p := range l.Projects {
+			label := models.ProjectLabel{ProjectKey: p, Label: l.Label}
+			if p == "" || existingLabels[label] {
+				continue
+			}
+			existingLabels[label] = true
+			label.UserID = user.ID
+			newLabels = append(newLabels, &label)
+		}
+	}
+
+	newRules := make([]*models.LabelRule, 0)
+	for _, r := range mappingConfig.LabelRules {
+		if existingRules[*r] {
+			continue
+		}
+		existingRules[*r] = true
+		newRules = append(newRules, &models.LabelRule{UserID: user.ID, Field: r.Field, Pattern: r.Pattern, Label: r.Label})
+	}
+
+	if err := srv.repository.ImportByUser(user.ID, newAliases, newLabels, newRules, replace); err != nil {
+		return nil, err
+	}
+
+	// data was modified bypassing the respective services
+	if err := srv.aliasSrvc.InitializeUser(user.ID); err != nil {
+		config.Log().Error("failed to reload aliases for user '%s' after import - %v", user.ID, err)
+	}
+	srv.projectLabelSrvc.FlushUserCache(user.ID)
+	srv.labelRuleSrvc.FlushUserCache(user.ID)
+
+	return &models.MappingConfigImportResult{
+		Aliases:    len(newAliases),
+		Labels:     len(newLabels),
+		LabelRules: len(newRules),
+	}, nil
+}
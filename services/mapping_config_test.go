@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type MappingConfigServiceTestSuite struct {
+	suite.Suite
+	TestUser                *models.User
+	TestAliases             []*models.Alias
+	TestLabels              []*models.ProjectLabel
+	TestRules               []*models.LabelRule
+	MappingConfigRepository *mocks.MappingConfigRepositoryMock
+	AliasService            *mocks.AliasServiceMock
+	ProjectLabelService     *mocks.ProjectLabelServiceMock
+	LabelRuleService        *mocks.LabelRuleServiceMock
+}
+
+func (suite *MappingConfigServiceTestSuite) SetupSuite() {
+	suite.TestUser = &models.User{ID: "testuser"}
+	suite.TestAliases = []*models.Alias{
+		{ID: 1, Type: models.SummaryProject, UserID: suite.TestUser.ID, Key: "wakapi", Value: "wakapi-v2"},
+		{ID: 2, Type: models.SummaryProject, UserID: suite.TestUser.ID, Key: "wakapi", Value: "wakapi-ui"},
+		{ID: 3, Type: models.SummaryLanguage, UserID: suite.TestUser.ID, Key: "Go", Value: "Golang"},
+	}
+	suite.TestLabels = []*models.ProjectLabel{
+		{ID: 1, UserID: suite.TestUser.ID, ProjectKey: "wakapi", Label: "oss"},
+		{ID: 2, UserID: suite.TestUser.ID, ProjectKey: "anchr", Label: "oss"},
+	}
+	suite.TestRules = []*models.LabelRule{
+		{ID: 1, UserID: suite.TestUser.ID, Field: models.LabelRuleFieldProject, Pattern: "^wakapi", Label: "oss"},
+	}
+	config.Set(config.Empty())
+}
+
+func (suite *MappingConfigServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.MappingConfigRepository = new(mocks.MappingConfigRepositoryMock)
+	suite.AliasService = new(mocks.AliasServiceMock)
+	suite.ProjectLabelService = new(mocks.ProjectLabelServiceMock)
+	suite.LabelRuleService = new(mocks.LabelRuleServiceMock)
+
+	labelsInverted := map[string][]*models.ProjectLabel{"oss": suite.TestLabels}
+
+	suite.AliasService.On("GetByUser", suite.TestUser.ID).Return(suite.TestAliases, nil)
+	suite.AliasService.On("InitializeUser", suite.TestUser.ID).Return(nil)
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return(suite.TestLabels, nil)
+	suite.ProjectLabelService.On("GetByUserGroupedInverted", suite.TestUser.ID).Return(labelsInverted, nil)
+	suite.ProjectLabelService.On("FlushUserCache", suite.TestUser.ID).Return()
+	suite.LabelRuleService.On("GetByUser", suite.TestUser.ID).Return(suite.TestRules, nil)
+	suite.LabelRuleService.On("FlushUserCache", suite.TestUser.ID).Return()
+}
+
+func TestMappingConfigServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(MappingConfigServiceTestSuite))
+}
+
+func (suite *MappingConfigServiceTestSuite) TestMappingConfigService_ExportImport_RoundTrip() {
+	sut := NewMappingConfigService(suite.MappingConfigRepository, suite.AliasService, suite.ProjectLabelService, suite.LabelRuleService)
+
+	suite.MappingConfigRepository.On("ImportByUser", suite.TestUser.ID, mock.Anything, mock.Anything, mock.Anything, false).Return(nil)
+
+	exported, err := sut.Export(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), exported.Aliases, 2)
+	assert.Equal(suite.T(), []string{"wakapi-ui", "wakapi-v2"}, exported.Aliases[1].Values)
+	assert.Equal(suite.T(), []string{"anchr", "wakapi"}, exported.Labels[0].Projects)
+
+	result, err := sut.Import(suite.TestUser, exported, false)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), &models.MappingConfigImportResult{}, result)
+
+	call := suite.MappingConfigRepository.Calls[0]
+	assert.Empty(suite.T(), call.Arguments.Get(1))
+	assert.Empty(suite.T(), call.Arguments.Get(2))
+	assert.Empty(suite.T(), call.Arguments.Get(3))
+}
+
+func (suite *MappingConfigServiceTestSuite) TestMappingConfigService_Import_Replace() {
+	sut := NewMappingConfigService(suite.MappingConfigRepository, suite.AliasService, suite.ProjectLabelService, suite.LabelRuleService)
+
+	suite.MappingConfigRepository.On("ImportByUser", suite.TestUser.ID, mock.Anything, mock.Anything, mock.Anything, true).Return(nil)
+
+	exported, err := sut.Export(suite.TestUser)
+	assert.Nil(suite.T(), err)
+
+	result, err := sut.Import(suite.TestUser, exported, true)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), &models.MappingConfigImportResult{Aliases: 3, Labels: 2, LabelRules: 1}, result)
+
+	aliases := suite.MappingConfigRepository.Calls[0].Arguments.Get(1).([]*models.Alias)
+	assert.Equal(suite.T(), suite.TestUser.ID, aliases[0].UserID)
+	assert.Zero(suite.T(), aliases[0].ID)
+
+	suite.AliasService.AssertCalled(suite.T(), "InitializeUser", suite.TestUser.ID)
+	suite.ProjectLabelService.AssertCalled(suite.T(), "FlushUserCache", suite.TestUser.ID)
+	suite.LabelRuleService.AssertCalled(suite.T(), "FlushUserCache", suite.TestUser.ID)
+}
+
+func (suite *MappingConfigServiceTestSuite) TestMappingConfigService_Import_Fails() {
+	sut := NewMappingConfigService(suite.MappingConfigRepository, suite.AliasService, suite.ProjectLabelService, suite.LabelRuleService)
+
+	suite.MappingConfigRepository.On("ImportByUser", suite.TestUser.ID, mock.Anything, mock.Anything, mock.Anything, true).Return(errors.New("failed"))
+
+	result, err := sut.Import(suite.TestUser, &models.MappingConfig{
+		LabelRules: []*models.MappingConfigLabelRule{{Field: models.LabelRuleFieldBranch, Pattern: "^main$", Label: "prod"}},
+	}, true)
+
+	assert.Error(suite.T(), err)
+	assert.Nil(suite.T(), result)
+	suite.ProjectLabelService.AssertNotCalled(suite.T(), "FlushUserCache", mock.Anything)
+}
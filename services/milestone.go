@@ -0,0 +1,43 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+type MilestoneService struct {
+	config     *config.Config
+	repository repositories.IMilestoneRepository
+}
+
+func NewMilestoneService(milestoneRepository repositories.IMilestoneRepository) *MilestoneService {
+	return &MilestoneService{
+		config:     config.Get(),
+		repository: milestoneRepository,
+	}
+}
+
+func (srv *MilestoneService) GetById(id uint) (*models.Milestone, error) {
+	return srv.repository.GetById(id)
+}
+
+// GetByUserWithin returns all of the user's milestones on days touched by the given interval, as seen from the user's time zone
+func (srv *MilestoneService) GetByUserWithin(user *models.User, from, to time.Time) ([]*models.Milestone, error) {
+	fromDay, toDay := models.AnnotationDayRange(from, to, user.TZ())
+	return srv.repository.GetByUserWithin(user.ID, fromDay, toDay)
+}
+
+func (srv *MilestoneService) Create(milestone *models.Milestone) (*models.Milestone, error) {
+	return srv.repository.Insert(milestone)
+}
+
+func (srv *MilestoneService) Delete(milestone *models.Milestone) error {
+	if milestone.UserID == "" {
+		return errors.New("no user id specified")
+	}
+	return srv.repository.Delete(milestone.ID)
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	mirrorBatchSize          = 100
+	mirrorMaxBuffered        = 10 * mirrorBatchSize // per user, oldest heartbeats are dropped beyond that while the endpoint is unreachable
+	mirrorFlushInterval      = 10 * time.Second
+	mirrorMaxFailuresPerDay  = 100
+	mirrorSignatureHeader    = "X-Wakapi-Signature"
+	mirrorDeliveryHeader     = "X-Wakapi-Delivery"
+	mirrorSignaturePrefix    = "sha256="
+	mirrorPayloadContentType = "application/json"
+)
+
+var (
+	ErrMirrorInvalidUrl  = errors.New("invalid mirror url")
+	ErrMirrorUnreachable = errors.New("failed to reach mirror endpoint")
+)
+
+type mirrorPayload struct {
+	User       string              `json:"user"`
+	Heartbeats []*models.Heartbeat `json:"heartbeats"`
+}
+
+// MirrorService forwards every accepted heartbeat to the user's mirror endpoint, if configured
+// heartbeats are collected per user and sent in batches, signed with an HMAC-SHA256 of the request body using the user's mirror secret
+// failed batches are kept and retried with the next flush, until too many deliveries failed and mirroring gets disabled
+type MirrorService struct {
+	config       *config.Config
+	eventBus     *hub.Hub
+	userSrvc     IUserService
+	httpClient   *http.Client
+	failureCache *cache.Cache
+	buffers      map[string][]*models.Heartbeat
+	lock         sync.Mutex
+}
+
+func NewMirrorService(userService IUserService) *MirrorService {
+	httpClient := utils.NewPublicOnlyHttpClient(10 * time.Second)
+	httpClient.Transport = config.GetOutboundDispatcher().Transport(httpClient.Transport, utils.OutboundPriorityNormal)
+
+	srv := &MirrorService{
+		config:       config.Get(),
+		eventBus:     config.EventBus(),
+		userSrvc:     userService,
+		httpClient:   httpClient,
+		failureCache: cache.New(24*time.Hour, 1*time.Hour),
+		buffers:      map[string][]*models.Heartbeat{},
+	}
+
+	onHeartbeat := srv.eventBus.Subscribe(0, config.EventHeartbeatCreate)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.enqueue(m.Fields[config.FieldPayload].(*models.Heartbeat))
+		}
+	}(&onHeartbeat)
+
+	go func() {
+		ticker := time.NewTicker(mirrorFlushInterval)
+		for range ticker.C {
+			srv.Flush()
+		}
+	}()
+
+	return srv
+}
+
+func (srv *MirrorService) enqueue(heartbeat *models.Heartbeat) {
+	user, err := srv.userSrvc.GetUserById(heartbeat.UserID)
+	if err != nil || user.MirrorUrl == "" {
+		return
+	}
+
+	srv.lock.Lock()
+	srv.buffers[user.ID] = srv.truncate(append(srv.buffers[user.ID], heartbeat))
+	full := len(srv.buffers[user.ID]) >= mirrorBatchSize
+	srv.lock.Unlock()
+
+	if full {
+		go srv.flush(user.ID)
+	}
+}
+
+// Flush sends out all buffered heartbeats right away, e.g. before shutting down
+func (srv *MirrorService) Flush() {
+	srv.lock.Lock()
+	userIds := make([]string, 0, len(srv.buffers))
+	for userId := range srv.buffers {
+		userIds = append(userIds, userId)
+	}
+	srv.lock.Unlock()
+
+	for _, userId := range userIds {
+		srv.flush(userId)
+	}
+}
+
+func (srv *MirrorService) flush(userId string) {
+	srv.lock.Lock()
+	heartbeats := srv.buffers[userId]
+	delete(srv.buffers, userId)
+	srv.lock.Unlock()
+
+	if len(heartbeats) == 0 {
+		return
+	}
+
+	// re-fetch user, because mirroring might have been disabled or the secret rotated in the meantime
+	user, err := srv.userSrvc.GetUserById(userId)
+	if err != nil || user.MirrorUrl == "" {
+		return
+	}
+
+	for i := 0; i < len(heartbeats); i += mirrorBatchSize {
+		end := i + mirrorBatchSize
+		if end > len(heartbeats) {
+			end = len(heartbeats)
+		}
+		if err := srv.send(user, heartbeats[i:end]); err != nil {
+			logbuch.Warn("failed to mirror %d heartbeats for user '%s' - %v", end-i, user.ID, err)
+			srv.requeue(user.ID, heartbeats[i:])
+			srv.registerFailure(user)
+			return
+		}
+	}
+}
+
+func (srv *MirrorService) send(user *models.User, heartbeats []*models.Heartbeat) error {
+	body, err := json.Marshal(&mirrorPayload{User: user.ID, Heartbeats: heartbeats})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, user.MirrorUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mirrorPayloadContentType)
+	req.Header.Set("User-Agent", fmt.Sprintf("wakapi v%s", srv.config.Version))
+	req.Header.Set(mirrorDeliveryHeader, uuid.NewV4().String())
+	req.Header.Set(mirrorSignatureHeader, mirrorSignaturePrefix+signMirrorPayload(body, user.MirrorSecret))
+
+	res, err := srv.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("got status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Configure sets up mirroring to the given url with a newly generated secret, after checking that the endpoint is reachable and accepts deliveries signed with that secret
+// an empty url disables mirroring
+func (srv *MirrorService) Configure(user *models.User, mirrorUrl string) (*models.User, error) {
+	if mirrorUrl == "" {
+		return srv.userSrvc.SetMirrorUrl(user, "", "")
+	}
+	if mirrorUrl == user.MirrorUrl {
+		return user, nil
+	}
+
+	if u, err := url.Parse(mirrorUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrMirrorInvalidUrl
+	}
+
+	probe := *user
+	probe.MirrorUrl = mirrorUrl
+	probe.MirrorSecret = uuid.NewV4().String()
+	if err := srv.send(&probe, []*models.Heartbeat{}); err != nil {
+		// details are not passed on to the user to not have this serve as a means to scan the network
+		logbuch.Warn("failed to reach mirror endpoint of user '%s' - %v", user.ID, err)
+		return nil, ErrMirrorUnreachable
+	}
+
+	return srv.userSrvc.SetMirrorUrl(user, probe.MirrorUrl, probe.MirrorSecret)
+}
+
+// requeue puts back heartbeats that failed to be delivered in front of those buffered in the meantime
+func (srv *MirrorService) requeue(userId string, heartbeats []*models.Heartbeat) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	srv.buffers[userId] = srv.truncate(append(heartbeats, srv.buffers[userId]...))
+}
+
+func (srv *MirrorService) truncate(heartbeats []*models.Heartbeat) []*models.Heartbeat {
+	if len(heartbeats) > mirrorMaxBuffered {
+		return heartbeats[len(heartbeats)-mirrorMaxBuffered:]
+	}
+	return heartbeats
+}
+
+// registerFailure disables mirroring for the user once too many deliveries failed within 24 hours
+func (srv *MirrorService) registerFailure(user *models.User) {
+	srv.failureCache.Add(user.ID, 0, cache.DefaultExpiration) // no-op if already present
+	if n, _ := srv.failureCache.IncrementInt(user.ID, 1); n >= mirrorMaxFailuresPerDay {
+		logbuch.Warn("disabling heartbeat mirroring for user '%s' after %d failed attempts", user.ID, n)
+		srv.failureCache.Delete(user.ID)
+		srv.lock.Lock()
+		delete(srv.buffers, user.ID)
+		srv.lock.Unlock()
+		if _, err := srv.userSrvc.SetMirrorUrl(user, "", ""); err != nil {
+			config.Log().Error("failed to disable heartbeat mirroring for user '%s' - %v", user.ID, err)
+		}
+	}
+}
+
+// signMirrorPayload computes the hex-encoded HMAC-SHA256 of a mirrored request body, receivers are expected to verify it against the X-Wakapi-Signature header
+func signMirrorPayload(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSignMirrorPayload(t *testing.T) {
+	// test vector from https://en.wikipedia.org/wiki/HMAC#Examples
+	assert.Equal(t, "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8", signMirrorPayload([]byte("The quick brown fox jumps over the lazy dog"), "key"))
+	assert.NotEqual(t, signMirrorPayload([]byte("payload"), "secret1"), signMirrorPayload([]byte("payload"), "secret2"))
+}
+
+func TestMirrorService_Configure_RejectsInternalAddresses(t *testing.T) {
+	config.Set(config.Empty())
+
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	userServiceMock := new(mocks.UserServiceMock)
+	sut := NewMirrorService(userServiceMock)
+
+	_, err := sut.Configure(&models.User{ID: "user01"}, server.URL)
+	assert.Equal(t, ErrMirrorUnreachable, err)
+	assert.False(t, called)
+
+	_, err = sut.Configure(&models.User{ID: "user01"}, "ftp://example.org")
+	assert.Equal(t, ErrMirrorInvalidUrl, err)
+
+	userServiceMock.AssertNotCalled(t, "SetMirrorUrl", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMirrorService_Configure_PingsWithNewSecret(t *testing.T) {
+	config.Set(config.Empty())
+
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(mirrorSignatureHeader)
+		assert.True(t, json.Valid(body))
+	}))
+	defer server.Close()
+
+	testUser := &models.User{ID: "user01", MirrorSecret: "old-secret"}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("SetMirrorUrl", testUser, server.URL, mock.Anything).Return(testUser, nil)
+
+	sut := NewMirrorService(userServiceMock)
+	sut.httpClient = server.Client() // test server listens on loopback
+
+	_, err := sut.Configure(testUser, server.URL)
+	assert.Nil(t, err)
+
+	secret := userServiceMock.Calls[0].Arguments.String(2)
+	assert.NotEmpty(t, secret)
+	assert.NotEqual(t, "old-secret", secret)
+	assert.Equal(t, mirrorSignaturePrefix+signMirrorPayload([]byte(`{"user":"user01","heartbeats":[]}`), secret), signature)
+}
+
+func TestMirrorService_Flush_RequeuesFailedBatches(t *testing.T) {
+	config.Set(config.Empty())
+
+	status := http.StatusServiceUnavailable
+	var received []*models.Heartbeat
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status == http.StatusOK {
+			var payload mirrorPayload
+			json.NewDecoder(r.Body).Decode(&payload)
+			received = append(received, payload.Heartbeats...)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	testUser := &models.User{ID: "user01", MirrorUrl: server.URL, MirrorSecret: "secret"}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserById", testUser.ID).Return(testUser, nil)
+
+	sut := NewMirrorService(userServiceMock)
+	sut.httpClient = server.Client()
+
+	sut.enqueue(&models.Heartbeat{UserID: testUser.ID, Entity: "file1.go"})
+	sut.enqueue(&models.Heartbeat{UserID: testUser.ID, Entity: "file2.go"})
+
+	sut.flush(testUser.ID)
+	assert.Len(t, sut.buffers[testUser.ID], 2)
+	assert.Empty(t, received)
+
+	status = http.StatusOK
+	sut.flush(testUser.ID)
+	assert.Empty(t, sut.buffers[testUser.ID])
+	assert.Len(t, received, 2)
+	assert.Equal(t, "file1.go", received[0].Entity)
+}
@@ -18,6 +18,7 @@ import (
 
 const (
 	countUsersEvery                  = 1 * time.Hour
+	countActiveUsersEvery            = 1 * time.Hour
 	computeOldestDataEvery           = 6 * time.Hour
 	notifyExpiringSubscriptionsEvery = 12 * time.Hour
 )
@@ -36,12 +37,13 @@ type MiscService struct {
 	summaryService   ISummaryService
 	keyValueService  IKeyValueService
 	mailService      IMailService
+	notifySrvc       INotificationService
 	queueDefault     *artifex.Dispatcher
 	queueWorkers     *artifex.Dispatcher
 	queueMails       *artifex.Dispatcher
 }
 
-func NewMiscService(userService IUserService, heartbeatService IHeartbeatService, summaryService ISummaryService, keyValueService IKeyValueService, mailService IMailService) *MiscService {
+func NewMiscService(userService IUserService, heartbeatService IHeartbeatService, summaryService ISummaryService, keyValueService IKeyValueService, mailService IMailService, notificationService INotificationService) *MiscService {
 	return &MiscService{
 		config:           config.Get(),
 		userService:      userService,
@@ -49,6 +51,7 @@ func NewMiscService(userService IUserService, heartbeatService IHeartbeatService
 		summaryService:   summaryService,
 		keyValueService:  keyValueService,
 		mailService:      mailService,
+		notifySrvc:       notificationService,
 		queueDefault:     config.GetDefaultQueue(),
 		queueWorkers:     config.GetQueue(config.QueueProcessing),
 		queueMails:       config.GetQueue(config.QueueMails),
@@ -61,6 +64,11 @@ func (srv *MiscService) Schedule() {
 		config.Log().Error("failed to schedule user counting jobs, %v", err)
 	}
 
+	logbuch.Info("scheduling active users counting")
+	if _, err := srv.queueDefault.DispatchEvery(srv.CountActiveUsers, countActiveUsersEvery); err != nil {
+		config.Log().Error("failed to schedule active users counting jobs, %v", err)
+	}
+
 	logbuch.Info("scheduling first data computing")
 	if _, err := srv.queueDefault.DispatchEvery(srv.ComputeOldestHeartbeats, computeOldestDataEvery); err != nil {
 		config.Log().Error("failed to schedule first data computing jobs, %v", err)
@@ -79,6 +87,9 @@ func (srv *MiscService) Schedule() {
 			config.Log().Error("failed to dispatch user counting jobs, %v", err)
 		}
 	}
+	if err := srv.queueDefault.Dispatch(srv.CountActiveUsers); err != nil {
+		config.Log().Error("failed to dispatch active users counting jobs, %v", err)
+	}
 	if !srv.existsUsersFirstData() {
 		if err := srv.queueDefault.Dispatch(srv.ComputeOldestHeartbeats); err != nil {
 			config.Log().Error("failed to dispatch first data computing jobs, %v", err)
@@ -104,7 +115,11 @@ func (srv *MiscService) CountTotalTime() {
 		return
 	}
 
+	privatizer := srv.config.App.PublicStatsPrivacy.NewPrivatizer()
+	maxTimePerUser := time.Duration(srv.config.App.PublicStatsPrivacy.MaxHoursPerUser) * time.Hour
+
 	var totalTime = atomic.NewDuration(0)
+	var totalTimeClamped = atomic.NewDuration(0) // each user's contribution bounded for differential privacy
 	var pendingJobs sync.WaitGroup
 	pendingJobs.Add(len(users))
 
@@ -112,7 +127,11 @@ func (srv *MiscService) CountTotalTime() {
 		user := *u
 		if err := srv.queueWorkers.Dispatch(func() {
 			defer pendingJobs.Done()
-			totalTime.Add(srv.countUserTotalTime(user.ID))
+			userTime := srv.countUserTotalTime(user.ID)
+			totalTime.Add(userTime)
+			if privatizer != nil {
+				totalTimeClamped.Add(time.Duration(privatizer.Clamp(float64(userTime), float64(maxTimePerUser))))
+			}
 		}); err != nil {
 			config.Log().Error("failed to enqueue counting job for user '%s'", user.ID)
 			pendingJobs.Done()
@@ -135,12 +154,60 @@ func (srv *MiscService) CountTotalTime() {
 			}); err != nil {
 				config.Log().Error("failed to save total users count: %v", err)
 			}
+
+			if privatizer != nil {
+				srv.savePublicTotals(privatizer, totalTimeClamped.Load(), maxTimePerUser, len(users))
+			}
 		} else {
 			config.Log().Error("waiting for user counting jobs timed out")
 		}
 	}(&pendingJobs)
 }
 
+// savePublicTotals persists differentially private versions of the total time and users counts to be shown publicly, or empty values, if they must not be published
+// the noise is drawn only once and reused for every later count, as the totals are re-published regularly
+func (srv *MiscService) savePublicTotals(privatizer *utils.Privatizer, totalTime, maxTimePerUser time.Duration, totalUsers int) {
+	noise := loadPrivacyNoise(srv.keyValueService, "totals")
+
+	var publicTime, publicUsers string
+	if hours, ok := noise.privatize(privatizer, "time", totalTime.Hours(), maxTimePerUser.Hours(), totalUsers); ok {
+		publicTime = (time.Duration(hours) * time.Hour).String()
+	}
+	if users, ok := noise.privatize(privatizer, "users", float64(totalUsers), 1, totalUsers); ok {
+		publicUsers = strconv.Itoa(int(users))
+	}
+
+	if err := noise.save(srv.keyValueService); err != nil {
+		config.Log().Error("failed to save privacy noise: %v", err)
+	}
+	if err := srv.keyValueService.PutString(&models.KeyStringValue{Key: config.KeyLatestTotalTimePublic, Value: publicTime}); err != nil {
+		config.Log().Error("failed to save public total time count: %v", err)
+	}
+	if err := srv.keyValueService.PutString(&models.KeyStringValue{Key: config.KeyLatestTotalUsersPublic, Value: publicUsers}); err != nil {
+		config.Log().Error("failed to save public total users count: %v", err)
+	}
+}
+
+// CountActiveUsers computes the number of daily, weekly and monthly active users and persists them as key-values
+func (srv *MiscService) CountActiveUsers() {
+	logbuch.Info("counting active users")
+
+	for _, class := range models.AllActivityClasses() {
+		count, err := srv.userService.CountActiveByClass(class)
+		if err != nil {
+			config.Log().Error("failed to count %s active users, %v", class, err)
+			continue
+		}
+
+		if err := srv.keyValueService.PutString(&models.KeyStringValue{
+			Key:   class.Key(),
+			Value: strconv.FormatInt(count, 10),
+		}); err != nil {
+			config.Log().Error("failed to save %s active users count: %v", class, err)
+		}
+	}
+}
+
 func (srv *MiscService) ComputeOldestHeartbeats() {
 	logbuch.Info("computing users' first data")
 
@@ -224,10 +291,11 @@ func (srv *MiscService) NotifyExpiringSubscription() {
 		}
 
 		// skip users without e-mail address
+		// skip users whose account is suspended or deactivated
 		// skip users who already received a notification before
 		// skip users who either never had a subscription before or intentionally deleted it
 		// skip users who have upcoming auto-renewal (everyone except users who chose to cancel subscription at later date)
-		if alreadySent || u.Email == "" || u.SubscribedUntil == nil || (u.SubscriptionRenewal != nil && u.SubscriptionRenewal.T().After(now)) {
+		if alreadySent || u.Email == "" || !u.IsActive() || u.SubscribedUntil == nil || (u.SubscriptionRenewal != nil && u.SubscriptionRenewal.T().After(now)) {
 			continue
 		}
 
@@ -253,7 +321,13 @@ func (srv *MiscService) sendSubscriptionNotificationScheduled(user *models.User,
 		logbuch.Info("sending subscription expiry notification mail to %s (expired: %v)", u.ID, hasExpired)
 		defer time.Sleep(10 * time.Second)
 
-		if err := srv.mailService.SendSubscriptionNotification(&u, hasExpired); err != nil {
+		subject, text := "Subscription about to expire", "Your Wakapi subscription is about to expire soon. Please renew it to keep your full history of coding activity."
+		if hasExpired {
+			subject, text = "Subscription expired", fmt.Sprintf("Your Wakapi subscription has expired. Coding activity older than %d months will be deleted soon, unless you renew your subscription.", srv.config.App.DataRetentionMonths)
+		}
+		if err := srv.notifySrvc.Notify(&u, subject, text, func() error {
+			return srv.mailService.SendSubscriptionNotification(&u, hasExpired)
+		}); err != nil {
 			config.Log().Error("failed to send subscription notification mail to user '%s', %v", u.ID, err)
 			return
 		}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"time"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/emvi/logbuch"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+const (
+	// how often to check for held back notifications to be sent out
+	notificationFlushInterval = 15 * time.Minute
+	// minimum age of the oldest held back notification before sending a digest, i.e. at most one digest per day
+	notificationDigestInterval = 24 * time.Hour
+)
+
+// NotificationService delivers e-mail notifications either immediately or, respecting users' quiet hours and digest preferences, batched into a single digest mail later on
+type NotificationService struct {
+	config       *config.Config
+	repository   repositories.INotificationRepository
+	mailService  IMailService
+	queueDefault *artifex.Dispatcher
+	queueMails   *artifex.Dispatcher
+}
+
+func NewNotificationService(notificationRepository repositories.INotificationRepository, mailService IMailService) *NotificationService {
+	return &NotificationService{
+		config:       config.Get(),
+		repository:   notificationRepository,
+		mailService:  mailService,
+		queueDefault: config.GetDefaultQueue(),
+		queueMails:   config.GetQueue(config.QueueMails),
+	}
+}
+
+func (srv *NotificationService) Schedule() {
+	logbuch.Info("scheduling notification digests")
+	if _, err := srv.queueDefault.DispatchEvery(srv.flush, notificationFlushInterval); err != nil {
+		config.Log().Error("failed to schedule notification digest jobs, %v", err)
+	}
+}
+
+// Notify sends a notification right away using the given function, unless the user is in their quiet hours or prefers digests
+// in the latter case, the notification's subject and text are held back to become part of a digest
+func (srv *NotificationService) Notify(user *models.User, subject, text string, send func() error) error {
+	if !user.NotificationDigest && !user.IsQuietTime(time.Now()) {
+		return send()
+	}
+
+	_, err := srv.repository.Insert(&models.Notification{
+		UserID:  user.ID,
+		Subject: subject,
+		Text:    text,
+	})
+	if err == nil {
+		logbuch.Info("held back notification '%s' for user '%s'", subject, user.ID)
+	}
+	return err
+}
+
+func (srv *NotificationService) flush() {
+	notifications, err := srv.repository.GetAllPending()
+	if err != nil {
+		config.Log().Error("failed to fetch pending notifications, %v", err)
+		return
+	}
+
+	byUser := slice.GroupWith[*models.Notification, string](notifications, func(n *models.Notification) string {
+		return n.UserID
+	})
+
+	for _, userNotifications := range byUser {
+		user := userNotifications[0].User
+		if !srv.isDue(user, userNotifications, time.Now()) {
+			continue
+		}
+
+		pending := userNotifications
+		if err := srv.queueMails.Dispatch(func() {
+			if err := srv.mailService.SendNotificationDigest(user, pending); err != nil {
+				config.Log().Error("failed to send notification digest to user '%s', %v", user.ID, err)
+				return
+			}
+			if err := srv.repository.DeleteByIds(slice.Map[*models.Notification, uint](pending, func(i int, n *models.Notification) uint {
+				return n.ID
+			})); err != nil {
+				config.Log().Error("failed to delete sent notifications of user '%s', %v", user.ID, err)
+			}
+			logbuch.Info("sent digest of %d notifications to user '%s'", len(pending), user.ID)
+		}); err != nil {
+			config.Log().Error("failed to dispatch notification digest for user '%s', %v", user.ID, err)
+		}
+	}
+}
+
+// isDue checks whether a user's held back notifications (sorted by age) are to be sent at the given time
+func (srv *NotificationService) isDue(user *models.User, notifications []*models.Notification, t time.Time) bool {
+	if user == nil || user.Email == "" || len(notifications) == 0 || user.IsQuietTime(t) {
+		return false
+	}
+	if user.NotificationDigest {
+		return t.Sub(notifications[0].CreatedAt.T()) >= notificationDigestInterval
+	}
+	return true // only held back because of quiet hours, which are now over
+}
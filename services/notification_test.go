@@ -0,0 +1,79 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type NotificationServiceTestSuite struct {
+	suite.Suite
+	NotificationRepository *mocks.NotificationRepositoryMock
+}
+
+func (suite *NotificationServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+}
+
+func (suite *NotificationServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.NotificationRepository = new(mocks.NotificationRepositoryMock)
+}
+
+func TestNotificationServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(NotificationServiceTestSuite))
+}
+
+func (suite *NotificationServiceTestSuite) TestNotificationService_Notify_SendsImmediately() {
+	sut := NewNotificationService(suite.NotificationRepository, nil)
+	user := &models.User{ID: "testuser", Email: "foo@example.org"}
+
+	var sent bool
+	err := sut.Notify(user, "subject", "text", func() error {
+		sent = true
+		return nil
+	})
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), sent)
+	suite.NotificationRepository.AssertNotCalled(suite.T(), "Insert", mock.Anything)
+}
+
+func (suite *NotificationServiceTestSuite) TestNotificationService_Notify_HoldsBackForDigest() {
+	sut := NewNotificationService(suite.NotificationRepository, nil)
+	user := &models.User{ID: "testuser", Email: "foo@example.org", NotificationDigest: true}
+
+	suite.NotificationRepository.On("Insert", mock.Anything).Return(&models.Notification{}, nil)
+
+	err := sut.Notify(user, "subject", "text", func() error {
+		return errors.New("must not be sent right away")
+	})
+
+	assert.Nil(suite.T(), err)
+	suite.NotificationRepository.AssertCalled(suite.T(), "Insert", &models.Notification{UserID: user.ID, Subject: "subject", Text: "text"})
+}
+
+func (suite *NotificationServiceTestSuite) TestNotificationService_IsDue() {
+	sut := NewNotificationService(suite.NotificationRepository, nil)
+
+	now := time.Date(2023, 5, 2, 12, 0, 0, 0, time.UTC)
+	recent := []*models.Notification{{CreatedAt: models.CustomTime(now.Add(-2 * time.Hour))}}
+	old := []*models.Notification{{CreatedAt: models.CustomTime(now.Add(-25 * time.Hour))}, {CreatedAt: models.CustomTime(now.Add(-1 * time.Hour))}}
+
+	quiet := &models.User{ID: "u1", Email: "foo@example.org", Location: "UTC", QuietHoursStart: 10, QuietHoursEnd: 14}
+	afterQuiet := &models.User{ID: "u2", Email: "foo@example.org", Location: "UTC", QuietHoursStart: 22, QuietHoursEnd: 7}
+	digest := &models.User{ID: "u3", Email: "foo@example.org", Location: "UTC", NotificationDigest: true}
+	noMail := &models.User{ID: "u4", Location: "UTC"}
+
+	assert.False(suite.T(), sut.isDue(quiet, recent, now))
+	assert.True(suite.T(), sut.isDue(afterQuiet, recent, now))
+	assert.False(suite.T(), sut.isDue(digest, recent, now))
+	assert.True(suite.T(), sut.isDue(digest, old, now))
+	assert.False(suite.T(), sut.isDue(noMail, old, now))
+}
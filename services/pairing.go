@@ -0,0 +1,165 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/patrickmn/go-cache"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	pairingTtl          = 10 * time.Minute
+	pairingRetention    = 2 * pairingTtl // expired pairings are kept a little longer to tell them apart from unknown ones
+	pairingPollInterval = 5 * time.Second
+	pairingUserCodeLen  = 8
+	// no vowels and no easily confused characters, see https://datatracker.ietf.org/doc/html/rfc8628#section-6.1
+	pairingUserCodeChars = "BCDFGHJKLMNPQRSTVWXZ"
+)
+
+// error codes as of https://datatracker.ietf.org/doc/html/rfc8628#section-3.5
+var (
+	ErrPairingPending  = errors.New("authorization_pending")
+	ErrPairingSlowDown = errors.New("slow_down")
+	ErrPairingExpired  = errors.New("expired_token")
+	ErrPairingNotFound = errors.New("invalid_grant")
+)
+
+type PairingService struct {
+	config *config.Config
+	cache  *cache.Cache // device code -> pairing
+	codes  *cache.Cache // user code -> device code
+	lock   sync.Mutex
+}
+
+func NewPairingService() *PairingService {
+	return &PairingService{
+		config: config.Get(),
+		cache:  cache.New(pairingRetention, pairingTtl),
+		codes:  cache.New(pairingTtl, pairingTtl),
+	}
+}
+
+// Create initiates a new pairing request on behalf of an (unauthenticated) device
+func (srv *PairingService) Create(clientId, scope string) (*models.DevicePairing, error) {
+	userCode, err := srv.generateUserCode()
+	if err != nil {
+		return nil, err
+	}
+
+	verificationUri := fmt.Sprintf("%s/pair", srv.config.Server.GetPublicUrl())
+	pairing := &models.DevicePairing{
+		DeviceCode:              uuid.NewV4().String(),
+		UserCode:                userCode,
+		VerificationUri:         verificationUri,
+		VerificationUriComplete: fmt.Sprintf("%s?code=%s", verificationUri, userCode),
+		ExpiresIn:               int(pairingTtl.Seconds()),
+		Interval:                int(pairingPollInterval.Seconds()),
+		ClientID:                clientId,
+		Scope:                   scope,
+		ExpiresAt:               time.Now().Add(pairingTtl),
+	}
+
+	srv.cache.SetDefault(pairing.DeviceCode, pairing)
+	srv.codes.SetDefault(srv.normalizeUserCode(pairing.UserCode), pairing.DeviceCode)
+	return pairing, nil
+}
+
+// GetByUserCode returns a copy of the pending pairing request with the given user code, e.g. to let the user review it before confirming
+func (srv *PairingService) GetByUserCode(userCode string) (*models.DevicePairing, error) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	pairing, err := srv.getByUserCode(userCode)
+	if err != nil {
+		return nil, err
+	}
+	pairingCopy := *pairing
+	return &pairingCopy, nil
+}
+
+// Confirm grants the device, which initiated the pairing request with the given user code, access to the user's account
+func (srv *PairingService) Confirm(userCode string, user *models.User) error {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	pairing, err := srv.getByUserCode(userCode)
+	if err != nil {
+		return err
+	}
+
+	pairing.UserID = user.ID
+	srv.codes.Delete(srv.normalizeUserCode(userCode))
+	return nil
+}
+
+// Poll is called by the device to check whether the pairing request was confirmed, yet
+// Returns the confirmed pairing exactly once, ErrPairingPending while waiting for the user, ErrPairingSlowDown if polled too frequently, ErrPairingExpired if expired and ErrPairingNotFound if unknown
+func (srv *PairingService) Poll(deviceCode string) (*models.DevicePairing, error) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	item, ok := srv.cache.Get(deviceCode)
+	if !ok {
+		return nil, ErrPairingNotFound
+	}
+
+	pairing := item.(*models.DevicePairing)
+	if pairing.IsExpired() {
+		return nil, ErrPairingExpired
+	}
+	if !pairing.IsConfirmed() {
+		tooFast := time.Since(pairing.LastPolledAt) < pairingPollInterval/2 // some tolerance for jitter
+		pairing.LastPolledAt = time.Now()
+		if tooFast {
+			return nil, ErrPairingSlowDown
+		}
+		return nil, ErrPairingPending
+	}
+
+	srv.cache.Delete(deviceCode)
+	return pairing, nil
+}
+
+func (srv *PairingService) getByUserCode(userCode string) (*models.DevicePairing, error) {
+	deviceCode, ok := srv.codes.Get(srv.normalizeUserCode(userCode))
+	if !ok {
+		return nil, ErrPairingNotFound
+	}
+	item, ok := srv.cache.Get(deviceCode.(string))
+	if !ok {
+		return nil, ErrPairingNotFound
+	}
+	pairing := item.(*models.DevicePairing)
+	if pairing.IsExpired() {
+		return nil, ErrPairingExpired
+	}
+	return pairing, nil
+}
+
+func (srv *PairingService) generateUserCode() (string, error) {
+	var sb strings.Builder
+	for i := 0; i < pairingUserCodeLen; i++ {
+		if i == pairingUserCodeLen/2 {
+			sb.WriteRune('-')
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(pairingUserCodeChars))))
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(pairingUserCodeChars[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// normalizeUserCode makes user code matching tolerant to case and separators
+func (srv *PairingService) normalizeUserCode(userCode string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(userCode))
+}
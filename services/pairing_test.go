@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPairingService_Poll(t *testing.T) {
+	config.Set(config.Empty())
+
+	sut := NewPairingService()
+
+	pairing, err := sut.Create("test-client", models.ScopeReadStats)
+	assert.Nil(t, err)
+
+	_, err = sut.Poll("unknown")
+	assert.Equal(t, ErrPairingNotFound, err)
+
+	_, err = sut.Poll(pairing.DeviceCode)
+	assert.Equal(t, ErrPairingPending, err)
+
+	reviewed, err := sut.GetByUserCode(pairing.UserCode)
+	assert.Nil(t, err)
+	assert.Equal(t, "test-client", reviewed.ClientID)
+	assert.Equal(t, []string{models.ScopeReadStats}, reviewed.Scopes())
+
+	assert.Nil(t, sut.Confirm(pairing.UserCode, &models.User{ID: "user01"}))
+
+	result, err := sut.Poll(pairing.DeviceCode)
+	assert.Nil(t, err)
+	assert.Equal(t, "user01", result.UserID)
+
+	// confirmed pairings can be redeemed only once
+	_, err = sut.Poll(pairing.DeviceCode)
+	assert.Equal(t, ErrPairingNotFound, err)
+}
+
+func TestPairingService_Poll_Expired(t *testing.T) {
+	config.Set(config.Empty())
+
+	sut := NewPairingService()
+
+	pairing, err := sut.Create("test-client", models.ScopeReadStats)
+	assert.Nil(t, err)
+
+	item, _ := sut.cache.Get(pairing.DeviceCode)
+	item.(*models.DevicePairing).ExpiresAt = time.Now().Add(-1 * time.Second)
+
+	_, err = sut.GetByUserCode(pairing.UserCode)
+	assert.Equal(t, ErrPairingExpired, err)
+
+	_, err = sut.Poll(pairing.DeviceCode)
+	assert.Equal(t, ErrPairingExpired, err)
+}
@@ -0,0 +1,81 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+// provider-agnostic subscription states, everything else (e.g. pending payments) is reported as is and not acted upon
+const (
+	StatusActive   = "active"
+	StatusTrialing = "trialing"
+	StatusCanceled = "canceled"
+)
+
+const (
+	EventSubscription     = "subscription"      // a subscription was created, changed, renewed or canceled
+	EventCheckoutComplete = "checkout_complete" // a user completed the checkout, which associates them with a customer at the provider
+)
+
+var ErrNoSubscription = errors.New("no active subscription found")
+
+// Provider processes payments for subscriptions, e.g. stripe or paypal
+// Plans are referenced by their PriceId, which is an identifier specific to the respective provider
+type Provider interface {
+	Name() string
+	// FetchPrices resolves the formatted monthly price of every plan
+	FetchPrices(plans []*conf.SubscriptionPlan) error
+	// Checkout starts a new subscription for the user and returns the url to redirect them to for payment
+	Checkout(user *models.User, plan *conf.SubscriptionPlan, trialDays int, successUrl, cancelUrl string) (string, error)
+	// Portal returns the url of a page, where the user can manage their payment details and subscription
+	Portal(user *models.User, returnUrl string) (string, error)
+	// ChangePlan switches the user's current subscription to a different plan and returns a url to redirect them to, if the change requires their approval, or an empty string otherwise
+	ChangePlan(user *models.User, plan *conf.SubscriptionPlan, returnUrl string) (string, error)
+	// Cancel immediately cancels the user's current subscription
+	Cancel(user *models.User) error
+	// ParseWebhook verifies an incoming webhook request and translates it into an event, returns nil for events not relevant to subscriptions
+	ParseWebhook(r *http.Request) (*Event, error)
+	// CustomerEmail resolves the e-mail address of a customer, as a last resort to find the user a subscription belongs to
+	CustomerEmail(subscription *Subscription) (string, error)
+}
+
+type Event struct {
+	Id           string
+	Type         string
+	Subscription *Subscription // for subscription events
+	UserId       string        // for checkout events
+	CustomerId   string        // for checkout events
+}
+
+type Subscription struct {
+	Id         string
+	Status     string
+	CustomerId string    // only for providers with a notion of customers
+	UserId     string    // only for providers, which echo a reference to the user
+	PriceId    string    // the provider's id of the plan's price
+	PeriodEnd  time.Time // end of the current billing (or trial) period
+	CancelAt   time.Time // zero, unless the subscription is set to end at the end of the current period
+}
+
+func NewProvider(config *conf.Config) (Provider, error) {
+	switch config.Subscriptions.Provider {
+	case conf.PaymentProviderStripe:
+		return NewStripeProvider(config.Subscriptions.StripeSecretKey, config.Subscriptions.StripeEndpointSecret), nil
+	case conf.PaymentProviderPayPal:
+		return NewPayPalProvider(config.Subscriptions.PayPal), nil
+	}
+	return nil, fmt.Errorf("unsupported payment provider '%s'", config.Subscriptions.Provider)
+}
+
+func formatPrice(amount float64, currency string) string {
+	symbol, ok := map[string]string{"eur": "€", "usd": "$", "gbp": "£"}[currency]
+	if !ok {
+		return fmt.Sprintf("%.2f %s", amount, currency)
+	}
+	return fmt.Sprintf("%.2f %s", amount, symbol)
+}
@@ -0,0 +1,381 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+/*
+  How to integrate with PayPal?
+  ---
+  1. Create a REST API app (https://developer.paypal.com/dashboard/applications), copy its client ID and secret and save them to 'paypal.client_id' and 'paypal.client_secret'
+  2. Create a product and a monthly billing plan per subscription plan (https://www.paypal.com/billing/plans), copy the plan IDs (P-...) and save them as 'price_id' of the respective entries in 'plans'
+     Trials are part of the billing plan at paypal (as a trial billing cycle), so 'trial_days' does not apply
+  3. Add a webhook to the app, with target URL '/subscription/webhook' and events ['Billing subscription activated', 'Billing subscription updated', 'Billing subscription re-activated', 'Billing subscription cancelled', 'Billing subscription suspended', 'Billing subscription expired', 'Payment sale completed'], copy its ID and save it to 'paypal.webhook_id'
+*/
+
+const (
+	paypalApiUrl            = "https://api-m.paypal.com"
+	paypalSandboxApiUrl     = "https://api-m.sandbox.paypal.com"
+	paypalAutopayUrl        = "https://www.paypal.com/myaccount/autopay/"
+	paypalSandboxAutopayUrl = "https://www.sandbox.paypal.com/myaccount/autopay/"
+)
+
+type PayPalProvider struct {
+	config      conf.PayPalConfig
+	apiUrl      string
+	autopayUrl  string
+	httpClient  *http.Client
+	token       string
+	tokenExpiry time.Time
+	lock        sync.Mutex
+}
+
+type paypalLink struct {
+	Href string `json:"href"`
+	Rel  string `json:"rel"`
+}
+
+type paypalSubscription struct {
+	Id         string `json:"id"`
+	Status     string `json:"status"`
+	PlanId     string `json:"plan_id"`
+	CustomId   string `json:"custom_id"`
+	Subscriber struct {
+		EmailAddress string `json:"email_address"`
+		PayerId      string `json:"payer_id"`
+	} `json:"subscriber"`
+	BillingInfo struct {
+		NextBillingTime time.Time `json:"next_billing_time"`
+		LastPayment     struct {
+			Time time.Time `json:"time"`
+		} `json:"last_payment"`
+	} `json:"billing_info"`
+	Links []paypalLink `json:"links"`
+}
+
+type paypalEvent struct {
+	Id        string          `json:"id"`
+	EventType string          `json:"event_type"`
+	Resource  json.RawMessage `json:"resource"`
+}
+
+func NewPayPalProvider(config conf.PayPalConfig) *PayPalProvider {
+	provider := &PayPalProvider{
+		config:     config,
+		apiUrl:     paypalApiUrl,
+		autopayUrl: paypalAutopayUrl,
+		httpClient: conf.NewOutboundClient(utils.OutboundPriorityHigh, 10*time.Second),
+	}
+	if config.Sandbox {
+		provider.apiUrl, provider.autopayUrl = paypalSandboxApiUrl, paypalSandboxAutopayUrl
+	}
+	return provider
+}
+
+func (p *PayPalProvider) Name() string {
+	return conf.PaymentProviderPayPal
+}
+
+func (p *PayPalProvider) FetchPrices(plans []*conf.SubscriptionPlan) error {
+	for _, plan := range plans {
+		var result struct {
+			BillingCycles []struct {
+				TenureType    string `json:"tenure_type"`
+				PricingScheme struct {
+					FixedPrice struct {
+						Value        string `json:"value"`
+						CurrencyCode string `json:"currency_code"`
+					} `json:"fixed_price"`
+				} `json:"pricing_scheme"`
+			} `json:"billing_cycles"`
+		}
+		if err := p.request(http.MethodGet, "/v1/billing/plans/"+url.PathEscape(plan.PriceId), nil, &result); err != nil {
+			return fmt.Errorf("failed to fetch paypal plan details for plan '%s': %v", plan.Id, err)
+		}
+
+		for _, cycle := range result.BillingCycles {
+			if cycle.TenureType != "REGULAR" {
+				continue
+			}
+			amount, err := strconv.ParseFloat(cycle.PricingScheme.FixedPrice.Value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid price of paypal plan '%s': %v", plan.Id, err)
+			}
+			plan.Price = formatPrice(amount, strings.ToLower(cycle.PricingScheme.FixedPrice.CurrencyCode))
+		}
+		if plan.Price == "" {
+			return fmt.Errorf("paypal plan '%s' has no regular billing cycle", plan.Id)
+		}
+	}
+	return nil
+}
+
+func (p *PayPalProvider) Checkout(user *models.User, plan *conf.SubscriptionPlan, trialDays int, successUrl, cancelUrl string) (string, error) {
+	payload := map[string]interface{}{
+		"plan_id":   plan.PriceId,
+		"custom_id": user.ID,
+		"subscriber": map[string]interface{}{
+			"email_address": user.Email,
+		},
+		"application_context": map[string]interface{}{
+			"return_url":          successUrl,
+			"cancel_url":          cancelUrl,
+			"user_action":         "SUBSCRIBE_NOW",
+			"shipping_preference": "NO_SHIPPING",
+		},
+	}
+
+	var subscription paypalSubscription
+	if err := p.request(http.MethodPost, "/v1/billing/subscriptions", payload, &subscription); err != nil {
+		return "", err
+	}
+	return approvalLink(subscription.Links)
+}
+
+// Portal links to the user's automatic payments at paypal, as there is no dedicated customer portal
+func (p *PayPalProvider) Portal(user *models.User, returnUrl string) (string, error) {
+	if user.SubscriptionId == "" {
+		return "", ErrNoSubscription
+	}
+	return p.autopayUrl, nil
+}
+
+// ChangePlan revises the user's subscription to use the given plan, which the user has to approve at paypal
+// paypal does not prorate, the new price applies as of the next billing cycle
+func (p *PayPalProvider) ChangePlan(user *models.User, plan *conf.SubscriptionPlan, returnUrl string) (string, error) {
+	if user.SubscriptionId == "" {
+		return "", ErrNoSubscription
+	}
+
+	payload := map[string]interface{}{
+		"plan_id": plan.PriceId,
+		"application_context": map[string]interface{}{
+			"return_url":          returnUrl,
+			"cancel_url":          returnUrl,
+			"shipping_preference": "NO_SHIPPING",
+		},
+	}
+
+	var result struct {
+		Links []paypalLink `json:"links"`
+	}
+	if err := p.request(http.MethodPost, fmt.Sprintf("/v1/billing/subscriptions/%s/revise", url.PathEscape(user.SubscriptionId)), payload, &result); err != nil {
+		return "", err
+	}
+	return approvalLink(result.Links)
+}
+
+func (p *PayPalProvider) Cancel(user *models.User) error {
+	if user.SubscriptionId == "" {
+		return ErrNoSubscription
+	}
+	payload := map[string]interface{}{"reason": "Cancelled via Wakapi"}
+	return p.request(http.MethodPost, fmt.Sprintf("/v1/billing/subscriptions/%s/cancel", url.PathEscape(user.SubscriptionId)), payload, nil)
+}
+
+func (p *PayPalProvider) ParseWebhook(r *http.Request) (*Event, error) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookPayloadSize))
+	if err != nil {
+		return nil, err
+	}
+	if err := p.verifyWebhook(r.Header, payload); err != nil {
+		return nil, fmt.Errorf("paypal webhook signature verification failed: %v", err)
+	}
+
+	var event paypalEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse paypal webhook payload: %v", err)
+	}
+
+	var subscription paypalSubscription
+	switch event.EventType {
+	case "BILLING.SUBSCRIPTION.ACTIVATED",
+		"BILLING.SUBSCRIPTION.UPDATED",
+		"BILLING.SUBSCRIPTION.RE-ACTIVATED",
+		"BILLING.SUBSCRIPTION.CANCELLED",
+		"BILLING.SUBSCRIPTION.SUSPENDED",
+		"BILLING.SUBSCRIPTION.EXPIRED":
+		if err := json.Unmarshal(event.Resource, &subscription); err != nil {
+			return nil, fmt.Errorf("failed to parse paypal webhook payload: %v", err)
+		}
+
+	case "PAYMENT.SALE.COMPLETED":
+		// renewals only refer to the subscription, which has to be fetched to learn about the new billing period
+		var sale struct {
+			BillingAgreementId string `json:"billing_agreement_id"`
+		}
+		if err := json.Unmarshal(event.Resource, &sale); err != nil {
+			return nil, fmt.Errorf("failed to parse paypal webhook payload: %v", err)
+		}
+		if sale.BillingAgreementId == "" {
+			return nil, nil // one-time payment
+		}
+		if err := p.request(http.MethodGet, "/v1/billing/subscriptions/"+url.PathEscape(sale.BillingAgreementId), nil, &subscription); err != nil {
+			return nil, err
+		}
+
+	default:
+		logbuch.Warn("got paypal event '%s' with no handler defined", event.EventType)
+		return nil, nil
+	}
+
+	return &Event{Id: event.Id, Type: EventSubscription, Subscription: fromPayPalSubscription(&subscription)}, nil
+}
+
+func (p *PayPalProvider) CustomerEmail(subscription *Subscription) (string, error) {
+	var result paypalSubscription
+	if err := p.request(http.MethodGet, "/v1/billing/subscriptions/"+url.PathEscape(subscription.Id), nil, &result); err != nil {
+		return "", err
+	}
+	return result.Subscriber.EmailAddress, nil
+}
+
+// verifyWebhook lets paypal check the webhook's signature, see https://developer.paypal.com/api/rest/webhooks/rest/#link-verifysignature
+func (p *PayPalProvider) verifyWebhook(header http.Header, payload []byte) error {
+	request := map[string]interface{}{
+		"auth_algo":         header.Get("Paypal-Auth-Algo"),
+		"cert_url":          header.Get("Paypal-Cert-Url"),
+		"transmission_id":   header.Get("Paypal-Transmission-Id"),
+		"transmission_sig":  header.Get("Paypal-Transmission-Sig"),
+		"transmission_time": header.Get("Paypal-Transmission-Time"),
+		"webhook_id":        p.config.WebhookId,
+		"webhook_event":     json.RawMessage(payload),
+	}
+
+	var result struct {
+		VerificationStatus string `json:"verification_status"`
+	}
+	if err := p.request(http.MethodPost, "/v1/notifications/verify-webhook-signature", request, &result); err != nil {
+		return err
+	}
+	if result.VerificationStatus != "SUCCESS" {
+		return fmt.Errorf("verification status '%s'", result.VerificationStatus)
+	}
+	return nil
+}
+
+func (p *PayPalProvider) request(method, path string, payload, result interface{}) error {
+	token, err := p.accessToken()
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, p.apiUrl+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("paypal api responded with status %d: %s", res.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if result == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+// accessToken returns an oauth token for the api, which is cached until shortly before it expires
+func (p *PayPalProvider) accessToken() (string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.token != "" && time.Now().Before(p.tokenExpiry) {
+		return p.token, nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.apiUrl+"/v1/oauth2/token", strings.NewReader("grant_type=client_credentials"))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(p.config.ClientId, p.config.ClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to authenticate with paypal, status %d", res.StatusCode)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	p.token = result.AccessToken
+	p.tokenExpiry = time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - 1*time.Minute)
+	return p.token, nil
+}
+
+func approvalLink(links []paypalLink) (string, error) {
+	for _, link := range links {
+		if link.Rel == "approve" {
+			return link.Href, nil
+		}
+	}
+	return "", fmt.Errorf("paypal returned no approval link")
+}
+
+func fromPayPalSubscription(subscription *paypalSubscription) *Subscription {
+	result := &Subscription{
+		Id:        subscription.Id,
+		Status:    strings.ToLower(subscription.Status),
+		UserId:    subscription.CustomId,
+		PriceId:   subscription.PlanId,
+		PeriodEnd: subscription.BillingInfo.NextBillingTime,
+	}
+
+	switch subscription.Status {
+	case "ACTIVE":
+		result.Status = StatusActive
+	case "CANCELLED":
+		// cancelled subscriptions are not billed anymore, but the last payment still covers the current (monthly) period
+		if result.PeriodEnd.IsZero() && !subscription.BillingInfo.LastPayment.Time.IsZero() {
+			result.PeriodEnd = subscription.BillingInfo.LastPayment.Time.AddDate(0, 1, 0)
+		}
+		result.Status, result.CancelAt = StatusActive, result.PeriodEnd
+		if !result.PeriodEnd.After(time.Now()) {
+			result.Status = StatusCanceled
+		}
+	case "SUSPENDED", "EXPIRED":
+		result.Status = StatusCanceled
+	}
+	return result
+}
@@ -0,0 +1,179 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPayPalTestServer(t *testing.T, verificationStatus string) *httptest.Server {
+	nextBilling := time.Now().AddDate(0, 1, 0).UTC().Format(time.RFC3339)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"access_token": "token", "expires_in": 3600}`))
+	})
+	mux.HandleFunc("/v1/billing/plans/P-BASIC", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "P-BASIC", "billing_cycles": [
+			{"tenure_type": "TRIAL", "pricing_scheme": {"fixed_price": {"value": "0", "currency_code": "EUR"}}},
+			{"tenure_type": "REGULAR", "pricing_scheme": {"fixed_price": {"value": "4.5", "currency_code": "EUR"}}}
+		]}`))
+	})
+	mux.HandleFunc("/v1/billing/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		assert.Equal(t, "P-BASIC", payload["plan_id"])
+		assert.Equal(t, "testuser", payload["custom_id"])
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "I-SUB", "status": "APPROVAL_PENDING", "links": [{"href": "https://paypal.example.org/approve", "rel": "approve"}]}`))
+	})
+	mux.HandleFunc("/v1/billing/subscriptions/I-SUB", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "I-SUB", "status": "ACTIVE", "plan_id": "P-BASIC", "custom_id": "testuser", "subscriber": {"email_address": "foo@example.org"}, "billing_info": {"next_billing_time": "` + nextBilling + `"}}`))
+	})
+	mux.HandleFunc("/v1/notifications/verify-webhook-signature", func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		assert.Equal(t, "webhook", payload["webhook_id"])
+		assert.Equal(t, "transmission", payload["transmission_id"])
+		assert.NotNil(t, payload["webhook_event"])
+		w.Write([]byte(`{"verification_status": "` + verificationStatus + `"}`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func newPayPalTestProvider(apiUrl string) *PayPalProvider {
+	return &PayPalProvider{
+		config:     conf.PayPalConfig{ClientId: "client", ClientSecret: "secret", WebhookId: "webhook"},
+		apiUrl:     apiUrl,
+		autopayUrl: paypalSandboxAutopayUrl,
+		httpClient: http.DefaultClient,
+	}
+}
+
+func newPayPalWebhookRequest(event string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/subscription/webhook", bytes.NewReader([]byte(event)))
+	r.Header.Set("Paypal-Transmission-Id", "transmission")
+	return r
+}
+
+func TestPayPalProvider_FetchPrices(t *testing.T) {
+	server := newPayPalTestServer(t, "SUCCESS")
+	defer server.Close()
+
+	sut := newPayPalTestProvider(server.URL)
+	plans := []*conf.SubscriptionPlan{{Id: "basic", PriceId: "P-BASIC"}}
+
+	assert.Nil(t, sut.FetchPrices(plans))
+	assert.Equal(t, "4.50 €", plans[0].Price)
+
+	assert.NotNil(t, sut.FetchPrices([]*conf.SubscriptionPlan{{Id: "pro", PriceId: "P-UNKNOWN"}}))
+}
+
+func TestPayPalProvider_Checkout(t *testing.T) {
+	server := newPayPalTestServer(t, "SUCCESS")
+	defer server.Close()
+
+	sut := newPayPalTestProvider(server.URL)
+	user := &models.User{ID: "testuser", Email: "foo@example.org"}
+
+	checkoutUrl, err := sut.Checkout(user, &conf.SubscriptionPlan{Id: "basic", PriceId: "P-BASIC"}, 0, "https://wakapi.example.org/subscription/success", "https://wakapi.example.org/subscription/cancel")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://paypal.example.org/approve", checkoutUrl)
+
+	_, err = sut.Portal(user, "")
+	assert.ErrorIs(t, err, ErrNoSubscription)
+
+	user.SubscriptionId = "I-SUB"
+	portalUrl, err := sut.Portal(user, "")
+	assert.Nil(t, err)
+	assert.Equal(t, paypalSandboxAutopayUrl, portalUrl)
+}
+
+func TestPayPalProvider_ParseWebhook(t *testing.T) {
+	server := newPayPalTestServer(t, "SUCCESS")
+	defer server.Close()
+
+	sut := newPayPalTestProvider(server.URL)
+	nextBilling := time.Now().AddDate(0, 0, 20).UTC().Truncate(time.Second)
+
+	// activation
+	event, err := sut.ParseWebhook(newPayPalWebhookRequest(`{"id": "WH-1", "event_type": "BILLING.SUBSCRIPTION.ACTIVATED", "resource": {"id": "I-SUB", "status": "ACTIVE", "plan_id": "P-BASIC", "custom_id": "testuser", "billing_info": {"next_billing_time": "` + nextBilling.Format(time.RFC3339) + `"}}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, EventSubscription, event.Type)
+	assert.Equal(t, "I-SUB", event.Subscription.Id)
+	assert.Equal(t, "testuser", event.Subscription.UserId)
+	assert.Equal(t, "P-BASIC", event.Subscription.PriceId)
+	assert.Equal(t, StatusActive, event.Subscription.Status)
+	assert.True(t, nextBilling.Equal(event.Subscription.PeriodEnd))
+	assert.Zero(t, event.Subscription.CancelAt)
+
+	// cancellation, paid period still lasts
+	lastPayment := time.Now().AddDate(0, 0, -10).UTC().Truncate(time.Second)
+	event, err = sut.ParseWebhook(newPayPalWebhookRequest(`{"id": "WH-2", "event_type": "BILLING.SUBSCRIPTION.CANCELLED", "resource": {"id": "I-SUB", "status": "CANCELLED", "plan_id": "P-BASIC", "custom_id": "testuser", "billing_info": {"last_payment": {"time": "` + lastPayment.Format(time.RFC3339) + `"}}}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, StatusActive, event.Subscription.Status)
+	assert.True(t, lastPayment.AddDate(0, 1, 0).Equal(event.Subscription.CancelAt))
+
+	// suspension
+	event, err = sut.ParseWebhook(newPayPalWebhookRequest(`{"id": "WH-3", "event_type": "BILLING.SUBSCRIPTION.SUSPENDED", "resource": {"id": "I-SUB", "status": "SUSPENDED", "custom_id": "testuser"}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, StatusCanceled, event.Subscription.Status)
+
+	// renewal payment
+	event, err = sut.ParseWebhook(newPayPalWebhookRequest(`{"id": "WH-4", "event_type": "PAYMENT.SALE.COMPLETED", "resource": {"id": "SALE-1", "billing_agreement_id": "I-SUB"}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "I-SUB", event.Subscription.Id)
+	assert.Equal(t, StatusActive, event.Subscription.Status)
+	assert.True(t, event.Subscription.PeriodEnd.After(time.Now()))
+
+	// irrelevant event
+	event, err = sut.ParseWebhook(newPayPalWebhookRequest(`{"id": "WH-5", "event_type": "CUSTOMER.DISPUTE.CREATED", "resource": {}}`))
+	assert.Nil(t, err)
+	assert.Nil(t, event)
+
+	email, err := sut.CustomerEmail(&Subscription{Id: "I-SUB"})
+	assert.Nil(t, err)
+	assert.Equal(t, "foo@example.org", email)
+}
+
+func TestPayPalProvider_ParseWebhook_InvalidSignature(t *testing.T) {
+	server := newPayPalTestServer(t, "FAILURE")
+	defer server.Close()
+
+	sut := newPayPalTestProvider(server.URL)
+
+	event, err := sut.ParseWebhook(newPayPalWebhookRequest(`{"id": "WH-1", "event_type": "BILLING.SUBSCRIPTION.ACTIVATED", "resource": {"id": "I-SUB", "status": "ACTIVE"}}`))
+	assert.NotNil(t, err)
+	assert.Nil(t, event)
+}
+
+func TestPayPalProvider_AccessToken(t *testing.T) {
+	var tokenRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte(`{"access_token": "token", "expires_in": 3600}`))
+	}))
+	defer server.Close()
+
+	sut := newPayPalTestProvider(server.URL)
+
+	for i := 0; i < 3; i++ {
+		token, err := sut.accessToken()
+		assert.Nil(t, err)
+		assert.Equal(t, "token", token)
+	}
+	assert.Equal(t, 1, tokenRequests)
+}
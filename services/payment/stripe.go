@@ -0,0 +1,246 @@
+package payment
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/emvi/logbuch"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stripe/stripe-go/v74"
+	stripePortalSession "github.com/stripe/stripe-go/v74/billingportal/session"
+	stripeCheckoutSession "github.com/stripe/stripe-go/v74/checkout/session"
+	stripeCustomer "github.com/stripe/stripe-go/v74/customer"
+	stripePrice "github.com/stripe/stripe-go/v74/price"
+	stripeSubscription "github.com/stripe/stripe-go/v74/subscription"
+	"github.com/stripe/stripe-go/v74/webhook"
+)
+
+/*
+  How to integrate with Stripe?
+  ---
+  1. Create a product with a recurring price (https://dashboard.stripe.com/test/products?active=true) per plan, copy the price IDs and save them as 'price_id' of the respective entries in 'plans' (or, for a single plan, as 'standard_price_id')
+  2. Create a webhook (https://dashboard.stripe.com/test/webhooks), with target URL '/subscription/webhook' and events ['customer.subscription.created', 'customer.subscription.updated', 'customer.subscription.deleted', 'checkout.session.completed'], copy the endpoint secret and save it to 'stripe_endpoint_secret'
+  3. Create a secret API key (https://dashboard.stripe.com/test/apikeys), copy it and save it to 'stripe_secret_key'
+  4. Copy the publishable API key (https://dashboard.stripe.com/test/apikeys) and save it to 'stripe_api_key'
+*/
+
+// https://stripe.com/docs/billing/quickstart?lang=go
+
+const maxWebhookPayloadSize = 65536
+
+type StripeProvider struct {
+	endpointSecret string
+}
+
+func NewStripeProvider(secretKey, endpointSecret string) *StripeProvider {
+	stripe.Key = secretKey
+	return &StripeProvider{endpointSecret: endpointSecret}
+}
+
+func (p *StripeProvider) Name() string {
+	return conf.PaymentProviderStripe
+}
+
+func (p *StripeProvider) FetchPrices(plans []*conf.SubscriptionPlan) error {
+	for _, plan := range plans {
+		price, err := stripePrice.Get(plan.PriceId, nil)
+		if err != nil {
+			return fmt.Errorf("failed to fetch stripe price details for plan '%s': %v", plan.Id, err)
+		}
+		plan.Price = formatPrice(price.UnitAmountDecimal/100.0, string(price.Currency))
+	}
+	return nil
+}
+
+func (p *StripeProvider) Checkout(user *models.User, plan *conf.SubscriptionPlan, trialDays int, successUrl, cancelUrl string) (string, error) {
+	checkoutParams := &stripe.CheckoutSessionParams{
+		Mode: stripe.String(string(stripe.CheckoutSessionModeSubscription)),
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			{
+				Price:    &plan.PriceId,
+				Quantity: stripe.Int64(1),
+			},
+		},
+		ClientReferenceID:   &user.ID,
+		AllowPromotionCodes: stripe.Bool(true),
+		SuccessURL:          &successUrl,
+		CancelURL:           &cancelUrl,
+	}
+
+	if user.StripeCustomerId != "" {
+		checkoutParams.Customer = &user.StripeCustomerId
+	} else {
+		checkoutParams.CustomerEmail = &user.Email
+	}
+
+	if trialDays > 0 {
+		checkoutParams.SubscriptionData = &stripe.CheckoutSessionSubscriptionDataParams{
+			TrialPeriodDays: stripe.Int64(int64(trialDays)),
+		}
+	}
+
+	session, err := stripeCheckoutSession.New(checkoutParams)
+	if err != nil {
+		return "", err
+	}
+	return session.URL, nil
+}
+
+func (p *StripeProvider) Portal(user *models.User, returnUrl string) (string, error) {
+	if user.StripeCustomerId == "" {
+		return "", ErrNoSubscription
+	}
+
+	session, err := stripePortalSession.New(&stripe.BillingPortalSessionParams{
+		Customer:  &user.StripeCustomerId,
+		ReturnURL: &returnUrl,
+	})
+	if err != nil {
+		return "", err
+	}
+	return session.URL, nil
+}
+
+// ChangePlan switches plans right away, the difference in price is prorated for the remainder of the current billing period
+func (p *StripeProvider) ChangePlan(user *models.User, plan *conf.SubscriptionPlan, returnUrl string) (string, error) {
+	subscription, err := p.findCurrentSubscription(user)
+	if err != nil {
+		return "", err
+	}
+	if subscription.Items == nil || len(subscription.Items.Data) == 0 {
+		return "", fmt.Errorf("subscription '%s' has no items", subscription.ID)
+	}
+
+	item := subscription.Items.Data[0]
+	if item.Price != nil && item.Price.ID == plan.PriceId {
+		return "", nil
+	}
+
+	_, err = stripeSubscription.Update(subscription.ID, &stripe.SubscriptionParams{
+		Items: []*stripe.SubscriptionItemsParams{
+			{
+				ID:    &item.ID,
+				Price: &plan.PriceId,
+			},
+		},
+		ProrationBehavior: stripe.String("create_prorations"),
+	})
+	return "", err
+}
+
+func (p *StripeProvider) Cancel(user *models.User) error {
+	subscription, err := p.findCurrentSubscription(user)
+	if err != nil {
+		return err
+	}
+	_, err = stripeSubscription.Cancel(subscription.ID, nil)
+	return err
+}
+
+func (p *StripeProvider) ParseWebhook(r *http.Request) (*Event, error) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookPayloadSize))
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := webhook.ConstructEventWithOptions(payload, r.Header.Get("Stripe-Signature"), p.endpointSecret, webhook.ConstructEventOptions{
+		IgnoreAPIVersionMismatch: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("stripe webhook signature verification failed: %v", err)
+	}
+
+	switch event.Type {
+	case "customer.subscription.deleted",
+		"customer.subscription.updated",
+		"customer.subscription.created":
+		// example payload: https://pastr.de/p/k7bx3alx38b1iawo6amtx09k
+		var subscription stripe.Subscription
+		if err := json.Unmarshal(event.Data.Raw, &subscription); err != nil {
+			return nil, fmt.Errorf("failed to parse stripe webhook payload: %v", err)
+		}
+		return &Event{Id: event.ID, Type: EventSubscription, Subscription: fromStripeSubscription(&subscription)}, nil
+
+	case "checkout.session.completed":
+		// example payload: https://pastr.de/p/d01iniw9naq9hkmvyqtxin2w
+		var checkoutSession stripe.CheckoutSession
+		if err := json.Unmarshal(event.Data.Raw, &checkoutSession); err != nil {
+			return nil, fmt.Errorf("failed to parse stripe webhook payload: %v", err)
+		}
+		result := &Event{Id: event.ID, Type: EventCheckoutComplete, UserId: checkoutSession.ClientReferenceID}
+		if checkoutSession.Customer != nil {
+			result.CustomerId = checkoutSession.Customer.ID
+		}
+		return result, nil
+	}
+
+	logbuch.Warn("got stripe event '%s' with no handler defined", event.Type)
+	return nil, nil
+}
+
+func (p *StripeProvider) CustomerEmail(subscription *Subscription) (string, error) {
+	customer, err := stripeCustomer.Get(subscription.CustomerId, nil)
+	if err != nil {
+		return "", err
+	}
+	return customer.Email, nil
+}
+
+func (p *StripeProvider) findCurrentSubscription(user *models.User) (*stripe.Subscription, error) {
+	if user.SubscriptionId != "" {
+		return stripeSubscription.Get(user.SubscriptionId, nil)
+	}
+	if user.StripeCustomerId == "" {
+		return nil, ErrNoSubscription
+	}
+
+	// subscriptions created before their ids were stored with the user
+	params := &stripe.SubscriptionListParams{
+		Customer: &user.StripeCustomerId,
+		CurrentPeriodEndRange: &stripe.RangeQueryParams{
+			GreaterThan: time.Now().Unix(),
+		},
+	}
+
+	result := stripeSubscription.List(params)
+	for result.Next() {
+		if s := result.Subscription(); s.Status == stripe.SubscriptionStatusActive || s.Status == stripe.SubscriptionStatusTrialing {
+			return s, nil
+		}
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+	return nil, ErrNoSubscription
+}
+
+func fromStripeSubscription(subscription *stripe.Subscription) *Subscription {
+	result := &Subscription{
+		Id:        subscription.ID,
+		Status:    string(subscription.Status),
+		PeriodEnd: time.Unix(subscription.CurrentPeriodEnd, 0),
+	}
+	if subscription.Customer != nil {
+		result.CustomerId = subscription.Customer.ID
+	}
+	if subscription.CancelAt > 0 {
+		result.CancelAt = time.Unix(subscription.CancelAt, 0)
+	}
+	if subscription.Items != nil && len(subscription.Items.Data) > 0 && subscription.Items.Data[0].Price != nil {
+		result.PriceId = subscription.Items.Data[0].Price.ID
+	}
+
+	switch subscription.Status {
+	case stripe.SubscriptionStatusActive:
+		result.Status = StatusActive
+	case stripe.SubscriptionStatusTrialing:
+		result.Status = StatusTrialing
+	case stripe.SubscriptionStatusCanceled, stripe.SubscriptionStatusUnpaid, stripe.SubscriptionStatusIncompleteExpired:
+		result.Status = StatusCanceled
+	}
+	return result
+}
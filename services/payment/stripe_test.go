@@ -0,0 +1,50 @@
+package payment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stripe/stripe-go/v74"
+)
+
+func Test_fromStripeSubscription(t *testing.T) {
+	periodEnd := time.Now().AddDate(0, 1, 0).Truncate(time.Second)
+
+	subscription := &stripe.Subscription{
+		ID:               "sub_1",
+		Status:           stripe.SubscriptionStatusTrialing,
+		Customer:         &stripe.Customer{ID: "cus_1"},
+		CurrentPeriodEnd: periodEnd.Unix(),
+		Items: &stripe.SubscriptionItemList{
+			Data: []*stripe.SubscriptionItem{{ID: "si_1", Price: &stripe.Price{ID: "price_basic"}}},
+		},
+	}
+
+	result := fromStripeSubscription(subscription)
+	assert.Equal(t, "sub_1", result.Id)
+	assert.Equal(t, "cus_1", result.CustomerId)
+	assert.Equal(t, "price_basic", result.PriceId)
+	assert.Equal(t, StatusTrialing, result.Status)
+	assert.True(t, periodEnd.Equal(result.PeriodEnd))
+	assert.Zero(t, result.CancelAt)
+
+	subscription.Status, subscription.CancelAt = stripe.SubscriptionStatusActive, periodEnd.Unix()
+	result = fromStripeSubscription(subscription)
+	assert.Equal(t, StatusActive, result.Status)
+	assert.True(t, periodEnd.Equal(result.CancelAt))
+
+	for _, status := range []stripe.SubscriptionStatus{stripe.SubscriptionStatusCanceled, stripe.SubscriptionStatusUnpaid, stripe.SubscriptionStatusIncompleteExpired} {
+		subscription.Status = status
+		assert.Equal(t, StatusCanceled, fromStripeSubscription(subscription).Status)
+	}
+
+	subscription.Status = stripe.SubscriptionStatusIncomplete
+	assert.Equal(t, "incomplete", fromStripeSubscription(subscription).Status)
+}
+
+func Test_formatPrice(t *testing.T) {
+	assert.Equal(t, "5.00 €", formatPrice(5, "eur"))
+	assert.Equal(t, "4.99 $", formatPrice(4.99, "usd"))
+	assert.Equal(t, "100.00 chf", formatPrice(100, "chf"))
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+// ErrJobInterrupted is to be returned by job handlers, which stopped early because the instance is shutting down
+// the job is kept in the database to be resumed after the restart, so handlers should persist their progress before returning it
+var ErrJobInterrupted = errors.New("job interrupted by shutdown")
+
+type persistentJobHandler struct {
+	queue *artifex.Dispatcher
+	run   func(payload []byte) error
+}
+
+// PersistentQueueService dispatches jobs of registered types to their in-memory queues, but keeps them in the database while pending, if enabled
+// jobs left over from a previous run (e.g. because the container got restarted in the middle of sending reports) are dispatched again on startup
+// jobs are run at least once, i.e. a job interrupted by a restart is started over from the beginning, and not retried if they fail
+// while draining on shutdown, no more jobs are started and running ones can stop early by returning ErrJobInterrupted
+type PersistentQueueService struct {
+	config     *config.Config
+	repository repositories.IQueuedJobRepository
+	handlers   map[string]*persistentJobHandler
+	lock       sync.RWMutex
+	running    sync.WaitGroup
+	runLock    sync.Mutex
+	draining   bool
+}
+
+func NewPersistentQueueService(queuedJobRepo repositories.IQueuedJobRepository) *PersistentQueueService {
+	return &PersistentQueueService{
+		config:     config.Get(),
+		repository: queuedJobRepo,
+		handlers:   map[string]*persistentJobHandler{},
+	}
+}
+
+// Register makes jobs of the given type run on the given queue, to be called by services on construction
+func (srv *PersistentQueueService) Register(jobType string, queue *artifex.Dispatcher, run func(payload []byte) error) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	srv.handlers[jobType] = &persistentJobHandler{queue: queue, run: run}
+}
+
+// Dispatch enqueues a job of the given type, with the payload getting passed to its handler as json
+func (srv *PersistentQueueService) Dispatch(jobType string, payload interface{}) error {
+	handler, ok := srv.getHandler(jobType)
+	if !ok {
+		return fmt.Errorf("no handler registered for jobs of type '%s'", jobType)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	job := &models.QueuedJob{Type: jobType, Payload: string(data)}
+	if srv.config.App.PersistentJobs {
+		if job, err = srv.repository.Insert(job); err != nil {
+			return err
+		}
+	}
+
+	return srv.dispatch(job, handler)
+}
+
+// Resume dispatches all jobs still pending from before the last shutdown, to be called once all services have registered their handlers
+func (srv *PersistentQueueService) Resume() {
+	if !srv.config.App.PersistentJobs {
+		return
+	}
+
+	jobs, err := srv.repository.GetAll()
+	if err != nil {
+		config.Log().Error("failed to fetch pending jobs, %v", err)
+		return
+	}
+	if len(jobs) > 0 {
+		logbuch.Info("resuming %d pending jobs", len(jobs))
+	}
+
+	for _, job := range jobs {
+		handler, ok := srv.getHandler(job.Type)
+		if !ok {
+			logbuch.Warn("discarding pending job %d of unknown type '%s'", job.ID, job.Type)
+			srv.delete(job)
+			continue
+		}
+		if err := srv.dispatch(job, handler); err != nil {
+			config.Log().Error("failed to resume job %d of type '%s', %v", job.ID, job.Type, err)
+		}
+	}
+}
+
+// Drain stops starting any further jobs and waits for running ones to finish for at most the given timeout
+// returns false if some jobs were still running when the timeout was hit
+func (srv *PersistentQueueService) Drain(timeout time.Duration) bool {
+	srv.runLock.Lock()
+	srv.draining = true
+	srv.runLock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		srv.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (srv *PersistentQueueService) dispatch(job *models.QueuedJob, handler *persistentJobHandler) error {
+	if err := handler.queue.Dispatch(func() {
+		if !srv.begin() {
+			return // left pending in the database, to be resumed after restart
+		}
+		defer srv.running.Done()
+
+		err := handler.run([]byte(job.Payload))
+		if errors.Is(err, ErrJobInterrupted) {
+			if job.ID != 0 {
+				logbuch.Info("job %d of type '%s' interrupted by shutdown, will be resumed after restart", job.ID, job.Type)
+			} else {
+				logbuch.Warn("job of type '%s' interrupted by shutdown and not persisted", job.Type)
+			}
+			return
+		}
+
+		defer srv.delete(job)
+		if err != nil {
+			config.Log().Error("job of type '%s' failed, %v", job.Type, err)
+		}
+	}); err != nil {
+		srv.delete(job)
+		return err
+	}
+	return nil
+}
+
+func (srv *PersistentQueueService) begin() bool {
+	srv.runLock.Lock()
+	defer srv.runLock.Unlock()
+	if srv.draining {
+		return false
+	}
+	srv.running.Add(1)
+	return true
+}
+
+func (srv *PersistentQueueService) delete(job *models.QueuedJob) {
+	if job.ID == 0 {
+		return
+	}
+	if err := srv.repository.Delete(job.ID); err != nil {
+		config.Log().Error("failed to delete job %d of type '%s', %v", job.ID, job.Type, err)
+	}
+}
+
+func (srv *PersistentQueueService) getHandler(jobType string) (*persistentJobHandler, bool) {
+	srv.lock.RLock()
+	defer srv.lock.RUnlock()
+	handler, ok := srv.handlers[jobType]
+	return handler, ok
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type PersistentQueueServiceTestSuite struct {
+	suite.Suite
+	QueuedJobRepository *mocks.QueuedJobRepositoryMock
+	Queue               *artifex.Dispatcher
+}
+
+func (suite *PersistentQueueServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+	suite.Queue = artifex.NewDispatcher(1, 16)
+	suite.Queue.Start()
+}
+
+func (suite *PersistentQueueServiceTestSuite) TearDownSuite() {
+	suite.Queue.Stop()
+}
+
+func (suite *PersistentQueueServiceTestSuite) BeforeTest(suiteName, testName string) {
+	config.Get().App.PersistentJobs = true
+	suite.QueuedJobRepository = new(mocks.QueuedJobRepositoryMock)
+}
+
+func TestPersistentQueueServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(PersistentQueueServiceTestSuite))
+}
+
+func (suite *PersistentQueueServiceTestSuite) TestPersistentQueueService_Dispatch() {
+	suite.QueuedJobRepository.On("Insert", mock.Anything).Return(&models.QueuedJob{ID: 1, Type: "test", Payload: `{"user_id":"user1"}`}, nil)
+	suite.QueuedJobRepository.On("Delete", uint64(1)).Return(nil)
+
+	payloads := make(chan string, 1)
+	sut := NewPersistentQueueService(suite.QueuedJobRepository)
+	sut.Register("test", suite.Queue, func(payload []byte) error {
+		payloads <- string(payload)
+		return nil
+	})
+
+	assert.Nil(suite.T(), sut.Dispatch("test", &userCleanupJob{UserID: "user1"}))
+	assert.NotNil(suite.T(), sut.Dispatch("unknown", nil))
+
+	assert.Equal(suite.T(), `{"user_id":"user1"}`, <-payloads)
+	assert.Eventually(suite.T(), func() bool {
+		return suite.QueuedJobRepository.AssertNumberOfCalls(&testing.T{}, "Delete", 1)
+	}, time.Second, 10*time.Millisecond)
+	suite.QueuedJobRepository.AssertCalled(suite.T(), "Insert", &models.QueuedJob{Type: "test", Payload: `{"user_id":"user1"}`})
+}
+
+func (suite *PersistentQueueServiceTestSuite) TestPersistentQueueService_Dispatch_NotPersistent() {
+	config.Get().App.PersistentJobs = false
+
+	payloads := make(chan string, 1)
+	sut := NewPersistentQueueService(suite.QueuedJobRepository)
+	sut.Register("test", suite.Queue, func(payload []byte) error {
+		payloads <- string(payload)
+		return nil
+	})
+
+	assert.Nil(suite.T(), sut.Dispatch("test", &userCleanupJob{UserID: "user1"}))
+	assert.Equal(suite.T(), `{"user_id":"user1"}`, <-payloads)
+
+	sut.Resume()
+	suite.QueuedJobRepository.AssertNotCalled(suite.T(), "Insert", mock.Anything)
+	suite.QueuedJobRepository.AssertNotCalled(suite.T(), "GetAll")
+}
+
+func (suite *PersistentQueueServiceTestSuite) TestPersistentQueueService_Resume() {
+	suite.QueuedJobRepository.On("GetAll").Return([]*models.QueuedJob{
+		{ID: 1, Type: "test", Payload: `{"user_id":"user1"}`},
+		{ID: 2, Type: "unknown", Payload: `{}`},
+		{ID: 3, Type: "test", Payload: `{"user_id":"user2"}`},
+	}, nil)
+	suite.QueuedJobRepository.On("Delete", mock.Anything).Return(nil)
+
+	payloads := make(chan string, 2)
+	sut := NewPersistentQueueService(suite.QueuedJobRepository)
+	sut.Register("test", suite.Queue, func(payload []byte) error {
+		payloads <- string(payload)
+		return nil
+	})
+
+	sut.Resume()
+
+	assert.ElementsMatch(suite.T(), []string{`{"user_id":"user1"}`, `{"user_id":"user2"}`}, []string{<-payloads, <-payloads})
+	assert.Eventually(suite.T(), func() bool {
+		return suite.QueuedJobRepository.AssertNumberOfCalls(&testing.T{}, "Delete", 3)
+	}, time.Second, 10*time.Millisecond)
+	suite.QueuedJobRepository.AssertNotCalled(suite.T(), "Insert", mock.Anything)
+}
+
+func (suite *PersistentQueueServiceTestSuite) TestPersistentQueueService_Interrupted() {
+	suite.QueuedJobRepository.On("Insert", mock.Anything).Return(&models.QueuedJob{ID: 1, Type: "test", Payload: `{}`}, nil)
+
+	done := make(chan bool, 1)
+	sut := NewPersistentQueueService(suite.QueuedJobRepository)
+	sut.Register("test", suite.Queue, func(payload []byte) error {
+		defer func() { done <- true }()
+		return ErrJobInterrupted
+	})
+
+	assert.Nil(suite.T(), sut.Dispatch("test", struct{}{}))
+	<-done
+	assert.True(suite.T(), sut.Drain(time.Second))
+	suite.QueuedJobRepository.AssertNotCalled(suite.T(), "Delete", mock.Anything)
+}
+
+func (suite *PersistentQueueServiceTestSuite) TestPersistentQueueService_Drain() {
+	suite.QueuedJobRepository.On("Insert", mock.Anything).Return(&models.QueuedJob{ID: 1, Type: "test", Payload: `{}`}, nil)
+
+	release, runs := make(chan bool), make(chan bool, 2)
+	sut := NewPersistentQueueService(suite.QueuedJobRepository)
+	sut.Register("test", suite.Queue, func(payload []byte) error {
+		runs <- true
+		<-release
+		return ErrJobInterrupted
+	})
+
+	assert.Nil(suite.T(), sut.Dispatch("test", struct{}{}))
+	<-runs
+	assert.False(suite.T(), sut.Drain(50*time.Millisecond))
+
+	close(release)
+	assert.True(suite.T(), sut.Drain(time.Second))
+
+	// not started anymore after draining, but kept in the database
+	assert.Nil(suite.T(), sut.Dispatch("test", struct{}{}))
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(suite.T(), runs, 0)
+	suite.QueuedJobRepository.AssertNotCalled(suite.T(), "Delete", mock.Anything)
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+const (
+	// a user is considered active if there was a heartbeat within this period, same as a break ending a session
+	presenceActiveThreshold = 15 * time.Minute
+	// sessions longer than this are only reported as being this long, to not have to fetch arbitrarily many heartbeats
+	presenceMaxSessionLength = 12 * time.Hour
+)
+
+type PresenceService struct {
+	config           *config.Config
+	heartbeatService IHeartbeatService
+}
+
+func NewPresenceService(heartbeatService IHeartbeatService) *PresenceService {
+	return &PresenceService{
+		config:           config.Get(),
+		heartbeatService: heartbeatService,
+	}
+}
+
+// GetByUser tells whether the user is currently active and if so, on which project and language and for how long without a break
+func (srv *PresenceService) GetByUser(user *models.User) (*models.Presence, error) {
+	presence := &models.Presence{}
+
+	latest, err := srv.heartbeatService.GetLatestByUser(user)
+	if err != nil || latest == nil {
+		return presence, nil // user doesn't have any heartbeats
+	}
+
+	lastHeartbeatAt := latest.Time
+	presence.LastHeartbeatAt = &lastHeartbeatAt
+
+	if time.Since(latest.Time.T()) > presenceActiveThreshold {
+		return presence, nil
+	}
+
+	presence.Active = true
+	presence.Project = latest.Project
+	presence.Language = latest.Language
+	presence.Editor = latest.Editor
+
+	heartbeats, err := srv.heartbeatService.GetAllWithin(latest.Time.T().Add(-presenceMaxSessionLength), latest.Time.T().Add(time.Second), user)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionStart := latest.Time.T()
+	for i := len(heartbeats) - 1; i >= 0; i-- {
+		t := heartbeats[i].Time.T()
+		if t.After(sessionStart) {
+			continue
+		}
+		if sessionStart.Sub(t) > presenceActiveThreshold {
+			break
+		}
+		sessionStart = t
+	}
+
+	start := models.CustomTime(sessionStart)
+	presence.SessionStart = &start
+	presence.SessionSeconds = int64(time.Since(sessionStart).Seconds())
+
+	return presence, nil
+}
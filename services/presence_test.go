@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type PresenceServiceTestSuite struct {
+	suite.Suite
+	TestUser         *models.User
+	HeartbeatService *mocks.HeartbeatServiceMock
+}
+
+func (suite *PresenceServiceTestSuite) SetupSuite() {
+	suite.TestUser = &models.User{ID: "testuser"}
+	config.Set(config.Empty())
+}
+
+func (suite *PresenceServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+}
+
+func TestPresenceServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(PresenceServiceTestSuite))
+}
+
+func (suite *PresenceServiceTestSuite) TestPresenceService_GetByUser_Active() {
+	sut := NewPresenceService(suite.HeartbeatService)
+
+	now := time.Now()
+	heartbeats := []*models.Heartbeat{
+		{Project: "wakapi", Time: models.CustomTime(now.Add(-90 * time.Minute))},
+		{Project: "wakapi", Time: models.CustomTime(now.Add(-40 * time.Minute))}, // session starts after break of more than 15 minutes
+		{Project: "wakapi", Time: models.CustomTime(now.Add(-30 * time.Minute))},
+		{Project: "anchr", Language: "Go", Editor: "vscode", Time: models.CustomTime(now.Add(-2 * time.Minute))},
+	}
+
+	suite.HeartbeatService.On("GetLatestByUser", suite.TestUser).Return(heartbeats[3], nil)
+	suite.HeartbeatService.On("GetAllWithin", mock.Anything, mock.Anything, suite.TestUser).Return(heartbeats, nil)
+
+	presence, err := sut.GetByUser(suite.TestUser)
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), presence.Active)
+	assert.Equal(suite.T(), "anchr", presence.Project)
+	assert.Equal(suite.T(), "Go", presence.Language)
+	assert.Equal(suite.T(), heartbeats[1].Time, *presence.SessionStart)
+	assert.InDelta(suite.T(), (40 * time.Minute).Seconds(), presence.SessionSeconds, 5)
+}
+
+func (suite *PresenceServiceTestSuite) TestPresenceService_GetByUser_Inactive() {
+	sut := NewPresenceService(suite.HeartbeatService)
+
+	latest := &models.Heartbeat{Project: "wakapi", Time: models.CustomTime(time.Now().Add(-1 * time.Hour))}
+	suite.HeartbeatService.On("GetLatestByUser", suite.TestUser).Return(latest, nil)
+
+	presence, err := sut.GetByUser(suite.TestUser)
+
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), presence.Active)
+	assert.Empty(suite.T(), presence.Project)
+	assert.Nil(suite.T(), presence.SessionStart)
+	assert.Equal(suite.T(), latest.Time, *presence.LastHeartbeatAt)
+	suite.HeartbeatService.AssertNotCalled(suite.T(), "GetAllWithin", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *PresenceServiceTestSuite) TestPresenceService_GetByUser_NoHeartbeats() {
+	sut := NewPresenceService(suite.HeartbeatService)
+
+	suite.HeartbeatService.On("GetLatestByUser", suite.TestUser).Return((*models.Heartbeat)(nil), errors.New("record not found"))
+
+	presence, err := sut.GetByUser(suite.TestUser)
+
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), presence.Active)
+	assert.Nil(suite.T(), presence.LastHeartbeatAt)
+}
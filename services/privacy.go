@@ -0,0 +1,49 @@
+package services
+
+import (
+	"encoding/json"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+// privacyNoise is the laplace noise drawn for a set of publicly shown aggregates, e.g. all languages' totals within a certain period
+// it is persisted as a key-value, so that noise is drawn only once per aggregate, no matter how often the aggregate is re-computed and published
+type privacyNoise struct {
+	key     string
+	values  map[string]float64
+	changed bool
+}
+
+func loadPrivacyNoise(keyValueService IKeyValueService, scope string) *privacyNoise {
+	noise := &privacyNoise{key: config.KeyPrivacyNoise + "_" + scope, values: map[string]float64{}}
+	if kv := keyValueService.MustGetString(noise.key); kv.Value != "" {
+		if err := json.Unmarshal([]byte(kv.Value), &noise.values); err != nil {
+			config.Log().Error("failed to decode privacy noise '%s' - %v", noise.key, err)
+		}
+	}
+	return noise
+}
+
+// privatize applies the aggregate's noise to its value, drawing the noise first, if not done before
+func (n *privacyNoise) privatize(privatizer *utils.Privatizer, aggregate string, value, sensitivity float64, users int) (float64, bool) {
+	noise, ok := n.values[aggregate]
+	if !ok {
+		noise = privatizer.Noise(sensitivity)
+		n.values[aggregate] = noise
+		n.changed = true
+	}
+	return privatizer.Privatize(value, noise, users)
+}
+
+func (n *privacyNoise) save(keyValueService IKeyValueService) error {
+	if !n.changed {
+		return nil
+	}
+	data, err := json.Marshal(n.values)
+	if err != nil {
+		return err
+	}
+	return keyValueService.PutString(&models.KeyStringValue{Key: n.key, Value: string(data)})
+}
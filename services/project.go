@@ -0,0 +1,204 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+var errProjectRewritePending = errors.New("a project rename or merge is already in progress")
+
+// ProjectService renames and merges projects by rewriting a user's heartbeats, aliases and project labels and subsequently re-generating all affected summaries
+type ProjectService struct {
+	config           *config.Config
+	eventBus         *hub.Hub
+	heartbeatSrvc    IHeartbeatService
+	aliasSrvc        IAliasService
+	projectLabelSrvc IProjectLabelService
+	aggregationSrvc  IAggregationService
+	queue            *artifex.Dispatcher
+	jobs             *userJobs[models.ProjectRewriteJob]
+}
+
+func NewProjectService(heartbeatService IHeartbeatService, aliasService IAliasService, projectLabelService IProjectLabelService, aggregationService IAggregationService) *ProjectService {
+	srv := &ProjectService{
+		config:           config.Get(),
+		eventBus:         config.EventBus(),
+		heartbeatSrvc:    heartbeatService,
+		aliasSrvc:        aliasService,
+		projectLabelSrvc: projectLabelService,
+		aggregationSrvc:  aggregationService,
+		queue:            config.GetQueue(config.QueueProjects),
+		jobs:             newUserJobs((*models.ProjectRewriteJob).IsPending),
+	}
+
+	onUserDelete := srv.eventBus.Subscribe(0, config.EventUserDelete)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.jobs.Delete(m.Fields[config.FieldPayload].(*models.User).ID)
+		}
+	}(&onUserDelete)
+
+	return srv
+}
+
+func (srv *ProjectService) Rename(user *models.User, oldProject, newProject string) error {
+	return srv.Merge(user, []string{oldProject}, newProject)
+}
+
+// Merge schedules a background job to move all heartbeats of the source projects into the target project
+func (srv *ProjectService) Merge(user *models.User, sources []string, target string) error {
+	sourceSet := datastructure.NewSet(sources...)
+	sourceSet.Delete(target)
+	if sourceSet.IsEmpty() {
+		return errors.New("no projects to rewrite")
+	}
+
+	job := &models.ProjectRewriteJob{
+		Status:    models.ProjectRewriteStatusPending,
+		Sources:   sourceSet.Values(),
+		Target:    target,
+		CreatedAt: time.Now(),
+	}
+	if !srv.jobs.Start(user.ID, job) {
+		return errProjectRewritePending
+	}
+
+	u := *user
+	if err := srv.queue.Dispatch(func() {
+		if err := srv.run(&u, job); err != nil {
+			config.Log().Error("failed to rewrite projects %v to '%s' for user '%s' - %v", job.Sources, job.Target, u.ID, err)
+			srv.jobs.Update(job, func(j *models.ProjectRewriteJob) {
+				j.Status = models.ProjectRewriteStatusFailed
+			})
+			return
+		}
+		logbuch.Info("rewrote %d heartbeats of projects %v to '%s' for user '%s'", job.Rewritten, job.Sources, job.Target, u.ID)
+		srv.jobs.Update(job, func(j *models.ProjectRewriteJob) {
+			j.Status = models.ProjectRewriteStatusDone
+			j.Progress = 1
+		})
+	}); err != nil {
+		srv.jobs.Update(job, func(j *models.ProjectRewriteJob) {
+			j.Status = models.ProjectRewriteStatusFailed
+		})
+		return err
+	}
+	return nil
+}
+
+// GetJob returns a snapshot of the user's current or latest rename / merge job
+func (srv *ProjectService) GetJob(user *models.User) *models.ProjectRewriteJob {
+	if job, ok := srv.jobs.Get(user.ID); ok {
+		return job
+	}
+	return &models.ProjectRewriteJob{Status: models.ProjectRewriteStatusNone}
+}
+
+func (srv *ProjectService) run(user *models.User, job *models.ProjectRewriteJob) error {
+	// one step per source project, one for aliases and labels and one for re-generating summaries
+	totalSteps := float64(len(job.Sources) + 2)
+
+	// only summaries between the sources' first and last heartbeat are affected
+	affected, err := srv.heartbeatSrvc.GetTimeRangeByUserAndProjects(user, job.Sources)
+	if err != nil {
+		return err
+	}
+
+	for i, source := range job.Sources {
+		n, err := srv.heartbeatSrvc.RenameProjectByUser(user, source, job.Target)
+		if err != nil {
+			return err
+		}
+		srv.jobs.Update(job, func(j *models.ProjectRewriteJob) {
+			j.Rewritten += n
+			j.Progress = float64(i+1) / totalSteps
+		})
+	}
+
+	if err := srv.rewriteAliases(user, job); err != nil {
+		return err
+	}
+	if err := srv.rewriteProjectLabels(user, job); err != nil {
+		return err
+	}
+	srv.jobs.Update(job, func(j *models.ProjectRewriteJob) {
+		j.Progress = float64(len(job.Sources)+1) / totalSteps
+	})
+
+	if affected.IsEmpty() {
+		return nil
+	}
+	return srv.aggregationSrvc.RegenerateSummaries(user, affected.First.T(), affected.Last.T())
+}
+
+// rewriteAliases points all project aliases, whose original name is one of the sources, to the target instead
+func (srv *ProjectService) rewriteAliases(user *models.User, job *models.ProjectRewriteJob) error {
+	aliases, err := srv.aliasSrvc.GetByUserAndType(user.ID, models.SummaryProject)
+	if err != nil {
+		return err
+	}
+
+	existing := datastructure.NewSet[string]()
+	for _, a := range aliases {
+		if a.Value == job.Target {
+			existing.Add(a.Key)
+		}
+	}
+
+	for _, a := range aliases {
+		if !slice.Contain(job.Sources, a.Value) {
+			continue
+		}
+		if err := srv.aliasSrvc.Delete(a); err != nil {
+			return err
+		}
+		if a.Key == job.Target || existing.Contain(a.Key) {
+			continue
+		}
+		if _, err := srv.aliasSrvc.Create(&models.Alias{Type: a.Type, UserID: a.UserID, Key: a.Key, Value: job.Target}); err != nil {
+			return err
+		}
+		existing.Add(a.Key)
+	}
+	return nil
+}
+
+// rewriteProjectLabels moves all labels of the sources to the target
+func (srv *ProjectService) rewriteProjectLabels(user *models.User, job *models.ProjectRewriteJob) error {
+	labels, err := srv.projectLabelSrvc.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+
+	existing := datastructure.NewSet[string]()
+	for _, l := range labels {
+		if l.ProjectKey == job.Target {
+			existing.Add(l.Label)
+		}
+	}
+
+	for _, l := range labels {
+		if !slice.Contain(job.Sources, l.ProjectKey) {
+			continue
+		}
+		if err := srv.projectLabelSrvc.Delete(l); err != nil {
+			return err
+		}
+		if existing.Contain(l.Label) {
+			continue
+		}
+		if _, err := srv.projectLabelSrvc.Create(&models.ProjectLabel{UserID: l.UserID, ProjectKey: job.Target, Label: l.Label}); err != nil {
+			return err
+		}
+		existing.Add(l.Label)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+var (
+	ErrProjectGroupNotFound = errors.New("project group not found")
+	ErrProjectGroupExists   = errors.New("a project group with this name already exists")
+)
+
+// ProjectGroupService manages umbrella projects, under which a user rolls up multiple projects
+type ProjectGroupService struct {
+	config     *config.Config
+	cache      *cache.Cache
+	eventBus   *hub.Hub
+	repository repositories.IProjectGroupRepository
+}
+
+func NewProjectGroupService(projectGroupRepository repositories.IProjectGroupRepository) *ProjectGroupService {
+	return &ProjectGroupService{
+		config:     config.Get(),
+		cache:      cache.New(1*time.Hour, 1*time.Hour),
+		eventBus:   config.EventBus(),
+		repository: projectGroupRepository,
+	}
+}
+
+func (srv *ProjectGroupService) GetByUser(userId string) ([]*models.ProjectGroup, error) {
+	if groups, found := srv.cache.Get(userId); found {
+		return groups.([]*models.ProjectGroup), nil
+	}
+
+	groups, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Set(userId, groups, cache.DefaultExpiration)
+	return groups, nil
+}
+
+func (srv *ProjectGroupService) GetByUserAndId(userId string, id uint) (*models.ProjectGroup, error) {
+	groups, err := srv.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		if g.ID == id {
+			return g, nil
+		}
+	}
+	return nil, ErrProjectGroupNotFound
+}
+
+// GetGroupsByProject maps each of the user's projects, which is a member of a group, to the group's name
+func (srv *ProjectGroupService) GetGroupsByProject(userId string) (map[string]string, error) {
+	groups, err := srv.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	mapping := make(map[string]string)
+	for _, g := range groups {
+		for _, p := range g.Projects {
+			mapping[p] = g.Name
+		}
+	}
+	return mapping, nil
+}
+
+func (srv *ProjectGroupService) Create(group *models.ProjectGroup) (*models.ProjectGroup, error) {
+	group.Name = strings.TrimSpace(group.Name)
+	if err := srv.checkNameAvailable(group); err != nil {
+		return nil, err
+	}
+	result, err := srv.repository.Insert(group)
+	if err != nil {
+		return nil, err
+	}
+	srv.notifyUpdate(group.UserID)
+	return result, nil
+}
+
+func (srv *ProjectGroupService) Update(group *models.ProjectGroup) (*models.ProjectGroup, error) {
+	group.Name = strings.TrimSpace(group.Name)
+	if err := srv.checkNameAvailable(group); err != nil {
+		return nil, err
+	}
+	result, err := srv.repository.Update(group)
+	if err != nil {
+		return nil, err
+	}
+	srv.notifyUpdate(group.UserID)
+	return result, nil
+}
+
+func (srv *ProjectGroupService) Delete(group *models.ProjectGroup) error {
+	if group.UserID == "" {
+		return errors.New("no user id assigned")
+	}
+	if err := srv.repository.Delete(group.ID); err != nil {
+		return err
+	}
+	srv.notifyUpdate(group.UserID)
+	return nil
+}
+
+func (srv *ProjectGroupService) checkNameAvailable(group *models.ProjectGroup) error {
+	groups, err := srv.GetByUser(group.UserID)
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if g.ID != group.ID && g.Name == group.Name {
+			return ErrProjectGroupExists
+		}
+	}
+	return nil
+}
+
+// notifyUpdate invalidates the user's cached groups and lets other services, e.g. summaries, know their projects were regrouped
+func (srv *ProjectGroupService) notifyUpdate(userId string) {
+	srv.cache.Delete(userId)
+	srv.eventBus.Publish(hub.Message{
+		Name:   config.EventProjectGroupUpdate,
+		Fields: map[string]interface{}{config.FieldPayload: userId, config.FieldUserId: userId},
+	})
+}
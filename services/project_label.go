@@ -89,6 +89,15 @@ func (srv *ProjectLabelService) Delete(label *models.ProjectLabel) error {
 	return err
 }
 
+// FlushUserCache drops the user's cached labels, e.g. after they were modified in the database directly
+func (srv *ProjectLabelService) FlushUserCache(userId string) {
+	srv.cache.Delete(userId)
+	srv.eventBus.Publish(hub.Message{
+		Name:   config.EventProjectLabelDelete,
+		Fields: map[string]interface{}{config.FieldPayload: (*models.ProjectLabel)(nil), config.FieldUserId: userId},
+	})
+}
+
 func (srv *ProjectLabelService) notifyUpdate(label *models.ProjectLabel, isDelete bool) {
 	name := config.EventProjectLabelCreate
 	if isDelete {
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"time"
+
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+// ProjectRemoteService keeps track of the repository remotes reported along with heartbeats and groups projects into namespaces by them
+type ProjectRemoteService struct {
+	config     *config.Config
+	cache      *cache.Cache
+	eventBus   *hub.Hub
+	repository repositories.IProjectRemoteRepository
+}
+
+func NewProjectRemoteService(projectRemoteRepository repositories.IProjectRemoteRepository) *ProjectRemoteService {
+	srv := &ProjectRemoteService{
+		config:     config.Get(),
+		cache:      cache.New(24*time.Hour, 24*time.Hour),
+		eventBus:   config.EventBus(),
+		repository: projectRemoteRepository,
+	}
+
+	onHeartbeat := srv.eventBus.Subscribe(0, config.EventHeartbeatCreate)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			heartbeat := m.Fields[config.FieldPayload].(*models.Heartbeat)
+			if err := srv.track(heartbeat); err != nil {
+				config.Log().Error("failed to track project remote for user '%s' - %v", heartbeat.UserID, err)
+			}
+		}
+	}(&onHeartbeat)
+
+	return srv
+}
+
+func (srv *ProjectRemoteService) GetByUser(userId string) ([]*models.ProjectRemote, error) {
+	if remotes, found := srv.cache.Get(userId); found {
+		return remotes.([]*models.ProjectRemote), nil
+	}
+
+	remotes, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Set(userId, remotes, cache.DefaultExpiration)
+	return remotes, nil
+}
+
+// GetNamespacesByUser maps each of the user's projects with a known remote to its namespace
+func (srv *ProjectRemoteService) GetNamespacesByUser(userId string) (map[string]string, error) {
+	remotes, err := srv.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make(map[string]string, len(remotes))
+	for _, r := range remotes {
+		namespaces[r.Project] = r.Namespace
+	}
+	return namespaces, nil
+}
+
+// track persists the remote reported with a heartbeat, unless it's already known for the heartbeat's project
+func (srv *ProjectRemoteService) track(heartbeat *models.Heartbeat) error {
+	if heartbeat.ProjectRemote == "" || heartbeat.Project == "" {
+		return nil
+	}
+
+	remotes, err := srv.GetByUser(heartbeat.UserID)
+	if err != nil {
+		return err
+	}
+	for _, r := range remotes {
+		if r.Project == heartbeat.Project && r.Remote == heartbeat.ProjectRemote {
+			return nil
+		}
+	}
+
+	namespace := models.ParseRemoteNamespace(heartbeat.ProjectRemote)
+	if namespace == "" {
+		return nil
+	}
+
+	if _, err := srv.repository.Upsert(&models.ProjectRemote{
+		UserID:    heartbeat.UserID,
+		Project:   heartbeat.Project,
+		Remote:    heartbeat.ProjectRemote,
+		Namespace: namespace,
+		UpdatedAt: models.CustomTime(time.Now()),
+	}); err != nil {
+		return err
+	}
+
+	srv.cache.Delete(heartbeat.UserID)
+	srv.eventBus.Publish(hub.Message{
+		Name:   config.EventProjectRemoteUpdate,
+		Fields: map[string]interface{}{config.FieldPayload: heartbeat.ProjectRemote, config.FieldUserId: heartbeat.UserID},
+	})
+	return nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ProjectServiceTestSuite struct {
+	suite.Suite
+	TestUser            *models.User
+	HeartbeatService    *mocks.HeartbeatServiceMock
+	AliasService        *mocks.AliasServiceMock
+	ProjectLabelService *mocks.ProjectLabelServiceMock
+	AggregationService  *mocks.AggregationServiceMock
+}
+
+func (suite *ProjectServiceTestSuite) SetupSuite() {
+	suite.TestUser = &models.User{ID: "testuser"}
+	config.Set(config.Empty())
+}
+
+func (suite *ProjectServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.AliasService = new(mocks.AliasServiceMock)
+	suite.ProjectLabelService = new(mocks.ProjectLabelServiceMock)
+	suite.AggregationService = new(mocks.AggregationServiceMock)
+}
+
+func TestProjectServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ProjectServiceTestSuite))
+}
+
+func (suite *ProjectServiceTestSuite) TestProjectService_Run_RewritesAliasesLabelsAndAffectedSummaries() {
+	sut := NewProjectService(suite.HeartbeatService, suite.AliasService, suite.ProjectLabelService, suite.AggregationService)
+
+	first, last := models.CustomTime(time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)), models.CustomTime(time.Date(2023, 1, 5, 10, 0, 0, 0, time.UTC))
+	aliases := []*models.Alias{
+		{ID: 1, Type: models.SummaryProject, UserID: suite.TestUser.ID, Key: "wakapi-old", Value: "old"},
+		{ID: 2, Type: models.SummaryProject, UserID: suite.TestUser.ID, Key: "new", Value: "old"},
+		{ID: 3, Type: models.SummaryProject, UserID: suite.TestUser.ID, Key: "other", Value: "anchr"},
+	}
+	labels := []*models.ProjectLabel{
+		{ID: 1, UserID: suite.TestUser.ID, ProjectKey: "old", Label: "work"},
+		{ID: 2, UserID: suite.TestUser.ID, ProjectKey: "old", Label: "oss"},
+		{ID: 3, UserID: suite.TestUser.ID, ProjectKey: "new", Label: "oss"},
+	}
+
+	suite.HeartbeatService.On("GetTimeRangeByUserAndProjects", suite.TestUser, []string{"old"}).Return(&models.HeartbeatTimeRange{First: &first, Last: &last}, nil)
+	suite.HeartbeatService.On("RenameProjectByUser", suite.TestUser, "old", "new").Return(42, nil)
+	suite.AliasService.On("GetByUserAndType", suite.TestUser.ID, models.SummaryProject).Return(aliases, nil)
+	suite.AliasService.On("Delete", mock.Anything).Return(nil)
+	suite.AliasService.On("Create", mock.Anything).Return(&models.Alias{}, nil)
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return(labels, nil)
+	suite.ProjectLabelService.On("Delete", mock.Anything).Return(nil)
+	suite.ProjectLabelService.On("Create", mock.Anything).Return(&models.ProjectLabel{}, nil)
+	suite.AggregationService.On("RegenerateSummaries", suite.TestUser, first.T(), last.T()).Return(nil)
+
+	job := &models.ProjectRewriteJob{Status: models.ProjectRewriteStatusPending, Sources: []string{"old"}, Target: "new"}
+	sut.jobs.Start(suite.TestUser.ID, job)
+
+	err := sut.run(suite.TestUser, job)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(42), job.Rewritten)
+
+	suite.AliasService.AssertCalled(suite.T(), "Delete", aliases[0])
+	suite.AliasService.AssertCalled(suite.T(), "Delete", aliases[1])
+	suite.AliasService.AssertNotCalled(suite.T(), "Delete", aliases[2])
+	suite.AliasService.AssertNumberOfCalls(suite.T(), "Create", 1)
+	suite.AliasService.AssertCalled(suite.T(), "Create", &models.Alias{Type: models.SummaryProject, UserID: suite.TestUser.ID, Key: "wakapi-old", Value: "new"})
+
+	suite.ProjectLabelService.AssertCalled(suite.T(), "Delete", labels[0])
+	suite.ProjectLabelService.AssertCalled(suite.T(), "Delete", labels[1])
+	suite.ProjectLabelService.AssertNotCalled(suite.T(), "Delete", labels[2])
+	suite.ProjectLabelService.AssertNumberOfCalls(suite.T(), "Create", 1)
+	suite.ProjectLabelService.AssertCalled(suite.T(), "Create", &models.ProjectLabel{UserID: suite.TestUser.ID, ProjectKey: "new", Label: "work"})
+
+	suite.AggregationService.AssertNumberOfCalls(suite.T(), "RegenerateSummaries", 1)
+}
+
+func (suite *ProjectServiceTestSuite) TestProjectService_Run_NoHeartbeats_SkipsRegeneration() {
+	sut := NewProjectService(suite.HeartbeatService, suite.AliasService, suite.ProjectLabelService, suite.AggregationService)
+
+	suite.HeartbeatService.On("GetTimeRangeByUserAndProjects", suite.TestUser, []string{"old"}).Return(&models.HeartbeatTimeRange{}, nil)
+	suite.HeartbeatService.On("RenameProjectByUser", suite.TestUser, "old", "new").Return(0, nil)
+	suite.AliasService.On("GetByUserAndType", suite.TestUser.ID, models.SummaryProject).Return([]*models.Alias{}, nil)
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
+
+	job := &models.ProjectRewriteJob{Status: models.ProjectRewriteStatusPending, Sources: []string{"old"}, Target: "new"}
+	sut.jobs.Start(suite.TestUser.ID, job)
+
+	err := sut.run(suite.TestUser, job)
+
+	assert.Nil(suite.T(), err)
+	suite.AggregationService.AssertNotCalled(suite.T(), "RegenerateSummaries", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ProjectServiceTestSuite) TestProjectService_Run_RenameFails_ReturnsError() {
+	sut := NewProjectService(suite.HeartbeatService, suite.AliasService, suite.ProjectLabelService, suite.AggregationService)
+
+	suite.HeartbeatService.On("GetTimeRangeByUserAndProjects", suite.TestUser, []string{"old"}).Return(&models.HeartbeatTimeRange{}, nil)
+	suite.HeartbeatService.On("RenameProjectByUser", suite.TestUser, "old", "new").Return(0, errors.New("failed"))
+
+	job := &models.ProjectRewriteJob{Status: models.ProjectRewriteStatusPending, Sources: []string{"old"}, Target: "new"}
+	sut.jobs.Start(suite.TestUser.ID, job)
+
+	err := sut.run(suite.TestUser, job)
+
+	assert.Error(suite.T(), err)
+	suite.AliasService.AssertNotCalled(suite.T(), "GetByUserAndType", mock.Anything, mock.Anything)
+	suite.AggregationService.AssertNotCalled(suite.T(), "RegenerateSummaries", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	publicStatsCacheTTL      = 1 * time.Hour
+	publicStatsCacheKey      = "public_stats"
+	publicStatsLanguageLimit = 10
+)
+
+// PublicStatsService provides anonymous, instance-wide statistics, based on the periodically computed totals and the (privacy-preserving) language trends
+type PublicStatsService struct {
+	config       *config.Config
+	cache        *cache.Cache
+	keyValueSrvc IKeyValueService
+	trendsSrvc   ITrendsService
+}
+
+func NewPublicStatsService(keyValueService IKeyValueService, trendsService ITrendsService) *PublicStatsService {
+	return &PublicStatsService{
+		config:       config.Get(),
+		cache:        cache.New(publicStatsCacheTTL, publicStatsCacheTTL),
+		keyValueSrvc: keyValueService,
+		trendsSrvc:   trendsService,
+	}
+}
+
+// Get returns the instance's total users, total coding hours and its top languages of the past 30 days
+// if differential privacy is enabled for public stats, only the noised totals are published, which are empty for too small instances
+func (srv *PublicStatsService) Get() (*models.PublicStats, error) {
+	if cacheResult, ok := srv.cache.Get(publicStatsCacheKey); ok {
+		return cacheResult.(*models.PublicStats), nil
+	}
+
+	keyTotalTime, keyTotalUsers := config.KeyLatestTotalTime, config.KeyLatestTotalUsers
+	if srv.config.App.PublicStatsPrivacy.Enabled {
+		keyTotalTime, keyTotalUsers = config.KeyLatestTotalTimePublic, config.KeyLatestTotalUsersPublic
+	}
+
+	stats := &models.PublicStats{UpdatedAt: time.Now()}
+
+	if kv, err := srv.keyValueSrvc.GetString(keyTotalTime); err == nil && kv != nil && kv.Value != "" {
+		if d, err := time.ParseDuration(kv.Value); err == nil {
+			stats.TotalHours = int(d.Hours())
+		}
+	}
+	if kv, err := srv.keyValueSrvc.GetString(keyTotalUsers); err == nil && kv != nil && kv.Value != "" {
+		if n, err := strconv.Atoi(kv.Value); err == nil {
+			stats.TotalUsers = n
+		}
+	}
+
+	languages, err := srv.trendsSrvc.GetTopLanguages(models.IntervalPast30Days, publicStatsLanguageLimit)
+	if err != nil {
+		return nil, err
+	}
+	stats.TopLanguages = languages
+
+	srv.cache.SetDefault(publicStatsCacheKey, stats)
+	return stats, nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type PublicStatsServiceTestSuite struct {
+	suite.Suite
+	KeyValueService   *mocks.KeyValueServiceMock
+	SummaryRepository *mocks.SummaryRepositoryMock
+}
+
+func (suite *PublicStatsServiceTestSuite) SetupSuite() {
+	cfg := config.Empty()
+	cfg.App.PublicStatsPrivacy.MinUsers = 3
+	cfg.App.PublicStatsPrivacy.MaxHoursPerUser = 5000
+	config.Set(cfg)
+}
+
+func (suite *PublicStatsServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.KeyValueService = new(mocks.KeyValueServiceMock)
+	suite.SummaryRepository = new(mocks.SummaryRepositoryMock)
+}
+
+func TestPublicStatsServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(PublicStatsServiceTestSuite))
+}
+
+func (suite *PublicStatsServiceTestSuite) TestPublicStatsService_Get() {
+	suite.KeyValueService.On("GetString", config.KeyLatestTotalTime).Return(&models.KeyStringValue{Key: config.KeyLatestTotalTime, Value: "1234h30m0s"}, nil)
+	suite.KeyValueService.On("GetString", config.KeyLatestTotalUsers).Return(&models.KeyStringValue{Key: config.KeyLatestTotalUsers, Value: "42"}, nil)
+	suite.SummaryRepository.On("GetTotalsByTypeWithin", models.SummaryLanguage, mock.Anything, mock.Anything, mock.Anything).Return([]*models.TotalByKey{
+		{Key: "Go", Total: 3 * 3600, Users: 5},
+		{Key: "Python", Total: 1 * 3600, Users: 3},
+		{Key: "Brainfuck", Total: 10 * 3600, Users: 1}, // too few users to be published
+	}, nil)
+
+	sut := NewPublicStatsService(suite.KeyValueService, NewTrendsService(suite.SummaryRepository, suite.KeyValueService))
+
+	result, err := sut.Get()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 42, result.TotalUsers)
+	assert.Equal(suite.T(), 1234, result.TotalHours)
+	assert.Len(suite.T(), result.TopLanguages.Languages, 2)
+	assert.Equal(suite.T(), "Go", result.TopLanguages.Languages[0].Language)
+	assert.Equal(suite.T(), 75.0, result.TopLanguages.Languages[0].Percent)
+
+	// served from cache
+	_, err = sut.Get()
+	assert.Nil(suite.T(), err)
+	suite.KeyValueService.AssertNumberOfCalls(suite.T(), "GetString", 2)
+	suite.SummaryRepository.AssertNumberOfCalls(suite.T(), "GetTotalsByTypeWithin", 1)
+}
+
+func (suite *PublicStatsServiceTestSuite) TestPublicStatsService_Get_NotComputedYet() {
+	suite.KeyValueService.On("GetString", mock.Anything).Return(&models.KeyStringValue{}, errors.New("not found"))
+	suite.SummaryRepository.On("GetTotalsByTypeWithin", models.SummaryLanguage, mock.Anything, mock.Anything, mock.Anything).Return([]*models.TotalByKey{}, nil)
+
+	sut := NewPublicStatsService(suite.KeyValueService, NewTrendsService(suite.SummaryRepository, suite.KeyValueService))
+
+	result, err := sut.Get()
+	assert.Nil(suite.T(), err)
+	assert.Zero(suite.T(), result.TotalUsers)
+	assert.Zero(suite.T(), result.TotalHours)
+	assert.Empty(suite.T(), result.TopLanguages.Languages)
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+const quotaCacheTTL = 25 * time.Hour // counts are kept per day
+
+var (
+	ErrQuotaHeartbeatsPerDay = errors.New("daily heartbeat quota exceeded")
+	ErrQuotaMachines         = errors.New("machine quota exceeded")
+	ErrQuotaProjects         = errors.New("project quota exceeded")
+)
+
+// QuotaService enforces the configured per-user limits for sending heartbeats
+// the number of heartbeats received per day is counted in the cache (shared among replicas, if configured), starting from the number of the user's heartbeats of today in the database
+type QuotaService struct {
+	config        *config.Config
+	heartbeatSrvc IHeartbeatService
+	cache         utils.Cache
+	lock          sync.Mutex
+}
+
+func NewQuotaService(heartbeatService IHeartbeatService) *QuotaService {
+	return &QuotaService{
+		config:        config.Get(),
+		heartbeatSrvc: heartbeatService,
+		cache:         config.NewCache("quotas", quotaCacheTTL, 1*time.Hour),
+	}
+}
+
+// Check tells for each of the given heartbeats, whether accepting it would exceed one of the user's quotas (non-nil error at the respective index)
+// additionally returns warnings for quotas, which are close to being exhausted
+// heartbeats are not counted as received before being passed to Record, i.e. only once they were actually stored
+func (srv *QuotaService) Check(user *models.User, heartbeats []*models.Heartbeat) ([]error, []string, error) {
+	results := make([]error, len(heartbeats))
+	quotas := srv.config.App.Quotas
+	if !quotas.Enabled() || user.IsAdmin {
+		return results, nil, nil
+	}
+
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	dayKey := srv.dayKey(user)
+
+	var dailyCount int64
+	if quotas.HeartbeatsPerDay > 0 {
+		var err error
+		if dailyCount, err = srv.countToday(user, dayKey); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	machines, err := srv.entitySet(models.SummaryMachine, user, quotas.Machines)
+	if err != nil {
+		return nil, nil, err
+	}
+	projects, err := srv.entitySet(models.SummaryProject, user, quotas.Projects)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, hb := range heartbeats {
+		if quotas.HeartbeatsPerDay > 0 && dailyCount >= int64(quotas.HeartbeatsPerDay) {
+			results[i] = ErrQuotaHeartbeatsPerDay
+			continue
+		}
+		if quotas.Machines > 0 && hb.Machine != "" && !machines.Contain(hb.Machine) {
+			if machines.Size() >= quotas.Machines {
+				results[i] = ErrQuotaMachines
+				continue
+			}
+			machines.Add(hb.Machine)
+		}
+		if quotas.Projects > 0 && hb.Project != "" && !projects.Contain(hb.Project) {
+			if projects.Size() >= quotas.Projects {
+				results[i] = ErrQuotaProjects
+				continue
+			}
+			projects.Add(hb.Project)
+		}
+		dailyCount++
+	}
+
+	warnings := make([]string, 0)
+	for _, q := range []struct {
+		name        string
+		used, quota int
+	}{
+		{"heartbeats today", int(dailyCount), quotas.HeartbeatsPerDay},
+		{"machines", machines.Size(), quotas.Machines},
+		{"projects", projects.Size(), quotas.Projects},
+	} {
+		if q.quota > 0 && float64(q.used) >= quotas.WarnRatio*float64(q.quota) {
+			warning := fmt.Sprintf("%d of %d %s", q.used, q.quota, q.name)
+			warnings = append(warnings, warning)
+			srv.logOnce(dayKey+"_warn_"+q.name, "user '%s' is close to exceeding quota - %s", user.ID, warning)
+		}
+	}
+	for _, err := range results {
+		if err != nil {
+			srv.logOnce(dayKey+"_"+err.Error(), "user '%s' exceeded quota - %v", user.ID, err)
+		}
+	}
+
+	return results, warnings, nil
+}
+
+// Record counts the given number of the user's heartbeats as received today, to be called after they were stored
+// other replicas might have counted heartbeats of the user meanwhile, so the count is incremented rather than overwritten
+// if not cached (anymore), nothing needs to be done, as the next check counts the user's heartbeats in the database anyway
+func (srv *QuotaService) Record(user *models.User, n int) {
+	if n <= 0 || srv.config.App.Quotas.HeartbeatsPerDay <= 0 || user.IsAdmin {
+		return
+	}
+	srv.cache.IncrementInt64(srv.dayKey(user), int64(n))
+}
+
+// countToday returns the number of heartbeats the user sent today, which is kept in the cache from then on
+func (srv *QuotaService) countToday(user *models.User, dayKey string) (int64, error) {
+	var count int64
+	if srv.cache.Get(dayKey, &count) {
+		return count, nil
+	}
+	now := time.Now().In(user.TZ())
+	count, err := srv.heartbeatSrvc.CountByUserWithinByFilters(user, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), now, &models.Filters{}, "")
+	if err != nil {
+		return 0, err
+	}
+	srv.cache.SetDefault(dayKey, count)
+	return count, nil
+}
+
+func (srv *QuotaService) dayKey(user *models.User) string {
+	return fmt.Sprintf("%s_%s", user.ID, time.Now().In(user.TZ()).Format(config.SimpleDateFormat))
+}
+
+func (srv *QuotaService) entitySet(entityType uint8, user *models.User, quota int) (datastructure.Set[string], error) {
+	if quota <= 0 {
+		return datastructure.NewSet[string](), nil
+	}
+	entities, err := srv.heartbeatSrvc.GetEntitySetByUser(entityType, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return datastructure.NewSet(entities...), nil
+}
+
+// logOnce logs a quota warning only once per key (i.e. per user, day and quota), as clients keep on sending heartbeats
+func (srv *QuotaService) logOnce(key, msg string, args ...interface{}) {
+	var logged bool
+	if srv.cache.Get(key, &logged) {
+		return
+	}
+	srv.cache.SetDefault(key, true)
+	config.Log().Warn(msg, args...)
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestQuotaService_Check_HeartbeatsPerDay(t *testing.T) {
+	cfg := config.Empty()
+	cfg.App.Quotas.HeartbeatsPerDay = 10
+	cfg.App.Quotas.WarnRatio = 0.8
+	config.Set(cfg)
+
+	user := &models.User{ID: "testuser"}
+	heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
+	heartbeatServiceMock.On("CountByUserWithinByFilters", user, mock.Anything, mock.Anything, mock.Anything, "").Return(7, nil)
+
+	sut := NewQuotaService(heartbeatServiceMock)
+
+	results, warnings, err := sut.Check(user, []*models.Heartbeat{{}, {}})
+	assert.Nil(t, err)
+	assert.Equal(t, []error{nil, nil}, results)
+	assert.Equal(t, []string{"9 of 10 heartbeats today"}, warnings)
+
+	// heartbeats only count once they were actually stored
+	results, _, err = sut.Check(user, []*models.Heartbeat{{}, {}})
+	assert.Nil(t, err)
+	assert.Equal(t, []error{nil, nil}, results)
+
+	// count is continued in the cache
+	sut.Record(user, 2)
+	results, _, err = sut.Check(user, []*models.Heartbeat{{}, {}})
+	assert.Nil(t, err)
+	assert.Nil(t, results[0])
+	assert.Equal(t, ErrQuotaHeartbeatsPerDay, results[1])
+	heartbeatServiceMock.AssertNumberOfCalls(t, "CountByUserWithinByFilters", 1)
+
+	// admins are exempt
+	results, warnings, err = sut.Check(&models.User{ID: "admin", IsAdmin: true}, []*models.Heartbeat{{}})
+	assert.Nil(t, err)
+	assert.Nil(t, results[0])
+	assert.Empty(t, warnings)
+}
@@ -1,6 +1,9 @@
 package services
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/duke-git/lancet/v2/datetime"
 	"github.com/duke-git/lancet/v2/slice"
 	"github.com/emvi/logbuch"
@@ -19,54 +22,61 @@ const reportDelay = 10 * time.Second
 // past time range to cover in the report
 const reportRange = 7 * 24 * time.Hour
 
+// maximum time range to generate per-day summaries for in ad-hoc reports
+const reportMaxDailyRange = 93 * 24 * time.Hour
+
+const jobTypeReport = "weekly_report"
+
+var ErrReportRangeTooLarge = errors.New("interval too large for a daily breakdown")
+
+type reportJob struct {
+	UserID string `json:"user_id"`
+}
+
 type ReportService struct {
-	config         *config.Config
-	eventBus       *hub.Hub
-	summaryService ISummaryService
-	userService    IUserService
-	mailService    IMailService
-	rand           *rand.Rand
-	queueDefault   *artifex.Dispatcher
-	queueWorkers   *artifex.Dispatcher
+	config           *config.Config
+	eventBus         *hub.Hub
+	summaryService   ISummaryService
+	userService      IUserService
+	mailService      IMailService
+	keyValueSrvc     IKeyValueService
+	annotationSrvc   IAnnotationService
+	trendsSrvc       IUserTrendsService
+	workingHoursSrvc IWorkingHoursService
+	rand             *rand.Rand
+	queueDefault     *artifex.Dispatcher
+	queueWorkers     *artifex.Dispatcher
+	queueSrvc        IPersistentQueueService
+	crons            *cronJobs
 }
 
-func NewReportService(summaryService ISummaryService, userService IUserService, mailService IMailService) *ReportService {
+func NewReportService(summaryService ISummaryService, userService IUserService, mailService IMailService, keyValueService IKeyValueService, annotationService IAnnotationService, userTrendsService IUserTrendsService, workingHoursService IWorkingHoursService, persistentQueueService IPersistentQueueService, jobLockService IJobLockService) *ReportService {
 	srv := &ReportService{
-		config:         config.Get(),
-		eventBus:       config.EventBus(),
-		summaryService: summaryService,
-		userService:    userService,
-		mailService:    mailService,
-		rand:           rand.New(rand.NewSource(time.Now().Unix())),
-		queueDefault:   config.GetDefaultQueue(),
-		queueWorkers:   config.GetQueue(config.QueueReports),
+		config:           config.Get(),
+		eventBus:         config.EventBus(),
+		summaryService:   summaryService,
+		userService:      userService,
+		mailService:      mailService,
+		keyValueSrvc:     keyValueService,
+		annotationSrvc:   annotationService,
+		trendsSrvc:       userTrendsService,
+		workingHoursSrvc: workingHoursService,
+		rand:             rand.New(rand.NewSource(time.Now().Unix())),
+		queueDefault:     config.GetDefaultQueue(),
+		queueWorkers:     config.GetQueue(config.QueueReports),
+		queueSrvc:        persistentQueueService,
+		crons:            newCronJobs("weekly_reports", config.GetDefaultQueue(), jobLockService),
 	}
 
+	persistentQueueService.Register(jobTypeReport, srv.queueWorkers, srv.runReportJob)
+
 	return srv
 }
 
 func (srv *ReportService) Schedule() {
 	logbuch.Info("scheduling report generation")
 
-	scheduleUserReport := func(u *models.User) {
-		if err := srv.queueWorkers.Dispatch(func() {
-			t0 := time.Now()
-
-			if err := srv.SendReport(u, reportRange); err != nil {
-				config.Log().Error("failed to generate report for '%s', %v", u.ID, err)
-			}
-
-			// make the job take at least reportDelay seconds
-			if diff := reportDelay - time.Now().Sub(t0); diff > 0 {
-				logbuch.Debug("waiting for %v before sending next report", diff)
-				time.Sleep(diff)
-			}
-		}); err != nil {
-			config.Log().Error("failed to dispatch report generation job for user '%s', %v", u.ID, err)
-		}
-	}
-
-	_, err := srv.queueDefault.DispatchCron(func() {
+	fanOut := func() {
 		// fetch all users with reports enabled
 		users, err := srv.userService.GetAllByReports(true)
 		if err != nil {
@@ -74,21 +84,28 @@ func (srv *ReportService) Schedule() {
 			return
 		}
 
-		// filter users who have their email set
+		// filter users who have their email set and whose account is active
 		users = slice.Filter[*models.User](users, func(i int, u *models.User) bool {
-			return u.Email != ""
+			return u.Email != "" && u.IsActive()
 		})
 
 		// schedule jobs, throttled by one job per x seconds
 		logbuch.Info("scheduling report generation for %d users", len(users))
 		for _, u := range users {
-			scheduleUserReport(u)
+			if err := srv.queueSrvc.Dispatch(jobTypeReport, &reportJob{UserID: u.ID}); err != nil {
+				config.Log().Error("failed to dispatch report generation job for user '%s', %v", u.ID, err)
+			}
 		}
-	}, srv.config.App.GetWeeklyReportCron())
+	}
 
-	if err != nil {
-		config.Log().Error("failed to dispatch report generation jobs, %v", err)
+	schedule := func() {
+		if err := srv.crons.Schedule(fanOut, config.Get().App.GetWeeklyReportCron()); err != nil {
+			config.Log().Error("failed to dispatch report generation jobs, %v", err)
+		}
 	}
+
+	schedule()
+	config.OnReload(schedule, config.ReloadKeyReportTimeWeekly)
 }
 
 func (srv *ReportService) SendReport(user *models.User, duration time.Duration) error {
@@ -102,41 +119,159 @@ func (srv *ReportService) SendReport(user *models.User, duration time.Duration)
 	end := time.Now().In(user.TZ())
 	start := time.Now().Add(-1 * duration)
 
-	fullSummary, err := srv.summaryService.Aliased(start, end, user, srv.summaryService.Retrieve, nil, false)
+	report, err := srv.generate(user, &models.ReportParams{From: start, To: end, Daily: true})
 	if err != nil {
 		config.Log().Error("failed to generate report for '%s' - %v", user.ID, err)
 		return err
 	}
 
-	// generate per-day summaries
-	dayIntervals := utils.SplitRangeByDays(start, end)
-	dailySummaries := make([]*models.Summary, len(dayIntervals))
+	// trends are supplementary, so the report is sent without them if they fail to compute
+	if report.Trends, err = srv.trendsSrvc.GetByUser(user, UserTrendsDefaultWeeks); err != nil {
+		config.Log().Error("failed to compute trends for report for '%s' - %v", user.ID, err)
+	}
+
+	if err := srv.mailService.SendReport(user, report); err != nil {
+		config.Log().Error("failed to send report for '%s', %v", user.ID, err)
+		srv.recordDelivery(user, err)
+		return err
+	}
+
+	logbuch.Info("sent report to user '%s'", user.ID)
+	srv.recordDelivery(user, nil)
+	return nil
+}
+
+func (srv *ReportService) runReportJob(payload []byte) error {
+	var job reportJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+
+	u, err := srv.userService.GetUserById(job.UserID)
+	if err != nil {
+		return err
+	}
+
+	t0 := time.Now()
+
+	if err := srv.SendReport(u, reportRange); err != nil {
+		config.Log().Error("failed to generate report for '%s', %v", u.ID, err)
+	}
 
-	for i, interval := range dayIntervals {
-		from, to := datetime.BeginOfDay(interval[0]), interval[1]
-		summary, err := srv.summaryService.Aliased(from, to, user, srv.summaryService.Retrieve, nil, false)
+	// make the job take at least reportDelay seconds
+	if diff := reportDelay - time.Now().Sub(t0); diff > 0 {
+		logbuch.Debug("waiting for %v before sending next report", diff)
+		time.Sleep(diff)
+	}
+	return nil
+}
+
+// Generate creates an ad-hoc report according to the given parameters
+func (srv *ReportService) Generate(user *models.User, params *models.ReportParams) (*models.Report, error) {
+	if params.Daily && params.To.Sub(params.From) > reportMaxDailyRange {
+		return nil, ErrReportRangeTooLarge
+	}
+	return srv.generate(user, params)
+}
+
+// SendCustomReport generates an ad-hoc report in the background and sends it to the user by e-mail
+func (srv *ReportService) SendCustomReport(user *models.User, params *models.ReportParams) error {
+	if user.Email == "" {
+		return errors.New("no e-mail address set")
+	}
+	if params.Daily && params.To.Sub(params.From) > reportMaxDailyRange {
+		return ErrReportRangeTooLarge
+	}
+
+	u := *user
+	return srv.queueWorkers.Dispatch(func() {
+		report, err := srv.generate(&u, params)
 		if err != nil {
-			config.Log().Error("failed to generate day summary (%v to %v) for report for '%s' - %v", from, to, user.ID, err)
-			break
+			config.Log().Error("failed to generate custom report for '%s' - %v", u.ID, err)
+			return
+		}
+		if err := srv.mailService.SendReport(&u, report); err != nil {
+			config.Log().Error("failed to send custom report for '%s', %v", u.ID, err)
+			srv.recordDelivery(&u, err)
+			return
+		}
+		logbuch.Info("sent custom report to user '%s'", u.ID)
+		srv.recordDelivery(&u, nil)
+	})
+}
+
+// recordDelivery remembers why sending the user's report failed, so it can be shown in their settings, or clears a previous failure once a report got through
+func (srv *ReportService) recordDelivery(user *models.User, sendErr error) {
+	key := fmt.Sprintf("%s_%s", config.KeyReportFailure, user.ID)
+	if sendErr == nil {
+		if srv.keyValueSrvc.MustGetString(key).Value != "" {
+			if err := srv.keyValueSrvc.DeleteString(key); err != nil {
+				config.Log().Error("failed to clear report failure for '%s' - %v", user.ID, err)
+			}
 		}
-		summary.FromTime = models.CustomTime(from)
-		summary.ToTime = models.CustomTime(to.Add(-1 * time.Second))
-		dailySummaries[i] = summary
+		return
+	}
+
+	value, _ := json.Marshal(&models.ReportFailure{Time: time.Now(), Reason: sendErr.Error()})
+	if err := srv.keyValueSrvc.PutString(&models.KeyStringValue{Key: key, Value: string(value)}); err != nil {
+		config.Log().Error("failed to record report failure for '%s' - %v", user.ID, err)
 	}
+}
+
+func (srv *ReportService) generate(user *models.User, params *models.ReportParams) (*models.Report, error) {
+	start, end := params.From, params.To
+
+	fullSummary, err := srv.summaryService.Aliased(start, end, user, srv.summaryService.Retrieve, params.Filters, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// generate per-day summaries
+	var dailySummaries []*models.Summary
+	if params.Daily {
+		dayIntervals := utils.SplitRangeByDays(start, end)
+		dailySummaries = make([]*models.Summary, len(dayIntervals))
 
-	report := &models.Report{
+		for i, interval := range dayIntervals {
+			from, to := datetime.BeginOfDay(interval[0]), interval[1]
+			summary, err := srv.summaryService.Aliased(from, to, user, srv.summaryService.Retrieve, params.Filters, false)
+			if err != nil {
+				config.Log().Error("failed to generate day summary (%v to %v) for report for '%s' - %v", from, to, user.ID, err)
+				break
+			}
+			summary.FromTime = models.CustomTime(from)
+			summary.ToTime = models.CustomTime(to.Add(-1 * time.Second))
+			dailySummaries[i] = summary
+		}
+	}
+
+	annotations, err := srv.annotationSrvc.GetByUserWithin(user, start, end)
+	if err != nil {
+		config.Log().Error("failed to fetch annotations for report for '%s' - %v", user.ID, err)
+	}
+
+	// working hours are only included for users who have set up a working schedule and for short enough reports
+	workingHours, err := srv.workingHoursSrvc.GetByUser(user, start, end, params.Filters)
+	if err != nil && !errors.Is(err, ErrWorkingHoursRangeTooLarge) {
+		config.Log().Error("failed to compute working hours for report for '%s' - %v", user.ID, err)
+	}
+
+	if len(params.Groupings) > 0 {
+		groupings := make(map[uint8]bool, len(params.Groupings))
+		for _, t := range params.Groupings {
+			groupings[t] = true
+		}
+		trimmed := *fullSummary // summary might be cached, so don't modify it in place
+		fullSummary = trimmed.KeepOnly(groupings)
+	}
+
+	return &models.Report{
 		From:           start,
 		To:             end,
 		User:           user,
 		Summary:        fullSummary,
 		DailySummaries: dailySummaries,
-	}
-
-	if err := srv.mailService.SendReport(user, report); err != nil {
-		config.Log().Error("failed to send report for '%s', %v", user.ID, err)
-		return err
-	}
-
-	logbuch.Info("sent report to user '%s'", user.ID)
-	return nil
+		Annotations:    annotations,
+		WorkingHours:   workingHours,
+	}, nil
 }
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+const (
+	reprocessingPageSize = 1000
+	// rules changes are only applied after a user has stopped editing them for a while, so that changing multiple rules in a row results in a single run
+	reprocessingDelay = 5 * time.Minute
+)
+
+var errReprocessingPending = errors.New("re-processing is already in progress")
+
+// ReprocessingService retroactively applies a user's current rules (language mappings, language detection, category and branch extraction and label rules) to their existing heartbeats and subsequently re-generates their summaries
+type ReprocessingService struct {
+	config          *config.Config
+	eventBus        *hub.Hub
+	userSrvc        IUserService
+	heartbeatSrvc   IHeartbeatService
+	labelRuleSrvc   ILabelRuleService
+	aggregationSrvc IAggregationService
+	queue           *artifex.Dispatcher
+	jobs            *userJobs[models.ReprocessingJob]
+	timers          map[string]*time.Timer
+	timersLock      sync.Mutex
+}
+
+func NewReprocessingService(userService IUserService, heartbeatService IHeartbeatService, labelRuleService ILabelRuleService, aggregationService IAggregationService) *ReprocessingService {
+	srv := &ReprocessingService{
+		config:          config.Get(),
+		eventBus:        config.EventBus(),
+		userSrvc:        userService,
+		heartbeatSrvc:   heartbeatService,
+		labelRuleSrvc:   labelRuleService,
+		aggregationSrvc: aggregationService,
+		queue:           config.GetQueue(config.QueueProjects), // shares queue with project rewrites to not have two jobs rewrite heartbeats concurrently
+		jobs:            newUserJobs((*models.ReprocessingJob).IsPending),
+		timers:          map[string]*time.Timer{},
+	}
+
+	onRuleChange := srv.eventBus.Subscribe(0, config.EventLanguageMappingUpdate, config.EventLabelRuleUpdate)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.scheduleDelayed(m.Fields[config.FieldUserId].(string))
+		}
+	}(&onRuleChange)
+
+	onUserDelete := srv.eventBus.Subscribe(0, config.EventUserDelete)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			userId := m.Fields[config.FieldPayload].(*models.User).ID
+			srv.jobs.Delete(userId)
+			srv.timersLock.Lock()
+			if timer, ok := srv.timers[userId]; ok {
+				timer.Stop()
+				delete(srv.timers, userId)
+			}
+			srv.timersLock.Unlock()
+		}
+	}(&onUserDelete)
+
+	return srv
+}
+
+// Reprocess schedules a background job to apply the user's current rules to all of their heartbeats
+func (srv *ReprocessingService) Reprocess(user *models.User) error {
+	job := &models.ReprocessingJob{
+		Status:    models.ReprocessingStatusPending,
+		CreatedAt: time.Now(),
+	}
+	if !srv.jobs.Start(user.ID, job) {
+		return errReprocessingPending
+	}
+
+	u := *user
+	if err := srv.queue.Dispatch(func() {
+		if err := srv.run(&u, job); err != nil {
+			config.Log().Error("failed to re-process heartbeats for user '%s' - %v", u.ID, err)
+			srv.jobs.Update(job, func(j *models.ReprocessingJob) {
+				j.Status = models.ReprocessingStatusFailed
+			})
+			return
+		}
+		logbuch.Info("re-processed heartbeats for user '%s' (%d with mapped language, %d updated, %d labels assigned)", u.ID, job.Rewritten, job.Updated, job.Labeled)
+		srv.jobs.Update(job, func(j *models.ReprocessingJob) {
+			j.Status = models.ReprocessingStatusDone
+			j.Step = ""
+			j.Progress = 1
+		})
+	}); err != nil {
+		srv.jobs.Update(job, func(j *models.ReprocessingJob) {
+			j.Status = models.ReprocessingStatusFailed
+		})
+		return err
+	}
+	return nil
+}
+
+// GetJob returns a snapshot of the user's current or latest re-processing job
+func (srv *ReprocessingService) GetJob(user *models.User) *models.ReprocessingJob {
+	if job, ok := srv.jobs.Get(user.ID); ok {
+		return job
+	}
+	return &models.ReprocessingJob{Status: models.ReprocessingStatusNone}
+}
+
+func (srv *ReprocessingService) run(user *models.User, job *models.ReprocessingJob) error {
+	// rules apply across the user's entire history, but summaries before their first and after their last heartbeat are left untouched
+	affected, err := srv.heartbeatSrvc.GetTimeRangeByUserAndProjects(user, nil)
+	if err != nil {
+		return err
+	}
+
+	srv.setStep(job, models.ReprocessingStepLanguageMappings, 0)
+	n, err := srv.heartbeatSrvc.ReprocessLanguagesByUser(user)
+	if err != nil {
+		return err
+	}
+	srv.jobs.Update(job, func(j *models.ReprocessingJob) {
+		j.Rewritten = n
+	})
+
+	srv.setStep(job, models.ReprocessingStepHeartbeats, 0.1)
+	if err := srv.reprocessHeartbeats(user, job); err != nil {
+		return err
+	}
+
+	srv.setStep(job, models.ReprocessingStepLabelRules, 0.6)
+	labeled, err := srv.labelRuleSrvc.ApplyToExisting(user)
+	if err != nil {
+		return err
+	}
+	srv.jobs.Update(job, func(j *models.ReprocessingJob) {
+		j.Labeled = labeled
+	})
+
+	if affected.IsEmpty() {
+		return nil
+	}
+	srv.setStep(job, models.ReprocessingStepSummaries, 0.7)
+	return srv.aggregationSrvc.RegenerateSummaries(user, affected.First.T(), affected.Last.T())
+}
+
+// reprocessHeartbeats re-runs all extractions of ingestion time page by page, which might not have been available or applicable back when the heartbeats were received or imported
+// this step accounts for half of the job's progress
+func (srv *ReprocessingService) reprocessHeartbeats(user *models.User, job *models.ReprocessingJob) error {
+	total, err := srv.heartbeatSrvc.CountByUser(user)
+	if err != nil {
+		return err
+	}
+
+	var processed int64
+	lastBranches := map[string]string{} // by project
+	for afterId := uint64(0); ; {
+		heartbeats, err := srv.heartbeatSrvc.GetPageByUser(user, afterId, reprocessingPageSize)
+		if err != nil {
+			return err
+		}
+		if len(heartbeats) == 0 {
+			return nil
+		}
+
+		updated := make([]*models.Heartbeat, 0)
+		for _, hb := range heartbeats {
+			if srv.reprocessHeartbeat(hb, lastBranches) {
+				updated = append(updated, hb)
+			}
+		}
+		if len(updated) > 0 {
+			if err := srv.heartbeatSrvc.UpdateBatch(updated); err != nil {
+				return err
+			}
+		}
+
+		processed += int64(len(heartbeats))
+		afterId = heartbeats[len(heartbeats)-1].ID
+		srv.jobs.Update(job, func(j *models.ReprocessingJob) {
+			j.Updated += int64(len(updated))
+			if total > 0 {
+				j.Progress = 0.1 + 0.5*float64(processed)/float64(total)
+			}
+		})
+
+		if len(heartbeats) < reprocessingPageSize {
+			return nil
+		}
+	}
+}
+
+// reprocessHeartbeat fills in missing languages, categories and branches and tells whether the heartbeat was changed
+// heartbeats must be passed in the order they were received for placeholder branches to be resolved from the same project's previous heartbeat
+func (srv *ReprocessingService) reprocessHeartbeat(hb *models.Heartbeat, lastBranches map[string]string) bool {
+	project, language, category, branch := hb.Project, hb.Language, hb.Category, hb.Branch
+
+	hb.Sanitize()
+	if srv.config.App.DetectLanguages {
+		hb.DetectLanguage()
+	}
+	if hb.Branch == models.LastBranchPlaceholder {
+		hb.Branch = lastBranches[hb.Project]
+	}
+	if hb.Branch != "" {
+		lastBranches[hb.Project] = hb.Branch
+	}
+
+	return hb.Project != project || hb.Language != language || hb.Category != category || hb.Branch != branch
+}
+
+// scheduleDelayed (re-)starts the countdown to re-process the user's heartbeats after their rules were changed
+func (srv *ReprocessingService) scheduleDelayed(userId string) {
+	srv.timersLock.Lock()
+	defer srv.timersLock.Unlock()
+
+	if timer, ok := srv.timers[userId]; ok {
+		timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(reprocessingDelay, func() {
+		srv.timersLock.Lock()
+		if srv.timers[userId] == timer {
+			delete(srv.timers, userId)
+		}
+		srv.timersLock.Unlock()
+
+		user, err := srv.userSrvc.GetUserById(userId)
+		if err != nil {
+			return
+		}
+		if err := srv.Reprocess(user); errors.Is(err, errReprocessingPending) {
+			srv.scheduleDelayed(userId) // rules were changed while a job was running, try again later
+		} else if err != nil {
+			config.Log().Error("failed to schedule re-processing for user '%s' - %v", userId, err)
+		}
+	})
+	srv.timers[userId] = timer
+}
+
+func (srv *ReprocessingService) setStep(job *models.ReprocessingJob, step string, progress float64) {
+	srv.jobs.Update(job, func(j *models.ReprocessingJob) {
+		j.Step = step
+		j.Progress = progress
+	})
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReprocessingServiceTestSuite struct {
+	suite.Suite
+	TestUser           *models.User
+	UserService        *mocks.UserServiceMock
+	HeartbeatService   *mocks.HeartbeatServiceMock
+	LabelRuleService   *mocks.LabelRuleServiceMock
+	AggregationService *mocks.AggregationServiceMock
+}
+
+func (suite *ReprocessingServiceTestSuite) SetupSuite() {
+	suite.TestUser = &models.User{ID: "testuser"}
+	cfg := config.Empty()
+	cfg.App.DetectLanguages = true
+	config.Set(cfg)
+}
+
+func (suite *ReprocessingServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.LabelRuleService = new(mocks.LabelRuleServiceMock)
+	suite.AggregationService = new(mocks.AggregationServiceMock)
+}
+
+func TestReprocessingServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ReprocessingServiceTestSuite))
+}
+
+func (suite *ReprocessingServiceTestSuite) TestReprocessingService_Run_RegeneratesUserTimeRange() {
+	sut := suite.newService()
+
+	first, last := models.CustomTime(time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)), models.CustomTime(time.Date(2023, 3, 5, 10, 0, 0, 0, time.UTC))
+
+	suite.HeartbeatService.On("GetTimeRangeByUserAndProjects", suite.TestUser, []string(nil)).Return(&models.HeartbeatTimeRange{First: &first, Last: &last}, nil)
+	suite.HeartbeatService.On("ReprocessLanguagesByUser", suite.TestUser).Return(12, nil)
+	suite.HeartbeatService.On("CountByUser", suite.TestUser).Return(int64(0), nil)
+	suite.HeartbeatService.On("GetPageByUser", suite.TestUser, uint64(0), reprocessingPageSize).Return([]*models.Heartbeat{}, nil)
+	suite.LabelRuleService.On("ApplyToExisting", suite.TestUser).Return(3, nil)
+	suite.AggregationService.On("RegenerateSummaries", suite.TestUser, first.T(), last.T()).Return(nil)
+
+	job := &models.ReprocessingJob{Status: models.ReprocessingStatusPending}
+	sut.jobs.Start(suite.TestUser.ID, job)
+
+	err := sut.run(suite.TestUser, job)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(12), job.Rewritten)
+	assert.Equal(suite.T(), 3, job.Labeled)
+	assert.Equal(suite.T(), models.ReprocessingStepSummaries, job.Step)
+	suite.AggregationService.AssertNumberOfCalls(suite.T(), "RegenerateSummaries", 1)
+}
+
+func (suite *ReprocessingServiceTestSuite) TestReprocessingService_Run_ReprocessingFails() {
+	sut := suite.newService()
+
+	suite.HeartbeatService.On("GetTimeRangeByUserAndProjects", suite.TestUser, []string(nil)).Return(&models.HeartbeatTimeRange{}, nil)
+	suite.HeartbeatService.On("ReprocessLanguagesByUser", suite.TestUser).Return(0, errors.New("failed"))
+
+	job := &models.ReprocessingJob{Status: models.ReprocessingStatusPending}
+	sut.jobs.Start(suite.TestUser.ID, job)
+
+	err := sut.run(suite.TestUser, job)
+
+	assert.Error(suite.T(), err)
+	suite.LabelRuleService.AssertNotCalled(suite.T(), "ApplyToExisting", mock.Anything)
+	suite.AggregationService.AssertNotCalled(suite.T(), "RegenerateSummaries", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ReprocessingServiceTestSuite) TestReprocessingService_ReprocessHeartbeats() {
+	sut := suite.newService()
+
+	heartbeats := []*models.Heartbeat{
+		{ID: 1, Entity: "/home/user/dev/main.go", Type: "file", Category: "coding", Project: "wakapi", Language: "Go", Branch: "master"},
+		{ID: 2, Entity: "/home/user/dev/main.rs", Type: "file", Project: "wakapi", Branch: models.LastBranchPlaceholder},
+		{ID: 3, Entity: "https://wakapi.dev", Type: "url", Category: "browsing", Project: "<<LAST_PROJECT>>"},
+		{ID: 4, Entity: "/home/user/dev/README.md", Type: "file", Category: "writing docs", Project: "other", Language: "Markdown", Branch: models.LastBranchPlaceholder},
+	}
+
+	suite.HeartbeatService.On("CountByUser", suite.TestUser).Return(int64(len(heartbeats)), nil)
+	suite.HeartbeatService.On("GetPageByUser", suite.TestUser, uint64(0), reprocessingPageSize).Return(heartbeats, nil)
+	suite.HeartbeatService.On("UpdateBatch", mock.Anything).Return(nil)
+
+	job := &models.ReprocessingJob{Status: models.ReprocessingStatusPending}
+	sut.jobs.Start(suite.TestUser.ID, job)
+
+	err := sut.reprocessHeartbeats(suite.TestUser, job)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(3), job.Updated)
+	assert.Equal(suite.T(), 0.6, job.Progress)
+
+	updated := suite.HeartbeatService.Calls[2].Arguments.Get(0).([]*models.Heartbeat)
+	assert.Len(suite.T(), updated, 3)
+	assert.Equal(suite.T(), "Rust", heartbeats[1].Language)
+	assert.Equal(suite.T(), "coding", heartbeats[1].Category)
+	assert.Equal(suite.T(), "master", heartbeats[1].Branch)
+	assert.Empty(suite.T(), heartbeats[2].Project)
+	assert.Empty(suite.T(), heartbeats[3].Branch) // no previous branch within the same project
+	assert.Equal(suite.T(), "writing docs", heartbeats[3].Category)
+}
+
+func (suite *ReprocessingServiceTestSuite) TestReprocessingService_GetJob_None() {
+	sut := suite.newService()
+
+	job := sut.GetJob(suite.TestUser)
+
+	assert.Equal(suite.T(), models.ReprocessingStatusNone, job.Status)
+}
+
+func (suite *ReprocessingServiceTestSuite) newService() *ReprocessingService {
+	return NewReprocessingService(suite.UserService, suite.HeartbeatService, suite.LabelRuleService, suite.AggregationService)
+}
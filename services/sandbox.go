@@ -0,0 +1,207 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
+	"github.com/duke-git/lancet/v2/maputil"
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+const (
+	maxSandboxHeartbeatsPerUser = 1000
+	SandboxResultsLimit         = 100
+)
+
+var ErrSandboxLimitExceeded = errors.New("too many sandbox heartbeats, please wait for them to be purged or delete them")
+
+// SandboxService processes heartbeats sent by plugin developers for testing purposes just like real ones, but instead of storing them, it reports on how they were processed
+// results are kept apart from regular heartbeats and purged nightly
+type SandboxService struct {
+	config              *config.Config
+	eventBus            *hub.Hub
+	repository          repositories.ISandboxRepository
+	languageMappingSrvc ILanguageMappingService
+	blockRuleSrvc       IBlockRuleService
+	crons               *cronJobs
+}
+
+func NewSandboxService(sandboxRepo repositories.ISandboxRepository, languageMappingService ILanguageMappingService, blockRuleService IBlockRuleService) *SandboxService {
+	srv := &SandboxService{
+		config:              config.Get(),
+		eventBus:            config.EventBus(),
+		repository:          sandboxRepo,
+		languageMappingSrvc: languageMappingService,
+		blockRuleSrvc:       blockRuleService,
+		crons:               newCronJobs("sandbox_purge", config.GetDefaultQueue(), nil),
+	}
+
+	onUserDelete := srv.eventBus.Subscribe(0, config.EventUserDelete)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			user := m.Fields[config.FieldPayload].(*models.User)
+			if err := srv.repository.DeleteByUser(user.ID); err != nil {
+				config.Log().Error("failed to delete sandbox heartbeats of user '%s' - %v", user.ID, err)
+			}
+		}
+	}(&onUserDelete)
+
+	return srv
+}
+
+func (srv *SandboxService) Schedule() {
+	if !srv.config.App.SandboxEnabled {
+		return
+	}
+
+	logbuch.Info("scheduling sandbox heartbeats purging")
+
+	if err := srv.crons.Schedule(srv.purge, srv.config.App.SandboxPurgeTime); err != nil {
+		config.Log().Error("failed to schedule sandbox heartbeats purging, %v", err)
+	}
+}
+
+// Process validates and augments the given heartbeats, which are expected to already be populated with the user and client information, and stores the results
+func (srv *SandboxService) Process(user *models.User, heartbeats []*models.Heartbeat) ([]*models.SandboxResult, error) {
+	count, err := srv.repository.CountByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if count+int64(len(heartbeats)) > maxSandboxHeartbeatsPerUser {
+		return nil, ErrSandboxLimitExceeded
+	}
+
+	mappings, err := srv.languageMappingSrvc.ResolveByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := srv.blockRuleSrvc.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := datastructure.NewSet[string]()
+	results := make([]*models.SandboxResult, len(heartbeats))
+	entries := make([]*models.SandboxHeartbeat, 0, len(heartbeats))
+
+	for i, hb := range heartbeats {
+		result := &models.SandboxResult{
+			Index:         i,
+			Status:        http.StatusCreated,
+			Errors:        []string{},
+			Warnings:      srv.warnings(hb),
+			UnknownFields: maputil.Keys(hb.Extras),
+			Heartbeat:     hb,
+			ReceivedAt:    models.CustomTime(time.Now()),
+		}
+		results[i] = result
+		sort.Strings(result.UnknownFields)
+
+		if hb.Time == models.CustomTime(time.Time{}) {
+			result.Errors = append(result.Errors, "missing or invalid time")
+		} else if !hb.Timely(srv.config.App.HeartbeatsMaxAge()) {
+			result.Errors = append(result.Errors, "time is too far in the past or in the future")
+		}
+		if len(result.Errors) > 0 {
+			result.Status = http.StatusBadRequest
+		}
+
+		for _, rule := range rules {
+			if rule.Matches(hb) {
+				result.Blocked = true
+				break
+			}
+		}
+
+		hb.Sanitize()
+		hb.MapLanguage(mappings)
+		if srv.config.App.DetectLanguages {
+			hb.DetectLanguage()
+		}
+		hb.Hashed()
+		result.Hash = hb.Hash
+		result.LanguageOriginal = hb.LanguageOriginal
+
+		if hashes.Contain(hb.Hash) {
+			result.Duplicate = true
+		}
+		hashes.Add(hb.Hash)
+
+		result.Accepted = len(result.Errors) == 0 && !result.Blocked && !result.Duplicate
+
+		entry, err := models.NewSandboxHeartbeat(user.ID, result)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := srv.repository.InsertBatch(entries); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetByUser returns the results of the user's most recent sandbox heartbeats, newest first
+func (srv *SandboxService) GetByUser(user *models.User, limit int) ([]*models.SandboxResult, error) {
+	heartbeats, err := srv.repository.GetByUser(user.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*models.SandboxResult, 0, len(heartbeats))
+	for _, h := range heartbeats {
+		result, err := h.Unmarshal()
+		if err != nil {
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (srv *SandboxService) DeleteByUser(user *models.User) error {
+	return srv.repository.DeleteByUser(user.ID)
+}
+
+func (srv *SandboxService) purge() {
+	logbuch.Info("purging sandbox heartbeats")
+	if err := srv.repository.DeleteBefore(time.Now()); err != nil {
+		config.Log().Error("failed to purge sandbox heartbeats, %v", err)
+	}
+}
+
+// warnings lists issues, which don't prevent a heartbeat from being accepted, but likely indicate a bug in the client
+func (srv *SandboxService) warnings(hb *models.Heartbeat) []string {
+	warnings := make([]string, 0)
+	if hb.Entity == "" {
+		warnings = append(warnings, "missing entity")
+	}
+	if hb.Type == "" {
+		warnings = append(warnings, "missing type, should be one of 'file', 'domain', 'url' or 'app'")
+	}
+	if hb.Category == "" {
+		warnings = append(warnings, "missing category, e.g. 'coding'")
+	}
+	if hb.Project == "" {
+		warnings = append(warnings, "missing project, time will be counted as 'unknown'")
+	}
+	if hb.Language == "" && hb.Type == "file" {
+		warnings = append(warnings, "missing language, time will be counted as 'unknown'")
+	}
+	if hb.Editor == "" || hb.OperatingSystem == "" {
+		warnings = append(warnings, "editor or operating system could not be determined from user agent")
+	}
+	if hb.Machine == "" {
+		warnings = append(warnings, "missing machine name, e.g. as X-Machine-Name header")
+	}
+	return warnings
+}
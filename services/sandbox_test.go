@@ -0,0 +1,109 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type SandboxServiceTestSuite struct {
+	suite.Suite
+	TestUser               *models.User
+	SandboxRepository      *mocks.SandboxRepositoryMock
+	BlockRuleRepository    *mocks.BlockRuleRepositoryMock
+	LanguageMappingService *mocks.LanguageMappingServiceMock
+}
+
+func (suite *SandboxServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+	config.Get().App.HeartbeatMaxAge = "4320h"
+	suite.TestUser = &models.User{ID: "user1"}
+}
+
+func (suite *SandboxServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.SandboxRepository = new(mocks.SandboxRepositoryMock)
+	suite.BlockRuleRepository = new(mocks.BlockRuleRepositoryMock)
+	suite.LanguageMappingService = new(mocks.LanguageMappingServiceMock)
+}
+
+func TestSandboxServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(SandboxServiceTestSuite))
+}
+
+func (suite *SandboxServiceTestSuite) TestSandboxService_Process() {
+	suite.SandboxRepository.On("CountByUser", "user1").Return(int64(0), nil)
+	suite.SandboxRepository.On("InsertBatch", mock.Anything).Return(nil)
+	suite.LanguageMappingService.On("ResolveByUser", "user1").Return(map[string]string{"tpl": "Go"}, nil)
+	suite.BlockRuleRepository.On("GetAll").Return([]*models.BlockRule{{ID: 1, Field: models.BlockRuleFieldProject, Pattern: "^secret$"}}, nil)
+
+	now := models.CustomTime(time.Now())
+	heartbeats := []*models.Heartbeat{
+		{Entity: "main.tpl", Type: "file", Category: "coding", Project: "wakapi", Language: "Text", Time: now, Editor: "vscode", OperatingSystem: "linux", Machine: "devbox"},
+		{Entity: "main.tpl", Type: "file", Category: "coding", Project: "wakapi", Language: "Text", Time: now, Editor: "vscode", OperatingSystem: "linux", Machine: "devbox"},
+		{Entity: "main.go", Type: "file", Project: "secret", Language: "Go", Time: now},
+		{Entity: "main.go", Type: "file", Project: "wakapi", Language: "Go"},
+		{Entity: "main.go", Type: "file", Project: "wakapi", Language: "Go", Time: models.CustomTime(time.Now().Add(-24 * 365 * time.Hour))},
+	}
+	for _, h := range heartbeats {
+		h.User = suite.TestUser
+		h.UserID = suite.TestUser.ID
+	}
+
+	sut := suite.newService()
+
+	results, err := sut.Process(suite.TestUser, heartbeats)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), results, 5)
+
+	assert.True(suite.T(), results[0].Accepted)
+	assert.Equal(suite.T(), 201, results[0].Status)
+	assert.Empty(suite.T(), results[0].Warnings)
+	assert.Equal(suite.T(), "Go", results[0].Heartbeat.Language)
+	assert.Equal(suite.T(), "Text", results[0].LanguageOriginal)
+	assert.Equal(suite.T(), "Vscode", results[0].Heartbeat.Editor)
+	assert.NotEmpty(suite.T(), results[0].Hash)
+
+	assert.False(suite.T(), results[1].Accepted)
+	assert.True(suite.T(), results[1].Duplicate)
+
+	assert.False(suite.T(), results[2].Accepted)
+	assert.True(suite.T(), results[2].Blocked)
+	assert.Contains(suite.T(), results[2].Warnings, "missing category, e.g. 'coding'")
+
+	assert.False(suite.T(), results[3].Accepted)
+	assert.Equal(suite.T(), 400, results[3].Status)
+	assert.Equal(suite.T(), []string{"missing or invalid time"}, results[3].Errors)
+
+	assert.Equal(suite.T(), 400, results[4].Status)
+
+	suite.SandboxRepository.AssertCalled(suite.T(), "InsertBatch", mock.MatchedBy(func(entries []*models.SandboxHeartbeat) bool {
+		result, err := entries[0].Unmarshal()
+		return len(entries) == 5 && entries[0].UserID == "user1" && err == nil && result.Heartbeat.Project == "wakapi"
+	}))
+}
+
+func (suite *SandboxServiceTestSuite) TestSandboxService_Process_LimitExceeded() {
+	suite.SandboxRepository.On("CountByUser", "user1").Return(int64(maxSandboxHeartbeatsPerUser), nil)
+
+	sut := suite.newService()
+
+	_, err := sut.Process(suite.TestUser, []*models.Heartbeat{{Entity: "main.go", Time: models.CustomTime(time.Now())}})
+	assert.ErrorIs(suite.T(), err, ErrSandboxLimitExceeded)
+	suite.SandboxRepository.AssertNotCalled(suite.T(), "InsertBatch", mock.Anything)
+}
+
+func (suite *SandboxServiceTestSuite) newService() *SandboxService {
+	// constructed directly to not subscribe to the event bus
+	return &SandboxService{
+		config:              config.Get(),
+		repository:          suite.SandboxRepository,
+		languageMappingSrvc: suite.LanguageMappingService,
+		blockRuleSrvc:       NewBlockRuleService(suite.BlockRuleRepository),
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+type SavedFilterService struct {
+	config     *config.Config
+	cache      *cache.Cache
+	repository repositories.ISavedFilterRepository
+}
+
+func NewSavedFilterService(savedFilterRepository repositories.ISavedFilterRepository) *SavedFilterService {
+	return &SavedFilterService{
+		config:     config.Get(),
+		cache:      cache.New(24*time.Hour, 24*time.Hour),
+		repository: savedFilterRepository,
+	}
+}
+
+func (srv *SavedFilterService) GetByUser(user *models.User) ([]*models.SavedFilter, error) {
+	if filters, found := srv.cache.Get(user.ID); found {
+		return filters.([]*models.SavedFilter), nil
+	}
+
+	filters, err := srv.repository.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Set(user.ID, filters, cache.DefaultExpiration)
+	return filters, nil
+}
+
+// GetByUserAndName is served from the user's cached list of filters, as it is called for every request referring to a saved filter
+func (srv *SavedFilterService) GetByUserAndName(user *models.User, name string) (*models.SavedFilter, error) {
+	filters, err := srv.GetByUser(user)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range filters {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+	return nil, errors.New("saved filter not found")
+}
+
+func (srv *SavedFilterService) Create(filter *models.SavedFilter) (*models.SavedFilter, error) {
+	result, err := srv.repository.Insert(filter)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Delete(filter.UserID)
+	return result, nil
+}
+
+func (srv *SavedFilterService) Delete(filter *models.SavedFilter) error {
+	if filter.UserID == "" {
+		return errors.New("no user id specified")
+	}
+	srv.cache.Delete(filter.UserID)
+	return srv.repository.Delete(filter.ID)
+}
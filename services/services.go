@@ -2,15 +2,27 @@ package services
 
 import (
 	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
+	"github.com/muety/artifex/v2"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/models/types"
 	"github.com/muety/wakapi/utils"
+	"io"
+	"net/url"
 	"time"
 )
 
 type IAggregationService interface {
 	Schedule()
 	AggregateSummaries(set datastructure.Set[string]) error
+	RegenerateSummaries(*models.User, time.Time, time.Time) error
+	ScheduleRegeneration(*models.User, time.Time, time.Time) error
+	ScheduleBackfill(*models.AggregationBackfillRequest) (*models.AggregationBackfillJob, error)
+	Backfill(*models.AggregationBackfillRequest) (*models.AggregationBackfillJob, error)
+	GetBackfillJob() *models.AggregationBackfillJob
+}
+
+type IPresenceService interface {
+	GetByUser(*models.User) (*models.Presence, error)
 }
 
 type IMiscService interface {
@@ -33,20 +45,132 @@ type IAliasService interface {
 type IHeartbeatService interface {
 	Insert(*models.Heartbeat) error
 	InsertBatch([]*models.Heartbeat) error
+	FilterBlocked([]*models.Heartbeat) []*models.Heartbeat
+	GetExistingHashes([]string) ([]string, error)
 	Count(bool) (int64, error)
 	CountByUser(*models.User) (int64, error)
 	CountByUsers([]*models.User) ([]*models.CountByUser, error)
+	CountByUsersBefore(time.Time) ([]*models.CountByUser, error)
 	GetAllWithin(time.Time, time.Time, *models.User) ([]*models.Heartbeat, error)
 	GetAllWithinByFilters(time.Time, time.Time, *models.User, *models.Filters) ([]*models.Heartbeat, error)
+	GetPageByUser(*models.User, uint64, int) ([]*models.Heartbeat, error)
 	GetFirstByUsers() ([]*models.TimeByUser, error)
 	GetLatestByUser(*models.User) (*models.Heartbeat, error)
 	GetLatestByOriginAndUser(string, *models.User) (*models.Heartbeat, error)
 	GetLatestByFilters(*models.User, *models.Filters) (*models.Heartbeat, error)
+	GetByUserAndId(*models.User, uint64) (*models.Heartbeat, error)
+	GetTimeRangeByUserAndProjects(*models.User, []string) (*models.HeartbeatTimeRange, error)
 	GetEntitySetByUser(uint8, string) ([]string, error)
+	GetDistinctByUser(*models.User, []string, int, int) ([]*models.Heartbeat, error)
 	DeleteBefore(time.Time) error
 	DeleteByUser(*models.User) error
 	DeleteByUserBefore(*models.User, time.Time) error
+	AnonymizeByUserBefore(*models.User, time.Time) (int64, error)
+	DropChunksBefore(time.Time) error
+	CreatePartitions(time.Time, time.Time) error
+	DropPartitionsBefore(time.Time) error
+	DeleteByUserAndId(*models.User, uint64) error
+	DeleteByUserWithinByFilters(*models.User, time.Time, time.Time, *models.Filters, string) (int64, error)
+	CountByUserWithinByFilters(*models.User, time.Time, time.Time, *models.Filters, string) (int64, error)
+	Update(*models.Heartbeat) error
+	UpdateBatch([]*models.Heartbeat) error
+	RenameProjectByUser(*models.User, string, string) (int64, error)
+	RenameProjectByUserWithin(*models.User, time.Time, time.Time, string, string) (int64, error)
+	ReprocessLanguagesByUser(*models.User) (int64, error)
 	GetUserProjectStats(*models.User, time.Time, time.Time, *utils.PageParams, bool) ([]*models.ProjectStats, error)
+	GetUserAgentsByUser(*models.User) ([]*models.UserAgentStats, error)
+	GetPluginVersionStats(time.Time) ([]*models.PluginVersionStats, error)
+}
+
+type IAbuseReportService interface {
+	GetById(uint) (*models.AbuseReport, error)
+	GetOpen() ([]*models.AbuseReport, error)
+	Create(*models.AbuseReport) (*models.AbuseReport, error)
+	Resolve(*models.AbuseReport, *models.AbuseReportResolution) (*models.AbuseReport, error)
+}
+
+type IDataCorrectionService interface {
+	GetById(uint) (*models.DataCorrection, error)
+	GetByUser(*models.User) ([]*models.DataCorrection, error)
+	GetPending() ([]*models.DataCorrection, error)
+	Create(*models.DataCorrection) (*models.DataCorrection, error)
+	Review(*models.DataCorrection, *models.User, *models.DataCorrectionReview) (*models.DataCorrection, error)
+}
+
+type IProjectService interface {
+	Rename(*models.User, string, string) error
+	Merge(*models.User, []string, string) error
+	GetJob(*models.User) *models.ProjectRewriteJob
+}
+
+type IReprocessingService interface {
+	Reprocess(*models.User) error
+	GetJob(*models.User) *models.ReprocessingJob
+}
+
+type IMirrorService interface {
+	Configure(*models.User, string) (*models.User, error)
+	Flush()
+}
+
+type IPairingService interface {
+	Create(string, string) (*models.DevicePairing, error)
+	GetByUserCode(string) (*models.DevicePairing, error)
+	Confirm(string, *models.User) error
+	Poll(string) (*models.DevicePairing, error)
+}
+
+type IAccessTokenService interface {
+	Create(*models.User, string, string) (string, *models.AccessToken, error)
+	GetByToken(string) (*models.AccessToken, error)
+	GetByUser(*models.User) ([]*models.AccessToken, error)
+	Delete(*models.User, uint) error
+}
+
+type IExportService interface {
+	Schedule()
+	Request(*models.User) error
+	Export(*models.User, io.Writer) error
+	Get(*models.User) *models.UserExport
+	Delete(*models.User)
+	GetTarget(*models.User) (*models.ExportTarget, error)
+	SetTarget(*models.User, *models.ExportTarget) (*models.ExportTarget, error)
+	DeleteTarget(*models.User) error
+	GetDumpUrl(*models.ExportTarget) (string, error)
+}
+
+type IPersistentQueueService interface {
+	Register(string, *artifex.Dispatcher, func([]byte) error)
+	Dispatch(string, interface{}) error
+	Resume()
+	Drain(time.Duration) bool
+}
+
+type IQuotaService interface {
+	Check(*models.User, []*models.Heartbeat) ([]error, []string, error)
+	Record(*models.User, int)
+}
+
+type IDemoService interface {
+	Schedule()
+	Seed() error
+}
+
+type IImportService interface {
+	ImportWakatime(*models.User, bool) error
+	RunWakatimeImport(*models.User, bool) (int, error)
+}
+
+type IJobLockService interface {
+	TryLock(string, time.Duration) bool
+	Exclusive(string, time.Duration, func()) func()
+}
+
+type ISandboxService interface {
+	Schedule()
+	Process(*models.User, []*models.Heartbeat) ([]*models.SandboxResult, error)
+	GetByUser(*models.User, int) ([]*models.SandboxResult, error)
+	DeleteByUser(*models.User) error
 }
 
 type IDiagnosticsService interface {
@@ -65,10 +189,77 @@ type ILanguageMappingService interface {
 	GetById(uint) (*models.LanguageMapping, error)
 	GetByUser(string) ([]*models.LanguageMapping, error)
 	ResolveByUser(string) (map[string]string, error)
+	GetDefaults() map[string]string
 	Create(*models.LanguageMapping) (*models.LanguageMapping, error)
 	Delete(mapping *models.LanguageMapping) error
 }
 
+type IAnnotationService interface {
+	GetById(uint) (*models.Annotation, error)
+	GetByUserWithin(*models.User, time.Time, time.Time) ([]*models.Annotation, error)
+	Create(*models.Annotation) (*models.Annotation, error)
+	Delete(*models.Annotation) error
+}
+
+type IMilestoneService interface {
+	GetById(uint) (*models.Milestone, error)
+	GetByUserWithin(*models.User, time.Time, time.Time) ([]*models.Milestone, error)
+	Create(*models.Milestone) (*models.Milestone, error)
+	Delete(*models.Milestone) error
+}
+
+type ISavedFilterService interface {
+	GetByUser(*models.User) ([]*models.SavedFilter, error)
+	GetByUserAndName(*models.User, string) (*models.SavedFilter, error)
+	Create(*models.SavedFilter) (*models.SavedFilter, error)
+	Delete(*models.SavedFilter) error
+}
+
+type IProjectRemoteService interface {
+	GetByUser(string) ([]*models.ProjectRemote, error)
+	GetNamespacesByUser(string) (map[string]string, error)
+}
+
+type IMachineService interface {
+	GetByUser(string) ([]*models.Machine, error)
+	DeleteByUserBefore(string, time.Time) error
+	AnonymizeByUserBefore(string, time.Time) (int64, error)
+}
+
+type ILabelRuleService interface {
+	GetById(uint) (*models.LabelRule, error)
+	GetByUser(string) ([]*models.LabelRule, error)
+	Create(*models.LabelRule) (*models.LabelRule, error)
+	Delete(*models.LabelRule) error
+	Backfill(*models.User) error
+	ApplyToExisting(*models.User) (int, error)
+	FlushUserCache(string)
+}
+
+type IBlockRuleService interface {
+	GetAll() ([]*models.BlockRule, error)
+	GetById(uint) (*models.BlockRule, error)
+	Create(*models.BlockRule) (*models.BlockRule, error)
+	Delete(*models.BlockRule) error
+	Filter([]*models.Heartbeat) []*models.Heartbeat
+	Schedule()
+}
+
+type IInvitationService interface {
+	GetAll() ([]*models.Invitation, error)
+	GetById(uint) (*models.Invitation, error)
+	GetByCode(string) (*models.Invitation, error)
+	Create(*models.Invitation) (*models.Invitation, error)
+	Delete(*models.Invitation) error
+	Redeem(string) (*models.Invitation, error)
+	Release(*models.Invitation) error
+}
+
+type IMappingConfigService interface {
+	Export(*models.User) (*models.MappingConfig, error)
+	Import(*models.User, *models.MappingConfig, bool) (*models.MappingConfigImportResult, error)
+}
+
 type IProjectLabelService interface {
 	GetById(uint) (*models.ProjectLabel, error)
 	GetByUser(string) ([]*models.ProjectLabel, error)
@@ -76,6 +267,13 @@ type IProjectLabelService interface {
 	GetByUserGroupedInverted(string) (map[string][]*models.ProjectLabel, error)
 	Create(*models.ProjectLabel) (*models.ProjectLabel, error)
 	Delete(*models.ProjectLabel) error
+	FlushUserCache(string)
+}
+
+type ICaptchaService interface {
+	Enabled() bool
+	IssueChallenge() (string, error)
+	Verify(url.Values, string) error
 }
 
 type IMailService interface {
@@ -83,7 +281,76 @@ type IMailService interface {
 	SendWakatimeFailureNotification(*models.User, int) error
 	SendImportNotification(*models.User, time.Duration, int) error
 	SendReport(*models.User, *models.Report) error
+	RenderReport(*models.Report) (string, error)
 	SendSubscriptionNotification(*models.User, bool) error
+	SendAccountDeletionConfirmation(*models.User, string) error
+	SendEmailVerification(*models.User, string) error
+	SendUsernameChange(*models.User, string) error
+	SendNotificationDigest(*models.User, []*models.Notification) error
+	SendOutdatedClientsNotification(*models.User, []*models.OutdatedClient) error
+	SendInactivityAlert(*models.User, time.Time, int) error
+	SendAchievementsUnlocked(*models.User, []*models.Achievement) error
+	SendLoginLockout(*models.User, *models.LoginLockout) error
+	SendTestMail(string) error
+}
+
+type ILoginThrottleService interface {
+	Check(string, string) time.Duration
+	RecordFailure(*models.User, string, string)
+	RecordSuccess(string)
+	GetLockouts() ([]*models.LoginLockout, error)
+	Unlock(string) error
+}
+
+type IClientVersionService interface {
+	Schedule()
+	GetLatestVersions() map[string]string
+	GetOutdatedByUser(*models.User) ([]*models.OutdatedClient, error)
+}
+
+type IClientService interface {
+	GetByUser(string) ([]*models.Client, error)
+	GetByUserAndId(string, uint) (*models.Client, error)
+	GetClientsByProject(string) (map[string]string, error)
+	Create(*models.Client) (*models.Client, error)
+	Update(*models.Client) (*models.Client, error)
+	Delete(*models.Client) error
+}
+
+type IProjectGroupService interface {
+	GetByUser(string) ([]*models.ProjectGroup, error)
+	GetByUserAndId(string, uint) (*models.ProjectGroup, error)
+	GetGroupsByProject(string) (map[string]string, error)
+	Create(*models.ProjectGroup) (*models.ProjectGroup, error)
+	Update(*models.ProjectGroup) (*models.ProjectGroup, error)
+	Delete(*models.ProjectGroup) error
+}
+
+type IBillingService interface {
+	GetProjectsByUser(string) ([]*models.BillableProject, error)
+	SetProject(*models.BillableProject) (*models.BillableProject, error)
+	DeleteProject(string, string) error
+	GetReport(*models.User, time.Time, time.Time) (*models.BillingReport, error)
+}
+
+type IWorkingHoursService interface {
+	GetByUser(*models.User, time.Time, time.Time, *models.Filters) (*models.WorkingHours, error)
+}
+
+type IAchievementService interface {
+	Schedule()
+	GetByUser(*models.User) ([]*models.Achievement, error)
+	Evaluate(*models.User) ([]*models.Achievement, error)
+}
+
+type IInactivityAlertService interface {
+	Schedule()
+	CheckAll()
+}
+
+type INotificationService interface {
+	Schedule()
+	Notify(*models.User, string, string, func() error) error
 }
 
 type IDurationService interface {
@@ -94,10 +361,18 @@ type ISummaryService interface {
 	Aliased(time.Time, time.Time, *models.User, types.SummaryRetriever, *models.Filters, bool) (*models.Summary, error)
 	Retrieve(time.Time, time.Time, *models.User, *models.Filters) (*models.Summary, error)
 	Summarize(time.Time, time.Time, *models.User, *models.Filters) (*models.Summary, error)
+	GetByUserWithin(*models.User, time.Time, time.Time) ([]*models.Summary, error)
 	GetLatestByUser() ([]*models.TimeByUser, error)
+	CountByUsersBefore(time.Time) ([]*models.CountByUser, error)
 	DeleteByUser(string) error
 	DeleteByUserBefore(string, time.Time) error
+	DeleteByUserWithin(string, time.Time, time.Time) error
+	AnonymizeByUserBefore(string, time.Time) error
+	DeletePartialByUser(string) error
+	DeletePartialBefore(time.Time) error
 	Insert(*models.Summary) error
+	ReplacePartial(*models.Summary) error
+	DataVersion(string) time.Time
 }
 
 type IActivityService interface {
@@ -107,11 +382,40 @@ type IActivityService interface {
 type IReportService interface {
 	Schedule()
 	SendReport(*models.User, time.Duration) error
+	Generate(*models.User, *models.ReportParams) (*models.Report, error)
+	SendCustomReport(*models.User, *models.ReportParams) error
+}
+
+type IInfluxExportService interface {
+	Schedule()
+	Export() error
 }
 
 type IHousekeepingService interface {
 	Schedule()
 	CleanUserDataBefore(*models.User, time.Time) error
+	GetRetentionImpact() (*models.RetentionImpact, error)
+	ConfirmRetention(int) error
+}
+
+type IArchiveService interface {
+	Schedule()
+	ArchiveUserBefore(*models.User, time.Time, time.Time) (int, error)
+	Restore(*models.User, time.Time, time.Time) (int, error)
+	DeleteByUser(*models.User) error
+}
+
+type ITrendsService interface {
+	GetLanguageTrends(*models.IntervalKey) (*models.LanguageTrends, error)
+	GetTopLanguages(*models.IntervalKey, int) (*models.LanguageTrendPeriod, error)
+}
+
+type IUserTrendsService interface {
+	GetByUser(*models.User, int) (*models.UserTrends, error)
+}
+
+type IPublicStatsService interface {
+	Get() (*models.PublicStats, error)
 }
 
 type ILeaderboardService interface {
@@ -132,6 +436,7 @@ type IUserService interface {
 	GetUserByKey(string) (*models.User, error)
 	GetUserByEmail(string) (*models.User, error)
 	GetUserByResetToken(string) (*models.User, error)
+	GetUserByDeletionToken(string) (*models.User, error)
 	GetUserByStripeCustomerId(string) (*models.User, error)
 	GetAll() ([]*models.User, error)
 	GetMany([]string) ([]*models.User, error)
@@ -139,13 +444,23 @@ type IUserService interface {
 	GetAllByReports(bool) ([]*models.User, error)
 	GetAllByLeaderboard(bool) ([]*models.User, error)
 	GetActive(bool) ([]*models.User, error)
+	GetInactive(int) ([]*models.User, error)
+	GetScheduledForDeletion() ([]*models.User, error)
 	Count() (int64, error)
+	CountActiveByClass(models.ActivityClass) (int64, error)
 	CreateOrGet(*models.Signup, bool) (*models.User, bool, error)
 	Update(*models.User) (*models.User, error)
 	Delete(*models.User) error
 	ResetApiKey(*models.User) (*models.User, error)
 	SetWakatimeApiCredentials(*models.User, string, string) (*models.User, error)
+	SetMirrorUrl(*models.User, string, string) (*models.User, error)
 	GenerateResetToken(*models.User) (*models.User, error)
+	GenerateDeletionToken(*models.User) (*models.User, error)
+	GenerateVerificationToken(*models.User) (*models.User, error)
+	VerifyEmail(string) (*models.User, error)
+	ScheduleDeletion(*models.User) (*models.User, error)
+	CancelDeletion(*models.User) (*models.User, error)
+	ChangeUserId(*models.User, string) (*models.User, error)
 	FlushCache()
 	FlushUserCache(string)
 }
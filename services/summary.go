@@ -9,7 +9,7 @@ import (
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/models/types"
 	"github.com/muety/wakapi/repositories"
-	"github.com/patrickmn/go-cache"
+	"github.com/muety/wakapi/utils"
 	"sort"
 	"strings"
 	"time"
@@ -17,26 +17,32 @@ import (
 
 type SummaryService struct {
 	config              *config.Config
-	cache               *cache.Cache
+	cache               utils.Cache
 	eventBus            *hub.Hub
 	repository          repositories.ISummaryRepository
 	durationService     IDurationService
 	aliasService        IAliasService
 	projectLabelService IProjectLabelService
+	projectRemoteSrvc   IProjectRemoteService
+	clientSrvc          IClientService
+	projectGroupSrvc    IProjectGroupService
 }
 
-func NewSummaryService(summaryRepo repositories.ISummaryRepository, durationService IDurationService, aliasService IAliasService, projectLabelService IProjectLabelService) *SummaryService {
+func NewSummaryService(summaryRepo repositories.ISummaryRepository, durationService IDurationService, aliasService IAliasService, projectLabelService IProjectLabelService, projectRemoteService IProjectRemoteService, clientService IClientService, projectGroupService IProjectGroupService) *SummaryService {
 	srv := &SummaryService{
 		config:              config.Get(),
-		cache:               cache.New(24*time.Hour, 24*time.Hour),
+		cache:               config.NewCache("summaries", 24*time.Hour, 24*time.Hour),
 		eventBus:            config.EventBus(),
 		repository:          summaryRepo,
 		durationService:     durationService,
 		aliasService:        aliasService,
 		projectLabelService: projectLabelService,
+		projectRemoteSrvc:   projectRemoteService,
+		clientSrvc:          clientService,
+		projectGroupSrvc:    projectGroupService,
 	}
 
-	sub1 := srv.eventBus.Subscribe(0, config.TopicProjectLabel)
+	sub1 := srv.eventBus.Subscribe(0, config.TopicProjectLabel, config.TopicProjectRemote, config.TopicClient, config.TopicProjectGroup, config.TopicAlias)
 	go func(sub *hub.Subscription) {
 		for m := range sub.Receiver {
 			srv.invalidateUserCache(m.Fields[config.FieldUserId].(string))
@@ -51,10 +57,11 @@ func NewSummaryService(summaryRepo repositories.ISummaryRepository, durationServ
 // Aliased retrieves or computes a new summary based on the given SummaryRetriever and augments it with entity aliases and project labels
 func (srv *SummaryService) Aliased(from, to time.Time, user *models.User, f types.SummaryRetriever, filters *models.Filters, skipCache bool) (*models.Summary, error) {
 	// Check cache (or skip for sub second-level date precision)
-	cacheKey := srv.getHash(from.String(), to.String(), user.ID, filters.Hash(), "--aliased")
+	cacheKey := srv.getHash(from.String(), to.String(), user.ID, filters.Hash(), user.HeartbeatsTimeout().String(), "--aliased")
 	if to.Truncate(time.Second).Equal(to) && from.Truncate(time.Second).Equal(from) {
-		if cacheResult, ok := srv.cache.Get(cacheKey); ok && !skipCache {
-			return cacheResult.(*models.Summary), nil
+		var cacheResult *models.Summary
+		if !skipCache && srv.cache.Get(cacheKey, &cacheResult) {
+			return cacheResult, nil
 		}
 	}
 
@@ -62,11 +69,17 @@ func (srv *SummaryService) Aliased(from, to time.Time, user *models.User, f type
 	resolveAliases := srv.getAliasResolver(user)
 	resolveAliasesReverse := srv.getAliasReverseResolver(user)
 	resolveProjectLabelsReverse := srv.getProjectLabelsReverseResolver(user)
+	resolveProjectNamespacesReverse := srv.getProjectNamespacesReverseResolver(user)
+	resolveClientsReverse := srv.getClientsReverseResolver(user)
+	resolveProjectGroupsReverse := srv.getProjectGroupsReverseResolver(user)
 
 	// Post-process filters
 	if filters != nil {
+		filters = filters.WithProjectGroups(resolveProjectGroupsReverse)
 		filters = filters.WithAliases(resolveAliasesReverse)
 		filters = filters.WithProjectLabels(resolveProjectLabelsReverse)
+		filters = filters.WithProjectNamespaces(resolveProjectNamespacesReverse)
+		filters = filters.WithClients(resolveClientsReverse)
 	}
 
 	// Initialize alias resolver service
@@ -83,8 +96,13 @@ func (srv *SummaryService) Aliased(from, to time.Time, user *models.User, f type
 	// Post-process summary and cache it
 	summary := s.WithResolvedAliases(resolveAliases)
 	summary = srv.withProjectLabels(summary)
-	summary.FillBy(models.SummaryProject, models.SummaryLabel) // first fill up labels from projects
-	summary.FillMissing()                                      // then, full up types which are entirely missing
+	summary = srv.withProjectNamespaces(summary)
+	summary = srv.withClients(summary)
+	summary = srv.withProjectGroups(summary)                       // last, as labels, namespaces and clients refer to the actual projects
+	summary.FillBy(models.SummaryProject, models.SummaryLabel)     // first fill up labels from projects
+	summary.FillBy(models.SummaryProject, models.SummaryNamespace) // same for namespaces, projects without known remote are "unknown"
+	summary.FillBy(models.SummaryProject, models.SummaryClient)    // and for clients, projects not assigned to any client are "unknown"
+	summary.FillMissing()                                          // then, full up types which are entirely missing
 
 	if withDetails := filters != nil && filters.IsProjectDetails(); !withDetails {
 		summary.Branches = nil
@@ -207,6 +225,10 @@ func (srv *SummaryService) Summarize(from, to time.Time, user *models.User, filt
 
 // CRUD methods
 
+func (srv *SummaryService) GetByUserWithin(user *models.User, from, to time.Time) ([]*models.Summary, error) {
+	return srv.repository.GetByUserWithin(user, from, to)
+}
+
 func (srv *SummaryService) GetLatestByUser() ([]*models.TimeByUser, error) {
 	return srv.repository.GetLastByUser()
 }
@@ -216,14 +238,66 @@ func (srv *SummaryService) DeleteByUser(userId string) error {
 	return srv.repository.DeleteByUser(userId)
 }
 
+func (srv *SummaryService) CountByUsersBefore(t time.Time) ([]*models.CountByUser, error) {
+	return srv.repository.CountByUsersBefore(t)
+}
+
 func (srv *SummaryService) DeleteByUserBefore(userId string, t time.Time) error {
 	srv.invalidateUserCache(userId)
 	return srv.repository.DeleteByUserBefore(userId, t)
 }
 
+// AnonymizeByUserBefore drops identifying items (machines, branches and files) from the user's summaries older than t
+func (srv *SummaryService) AnonymizeByUserBefore(userId string, t time.Time) error {
+	srv.invalidateUserCache(userId)
+	return srv.repository.DeleteItemsByUserBefore(userId, t, []uint8{models.SummaryMachine, models.SummaryBranch, models.SummaryEntity})
+}
+
+func (srv *SummaryService) DeleteByUserWithin(userId string, from, to time.Time) error {
+	srv.invalidateUserCache(userId)
+	return srv.repository.DeleteByUserWithin(userId, from, to)
+}
+
+// DeletePartialByUser drops the user's partial summary of the current day, so that today's stats are computed from heartbeats until a new one is generated
+func (srv *SummaryService) DeletePartialByUser(userId string) error {
+	srv.invalidateUserCache(userId)
+	return srv.repository.DeletePartialByUser(userId)
+}
+
+// DeletePartialBefore drops partial summaries of past days, so that their remainder is computed from heartbeats until the nightly aggregation has generated final ones
+func (srv *SummaryService) DeletePartialBefore(t time.Time) error {
+	return srv.repository.DeletePartialBefore(t)
+}
+
+// DataVersion tells when the user's summaries were last invalidated, e.g. because heartbeats were edited, deleted or reprocessed, or because aliases, labels, clients or project groups changed
+// the version is kept as a cache entry, which is dropped along with all others of the user upon invalidation and re-initialized with the current time when requested next
+func (srv *SummaryService) DataVersion(userId string) time.Time {
+	key := srv.getHash(userId, "--data-version")
+	var version time.Time
+	if srv.cache.Get(key, &version) {
+		return version
+	}
+	version = time.Now()
+	srv.cache.SetDefault(key, version)
+	return version
+}
+
 func (srv *SummaryService) Insert(summary *models.Summary) error {
 	srv.invalidateUserCache(summary.UserID)
-	return srv.repository.Insert(summary)
+	if err := srv.repository.Insert(summary); err != nil {
+		return err
+	}
+
+	srv.eventBus.Publish(hub.Message{
+		Name:   config.EventSummaryCreate,
+		Fields: map[string]interface{}{config.FieldPayload: summary, config.FieldUserId: summary.UserID},
+	})
+	return nil
+}
+
+func (srv *SummaryService) ReplacePartial(summary *models.Summary) error {
+	srv.invalidateUserCache(summary.UserID)
+	return srv.repository.ReplacePartial(summary)
 }
 
 // Private summary generation and utility methods
@@ -294,6 +368,83 @@ func (srv *SummaryService) withProjectLabels(summary *models.Summary) *models.Su
 	return summary
 }
 
+func (srv *SummaryService) withProjectNamespaces(summary *models.Summary) *models.Summary {
+	namespaces, err := srv.projectRemoteSrvc.GetNamespacesByUser(summary.UserID)
+	if err != nil {
+		config.Log().Error("failed to retrieve project namespaces for user summary ('%s', '%s', '%s')", summary.UserID, summary.FromTime.String(), summary.ToTime.String())
+		return summary
+	}
+
+	namespaceMap := make(map[string]*models.SummaryItem)
+	for _, p := range summary.Projects {
+		ns, ok := namespaces[p.Key]
+		if !ok {
+			continue
+		}
+		if _, ok := namespaceMap[ns]; !ok {
+			namespaceMap[ns] = &models.SummaryItem{Type: models.SummaryNamespace, Key: ns}
+		}
+		namespaceMap[ns].Total += p.Total
+	}
+
+	items := make([]*models.SummaryItem, 0, len(namespaceMap))
+	for _, v := range namespaceMap {
+		if v.Total > 0 {
+			items = append(items, v)
+		}
+	}
+	summary.Namespaces = items
+	return summary
+}
+
+func (srv *SummaryService) withClients(summary *models.Summary) *models.Summary {
+	clients, err := srv.clientSrvc.GetClientsByProject(summary.UserID)
+	if err != nil {
+		config.Log().Error("failed to retrieve clients for user summary ('%s', '%s', '%s')", summary.UserID, summary.FromTime.String(), summary.ToTime.String())
+		return summary
+	}
+
+	clientMap := make(map[string]*models.SummaryItem)
+	for _, p := range summary.Projects {
+		c, ok := clients[p.Key]
+		if !ok {
+			continue
+		}
+		if _, ok := clientMap[c]; !ok {
+			clientMap[c] = &models.SummaryItem{Type: models.SummaryClient, Key: c}
+		}
+		clientMap[c].Total += p.Total
+	}
+
+	items := make([]*models.SummaryItem, 0, len(clientMap))
+	for _, v := range clientMap {
+		if v.Total > 0 {
+			items = append(items, v)
+		}
+	}
+	summary.Clients = items
+	return summary
+}
+
+// withProjectGroups rolls up the member projects of every project group into a single project named after the group
+func (srv *SummaryService) withProjectGroups(summary *models.Summary) *models.Summary {
+	groups, err := srv.projectGroupSrvc.GetGroupsByProject(summary.UserID)
+	if err != nil {
+		config.Log().Error("failed to retrieve project groups for user summary ('%s', '%s', '%s')", summary.UserID, summary.FromTime.String(), summary.ToTime.String())
+		return summary
+	}
+	if len(groups) == 0 {
+		return summary
+	}
+
+	return summary.WithResolvedAliases(func(t uint8, k string) string {
+		if g, ok := groups[k]; ok && t == models.SummaryProject {
+			return g
+		}
+		return k
+	})
+}
+
 func (srv *SummaryService) mergeSummaries(summaries []*models.Summary) (*models.Summary, error) {
 	// summaries must be sorted by from_date
 	// also, this function implicitly assumes summaries are distinct, i.e. don't cover overlapping time intervals
@@ -316,6 +467,8 @@ func (srv *SummaryService) mergeSummaries(summaries []*models.Summary) (*models.
 		Labels:           make([]*models.SummaryItem, 0),
 		Branches:         make([]*models.SummaryItem, 0),
 		Entities:         make([]*models.SummaryItem, 0),
+		Namespaces:       make([]*models.SummaryItem, 0),
+		Clients:          make([]*models.SummaryItem, 0),
 	}
 
 	var processed = map[time.Time]bool{}
@@ -353,6 +506,8 @@ func (srv *SummaryService) mergeSummaries(summaries []*models.Summary) (*models.
 		finalSummary.Labels = srv.mergeSummaryItems(finalSummary.Labels, s.Labels)
 		finalSummary.Branches = srv.mergeSummaryItems(finalSummary.Branches, s.Branches)
 		finalSummary.Entities = srv.mergeSummaryItems(finalSummary.Entities, s.Entities)
+		finalSummary.Namespaces = srv.mergeSummaryItems(finalSummary.Namespaces, s.Namespaces)
+		finalSummary.Clients = srv.mergeSummaryItems(finalSummary.Clients, s.Clients)
 		finalSummary.NumHeartbeats += s.NumHeartbeats
 
 		processed[hash] = true
@@ -450,11 +605,7 @@ func (srv *SummaryService) getHash(args ...string) string {
 }
 
 func (srv *SummaryService) invalidateUserCache(userId string) {
-	for key := range srv.cache.Items() {
-		if strings.Contains(key, userId) {
-			srv.cache.Delete(key)
-		}
-	}
+	srv.cache.DeleteContaining(userId)
 }
 
 func (srv *SummaryService) getAliasResolver(user *models.User) models.AliasResolver {
@@ -494,3 +645,51 @@ func (srv *SummaryService) getProjectLabelsReverseResolver(user *models.User) mo
 		return projectStrings
 	}
 }
+
+func (srv *SummaryService) getProjectNamespacesReverseResolver(user *models.User) models.ProjectNamespaceReverseResolver {
+	return func(ns string) []string {
+		projects := make([]string, 0)
+		namespaces, err := srv.projectRemoteSrvc.GetNamespacesByUser(user.ID)
+		if err != nil {
+			return projects
+		}
+		for p, n := range namespaces {
+			if n == ns {
+				projects = append(projects, p)
+			}
+		}
+		return projects
+	}
+}
+
+func (srv *SummaryService) getProjectGroupsReverseResolver(user *models.User) models.ProjectGroupReverseResolver {
+	return func(group string) []string {
+		projects := make([]string, 0)
+		groups, err := srv.projectGroupSrvc.GetGroupsByProject(user.ID)
+		if err != nil {
+			return projects
+		}
+		for p, g := range groups {
+			if g == group {
+				projects = append(projects, p)
+			}
+		}
+		return projects
+	}
+}
+
+func (srv *SummaryService) getClientsReverseResolver(user *models.User) models.ClientReverseResolver {
+	return func(client string) []string {
+		projects := make([]string, 0)
+		clients, err := srv.clientSrvc.GetClientsByProject(user.ID)
+		if err != nil {
+			return projects
+		}
+		for p, c := range clients {
+			if c == client {
+				projects = append(projects, p)
+			}
+		}
+		return projects
+	}
+}
@@ -20,14 +20,17 @@ const (
 
 type SummaryServiceTestSuite struct {
 	suite.Suite
-	TestUser            *models.User
-	TestStartTime       time.Time
-	TestDurations       []*models.Duration
-	TestLabels          []*models.ProjectLabel
-	SummaryRepository   *mocks.SummaryRepositoryMock
-	DurationService     *mocks.DurationServiceMock
-	AliasService        *mocks.AliasServiceMock
-	ProjectLabelService *mocks.ProjectLabelServiceMock
+	TestUser             *models.User
+	TestStartTime        time.Time
+	TestDurations        []*models.Duration
+	TestLabels           []*models.ProjectLabel
+	SummaryRepository    *mocks.SummaryRepositoryMock
+	DurationService      *mocks.DurationServiceMock
+	AliasService         *mocks.AliasServiceMock
+	ProjectLabelService  *mocks.ProjectLabelServiceMock
+	ProjectRemoteService *mocks.ProjectRemoteServiceMock
+	ClientService        *mocks.ClientServiceMock
+	ProjectGroupService  *mocks.ProjectGroupServiceMock
 }
 
 func (suite *SummaryServiceTestSuite) SetupSuite() {
@@ -96,6 +99,9 @@ func (suite *SummaryServiceTestSuite) BeforeTest(suiteName, testName string) {
 	suite.DurationService = new(mocks.DurationServiceMock)
 	suite.AliasService = new(mocks.AliasServiceMock)
 	suite.ProjectLabelService = new(mocks.ProjectLabelServiceMock)
+	suite.ProjectRemoteService = new(mocks.ProjectRemoteServiceMock)
+	suite.ClientService = new(mocks.ClientServiceMock)
+	suite.ProjectGroupService = new(mocks.ProjectGroupServiceMock)
 }
 
 func TestSummaryServiceTestSuite(t *testing.T) {
@@ -103,7 +109,7 @@ func TestSummaryServiceTestSuite(t *testing.T) {
 }
 
 func (suite *SummaryServiceTestSuite) TestSummaryService_Summarize() {
-	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService)
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ProjectRemoteService, suite.ClientService, suite.ProjectGroupService)
 
 	var (
 		from   time.Time
@@ -167,7 +173,7 @@ func (suite *SummaryServiceTestSuite) TestSummaryService_Summarize() {
 }
 
 func (suite *SummaryServiceTestSuite) TestSummaryService_Retrieve() {
-	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService)
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ProjectRemoteService, suite.ClientService, suite.ProjectGroupService)
 
 	var (
 		summaries []*models.Summary
@@ -326,7 +332,7 @@ func (suite *SummaryServiceTestSuite) TestSummaryService_Retrieve() {
 }
 
 func (suite *SummaryServiceTestSuite) TestSummaryService_Retrieve_DuplicateSummaries() {
-	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService)
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ProjectRemoteService, suite.ClientService, suite.ProjectGroupService)
 
 	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
 
@@ -374,7 +380,10 @@ func (suite *SummaryServiceTestSuite) TestSummaryService_Retrieve_DuplicateSumma
 }
 
 func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased() {
-	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService)
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ProjectRemoteService, suite.ClientService, suite.ProjectGroupService)
+	suite.ProjectRemoteService.On("GetNamespacesByUser", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.ClientService.On("GetClientsByProject", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.ProjectGroupService.On("GetGroupsByProject", suite.TestUser.ID).Return(map[string]string{}, nil)
 
 	suite.AliasService.On("InitializeUser", suite.TestUser.ID).Return(nil)
 	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
@@ -418,7 +427,10 @@ func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased() {
 }
 
 func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased_ProjectLabels() {
-	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService)
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ProjectRemoteService, suite.ClientService, suite.ProjectGroupService)
+	suite.ProjectRemoteService.On("GetNamespacesByUser", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.ClientService.On("GetClientsByProject", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.ProjectGroupService.On("GetGroupsByProject", suite.TestUser.ID).Return(map[string]string{}, nil)
 
 	var (
 		from   time.Time
@@ -456,8 +468,157 @@ func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased_ProjectLabels()
 	assert.Equal(suite.T(), 6, result.NumHeartbeats)
 }
 
+func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased_ProjectNamespaces() {
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ProjectRemoteService, suite.ClientService, suite.ProjectGroupService)
+
+	from, to := suite.TestStartTime, suite.TestStartTime.Add(1*time.Hour)
+
+	durations := filterDurations(from, to, suite.TestDurations)
+	durations = append(durations, &models.Duration{
+		UserID:          TestUserId,
+		Project:         TestProject2,
+		Language:        TestLanguageGo,
+		Editor:          TestEditorGoland,
+		OperatingSystem: TestOsLinux,
+		Machine:         TestMachine1,
+		Time:            models.CustomTime(durations[len(durations)-1].Time.T().Add(10 * time.Second)),
+		Duration:        10 * time.Second,
+	})
+
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
+	suite.ProjectRemoteService.On("GetNamespacesByUser", suite.TestUser.ID).Return(map[string]string{TestProject1: "github.com/muety"}, nil)
+	suite.ClientService.On("GetClientsByProject", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.ProjectGroupService.On("GetGroupsByProject", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.DurationService.On("Get", from, to, suite.TestUser, mock.Anything).Return(models.Durations(durations), nil)
+	suite.AliasService.On("InitializeUser", TestUserId).Return(nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, TestProject1).Return(TestProject1, nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, TestProject2).Return(TestProject2, nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, mock.Anything).Return("", nil)
+
+	result, err := sut.Aliased(from, to, suite.TestUser, sut.Summarize, nil, false)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result.Namespaces, 2)
+	assert.Equal(suite.T(), 185*time.Second, result.TotalTimeByKey(models.SummaryNamespace, "github.com/muety"))
+	assert.Equal(suite.T(), 10*time.Second, result.TotalTimeByKey(models.SummaryNamespace, models.UnknownSummaryKey)) // project without known remote
+}
+
+func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased_Clients() {
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ProjectRemoteService, suite.ClientService, suite.ProjectGroupService)
+
+	from, to := suite.TestStartTime, suite.TestStartTime.Add(1*time.Hour)
+
+	durations := filterDurations(from, to, suite.TestDurations)
+	durations = append(durations, &models.Duration{
+		UserID:          TestUserId,
+		Project:         TestProject2,
+		Language:        TestLanguageGo,
+		Editor:          TestEditorGoland,
+		OperatingSystem: TestOsLinux,
+		Machine:         TestMachine1,
+		Time:            models.CustomTime(durations[len(durations)-1].Time.T().Add(10 * time.Second)),
+		Duration:        10 * time.Second,
+	})
+
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
+	suite.ProjectRemoteService.On("GetNamespacesByUser", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.ClientService.On("GetClientsByProject", suite.TestUser.ID).Return(map[string]string{TestProject1: "Acme"}, nil)
+	suite.ProjectGroupService.On("GetGroupsByProject", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.DurationService.On("Get", from, to, suite.TestUser, mock.Anything).Return(models.Durations(durations), nil)
+	suite.AliasService.On("InitializeUser", TestUserId).Return(nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, TestProject1).Return(TestProject1, nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, TestProject2).Return(TestProject2, nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, mock.Anything).Return("", nil)
+
+	result, err := sut.Aliased(from, to, suite.TestUser, sut.Summarize, nil, false)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result.Clients, 2)
+	assert.Equal(suite.T(), 185*time.Second, result.TotalTimeByKey(models.SummaryClient, "Acme"))
+	assert.Equal(suite.T(), 10*time.Second, result.TotalTimeByKey(models.SummaryClient, models.UnknownSummaryKey)) // project not assigned to any client
+}
+
+func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased_ProjectGroups() {
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ProjectRemoteService, suite.ClientService, suite.ProjectGroupService)
+
+	from, to := suite.TestStartTime, suite.TestStartTime.Add(1*time.Hour)
+
+	durations := filterDurations(from, to, suite.TestDurations)
+	durations = append(durations, &models.Duration{
+		UserID:          TestUserId,
+		Project:         TestProject2,
+		Language:        TestLanguageGo,
+		Editor:          TestEditorGoland,
+		OperatingSystem: TestOsLinux,
+		Machine:         TestMachine1,
+		Time:            models.CustomTime(durations[len(durations)-1].Time.T().Add(10 * time.Second)),
+		Duration:        10 * time.Second,
+	})
+
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
+	suite.ProjectRemoteService.On("GetNamespacesByUser", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.ClientService.On("GetClientsByProject", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.ProjectGroupService.On("GetGroupsByProject", suite.TestUser.ID).Return(map[string]string{TestProject1: "umbrella", TestProject2: "umbrella"}, nil)
+	suite.DurationService.On("Get", from, to, suite.TestUser, mock.Anything).Return(models.Durations(durations), nil)
+	suite.AliasService.On("InitializeUser", TestUserId).Return(nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, TestProject1).Return(TestProject1, nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, TestProject2).Return(TestProject2, nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, mock.Anything).Return("", nil)
+
+	result, err := sut.Aliased(from, to, suite.TestUser, sut.Summarize, nil, false)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result.Projects, 1)
+	assert.Equal(suite.T(), 195*time.Second, result.TotalTimeByKey(models.SummaryProject, "umbrella"))
+}
+
+func (suite *SummaryServiceTestSuite) TestSummaryService_Filters_ProjectGroups() {
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ProjectRemoteService, suite.ClientService, suite.ProjectGroupService)
+
+	from, to := suite.TestStartTime, suite.TestStartTime.Add(1*time.Hour)
+	filters := models.NewFiltersWith(models.SummaryProject, "umbrella")
+
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
+	suite.ProjectRemoteService.On("GetNamespacesByUser", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.ClientService.On("GetClientsByProject", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.ProjectGroupService.On("GetGroupsByProject", suite.TestUser.ID).Return(map[string]string{TestProject1: "umbrella"}, nil)
+	suite.DurationService.On("Get", from, to, suite.TestUser, mock.Anything).Return(models.Durations{}, nil)
+	suite.AliasService.On("InitializeUser", TestUserId).Return(nil)
+	suite.AliasService.On("GetByUserAndKeyAndType", TestUserId, mock.Anything, mock.Anything).Return([]*models.Alias{}, nil)
+
+	sut.Aliased(from, to, suite.TestUser, sut.Summarize, filters, false)
+
+	effectiveFilters := suite.DurationService.Calls[0].Arguments[3].(*models.Filters)
+	assert.Contains(suite.T(), effectiveFilters.Project, "umbrella")
+	assert.Contains(suite.T(), effectiveFilters.Project, TestProject1) // because of group
+}
+
+func (suite *SummaryServiceTestSuite) TestSummaryService_Filters_Clients() {
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ProjectRemoteService, suite.ClientService, suite.ProjectGroupService)
+
+	from, to := suite.TestStartTime, suite.TestStartTime.Add(1*time.Hour)
+	filters := models.NewFiltersWith(models.SummaryClient, "Acme")
+
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
+	suite.ProjectRemoteService.On("GetNamespacesByUser", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.ClientService.On("GetClientsByProject", suite.TestUser.ID).Return(map[string]string{TestProject1: "Acme", TestProject2: "Beta Corp"}, nil)
+	suite.ProjectGroupService.On("GetGroupsByProject", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.DurationService.On("Get", from, to, suite.TestUser, mock.Anything).Return(models.Durations{}, nil)
+	suite.AliasService.On("InitializeUser", TestUserId).Return(nil)
+	suite.AliasService.On("GetByUserAndKeyAndType", TestUserId, mock.Anything, mock.Anything).Return([]*models.Alias{}, nil)
+
+	sut.Aliased(from, to, suite.TestUser, sut.Summarize, filters, false)
+
+	effectiveFilters := suite.DurationService.Calls[0].Arguments[3].(*models.Filters)
+	assert.Equal(suite.T(), models.OrFilter{TestProject1}, effectiveFilters.Project) // because of client
+	assert.Contains(suite.T(), effectiveFilters.Client, "Acme")
+}
+
 func (suite *SummaryServiceTestSuite) TestSummaryService_Filters() {
-	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService)
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ProjectRemoteService, suite.ClientService, suite.ProjectGroupService)
+	suite.ProjectRemoteService.On("GetNamespacesByUser", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.ClientService.On("GetClientsByProject", suite.TestUser.ID).Return(map[string]string{}, nil)
+	suite.ProjectGroupService.On("GetGroupsByProject", suite.TestUser.ID).Return(map[string]string{}, nil)
 
 	suite.AliasService.On("InitializeUser", suite.TestUser.ID).Return(nil)
 	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
@@ -491,7 +652,7 @@ func (suite *SummaryServiceTestSuite) TestSummaryService_Filters() {
 }
 
 func (suite *SummaryServiceTestSuite) TestSummaryService_getMissingIntervals() {
-	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService)
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ProjectRemoteService, suite.ClientService, suite.ProjectGroupService)
 
 	from1, _ := time.Parse(time.RFC822, "25 Mar 22 11:00 UTC")
 	to1, _ := time.Parse(time.RFC822, "25 Mar 22 13:00 UTC")
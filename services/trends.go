@@ -0,0 +1,185 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	trendsCacheTTL      = 6 * time.Hour
+	trendsMaxDailyRange = 62 * 24 * time.Hour // longer intervals are split into monthly instead of daily periods
+)
+
+var ErrTrendsIntervalUnsupported = errors.New("interval not supported for trends")
+
+type TrendsService struct {
+	config       *config.Config
+	cache        *cache.Cache
+	repository   repositories.ISummaryRepository
+	keyValueSrvc IKeyValueService
+	privatizer   *utils.Privatizer
+	noiseLock    sync.Mutex
+}
+
+func NewTrendsService(summaryRepo repositories.ISummaryRepository, keyValueService IKeyValueService) *TrendsService {
+	cfg := config.Get()
+	return &TrendsService{
+		config:       cfg,
+		cache:        cache.New(trendsCacheTTL, trendsCacheTTL),
+		repository:   summaryRepo,
+		keyValueSrvc: keyValueService,
+		privatizer:   cfg.App.PublicStatsPrivacy.NewPrivatizer(),
+	}
+}
+
+// GetLanguageTrends computes each language's coding time across all users of the instance and its share of the total per period (days or months, depending on the interval's length).
+// Languages used by fewer users than required for public stats are omitted and, if differential privacy is enabled, hours are noised.
+// Noise is drawn only once per period and language and persisted, it is shared among all intervals covering the period.
+func (srv *TrendsService) GetLanguageTrends(interval *models.IntervalKey) (*models.LanguageTrends, error) {
+	if interval == models.IntervalAny {
+		return nil, ErrTrendsIntervalUnsupported
+	}
+
+	cacheKey := (*interval)[0]
+	if cacheResult, ok := srv.cache.Get(cacheKey); ok {
+		return cacheResult.(*models.LanguageTrends), nil
+	}
+
+	err, from, to := helpers.ResolveIntervalTZ(interval, time.Local)
+	if err != nil {
+		return nil, err
+	}
+
+	var periods [][]time.Time
+	periodEnd := func(from time.Time) time.Time { return from.AddDate(0, 0, 1) }
+	if to.Sub(from) > trendsMaxDailyRange {
+		from = datetime.BeginOfMonth(from)
+		periods = utils.SplitRangeByMonths(from, to)
+		periodEnd = func(from time.Time) time.Time { return from.AddDate(0, 1, 0) }
+	} else {
+		from = datetime.BeginOfDay(from)
+		periods = utils.SplitRangeByDays(from, to)
+	}
+
+	trends := &models.LanguageTrends{
+		Interval: (*interval)[0],
+		From:     from,
+		To:       to,
+		Periods:  make([]*models.LanguageTrendPeriod, 0, len(periods)),
+	}
+
+	for _, p := range periods {
+		period, err := srv.computeLanguageTrendPeriod(p[0], p[1], periodEnd(p[0]))
+		if err != nil {
+			return nil, err
+		}
+		trends.Periods = append(trends.Periods, period)
+	}
+
+	srv.cache.SetDefault(cacheKey, trends)
+	return trends, nil
+}
+
+// GetTopLanguages computes the instance's most used languages within the interval as a single period, subject to the same privacy measures as GetLanguageTrends
+func (srv *TrendsService) GetTopLanguages(interval *models.IntervalKey, limit int) (*models.LanguageTrendPeriod, error) {
+	if interval == models.IntervalAny {
+		return nil, ErrTrendsIntervalUnsupported
+	}
+
+	cacheKey := fmt.Sprintf("top_%s_%d", (*interval)[0], limit)
+	if cacheResult, ok := srv.cache.Get(cacheKey); ok {
+		return cacheResult.(*models.LanguageTrendPeriod), nil
+	}
+
+	err, from, to := helpers.ResolveIntervalTZ(interval, time.Local)
+	if err != nil {
+		return nil, err
+	}
+
+	// the current day is still ongoing, its noise is drawn once for the entire day
+	period, err := srv.computeLanguageTrendPeriod(from, to, datetime.BeginOfDay(to).AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(period.Languages) > limit {
+		period.Languages = period.Languages[:limit]
+	}
+
+	srv.cache.SetDefault(cacheKey, period)
+	return period, nil
+}
+
+// computeLanguageTrendPeriod sums up the languages' hours within from and to, where to might lie before periodEnd, if the period is still ongoing
+// the period's full length determines the privacy measures' sensitivity, and the noise drawn for the period's languages is kept for its entire duration
+func (srv *TrendsService) computeLanguageTrendPeriod(from, to, periodEnd time.Time) (*models.LanguageTrendPeriod, error) {
+	// a single user can't have contributed more than the period's length to a language's total, plus contributions are capped at the configured maximum
+	maxPerUser := time.Duration(math.Min(periodEnd.Sub(from).Hours(), float64(srv.config.App.PublicStatsPrivacy.MaxHoursPerUser)) * float64(time.Hour))
+
+	var noise *privacyNoise
+	if srv.privatizer != nil {
+		srv.noiseLock.Lock()
+		defer srv.noiseLock.Unlock()
+		noise = loadPrivacyNoise(srv.keyValueSrvc, fmt.Sprintf("languages_%d_%d", from.Unix(), periodEnd.Unix()))
+	} else {
+		maxPerUser = 0
+	}
+
+	totals, err := srv.repository.GetTotalsByTypeWithin(models.SummaryLanguage, from, to, maxPerUser)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalHours float64
+	items := make([]*models.LanguageTrendItem, 0, len(totals))
+	for _, t := range totals {
+		// rarely used languages could be attributed to individual users
+		if t.Users < srv.config.App.PublicStatsPrivacy.MinUsers || t.Key == "" {
+			continue
+		}
+
+		hours := (t.Total * time.Second).Hours()
+		if noise != nil {
+			var ok bool
+			if hours, ok = noise.privatize(srv.privatizer, t.Key, hours, maxPerUser.Hours(), t.Users); !ok {
+				continue
+			}
+		}
+
+		totalHours += hours
+		items = append(items, &models.LanguageTrendItem{Language: t.Key, Hours: roundTrend(hours)})
+	}
+
+	if noise != nil {
+		if err := noise.save(srv.keyValueSrvc); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, item := range items {
+		if totalHours > 0 {
+			item.Percent = roundTrend(item.Hours / totalHours * 100)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Hours > items[j].Hours
+	})
+
+	return &models.LanguageTrendPeriod{From: from, To: to, Languages: items}, nil
+}
+
+func roundTrend(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type TrendsServiceTestSuite struct {
+	suite.Suite
+	SummaryRepository *mocks.SummaryRepositoryMock
+	KeyValueService   *mocks.KeyValueServiceMock
+}
+
+func (suite *TrendsServiceTestSuite) SetupSuite() {
+	cfg := config.Empty()
+	cfg.App.PublicStatsPrivacy.MinUsers = 3
+	cfg.App.PublicStatsPrivacy.MaxHoursPerUser = 5000
+	config.Set(cfg)
+}
+
+func (suite *TrendsServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.SummaryRepository = new(mocks.SummaryRepositoryMock)
+	suite.KeyValueService = new(mocks.KeyValueServiceMock)
+}
+
+func TestTrendsServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(TrendsServiceTestSuite))
+}
+
+func (suite *TrendsServiceTestSuite) TestTrendsService_GetLanguageTrends() {
+	suite.SummaryRepository.On("GetTotalsByTypeWithin", models.SummaryLanguage, mock.Anything, mock.Anything, mock.Anything).Return([]*models.TotalByKey{
+		{Key: "Go", Total: 3 * 3600, Users: 5},
+		{Key: "Python", Total: 1 * 3600, Users: 3},
+		{Key: "Brainfuck", Total: 10 * 3600, Users: 1}, // too few users to be published
+	}, nil)
+
+	sut := NewTrendsService(suite.SummaryRepository, suite.KeyValueService)
+
+	result, err := sut.GetLanguageTrends(models.IntervalPast7Days)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "7_days", result.Interval)
+	assert.GreaterOrEqual(suite.T(), len(result.Periods), 7)
+	assert.LessOrEqual(suite.T(), len(result.Periods), 8)
+	assert.Equal(suite.T(), 0, result.Periods[1].From.Hour())
+
+	period := result.Periods[0]
+	assert.Len(suite.T(), period.Languages, 2)
+	assert.Equal(suite.T(), "Go", period.Languages[0].Language)
+	assert.Equal(suite.T(), 3.0, period.Languages[0].Hours)
+	assert.Equal(suite.T(), 75.0, period.Languages[0].Percent)
+	assert.Equal(suite.T(), "Python", period.Languages[1].Language)
+	assert.Equal(suite.T(), 25.0, period.Languages[1].Percent)
+
+	// served from cache
+	_, err = sut.GetLanguageTrends(models.IntervalPast7Days)
+	assert.Nil(suite.T(), err)
+	suite.SummaryRepository.AssertNumberOfCalls(suite.T(), "GetTotalsByTypeWithin", len(result.Periods))
+}
+
+func (suite *TrendsServiceTestSuite) TestTrendsService_GetLanguageTrends_Monthly() {
+	suite.SummaryRepository.On("GetTotalsByTypeWithin", models.SummaryLanguage, mock.Anything, mock.Anything, mock.Anything).Return([]*models.TotalByKey{}, nil)
+
+	sut := NewTrendsService(suite.SummaryRepository, suite.KeyValueService)
+
+	result, err := sut.GetLanguageTrends(models.IntervalPast12Months)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result.Periods, 13)
+	assert.Equal(suite.T(), 1, result.Periods[1].From.Day())
+	assert.Equal(suite.T(), time.Now().Month(), result.Periods[12].From.Month())
+	assert.Empty(suite.T(), result.Periods[0].Languages)
+}
+
+func (suite *TrendsServiceTestSuite) TestTrendsService_GetLanguageTrends_AllTime() {
+	sut := NewTrendsService(suite.SummaryRepository, suite.KeyValueService)
+
+	_, err := sut.GetLanguageTrends(models.IntervalAny)
+	assert.ErrorIs(suite.T(), err, ErrTrendsIntervalUnsupported)
+}
+
+func (suite *TrendsServiceTestSuite) TestTrendsService_GetTopLanguages_Privacy() {
+	config.Get().App.PublicStatsPrivacy.Enabled = true
+	config.Get().App.PublicStatsPrivacy.Epsilon = 1.0
+	defer func() { config.Get().App.PublicStatsPrivacy.Enabled = false }()
+
+	// each user's contribution is capped at the day's length
+	suite.SummaryRepository.On("GetTotalsByTypeWithin", models.SummaryLanguage, mock.Anything, mock.Anything, 24*time.Hour).Return([]*models.TotalByKey{
+		{Key: "Go", Total: 300 * 3600, Users: 50},
+	}, nil)
+
+	var saved *models.KeyStringValue
+	suite.KeyValueService.On("MustGetString", mock.Anything).Return(&models.KeyStringValue{}).Once()
+	suite.KeyValueService.On("PutString", mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(0).(*models.KeyStringValue)
+	}).Return(nil).Once()
+
+	result1, err := NewTrendsService(suite.SummaryRepository, suite.KeyValueService).GetTopLanguages(models.IntervalToday, 10)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result1.Languages, 1)
+	assert.NotNil(suite.T(), saved)
+	assert.Contains(suite.T(), saved.Key, config.KeyPrivacyNoise)
+
+	// noise is not drawn again, e.g. after the cache expired
+	suite.KeyValueService.On("MustGetString", saved.Key).Return(saved)
+	for i := 0; i < 5; i++ {
+		result2, err := NewTrendsService(suite.SummaryRepository, suite.KeyValueService).GetTopLanguages(models.IntervalToday, 10)
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), result1.Languages[0].Hours, result2.Languages[0].Hours)
+	}
+	suite.KeyValueService.AssertNumberOfCalls(suite.T(), "PutString", 1)
+}
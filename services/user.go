@@ -16,20 +16,31 @@ import (
 	"time"
 )
 
+const deletionTokenValidity = 24 * time.Hour
+
+var (
+	ErrDeletionTokenExpired = errors.New("deletion token expired")
+	ErrUserSuspended        = errors.New("user is suspended")
+	ErrInvalidUsername      = errors.New("invalid username")
+	ErrUsernameTaken        = errors.New("username already taken")
+)
+
 type UserService struct {
 	config      *config.Config
 	cache       *cache.Cache
 	eventBus    *hub.Hub
 	mailService IMailService
+	notifySrvc  INotificationService
 	repository  repositories.IUserRepository
 }
 
-func NewUserService(mailService IMailService, userRepo repositories.IUserRepository) *UserService {
+func NewUserService(mailService IMailService, notificationService INotificationService, userRepo repositories.IUserRepository) *UserService {
 	srv := &UserService{
 		config:      config.Get(),
 		eventBus:    config.EventBus(),
 		cache:       cache.New(1*time.Hour, 2*time.Hour),
 		mailService: mailService,
+		notifySrvc:  notificationService,
 		repository:  userRepo,
 	}
 
@@ -45,8 +56,11 @@ func NewUserService(mailService IMailService, userRepo repositories.IUserReposit
 				config.Log().Error("failed to set wakatime api key for user %s", user.ID)
 			}
 
-			if user.Email != "" {
-				if err := mailService.SendWakatimeFailureNotification(user, n); err != nil {
+			if user.Email != "" && user.IsActive() {
+				text := fmt.Sprintf("Relaying your heartbeats to WakaTime has failed %d times in a row, so the connection was paused. To resume it, please re-enter your WakaTime API key in the settings.", n)
+				if err := notificationService.Notify(user, "WakaTime Connection Failure", text, func() error {
+					return mailService.SendWakatimeFailureNotification(user, n)
+				}); err != nil {
 					config.Log().Error("failed to send wakatime failure notification mail to user %s", user.ID)
 				} else {
 					logbuch.Info("sent wakatime connection failure mail to %s", user.ID)
@@ -55,6 +69,13 @@ func NewUserService(mailService IMailService, userRepo repositories.IUserReposit
 		}
 	}(&sub1)
 
+	sub2 := srv.eventBus.Subscribe(0, config.EventMailBounce)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.handleMailBounce(m.Fields[config.FieldPayload].(string))
+		}
+	}(&sub2)
+
 	return srv
 }
 
@@ -108,6 +129,20 @@ func (srv *UserService) GetUserByResetToken(resetToken string) (*models.User, er
 	return srv.repository.FindOne(models.User{ResetToken: resetToken})
 }
 
+func (srv *UserService) GetUserByDeletionToken(deletionToken string) (*models.User, error) {
+	if deletionToken == "" {
+		return nil, errors.New("deletion token must not be empty")
+	}
+	user, err := srv.repository.FindOne(models.User{DeletionToken: deletionToken})
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletionTokenExpiry == nil || user.DeletionTokenExpiry.T().Before(time.Now()) {
+		return nil, ErrDeletionTokenExpired
+	}
+	return user, nil
+}
+
 func (srv *UserService) GetUserByStripeCustomerId(customerId string) (*models.User, error) {
 	if customerId == "" {
 		return nil, errors.New("customer id must not be empty")
@@ -161,10 +196,25 @@ func (srv *UserService) GetActive(exact bool) ([]*models.User, error) {
 	return results, nil
 }
 
+// GetInactive returns all users who didn't send any heartbeat within the given number of days, i.e. based on the same notion of activity as CountActiveByClass
+// admins as well as deactivated and suspended accounts are never considered inactive
+func (srv *UserService) GetInactive(days int) ([]*models.User, error) {
+	return srv.repository.GetInactiveSince(time.Now().Add(-time.Duration(days) * 24 * time.Hour))
+}
+
+// GetScheduledForDeletion returns all users whose account deletion grace period has passed
+func (srv *UserService) GetScheduledForDeletion() ([]*models.User, error) {
+	return srv.repository.GetByDeleteAtBefore(time.Now())
+}
+
 func (srv *UserService) Count() (int64, error) {
 	return srv.repository.Count()
 }
 
+func (srv *UserService) CountActiveByClass(class models.ActivityClass) (int64, error) {
+	return srv.repository.CountByLastActiveAfter(time.Now().Add(-class.Window()))
+}
+
 func (srv *UserService) CreateOrGet(signup *models.Signup, isAdmin bool) (*models.User, bool, error) {
 	u := &models.User{
 		ID:       signup.Username,
@@ -173,6 +223,7 @@ func (srv *UserService) CreateOrGet(signup *models.Signup, isAdmin bool) (*model
 		Location: signup.Location,
 		Password: signup.Password,
 		IsAdmin:  isAdmin,
+		Status:   models.UserStatusActive,
 	}
 
 	if hash, err := utils.HashPassword(u.Password, srv.config.Security.PasswordSalt); err != nil {
@@ -212,10 +263,109 @@ func (srv *UserService) SetWakatimeApiCredentials(user *models.User, apiKey stri
 	return user, nil
 }
 
+// SetMirrorUrl sets the endpoint to mirror the user's heartbeats to along with the secret to sign deliveries with
+func (srv *UserService) SetMirrorUrl(user *models.User, mirrorUrl, mirrorSecret string) (*models.User, error) {
+	srv.FlushUserCache(user.ID)
+
+	if mirrorUrl == user.MirrorUrl && mirrorSecret == user.MirrorSecret {
+		return user, nil
+	}
+
+	if u, err := srv.repository.UpdateField(user, "mirror_url", mirrorUrl); err != nil {
+		return u, err
+	}
+	return srv.repository.UpdateField(user, "mirror_secret", mirrorSecret)
+}
+
 func (srv *UserService) GenerateResetToken(user *models.User) (*models.User, error) {
 	return srv.repository.UpdateField(user, "reset_token", uuid.NewV4())
 }
 
+// GenerateVerificationToken creates a token for the user to confirm the ownership of their e-mail address with
+func (srv *UserService) GenerateVerificationToken(user *models.User) (*models.User, error) {
+	user.VerificationToken = uuid.NewV4().String()
+	return srv.Update(user)
+}
+
+// VerifyEmail marks the e-mail address of the user, who the given verification token was issued to, as confirmed
+func (srv *UserService) VerifyEmail(verificationToken string) (*models.User, error) {
+	if verificationToken == "" {
+		return nil, errors.New("verification token must not be empty")
+	}
+	user, err := srv.repository.FindOne(models.User{VerificationToken: verificationToken})
+	if err != nil {
+		return nil, err
+	}
+	user.EmailVerified = true
+	user.VerificationToken = ""
+	return srv.Update(user)
+}
+
+// GenerateDeletionToken creates a token to confirm the deletion of the user's account with, which is valid for 24 hours
+func (srv *UserService) GenerateDeletionToken(user *models.User) (*models.User, error) {
+	expiry := models.CustomTime(time.Now().Add(deletionTokenValidity))
+	user.DeletionToken = uuid.NewV4().String()
+	user.DeletionTokenExpiry = &expiry
+	return srv.Update(user)
+}
+
+// ScheduleDeletion deactivates the user's account and marks it for being purged after the configured grace period
+// Suspended accounts are refused, as deactivating them would lift the suspension upon reactivation
+func (srv *UserService) ScheduleDeletion(user *models.User) (*models.User, error) {
+	if user.IsSuspended() {
+		return nil, ErrUserSuspended
+	}
+	deleteAt := models.CustomTime(time.Now().AddDate(0, 0, srv.config.App.AccountDeletionGraceDays))
+	user.Status = models.UserStatusDeactivated
+	user.DeletionToken = ""
+	user.DeletionTokenExpiry = nil
+	user.DeleteAt = &deleteAt
+	return srv.Update(user)
+}
+
+// CancelDeletion recovers a user's account during the deletion grace period
+func (srv *UserService) CancelDeletion(user *models.User) (*models.User, error) {
+	if user.IsSuspended() {
+		return nil, ErrUserSuspended
+	}
+	user.Status = models.UserStatusActive
+	user.DeleteAt = nil
+	return srv.Update(user)
+}
+
+// ChangeUserId renames a user, moving all their data over to the new id, and notifies them by mail
+func (srv *UserService) ChangeUserId(user *models.User, newUserId string) (*models.User, error) {
+	if !models.ValidateUsername(newUserId) {
+		return nil, ErrInvalidUsername
+	}
+	if _, err := srv.repository.FindOne(models.User{ID: newUserId}); err == nil {
+		return nil, ErrUsernameTaken
+	}
+
+	oldUserId := user.ID
+	srv.FlushUserCache(oldUserId)
+
+	renamed, err := srv.repository.ChangeId(user, newUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	srv.FlushUserCache(newUserId)
+	srv.notifyUpdate(renamed)
+
+	if renamed.Email != "" {
+		go func(user *models.User) {
+			if err := srv.mailService.SendUsernameChange(user, oldUserId); err != nil {
+				config.Log().Error("failed to send username change mail to user %s", user.ID)
+			} else {
+				logbuch.Info("sent username change mail to %s", user.ID)
+			}
+		}(renamed)
+	}
+
+	return renamed, nil
+}
+
 func (srv *UserService) Delete(user *models.User) error {
 	srv.FlushUserCache(user.ID)
 
@@ -226,6 +376,22 @@ func (srv *UserService) Delete(user *models.User) error {
 	return srv.repository.Delete(user)
 }
 
+// handleMailBounce stops sending mails to an address, which permanently bounced or complained, by marking it unverified and turning off reports
+func (srv *UserService) handleMailBounce(email string) {
+	user, err := srv.GetUserByEmail(email)
+	if err != nil {
+		return // not a user's address, e.g. a mail to an admin
+	}
+
+	logbuch.Warn("disabling mails to user %s, because their address bounced or they complained", user.ID)
+
+	user.EmailVerified = false
+	user.ReportsWeekly = false
+	if _, err := srv.Update(user); err != nil {
+		config.Log().Error("failed to update user %s after mail bounce - %v", user.ID, err)
+	}
+}
+
 func (srv *UserService) FlushCache() {
 	srv.cache.Flush()
 }
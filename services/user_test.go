@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type UserServiceTestSuite struct {
+	suite.Suite
+	UserRepository *mocks.UserRepositoryMock
+}
+
+func (suite *UserServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+}
+
+func (suite *UserServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.UserRepository = new(mocks.UserRepositoryMock)
+	suite.UserRepository.On("Update", mock.Anything).Return(&models.User{}, nil)
+}
+
+func TestUserServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(UserServiceTestSuite))
+}
+
+func (suite *UserServiceTestSuite) TestUserService_GenerateDeletionToken() {
+	sut := NewUserService(nil, nil, suite.UserRepository)
+
+	user := &models.User{ID: "user1"}
+	_, err := sut.GenerateDeletionToken(user)
+
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), user.DeletionToken)
+	assert.NotNil(suite.T(), user.DeletionTokenExpiry)
+	assert.True(suite.T(), user.DeletionTokenExpiry.T().After(time.Now()))
+	assert.True(suite.T(), user.DeletionTokenExpiry.T().Before(time.Now().Add(deletionTokenValidity+time.Minute)))
+}
+
+func (suite *UserServiceTestSuite) TestUserService_GetUserByDeletionToken() {
+	valid := models.CustomTime(time.Now().Add(1 * time.Hour))
+	expired := models.CustomTime(time.Now().Add(-1 * time.Minute))
+
+	suite.UserRepository.On("FindOne", models.User{DeletionToken: "valid"}).Return(&models.User{ID: "user1", DeletionToken: "valid", DeletionTokenExpiry: &valid}, nil)
+	suite.UserRepository.On("FindOne", models.User{DeletionToken: "expired"}).Return(&models.User{ID: "user2", DeletionToken: "expired", DeletionTokenExpiry: &expired}, nil)
+	suite.UserRepository.On("FindOne", models.User{DeletionToken: "legacy"}).Return(&models.User{ID: "user3", DeletionToken: "legacy"}, nil)
+
+	sut := NewUserService(nil, nil, suite.UserRepository)
+
+	user, err := sut.GetUserByDeletionToken("valid")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "user1", user.ID)
+
+	_, err = sut.GetUserByDeletionToken("expired")
+	assert.ErrorIs(suite.T(), err, ErrDeletionTokenExpired)
+
+	_, err = sut.GetUserByDeletionToken("legacy")
+	assert.ErrorIs(suite.T(), err, ErrDeletionTokenExpired)
+
+	_, err = sut.GetUserByDeletionToken("")
+	assert.Error(suite.T(), err)
+}
+
+func (suite *UserServiceTestSuite) TestUserService_VerifyEmail() {
+	user := &models.User{ID: "user1", Email: "user1@wakapi.dev", VerificationToken: "valid"}
+	suite.UserRepository.On("FindOne", models.User{VerificationToken: "valid"}).Return(user, nil)
+	suite.UserRepository.On("FindOne", models.User{VerificationToken: "invalid"}).Return((*models.User)(nil), errors.New("record not found"))
+
+	sut := NewUserService(nil, nil, suite.UserRepository)
+
+	_, err := sut.VerifyEmail("valid")
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), user.EmailVerified)
+	assert.True(suite.T(), user.HasVerifiedEmail())
+	assert.Empty(suite.T(), user.VerificationToken)
+
+	_, err = sut.VerifyEmail("invalid")
+	assert.Error(suite.T(), err)
+
+	_, err = sut.VerifyEmail("")
+	assert.Error(suite.T(), err)
+}
+
+func (suite *UserServiceTestSuite) TestUserService_ScheduleDeletion() {
+	expiry := models.CustomTime(time.Now().Add(1 * time.Hour))
+	sut := NewUserService(nil, nil, suite.UserRepository)
+
+	user := &models.User{ID: "user1", Status: models.UserStatusActive, DeletionToken: "token", DeletionTokenExpiry: &expiry}
+	_, err := sut.ScheduleDeletion(user)
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), user.IsDeactivated())
+	assert.True(suite.T(), user.IsScheduledForDeletion())
+	assert.Empty(suite.T(), user.DeletionToken)
+	assert.Nil(suite.T(), user.DeletionTokenExpiry)
+}
+
+func (suite *UserServiceTestSuite) TestUserService_ScheduleDeletion_Suspended() {
+	sut := NewUserService(nil, nil, suite.UserRepository)
+	user := &models.User{ID: "user1", Status: models.UserStatusSuspended}
+
+	_, err := sut.ScheduleDeletion(user)
+
+	assert.ErrorIs(suite.T(), err, ErrUserSuspended)
+	assert.True(suite.T(), user.IsSuspended())
+	assert.False(suite.T(), user.IsScheduledForDeletion())
+	suite.UserRepository.AssertNotCalled(suite.T(), "Update", mock.Anything)
+}
+
+func (suite *UserServiceTestSuite) TestUserService_CancelDeletion() {
+	deleteAt := models.CustomTime(time.Now().Add(24 * time.Hour))
+	sut := NewUserService(nil, nil, suite.UserRepository)
+
+	user := &models.User{ID: "user1", Status: models.UserStatusDeactivated, DeleteAt: &deleteAt}
+	_, err := sut.CancelDeletion(user)
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), user.IsActive())
+	assert.False(suite.T(), user.IsScheduledForDeletion())
+}
+
+func (suite *UserServiceTestSuite) TestUserService_CancelDeletion_Suspended() {
+	deleteAt := models.CustomTime(time.Now().Add(24 * time.Hour))
+	sut := NewUserService(nil, nil, suite.UserRepository)
+	user := &models.User{ID: "user1", Status: models.UserStatusSuspended, DeleteAt: &deleteAt}
+
+	_, err := sut.CancelDeletion(user)
+
+	assert.ErrorIs(suite.T(), err, ErrUserSuspended)
+	assert.True(suite.T(), user.IsSuspended())
+	suite.UserRepository.AssertNotCalled(suite.T(), "Update", mock.Anything)
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alitto/pond"
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	UserTrendsDefaultWeeks = 8
+	UserTrendsMinWeeks     = 5 // enough to cover the current month
+	UserTrendsMaxWeeks     = 26
+	userTrendsCacheTTL     = 15 * time.Minute
+	userTrendsRollingDays  = 7
+)
+
+type UserTrendsService struct {
+	config         *config.Config
+	cache          *cache.Cache
+	summaryService ISummaryService
+}
+
+func NewUserTrendsService(summaryService ISummaryService) *UserTrendsService {
+	return &UserTrendsService{
+		config:         config.Get(),
+		cache:          cache.New(userTrendsCacheTTL, userTrendsCacheTTL),
+		summaryService: summaryService,
+	}
+}
+
+// GetByUser computes rolling averages and weekday distributions from the user's daily totals of the past weeks and projects the hours to expect by the end of the current week and month from them
+func (srv *UserTrendsService) GetByUser(user *models.User, weeks int) (*models.UserTrends, error) {
+	if weeks < UserTrendsMinWeeks {
+		weeks = UserTrendsMinWeeks
+	}
+	if weeks > UserTrendsMaxWeeks {
+		weeks = UserTrendsMaxWeeks
+	}
+
+	cacheKey := fmt.Sprintf("%s_%d", user.ID, weeks)
+	if cacheResult, ok := srv.cache.Get(cacheKey); ok {
+		return cacheResult.(*models.UserTrends), nil
+	}
+
+	now := time.Now().In(user.TZ())
+	from := datetime.BeginOfDay(now).AddDate(0, 0, -7*weeks)
+
+	intervals := utils.SplitRangeByDays(from, now)
+	totals := make([]time.Duration, len(intervals))
+
+	wp := pond.New(utils.HalfCPUs(), 0)
+	mut := sync.Mutex{}
+	var fetchErr error
+
+	for i, interval := range intervals {
+		i := i
+		interval := interval
+
+		wp.Submit(func() {
+			summary, err := srv.summaryService.Retrieve(interval[0], interval[1], user, nil)
+			mut.Lock()
+			defer mut.Unlock()
+			if err != nil {
+				fetchErr = err
+				return
+			}
+			totals[i] = summary.TotalTime()
+		})
+	}
+
+	wp.StopAndWait()
+
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+
+	trends := computeUserTrends(from, now, totals)
+	srv.cache.SetDefault(cacheKey, trends)
+	return trends, nil
+}
+
+// computeUserTrends derives trends from per-day totals, starting at from (midnight) and ending with the current day (today), which is still in progress and thus excluded from all averages
+func computeUserTrends(from, now time.Time, totals []time.Duration) *models.UserTrends {
+	trends := &models.UserTrends{
+		From:     from,
+		To:       now,
+		Days:     make([]*models.UserTrendDay, len(totals)),
+		Weekdays: make([]*models.UserTrendWeekday, 7),
+	}
+
+	var (
+		completeDays     = len(totals) - 1
+		completeTotal    time.Duration
+		weekdayTotals    [7]time.Duration
+		weekdayCounts    [7]int
+		weekdayAverages  [7]time.Duration
+		rollingTotal     time.Duration
+		weekStart        = datetime.BeginOfWeek(now)
+		monthStart       = datetime.BeginOfMonth(now)
+		weekSoFar        time.Duration
+		monthSoFar       time.Duration
+		todaySoFar       = totals[len(totals)-1]
+		weekdayIndexFrom = func(t time.Time) int { return (int(t.Weekday()) + 6) % 7 } // monday first
+	)
+
+	for i, total := range totals {
+		day := from.AddDate(0, 0, i)
+
+		rollingTotal += total
+		if i >= userTrendsRollingDays {
+			rollingTotal -= totals[i-userTrendsRollingDays]
+		}
+		rollingDays := userTrendsRollingDays
+		if i+1 < rollingDays {
+			rollingDays = i + 1
+		}
+
+		trends.Days[i] = &models.UserTrendDay{
+			Date:           day.Format(config.SimpleDateFormat),
+			Hours:          roundTrend(total.Hours()),
+			RollingAverage: roundTrend(rollingTotal.Hours() / float64(rollingDays)),
+		}
+
+		if !day.Before(weekStart) {
+			weekSoFar += total
+		}
+		if !day.Before(monthStart) {
+			monthSoFar += total
+		}
+
+		if i < completeDays {
+			completeTotal += total
+			weekdayTotals[weekdayIndexFrom(day)] += total
+			weekdayCounts[weekdayIndexFrom(day)]++
+		}
+	}
+
+	if completeDays > 0 {
+		trends.DailyAverage = roundTrend(completeTotal.Hours() / float64(completeDays))
+	}
+
+	for i := range weekdayTotals {
+		if weekdayCounts[i] > 0 {
+			weekdayAverages[i] = weekdayTotals[i] / time.Duration(weekdayCounts[i])
+		}
+		trends.Weekdays[i] = &models.UserTrendWeekday{
+			Weekday:      time.Weekday((i + 1) % 7).String(),
+			AverageHours: roundTrend(weekdayAverages[i].Hours()),
+		}
+		if completeTotal > 0 {
+			trends.Weekdays[i].Percent = roundTrend(float64(weekdayTotals[i]) / float64(completeTotal) * 100)
+		}
+	}
+
+	// expect each remaining day of a period to match the average of its weekday, today counts with whatever is left of its average
+	project := func(periodFrom, periodTo time.Time, soFar time.Duration) *models.UserProjection {
+		expected := soFar
+		if remainingToday := weekdayAverages[weekdayIndexFrom(now)] - todaySoFar; remainingToday > 0 {
+			expected += remainingToday
+		}
+		for day := datetime.BeginOfDay(now).AddDate(0, 0, 1); day.Before(periodTo); day = day.AddDate(0, 0, 1) {
+			expected += weekdayAverages[weekdayIndexFrom(day)]
+		}
+		return &models.UserProjection{
+			From:     periodFrom,
+			To:       periodTo,
+			Hours:    roundTrend(soFar.Hours()),
+			Expected: roundTrend(expected.Hours()),
+		}
+	}
+
+	trends.Week = project(weekStart, weekStart.AddDate(0, 0, 7), weekSoFar)
+	trends.Month = project(monthStart, monthStart.AddDate(0, 1, 0), monthSoFar)
+
+	return trends
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestComputeUserTrends(t *testing.T) {
+	now := time.Date(2024, 2, 7, 10, 0, 0, 0, time.UTC) // a wednesday
+	from := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+
+	// two hours on every weekday, nothing on weekends, one hour so far today
+	totals := make([]time.Duration, 36)
+	for i := range totals {
+		if weekday := from.AddDate(0, 0, i).Weekday(); weekday != time.Saturday && weekday != time.Sunday {
+			totals[i] = 2 * time.Hour
+		}
+	}
+	totals[len(totals)-1] = 1 * time.Hour
+
+	trends := computeUserTrends(from, now, totals)
+
+	assert.Len(t, trends.Days, 36)
+	assert.Equal(t, "2024-01-03", trends.Days[0].Date)
+	assert.Equal(t, 2.0, trends.Days[0].RollingAverage)
+	assert.Equal(t, 1.43, trends.Days[6].RollingAverage)
+	assert.Equal(t, 1.43, trends.DailyAverage)
+
+	assert.Len(t, trends.Weekdays, 7)
+	assert.Equal(t, "Monday", trends.Weekdays[0].Weekday)
+	assert.Equal(t, 2.0, trends.Weekdays[0].AverageHours)
+	assert.Equal(t, 20.0, trends.Weekdays[0].Percent)
+	assert.Equal(t, "Sunday", trends.Weekdays[6].Weekday)
+	assert.Zero(t, trends.Weekdays[6].AverageHours)
+
+	assert.Equal(t, 5.0, trends.Week.Hours)
+	assert.Equal(t, 10.0, trends.Week.Expected)
+	assert.Equal(t, 9.0, trends.Month.Hours)
+	assert.Equal(t, 42.0, trends.Month.Expected)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), trends.Month.To)
+
+	assert.Equal(t, "Monday", trends.BusiestWeekday().Weekday)
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+// working hours are computed from raw heartbeats rather than pre-aggregated summaries, so they're limited to reasonably short intervals
+const WorkingHoursMaxRange = 31 * 24 * time.Hour
+
+var ErrWorkingHoursRangeTooLarge = errors.New("working hours are only available for intervals of up to 31 days")
+
+type WorkingHoursService struct {
+	config          *config.Config
+	durationService IDurationService
+}
+
+func NewWorkingHoursService(durationService IDurationService) *WorkingHoursService {
+	return &WorkingHoursService{
+		config:          config.Get(),
+		durationService: durationService,
+	}
+}
+
+// GetByUser relates the user's coding time within the given interval to their working schedule, returns nil if they haven't set up any
+func (srv *WorkingHoursService) GetByUser(user *models.User, from, to time.Time, filters *models.Filters) (*models.WorkingHours, error) {
+	if !user.HasWorkingSchedule() {
+		return nil, nil
+	}
+	if to.Sub(from) > WorkingHoursMaxRange {
+		return nil, ErrWorkingHoursRangeTooLarge
+	}
+
+	durations, err := srv.durationService.Get(from, to, user, filters)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewWorkingHours(user, durations, from, to), nil
+}
@@ -61,6 +61,12 @@ String.prototype.toHHMMSS = function () {
     return `${hours}:${minutes}:${seconds}`
 }
 
+function getProjectNotes(project) {
+    return (wakapiData.annotations || [])
+        .filter(a => a.project === project)
+        .map(a => ` ${a.day}: ${a.text}`)
+}
+
 function draw(subselection) {
     function getTooltipOptions(key) {
         return {
@@ -69,6 +75,7 @@ function draw(subselection) {
                     const d = wakapiData[key][item.dataIndex]
                     return ` ${d.key}: ${d.total.toString().toHHMMSS()}`
                 },
+                afterLabel: (item) => key === 'projects' ? getProjectNotes(wakapiData[key][item.dataIndex].key) : null,
                 title: () => 'Total Time',
                 footer: () => key === 'projects' ? 'Click for details' : null
             }
@@ -1,5 +1,4 @@
-// Package docs GENERATED BY SWAG; DO NOT EDIT
-// This file was generated by swaggo/swag
+// Package docs Code generated by swaggo/swag. DO NOT EDIT
 package docs
 
 import "github.com/swaggo/swag"
@@ -24,177 +23,188 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/compat/shields/v1/{user}/{interval}/{filter}": {
+        "/admin/aggregation/backfill": {
             "get": {
-                "description": "Retrieve total time for a given entity (e.g. a project) within a given range (e.g. one week) in a format compatible with [Shields.io](https://shields.io/endpoint). Requires public data access to be allowed.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "badges"
-                ],
-                "summary": "Get badge data",
-                "operationId": "get-badge",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "User ID to fetch data for",
-                        "name": "user",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "enum": [
-                            "today",
-                            "yesterday",
-                            "week",
-                            "month",
-                            "year",
-                            "7_days",
-                            "last_7_days",
-                            "30_days",
-                            "last_30_days",
-                            "6_months",
-                            "last_6_months",
-                            "12_months",
-                            "last_12_months",
-                            "last_year",
-                            "any",
-                            "all_time"
-                        ],
-                        "type": "string",
-                        "description": "Interval to aggregate data for",
-                        "name": "interval",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Filter to apply (e.g. 'project:wakapi' or 'language:Go')",
-                        "name": "filter",
-                        "in": "path",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "Retrieve the progress of the current or latest aggregation backfill",
+                "operationId": "get-admin-aggregation-backfill",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/v1.BadgeData"
+                            "$ref": "#/definitions/models.AggregationBackfillJob"
                         }
                     }
                 }
-            }
-        },
-        "/compat/wakatime/v1/users/{user}": {
-            "get": {
+            },
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Mimics https://wakatime.com/developers#users",
+                "description": "Re-runs summary aggregation for the given users (or all users, if none are given) between two dates, both inclusive and interpreted in each user's time zone. Other than the nightly aggregation, existing summaries within the range are re-generated as well. Users are processed one after another in the background, progress can be tracked via /admin/aggregation/backfill.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "wakatime"
+                    "admin"
                 ],
-                "summary": "Retrieve the given user",
-                "operationId": "get-wakatime-user",
+                "summary": "Backfill summaries for a date range",
+                "operationId": "post-admin-aggregation-backfill",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User ID to fetch (or 'current')",
-                        "name": "user",
-                        "in": "path",
-                        "required": true
+                        "description": "Date range and users to backfill",
+                        "name": "backfill",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AggregationBackfillRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/v1.UserViewModel"
+                            "$ref": "#/definitions/models.AggregationBackfillJob"
                         }
+                    },
+                    "409": {
+                        "description": "Conflict"
                     }
                 }
             }
         },
-        "/compat/wakatime/v1/users/{user}/all_time_since_today": {
+        "/admin/block_rules": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Mimics https://wakatime.com/developers#all_time_since_today",
+                "description": "Includes the number of heartbeats dropped by each rule so far",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "wakatime"
+                    "admin"
                 ],
-                "summary": "Retrieve summary for all time",
-                "operationId": "get-all-time",
+                "summary": "Retrieve all instance-wide heartbeat block rules",
+                "operationId": "get-admin-block-rules",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.BlockRule"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Drops all incoming heartbeats of any user, whose project, language, entity or branch matches the given regular expression, before they are persisted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Create an instance-wide heartbeat block rule",
+                "operationId": "post-admin-block-rule",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User ID to fetch data for (or 'current')",
-                        "name": "user",
-                        "in": "path",
-                        "required": true
+                        "description": "Rule to create",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BlockRule"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/v1.AllTimeViewModel"
+                            "$ref": "#/definitions/models.BlockRule"
                         }
                     }
                 }
             }
         },
-        "/compat/wakatime/v1/users/{user}/heartbeats": {
-            "get": {
+        "/admin/block_rules/{id}": {
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
                 "tags": [
-                    "heartbeat"
+                    "admin"
                 ],
-                "summary": "Get heartbeats of user for specified date",
-                "operationId": "get-heartbeats",
+                "summary": "Delete an instance-wide heartbeat block rule",
+                "operationId": "delete-admin-block-rule",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Date",
-                        "name": "date",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Username (or current)",
-                        "name": "user",
+                        "type": "integer",
+                        "description": "Rule ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/admin/config/reload": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Reports whether the running config is in sync with the config file or whether the last reload failed and the previous config is still in use.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Retrieve the outcome of the most recent config reload",
+                "operationId": "get-admin-reload-config",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/v1.HeartbeatsResult"
-                        }
-                    },
-                    "400": {
-                        "description": "bad date",
-                        "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/config.ReloadStatus"
                         }
                     }
                 }
@@ -205,611 +215,610 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "consumes": [
+                "description": "Re-reads the config file and applies changes to custom languages, mail settings, rate limits, newsbox and cron schedules without a restart. Returns the changed config sections.",
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "heartbeat"
+                    "admin"
                 ],
-                "summary": "Push a new heartbeat",
-                "operationId": "post-heartbeat-3",
-                "parameters": [
-                    {
-                        "description": "A single heartbeat",
-                        "name": "heartbeat",
-                        "in": "body",
-                        "required": true,
+                "summary": "Reload the server config",
+                "operationId": "post-admin-reload-config",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Heartbeat"
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
                         }
-                    },
-                    {
-                        "type": "string",
-                        "description": "Username (or current)",
-                        "name": "user",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": ""
                     }
                 }
             }
         },
-        "/compat/wakatime/v1/users/{user}/heartbeats.bulk": {
-            "post": {
+        "/admin/corrections": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "consumes": [
+                "description": "Only available in org mode",
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "heartbeat"
+                    "admin"
                 ],
-                "summary": "Push new heartbeats",
-                "operationId": "post-heartbeat-7",
-                "parameters": [
-                    {
-                        "description": "Multiple heartbeats",
-                        "name": "heartbeat",
-                        "in": "body",
-                        "required": true,
+                "summary": "Retrieve the review queue of pending data corrections",
+                "operationId": "get-admin-corrections",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Heartbeat"
+                                "$ref": "#/definitions/models.DataCorrection"
                             }
                         }
-                    },
-                    {
-                        "type": "string",
-                        "description": "Username (or current)",
-                        "name": "user",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": ""
                     }
                 }
             }
         },
-        "/compat/wakatime/v1/users/{user}/projects": {
-            "get": {
+        "/admin/corrections/{id}/review": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Mimics https://wakatime.com/developers#projects",
+                "description": "Approved corrections are applied in the background, their status changes to \"applied\" once done. Admins can't review their own corrections. Only available in org mode.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "wakatime"
+                    "admin"
                 ],
-                "summary": "Retrieve and fitler the user's projects",
-                "operationId": "get-wakatime-projects",
+                "summary": "Approve or reject a data correction",
+                "operationId": "post-admin-review-correction",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User ID to fetch data for (or 'current')",
-                        "name": "user",
+                        "type": "integer",
+                        "description": "Correction ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Query to filter projects by",
-                        "name": "q",
-                        "in": "query",
-                        "required": true
+                        "description": "Decision",
+                        "name": "review",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.DataCorrectionReview"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/v1.ProjectsViewModel"
+                            "$ref": "#/definitions/models.DataCorrection"
                         }
                     }
                 }
             }
         },
-        "/compat/wakatime/v1/users/{user}/stats/{range}": {
+        "/admin/invitations": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Mimics https://wakatime.com/developers#stats",
+                "description": "Includes the number of times each invitation was used to sign up so far",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "wakatime"
+                    "admin"
                 ],
-                "summary": "Retrieve statistics for a given user",
-                "operationId": "get-wakatimes-tats",
-                "parameters": [
+                "summary": "Retrieve all invitations",
+                "operationId": "get-admin-invitations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Invitation"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
                     {
-                        "type": "string",
-                        "description": "User ID to fetch data for (or 'current')",
-                        "name": "user",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "enum": [
-                            "today",
-                            "yesterday",
-                            "week",
-                            "month",
-                            "year",
-                            "7_days",
-                            "last_7_days",
-                            "30_days",
-                            "last_30_days",
-                            "6_months",
-                            "last_6_months",
-                            "12_months",
-                            "last_12_months",
-                            "last_year",
-                            "any",
-                            "all_time"
-                        ],
-                        "type": "string",
-                        "description": "Range interval identifier",
-                        "name": "range",
-                        "in": "path"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Project to filter by",
-                        "name": "project",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Language to filter by",
-                        "name": "language",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Editor to filter by",
-                        "name": "editor",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "OS to filter by",
-                        "name": "operating_system",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Machine to filter by",
-                        "name": "machine",
-                        "in": "query"
-                    },
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Generates a new invitation code, that allows to sign up even if registration is disabled. Can be limited to a number of uses and expire after a number of days.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Create an invitation",
+                "operationId": "post-admin-invitation",
+                "parameters": [
                     {
-                        "type": "string",
-                        "description": "Project label to filter by",
-                        "name": "label",
-                        "in": "query"
+                        "description": "Invitation to create",
+                        "name": "invitation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.adminInvitationRequestVm"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/v1.StatsViewModel"
+                            "$ref": "#/definitions/models.Invitation"
                         }
                     }
                 }
             }
         },
-        "/compat/wakatime/v1/users/{user}/summaries": {
-            "get": {
+        "/admin/invitations/{id}": {
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Mimics https://wakatime.com/developers#summaries.",
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Revokes the invitation, accounts created with it are not affected",
                 "tags": [
-                    "wakatime"
+                    "admin"
                 ],
-                "summary": "Retrieve WakaTime-compatible summaries",
-                "operationId": "get-wakatime-summaries",
+                "summary": "Delete an invitation",
+                "operationId": "delete-admin-invitation",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User ID to fetch data for (or 'current')",
-                        "name": "user",
+                        "type": "integer",
+                        "description": "Invitation ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "enum": [
-                            "today",
-                            "yesterday",
-                            "week",
-                            "month",
-                            "year",
-                            "7_days",
-                            "last_7_days",
-                            "30_days",
-                            "last_30_days",
-                            "6_months",
-                            "last_6_months",
-                            "12_months",
-                            "last_12_months",
-                            "last_year",
-                            "any",
-                            "all_time"
-                        ],
-                        "type": "string",
-                        "description": "Range interval identifier",
-                        "name": "range",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Start date (e.g. '2021-02-07')",
-                        "name": "start",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "End date (e.g. '2021-02-08')",
-                        "name": "end",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Project to filter by",
-                        "name": "project",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Language to filter by",
-                        "name": "language",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Editor to filter by",
-                        "name": "editor",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "OS to filter by",
-                        "name": "operating_system",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Machine to filter by",
-                        "name": "machine",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Project label to filter by",
-                        "name": "label",
-                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/v1.SummariesViewModel"
-                        }
+                    "204": {
+                        "description": "No Content"
                     }
                 }
             }
         },
-        "/health": {
+        "/admin/lockouts": {
             "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "misc"
+                    "admin"
                 ],
-                "summary": "Check the application's health status",
-                "operationId": "get-health",
+                "summary": "Retrieve all accounts currently locked after too many failed login attempts",
+                "operationId": "get-admin-lockouts",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.LoginLockout"
+                            }
                         }
                     }
                 }
             }
         },
-        "/heartbeat": {
+        "/admin/mail/test": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "Sends a mail through the configured mail provider to check the mail setup. Errors reported by the provider are returned as-is.",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "heartbeat"
+                    "admin"
                 ],
-                "summary": "Push a new heartbeat",
-                "operationId": "post-heartbeat",
+                "summary": "Send a test mail",
+                "operationId": "post-admin-test-mail",
                 "parameters": [
                     {
-                        "description": "A single heartbeat",
-                        "name": "heartbeat",
+                        "description": "Recipient, defaults to the admin's own e-mail address",
+                        "name": "request",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Heartbeat"
+                            "$ref": "#/definitions/api.adminTestMailRequestVm"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": ""
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.adminTestMailResponseVm"
+                        }
                     }
                 }
             }
         },
-        "/heartbeats": {
-            "post": {
+        "/admin/plugins": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "consumes": [
+                "description": "Returns the number of distinct users per plugin- and wakatime-cli version among all heartbeats of the last days, e.g. to spot users stuck on broken client versions",
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "heartbeat"
+                    "admin"
                 ],
-                "summary": "Push new heartbeats",
-                "operationId": "post-heartbeat-5",
+                "summary": "Retrieve the distribution of plugin versions",
+                "operationId": "get-admin-plugins",
                 "parameters": [
                     {
-                        "description": "Multiple heartbeats",
-                        "name": "heartbeat",
-                        "in": "body",
-                        "required": true,
+                        "type": "integer",
+                        "description": "Number of days to look back (defaults to inactive_days)",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Heartbeat"
+                                "$ref": "#/definitions/models.PluginVersionStats"
                             }
                         }
                     }
+                }
+            }
+        },
+        "/admin/reports": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Retrieve the moderation queue of open abuse reports",
+                "operationId": "get-admin-reports",
                 "responses": {
-                    "201": {
-                        "description": ""
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.AbuseReport"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/plugins/errors": {
+        "/admin/reports/{id}/resolve": {
             "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Either hides the reported user (or their projects) from all public views, force-renames the reported user or project or dismisses the report",
                 "consumes": [
                     "application/json"
                 ],
                 "tags": [
-                    "diagnostics"
+                    "admin"
                 ],
-                "summary": "Push a new diagnostics object",
-                "operationId": "post-diagnostics",
+                "summary": "Resolve an abuse report",
+                "operationId": "post-admin-resolve-report",
                 "parameters": [
                     {
-                        "description": "A single diagnostics object sent by WakaTime CLI",
-                        "name": "diagnostics",
+                        "type": "integer",
+                        "description": "Report ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Moderation action",
+                        "name": "resolution",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Diagnostics"
+                            "$ref": "#/definitions/models.AbuseReportResolution"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": ""
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AbuseReport"
+                        }
                     }
                 }
             }
         },
-        "/relay": {
-            "get": {
+        "/admin/retention/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Resumes data cleanups after the retention period was shortened. The confirmed period must match the configured one, so that a config change in between is not confirmed inadvertently.",
+                "consumes": [
+                    "application/json"
+                ],
                 "tags": [
-                    "relay"
+                    "admin"
                 ],
-                "summary": "Proxy an GET API request to another Wakapi instance",
-                "operationId": "relay-get",
+                "summary": "Confirm the data retention period",
+                "operationId": "post-admin-retention-confirm",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Original URL to perform the request to",
-                        "name": "X-Target-URL",
-                        "in": "header",
-                        "required": true
+                        "description": "Retention period to confirm",
+                        "name": "confirmation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.adminRetentionConfirmRequestVm"
+                        }
                     }
                 ],
                 "responses": {
-                    "403": {
-                        "description": "Returned if request path is not whitelisted",
-                        "schema": {
-                            "type": "string"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
-                    "502": {
-                        "description": "Returned if upstream host is down",
-                        "schema": {
-                            "type": "string"
-                        }
+                    "409": {
+                        "description": "Conflict"
                     }
                 }
-            },
-            "put": {
-                "tags": [
-                    "relay"
-                ],
-                "summary": "Proxy an PUT API request to another Wakapi instance",
-                "operationId": "relay-put",
-                "parameters": [
+            }
+        },
+        "/admin/retention/impact": {
+            "get": {
+                "security": [
                     {
-                        "type": "string",
-                        "description": "Original URL to perform the request to",
-                        "name": "X-Target-URL",
-                        "in": "header",
-                        "required": true
+                        "ApiKeyAuth": []
                     }
                 ],
+                "description": "Lists the number of heartbeats and summaries per user, which the next data cleanup run will delete under the configured retention period, including those which would have been kept under the previously confirmed one. If the configured period was shortened, cleanups are paused until it is confirmed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Preview the impact of the data retention period",
+                "operationId": "get-admin-retention-impact",
                 "responses": {
-                    "403": {
-                        "description": "Returned if request path is not whitelisted",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.RetentionImpact"
                         }
-                    },
-                    "502": {
-                        "description": "Returned if upstream host is down",
+                    }
+                }
+            }
+        },
+        "/admin/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the total number of users, the number of active users and the number of users per activity class (daily, weekly and monthly active), as last computed by the hourly rollup job",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Retrieve user statistics",
+                "operationId": "get-admin-stats",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.adminStatsResponseVm"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/summaries/regenerate": {
             "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Drops all users' stored summaries within the given date range, invalidates cached ones and re-computes them from heartbeats in the background, e.g. after changing server-wide language mappings. Without from or to, the range spans from each user's first or until their last heartbeat respectively. Users with an aggregation already in progress are skipped.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "relay"
+                    "admin"
                 ],
-                "summary": "Proxy an POST API request to another Wakapi instance",
-                "operationId": "relay-post",
+                "summary": "Re-generate all users' summaries",
+                "operationId": "post-admin-summaries-regenerate",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Original URL to perform the request to",
-                        "name": "X-Target-URL",
-                        "in": "header",
-                        "required": true
+                        "description": "Start date (e.g. '2021-02-07')",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, inclusive (e.g. '2021-02-08')",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "403": {
-                        "description": "Returned if request path is not whitelisted",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "502": {
-                        "description": "Returned if upstream host is down",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.adminRegenerateResponseVm"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/users/{user}/lockout": {
             "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Also resets the account's failed login attempts",
                 "tags": [
-                    "relay"
+                    "admin"
                 ],
-                "summary": "Proxy an DELETE API request to another Wakapi instance",
-                "operationId": "relay-delete",
+                "summary": "Lift the login lockout of a user account",
+                "operationId": "delete-admin-lockout",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Original URL to perform the request to",
-                        "name": "X-Target-URL",
-                        "in": "header",
+                        "description": "Username",
+                        "name": "user",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "403": {
-                        "description": "Returned if request path is not whitelisted",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "502": {
-                        "description": "Returned if upstream host is down",
-                        "schema": {
-                            "type": "string"
-                        }
+                    "204": {
+                        "description": "No Content"
                     }
                 }
-            },
-            "patch": {
+            }
+        },
+        "/admin/users/{user}/suspend": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Freezes the account without deleting any data. Suspended users can neither log in nor push heartbeats.",
                 "tags": [
-                    "relay"
+                    "admin"
                 ],
-                "summary": "Proxy an PATCH API request to another Wakapi instance",
-                "operationId": "relay-patch",
+                "summary": "Suspend a user account",
+                "operationId": "post-admin-suspend",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Original URL to perform the request to",
-                        "name": "X-Target-URL",
-                        "in": "header",
+                        "description": "Username",
+                        "name": "user",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "403": {
-                        "description": "Returned if request path is not whitelisted",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "502": {
-                        "description": "Returned if upstream host is down",
-                        "schema": {
-                            "type": "string"
-                        }
+                    "200": {
+                        "description": "OK"
                     }
                 }
             }
         },
-        "/summary": {
-            "get": {
+        "/admin/users/{user}/unsuspend": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Lift the suspension of a user account",
+                "operationId": "post-admin-unsuspend",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/compat/shields/v1/{user}/{interval}/{filter}": {
+            "get": {
+                "description": "Retrieve total time for a given entity (e.g. a project) within a given range (e.g. one week) in a format compatible with [Shields.io](https://shields.io/endpoint). Requires public data access to be allowed.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "summary"
+                    "badges"
                 ],
-                "summary": "Retrieve a summary",
-                "operationId": "get-summary",
+                "summary": "Get badge data",
+                "operationId": "get-badge",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID to fetch data for",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "enum": [
                             "today",
@@ -830,76 +839,142 @@ const docTemplate = `{
                             "all_time"
                         ],
                         "type": "string",
-                        "description": "Interval identifier",
+                        "description": "Interval to aggregate data for",
                         "name": "interval",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Start date (e.g. '2021-02-07')",
-                        "name": "from",
-                        "in": "query"
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "End date (e.g. '2021-02-08')",
-                        "name": "to",
-                        "in": "query"
-                    },
+                        "description": "Filter to apply (e.g. 'project:wakapi', 'language:Go' or 'filter:\u003csaved filter name\u003e')",
+                        "name": "filter",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.BadgeData"
+                        }
+                    }
+                }
+            }
+        },
+        "/compat/wakatime/v1/users/{user}": {
+            "get": {
+                "security": [
                     {
-                        "type": "boolean",
-                        "description": "Whether to recompute the summary from raw heartbeat or use cache",
-                        "name": "recompute",
-                        "in": "query"
-                    },
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Mimics https://wakatime.com/developers#users",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wakatime"
+                ],
+                "summary": "Retrieve the given user",
+                "operationId": "get-wakatime-user",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Project to filter by",
-                        "name": "project",
-                        "in": "query"
-                    },
+                        "description": "User ID to fetch (or 'current')",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.UserViewModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/compat/wakatime/v1/users/{user}/all_time_since_today": {
+            "get": {
+                "security": [
                     {
-                        "type": "string",
-                        "description": "Language to filter by",
-                        "name": "language",
-                        "in": "query"
-                    },
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Mimics https://wakatime.com/developers#all_time_since_today",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wakatime"
+                ],
+                "summary": "Retrieve summary for all time",
+                "operationId": "get-all-time",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Editor to filter by",
-                        "name": "editor",
-                        "in": "query"
-                    },
+                        "description": "User ID to fetch data for (or 'current')",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.AllTimeViewModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/compat/wakatime/v1/users/{user}/heartbeats": {
+            "get": {
+                "security": [
                     {
-                        "type": "string",
-                        "description": "OS to filter by",
-                        "name": "operating_system",
-                        "in": "query"
-                    },
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "heartbeat"
+                ],
+                "summary": "Get heartbeats of user for specified date",
+                "operationId": "get-heartbeats",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Machine to filter by",
-                        "name": "machine",
-                        "in": "query"
+                        "description": "Date",
+                        "name": "date",
+                        "in": "query",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Project label to filter by",
-                        "name": "label",
-                        "in": "query"
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Summary"
+                            "$ref": "#/definitions/v1.HeartbeatsResult"
+                        }
+                    },
+                    "400": {
+                        "description": "bad date",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
-            }
-        },
-        "/users/{user}/heartbeats": {
+            },
             "post": {
                 "security": [
                     {
@@ -913,7 +988,7 @@ const docTemplate = `{
                     "heartbeat"
                 ],
                 "summary": "Push a new heartbeat",
-                "operationId": "post-heartbeat-4",
+                "operationId": "post-heartbeat-3",
                 "parameters": [
                     {
                         "description": "A single heartbeat",
@@ -934,12 +1009,12 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "201": {
-                        "description": ""
+                        "description": "Created"
                     }
                 }
             }
         },
-        "/users/{user}/heartbeats.bulk": {
+        "/compat/wakatime/v1/users/{user}/heartbeats.bulk": {
             "post": {
                 "security": [
                     {
@@ -953,7 +1028,7 @@ const docTemplate = `{
                     "heartbeat"
                 ],
                 "summary": "Push new heartbeats",
-                "operationId": "post-heartbeat-8",
+                "operationId": "post-heartbeat-7",
                 "parameters": [
                     {
                         "description": "Multiple heartbeats",
@@ -977,27 +1052,27 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "201": {
-                        "description": ""
+                        "description": "Created"
                     }
                 }
             }
         },
-        "/users/{user}/statusbar/today": {
+        "/compat/wakatime/v1/users/{user}/orgs": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Mimics https://wakatime.com/api/v1/users/current/statusbar/today. Have no official documentation",
+                "description": "Mimics https://wakatime.com/developers#orgs. Only available in org mode, where the instance itself is the only organization.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "wakatime"
                 ],
-                "summary": "Retrieve summary for statusbar",
-                "operationId": "statusbar",
+                "summary": "List the user's organizations",
+                "operationId": "get-wakatime-orgs",
                 "parameters": [
                     {
                         "type": "string",
@@ -1011,244 +1086,6006 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/v1.StatusBarViewModel"
+                            "$ref": "#/definitions/v1.OrgsViewModel"
                         }
                     }
                 }
             }
         },
-        "/v1/users/{user}/heartbeats": {
-            "post": {
+        "/compat/wakatime/v1/users/{user}/orgs/{org}/dashboards": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "consumes": [
+                "description": "Mimics https://wakatime.com/developers#org_dashboards. There is a single dashboard, which is only visible to admins.",
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "heartbeat"
+                    "wakatime"
                 ],
-                "summary": "Push a new heartbeat",
-                "operationId": "post-heartbeat-2",
+                "summary": "List the organization's dashboards",
+                "operationId": "get-wakatime-org-dashboards",
                 "parameters": [
                     {
-                        "description": "A single heartbeat",
-                        "name": "heartbeat",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Heartbeat"
-                        }
+                        "type": "string",
+                        "description": "User ID to fetch data for (or 'current')",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Username (or current)",
-                        "name": "user",
+                        "description": "Organization ID",
+                        "name": "org",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": ""
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.DashboardsViewModel"
+                        }
                     }
                 }
             }
         },
-        "/v1/users/{user}/heartbeats.bulk": {
-            "post": {
+        "/compat/wakatime/v1/users/{user}/orgs/{org}/dashboards/{dashboard}/members": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "consumes": [
+                "description": "Mimics https://wakatime.com/developers#org_dashboard_members. Requires admin permissions.",
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "heartbeat"
+                    "wakatime"
                 ],
-                "summary": "Push new heartbeats",
-                "operationId": "post-heartbeat-6",
+                "summary": "List the dashboard's members",
+                "operationId": "get-wakatime-org-dashboard-members",
                 "parameters": [
                     {
-                        "description": "Multiple heartbeats",
-                        "name": "heartbeat",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "User ID to fetch data for (or 'current')",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "org",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Dashboard ID",
+                        "name": "dashboard",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (starting at 1)",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Heartbeat"
-                            }
+                            "$ref": "#/definitions/v1.DashboardMembersViewModel"
                         }
-                    },
+                    }
+                }
+            }
+        },
+        "/compat/wakatime/v1/users/{user}/orgs/{org}/dashboards/{dashboard}/members/{member}/durations": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Mimics https://wakatime.com/developers#org_dashboard_member_durations. Requires admin permissions.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wakatime"
+                ],
+                "summary": "Retrieve a dashboard member's coding activity on the given day",
+                "operationId": "get-wakatime-org-dashboard-member-durations",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Username (or current)",
+                        "description": "User ID to fetch data for (or 'current')",
                         "name": "user",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "org",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Dashboard ID",
+                        "name": "dashboard",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Member's user ID",
+                        "name": "member",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Day to retrieve durations for (e.g. '2021-02-07'), in the member's time zone",
+                        "name": "date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project to filter by",
+                        "name": "project",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": ""
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.DurationsViewModel"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.Diagnostics": {
-            "type": "object",
-            "properties": {
-                "architecture": {
-                    "type": "string"
-                },
+        },
+        "/compat/wakatime/v1/users/{user}/projects": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Mimics https://wakatime.com/developers#projects. Projects are sorted by their latest heartbeat, most recent first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wakatime"
+                ],
+                "summary": "Retrieve and filter the user's projects",
+                "operationId": "get-wakatime-projects",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID to fetch data for (or 'current')",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Query to filter projects by (case-insensitive substring of the project name)",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (starting at 1)",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ProjectsViewModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/compat/wakatime/v1/users/{user}/stats/{range}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Mimics https://wakatime.com/developers#stats",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wakatime"
+                ],
+                "summary": "Retrieve statistics for a given user",
+                "operationId": "get-wakatimes-tats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID to fetch data for (or 'current')",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "today",
+                            "yesterday",
+                            "week",
+                            "month",
+                            "year",
+                            "7_days",
+                            "last_7_days",
+                            "30_days",
+                            "last_30_days",
+                            "6_months",
+                            "last_6_months",
+                            "12_months",
+                            "last_12_months",
+                            "last_year",
+                            "any",
+                            "all_time"
+                        ],
+                        "type": "string",
+                        "description": "Range interval identifier",
+                        "name": "range",
+                        "in": "path"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project to filter by",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language to filter by",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Editor to filter by",
+                        "name": "editor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OS to filter by",
+                        "name": "operating_system",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Machine to filter by",
+                        "name": "machine",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project label to filter by",
+                        "name": "label",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.StatsViewModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/compat/wakatime/v1/users/{user}/summaries": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Mimics https://wakatime.com/developers#summaries.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wakatime"
+                ],
+                "summary": "Retrieve WakaTime-compatible summaries",
+                "operationId": "get-wakatime-summaries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID to fetch data for (or 'current')",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "today",
+                            "yesterday",
+                            "week",
+                            "month",
+                            "year",
+                            "7_days",
+                            "last_7_days",
+                            "30_days",
+                            "last_30_days",
+                            "6_months",
+                            "last_6_months",
+                            "12_months",
+                            "last_12_months",
+                            "last_year",
+                            "any",
+                            "all_time"
+                        ],
+                        "type": "string",
+                        "description": "Range interval identifier",
+                        "name": "range",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (e.g. '2021-02-07')",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date (e.g. '2021-02-08')",
+                        "name": "end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project to filter by",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language to filter by",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Editor to filter by",
+                        "name": "editor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OS to filter by",
+                        "name": "operating_system",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Machine to filter by",
+                        "name": "machine",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project label to filter by",
+                        "name": "label",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.SummariesViewModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/compat/wakatime/v1/users/{user}/user_agents": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Mimics https://wakatime.com/developers#user_agents",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wakatime"
+                ],
+                "summary": "Retrieve the user's user agents",
+                "operationId": "get-wakatime-user-agents",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID to fetch data for (or 'current')",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.UserAgentsViewModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/corrections": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Only available in org mode",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "correction"
+                ],
+                "summary": "Retrieve the current user's data corrections",
+                "operationId": "get-corrections",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.DataCorrection"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Requests to move the current user's coding time within the given time range from one project to another. Only takes effect once approved by an admin. Only available in org mode.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "correction"
+                ],
+                "summary": "Submit a data correction for review",
+                "operationId": "post-correction",
+                "parameters": [
+                    {
+                        "description": "Correction",
+                        "name": "correction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.DataCorrectionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.DataCorrection"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "misc"
+                ],
+                "summary": "Check the application's health status",
+                "operationId": "get-health",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/heartbeat": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Responds with a status per heartbeat, i.e. 201 if it was stored, 400 if it was invalid or 409 if it was a duplicate, so clients can retry failed ones only. The request as a whole only fails if none of the heartbeats was valid.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "heartbeat"
+                ],
+                "summary": "Push a new heartbeat",
+                "operationId": "post-heartbeat",
+                "parameters": [
+                    {
+                        "description": "A single heartbeat",
+                        "name": "heartbeat",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Heartbeat"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.heartbeatResponseVm"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.heartbeatResponseVm"
+                        }
+                    }
+                }
+            }
+        },
+        "/heartbeats": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "heartbeat"
+                ],
+                "summary": "Push new heartbeats",
+                "operationId": "post-heartbeat-5",
+                "parameters": [
+                    {
+                        "description": "Multiple heartbeats",
+                        "name": "heartbeat",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Heartbeat"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    }
+                }
+            }
+        },
+        "/homeassistant": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Flat response, whose fields can be mapped to sensor states and attributes one by one. Best used with a read-only access token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "homeassistant"
+                ],
+                "summary": "Retrieve today's coding time and what the user is currently working on for home assistant rest sensors",
+                "operationId": "get-homeassistant",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.HomeAssistantViewModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/mail/ses/notifications": {
+            "post": {
+                "description": "Endpoint for an sns subscription to the topic configured as mail.ses.notification_topic_arn. Recipients of permanent bounces and complaints have their e-mail address marked as unverified and their reports disabled.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "misc"
+                ],
+                "summary": "Receive amazon ses bounce and complaint notifications",
+                "operationId": "post-mail-ses-notification",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/oauth/device/code": {
+            "post": {
+                "description": "See https://datatracker.ietf.org/doc/html/rfc8628#section-3.1. Available scopes are \"read_stats\" and \"write_heartbeats\", defaults to all.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Initiate a device authorization request",
+                "operationId": "post-oauth-device-code",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the client application",
+                        "name": "client_id",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Space-separated list of requested scopes",
+                        "name": "scope",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DevicePairing"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth/token": {
+            "post": {
+                "description": "See https://datatracker.ietf.org/doc/html/rfc8628#section-3.4. Responds with 400 and error \"authorization_pending\" until the user has approved the request, \"expired_token\" once the device code has expired and \"invalid_grant\" for unknown device codes.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Poll for an access token",
+                "operationId": "post-oauth-token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Must be urn:ietf:params:oauth:grant-type:device_code",
+                        "name": "grant_type",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Device code",
+                        "name": "device_code",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the client application",
+                        "name": "client_id",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AccessTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.AccessTokenErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/openapi.json": {
+            "get": {
+                "description": "Covers both the native and the compat (wakatime, shields) apis, e.g. for generating clients",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "misc"
+                ],
+                "summary": "Retrieve the api's openapi 3 specification",
+                "operationId": "get-openapi",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/plugins/errors": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "diagnostics"
+                ],
+                "summary": "Push a new diagnostics object",
+                "operationId": "post-diagnostics",
+                "parameters": [
+                    {
+                        "description": "A single diagnostics object sent by WakaTime CLI",
+                        "name": "diagnostics",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Diagnostics"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    }
+                }
+            }
+        },
+        "/presence/{user}": {
+            "get": {
+                "description": "Same as /users/{user}/presence, e.g. for streaming overlays or team pages, but only available if the user opted in to share their presence and only including the project, language and editor if they share these respectively",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "presence"
+                ],
+                "summary": "Retrieve what a user is currently working on, publicly",
+                "operationId": "get-presence-public",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Presence"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    }
+                }
+            }
+        },
+        "/relay": {
+            "get": {
+                "tags": [
+                    "relay"
+                ],
+                "summary": "Proxy an GET API request to another Wakapi instance",
+                "operationId": "relay-get",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Original URL to perform the request to",
+                        "name": "X-Target-URL",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "403": {
+                        "description": "Returned if request path is not whitelisted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "Returned if upstream host is down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "tags": [
+                    "relay"
+                ],
+                "summary": "Proxy an PUT API request to another Wakapi instance",
+                "operationId": "relay-put",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Original URL to perform the request to",
+                        "name": "X-Target-URL",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "403": {
+                        "description": "Returned if request path is not whitelisted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "Returned if upstream host is down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "tags": [
+                    "relay"
+                ],
+                "summary": "Proxy an POST API request to another Wakapi instance",
+                "operationId": "relay-post",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Original URL to perform the request to",
+                        "name": "X-Target-URL",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "403": {
+                        "description": "Returned if request path is not whitelisted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "Returned if upstream host is down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "relay"
+                ],
+                "summary": "Proxy an DELETE API request to another Wakapi instance",
+                "operationId": "relay-delete",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Original URL to perform the request to",
+                        "name": "X-Target-URL",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "403": {
+                        "description": "Returned if request path is not whitelisted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "Returned if upstream host is down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "tags": [
+                    "relay"
+                ],
+                "summary": "Proxy an PATCH API request to another Wakapi instance",
+                "operationId": "relay-patch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Original URL to perform the request to",
+                        "name": "X-Target-URL",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "403": {
+                        "description": "Returned if request path is not whitelisted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "Returned if upstream host is down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "moderation"
+                ],
+                "summary": "Report an offensive username or project name",
+                "operationId": "post-abuse-report",
+                "parameters": [
+                    {
+                        "description": "Report",
+                        "name": "report",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AbuseReportRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    }
+                }
+            }
+        },
+        "/sandbox/heartbeats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandbox"
+                ],
+                "summary": "Retrieve the results of recent sandbox heartbeats",
+                "operationId": "get-sandbox-heartbeats",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.sandboxResultsViewModel"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Validates and processes heartbeats just like the regular endpoint, but keeps them apart from real ones, so they never affect any statistics. Responds with details on how each heartbeat was processed, in the same format as the regular endpoint (list of [result, status] pairs). Sandbox heartbeats are purged nightly.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sandbox"
+                ],
+                "summary": "Push heartbeats to the sandbox",
+                "operationId": "post-sandbox-heartbeats",
+                "parameters": [
+                    {
+                        "description": "One or multiple heartbeats",
+                        "name": "heartbeat",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Heartbeat"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.heartbeatResponseVm"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "sandbox"
+                ],
+                "summary": "Delete all of the user's sandbox heartbeats",
+                "operationId": "delete-sandbox-heartbeats",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/stats/public": {
+            "get": {
+                "description": "Total number of users, total coding hours and the most used languages of the past 30 days across all users of this instance, e.g. for status pages. Only available if enabled by the instance's operator.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "misc"
+                ],
+                "summary": "Retrieve anonymous, instance-wide statistics",
+                "operationId": "get-public-stats",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PublicStats"
+                        }
+                    }
+                }
+            }
+        },
+        "/summary": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "summary"
+                ],
+                "summary": "Retrieve a summary",
+                "operationId": "get-summary",
+                "parameters": [
+                    {
+                        "enum": [
+                            "today",
+                            "yesterday",
+                            "week",
+                            "month",
+                            "year",
+                            "7_days",
+                            "last_7_days",
+                            "30_days",
+                            "last_30_days",
+                            "6_months",
+                            "last_6_months",
+                            "12_months",
+                            "last_12_months",
+                            "last_year",
+                            "any",
+                            "all_time"
+                        ],
+                        "type": "string",
+                        "description": "Interval identifier",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (e.g. '2021-02-07')",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date (e.g. '2021-02-08')",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Whether to recompute the summary from raw heartbeat or use cache, requires the 'write_heartbeats' scope when authenticating by access token",
+                        "name": "recompute",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project to filter by",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language to filter by",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Editor to filter by",
+                        "name": "editor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OS to filter by",
+                        "name": "operating_system",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Machine to filter by",
+                        "name": "machine",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project label to filter by",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client to filter by",
+                        "name": "client",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of a saved filter to apply",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Whether to include the user's annotations within the interval",
+                        "name": "annotations",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Whether to include the user's project milestones within the interval",
+                        "name": "milestones",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Whether to include time coded within and outside the user's working hours, only for users with a working schedule and intervals of up to 31 days",
+                        "name": "working_hours",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Whether to include the colors of the summary's languages, as shown on the dashboard (derived ones for languages without a predefined color)",
+                        "name": "colors",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Summary"
+                        }
+                    }
+                }
+            }
+        },
+        "/summary/compare": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves the summary of the given interval along with the one of the same interval, shifted back in time by offset periods (e.g. this week so far vs. the same days of last week), plus per-dimension deltas (in seconds)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "summary"
+                ],
+                "summary": "Compare a summary to the one of a previous period",
+                "operationId": "get-summary-compare",
+                "parameters": [
+                    {
+                        "enum": [
+                            "today",
+                            "yesterday",
+                            "week",
+                            "month",
+                            "year",
+                            "7_days",
+                            "last_7_days",
+                            "30_days",
+                            "last_30_days",
+                            "6_months",
+                            "last_6_months",
+                            "12_months",
+                            "last_12_months",
+                            "last_year"
+                        ],
+                        "type": "string",
+                        "description": "Interval identifier",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Number of periods to go back for the comparison (e.g. 52 to compare a week to the same one last year)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (e.g. '2021-02-07'), if no interval is given, the previous period is the one of equal length right before",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date (e.g. '2021-02-08')",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project to filter by",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language to filter by",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Editor to filter by",
+                        "name": "editor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OS to filter by",
+                        "name": "operating_system",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Machine to filter by",
+                        "name": "machine",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project label to filter by",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client to filter by",
+                        "name": "client",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of a saved filter to apply",
+                        "name": "filter",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SummaryComparison"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    }
+                }
+            }
+        },
+        "/summary/trends": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Rolling 7-day averages and the distribution across weekdays of the past weeks' coding time, plus the hours to expect by the end of the current week and month, projected from the average of each remaining weekday. All times are in hours.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "summary"
+                ],
+                "summary": "Retrieve trends and projections",
+                "operationId": "get-summary-trends",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 8,
+                        "description": "Number of past weeks to consider (5 to 26)",
+                        "name": "weeks",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserTrends"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    }
+                }
+            }
+        },
+        "/summary/xlsx": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Exports the summary as an xlsx workbook, containing one sheet each for projects, languages and days",
+                "produces": [
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "summary"
+                ],
+                "summary": "Export a summary as excel workbook",
+                "operationId": "get-summary-xlsx",
+                "parameters": [
+                    {
+                        "enum": [
+                            "today",
+                            "yesterday",
+                            "week",
+                            "month",
+                            "year",
+                            "7_days",
+                            "last_7_days",
+                            "30_days",
+                            "last_30_days",
+                            "6_months",
+                            "last_6_months",
+                            "12_months",
+                            "last_12_months",
+                            "last_year",
+                            "any",
+                            "all_time"
+                        ],
+                        "type": "string",
+                        "description": "Interval identifier",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (e.g. '2021-02-07')",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date (e.g. '2021-02-08')",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project to filter by",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language to filter by",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Editor to filter by",
+                        "name": "editor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OS to filter by",
+                        "name": "operating_system",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Machine to filter by",
+                        "name": "machine",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project label to filter by",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client to filter by",
+                        "name": "client",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of a saved filter to apply",
+                        "name": "filter",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    }
+                }
+            }
+        },
+        "/tray": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Optimized for frequent polling. Supports long-polling by passing the previous response's ETag as If-None-Match header along with a wait parameter, in which case the request blocks until new data arrives or the timeout is reached (304).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tray"
+                ],
+                "summary": "Retrieve today's coding time, current project and goal progress for companion apps",
+                "operationId": "get-tray",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of seconds to wait for changes",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.TrayViewModel"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    }
+                }
+            }
+        },
+        "/tray/pair": {
+            "post": {
+                "description": "Shorthand for a device authorization request (see /oauth/device/code) with read-only scope. Returns a device code for polling and a short user code, which the user has to enter at the verification uri",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tray"
+                ],
+                "summary": "Initiate the pairing of a companion app",
+                "operationId": "post-tray-pair",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.DevicePairing"
+                        }
+                    }
+                }
+            }
+        },
+        "/tray/pair/token": {
+            "post": {
+                "description": "Responds with 400 and error \"authorization_pending\" until the user has confirmed the pairing",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tray"
+                ],
+                "summary": "Poll for the result of a pairing request",
+                "operationId": "post-tray-pair-token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device code",
+                        "name": "device_code",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AccessTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.AccessTokenErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/trends/languages": {
+            "get": {
+                "description": "Time spent in each language across all users of this instance, per day or month (for intervals longer than two months). Languages used by only few users are omitted. Only available if enabled by the instance's operator.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "trends"
+                ],
+                "summary": "Retrieve instance-wide language trends",
+                "operationId": "get-trends-languages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "12_months",
+                        "description": "Interval to get trends for",
+                        "name": "interval",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LanguageTrends"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    }
+                }
+            }
+        },
+        "/users/{user}/achievements": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Achievements (e.g. 100 hours of coding in total or a 30-day streak) are evaluated after summaries were aggregated, so newly reached ones might show up with a delay.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "achievements"
+                ],
+                "summary": "Retrieve a user's unlocked achievements",
+                "operationId": "get-achievements",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Achievement"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/annotations": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "annotations"
+                ],
+                "summary": "Retrieve a user's annotations",
+                "operationId": "get-annotations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "today",
+                            "yesterday",
+                            "week",
+                            "month",
+                            "year",
+                            "7_days",
+                            "last_7_days",
+                            "30_days",
+                            "last_30_days",
+                            "6_months",
+                            "last_6_months",
+                            "12_months",
+                            "last_12_months",
+                            "last_year",
+                            "any",
+                            "all_time"
+                        ],
+                        "type": "string",
+                        "description": "Interval identifier",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (e.g. '2021-02-07')",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date (e.g. '2021-02-08')",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Annotation"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Attaches a note to a day (in the user's time zone) and, optionally, to a single project. Annotations are returned alongside summaries and shown in reports.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "annotations"
+                ],
+                "summary": "Add an annotation",
+                "operationId": "post-annotation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Day (yyyy-mm-dd), text and optional project",
+                        "name": "annotation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Annotation"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Annotation"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/annotations/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "annotations"
+                ],
+                "summary": "Delete an annotation",
+                "operationId": "delete-annotation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Annotation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/users/{user}/billing/projects": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Retrieve a user's billable projects",
+                "operationId": "get-billable-projects",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.BillableProject"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Assigns a project to a client and sets its hourly rate, replacing any previous settings for the same project. All projects of a client must share the same currency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Mark a project as billable",
+                "operationId": "put-billable-project",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Project, client, hourly rate and currency (e.g. EUR)",
+                        "name": "project",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BillableProject"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BillableProject"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Unmark a project as billable",
+                "operationId": "delete-billable-project",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project name",
+                        "name": "project",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/users/{user}/billing/report": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Breaks down the time coded in billable projects by client and calendar month, along with the resulting amounts, e.g. to write invoices from. Available as json, csv (one row per client, month and project) or pdf.",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/pdf"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Retrieve a user's billable hours",
+                "operationId": "get-billing-report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "today",
+                            "yesterday",
+                            "week",
+                            "last_week",
+                            "month",
+                            "last_month",
+                            "year",
+                            "6_months",
+                            "last_6_months",
+                            "12_months",
+                            "last_12_months",
+                            "last_year",
+                            "any",
+                            "all_time"
+                        ],
+                        "type": "string",
+                        "default": "last_month",
+                        "description": "Interval identifier",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (e.g. '2021-02-07'), takes precedence over interval",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date (e.g. '2021-02-08')",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv",
+                            "pdf"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Output format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BillingReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/clients/outdated": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Compares the wakatime-cli and plugin versions of a user's heartbeats of the last days against the latest known releases. Severely outdated clients are known to cause data quality issues.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "clients"
+                ],
+                "summary": "Check a user's recently used clients for being outdated",
+                "operationId": "get-outdated-clients",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.outdatedClientsResponseVm"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the export archive (zip), if ready, or the current export status otherwise",
+                "produces": [
+                    "application/json",
+                    "application/zip"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Retrieve the user's data export",
+                "operationId": "get-export",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserExport"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Generates a machine-readable archive containing the user's profile, settings, heartbeats, summaries, aliases and labels asynchronously. The archive can be downloaded until it expires (72 hours by default).",
+                "tags": [
+                    "user"
+                ],
+                "summary": "Request a new export of all the user's data",
+                "operationId": "post-export",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    }
+                }
+            }
+        },
+        "/users/{user}/export/target": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the user's own s3 bucket, to which their data is dumped every day, along with the status of the latest dump and a link to download it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Retrieve the user's export target",
+                "operationId": "get-export-target",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.exportTargetViewModel"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Configures an s3 bucket owned by the user, to which an archive of all their data is uploaded every day, starting right away. Dumps are encrypted at rest (SSE-S3) and tagged with the given retention period, for the bucket's lifecycle rules to match on.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Set the user's export target",
+                "operationId": "put-export-target",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Bucket and credentials",
+                        "name": "target",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ExportTargetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExportTarget"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stops dumping data to the user's bucket, previous dumps are left untouched",
+                "tags": [
+                    "user"
+                ],
+                "summary": "Remove the user's export target",
+                "operationId": "delete-export-target",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/users/{user}/filters": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "filters"
+                ],
+                "summary": "Retrieve a user's saved filters",
+                "operationId": "get-saved-filters",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SavedFilter"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Persists a named set of filter parameters (e.g. 'project=wakapi,anchr\u0026branch!=main'), which can then be applied to summaries using the 'filter' query parameter (e.g. '?interval=week\u0026filter=work') and to badges using a 'filter:\u003cname\u003e' path segment",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "filters"
+                ],
+                "summary": "Save a filter",
+                "operationId": "post-saved-filter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Name (letters, digits, dashes and underscores) and query",
+                        "name": "filter",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SavedFilter"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.SavedFilter"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/filters/{name}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "filters"
+                ],
+                "summary": "Retrieve a saved filter",
+                "operationId": "get-saved-filter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SavedFilter"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "filters"
+                ],
+                "summary": "Delete a saved filter",
+                "operationId": "delete-saved-filter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/users/{user}/heartbeats": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "heartbeat"
+                ],
+                "summary": "Push a new heartbeat",
+                "operationId": "post-heartbeat-4",
+                "parameters": [
+                    {
+                        "description": "A single heartbeat",
+                        "name": "heartbeat",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Heartbeat"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes all heartbeats within the given time range, optionally filtered by project, language, etc. and an entity pattern (\"*\" as wildcard), and re-generates all affected summaries\nWith dry_run set, only the number of heartbeats that would be deleted is returned, e.g. to have the user confirm it first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "heartbeat"
+                ],
+                "summary": "Delete all heartbeats matching the given filters",
+                "operationId": "delete-heartbeats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (e.g. '2021-02-07')",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date (e.g. '2021-02-08')",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project to filter by",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language to filter by",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Editor to filter by",
+                        "name": "editor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OS to filter by",
+                        "name": "operating_system",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Machine to filter by",
+                        "name": "machine",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Branch to filter by",
+                        "name": "branch",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Entity (e.g. file path) pattern to filter by, use '*' as wildcard",
+                        "name": "entity_pattern",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Whether to only count the matching heartbeats instead of deleting them",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.heartbeatDeleteResponseVm"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/heartbeats.bulk": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "heartbeat"
+                ],
+                "summary": "Push new heartbeats",
+                "operationId": "post-heartbeat-8",
+                "parameters": [
+                    {
+                        "description": "Multiple heartbeats",
+                        "name": "heartbeat",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Heartbeat"
+                            }
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    }
+                }
+            }
+        },
+        "/users/{user}/heartbeats/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes the heartbeat and re-generates the summary of the day it belongs to",
+                "tags": [
+                    "heartbeat"
+                ],
+                "summary": "Delete a single heartbeat",
+                "operationId": "delete-heartbeat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Heartbeat ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Changes the project, language and / or branch of the heartbeat and re-generates the summary of the day it belongs to",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "heartbeat"
+                ],
+                "summary": "Edit a single heartbeat",
+                "operationId": "patch-heartbeat",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Heartbeat ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "heartbeat",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.heartbeatUpdateRequestVm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Heartbeat"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/machines": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists every machine along with when, with which operating system and from which ip address (and location, if enabled) it was last seen, most recently seen first. Helps to notice an api key being used from unexpected places.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Retrieve the machines a user sent heartbeats from",
+                "operationId": "get-machines",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Machine"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/milestones": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "milestones"
+                ],
+                "summary": "Retrieve a user's milestones",
+                "operationId": "get-milestones",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "today",
+                            "yesterday",
+                            "week",
+                            "month",
+                            "year",
+                            "7_days",
+                            "last_7_days",
+                            "30_days",
+                            "last_30_days",
+                            "6_months",
+                            "last_6_months",
+                            "12_months",
+                            "last_12_months",
+                            "last_year",
+                            "any",
+                            "all_time"
+                        ],
+                        "type": "string",
+                        "description": "Interval identifier",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (e.g. '2021-02-07')",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date (e.g. '2021-02-08')",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Project to filter by",
+                        "name": "project",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Milestone"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Marks a notable event of a project (e.g. a release) on a day (in the user's time zone). Milestones can be overlaid on summaries to correlate them with activity.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "milestones"
+                ],
+                "summary": "Add a milestone",
+                "operationId": "post-milestone",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Day (yyyy-mm-dd), project and name",
+                        "name": "milestone",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Milestone"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Milestone"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/milestones/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "milestones"
+                ],
+                "summary": "Delete a milestone",
+                "operationId": "delete-milestone",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Milestone ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/users/{user}/presence": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Tells whether the user is currently active, i.e. sent a heartbeat within the last 15 minutes, and if so, on which project, language and editor and since when without a break of more than 15 minutes",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "presence"
+                ],
+                "summary": "Retrieve what a user is currently working on",
+                "operationId": "get-presence",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Presence"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/projects/merge": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Moves all heartbeats of the source projects to the target project and re-generates summaries in the background. Progress can be tracked via /users/{user}/projects/rewrite.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "summary": "Merge several projects into one",
+                "operationId": "post-project-merge",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Source projects and target project",
+                        "name": "merge",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ProjectMergeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    }
+                }
+            }
+        },
+        "/users/{user}/projects/rename": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Moves all heartbeats of a project to a new project name and re-generates summaries in the background. Progress can be tracked via /users/{user}/projects/rewrite.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "summary": "Rename a project",
+                "operationId": "post-project-rename",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Old and new project name",
+                        "name": "rename",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ProjectRenameRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    }
+                }
+            }
+        },
+        "/users/{user}/projects/rewrite": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "project"
+                ],
+                "summary": "Retrieve the progress of the latest project rename or merge",
+                "operationId": "get-project-rewrite",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProjectRewriteJob"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/reports": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Generates a one-off report for the given interval, filters and groupings. Unless requested to be generated asynchronously, the report is returned either as json or rendered as html, using the same template as the weekly e-mail reports. Asynchronously generated reports are sent to the user's e-mail address instead.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/html"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Generate a custom report",
+                "operationId": "post-report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Report definition",
+                        "name": "report",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReportDefinition"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Report"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted"
+                    }
+                }
+            }
+        },
+        "/users/{user}/settings/clients": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Retrieve a user's clients",
+                "operationId": "get-clients",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Client"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Groups the given projects under a new client (customer). Time per client is included in summaries and can be filtered by using the client query parameter. Projects previously assigned to another client are moved to the new one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Add a client",
+                "operationId": "post-client",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Name and projects",
+                        "name": "client",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Client"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Client"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/settings/clients/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Renames a client and replaces its projects",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Update a client",
+                "operationId": "put-client",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Client ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Name and projects",
+                        "name": "client",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Client"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Client"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes a client, its projects remain untouched",
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Delete a client",
+                "operationId": "delete-client",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Client ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/users/{user}/settings/label_rules": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Retrieve a user's automatic labeling rules",
+                "operationId": "get-label-rules",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.LabelRule"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Assigns the given label to every project, for which a newly received heartbeat's project name, entity (file path) or branch matches the given regular expression. Use /users/{user}/settings/label_rules/backfill to apply rules to existing data as well.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Add an automatic labeling rule",
+                "operationId": "post-label-rule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Field (one of project, entity, branch), pattern and label",
+                        "name": "rule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LabelRule"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.LabelRule"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/settings/label_rules/backfill": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Assigns labels according to all of the user's rules to projects of their existing heartbeats in the background.",
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Apply automatic labeling rules to existing data",
+                "operationId": "post-label-rules-backfill",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    }
+                }
+            }
+        },
+        "/users/{user}/settings/label_rules/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Labels previously assigned by the rule are kept.",
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Delete an automatic labeling rule",
+                "operationId": "delete-label-rule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/users/{user}/settings/language_mappings": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the user's own extension to language mappings as well as the server-wide defaults, which the user's mappings take precedence over",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Retrieve a user's language mappings",
+                "operationId": "get-language-mappings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.languageMappingsResponseVm"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Maps a file extension to a language for all heartbeats received from now on. It is applied to existing data a few minutes later, or immediately via /users/{user}/settings/reprocess.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Add a language mapping",
+                "operationId": "post-language-mapping",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Extension and language",
+                        "name": "mapping",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LanguageMapping"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.LanguageMapping"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/settings/language_mappings/reprocess": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Same as GET /users/{user}/settings/reprocess, kept for backwards compatibility",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Retrieve the progress of the latest re-processing",
+                "operationId": "get-reprocess-2",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReprocessingJob"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Same as POST /users/{user}/settings/reprocess, kept for backwards compatibility",
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Apply current rules to existing data",
+                "operationId": "post-reprocess-2",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    }
+                }
+            }
+        },
+        "/users/{user}/settings/language_mappings/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Delete a language mapping",
+                "operationId": "delete-language-mapping",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Mapping ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/users/{user}/settings/mappings": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json",
+                    "application/yaml"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Export a user's aliases, project labels and labeling rules",
+                "operationId": "get-mapping-config",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Output format, either json (default) or yaml",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MappingConfig"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Adds all aliases, labels and rules from the given configuration, which do not exist yet. With mode \"replace\", all existing ones are deleted first. Accepts JSON or, if sent with a YAML content type, YAML in the format produced by the export.",
+                "consumes": [
+                    "application/json",
+                    "application/yaml"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Import aliases, project labels and labeling rules",
+                "operationId": "post-mapping-config",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Either merge (default) or replace",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Mapping configuration",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MappingConfig"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MappingConfigImportResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/settings/project_groups": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Retrieve a user's project groups",
+                "operationId": "get-project-groups",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ProjectGroup"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Rolls up the given projects into a single logical project named after the group. Applies to summaries, leaderboards and badges and the group's name can be used as a regular project filter. Projects previously assigned to another group are moved to the new one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Add a project group",
+                "operationId": "post-project-group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Name and projects",
+                        "name": "group",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ProjectGroup"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProjectGroup"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/settings/project_groups/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Renames a project group and replaces its projects",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Update a project group",
+                "operationId": "put-project-group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Project group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Name and projects",
+                        "name": "group",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ProjectGroup"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProjectGroup"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes a project group, its projects are listed individually again",
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Delete a project group",
+                "operationId": "delete-project-group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Project group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/users/{user}/settings/reprocess": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Retrieve the progress of the latest re-processing",
+                "operationId": "get-reprocess",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReprocessingJob"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Re-applies language mappings, language detection, category and branch extraction and label rules to all of the user's existing heartbeats and re-generates summaries in the background. Also happens automatically a few minutes after language mappings or label rules were changed. Progress can be tracked via GET on the same endpoint.",
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Apply current rules to existing data",
+                "operationId": "post-reprocess",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    }
+                }
+            }
+        },
+        "/users/{user}/status_bar/today": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Mimics https://wakatime.com/api/v1/users/current/status_bar/today, as queried by the editor plugins for their status bar text. Have no official documentation. Heartbeat categories are not tracked, so all time is reported as \"Coding\".",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wakatime"
+                ],
+                "summary": "Retrieve summary for statusbar",
+                "operationId": "statusbar",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID to fetch data for (or 'current')",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.StatusBarViewModel"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user}/summaries/regenerate": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Drops the user's stored summaries within the given date range, invalidates cached ones and re-computes them from heartbeats in the background. Useful after changing aliases, project labels or language mappings, which otherwise only apply to summaries generated afterwards. Without from or to, the range spans from the user's first or until their last heartbeat respectively.",
+                "tags": [
+                    "summary"
+                ],
+                "summary": "Re-generate a user's summaries",
+                "operationId": "post-summaries-regenerate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (e.g. '2021-02-07')",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, inclusive (e.g. '2021-02-08')",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    }
+                }
+            }
+        },
+        "/v1/users/{user}/heartbeats": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "heartbeat"
+                ],
+                "summary": "Push a new heartbeat",
+                "operationId": "post-heartbeat-2",
+                "parameters": [
+                    {
+                        "description": "A single heartbeat",
+                        "name": "heartbeat",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Heartbeat"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    }
+                }
+            }
+        },
+        "/v1/users/{user}/heartbeats.bulk": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "heartbeat"
+                ],
+                "summary": "Push new heartbeats",
+                "operationId": "post-heartbeat-6",
+                "parameters": [
+                    {
+                        "description": "Multiple heartbeats",
+                        "name": "heartbeat",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Heartbeat"
+                            }
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Username (or current)",
+                        "name": "user",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.HomeAssistantViewModel": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "editor": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "last_heartbeat_at": {
+                    "description": "rfc 3339, as expected by home assistant's timestamp device class",
+                    "type": "string"
+                },
+                "project": {
+                    "type": "string"
+                },
+                "session_seconds": {
+                    "type": "integer"
+                },
+                "today": {
+                    "description": "seconds",
+                    "type": "integer"
+                },
+                "today_hours": {
+                    "description": "rounded to two decimals",
+                    "type": "number"
+                },
+                "today_text": {
+                    "description": "e.g. \"2 hrs 15 mins\"",
+                    "type": "string"
+                }
+            }
+        },
+        "api.TrayViewModel": {
+            "type": "object",
+            "properties": {
+                "goal": {
+                    "description": "seconds",
+                    "type": "integer"
+                },
+                "goal_progress": {
+                    "description": "between 0 and 1",
+                    "type": "number"
+                },
+                "project": {
+                    "type": "string"
+                },
+                "today": {
+                    "description": "seconds",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.adminInvitationRequestVm": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "type": "string"
+                },
+                "max_uses": {
+                    "description": "0 for unlimited",
+                    "type": "integer"
+                },
+                "valid_days": {
+                    "description": "0 for no expiry",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.adminRegenerateResponseVm": {
+            "type": "object",
+            "properties": {
+                "scheduled": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "description": "users with an aggregation already in progress",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.adminRetentionConfirmRequestVm": {
+            "type": "object",
+            "properties": {
+                "retention_months": {
+                    "description": "must match the configured retention period",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.adminStatsResponseVm": {
+            "type": "object",
+            "properties": {
+                "users_active": {
+                    "description": "within the last inactive_days",
+                    "type": "integer"
+                },
+                "users_by_activity_class": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "users_total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.adminTestMailRequestVm": {
+            "type": "object",
+            "properties": {
+                "recipient": {
+                    "description": "defaults to the admin's own e-mail address",
+                    "type": "string"
+                }
+            }
+        },
+        "api.adminTestMailResponseVm": {
+            "type": "object",
+            "properties": {
+                "provider": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.exportTargetViewModel": {
+            "type": "object",
+            "properties": {
+                "access_key": {
+                    "type": "string"
+                },
+                "bucket": {
+                    "type": "string"
+                },
+                "dump_url": {
+                    "description": "presigned, expires after an hour",
+                    "type": "string"
+                },
+                "endpoint": {
+                    "description": "blank for aws",
+                    "type": "string"
+                },
+                "last_dump_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "last_dump_key": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "path_style": {
+                    "type": "boolean"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "region": {
+                    "type": "string"
+                },
+                "retention_days": {
+                    "description": "lifecycle hint only, 0 to keep dumps forever",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.heartbeatDeleteResponseVm": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "matched": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api.heartbeatResponseVm": {
+            "type": "object",
+            "properties": {
+                "responses": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {}
+                    }
+                }
+            }
+        },
+        "api.heartbeatUpdateRequestVm": {
+            "type": "object",
+            "properties": {
+                "branch": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "project": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.languageMappingsResponseVm": {
+            "type": "object",
+            "properties": {
+                "defaults": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "mappings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LanguageMapping"
+                    }
+                }
+            }
+        },
+        "api.outdatedClientsResponseVm": {
+            "type": "object",
+            "properties": {
+                "clients": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OutdatedClient"
+                    }
+                },
+                "latest_versions": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "outdated": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "api.sandboxResultsViewModel": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SandboxResult"
+                    }
+                }
+            }
+        },
+        "config.ReloadStatus": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "in_sync": {
+                    "type": "boolean"
+                },
+                "last_attempt": {
+                    "type": "string"
+                },
+                "last_success": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AbuseReport": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "reporter_id": {
+                    "description": "pointer because nullable",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "target_id": {
+                    "type": "string"
+                },
+                "target_project": {
+                    "type": "string"
+                },
+                "target_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AbuseReportRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
+                },
+                "target_id": {
+                    "type": "string"
+                },
+                "target_project": {
+                    "type": "string"
+                },
+                "target_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AbuseReportResolution": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "new_name": {
+                    "description": "only for action \"rename\"",
+                    "type": "string"
+                }
+            }
+        },
+        "models.AccessTokenErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AccessTokenResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Achievement": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "unlocked_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                }
+            }
+        },
+        "models.AggregationBackfillJob": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "failed": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "processed": {
+                    "type": "integer"
+                },
+                "progress": {
+                    "description": "between 0 and 1",
+                    "type": "number"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "users": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AggregationBackfillRequest": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "description": "e.g. 2021-02-07",
+                    "type": "string"
+                },
+                "to": {
+                    "description": "e.g. 2021-02-08",
+                    "type": "string"
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.Annotation": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "day": {
+                    "description": "in the user's time zone",
+                    "type": "string",
+                    "example": "2006-01-02"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "project": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BillableProject": {
+            "type": "object",
+            "properties": {
+                "client": {
+                    "type": "string"
+                },
+                "currency": {
+                    "description": "iso 4217 code, all projects of the same client share a currency",
+                    "type": "string"
+                },
+                "hourly_rate": {
+                    "type": "number"
+                },
+                "project": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BillingClient": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "client": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "hours": {
+                    "type": "number"
+                },
+                "months": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BillingMonth"
+                    }
+                }
+            }
+        },
+        "models.BillingMonth": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "hours": {
+                    "type": "number"
+                },
+                "month": {
+                    "description": "formatted as yyyy-mm",
+                    "type": "string"
+                },
+                "projects": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BillingProjectItem"
+                    }
+                }
+            }
+        },
+        "models.BillingProjectItem": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "hourly_rate": {
+                    "type": "number"
+                },
+                "hours": {
+                    "type": "number"
+                },
+                "project": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BillingReport": {
+            "type": "object",
+            "properties": {
+                "clients": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BillingClient"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BlockRule": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "dropped": {
+                    "description": "number of heartbeats dropped by this rule so far",
+                    "type": "integer"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_dropped_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "pattern": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Client": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "projects": {
+                    "description": "persisted as client projects",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.DataCorrection": {
+            "type": "object",
+            "properties": {
+                "applied_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "from": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "from_project": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "review_comment": {
+                    "type": "string"
+                },
+                "reviewed_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "reviewer_id": {
+                    "description": "pointer because nullable",
+                    "type": "string"
+                },
+                "rewritten": {
+                    "description": "number of heartbeats moved to the target project",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "to_project": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DataCorrectionRequest": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "from_project": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "to_project": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DataCorrectionReview": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "type": "string"
+                },
+                "decision": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DevicePairing": {
+            "type": "object",
+            "properties": {
+                "device_code": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "description": "seconds",
+                    "type": "integer"
+                },
+                "interval": {
+                    "description": "seconds",
+                    "type": "integer"
+                },
+                "user_code": {
+                    "type": "string"
+                },
+                "verification_uri": {
+                    "type": "string"
+                },
+                "verification_uri_complete": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Diagnostics": {
+            "type": "object",
+            "properties": {
+                "architecture": {
+                    "type": "string"
+                },
                 "cli_version": {
                     "type": "string"
                 },
-                "id": {
+                "id": {
+                    "type": "integer"
+                },
+                "logs": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "plugin": {
+                    "type": "string"
+                },
+                "stacktrace": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ExportTarget": {
+            "type": "object",
+            "properties": {
+                "access_key": {
+                    "type": "string"
+                },
+                "bucket": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "description": "blank for aws",
+                    "type": "string"
+                },
+                "last_dump_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "last_dump_key": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "path_style": {
+                    "type": "boolean"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "region": {
+                    "type": "string"
+                },
+                "retention_days": {
+                    "description": "lifecycle hint only, 0 to keep dumps forever",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ExportTargetRequest": {
+            "type": "object",
+            "properties": {
+                "access_key": {
+                    "type": "string"
+                },
+                "bucket": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "path_style": {
+                    "type": "boolean"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "region": {
+                    "type": "string"
+                },
+                "retention_days": {
+                    "type": "integer"
+                },
+                "secret_key": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Heartbeat": {
+            "type": "object",
+            "properties": {
+                "branch": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "https://gorm.io/docs/conventions.html#CreatedAt",
+                    "type": "number"
+                },
+                "editor": {
+                    "description": "ignored because editor might be parsed differently by wakatime",
+                    "type": "string"
+                },
+                "entity": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_write": {
+                    "type": "boolean"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "machine": {
+                    "description": "ignored because wakatime api doesn't return machines currently",
+                    "type": "string"
+                },
+                "operating_system": {
+                    "description": "ignored because os might be parsed differently by wakatime",
+                    "type": "string"
+                },
+                "project": {
+                    "type": "string"
+                },
+                "project_remote": {
+                    "description": "repository remote url as sent by newer clients, not persisted with the heartbeat, but per project (see ProjectRemote)",
+                    "type": "string"
+                },
+                "time": {
+                    "type": "number"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Invitation": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "comment": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "max_uses": {
+                    "description": "0 for unlimited",
+                    "type": "integer"
+                },
+                "signup_url": {
+                    "type": "string"
+                },
+                "uses": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.LabelRule": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "pattern": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LanguageMapping": {
+            "type": "object",
+            "properties": {
+                "extension": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "language": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LanguageTrendItem": {
+            "type": "object",
+            "properties": {
+                "hours": {
+                    "type": "number"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "percent": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.LanguageTrendPeriod": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LanguageTrendItem"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LanguageTrends": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "interval": {
+                    "type": "string"
+                },
+                "periods": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LanguageTrendPeriod"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LoginLockout": {
+            "type": "object",
+            "properties": {
+                "failures": {
+                    "type": "integer"
+                },
+                "last_ip": {
+                    "type": "string"
+                },
+                "locked_until": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Machine": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string"
+                },
+                "country": {
+                    "description": "empty unless geoip lookups are enabled",
+                    "type": "string"
+                },
+                "first_seen_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "last_ip": {
+                    "type": "string"
+                },
+                "last_seen_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "operating_system": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MappingConfig": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MappingConfigAlias"
+                    }
+                },
+                "label_rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MappingConfigLabelRule"
+                    }
+                },
+                "labels": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MappingConfigLabel"
+                    }
+                }
+            }
+        },
+        "models.MappingConfigAlias": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string"
+                },
+                "type": {
+                    "description": "e.g. \"project\" or \"language\"",
+                    "type": "string"
+                },
+                "values": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.MappingConfigImportResult": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "type": "integer"
+                },
+                "label_rules": {
+                    "type": "integer"
+                },
+                "labels": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.MappingConfigLabel": {
+            "type": "object",
+            "properties": {
+                "label": {
+                    "type": "string"
+                },
+                "projects": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.MappingConfigLabelRule": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "pattern": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Milestone": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "day": {
+                    "description": "in the user's time zone",
+                    "type": "string",
+                    "example": "2006-01-02"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "project": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OutdatedClient": {
+            "type": "object",
+            "properties": {
+                "last_seen_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "latest_version": {
+                    "type": "string"
+                },
+                "plugin": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PluginVersionStats": {
+            "type": "object",
+            "properties": {
+                "editor": {
+                    "type": "string"
+                },
+                "last_seen_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "plugin": {
+                    "type": "string"
+                },
+                "users": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Presence": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "editor": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "last_heartbeat_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "project": {
+                    "type": "string"
+                },
+                "session_seconds": {
+                    "description": "time since the start of the current session, i.e. since the first heartbeat after a break",
+                    "type": "integer"
+                },
+                "session_start": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                }
+            }
+        },
+        "models.ProjectGroup": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "projects": {
+                    "description": "persisted as project group members",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.ProjectMergeRequest": {
+            "type": "object",
+            "properties": {
+                "sources": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "target": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ProjectRenameRequest": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ProjectRewriteJob": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "progress": {
+                    "description": "between 0 and 1",
+                    "type": "number"
+                },
+                "rewritten": {
+                    "type": "integer"
+                },
+                "sources": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PublicStats": {
+            "type": "object",
+            "properties": {
+                "top_languages": {
+                    "$ref": "#/definitions/models.LanguageTrendPeriod"
+                },
+                "total_hours": {
+                    "type": "integer"
+                },
+                "total_users": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Report": {
+            "type": "object",
+            "properties": {
+                "annotations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Annotation"
+                    }
+                },
+                "daily_summaries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Summary"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "summary": {
+                    "$ref": "#/definitions/models.Summary"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "trends": {
+                    "description": "only included in the weekly e-mail reports",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.UserTrends"
+                        }
+                    ]
+                },
+                "working_hours": {
+                    "description": "only included if the user has set up a working schedule",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.WorkingHours"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.ReportDefinition": {
+            "type": "object",
+            "properties": {
+                "async": {
+                    "description": "whether to generate the report in the background and send it by e-mail instead of returning it",
+                    "type": "boolean"
+                },
+                "daily": {
+                    "description": "whether to include a per-day breakdown",
+                    "type": "boolean"
+                },
+                "filters": {
+                    "description": "summary type name to key, e.g. \"project\": \"wakapi\"",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "format": {
+                    "description": "output format, json by default",
+                    "type": "string",
+                    "enum": [
+                        "json",
+                        "html"
+                    ],
+                    "example": "json"
+                },
+                "from": {
+                    "type": "string",
+                    "example": "2021-02-07"
+                },
+                "groupings": {
+                    "description": "summary type names to include, all if empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "project",
+                        "language"
+                    ]
+                },
+                "interval": {
+                    "description": "alternatively, from and to can be specified",
+                    "type": "string",
+                    "example": "last_month"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "2021-02-08"
+                }
+            }
+        },
+        "models.ReprocessingJob": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "labeled": {
+                    "description": "newly assigned project labels",
+                    "type": "integer"
+                },
+                "progress": {
+                    "description": "between 0 and 1",
+                    "type": "number"
+                },
+                "rewritten": {
+                    "description": "heartbeats with a mapped language",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "step": {
+                    "type": "string"
+                },
+                "updated": {
+                    "description": "heartbeats with a newly detected language, category or branch",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.RetentionImpact": {
+            "type": "object",
+            "properties": {
+                "confirmation_required": {
+                    "description": "whether cleanups are paused, because the configured period would delete more data than the confirmed one",
+                    "type": "boolean"
+                },
+                "confirmed_retention_months": {
+                    "description": "as last confirmed, 0 or less if disabled",
+                    "type": "integer"
+                },
+                "cutoff": {
+                    "description": "data older than this is deleted at the next run, nil if retention is disabled",
+                    "type": "string",
+                    "format": "date"
+                },
+                "heartbeats": {
+                    "type": "integer"
+                },
+                "mode": {
+                    "description": "whether expired data is deleted or anonymized",
+                    "type": "string"
+                },
+                "next_run": {
+                    "type": "string",
+                    "format": "date"
+                },
+                "retention_months": {
+                    "description": "as currently configured, 0 or less if disabled",
+                    "type": "integer"
+                },
+                "summaries": {
+                    "type": "integer"
+                },
+                "users": {
+                    "description": "affected users only, users exempt from data retention (e.g. subscribers) are omitted",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.UserRetentionImpact"
+                    }
+                }
+            }
+        },
+        "models.SandboxResult": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "description": "whether the heartbeat would have been stored",
+                    "type": "boolean"
+                },
+                "blocked": {
+                    "description": "dropped by an instance-wide block rule",
+                    "type": "boolean"
+                },
+                "duplicate": {
+                    "description": "identical to a previous heartbeat of the same request and thus ignored",
+                    "type": "boolean"
+                },
+                "errors": {
+                    "description": "reasons for the heartbeat to be rejected",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "hash": {
+                    "description": "used for deduplication",
+                    "type": "string"
+                },
+                "heartbeat": {
+                    "description": "the heartbeat as it would have been stored",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Heartbeat"
+                        }
+                    ]
+                },
+                "index": {
+                    "description": "position within the request",
                     "type": "integer"
                 },
-                "logs": {
+                "language_original": {
+                    "description": "language as reported by the client, if overridden by one of the user's language mappings",
                     "type": "string"
                 },
-                "platform": {
+                "received_at": {
+                    "type": "number"
+                },
+                "status": {
+                    "description": "http status the heartbeat would have been answered with",
+                    "type": "integer"
+                },
+                "unknown_fields": {
+                    "description": "fields unknown to wakapi, which are kept as extras",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "warnings": {
+                    "description": "issues which don't prevent the heartbeat from being stored, but might be unintended",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.SavedFilter": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "work"
+                },
+                "query": {
+                    "description": "filter query parameters, as accepted by the summary endpoints",
+                    "type": "string",
+                    "example": "project=wakapi,anchr\u0026branch!=main"
+                }
+            }
+        },
+        "models.Summary": {
+            "type": "object",
+            "properties": {
+                "branches": {
+                    "description": "branches are not persisted, but calculated at runtime in case a project filter is applied",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItem"
+                    }
+                },
+                "clients": {
+                    "description": "clients are not persisted, but calculated at runtime from the user's client projects",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItem"
+                    }
+                },
+                "editors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItem"
+                    }
+                },
+                "entities": {
+                    "description": "entities are not persisted, but calculated at runtime in case a project filter is applied",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItem"
+                    }
+                },
+                "from": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "labels": {
+                    "description": "labels are not persisted, but calculated at runtime, i.e. when summary is retrieved",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItem"
+                    }
+                },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItem"
+                    }
+                },
+                "machines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItem"
+                    }
+                },
+                "namespaces": {
+                    "description": "namespaces are not persisted, but calculated at runtime from projects' repository remotes",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItem"
+                    }
+                },
+                "operating_systems": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItem"
+                    }
+                },
+                "projects": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItem"
+                    }
+                },
+                "to": {
+                    "type": "string",
+                    "format": "date",
+                    "example": "2006-01-02 15:04:05.000"
+                },
+                "user_id": {
                     "type": "string"
+                }
+            }
+        },
+        "models.SummaryComparison": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "$ref": "#/definitions/models.Summary"
                 },
-                "plugin": {
+                "deltas": {
+                    "$ref": "#/definitions/models.SummaryDelta"
+                },
+                "previous": {
+                    "$ref": "#/definitions/models.Summary"
+                }
+            }
+        },
+        "models.SummaryDelta": {
+            "type": "object",
+            "properties": {
+                "branches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItemDelta"
+                    }
+                },
+                "current": {
+                    "type": "integer"
+                },
+                "editors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItemDelta"
+                    }
+                },
+                "entities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItemDelta"
+                    }
+                },
+                "labels": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItemDelta"
+                    }
+                },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItemDelta"
+                    }
+                },
+                "machines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItemDelta"
+                    }
+                },
+                "operating_systems": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItemDelta"
+                    }
+                },
+                "percent": {
+                    "description": "relative change, nil if nothing was tracked in the previous period",
+                    "type": "number"
+                },
+                "previous": {
+                    "type": "integer"
+                },
+                "projects": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SummaryItemDelta"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.SummaryItem": {
+            "type": "object",
+            "properties": {
+                "key": {
                     "type": "string"
                 },
-                "stacktrace": {
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.SummaryItemDelta": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "type": "integer"
+                },
+                "key": {
                     "type": "string"
+                },
+                "percent": {
+                    "type": "number"
+                },
+                "previous": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
                 }
             }
         },
-        "models.Heartbeat": {
+        "models.UserExport": {
             "type": "object",
             "properties": {
-                "branch": {
+                "created_at": {
                     "type": "string"
                 },
-                "category": {
+                "expires_at": {
+                    "description": "only set if ready",
                     "type": "string"
                 },
-                "created_at": {
-                    "description": "https://gorm.io/docs/conventions.html#CreatedAt",
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserProjection": {
+            "type": "object",
+            "properties": {
+                "expected": {
+                    "description": "projected total by the end of the period",
                     "type": "number"
                 },
-                "editor": {
-                    "description": "ignored because editor might be parsed differently by wakatime",
+                "from": {
                     "type": "string"
                 },
-                "entity": {
+                "hours": {
+                    "description": "tracked so far",
+                    "type": "number"
+                },
+                "to": {
                     "type": "string"
+                }
+            }
+        },
+        "models.UserRetentionImpact": {
+            "type": "object",
+            "properties": {
+                "additional_heartbeats": {
+                    "description": "number of heartbeats, which would not have been deleted under the confirmed retention period",
+                    "type": "integer"
                 },
-                "id": {
+                "additional_summaries": {
                     "type": "integer"
                 },
-                "is_write": {
-                    "type": "boolean"
+                "heartbeats": {
+                    "type": "integer"
                 },
-                "language": {
-                    "type": "string"
+                "summaries": {
+                    "type": "integer"
                 },
-                "machine": {
-                    "description": "ignored because wakatime api doesn't return machines currently",
+                "user": {
                     "type": "string"
-                },
-                "operating_system": {
-                    "description": "ignored because os might be parsed differently by wakatime",
+                }
+            }
+        },
+        "models.UserTrendDay": {
+            "type": "object",
+            "properties": {
+                "date": {
                     "type": "string"
                 },
-                "project": {
-                    "type": "string"
+                "hours": {
+                    "type": "number"
                 },
-                "time": {
+                "rolling_average": {
+                    "description": "average hours per day of the past seven days, up to and including this one",
+                    "type": "number"
+                }
+            }
+        },
+        "models.UserTrendWeekday": {
+            "type": "object",
+            "properties": {
+                "average_hours": {
                     "type": "number"
                 },
-                "type": {
-                    "type": "string"
+                "percent": {
+                    "description": "share of the total time",
+                    "type": "number"
                 },
-                "user_agent": {
+                "weekday": {
                     "type": "string"
                 }
             }
         },
-        "models.Summary": {
+        "models.UserTrends": {
             "type": "object",
             "properties": {
-                "branches": {
-                    "description": "branches are not persisted, but calculated at runtime in case a project filter is applied",
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.SummaryItem"
-                    }
+                "daily_average": {
+                    "description": "excluding today",
+                    "type": "number"
                 },
-                "editors": {
+                "days": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.SummaryItem"
+                        "$ref": "#/definitions/models.UserTrendDay"
                     }
                 },
                 "from": {
-                    "type": "string",
-                    "format": "date",
-                    "example": "2006-01-02 15:04:05.000"
-                },
-                "labels": {
-                    "description": "labels are not persisted, but calculated at runtime, i.e. when summary is retrieved",
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.SummaryItem"
-                    }
+                    "type": "string"
                 },
-                "languages": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.SummaryItem"
-                    }
+                "month": {
+                    "$ref": "#/definitions/models.UserProjection"
                 },
-                "machines": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.SummaryItem"
-                    }
+                "to": {
+                    "type": "string"
                 },
-                "operating_systems": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.SummaryItem"
-                    }
+                "week": {
+                    "$ref": "#/definitions/models.UserProjection"
                 },
-                "projects": {
+                "weekdays": {
+                    "description": "starting on monday",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.SummaryItem"
+                        "$ref": "#/definitions/models.UserTrendWeekday"
                     }
-                },
-                "to": {
-                    "type": "string",
-                    "format": "date",
-                    "example": "2006-01-02 15:04:05.000"
-                },
-                "user_id": {
-                    "type": "string"
                 }
             }
         },
-        "models.SummaryItem": {
+        "models.WorkingHours": {
             "type": "object",
             "properties": {
-                "key": {
+                "from": {
+                    "type": "string"
+                },
+                "in_hours": {
+                    "description": "within working hours on working days",
+                    "type": "number"
+                },
+                "out_of_hours": {
+                    "description": "outside working hours or on days off",
+                    "type": "number"
+                },
+                "overtime": {
+                    "description": "time coded beyond the target",
+                    "type": "number"
+                },
+                "target": {
+                    "description": "share of the weekly target for the working days within the interval, 0 if no target is set",
+                    "type": "number"
+                },
+                "target_percent": {
+                    "description": "share of the target reached so far, 0 if no target is set",
+                    "type": "number"
+                },
+                "to": {
                     "type": "string"
                 },
                 "total": {
-                    "type": "integer"
+                    "type": "number"
                 }
             }
         },
@@ -1317,6 +7154,152 @@ const docTemplate = `{
                 }
             }
         },
+        "v1.Dashboard": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "is_current_user_member": {
+                    "type": "boolean"
+                },
+                "members_count": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.DashboardMember": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "full_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_view_only": {
+                    "type": "boolean"
+                },
+                "photo": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.DashboardMembersViewModel": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.DashboardMember"
+                    }
+                },
+                "next_page": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "prev_page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "total_pages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "v1.DashboardsViewModel": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.Dashboard"
+                    }
+                },
+                "next_page": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "prev_page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "total_pages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "v1.Duration": {
+            "type": "object",
+            "properties": {
+                "branch": {
+                    "type": "string"
+                },
+                "duration": {
+                    "description": "seconds",
+                    "type": "number"
+                },
+                "editor": {
+                    "type": "string"
+                },
+                "entity": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "machine": {
+                    "type": "string"
+                },
+                "operating_system": {
+                    "type": "string"
+                },
+                "project": {
+                    "type": "string"
+                },
+                "time": {
+                    "description": "unix timestamp",
+                    "type": "number"
+                }
+            }
+        },
+        "v1.DurationsViewModel": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.Duration"
+                    }
+                },
+                "end": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                }
+            }
+        },
         "v1.HeartbeatEntry": {
             "type": "object",
             "properties": {
@@ -1370,27 +7353,119 @@ const docTemplate = `{
                         "$ref": "#/definitions/v1.HeartbeatEntry"
                     }
                 },
-                "end": {
+                "end": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.Org": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "timeout": {
+                    "description": "minutes",
+                    "type": "integer"
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "writes_only": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "v1.OrgsViewModel": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.Org"
+                    }
+                },
+                "next_page": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "prev_page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "total_pages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "v1.Project": {
+            "type": "object",
+            "properties": {
+                "badge": {},
+                "color": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "first_heartbeat_at": {
+                    "type": "string"
+                },
+                "has_public_url": {
+                    "type": "boolean"
+                },
+                "human_readable_first_heartbeat_at": {
                     "type": "string"
                 },
-                "start": {
+                "human_readable_last_heartbeat_at": {
                     "type": "string"
                 },
-                "timezone": {
+                "id": {
+                    "type": "string"
+                },
+                "last_heartbeat_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "repository": {
+                    "$ref": "#/definitions/v1.ProjectRepository"
+                },
+                "urlencoded_name": {
                     "type": "string"
                 }
             }
         },
-        "v1.Project": {
+        "v1.ProjectRepository": {
             "type": "object",
             "properties": {
-                "id": {
+                "full_name": {
+                    "type": "string"
+                },
+                "html_url": {
                     "type": "string"
                 },
                 "name": {
                     "type": "string"
                 },
-                "repository": {
+                "provider": {
+                    "type": "string"
+                },
+                "url": {
                     "type": "string"
                 }
             }
@@ -1403,6 +7478,21 @@ const docTemplate = `{
                     "items": {
                         "$ref": "#/definitions/v1.Project"
                     }
+                },
+                "next_page": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "prev_page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "total_pages": {
+                    "type": "integer"
                 }
             }
         },
@@ -1430,6 +7520,21 @@ const docTemplate = `{
                 "end": {
                     "type": "string"
                 },
+                "human_readable_daily_average": {
+                    "type": "string"
+                },
+                "human_readable_range": {
+                    "type": "string"
+                },
+                "human_readable_total": {
+                    "type": "string"
+                },
+                "is_coding_activity_visible": {
+                    "type": "boolean"
+                },
+                "is_other_usage_visible": {
+                    "type": "boolean"
+                },
                 "languages": {
                     "type": "array",
                     "items": {
@@ -1454,9 +7559,15 @@ const docTemplate = `{
                         "$ref": "#/definitions/v1.SummariesEntry"
                     }
                 },
+                "range": {
+                    "type": "string"
+                },
                 "start": {
                     "type": "string"
                 },
+                "status": {
+                    "type": "string"
+                },
                 "total_seconds": {
                     "type": "number"
                 },
@@ -1476,6 +7587,134 @@ const docTemplate = `{
                 }
             }
         },
+        "v1.StatusBarData": {
+            "type": "object",
+            "properties": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.StatusBarEntry"
+                    }
+                },
+                "dependencies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.StatusBarEntry"
+                    }
+                },
+                "editors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.StatusBarEntry"
+                    }
+                },
+                "grand_total": {
+                    "$ref": "#/definitions/v1.StatusBarTotal"
+                },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.StatusBarEntry"
+                    }
+                },
+                "machines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.StatusBarEntry"
+                    }
+                },
+                "operating_systems": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.StatusBarEntry"
+                    }
+                },
+                "projects": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.StatusBarEntry"
+                    }
+                },
+                "range": {
+                    "$ref": "#/definitions/v1.StatusBarDataRange"
+                }
+            }
+        },
+        "v1.StatusBarDataRange": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "end": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.StatusBarEntry": {
+            "type": "object",
+            "properties": {
+                "decimal": {
+                    "type": "string"
+                },
+                "digital": {
+                    "type": "string"
+                },
+                "hours": {
+                    "type": "integer"
+                },
+                "minutes": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "percent": {
+                    "type": "number"
+                },
+                "seconds": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "total_seconds": {
+                    "type": "number"
+                }
+            }
+        },
+        "v1.StatusBarTotal": {
+            "type": "object",
+            "properties": {
+                "decimal": {
+                    "type": "string"
+                },
+                "digital": {
+                    "type": "string"
+                },
+                "hours": {
+                    "type": "integer"
+                },
+                "minutes": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "total_seconds": {
+                    "type": "number"
+                }
+            }
+        },
         "v1.StatusBarViewModel": {
             "type": "object",
             "properties": {
@@ -1483,7 +7722,53 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "data": {
-                    "$ref": "#/definitions/v1.SummariesData"
+                    "$ref": "#/definitions/v1.StatusBarData"
+                },
+                "has_team_features": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "v1.SummariesCumulativeTotal": {
+            "type": "object",
+            "properties": {
+                "decimal": {
+                    "type": "string"
+                },
+                "digital": {
+                    "type": "string"
+                },
+                "seconds": {
+                    "type": "number"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.SummariesDailyAverage": {
+            "type": "object",
+            "properties": {
+                "days_including_holidays": {
+                    "type": "integer"
+                },
+                "days_minus_holidays": {
+                    "type": "integer"
+                },
+                "holidays": {
+                    "type": "integer"
+                },
+                "seconds": {
+                    "type": "integer"
+                },
+                "seconds_including_other_language": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "text_including_other_language": {
+                    "type": "string"
                 }
             }
         },
@@ -1514,6 +7799,12 @@ const docTemplate = `{
                         "$ref": "#/definitions/v1.SummariesEntry"
                     }
                 },
+                "entities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.SummariesEntry"
+                    }
+                },
                 "grand_total": {
                     "$ref": "#/definitions/v1.SummariesGrandTotal"
                 },
@@ -1618,6 +7909,12 @@ const docTemplate = `{
         "v1.SummariesViewModel": {
             "type": "object",
             "properties": {
+                "cumulative_total": {
+                    "$ref": "#/definitions/v1.SummariesCumulativeTotal"
+                },
+                "daily_average": {
+                    "$ref": "#/definitions/v1.SummariesDailyAverage"
+                },
                 "data": {
                     "type": "array",
                     "items": {
@@ -1668,6 +7965,13 @@ const docTemplate = `{
                 "modified_at": {
                     "type": "string"
                 },
+                "photo": {
+                    "type": "string"
+                },
+                "timeout": {
+                    "description": "minutes",
+                    "type": "integer"
+                },
                 "timezone": {
                     "type": "string"
                 },
@@ -1679,6 +7983,64 @@ const docTemplate = `{
                 }
             }
         },
+        "v1.UserAgentEntry": {
+            "type": "object",
+            "properties": {
+                "cli_version": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "editor": {
+                    "type": "string"
+                },
+                "editor_version": {
+                    "type": "string"
+                },
+                "first_seen_at": {
+                    "type": "string"
+                },
+                "go_version": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_browser_extension": {
+                    "type": "boolean"
+                },
+                "is_desktop_app": {
+                    "type": "boolean"
+                },
+                "last_seen_at": {
+                    "type": "string"
+                },
+                "os": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.UserAgentsViewModel": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.UserAgentEntry"
+                    }
+                },
+                "total_pages": {
+                    "type": "integer"
+                }
+            }
+        },
         "v1.UserViewModel": {
             "type": "object",
             "properties": {
@@ -1707,6 +8069,8 @@ var SwaggerInfo = &swag.Spec{
 	Description:      "REST API to interact with [Wakapi](https://wakapi.dev)\n\n## Authentication\nSet header `Authorization` to your API Key encoded as Base64 and prefixed with `Basic`\n**Example:** `Basic ODY2NDhkNzQtMTljNS00NTJiLWJhMDEtZmIzZWM3MGQ0YzJmCg==`",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
+	LeftDelim:        "{{",
+	RightDelim:       "}}",
 }
 
 func init() {
@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/patrickmn/go-cache"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	NoExpiration      = cache.NoExpiration
+	redisScanPageSize = 1000
+)
+
+// increments a counter only if it exists, for consistency with the in-memory cache, where incrementing a missing key is a no-op
+var redisIncrementIfExists = redis.NewScript(`if redis.call('EXISTS', KEYS[1]) == 1 then return redis.call('INCRBY', KEYS[1], ARGV[1]) end return nil`)
+
+// Cache is a key-value cache, either held in memory of the current process or in redis, to be shared among multiple replicas
+// caches are best-effort, i.e. failing to read or write an entry is treated like a cache miss
+type Cache interface {
+	// Get loads the entry into target, which must be a pointer to a value of the type that was put into the cache
+	Get(key string, target interface{}) bool
+	Set(key string, value interface{}, ttl time.Duration)
+	SetDefault(key string, value interface{})
+	IncrementInt64(key string, n int64)
+	Delete(key string)
+	DeleteContaining(s string)
+	Flush()
+}
+
+// MemoryCache keeps entries in memory as they are, i.e. without copying them
+type MemoryCache struct {
+	cache *cache.Cache
+}
+
+func NewMemoryCache(defaultTtl, cleanupInterval time.Duration) *MemoryCache {
+	return &MemoryCache{cache: cache.New(defaultTtl, cleanupInterval)}
+}
+
+func (c *MemoryCache) Get(key string, target interface{}) bool {
+	v, ok := c.cache.Get(key)
+	if !ok {
+		return false
+	}
+	value, dest := reflect.ValueOf(v), reflect.ValueOf(target).Elem()
+	if !value.Type().AssignableTo(dest.Type()) {
+		return false
+	}
+	dest.Set(value)
+	return true
+}
+
+func (c *MemoryCache) Set(key string, value interface{}, ttl time.Duration) {
+	c.cache.Set(key, value, ttl)
+}
+
+func (c *MemoryCache) SetDefault(key string, value interface{}) {
+	c.cache.SetDefault(key, value)
+}
+
+func (c *MemoryCache) IncrementInt64(key string, n int64) {
+	c.cache.IncrementInt64(key, n)
+}
+
+func (c *MemoryCache) Delete(key string) {
+	c.cache.Delete(key)
+}
+
+func (c *MemoryCache) DeleteContaining(s string) {
+	for key := range c.cache.Items() {
+		if strings.Contains(key, s) {
+			c.cache.Delete(key)
+		}
+	}
+}
+
+func (c *MemoryCache) Flush() {
+	c.cache.Flush()
+}
+
+// NewRedisClient creates a client for redis (or compatible servers like valkey or keydb) from an url like redis://[[user]:password@]host[:port][/db] or rediss://... for tls
+func NewRedisClient(rawUrl string) (*redis.Client, error) {
+	options, err := redis.ParseURL(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+	return redis.NewClient(options), nil
+}
+
+// RedisCache stores gob-encoded entries in redis, all keys of which are prefixed with the cache's namespace
+// integers are stored as plain numbers, so they can be incremented atomically
+type RedisCache struct {
+	client     *redis.Client
+	namespace  string
+	defaultTtl time.Duration
+}
+
+func NewRedisCache(client *redis.Client, namespace string, defaultTtl time.Duration) *RedisCache {
+	return &RedisCache{client: client, namespace: namespace + ":", defaultTtl: defaultTtl}
+}
+
+func (c *RedisCache) Get(key string, target interface{}) bool {
+	data, err := c.client.Get(context.Background(), c.namespace+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logbuch.Warn("failed to get '%s' from redis cache - %v", c.namespace+key, err)
+		}
+		return false
+	}
+
+	if n, ok := target.(*int64); ok {
+		parsed, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return false
+		}
+		*n = parsed
+		return true
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(target); err != nil {
+		logbuch.Warn("failed to decode '%s' from redis cache - %v", c.namespace+key, err)
+		return false
+	}
+	return true
+}
+
+func (c *RedisCache) Set(key string, value interface{}, ttl time.Duration) {
+	var data string
+	if n, ok := value.(int64); ok {
+		data = strconv.FormatInt(n, 10)
+	} else {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+			logbuch.Warn("failed to encode '%s' for redis cache - %v", c.namespace+key, err)
+			return
+		}
+		data = buf.String()
+	}
+
+	if ttl == 0 {
+		ttl = c.defaultTtl
+	}
+	if ttl < 0 {
+		ttl = 0 // no expiration, whereas negative ones would keep the previous entry's expiration in go-redis
+	}
+	if err := c.client.Set(context.Background(), c.namespace+key, data, ttl).Err(); err != nil {
+		logbuch.Warn("failed to set '%s' in redis cache - %v", c.namespace+key, err)
+	}
+}
+
+func (c *RedisCache) SetDefault(key string, value interface{}) {
+	c.Set(key, value, c.defaultTtl)
+}
+
+func (c *RedisCache) IncrementInt64(key string, n int64) {
+	if err := redisIncrementIfExists.Run(context.Background(), c.client, []string{c.namespace + key}, n).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		logbuch.Warn("failed to increment '%s' in redis cache - %v", c.namespace+key, err)
+	}
+}
+
+func (c *RedisCache) Delete(key string) {
+	if err := c.client.Del(context.Background(), c.namespace+key).Err(); err != nil {
+		logbuch.Warn("failed to delete '%s' from redis cache - %v", c.namespace+key, err)
+	}
+}
+
+func (c *RedisCache) DeleteContaining(s string) {
+	c.deleteMatching(c.namespace + "*" + escapeRedisPattern(s) + "*")
+}
+
+func (c *RedisCache) Flush() {
+	c.deleteMatching(c.namespace + "*")
+}
+
+func (c *RedisCache) deleteMatching(pattern string) {
+	ctx := context.Background()
+	var cursor uint64
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, pattern, redisScanPageSize).Result()
+		if err != nil {
+			logbuch.Warn("failed to scan redis cache for '%s' - %v", pattern, err)
+			return
+		}
+		if len(keys) > 0 {
+			if err := c.client.Del(ctx, keys...).Err(); err != nil {
+				logbuch.Warn("failed to delete keys from redis cache - %v", err)
+			}
+		}
+		if cursor = next; cursor == 0 {
+			return
+		}
+	}
+}
+
+func escapeRedisPattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(s)
+}
@@ -2,7 +2,9 @@ package utils
 
 import (
 	"fmt"
+	"hash/fnv"
 	"image/color"
+	"strings"
 )
 
 func HexToRGBA(s string) (c color.RGBA) {
@@ -38,3 +40,17 @@ func FadeColors(color1, color2 color.RGBA, ratio float64) color.RGBA {
 
 	return color.RGBA{R: r, G: g, B: b, A: a}
 }
+
+// palette of distinguishable, moderately saturated colors to pick from for entities without a predefined color
+var derivedColorPalette = []string{
+	"#E6194B", "#3CB44B", "#FFE119", "#4363D8", "#F58231", "#911EB4", "#46F0F0", "#F032E6",
+	"#BCF60C", "#FABEBE", "#008080", "#E6BEFF", "#9A6324", "#FFFAC8", "#800000", "#AAFFC3",
+	"#808000", "#FFD8B1", "#000075", "#DB7093", "#2E8B57", "#CD853F", "#6A5ACD", "#20B2AA",
+}
+
+// DeriveColor deterministically maps the given key to a color of a fixed palette, such that the same key always gets the same color
+func DeriveColor(key string) string {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(key)))
+	return derivedColorPalette[h.Sum32()%uint32(len(derivedColorPalette))]
+}
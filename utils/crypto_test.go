@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptString(t *testing.T) {
+	ciphertext1, err := EncryptString("wJalrXUtnFEMI/K7MDENG", "passphrase")
+	assert.Nil(t, err)
+	ciphertext2, _ := EncryptString("wJalrXUtnFEMI/K7MDENG", "passphrase")
+	assert.NotEqual(t, ciphertext1, ciphertext2)
+	assert.NotContains(t, ciphertext1, "wJalrXUtnFEMI")
+
+	plaintext, err := DecryptString(ciphertext1, "passphrase")
+	assert.Nil(t, err)
+	assert.Equal(t, "wJalrXUtnFEMI/K7MDENG", plaintext)
+
+	_, err = DecryptString(ciphertext1, "other")
+	assert.Error(t, err)
+	_, err = DecryptString("dG9vIHNob3J0", "passphrase")
+	assert.Error(t, err)
+	_, err = EncryptString("secret", "")
+	assert.Error(t, err)
+}
@@ -47,6 +47,22 @@ func SplitRangeByDays(from time.Time, to time.Time) [][]time.Time {
 	return intervals
 }
 
+// SplitRangeByMonths creates a slice of intervals between from and to, each of which spans at max one calendar month and has its split at the first of a month
+func SplitRangeByMonths(from time.Time, to time.Time) [][]time.Time {
+	intervals := make([][]time.Time, 0)
+
+	for t1 := from; t1.Before(to); {
+		t2 := datetime.BeginOfMonth(t1).AddDate(0, 1, 0)
+		if t2.After(to) {
+			t2 = to
+		}
+		intervals = append(intervals, []time.Time{t1, t2})
+		t1 = t2
+	}
+
+	return intervals
+}
+
 // LocalTZOffset returns the time difference between server local time and UTC
 func LocalTZOffset() time.Duration {
 	_, offset := time.Now().Zone()
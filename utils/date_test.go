@@ -57,3 +57,29 @@ func TestDate_SplitRangeByDays(t *testing.T) {
 
 	assert.Len(t, result4, 0)
 }
+
+func TestDate_SplitRangeByMonths(t *testing.T) {
+	df1, _ := time.Parse("2006-01-02 15:04:05", "2021-11-25 20:25:00")
+	dt1, _ := time.Parse("2006-01-02 15:04:05", "2022-02-03 06:45:00")
+	df2 := df1
+	dt2 := df1.AddDate(0, 0, 2)
+
+	result1 := SplitRangeByMonths(df1, dt1)
+	result2 := SplitRangeByMonths(df2, dt2)
+	result3 := SplitRangeByMonths(df1, df1)
+
+	assert.Len(t, result1, 4)
+	assert.Equal(t, df1, result1[0][0])
+	assert.Equal(t, dt1, result1[3][1])
+	assert.Equal(t, time.December, result1[1][0].Month())
+	assert.Equal(t, 1, result1[1][0].Day())
+	assert.Equal(t, 2022, result1[2][0].Year())
+	assert.Equal(t, result1[1][0], result1[0][1])
+	assert.Equal(t, result1[2][0], result1[1][1])
+	assert.Equal(t, result1[3][0], result1[2][1])
+
+	assert.Len(t, result2, 1)
+	assert.Equal(t, dt2, result2[0][1])
+
+	assert.Len(t, result3, 0)
+}
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	dkimSignedHeaders = []string{"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type"}
+	dkimWspRegex      = regexp.MustCompile(`[ \t]+`)
+)
+
+// DkimSigner signs mails according to https://www.rfc-editor.org/rfc/rfc6376, using relaxed canonicalization for both header and body
+type DkimSigner struct {
+	Domain    string
+	Selector  string
+	key       crypto.Signer
+	algorithm string
+	now       func() time.Time
+}
+
+type dkimHeader struct {
+	name  string
+	value string // unfolded, but otherwise as-is
+}
+
+// NewDkimSigner creates a signer from a pem-encoded rsa (pkcs1 or pkcs8) or ed25519 (pkcs8) private key
+func NewDkimSigner(domain, selector string, keyPem []byte) (*DkimSigner, error) {
+	block, _ := pem.Decode(keyPem)
+	if block == nil {
+		return nil, errors.New("failed to decode pem block")
+	}
+
+	var parsed interface{}
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer := &DkimSigner{Domain: domain, Selector: selector, now: time.Now}
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		signer.key, signer.algorithm = key, "rsa-sha256"
+	case ed25519.PrivateKey:
+		signer.key, signer.algorithm = key, "ed25519-sha256"
+	default:
+		return nil, errors.New("unsupported private key type")
+	}
+	return signer, nil
+}
+
+// Sign returns the given message, with its line endings normalized to crlf and a dkim-signature header prepended
+func (s *DkimSigner) Sign(message []byte) ([]byte, error) {
+	message = bytes.ReplaceAll(bytes.ReplaceAll(message, []byte("\r\n"), []byte("\n")), []byte("\n"), []byte("\r\n"))
+
+	split := bytes.Index(message, []byte("\r\n\r\n"))
+	if split < 0 {
+		return nil, errors.New("message has no body")
+	}
+	headers := parseDkimHeaders(string(message[:split+2]))
+	bodyHash := sha256.Sum256([]byte(dkimRelaxedBody(string(message[split+4:]))))
+
+	var signedNames []string
+	var signedData strings.Builder
+	for _, name := range dkimSignedHeaders {
+		for i := len(headers) - 1; i >= 0; i-- {
+			if strings.EqualFold(headers[i].name, name) {
+				signedNames = append(signedNames, strings.ToLower(name))
+				signedData.WriteString(dkimRelaxedHeader(headers[i].name, headers[i].value))
+				break
+			}
+		}
+	}
+
+	value := fmt.Sprintf(
+		"v=1; a=%s; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		s.algorithm, s.Domain, s.Selector, s.now().Unix(), strings.Join(signedNames, ":"), base64.StdEncoding.EncodeToString(bodyHash[:]),
+	)
+	signedData.WriteString(strings.TrimSuffix(dkimRelaxedHeader("DKIM-Signature", value), "\r\n"))
+
+	digest := sha256.Sum256([]byte(signedData.String()))
+	var signature []byte
+	var err error
+	if s.algorithm == "ed25519-sha256" {
+		signature, err = s.key.Sign(rand.Reader, digest[:], crypto.Hash(0))
+	} else {
+		signature, err = s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := "DKIM-Signature: " + value + base64.StdEncoding.EncodeToString(signature) + "\r\n"
+	return append([]byte(header), message...), nil
+}
+
+func parseDkimHeaders(raw string) []*dkimHeader {
+	headers := make([]*dkimHeader, 0)
+	for _, line := range strings.Split(strings.TrimSuffix(raw, "\r\n"), "\r\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(headers) > 0 {
+			headers[len(headers)-1].value += line // continuation of folded header
+			continue
+		}
+		if i := strings.Index(line, ":"); i > 0 {
+			headers = append(headers, &dkimHeader{name: line[:i], value: line[i+1:]})
+		}
+	}
+	return headers
+}
+
+// see https://www.rfc-editor.org/rfc/rfc6376#section-3.4.2
+func dkimRelaxedHeader(name, value string) string {
+	value = strings.ReplaceAll(value, "\r\n", "")
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + strings.TrimSpace(dkimWspRegex.ReplaceAllString(value, " ")) + "\r\n"
+}
+
+// see https://www.rfc-editor.org/rfc/rfc6376#section-3.4.4
+func dkimRelaxedBody(body string) string {
+	lines := strings.Split(body, "\r\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(dkimWspRegex.ReplaceAllString(line, " "), " ")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDkim_RelaxedCanonicalization(t *testing.T) {
+	// example taken from https://www.rfc-editor.org/rfc/rfc6376#section-3.4.5
+	headers := parseDkimHeaders("A: X\r\nB : Y\t\r\n\tZ  \r\n")
+	assert.Len(t, headers, 2)
+	assert.Equal(t, "a:X\r\n", dkimRelaxedHeader(headers[0].name, headers[0].value))
+	assert.Equal(t, "b:Y Z\r\n", dkimRelaxedHeader(headers[1].name, headers[1].value))
+	assert.Equal(t, " C\r\nD E\r\n", dkimRelaxedBody(" C \r\nD \t E\r\n\r\n\r\n"))
+	assert.Equal(t, "", dkimRelaxedBody("\r\n"))
+}
+
+func TestDkimSigner_Sign_Rsa(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	sut, err := NewDkimSigner("wakapi.dev", "mail", keyPem)
+	assert.Nil(t, err)
+	sut.now = func() time.Time { return time.Unix(1685620800, 0) }
+
+	signed, err := sut.Sign([]byte("From: Wakapi <noreply@wakapi.dev>\nTo: john.doe@example.org\nSubject: Test\n\nHello  World \n\n"))
+	assert.Nil(t, err)
+
+	header, data := verifiableDkimData(t, string(signed))
+	assert.Contains(t, header, "a=rsa-sha256; c=relaxed/relaxed; d=wakapi.dev; s=mail; t=1685620800; h=from:to:subject;")
+	assert.Contains(t, string(signed), "\r\n\r\nHello  World \r\n\r\n") // body is sent as-is, except for line endings
+
+	signature, _ := base64.StdEncoding.DecodeString(header[strings.Index(header, "; b=")+4:])
+	digest := sha256.Sum256([]byte(data))
+	assert.Nil(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+}
+
+func TestDkimSigner_Sign_Ed25519(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+
+	sut, err := NewDkimSigner("wakapi.dev", "mail", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	assert.Nil(t, err)
+
+	signed, err := sut.Sign([]byte("From: noreply@wakapi.dev\r\nTo: john.doe@example.org\r\n\r\nHello\r\n"))
+	assert.Nil(t, err)
+
+	header, data := verifiableDkimData(t, string(signed))
+	assert.Contains(t, header, "a=ed25519-sha256;")
+
+	signature, _ := base64.StdEncoding.DecodeString(header[strings.Index(header, "; b=")+4:])
+	digest := sha256.Sum256([]byte(data))
+	assert.True(t, ed25519.Verify(publicKey, digest[:], signature))
+}
+
+func TestNewDkimSigner_Invalid(t *testing.T) {
+	_, err := NewDkimSigner("wakapi.dev", "mail", []byte("not a key"))
+	assert.Error(t, err)
+}
+
+// verifiableDkimData returns the dkim-signature header's value and the data it was calculated from, as a verifier would reconstruct it
+func verifiableDkimData(t *testing.T, signed string) (string, string) {
+	split := strings.Index(signed, "\r\n\r\n")
+	headers := parseDkimHeaders(signed[:split+2])
+	assert.Equal(t, "DKIM-Signature", headers[0].name)
+
+	value := strings.TrimSpace(headers[0].value)
+	bodyHash := sha256.Sum256([]byte(dkimRelaxedBody(signed[split+4:])))
+	assert.Contains(t, value, "bh="+base64.StdEncoding.EncodeToString(bodyHash[:])+";")
+
+	names := strings.Split(value[strings.Index(value, "h=")+2:strings.Index(value, "; bh=")], ":")
+	var data strings.Builder
+	for _, name := range names {
+		for i := len(headers) - 1; i > 0; i-- {
+			if strings.EqualFold(headers[i].name, name) {
+				data.WriteString(dkimRelaxedHeader(headers[i].name, headers[i].value))
+				break
+			}
+		}
+	}
+	data.WriteString(strings.TrimSuffix(dkimRelaxedHeader("DKIM-Signature", value[:strings.Index(value, "; b=")+4]), "\r\n"))
+	return value, data.String()
+}
@@ -14,3 +14,8 @@ func CheckEmailMX(email string) bool {
 	records, err := net.LookupMX(parts[1])
 	return len(records) > 0 && err == nil
 }
+
+// IsPublicIP returns false for loopback, private, link-local, multicast and unspecified addresses, i.e. all those that must not be reachable through user-supplied urls
+func IsPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
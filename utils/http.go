@@ -7,10 +7,12 @@ import (
 	"github.com/duke-git/lancet/v2/strutil"
 	"github.com/mileusna/useragent"
 	"io"
+	"net"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -22,6 +24,8 @@ var (
 	cacheMaxAgeRe *regexp.Regexp
 )
 
+var ErrNonPublicAddress = errors.New("refusing to connect to non-public address")
+
 func init() {
 	cacheMaxAgeRe = regexp.MustCompile(cacheMaxAgePattern)
 }
@@ -99,6 +103,15 @@ func ParseUserAgent(ua string) (string, string, error) { // os, editor, err
 	return "", "", errors.New("failed to parse user agent string")
 }
 
+// ParseRemoteIp extracts the client ip from a remote address including port or a list of forwarded addresses
+func ParseRemoteIp(remoteAddr string) string {
+	ip := strings.TrimSpace(strings.Split(remoteAddr, ",")[0])
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		return host
+	}
+	return ip
+}
+
 func RaiseForStatus(res *http.Response, err error) (*http.Response, error) {
 	if err != nil {
 		return res, err
@@ -116,3 +129,30 @@ func RaiseForStatus(res *http.Response, err error) (*http.Response, error) {
 	}
 	return res, nil
 }
+
+// NewPublicOnlyHttpClient returns a client for requests against user-supplied urls, which refuses to connect to any non-public address (see IsPublicIP)
+// the check is performed on the resolved address of every connection, so it also applies to redirects and can not be bypassed through dns
+func NewPublicOnlyHttpClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+				return ErrNonPublicAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // a proxy would connect on our behalf, bypassing the check
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tag values originate from user-provided heartbeats, line breaks can't be escaped in line protocol and would allow to inject arbitrary points,
+// so they're replaced by (escaped) spaces, backslashes are escaped, such that a trailing one can't escape the subsequent delimiter
+var (
+	influxMeasurementEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, " ", `\ `, "\n", `\ `, "\r", `\ `)
+	influxTagEscaper         = strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`, " ", `\ `, "\n", `\ `, "\r", `\ `)
+)
+
+// InfluxPoint is a single data point in influxdb's line protocol, see https://docs.influxdata.com/influxdb/v2/reference/syntax/line-protocol/
+// only integer fields are supported, as that's all we need
+type InfluxPoint struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      map[string]int64
+	Time        time.Time
+}
+
+// Line encodes the point with second precision, tags and fields are sorted by key and empty tag values are omitted, as these are not allowed
+func (p *InfluxPoint) Line() string {
+	var sb strings.Builder
+	sb.WriteString(influxMeasurementEscaper.Replace(p.Measurement))
+
+	tagKeys := make([]string, 0, len(p.Tags))
+	for k := range p.Tags {
+		tagKeys = append(tagKeys, k)
+	}
+	sort.Strings(tagKeys)
+	for _, k := range tagKeys {
+		if p.Tags[k] == "" {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf(",%s=%s", influxTagEscaper.Replace(k), influxTagEscaper.Replace(p.Tags[k])))
+	}
+
+	fieldKeys := make([]string, 0, len(p.Fields))
+	for k := range p.Fields {
+		fieldKeys = append(fieldKeys, k)
+	}
+	sort.Strings(fieldKeys)
+	for i, k := range fieldKeys {
+		sep := ","
+		if i == 0 {
+			sep = " "
+		}
+		sb.WriteString(fmt.Sprintf("%s%s=%di", sep, influxTagEscaper.Replace(k), p.Fields[k]))
+	}
+
+	sb.WriteString(fmt.Sprintf(" %d", p.Time.Unix()))
+	return sb.String()
+}
+
+// EncodeInfluxPoints returns the newline-separated lines of all given points
+func EncodeInfluxPoints(points []*InfluxPoint) []byte {
+	lines := make([]string, len(points))
+	for i, p := range points {
+		lines[i] = p.Line()
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
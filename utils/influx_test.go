@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfluxPoint_Line(t *testing.T) {
+	point := &InfluxPoint{
+		Measurement: "wakapi language",
+		Tags:        map[string]string{"user": "john", "language": "C, C++=1", "machine": ""},
+		Fields:      map[string]int64{"seconds": 3600, "heartbeats": 42},
+		Time:        time.Unix(1704067200, 0),
+	}
+	assert.Equal(t, `wakapi\ language,language=C\,\ C++\=1,user=john heartbeats=42i,seconds=3600i 1704067200`, point.Line())
+}
+
+func TestInfluxPoint_Line_Injection(t *testing.T) {
+	point := &InfluxPoint{
+		Measurement: "wakapi_project",
+		Tags:        map[string]string{"project": "evil 1\nwakapi_project,user=admin seconds=999999i 1\r\n", "user": "john\\"},
+		Fields:      map[string]int64{"seconds": 60},
+		Time:        time.Unix(1704067200, 0),
+	}
+	line := point.Line()
+	assert.NotContains(t, line, "\n")
+	assert.NotContains(t, line, "\r")
+	assert.Equal(t, `wakapi_project,project=evil\ 1\ wakapi_project\,user\=admin\ seconds\=999999i\ 1\ \ ,user=john\\ seconds=60i 1704067200`, line)
+}
+
+func TestEncodeInfluxPoints(t *testing.T) {
+	points := []*InfluxPoint{
+		{Measurement: "a", Fields: map[string]int64{"v": 1}, Time: time.Unix(1, 0)},
+		{Measurement: "b", Tags: map[string]string{"k": "v"}, Fields: map[string]int64{"v": 2}, Time: time.Unix(2, 0)},
+	}
+	assert.Equal(t, "a v=1i 1\nb,k=v v=2i 2", string(EncodeInfluxPoints(points)))
+}
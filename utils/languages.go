@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/data"
+)
+
+// languageDatabase maps lower-case file extensions (without leading dot) and full file names to languages, mostly following github linguist's naming
+type languageDatabase struct {
+	Extensions map[string]string `json:"extensions"`
+	Filenames  map[string]string `json:"filenames"`
+}
+
+var (
+	languageDb     *languageDatabase
+	languageDbOnce sync.Once
+)
+
+// DetectLanguage guesses the language of the file at the given path from its name or extension, returns an empty string if unknown
+// compound extensions (e.g. .blade.php) take precedence over simple ones (e.g. .php)
+func DetectLanguage(path string) string {
+	db := getLanguageDatabase()
+
+	filename := strings.ToLower(path[strings.LastIndexAny(path, "/\\")+1:])
+	if language, ok := db.Filenames[filename]; ok {
+		return language
+	}
+
+	for i := strings.Index(filename, "."); i >= 0 && i < len(filename)-1; {
+		if language, ok := db.Extensions[filename[i+1:]]; ok {
+			return language
+		}
+		next := strings.Index(filename[i+1:], ".")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return ""
+}
+
+func getLanguageDatabase() *languageDatabase {
+	languageDbOnce.Do(func() {
+		languageDb = &languageDatabase{}
+		if err := json.Unmarshal(data.LanguagesFile, languageDb); err != nil {
+			logbuch.Fatal("failed to parse language database - %v", err)
+		}
+	})
+	return languageDb
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{"/home/user/dev/wakapi/main.go", "Go"},
+		{"C:\\Users\\user\\dev\\app\\Program.CS", "C#"},
+		{"src/components/App.tsx", "TSX"},
+		{"src/types/index.d.ts", "TypeScript"},
+		{"resources/views/welcome.blade.php", "Blade"},
+		{"app/Http/Kernel.php", "PHP"},
+		{"/home/user/dev/wakapi/Dockerfile", "Dockerfile"},
+		{"/home/user/dev/wakapi/Makefile", "Makefile"},
+		{"/home/user/.zshrc", "Shell"},
+		{"/home/user/.config/.hidden.py", "Python"},
+		{"hashed:0123456789abcdef0123456789abcdef.rs", "Rust"},
+		{"/home/user/dev/wakapi/unknown.foobar", ""},
+		{"/home/user/dev/wakapi/noextension", ""},
+		{"/home/user/dev/wakapi/trailingdot.", ""},
+		{"", ""},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, DetectLanguage(tc.path), tc.path)
+	}
+}
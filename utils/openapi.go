@@ -0,0 +1,276 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/duke-git/lancet/v2/slice"
+)
+
+const openApiVersion = "3.0.3"
+
+// parameter attributes, which go into the parameter's schema in openapi 3
+var openApiSchemaKeys = []string{"type", "format", "items", "enum", "default", "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "minLength", "maxLength", "pattern", "minItems", "maxItems", "uniqueItems"}
+
+// ConvertSwaggerToOpenApi3 translates a swagger 2.0 spec, as generated by swag from the handlers' annotations, to an openapi 3.0 spec, with the given url as its only server
+// only covers the subset of swagger used by swag, e.g. there are no links, callbacks or (non-basic) http auth schemes
+func ConvertSwaggerToOpenApi3(swagger []byte, serverUrl string) ([]byte, error) {
+	var spec map[string]interface{}
+	if err := json.Unmarshal(swagger, &spec); err != nil {
+		return nil, err
+	}
+	if spec["swagger"] != "2.0" {
+		return nil, errors.New("not a swagger 2.0 spec")
+	}
+
+	consumes := openApiMediaTypes(spec["consumes"])
+	produces := openApiMediaTypes(spec["produces"])
+
+	paths := make(map[string]interface{})
+	for path, item := range asMap(spec["paths"]) {
+		paths[path] = convertPathItem(asMap(item), consumes, produces)
+	}
+
+	components := map[string]interface{}{
+		"schemas": asMap(spec["definitions"]),
+	}
+	if securityDefinitions := asMap(spec["securityDefinitions"]); len(securityDefinitions) > 0 {
+		securitySchemes := make(map[string]interface{}, len(securityDefinitions))
+		for name, definition := range securityDefinitions {
+			securitySchemes[name] = convertSecurityScheme(asMap(definition))
+		}
+		components["securitySchemes"] = securitySchemes
+	}
+
+	result := map[string]interface{}{
+		"openapi":    openApiVersion,
+		"info":       spec["info"],
+		"servers":    []interface{}{map[string]interface{}{"url": serverUrl}},
+		"paths":      paths,
+		"components": components,
+	}
+	for _, key := range []string{"tags", "security", "externalDocs"} {
+		if v, ok := spec[key]; ok {
+			result[key] = v
+		}
+	}
+
+	return json.Marshal(rewriteRefs(result))
+}
+
+func convertPathItem(item map[string]interface{}, consumes, produces []string) map[string]interface{} {
+	result := make(map[string]interface{}, len(item))
+	for key, value := range item {
+		switch key {
+		case "parameters":
+			parameters, _ := convertParameters(asSlice(value), consumes)
+			result[key] = parameters
+		case "$ref":
+			result[key] = value
+		default:
+			result[key] = convertOperation(asMap(value), consumes, produces)
+		}
+	}
+	return result
+}
+
+func convertOperation(op map[string]interface{}, consumes, produces []string) map[string]interface{} {
+	if v, ok := op["consumes"]; ok {
+		consumes = openApiMediaTypes(v)
+	}
+	if v, ok := op["produces"]; ok {
+		produces = openApiMediaTypes(v)
+	}
+
+	result := make(map[string]interface{}, len(op))
+	for _, key := range []string{"summary", "description", "operationId", "tags", "security", "deprecated", "externalDocs"} {
+		if v, ok := op[key]; ok {
+			result[key] = v
+		}
+	}
+
+	parameters, requestBody := convertParameters(asSlice(op["parameters"]), consumes)
+	if len(parameters) > 0 {
+		result["parameters"] = parameters
+	}
+	if requestBody != nil {
+		result["requestBody"] = requestBody
+	}
+
+	responses := make(map[string]interface{})
+	for code, response := range asMap(op["responses"]) {
+		responses[code] = convertResponse(code, asMap(response), produces)
+	}
+	if len(responses) == 0 {
+		responses["default"] = map[string]interface{}{"description": "Response"}
+	}
+	result["responses"] = responses
+
+	return result
+}
+
+// convertParameters splits swagger parameters into openapi 3 parameters and a request body, which replaces body and form data parameters
+func convertParameters(params []interface{}, consumes []string) ([]interface{}, map[string]interface{}) {
+	parameters := make([]interface{}, 0, len(params))
+	var requestBody map[string]interface{}
+
+	formProperties, formRequired, hasFile := make(map[string]interface{}), make([]interface{}, 0), false
+
+	for _, p := range params {
+		param := asMap(p)
+		switch param["in"] {
+		case "body":
+			content := make(map[string]interface{}, len(consumes))
+			for _, mediaType := range consumes {
+				content[mediaType] = map[string]interface{}{"schema": param["schema"]}
+			}
+			requestBody = map[string]interface{}{"content": content}
+			copyKeys(requestBody, param, "description", "required")
+		case "formData":
+			schema := convertParameterSchema(param)
+			if schema["type"] == "file" {
+				schema = map[string]interface{}{"type": "string", "format": "binary"}
+				hasFile = true
+			}
+			copyKeys(schema, param, "description")
+			formProperties[param["name"].(string)] = schema
+			if required, _ := param["required"].(bool); required {
+				formRequired = append(formRequired, param["name"])
+			}
+		default:
+			converted := map[string]interface{}{"schema": convertParameterSchema(param)}
+			copyKeys(converted, param, "name", "in", "description", "required", "allowEmptyValue")
+			switch param["collectionFormat"] {
+			case "multi":
+				converted["style"], converted["explode"] = "form", true
+			case "csv":
+				converted["explode"] = false
+			}
+			parameters = append(parameters, converted)
+		}
+	}
+
+	if len(formProperties) > 0 {
+		mediaType := "application/x-www-form-urlencoded"
+		if hasFile || slice.Contain(consumes, "multipart/form-data") {
+			mediaType = "multipart/form-data"
+		}
+		schema := map[string]interface{}{"type": "object", "properties": formProperties}
+		if len(formRequired) > 0 {
+			schema["required"] = formRequired
+		}
+		requestBody = map[string]interface{}{"content": map[string]interface{}{mediaType: map[string]interface{}{"schema": schema}}}
+	}
+
+	return parameters, requestBody
+}
+
+func convertParameterSchema(param map[string]interface{}) map[string]interface{} {
+	schema := make(map[string]interface{})
+	copyKeys(schema, param, openApiSchemaKeys...)
+	return schema
+}
+
+func convertResponse(code string, response map[string]interface{}, produces []string) map[string]interface{} {
+	result := map[string]interface{}{"description": response["description"]}
+	if description, _ := response["description"].(string); description == "" {
+		result["description"] = "Response"
+		if status, err := strconv.Atoi(code); err == nil && http.StatusText(status) != "" {
+			result["description"] = http.StatusText(status)
+		}
+	}
+
+	if schema, ok := response["schema"]; ok {
+		content := make(map[string]interface{}, len(produces))
+		for _, mediaType := range produces {
+			content[mediaType] = map[string]interface{}{"schema": schema}
+		}
+		result["content"] = content
+	}
+
+	if headers := asMap(response["headers"]); len(headers) > 0 {
+		converted := make(map[string]interface{}, len(headers))
+		for name, h := range headers {
+			header := asMap(h)
+			converted[name] = map[string]interface{}{"description": header["description"], "schema": convertParameterSchema(header)}
+		}
+		result["headers"] = converted
+	}
+
+	return result
+}
+
+func convertSecurityScheme(definition map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	switch definition["type"] {
+	case "basic":
+		result["type"], result["scheme"] = "http", "basic"
+	case "oauth2":
+		result["type"] = "oauth2"
+		flow := make(map[string]interface{})
+		copyKeys(flow, definition, "authorizationUrl", "tokenUrl", "scopes")
+		flowName := map[interface{}]string{"implicit": "implicit", "password": "password", "application": "clientCredentials", "accessCode": "authorizationCode"}[definition["flow"]]
+		result["flows"] = map[string]interface{}{flowName: flow}
+	default:
+		copyKeys(result, definition, "type", "name", "in")
+	}
+	copyKeys(result, definition, "description")
+	return result
+}
+
+// rewriteRefs recursively points references to definitions to the corresponding component schemas
+func rewriteRefs(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for key, value := range t {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				t[key] = strings.Replace(ref, "#/definitions/", "#/components/schemas/", 1)
+			} else {
+				t[key] = rewriteRefs(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range t {
+			t[i] = rewriteRefs(value)
+		}
+	}
+	return v
+}
+
+func openApiMediaTypes(v interface{}) []string {
+	mediaTypes := make([]string, 0)
+	for _, m := range asSlice(v) {
+		if s, ok := m.(string); ok {
+			mediaTypes = append(mediaTypes, s)
+		}
+	}
+	if len(mediaTypes) == 0 {
+		return []string{"application/json"}
+	}
+	return mediaTypes
+}
+
+func copyKeys(dst, src map[string]interface{}, keys ...string) {
+	for _, key := range keys {
+		if v, ok := src[key]; ok {
+			dst[key] = v
+		}
+	}
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+	return map[string]interface{}{}
+}
+
+func asSlice(v interface{}) []interface{} {
+	if s, ok := v.([]interface{}); ok {
+		return s
+	}
+	return []interface{}{}
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSwaggerSpec = `{
+	"swagger": "2.0",
+	"info": {"title": "Wakapi API", "version": "1.0"},
+	"basePath": "/api",
+	"paths": {
+		"/users/{user}/heartbeats": {
+			"post": {
+				"consumes": ["application/json"],
+				"produces": ["application/json"],
+				"tags": ["heartbeat"],
+				"operationId": "post-heartbeat",
+				"security": [{"ApiKeyAuth": []}],
+				"parameters": [
+					{"type": "string", "description": "Username", "name": "user", "in": "path", "required": true},
+					{"type": "boolean", "name": "dry_run", "in": "query"},
+					{"description": "A single heartbeat", "name": "heartbeat", "in": "body", "required": true, "schema": {"$ref": "#/definitions/models.Heartbeat"}}
+				],
+				"responses": {
+					"201": {"description": "Created", "schema": {"type": "array", "items": {"$ref": "#/definitions/models.Heartbeat"}}},
+					"400": {}
+				}
+			}
+		},
+		"/import": {
+			"post": {
+				"parameters": [{"type": "file", "name": "file", "in": "formData", "required": true}],
+				"responses": {"204": {"description": "No Content"}}
+			}
+		}
+	},
+	"definitions": {
+		"models.Heartbeat": {"type": "object", "properties": {"entity": {"type": "string"}}}
+	},
+	"securityDefinitions": {
+		"ApiKeyAuth": {"type": "apiKey", "name": "Authorization", "in": "header"},
+		"BasicAuth": {"type": "basic"}
+	}
+}`
+
+func TestConvertSwaggerToOpenApi3(t *testing.T) {
+	result, err := ConvertSwaggerToOpenApi3([]byte(testSwaggerSpec), "/api")
+	assert.Nil(t, err)
+
+	var spec map[string]interface{}
+	assert.Nil(t, json.Unmarshal(result, &spec))
+
+	assert.Equal(t, "3.0.3", spec["openapi"])
+	assert.Equal(t, "/api", asMap(asSlice(spec["servers"])[0])["url"])
+	assert.Nil(t, spec["definitions"])
+	assert.Contains(t, asMap(asMap(spec["components"])["schemas"]), "models.Heartbeat")
+	assert.Equal(t, "apiKey", asMap(asMap(asMap(spec["components"])["securitySchemes"])["ApiKeyAuth"])["type"])
+	assert.Equal(t, "http", asMap(asMap(asMap(spec["components"])["securitySchemes"])["BasicAuth"])["type"])
+
+	op := asMap(asMap(asMap(spec["paths"])["/users/{user}/heartbeats"])["post"])
+	assert.Equal(t, "post-heartbeat", op["operationId"])
+
+	params := asSlice(op["parameters"])
+	assert.Len(t, params, 2)
+	assert.Equal(t, "path", asMap(params[0])["in"])
+	assert.Equal(t, "string", asMap(asMap(params[0])["schema"])["type"])
+	assert.Nil(t, asMap(params[0])["type"])
+
+	requestBody := asMap(op["requestBody"])
+	assert.Equal(t, true, requestBody["required"])
+	assert.Equal(t, "#/components/schemas/models.Heartbeat", asMap(asMap(asMap(requestBody["content"])["application/json"])["schema"])["$ref"])
+
+	responses := asMap(op["responses"])
+	created := asMap(asMap(asMap(asMap(responses["201"])["content"])["application/json"])["schema"])
+	assert.Equal(t, "#/components/schemas/models.Heartbeat", asMap(created["items"])["$ref"])
+	assert.Equal(t, "Bad Request", asMap(responses["400"])["description"])
+
+	upload := asMap(asMap(asMap(spec["paths"])["/import"])["post"])
+	formSchema := asMap(asMap(asMap(asMap(upload["requestBody"])["content"])["multipart/form-data"])["schema"])
+	assert.Equal(t, "binary", asMap(asMap(formSchema["properties"])["file"])["format"])
+	assert.Equal(t, []interface{}{"file"}, formSchema["required"])
+}
+
+func TestConvertSwaggerToOpenApi3_Invalid(t *testing.T) {
+	_, err := ConvertSwaggerToOpenApi3([]byte(`{"openapi": "3.0.0"}`), "/api")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,318 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type OutboundPriority int
+
+const (
+	OutboundPriorityLow    OutboundPriority = iota // bulk work, e.g. data imports
+	OutboundPriorityNormal                         // background jobs, e.g. mails or release checks
+	OutboundPriorityHigh                           // work on behalf of a pending user request, e.g. heartbeat relaying
+)
+
+var ErrCircuitOpen = errors.New("too many failed requests against destination, circuit is open")
+
+type OutboundDispatcherOptions struct {
+	MaxConcurrent        int                // max. number of concurrent outbound requests in total
+	MaxConcurrentPerHost int                // max. number of concurrent outbound requests per destination host
+	RateLimit            float64            // max. requests per second per destination host (0 for unlimited)
+	RateBurst            int                // number of requests to allow at once before throttling
+	HostRateLimits       map[string]float64 // per-host overrides of RateLimit
+	BreakerThreshold     int                // number of consecutive failures before refusing further requests to a host (0 to disable)
+	BreakerCooldown      time.Duration      // time to refuse requests for before letting a single trial request through
+}
+
+type OutboundHostMetrics struct {
+	Host      string
+	Active    int
+	Requests  int64 // requests actually sent
+	Failures  int64 // transport errors, 429 and 5xx responses
+	Throttled int64 // requests delayed by the host's rate limit
+	Rejected  int64 // requests refused while circuit was open
+	Open      bool
+}
+
+// OutboundDispatcher throttles outbound http requests per destination host, limits total and per-host concurrency, grants free capacity to higher priority requests first and stops sending requests to consistently failing hosts for a while (circuit breaker)
+type OutboundDispatcher struct {
+	options OutboundDispatcherOptions
+	lock    sync.Mutex
+	active  int
+	waiting []*outboundWaiter
+	hosts   map[string]*outboundHost
+	seq     uint64
+}
+
+type outboundHost struct {
+	OutboundHostMetrics
+	tokens     float64
+	refilledAt time.Time
+	failures   int
+	openUntil  time.Time
+	probing    bool
+}
+
+type outboundWaiter struct {
+	host     string
+	priority OutboundPriority
+	seq      uint64
+	granted  chan struct{}
+}
+
+type outboundTransport struct {
+	dispatcher *OutboundDispatcher
+	base       http.RoundTripper
+	priority   OutboundPriority
+}
+
+func NewOutboundDispatcher(options OutboundDispatcherOptions) *OutboundDispatcher {
+	return &OutboundDispatcher{
+		options: options.normalized(),
+		hosts:   map[string]*outboundHost{},
+	}
+}
+
+// SetOptions changes the dispatcher's limits at runtime, e.g. after a config reload
+func (d *OutboundDispatcher) SetOptions(options OutboundDispatcherOptions) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.options = options.normalized()
+	d.dispatch() // limits might have been raised
+}
+
+// Client returns an http client whose requests are all passed through the dispatcher with the given priority
+func (d *OutboundDispatcher) Client(priority OutboundPriority, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: d.Transport(http.DefaultTransport, priority),
+	}
+}
+
+// Transport wraps an existing round tripper, e.g. to dispatch requests of a client with custom dial settings
+func (d *OutboundDispatcher) Transport(base http.RoundTripper, priority OutboundPriority) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &outboundTransport{dispatcher: d, base: base, priority: priority}
+}
+
+func (d *OutboundDispatcher) Metrics() []*OutboundHostMetrics {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	now := time.Now()
+	metrics := make([]*OutboundHostMetrics, 0, len(d.hosts))
+	for _, h := range d.hosts {
+		m := h.OutboundHostMetrics
+		m.Open = now.Before(h.openUntil)
+		metrics = append(metrics, &m)
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].Host < metrics[j].Host
+	})
+	return metrics
+}
+
+func (t *outboundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	d, host := t.dispatcher, req.URL.Host
+
+	if err := d.admit(host); err != nil {
+		return nil, err
+	}
+	if err := d.throttle(req, host); err != nil {
+		d.abort(host)
+		return nil, err
+	}
+	if err := d.acquire(req, host, t.priority); err != nil {
+		d.abort(host)
+		return nil, err
+	}
+
+	res, err := t.base.RoundTrip(req)
+	d.release(host, err != nil || res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500)
+	return res, err
+}
+
+// admit rejects requests to hosts with an open circuit, except for a single trial request once the cooldown has passed
+func (d *OutboundDispatcher) admit(host string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	h := d.host(host)
+	if d.options.BreakerThreshold <= 0 || h.failures < d.options.BreakerThreshold {
+		return nil
+	}
+	if time.Now().Before(h.openUntil) || h.probing {
+		h.Rejected++
+		return fmt.Errorf("%w (%s)", ErrCircuitOpen, host)
+	}
+	h.probing = true
+	return nil
+}
+
+// throttle blocks until the host's token bucket permits another request or the request's context is done
+func (d *OutboundDispatcher) throttle(req *http.Request, host string) error {
+	d.lock.Lock()
+	rate := d.options.RateLimit
+	if r, ok := d.options.HostRateLimits[host]; ok {
+		rate = r
+	}
+	if rate <= 0 {
+		d.lock.Unlock()
+		return nil
+	}
+
+	h := d.host(host)
+	now := time.Now()
+	if h.refilledAt.IsZero() {
+		h.tokens = float64(d.options.RateBurst)
+	} else {
+		h.tokens = math.Min(float64(d.options.RateBurst), h.tokens+now.Sub(h.refilledAt).Seconds()*rate)
+	}
+	h.refilledAt = now
+	h.tokens-- // reserve a token, possibly going into debt, which later requests will have to wait for
+	wait := time.Duration(-h.tokens / rate * float64(time.Second))
+	if wait > 0 {
+		h.Throttled++
+	}
+	d.lock.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// acquire blocks until a slot is free, both in total and for the given host, granting slots to higher priority requests first
+func (d *OutboundDispatcher) acquire(req *http.Request, host string, priority OutboundPriority) error {
+	d.lock.Lock()
+	d.seq++
+	w := &outboundWaiter{host: host, priority: priority, seq: d.seq, granted: make(chan struct{})}
+	d.waiting = append(d.waiting, w)
+	d.dispatch()
+	d.lock.Unlock()
+
+	select {
+	case <-w.granted:
+		return nil
+	case <-req.Context().Done():
+		d.lock.Lock()
+		defer d.lock.Unlock()
+		select {
+		case <-w.granted:
+			// slot was granted concurrently, hand it on
+			d.hosts[host].Active--
+			d.active--
+			d.dispatch()
+		default:
+			d.removeWaiter(w)
+		}
+		return req.Context().Err()
+	}
+}
+
+func (d *OutboundDispatcher) release(host string, failed bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	h := d.host(host)
+	h.Active--
+	h.Requests++
+	h.probing = false
+	d.active--
+
+	if failed {
+		h.Failures++
+		h.failures++
+		if d.options.BreakerThreshold > 0 && h.failures >= d.options.BreakerThreshold {
+			h.openUntil = time.Now().Add(d.options.BreakerCooldown)
+		}
+	} else {
+		h.failures = 0
+		h.openUntil = time.Time{}
+	}
+
+	d.dispatch()
+}
+
+// abort resets a trial request's state when it was never sent
+func (d *OutboundDispatcher) abort(host string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.host(host).probing = false
+}
+
+// dispatch grants free slots to waiting requests, highest priority first, oldest first within the same priority
+// must be called while holding the lock
+func (d *OutboundDispatcher) dispatch() {
+	sort.SliceStable(d.waiting, func(i, j int) bool {
+		if d.waiting[i].priority != d.waiting[j].priority {
+			return d.waiting[i].priority > d.waiting[j].priority
+		}
+		return d.waiting[i].seq < d.waiting[j].seq
+	})
+
+	remaining := d.waiting[:0]
+	for _, w := range d.waiting {
+		if d.fits(w.host) {
+			d.grant(w.host)
+			close(w.granted)
+			continue
+		}
+		remaining = append(remaining, w)
+	}
+	d.waiting = remaining
+}
+
+func (d *OutboundDispatcher) fits(host string) bool {
+	return d.active < d.options.MaxConcurrent && d.host(host).Active < d.options.MaxConcurrentPerHost
+}
+
+func (d *OutboundDispatcher) grant(host string) {
+	d.host(host).Active++
+	d.active++
+}
+
+func (d *OutboundDispatcher) removeWaiter(w *outboundWaiter) {
+	for i, other := range d.waiting {
+		if other == w {
+			d.waiting = append(d.waiting[:i], d.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+func (o OutboundDispatcherOptions) normalized() OutboundDispatcherOptions {
+	if o.MaxConcurrent <= 0 {
+		o.MaxConcurrent = math.MaxInt32
+	}
+	if o.MaxConcurrentPerHost <= 0 {
+		o.MaxConcurrentPerHost = o.MaxConcurrent
+	}
+	if o.RateBurst <= 0 {
+		o.RateBurst = 1
+	}
+	return o
+}
+
+func (d *OutboundDispatcher) host(host string) *outboundHost {
+	if _, ok := d.hosts[host]; !ok {
+		d.hosts[host] = &outboundHost{OutboundHostMetrics: OutboundHostMetrics{Host: host}}
+	}
+	return d.hosts[host]
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboundDispatcher_CircuitBreaker(t *testing.T) {
+	status := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sut := NewOutboundDispatcher(OutboundDispatcherOptions{BreakerThreshold: 3, BreakerCooldown: 50 * time.Millisecond})
+	client := sut.Client(OutboundPriorityNormal, time.Second)
+
+	for i := 0; i < 3; i++ {
+		res, err := client.Get(server.URL)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	}
+
+	_, err := client.Get(server.URL)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+
+	time.Sleep(60 * time.Millisecond)
+	status = http.StatusOK
+
+	res, err := client.Get(server.URL) // trial request
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, err = client.Get(server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	metrics := sut.Metrics()
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, int64(5), metrics[0].Requests)
+	assert.Equal(t, int64(3), metrics[0].Failures)
+	assert.Equal(t, int64(1), metrics[0].Rejected)
+	assert.False(t, metrics[0].Open)
+}
+
+func TestOutboundDispatcher_RateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	sut := NewOutboundDispatcher(OutboundDispatcherOptions{RateLimit: 20, RateBurst: 2})
+	client := sut.Client(OutboundPriorityNormal, time.Second)
+
+	t0 := time.Now()
+	for i := 0; i < 4; i++ {
+		_, err := client.Get(server.URL)
+		assert.Nil(t, err)
+	}
+
+	// 2 requests within burst, 2 more delayed by 50 ms each
+	assert.GreaterOrEqual(t, time.Since(t0), 90*time.Millisecond)
+	assert.Equal(t, int64(2), sut.Metrics()[0].Throttled)
+}
+
+func TestOutboundDispatcher_Priority(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("block") != "" {
+			<-release
+		}
+	}))
+	defer server.Close()
+
+	sut := NewOutboundDispatcher(OutboundDispatcherOptions{MaxConcurrent: 1})
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var order []string
+
+	get := func(priority OutboundPriority, name string) {
+		defer wg.Done()
+		if _, err := sut.Client(priority, time.Second).Get(server.URL + "?name=" + name); err == nil {
+			lock.Lock()
+			order = append(order, name)
+			lock.Unlock()
+		}
+	}
+
+	// occupy the only slot
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sut.Client(OutboundPriorityNormal, time.Second).Get(server.URL + "?block=1")
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	wg.Add(2)
+	go get(OutboundPriorityLow, "low")
+	time.Sleep(20 * time.Millisecond)
+	go get(OutboundPriorityHigh, "high")
+	time.Sleep(20 * time.Millisecond)
+
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, []string{"high", "low"}, order)
+}
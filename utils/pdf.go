@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const PdfMimeType = "application/pdf"
+
+const (
+	pdfPageWidth    = 595 // a4 in points
+	pdfPageHeight   = 842
+	pdfMargin       = 50
+	pdfFontSize     = 9
+	pdfTitleSize    = 14
+	pdfLineHeight   = 12
+	pdfLinesPerPage = (pdfPageHeight - 2*pdfMargin) / pdfLineHeight
+)
+
+// PdfDocument is a plain text document, whose title is printed in bold on top of the first page
+// lines are set in a monospace font, so they can be aligned to columns by padding with spaces
+type PdfDocument struct {
+	Title string
+	Lines []string
+}
+
+// WritePdf writes a minimal pdf 1.4 file of the given document, split into as many a4 pages as needed
+// only the standard courier fonts are used (no embedding), characters outside latin-1 are replaced by question marks
+func WritePdf(w io.Writer, doc *PdfDocument) error {
+	lines := doc.Lines
+	if doc.Title != "" {
+		lines = append([]string{"", ""}, lines...) // leave room for the title
+	}
+
+	pages := make([][]string, 0)
+	for len(lines) > pdfLinesPerPage {
+		pages = append(pages, lines[:pdfLinesPerPage])
+		lines = lines[pdfLinesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	var buf bytes.Buffer
+	offsets := make([]int, 0)
+	writeObject := func(body string) {
+		offsets = append(offsets, buf.Len())
+		buf.WriteString(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", len(offsets), body))
+	}
+
+	// objects 1 - 4 are catalog, page tree and fonts, followed by a page and a content stream object per page
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range pages {
+		var content strings.Builder
+		if i == 0 && doc.Title != "" {
+			content.WriteString(fmt.Sprintf("BT /F2 %d Tf %d %d Td (%s) Tj ET\n", pdfTitleSize, pdfMargin, pdfPageHeight-pdfMargin-pdfTitleSize, pdfEscape(doc.Title)))
+		}
+		content.WriteString(fmt.Sprintf("BT /F1 %d Tf %d TL %d %d Td\n", pdfFontSize, pdfLineHeight, pdfMargin, pdfPageHeight-pdfMargin-pdfFontSize))
+		for _, line := range page {
+			content.WriteString(fmt.Sprintf("(%s) Tj T*\n", pdfEscape(line)))
+		}
+		content.WriteString("ET")
+
+		writeObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, 6+2*i))
+		writeObject(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	xrefOffset := buf.Len()
+	buf.WriteString(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1))
+	for _, o := range offsets {
+		buf.WriteString(fmt.Sprintf("%010d 00000 n \n", o))
+	}
+	buf.WriteString(fmt.Sprintf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xrefOffset))
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pdfEscape encodes a string as latin-1 and escapes characters with special meaning in pdf string literals
+func pdfEscape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r < 0x20 || (r >= 0x7f && r < 0xa0) || r > 0xff:
+			sb.WriteByte('?')
+		default:
+			sb.WriteByte(byte(r))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPdf_WritePdf(t *testing.T) {
+	var buf bytes.Buffer
+	err := WritePdf(&buf, &PdfDocument{Title: "Invoice (draft)", Lines: []string{"Client  Hours", "Acme    1.50", "Grüße ✓"}})
+	assert.Nil(t, err)
+
+	data := buf.String()
+	assert.True(t, strings.HasPrefix(data, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(data, "%%EOF\n"))
+	assert.Contains(t, data, "/Count 1")
+	assert.Contains(t, data, `(Invoice \(draft\)) Tj`)
+	assert.Contains(t, data, "(Acme    1.50) Tj")
+	assert.Contains(t, data, "(Gr\xfc\xdfe ?) Tj")
+
+	// xref offsets must point to the respective objects
+	xref := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(data, -1)
+	assert.Len(t, xref, 6)
+	for i, m := range xref {
+		offset, _ := strconv.Atoi(m[1])
+		assert.True(t, strings.HasPrefix(data[offset:], fmt.Sprintf("%d 0 obj", i+1)))
+	}
+}
+
+func TestPdf_WritePdf_MultiplePages(t *testing.T) {
+	lines := make([]string, 2*pdfLinesPerPage)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i)
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, WritePdf(&buf, &PdfDocument{Lines: lines}))
+	assert.Contains(t, buf.String(), "/Kids [5 0 R 7 0 R] /Count 2")
+}
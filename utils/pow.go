@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const powNonceBytes = 16
+
+var (
+	ErrPowChallengeInvalid = errors.New("invalid or expired challenge")
+	ErrPowSolutionInvalid  = errors.New("invalid solution")
+)
+
+// PowChallenger issues and verifies stateless proof-of-work challenges
+// a challenge is of the form <timestamp>.<nonce>.<signature>, a solution is any string, for which sha256(challenge + solution) has at least the given number of leading zero bits
+type PowChallenger struct {
+	key        []byte
+	difficulty int
+	maxAge     time.Duration
+	now        func() time.Time
+}
+
+func NewPowChallenger(key []byte, difficulty int, maxAge time.Duration) *PowChallenger {
+	return &PowChallenger{key: key, difficulty: difficulty, maxAge: maxAge, now: time.Now}
+}
+
+func (p *PowChallenger) Difficulty() int {
+	return p.difficulty
+}
+
+func (p *PowChallenger) Issue() (string, error) {
+	nonce := make([]byte, powNonceBytes)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	payload := fmt.Sprintf("%d.%s", p.now().Unix(), hex.EncodeToString(nonce))
+	return payload + "." + p.sign(payload), nil
+}
+
+func (p *PowChallenger) Verify(challenge, solution string) error {
+	i := strings.LastIndex(challenge, ".")
+	if i < 0 || !hmac.Equal([]byte(challenge[i+1:]), []byte(p.sign(challenge[:i]))) {
+		return ErrPowChallengeInvalid
+	}
+	ts, err := strconv.ParseInt(strings.SplitN(challenge, ".", 2)[0], 10, 64)
+	if err != nil || p.now().Sub(time.Unix(ts, 0)) > p.maxAge {
+		return ErrPowChallengeInvalid
+	}
+
+	digest := sha256.Sum256([]byte(challenge + solution))
+	if PowLeadingZeroBits(digest[:]) < p.difficulty {
+		return ErrPowSolutionInvalid
+	}
+	return nil
+}
+
+func (p *PowChallenger) sign(payload string) string {
+	h := hmac.New(sha256.New, p.key)
+	h.Write([]byte(payload))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func PowLeadingZeroBits(digest []byte) int {
+	var n int
+	for _, b := range digest {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
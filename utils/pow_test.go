@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPowLeadingZeroBits(t *testing.T) {
+	assert.Equal(t, 0, PowLeadingZeroBits([]byte{0x80, 0x00}))
+	assert.Equal(t, 3, PowLeadingZeroBits([]byte{0x10, 0x00}))
+	assert.Equal(t, 12, PowLeadingZeroBits([]byte{0x00, 0x0f}))
+	assert.Equal(t, 16, PowLeadingZeroBits([]byte{0x00, 0x00}))
+}
+
+func TestPowChallenger_Verify(t *testing.T) {
+	sut := NewPowChallenger([]byte("secret"), 8, 10*time.Minute)
+
+	challenge, err := sut.Issue()
+	assert.Nil(t, err)
+
+	var solution string
+	for i := 0; ; i++ {
+		digest := sha256.Sum256([]byte(challenge + strconv.Itoa(i)))
+		if PowLeadingZeroBits(digest[:]) >= 8 {
+			solution = strconv.Itoa(i)
+			break
+		}
+	}
+	assert.Nil(t, sut.Verify(challenge, solution))
+
+	// tampered challenge
+	assert.ErrorIs(t, sut.Verify("1"+challenge, solution), ErrPowChallengeInvalid)
+	// challenge issued with a different key
+	assert.ErrorIs(t, NewPowChallenger([]byte("other"), 8, 10*time.Minute).Verify(challenge, solution), ErrPowChallengeInvalid)
+
+	// expired challenge
+	sut.now = func() time.Time { return time.Now().Add(11 * time.Minute) }
+	assert.ErrorIs(t, sut.Verify(challenge, solution), ErrPowChallengeInvalid)
+	sut.now = time.Now
+
+	// unsolved challenge, chances of a random string meeting the difficulty are negligible
+	sut.difficulty = 32
+	assert.ErrorIs(t, sut.Verify(challenge, solution), ErrPowSolutionInvalid)
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// Privatizer makes aggregate statistics differentially private before they're published, by suppressing aggregates computed from too few users and adding laplace noise to all others
+// noise must only be drawn once per published value, as repeatedly querying freshly noised values of the same aggregate would allow for averaging the noise out
+type Privatizer struct {
+	epsilon  float64 // privacy budget per published value, smaller values mean more noise
+	minUsers int     // min. number of distinct users an aggregate must be based on to be published at all
+	rand     *rand.Rand
+	lock     sync.Mutex
+}
+
+func NewPrivatizer(epsilon float64, minUsers int) *Privatizer {
+	var seed [8]byte
+	crand.Read(seed[:])
+	return &Privatizer{
+		epsilon:  epsilon,
+		minUsers: minUsers,
+		rand:     rand.New(rand.NewSource(int64(binary.LittleEndian.Uint64(seed[:])))),
+	}
+}
+
+// Noise draws laplace noise for an aggregate, which each user can have changed by at most sensitivity
+// it must only be drawn once per aggregate and be reused for all later publications of the same aggregate
+func (p *Privatizer) Noise(sensitivity float64) float64 {
+	return p.laplace(sensitivity / p.epsilon)
+}
+
+// Privatize returns a noisy version of the given aggregate, which is based on the given number of users, by adding the noise previously drawn for it
+// returns false if the aggregate must not be published
+func (p *Privatizer) Privatize(value, noise float64, users int) (float64, bool) {
+	if users < p.minUsers || p.epsilon <= 0 {
+		return 0, false
+	}
+	return math.Max(0, math.Round(value+noise)), true
+}
+
+// Clamp bounds a single user's contribution to an aggregate, so that the aggregate's sensitivity is known
+func (p *Privatizer) Clamp(value, max float64) float64 {
+	return math.Max(0, math.Min(value, max))
+}
+
+func (p *Privatizer) laplace(scale float64) float64 {
+	p.lock.Lock()
+	u := p.rand.Float64() - 0.5
+	p.lock.Unlock()
+	if u < 0 {
+		return scale * math.Log(1+2*u)
+	}
+	return -scale * math.Log(1-2*u)
+}
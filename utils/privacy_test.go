@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrivatizer_Privatize(t *testing.T) {
+	sut := NewPrivatizer(1.0, 10)
+
+	_, ok := sut.Privatize(100, sut.Noise(1), 9)
+	assert.False(t, ok)
+
+	var sum float64
+	n := 10000
+	for i := 0; i < n; i++ {
+		v, ok := sut.Privatize(1000, sut.Noise(1), 10)
+		assert.True(t, ok)
+		assert.GreaterOrEqual(t, v, 0.0)
+		assert.Equal(t, math.Round(v), v)
+		sum += v
+	}
+
+	// laplace noise is centered around zero
+	assert.InDelta(t, 1000, sum/float64(n), 0.5)
+
+	v, _ := sut.Privatize(1000, 12.4, 10)
+	assert.Equal(t, 1012.0, v)
+}
+
+func TestPrivatizer_Clamp(t *testing.T) {
+	sut := NewPrivatizer(1.0, 10)
+	assert.Equal(t, 10.0, sut.Clamp(25, 10))
+	assert.Equal(t, 5.0, sut.Clamp(5, 10))
+	assert.Equal(t, 0.0, sut.Clamp(-1, 10))
+}
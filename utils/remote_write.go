@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// Minimal encoder for prometheus remote write requests, see https://prometheus.io/docs/concepts/remote_write_spec/
+// Since we only ever send a handful of samples, we neither need the protobuf nor the snappy libraries as a dependency
+
+type RemoteWriteLabel struct {
+	Name  string
+	Value string
+}
+
+type RemoteWriteSeries struct {
+	Labels    []RemoteWriteLabel // must include __name__
+	Value     float64
+	Timestamp int64 // milliseconds since epoch
+}
+
+// EncodeRemoteWriteRequest returns the snappy-compressed, protobuf-encoded WriteRequest message for the given series
+func EncodeRemoteWriteRequest(series []*RemoteWriteSeries) []byte {
+	var request []byte
+	for _, s := range series {
+		request = appendProtoBytes(request, 1, encodeRemoteWriteSeries(s))
+	}
+	return SnappyEncodeUncompressed(request)
+}
+
+// TimeSeries message, labels are required to be sorted by name
+func encodeRemoteWriteSeries(s *RemoteWriteSeries) []byte {
+	labels := make([]RemoteWriteLabel, len(s.Labels))
+	copy(labels, s.Labels)
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+
+	var msg []byte
+	for _, l := range labels {
+		var label []byte
+		label = appendProtoBytes(label, 1, []byte(l.Name))
+		label = appendProtoBytes(label, 2, []byte(l.Value))
+		msg = appendProtoBytes(msg, 1, label)
+	}
+
+	var sample []byte
+	sample = binary.AppendUvarint(sample, 1<<3|1) // field 1, wire type i64
+	sample = binary.LittleEndian.AppendUint64(sample, math.Float64bits(s.Value))
+	sample = binary.AppendUvarint(sample, 2<<3|0) // field 2, wire type varint
+	sample = binary.AppendUvarint(sample, uint64(s.Timestamp))
+	return appendProtoBytes(msg, 2, sample)
+}
+
+// appendProtoBytes appends a length-delimited field (strings, bytes, embedded messages)
+func appendProtoBytes(buf []byte, field int, data []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|2)
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+// SnappyEncodeUncompressed produces a valid snappy block (as opposed to the framing format), which consists of literals only, i.e. is not actually compressed
+// see https://github.com/google/snappy/blob/main/format_description.txt
+func SnappyEncodeUncompressed(data []byte) []byte {
+	const maxLiteral = 1 << 16
+
+	buf := binary.AppendUvarint(make([]byte, 0, len(data)+len(data)/maxLiteral*3+8), uint64(len(data)))
+	for len(data) > 0 {
+		n := len(data)
+		if n > maxLiteral {
+			n = maxLiteral
+		}
+		if n <= 60 {
+			buf = append(buf, byte(n-1)<<2)
+		} else if n <= 1<<8 {
+			buf = append(buf, 60<<2, byte(n-1))
+		} else {
+			buf = append(buf, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		buf = append(buf, data[:n]...)
+		data = data[n:]
+	}
+	return buf
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeRemoteWriteRequest(t *testing.T) {
+	encoded := EncodeRemoteWriteRequest([]*RemoteWriteSeries{
+		{Labels: []RemoteWriteLabel{{Name: "__name__", Value: "up"}}, Value: 1, Timestamp: 1000},
+	})
+
+	// snappy preamble and literal tag
+	expected := "207c"
+	// WriteRequest.timeseries
+	expected += "0a1e"
+	// TimeSeries.labels
+	expected += "0a0e" + "0a08" + hex.EncodeToString([]byte("__name__")) + "1202" + hex.EncodeToString([]byte("up"))
+	// TimeSeries.samples
+	expected += "120c" + "09000000000000f03f" + "10e807"
+
+	assert.Equal(t, expected, hex.EncodeToString(encoded))
+}
+
+func TestEncodeRemoteWriteRequest_SortsLabels(t *testing.T) {
+	a := EncodeRemoteWriteRequest([]*RemoteWriteSeries{{Labels: []RemoteWriteLabel{{Name: "job", Value: "wakapi"}, {Name: "__name__", Value: "up"}}}})
+	b := EncodeRemoteWriteRequest([]*RemoteWriteSeries{{Labels: []RemoteWriteLabel{{Name: "__name__", Value: "up"}, {Name: "job", Value: "wakapi"}}}})
+	assert.Equal(t, a, b)
+}
+
+func TestSnappyEncodeUncompressed(t *testing.T) {
+	for _, n := range []int{0, 1, 60, 61, 256, 257, 70000, 200000} {
+		data := bytes.Repeat([]byte{'x'}, n)
+		assert.Equal(t, data, decodeSnappyLiterals(t, SnappyEncodeUncompressed(data)), "length %d", n)
+	}
+}
+
+// decodeSnappyLiterals is a minimal snappy block decoder, which only supports literal elements
+func decodeSnappyLiterals(t *testing.T, buf []byte) []byte {
+	length, n := binary.Uvarint(buf)
+	buf = buf[n:]
+	out := make([]byte, 0, length)
+	for len(buf) > 0 {
+		tag := buf[0]
+		assert.Equal(t, byte(0), tag&0x03, "not a literal")
+		size := int(tag>>2) + 1
+		buf = buf[1:]
+		switch tag >> 2 {
+		case 60:
+			size, buf = int(buf[0])+1, buf[1:]
+		case 61:
+			size, buf = int(binary.LittleEndian.Uint16(buf))+1, buf[2:]
+		}
+		out, buf = append(out, buf[:size]...), buf[size:]
+	}
+	assert.Equal(t, int(length), len(out))
+	return out
+}
@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	s3DefaultTimeout = 60 * time.Second
+	s3MaxPresignTtl  = 7 * 24 * time.Hour
+	s3UploadPartSize = 8 * 1024 * 1024 // parts are buffered in memory, one per concurrent upload
+)
+
+var ErrS3NotFound = errors.New("s3 object not found")
+
+type S3Options struct {
+	Endpoint  string // optional, defaults to aws, e.g. https://minio.example.org
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool // address buckets as path instead of as subdomain, required by most self-hosted s3-compatible stores
+}
+
+// S3PutOptions are optional attributes of an uploaded object
+type S3PutOptions struct {
+	ServerSideEncryption string            // e.g. "AES256" to have the store encrypt the object at rest
+	Tags                 map[string]string // object tags, e.g. for lifecycle rules to match on
+}
+
+// S3Client is a thin wrapper around the aws sdk, supporting only what's needed to store and retrieve files in s3-compatible object stores
+type S3Client struct {
+	options  S3Options
+	client   *s3.Client
+	uploader *manager.Uploader
+}
+
+func NewS3Client(options S3Options, httpClient *http.Client) (*S3Client, error) {
+	if options.Bucket == "" || options.Region == "" {
+		return nil, errors.New("s3 bucket and region must be set")
+	}
+	if options.AccessKey == "" || options.SecretKey == "" {
+		return nil, errors.New("s3 access key and secret key must be set")
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: s3DefaultTimeout}
+	}
+
+	clientOptions := s3.Options{
+		Region:       options.Region,
+		Credentials:  credentials.NewStaticCredentialsProvider(options.AccessKey, options.SecretKey, ""),
+		UsePathStyle: options.PathStyle,
+		HTTPClient:   httpClient,
+	}
+	if options.Endpoint != "" {
+		if _, err := url.ParseRequestURI(options.Endpoint); err != nil {
+			return nil, err
+		}
+		clientOptions.BaseEndpoint = aws.String(strings.TrimSuffix(options.Endpoint, "/"))
+	}
+
+	client := s3.New(clientOptions)
+	return &S3Client{
+		options: options,
+		client:  client,
+		uploader: manager.NewUploader(client, func(u *manager.Uploader) {
+			u.PartSize = s3UploadPartSize
+		}),
+	}, nil
+}
+
+func (c *S3Client) PutObject(key string, data []byte, contentType string) error {
+	return c.PutObjectWithOptions(key, data, contentType, S3PutOptions{})
+}
+
+func (c *S3Client) PutObjectWithOptions(key string, data []byte, contentType string, options S3PutOptions) error {
+	return c.Upload(key, bytes.NewReader(data), contentType, options)
+}
+
+// Upload streams the given reader's content to the store, using a multipart upload for anything larger than a single part, so that arbitrarily large objects never have to be held in memory as a whole
+func (c *S3Client) Upload(key string, body io.Reader, contentType string, options S3PutOptions) error {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(c.options.Bucket),
+		Key:    aws.String(key),
+		Body:   body,
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	if options.ServerSideEncryption != "" {
+		input.ServerSideEncryption = types.ServerSideEncryption(options.ServerSideEncryption)
+	}
+	if len(options.Tags) > 0 {
+		tags := url.Values{}
+		for k, v := range options.Tags {
+			tags.Set(k, v)
+		}
+		input.Tagging = aws.String(tags.Encode())
+	}
+
+	_, err := c.uploader.Upload(context.Background(), input)
+	return err
+}
+
+func (c *S3Client) GetObject(key string) ([]byte, error) {
+	out, err := c.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(c.options.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+func (c *S3Client) DeleteObject(key string) error {
+	_, err := c.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(c.options.Bucket),
+		Key:    aws.String(key),
+	})
+	return s3Error(err)
+}
+
+// ListObjects returns the keys of all objects starting with the given prefix
+func (c *S3Client) ListObjects(prefix string) ([]string, error) {
+	keys := make([]string, 0)
+
+	paginator := s3.NewListObjectsV2Paginator(c.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(c.options.Bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, s3Error(err)
+		}
+		for _, o := range page.Contents {
+			keys = append(keys, aws.ToString(o.Key))
+		}
+	}
+	return keys, nil
+}
+
+// PresignGetObject returns a url, through which the given object can be downloaded without any further credentials until it expires (at most after 7 days)
+func (c *S3Client) PresignGetObject(key string, ttl time.Duration) (string, error) {
+	if ttl <= 0 || ttl > s3MaxPresignTtl {
+		return "", errors.New("invalid presigned url expiry")
+	}
+
+	req, err := s3.NewPresignClient(c.client, s3.WithPresignExpires(ttl)).PresignGetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(c.options.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+func s3Error(err error) error {
+	var noSuchKey *types.NoSuchKey
+	var responseErr *awshttp.ResponseError
+	if errors.As(err, &noSuchKey) || (errors.As(err, &responseErr) && responseErr.HTTPStatusCode() == http.StatusNotFound) {
+		return ErrS3NotFound
+	}
+	return err
+}
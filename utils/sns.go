@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	SnsTypeNotification             = "Notification"
+	SnsTypeSubscriptionConfirmation = "SubscriptionConfirmation"
+	SnsTypeUnsubscribeConfirmation  = "UnsubscribeConfirmation"
+)
+
+var snsHostRegex = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// SnsMessage is a message delivered by amazon sns to an http(s) subscription, see https://docs.aws.amazon.com/sns/latest/dg/sns-message-and-json-formats.html
+type SnsMessage struct {
+	Type             string `json:"Type"`
+	MessageId        string `json:"MessageId"`
+	Token            string `json:"Token"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+	SubscribeURL     string `json:"SubscribeURL"`
+}
+
+// SnsVerifier checks the signatures of sns messages, signing certificates are fetched once and kept in memory
+type SnsVerifier struct {
+	httpClient *http.Client
+	certs      map[string]*x509.Certificate
+	lock       sync.Mutex
+}
+
+func NewSnsVerifier(httpClient *http.Client) *SnsVerifier {
+	return &SnsVerifier{httpClient: httpClient, certs: map[string]*x509.Certificate{}}
+}
+
+func ParseSnsMessage(data []byte) (*SnsMessage, error) {
+	var message SnsMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, err
+	}
+	if message.Type == "" || message.TopicArn == "" {
+		return nil, errors.New("not an sns message")
+	}
+	return &message, nil
+}
+
+// Verify checks the message's signature against the certificate it was signed with, which has to be served by sns itself
+// see https://docs.aws.amazon.com/sns/latest/dg/sns-verify-signature-of-message.html
+func (v *SnsVerifier) Verify(message *SnsMessage) error {
+	var hash crypto.Hash
+	switch message.SignatureVersion {
+	case "1":
+		hash = crypto.SHA1
+	case "2":
+		hash = crypto.SHA256
+	default:
+		return fmt.Errorf("unsupported sns signature version '%s'", message.SignatureVersion)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(message.Signature)
+	if err != nil {
+		return err
+	}
+
+	cert, err := v.certificate(message.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("unexpected sns signing key type")
+	}
+
+	var digest []byte
+	if hash == crypto.SHA1 {
+		h := sha1.Sum([]byte(message.StringToSign()))
+		digest = h[:]
+	} else {
+		h := sha256.Sum256([]byte(message.StringToSign()))
+		digest = h[:]
+	}
+	return rsa.VerifyPKCS1v15(publicKey, hash, digest, signature)
+}
+
+// StringToSign returns the message's fields, which its signature was calculated from, in their canonical form
+func (m *SnsMessage) StringToSign() string {
+	fields := [][2]string{{"Message", m.Message}, {"MessageId", m.MessageId}}
+	if m.Type == SnsTypeNotification {
+		if m.Subject != "" {
+			fields = append(fields, [2]string{"Subject", m.Subject})
+		}
+	} else {
+		fields = append(fields, [2]string{"SubscribeURL", m.SubscribeURL})
+	}
+	fields = append(fields, [2]string{"Timestamp", m.Timestamp})
+	if m.Type != SnsTypeNotification {
+		fields = append(fields, [2]string{"Token", m.Token})
+	}
+	fields = append(fields, [2]string{"TopicArn", m.TopicArn}, [2]string{"Type", m.Type})
+
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteString(f[0] + "\n" + f[1] + "\n")
+	}
+	return b.String()
+}
+
+// IsSnsUrl returns true if the given url points to an sns endpoint, e.g. to prevent fetching arbitrary resources from urls given in a message
+func IsSnsUrl(rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	return err == nil && u.Scheme == "https" && snsHostRegex.MatchString(u.Hostname())
+}
+
+func (v *SnsVerifier) certificate(certUrl string) (*x509.Certificate, error) {
+	if !IsSnsUrl(certUrl) {
+		return nil, fmt.Errorf("invalid sns signing certificate url '%s'", certUrl)
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if cert, ok := v.certs[certUrl]; ok {
+		return cert, nil
+	}
+
+	res, err := RaiseForStatus(v.httpClient.Get(certUrl))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to decode sns signing certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	v.certs[certUrl] = cert
+	return cert, nil
+}
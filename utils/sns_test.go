@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnsMessage_StringToSign(t *testing.T) {
+	notification := &SnsMessage{Type: SnsTypeNotification, MessageId: "id", TopicArn: "arn", Message: "msg", Timestamp: "ts"}
+	assert.Equal(t, "Message\nmsg\nMessageId\nid\nTimestamp\nts\nTopicArn\narn\nType\nNotification\n", notification.StringToSign())
+
+	notification.Subject = "subj"
+	assert.Equal(t, "Message\nmsg\nMessageId\nid\nSubject\nsubj\nTimestamp\nts\nTopicArn\narn\nType\nNotification\n", notification.StringToSign())
+
+	confirmation := &SnsMessage{Type: SnsTypeSubscriptionConfirmation, MessageId: "id", TopicArn: "arn", Message: "msg", Timestamp: "ts", Token: "tok", SubscribeURL: "url", Subject: "ignored"}
+	assert.Equal(t, "Message\nmsg\nMessageId\nid\nSubscribeURL\nurl\nTimestamp\nts\nToken\ntok\nTopicArn\narn\nType\nSubscriptionConfirmation\n", confirmation.StringToSign())
+}
+
+func TestSnsVerifier_Verify(t *testing.T) {
+	const certUrl = "https://sns.eu-central-1.amazonaws.com/SimpleNotificationService-test.pem"
+
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "sns.amazonaws.com"}, NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	cert, _ := x509.ParseCertificate(der)
+
+	sut := NewSnsVerifier(nil)
+	sut.certs[certUrl] = cert
+
+	message, err := ParseSnsMessage([]byte(`{"Type":"Notification","MessageId":"22b80b92","TopicArn":"arn:aws:sns:eu-central-1:123456789012:ses-bounces","Message":"{\"notificationType\":\"Bounce\"}","Timestamp":"2023-06-01T12:00:00.000Z","SignatureVersion":"2","SigningCertURL":"` + certUrl + `"}`))
+	assert.Nil(t, err)
+
+	digest := sha256.Sum256([]byte(message.StringToSign()))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	message.Signature = base64.StdEncoding.EncodeToString(signature)
+	assert.Nil(t, sut.Verify(message))
+
+	message.Message = `{"notificationType":"Complaint"}`
+	assert.Error(t, sut.Verify(message))
+
+	message.SigningCertURL = "https://attacker.example.org/sns.amazonaws.com.pem"
+	assert.Error(t, sut.Verify(message))
+
+	_, err = ParseSnsMessage([]byte(`{"foo":"bar"}`))
+	assert.Error(t, err)
+}
+
+func TestIsSnsUrl(t *testing.T) {
+	assert.True(t, IsSnsUrl("https://sns.us-east-1.amazonaws.com/?Action=ConfirmSubscription&TopicArn=arn"))
+	assert.True(t, IsSnsUrl("https://sns.cn-north-1.amazonaws.com.cn/cert.pem"))
+	assert.False(t, IsSnsUrl("http://sns.us-east-1.amazonaws.com/cert.pem"))
+	assert.False(t, IsSnsUrl("https://sns.us-east-1.amazonaws.com.example.org/cert.pem"))
+	assert.False(t, IsSnsUrl("https://example.org/sns.us-east-1.amazonaws.com"))
+}
@@ -23,3 +23,8 @@ func FindString(needle string, haystack []string, defaultVal string) string {
 	}
 	return defaultVal
 }
+
+// WildcardToLike turns a pattern with "*" as a wildcard into one for a LIKE query with "!" as escape character, escaping all of LIKE's own special characters
+func WildcardToLike(pattern string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_", "*", "%").Replace(pattern)
+}
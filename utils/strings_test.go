@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStrings_WildcardToLike(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"*.go", "%.go"},
+		{"/home/*/private/*", "/home/%/private/%"},
+		{"100%_done.txt", "100!%!_done.txt"},
+		{"wow!*", "wow!!%"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.out, WildcardToLike(test.in))
+	}
+}
@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const XlsxMimeType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// XlsxSheet is a single worksheet, whose rows may contain strings and numbers
+type XlsxSheet struct {
+	Name string
+	Rows [][]interface{}
+}
+
+// WriteXlsx writes a minimal office open xml workbook consisting of the given sheets
+// cells are written as inline strings or plain numbers, no styles, formulas or shared strings are used
+func WriteXlsx(w io.Writer, sheets []*XlsxSheet) error {
+	if len(sheets) == 0 {
+		return fmt.Errorf("workbook must contain at least one sheet")
+	}
+
+	zw := zip.NewWriter(w)
+
+	var contentTypes, workbook, workbookRels strings.Builder
+
+	contentTypes.WriteString(xml.Header)
+	contentTypes.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	contentTypes.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	contentTypes.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	contentTypes.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+
+	workbook.WriteString(xml.Header)
+	workbook.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+
+	workbookRels.WriteString(xml.Header)
+	workbookRels.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+
+	for i, sheet := range sheets {
+		n := i + 1
+		contentTypes.WriteString(fmt.Sprintf(`<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n))
+		workbook.WriteString(fmt.Sprintf(`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(xlsxSheetName(sheet.Name, n)), n, n))
+		workbookRels.WriteString(fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n))
+
+		data, err := xlsxSheetXml(sheet)
+		if err != nil {
+			return err
+		}
+		if err := writeZipEntry(zw, fmt.Sprintf("xl/worksheets/sheet%d.xml", n), data); err != nil {
+			return err
+		}
+	}
+
+	contentTypes.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	workbookRels.WriteString(`</Relationships>`)
+
+	rootRels := xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	parts := []struct {
+		name string
+		data string
+	}{
+		{"[Content_Types].xml", contentTypes.String()},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", workbookRels.String()},
+	}
+	for _, p := range parts {
+		if err := writeZipEntry(zw, p.name, []byte(p.data)); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// XlsxColumnName converts a zero-based column index to its spreadsheet letter representation (0 -> A, 26 -> AA)
+func XlsxColumnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func xlsxSheetXml(sheet *XlsxSheet) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	for i, row := range sheet.Rows {
+		buf.WriteString(fmt.Sprintf(`<row r="%d">`, i+1))
+		for j, cell := range row {
+			ref := fmt.Sprintf("%s%d", XlsxColumnName(j), i+1)
+			switch v := cell.(type) {
+			case nil:
+				continue
+			case string:
+				buf.WriteString(fmt.Sprintf(`<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xmlEscape(v)))
+			case int:
+				buf.WriteString(fmt.Sprintf(`<c r="%s"><v>%d</v></c>`, ref, v))
+			case int64:
+				buf.WriteString(fmt.Sprintf(`<c r="%s"><v>%d</v></c>`, ref, v))
+			case float64:
+				buf.WriteString(fmt.Sprintf(`<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64)))
+			default:
+				return nil, fmt.Errorf("unsupported cell type %T in sheet '%s'", cell, sheet.Name)
+			}
+		}
+		buf.WriteString(`</row>`)
+	}
+
+	buf.WriteString(`</sheetData></worksheet>`)
+	return buf.Bytes(), nil
+}
+
+// excel limits sheet names to 31 characters and forbids some special characters
+func xlsxSheetName(name string, n int) string {
+	name = strings.NewReplacer(":", "", "\\", "", "/", "", "?", "", "*", "", "[", "", "]", "").Replace(name)
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if name == "" {
+		name = fmt.Sprintf("Sheet%d", n)
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+func writeZipEntry(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestXlsx_XlsxColumnName(t *testing.T) {
+	tests := []struct {
+		in  int
+		out string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{51, "AZ"},
+		{702, "AAA"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.out, XlsxColumnName(test.in))
+	}
+}
+
+func TestXlsx_WriteXlsx(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteXlsx(&buf, []*XlsxSheet{
+		{Name: "Projects", Rows: [][]interface{}{{"Project", "Hours"}, {"wakapi <&>", 1.5}}},
+		{Name: "Days", Rows: [][]interface{}{{"Date", "Hours"}, {"2026-10-16", 2}}},
+	})
+	assert.Nil(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	assert.Contains(t, files, "[Content_Types].xml")
+	assert.Contains(t, files, "_rels/.rels")
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="Projects" sheetId="1" r:id="rId1"/>`)
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="Days" sheetId="2" r:id="rId2"/>`)
+	assert.Contains(t, files["xl/worksheets/sheet1.xml"], `<c r="A2" t="inlineStr"><is><t xml:space="preserve">wakapi &lt;&amp;&gt;</t></is></c>`)
+	assert.Contains(t, files["xl/worksheets/sheet1.xml"], `<c r="B2"><v>1.5</v></c>`)
+	assert.Contains(t, files["xl/worksheets/sheet2.xml"], `<c r="B2"><v>2</v></c>`)
+}
+
+func TestXlsx_WriteXlsx_Unsupported(t *testing.T) {
+	assert.Error(t, WriteXlsx(io.Discard, []*XlsxSheet{}))
+	assert.Error(t, WriteXlsx(io.Discard, []*XlsxSheet{{Name: "Invalid", Rows: [][]interface{}{{true}}}}))
+}